@@ -0,0 +1,188 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	coreosoidc "github.com/coreos/go-oidc/v3/oidc"
+	"github.com/spf13/cobra"
+
+	oidcapi "go.pinniped.dev/generated/latest/apis/supervisor/oidc"
+	"go.pinniped.dev/internal/execcredcache"
+	"go.pinniped.dev/internal/here"
+	"go.pinniped.dev/internal/net/phttp"
+	"go.pinniped.dev/internal/plog"
+	"go.pinniped.dev/pkg/oidcclient/filesession"
+	"go.pinniped.dev/pkg/oidcclient/oidctypes"
+)
+
+//nolint:gochecknoinits
+func init() {
+	rootCmd.AddCommand(newLogoutCommand(logoutRealDeps()))
+}
+
+type logoutDeps struct {
+	lookupEnv func(string) (string, bool)
+}
+
+func logoutRealDeps() logoutDeps {
+	return logoutDeps{
+		lookupEnv: os.LookupEnv,
+	}
+}
+
+type logoutFlags struct {
+	issuer              string
+	clientID            string
+	sessionCachePath    string
+	credentialCachePath string
+	caBundlePaths       []string
+	caBundleData        []string
+	timeout             time.Duration
+}
+
+func newLogoutCommand(deps logoutDeps) *cobra.Command {
+	cmd := &cobra.Command{
+		Args:  cobra.NoArgs, // do not accept positional arguments for this command
+		Use:   "logout --issuer ISSUER",
+		Short: "Log out of a Pinniped Supervisor",
+		Long: here.Doc(
+			`Log out of a Pinniped Supervisor
+
+				Removes the sessions for the issuer from the session cache, removes all cached
+				cluster credentials, and revokes the removed sessions using the token revocation
+				endpoint of the issuer. The next kubectl command which uses a kubeconfig file for
+				this issuer will require logging in again.`,
+		),
+		SilenceUsage: true, // do not print usage message when commands fail
+	}
+	flags := &logoutFlags{}
+
+	f := cmd.Flags()
+	f.StringVar(&flags.issuer, "issuer", "", "OpenID Connect issuer URL")
+	f.StringVar(&flags.clientID, "client-id", oidcapi.ClientIDPinnipedCLI, "OpenID Connect client ID")
+	f.StringVar(&flags.sessionCachePath, "session-cache", filepath.Join(mustGetConfigDir(), "sessions.yaml"), "Path to session cache file")
+	f.StringVar(&flags.credentialCachePath, "credential-cache", filepath.Join(mustGetConfigDir(), "credentials.yaml"), "Path to cluster-specific credentials cache (\"\" disables the cache)")
+	f.StringSliceVar(&flags.caBundlePaths, "ca-bundle", nil, "Path to TLS certificate authority bundle (PEM format, optional, can be repeated)")
+	f.StringSliceVar(&flags.caBundleData, "ca-bundle-data", nil, "Base64 encoded TLS certificate authority bundle (base64 encoded PEM format, optional, can be repeated)")
+	f.DurationVar(&flags.timeout, "timeout", 30*time.Second, "Timeout for the requests to the issuer")
+	mustMarkRequired(cmd, "issuer")
+
+	cmd.RunE = func(cmd *cobra.Command, _ []string) error {
+		return runLogout(cmd.Context(), cmd.OutOrStdout(), deps, flags)
+	}
+
+	return cmd
+}
+
+func runLogout(ctx context.Context, output io.Writer, deps logoutDeps, flags *logoutFlags) error {
+	pLogger, err := SetLogLevel(ctx, deps.lookupEnv)
+	if err != nil {
+		plog.WarningErr("Received error while setting log level", err)
+	}
+
+	httpClient := phttp.Default(nil)
+	if len(flags.caBundlePaths) > 0 || len(flags.caBundleData) > 0 {
+		httpClient, err = makeClient(flags.caBundlePaths, flags.caBundleData)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Remove the local state first, so the user is logged out of this machine even when the issuer is unreachable.
+	tokens := filesession.New(flags.sessionCachePath, filesession.WithErrorReporter(func(err error) {
+		pLogger.Error("error during session cache operation", err)
+	})).DeleteTokens(flags.issuer, flags.clientID)
+	pLogger.Debug("removed sessions from session cache", "issuer", flags.issuer, "count", len(tokens))
+
+	if flags.credentialCachePath != "" {
+		execcredcache.New(flags.credentialCachePath).Clear()
+		pLogger.Debug("removed all cluster credentials from credential cache")
+	}
+
+	if len(tokens) == 0 {
+		_, _ = fmt.Fprintf(output, "No sessions found for issuer %s\n", flags.issuer)
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, flags.timeout)
+	defer cancel()
+
+	revocationEndpoint, err := discoverRevocationEndpoint(ctx, httpClient, flags.issuer)
+	if err != nil {
+		return fmt.Errorf("removed local sessions, but could not revoke them: %w", err)
+	}
+	if revocationEndpoint == "" {
+		_, _ = fmt.Fprintf(output, "Removed local sessions for issuer %s, which does not support token revocation\n", flags.issuer)
+		return nil
+	}
+
+	for _, token := range tokens {
+		if err := revokeToken(ctx, httpClient, revocationEndpoint, flags.clientID, token); err != nil {
+			return fmt.Errorf("removed local sessions, but could not revoke them: %w", err)
+		}
+	}
+
+	_, _ = fmt.Fprintf(output, "Logged out of issuer %s\n", flags.issuer)
+	return nil
+}
+
+func discoverRevocationEndpoint(ctx context.Context, httpClient *http.Client, issuer string) (string, error) {
+	provider, err := coreosoidc.NewProvider(coreosoidc.ClientContext(ctx, httpClient), issuer)
+	if err != nil {
+		return "", fmt.Errorf("could not perform OIDC discovery for %q: %w", issuer, err)
+	}
+
+	var claims struct {
+		RevocationEndpoint string `json:"revocation_endpoint"`
+	}
+	if err := provider.Claims(&claims); err != nil {
+		return "", fmt.Errorf("could not decode OIDC discovery claims for %q: %w", issuer, err)
+	}
+	return claims.RevocationEndpoint, nil
+}
+
+// revokeToken revokes the session of the token. Revoking the refresh token is preferred, because the Supervisor
+// would revoke the whole session anyway, and because the access token may have already expired.
+func revokeToken(ctx context.Context, httpClient *http.Client, revocationEndpoint string, clientID string, token *oidctypes.Token) error {
+	params := url.Values{"client_id": {clientID}}
+	switch {
+	case token.RefreshToken != nil:
+		params.Set("token", token.RefreshToken.Token)
+		params.Set("token_type_hint", "refresh_token")
+	case token.AccessToken != nil:
+		params.Set("token", token.AccessToken.Token)
+		params.Set("token_type_hint", "access_token")
+	default:
+		// Only an ID token was cached, which cannot be revoked.
+		return nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, revocationEndpoint, strings.NewReader(params.Encode()))
+	if err != nil {
+		return fmt.Errorf("could not build revocation request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	rsp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("revocation request failed: %w", err)
+	}
+	defer func() { _ = rsp.Body.Close() }()
+
+	if rsp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(rsp.Body, 1024))
+		return fmt.Errorf("revocation request failed with status %d: %s", rsp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
@@ -0,0 +1,247 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientauthv1beta1 "k8s.io/client-go/pkg/apis/clientauthentication/v1beta1"
+
+	"go.pinniped.dev/internal/execcredcache"
+	"go.pinniped.dev/internal/here"
+	"go.pinniped.dev/internal/testutil/tlsserver"
+	"go.pinniped.dev/pkg/oidcclient"
+	"go.pinniped.dev/pkg/oidcclient/filesession"
+	"go.pinniped.dev/pkg/oidcclient/oidctypes"
+)
+
+func TestLogoutCommand(t *testing.T) {
+	cfgDir := mustGetConfigDir()
+	expiry := metav1.NewTime(time.Now().Add(time.Hour).Round(time.Second))
+
+	refreshTokenSession := &oidctypes.Token{
+		IDToken:      &oidctypes.IDToken{Token: "some-id-token", Expiry: expiry},
+		RefreshToken: &oidctypes.RefreshToken{Token: "some-refresh-token"},
+	}
+	accessTokenSession := &oidctypes.Token{
+		AccessToken: &oidctypes.AccessToken{Token: "some-access-token", Expiry: expiry},
+	}
+
+	tests := []struct {
+		name                     string
+		args                     func(issuer, caBundleData string) []string
+		sessions                 []*oidctypes.Token
+		noRevocationEndpoint     bool
+		revocationStatus         int
+		wantError                bool
+		wantStdout               func(issuer string) string
+		wantStderr               func(issuer string) string
+		wantRevocationParams     []map[string]string
+		wantCredentialCacheEmpty bool
+	}{
+		{
+			name: "help flag passed",
+			args: func(_, _ string) []string { return []string{"--help"} },
+			wantStdout: func(_ string) string {
+				return here.Doc(`
+					Log out of a Pinniped Supervisor
+
+					Removes the sessions for the issuer from the session cache, removes all cached
+					cluster credentials, and revokes the removed sessions using the token revocation
+					endpoint of the issuer. The next kubectl command which uses a kubeconfig file for
+					this issuer will require logging in again.
+
+					Usage:
+					  logout --issuer ISSUER [flags]
+
+					Flags:
+					      --ca-bundle strings         Path to TLS certificate authority bundle (PEM format, optional, can be repeated)
+					      --ca-bundle-data strings    Base64 encoded TLS certificate authority bundle (base64 encoded PEM format, optional, can be repeated)
+					      --client-id string          OpenID Connect client ID (default "pinniped-cli")
+					      --credential-cache string   Path to cluster-specific credentials cache ("" disables the cache) (default "` + cfgDir + `/credentials.yaml")
+					  -h, --help                      help for logout
+					      --issuer string             OpenID Connect issuer URL
+					      --session-cache string      Path to session cache file (default "` + cfgDir + `/sessions.yaml")
+					      --timeout duration          Timeout for the requests to the issuer (default 30s)
+				`)
+			},
+		},
+		{
+			name:      "missing required flags",
+			args:      func(_, _ string) []string { return []string{} },
+			wantError: true,
+			wantStderr: func(_ string) string {
+				return here.Doc(`
+					Error: required flag(s) "issuer" not set
+				`)
+			},
+		},
+		{
+			name: "no sessions for the issuer",
+			args: func(issuer, caBundleData string) []string {
+				return []string{"--issuer", issuer, "--ca-bundle-data", caBundleData}
+			},
+			wantStdout: func(issuer string) string {
+				return "No sessions found for issuer " + issuer + "\n"
+			},
+			wantCredentialCacheEmpty: true,
+		},
+		{
+			name: "revokes the refresh token or access token of each session",
+			args: func(issuer, caBundleData string) []string {
+				return []string{"--issuer", issuer, "--ca-bundle-data", caBundleData}
+			},
+			sessions: []*oidctypes.Token{refreshTokenSession, accessTokenSession},
+			wantStdout: func(issuer string) string {
+				return "Logged out of issuer " + issuer + "\n"
+			},
+			wantRevocationParams: []map[string]string{
+				{"client_id": "pinniped-cli", "token": "some-refresh-token", "token_type_hint": "refresh_token"},
+				{"client_id": "pinniped-cli", "token": "some-access-token", "token_type_hint": "access_token"},
+			},
+			wantCredentialCacheEmpty: true,
+		},
+		{
+			name: "issuer does not support token revocation",
+			args: func(issuer, caBundleData string) []string {
+				return []string{"--issuer", issuer, "--ca-bundle-data", caBundleData}
+			},
+			sessions:             []*oidctypes.Token{refreshTokenSession},
+			noRevocationEndpoint: true,
+			wantStdout: func(issuer string) string {
+				return "Removed local sessions for issuer " + issuer + ", which does not support token revocation\n"
+			},
+			wantCredentialCacheEmpty: true,
+		},
+		{
+			name: "revocation request fails",
+			args: func(issuer, caBundleData string) []string {
+				return []string{"--issuer", issuer, "--ca-bundle-data", caBundleData}
+			},
+			sessions:         []*oidctypes.Token{refreshTokenSession},
+			revocationStatus: http.StatusServiceUnavailable,
+			wantError:        true,
+			wantStderr: func(_ string) string {
+				return "Error: removed local sessions, but could not revoke them: revocation request failed with status 503: some error\n"
+			},
+			wantRevocationParams: []map[string]string{
+				{"client_id": "pinniped-cli", "token": "some-refresh-token", "token_type_hint": "refresh_token"},
+			},
+			wantCredentialCacheEmpty: true,
+		},
+		{
+			name: "discovery fails because the CA bundle is not trusted",
+			args: func(issuer, _ string) []string {
+				return []string{"--issuer", issuer}
+			},
+			sessions:  []*oidctypes.Token{refreshTokenSession},
+			wantError: true,
+			wantStderr: func(issuer string) string {
+				return `Error: removed local sessions, but could not revoke them: could not perform OIDC discovery for "` + issuer + `": ` +
+					`Get "` + issuer + `/.well-known/openid-configuration": tls: failed to verify certificate: x509: certificate signed by unknown authority` + "\n"
+			},
+			wantCredentialCacheEmpty: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var issuer string
+			var gotRevocationParams []map[string]string
+			server, caBundle := tlsserver.TestServerIPv4(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/.well-known/openid-configuration":
+					discovery := map[string]string{"issuer": issuer}
+					if !tt.noRevocationEndpoint {
+						discovery["revocation_endpoint"] = issuer + "/oauth2/revoke"
+					}
+					w.Header().Set("Content-Type", "application/json")
+					require.NoError(t, json.NewEncoder(w).Encode(discovery))
+				case "/oauth2/revoke":
+					require.Equal(t, http.MethodPost, r.Method)
+					require.NoError(t, r.ParseForm())
+					params := map[string]string{}
+					for k := range r.PostForm {
+						params[k] = r.PostForm.Get(k)
+					}
+					gotRevocationParams = append(gotRevocationParams, params)
+					if tt.revocationStatus != 0 {
+						http.Error(w, "some error", tt.revocationStatus)
+					}
+				default:
+					http.NotFound(w, r)
+				}
+			}), nil)
+			issuer = server.URL
+
+			tmpdir := t.TempDir()
+			sessionCachePath := filepath.Join(tmpdir, "sessions.yaml")
+			credentialCachePath := filepath.Join(tmpdir, "credentials.yaml")
+
+			sessionCache := filesession.New(sessionCachePath)
+			for i, token := range tt.sessions {
+				sessionCache.PutToken(oidcclient.SessionCacheKey{
+					Issuer:      issuer,
+					ClientID:    "pinniped-cli",
+					Scopes:      []string{"openid", "offline_access"},
+					RedirectURI: "http://localhost:0/callback",
+					// Make each key unique.
+					UpstreamProviderName: string(rune('a' + i)),
+				}, token)
+			}
+			otherIssuerKey := oidcclient.SessionCacheKey{Issuer: "https://other-issuer.example.com", ClientID: "pinniped-cli"}
+			sessionCache.PutToken(otherIssuerKey, refreshTokenSession)
+
+			credCache := execcredcache.New(credentialCachePath)
+			credCache.Put("some-key", &clientauthv1beta1.ExecCredential{
+				Status: &clientauthv1beta1.ExecCredentialStatus{Token: "some-cluster-token", ExpirationTimestamp: &expiry},
+			})
+
+			cmd := newLogoutCommand(logoutDeps{
+				lookupEnv: func(_ string) (string, bool) { return "", false },
+			})
+			var stdout, stderr bytes.Buffer
+			cmd.SetOut(&stdout)
+			cmd.SetErr(&stderr)
+			args := tt.args(issuer, base64.StdEncoding.EncodeToString(caBundle))
+			if len(args) > 0 && args[0] != "--help" {
+				args = append(args, "--session-cache", sessionCachePath, "--credential-cache", credentialCachePath)
+			}
+			cmd.SetArgs(args)
+			err := cmd.Execute()
+			if tt.wantError {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+
+			wantStdout, wantStderr := "", ""
+			if tt.wantStdout != nil {
+				wantStdout = tt.wantStdout(issuer)
+			}
+			if tt.wantStderr != nil {
+				wantStderr = tt.wantStderr(issuer)
+			}
+			require.Equal(t, wantStdout, stdout.String(), "unexpected stdout")
+			require.Equal(t, wantStderr, stderr.String(), "unexpected stderr")
+			require.Equal(t, tt.wantRevocationParams, gotRevocationParams)
+
+			// Sessions for other issuers are never removed.
+			require.NotNil(t, sessionCache.GetToken(otherIssuerKey))
+			if tt.wantCredentialCacheEmpty {
+				require.Empty(t, sessionCache.DeleteTokens(issuer, "pinniped-cli"))
+				require.Nil(t, credCache.Get("some-key"))
+			} else {
+				require.NotNil(t, credCache.Get("some-key"))
+			}
+		})
+	}
+}
@@ -181,6 +181,48 @@ func (in *ImpersonationProxyInfo) DeepCopy() *ImpersonationProxyInfo {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImpersonationProxyRateLimit) DeepCopyInto(out *ImpersonationProxyRateLimit) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImpersonationProxyRateLimit.
+func (in *ImpersonationProxyRateLimit) DeepCopy() *ImpersonationProxyRateLimit {
+	if in == nil {
+		return nil
+	}
+	out := new(ImpersonationProxyRateLimit)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImpersonationProxyRateLimitsSpec) DeepCopyInto(out *ImpersonationProxyRateLimitsSpec) {
+	*out = *in
+	if in.PerUser != nil {
+		in, out := &in.PerUser, &out.PerUser
+		*out = new(ImpersonationProxyRateLimit)
+		**out = **in
+	}
+	if in.PerGroup != nil {
+		in, out := &in.PerGroup, &out.PerGroup
+		*out = new(ImpersonationProxyRateLimit)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImpersonationProxyRateLimitsSpec.
+func (in *ImpersonationProxyRateLimitsSpec) DeepCopy() *ImpersonationProxyRateLimitsSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ImpersonationProxyRateLimitsSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ImpersonationProxyServiceSpec) DeepCopyInto(out *ImpersonationProxyServiceSpec) {
 	*out = *in
@@ -213,6 +255,11 @@ func (in *ImpersonationProxySpec) DeepCopyInto(out *ImpersonationProxySpec) {
 		*out = new(ImpersonationProxyTLSSpec)
 		**out = **in
 	}
+	if in.RateLimits != nil {
+		in, out := &in.RateLimits, &out.RateLimits
+		*out = new(ImpersonationProxyRateLimitsSpec)
+		(*in).DeepCopyInto(*out)
+	}
 	return
 }
 
@@ -0,0 +1,12 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// +k8s:openapi-gen=true
+// +k8s:deepcopy-gen=package
+// +k8s:conversion-gen=go.pinniped.dev/generated/1.31/apis/supervisor/session
+// +k8s:defaulter-gen=TypeMeta
+// +k8s:openapi-model-package=dev.pinniped.apis.supervisor.session.v1alpha1
+// +groupName=session.supervisor.pinniped.dev
+
+// Package v1alpha1 is the v1alpha1 version of the Pinniped session API.
+package v1alpha1
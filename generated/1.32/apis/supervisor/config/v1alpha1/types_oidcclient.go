@@ -1,4 +1,4 @@
-// Copyright 2022-2025 the Pinniped contributors. All Rights Reserved.
+// Copyright 2022-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package v1alpha1
@@ -21,33 +21,72 @@ const (
 // +kubebuilder:validation:Pattern=`^https://.+|^http://(127\.0\.0\.1|\[::1\])(:\d+)?/`
 type RedirectURI string
 
-// +kubebuilder:validation:Enum="authorization_code";"refresh_token";"urn:ietf:params:oauth:grant-type:token-exchange"
+// +kubebuilder:validation:Enum="authorization_code";"refresh_token";"urn:ietf:params:oauth:grant-type:token-exchange";"client_credentials"
 type GrantType string
 
 // +kubebuilder:validation:Enum="openid";"offline_access";"username";"groups";"pinniped:request-audience"
 type Scope string
 
+// +kubebuilder:validation:Enum=client_secret_basic;private_key_jwt;tls_client_auth
+type TokenEndpointAuthMethod string
+
+const (
+	// TokenEndpointAuthMethodClientSecretBasic authenticates the client using HTTP basic auth with a client secret
+	// which was generated by an OIDCClientSecretRequest.
+	TokenEndpointAuthMethodClientSecretBasic TokenEndpointAuthMethod = "client_secret_basic"
+
+	// TokenEndpointAuthMethodPrivateKeyJWT authenticates the client using a JWT assertion which was signed by the
+	// private key of the client, as described in RFC7523 and OpenID Connect Core 1.0 section 9.
+	TokenEndpointAuthMethodPrivateKeyJWT TokenEndpointAuthMethod = "private_key_jwt"
+
+	// TokenEndpointAuthMethodTLSClientAuth authenticates the client using a TLS client certificate which was issued
+	// by a trusted certificate authority, as described in RFC8705 section 2.1.
+	TokenEndpointAuthMethodTLSClientAuth TokenEndpointAuthMethod = "tls_client_auth"
+)
+
+// +kubebuilder:validation:Enum=RS256;RS384;RS512;PS256;PS384;PS512;ES256;ES384;ES512
+type SigningAlgorithm string
+
+// +kubebuilder:validation:Enum=opaque;jwt
+type AccessTokenFormat string
+
+const (
+	// AccessTokenFormatOpaque issues access tokens which are opaque strings. They can only be validated by the
+	// Supervisor, for example by using the token introspection endpoint of the FederationDomain.
+	AccessTokenFormatOpaque AccessTokenFormat = "opaque"
+
+	// AccessTokenFormatJWT issues access tokens which are JWTs, as described in RFC9068. They are signed by the
+	// active signing key of the FederationDomain, so they can be validated using the JWKS of the FederationDomain.
+	AccessTokenFormatJWT AccessTokenFormat = "jwt"
+)
+
 // OIDCClientSpec is a struct that describes an OIDCClient.
+// +kubebuilder:validation:XValidation:message="accessTokenAudience must be configured when accessTokenFormat is jwt",rule="!has(self.accessTokenFormat) || self.accessTokenFormat != 'jwt' || has(self.accessTokenAudience)"
 type OIDCClientSpec struct {
 	// allowedRedirectURIs is a list of the allowed redirect_uri param values that should be accepted during OIDC flows with this
 	// client. Any other uris will be rejected.
 	// Must be a URI with the https scheme, unless the hostname is 127.0.0.1 or ::1 which may use the http scheme.
 	// Port numbers are not required for 127.0.0.1 or ::1 and are ignored when checking for a matching redirect_uri.
+	// May be empty when the client does not use the authorization_code grant.
 	// +listType=set
-	// +kubebuilder:validation:MinItems=1
-	AllowedRedirectURIs []RedirectURI `json:"allowedRedirectURIs"`
+	// +optional
+	AllowedRedirectURIs []RedirectURI `json:"allowedRedirectURIs,omitempty"`
 
 	// allowedGrantTypes is a list of the allowed grant_type param values that should be accepted during OIDC flows with this
 	// client.
 	//
 	// Must only contain the following values:
 	// - authorization_code: allows the client to perform the authorization code grant flow, i.e. allows the webapp to
-	//   authenticate users. This grant must always be listed.
+	//   authenticate users. This grant must be listed, unless client_credentials is listed.
 	// - refresh_token: allows the client to perform refresh grants for the user to extend the user's session.
 	//   This grant must be listed if allowedScopes lists offline_access.
 	// - urn:ietf:params:oauth:grant-type:token-exchange: allows the client to perform RFC8693 token exchange,
 	//   which is a step in the process to be able to get a cluster credential for the user.
 	//   This grant must be listed if allowedScopes lists pinniped:request-audience.
+	// - client_credentials: allows the client to perform the client credentials grant, i.e. allows the client to get
+	//   tokens for itself, acting as the identity described by clientCredentials. This is intended for clients which
+	//   are services, such as CI systems, rather than webapps. clientCredentials must be configured when this
+	//   grant is listed.
 	// +listType=set
 	// +kubebuilder:validation:MinItems=1
 	AllowedGrantTypes []GrantType `json:"allowedGrantTypes"`
@@ -75,6 +114,139 @@ type OIDCClientSpec struct {
 	// tokenLifetimes are the optional overrides of token lifetimes for an OIDCClient.
 	// +optional
 	TokenLifetimes OIDCClientTokenLifetimes `json:"tokenLifetimes,omitempty"`
+
+	// sessionPolicy optionally overrides the settings of the sessionPolicy of the FederationDomain for the sessions
+	// and tokens of this client. Each setting which is not specified here will use the FederationDomain's setting.
+	// +optional
+	SessionPolicy *OIDCClientSessionPolicy `json:"sessionPolicy,omitempty"`
+
+	// tokenEndpointAuthMethod is the method that the client must use to authenticate itself to the Supervisor's
+	// token endpoint, and to the other endpoints which require client authentication.
+	//
+	// Must be one of the following values:
+	// - client_secret_basic: The client uses HTTP basic auth with one of its client secrets, which must be generated
+	//   using an OIDCClientSecretRequest. This is the default.
+	// - private_key_jwt: The client sends a JWT assertion which was signed by its private key, as described in
+	//   RFC7523. privateKeyJWT must be configured.
+	// - tls_client_auth: The client presents a TLS client certificate, as described in RFC8705.
+	//   tlsClientAuth must be configured, and the Supervisor's HTTPS endpoint must be configured to request
+	//   client certificates.
+	// +kubebuilder:default=client_secret_basic
+	// +optional
+	TokenEndpointAuthMethod TokenEndpointAuthMethod `json:"tokenEndpointAuthMethod,omitempty"`
+
+	// privateKeyJWT configures how to verify the JWT assertions of the client.
+	// Required when tokenEndpointAuthMethod is private_key_jwt, and not allowed otherwise.
+	// +optional
+	PrivateKeyJWT *OIDCClientPrivateKeyJWT `json:"privateKeyJWT,omitempty"`
+
+	// tlsClientAuth configures how to verify the TLS client certificates of the client.
+	// Required when tokenEndpointAuthMethod is tls_client_auth, and not allowed otherwise.
+	// +optional
+	TLSClientAuth *OIDCClientTLSClientAuth `json:"tlsClientAuth,omitempty"`
+
+	// clientCredentials describes the identity that the client acts as when it uses the client_credentials grant.
+	// Required when allowedGrantTypes lists client_credentials, and not allowed otherwise.
+	// +optional
+	ClientCredentials *OIDCClientClientCredentials `json:"clientCredentials,omitempty"`
+
+	// accessTokenAudience is the value of the aud claim of the JWT access tokens which are issued to the client.
+	// It identifies the resource servers which should accept the access tokens, as described in RFC9068.
+	// Required when accessTokenFormat is jwt. It must not be a client ID, so that the access tokens cannot be mistaken
+	// for the ID tokens which are issued to clients, since the aud claim of an ID token is the client ID.
+	// +kubebuilder:validation:MinLength=1
+	// +kubebuilder:validation:XValidation:message="accessTokenAudience must not be a client ID",rule="self != 'pinniped-cli' && !self.startsWith('client.oauth.pinniped.dev-')"
+	// +optional
+	AccessTokenAudience string `json:"accessTokenAudience,omitempty"`
+
+	// accessTokenFormat is the format of the access tokens which are issued to the client.
+	//
+	// Must be one of the following values:
+	// - opaque: The access tokens are opaque strings, which can only be validated by the Supervisor, for example by
+	//   using the token introspection endpoint of the FederationDomain. This is the default.
+	// - jwt: The access tokens are JWTs, as described in RFC9068, which are signed by the active signing key of the
+	//   FederationDomain. They contain the username and groups of the user when the client was granted the username
+	//   and groups scopes, and they may be validated by resource servers using the JWKS of the FederationDomain.
+	// +kubebuilder:default=opaque
+	// +optional
+	AccessTokenFormat AccessTokenFormat `json:"accessTokenFormat,omitempty"`
+
+	// requirePushedAuthorizationRequests, when true, requires the client to start each authorization request by pushing
+	// its authorization request parameters to the pushed authorization request endpoint of the FederationDomain, as
+	// described in RFC9126. The client must then send only its client_id and the returned request_uri to the authorization
+	// endpoint. This keeps the authorization request parameters out of the URL of the end user's browser, and therefore out
+	// of browser history and proxy logs. When false, the client may still use pushed authorization requests.
+	// +optional
+	RequirePushedAuthorizationRequests bool `json:"requirePushedAuthorizationRequests,omitempty"`
+}
+
+// OIDCClientClientCredentials describes the identity that an OIDCClient acts as when it uses the client_credentials
+// grant. This identity is treated like an identity from an external identity provider, so the identity
+// transformations and policies configured on the FederationDomain for the chosen identity provider are applied
+// to it to determine the final username and group memberships, or to reject the request.
+type OIDCClientClientCredentials struct {
+	// username is the username of the client, before identity transformations are applied.
+	// +kubebuilder:validation:MinLength=1
+	Username string `json:"username"`
+
+	// groups are the group memberships of the client, before identity transformations are applied.
+	// +listType=set
+	// +optional
+	Groups []string `json:"groups,omitempty"`
+
+	// identityProvider is the displayName of an identity provider of the FederationDomain, as configured in
+	// the spec.identityProviders of the FederationDomain. The identity transformations and policies of that
+	// identity provider are applied to the identity of the client. The client does not authenticate with the
+	// identity provider itself, but the identity provider must be ready to be used by the FederationDomain.
+	// When not set, the default identity provider of the FederationDomain is used, which only exists when
+	// the FederationDomain does not list any spec.identityProviders and there is exactly one identity provider
+	// resource in the same namespace as the Supervisor.
+	// +optional
+	IdentityProvider string `json:"identityProvider,omitempty"`
+}
+
+// OIDCClientPrivateKeyJWT describes how to verify the JWT assertions of an OIDCClient.
+// Exactly one of jwks or jwksURI must be configured.
+type OIDCClientPrivateKeyJWT struct {
+	// jwks is a JSON Web Key Set, as described in RFC7517, containing the public keys of the client.
+	// It must not contain any private keys.
+	// +optional
+	JWKS string `json:"jwks,omitempty"`
+
+	// jwksURI is the HTTPS URL of a JSON Web Key Set containing the public keys of the client. The Supervisor will
+	// fetch and cache the keys, and will fetch them again when it sees a key ID that it does not know.
+	// The URL must be trusted by the system certificate authorities of the Supervisor.
+	// +kubebuilder:validation:Pattern=`^https://`
+	// +optional
+	JWKSURI string `json:"jwksURI,omitempty"`
+
+	// signingAlgorithm is the JWS algorithm that the client must use to sign its JWT assertions.
+	// The keys of the client must be usable with this algorithm.
+	// +kubebuilder:default=RS256
+	// +optional
+	SigningAlgorithm SigningAlgorithm `json:"signingAlgorithm,omitempty"`
+}
+
+// OIDCClientTLSClientAuth describes how to verify the TLS client certificates of an OIDCClient.
+// Exactly one of subjectDN, sanDNS, or sanURI must be configured to identify the certificate of the client.
+type OIDCClientTLSClientAuth struct {
+	// certificateAuthorityData is the X.509 Certificate Authority (base64-encoded PEM bundle) which issues the
+	// TLS client certificates of the client.
+	// +kubebuilder:validation:MinLength=1
+	CertificateAuthorityData string `json:"certificateAuthorityData"`
+
+	// subjectDN is the expected subject distinguished name of the certificate, in the RFC4514 string
+	// representation, e.g. "CN=my-client,O=my-org".
+	// +optional
+	SubjectDN string `json:"subjectDN,omitempty"`
+
+	// sanDNS is the expected dNSName subject alternative name of the certificate.
+	// +optional
+	SANDNS string `json:"sanDNS,omitempty"`
+
+	// sanURI is the expected uniformResourceIdentifier subject alternative name of the certificate.
+	// +optional
+	SANURI string `json:"sanURI,omitempty"`
 }
 
 // OIDCClientTokenLifetimes describes the optional overrides of token lifetimes for an OIDCClient.
@@ -95,6 +267,40 @@ type OIDCClientTokenLifetimes struct {
 	IDTokenSeconds *int32 `json:"idTokenSeconds,omitempty"`
 }
 
+// OIDCClientSessionPolicy describes the optional overrides of a FederationDomain's session policy for an OIDCClient.
+// See the sessionPolicy of the FederationDomain for a description of each setting.
+type OIDCClientSessionPolicy struct {
+	// maxSessionAgeSeconds is the maximum length of time, in seconds, that an end user's session with this client
+	// may continue after their initial login, no matter how often the session is refreshed.
+	// This value must be between 300 seconds (5 minutes) and 2,592,000 seconds (30 days), inclusive.
+	// +kubebuilder:validation:Minimum=300
+	// +kubebuilder:validation:Maximum=2592000
+	// +optional
+	MaxSessionAgeSeconds *int32 `json:"maxSessionAgeSeconds,omitempty"`
+
+	// refreshTokenSeconds is the lifetime of each refresh token issued to this client, in seconds.
+	// This value must be between 300 seconds (5 minutes) and 2,592,000 seconds (30 days), inclusive.
+	// +kubebuilder:validation:Minimum=300
+	// +kubebuilder:validation:Maximum=2592000
+	// +optional
+	RefreshTokenSeconds *int32 `json:"refreshTokenSeconds,omitempty"`
+
+	// idleTimeoutSeconds is the length of time, in seconds, after which a session with this client which has not
+	// been used to perform a refresh grant will end.
+	// This value must be between 300 seconds (5 minutes) and 2,592,000 seconds (30 days), inclusive.
+	// +kubebuilder:validation:Minimum=300
+	// +kubebuilder:validation:Maximum=2592000
+	// +optional
+	IdleTimeoutSeconds *int32 `json:"idleTimeoutSeconds,omitempty"`
+
+	// accessTokenSeconds is the lifetime of access tokens issued to this client, in seconds.
+	// This value must be between 60 and 3,600 seconds (1 hour), inclusive.
+	// +kubebuilder:validation:Minimum=60
+	// +kubebuilder:validation:Maximum=3600
+	// +optional
+	AccessTokenSeconds *int32 `json:"accessTokenSeconds,omitempty"`
+}
+
 // OIDCClientStatus is a struct that describes the actual state of an OIDCClient.
 type OIDCClientStatus struct {
 	// phase summarizes the overall status of the OIDCClient.
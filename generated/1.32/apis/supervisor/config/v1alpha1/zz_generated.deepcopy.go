@@ -41,11 +41,84 @@ func (in *FederationDomain) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FederationDomainAuthenticationPolicy) DeepCopyInto(out *FederationDomainAuthenticationPolicy) {
+	*out = *in
+	if in.ACRValues != nil {
+		in, out := &in.ACRValues, &out.ACRValues
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FederationDomainAuthenticationPolicy.
+func (in *FederationDomainAuthenticationPolicy) DeepCopy() *FederationDomainAuthenticationPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(FederationDomainAuthenticationPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FederationDomainBranding) DeepCopyInto(out *FederationDomainBranding) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FederationDomainBranding.
+func (in *FederationDomainBranding) DeepCopy() *FederationDomainBranding {
+	if in == nil {
+		return nil
+	}
+	out := new(FederationDomainBranding)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FederationDomainClientRegistration) DeepCopyInto(out *FederationDomainClientRegistration) {
+	*out = *in
+	if in.AllowedRedirectURIPatterns != nil {
+		in, out := &in.AllowedRedirectURIPatterns, &out.AllowedRedirectURIPatterns
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.AllowedGrantTypes != nil {
+		in, out := &in.AllowedGrantTypes, &out.AllowedGrantTypes
+		*out = make([]GrantType, len(*in))
+		copy(*out, *in)
+	}
+	if in.AllowedScopes != nil {
+		in, out := &in.AllowedScopes, &out.AllowedScopes
+		*out = make([]Scope, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FederationDomainClientRegistration.
+func (in *FederationDomainClientRegistration) DeepCopy() *FederationDomainClientRegistration {
+	if in == nil {
+		return nil
+	}
+	out := new(FederationDomainClientRegistration)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *FederationDomainIdentityProvider) DeepCopyInto(out *FederationDomainIdentityProvider) {
 	*out = *in
 	in.ObjectRef.DeepCopyInto(&out.ObjectRef)
 	in.Transforms.DeepCopyInto(&out.Transforms)
+	if in.AuthenticationPolicy != nil {
+		in, out := &in.AuthenticationPolicy, &out.AuthenticationPolicy
+		*out = new(FederationDomainAuthenticationPolicy)
+		(*in).DeepCopyInto(*out)
+	}
 	return
 }
 
@@ -112,6 +185,73 @@ func (in *FederationDomainSecrets) DeepCopy() *FederationDomainSecrets {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FederationDomainSessionPolicy) DeepCopyInto(out *FederationDomainSessionPolicy) {
+	*out = *in
+	if in.MaxSessionAgeSeconds != nil {
+		in, out := &in.MaxSessionAgeSeconds, &out.MaxSessionAgeSeconds
+		*out = new(int32)
+		**out = **in
+	}
+	if in.RefreshTokenSeconds != nil {
+		in, out := &in.RefreshTokenSeconds, &out.RefreshTokenSeconds
+		*out = new(int32)
+		**out = **in
+	}
+	if in.IdleTimeoutSeconds != nil {
+		in, out := &in.IdleTimeoutSeconds, &out.IdleTimeoutSeconds
+		*out = new(int32)
+		**out = **in
+	}
+	if in.AccessTokenSeconds != nil {
+		in, out := &in.AccessTokenSeconds, &out.AccessTokenSeconds
+		*out = new(int32)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FederationDomainSessionPolicy.
+func (in *FederationDomainSessionPolicy) DeepCopy() *FederationDomainSessionPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(FederationDomainSessionPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FederationDomainSigning) DeepCopyInto(out *FederationDomainSigning) {
+	*out = *in
+	if in.RotationPeriodSeconds != nil {
+		in, out := &in.RotationPeriodSeconds, &out.RotationPeriodSeconds
+		*out = new(int32)
+		**out = **in
+	}
+	if in.RetiredKeyRetentionSeconds != nil {
+		in, out := &in.RetiredKeyRetentionSeconds, &out.RetiredKeyRetentionSeconds
+		*out = new(int32)
+		**out = **in
+	}
+	if in.NextKeyPublicationSeconds != nil {
+		in, out := &in.NextKeyPublicationSeconds, &out.NextKeyPublicationSeconds
+		*out = new(int32)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FederationDomainSigning.
+func (in *FederationDomainSigning) DeepCopy() *FederationDomainSigning {
+	if in == nil {
+		return nil
+	}
+	out := new(FederationDomainSigning)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *FederationDomainSpec) DeepCopyInto(out *FederationDomainSpec) {
 	*out = *in
@@ -127,6 +267,26 @@ func (in *FederationDomainSpec) DeepCopyInto(out *FederationDomainSpec) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.SessionPolicy != nil {
+		in, out := &in.SessionPolicy, &out.SessionPolicy
+		*out = new(FederationDomainSessionPolicy)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Signing != nil {
+		in, out := &in.Signing, &out.Signing
+		*out = new(FederationDomainSigning)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ClientRegistration != nil {
+		in, out := &in.ClientRegistration, &out.ClientRegistration
+		*out = new(FederationDomainClientRegistration)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Branding != nil {
+		in, out := &in.Branding, &out.Branding
+		*out = new(FederationDomainBranding)
+		**out = **in
+	}
 	return
 }
 
@@ -323,6 +483,27 @@ func (in *OIDCClient) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OIDCClientClientCredentials) DeepCopyInto(out *OIDCClientClientCredentials) {
+	*out = *in
+	if in.Groups != nil {
+		in, out := &in.Groups, &out.Groups
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OIDCClientClientCredentials.
+func (in *OIDCClientClientCredentials) DeepCopy() *OIDCClientClientCredentials {
+	if in == nil {
+		return nil
+	}
+	out := new(OIDCClientClientCredentials)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *OIDCClientList) DeepCopyInto(out *OIDCClientList) {
 	*out = *in
@@ -356,6 +537,58 @@ func (in *OIDCClientList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OIDCClientPrivateKeyJWT) DeepCopyInto(out *OIDCClientPrivateKeyJWT) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OIDCClientPrivateKeyJWT.
+func (in *OIDCClientPrivateKeyJWT) DeepCopy() *OIDCClientPrivateKeyJWT {
+	if in == nil {
+		return nil
+	}
+	out := new(OIDCClientPrivateKeyJWT)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OIDCClientSessionPolicy) DeepCopyInto(out *OIDCClientSessionPolicy) {
+	*out = *in
+	if in.MaxSessionAgeSeconds != nil {
+		in, out := &in.MaxSessionAgeSeconds, &out.MaxSessionAgeSeconds
+		*out = new(int32)
+		**out = **in
+	}
+	if in.RefreshTokenSeconds != nil {
+		in, out := &in.RefreshTokenSeconds, &out.RefreshTokenSeconds
+		*out = new(int32)
+		**out = **in
+	}
+	if in.IdleTimeoutSeconds != nil {
+		in, out := &in.IdleTimeoutSeconds, &out.IdleTimeoutSeconds
+		*out = new(int32)
+		**out = **in
+	}
+	if in.AccessTokenSeconds != nil {
+		in, out := &in.AccessTokenSeconds, &out.AccessTokenSeconds
+		*out = new(int32)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OIDCClientSessionPolicy.
+func (in *OIDCClientSessionPolicy) DeepCopy() *OIDCClientSessionPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(OIDCClientSessionPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *OIDCClientSpec) DeepCopyInto(out *OIDCClientSpec) {
 	*out = *in
@@ -375,6 +608,26 @@ func (in *OIDCClientSpec) DeepCopyInto(out *OIDCClientSpec) {
 		copy(*out, *in)
 	}
 	in.TokenLifetimes.DeepCopyInto(&out.TokenLifetimes)
+	if in.SessionPolicy != nil {
+		in, out := &in.SessionPolicy, &out.SessionPolicy
+		*out = new(OIDCClientSessionPolicy)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.PrivateKeyJWT != nil {
+		in, out := &in.PrivateKeyJWT, &out.PrivateKeyJWT
+		*out = new(OIDCClientPrivateKeyJWT)
+		**out = **in
+	}
+	if in.TLSClientAuth != nil {
+		in, out := &in.TLSClientAuth, &out.TLSClientAuth
+		*out = new(OIDCClientTLSClientAuth)
+		**out = **in
+	}
+	if in.ClientCredentials != nil {
+		in, out := &in.ClientCredentials, &out.ClientCredentials
+		*out = new(OIDCClientClientCredentials)
+		(*in).DeepCopyInto(*out)
+	}
 	return
 }
 
@@ -411,6 +664,22 @@ func (in *OIDCClientStatus) DeepCopy() *OIDCClientStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OIDCClientTLSClientAuth) DeepCopyInto(out *OIDCClientTLSClientAuth) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OIDCClientTLSClientAuth.
+func (in *OIDCClientTLSClientAuth) DeepCopy() *OIDCClientTLSClientAuth {
+	if in == nil {
+		return nil
+	}
+	out := new(OIDCClientTLSClientAuth)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *OIDCClientTokenLifetimes) DeepCopyInto(out *OIDCClientTokenLifetimes) {
 	*out = *in
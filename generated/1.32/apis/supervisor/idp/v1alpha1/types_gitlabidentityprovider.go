@@ -0,0 +1,261 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+type GitLabIdentityProviderPhase string
+
+const (
+	// GitLabPhasePending is the default phase for newly-created GitLabIdentityProvider resources.
+	GitLabPhasePending GitLabIdentityProviderPhase = "Pending"
+
+	// GitLabPhaseReady is the phase for an GitLabIdentityProvider resource in a healthy state.
+	GitLabPhaseReady GitLabIdentityProviderPhase = "Ready"
+
+	// GitLabPhaseError is the phase for an GitLabIdentityProvider in an unhealthy state.
+	GitLabPhaseError GitLabIdentityProviderPhase = "Error"
+)
+
+type GitLabAllowedAuthGroupsPolicy string
+
+const (
+	// GitLabAllowedAuthGroupsPolicyAllGitLabUsers means any GitLab user is allowed to log in using this identity
+	// provider, regardless of their group membership or lack thereof.
+	GitLabAllowedAuthGroupsPolicyAllGitLabUsers GitLabAllowedAuthGroupsPolicy = "AllGitLabUsers"
+
+	// GitLabAllowedAuthGroupsPolicyOnlyUsersFromAllowedGroups means only those users with membership in
+	// the listed top-level GitLab groups (or any of their subgroups) are allowed to log in.
+	GitLabAllowedAuthGroupsPolicyOnlyUsersFromAllowedGroups GitLabAllowedAuthGroupsPolicy = "OnlyUsersFromAllowedGroups"
+)
+
+// GitLabIdentityProviderStatus is the status of an GitLab identity provider.
+type GitLabIdentityProviderStatus struct {
+	// Phase summarizes the overall status of the GitLabIdentityProvider.
+	//
+	// +kubebuilder:default=Pending
+	// +kubebuilder:validation:Enum=Pending;Ready;Error
+	Phase GitLabIdentityProviderPhase `json:"phase,omitempty"`
+
+	// Conditions represents the observations of an identity provider's current state.
+	//
+	// +patchMergeKey=type
+	// +patchStrategy=merge
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty" patchStrategy:"merge" patchMergeKey:"type"`
+}
+
+// GitLabAPIConfig allows configuration for self-managed GitLab instances.
+type GitLabAPIConfig struct {
+	// Host is required only for self-managed GitLab instances.
+	// Defaults to using GitLab.com ("gitlab.com").
+	// Do not specify a protocol or scheme since "https://" will always be used.
+	// Port is optional. Do not specify a path, query, fragment, or userinfo.
+	// Only specify domain name or IP address, subdomains (optional), and port (optional).
+	// IPv4 and IPv6 are supported. If using an IPv6 address with a port, you must enclose the IPv6 address
+	// in square brackets. Example: "[::1]:443".
+	//
+	// +kubebuilder:default="gitlab.com"
+	// +kubebuilder:validation:MinLength=1
+	// +optional
+	Host *string `json:"host"`
+
+	// TLS configuration for self-managed GitLab instances.
+	// Note that this field should not be needed when using GitLab.com.
+	// However, if you choose to specify this field when using GitLab.com, you must
+	// specify a CA bundle that will verify connections to "gitlab.com".
+	//
+	// +optional
+	TLS *TLSSpec `json:"tls,omitempty"`
+}
+
+// GitLabUsernameAttribute allows the user to specify which attribute(s) from GitLab to use for the username to present
+// to Kubernetes. See the response schema for
+// [Get the current user](https://docs.gitlab.com/ee/api/users.html#for-non-administrator-users).
+type GitLabUsernameAttribute string
+
+const (
+	// GitLabUsernameID specifies using the `id` attribute from the GitLab user for the username to present to Kubernetes.
+	GitLabUsernameID GitLabUsernameAttribute = "id"
+
+	// GitLabUsernameUsername specifies using the `username` attribute from the GitLab user as the username to present to Kubernetes.
+	GitLabUsernameUsername GitLabUsernameAttribute = "username"
+
+	// GitLabUsernameUsernameAndID specifies combining the `username` and `id` attributes from the GitLab user as the
+	// username to present to Kubernetes, separated by a colon. Example: "my-username:1234"
+	GitLabUsernameUsernameAndID GitLabUsernameAttribute = "username:id"
+)
+
+// GitLabGroupNameAttribute allows the user to specify which attribute from GitLab to use for the group
+// names to present to Kubernetes. See the response schema for
+// [List groups](https://docs.gitlab.com/ee/api/groups.html#list-groups).
+type GitLabGroupNameAttribute string
+
+const (
+	// GitLabUseGroupPathForGroupName specifies using the GitLab group's `path` attribute as the group name to present to Kubernetes.
+	GitLabUseGroupPathForGroupName GitLabGroupNameAttribute = "path"
+
+	// GitLabUseGroupFullPathForGroupName specifies using the GitLab group's `full_path` attribute as the group name to present to Kubernetes.
+	GitLabUseGroupFullPathForGroupName GitLabGroupNameAttribute = "full_path"
+
+	// GitLabUseGroupIDForGroupName specifies using the GitLab group's `id` attribute as the group name to present to Kubernetes.
+	GitLabUseGroupIDForGroupName GitLabGroupNameAttribute = "id"
+)
+
+// GitLabClaims allows customization of the username and groups claims.
+type GitLabClaims struct {
+	// Username configures which property of the GitLab user record shall determine the username in Kubernetes.
+	//
+	// Can be either "id", "username", or "username:id". Defaults to "username:id".
+	//
+	// GitLab users are allowed to change their username. If a GitLab user changed their username from "foo" to "bar",
+	// then a second user might change their username from "baz" to "foo" in order to take the old
+	// username of the first user. For this reason, it is not as safe to make authorization decisions
+	// based only on the user's username attribute.
+	//
+	// If desired, an admin could configure identity transformation expressions on the Pinniped Supervisor's
+	// FederationDomain to further customize how these usernames are presented to Kubernetes.
+	//
+	// Defaults to "username:id", which is the user's username attribute, followed by a colon, followed by the unique and
+	// unchanging integer ID number attribute. This blends human-readable usernames with the unchanging ID value
+	// from GitLab. Colons are not allowed in GitLab usernames or ID numbers, so this is a reasonable
+	// choice to concatenate the two values.
+	//
+	// See the response schema for
+	// [Get the current user](https://docs.gitlab.com/ee/api/users.html#for-non-administrator-users).
+	//
+	// +kubebuilder:default="username:id"
+	// +kubebuilder:validation:Enum={"id","username","username:id"}
+	// +optional
+	Username *GitLabUsernameAttribute `json:"username"`
+
+	// Groups configures which property of the GitLab group record shall determine the group names in Kubernetes.
+	//
+	// Can be either "path", "full_path", or "id". Defaults to "full_path".
+	//
+	// GitLab group paths are the URL-friendly name of the group itself (e.g. "kube-admins"), which is only unique
+	// among the other subgroups of the same parent group.
+	//
+	// GitLab group full paths include the paths of all the group's parent groups, separated by forward
+	// slashes (e.g. "my-top-level-group/my-subgroup/kube-admins"). Full paths are unique within the GitLab instance.
+	//
+	// GitLab group IDs are the unique and unchanging integer ID number of the group.
+	//
+	// If desired, an admin could configure identity transformation expressions on the Pinniped Supervisor's
+	// FederationDomain to further customize how these group names are presented to Kubernetes.
+	//
+	// See the response schema for
+	// [List groups](https://docs.gitlab.com/ee/api/groups.html#list-groups).
+	//
+	// +kubebuilder:default=full_path
+	// +kubebuilder:validation:Enum=path;full_path;id
+	// +optional
+	Groups *GitLabGroupNameAttribute `json:"groups"`
+}
+
+// GitLabClientSpec contains information about the GitLab client that this identity provider will use
+// for web-based login flows.
+type GitLabClientSpec struct {
+	// SecretName contains the name of a namespace-local Secret object that provides the clientID and
+	// clientSecret for a GitLab OAuth2 application.
+	//
+	// This secret must be of type "secrets.pinniped.dev/gitlab-client" with keys "clientID" and "clientSecret".
+	//
+	// +kubebuilder:validation:MinLength=1
+	SecretName string `json:"secretName"`
+}
+
+type GitLabGroupsSpec struct {
+	// Allowed values are "OnlyUsersFromAllowedGroups" or "AllGitLabUsers".
+	// Defaults to "OnlyUsersFromAllowedGroups".
+	//
+	// Must be set to "AllGitLabUsers" if the allowed field is empty.
+	//
+	// This field only exists to ensure that Pinniped administrators are aware that an empty list of
+	// allowed groups means all GitLab users are allowed to log in.
+	//
+	// +kubebuilder:default=OnlyUsersFromAllowedGroups
+	// +kubebuilder:validation:Enum=OnlyUsersFromAllowedGroups;AllGitLabUsers
+	// +optional
+	Policy *GitLabAllowedAuthGroupsPolicy `json:"policy"`
+
+	// Allowed, when specified, indicates that only users with membership in at least one of the listed
+	// top-level GitLab groups (or in any of their subgroups) may log in. In addition, the group membership
+	// presented to Kubernetes will only include the listed top-level groups and their subgroups.
+	// Additional login rules or group filtering can optionally be provided as policy expression on any
+	// Pinniped Supervisor FederationDomain that includes this IDP.
+	//
+	// Each entry must be the path of a top-level group (e.g. "my-top-level-group"), not the full path of a subgroup.
+	//
+	// If no groups are listed, you must set policy: AllGitLabUsers.
+	//
+	// +kubebuilder:validation:MaxItems=64
+	// +listType=set
+	// +optional
+	Allowed []string `json:"allowed,omitempty"`
+}
+
+// GitLabAllowAuthenticationSpec allows customization of who can authenticate using this IDP and how.
+type GitLabAllowAuthenticationSpec struct {
+	// Groups allows customization of which top-level groups can authenticate using this IDP.
+	// +kubebuilder:validation:XValidation:message="spec.allowAuthentication.groups.policy must be 'OnlyUsersFromAllowedGroups' when spec.allowAuthentication.groups.allowed has groups listed",rule="!(has(self.allowed) && size(self.allowed) > 0 && self.policy == 'AllGitLabUsers')"
+	// +kubebuilder:validation:XValidation:message="spec.allowAuthentication.groups.policy must be 'AllGitLabUsers' when spec.allowAuthentication.groups.allowed is empty",rule="!((!has(self.allowed) || size(self.allowed) == 0) && self.policy == 'OnlyUsersFromAllowedGroups')"
+	Groups GitLabGroupsSpec `json:"groups"`
+}
+
+// GitLabIdentityProviderSpec is the spec for configuring an GitLab identity provider.
+type GitLabIdentityProviderSpec struct {
+	// GitLabAPI allows configuration for self-managed GitLab instances.
+	//
+	// +kubebuilder:default={}
+	GitLabAPI GitLabAPIConfig `json:"gitlabAPI,omitempty"`
+
+	// Claims allows customization of the username and groups claims.
+	//
+	// +kubebuilder:default={}
+	Claims GitLabClaims `json:"claims,omitempty"`
+
+	// AllowAuthentication allows customization of who can authenticate using this IDP and how.
+	AllowAuthentication GitLabAllowAuthenticationSpec `json:"allowAuthentication"`
+
+	// Client identifies the secret with credentials for a GitLab OAuth2 application.
+	Client GitLabClientSpec `json:"client"`
+}
+
+// GitLabIdentityProvider describes the configuration of an upstream GitLab identity provider.
+// This upstream provider can be configured for either GitLab.com or a self-managed GitLab instance.
+//
+// Right now, only web-based logins are supported, for both the pinniped-cli client and clients configured
+// as OIDCClients.
+//
+// +genclient
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// +kubebuilder:resource:categories=pinniped;pinniped-idp;pinniped-idps
+// +kubebuilder:printcolumn:name="Host",type=string,JSONPath=`.spec.gitlabAPI.host`
+// +kubebuilder:printcolumn:name="Status",type=string,JSONPath=`.status.phase`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+// +kubebuilder:subresource:status
+type GitLabIdentityProvider struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	// Spec for configuring the identity provider.
+	Spec GitLabIdentityProviderSpec `json:"spec"`
+
+	// Status of the identity provider.
+	Status GitLabIdentityProviderStatus `json:"status,omitempty"`
+}
+
+// GitLabIdentityProviderList lists GitLabIdentityProvider objects.
+//
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+type GitLabIdentityProviderList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+
+	Items []GitLabIdentityProvider `json:"items"`
+}
@@ -0,0 +1,61 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package session
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// Session is a downstream session which was started by a user logging in to a FederationDomain.
+// Deleting a Session ends the session.
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+type Session struct {
+	metav1.TypeMeta
+	metav1.ObjectMeta // metadata.name is the session ID, which is also the "sid" claim of the session's ID tokens
+
+	// +optional
+	Status SessionStatus
+}
+
+// Status of the Session.
+type SessionStatus struct {
+	// The downstream username of the user.
+	Username string
+
+	// The downstream group memberships of the user.
+	// +optional
+	Groups []string
+
+	// The client ID of the OIDC client which started the session.
+	ClientID string
+
+	// The upstream identity provider which was used to log in.
+	IdentityProvider SessionIdentityProvider
+
+	// The time after which the session can no longer be used.
+	ExpiresAt metav1.Time
+}
+
+// SessionIdentityProvider identifies the upstream identity provider of a Session.
+type SessionIdentityProvider struct {
+	// The name of the identity provider resource.
+	Name string
+
+	// The type of the identity provider, e.g. "oidc", "ldap", "activedirectory", or "github".
+	Type string
+
+	// The UID of the identity provider resource.
+	UID types.UID
+}
+
+// SessionList is a list of Session objects.
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+type SessionList struct {
+	metav1.TypeMeta
+	metav1.ListMeta
+
+	// Items is a list of Session.
+	Items []Session
+}
@@ -1,4 +1,4 @@
-// Copyright 2020-2025 the Pinniped contributors. All Rights Reserved.
+// Copyright 2020-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package v1alpha1
@@ -8,7 +8,7 @@ import (
 )
 
 // StrategyType enumerates a type of "strategy" used to implement credential access on a cluster.
-// +kubebuilder:validation:Enum=KubeClusterSigningCertificate;ImpersonationProxy
+// +kubebuilder:validation:Enum=KubeClusterSigningCertificate;ImpersonationProxy;CertificateSigningRequest
 type StrategyType string
 
 // FrontendType enumerates a type of "frontend" used to provide access to users of a cluster.
@@ -20,12 +20,13 @@ type FrontendType string
 type StrategyStatus string
 
 // StrategyReason enumerates the detailed reason why a strategy is in a particular status.
-// +kubebuilder:validation:Enum=Listening;Pending;Disabled;ErrorDuringSetup;CouldNotFetchKey;CouldNotGetClusterInfo;FetchedKey
+// +kubebuilder:validation:Enum=Listening;Pending;Disabled;ErrorDuringSetup;CouldNotFetchKey;CouldNotGetClusterInfo;FetchedKey;SigningRequestsAllowed;SigningRequestsForbidden
 type StrategyReason string
 
 const (
 	KubeClusterSigningCertificateStrategyType = StrategyType("KubeClusterSigningCertificate")
 	ImpersonationProxyStrategyType            = StrategyType("ImpersonationProxy")
+	CertificateSigningRequestStrategyType     = StrategyType("CertificateSigningRequest")
 
 	TokenCredentialRequestAPIFrontendType = FrontendType("TokenCredentialRequestAPI")
 	ImpersonationProxyFrontendType        = FrontendType("ImpersonationProxy")
@@ -33,13 +34,15 @@ const (
 	SuccessStrategyStatus = StrategyStatus("Success")
 	ErrorStrategyStatus   = StrategyStatus("Error")
 
-	ListeningStrategyReason              = StrategyReason("Listening")
-	PendingStrategyReason                = StrategyReason("Pending")
-	DisabledStrategyReason               = StrategyReason("Disabled")
-	ErrorDuringSetupStrategyReason       = StrategyReason("ErrorDuringSetup")
-	CouldNotFetchKeyStrategyReason       = StrategyReason("CouldNotFetchKey")
-	CouldNotGetClusterInfoStrategyReason = StrategyReason("CouldNotGetClusterInfo")
-	FetchedKeyStrategyReason             = StrategyReason("FetchedKey")
+	ListeningStrategyReason                = StrategyReason("Listening")
+	PendingStrategyReason                  = StrategyReason("Pending")
+	DisabledStrategyReason                 = StrategyReason("Disabled")
+	ErrorDuringSetupStrategyReason         = StrategyReason("ErrorDuringSetup")
+	CouldNotFetchKeyStrategyReason         = StrategyReason("CouldNotFetchKey")
+	CouldNotGetClusterInfoStrategyReason   = StrategyReason("CouldNotGetClusterInfo")
+	FetchedKeyStrategyReason               = StrategyReason("FetchedKey")
+	SigningRequestsAllowedStrategyReason   = StrategyReason("SigningRequestsAllowed")
+	SigningRequestsForbiddenStrategyReason = StrategyReason("SigningRequestsForbidden")
 )
 
 // CredentialIssuerSpec describes the intended configuration of the Concierge.
@@ -131,6 +134,54 @@ type ImpersonationProxySpec struct {
 	//
 	// +optional
 	TLS *ImpersonationProxyTLSSpec `json:"tls,omitempty"`
+
+	// RateLimits configures limits on the requests which the impersonation proxy will serve for each user and
+	// for each group. Requests which exceed a limit are rejected with a 429 (Too Many Requests) response.
+	// When this field is not set, requests are not limited by the impersonation proxy.
+	//
+	// +optional
+	RateLimits *ImpersonationProxyRateLimitsSpec `json:"rateLimits,omitempty"`
+}
+
+// ImpersonationProxyRateLimitsSpec describes the limits which the impersonation proxy applies to the requests
+// of each user and of each group.
+type ImpersonationProxyRateLimitsSpec struct {
+	// PerUser limits the requests of each user. Every user is limited independently of all other users.
+	// Anonymous requests are not limited per user, since all anonymous clients would share the same limits.
+	//
+	// +optional
+	PerUser *ImpersonationProxyRateLimit `json:"perUser,omitempty"`
+
+	// PerGroup limits the requests of each group. The limits of a group are shared by all of its members,
+	// and a request counts against the limits of every group of the user who made it.
+	// The system:authenticated and system:unauthenticated groups are never limited, because every user
+	// is a member of one of them.
+	//
+	// +optional
+	PerGroup *ImpersonationProxyRateLimit `json:"perGroup,omitempty"`
+}
+
+// ImpersonationProxyRateLimit describes the limits of a single user or group. A value of zero means unlimited.
+type ImpersonationProxyRateLimit struct {
+	// QPS is the average number of requests per second which are allowed.
+	//
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	QPS int32 `json:"qps,omitempty"`
+
+	// Burst is the maximum number of requests which are allowed at once, above the average allowed by QPS.
+	// When not set, it defaults to the value of QPS. It is ignored when QPS is not set.
+	//
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	Burst int32 `json:"burst,omitempty"`
+
+	// MaxInFlightLongRunningRequests is the maximum number of long-running requests (e.g. watch, exec, attach,
+	// port-forward, and logs) which may be in progress at the same time.
+	//
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	MaxInFlightLongRunningRequests int32 `json:"maxInFlightLongRunningRequests,omitempty"`
 }
 
 // ImpersonationProxyServiceSpec describes how the Concierge should provision a Service to expose the impersonation proxy.
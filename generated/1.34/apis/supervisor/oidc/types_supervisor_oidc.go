@@ -1,4 +1,4 @@
-// Copyright 2021-2025 the Pinniped contributors. All Rights Reserved.
+// Copyright 2021-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package oidc
@@ -36,6 +36,10 @@ const (
 	// IDTokenClaimAuthorizedParty is name of the authorized party claim defined by the OIDC spec.
 	IDTokenClaimAuthorizedParty = "azp"
 
+	// IDTokenClaimSessionID is the name of the session ID claim defined by the OIDC Front-Channel Logout spec.
+	// Its value identifies the downstream session, and may be used at the end_session endpoint to end the session.
+	IDTokenClaimSessionID = "sid"
+
 	// IDTokenClaimUsername is the name of a custom claim in the downstream ID token whose value will contain the user's
 	// username which was mapped from the upstream identity provider.
 	IDTokenClaimUsername = "username"
@@ -57,6 +61,12 @@ const (
 	// GrantTypeTokenExchange is the name of a custom grant type for RFC8693 token exchanges.
 	GrantTypeTokenExchange = "urn:ietf:params:oauth:grant-type:token-exchange" //nolint:gosec // this is not a credential
 
+	// GrantTypeDeviceCode is the name of the grant type for RFC8628 device authorization grants.
+	GrantTypeDeviceCode = "urn:ietf:params:oauth:grant-type:device_code" //nolint:gosec // this is not a credential
+
+	// GrantTypeClientCredentials is the name of the grant type for client credentials grants defined by RFC6749.
+	GrantTypeClientCredentials = "client_credentials"
+
 	// ScopeOpenID is name of the openid scope defined by the OIDC spec.
 	ScopeOpenID = "openid"
 
@@ -0,0 +1,206 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+type OAuth2IdentityProviderPhase string
+
+const (
+	// OAuth2PhasePending is the default phase for newly-created OAuth2IdentityProvider resources.
+	OAuth2PhasePending OAuth2IdentityProviderPhase = "Pending"
+
+	// OAuth2PhaseReady is the phase for an OAuth2IdentityProvider resource in a healthy state.
+	OAuth2PhaseReady OAuth2IdentityProviderPhase = "Ready"
+
+	// OAuth2PhaseError is the phase for an OAuth2IdentityProvider in an unhealthy state.
+	OAuth2PhaseError OAuth2IdentityProviderPhase = "Error"
+)
+
+// OAuth2IdentityProviderStatus is the status of an OAuth2 identity provider.
+type OAuth2IdentityProviderStatus struct {
+	// Phase summarizes the overall status of the OAuth2IdentityProvider.
+	//
+	// +kubebuilder:default=Pending
+	// +kubebuilder:validation:Enum=Pending;Ready;Error
+	Phase OAuth2IdentityProviderPhase `json:"phase,omitempty"`
+
+	// Conditions represents the observations of an identity provider's current state.
+	//
+	// +patchMergeKey=type
+	// +patchStrategy=merge
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty" patchStrategy:"merge" patchMergeKey:"type"`
+}
+
+// OAuth2Endpoints holds the URLs of the OAuth2 and user info endpoints of the upstream provider.
+type OAuth2Endpoints struct {
+	// AuthorizationURL is the URL of the OAuth2 authorization endpoint of the upstream provider
+	// (see https://datatracker.ietf.org/doc/html/rfc6749#section-3.1). End users' browsers will be
+	// redirected to this URL to log in.
+	//
+	// +kubebuilder:validation:MinLength=1
+	// +kubebuilder:validation:Pattern=`^https://`
+	AuthorizationURL string `json:"authorizationURL"`
+
+	// TokenURL is the URL of the OAuth2 token endpoint of the upstream provider
+	// (see https://datatracker.ietf.org/doc/html/rfc6749#section-3.2). The Supervisor will call this
+	// endpoint to exchange authorization codes and to perform refreshes.
+	//
+	// +kubebuilder:validation:MinLength=1
+	// +kubebuilder:validation:Pattern=`^https://`
+	TokenURL string `json:"tokenURL"`
+
+	// UserInfoURL is the URL of an endpoint of the upstream provider which returns a JSON object describing
+	// the authenticated user. The Supervisor will call this endpoint using an HTTP GET request with the
+	// user's access token as a bearer token. The JSON object in the response will be made available to the
+	// CEL expressions configured in the claims setting as the variable "userinfo".
+	//
+	// +kubebuilder:validation:MinLength=1
+	// +kubebuilder:validation:Pattern=`^https://`
+	UserInfoURL string `json:"userInfoURL"`
+
+	// GroupsURL is the URL of an optional endpoint of the upstream provider which returns JSON describing
+	// the group memberships of the authenticated user. The Supervisor will call this endpoint using an HTTP
+	// GET request with the user's access token as a bearer token. The JSON value in the response (which may
+	// be an object or an array) will be made available to the CEL expression configured in the claims.groups
+	// setting as the variable "groupsResponse". When this setting is not configured, the groups endpoint will
+	// not be called and the "groupsResponse" variable will be null.
+	//
+	// +kubebuilder:validation:Pattern=`^https://`
+	// +optional
+	GroupsURL string `json:"groupsURL,omitempty"`
+}
+
+// OAuth2AuthorizationConfig provides information about how to form the OAuth2 authorization request parameters.
+type OAuth2AuthorizationConfig struct {
+	// Scopes are the scopes that will be requested from your OAuth2 provider in the authorization request.
+	// By default, no scopes are requested. Include any scopes required to allow the user's access token to
+	// call the configured user info and groups endpoints, and any scopes required to receive refresh tokens.
+	//
+	// +listType=atomic
+	// +optional
+	Scopes []string `json:"scopes,omitempty"`
+
+	// AdditionalAuthorizeParameters are extra query parameters that should be included in the authorize request
+	// to your OAuth2 provider. By default, no extra parameters are sent. The standard parameters that will be
+	// sent are "response_type", "scope", "client_id", "state", "code_challenge", "code_challenge_method", and
+	// "redirect_uri". These parameters cannot be included in this setting.
+	//
+	// +patchMergeKey=name
+	// +patchStrategy=merge
+	// +listType=map
+	// +listMapKey=name
+	// +optional
+	AdditionalAuthorizeParameters []Parameter `json:"additionalAuthorizeParameters,omitempty"`
+}
+
+// OAuth2Claims provides CEL expressions which determine the identity of a user from the responses
+// of the upstream provider's user info and groups endpoints.
+//
+// Each expression may use the variable "userinfo", which is the JSON object returned by the user info endpoint,
+// and the variable "groupsResponse", which is the JSON value returned by the groups endpoint (or null when
+// no groups endpoint is configured). JSON numbers are represented as doubles, so use an expression such as
+// `string(int(userinfo.id))` to convert a numeric ID into a string.
+//
+// The CEL language is documented in https://github.com/google/cel-spec/blob/master/doc/langdef.md
+// with the strings extensions documented in https://github.com/google/cel-go/tree/master/ext#strings.
+type OAuth2Claims struct {
+	// Username is a CEL expression which must evaluate to a non-empty string. The result will be used as the user's
+	// username in Kubernetes. For example, `userinfo.login`.
+	//
+	// If desired, an admin could configure identity transformation expressions on the Pinniped Supervisor's
+	// FederationDomain to further customize how these usernames are presented to Kubernetes.
+	//
+	// +kubebuilder:validation:MinLength=1
+	Username string `json:"username"`
+
+	// UID is a CEL expression which must evaluate to a non-empty string. The result must uniquely and permanently
+	// identify the user in the upstream provider, and will be used to compute the subject of the user's
+	// downstream ID tokens. For example, `string(int(userinfo.id))`.
+	//
+	// +kubebuilder:validation:MinLength=1
+	UID string `json:"uid"`
+
+	// Groups is an optional CEL expression which must evaluate to a list of strings. The result will be used as
+	// the user's group names in Kubernetes. For example, `groupsResponse.map(g, g.name)`. When not configured,
+	// the identities will not include any group memberships.
+	//
+	// +optional
+	Groups string `json:"groups,omitempty"`
+}
+
+// OAuth2ClientSpec contains information about the OAuth2 client that this identity provider will use
+// for web-based login flows.
+type OAuth2ClientSpec struct {
+	// SecretName contains the name of a namespace-local Secret object that provides the clientID and
+	// clientSecret for an OAuth2 client.
+	//
+	// This secret must be of type "secrets.pinniped.dev/oauth2-client" with keys "clientID" and "clientSecret".
+	//
+	// +kubebuilder:validation:MinLength=1
+	SecretName string `json:"secretName"`
+}
+
+// OAuth2IdentityProviderSpec is the spec for configuring an OAuth2 identity provider.
+type OAuth2IdentityProviderSpec struct {
+	// Endpoints holds the URLs of the OAuth2 and user info endpoints of the upstream provider.
+	Endpoints OAuth2Endpoints `json:"endpoints"`
+
+	// TLS configuration for requests to the token, user info, and groups endpoints.
+	//
+	// +optional
+	TLS *TLSSpec `json:"tls,omitempty"`
+
+	// AuthorizationConfig holds information about how to form the OAuth2 authorization request
+	// parameters to be used with this OAuth2 identity provider.
+	//
+	// +optional
+	AuthorizationConfig OAuth2AuthorizationConfig `json:"authorizationConfig,omitempty"`
+
+	// Claims provides CEL expressions which determine the username, UID, and groups of an identity from
+	// this OAuth2 identity provider.
+	Claims OAuth2Claims `json:"claims"`
+
+	// Client identifies the secret with credentials for an OAuth2 client.
+	Client OAuth2ClientSpec `json:"client"`
+}
+
+// OAuth2IdentityProvider describes the configuration of an upstream identity provider which implements
+// OAuth 2.0 (but not OpenID Connect) and provides a JSON endpoint describing the authenticated user.
+// Use an OIDCIdentityProvider instead for providers which support OpenID Connect.
+//
+// Right now, only web-based logins are supported, for both the pinniped-cli client and clients configured
+// as OIDCClients.
+//
+// +genclient
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// +kubebuilder:resource:categories=pinniped;pinniped-idp;pinniped-idps
+// +kubebuilder:printcolumn:name="Authorization URL",type=string,JSONPath=`.spec.endpoints.authorizationURL`
+// +kubebuilder:printcolumn:name="Status",type=string,JSONPath=`.status.phase`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+// +kubebuilder:subresource:status
+type OAuth2IdentityProvider struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	// Spec for configuring the identity provider.
+	Spec OAuth2IdentityProviderSpec `json:"spec"`
+
+	// Status of the identity provider.
+	Status OAuth2IdentityProviderStatus `json:"status,omitempty"`
+}
+
+// OAuth2IdentityProviderList lists OAuth2IdentityProvider objects.
+//
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+type OAuth2IdentityProviderList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+
+	Items []OAuth2IdentityProvider `json:"items"`
+}
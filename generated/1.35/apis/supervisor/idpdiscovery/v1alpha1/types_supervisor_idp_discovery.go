@@ -1,4 +1,4 @@
-// Copyright 2021-2025 the Pinniped contributors. All Rights Reserved.
+// Copyright 2021-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package v1alpha1
@@ -16,9 +16,13 @@ const (
 	IDPTypeLDAP            IDPType = "ldap"
 	IDPTypeActiveDirectory IDPType = "activedirectory"
 	IDPTypeGitHub          IDPType = "github"
+	IDPTypeSAML            IDPType = "saml"
+	IDPTypeGitLab          IDPType = "gitlab"
+	IDPTypeOAuth2          IDPType = "oauth2"
 
 	IDPFlowCLIPassword     IDPFlow = "cli_password"
 	IDPFlowBrowserAuthcode IDPFlow = "browser_authcode"
+	IDPFlowDeviceCode      IDPFlow = "device_code"
 )
 
 // Equals is a convenience function for comparing an IDPType to a string.
@@ -0,0 +1,64 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// Session is a downstream session which was started by a user logging in to a FederationDomain.
+// The metadata.creationTimestamp of a Session is the time when the user logged in.
+// Sessions cannot be created or updated, but deleting a Session ends the session, which revokes all of its tokens.
+// +genclient
+// +genclient:onlyVerbs=get,list,delete
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+type Session struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"` // metadata.name is the session ID, which is also the "sid" claim of the session's ID tokens
+
+	// +optional
+	Status SessionStatus `json:"status"`
+}
+
+// Status of the Session.
+type SessionStatus struct {
+	// The downstream username of the user.
+	Username string `json:"username"`
+
+	// The downstream group memberships of the user. Only included when the session was granted the "groups" scope.
+	// +optional
+	Groups []string `json:"groups,omitempty"`
+
+	// The client ID of the OIDC client which started the session.
+	ClientID string `json:"clientID"`
+
+	// The upstream identity provider which was used to log in.
+	IdentityProvider SessionIdentityProvider `json:"identityProvider"`
+
+	// The time after which the session can no longer be used.
+	ExpiresAt metav1.Time `json:"expiresAt"`
+}
+
+// SessionIdentityProvider identifies the upstream identity provider of a Session.
+type SessionIdentityProvider struct {
+	// The name of the identity provider resource.
+	Name string `json:"name"`
+
+	// The type of the identity provider, e.g. "oidc", "ldap", "activedirectory", or "github".
+	Type string `json:"type"`
+
+	// The UID of the identity provider resource.
+	UID types.UID `json:"uid"`
+}
+
+// SessionList is a list of Session objects.
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+type SessionList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+
+	// Items is a list of Session.
+	Items []Session `json:"items"`
+}
@@ -178,6 +178,39 @@ type FederationDomainTransforms struct {
 	Examples []FederationDomainTransformsExample `json:"examples,omitempty"`
 }
 
+// FederationDomainAuthenticationPolicy describes requirements on how an upstream OIDC identity provider must have
+// authenticated a user, e.g. using multi-factor authentication. The requirements are checked against the claims of
+// the ID token returned by the upstream OIDC identity provider.
+type FederationDomainAuthenticationPolicy struct {
+	// ACRValues is an optional list of Authentication Context Class Reference values, in order of preference.
+	// When set, these values will be sent to the OIDC identity provider as the "acr_values" parameter of the
+	// authorization request (see https://openid.net/specs/openid-connect-core-1_0.html#AuthRequest), replacing any
+	// "acr_values" parameter configured in the additionalAuthorizeParameters of the OIDCIdentityProvider. The "acr"
+	// claim of the ID token returned by the OIDC identity provider must be one of these values, or else the
+	// authentication attempt will be rejected. The meaning of each value is defined by your OIDC identity provider.
+	// +listType=set
+	// +optional
+	ACRValues []string `json:"acrValues,omitempty"`
+
+	// Expression is an optional CEL expression which must return a boolean. When it returns false, the
+	// authentication attempt will be rejected. It may use the basic CEL language as defined in
+	// https://github.com/google/cel-spec/blob/master/doc/langdef.md plus the CEL string extensions defined in
+	// https://github.com/google/cel-go/tree/master/ext#strings.
+	//
+	// The claims of the validated ID token returned by the OIDC identity provider (merged with the claims returned
+	// by its userinfo endpoint, if any) are available as a map in a variable called `claims`. For example,
+	// `has(claims.amr) && "mfa" in claims.amr` requires that the "amr" (Authentication Methods References) claim
+	// includes "mfa". Any unexpected runtime evaluation error, such as referencing a claim which does not exist,
+	// will cause the authentication attempt to be rejected.
+	// +optional
+	Expression string `json:"expression,omitempty"`
+
+	// Message is an optional error message which will be shown to the user when this policy rejects their
+	// authentication attempt. When empty, a default message will be used.
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
 // FederationDomainIdentityProvider describes how an identity provider is made available in this FederationDomain.
 type FederationDomainIdentityProvider struct {
 	// DisplayName is the name of this identity provider as it will appear to clients. This name ends up in the
@@ -196,6 +229,15 @@ type FederationDomainIdentityProvider struct {
 	// session refresh.
 	// +optional
 	Transforms FederationDomainTransforms `json:"transforms,omitempty"`
+
+	// AuthenticationPolicy is an optional way to require that the identity provider authenticated the user in a
+	// certain way, e.g. using multi-factor authentication, before they may log in using this FederationDomain.
+	// The policy is enforced during every user authentication. It is enforced again during every session refresh,
+	// using the claims of the new ID token when the identity provider returns one, or otherwise using the acr
+	// and amr claims of the most recent ID token. It may only be used when the objectRef refers to an
+	// OIDCIdentityProvider.
+	// +optional
+	AuthenticationPolicy *FederationDomainAuthenticationPolicy `json:"authenticationPolicy,omitempty"`
 }
 
 // FederationDomainSpec is a struct that describes an OIDC Provider.
@@ -241,6 +283,194 @@ type FederationDomainSpec struct {
 	//
 	// +optional
 	IdentityProviders []FederationDomainIdentityProvider `json:"identityProviders,omitempty"`
+
+	// SessionPolicy optionally configures the lifetimes of the end user sessions and tokens of this FederationDomain.
+	// Each setting may be overridden for a specific client by the same setting in the sessionPolicy of an OIDCClient.
+	// When a setting is not specified, a default value will be used.
+	// +optional
+	SessionPolicy *FederationDomainSessionPolicy `json:"sessionPolicy,omitempty"`
+
+	// Signing optionally configures the keys which this FederationDomain uses to sign ID tokens.
+	// When not specified, ID tokens are signed using an ES256 key which is never rotated.
+	// +optional
+	Signing *FederationDomainSigning `json:"signing,omitempty"`
+
+	// ClientRegistration optionally enables the OAuth 2.0 Dynamic Client Registration endpoint of this
+	// FederationDomain, as described in RFC7591 and RFC7592. Clients registered using this endpoint are created as
+	// OIDCClient resources in the same namespace. When not specified, clients cannot register themselves, and all
+	// OIDCClients must be created by an admin.
+	// +optional
+	ClientRegistration *FederationDomainClientRegistration `json:"clientRegistration,omitempty"`
+
+	// Branding optionally customizes the appearance of the web pages which this FederationDomain shows to end users
+	// during login, i.e. the login page, the page for choosing an identity provider, and the page which finishes a
+	// login using response_mode=form_post. When not specified, the pages show the default Pinniped branding.
+	// +optional
+	Branding *FederationDomainBranding `json:"branding,omitempty"`
+
+	// DefaultLanguage is the language of the web pages which this FederationDomain shows to end users during login,
+	// i.e. the login page, the page for choosing an identity provider, and the page which finishes a login using
+	// response_mode=form_post, when the Accept-Language header of the user's browser does not ask for any of the
+	// supported languages. The supported languages are "en" (English), "de" (German), "es" (Spanish), "fr" (French),
+	// and "ja" (Japanese). When not specified, English is used.
+	// +kubebuilder:validation:Enum=en;de;es;fr;ja
+	// +optional
+	DefaultLanguage string `json:"defaultLanguage,omitempty"`
+}
+
+// FederationDomainBranding refers to the ConfigMap which customizes the web pages of a FederationDomain.
+type FederationDomainBranding struct {
+	// configMapName is the name of a ConfigMap in the same namespace as the FederationDomain. All of its keys are
+	// optional. The "logo" key of its binaryData is a PNG, JPEG, GIF, or WebP image which is shown at the top of each
+	// page. The "primaryColor", "backgroundColor", and "textColor" keys of its data are CSS hex colors, e.g. "#1a73e8".
+	// The "helpText" key is plain text which is shown below each page's content. The "footerLinks" key is a YAML list
+	// of links which are shown at the bottom of each page, each with a "text" and an https "url". The
+	// "login.gohtml", "choose_idp.gohtml", and "form_post.gohtml" keys are Go html/template files which replace the
+	// default templates of the pages. The ConfigMap is validated whenever it changes, and the FederationDomain will
+	// not be loaded while it is invalid. Template overrides may not use inline scripts, styles, or event handlers
+	// other than the ones provided by the default templates, because the pages are served with a strict Content
+	// Security Policy.
+	// +kubebuilder:validation:MinLength=1
+	ConfigMapName string `json:"configMapName"`
+}
+
+// FederationDomainClientRegistration describes the optional configuration of the dynamic client registration
+// endpoint of a FederationDomain, which is served at the path /oauth2/register below the issuer.
+// +kubebuilder:validation:XValidation:message="at least one of initialAccessTokenSecretName or allowKubernetesAuthentication must be configured",rule="has(self.initialAccessTokenSecretName) || (has(self.allowKubernetesAuthentication) && self.allowKubernetesAuthentication)"
+type FederationDomainClientRegistration struct {
+	// initialAccessTokenSecretName is the name of a Secret in the same namespace as the FederationDomain.
+	// The value of each key in the Secret's data is an initial access token, as described in RFC7591 section 3,
+	// which a caller may send as a bearer token to register a client. Tokens may be added to and removed from the
+	// Secret at any time.
+	// +optional
+	InitialAccessTokenSecretName string `json:"initialAccessTokenSecretName,omitempty"`
+
+	// allowKubernetesAuthentication allows a caller to register a client by sending a Kubernetes bearer token,
+	// such as a service account token, in place of an initial access token. The token is validated using a
+	// TokenReview, and the caller must be authorized to create OIDCClients in the namespace of the FederationDomain.
+	// +optional
+	AllowKubernetesAuthentication bool `json:"allowKubernetesAuthentication,omitempty"`
+
+	// allowedRedirectURIPatterns are the patterns which the redirect URIs of a registered client must match.
+	// The scheme, host, port and path of a redirect URI are matched separately, and must be the same as those of the
+	// pattern, except that within a label of the pattern's host "*" matches any sequence of letters, digits and hyphens,
+	// and a port of "*" matches any port. For example, "https://*.preview.example.com/callback" allows a redirect URI
+	// for any preview environment. Redirect URIs with userinfo, a query, or a fragment are never allowed.
+	// A redirect URI must also be allowed by the validation of OIDCClient's allowedRedirectURIs.
+	// When empty, only clients which do not use redirect URIs may be registered.
+	// +listType=set
+	// +optional
+	AllowedRedirectURIPatterns []string `json:"allowedRedirectURIPatterns,omitempty"`
+
+	// allowedGrantTypes are the grant types which a registered client may request. The client_credentials grant
+	// type cannot be registered dynamically.
+	// +listType=set
+	// +kubebuilder:validation:MinItems=1
+	// +kubebuilder:validation:XValidation:message="client_credentials cannot be registered dynamically",rule="!self.exists(g, g == 'client_credentials')"
+	AllowedGrantTypes []GrantType `json:"allowedGrantTypes"`
+
+	// allowedScopes are the scopes which a registered client may request. The openid scope must be listed.
+	// +listType=set
+	// +kubebuilder:validation:MinItems=1
+	// +kubebuilder:validation:XValidation:message="openid must be included",rule="self.exists(s, s == 'openid')"
+	AllowedScopes []Scope `json:"allowedScopes"`
+}
+
+// FederationDomainSigningAlgorithm is a JWS algorithm which a FederationDomain may use to sign ID tokens.
+type FederationDomainSigningAlgorithm string
+
+const (
+	// FederationDomainSigningAlgorithmES256 signs using ECDSA with a P-256 key and SHA-256.
+	FederationDomainSigningAlgorithmES256 FederationDomainSigningAlgorithm = "ES256"
+
+	// FederationDomainSigningAlgorithmES384 signs using ECDSA with a P-384 key and SHA-384.
+	FederationDomainSigningAlgorithmES384 FederationDomainSigningAlgorithm = "ES384"
+
+	// FederationDomainSigningAlgorithmRS256 signs using RSASSA-PKCS1-v1_5 with a 2048 bit key and SHA-256.
+	FederationDomainSigningAlgorithmRS256 FederationDomainSigningAlgorithm = "RS256"
+
+	// FederationDomainSigningAlgorithmEdDSA signs using EdDSA with an Ed25519 key.
+	FederationDomainSigningAlgorithmEdDSA FederationDomainSigningAlgorithm = "EdDSA"
+)
+
+// FederationDomainSigning describes the optional configuration of the keys which sign ID tokens.
+type FederationDomainSigning struct {
+	// algorithm is the JWS algorithm used to sign ID tokens. When empty, ES256 will be used.
+	// When the algorithm is changed, a new key for the new algorithm will be generated and published immediately,
+	// and it will replace the previous key once nextKeyPublicationSeconds have passed.
+	// +kubebuilder:validation:Enum=ES256;ES384;RS256;EdDSA
+	// +optional
+	Algorithm FederationDomainSigningAlgorithm `json:"algorithm,omitempty"`
+
+	// rotationPeriodSeconds is how long, in seconds, each signing key will be used before it is replaced by the next
+	// key and retired. The next key is generated and published nextKeyPublicationSeconds before it replaces the
+	// previous key. When null, the signing key will not be rotated automatically.
+	// This value must be between 3,600 seconds (1 hour) and 31,536,000 seconds (365 days), inclusive.
+	// +kubebuilder:validation:Minimum=3600
+	// +kubebuilder:validation:Maximum=31536000
+	// +optional
+	RotationPeriodSeconds *int32 `json:"rotationPeriodSeconds,omitempty"`
+
+	// retiredKeyRetentionSeconds is how long, in seconds, a retired signing key will continue to be published in the
+	// FederationDomain's JWKS, so that ID tokens which were signed by the key before it was retired can still be
+	// verified. When null, a default of 86,400 seconds (24 hours) will be used.
+	// This value must be between 300 seconds (5 minutes) and 2,592,000 seconds (30 days), inclusive.
+	// +kubebuilder:validation:Minimum=300
+	// +kubebuilder:validation:Maximum=2592000
+	// +optional
+	RetiredKeyRetentionSeconds *int32 `json:"retiredKeyRetentionSeconds,omitempty"`
+
+	// nextKeyPublicationSeconds is how long, in seconds, a newly generated signing key will be published in the
+	// FederationDomain's JWKS before it is used to sign ID tokens, so that clients which cache the JWKS can verify
+	// the ID tokens signed by the key as soon as it becomes active. When null, a default of 3,600 seconds (1 hour)
+	// will be used. This value must be between 60 seconds (1 minute) and 86,400 seconds (24 hours), inclusive.
+	// +kubebuilder:validation:Minimum=60
+	// +kubebuilder:validation:Maximum=86400
+	// +optional
+	NextKeyPublicationSeconds *int32 `json:"nextKeyPublicationSeconds,omitempty"`
+}
+
+// FederationDomainSessionPolicy describes the optional configuration of the lifetimes of sessions and tokens.
+type FederationDomainSessionPolicy struct {
+	// maxSessionAgeSeconds is the maximum length of time, in seconds, that an end user's session may continue after
+	// their initial login, no matter how often the session is refreshed. After this time, the refresh grant will fail
+	// and the end user will need to log in again. Access tokens, refresh tokens, and ID tokens will never be issued with lifetimes
+	// which extend beyond this time. When null, there is no maximum, and a session can continue for as long as it
+	// keeps being refreshed successfully.
+	// This value must be between 300 seconds (5 minutes) and 2,592,000 seconds (30 days), inclusive.
+	// +kubebuilder:validation:Minimum=300
+	// +kubebuilder:validation:Maximum=2592000
+	// +optional
+	MaxSessionAgeSeconds *int32 `json:"maxSessionAgeSeconds,omitempty"`
+
+	// refreshTokenSeconds is the lifetime of each refresh token, in seconds. Each refresh grant returns a new refresh
+	// token which replaces the old one, so this is the longest time that a client can wait between refreshes before
+	// its session ends. When null, a default of 32,400 seconds (9 hours) will be used.
+	// This value must be between 300 seconds (5 minutes) and 2,592,000 seconds (30 days), inclusive.
+	// +kubebuilder:validation:Minimum=300
+	// +kubebuilder:validation:Maximum=2592000
+	// +optional
+	RefreshTokenSeconds *int32 `json:"refreshTokenSeconds,omitempty"`
+
+	// idleTimeoutSeconds is the length of time, in seconds, after which a session which has not been used to perform
+	// a refresh grant will end. This is enforced by limiting the lifetime of each refresh token, so when this value
+	// is shorter than refreshTokenSeconds, then it takes precedence. When null, only refreshTokenSeconds applies.
+	// This value must be between 300 seconds (5 minutes) and 2,592,000 seconds (30 days), inclusive.
+	// +kubebuilder:validation:Minimum=300
+	// +kubebuilder:validation:Maximum=2592000
+	// +optional
+	IdleTimeoutSeconds *int32 `json:"idleTimeoutSeconds,omitempty"`
+
+	// accessTokenSeconds is the lifetime of access tokens, in seconds. Access tokens are used to get cluster-scoped
+	// ID tokens by RFC8693 token exchange. When null, a default of 120 seconds (2 minutes) will be used.
+	// It is recommended to make these tokens short-lived to force clients to perform the refresh grant often, because
+	// the refresh grant will check with the external identity provider to decide if it is acceptable for the end user
+	// to continue their session, and will update the end user's group memberships from the external identity provider.
+	// This value must be between 60 and 3,600 seconds (1 hour), inclusive.
+	// +kubebuilder:validation:Minimum=60
+	// +kubebuilder:validation:Maximum=3600
+	// +optional
+	AccessTokenSeconds *int32 `json:"accessTokenSeconds,omitempty"`
 }
 
 // FederationDomainSecrets holds information about this OIDC Provider's secrets.
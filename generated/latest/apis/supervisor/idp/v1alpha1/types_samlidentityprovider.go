@@ -0,0 +1,162 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+type SAMLIdentityProviderPhase string
+
+const (
+	// SAMLPhasePending is the default phase for newly-created SAMLIdentityProvider resources.
+	SAMLPhasePending SAMLIdentityProviderPhase = "Pending"
+
+	// SAMLPhaseReady is the phase for an SAMLIdentityProvider resource in a healthy state.
+	SAMLPhaseReady SAMLIdentityProviderPhase = "Ready"
+
+	// SAMLPhaseError is the phase for an SAMLIdentityProvider in an unhealthy state.
+	SAMLPhaseError SAMLIdentityProviderPhase = "Error"
+)
+
+// SAMLIdentityProviderStatus is the status of an SAML identity provider.
+type SAMLIdentityProviderStatus struct {
+	// Phase summarizes the overall status of the SAMLIdentityProvider.
+	//
+	// +kubebuilder:default=Pending
+	// +kubebuilder:validation:Enum=Pending;Ready;Error
+	Phase SAMLIdentityProviderPhase `json:"phase,omitempty"`
+
+	// Conditions represents the observations of an identity provider's current state.
+	//
+	// +patchMergeKey=type
+	// +patchStrategy=merge
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty" patchStrategy:"merge" patchMergeKey:"type"`
+}
+
+// SAMLMetadataSpec describes where to find the SAML metadata document of the upstream identity provider.
+// The metadata provides the entity ID of the identity provider, the location of its single sign-on service,
+// and the certificates which are used to validate the signatures of its SAML responses and assertions.
+type SAMLMetadataSpec struct {
+	// URL is the location from which the Supervisor will download the SAML metadata document of the
+	// identity provider, e.g. "https://adfs.example.com/FederationMetadata/2007-06/FederationMetadata.xml".
+	// Must use the "https" scheme. The metadata document will be periodically downloaded again to pick
+	// up any changes, such as rotated signing certificates.
+	//
+	// +kubebuilder:validation:Pattern=`^https://`
+	// +optional
+	URL string `json:"url,omitempty"`
+
+	// TLS configuration for downloading the metadata document from the URL.
+	//
+	// +optional
+	TLS *TLSSpec `json:"tls,omitempty"`
+
+	// Inline is the SAML metadata document of the identity provider as an XML string. This may be used
+	// instead of URL when the identity provider does not publish its metadata at a URL which is reachable
+	// from the Supervisor. When using this field, you must update it yourself when the identity provider
+	// rotates its signing certificates.
+	//
+	// +optional
+	Inline string `json:"inline,omitempty"`
+}
+
+// SAMLClaims allows customization of how the username and groups are determined from the SAML assertion.
+type SAMLClaims struct {
+	// Username is the name of the attribute in the SAML assertion whose value shall determine the username
+	// in Kubernetes, e.g. "http://schemas.xmlsoap.org/ws/2005/05/identity/claims/upn". The attribute must
+	// have exactly one non-empty value.
+	//
+	// When not set, the NameID of the subject of the SAML assertion will be used as the username.
+	//
+	// +optional
+	Username string `json:"username,omitempty"`
+
+	// Groups is the name of the attribute in the SAML assertion whose values shall determine the group
+	// names in Kubernetes, e.g. "http://schemas.xmlsoap.org/claims/Group". Each value of the attribute
+	// becomes one group name.
+	//
+	// When not set, the user will not belong to any groups, unless they are added by identity
+	// transformations configured on the FederationDomain.
+	//
+	// +optional
+	Groups string `json:"groups,omitempty"`
+}
+
+// SAMLServiceProviderSpec allows customization of how the Supervisor presents itself to the identity
+// provider as a SAML service provider.
+type SAMLServiceProviderSpec struct {
+	// EntityID is the SAML entity ID of the Supervisor as a service provider. This is the value which the
+	// Supervisor will send as the issuer of its authentication requests, and the value which must be present
+	// in the audience restriction of the assertions returned by the identity provider.
+	//
+	// When not set, the issuer URL of the FederationDomain which is being used to log in will be used
+	// as the entity ID. In that case, each FederationDomain that includes this identity provider must be
+	// registered at the identity provider as a separate service provider (also called a relying party).
+	//
+	// +kubebuilder:validation:MinLength=1
+	// +optional
+	EntityID string `json:"entityID,omitempty"`
+}
+
+// SAMLIdentityProviderSpec is the spec for configuring an SAML identity provider.
+type SAMLIdentityProviderSpec struct {
+	// Metadata describes where to find the SAML metadata of the identity provider.
+	// Exactly one of url or inline must be set.
+	//
+	// +kubebuilder:validation:XValidation:message="exactly one of spec.metadata.url or spec.metadata.inline must be set",rule="has(self.url) != has(self.inline)"
+	Metadata SAMLMetadataSpec `json:"metadata"`
+
+	// Claims allows customization of how the username and groups are determined from the SAML assertion.
+	//
+	// +optional
+	Claims SAMLClaims `json:"claims,omitempty"`
+
+	// ServiceProvider allows customization of how the Supervisor presents itself to the identity provider.
+	//
+	// +optional
+	ServiceProvider SAMLServiceProviderSpec `json:"serviceProvider,omitempty"`
+}
+
+// SAMLIdentityProvider describes the configuration of an upstream SAML 2.0 identity provider,
+// such as Active Directory Federation Services (ADFS) or Shibboleth.
+//
+// The Supervisor acts as a SAML service provider. It sends SP-initiated authentication requests to the
+// identity provider using the HTTP-Redirect binding, and expects the SAML response to be returned to the
+// callback endpoint of the FederationDomain (e.g. "https://issuer.example.com/callback") using the
+// HTTP-POST binding. The callback endpoint should be registered at the identity provider as the
+// assertion consumer service URL. The SAML response or its assertion must be signed by the identity provider.
+//
+// Only web-based logins are supported, for both the pinniped-cli client and clients configured
+// as OIDCClients.
+//
+// +genclient
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// +kubebuilder:resource:categories=pinniped;pinniped-idp;pinniped-idps
+// +kubebuilder:printcolumn:name="Metadata URL",type=string,JSONPath=`.spec.metadata.url`
+// +kubebuilder:printcolumn:name="Status",type=string,JSONPath=`.status.phase`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+// +kubebuilder:subresource:status
+type SAMLIdentityProvider struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	// Spec for configuring the identity provider.
+	Spec SAMLIdentityProviderSpec `json:"spec"`
+
+	// Status of the identity provider.
+	Status SAMLIdentityProviderStatus `json:"status,omitempty"`
+}
+
+// SAMLIdentityProviderList lists SAMLIdentityProvider objects.
+//
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+type SAMLIdentityProviderList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+
+	Items []SAMLIdentityProvider `json:"items"`
+}
@@ -23,14 +23,26 @@ func (c *FakeIDPV1alpha1) GitHubIdentityProviders(namespace string) v1alpha1.Git
 	return newFakeGitHubIdentityProviders(c, namespace)
 }
 
+func (c *FakeIDPV1alpha1) GitLabIdentityProviders(namespace string) v1alpha1.GitLabIdentityProviderInterface {
+	return newFakeGitLabIdentityProviders(c, namespace)
+}
+
 func (c *FakeIDPV1alpha1) LDAPIdentityProviders(namespace string) v1alpha1.LDAPIdentityProviderInterface {
 	return newFakeLDAPIdentityProviders(c, namespace)
 }
 
+func (c *FakeIDPV1alpha1) OAuth2IdentityProviders(namespace string) v1alpha1.OAuth2IdentityProviderInterface {
+	return newFakeOAuth2IdentityProviders(c, namespace)
+}
+
 func (c *FakeIDPV1alpha1) OIDCIdentityProviders(namespace string) v1alpha1.OIDCIdentityProviderInterface {
 	return newFakeOIDCIdentityProviders(c, namespace)
 }
 
+func (c *FakeIDPV1alpha1) SAMLIdentityProviders(namespace string) v1alpha1.SAMLIdentityProviderInterface {
+	return newFakeSAMLIdentityProviders(c, namespace)
+}
+
 // RESTClient returns a RESTClient that is used to communicate
 // with API server by this client implementation.
 func (c *FakeIDPV1alpha1) RESTClient() rest.Interface {
@@ -1,6 +1,6 @@
 #!/usr/bin/env bash
 
-# Copyright 2020-2025 the Pinniped contributors. All Rights Reserved.
+# Copyright 2020-2026 the Pinniped contributors. All Rights Reserved.
 # SPDX-License-Identifier: Apache-2.0
 set -euo pipefail
 ROOT="$(cd "$(dirname "${BASH_SOURCE[0]}")/../.." && pwd)"
@@ -289,7 +289,7 @@ else
           "deepcopy" \
           "${BASE_PKG}/generated/${KUBE_MINOR_VERSION}/apis" \
           "${BASE_PKG}/generated/${KUBE_MINOR_VERSION}/apis" \
-          "supervisor/config:v1alpha1 supervisor/idp:v1alpha1 supervisor/clientsecret:v1alpha1 concierge/config:v1alpha1 concierge/authentication:v1alpha1 concierge/login:v1alpha1 concierge/identity:v1alpha1" \
+          "supervisor/config:v1alpha1 supervisor/idp:v1alpha1 supervisor/clientsecret:v1alpha1 supervisor/session:v1alpha1 concierge/config:v1alpha1 concierge/authentication:v1alpha1 concierge/login:v1alpha1 concierge/identity:v1alpha1" \
           --go-header-file "${ROOT}/hack/boilerplate.go.txt" -v "$debug_level" 2>&1 | sed "s|^|gen-api > |"
   )
 
@@ -315,7 +315,7 @@ else
           "${BASE_PKG}/generated/${KUBE_MINOR_VERSION}/client/supervisor" \
           "${BASE_PKG}/generated/${KUBE_MINOR_VERSION}/apis" \
           "${BASE_PKG}/generated/${KUBE_MINOR_VERSION}/apis" \
-          "supervisor/clientsecret:v1alpha1" \
+          "supervisor/clientsecret:v1alpha1 supervisor/session:v1alpha1" \
           --go-header-file "${ROOT}/hack/boilerplate.go.txt" -v "$debug_level" 2>&1 | sed "s|^|gen-supervisor-int-api > |"
   )
 
@@ -338,7 +338,7 @@ else
           "client,lister,informer" \
           "${BASE_PKG}/generated/${KUBE_MINOR_VERSION}/client/supervisor" \
           "${BASE_PKG}/generated/${KUBE_MINOR_VERSION}/apis" \
-          "supervisor/config:v1alpha1 supervisor/idp:v1alpha1 supervisor/clientsecret:v1alpha1" \
+          "supervisor/config:v1alpha1 supervisor/idp:v1alpha1 supervisor/clientsecret:v1alpha1 supervisor/session:v1alpha1" \
           --go-header-file "${ROOT}/hack/boilerplate.go.txt" -v "$debug_level" 2>&1 | sed "s|^|gen-supervisor-client > |"
   )
 
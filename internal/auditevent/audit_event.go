@@ -1,4 +1,4 @@
-// Copyright 2024 the Pinniped contributors. All Rights Reserved.
+// Copyright 2024-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package auditevent
@@ -16,22 +16,32 @@ const (
 
 	// Supervisor authentication logging.
 
-	UsingUpstreamIDP                   Message = "Using Upstream IDP"
-	AuthorizeIDFromParameters          Message = "AuthorizeID From Parameters"
-	IdentityFromUpstreamIDP            Message = "Identity From Upstream IDP"
-	UpstreamAuthorizeRedirect          Message = "Upstream Authorize Redirect"
-	IdentityRefreshedFromUpstreamIDP   Message = "Identity Refreshed From Upstream IDP"
-	IDTokenIssued                      Message = "ID Token Issued" //nolint:gosec // this is not a credential
-	SessionStarted                     Message = "Session Started"
-	SessionRefreshed                   Message = "Session Refreshed"
-	SessionFound                       Message = "Session Found"
-	AuthenticationRejectedByTransforms Message = "Authentication Rejected By Transforms"
-	IncorrectUsernameOrPassword        Message = "Incorrect Username Or Password"
+	UsingUpstreamIDP                       Message = "Using Upstream IDP"
+	AuthorizeIDFromParameters              Message = "AuthorizeID From Parameters"
+	IdentityFromUpstreamIDP                Message = "Identity From Upstream IDP"
+	UpstreamAuthorizeRedirect              Message = "Upstream Authorize Redirect"
+	IdentityRefreshedFromUpstreamIDP       Message = "Identity Refreshed From Upstream IDP"
+	IDTokenIssued                          Message = "ID Token Issued" //nolint:gosec // this is not a credential
+	SessionStarted                         Message = "Session Started"
+	SessionRefreshed                       Message = "Session Refreshed"
+	SessionFound                           Message = "Session Found"
+	AuthenticationRejectedByTransforms     Message = "Authentication Rejected By Transforms"
+	AuthenticationRejectedByUpstreamPolicy Message = "Authentication Rejected By Upstream Policy"
+	IncorrectUsernameOrPassword            Message = "Incorrect Username Or Password"
+	DeviceAuthorizationApproved            Message = "Device Authorization Approved"
+	IdentityFromClientCredentials          Message = "Identity From Client Credentials"
 
 	// Supervisor session ending logging.
 
 	UpstreamOIDCTokenRevoked Message = "Upstream OIDC Token Revoked" //nolint:gosec // this is not a credential
 	SessionGarbageCollected  Message = "Session Garbage Collected"
+	SessionRevoked           Message = "Session Revoked"
+
+	// Supervisor dynamic client registration logging.
+
+	OIDCClientRegistered          Message = "OIDCClient Registered"
+	OIDCClientRegistrationUpdated Message = "OIDCClient Registration Updated"
+	OIDCClientRegistrationDeleted Message = "OIDCClient Registration Deleted"
 
 	// Supervisor aggregated APIs logging.
 
@@ -44,4 +54,9 @@ const (
 	TokenCredentialRequestAuthenticationFailed Message = "TokenCredentialRequest Authentication Failed" //nolint:gosec // this is not a credential
 	TokenCredentialRequestUnexpectedError      Message = "TokenCredentialRequest Unexpected Error"      //nolint:gosec // this is not a credential
 	TokenCredentialRequestUnsupportedUserInfo  Message = "TokenCredentialRequest Unsupported UserInfo"  //nolint:gosec // this is not a credential
+
+	// Concierge impersonation proxy logging.
+
+	ImpersonationProxyRequestThrottled Message = "Impersonation Proxy Request Throttled"
+	ImpersonationProxySessionRecorded  Message = "Impersonation Proxy Session Recorded"
 )
@@ -13,6 +13,7 @@ import (
 	"fmt"
 	"reflect"
 	"regexp"
+	"slices"
 	"strings"
 	"time"
 
@@ -36,6 +37,8 @@ const (
 // Each compiled pipeline can be cached in memory for later thread-safe evaluation.
 type CELTransformer struct {
 	compiler             *cel.Env
+	userInfoCompiler     *cel.Env
+	claimsCompiler       *cel.Env
 	maxExpressionRuntime time.Duration
 }
 
@@ -46,7 +49,20 @@ func NewCELTransformer(maxExpressionRuntime time.Duration) (*CELTransformer, err
 	if err != nil {
 		return nil, err
 	}
-	return &CELTransformer{compiler: env, maxExpressionRuntime: maxExpressionRuntime}, nil
+	userInfoEnv, err := newUserInfoEnv()
+	if err != nil {
+		return nil, err
+	}
+	claimsEnv, err := newClaimsEnv()
+	if err != nil {
+		return nil, err
+	}
+	return &CELTransformer{
+		compiler:             env,
+		userInfoCompiler:     userInfoEnv,
+		claimsCompiler:       claimsEnv,
+		maxExpressionRuntime: maxExpressionRuntime,
+	}, nil
 }
 
 // TransformationConstants can be used to make more variables available to compiled CEL expressions for convenience.
@@ -122,6 +138,12 @@ type AllowAuthenticationPolicy struct {
 }
 
 func compileProgram(transformer *CELTransformer, expectedExpressionType *cel.Type, expr string) (cel.Program, error) {
+	return compileProgramWithEnv(transformer.compiler, expr, expectedExpressionType)
+}
+
+// compileProgramWithEnv compiles the expression using the given environment and checks that the type of the
+// expression's result is one of the acceptedExpressionTypes.
+func compileProgramWithEnv(env *cel.Env, expr string, acceptedExpressionTypes ...*cel.Type) (cel.Program, error) {
 	if strings.TrimSpace(expr) == "" {
 		return nil, fmt.Errorf("cannot compile empty CEL expression")
 	}
@@ -129,19 +151,19 @@ func compileProgram(transformer *CELTransformer, expectedExpressionType *cel.Typ
 	// compile does both parsing and type checking. The parsing phase indicates whether the expression is
 	// syntactically valid and expands any macros present within the environment. Parsing and checking are
 	// more computationally expensive than evaluation, so parsing and checking are done in advance.
-	ast, issues := transformer.compiler.Compile(expr)
+	ast, issues := env.Compile(expr)
 	if issues != nil {
 		return nil, fmt.Errorf("CEL expression compile error: %s", issues.String())
 	}
 
 	// The compiler's type checker has determined the type of the expression's result.
 	// Check that it matches the type that we expect.
-	if ast.OutputType().String() != expectedExpressionType.String() {
-		return nil, fmt.Errorf("CEL expression should return type %q but returns type %q", expectedExpressionType, ast.OutputType())
+	if !slices.ContainsFunc(acceptedExpressionTypes, func(t *cel.Type) bool { return ast.OutputType().String() == t.String() }) {
+		return nil, fmt.Errorf("CEL expression should return type %q but returns type %q", acceptedExpressionTypes[0], ast.OutputType())
 	}
 
 	// The cel.Program is stateless, thread-safe, and cachable.
-	program, err := transformer.compiler.Program(ast,
+	program, err := env.Program(ast,
 		cel.InterruptCheckFrequency(100), // Kubernetes uses 100 here, so we'll copy that setting.
 		cel.EvalOptions(cel.OptOptimize), // Optimize certain things now rather than at evaluation time.
 	)
@@ -328,14 +350,19 @@ func newEnv() (*cel.Env, error) {
 	// how to configure the CEL compiler for production usage. Examples:
 	// https://github.com/kubernetes/kubernetes/blob/master/staging/src/k8s.io/apiserver/pkg/admission/plugin/validatingadmissionpolicy/compiler.go
 	// https://github.com/kubernetes/kubernetes/blob/master/staging/src/k8s.io/apiextensions-apiserver/pkg/apiserver/schema/cel/compilation.go
-	return cel.NewEnv(
+	return cel.NewEnv(append(commonEnvOptions(),
 		// Declare our variable without giving them values yet. By declaring them here, the type is known during
 		// the parsing/checking phase.
 		cel.Variable(usernameVariableName, cel.StringType),
 		cel.Variable(groupsVariableName, cel.ListType(cel.StringType)),
 		cel.Variable(constStringVariableName, cel.MapType(cel.StringType, cel.StringType)),
 		cel.Variable(constStringListVariableName, cel.MapType(cel.StringType, cel.ListType(cel.StringType))),
+	)...)
+}
 
+// commonEnvOptions returns the options shared by all environments used to compile expressions.
+func commonEnvOptions() []cel.EnvOption {
+	return []cel.EnvOption{
 		// Enable the strings extensions.
 		// See https://github.com/google/cel-go/tree/master/ext#strings
 		// CEL also has other extensions for bas64 encoding/decoding and for math that we could choose to enable.
@@ -355,5 +382,5 @@ func newEnv() (*cel.Env, error) {
 
 		// Check for collisions in declarations now instead of later.
 		cel.EagerlyValidateDeclarations(true),
-	)
+	}
 }
@@ -0,0 +1,149 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package celtransformer
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"cel.dev/cel-go/cel"
+)
+
+const (
+	claimsVariableName = "claims"
+
+	// The name of the Authentication Context Class Reference claim from
+	// https://openid.net/specs/openid-connect-core-1_0.html#IDToken
+	acrClaimName = "acr"
+
+	DefaultUpstreamAuthenticationPolicyRejectedAuthMessage = "authentication was rejected by the configured authentication policy"
+)
+
+// UpstreamAuthenticationPolicy describes requirements on how an upstream OIDC provider authenticated a user,
+// which are checked against the claims of the upstream provider's validated ID token.
+type UpstreamAuthenticationPolicy struct {
+	// ACRValues, when not empty, requires that the "acr" claim is one of these values. Optional.
+	ACRValues []string
+	// Expression is a CEL expression which must evaluate to a boolean. It may use the variable `claims`,
+	// which is the map of the upstream ID token's claims. Optional.
+	Expression string
+	// RejectedAuthenticationMessage is shown to the user when the policy rejects the authentication. Optional.
+	// When empty, a default message will be used.
+	RejectedAuthenticationMessage string
+}
+
+// UpstreamAuthenticationPolicyResult is the result of evaluating a CompiledUpstreamAuthenticationPolicy.
+type UpstreamAuthenticationPolicyResult struct {
+	// AuthenticationAllowed is true when the claims satisfied the policy.
+	AuthenticationAllowed bool
+	// RejectedAuthenticationMessage is the message for the user when AuthenticationAllowed is false.
+	RejectedAuthenticationMessage string
+	// Reason describes why the authentication was rejected when AuthenticationAllowed is false.
+	// It is intended for logs, not for the user.
+	Reason string
+}
+
+// CompiledUpstreamAuthenticationPolicy can be evaluated repeatedly and in a thread-safe way.
+type CompiledUpstreamAuthenticationPolicy struct {
+	acrValues                     []string
+	program                       cel.Program // nil when there is no expression
+	rejectedAuthenticationMessage string
+	maxExpressionRuntime          time.Duration
+}
+
+// CompileUpstreamAuthenticationPolicy compiles the CEL expression of an UpstreamAuthenticationPolicy.
+// The compiled result can be cached in memory and evaluated repeatedly and in a thread-safe way.
+func (c *CELTransformer) CompileUpstreamAuthenticationPolicy(p *UpstreamAuthenticationPolicy) (*CompiledUpstreamAuthenticationPolicy, error) {
+	if len(p.ACRValues) == 0 && strings.TrimSpace(p.Expression) == "" {
+		return nil, fmt.Errorf("at least one of acrValues or expression must be specified")
+	}
+
+	for _, acrValue := range p.ACRValues {
+		if acrValue == "" || strings.ContainsAny(acrValue, " \t\n") {
+			return nil, fmt.Errorf("acrValues must not be empty or contain whitespace: %q", acrValue)
+		}
+	}
+
+	var program cel.Program
+	if strings.TrimSpace(p.Expression) != "" {
+		var err error
+		// The type checker cannot know the types of the values of the claims, so expressions which navigate
+		// the claims may have dynamic types. Those are checked when the expression is evaluated.
+		program, err = compileProgramWithEnv(c.claimsCompiler, p.Expression, cel.BoolType, cel.DynType)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	rejectedAuthenticationMessage := p.RejectedAuthenticationMessage
+	if rejectedAuthenticationMessage == "" {
+		rejectedAuthenticationMessage = DefaultUpstreamAuthenticationPolicyRejectedAuthMessage
+	}
+
+	return &CompiledUpstreamAuthenticationPolicy{
+		acrValues:                     slices.Clone(p.ACRValues),
+		program:                       program,
+		rejectedAuthenticationMessage: rejectedAuthenticationMessage,
+		maxExpressionRuntime:          c.maxExpressionRuntime,
+	}, nil
+}
+
+// ACRValues returns the required acr values, in order of preference, or nil when there are none.
+// Callers must not modify the returned slice.
+func (c *CompiledUpstreamAuthenticationPolicy) ACRValues() []string {
+	return c.acrValues
+}
+
+// Evaluate checks the claims against the policy. An error is returned when the expression could not be evaluated,
+// which callers should treat as a rejected authentication.
+func (c *CompiledUpstreamAuthenticationPolicy) Evaluate(ctx context.Context, claims map[string]any) (*UpstreamAuthenticationPolicyResult, error) {
+	if len(c.acrValues) > 0 {
+		acr, ok := claims[acrClaimName].(string)
+		if !ok {
+			return c.rejected(fmt.Sprintf("required %q claim is missing or is not a string", acrClaimName)), nil
+		}
+		if !slices.Contains(c.acrValues, acr) {
+			return c.rejected(fmt.Sprintf("%q claim value %q is not one of the required values [%s]",
+				acrClaimName, acr, strings.Join(c.acrValues, ", "))), nil
+		}
+	}
+
+	if c.program != nil {
+		// Limit the runtime of a CEL expression to avoid accidental very expensive expressions.
+		timeoutCtx, cancel := context.WithTimeout(ctx, c.maxExpressionRuntime)
+		defer cancel()
+
+		val, _, err := c.program.ContextEval(timeoutCtx, map[string]any{claimsVariableName: claims})
+		if err != nil {
+			return nil, err
+		}
+		allowed, ok := val.Value().(bool)
+		if !ok {
+			return nil, fmt.Errorf("could not convert expression result to bool")
+		}
+		if !allowed {
+			return c.rejected("expression evaluated to false"), nil
+		}
+	}
+
+	return &UpstreamAuthenticationPolicyResult{AuthenticationAllowed: true}, nil
+}
+
+func (c *CompiledUpstreamAuthenticationPolicy) rejected(reason string) *UpstreamAuthenticationPolicyResult {
+	return &UpstreamAuthenticationPolicyResult{
+		AuthenticationAllowed:         false,
+		RejectedAuthenticationMessage: c.rejectedAuthenticationMessage,
+		Reason:                        reason,
+	}
+}
+
+func newClaimsEnv() (*cel.Env, error) {
+	return cel.NewEnv(append(commonEnvOptions(),
+		// The claims of an ID token can have values of any type.
+		cel.Variable(claimsVariableName, cel.MapType(cel.StringType, cel.DynType)),
+	)...)
+}
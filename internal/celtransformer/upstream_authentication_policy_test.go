@@ -0,0 +1,173 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package celtransformer
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpstreamAuthenticationPolicy(t *testing.T) {
+	const claimsJSON = `{
+		"iss": "https://issuer.example.com",
+		"sub": "some-subject",
+		"acr": "phrh",
+		"amr": ["pwd", "mfa", "hwk"],
+		"auth_time": 1700000000,
+		"email": "ryan@example.com"
+	}`
+
+	tests := []struct {
+		name       string
+		policy     UpstreamAuthenticationPolicy
+		claimsJSON string
+
+		wantACRValues     []string
+		wantResult        *UpstreamAuthenticationPolicyResult
+		wantCompileErr    string
+		wantEvaluationErr string
+	}{
+		{
+			name:          "acr values which are satisfied",
+			policy:        UpstreamAuthenticationPolicy{ACRValues: []string{"phr", "phrh"}},
+			wantACRValues: []string{"phr", "phrh"},
+			wantResult:    &UpstreamAuthenticationPolicyResult{AuthenticationAllowed: true},
+		},
+		{
+			name:          "acr values which are not satisfied",
+			policy:        UpstreamAuthenticationPolicy{ACRValues: []string{"phr"}},
+			wantACRValues: []string{"phr"},
+			wantResult: &UpstreamAuthenticationPolicyResult{
+				AuthenticationAllowed:         false,
+				RejectedAuthenticationMessage: "authentication was rejected by the configured authentication policy",
+				Reason:                        `"acr" claim value "phrh" is not one of the required values [phr]`,
+			},
+		},
+		{
+			name:          "acr values when the acr claim is missing",
+			policy:        UpstreamAuthenticationPolicy{ACRValues: []string{"phr"}, RejectedAuthenticationMessage: "please use MFA"},
+			claimsJSON:    `{"sub": "some-subject"}`,
+			wantACRValues: []string{"phr"},
+			wantResult: &UpstreamAuthenticationPolicyResult{
+				AuthenticationAllowed:         false,
+				RejectedAuthenticationMessage: "please use MFA",
+				Reason:                        `required "acr" claim is missing or is not a string`,
+			},
+		},
+		{
+			name:       "expression which allows the authentication",
+			policy:     UpstreamAuthenticationPolicy{Expression: `"mfa" in claims.amr`},
+			wantResult: &UpstreamAuthenticationPolicyResult{AuthenticationAllowed: true},
+		},
+		{
+			name:   "expression which rejects the authentication",
+			policy: UpstreamAuthenticationPolicy{Expression: `"otp" in claims.amr`, RejectedAuthenticationMessage: "please use a one-time password"},
+			wantResult: &UpstreamAuthenticationPolicyResult{
+				AuthenticationAllowed:         false,
+				RejectedAuthenticationMessage: "please use a one-time password",
+				Reason:                        "expression evaluated to false",
+			},
+		},
+		{
+			name:       "expression which checks for an optional claim",
+			policy:     UpstreamAuthenticationPolicy{Expression: `has(claims.amr) && claims.amr.exists(m, m == "hwk")`},
+			wantResult: &UpstreamAuthenticationPolicyResult{AuthenticationAllowed: true},
+		},
+		{
+			name: "acr values and expression which are both satisfied",
+			policy: UpstreamAuthenticationPolicy{
+				ACRValues:  []string{"phrh"},
+				Expression: `claims.email.endsWith("@example.com")`,
+			},
+			wantACRValues: []string{"phrh"},
+			wantResult:    &UpstreamAuthenticationPolicyResult{AuthenticationAllowed: true},
+		},
+		{
+			name: "acr values which are not satisfied are checked before the expression",
+			policy: UpstreamAuthenticationPolicy{
+				ACRValues:  []string{"phr"},
+				Expression: `claims.missing`,
+			},
+			wantACRValues: []string{"phr"},
+			wantResult: &UpstreamAuthenticationPolicyResult{
+				AuthenticationAllowed:         false,
+				RejectedAuthenticationMessage: "authentication was rejected by the configured authentication policy",
+				Reason:                        `"acr" claim value "phrh" is not one of the required values [phr]`,
+			},
+		},
+		{
+			name:              "expression which references a missing claim",
+			policy:            UpstreamAuthenticationPolicy{Expression: `claims.missing == "x"`},
+			wantEvaluationErr: `no such key: missing`,
+		},
+		{
+			name:              "expression which evaluates to a non-boolean",
+			policy:            UpstreamAuthenticationPolicy{Expression: `claims.acr`},
+			wantEvaluationErr: `could not convert expression result to bool`,
+		},
+		{
+			name:           "neither acr values nor expression",
+			policy:         UpstreamAuthenticationPolicy{RejectedAuthenticationMessage: "some message"},
+			wantCompileErr: `at least one of acrValues or expression must be specified`,
+		},
+		{
+			name:           "acr value which contains a space",
+			policy:         UpstreamAuthenticationPolicy{ACRValues: []string{"phr phrh"}},
+			wantCompileErr: `acrValues must not be empty or contain whitespace: "phr phrh"`,
+		},
+		{
+			name:           "empty acr value",
+			policy:         UpstreamAuthenticationPolicy{ACRValues: []string{""}},
+			wantCompileErr: `acrValues must not be empty or contain whitespace: ""`,
+		},
+		{
+			name:           "expression with the wrong type",
+			policy:         UpstreamAuthenticationPolicy{Expression: `"mfa"`},
+			wantCompileErr: `CEL expression should return type "bool" but returns type "string"`,
+		},
+		{
+			name:   "expression which uses an unknown variable",
+			policy: UpstreamAuthenticationPolicy{Expression: `username == "ryan"`},
+			wantCompileErr: "CEL expression compile error: ERROR: <input>:1:1: undeclared reference to 'username' (in container '')\n" +
+				" | username == \"ryan\"\n" +
+				" | ^",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			transformer, err := NewCELTransformer(100 * time.Millisecond)
+			require.NoError(t, err)
+
+			compiled, err := transformer.CompileUpstreamAuthenticationPolicy(&tt.policy)
+			if tt.wantCompileErr != "" {
+				require.EqualError(t, err, tt.wantCompileErr)
+				return // the rest of the test doesn't make sense when there was a compile error
+			}
+			require.NoError(t, err, "got an unexpected compile error")
+			require.Equal(t, tt.wantACRValues, compiled.ACRValues())
+
+			claimsJSONToUse := claimsJSON
+			if tt.claimsJSON != "" {
+				claimsJSONToUse = tt.claimsJSON
+			}
+			var claims map[string]any
+			require.NoError(t, json.Unmarshal([]byte(claimsJSONToUse), &claims))
+
+			result, err := compiled.Evaluate(context.Background(), claims)
+			if tt.wantEvaluationErr != "" {
+				require.EqualError(t, err, tt.wantEvaluationErr)
+				return // the rest of the test doesn't make sense when there was an evaluation error
+			}
+			require.NoError(t, err, "got an unexpected evaluation error")
+			require.Equal(t, tt.wantResult, result)
+		})
+	}
+}
@@ -0,0 +1,156 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package celtransformer
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"cel.dev/cel-go/cel"
+	"cel.dev/cel-go/common/types/ref"
+)
+
+const (
+	userInfoVariableName       = "userinfo"
+	groupsResponseVariableName = "groupsResponse"
+)
+
+// UserInfoClaimsExtraction holds CEL expressions which can determine an upstream identity from the JSON
+// responses of an upstream OAuth2 provider's user info endpoint and optional groups endpoint.
+//
+// Each expression may use the variable `userinfo`, which is the JSON object returned by the user info endpoint,
+// and the variable `groupsResponse`, which is the JSON value returned by the groups endpoint (or null).
+type UserInfoClaimsExtraction struct {
+	// UsernameExpression must evaluate to a non-empty string. Required.
+	UsernameExpression string
+	// UIDExpression must evaluate to a non-empty string. Required.
+	UIDExpression string
+	// GroupsExpression must evaluate to a list of strings. Optional. When empty, no groups will be extracted.
+	GroupsExpression string
+}
+
+// ExtractedClaims is the result of evaluating a CompiledUserInfoClaimsExtraction.
+type ExtractedClaims struct {
+	Username string
+	UID      string
+	Groups   []string
+}
+
+// CompiledUserInfoClaimsExtraction can be evaluated repeatedly and in a thread-safe way.
+type CompiledUserInfoClaimsExtraction struct {
+	username             cel.Program
+	uid                  cel.Program
+	groups               cel.Program // nil when there is no groups expression
+	maxExpressionRuntime time.Duration
+}
+
+// CompileUserInfoClaimsExtraction compiles the CEL expressions of a UserInfoClaimsExtraction.
+// The compiled result can be cached in memory and evaluated repeatedly and in a thread-safe way.
+func (c *CELTransformer) CompileUserInfoClaimsExtraction(e *UserInfoClaimsExtraction) (*CompiledUserInfoClaimsExtraction, error) {
+	// The type checker cannot know the types of the values inside the JSON responses, so expressions which
+	// only navigate the JSON will have dynamic types. Those are checked when the expressions are evaluated.
+	username, err := compileProgramWithEnv(c.userInfoCompiler, e.UsernameExpression, cel.StringType, cel.DynType)
+	if err != nil {
+		return nil, fmt.Errorf("username expression: %w", err)
+	}
+
+	uid, err := compileProgramWithEnv(c.userInfoCompiler, e.UIDExpression, cel.StringType, cel.DynType)
+	if err != nil {
+		return nil, fmt.Errorf("uid expression: %w", err)
+	}
+
+	var groups cel.Program
+	if strings.TrimSpace(e.GroupsExpression) != "" {
+		groups, err = compileProgramWithEnv(c.userInfoCompiler, e.GroupsExpression,
+			cel.ListType(cel.StringType), cel.ListType(cel.DynType), cel.DynType)
+		if err != nil {
+			return nil, fmt.Errorf("groups expression: %w", err)
+		}
+	}
+
+	return &CompiledUserInfoClaimsExtraction{
+		username:             username,
+		uid:                  uid,
+		groups:               groups,
+		maxExpressionRuntime: c.maxExpressionRuntime,
+	}, nil
+}
+
+// HasGroupsExpression returns true when a groups expression was compiled.
+func (c *CompiledUserInfoClaimsExtraction) HasGroupsExpression() bool {
+	return c.groups != nil
+}
+
+// Evaluate runs the compiled expressions. The userInfo param should be the decoded JSON object from the user info
+// endpoint. The groupsResponse param should be the decoded JSON value from the groups endpoint, or nil.
+func (c *CompiledUserInfoClaimsExtraction) Evaluate(ctx context.Context, userInfo map[string]any, groupsResponse any) (*ExtractedClaims, error) {
+	vars := map[string]any{
+		userInfoVariableName:       userInfo,
+		groupsResponseVariableName: groupsResponse,
+	}
+
+	username, err := c.evalNonEmptyString(ctx, c.username, vars)
+	if err != nil {
+		return nil, fmt.Errorf("username expression: %w", err)
+	}
+
+	uid, err := c.evalNonEmptyString(ctx, c.uid, vars)
+	if err != nil {
+		return nil, fmt.Errorf("uid expression: %w", err)
+	}
+
+	groups := []string{}
+	if c.groups != nil {
+		val, err := c.evalProgram(ctx, c.groups, vars)
+		if err != nil {
+			return nil, fmt.Errorf("groups expression: %w", err)
+		}
+		nativeValue, err := val.ConvertToNative(reflect.TypeOf([]string{}))
+		if err != nil {
+			return nil, fmt.Errorf("groups expression: could not convert expression result to []string: %w", err)
+		}
+		groups = nativeValue.([]string)
+	}
+
+	return &ExtractedClaims{Username: username, UID: uid, Groups: groups}, nil
+}
+
+func (c *CompiledUserInfoClaimsExtraction) evalProgram(ctx context.Context, program cel.Program, vars map[string]any) (ref.Val, error) {
+	// Limit the runtime of a CEL expression to avoid accidental very expensive expressions.
+	timeoutCtx, cancel := context.WithTimeout(ctx, c.maxExpressionRuntime)
+	defer cancel()
+
+	val, _, err := program.ContextEval(timeoutCtx, vars)
+	return val, err
+}
+
+func (c *CompiledUserInfoClaimsExtraction) evalNonEmptyString(ctx context.Context, program cel.Program, vars map[string]any) (string, error) {
+	val, err := c.evalProgram(ctx, program, vars)
+	if err != nil {
+		return "", err
+	}
+	nativeValue, err := val.ConvertToNative(reflect.TypeOf(""))
+	if err != nil {
+		return "", fmt.Errorf("could not convert expression result to string: %w", err)
+	}
+	stringValue, ok := nativeValue.(string)
+	if !ok {
+		return "", fmt.Errorf("could not convert expression result to string")
+	}
+	if stringValue == "" {
+		return "", fmt.Errorf("expression result was an empty string")
+	}
+	return stringValue, nil
+}
+
+func newUserInfoEnv() (*cel.Env, error) {
+	return cel.NewEnv(append(commonEnvOptions(),
+		// The JSON responses from the upstream provider can contain values of any type.
+		cel.Variable(userInfoVariableName, cel.MapType(cel.StringType, cel.DynType)),
+		cel.Variable(groupsResponseVariableName, cel.DynType),
+	)...)
+}
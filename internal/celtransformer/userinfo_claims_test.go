@@ -0,0 +1,194 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package celtransformer
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestUserInfoClaimsExtraction(t *testing.T) {
+	const userInfoJSON = `{
+		"login": "ryan",
+		"id": 1234567890,
+		"email": "ryan@example.com",
+		"teams": [{"name": "admins"}, {"name": "developers"}],
+		"profile": {"display_name": "Ryan"}
+	}`
+
+	const groupsResponseJSON = `{"groups": [{"slug": "admins", "org": "a"}, {"slug": "devs", "org": "b"}]}`
+
+	tests := []struct {
+		name               string
+		extraction         UserInfoClaimsExtraction
+		groupsResponseJSON string
+
+		wantClaims        *ExtractedClaims
+		wantHasGroups     bool
+		wantCompileErr    string
+		wantEvaluationErr string
+	}{
+		{
+			name: "username and uid without groups",
+			extraction: UserInfoClaimsExtraction{
+				UsernameExpression: `userinfo.login`,
+				UIDExpression:      `string(int(userinfo.id))`,
+			},
+			wantClaims: &ExtractedClaims{Username: "ryan", UID: "1234567890", Groups: []string{}},
+		},
+		{
+			name: "groups from the user info response",
+			extraction: UserInfoClaimsExtraction{
+				UsernameExpression: `userinfo.login + ":" + string(int(userinfo.id))`,
+				UIDExpression:      `string(int(userinfo.id))`,
+				GroupsExpression:   `userinfo.teams.map(t, t.name)`,
+			},
+			wantClaims:    &ExtractedClaims{Username: "ryan:1234567890", UID: "1234567890", Groups: []string{"admins", "developers"}},
+			wantHasGroups: true,
+		},
+		{
+			name: "groups from the groups response",
+			extraction: UserInfoClaimsExtraction{
+				UsernameExpression: `userinfo.email`,
+				UIDExpression:      `string(int(userinfo.id))`,
+				GroupsExpression:   `groupsResponse.groups.map(g, g.org + "/" + g.slug)`,
+			},
+			groupsResponseJSON: groupsResponseJSON,
+			wantClaims:         &ExtractedClaims{Username: "ryan@example.com", UID: "1234567890", Groups: []string{"a/admins", "b/devs"}},
+			wantHasGroups:      true,
+		},
+		{
+			name: "groups expression which returns a typed list of strings",
+			extraction: UserInfoClaimsExtraction{
+				UsernameExpression: `userinfo.profile.display_name.lowerAscii()`,
+				UIDExpression:      `"uid"`,
+				GroupsExpression:   `["a", "b"]`,
+			},
+			wantClaims:    &ExtractedClaims{Username: "ryan", UID: "uid", Groups: []string{"a", "b"}},
+			wantHasGroups: true,
+		},
+		{
+			name: "groups expression which checks for a missing groups response",
+			extraction: UserInfoClaimsExtraction{
+				UsernameExpression: `userinfo.login`,
+				UIDExpression:      `userinfo.login`,
+				GroupsExpression:   `groupsResponse == null ? [] : groupsResponse.groups.map(g, g.slug)`,
+			},
+			wantClaims:    &ExtractedClaims{Username: "ryan", UID: "ryan", Groups: []string{}},
+			wantHasGroups: true,
+		},
+		{
+			name: "empty username expression",
+			extraction: UserInfoClaimsExtraction{
+				UIDExpression: `userinfo.login`,
+			},
+			wantCompileErr: `username expression: cannot compile empty CEL expression`,
+		},
+		{
+			name: "empty uid expression",
+			extraction: UserInfoClaimsExtraction{
+				UsernameExpression: `userinfo.login`,
+			},
+			wantCompileErr: `uid expression: cannot compile empty CEL expression`,
+		},
+		{
+			name: "username expression with the wrong type",
+			extraction: UserInfoClaimsExtraction{
+				UsernameExpression: `1 + 2`,
+				UIDExpression:      `userinfo.login`,
+			},
+			wantCompileErr: `username expression: CEL expression should return type "string" but returns type "int"`,
+		},
+		{
+			name: "groups expression with the wrong type",
+			extraction: UserInfoClaimsExtraction{
+				UsernameExpression: `userinfo.login`,
+				UIDExpression:      `userinfo.login`,
+				GroupsExpression:   `"admins"`,
+			},
+			wantCompileErr: `groups expression: CEL expression should return type "list(string)" but returns type "string"`,
+		},
+		{
+			name: "expression which uses an unknown variable",
+			extraction: UserInfoClaimsExtraction{
+				UsernameExpression: `username`,
+				UIDExpression:      `userinfo.login`,
+			},
+			wantCompileErr: "username expression: CEL expression compile error: ERROR: <input>:1:1: undeclared reference to 'username' (in container '')\n" +
+				" | username\n" +
+				" | ^",
+		},
+		{
+			name: "username expression which evaluates to a number",
+			extraction: UserInfoClaimsExtraction{
+				UsernameExpression: `userinfo.id`,
+				UIDExpression:      `userinfo.login`,
+			},
+			wantEvaluationErr: `username expression: could not convert expression result to string: type conversion error from Double to 'string'`,
+		},
+		{
+			name: "uid expression which evaluates to an empty string",
+			extraction: UserInfoClaimsExtraction{
+				UsernameExpression: `userinfo.login`,
+				UIDExpression:      `userinfo.login.substring(0, 0)`,
+			},
+			wantEvaluationErr: `uid expression: expression result was an empty string`,
+		},
+		{
+			name: "expression which references a missing key",
+			extraction: UserInfoClaimsExtraction{
+				UsernameExpression: `userinfo.missing`,
+				UIDExpression:      `userinfo.login`,
+			},
+			wantEvaluationErr: `username expression: no such key: missing`,
+		},
+		{
+			name: "groups expression which evaluates to a list of non-strings",
+			extraction: UserInfoClaimsExtraction{
+				UsernameExpression: `userinfo.login`,
+				UIDExpression:      `userinfo.login`,
+				GroupsExpression:   `userinfo.teams`,
+			},
+			wantHasGroups:     true,
+			wantEvaluationErr: `groups expression: could not convert expression result to []string: type conversion error from map to 'string'`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			transformer, err := NewCELTransformer(100 * time.Millisecond)
+			require.NoError(t, err)
+
+			compiled, err := transformer.CompileUserInfoClaimsExtraction(&tt.extraction)
+			if tt.wantCompileErr != "" {
+				require.EqualError(t, err, tt.wantCompileErr)
+				return // the rest of the test doesn't make sense when there was a compile error
+			}
+			require.NoError(t, err, "got an unexpected compile error")
+			require.Equal(t, tt.wantHasGroups, compiled.HasGroupsExpression())
+
+			var userInfo map[string]any
+			require.NoError(t, json.Unmarshal([]byte(userInfoJSON), &userInfo))
+
+			var groupsResponse any
+			if tt.groupsResponseJSON != "" {
+				require.NoError(t, json.Unmarshal([]byte(tt.groupsResponseJSON), &groupsResponse))
+			}
+
+			claims, err := compiled.Evaluate(context.Background(), userInfo, groupsResponse)
+			if tt.wantEvaluationErr != "" {
+				require.EqualError(t, err, tt.wantEvaluationErr)
+				return // the rest of the test doesn't make sense when there was an evaluation error
+			}
+			require.NoError(t, err, "got an unexpected evaluation error")
+			require.Equal(t, tt.wantClaims, claims)
+		})
+	}
+}
@@ -0,0 +1,181 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package csrissuer implements a ClientCertIssuer which asks the Kubernetes API server to sign client
+// certificates by creating CertificateSigningRequests for the kubernetes.io/kube-apiserver-client signer.
+// Unlike the kube-cert-agent, this does not require reading the cluster's signing key from a control plane node.
+package csrissuer
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	certificatesv1 "k8s.io/api/certificates/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+
+	"go.pinniped.dev/internal/cert"
+	"go.pinniped.dev/internal/clientcertissuer"
+	"go.pinniped.dev/internal/plog"
+)
+
+const (
+	// generateName is the prefix of the names of the CertificateSigningRequests created by this issuer.
+	generateName = "pinniped-concierge-client-"
+
+	// approvalReason is the reason of the Approved condition which this issuer adds to its own requests.
+	approvalReason = "PinnipedConciergeApproved"
+
+	defaultPollInterval = 250 * time.Millisecond
+	defaultTimeout      = 30 * time.Second
+)
+
+// issuer is a type capable of issuing client certificates using the Kubernetes CSR API.
+type issuer struct {
+	client            kubernetes.Interface
+	expirationSeconds int32
+	rng               io.Reader
+	pollInterval      time.Duration
+	timeout           time.Duration
+}
+
+// New creates a ClientCertIssuer which requests client certificates from the kubernetes.io/kube-apiserver-client
+// signer, asking for them to expire after the given number of seconds. The client must be authorized to create,
+// get, and delete CertificateSigningRequests, and to approve requests for the kubernetes.io/kube-apiserver-client
+// signer.
+func New(client kubernetes.Interface, expirationSeconds int32) clientcertissuer.ClientCertIssuer {
+	return &issuer{
+		client:            client,
+		expirationSeconds: expirationSeconds,
+		rng:               rand.Reader,
+		pollInterval:      defaultPollInterval,
+		timeout:           defaultTimeout,
+	}
+}
+
+func (i *issuer) Name() string {
+	return "certificate signing request"
+}
+
+// IssueClientCertPEM requests a new client certificate for the given identity, returning it as a pair of
+// PEM-formatted byte slices for the certificate and private key, along with the notBefore and notAfter values.
+// The ttl is ignored, because the lifetime of the certificate is decided by the configured expirationSeconds
+// (which cannot be less than ten minutes) and by the signer.
+func (i *issuer) IssueClientCertPEM(username string, groups []string, extras []string, _ time.Duration) (*cert.PEM, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), i.timeout)
+	defer cancel()
+
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), i.rng)
+	if err != nil {
+		return nil, fmt.Errorf("could not generate private key: %w", err)
+	}
+
+	csrDER, err := x509.CreateCertificateRequest(i.rng, &x509.CertificateRequest{
+		Subject: pkix.Name{CommonName: username, Organization: groups, OrganizationalUnit: extras},
+	}, privateKey)
+	if err != nil {
+		return nil, fmt.Errorf("could not create certificate request: %w", err)
+	}
+
+	csr, err := i.client.CertificatesV1().CertificateSigningRequests().Create(ctx, &certificatesv1.CertificateSigningRequest{
+		ObjectMeta: metav1.ObjectMeta{GenerateName: generateName},
+		Spec: certificatesv1.CertificateSigningRequestSpec{
+			Request:           pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: csrDER}),
+			SignerName:        certificatesv1.KubeAPIServerClientSignerName,
+			ExpirationSeconds: &i.expirationSeconds,
+			Usages:            []certificatesv1.KeyUsage{certificatesv1.UsageDigitalSignature, certificatesv1.UsageClientAuth},
+		},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("could not create CertificateSigningRequest: %w", err)
+	}
+
+	// The request is only useful until its certificate has been read, so always clean it up. Kubernetes would
+	// eventually garbage collect it anyway, but there is no reason to leave copies of certificates lying around.
+	defer i.deleteCSR(csr)
+
+	csr.Status.Conditions = append(csr.Status.Conditions, certificatesv1.CertificateSigningRequestCondition{
+		Type:    certificatesv1.CertificateApproved,
+		Status:  corev1.ConditionTrue,
+		Reason:  approvalReason,
+		Message: "This CertificateSigningRequest was approved by the Pinniped Concierge for a TokenCredentialRequest.",
+	})
+	if _, err := i.client.CertificatesV1().CertificateSigningRequests().UpdateApproval(ctx, csr.Name, csr, metav1.UpdateOptions{}); err != nil {
+		return nil, fmt.Errorf("could not approve CertificateSigningRequest %q: %w", csr.Name, err)
+	}
+
+	certPEM, err := i.waitForCertificate(ctx, csr.Name)
+	if err != nil {
+		return nil, fmt.Errorf("CertificateSigningRequest %q was not signed: %w", csr.Name, err)
+	}
+
+	certBlock, _ := pem.Decode(certPEM)
+	if certBlock == nil || certBlock.Type != "CERTIFICATE" {
+		return nil, fmt.Errorf("CertificateSigningRequest %q has an invalid certificate", csr.Name)
+	}
+	leaf, err := x509.ParseCertificate(certBlock.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("CertificateSigningRequest %q has an invalid certificate: %w", csr.Name, err)
+	}
+
+	privateKeyPKCS8, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal private key into PKCS8: %w", err)
+	}
+
+	return &cert.PEM{
+		CertPEM:   certPEM,
+		KeyPEM:    pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privateKeyPKCS8}),
+		NotBefore: leaf.NotBefore,
+		NotAfter:  leaf.NotAfter,
+	}, nil
+}
+
+// waitForCertificate polls the CertificateSigningRequest until the signer has issued its certificate,
+// or until the signer has refused to issue it.
+func (i *issuer) waitForCertificate(ctx context.Context, name string) ([]byte, error) {
+	var certPEM []byte
+	err := wait.PollUntilContextCancel(ctx, i.pollInterval, true, func(ctx context.Context) (bool, error) {
+		csr, err := i.client.CertificatesV1().CertificateSigningRequests().Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+		for _, condition := range csr.Status.Conditions {
+			if condition.Type == certificatesv1.CertificateDenied || condition.Type == certificatesv1.CertificateFailed {
+				return false, fmt.Errorf("%s: %s: %s", condition.Type, condition.Reason, condition.Message)
+			}
+		}
+		if len(csr.Status.Certificate) == 0 {
+			return false, nil
+		}
+		certPEM = csr.Status.Certificate
+		return true, nil
+	})
+	if errors.Is(err, context.DeadlineExceeded) {
+		return nil, errors.New("timed out waiting for the signer to issue the certificate")
+	}
+	return certPEM, err
+}
+
+func (i *issuer) deleteCSR(csr *certificatesv1.CertificateSigningRequest) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	err := i.client.CertificatesV1().CertificateSigningRequests().Delete(ctx, csr.Name, metav1.DeleteOptions{
+		Preconditions: &metav1.Preconditions{UID: &csr.UID},
+	})
+	if err != nil {
+		plog.WarningErr("could not delete CertificateSigningRequest", err, "name", csr.Name)
+	}
+}
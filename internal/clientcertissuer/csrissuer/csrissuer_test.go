@@ -0,0 +1,217 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package csrissuer
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	certificatesv1 "k8s.io/api/certificates/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	coretesting "k8s.io/client-go/testing"
+	"k8s.io/utils/ptr"
+)
+
+func TestIssueClientCertPEM(t *testing.T) {
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test-signer"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	require.NoError(t, err)
+	caCert, err := x509.ParseCertificate(caDER)
+	require.NoError(t, err)
+
+	notBefore := time.Now().Add(-time.Minute).UTC().Truncate(time.Second)
+	notAfter := notBefore.Add(10 * time.Minute)
+
+	// sign acts like the kube-controller-manager's signer for the given CSR.
+	sign := func(t *testing.T, csr *certificatesv1.CertificateSigningRequest) []byte {
+		t.Helper()
+		block, _ := pem.Decode(csr.Spec.Request)
+		require.NotNil(t, block)
+		request, err := x509.ParseCertificateRequest(block.Bytes)
+		require.NoError(t, err)
+		der, err := x509.CreateCertificate(rand.Reader, &x509.Certificate{
+			SerialNumber: big.NewInt(2),
+			Subject:      request.Subject,
+			NotBefore:    notBefore,
+			NotAfter:     notAfter,
+			KeyUsage:     x509.KeyUsageDigitalSignature,
+			ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		}, caCert, request.PublicKey, caKey)
+		require.NoError(t, err)
+		return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	}
+
+	tests := []struct {
+		name string
+		// onApproval is called with the approved CSR, and may change it before it is stored, like a signer would.
+		onApproval func(t *testing.T, csr *certificatesv1.CertificateSigningRequest)
+		// reactors may make API calls fail.
+		reactors  func(client *kubefake.Clientset)
+		wantError string
+	}{
+		{
+			name: "happy path",
+			onApproval: func(t *testing.T, csr *certificatesv1.CertificateSigningRequest) {
+				csr.Status.Certificate = sign(t, csr)
+			},
+		},
+		{
+			name: "create fails",
+			reactors: func(client *kubefake.Clientset) {
+				client.PrependReactor("create", "certificatesigningrequests", func(_ coretesting.Action) (bool, runtime.Object, error) {
+					return true, nil, errors.New("some create error")
+				})
+			},
+			wantError: "could not create CertificateSigningRequest: some create error",
+		},
+		{
+			name: "approval fails",
+			reactors: func(client *kubefake.Clientset) {
+				client.PrependReactor("update", "certificatesigningrequests", func(_ coretesting.Action) (bool, runtime.Object, error) {
+					return true, nil, errors.New("some approval error")
+				})
+			},
+			wantError: `could not approve CertificateSigningRequest "pinniped-concierge-client-abcde": some approval error`,
+		},
+		{
+			name: "signer fails",
+			onApproval: func(_ *testing.T, csr *certificatesv1.CertificateSigningRequest) {
+				csr.Status.Conditions = append(csr.Status.Conditions, certificatesv1.CertificateSigningRequestCondition{
+					Type:    certificatesv1.CertificateFailed,
+					Status:  corev1.ConditionTrue,
+					Reason:  "SignerValidationFailure",
+					Message: "some signer error",
+				})
+			},
+			wantError: `CertificateSigningRequest "pinniped-concierge-client-abcde" was not signed: Failed: SignerValidationFailure: some signer error`,
+		},
+		{
+			name: "get fails",
+			onApproval: func(t *testing.T, csr *certificatesv1.CertificateSigningRequest) {
+				csr.Status.Certificate = sign(t, csr)
+			},
+			reactors: func(client *kubefake.Clientset) {
+				client.PrependReactor("get", "certificatesigningrequests", func(_ coretesting.Action) (bool, runtime.Object, error) {
+					return true, nil, errors.New("some get error")
+				})
+			},
+			wantError: `CertificateSigningRequest "pinniped-concierge-client-abcde" was not signed: some get error`,
+		},
+		{
+			name:      "never signed",
+			wantError: `CertificateSigningRequest "pinniped-concierge-client-abcde" was not signed: timed out waiting for the signer to issue the certificate`,
+		},
+		{
+			name: "signed with something that is not a certificate",
+			onApproval: func(_ *testing.T, csr *certificatesv1.CertificateSigningRequest) {
+				csr.Status.Certificate = []byte("this is not a certificate")
+			},
+			wantError: `CertificateSigningRequest "pinniped-concierge-client-abcde" has an invalid certificate`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := kubefake.NewClientset()
+
+			// The fake client does not implement generateName, so pretend to be the API server.
+			client.PrependReactor("create", "certificatesigningrequests", func(action coretesting.Action) (bool, runtime.Object, error) {
+				csr := action.(coretesting.CreateAction).GetObject().(*certificatesv1.CertificateSigningRequest)
+				require.Equal(t, "pinniped-concierge-client-", csr.GenerateName)
+				csr.Name = csr.GenerateName + "abcde"
+				csr.UID = types.UID("some-uid")
+				return false, nil, nil
+			})
+			client.PrependReactor("update", "certificatesigningrequests", func(action coretesting.Action) (bool, runtime.Object, error) {
+				require.Equal(t, "approval", action.GetSubresource())
+				csr := action.(coretesting.UpdateAction).GetObject().(*certificatesv1.CertificateSigningRequest)
+				if tt.onApproval != nil {
+					tt.onApproval(t, csr)
+				}
+				return false, nil, nil
+			})
+			if tt.reactors != nil {
+				tt.reactors(client)
+			}
+
+			subject := New(client, 1234).(*issuer)
+			subject.pollInterval = time.Millisecond
+			subject.timeout = time.Second
+
+			pemResult, err := subject.IssueClientCertPEM("some-user", []string{"group-1", "group-2"}, []string{"some-extra"}, 5*time.Minute)
+
+			// The CSR is always cleaned up after it was created.
+			csrs, listErr := client.CertificatesV1().CertificateSigningRequests().List(context.Background(), metav1.ListOptions{})
+			require.NoError(t, listErr)
+			require.Empty(t, csrs.Items)
+
+			if tt.wantError != "" {
+				require.EqualError(t, err, tt.wantError)
+				require.Nil(t, pemResult)
+				return
+			}
+			require.NoError(t, err)
+
+			// Check what was requested, including the approval.
+			var created, approved *certificatesv1.CertificateSigningRequest
+			for _, action := range client.Actions() {
+				switch {
+				case action.Matches("create", "certificatesigningrequests"):
+					created = action.(coretesting.CreateAction).GetObject().(*certificatesv1.CertificateSigningRequest)
+				case action.Matches("update", "certificatesigningrequests"):
+					approved = action.(coretesting.UpdateAction).GetObject().(*certificatesv1.CertificateSigningRequest)
+				}
+			}
+			require.NotNil(t, created)
+			require.Equal(t, "kubernetes.io/kube-apiserver-client", created.Spec.SignerName)
+			require.Equal(t, ptr.To[int32](1234), created.Spec.ExpirationSeconds)
+			require.Equal(t, []certificatesv1.KeyUsage{"digital signature", "client auth"}, created.Spec.Usages)
+			require.NotNil(t, approved)
+			require.Len(t, approved.Status.Conditions, 1)
+			require.Equal(t, certificatesv1.CertificateApproved, approved.Status.Conditions[0].Type)
+			require.Equal(t, corev1.ConditionTrue, approved.Status.Conditions[0].Status)
+			require.Equal(t, "PinnipedConciergeApproved", approved.Status.Conditions[0].Reason)
+
+			// Check the issued certificate.
+			require.Equal(t, notBefore, pemResult.NotBefore)
+			require.Equal(t, notAfter, pemResult.NotAfter)
+			keyPair, err := tls.X509KeyPair(pemResult.CertPEM, pemResult.KeyPEM)
+			require.NoError(t, err)
+			leaf, err := x509.ParseCertificate(keyPair.Certificate[0])
+			require.NoError(t, err)
+			require.Equal(t, "some-user", leaf.Subject.CommonName)
+			require.Equal(t, []string{"group-1", "group-2"}, leaf.Subject.Organization)
+			require.Equal(t, []string{"some-extra"}, leaf.Subject.OrganizationalUnit)
+			require.NoError(t, leaf.CheckSignatureFrom(caCert))
+		})
+	}
+}
+
+func TestName(t *testing.T) {
+	require.Equal(t, "certificate signing request", New(kubefake.NewClientset(), 600).Name())
+}
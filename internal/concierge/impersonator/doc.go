@@ -1,4 +1,4 @@
-// Copyright 2021 the Pinniped contributors. All Rights Reserved.
+// Copyright 2021-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 /*
@@ -19,7 +19,12 @@ In terms of authentication, we aim to handle every type of authentication that
 the Kubernetes API server supports by delegating most of the checks to it.  We
 also honor client certs from a CA that is specific to the impersonation proxy.
 This approach allows clients to use the Token Credential Request API even when
-we do not have the cluster's signing key.
+we do not have the cluster's signing key.  When the Kubernetes API server does
+not authenticate a request, its bearer token is also checked against each of the
+configured JWTAuthenticators.  This allows clients which cannot run the Pinniped
+exec plugin (e.g. a kubeconfig with a static OIDC ID token) to use the proxy
+directly.  WebhookAuthenticators are not used for this since they would receive
+every bearer token sent to the proxy.
 
 The proxy will honor cluster configuration in regards to anonymous authentication.
 When disabled, the proxy will not authenticate these requests. There is one caveat
@@ -46,12 +51,19 @@ only shares this information with the audit stack).  To keep things simple,
 we use the fake audit backend at the Metadata level for all requests.  This
 guarantees that we always have an audit event on every request.
 
-One final wrinkle is that impersonation cannot impersonate UIDs (yet).  This is
-problematic because service account tokens always assert a UID.  To handle this
-case without losing authentication information, when we see an identity with a
-UID that was asserted via a bearer token, we simply pass the request through
-with the original bearer token and no impersonation headers set (as if the user
-had made the request directly against the Kubernetes API server).
+One final wrinkle is that impersonation can only impersonate UIDs starting in
+Kubernetes 1.22.  This matters because service account tokens always assert a UID.
+We use discovery to check the Kubernetes API server's version the first time that
+we need to impersonate a UID.  When it is new enough, identities with UIDs are
+impersonated just like every other identity, so they all get the same audit and
+authorization treatment.  On older clusters (or when the version cannot be
+determined), we cannot impersonate the UID without losing authentication
+information.  To handle this case, when we see an identity with a UID that was
+asserted via a bearer token, we simply pass the request through with the original
+bearer token and no impersonation headers set (as if the user had made the request
+directly against the Kubernetes API server).  We only do this after the Kubernetes
+API server has reviewed the token, so tokens which are only valid for a
+JWTAuthenticator are never passed through.
 
 For all normal requests, we only use http/2.0 when proxying to the API server.
 For upgrade requests, we only use http/1.1 since these always go from http/1.1
@@ -47,8 +47,10 @@ import (
 	"k8s.io/client-go/transport"
 	utilversion "k8s.io/component-base/compatibility"
 	"k8s.io/streaming/pkg/httpstream"
+	"k8s.io/utils/clock"
 
 	"go.pinniped.dev/internal/constable"
+	"go.pinniped.dev/internal/controller/authenticator/authncache"
 	"go.pinniped.dev/internal/crypto/ptls"
 	"go.pinniped.dev/internal/dynamiccert"
 	"go.pinniped.dev/internal/httputil/securityheader"
@@ -69,6 +71,9 @@ type FactoryFunc func(
 	dynamicCertProvider dynamiccert.Private,
 	impersonationProxySignerCA dynamiccert.Public,
 	impersonationProxyTokenCache tokenclient.ExpiringSingletonTokenCacheGet,
+	authenticatorCache *authncache.Cache,
+	requestLimiter *RequestLimiter,
+	sessionRecorder *SessionRecorder,
 ) (func(ctx context.Context) error, error)
 
 func New(
@@ -76,8 +81,11 @@ func New(
 	dynamicCertProvider dynamiccert.Private,
 	impersonationProxySignerCA dynamiccert.Public,
 	impersonationProxyTokenCache tokenclient.ExpiringSingletonTokenCacheGet,
+	authenticatorCache *authncache.Cache,
+	requestLimiter *RequestLimiter,
+	sessionRecorder *SessionRecorder,
 ) (func(ctx context.Context) error, error) {
-	return newInternal(port, dynamicCertProvider, impersonationProxySignerCA, kubeclient.Secure, impersonationProxyTokenCache, nil, nil, nil)
+	return newInternal(port, dynamicCertProvider, impersonationProxySignerCA, kubeclient.Secure, impersonationProxyTokenCache, authenticatorCache, requestLimiter, sessionRecorder, nil, nil, nil)
 }
 
 var _ FactoryFunc = New
@@ -89,6 +97,9 @@ func newInternal(
 	impersonationProxySignerCA dynamiccert.Public,
 	restConfigFunc ptls.RestConfigFunc, // for unit testing, should always be kubeclient.Secure in production
 	cache tokenclient.ExpiringSingletonTokenCacheGet,
+	authenticatorCache *authncache.Cache,
+	requestLimiter *RequestLimiter,
+	sessionRecorder *SessionRecorder,
 	baseConfig *rest.Config, // for unit testing, should always be nil in production
 	recOpts func(*genericoptions.RecommendedOptions), // for unit testing, should always be nil in production
 	recConfig func(*genericapiserver.RecommendedConfig), // for unit testing, should always be nil in production
@@ -132,6 +143,7 @@ func newInternal(
 		// along with the Kube API server's CA.
 		// Note: any changes to the Authentication stack need to be kept in sync with any assumptions made
 		// by getTransportForUser, especially if we ever update the TCR API to start returning bearer tokens.
+		// Bearer tokens for JWTAuthenticators are added to the Authentication stack below.
 		kubeClientUnsafeForProxying, err := kubeclient.New(kubeclient.WithConfig(baseConfig))
 		if err != nil {
 			return nil, err
@@ -193,9 +205,23 @@ func newInternal(
 			return nil, fmt.Errorf("failed to build reverse proxy client: %w", err)
 		}
 
+		// This authenticator handles client certs and asks the Kubernetes API server to review bearer tokens.
+		// Bearer tokens which it authenticates are known to be valid against the Kubernetes API server,
+		// which is what the token passthrough logic of the reverse proxy needs to know.
+		kubeAPIServerAuthenticator := serverConfig.Authentication.Authenticator
+
+		// Older Kubernetes API servers cannot impersonate UIDs, so use discovery to check before trying.
+		uidImpersonation := newUIDImpersonationDetector(kubeClientUnsafeForProxying.Kubernetes.Discovery(), clock.RealClock{})
+
 		// Assume proto config is safe because transport level configs do not use rest.ContentConfig.
 		// Thus if we are interacting with actual APIs, they should be using pre-built clients.
-		impersonationProxyFunc, err := newImpersonationReverseProxyFunc(rest.CopyConfig(kubeClientForProxy.ProtoConfig))
+		impersonationProxyFunc, err := newImpersonationReverseProxyFunc(
+			rest.CopyConfig(kubeClientForProxy.ProtoConfig),
+			kubeAPIServerAuthenticator,
+			uidImpersonation,
+			requestLimiter,
+			sessionRecorder,
+		)
 		if err != nil {
 			return nil, err
 		}
@@ -243,10 +269,11 @@ func newInternal(
 		}
 		plog.Debug("anonymous authentication probed", "results", anonymousAuthProbeResult)
 
-		// if we ever start unioning a TCR bearer token authenticator with serverConfig.Authenticator
-		// then we will need to update the related assumption in tokenPassthroughRoundTripper
-
-		delegatingAuthenticator := serverConfig.Authentication.Authenticator
+		// When the Kubernetes API server does not authenticate a request, try the request's bearer token with the
+		// JWTAuthenticators. This lets clients use their JWTs directly with the impersonation proxy. Tokens which were
+		// only authenticated by a JWTAuthenticator are never passed through to the Kubernetes API server, because
+		// tokenPassthroughRoundTripper only trusts kubeAPIServerAuthenticator.
+		delegatingAuthenticator := withJWTAuthenticators(kubeAPIServerAuthenticator, authenticatorCache)
 		blockAnonymousAuthenticator := &comparableAuthenticator{
 			RequestFunc: func(req *http.Request) (*authenticator.Response, bool, error) {
 				resp, ok, err := delegatingAuthenticator.AuthenticateRequest(req)
@@ -539,7 +566,13 @@ type auditEventUserInfo struct {
 	ImpersonatedUser *authenticationv1.UserInfo
 }
 
-func newImpersonationReverseProxyFunc(restConfig *rest.Config) (func(*genericapiserver.Config) http.Handler, error) {
+func newImpersonationReverseProxyFunc(
+	restConfig *rest.Config,
+	tokenReviewAuthenticator authenticator.Request,
+	uidImpersonation *uidImpersonationDetector,
+	requestLimiter *RequestLimiter,
+	sessionRecorder *SessionRecorder,
+) (func(*genericapiserver.Config) http.Handler, error) {
 	serverURL, err := url.Parse(restConfig.Host)
 	if err != nil {
 		return nil, fmt.Errorf("could not parse host URL from in-cluster config: %w", err)
@@ -608,6 +641,19 @@ func newImpersonationReverseProxyFunc(restConfig *rest.Config) (func(*genericapi
 				ImpersonatedUser: ac.GetEventImpersonatedUser(),
 			}
 
+			// Limits apply to the user who made the request, even when they are impersonating someone else.
+			requester := &user.DefaultInfo{Name: ae.User.Username, Groups: ae.User.Groups}
+			requestInfo, _ := genericapirequest.RequestInfoFrom(r.Context())
+			longRunning := c.LongRunningFunc != nil && requestInfo != nil && c.LongRunningFunc(r, requestInfo)
+			release, throttled := requestLimiter.acquire(requester, longRunning)
+			if throttled != nil {
+				requestLimiter.auditThrottled(r, requester, requestInfo, throttled)
+				// This also sets the Retry-After header.
+				newStatusErrResponse(w, r, c.Serializer, apierrors.NewTooManyRequests(throttled.message(), throttled.retryAfterSeconds()))
+				return
+			}
+			defer release()
+
 			// grab the request's bearer token if present.  this is optional and does not fail the request if missing.
 			token := tokenFrom(r.Context())
 
@@ -619,7 +665,7 @@ func newImpersonationReverseProxyFunc(restConfig *rest.Config) (func(*genericapi
 				baseRT, baseRTAnonymous = http1RoundTripper, http1RoundTripperAnonymous
 			}
 
-			rt, err := getTransportForUser(r.Context(), userInfo, baseRT, baseRTAnonymous, ae, token, c.Authentication.Authenticator)
+			rt, err := getTransportForUser(r.Context(), userInfo, baseRT, baseRTAnonymous, ae, token, tokenReviewAuthenticator, uidImpersonation)
 			if err != nil {
 				plog.WarningErr("rejecting request as we cannot act as the current user", err,
 					"url", r.URL.String(),
@@ -630,6 +676,11 @@ func newImpersonationReverseProxyFunc(restConfig *rest.Config) (func(*genericapi
 				return
 			}
 
+			// Only upgrade requests can be exec or attach sessions. This is a no-op when recording is disabled.
+			if isUpgradeRequest {
+				rt = sessionRecorder.wrap(rt, r, requestInfo, ae)
+			}
+
 			plog.Debug("impersonation proxy servicing request",
 				"url", r.URL.String(),
 				"method", r.Method,
@@ -720,22 +771,29 @@ func ensureNoImpersonationHeaders(r *http.Request) error {
 	return nil
 }
 
-func getTransportForUser(ctx context.Context, userInfo user.Info, delegate, delegateAnonymous http.RoundTripper, ae *auditEventUserInfo, token string, authenticator authenticator.Request) (http.RoundTripper, error) {
-	if canImpersonateFully(userInfo) {
+func getTransportForUser(
+	ctx context.Context,
+	userInfo user.Info,
+	delegate, delegateAnonymous http.RoundTripper,
+	ae *auditEventUserInfo,
+	token string,
+	authenticator authenticator.Request,
+	uidImpersonation *uidImpersonationDetector,
+) (http.RoundTripper, error) {
+	if canImpersonateFully(userInfo, uidImpersonation) {
 		return standardImpersonationRoundTripper(userInfo, ae, delegate)
 	}
 
 	return tokenPassthroughRoundTripper(ctx, delegateAnonymous, ae, token, authenticator)
 }
 
-func canImpersonateFully(userInfo user.Info) bool {
+func canImpersonateFully(userInfo user.Info, uidImpersonation *uidImpersonationDetector) bool {
 	if len(userInfo.GetUID()) == 0 {
 		return true
 	}
 
-	// once kube supports UID impersonation, add logic to detect if the KAS is
-	// new enough to have this functionality and return true in that case as well
-	return false
+	// only ask the KAS about its version when we need to impersonate a UID
+	return uidImpersonation.supportsUIDImpersonation()
 }
 
 func standardImpersonationRoundTripper(userInfo user.Info, ae *auditEventUserInfo, delegate http.RoundTripper) (http.RoundTripper, error) {
@@ -746,6 +804,7 @@ func standardImpersonationRoundTripper(userInfo user.Info, ae *auditEventUserInf
 
 	impersonateConfig := transport.ImpersonationConfig{
 		UserName: userInfo.GetName(),
+		UID:      userInfo.GetUID(), // only set when canImpersonateFully has confirmed that the KAS supports it
 		Groups:   userInfo.GetGroups(),
 		Extra:    extra,
 	}
@@ -755,16 +814,19 @@ func standardImpersonationRoundTripper(userInfo user.Info, ae *auditEventUserInf
 }
 
 func tokenPassthroughRoundTripper(ctx context.Context, delegateAnonymous http.RoundTripper, ae *auditEventUserInfo, token string, authenticator authenticator.Request) (http.RoundTripper, error) {
-	// all code below assumes KAS does not support UID impersonation because that case is handled in the standard path
+	// all code below assumes KAS does not support UID impersonation (or that we could not tell if it does)
+	// because the case where it does is handled in the standard path
 
 	// it also assumes that the TCR API does not issue tokens - if this assumption changes, we will need
 	// some way to distinguish a token that is only valid against this impersonation proxy and not against KAS.
 	// this code will fail closed because said TCR token would not work against KAS and the request would fail.
+	// tokens which are only valid for a JWTAuthenticator are handled the same way since the given authenticator
+	// must only ever ask KAS to review the token.
 
 	// if we get here we know the final user info had a UID
 	// if the original user is also performing a nested impersonation, it means that said nested
 	// impersonation is trying to impersonate a UID since final user info == ae.ImpersonatedUser
-	// we know this KAS does not support UID impersonation so this request must be rejected
+	// we do not know that this KAS supports UID impersonation so this request must be rejected
 	if ae.ImpersonatedUser != nil {
 		return nil, constable.Error("unable to impersonate uid")
 	}
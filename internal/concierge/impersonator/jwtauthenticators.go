@@ -0,0 +1,98 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package impersonator
+
+import (
+	"context"
+	"net/http"
+
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apiserver/pkg/authentication/authenticator"
+	"k8s.io/apiserver/pkg/authentication/group"
+	"k8s.io/apiserver/pkg/authentication/request/bearertoken"
+	"k8s.io/apiserver/pkg/authentication/user"
+
+	authenticationv1alpha1 "go.pinniped.dev/generated/latest/apis/concierge/authentication/v1alpha1"
+	"go.pinniped.dev/internal/controller/authenticator/authncache"
+	"go.pinniped.dev/internal/plog"
+	"go.pinniped.dev/internal/valuelesscontext"
+)
+
+const jwtAuthenticatorKind = "JWTAuthenticator"
+
+// withJWTAuthenticators returns an authenticator which first tries the delegate. When the delegate does not
+// authenticate the request as a real user, the request's bearer token is given to the JWTAuthenticators.
+// This allows clients to use the impersonation proxy directly with their JWTs (e.g. an OIDC ID token from a
+// plain kubeconfig), without first calling the Token Credential Request API.
+//
+// The delegate always wins when it authenticates the request, so client certs keep their precedence over
+// bearer tokens and tokens which the Kubernetes API server understands keep their current meaning. When the
+// JWTAuthenticators also fail, the delegate's result is returned, so anonymous requests and bad credentials
+// are handled exactly as they would be without this authenticator.
+func withJWTAuthenticators(delegate authenticator.Request, cache *authncache.Cache) authenticator.Request {
+	jwtAuthenticator := group.NewAuthenticatedGroupAdder(
+		bearertoken.New(&jwtAuthenticatorsTokenAuthenticator{cache: cache}),
+	)
+
+	return authenticator.RequestFunc(func(req *http.Request) (*authenticator.Response, bool, error) {
+		resp, ok, err := delegate.AuthenticateRequest(req)
+		if err == nil && ok && resp.User.GetName() != user.Anonymous {
+			return resp, ok, err
+		}
+
+		jwtResp, jwtOK, jwtErr := jwtAuthenticator.AuthenticateRequest(req)
+		if jwtErr == nil && jwtOK {
+			return jwtResp, true, nil
+		}
+		if jwtErr != nil {
+			plog.DebugErr("bearer token was not authenticated by any JWTAuthenticator", jwtErr)
+		}
+
+		return resp, ok, err
+	})
+}
+
+// jwtAuthenticatorsTokenAuthenticator authenticates bearer tokens using every JWTAuthenticator in the cache.
+//
+// WebhookAuthenticators are intentionally not used here. Unlike a JWTAuthenticator, which validates the token
+// locally, a WebhookAuthenticator would send every bearer token presented to the impersonation proxy (including
+// service account tokens meant for the Kubernetes API server) to an external webhook.
+type jwtAuthenticatorsTokenAuthenticator struct {
+	cache *authncache.Cache
+}
+
+var _ authenticator.Token = (*jwtAuthenticatorsTokenAuthenticator)(nil)
+
+// AuthenticateToken tries each JWTAuthenticator in a consistent order and returns the first successful result.
+// Errors are only returned when no JWTAuthenticator was able to authenticate the token.
+func (a *jwtAuthenticatorsTokenAuthenticator) AuthenticateToken(ctx context.Context, token string) (*authenticator.Response, bool, error) {
+	if a.cache == nil {
+		return nil, false, nil
+	}
+
+	// The incoming context could have an audience. JWTAuthenticators validate their own audience,
+	// so do not pass it through, just like for the Token Credential Request API.
+	ctx = valuelesscontext.New(ctx)
+
+	var errs []error
+	for _, key := range a.cache.Keys() {
+		if key.APIGroup != authenticationv1alpha1.SchemeGroupVersion.Group || key.Kind != jwtAuthenticatorKind {
+			continue
+		}
+		val := a.cache.Get(key)
+		if val == nil {
+			continue // it was deleted after the keys were listed
+		}
+		resp, ok, err := val.AuthenticateToken(ctx, token)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if ok && resp != nil {
+			return resp, true, nil
+		}
+	}
+
+	return nil, false, utilerrors.NewAggregate(errs)
+}
@@ -0,0 +1,167 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package impersonator
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"k8s.io/apiserver/pkg/authentication/authenticator"
+	"k8s.io/apiserver/pkg/authentication/user"
+
+	"go.pinniped.dev/internal/controller/authenticator/authncache"
+	"go.pinniped.dev/internal/mocks/mockcachevalue"
+)
+
+func TestWithJWTAuthenticators(t *testing.T) {
+	jwtKey := func(name string) authncache.Key {
+		return authncache.Key{APIGroup: "authentication.concierge.pinniped.dev", Kind: "JWTAuthenticator", Name: name}
+	}
+	webhookKey := authncache.Key{APIGroup: "authentication.concierge.pinniped.dev", Kind: "WebhookAuthenticator", Name: "some-webhook"}
+	otherGroupKey := authncache.Key{APIGroup: "authentication.concierge.walrus.tld", Kind: "JWTAuthenticator", Name: "some-jwt"}
+
+	kasUser := &user.DefaultInfo{Name: "kas-user", Groups: []string{"kas-group", "system:authenticated"}}
+	anonymousUser := &user.DefaultInfo{Name: "system:anonymous", Groups: []string{"system:unauthenticated"}}
+
+	tests := []struct {
+		name                    string
+		token                   string
+		delegate                authenticator.RequestFunc
+		setupCache              func(t *testing.T, cache *authncache.Cache)
+		wantUser                user.Info
+		wantOK                  bool
+		wantErr                 string
+		wantAuthorizationHeader bool
+		nilCache                bool
+	}{
+		{
+			name:  "delegate authenticates a real user, so JWTAuthenticators are not called",
+			token: "some-token",
+			delegate: func(_ *http.Request) (*authenticator.Response, bool, error) {
+				return &authenticator.Response{User: kasUser}, true, nil
+			},
+			setupCache: func(t *testing.T, cache *authncache.Cache) {
+				cache.Store(jwtKey("a"), mockcachevalue.NewMockValue(gomock.NewController(t)))
+			},
+			wantUser:                kasUser,
+			wantOK:                  true,
+			wantAuthorizationHeader: true, // the fake delegate does not remove it
+		},
+		{
+			name:  "delegate returns anonymous and a JWTAuthenticator authenticates the token",
+			token: "some-jwt",
+			delegate: func(_ *http.Request) (*authenticator.Response, bool, error) {
+				return &authenticator.Response{User: anonymousUser}, true, nil
+			},
+			setupCache: func(t *testing.T, cache *authncache.Cache) {
+				// The WebhookAuthenticator and the JWTAuthenticator from another API group must never be called.
+				cache.Store(webhookKey, mockcachevalue.NewMockValue(gomock.NewController(t)))
+				cache.Store(otherGroupKey, mockcachevalue.NewMockValue(gomock.NewController(t)))
+				first := mockcachevalue.NewMockValue(gomock.NewController(t))
+				first.EXPECT().AuthenticateToken(gomock.Any(), "some-jwt").Return(nil, false, nil)
+				cache.Store(jwtKey("a"), first)
+				second := mockcachevalue.NewMockValue(gomock.NewController(t))
+				second.EXPECT().AuthenticateToken(gomock.Any(), "some-jwt").
+					Return(&authenticator.Response{User: &user.DefaultInfo{Name: "jwt-user", Groups: []string{"jwt-group"}}}, true, nil)
+				cache.Store(jwtKey("b"), second)
+				cache.Store(jwtKey("c"), mockcachevalue.NewMockValue(gomock.NewController(t)))
+			},
+			wantUser: &user.DefaultInfo{Name: "jwt-user", Groups: []string{"jwt-group", "system:authenticated"}},
+			wantOK:   true,
+		},
+		{
+			name:  "delegate fails and a JWTAuthenticator authenticates the token after another one errors",
+			token: "some-jwt",
+			delegate: func(_ *http.Request) (*authenticator.Response, bool, error) {
+				return nil, false, errors.New("invalid bearer token")
+			},
+			setupCache: func(t *testing.T, cache *authncache.Cache) {
+				first := mockcachevalue.NewMockValue(gomock.NewController(t))
+				first.EXPECT().AuthenticateToken(gomock.Any(), "some-jwt").Return(nil, false, errors.New("some JWT error"))
+				cache.Store(jwtKey("a"), first)
+				second := mockcachevalue.NewMockValue(gomock.NewController(t))
+				second.EXPECT().AuthenticateToken(gomock.Any(), "some-jwt").
+					Return(&authenticator.Response{User: &user.DefaultInfo{Name: "jwt-user"}}, true, nil)
+				cache.Store(jwtKey("b"), second)
+			},
+			wantUser: &user.DefaultInfo{Name: "jwt-user", Groups: []string{"system:authenticated"}},
+			wantOK:   true,
+		},
+		{
+			name:  "delegate fails and no JWTAuthenticator authenticates the token, so the delegate's error is returned",
+			token: "some-jwt",
+			delegate: func(_ *http.Request) (*authenticator.Response, bool, error) {
+				return nil, false, errors.New("invalid bearer token")
+			},
+			setupCache: func(t *testing.T, cache *authncache.Cache) {
+				first := mockcachevalue.NewMockValue(gomock.NewController(t))
+				first.EXPECT().AuthenticateToken(gomock.Any(), "some-jwt").Return(nil, false, errors.New("some JWT error"))
+				cache.Store(jwtKey("a"), first)
+			},
+			wantErr:                 "invalid bearer token",
+			wantAuthorizationHeader: true,
+		},
+		{
+			name:  "delegate returns anonymous and there are no JWTAuthenticators, so the request is anonymous",
+			token: "some-jwt",
+			delegate: func(_ *http.Request) (*authenticator.Response, bool, error) {
+				return &authenticator.Response{User: anonymousUser}, true, nil
+			},
+			wantUser:                anonymousUser,
+			wantOK:                  true,
+			wantAuthorizationHeader: true,
+		},
+		{
+			name: "no bearer token",
+			delegate: func(_ *http.Request) (*authenticator.Response, bool, error) {
+				return nil, false, nil
+			},
+			setupCache: func(t *testing.T, cache *authncache.Cache) {
+				cache.Store(jwtKey("a"), mockcachevalue.NewMockValue(gomock.NewController(t)))
+			},
+		},
+		{
+			name:     "nil cache",
+			token:    "some-jwt",
+			nilCache: true,
+			delegate: func(_ *http.Request) (*authenticator.Response, bool, error) {
+				return nil, false, nil
+			},
+			wantAuthorizationHeader: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var cache *authncache.Cache
+			if !tt.nilCache {
+				cache = authncache.New()
+			}
+			if tt.setupCache != nil {
+				tt.setupCache(t, cache)
+			}
+
+			req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, "https://pinniped.dev/some/path", nil)
+			require.NoError(t, err)
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+
+			resp, ok, err := withJWTAuthenticators(tt.delegate, cache).AuthenticateRequest(req)
+			if tt.wantErr != "" {
+				require.EqualError(t, err, tt.wantErr)
+			} else {
+				require.NoError(t, err)
+			}
+			require.Equal(t, tt.wantOK, ok)
+			if tt.wantUser != nil {
+				require.Equal(t, tt.wantUser, resp.User)
+			}
+			require.Equal(t, tt.wantAuthorizationHeader, req.Header.Get("Authorization") != "")
+		})
+	}
+}
@@ -0,0 +1,262 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package impersonator
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apiserver/pkg/authentication/user"
+	genericapirequest "k8s.io/apiserver/pkg/endpoints/request"
+	"k8s.io/utils/clock"
+
+	conciergeconfigv1alpha1 "go.pinniped.dev/generated/latest/apis/concierge/config/v1alpha1"
+	"go.pinniped.dev/internal/auditevent"
+	"go.pinniped.dev/internal/plog"
+)
+
+// pruneInterval is how often the RequestLimiter forgets about users and groups which are not currently limited.
+const pruneInterval = time.Minute
+
+// RequestLimiter limits the requests which the impersonation proxy serves for each user and for each group.
+// Its limits may be changed at any time using SetLimits, e.g. when the CredentialIssuer is updated.
+// The zero value is not usable, so use NewRequestLimiter. A nil *RequestLimiter does not limit any requests.
+type RequestLimiter struct {
+	auditLogger plog.AuditLogger
+	clock       clock.PassiveClock
+
+	lock      sync.Mutex
+	limits    *conciergeconfigv1alpha1.ImpersonationProxyRateLimitsSpec
+	users     map[string]*limitState
+	groups    map[string]*limitState
+	lastPrune time.Time
+}
+
+// limitState tracks the requests of a single user or group.
+type limitState struct {
+	limiter  *rate.Limiter // nil when there is no QPS limit
+	inFlight int32
+}
+
+// requestThrottled describes why a request was not allowed.
+type requestThrottled struct {
+	limitedBy  string // "perUser" or "perGroup"
+	group      string // only set when limitedBy is "perGroup"
+	limit      string // "qps" or "maxInFlightLongRunningRequests"
+	retryAfter time.Duration
+}
+
+// NewRequestLimiter returns a RequestLimiter which does not limit any requests until SetLimits is called.
+func NewRequestLimiter(auditLogger plog.AuditLogger, clock clock.PassiveClock) *RequestLimiter {
+	return &RequestLimiter{
+		auditLogger: auditLogger,
+		clock:       clock,
+	}
+}
+
+// SetLimits replaces the current limits. When the limits have changed, all users and groups start over with
+// their new limits. Requests which are already in flight do not count against the new limits. A nil value
+// removes all limits.
+func (l *RequestLimiter) SetLimits(limits *conciergeconfigv1alpha1.ImpersonationProxyRateLimitsSpec) {
+	if l == nil {
+		return
+	}
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if equality.Semantic.DeepEqual(l.limits, limits) {
+		return
+	}
+
+	l.limits = limits.DeepCopy()
+	l.users = map[string]*limitState{}
+	l.groups = map[string]*limitState{}
+}
+
+// acquire decides whether a request from the given user may be served. When it may be served, the returned
+// release func must be called after the request has finished. Otherwise, the reason is returned.
+func (l *RequestLimiter) acquire(userInfo user.Info, longRunning bool) (func(), *requestThrottled) {
+	noop := func() {}
+	if l == nil || userInfo == nil {
+		return noop, nil
+	}
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if l.limits == nil {
+		return noop, nil
+	}
+
+	now := l.clock.Now()
+	l.maybePrune(now)
+
+	type applicableLimit struct {
+		state *limitState
+		limit *conciergeconfigv1alpha1.ImpersonationProxyRateLimit
+		throttled
+	}
+	var applicable []applicableLimit
+
+	// Anonymous requests are not limited per user, because then all anonymous clients (e.g. everyone who is
+	// calling the TokenCredentialRequest API to log in) would share the same limits.
+	if isActive(l.limits.PerUser) && userInfo.GetName() != user.Anonymous {
+		applicable = append(applicable, applicableLimit{
+			state:     getOrCreateState(l.users, userInfo.GetName(), l.limits.PerUser),
+			limit:     l.limits.PerUser,
+			throttled: throttled{limitedBy: "perUser"},
+		})
+	}
+	if isActive(l.limits.PerGroup) {
+		seen := map[string]bool{}
+		for _, group := range userInfo.GetGroups() {
+			if group == user.AllAuthenticated || group == user.AllUnauthenticated || seen[group] {
+				continue
+			}
+			seen[group] = true
+			applicable = append(applicable, applicableLimit{
+				state:     getOrCreateState(l.groups, group, l.limits.PerGroup),
+				limit:     l.limits.PerGroup,
+				throttled: throttled{limitedBy: "perGroup", group: group},
+			})
+		}
+	}
+
+	// Check every limit before changing any state, so a rejected request does not count against any limit.
+	for _, a := range applicable {
+		if longRunning && a.limit.MaxInFlightLongRunningRequests > 0 && a.state.inFlight >= a.limit.MaxInFlightLongRunningRequests {
+			// There is no way to know when another long-running request will finish, so suggest a short wait.
+			return nil, a.throttled.because("maxInFlightLongRunningRequests", time.Second)
+		}
+		if a.state.limiter != nil {
+			if tokens := a.state.limiter.TokensAt(now); tokens < 1 {
+				wait := time.Duration((1 - tokens) / float64(a.state.limiter.Limit()) * float64(time.Second))
+				return nil, a.throttled.because("qps", wait)
+			}
+		}
+	}
+
+	var inFlight []*limitState
+	for _, a := range applicable {
+		if a.state.limiter != nil {
+			a.state.limiter.AllowN(now, 1)
+		}
+		if longRunning && a.limit.MaxInFlightLongRunningRequests > 0 {
+			a.state.inFlight++
+			inFlight = append(inFlight, a.state)
+		}
+	}
+
+	if len(inFlight) == 0 {
+		return noop, nil
+	}
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			l.lock.Lock()
+			defer l.lock.Unlock()
+			for _, state := range inFlight {
+				state.inFlight--
+			}
+		})
+	}, nil
+}
+
+// auditThrottled records a throttled request in the audit logs.
+func (l *RequestLimiter) auditThrottled(r *http.Request, requester user.Info, requestInfo *genericapirequest.RequestInfo, t *requestThrottled) {
+	if l.auditLogger == nil {
+		return
+	}
+
+	piiKeysAndValues := []any{
+		"username", requester.GetName(),
+		"groups", requester.GetGroups(),
+	}
+	if t.group != "" {
+		piiKeysAndValues = append(piiKeysAndValues, "limitedGroup", t.group)
+	}
+
+	var verb string
+	if requestInfo != nil {
+		verb = requestInfo.Verb
+	}
+
+	l.auditLogger.Audit(auditevent.ImpersonationProxyRequestThrottled, &plog.AuditParams{
+		ReqCtx:           r.Context(),
+		PIIKeysAndValues: piiKeysAndValues,
+		KeysAndValues: []any{
+			"verb", verb,
+			"path", r.URL.Path,
+			"limitedBy", t.limitedBy,
+			"limit", t.limit,
+			"retryAfterSeconds", t.retryAfterSeconds(),
+		},
+	})
+}
+
+// maybePrune forgets the users and groups which are not currently limited, so the maps do not grow forever.
+// A forgotten user or group will start over with a full burst of requests, which is what it would have anyway.
+// The caller must hold the lock.
+func (l *RequestLimiter) maybePrune(now time.Time) {
+	if now.Sub(l.lastPrune) < pruneInterval {
+		return
+	}
+	l.lastPrune = now
+
+	for _, states := range []map[string]*limitState{l.users, l.groups} {
+		for name, state := range states {
+			if state.inFlight > 0 {
+				continue
+			}
+			if state.limiter != nil && state.limiter.TokensAt(now) < float64(state.limiter.Burst()) {
+				continue
+			}
+			delete(states, name)
+		}
+	}
+}
+
+type throttled struct {
+	limitedBy string
+	group     string
+}
+
+func (t throttled) because(limit string, retryAfter time.Duration) *requestThrottled {
+	return &requestThrottled{limitedBy: t.limitedBy, group: t.group, limit: limit, retryAfter: retryAfter}
+}
+
+// retryAfterSeconds rounds up to whole seconds, since that is what the Retry-After header supports.
+func (t *requestThrottled) retryAfterSeconds() int {
+	return max(1, int(math.Ceil(t.retryAfter.Seconds())))
+}
+
+func (t *requestThrottled) message() string {
+	return fmt.Sprintf("the impersonation proxy %s %s limit was exceeded, please try again later", t.limitedBy, t.limit)
+}
+
+func isActive(limit *conciergeconfigv1alpha1.ImpersonationProxyRateLimit) bool {
+	return limit != nil && (limit.QPS > 0 || limit.MaxInFlightLongRunningRequests > 0)
+}
+
+func getOrCreateState(states map[string]*limitState, name string, limit *conciergeconfigv1alpha1.ImpersonationProxyRateLimit) *limitState {
+	if state, ok := states[name]; ok {
+		return state
+	}
+	state := &limitState{}
+	if limit.QPS > 0 {
+		burst := limit.Burst
+		if burst == 0 {
+			burst = limit.QPS
+		}
+		state.limiter = rate.NewLimiter(rate.Limit(limit.QPS), int(burst))
+	}
+	states[name] = state
+	return state
+}
@@ -0,0 +1,280 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package impersonator
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/apiserver/pkg/endpoints/request"
+	clocktesting "k8s.io/utils/clock/testing"
+
+	conciergeconfigv1alpha1 "go.pinniped.dev/generated/latest/apis/concierge/config/v1alpha1"
+	"go.pinniped.dev/internal/plog"
+	"go.pinniped.dev/internal/testutil"
+)
+
+func TestRequestLimiter(t *testing.T) {
+	alice := &user.DefaultInfo{Name: "alice", Groups: []string{"devs", "system:authenticated"}}
+	bob := &user.DefaultInfo{Name: "bob", Groups: []string{"devs", "devs", "system:authenticated"}}
+	carol := &user.DefaultInfo{Name: "carol", Groups: []string{"ops", "system:authenticated"}}
+	anonymous := &user.DefaultInfo{Name: "system:anonymous", Groups: []string{"system:unauthenticated"}}
+
+	type step struct {
+		user          user.Info
+		longRunning   bool
+		advanceClock  time.Duration
+		setLimits     *conciergeconfigv1alpha1.ImpersonationProxyRateLimitsSpec
+		clearLimits   bool
+		releasePrev   bool // call the release func returned by the most recent allowed request
+		wantThrottled *requestThrottled
+	}
+
+	perUserQPS := &conciergeconfigv1alpha1.ImpersonationProxyRateLimitsSpec{
+		PerUser: &conciergeconfigv1alpha1.ImpersonationProxyRateLimit{QPS: 2, Burst: 3},
+	}
+	perGroupQPS := &conciergeconfigv1alpha1.ImpersonationProxyRateLimitsSpec{
+		PerGroup: &conciergeconfigv1alpha1.ImpersonationProxyRateLimit{QPS: 1},
+	}
+	perUserInFlight := &conciergeconfigv1alpha1.ImpersonationProxyRateLimitsSpec{
+		PerUser: &conciergeconfigv1alpha1.ImpersonationProxyRateLimit{MaxInFlightLongRunningRequests: 1},
+	}
+
+	tests := []struct {
+		name   string
+		limits *conciergeconfigv1alpha1.ImpersonationProxyRateLimitsSpec
+		steps  []step
+	}{
+		{
+			name: "no limits",
+			steps: []step{
+				{user: alice}, {user: alice}, {user: alice, longRunning: true}, {user: alice, longRunning: true},
+			},
+		},
+		{
+			name:   "empty limits",
+			limits: &conciergeconfigv1alpha1.ImpersonationProxyRateLimitsSpec{PerUser: &conciergeconfigv1alpha1.ImpersonationProxyRateLimit{Burst: 1}},
+			steps: []step{
+				{user: alice}, {user: alice}, {user: alice, longRunning: true}, {user: alice, longRunning: true},
+			},
+		},
+		{
+			name:   "per user QPS allows a burst and then throttles until tokens are refilled",
+			limits: perUserQPS,
+			steps: []step{
+				{user: alice}, {user: alice}, {user: alice},
+				{user: alice, wantThrottled: &requestThrottled{limitedBy: "perUser", limit: "qps", retryAfter: 500 * time.Millisecond}},
+				{user: bob}, // other users are not affected
+				{user: alice, advanceClock: 250 * time.Millisecond, wantThrottled: &requestThrottled{limitedBy: "perUser", limit: "qps", retryAfter: 250 * time.Millisecond}},
+				{user: alice, advanceClock: 250 * time.Millisecond},
+				{user: alice, wantThrottled: &requestThrottled{limitedBy: "perUser", limit: "qps", retryAfter: 500 * time.Millisecond}},
+			},
+		},
+		{
+			name:   "per user limits do not apply to anonymous requests",
+			limits: perUserQPS,
+			steps: []step{
+				{user: anonymous}, {user: anonymous}, {user: anonymous}, {user: anonymous}, {user: anonymous},
+			},
+		},
+		{
+			name:   "per group QPS is shared by the members of the group and ignores system groups",
+			limits: perGroupQPS,
+			steps: []step{
+				{user: alice},
+				{user: bob, wantThrottled: &requestThrottled{limitedBy: "perGroup", group: "devs", limit: "qps", retryAfter: time.Second}},
+				{user: carol},
+				{user: anonymous},
+				{user: anonymous},
+				{user: bob, advanceClock: time.Second},
+			},
+		},
+		{
+			name: "a throttled request does not count against the other limits",
+			limits: &conciergeconfigv1alpha1.ImpersonationProxyRateLimitsSpec{
+				PerUser:  &conciergeconfigv1alpha1.ImpersonationProxyRateLimit{QPS: 1},
+				PerGroup: &conciergeconfigv1alpha1.ImpersonationProxyRateLimit{QPS: 2, Burst: 1},
+			},
+			steps: []step{
+				{user: alice},
+				{user: alice, wantThrottled: &requestThrottled{limitedBy: "perUser", limit: "qps", retryAfter: time.Second}},
+				{user: &user.DefaultInfo{Name: "dave", Groups: []string{"ops"}}},
+				{user: carol, wantThrottled: &requestThrottled{limitedBy: "perGroup", group: "ops", limit: "qps", retryAfter: 500 * time.Millisecond}},
+				// this would be throttled by carol's per user limit if the previous request had used it up
+				{user: carol, advanceClock: 500 * time.Millisecond},
+			},
+		},
+		{
+			name:   "max in-flight long-running requests",
+			limits: perUserInFlight,
+			steps: []step{
+				{user: alice, longRunning: true},
+				{user: alice, longRunning: true, wantThrottled: &requestThrottled{limitedBy: "perUser", limit: "maxInFlightLongRunningRequests", retryAfter: time.Second}},
+				{user: alice}, // short requests are not limited
+				{user: bob, longRunning: true},
+				{user: alice, longRunning: true, releasePrev: true, wantThrottled: &requestThrottled{limitedBy: "perUser", limit: "maxInFlightLongRunningRequests", retryAfter: time.Second}},
+			},
+		},
+		{
+			name:   "releasing a long-running request allows another one",
+			limits: perUserInFlight,
+			steps: []step{
+				{user: alice, longRunning: true},
+				{user: alice, longRunning: true, releasePrev: true},
+				{user: alice, longRunning: true, wantThrottled: &requestThrottled{limitedBy: "perUser", limit: "maxInFlightLongRunningRequests", retryAfter: time.Second}},
+			},
+		},
+		{
+			name:   "setting the same limits again keeps the current state",
+			limits: perGroupQPS,
+			steps: []step{
+				{user: alice},
+				{user: alice, setLimits: perGroupQPS.DeepCopy(), wantThrottled: &requestThrottled{limitedBy: "perGroup", group: "devs", limit: "qps", retryAfter: time.Second}},
+			},
+		},
+		{
+			name:   "changing the limits starts over",
+			limits: perGroupQPS,
+			steps: []step{
+				{user: alice},
+				{user: alice, setLimits: &conciergeconfigv1alpha1.ImpersonationProxyRateLimitsSpec{
+					PerGroup: &conciergeconfigv1alpha1.ImpersonationProxyRateLimit{QPS: 1, Burst: 2},
+				}},
+				{user: alice},
+				{user: alice, wantThrottled: &requestThrottled{limitedBy: "perGroup", group: "devs", limit: "qps", retryAfter: time.Second}},
+				{user: alice, clearLimits: true},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fakeClock := clocktesting.NewFakeClock(time.Now())
+			subject := NewRequestLimiter(nil, fakeClock)
+			subject.SetLimits(tt.limits)
+
+			var prevRelease func()
+			for i, s := range tt.steps {
+				if s.advanceClock > 0 {
+					fakeClock.Step(s.advanceClock)
+				}
+				if s.setLimits != nil || s.clearLimits {
+					subject.SetLimits(s.setLimits)
+				}
+				if s.releasePrev {
+					require.NotNil(t, prevRelease)
+					prevRelease()
+					prevRelease() // calling it again must have no effect
+				}
+
+				release, throttled := subject.acquire(s.user, s.longRunning)
+				require.Equal(t, s.wantThrottled, throttled, "step %d", i)
+				if throttled == nil {
+					require.NotNil(t, release, "step %d", i)
+					prevRelease = release
+				} else {
+					require.Nil(t, release, "step %d", i)
+				}
+			}
+		})
+	}
+}
+
+func TestRequestLimiterNil(t *testing.T) {
+	var subject *RequestLimiter
+	subject.SetLimits(&conciergeconfigv1alpha1.ImpersonationProxyRateLimitsSpec{
+		PerUser: &conciergeconfigv1alpha1.ImpersonationProxyRateLimit{QPS: 1},
+	})
+	for range 3 {
+		release, throttled := subject.acquire(&user.DefaultInfo{Name: "alice"}, true)
+		require.Nil(t, throttled)
+		release()
+	}
+}
+
+func TestRequestLimiterPrunesIdleState(t *testing.T) {
+	fakeClock := clocktesting.NewFakeClock(time.Now())
+	subject := NewRequestLimiter(nil, fakeClock)
+	subject.SetLimits(&conciergeconfigv1alpha1.ImpersonationProxyRateLimitsSpec{
+		PerUser: &conciergeconfigv1alpha1.ImpersonationProxyRateLimit{QPS: 1, MaxInFlightLongRunningRequests: 1},
+	})
+
+	_, throttled := subject.acquire(&user.DefaultInfo{Name: "alice"}, false)
+	require.Nil(t, throttled)
+	releaseBob, throttled := subject.acquire(&user.DefaultInfo{Name: "bob"}, true)
+	require.Nil(t, throttled)
+	require.Len(t, subject.users, 2)
+
+	// Alice's tokens have been refilled, but bob still has a request in flight.
+	fakeClock.Step(pruneInterval)
+	_, throttled = subject.acquire(&user.DefaultInfo{Name: "carol"}, false)
+	require.Nil(t, throttled)
+	require.Len(t, subject.users, 2)
+	require.Contains(t, subject.users, "bob")
+	require.Contains(t, subject.users, "carol")
+
+	releaseBob()
+	fakeClock.Step(pruneInterval)
+	_, throttled = subject.acquire(&user.DefaultInfo{Name: "dave"}, false)
+	require.Nil(t, throttled)
+	require.Len(t, subject.users, 1)
+	require.Contains(t, subject.users, "dave")
+}
+
+func TestRequestThrottled(t *testing.T) {
+	require.Equal(t, 1, (&requestThrottled{retryAfter: 0}).retryAfterSeconds())
+	require.Equal(t, 1, (&requestThrottled{retryAfter: 100 * time.Millisecond}).retryAfterSeconds())
+	require.Equal(t, 1, (&requestThrottled{retryAfter: time.Second}).retryAfterSeconds())
+	require.Equal(t, 3, (&requestThrottled{retryAfter: 2*time.Second + time.Nanosecond}).retryAfterSeconds())
+
+	require.Equal(t,
+		"the impersonation proxy perGroup maxInFlightLongRunningRequests limit was exceeded, please try again later",
+		(&requestThrottled{limitedBy: "perGroup", limit: "maxInFlightLongRunningRequests"}).message(),
+	)
+}
+
+func TestRequestLimiterAuditThrottled(t *testing.T) {
+	auditLogger, actualAuditLog := plog.TestAuditLogger(t)
+	subject := NewRequestLimiter(auditLogger, clocktesting.NewFakeClock(time.Now()))
+
+	r, err := http.NewRequestWithContext(context.Background(), http.MethodGet, "https://pinniped.dev/api/v1/namespaces/foo/pods?watch=true", nil)
+	require.NoError(t, err)
+	requester := &user.DefaultInfo{Name: "alice", Groups: []string{"devs", "system:authenticated"}}
+
+	subject.auditThrottled(r, requester, &request.RequestInfo{Verb: "watch"},
+		&requestThrottled{limitedBy: "perGroup", group: "devs", limit: "qps", retryAfter: 1500 * time.Millisecond},
+	)
+	subject.auditThrottled(r, requester, nil,
+		&requestThrottled{limitedBy: "perUser", limit: "maxInFlightLongRunningRequests", retryAfter: time.Second},
+	)
+
+	testutil.CompareAuditLogs(t, []testutil.WantedAuditLog{
+		testutil.WantAuditLog("Impersonation Proxy Request Throttled", map[string]any{
+			"personalInfo": map[string]any{
+				"username":     "alice",
+				"groups":       []any{"devs", "system:authenticated"},
+				"limitedGroup": "devs",
+			},
+			"verb":              "watch",
+			"path":              "/api/v1/namespaces/foo/pods",
+			"limitedBy":         "perGroup",
+			"limit":             "qps",
+			"retryAfterSeconds": float64(2),
+		}),
+		testutil.WantAuditLog("Impersonation Proxy Request Throttled", map[string]any{
+			"personalInfo": map[string]any{
+				"username": "alice",
+				"groups":   []any{"devs", "system:authenticated"},
+			},
+			"verb":              "",
+			"path":              "/api/v1/namespaces/foo/pods",
+			"limitedBy":         "perUser",
+			"limit":             "maxInFlightLongRunningRequests",
+			"retryAfterSeconds": float64(1),
+		}),
+	}, actualAuditLog.String())
+}
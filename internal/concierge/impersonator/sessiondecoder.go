@@ -0,0 +1,258 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package impersonator
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/moby/spdystream/spdy"
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	streamTypeStdin  = corev1.StreamTypeStdin
+	streamTypeStdout = corev1.StreamTypeStdout
+	streamTypeStderr = corev1.StreamTypeStderr
+	streamTypeError  = corev1.StreamTypeError
+	streamTypeResize = corev1.StreamTypeResize
+
+	// maxSessionFrameLength limits how much of a single frame or message is buffered while it is decoded.
+	// This is the same as the largest SPDY frame, and clients send much smaller frames in practice.
+	maxSessionFrameLength = spdy.MaxDataLength
+)
+
+// websocketChannels are the stream types of the channels of the channel.k8s.io websocket subprotocols.
+var websocketChannels = []string{streamTypeStdin, streamTypeStdout, streamTypeStderr, streamTypeError, streamTypeResize}
+
+// sessionEvent is some data which was sent on one of the streams of a session.
+type sessionEvent struct {
+	streamType string
+	data       []byte
+}
+
+// sessionDecoder decodes the streams of a session from the raw bytes that are sent in each direction.
+// The bytes may be split anywhere, so a decoder buffers incomplete frames until the rest of them has been sent.
+type sessionDecoder interface {
+	decode(fromClient bool, data []byte) ([]sessionEvent, error)
+}
+
+// newSessionDecoder returns a decoder for the streaming protocol which the Kubernetes API server has switched to,
+// along with the name of that protocol.
+func newSessionDecoder(header http.Header) (sessionDecoder, string, error) {
+	upgrade := header.Get("Upgrade")
+	switch {
+	case strings.EqualFold(upgrade, "SPDY/3.1"):
+		protocol := header.Get("X-Stream-Protocol-Version")
+		decoder, err := newSPDYDecoder()
+		if err != nil {
+			return nil, "", err
+		}
+		return decoder, protocol, nil
+
+	case strings.EqualFold(upgrade, "websocket"):
+		protocol := header.Get("Sec-Websocket-Protocol")
+		if !strings.HasSuffix(protocol, "channel.k8s.io") {
+			return nil, "", fmt.Errorf("unsupported websocket subprotocol %q", protocol)
+		}
+		return &websocketDecoder{base64: strings.HasSuffix(protocol, "base64.channel.k8s.io")}, protocol, nil
+
+	default:
+		return nil, "", fmt.Errorf("unsupported upgrade %q", upgrade)
+	}
+}
+
+// spdyDecoder decodes the SPDY/3.1 streams of the Kubernetes remote command protocols. The client creates every
+// stream and declares its type in a header, and both the client and the server send data frames on those streams.
+type spdyDecoder struct {
+	client, server *spdyFrameReader
+	streamTypes    map[spdy.StreamId]string
+}
+
+func newSPDYDecoder() (*spdyDecoder, error) {
+	client, err := newSPDYFrameReader()
+	if err != nil {
+		return nil, err
+	}
+	server, err := newSPDYFrameReader()
+	if err != nil {
+		return nil, err
+	}
+	return &spdyDecoder{client: client, server: server, streamTypes: map[spdy.StreamId]string{}}, nil
+}
+
+func (d *spdyDecoder) decode(fromClient bool, data []byte) ([]sessionEvent, error) {
+	r := d.server
+	if fromClient {
+		r = d.client
+	}
+
+	var events []sessionEvent
+	frames, err := r.frames(data)
+	for _, frame := range frames {
+		switch frame := frame.(type) {
+		case *spdy.SynStreamFrame:
+			if fromClient {
+				d.streamTypes[frame.StreamId] = frame.Headers.Get(corev1.StreamType)
+			}
+		case *spdy.DataFrame:
+			if streamType, ok := d.streamTypes[frame.StreamId]; ok && len(frame.Data) > 0 {
+				events = append(events, sessionEvent{streamType: streamType, data: frame.Data})
+			}
+		}
+	}
+	return events, err
+}
+
+// spdyFrameReader reads the frames which are sent in one direction. Each direction has its own header
+// compression context, so every frame must be read in order, even the ones which are not recorded.
+type spdyFrameReader struct {
+	buf    bytes.Buffer
+	framer *spdy.Framer
+}
+
+func newSPDYFrameReader() (*spdyFrameReader, error) {
+	r := &spdyFrameReader{}
+	framer, err := spdy.NewFramer(io.Discard, &r.buf)
+	if err != nil {
+		return nil, fmt.Errorf("could not create SPDY framer: %w", err)
+	}
+	r.framer = framer
+	return r, nil
+}
+
+func (r *spdyFrameReader) frames(data []byte) ([]spdy.Frame, error) {
+	r.buf.Write(data)
+
+	var frames []spdy.Frame
+	// Only give complete frames to the framer, since it cannot continue a frame which it has started to read.
+	// Control frames and data frames both start with 8 bytes which end with the 24-bit length of the payload.
+	for r.buf.Len() >= 8 {
+		b := r.buf.Bytes()
+		length := int(b[5])<<16 | int(b[6])<<8 | int(b[7])
+		if r.buf.Len() < 8+length {
+			break
+		}
+		frame, err := r.framer.ReadFrame()
+		if err != nil {
+			return frames, fmt.Errorf("could not read SPDY frame: %w", err)
+		}
+		frames = append(frames, frame)
+	}
+	return frames, nil
+}
+
+// websocketDecoder decodes the channel.k8s.io websocket subprotocols. Each message starts with its channel number,
+// which is an ASCII digit for the base64 subprotocols, whose messages are otherwise base64 encoded.
+type websocketDecoder struct {
+	base64         bool
+	client, server websocketMessageReader
+}
+
+func (d *websocketDecoder) decode(fromClient bool, data []byte) ([]sessionEvent, error) {
+	r := &d.server
+	if fromClient {
+		r = &d.client
+	}
+
+	var events []sessionEvent
+	messages, err := r.messages(data)
+	for _, message := range messages {
+		if len(message) == 0 {
+			continue
+		}
+		channel, payload := int(message[0]), message[1:]
+		if d.base64 {
+			channel -= '0'
+			decoded, decodeErr := base64.StdEncoding.DecodeString(string(payload))
+			if decodeErr != nil {
+				return events, fmt.Errorf("could not decode base64 websocket message: %w", decodeErr)
+			}
+			payload = decoded
+		}
+		// Unknown channels include the close signal of the v5.channel.k8s.io subprotocol, which has no data.
+		if channel < 0 || channel >= len(websocketChannels) || len(payload) == 0 {
+			continue
+		}
+		events = append(events, sessionEvent{streamType: websocketChannels[channel], data: payload})
+	}
+	return events, err
+}
+
+// websocketMessageReader reads the data messages which are sent in one direction (see RFC 6455).
+type websocketMessageReader struct {
+	buf     []byte
+	message []byte
+}
+
+func (r *websocketMessageReader) messages(data []byte) ([][]byte, error) {
+	r.buf = append(r.buf, data...)
+
+	var messages [][]byte
+	for len(r.buf) >= 2 {
+		b := r.buf
+		if b[0]&0x70 != 0 {
+			return messages, fmt.Errorf("websocket extensions are not supported")
+		}
+		fin, opcode := b[0]&0x80 != 0, b[0]&0x0f
+		masked, length, pos := b[1]&0x80 != 0, uint64(b[1]&0x7f), 2
+
+		switch length {
+		case 126:
+			if len(b) < 4 {
+				return messages, nil
+			}
+			length, pos = uint64(binary.BigEndian.Uint16(b[2:4])), 4
+		case 127:
+			if len(b) < 10 {
+				return messages, nil
+			}
+			length, pos = binary.BigEndian.Uint64(b[2:10]), 10
+		}
+		if length+uint64(len(r.message)) > maxSessionFrameLength {
+			return messages, fmt.Errorf("websocket message is too large")
+		}
+
+		var mask []byte
+		if masked {
+			if len(b) < pos+4 {
+				return messages, nil
+			}
+			mask, pos = b[pos:pos+4], pos+4
+		}
+		if uint64(len(b)-pos) < length {
+			return messages, nil
+		}
+
+		payload := b[pos : pos+int(length)]
+		if mask != nil {
+			for i := range payload {
+				payload[i] ^= mask[i%4]
+			}
+		}
+		r.buf = b[pos+int(length):]
+
+		switch opcode {
+		case 0x0: // continuation
+			r.message = append(r.message, payload...)
+		case 0x1, 0x2: // text, binary
+			r.message = append(r.message[:0], payload...)
+		case 0x8, 0x9, 0xa: // close, ping, pong
+			continue
+		default:
+			return messages, fmt.Errorf("unknown websocket opcode %d", opcode)
+		}
+
+		if fin {
+			messages = append(messages, r.message)
+			r.message = nil
+		}
+	}
+	return messages, nil
+}
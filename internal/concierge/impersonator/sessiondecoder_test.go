@@ -0,0 +1,284 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package impersonator
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"net/http"
+	"slices"
+	"testing"
+
+	"github.com/moby/spdystream/spdy"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewSessionDecoder(t *testing.T) {
+	tests := []struct {
+		name         string
+		header       http.Header
+		wantDecoder  sessionDecoder
+		wantProtocol string
+		wantErr      string
+	}{
+		{
+			name:         "spdy",
+			header:       http.Header{"Upgrade": {"SPDY/3.1"}, "X-Stream-Protocol-Version": {"v4.channel.k8s.io"}},
+			wantDecoder:  &spdyDecoder{},
+			wantProtocol: "v4.channel.k8s.io",
+		},
+		{
+			name:         "websocket",
+			header:       http.Header{"Upgrade": {"websocket"}, "Sec-Websocket-Protocol": {"v5.channel.k8s.io"}},
+			wantDecoder:  &websocketDecoder{},
+			wantProtocol: "v5.channel.k8s.io",
+		},
+		{
+			name:         "base64 websocket",
+			header:       http.Header{"Upgrade": {"WebSocket"}, "Sec-Websocket-Protocol": {"v4.base64.channel.k8s.io"}},
+			wantDecoder:  &websocketDecoder{base64: true},
+			wantProtocol: "v4.base64.channel.k8s.io",
+		},
+		{
+			name:    "unsupported websocket subprotocol",
+			header:  http.Header{"Upgrade": {"websocket"}, "Sec-Websocket-Protocol": {"some-other-protocol"}},
+			wantErr: `unsupported websocket subprotocol "some-other-protocol"`,
+		},
+		{
+			name:    "unsupported upgrade",
+			header:  http.Header{"Upgrade": {"h2c"}},
+			wantErr: `unsupported upgrade "h2c"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decoder, protocol, err := newSessionDecoder(tt.header)
+			if tt.wantErr != "" {
+				require.EqualError(t, err, tt.wantErr)
+				require.Nil(t, decoder)
+				return
+			}
+			require.NoError(t, err)
+			require.IsType(t, tt.wantDecoder, decoder)
+			if want, ok := tt.wantDecoder.(*websocketDecoder); ok {
+				require.Equal(t, want.base64, decoder.(*websocketDecoder).base64)
+			}
+			require.Equal(t, tt.wantProtocol, protocol)
+		})
+	}
+}
+
+func TestSPDYDecoder(t *testing.T) {
+	fromClient := spdyFrames(t,
+		&spdy.SynStreamFrame{StreamId: 1, Headers: http.Header{"streamType": {"error"}}},
+		&spdy.SynStreamFrame{StreamId: 3, Headers: http.Header{"streamType": {"stdin"}}},
+		&spdy.SynStreamFrame{StreamId: 5, Headers: http.Header{"streamType": {"stdout"}}},
+		&spdy.SynStreamFrame{StreamId: 7, Headers: http.Header{"streamType": {"resize"}}},
+		&spdy.PingFrame{Id: 1},
+		&spdy.DataFrame{StreamId: 7, Data: []byte(`{"Width":100,"Height":30}`)},
+		&spdy.DataFrame{StreamId: 3, Data: []byte("ls\n")},
+		&spdy.DataFrame{StreamId: 3, Flags: spdy.DataFlagFin},
+	)
+	fromServer := spdyFrames(t,
+		&spdy.SynReplyFrame{StreamId: 1, Headers: http.Header{}},
+		&spdy.SynReplyFrame{StreamId: 3, Headers: http.Header{}},
+		&spdy.SynReplyFrame{StreamId: 5, Headers: http.Header{}},
+		&spdy.SynReplyFrame{StreamId: 7, Headers: http.Header{}},
+		&spdy.DataFrame{StreamId: 5, Data: []byte("file1\n")},
+		&spdy.DataFrame{StreamId: 9, Data: []byte("unknown stream")},
+		&spdy.DataFrame{StreamId: 1, Data: []byte(`{"status":"Success"}`)},
+	)
+
+	decoder, err := newSPDYDecoder()
+	require.NoError(t, err)
+
+	// The client creates the streams before the server may send anything on them.
+	// Decode a few bytes at a time, since that is how they might arrive.
+	events := decodeInChunks(t, decoder, true, fromClient, 3)
+	events = append(events, decodeInChunks(t, decoder, false, fromServer, 5)...)
+
+	require.Equal(t, []sessionEvent{
+		{streamType: "resize", data: []byte(`{"Width":100,"Height":30}`)},
+		{streamType: "stdin", data: []byte("ls\n")},
+		{streamType: "stdout", data: []byte("file1\n")},
+		{streamType: "error", data: []byte(`{"status":"Success"}`)},
+	}, events)
+}
+
+func TestSPDYDecoderInvalidFrame(t *testing.T) {
+	decoder, err := newSPDYDecoder()
+	require.NoError(t, err)
+
+	// A control frame of an unknown type.
+	events, err := decoder.decode(true, []byte{0x80, 0x03, 0x00, 0xff, 0x00, 0x00, 0x00, 0x00})
+	require.EqualError(t, err, "could not read SPDY frame: invalid control frame")
+	require.Empty(t, events)
+}
+
+func TestWebsocketDecoder(t *testing.T) {
+	mask := []byte{1, 2, 3, 4}
+	large := bytes.Repeat([]byte("x"), 70000) // needs a 64-bit length
+
+	tests := []struct {
+		name       string
+		base64     bool
+		fromClient []byte
+		fromServer []byte
+		wantEvents []sessionEvent
+		wantErr    string
+	}{
+		{
+			name: "binary messages",
+			fromClient: bytes.Join([][]byte{
+				websocketFrame(0x2, true, mask, []byte("\x00ls\n")),
+				websocketFrame(0x2, true, mask, []byte("\x04"+`{"Width":100,"Height":30}`)),
+				websocketFrame(0x2, true, mask, []byte("\xff\x00")), // v5 close signal for stdin
+			}, nil),
+			fromServer: bytes.Join([][]byte{
+				websocketFrame(0x2, true, nil, []byte("\x01")), // empty messages are sent when a stream is opened
+				websocketFrame(0x2, true, nil, append([]byte("\x01"), large...)),
+				websocketFrame(0x2, true, nil, []byte("\x02oops\n")),
+				websocketFrame(0x2, true, nil, []byte("\x03"+`{"status":"Success"}`)),
+				websocketFrame(0x8, true, nil, nil), // close
+			}, nil),
+			wantEvents: []sessionEvent{
+				{streamType: "stdin", data: []byte("ls\n")},
+				{streamType: "resize", data: []byte(`{"Width":100,"Height":30}`)},
+				{streamType: "stdout", data: large},
+				{streamType: "stderr", data: []byte("oops\n")},
+				{streamType: "error", data: []byte(`{"status":"Success"}`)},
+			},
+		},
+		{
+			name: "fragmented messages with interleaved control frames",
+			fromClient: bytes.Join([][]byte{
+				websocketFrame(0x2, false, mask, []byte("\x00he")),
+				websocketFrame(0x9, true, mask, []byte("ping")),
+				websocketFrame(0x0, false, mask, []byte("ll")),
+				websocketFrame(0x0, true, mask, []byte("o")),
+			}, nil),
+			fromServer: bytes.Join([][]byte{
+				websocketFrame(0xa, true, nil, []byte("pong")),
+				websocketFrame(0x1, false, nil, []byte("\x01wor")),
+				websocketFrame(0x0, true, nil, []byte("ld")),
+			}, nil),
+			wantEvents: []sessionEvent{
+				{streamType: "stdin", data: []byte("hello")},
+				{streamType: "stdout", data: []byte("world")},
+			},
+		},
+		{
+			name:   "base64 messages",
+			base64: true,
+			fromClient: bytes.Join([][]byte{
+				websocketFrame(0x1, true, mask, []byte("0"+base64.StdEncoding.EncodeToString([]byte("ls\n")))),
+			}, nil),
+			fromServer: bytes.Join([][]byte{
+				websocketFrame(0x1, true, nil, []byte("1")),
+				websocketFrame(0x1, true, nil, []byte("1"+base64.StdEncoding.EncodeToString([]byte("file1\n")))),
+			}, nil),
+			wantEvents: []sessionEvent{
+				{streamType: "stdin", data: []byte("ls\n")},
+				{streamType: "stdout", data: []byte("file1\n")},
+			},
+		},
+		{
+			name:       "invalid base64",
+			base64:     true,
+			fromServer: websocketFrame(0x1, true, nil, []byte("1!!!")),
+			wantErr:    "could not decode base64 websocket message: illegal base64 data at input byte 0",
+		},
+		{
+			name:       "compressed frame",
+			fromServer: append([]byte{0xc2, 0x01}, 0x01),
+			wantErr:    "websocket extensions are not supported",
+		},
+		{
+			name:       "unknown opcode",
+			fromServer: websocketFrame(0x3, true, nil, []byte("\x01")),
+			wantErr:    "unknown websocket opcode 3",
+		},
+		{
+			name:       "too large",
+			fromServer: binary.BigEndian.AppendUint64([]byte{0x82, 0x7f}, 1<<32),
+			wantErr:    "websocket message is too large",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decoder := &websocketDecoder{base64: tt.base64}
+
+			var events []sessionEvent
+			if tt.fromClient != nil {
+				events = append(events, decodeInChunks(t, decoder, true, tt.fromClient, 7)...)
+			}
+			if tt.wantErr != "" {
+				_, err := decoder.decode(false, tt.fromServer)
+				require.EqualError(t, err, tt.wantErr)
+				return
+			}
+			events = append(events, decodeInChunks(t, decoder, false, tt.fromServer, 7)...)
+
+			require.Equal(t, tt.wantEvents, events)
+		})
+	}
+}
+
+func decodeInChunks(t *testing.T, decoder sessionDecoder, fromClient bool, data []byte, chunkSize int) []sessionEvent {
+	t.Helper()
+
+	var events []sessionEvent
+	for chunk := range slices.Chunk(data, chunkSize) {
+		// Copy each chunk, since the connection may reuse its buffers.
+		chunkEvents, err := decoder.decode(fromClient, bytes.Clone(chunk))
+		require.NoError(t, err)
+		events = append(events, chunkEvents...)
+	}
+	return events
+}
+
+func spdyFrames(t *testing.T, frames ...spdy.Frame) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	framer, err := spdy.NewFramer(&buf, nil)
+	require.NoError(t, err)
+	for _, frame := range frames {
+		require.NoError(t, framer.WriteFrame(frame))
+	}
+	return buf.Bytes()
+}
+
+// websocketFrame encodes a single websocket frame. Clients must mask their frames, and servers must not.
+func websocketFrame(opcode byte, fin bool, mask []byte, payload []byte) []byte {
+	first := opcode
+	if fin {
+		first |= 0x80
+	}
+	var maskBit byte
+	if mask != nil {
+		maskBit = 0x80
+	}
+
+	frame := []byte{first}
+	switch {
+	case len(payload) < 126:
+		frame = append(frame, maskBit|byte(len(payload)))
+	case len(payload) <= 0xffff:
+		frame = binary.BigEndian.AppendUint16(append(frame, maskBit|126), uint16(len(payload)))
+	default:
+		frame = binary.BigEndian.AppendUint64(append(frame, maskBit|127), uint64(len(payload)))
+	}
+
+	if mask != nil {
+		frame = append(frame, mask...)
+		masked := bytes.Clone(payload)
+		for i := range masked {
+			masked[i] ^= mask[i%4]
+		}
+		payload = masked
+	}
+	return append(frame, payload...)
+}
@@ -0,0 +1,453 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package impersonator
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	"k8s.io/apimachinery/pkg/util/uuid"
+	"k8s.io/apiserver/pkg/audit"
+	genericapirequest "k8s.io/apiserver/pkg/endpoints/request"
+	"k8s.io/utils/clock"
+
+	"go.pinniped.dev/internal/auditevent"
+	"go.pinniped.dev/internal/httputil/roundtripper"
+	"go.pinniped.dev/internal/plog"
+)
+
+// Recordings are written in the asciicast v2 format (https://docs.asciinema.org/manual/asciicast/v2/), so that they
+// can be replayed with the usual tools. The header line has an extra "pinniped" key which holds the SessionMetadata.
+const (
+	asciicastVersion = 2
+
+	// The size of the terminal is not known until the client sends a resize event (if ever),
+	// so the header uses a typical default. Resize events are recorded as "r" events.
+	asciicastDefaultWidth  = 80
+	asciicastDefaultHeight = 24
+
+	asciicastInputEvent  = "i"
+	asciicastOutputEvent = "o"
+	asciicastResizeEvent = "r"
+	asciicastMarkerEvent = "m"
+)
+
+// SessionSink stores session recordings.
+type SessionSink interface {
+	// Open is called once a session has started. The recording is written to the returned io.WriteCloser one
+	// line at a time, and it is closed when the session ends. The sink should not assume that the session ends
+	// quickly, since an interactive session may last for hours.
+	Open(metadata *SessionMetadata) (io.WriteCloser, error)
+}
+
+// SessionRedactor may change the data of each event before it is written to a session recording, e.g. to hide
+// secrets. The eventType is "i" for the input of the session, "o" for its output, or "m" for the status which
+// the Kubernetes API server sends at the end of the session. The redactor should return the data unchanged when
+// there is nothing to redact. Note that interactive clients usually send their input one keystroke at a time,
+// so the input of a session is rarely redactable.
+type SessionRedactor interface {
+	Redact(eventType string, data []byte) []byte
+}
+
+// SessionMetadata describes a recorded session.
+type SessionMetadata struct {
+	// Name is unique for each recording. It is safe to use as a file name.
+	Name string `json:"name"`
+
+	// AuditID is the audit ID of the exec or attach request, which can be used to find it in the audit logs.
+	AuditID string `json:"auditID,omitempty"`
+
+	// User is the user who made the request, and ImpersonatedUser is the user who they were impersonating, if any.
+	User             authenticationv1.UserInfo  `json:"user"`
+	ImpersonatedUser *authenticationv1.UserInfo `json:"impersonatedUser,omitempty"`
+
+	// Subresource is either "exec" or "attach".
+	Subresource string   `json:"subresource"`
+	Namespace   string   `json:"namespace"`
+	Pod         string   `json:"pod"`
+	Container   string   `json:"container,omitempty"`
+	Command     []string `json:"command,omitempty"`
+	Stdin       bool     `json:"stdin"`
+	TTY         bool     `json:"tty"`
+
+	// Protocol is the streaming protocol which was negotiated with the Kubernetes API server.
+	Protocol string `json:"protocol"`
+}
+
+// SessionRecorder records the exec and attach sessions which are served by the impersonation proxy.
+// Each session is recorded while it is proxied, including its input, its output, and the size of its terminal.
+// When a recording reaches its size limit, the rest of the session is not recorded, but the session continues.
+// When a recording cannot be started, the session is not allowed.
+// A nil *SessionRecorder does not record any sessions.
+type SessionRecorder struct {
+	sink               SessionSink
+	maxBytesPerSession int64
+	redactors          []SessionRedactor
+	auditLogger        plog.AuditLogger
+	clock              clock.PassiveClock
+}
+
+// NewSessionRecorder returns a SessionRecorder which writes the recordings to the given sink. The redactors are
+// applied in order to each event.
+func NewSessionRecorder(
+	sink SessionSink,
+	maxBytesPerSession int64,
+	redactors []SessionRedactor,
+	auditLogger plog.AuditLogger,
+	clock clock.PassiveClock,
+) *SessionRecorder {
+	return &SessionRecorder{
+		sink:               sink,
+		maxBytesPerSession: maxBytesPerSession,
+		redactors:          redactors,
+		auditLogger:        auditLogger,
+		clock:              clock,
+	}
+}
+
+// wrap returns a round tripper which records the session when the request is an exec or attach request.
+// Other requests are not changed. The recording starts once the Kubernetes API server has switched protocols.
+func (s *SessionRecorder) wrap(
+	rt http.RoundTripper,
+	r *http.Request,
+	requestInfo *genericapirequest.RequestInfo,
+	ae *auditEventUserInfo,
+) http.RoundTripper {
+	if s == nil || !isRecordableSession(requestInfo) {
+		return rt
+	}
+
+	query := r.URL.Query()
+	metadata := &SessionMetadata{
+		AuditID:          audit.GetAuditIDTruncated(r.Context()),
+		User:             ae.User,
+		ImpersonatedUser: ae.ImpersonatedUser,
+		Subresource:      requestInfo.Subresource,
+		Namespace:        requestInfo.Namespace,
+		Pod:              requestInfo.Name,
+		Container:        query.Get("container"),
+		Command:          query["command"],
+		Stdin:            isTrueQueryParam(query.Get("stdin")),
+		TTY:              isTrueQueryParam(query.Get("tty")),
+	}
+
+	return roundtripper.WrapFunc(rt, func(req *http.Request) (*http.Response, error) {
+		resp, err := rt.RoundTrip(req)
+		if err != nil || resp.StatusCode != http.StatusSwitchingProtocols {
+			return resp, err
+		}
+
+		backendConn, ok := resp.Body.(io.ReadWriteCloser)
+		if !ok {
+			return resp, nil // the reverse proxy will fail this request anyway
+		}
+
+		recording, err := s.start(req.Context(), metadata, resp)
+		if err != nil {
+			// Sessions which cannot be recorded are not allowed.
+			plog.WarningErr("impersonation proxy could not start session recording, rejecting session", err,
+				"url", req.URL.String(),
+				"upgrade", resp.Header.Get("Upgrade"),
+			)
+			_ = backendConn.Close()
+			return nil, fmt.Errorf("could not start session recording: %w", err)
+		}
+
+		resp.Body = &recordingConn{ReadWriteCloser: backendConn, recording: recording}
+		return resp, nil
+	})
+}
+
+func (s *SessionRecorder) start(ctx context.Context, metadata *SessionMetadata, resp *http.Response) (*sessionRecording, error) {
+	decoder, protocol, err := newSessionDecoder(resp.Header)
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.clock.Now()
+
+	copied := *metadata // each attempt gets its own name
+	metadata = &copied
+	metadata.Protocol = protocol
+	metadata.Name = fmt.Sprintf("%s_%s_%s_%s.cast",
+		now.UTC().Format("20060102T150405Z"), metadata.Namespace, metadata.Pod, uuid.NewUUID())
+
+	w, err := s.sink.Open(metadata)
+	if err != nil {
+		return nil, fmt.Errorf("could not open session recording: %w", err)
+	}
+
+	recording := &sessionRecording{
+		recorder: s,
+		ctx:      ctx,
+		metadata: metadata,
+		start:    now,
+		w:        w,
+		decoder:  decoder,
+	}
+
+	header, err := json.Marshal(&asciicastHeader{
+		Version:   asciicastVersion,
+		Width:     asciicastDefaultWidth,
+		Height:    asciicastDefaultHeight,
+		Timestamp: now.Unix(),
+		Command:   strings.Join(metadata.Command, " "),
+		Title:     fmt.Sprintf("%s %s/%s", metadata.Subresource, metadata.Namespace, metadata.Pod),
+		Pinniped:  metadata,
+	})
+	if err != nil {
+		_ = w.Close()
+		return nil, fmt.Errorf("could not encode session recording header: %w", err)
+	}
+	if err := recording.writeLine(header); err != nil {
+		_ = w.Close()
+		return nil, fmt.Errorf("could not write session recording header: %w", err)
+	}
+
+	plog.Debug("impersonation proxy started session recording",
+		"recording", metadata.Name,
+		"protocol", protocol,
+	)
+
+	return recording, nil
+}
+
+type asciicastHeader struct {
+	Version   int              `json:"version"`
+	Width     int              `json:"width"`
+	Height    int              `json:"height"`
+	Timestamp int64            `json:"timestamp"`
+	Command   string           `json:"command,omitempty"`
+	Title     string           `json:"title"`
+	Pinniped  *SessionMetadata `json:"pinniped"`
+}
+
+// sessionRecording is a single recording which is in progress.
+// Both directions of the session are recorded concurrently, so all of its methods are safe to call concurrently.
+type sessionRecording struct {
+	recorder *SessionRecorder
+	ctx      context.Context // only used for audit logging
+	metadata *SessionMetadata
+	start    time.Time
+
+	lock      sync.Mutex
+	w         io.WriteCloser
+	decoder   sessionDecoder
+	bytes     int64
+	stopped   bool // no more events will be recorded
+	truncated bool
+	finished  bool
+	err       error
+}
+
+// record decodes the bytes which were sent by the client or by the Kubernetes API server and records the resulting
+// events. Errors are remembered and stop the recording, but never interrupt the session itself.
+func (r *sessionRecording) record(fromClient bool, data []byte) {
+	if len(data) == 0 {
+		return
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if r.stopped {
+		return
+	}
+
+	events, err := r.decoder.decode(fromClient, data)
+	for _, event := range events {
+		if r.stopped {
+			return
+		}
+		r.recordEvent(event)
+	}
+	if err != nil {
+		r.stop(fmt.Sprintf("session recording stopped because the stream could not be decoded: %v", err), err)
+	}
+}
+
+// recordEvent writes a single event, unless it would make the recording exceed its size limit.
+// The caller must hold the lock.
+func (r *sessionRecording) recordEvent(event sessionEvent) {
+	var eventType string
+	data := event.data
+
+	switch event.streamType {
+	case streamTypeStdin:
+		eventType = asciicastInputEvent
+	case streamTypeStdout, streamTypeStderr:
+		eventType = asciicastOutputEvent
+	case streamTypeError:
+		eventType = asciicastMarkerEvent
+	case streamTypeResize:
+		var size struct{ Width, Height uint16 }
+		if err := json.Unmarshal(data, &size); err != nil {
+			return // the Kubernetes API server will ignore it too
+		}
+		eventType = asciicastResizeEvent
+		data = fmt.Appendf(nil, "%dx%d", size.Width, size.Height)
+	default:
+		return
+	}
+
+	if eventType != asciicastResizeEvent {
+		for _, redactor := range r.recorder.redactors {
+			data = redactor.Redact(eventType, data)
+		}
+	}
+	if len(data) == 0 {
+		return
+	}
+
+	line := r.eventLine(eventType, data)
+	if r.bytes+int64(len(line)) > r.recorder.maxBytesPerSession {
+		r.truncated = true
+		r.stop("session recording truncated because it reached its size limit", nil)
+		return
+	}
+	if err := r.writeLine(line); err != nil {
+		r.stopped = true
+		r.err = fmt.Errorf("could not write session recording: %w", err)
+	}
+}
+
+// stop writes a final marker event which explains why the rest of the session was not recorded.
+// The marker is written even when the recording has reached its size limit, so it may exceed the limit slightly.
+// The caller must hold the lock.
+func (r *sessionRecording) stop(reason string, err error) {
+	r.stopped = true
+	r.err = err
+
+	if writeErr := r.writeLine(r.eventLine(asciicastMarkerEvent, []byte(reason))); writeErr != nil && r.err == nil {
+		r.err = fmt.Errorf("could not write session recording: %w", writeErr)
+	}
+}
+
+func (r *sessionRecording) eventLine(eventType string, data []byte) []byte {
+	// Round to microseconds, which is plenty for replaying a session, and keeps the recording small.
+	elapsed := math.Round(r.recorder.clock.Since(r.start).Seconds()*1e6) / 1e6
+
+	// Data which is not valid UTF-8 (e.g. when a multibyte character was split across frames) is encoded
+	// with replacement characters, as usual for asciicast recordings.
+	line, _ := json.Marshal([]any{elapsed, eventType, string(data)}) // cannot fail for these types
+	return line
+}
+
+func (r *sessionRecording) writeLine(line []byte) error {
+	line = append(line, '\n')
+	n, err := r.w.Write(line)
+	r.bytes += int64(n)
+	return err
+}
+
+// finish closes the recording and audit logs the result. It is safe to call more than once.
+func (r *sessionRecording) finish() {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if r.finished {
+		return
+	}
+	r.finished = true
+	r.stopped = true
+
+	if err := r.w.Close(); err != nil {
+		r.err = errors.Join(r.err, fmt.Errorf("could not close session recording: %w", err))
+	}
+
+	if r.err != nil {
+		plog.WarningErr("impersonation proxy could not fully record session", r.err,
+			"recording", r.metadata.Name,
+		)
+	} else {
+		plog.Debug("impersonation proxy finished session recording",
+			"recording", r.metadata.Name,
+			"bytes", r.bytes,
+			"truncated", r.truncated,
+		)
+	}
+
+	r.audit()
+}
+
+// audit records the recording in the audit logs, so that it can be found by the audit ID of the request.
+// The caller must hold the lock.
+func (r *sessionRecording) audit() {
+	if r.recorder.auditLogger == nil {
+		return
+	}
+
+	keysAndValues := []any{
+		"recording", r.metadata.Name,
+		"subresource", r.metadata.Subresource,
+		"namespace", r.metadata.Namespace,
+		"pod", r.metadata.Pod,
+		"bytes", r.bytes,
+		"truncated", r.truncated,
+	}
+	if r.err != nil {
+		keysAndValues = append(keysAndValues, "recordingError", r.err.Error())
+	}
+
+	r.recorder.auditLogger.Audit(auditevent.ImpersonationProxySessionRecorded, &plog.AuditParams{
+		ReqCtx: r.ctx,
+		PIIKeysAndValues: []any{
+			"username", r.metadata.User.Username,
+			"groups", r.metadata.User.Groups,
+		},
+		KeysAndValues: keysAndValues,
+	})
+}
+
+var _ io.ReadWriteCloser = &recordingConn{}
+
+// recordingConn records a session as it is proxied. It wraps the connection to the Kubernetes API server, so
+// everything which is read from it was sent by the Kubernetes API server, and everything which is written to it
+// was sent by the client.
+type recordingConn struct {
+	io.ReadWriteCloser
+	recording *sessionRecording
+}
+
+func (c *recordingConn) Read(p []byte) (int, error) {
+	n, err := c.ReadWriteCloser.Read(p)
+	c.recording.record(false, p[:n])
+	return n, err
+}
+
+func (c *recordingConn) Write(p []byte) (int, error) {
+	n, err := c.ReadWriteCloser.Write(p)
+	c.recording.record(true, p[:n])
+	return n, err
+}
+
+func (c *recordingConn) Close() error {
+	err := c.ReadWriteCloser.Close()
+	c.recording.finish()
+	return err
+}
+
+func isRecordableSession(requestInfo *genericapirequest.RequestInfo) bool {
+	return requestInfo != nil &&
+		requestInfo.IsResourceRequest &&
+		requestInfo.APIGroup == "" &&
+		requestInfo.Resource == "pods" &&
+		(requestInfo.Subresource == "exec" || requestInfo.Subresource == "attach")
+}
+
+// isTrueQueryParam parses boolean query params the same way as the Kubernetes API server.
+func isTrueQueryParam(value string) bool {
+	b, err := strconv.ParseBool(value)
+	return err == nil && b
+}
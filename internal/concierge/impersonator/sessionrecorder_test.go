@@ -0,0 +1,484 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package impersonator
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/moby/spdystream/spdy"
+	"github.com/stretchr/testify/require"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	"k8s.io/apiserver/pkg/audit"
+	genericapirequest "k8s.io/apiserver/pkg/endpoints/request"
+	clocktesting "k8s.io/utils/clock/testing"
+
+	"go.pinniped.dev/internal/plog"
+	"go.pinniped.dev/internal/testutil"
+)
+
+func TestSessionRecorder(t *testing.T) {
+	mask := []byte{1, 2, 3, 4}
+	startTime := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	wsClient := func(channel byte, data string) []byte {
+		return websocketFrame(0x2, true, mask, append([]byte{channel}, data...))
+	}
+	wsServer := func(channel byte, data string) []byte {
+		return websocketFrame(0x2, true, nil, append([]byte{channel}, data...))
+	}
+
+	// A step of the session: either the client sends some bytes, the server sends some bytes, or time passes.
+	type step struct {
+		fromClient []byte
+		fromServer []byte
+		wait       time.Duration
+	}
+
+	tests := []struct {
+		name               string
+		maxBytesPerSession int64
+		redactors          []SessionRedactor
+		steps              []step
+		closeErr           error
+		wantEvents         []string
+		wantAuditParams    map[string]any
+	}{
+		{
+			name: "records input, output, resizes, and the final status",
+			steps: []step{
+				{fromServer: wsServer(1, "")},
+				{fromClient: wsClient(4, `{"Width":100,"Height":30}`)},
+				{wait: 1500 * time.Millisecond},
+				{fromClient: wsClient(0, "ls\n")},
+				{wait: 250 * time.Millisecond},
+				{fromServer: wsServer(1, "file1\n")},
+				{fromServer: wsServer(2, "oops\n")},
+				{fromClient: wsClient(4, `not json`)},
+				{wait: time.Millisecond},
+				{fromServer: wsServer(3, `{"status":"Success"}`)},
+			},
+			wantEvents: []string{
+				`[0,"r","100x30"]`,
+				`[1.5,"i","ls\n"]`,
+				`[1.75,"o","file1\n"]`,
+				`[1.75,"o","oops\n"]`,
+				`[1.751,"m","{\"status\":\"Success\"}"]`,
+			},
+			wantAuditParams: map[string]any{"truncated": false},
+		},
+		{
+			name: "applies the redactors in order",
+			redactors: []SessionRedactor{
+				sessionRedactorFunc(func(eventType string, data []byte) []byte {
+					return bytes.ReplaceAll(data, []byte("password"), []byte("secret"))
+				}),
+				sessionRedactorFunc(func(eventType string, data []byte) []byte {
+					if eventType == "i" {
+						return nil // drop all input
+					}
+					return bytes.ReplaceAll(data, []byte("secret"), []byte("***"))
+				}),
+			},
+			steps: []step{
+				{fromClient: wsClient(4, `{"Width":100,"Height":30}`)},
+				{fromClient: wsClient(0, "echo $password\n")},
+				{fromServer: wsServer(1, "password: hunter2\n")},
+			},
+			wantEvents: []string{
+				`[0,"r","100x30"]`,
+				`[0,"o","***: hunter2\n"]`,
+			},
+			wantAuditParams: map[string]any{"truncated": false},
+		},
+		{
+			name:               "stops recording at the size limit but does not stop the session",
+			maxBytesPerSession: -1, // replaced by the size of the header plus the first event
+			steps: []step{
+				{fromServer: wsServer(1, "a")},
+				{fromServer: wsServer(1, "b")},
+				{fromServer: wsServer(1, "c")},
+			},
+			wantEvents: []string{
+				`[0,"o","a"]`,
+				`[0,"m","session recording truncated because it reached its size limit"]`,
+			},
+			wantAuditParams: map[string]any{"truncated": true},
+		},
+		{
+			name: "stops recording when the stream cannot be decoded",
+			steps: []step{
+				{fromServer: wsServer(1, "a")},
+				{fromServer: websocketFrame(0x3, true, nil, []byte("\x01b"))},
+				{fromServer: wsServer(1, "c")},
+			},
+			wantEvents: []string{
+				`[0,"o","a"]`,
+				`[0,"m","session recording stopped because the stream could not be decoded: unknown websocket opcode 3"]`,
+			},
+			wantAuditParams: map[string]any{
+				"truncated":      false,
+				"recordingError": "unknown websocket opcode 3",
+			},
+		},
+		{
+			name: "close fails",
+			steps: []step{
+				{fromServer: wsServer(1, "a")},
+			},
+			closeErr: errors.New("some close error"),
+			wantEvents: []string{
+				`[0,"o","a"]`,
+			},
+			wantAuditParams: map[string]any{
+				"truncated":      false,
+				"recordingError": "could not close session recording: some close error",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fakeClock := clocktesting.NewFakeClock(startTime)
+			auditLogger, actualAuditLog := plog.TestAuditLogger(t)
+			sink := &fakeSessionSink{closeErr: tt.closeErr}
+
+			maxBytes := tt.maxBytesPerSession
+			if maxBytes == 0 {
+				maxBytes = 1024 * 1024
+			}
+			subject := NewSessionRecorder(sink, maxBytes, tt.redactors, auditLogger, fakeClock)
+
+			r := newExecRequest(t, "/api/v1/namespaces/ns/pods/pod/exec?command=sh&command=-c&container=c&stdin=true&tty=1")
+			backend := &fakeBackendConn{}
+			delegate := &fakeUpgradeRoundTripper{resp: &http.Response{
+				StatusCode: http.StatusSwitchingProtocols,
+				Header:     http.Header{"Upgrade": {"websocket"}, "Sec-Websocket-Protocol": {"v5.channel.k8s.io"}},
+				Body:       backend,
+			}}
+
+			rt := subject.wrap(delegate, r, execRequestInfo("exec"), testAuditEventUserInfo())
+			resp, err := rt.RoundTrip(r)
+			require.NoError(t, err)
+			conn, ok := resp.Body.(io.ReadWriteCloser)
+			require.True(t, ok)
+			require.Len(t, sink.opened, 1)
+			recording := sink.opened[0]
+
+			if tt.maxBytesPerSession < 0 {
+				subject.maxBytesPerSession = int64(recording.buf.Len() + len(`[0,"o","a"]`+"\n"))
+			}
+
+			for _, s := range tt.steps {
+				fakeClock.Step(s.wait)
+				if s.fromClient != nil {
+					n, err := conn.Write(s.fromClient)
+					require.NoError(t, err)
+					require.Len(t, s.fromClient, n)
+				}
+				if s.fromServer != nil {
+					backend.toRead.Write(s.fromServer)
+					p := make([]byte, len(s.fromServer))
+					n, err := conn.Read(p)
+					require.NoError(t, err)
+					require.Len(t, s.fromServer, n)
+				}
+			}
+
+			// The session itself is never changed.
+			for _, s := range tt.steps {
+				if s.fromClient != nil {
+					require.True(t, bytes.HasPrefix(backend.written.Bytes(), s.fromClient))
+					backend.written.Next(len(s.fromClient))
+				}
+			}
+
+			require.NoError(t, conn.Close())
+			require.NoError(t, conn.Close()) // closing again does nothing
+			require.Equal(t, 2, backend.closed)
+			require.Equal(t, 1, recording.closed)
+
+			lines := strings.Split(strings.TrimSuffix(recording.buf.String(), "\n"), "\n")
+			requireSessionRecordingHeader(t, lines[0], recording.metadata.Name, startTime)
+			require.Equal(t, tt.wantEvents, lines[1:])
+
+			wantAuditParams := map[string]any{
+				"auditID": "some-audit-id",
+				"personalInfo": map[string]any{
+					"username": "alice",
+					"groups":   []any{"devs", "system:authenticated"},
+				},
+				"recording":   recording.metadata.Name,
+				"subresource": "exec",
+				"namespace":   "ns",
+				"pod":         "pod",
+				"bytes":       float64(recording.buf.Len()),
+			}
+			for k, v := range tt.wantAuditParams {
+				wantAuditParams[k] = v
+			}
+			testutil.CompareAuditLogs(t, []testutil.WantedAuditLog{
+				testutil.WantAuditLog("Impersonation Proxy Session Recorded", wantAuditParams),
+			}, actualAuditLog.String())
+		})
+	}
+}
+
+func TestSessionRecorderSPDY(t *testing.T) {
+	fakeClock := clocktesting.NewFakeClock(time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC))
+	sink := &fakeSessionSink{}
+	subject := NewSessionRecorder(sink, 1024*1024, nil, nil, fakeClock)
+
+	r := newExecRequest(t, "/api/v1/namespaces/ns/pods/pod/attach?stdin=true&stdout=true")
+	backend := &fakeBackendConn{}
+	delegate := &fakeUpgradeRoundTripper{resp: &http.Response{
+		StatusCode: http.StatusSwitchingProtocols,
+		Header:     http.Header{"Upgrade": {"SPDY/3.1"}, "X-Stream-Protocol-Version": {"v4.channel.k8s.io"}},
+		Body:       backend,
+	}}
+
+	rt := subject.wrap(delegate, r, execRequestInfo("attach"), testAuditEventUserInfo())
+	resp, err := rt.RoundTrip(r)
+	require.NoError(t, err)
+	conn := resp.Body.(io.ReadWriteCloser)
+
+	_, err = conn.Write(spdyFrames(t,
+		&spdy.SynStreamFrame{StreamId: 1, Headers: http.Header{"streamType": {"stdin"}}},
+		&spdy.SynStreamFrame{StreamId: 3, Headers: http.Header{"streamType": {"stdout"}}},
+		&spdy.DataFrame{StreamId: 1, Data: []byte("whoami\n")},
+	))
+	require.NoError(t, err)
+	backend.toRead.Write(spdyFrames(t, &spdy.DataFrame{StreamId: 3, Data: []byte("root\n")}))
+	_, err = io.ReadAll(conn)
+	require.NoError(t, err)
+	require.NoError(t, conn.Close())
+
+	require.Len(t, sink.opened, 1)
+	recording := sink.opened[0]
+	require.Equal(t, "attach", recording.metadata.Subresource)
+	require.Equal(t, "v4.channel.k8s.io", recording.metadata.Protocol)
+	require.True(t, recording.metadata.Stdin)
+	require.False(t, recording.metadata.TTY)
+	require.Empty(t, recording.metadata.Command)
+
+	lines := strings.Split(strings.TrimSuffix(recording.buf.String(), "\n"), "\n")
+	require.Equal(t, []string{`[0,"i","whoami\n"]`, `[0,"o","root\n"]`}, lines[1:])
+}
+
+func TestSessionRecorderDoesNotRecord(t *testing.T) {
+	sink := &fakeSessionSink{}
+	subject := NewSessionRecorder(sink, 1024*1024, nil, nil, clocktesting.NewFakeClock(time.Now()))
+	delegate := &fakeUpgradeRoundTripper{}
+	r := newExecRequest(t, "/api/v1/namespaces/ns/pods/pod/portforward")
+
+	t.Run("nil recorder", func(t *testing.T) {
+		var nilRecorder *SessionRecorder
+		require.Same(t, delegate, nilRecorder.wrap(delegate, r, execRequestInfo("exec"), testAuditEventUserInfo()))
+	})
+
+	t.Run("other requests", func(t *testing.T) {
+		for _, requestInfo := range []*genericapirequest.RequestInfo{
+			nil,
+			execRequestInfo("portforward"),
+			execRequestInfo("log"),
+			{IsResourceRequest: true, APIGroup: "example.com", Resource: "pods", Subresource: "exec"},
+			{IsResourceRequest: false, Path: "/api/v1/namespaces/ns/pods/pod/exec"},
+		} {
+			require.Same(t, delegate, subject.wrap(delegate, r, requestInfo, testAuditEventUserInfo()))
+		}
+	})
+
+	t.Run("protocols were not switched", func(t *testing.T) {
+		delegate := &fakeUpgradeRoundTripper{resp: &http.Response{StatusCode: http.StatusForbidden, Body: http.NoBody}}
+		resp, err := subject.wrap(delegate, r, execRequestInfo("exec"), testAuditEventUserInfo()).RoundTrip(r)
+		require.NoError(t, err)
+		require.Same(t, delegate.resp, resp)
+		require.Equal(t, http.NoBody, resp.Body)
+	})
+
+	t.Run("round trip fails", func(t *testing.T) {
+		delegate := &fakeUpgradeRoundTripper{err: errors.New("some round trip error")}
+		resp, err := subject.wrap(delegate, r, execRequestInfo("exec"), testAuditEventUserInfo()).RoundTrip(r)
+		require.EqualError(t, err, "some round trip error")
+		require.Nil(t, resp)
+	})
+
+	require.Empty(t, sink.opened)
+}
+
+func TestSessionRecorderRejectsSessionsWhichCannotBeRecorded(t *testing.T) {
+	tests := []struct {
+		name    string
+		header  http.Header
+		openErr error
+		wantErr string
+	}{
+		{
+			name:    "unsupported protocol",
+			header:  http.Header{"Upgrade": {"websocket"}, "Sec-Websocket-Protocol": {"some-protocol"}},
+			wantErr: `could not start session recording: unsupported websocket subprotocol "some-protocol"`,
+		},
+		{
+			name:    "sink cannot be opened",
+			header:  http.Header{"Upgrade": {"websocket"}, "Sec-Websocket-Protocol": {"v5.channel.k8s.io"}},
+			openErr: errors.New("disk full"),
+			wantErr: "could not start session recording: could not open session recording: disk full",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sink := &fakeSessionSink{openErr: tt.openErr}
+			subject := NewSessionRecorder(sink, 1024*1024, nil, nil, clocktesting.NewFakeClock(time.Now()))
+
+			r := newExecRequest(t, "/api/v1/namespaces/ns/pods/pod/exec")
+			backend := &fakeBackendConn{}
+			delegate := &fakeUpgradeRoundTripper{resp: &http.Response{
+				StatusCode: http.StatusSwitchingProtocols,
+				Header:     tt.header,
+				Body:       backend,
+			}}
+
+			resp, err := subject.wrap(delegate, r, execRequestInfo("exec"), testAuditEventUserInfo()).RoundTrip(r)
+			require.EqualError(t, err, tt.wantErr)
+			require.Nil(t, resp)
+			require.Equal(t, 1, backend.closed)
+		})
+	}
+}
+
+func requireSessionRecordingHeader(t *testing.T, line, name string, startTime time.Time) {
+	t.Helper()
+
+	require.Regexp(t, `^20260102T030405Z_ns_pod_[0-9a-f-]{36}\.cast$`, name)
+
+	var header map[string]any
+	require.NoError(t, json.Unmarshal([]byte(line), &header))
+	require.Equal(t, map[string]any{
+		"version":   float64(2),
+		"width":     float64(80),
+		"height":    float64(24),
+		"timestamp": float64(startTime.Unix()),
+		"command":   "sh -c",
+		"title":     "exec ns/pod",
+		"pinniped": map[string]any{
+			"name":    name,
+			"auditID": "some-audit-id",
+			"user": map[string]any{
+				"username": "alice",
+				"groups":   []any{"devs", "system:authenticated"},
+			},
+			"impersonatedUser": map[string]any{
+				"username": "bob",
+			},
+			"subresource": "exec",
+			"namespace":   "ns",
+			"pod":         "pod",
+			"container":   "c",
+			"command":     []any{"sh", "-c"},
+			"stdin":       true,
+			"tty":         true,
+			"protocol":    "v5.channel.k8s.io",
+		},
+	}, header)
+}
+
+func newExecRequest(t *testing.T, path string) *http.Request {
+	t.Helper()
+
+	ctx := audit.WithAuditContext(context.Background())
+	audit.WithAuditID(ctx, "some-audit-id")
+	r, err := http.NewRequestWithContext(ctx, http.MethodPost, "https://pinniped.dev"+path, nil)
+	require.NoError(t, err)
+	return r
+}
+
+func execRequestInfo(subresource string) *genericapirequest.RequestInfo {
+	return &genericapirequest.RequestInfo{
+		IsResourceRequest: true,
+		Verb:              "create",
+		Resource:          "pods",
+		Subresource:       subresource,
+		Namespace:         "ns",
+		Name:              "pod",
+	}
+}
+
+func testAuditEventUserInfo() *auditEventUserInfo {
+	return &auditEventUserInfo{
+		User:             authenticationv1.UserInfo{Username: "alice", Groups: []string{"devs", "system:authenticated"}},
+		ImpersonatedUser: &authenticationv1.UserInfo{Username: "bob"},
+	}
+}
+
+type sessionRedactorFunc func(eventType string, data []byte) []byte
+
+func (f sessionRedactorFunc) Redact(eventType string, data []byte) []byte {
+	return f(eventType, data)
+}
+
+type fakeSessionSink struct {
+	openErr  error
+	closeErr error
+	opened   []*fakeSessionRecording
+}
+
+func (s *fakeSessionSink) Open(metadata *SessionMetadata) (io.WriteCloser, error) {
+	if s.openErr != nil {
+		return nil, s.openErr
+	}
+	recording := &fakeSessionRecording{metadata: metadata, closeErr: s.closeErr}
+	s.opened = append(s.opened, recording)
+	return recording, nil
+}
+
+type fakeSessionRecording struct {
+	metadata *SessionMetadata
+	buf      bytes.Buffer
+	closed   int
+	closeErr error
+}
+
+func (r *fakeSessionRecording) Write(p []byte) (int, error) {
+	return r.buf.Write(p)
+}
+
+func (r *fakeSessionRecording) Close() error {
+	r.closed++
+	return r.closeErr
+}
+
+// fakeBackendConn is the connection to the Kubernetes API server after it has switched protocols.
+type fakeBackendConn struct {
+	toRead  bytes.Buffer
+	written bytes.Buffer
+	closed  int
+}
+
+func (c *fakeBackendConn) Read(p []byte) (int, error) {
+	return c.toRead.Read(p)
+}
+
+func (c *fakeBackendConn) Write(p []byte) (int, error) {
+	return c.written.Write(p)
+}
+
+func (c *fakeBackendConn) Close() error {
+	c.closed++
+	return nil
+}
+
+type fakeUpgradeRoundTripper struct {
+	resp *http.Response
+	err  error
+}
+
+func (rt *fakeUpgradeRoundTripper) RoundTrip(_ *http.Request) (*http.Response, error) {
+	return rt.resp, rt.err
+}
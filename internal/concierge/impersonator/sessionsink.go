@@ -0,0 +1,123 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package impersonator
+
+import (
+	"bytes"
+	"context"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+
+	"go.pinniped.dev/internal/net/phttp"
+)
+
+// webhookSessionSinkTimeout limits how long it may take to upload a single recording.
+const webhookSessionSinkTimeout = time.Minute
+
+var _ SessionSink = &directorySessionSink{}
+
+type directorySessionSink struct {
+	directory string
+}
+
+// NewDirectorySessionSink returns a SessionSink which writes each recording to its own file in the given directory,
+// e.g. a persistent volume. Recordings are written as the session happens, so an interrupted session is still
+// recorded up to the point of interruption.
+func NewDirectorySessionSink(directory string) SessionSink {
+	return &directorySessionSink{directory: directory}
+}
+
+func (s *directorySessionSink) Open(metadata *SessionMetadata) (io.WriteCloser, error) {
+	// The recordings may contain sensitive data, so only the Concierge may read them.
+	f, err := os.OpenFile(filepath.Join(s.directory, metadata.Name), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("could not create recording file: %w", err)
+	}
+	return f, nil
+}
+
+var _ SessionSink = &webhookSessionSink{}
+
+type webhookSessionSink struct {
+	url    string
+	client *http.Client
+}
+
+// NewWebhookSessionSink returns a SessionSink which POSTs each recording to the given HTTPS URL once its session
+// has ended. Recordings are kept in memory until then, so their size is bounded by the size limit of the recorder.
+// When rootCAs is nil, the system's trusted CAs are used to verify the webhook's serving certificate.
+func NewWebhookSessionSink(url string, rootCAs *x509.CertPool) SessionSink {
+	return &webhookSessionSink{url: url, client: phttp.Default(rootCAs)}
+}
+
+func (s *webhookSessionSink) Open(metadata *SessionMetadata) (io.WriteCloser, error) {
+	return &webhookSessionRecording{sink: s, name: metadata.Name}, nil
+}
+
+type webhookSessionRecording struct {
+	sink *webhookSessionSink
+	name string
+	buf  bytes.Buffer
+}
+
+func (r *webhookSessionRecording) Write(p []byte) (int, error) {
+	return r.buf.Write(p)
+}
+
+func (r *webhookSessionRecording) Close() error {
+	// The session's request has usually ended by now, so its context cannot be used.
+	ctx, cancel := context.WithTimeout(context.Background(), webhookSessionSinkTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.sink.url, &r.buf)
+	if err != nil {
+		return fmt.Errorf("could not create webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-asciicast")
+	req.Header.Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", r.name))
+
+	resp, err := r.sink.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("could not send recording to webhook: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook responded with unexpected status code %d", resp.StatusCode)
+	}
+	return nil
+}
+
+var _ SessionRedactor = &regexpSessionRedactor{}
+
+type regexpSessionRedactor struct {
+	patterns []*regexp.Regexp
+}
+
+// NewRegexpSessionRedactor returns a SessionRedactor which replaces every match of the given regular expressions
+// (RE2 syntax) with "[REDACTED]".
+func NewRegexpSessionRedactor(patterns []string) (SessionRedactor, error) {
+	r := &regexpSessionRedactor{}
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("could not compile redaction pattern %q: %w", pattern, err)
+		}
+		r.patterns = append(r.patterns, re)
+	}
+	return r, nil
+}
+
+func (r *regexpSessionRedactor) Redact(_ string, data []byte) []byte {
+	for _, re := range r.patterns {
+		data = re.ReplaceAllLiteral(data, []byte("[REDACTED]"))
+	}
+	return data
+}
@@ -0,0 +1,119 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package impersonator
+
+import (
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"k8s.io/client-go/util/cert"
+
+	"go.pinniped.dev/internal/testutil/tlsserver"
+)
+
+func TestDirectorySessionSink(t *testing.T) {
+	dir := t.TempDir()
+	subject := NewDirectorySessionSink(dir)
+
+	w, err := subject.Open(&SessionMetadata{Name: "some-recording.cast"})
+	require.NoError(t, err)
+	_, err = w.Write([]byte("line 1\n"))
+	require.NoError(t, err)
+	_, err = w.Write([]byte("line 2\n"))
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+
+	path := filepath.Join(dir, "some-recording.cast")
+	contents, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, "line 1\nline 2\n", string(contents))
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+
+	// Existing recordings are never overwritten.
+	_, err = subject.Open(&SessionMetadata{Name: "some-recording.cast"})
+	require.ErrorContains(t, err, "could not create recording file: ")
+	require.ErrorIs(t, err, os.ErrExist)
+}
+
+func TestWebhookSessionSink(t *testing.T) {
+	tests := []struct {
+		name       string
+		statusCode int
+		wantErr    string
+	}{
+		{
+			name:       "success",
+			statusCode: http.StatusCreated,
+		},
+		{
+			name:       "failure",
+			statusCode: http.StatusInternalServerError,
+			wantErr:    "webhook responded with unexpected status code 500",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var received []string
+			server, serverCA := tlsserver.TestServerIPv4(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				require.Equal(t, http.MethodPost, r.Method)
+				require.Equal(t, "/recordings", r.URL.Path)
+				require.Equal(t, "application/x-asciicast", r.Header.Get("Content-Type"))
+				require.Equal(t, `attachment; filename="some-recording.cast"`, r.Header.Get("Content-Disposition"))
+				body, err := io.ReadAll(r.Body)
+				require.NoError(t, err)
+				received = append(received, string(body))
+				w.WriteHeader(tt.statusCode)
+			}), nil)
+
+			pool, err := cert.NewPoolFromBytes(serverCA)
+			require.NoError(t, err)
+			subject := NewWebhookSessionSink(server.URL+"/recordings", pool)
+
+			w, err := subject.Open(&SessionMetadata{Name: "some-recording.cast"})
+			require.NoError(t, err)
+			_, err = w.Write([]byte("line 1\n"))
+			require.NoError(t, err)
+			_, err = w.Write([]byte("line 2\n"))
+			require.NoError(t, err)
+			require.Empty(t, received, "nothing is sent until the session ends")
+
+			err = w.Close()
+			if tt.wantErr != "" {
+				require.EqualError(t, err, tt.wantErr)
+			} else {
+				require.NoError(t, err)
+			}
+			require.Equal(t, []string{"line 1\nline 2\n"}, received)
+		})
+	}
+}
+
+func TestWebhookSessionSinkUntrustedServer(t *testing.T) {
+	server, _ := tlsserver.TestServerIPv4(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("the webhook should not have been called")
+	}), nil)
+
+	w, err := NewWebhookSessionSink(server.URL, nil).Open(&SessionMetadata{Name: "some-recording.cast"})
+	require.NoError(t, err)
+	require.ErrorContains(t, w.Close(), "could not send recording to webhook: ")
+}
+
+func TestRegexpSessionRedactor(t *testing.T) {
+	subject, err := NewRegexpSessionRedactor([]string{`password=\S+`, `(?i)bearer [a-z0-9.]+`})
+	require.NoError(t, err)
+
+	require.Equal(t, "curl -H 'Authorization: [REDACTED]' https://example.com?[REDACTED]\n",
+		string(subject.Redact("o", []byte("curl -H 'Authorization: Bearer abc.123' https://example.com?password=hunter2\n"))))
+	require.Equal(t, "nothing to see here", string(subject.Redact("i", []byte("nothing to see here"))))
+
+	_, err = NewRegexpSessionRedactor([]string{"valid", "("})
+	require.EqualError(t, err, "could not compile redaction pattern \"(\": error parsing regexp: missing closing ): `(`")
+}
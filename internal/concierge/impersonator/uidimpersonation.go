@@ -0,0 +1,117 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package impersonator
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+	"k8s.io/apimachinery/pkg/util/version"
+	"k8s.io/client-go/discovery"
+	"k8s.io/utils/clock"
+
+	"go.pinniped.dev/internal/plog"
+)
+
+// The Impersonate-Uid header was added in Kubernetes 1.22.
+// See https://github.com/kubernetes/kubernetes/pull/99961.
+var minKubeVersionForUIDImpersonation = version.MajorMinor(1, 22)
+
+const (
+	// After a failed lookup, wait this long before trying again, doubling the wait after each further failure.
+	minUIDImpersonationRetryBackoff = time.Second
+	maxUIDImpersonationRetryBackoff = 5 * time.Minute
+)
+
+// uidImpersonationDetector uses discovery to decide if the Kubernetes API server supports impersonating UIDs.
+// The result is only looked up once it is needed, and it is remembered once the lookup succeeds.
+// A nil *uidImpersonationDetector never supports UID impersonation.
+type uidImpersonationDetector struct {
+	client discovery.ServerVersionInterface
+	clock  clock.PassiveClock
+
+	// lookups makes concurrent callers share a single lookup, which is made without holding the lock.
+	lookups singleflight.Group
+
+	lock         sync.Mutex
+	supported    *bool
+	retryAfter   time.Time
+	retryBackoff time.Duration
+}
+
+func newUIDImpersonationDetector(client discovery.ServerVersionInterface, clock clock.PassiveClock) *uidImpersonationDetector {
+	return &uidImpersonationDetector{client: client, clock: clock}
+}
+
+// supportsUIDImpersonation returns true when the Kubernetes API server is known to support the Impersonate-Uid
+// header. When the Kubernetes API server's version cannot be determined, it returns false and does not try again
+// until a backoff has passed, so callers fall back to behavior which works on all clusters in the meantime.
+func (d *uidImpersonationDetector) supportsUIDImpersonation() bool {
+	if d == nil {
+		return false
+	}
+
+	if supported, known := d.cached(); known {
+		return supported
+	}
+
+	supported, _, _ := d.lookups.Do("", func() (any, error) {
+		// Another caller's lookup may have finished since this caller checked the cache.
+		if supported, known := d.cached(); known {
+			return supported, nil
+		}
+		return d.lookup(), nil
+	})
+	return supported.(bool)
+}
+
+// cached returns the remembered result of a successful lookup, or false while waiting to retry a failed lookup.
+// It returns known=false when a new lookup is needed.
+func (d *uidImpersonationDetector) cached() (supported bool, known bool) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	if d.supported != nil {
+		return *d.supported, true
+	}
+	if d.clock.Now().Before(d.retryAfter) {
+		return false, true
+	}
+	return false, false
+}
+
+func (d *uidImpersonationDetector) lookup() bool {
+	supported, err := d.detect()
+
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	if err != nil {
+		d.retryBackoff = min(max(2*d.retryBackoff, minUIDImpersonationRetryBackoff), maxUIDImpersonationRetryBackoff)
+		d.retryAfter = d.clock.Now().Add(d.retryBackoff)
+		plog.WarningErr("could not determine if the Kubernetes API server supports UID impersonation", err,
+			"retryAfter", d.retryBackoff)
+		return false
+	}
+
+	plog.Debug("determined if the Kubernetes API server supports UID impersonation", "supported", supported)
+	d.supported = &supported
+	return supported
+}
+
+func (d *uidImpersonationDetector) detect() (bool, error) {
+	info, err := d.client.ServerVersion()
+	if err != nil {
+		return false, fmt.Errorf("could not get server version: %w", err)
+	}
+
+	serverVersion, err := version.ParseGeneric(info.GitVersion)
+	if err != nil {
+		return false, fmt.Errorf("could not parse server version %q: %w", info.GitVersion, err)
+	}
+
+	return serverVersion.AtLeast(minKubeVersionForUIDImpersonation), nil
+}
@@ -0,0 +1,169 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package impersonator
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/version"
+	clocktesting "k8s.io/utils/clock/testing"
+)
+
+func TestUIDImpersonationDetector(t *testing.T) {
+	tests := []struct {
+		name          string
+		gitVersion    string
+		err           error
+		wantSupported bool
+		wantCached    bool
+	}{
+		{
+			name:          "new enough",
+			gitVersion:    "v1.22.0",
+			wantSupported: true,
+			wantCached:    true,
+		},
+		{
+			name:          "much newer with a vendor suffix",
+			gitVersion:    "v1.36.1-gke.1200",
+			wantSupported: true,
+			wantCached:    true,
+		},
+		{
+			name:          "too old",
+			gitVersion:    "v1.21.14",
+			wantSupported: false,
+			wantCached:    true,
+		},
+		{
+			name:          "version cannot be parsed",
+			gitVersion:    "not-a-version",
+			wantSupported: false,
+			wantCached:    false,
+		},
+		{
+			name:          "discovery fails",
+			gitVersion:    "v1.30.0",
+			err:           errors.New("some discovery error"),
+			wantSupported: false,
+			wantCached:    false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fakeDiscovery := newFakeDiscovery(tt.gitVersion, tt.err)
+			fakeClock := clocktesting.NewFakeClock(time.Now())
+			subject := newUIDImpersonationDetector(fakeDiscovery, fakeClock)
+
+			require.Equal(t, tt.wantSupported, subject.supportsUIDImpersonation())
+			require.Len(t, fakeDiscovery.Actions(), 1)
+
+			// Successful lookups are remembered, and failed lookups are not tried again until the backoff has passed.
+			require.Equal(t, tt.wantSupported, subject.supportsUIDImpersonation())
+			require.Len(t, fakeDiscovery.Actions(), 1)
+
+			fakeClock.Step(minUIDImpersonationRetryBackoff)
+			require.Equal(t, tt.wantSupported, subject.supportsUIDImpersonation())
+			if tt.wantCached {
+				require.Len(t, fakeDiscovery.Actions(), 1)
+			} else {
+				require.Len(t, fakeDiscovery.Actions(), 2)
+			}
+		})
+	}
+}
+
+func TestUIDImpersonationDetectorStartsWorkingAfterAnError(t *testing.T) {
+	fakeDiscovery := newFakeDiscovery("", nil)
+	fakeDiscovery.FakedServerVersion = &version.Info{GitVersion: "garbage"}
+	fakeClock := clocktesting.NewFakeClock(time.Now())
+	subject := newUIDImpersonationDetector(fakeDiscovery, fakeClock)
+
+	require.False(t, subject.supportsUIDImpersonation())
+
+	fakeDiscovery.FakedServerVersion = &version.Info{GitVersion: "v1.30.2"}
+	require.False(t, subject.supportsUIDImpersonation())
+
+	fakeClock.Step(minUIDImpersonationRetryBackoff)
+	require.True(t, subject.supportsUIDImpersonation())
+	require.True(t, subject.supportsUIDImpersonation())
+	require.Len(t, fakeDiscovery.Actions(), 2)
+}
+
+func TestUIDImpersonationDetectorBacksOffAfterRepeatedErrors(t *testing.T) {
+	fakeDiscovery := newFakeDiscovery("v1.30.0", errors.New("some discovery error"))
+	fakeClock := clocktesting.NewFakeClock(time.Now())
+	subject := newUIDImpersonationDetector(fakeDiscovery, fakeClock)
+
+	wantLookups := 0
+	for _, wantBackoff := range []time.Duration{
+		time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second, 16 * time.Second, 32 * time.Second,
+		64 * time.Second, 128 * time.Second, 256 * time.Second, 5 * time.Minute, 5 * time.Minute,
+	} {
+		require.False(t, subject.supportsUIDImpersonation())
+		wantLookups++
+		require.Len(t, fakeDiscovery.Actions(), wantLookups)
+
+		fakeClock.Step(wantBackoff - time.Millisecond)
+		require.False(t, subject.supportsUIDImpersonation())
+		require.Len(t, fakeDiscovery.Actions(), wantLookups, "should not retry before the backoff of %s has passed", wantBackoff)
+
+		fakeClock.Step(time.Millisecond)
+	}
+}
+
+// blockingServerVersion is a discovery.ServerVersionInterface which does not return until it is unblocked.
+type blockingServerVersion struct {
+	started chan struct{}
+	unblock chan struct{}
+	calls   atomic.Int32
+}
+
+func (b *blockingServerVersion) ServerVersion() (*version.Info, error) {
+	if b.calls.Add(1) == 1 {
+		close(b.started)
+	}
+	<-b.unblock
+	return &version.Info{GitVersion: "v1.30.0"}, nil
+}
+
+func TestUIDImpersonationDetectorConcurrentCallersShareOneLookup(t *testing.T) {
+	serverVersion := &blockingServerVersion{started: make(chan struct{}), unblock: make(chan struct{})}
+	subject := newUIDImpersonationDetector(serverVersion, clocktesting.NewFakeClock(time.Now()))
+
+	const callers = 10
+	results := make(chan bool, callers)
+	var wg sync.WaitGroup
+	for range callers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results <- subject.supportsUIDImpersonation()
+		}()
+	}
+
+	// The lock must not be held while waiting for the Kubernetes API server.
+	<-serverVersion.started
+	require.True(t, subject.lock.TryLock())
+	subject.lock.Unlock()
+
+	close(serverVersion.unblock)
+	wg.Wait()
+	close(results)
+
+	for supported := range results {
+		require.True(t, supported)
+	}
+	require.Equal(t, int32(1), serverVersion.calls.Load())
+}
+
+func TestUIDImpersonationDetectorNil(t *testing.T) {
+	var subject *uidImpersonationDetector
+	require.False(t, subject.supportsUIDImpersonation())
+}
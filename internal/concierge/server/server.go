@@ -1,4 +1,4 @@
-// Copyright 2020-2025 the Pinniped contributors. All Rights Reserved.
+// Copyright 2020-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 // Package server is the command line entry point for pinniped-concierge.
@@ -26,6 +26,7 @@ import (
 	"go.pinniped.dev/internal/admissionpluginconfig"
 	"go.pinniped.dev/internal/certauthority/dynamiccertauthority"
 	"go.pinniped.dev/internal/clientcertissuer"
+	"go.pinniped.dev/internal/clientcertissuer/csrissuer"
 	"go.pinniped.dev/internal/concierge/apiserver"
 	conciergescheme "go.pinniped.dev/internal/concierge/scheme"
 	"go.pinniped.dev/internal/config/concierge"
@@ -38,6 +39,7 @@ import (
 	"go.pinniped.dev/internal/dynamiccert"
 	"go.pinniped.dev/internal/here"
 	"go.pinniped.dev/internal/kubeclient"
+	"go.pinniped.dev/internal/metrics"
 	"go.pinniped.dev/internal/plog"
 	"go.pinniped.dev/internal/pversion"
 	"go.pinniped.dev/internal/registry/credentialrequest"
@@ -150,6 +152,10 @@ func (a *App) runServer(ctx context.Context) error {
 
 	impersonationProxyTokenCache := tokenclient.NewExpiringSingletonTokenCache()
 
+	auditLogger := plog.NewAuditLogger(plog.AuditLogConfig{
+		LogUsernamesAndGroupNames: cfg.Audit.LogUsernamesAndGroups.Enabled(),
+	})
+
 	// Prepare to start the controllers, but defer actually starting them until the
 	// post start hook of the aggregated API server.
 	buildControllers, err := controllermanager.PrepareControllers(
@@ -159,6 +165,8 @@ func (a *App) runServer(ctx context.Context) error {
 			NamesConfig:                      &cfg.NamesConfig,
 			Labels:                           cfg.Labels,
 			KubeCertAgentConfig:              &cfg.KubeCertAgentConfig,
+			CertificateSigningRequestConfig:  &cfg.CertificateSigningRequestConfig,
+			SessionRecordingConfig:           &cfg.ImpersonationProxySessionRecording,
 			DiscoveryURLOverride:             cfg.DiscoveryInfo.URL,
 			DynamicServingCertProvider:       dynamicServingCertProvider,
 			DynamicSigningCertProvider:       dynamicSigningCertProvider,
@@ -169,21 +177,13 @@ func (a *App) runServer(ctx context.Context) error {
 			// This port should be safe to cast because the config reader already validated it.
 			ImpersonationProxyServerPort: int(*cfg.ImpersonationProxyServerPort),
 			ImpersonationProxyTokenCache: impersonationProxyTokenCache,
+			AuditLogger:                  auditLogger,
 		},
 	)
 	if err != nil {
 		return fmt.Errorf("could not prepare controllers: %w", err)
 	}
 
-	certIssuer := clientcertissuer.ClientCertIssuers{
-		dynamiccertauthority.New(dynamicSigningCertProvider),            // attempt to use the real Kube CA if possible
-		dynamiccertauthority.New(impersonationProxySigningCertProvider), // fallback to our internal CA if we need to
-	}
-
-	auditLogger := plog.NewAuditLogger(plog.AuditLogConfig{
-		LogUsernamesAndGroupNames: cfg.Audit.LogUsernamesAndGroups.Enabled(),
-	})
-
 	// Configure a token client that retrieves relatively short-lived tokens from the API server.
 	// It uses a k8s client without leader election because all pods need tokens.
 	// This k8s client should not be reused for other purposes.
@@ -201,6 +201,21 @@ func (a *App) runServer(ctx context.Context) error {
 		plog.New(),
 		tokenclient.WithExpirationSeconds(oneDayInSeconds))
 
+	certIssuer := clientcertissuer.ClientCertIssuers{
+		dynamiccertauthority.New(dynamicSigningCertProvider), // attempt to use the real Kube CA if possible
+	}
+	if cfg.CertificateSigningRequestConfig.Enabled() {
+		// Next, ask the Kube API server to sign a CSR if that strategy was enabled.
+		// It uses a k8s client without leader election because all pods need to issue certs.
+		csrClient, err := kubeclient.New()
+		if err != nil {
+			return fmt.Errorf("could not create kubernetes client for certificate signing requests: %w", err)
+		}
+		certIssuer = append(certIssuer, csrissuer.New(csrClient.Kubernetes, *cfg.CertificateSigningRequestConfig.ExpirationSeconds))
+	}
+	// Fallback to our internal CA if we need to.
+	certIssuer = append(certIssuer, dynamiccertauthority.New(impersonationProxySigningCertProvider))
+
 	// Get the aggregated API server config.
 	aggregatedAPIServerConfig, err := getAggregatedAPIServerConfig(
 		dynamicServingCertProvider,
@@ -226,6 +241,12 @@ func (a *App) runServer(ctx context.Context) error {
 		return fmt.Errorf("could not create aggregated API server: %w", err)
 	}
 
+	if cfg.Metrics.Port != nil {
+		if err := metrics.Serve(ctx, *cfg.Metrics.Port); err != nil {
+			return err
+		}
+	}
+
 	// Run the server. Its post-start hook will start the controllers. Its pre shutdown hook will be called when ctx is
 	// cancelled, and that hook should graceful stop the controllers and give up the leader election lease. See the
 	// code for these hooks in internal/concierge/apiserver.go.
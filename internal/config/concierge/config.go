@@ -1,4 +1,4 @@
-// Copyright 2020-2025 the Pinniped contributors. All Rights Reserved.
+// Copyright 2020-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 // Package concierge contains functionality to load/store Config's from/to
@@ -8,7 +8,11 @@ package concierge
 import (
 	"context"
 	"fmt"
+	"net/url"
 	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
 	"strings"
 
 	appsv1 "k8s.io/api/apps/v1"
@@ -38,6 +42,15 @@ const (
 	// impersonation proxy, and has been the value since. It was originally selected because the
 	// aggregated API server used to run on 8443 (has since changed), so 8444 was the next available port.
 	impersonationProxyPortDefault = 8444
+
+	// Kubernetes rejects CertificateSigningRequests which ask for a shorter expiration than 10 minutes.
+	minCertificateSigningRequestExpirationSeconds = 600
+
+	// Big enough for a few screens of output, yet small enough to keep in memory while it is sent to a webhook.
+	sessionRecordingMaxBytesPerSessionDefault = 10 * 1024 * 1024
+
+	// Every recording starts with a header line which describes its session, so smaller limits make no sense.
+	minSessionRecordingMaxBytesPerSession = 4096
 )
 
 // FromPath loads a Config from a provided local file path, inserts any
@@ -63,6 +76,8 @@ func FromPath(ctx context.Context, path string, setAllowedCiphers ptls.SetAllowe
 	maybeSetImpersonationProxyServerPortDefaults(&config.ImpersonationProxyServerPort)
 	maybeSetAPIGroupSuffixDefault(&config.APIGroupSuffix)
 	maybeSetKubeCertAgentDefaults(&config.KubeCertAgentConfig)
+	maybeSetCertificateSigningRequestDefaults(&config.CertificateSigningRequestConfig)
+	maybeSetSessionRecordingDefaults(&config.ImpersonationProxySessionRecording)
 
 	if err := validateAPI(&config.APIConfig); err != nil {
 		return nil, fmt.Errorf("validate api: %w", err)
@@ -104,6 +119,18 @@ func FromPath(ctx context.Context, path string, setAllowedCiphers ptls.SetAllowe
 		return nil, fmt.Errorf("validate audit: %w", err)
 	}
 
+	if err := validateMetrics(&config.Metrics, *config.AggregatedAPIServerPort, *config.ImpersonationProxyServerPort); err != nil {
+		return nil, fmt.Errorf("validate metrics: %w", err)
+	}
+
+	if err := validateCertificateSigningRequest(&config.CertificateSigningRequestConfig); err != nil {
+		return nil, fmt.Errorf("validate certificateSigningRequest: %w", err)
+	}
+
+	if err := validateSessionRecording(&config.ImpersonationProxySessionRecording); err != nil {
+		return nil, fmt.Errorf("validate impersonationProxySessionRecording: %w", err)
+	}
+
 	if config.Labels == nil {
 		config.Labels = make(map[string]string)
 	}
@@ -149,6 +176,18 @@ func maybeSetKubeCertAgentDefaults(cfg *KubeCertAgentSpec) {
 	}
 }
 
+func maybeSetCertificateSigningRequestDefaults(cfg *CertificateSigningRequestSpec) {
+	if cfg.ExpirationSeconds == nil {
+		cfg.ExpirationSeconds = ptr.To[int32](minCertificateSigningRequestExpirationSeconds)
+	}
+}
+
+func maybeSetSessionRecordingDefaults(cfg *SessionRecordingSpec) {
+	if cfg.MaxBytesPerSession == nil {
+		cfg.MaxBytesPerSession = ptr.To[int64](sessionRecordingMaxBytesPerSessionDefault)
+	}
+}
+
 func validateNames(names *NamesConfigSpec) error {
 	missingNames := []string{}
 	if names == nil {
@@ -254,3 +293,71 @@ func validateAudit(auditConfig *AuditSpec) error {
 	}
 	return nil
 }
+
+func validateCertificateSigningRequest(cfg *CertificateSigningRequestSpec) error {
+	if cfg.Mode != "" && cfg.Mode != Enabled && cfg.Mode != Disabled {
+		return constable.Error("invalid mode, valid choices are 'enabled', 'disabled', or empty string (equivalent to 'disabled')")
+	}
+	if *cfg.ExpirationSeconds < minCertificateSigningRequestExpirationSeconds {
+		return constable.Error(fmt.Sprintf("expirationSeconds must be %d or greater (instead of %d)",
+			minCertificateSigningRequestExpirationSeconds, *cfg.ExpirationSeconds))
+	}
+	return nil
+}
+
+func validateSessionRecording(cfg *SessionRecordingSpec) error {
+	if cfg.Mode != "" && cfg.Mode != Enabled && cfg.Mode != Disabled {
+		return constable.Error("invalid mode, valid choices are 'enabled', 'disabled', or empty string (equivalent to 'disabled')")
+	}
+	if !cfg.Enabled() {
+		return nil
+	}
+	if *cfg.MaxBytesPerSession < minSessionRecordingMaxBytesPerSession {
+		return constable.Error(fmt.Sprintf("maxBytesPerSession must be %d or greater (instead of %d)",
+			minSessionRecordingMaxBytesPerSession, *cfg.MaxBytesPerSession))
+	}
+	for _, pattern := range cfg.RedactPatterns {
+		if _, err := regexp.Compile(pattern); err != nil {
+			return fmt.Errorf("invalid redactPatterns: %w", err)
+		}
+	}
+	if (cfg.Directory == "") == (cfg.Webhook == nil) {
+		return constable.Error("exactly one of directory or webhook must be configured")
+	}
+	if cfg.Directory != "" && !filepath.IsAbs(cfg.Directory) {
+		return constable.Error("directory must be an absolute path")
+	}
+	if cfg.Webhook != nil {
+		if err := validateSessionRecordingWebhook(cfg.Webhook); err != nil {
+			return fmt.Errorf("webhook: %w", err)
+		}
+	}
+	return nil
+}
+
+func validateSessionRecordingWebhook(cfg *SessionRecordingWebhookSpec) error {
+	u, err := url.Parse(cfg.URL)
+	if err != nil {
+		return fmt.Errorf("invalid url: %w", err)
+	}
+	if u.Scheme != "https" || u.Host == "" {
+		return constable.Error("url must be an https URL")
+	}
+	if _, err := cfg.CertPool(); err != nil {
+		return err
+	}
+	return nil
+}
+
+func validateMetrics(metricsConfig *MetricsSpec, otherPorts ...int64) error {
+	if metricsConfig.Port == nil {
+		return nil
+	}
+	if err := validateServerPort(metricsConfig.Port); err != nil {
+		return fmt.Errorf("port: %w", err)
+	}
+	if slices.Contains(otherPorts, *metricsConfig.Port) {
+		return fmt.Errorf("port %d is already used by another listener", *metricsConfig.Port)
+	}
+	return nil
+}
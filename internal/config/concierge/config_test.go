@@ -5,15 +5,18 @@ package concierge
 
 import (
 	"context"
+	"encoding/base64"
 	"fmt"
 	"os"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 	appsv1 "k8s.io/api/apps/v1"
 	"k8s.io/utils/ptr"
 
+	"go.pinniped.dev/internal/certauthority"
 	"go.pinniped.dev/internal/here"
 	"go.pinniped.dev/internal/plog"
 )
@@ -22,6 +25,27 @@ func TestFromPath(t *testing.T) {
 	stringOfLength253 := strings.Repeat("a", 253)
 	stringOfLength254 := strings.Repeat("a", 254)
 
+	ca, err := certauthority.New("test-ca", time.Hour)
+	require.NoError(t, err)
+	caBundleBase64 := base64.StdEncoding.EncodeToString(ca.Bundle())
+
+	// All the required config, which error cases can add to.
+	namesYAML := here.Doc(`
+		---
+		names:
+		  servingCertificateSecret: pinniped-concierge-api-tls-serving-certificate
+		  credentialIssuer: pinniped-config
+		  apiService: pinniped-api
+		  impersonationLoadBalancerService: impersonationLoadBalancerService-value
+		  impersonationClusterIPService: impersonationClusterIPService-value
+		  impersonationTLSCertificateSecret: impersonationTLSCertificateSecret-value
+		  impersonationCACertificateSecret: impersonationCACertificateSecret-value
+		  impersonationSignerSecret: impersonationSignerSecret-value
+		  agentServiceAccount: agentServiceAccount-value
+		  impersonationProxyServiceAccount: impersonationProxyServiceAccount-value
+		  impersonationProxyLegacySecret: impersonationProxyLegacySecret-value
+	`)
+
 	tests := []struct {
 		name                string
 		yaml                string
@@ -83,6 +107,17 @@ func TestFromPath(t *testing.T) {
 					- TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305
 				audit:
 				  logUsernamesAndGroups: enabled
+				metrics:
+				  port: 9090
+				certificateSigningRequest:
+				  mode: enabled
+				  expirationSeconds: 3600
+				impersonationProxySessionRecording:
+				  mode: enabled
+				  maxBytesPerSession: 1048576
+				  redactPatterns:
+				  - password=\S+
+				  directory: /var/lib/pinniped/recordings
 			`, stringOfLength253),
 			wantConfig: &Config{
 				DiscoveryInfo: DiscoveryInfoSpec{
@@ -145,6 +180,19 @@ func TestFromPath(t *testing.T) {
 				Audit: AuditSpec{
 					LogUsernamesAndGroups: "enabled",
 				},
+				Metrics: MetricsSpec{
+					Port: ptr.To[int64](9090),
+				},
+				CertificateSigningRequestConfig: CertificateSigningRequestSpec{
+					Mode:              "enabled",
+					ExpirationSeconds: ptr.To[int32](3600),
+				},
+				ImpersonationProxySessionRecording: SessionRecordingSpec{
+					Mode:               "enabled",
+					MaxBytesPerSession: ptr.To[int64](1048576),
+					RedactPatterns:     []string{`password=\S+`},
+					Directory:          "/var/lib/pinniped/recordings",
+				},
 			},
 		},
 		{
@@ -250,6 +298,12 @@ func TestFromPath(t *testing.T) {
 				Audit: AuditSpec{
 					LogUsernamesAndGroups: "disabled",
 				},
+				CertificateSigningRequestConfig: CertificateSigningRequestSpec{
+					ExpirationSeconds: ptr.To[int32](600),
+				},
+				ImpersonationProxySessionRecording: SessionRecordingSpec{
+					MaxBytesPerSession: ptr.To[int64](10485760),
+				},
 			},
 		},
 		{
@@ -347,6 +401,12 @@ func TestFromPath(t *testing.T) {
 				AggregatedAPIServerDisableAdmissionPlugins: nil,
 				TLS: TLSSpec{},
 				Log: plog.LogSpec{},
+				CertificateSigningRequestConfig: CertificateSigningRequestSpec{
+					ExpirationSeconds: ptr.To[int32](600),
+				},
+				ImpersonationProxySessionRecording: SessionRecordingSpec{
+					MaxBytesPerSession: ptr.To[int64](10485760),
+				},
 			},
 		},
 		{
@@ -705,6 +765,49 @@ func TestFromPath(t *testing.T) {
 			allowedCiphersError: fmt.Errorf("some error from setAllowedCiphers"),
 			wantError:           "validate tls: some error from setAllowedCiphers",
 		},
+		{
+			name: "metrics port too large",
+			yaml: here.Doc(`
+				---
+				names:
+				  servingCertificateSecret: pinniped-concierge-api-tls-serving-certificate
+				  credentialIssuer: pinniped-config
+				  apiService: pinniped-api
+				  impersonationLoadBalancerService: impersonationLoadBalancerService-value
+				  impersonationClusterIPService: impersonationClusterIPService-value
+				  impersonationTLSCertificateSecret: impersonationTLSCertificateSecret-value
+				  impersonationCACertificateSecret: impersonationCACertificateSecret-value
+				  impersonationSignerSecret: impersonationSignerSecret-value
+				  agentServiceAccount: agentServiceAccount-value
+				  impersonationProxyServiceAccount: impersonationProxyServiceAccount-value
+				  impersonationProxyLegacySecret: impersonationProxyLegacySecret-value
+				metrics:
+				  port: 65536
+			`),
+			wantError: "validate metrics: port: must be within range 1024 to 65535",
+		},
+		{
+			name: "metrics port conflicts with impersonationProxyServerPort",
+			yaml: here.Doc(`
+				---
+				names:
+				  servingCertificateSecret: pinniped-concierge-api-tls-serving-certificate
+				  credentialIssuer: pinniped-config
+				  apiService: pinniped-api
+				  impersonationLoadBalancerService: impersonationLoadBalancerService-value
+				  impersonationClusterIPService: impersonationClusterIPService-value
+				  impersonationTLSCertificateSecret: impersonationTLSCertificateSecret-value
+				  impersonationCACertificateSecret: impersonationCACertificateSecret-value
+				  impersonationSignerSecret: impersonationSignerSecret-value
+				  agentServiceAccount: agentServiceAccount-value
+				  impersonationProxyServiceAccount: impersonationProxyServiceAccount-value
+				  impersonationProxyLegacySecret: impersonationProxyLegacySecret-value
+				impersonationProxyServerPort: 4242
+				metrics:
+				  port: 4242
+			`),
+			wantError: "validate metrics: port 4242 is already used by another listener",
+		},
 		{
 			name: "invalid audit.logUsernamesAndGroups format",
 			yaml: here.Doc(`
@@ -727,6 +830,190 @@ func TestFromPath(t *testing.T) {
 			`),
 			wantError: "validate audit: invalid logUsernamesAndGroups format, valid choices are 'enabled', 'disabled', or empty string (equivalent to 'disabled')",
 		},
+		{
+			name: "invalid certificateSigningRequest.mode",
+			yaml: here.Doc(`
+				---
+				names:
+				  servingCertificateSecret: pinniped-concierge-api-tls-serving-certificate
+				  credentialIssuer: pinniped-config
+				  apiService: pinniped-api
+				  impersonationLoadBalancerService: impersonationLoadBalancerService-value
+				  impersonationClusterIPService: impersonationClusterIPService-value
+				  impersonationTLSCertificateSecret: impersonationTLSCertificateSecret-value
+				  impersonationCACertificateSecret: impersonationCACertificateSecret-value
+				  impersonationSignerSecret: impersonationSignerSecret-value
+				  impersonationSignerSecret: impersonationSignerSecret-value
+				  agentServiceAccount: agentServiceAccount-value
+				  impersonationProxyServiceAccount: impersonationProxyServiceAccount-value
+				  impersonationProxyLegacySecret: impersonationProxyLegacySecret-value
+				certificateSigningRequest:
+				  mode: this-value-is-not-allowed
+			`),
+			wantError: "validate certificateSigningRequest: invalid mode, valid choices are 'enabled', 'disabled', or empty string (equivalent to 'disabled')",
+		},
+		{
+			name: "certificateSigningRequest.expirationSeconds too small",
+			yaml: here.Doc(`
+				---
+				names:
+				  servingCertificateSecret: pinniped-concierge-api-tls-serving-certificate
+				  credentialIssuer: pinniped-config
+				  apiService: pinniped-api
+				  impersonationLoadBalancerService: impersonationLoadBalancerService-value
+				  impersonationClusterIPService: impersonationClusterIPService-value
+				  impersonationTLSCertificateSecret: impersonationTLSCertificateSecret-value
+				  impersonationCACertificateSecret: impersonationCACertificateSecret-value
+				  impersonationSignerSecret: impersonationSignerSecret-value
+				  impersonationSignerSecret: impersonationSignerSecret-value
+				  agentServiceAccount: agentServiceAccount-value
+				  impersonationProxyServiceAccount: impersonationProxyServiceAccount-value
+				  impersonationProxyLegacySecret: impersonationProxyLegacySecret-value
+				certificateSigningRequest:
+				  mode: enabled
+				  expirationSeconds: 599
+			`),
+			wantError: "validate certificateSigningRequest: expirationSeconds must be 600 or greater (instead of 599)",
+		},
+		{
+			name: "impersonationProxySessionRecording to a webhook",
+			yaml: namesYAML + here.Docf(`
+				impersonationProxySessionRecording:
+				  mode: enabled
+				  webhook:
+				    url: https://recordings.example.com/upload
+				    certificateAuthorityData: %s
+			`, caBundleBase64),
+			wantConfig: &Config{
+				APIGroupSuffix:               ptr.To("pinniped.dev"),
+				AggregatedAPIServerPort:      ptr.To[int64](10250),
+				ImpersonationProxyServerPort: ptr.To[int64](8444),
+				APIConfig: APIConfigSpec{
+					ServingCertificateConfig: ServingCertificateConfigSpec{
+						DurationSeconds:    ptr.To[int64](60 * 60 * 24 * 365),    // about a year
+						RenewBeforeSeconds: ptr.To[int64](60 * 60 * 24 * 30 * 9), // about 9 months
+					},
+				},
+				//nolint:gosec // no credentials here
+				NamesConfig: NamesConfigSpec{
+					ServingCertificateSecret:          "pinniped-concierge-api-tls-serving-certificate",
+					CredentialIssuer:                  "pinniped-config",
+					APIService:                        "pinniped-api",
+					ImpersonationLoadBalancerService:  "impersonationLoadBalancerService-value",
+					ImpersonationClusterIPService:     "impersonationClusterIPService-value",
+					ImpersonationTLSCertificateSecret: "impersonationTLSCertificateSecret-value",
+					ImpersonationCACertificateSecret:  "impersonationCACertificateSecret-value",
+					ImpersonationSignerSecret:         "impersonationSignerSecret-value",
+					AgentServiceAccount:               "agentServiceAccount-value",
+					ImpersonationProxyServiceAccount:  "impersonationProxyServiceAccount-value",
+					ImpersonationProxyLegacySecret:    "impersonationProxyLegacySecret-value",
+				},
+				Labels: map[string]string{},
+				KubeCertAgentConfig: KubeCertAgentSpec{
+					NamePrefix: ptr.To("pinniped-kube-cert-agent-"),
+					Image:      ptr.To("debian:latest"),
+				},
+				CertificateSigningRequestConfig: CertificateSigningRequestSpec{
+					ExpirationSeconds: ptr.To[int32](600),
+				},
+				ImpersonationProxySessionRecording: SessionRecordingSpec{
+					Mode:               "enabled",
+					MaxBytesPerSession: ptr.To[int64](10485760),
+					Webhook: &SessionRecordingWebhookSpec{
+						URL:                      "https://recordings.example.com/upload",
+						CertificateAuthorityData: caBundleBase64,
+					},
+				},
+			},
+		},
+		{
+			name: "invalid impersonationProxySessionRecording.mode",
+			yaml: namesYAML + here.Doc(`
+				impersonationProxySessionRecording:
+				  mode: this-value-is-not-allowed
+			`),
+			wantError: "validate impersonationProxySessionRecording: invalid mode, valid choices are 'enabled', 'disabled', or empty string (equivalent to 'disabled')",
+		},
+		{
+			name: "impersonationProxySessionRecording.maxBytesPerSession too small",
+			yaml: namesYAML + here.Doc(`
+				impersonationProxySessionRecording:
+				  mode: enabled
+				  maxBytesPerSession: 4095
+				  directory: /recordings
+			`),
+			wantError: "validate impersonationProxySessionRecording: maxBytesPerSession must be 4096 or greater (instead of 4095)",
+		},
+		{
+			name: "invalid impersonationProxySessionRecording.redactPatterns",
+			yaml: namesYAML + here.Doc(`
+				impersonationProxySessionRecording:
+				  mode: enabled
+				  redactPatterns: ["("]
+				  directory: /recordings
+			`),
+			wantError: "validate impersonationProxySessionRecording: invalid redactPatterns: error parsing regexp: missing closing ): `(`",
+		},
+		{
+			name: "impersonationProxySessionRecording without a sink",
+			yaml: namesYAML + here.Doc(`
+				impersonationProxySessionRecording:
+				  mode: enabled
+			`),
+			wantError: "validate impersonationProxySessionRecording: exactly one of directory or webhook must be configured",
+		},
+		{
+			name: "impersonationProxySessionRecording with two sinks",
+			yaml: namesYAML + here.Doc(`
+				impersonationProxySessionRecording:
+				  mode: enabled
+				  directory: /recordings
+				  webhook:
+				    url: https://recordings.example.com/upload
+			`),
+			wantError: "validate impersonationProxySessionRecording: exactly one of directory or webhook must be configured",
+		},
+		{
+			name: "impersonationProxySessionRecording.directory is relative",
+			yaml: namesYAML + here.Doc(`
+				impersonationProxySessionRecording:
+				  mode: enabled
+				  directory: recordings
+			`),
+			wantError: "validate impersonationProxySessionRecording: directory must be an absolute path",
+		},
+		{
+			name: "impersonationProxySessionRecording.webhook.url is not https",
+			yaml: namesYAML + here.Doc(`
+				impersonationProxySessionRecording:
+				  mode: enabled
+				  webhook:
+				    url: http://recordings.example.com/upload
+			`),
+			wantError: "validate impersonationProxySessionRecording: webhook: url must be an https URL",
+		},
+		{
+			name: "impersonationProxySessionRecording.webhook.certificateAuthorityData is not base64",
+			yaml: namesYAML + here.Doc(`
+				impersonationProxySessionRecording:
+				  mode: enabled
+				  webhook:
+				    url: https://recordings.example.com/upload
+				    certificateAuthorityData: "!!!"
+			`),
+			wantError: "validate impersonationProxySessionRecording: webhook: certificateAuthorityData is not valid base64: illegal base64 data at input byte 0",
+		},
+		{
+			name: "impersonationProxySessionRecording.webhook.certificateAuthorityData is not PEM",
+			yaml: namesYAML + here.Docf(`
+				impersonationProxySessionRecording:
+				  mode: enabled
+				  webhook:
+				    url: https://recordings.example.com/upload
+				    certificateAuthorityData: %s
+			`, base64.StdEncoding.EncodeToString([]byte("not a cert"))),
+			wantError: "validate impersonationProxySessionRecording: webhook: certificateAuthorityData does not contain any PEM certificates",
+		},
 		{
 			name: "invalid kubeCertAgent.priorityClassName length",
 			yaml: here.Docf(`
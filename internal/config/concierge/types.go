@@ -1,11 +1,16 @@
-// Copyright 2020-2025 the Pinniped contributors. All Rights Reserved.
+// Copyright 2020-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package concierge
 
 import (
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+
 	appsv1 "k8s.io/api/apps/v1"
 
+	"go.pinniped.dev/internal/constable"
 	"go.pinniped.dev/internal/plog"
 )
 
@@ -28,6 +33,11 @@ type Config struct {
 	Log                                        plog.LogSpec      `json:"log"`
 	TLS                                        TLSSpec           `json:"tls"`
 	Audit                                      AuditSpec         `json:"audit"`
+	Metrics                                    MetricsSpec       `json:"metrics"`
+
+	CertificateSigningRequestConfig CertificateSigningRequestSpec `json:"certificateSigningRequest"`
+
+	ImpersonationProxySessionRecording SessionRecordingSpec `json:"impersonationProxySessionRecording"`
 }
 
 type AuditUsernamesAndGroups string
@@ -40,6 +50,85 @@ type AuditSpec struct {
 	LogUsernamesAndGroups AuditUsernamesAndGroups `json:"logUsernamesAndGroups"`
 }
 
+// MetricsSpec configures the optional listener which serves Prometheus metrics.
+type MetricsSpec struct {
+	// Port is the port on which metrics will be served over plain HTTP at the path /metrics.
+	// When nil, metrics will not be served.
+	Port *int64 `json:"port,omitempty"`
+}
+
+// CertificateSigningRequestSpec configures the optional strategy which issues the client certificates for
+// TokenCredentialRequests by creating CertificateSigningRequests for the kubernetes.io/kube-apiserver-client signer.
+// Unlike the kube-cert-agent, this strategy does not need to run any privileged pods on the control plane nodes.
+type CertificateSigningRequestSpec struct {
+	// Mode is either "enabled" or "disabled". The empty string is equivalent to "disabled".
+	Mode string `json:"mode"`
+
+	// ExpirationSeconds is the requested validity period, in seconds, of the issued client certificates.
+	// Kubernetes does not allow this to be less than 600 seconds (10 minutes), which is also the default.
+	// The signer may choose to issue certificates with a shorter validity period than requested.
+	ExpirationSeconds *int32 `json:"expirationSeconds,omitempty"`
+}
+
+// Enabled returns true when the CertificateSigningRequest strategy should be used.
+func (s *CertificateSigningRequestSpec) Enabled() bool {
+	return s.Mode == Enabled
+}
+
+// SessionRecordingSpec configures the optional recording of the exec and attach sessions which are served by the
+// impersonation proxy. Each session is recorded in the asciicast v2 format, along with the identity of the user and
+// the pod's metadata. Exactly one of Directory and Webhook must be configured when recording is enabled.
+type SessionRecordingSpec struct {
+	// Mode is either "enabled" or "disabled". The empty string is equivalent to "disabled".
+	Mode string `json:"mode"`
+
+	// MaxBytesPerSession limits the size of each recording. Once a recording reaches this size, the rest of its
+	// session is not recorded, but the session itself continues. Defaults to 10 MiB.
+	MaxBytesPerSession *int64 `json:"maxBytesPerSession,omitempty"`
+
+	// RedactPatterns are regular expressions (RE2 syntax) whose matches are replaced with "[REDACTED]" in the
+	// recordings. Interactive clients usually send their input one keystroke at a time, so these patterns are
+	// mostly useful for the output of the sessions.
+	RedactPatterns []string `json:"redactPatterns,omitempty"`
+
+	// Directory is the absolute path of a directory, e.g. a mounted volume, in which each recording is written
+	// to its own file while its session happens.
+	Directory string `json:"directory,omitempty"`
+
+	// Webhook configures an HTTPS endpoint to which each recording is POSTed once its session has ended.
+	Webhook *SessionRecordingWebhookSpec `json:"webhook,omitempty"`
+}
+
+// Enabled returns true when the impersonation proxy should record sessions.
+func (s *SessionRecordingSpec) Enabled() bool {
+	return s.Mode == Enabled
+}
+
+type SessionRecordingWebhookSpec struct {
+	// URL is the HTTPS URL to which the recordings are POSTed.
+	URL string `json:"url"`
+
+	// CertificateAuthorityData is the base64-encoded PEM CA bundle which is used to verify the webhook's
+	// serving certificate. When empty, the system's trusted CAs are used.
+	CertificateAuthorityData string `json:"certificateAuthorityData,omitempty"`
+}
+
+// CertPool decodes the CertificateAuthorityData. It returns nil when CertificateAuthorityData is empty.
+func (s *SessionRecordingWebhookSpec) CertPool() (*x509.CertPool, error) {
+	if s.CertificateAuthorityData == "" {
+		return nil, nil
+	}
+	pemBytes, err := base64.StdEncoding.DecodeString(s.CertificateAuthorityData)
+	if err != nil {
+		return nil, fmt.Errorf("certificateAuthorityData is not valid base64: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pemBytes) {
+		return nil, constable.Error("certificateAuthorityData does not contain any PEM certificates")
+	}
+	return pool, nil
+}
+
 type TLSSpec struct {
 	OneDotTwo TLSProtocolSpec `json:"onedottwo"`
 }
@@ -1,4 +1,4 @@
-// Copyright 2020-2025 the Pinniped contributors. All Rights Reserved.
+// Copyright 2020-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 // Package supervisor contains functionality to load/store Config's from/to
@@ -10,6 +10,7 @@ import (
 	"fmt"
 	"net"
 	"os"
+	"path/filepath"
 	"strings"
 
 	"k8s.io/utils/ptr"
@@ -109,6 +110,14 @@ func FromPath(ctx context.Context, path string, setAllowedCiphers ptls.SetAllowe
 		return nil, fmt.Errorf("validate audit: %w", err)
 	}
 
+	if err := validateMetrics(&config.Metrics, *config.AggregatedAPIServerPort); err != nil {
+		return nil, fmt.Errorf("validate metrics: %w", err)
+	}
+
+	if err := validateSessionStorage(&config.SessionStorage); err != nil {
+		return nil, fmt.Errorf("validate sessionStorage: %w", err)
+	}
+
 	return &config, nil
 }
 
@@ -170,6 +179,9 @@ func validateAdditionalHTTPEndpointRequirements(endpoint Endpoint) error {
 			endpoint.Address,
 			endpoint.Network)
 	}
+	if endpoint.RequestClientCertificates {
+		return constable.Error("requestClientCertificates is only supported by the https listener")
+	}
 	return nil
 }
 
@@ -243,3 +255,49 @@ func validateAudit(auditConfig *AuditSpec) error {
 
 	return nil
 }
+
+func validateMetrics(metricsConfig *MetricsSpec, aggregatedAPIServerPort int64) error {
+	if metricsConfig.Port == nil {
+		return nil
+	}
+	if err := validateServerPort(metricsConfig.Port); err != nil {
+		return fmt.Errorf("port: %w", err)
+	}
+	if *metricsConfig.Port == aggregatedAPIServerPort {
+		return fmt.Errorf("port %d is already used by the aggregated API server", *metricsConfig.Port)
+	}
+	return nil
+}
+
+func validateSessionStorage(sessionStorageConfig *SessionStorageSpec) error {
+	switch sessionStorageConfig.Backend {
+	case SessionStorageBackendKubernetesSecrets, "":
+		if sessionStorageConfig.Bolt != nil {
+			return fmt.Errorf("bolt may only be configured when backend is %q", SessionStorageBackendBolt)
+		}
+	case SessionStorageBackendBolt:
+		if sessionStorageConfig.Bolt == nil {
+			return fmt.Errorf("bolt is required when backend is %q", SessionStorageBackendBolt)
+		}
+		if !filepath.IsAbs(sessionStorageConfig.Bolt.Path) {
+			return constable.Error("bolt.path must be an absolute path")
+		}
+		if !filepath.IsAbs(sessionStorageConfig.Bolt.EncryptionKeyPath) {
+			return constable.Error("bolt.encryptionKeyPath must be an absolute path")
+		}
+	default:
+		return fmt.Errorf("invalid backend %q, valid choices are %q, %q, or empty string (equivalent to %q)",
+			sessionStorageConfig.Backend, SessionStorageBackendKubernetesSecrets, SessionStorageBackendBolt, SessionStorageBackendKubernetesSecrets)
+	}
+
+	if encryption := sessionStorageConfig.Encryption; encryption != nil {
+		if (encryption.KEKSecretName == "") == (encryption.KMS == nil) {
+			return constable.Error("encryption must configure exactly one of kekSecretName or kms")
+		}
+		if encryption.KMS != nil && !strings.HasPrefix(encryption.KMS.Endpoint, "unix:///") {
+			return constable.Error("encryption.kms.endpoint must be an absolute unix domain socket path starting with unix:///")
+		}
+	}
+
+	return nil
+}
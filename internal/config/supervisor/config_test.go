@@ -1,4 +1,4 @@
-// Copyright 2020-2025 the Pinniped contributors. All Rights Reserved.
+// Copyright 2020-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package supervisor
@@ -38,6 +38,7 @@ func TestFromPath(t *testing.T) {
 				  https:
 				    network: unix
 				    address: :1234
+				    requestClientCertificates: true
 				  http:
 				    network: tcp
 				    address: 127.0.0.1:1234
@@ -65,6 +66,16 @@ func TestFromPath(t *testing.T) {
 				    whenIssuerExactlyMatches:
 				      - https://foo.com
 				      - https://bar.com
+				metrics:
+				  port: 9090
+				sessionStorage:
+				  backend: Bolt
+				  bolt:
+				    path: /var/lib/pinniped/sessions.db
+				    encryptionKeyPath: /etc/session-storage-key/key
+				  encryption:
+				    kms:
+				      endpoint: unix:///var/run/kms-plugin/socket.sock
 			`),
 			wantConfig: &Config{
 				APIGroupSuffix: ptr.To("some.suffix.com"),
@@ -77,8 +88,9 @@ func TestFromPath(t *testing.T) {
 				},
 				Endpoints: &Endpoints{
 					HTTPS: &Endpoint{
-						Network: "unix",
-						Address: ":1234",
+						Network:                   "unix",
+						Address:                   ":1234",
+						RequestClientCertificates: true,
 					},
 					HTTP: &Endpoint{
 						Network: "tcp",
@@ -117,6 +129,19 @@ func TestFromPath(t *testing.T) {
 						},
 					},
 				},
+				Metrics: MetricsSpec{
+					Port: ptr.To[int64](9090),
+				},
+				SessionStorage: SessionStorageSpec{
+					Backend: "Bolt",
+					Bolt: &BoltSessionStorageSpec{
+						Path:              "/var/lib/pinniped/sessions.db",
+						EncryptionKeyPath: "/etc/session-storage-key/key",
+					},
+					Encryption: &SessionStorageEncryptionSpec{
+						KMS: &KMSPluginSpec{Endpoint: "unix:///var/run/kms-plugin/socket.sock"},
+					},
+				},
 			},
 		},
 		{
@@ -299,6 +324,22 @@ func TestFromPath(t *testing.T) {
 			`),
 			wantError: `validate http endpoint: http listener address ":8080" for "tcp" network may only bind to loopback interfaces`,
 		},
+		{
+			name: "http endpoint requests client certificates",
+			yaml: here.Doc(`
+				---
+				names:
+				  defaultTLSCertificateSecret: my-secret-name
+				endpoints:
+				  https:
+				    network: disabled
+				  http:
+				    network: tcp
+				    address: 127.0.0.1:8080
+				    requestClientCertificates: true
+			`),
+			wantError: `validate http endpoint: requestClientCertificates is only supported by the https listener`,
+		},
 		{
 			name: "endpoint disabled with non-empty address",
 			yaml: here.Doc(`
@@ -369,6 +410,130 @@ func TestFromPath(t *testing.T) {
 			`),
 			wantError: "validate aggregatedAPIServerPort: must be within range 1024 to 65535",
 		},
+		{
+			name: "metrics port too small",
+			yaml: here.Doc(`
+				---
+				names:
+				  defaultTLSCertificateSecret: my-secret-name
+				metrics:
+				  port: 80
+			`),
+			wantError: "validate metrics: port: must be within range 1024 to 65535",
+		},
+		{
+			name: "metrics port conflicts with aggregatedAPIServerPort",
+			yaml: here.Doc(`
+				---
+				names:
+				  defaultTLSCertificateSecret: my-secret-name
+				aggregatedAPIServerPort: 12345
+				metrics:
+				  port: 12345
+			`),
+			wantError: "validate metrics: port 12345 is already used by the aggregated API server",
+		},
+		{
+			name: "invalid sessionStorage.backend",
+			yaml: here.Doc(`
+				---
+				names:
+				  defaultTLSCertificateSecret: my-secret-name
+				sessionStorage:
+				  backend: Postgres
+			`),
+			wantError: `validate sessionStorage: invalid backend "Postgres", valid choices are "KubernetesSecrets", "Bolt", or empty string (equivalent to "KubernetesSecrets")`,
+		},
+		{
+			name: "sessionStorage.bolt is configured but the backend is not Bolt",
+			yaml: here.Doc(`
+				---
+				names:
+				  defaultTLSCertificateSecret: my-secret-name
+				sessionStorage:
+				  backend: KubernetesSecrets
+				  bolt:
+				    path: /var/lib/pinniped/sessions.db
+				    encryptionKeyPath: /etc/session-storage-key/key
+			`),
+			wantError: `validate sessionStorage: bolt may only be configured when backend is "Bolt"`,
+		},
+		{
+			name: "sessionStorage.bolt is missing when the backend is Bolt",
+			yaml: here.Doc(`
+				---
+				names:
+				  defaultTLSCertificateSecret: my-secret-name
+				sessionStorage:
+				  backend: Bolt
+			`),
+			wantError: `validate sessionStorage: bolt is required when backend is "Bolt"`,
+		},
+		{
+			name: "sessionStorage.bolt.path is not absolute",
+			yaml: here.Doc(`
+				---
+				names:
+				  defaultTLSCertificateSecret: my-secret-name
+				sessionStorage:
+				  backend: Bolt
+				  bolt:
+				    path: sessions.db
+				    encryptionKeyPath: /etc/session-storage-key/key
+			`),
+			wantError: "validate sessionStorage: bolt.path must be an absolute path",
+		},
+		{
+			name: "sessionStorage.bolt.encryptionKeyPath is missing",
+			yaml: here.Doc(`
+				---
+				names:
+				  defaultTLSCertificateSecret: my-secret-name
+				sessionStorage:
+				  backend: Bolt
+				  bolt:
+				    path: /var/lib/pinniped/sessions.db
+			`),
+			wantError: "validate sessionStorage: bolt.encryptionKeyPath must be an absolute path",
+		},
+		{
+			name: "sessionStorage.encryption configures both kekSecretName and kms",
+			yaml: here.Doc(`
+				---
+				names:
+				  defaultTLSCertificateSecret: my-secret-name
+				sessionStorage:
+				  encryption:
+				    kekSecretName: my-kek-secret
+				    kms:
+				      endpoint: unix:///var/run/kms-plugin/socket.sock
+			`),
+			wantError: "validate sessionStorage: encryption must configure exactly one of kekSecretName or kms",
+		},
+		{
+			name: "sessionStorage.encryption configures neither kekSecretName nor kms",
+			yaml: here.Doc(`
+				---
+				names:
+				  defaultTLSCertificateSecret: my-secret-name
+				sessionStorage:
+				  encryption: {}
+			`),
+			wantError: "validate sessionStorage: encryption must configure exactly one of kekSecretName or kms",
+		},
+		{
+			name: "sessionStorage.encryption.kms.endpoint is not a unix domain socket",
+			yaml: here.Doc(`
+				---
+				names:
+				  defaultTLSCertificateSecret: my-secret-name
+				sessionStorage:
+				  encryption:
+				    kms:
+				      endpoint: https://kms.example.com
+			`),
+			wantError: "validate sessionStorage: encryption.kms.endpoint must be an absolute unix domain socket path starting with unix:///",
+		},
 		{
 			name: "invalid audit.logUsernamesAndGroups format",
 			yaml: here.Doc(`
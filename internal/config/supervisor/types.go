@@ -1,4 +1,4 @@
-// Copyright 2020-2025 the Pinniped contributors. All Rights Reserved.
+// Copyright 2020-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package supervisor
@@ -14,16 +14,18 @@ const (
 
 // Config contains knobs to set up an instance of the Pinniped Supervisor.
 type Config struct {
-	APIGroupSuffix                             *string           `json:"apiGroupSuffix,omitempty"`
-	Labels                                     map[string]string `json:"labels"`
-	NamesConfig                                NamesConfigSpec   `json:"names"`
-	Log                                        plog.LogSpec      `json:"log"`
-	Endpoints                                  *Endpoints        `json:"endpoints"`
-	AggregatedAPIServerPort                    *int64            `json:"aggregatedAPIServerPort"`
-	AggregatedAPIServerDisableAdmissionPlugins []string          `json:"aggregatedAPIServerDisableAdmissionPlugins"`
-	TLS                                        TLSSpec           `json:"tls"`
-	Audit                                      AuditSpec         `json:"audit"`
-	OIDC                                       OIDCSpec          `json:"oidc"`
+	APIGroupSuffix                             *string            `json:"apiGroupSuffix,omitempty"`
+	Labels                                     map[string]string  `json:"labels"`
+	NamesConfig                                NamesConfigSpec    `json:"names"`
+	Log                                        plog.LogSpec       `json:"log"`
+	Endpoints                                  *Endpoints         `json:"endpoints"`
+	AggregatedAPIServerPort                    *int64             `json:"aggregatedAPIServerPort"`
+	AggregatedAPIServerDisableAdmissionPlugins []string           `json:"aggregatedAPIServerDisableAdmissionPlugins"`
+	TLS                                        TLSSpec            `json:"tls"`
+	Audit                                      AuditSpec          `json:"audit"`
+	OIDC                                       OIDCSpec           `json:"oidc"`
+	Metrics                                    MetricsSpec        `json:"metrics"`
+	SessionStorage                             SessionStorageSpec `json:"sessionStorage"`
 }
 
 type AuditInternalPaths string
@@ -68,6 +70,59 @@ type OIDCSpec struct {
 	IgnoreUserInfoEndpoint IgnoreUserInfoEndpointSpec `json:"ignoreUserInfoEndpoint"`
 }
 
+// MetricsSpec configures the optional listener which serves Prometheus metrics.
+type MetricsSpec struct {
+	// Port is the port on which metrics will be served over plain HTTP at the path /metrics.
+	// When nil, metrics will not be served.
+	Port *int64 `json:"port,omitempty"`
+}
+
+const (
+	SessionStorageBackendKubernetesSecrets = "KubernetesSecrets"
+	SessionStorageBackendBolt              = "Bolt"
+)
+
+// SessionStorageSpec configures where downstream session data (authorization codes, tokens, etc.) is stored.
+type SessionStorageSpec struct {
+	// Backend is either KubernetesSecrets or Bolt. When empty, it defaults to KubernetesSecrets.
+	Backend string `json:"backend,omitempty"`
+	// Bolt is required when Backend is Bolt.
+	Bolt *BoltSessionStorageSpec `json:"bolt,omitempty"`
+	// Encryption, when configured, causes the data of each session to be encrypted using a newly generated
+	// data encryption key, which is itself encrypted using a key encryption key. When not configured, the data
+	// of each session is stored unencrypted.
+	Encryption *SessionStorageEncryptionSpec `json:"encryption,omitempty"`
+}
+
+// SessionStorageEncryptionSpec configures where the key encryption keys come from. Exactly one of KEKSecretName
+// or KMS must be configured.
+type SessionStorageEncryptionSpec struct {
+	// KEKSecretName is the name of a Secret in the Supervisor's namespace which holds the key encryption keys.
+	// Its "active" key names the active key encryption key, and every other key holds a 32 byte key encryption key.
+	KEKSecretName string `json:"kekSecretName,omitempty"`
+	// KMS configures a Kubernetes KMS v2 plugin which holds the key encryption keys.
+	KMS *KMSPluginSpec `json:"kms,omitempty"`
+}
+
+type KMSPluginSpec struct {
+	// Endpoint is the unix domain socket of the KMS v2 plugin, e.g. unix:///var/run/kms-plugin/socket.sock.
+	Endpoint string `json:"endpoint"`
+}
+
+// BoltSessionStorageSpec configures session storage in an encrypted bbolt database file. Only one Supervisor pod
+// can open the file at a time, so this backend requires running the Supervisor with a single replica.
+type BoltSessionStorageSpec struct {
+	// Path is the absolute path of the database file, which should be on a persistent volume.
+	Path string `json:"path"`
+	// EncryptionKeyPath is the absolute path of a file containing exactly 32 bytes, which are used as the
+	// AES-256 key to encrypt the contents of the database file.
+	EncryptionKeyPath string `json:"encryptionKeyPath"`
+}
+
+func (s *SessionStorageSpec) UsesBolt() bool {
+	return s.Backend == SessionStorageBackendBolt
+}
+
 type TLSSpec struct {
 	OneDotTwo TLSProtocolSpec `json:"onedottwo"`
 }
@@ -93,4 +148,14 @@ type Endpoints struct {
 type Endpoint struct {
 	Network string `json:"network"`
 	Address string `json:"address"`
+
+	// RequestClientCertificates makes the listener ask clients for TLS client certificates, which are needed
+	// by OIDCClients that use the tls_client_auth client authentication method. Only allowed for HTTPS.
+	// The listener also serves the browser-based login pages, so browsers may prompt users to choose a certificate.
+	RequestClientCertificates bool `json:"requestClientCertificates,omitempty"`
+}
+
+// ClientCertificatesRequested returns true when the HTTPS listener is enabled and requests TLS client certificates.
+func (e *Endpoints) ClientCertificatesRequested() bool {
+	return e.HTTPS != nil && e.HTTPS.Network != NetworkDisabled && e.HTTPS.RequestClientCertificates
 }
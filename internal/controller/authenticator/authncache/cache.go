@@ -1,4 +1,4 @@
-// Copyright 2020-2024 the Pinniped contributors. All Rights Reserved.
+// Copyright 2020-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 // Package authncache implements a cache of active authenticators.
@@ -8,6 +8,7 @@ import (
 	"context"
 	"sort"
 	"sync"
+	"time"
 
 	"k8s.io/apiserver/pkg/authentication/authenticator"
 	"k8s.io/apiserver/pkg/authentication/user"
@@ -15,6 +16,7 @@ import (
 
 	loginapi "go.pinniped.dev/generated/latest/apis/concierge/login"
 	"go.pinniped.dev/internal/constable"
+	"go.pinniped.dev/internal/metrics"
 	"go.pinniped.dev/internal/plog"
 	"go.pinniped.dev/internal/valuelesscontext"
 )
@@ -91,6 +93,8 @@ func (c *Cache) Keys() []Key {
 }
 
 func (c *Cache) AuthenticateTokenCredentialRequest(ctx context.Context, req *loginapi.TokenCredentialRequest) (user.Info, error) {
+	start := time.Now()
+
 	// Map the incoming request to a cache key.
 	key := Key{
 		Name: req.Spec.Authenticator.Name,
@@ -108,6 +112,8 @@ func (c *Cache) AuthenticateTokenCredentialRequest(ctx context.Context, req *log
 			"kind", key.Kind,
 			"apiGroup", key.APIGroup,
 		)
+		// Do not use the kind from the request as a metrics label because it is user input. It could be anything.
+		metrics.ObserveTokenCredentialRequest("", metrics.ResultNoSuchAuthenticator, start)
 		return nil, ErrNoSuchAuthenticator
 	}
 
@@ -118,11 +124,14 @@ func (c *Cache) AuthenticateTokenCredentialRequest(ctx context.Context, req *log
 	// Call the selected authenticator.
 	resp, authenticated, err := val.AuthenticateToken(ctx, req.Spec.Token)
 	if err != nil {
+		metrics.ObserveTokenCredentialRequest(key.Kind, metrics.ResultError, start)
 		return nil, err
 	}
 	if !authenticated {
+		metrics.ObserveTokenCredentialRequest(key.Kind, metrics.ResultFailure, start)
 		return nil, nil
 	}
+	metrics.ObserveTokenCredentialRequest(key.Kind, metrics.ResultSuccess, start)
 
 	// Return the user.Info from the response (if it is non-nil).
 	var respUser user.Info
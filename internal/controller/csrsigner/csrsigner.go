@@ -0,0 +1,170 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package csrsigner provides a controller which reports the status of the CertificateSigningRequest strategy,
+// which issues client certificates for TokenCredentialRequests using the Kubernetes CSR API.
+package csrsigner
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	certificatesv1 "k8s.io/api/certificates/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	corev1informers "k8s.io/client-go/informers/core/v1"
+	"k8s.io/utils/clock"
+
+	conciergeconfigv1alpha1 "go.pinniped.dev/generated/latest/apis/concierge/config/v1alpha1"
+	configv1alpha1informers "go.pinniped.dev/generated/latest/client/concierge/informers/externalversions/config/v1alpha1"
+	pinnipedcontroller "go.pinniped.dev/internal/controller"
+	"go.pinniped.dev/internal/controller/issuerconfig"
+	"go.pinniped.dev/internal/controller/kubecertagent"
+	"go.pinniped.dev/internal/controllerlib"
+	"go.pinniped.dev/internal/kubeclient"
+)
+
+// requiredPermissions are the permissions which the Concierge needs to get its CertificateSigningRequests signed.
+var requiredPermissions = []authorizationv1.ResourceAttributes{ //nolint:gochecknoglobals
+	{Verb: "create", Group: certificatesv1.GroupName, Resource: "certificatesigningrequests"},
+	{Verb: "get", Group: certificatesv1.GroupName, Resource: "certificatesigningrequests"},
+	{Verb: "delete", Group: certificatesv1.GroupName, Resource: "certificatesigningrequests"},
+	{Verb: "update", Group: certificatesv1.GroupName, Resource: "certificatesigningrequests", Subresource: "approval"},
+	{Verb: "approve", Group: certificatesv1.GroupName, Resource: "signers", Name: certificatesv1.KubeAPIServerClientSignerName},
+}
+
+type controller struct {
+	credentialIssuerName string
+	discoveryURLOverride *string
+	client               *kubeclient.Client
+	kubePublicConfigMaps corev1informers.ConfigMapInformer
+	credentialIssuers    configv1alpha1informers.CredentialIssuerInformer
+	clock                clock.Clock
+}
+
+// NewController returns a controller which reports the status of the CertificateSigningRequest strategy on the
+// CredentialIssuer. The strategy works when the Concierge is allowed to create and approve CertificateSigningRequests
+// for the kubernetes.io/kube-apiserver-client signer, so that is what this controller checks. This controller should
+// only be used when the strategy is enabled.
+func NewController(
+	credentialIssuerName string,
+	discoveryURLOverride *string,
+	client *kubeclient.Client,
+	kubePublicConfigMaps corev1informers.ConfigMapInformer,
+	credentialIssuers configv1alpha1informers.CredentialIssuerInformer,
+	clock clock.Clock,
+) controllerlib.Controller {
+	return controllerlib.New(
+		controllerlib.Config{
+			Name: "csr-signer-controller",
+			Syncer: &controller{
+				credentialIssuerName: credentialIssuerName,
+				discoveryURLOverride: discoveryURLOverride,
+				client:               client,
+				kubePublicConfigMaps: kubePublicConfigMaps,
+				credentialIssuers:    credentialIssuers,
+				clock:                clock,
+			},
+		},
+		controllerlib.WithInformer(
+			kubePublicConfigMaps,
+			pinnipedcontroller.SimpleFilterWithSingletonQueue(func(obj metav1.Object) bool {
+				return obj.GetNamespace() == kubecertagent.ClusterInfoNamespace && obj.GetName() == kubecertagent.ClusterInfoName
+			}),
+			controllerlib.InformerOption{},
+		),
+		controllerlib.WithInformer(
+			credentialIssuers,
+			pinnipedcontroller.SimpleFilterWithSingletonQueue(func(obj metav1.Object) bool {
+				return obj.GetName() == credentialIssuerName
+			}),
+			controllerlib.InformerOption{},
+		),
+		// Run once even when the cluster-info ConfigMap does not exist, so the CredentialIssuer reports why.
+		controllerlib.WithInitialEvent(controllerlib.Key{}),
+	)
+}
+
+// Sync implements controllerlib.Syncer.
+func (c *controller) Sync(ctx controllerlib.Context) error {
+	credIssuer, err := c.credentialIssuers.Lister().Get(c.credentialIssuerName)
+	if err != nil {
+		return fmt.Errorf("could not get CredentialIssuer to update: %w", err)
+	}
+
+	// Load the Kubernetes API info from the kube-public/cluster-info ConfigMap.
+	configMap, err := c.kubePublicConfigMaps.Lister().ConfigMaps(kubecertagent.ClusterInfoNamespace).Get(kubecertagent.ClusterInfoName)
+	if err != nil {
+		err := fmt.Errorf("failed to get %s/%s configmap: %w", kubecertagent.ClusterInfoNamespace, kubecertagent.ClusterInfoName, err)
+		return c.failStrategyAndErr(ctx.Context, credIssuer, err, conciergeconfigv1alpha1.CouldNotGetClusterInfoStrategyReason)
+	}
+
+	apiInfo, err := kubecertagent.APIInfoFromClusterInfo(configMap, c.discoveryURLOverride)
+	if err != nil {
+		err := fmt.Errorf("could not extract Kubernetes API endpoint info from %s/%s configmap: %w", kubecertagent.ClusterInfoNamespace, kubecertagent.ClusterInfoName, err)
+		return c.failStrategyAndErr(ctx.Context, credIssuer, err, conciergeconfigv1alpha1.CouldNotGetClusterInfoStrategyReason)
+	}
+
+	missing, err := c.missingPermissions(ctx.Context)
+	if err != nil {
+		return c.failStrategyAndErr(ctx.Context, credIssuer, err, conciergeconfigv1alpha1.ErrorDuringSetupStrategyReason)
+	}
+	if len(missing) > 0 {
+		err := fmt.Errorf("the Concierge is not allowed to %s", strings.Join(missing, ", "))
+		return c.failStrategyAndErr(ctx.Context, credIssuer, err, conciergeconfigv1alpha1.SigningRequestsForbiddenStrategyReason)
+	}
+
+	return issuerconfig.Update(ctx.Context, c.client.PinnipedConcierge, credIssuer, conciergeconfigv1alpha1.CredentialIssuerStrategy{
+		Type:           conciergeconfigv1alpha1.CertificateSigningRequestStrategyType,
+		Status:         conciergeconfigv1alpha1.SuccessStrategyStatus,
+		Reason:         conciergeconfigv1alpha1.SigningRequestsAllowedStrategyReason,
+		Message:        "client certificates will be requested from the " + certificatesv1.KubeAPIServerClientSignerName + " signer",
+		LastUpdateTime: metav1.NewTime(c.clock.Now()),
+		Frontend: &conciergeconfigv1alpha1.CredentialIssuerFrontend{
+			Type:                          conciergeconfigv1alpha1.TokenCredentialRequestAPIFrontendType,
+			TokenCredentialRequestAPIInfo: apiInfo,
+		},
+	})
+}
+
+// missingPermissions uses SelfSubjectAccessReviews to describe which of the requiredPermissions are not granted.
+func (c *controller) missingPermissions(ctx context.Context) ([]string, error) {
+	var missing []string
+	for _, permission := range requiredPermissions {
+		review, err := c.client.Kubernetes.AuthorizationV1().SelfSubjectAccessReviews().Create(ctx, &authorizationv1.SelfSubjectAccessReview{
+			Spec: authorizationv1.SelfSubjectAccessReviewSpec{ResourceAttributes: &permission},
+		}, metav1.CreateOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("could not check whether the Concierge is allowed to %s: %w", describe(permission), err)
+		}
+		if !review.Status.Allowed {
+			missing = append(missing, describe(permission))
+		}
+	}
+	return missing, nil
+}
+
+// describe returns a description of the permission like "update certificatesigningrequests.certificates.k8s.io/approval".
+func describe(permission authorizationv1.ResourceAttributes) string {
+	description := fmt.Sprintf("%s %s.%s", permission.Verb, permission.Resource, permission.Group)
+	if permission.Subresource != "" {
+		description += "/" + permission.Subresource
+	}
+	if permission.Name != "" {
+		description += fmt.Sprintf(" named %q", permission.Name)
+	}
+	return description
+}
+
+func (c *controller) failStrategyAndErr(ctx context.Context, credIssuer *conciergeconfigv1alpha1.CredentialIssuer, err error, reason conciergeconfigv1alpha1.StrategyReason) error {
+	updateErr := issuerconfig.Update(ctx, c.client.PinnipedConcierge, credIssuer, conciergeconfigv1alpha1.CredentialIssuerStrategy{
+		Type:           conciergeconfigv1alpha1.CertificateSigningRequestStrategyType,
+		Status:         conciergeconfigv1alpha1.ErrorStrategyStatus,
+		Reason:         reason,
+		Message:        err.Error(),
+		LastUpdateTime: metav1.NewTime(c.clock.Now()),
+	})
+	return utilerrors.NewAggregate([]error{err, updateErr})
+}
@@ -0,0 +1,225 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package csrsigner
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	kubeinformers "k8s.io/client-go/informers"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	coretesting "k8s.io/client-go/testing"
+	clocktesting "k8s.io/utils/clock/testing"
+	"k8s.io/utils/ptr"
+
+	conciergeconfigv1alpha1 "go.pinniped.dev/generated/latest/apis/concierge/config/v1alpha1"
+	conciergefake "go.pinniped.dev/generated/latest/client/concierge/clientset/versioned/fake"
+	conciergeinformers "go.pinniped.dev/generated/latest/client/concierge/informers/externalversions"
+	"go.pinniped.dev/internal/controllerlib"
+	"go.pinniped.dev/internal/here"
+	"go.pinniped.dev/internal/kubeclient"
+)
+
+func TestController(t *testing.T) {
+	now := time.Date(2099, time.August, 8, 13, 57, 36, 123456789, time.Local)
+
+	credentialIssuer := &conciergeconfigv1alpha1.CredentialIssuer{
+		ObjectMeta: metav1.ObjectMeta{Name: "pinniped-concierge-config"},
+	}
+
+	clusterInfo := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "kube-public", Name: "cluster-info"},
+		Data: map[string]string{"kubeconfig": here.Docf(`
+			kind: Config
+			apiVersion: v1
+			clusters:
+			- name: ""
+			  cluster:
+				certificate-authority-data: dGVzdC1rdWJlcm5ldGVzLWNh # "test-kubernetes-ca"
+				server: https://test-kubernetes-endpoint.example.com
+			`),
+		},
+	}
+
+	// allowAllExcept answers SelfSubjectAccessReviews, denying the given verbs.
+	allowAllExcept := func(deniedVerbs ...string) func(*kubefake.Clientset) {
+		return func(client *kubefake.Clientset) {
+			client.PrependReactor("create", "selfsubjectaccessreviews", func(action coretesting.Action) (bool, runtime.Object, error) {
+				review := action.(coretesting.CreateAction).GetObject().(*authorizationv1.SelfSubjectAccessReview)
+				review.Status.Allowed = true
+				for _, verb := range deniedVerbs {
+					if review.Spec.ResourceAttributes.Verb == verb {
+						review.Status.Allowed = false
+					}
+				}
+				return true, review, nil
+			})
+		}
+	}
+
+	tests := []struct {
+		name                 string
+		kubeObjects          []runtime.Object
+		pinnipedObjects      []runtime.Object
+		discoveryURLOverride *string
+		addKubeReactions     func(*kubefake.Clientset)
+		wantErr              string
+		wantStrategy         *conciergeconfigv1alpha1.CredentialIssuerStrategy
+	}{
+		{
+			name:    "missing CredentialIssuer",
+			wantErr: `could not get CredentialIssuer to update: credentialissuer.config.concierge.pinniped.dev "pinniped-concierge-config" not found`,
+		},
+		{
+			name:            "missing cluster-info ConfigMap",
+			pinnipedObjects: []runtime.Object{credentialIssuer},
+			wantErr:         `failed to get kube-public/cluster-info configmap: configmap "cluster-info" not found`,
+			wantStrategy: &conciergeconfigv1alpha1.CredentialIssuerStrategy{
+				Type:           conciergeconfigv1alpha1.CertificateSigningRequestStrategyType,
+				Status:         conciergeconfigv1alpha1.ErrorStrategyStatus,
+				Reason:         conciergeconfigv1alpha1.CouldNotGetClusterInfoStrategyReason,
+				Message:        `failed to get kube-public/cluster-info configmap: configmap "cluster-info" not found`,
+				LastUpdateTime: metav1.NewTime(now),
+			},
+		},
+		{
+			name:            "invalid cluster-info ConfigMap",
+			pinnipedObjects: []runtime.Object{credentialIssuer},
+			kubeObjects: []runtime.Object{&corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "kube-public", Name: "cluster-info"},
+			}},
+			wantErr: `could not extract Kubernetes API endpoint info from kube-public/cluster-info configmap: missing "kubeconfig" key`,
+			wantStrategy: &conciergeconfigv1alpha1.CredentialIssuerStrategy{
+				Type:           conciergeconfigv1alpha1.CertificateSigningRequestStrategyType,
+				Status:         conciergeconfigv1alpha1.ErrorStrategyStatus,
+				Reason:         conciergeconfigv1alpha1.CouldNotGetClusterInfoStrategyReason,
+				Message:        `could not extract Kubernetes API endpoint info from kube-public/cluster-info configmap: missing "kubeconfig" key`,
+				LastUpdateTime: metav1.NewTime(now),
+			},
+		},
+		{
+			name:            "SelfSubjectAccessReview fails",
+			pinnipedObjects: []runtime.Object{credentialIssuer},
+			kubeObjects:     []runtime.Object{clusterInfo},
+			addKubeReactions: func(client *kubefake.Clientset) {
+				client.PrependReactor("create", "selfsubjectaccessreviews", func(_ coretesting.Action) (bool, runtime.Object, error) {
+					return true, nil, errors.New("some SSAR error")
+				})
+			},
+			wantErr: "could not check whether the Concierge is allowed to create certificatesigningrequests.certificates.k8s.io: some SSAR error",
+			wantStrategy: &conciergeconfigv1alpha1.CredentialIssuerStrategy{
+				Type:           conciergeconfigv1alpha1.CertificateSigningRequestStrategyType,
+				Status:         conciergeconfigv1alpha1.ErrorStrategyStatus,
+				Reason:         conciergeconfigv1alpha1.ErrorDuringSetupStrategyReason,
+				Message:        "could not check whether the Concierge is allowed to create certificatesigningrequests.certificates.k8s.io: some SSAR error",
+				LastUpdateTime: metav1.NewTime(now),
+			},
+		},
+		{
+			name:             "missing permissions",
+			pinnipedObjects:  []runtime.Object{credentialIssuer},
+			kubeObjects:      []runtime.Object{clusterInfo},
+			addKubeReactions: allowAllExcept("update", "approve"),
+			wantErr: `the Concierge is not allowed to update certificatesigningrequests.certificates.k8s.io/approval, ` +
+				`approve signers.certificates.k8s.io named "kubernetes.io/kube-apiserver-client"`,
+			wantStrategy: &conciergeconfigv1alpha1.CredentialIssuerStrategy{
+				Type:   conciergeconfigv1alpha1.CertificateSigningRequestStrategyType,
+				Status: conciergeconfigv1alpha1.ErrorStrategyStatus,
+				Reason: conciergeconfigv1alpha1.SigningRequestsForbiddenStrategyReason,
+				Message: `the Concierge is not allowed to update certificatesigningrequests.certificates.k8s.io/approval, ` +
+					`approve signers.certificates.k8s.io named "kubernetes.io/kube-apiserver-client"`,
+				LastUpdateTime: metav1.NewTime(now),
+			},
+		},
+		{
+			name:             "success",
+			pinnipedObjects:  []runtime.Object{credentialIssuer},
+			kubeObjects:      []runtime.Object{clusterInfo},
+			addKubeReactions: allowAllExcept(),
+			wantStrategy: &conciergeconfigv1alpha1.CredentialIssuerStrategy{
+				Type:           conciergeconfigv1alpha1.CertificateSigningRequestStrategyType,
+				Status:         conciergeconfigv1alpha1.SuccessStrategyStatus,
+				Reason:         conciergeconfigv1alpha1.SigningRequestsAllowedStrategyReason,
+				Message:        "client certificates will be requested from the kubernetes.io/kube-apiserver-client signer",
+				LastUpdateTime: metav1.NewTime(now),
+				Frontend: &conciergeconfigv1alpha1.CredentialIssuerFrontend{
+					Type: conciergeconfigv1alpha1.TokenCredentialRequestAPIFrontendType,
+					TokenCredentialRequestAPIInfo: &conciergeconfigv1alpha1.TokenCredentialRequestAPIInfo{
+						Server:                   "https://test-kubernetes-endpoint.example.com",
+						CertificateAuthorityData: "dGVzdC1rdWJlcm5ldGVzLWNh",
+					},
+				},
+			},
+		},
+		{
+			name:                 "success with discovery URL override",
+			pinnipedObjects:      []runtime.Object{credentialIssuer},
+			kubeObjects:          []runtime.Object{clusterInfo},
+			discoveryURLOverride: ptr.To("https://overridden-server.example.com/some/path"),
+			addKubeReactions:     allowAllExcept(),
+			wantStrategy: &conciergeconfigv1alpha1.CredentialIssuerStrategy{
+				Type:           conciergeconfigv1alpha1.CertificateSigningRequestStrategyType,
+				Status:         conciergeconfigv1alpha1.SuccessStrategyStatus,
+				Reason:         conciergeconfigv1alpha1.SigningRequestsAllowedStrategyReason,
+				Message:        "client certificates will be requested from the kubernetes.io/kube-apiserver-client signer",
+				LastUpdateTime: metav1.NewTime(now),
+				Frontend: &conciergeconfigv1alpha1.CredentialIssuerFrontend{
+					Type: conciergeconfigv1alpha1.TokenCredentialRequestAPIFrontendType,
+					TokenCredentialRequestAPIInfo: &conciergeconfigv1alpha1.TokenCredentialRequestAPIInfo{
+						Server:                   "https://overridden-server.example.com/some/path",
+						CertificateAuthorityData: "dGVzdC1rdWJlcm5ldGVzLWNh",
+					},
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conciergeClientset := conciergefake.NewSimpleClientset(tt.pinnipedObjects...)
+			conciergeInformers := conciergeinformers.NewSharedInformerFactory(conciergeClientset, 0)
+			kubeClientset := kubefake.NewSimpleClientset(tt.kubeObjects...)
+			if tt.addKubeReactions != nil {
+				tt.addKubeReactions(kubeClientset)
+			}
+			kubeInformers := kubeinformers.NewSharedInformerFactory(kubeClientset, 0)
+
+			controller := NewController(
+				"pinniped-concierge-config",
+				tt.discoveryURLOverride,
+				&kubeclient.Client{Kubernetes: kubeClientset, PinnipedConcierge: conciergeClientset},
+				kubeInformers.Core().V1().ConfigMaps(),
+				conciergeInformers.Config().V1alpha1().CredentialIssuers(),
+				clocktesting.NewFakeClock(now),
+			)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			conciergeInformers.Start(ctx.Done())
+			kubeInformers.Start(ctx.Done())
+			controllerlib.TestRunSynchronously(t, controller)
+
+			err := controllerlib.TestSync(t, controller, controllerlib.Context{Context: ctx})
+			if tt.wantErr != "" {
+				require.EqualError(t, err, tt.wantErr)
+			} else {
+				require.NoError(t, err)
+			}
+
+			if tt.wantStrategy != nil {
+				credIssuer, err := conciergeClientset.ConfigV1alpha1().CredentialIssuers().Get(ctx, "pinniped-concierge-config", metav1.GetOptions{})
+				require.NoError(t, err)
+				require.Len(t, credIssuer.Status.Strategies, 1)
+				require.Equal(t, tt.wantStrategy, &credIssuer.Status.Strategies[0])
+			}
+		})
+	}
+}
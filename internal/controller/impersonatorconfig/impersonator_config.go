@@ -41,6 +41,7 @@ import (
 	"go.pinniped.dev/internal/constable"
 	pinnipedcontroller "go.pinniped.dev/internal/controller"
 	"go.pinniped.dev/internal/controller/apicerts"
+	"go.pinniped.dev/internal/controller/authenticator/authncache"
 	"go.pinniped.dev/internal/controller/issuerconfig"
 	"go.pinniped.dev/internal/controllerlib"
 	"go.pinniped.dev/internal/dynamiccert"
@@ -89,6 +90,9 @@ type impersonatorConfigController struct {
 	log                               plog.Logger
 
 	impersonationProxyTokenCache tokenclient.ExpiringSingletonTokenCacheGet
+	authenticatorCache           *authncache.Cache
+	requestLimiter               *impersonator.RequestLimiter
+	sessionRecorder              *impersonator.SessionRecorder
 }
 
 func NewImpersonatorConfigController(
@@ -112,6 +116,9 @@ func NewImpersonatorConfigController(
 	impersonationSigningCertProvider dynamiccert.Provider,
 	log plog.Logger,
 	impersonationProxyTokenCache tokenclient.ExpiringSingletonTokenCacheGet,
+	authenticatorCache *authncache.Cache,
+	requestLimiter *impersonator.RequestLimiter,
+	sessionRecorder *impersonator.SessionRecorder,
 ) controllerlib.Controller {
 	secretNames := sets.NewString(tlsSecretName, caSecretName, impersonationSignerSecretName)
 	log = log.WithName("impersonator-config-controller")
@@ -139,6 +146,9 @@ func NewImpersonatorConfigController(
 				tlsServingCertDynamicCertProvider: dynamiccert.NewServingCert("impersonation-proxy-serving-cert"),
 				log:                               log,
 				impersonationProxyTokenCache:      impersonationProxyTokenCache,
+				authenticatorCache:                authenticatorCache,
+				requestLimiter:                    requestLimiter,
+				sessionRecorder:                   sessionRecorder,
 			},
 		},
 		withInformer(credentialIssuerInformer,
@@ -250,6 +260,9 @@ func (c *impersonatorConfigController) doSync(syncCtx controllerlib.Context, cre
 		return nil, err
 	}
 
+	// The limits can change while the impersonator is running, so always pass along the latest limits.
+	c.requestLimiter.SetLimits(impersonationSpec.RateLimits)
+
 	// Make a live API call to avoid the cost of having an informer watch all node changes on the cluster,
 	// since there could be lots, and we don't especially care about node changes.
 	// Once we have concluded that there is or is not a visible control plane, then cache that decision
@@ -492,6 +505,9 @@ func (c *impersonatorConfigController) ensureImpersonatorIsStarted(syncCtx contr
 		c.tlsServingCertDynamicCertProvider,
 		c.impersonationSigningCertProvider,
 		c.impersonationProxyTokenCache,
+		c.authenticatorCache,
+		c.requestLimiter,
+		c.sessionRecorder,
 	)
 	if err != nil {
 		return err
@@ -1260,6 +1276,25 @@ func validateCredentialIssuerSpec(spec *conciergeconfigv1alpha1.ImpersonationPro
 		}
 	}
 
+	if spec.RateLimits != nil {
+		if err := validateRateLimit("perUser", spec.RateLimits.PerUser); err != nil {
+			return err
+		}
+		if err := validateRateLimit("perGroup", spec.RateLimits.PerGroup); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func validateRateLimit(name string, limit *conciergeconfigv1alpha1.ImpersonationProxyRateLimit) error {
+	if limit == nil {
+		return nil
+	}
+	if limit.QPS < 0 || limit.Burst < 0 || limit.MaxInFlightLongRunningRequests < 0 {
+		return fmt.Errorf("invalid rateLimits.%s: qps, burst, and maxInFlightLongRunningRequests must not be negative", name)
+	}
 	return nil
 }
 
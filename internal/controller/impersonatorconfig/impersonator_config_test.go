@@ -40,7 +40,9 @@ import (
 	conciergefake "go.pinniped.dev/generated/latest/client/concierge/clientset/versioned/fake"
 	conciergeinformers "go.pinniped.dev/generated/latest/client/concierge/informers/externalversions"
 	"go.pinniped.dev/internal/certauthority"
+	"go.pinniped.dev/internal/concierge/impersonator"
 	"go.pinniped.dev/internal/controller/apicerts"
+	"go.pinniped.dev/internal/controller/authenticator/authncache"
 	"go.pinniped.dev/internal/controllerlib"
 	"go.pinniped.dev/internal/dynamiccert"
 	"go.pinniped.dev/internal/kubeclient"
@@ -98,6 +100,9 @@ func TestImpersonatorConfigControllerOptions(t *testing.T) {
 				nil,
 				logger,
 				nil,
+				nil,
+				nil,
+				nil,
 			)
 			credIssuerInformerFilter = observableWithInformerOption.GetFilterForInformer(credIssuerInformer)
 			servicesInformerFilter = observableWithInformerOption.GetFilterForInformer(servicesInformer)
@@ -278,6 +283,9 @@ func TestImpersonatorConfigControllerSync(t *testing.T) {
 		const fakeServerResponseBody = "hello, world!"
 		const externallyProvidedTLSSecretName = "external-tls-secret" //nolint:gosec // this is not a credential
 		var fakeExpiringSingletonTokenCacheGet = tokenclient.NewExpiringSingletonTokenCache()
+		var fakeAuthenticatorCache = authncache.New()
+		var fakeRequestLimiter = impersonator.NewRequestLimiter(nil, clocktesting.NewFakeClock(time.Now()))
+		var fakeSessionRecorder = impersonator.NewSessionRecorder(nil, 0, nil, nil, clocktesting.NewFakeClock(time.Now()))
 		var labels = map[string]string{"app": "app-name", "other-key": "other-value"}
 
 		var r *require.Assertions
@@ -315,12 +323,18 @@ func TestImpersonatorConfigControllerSync(t *testing.T) {
 			dynamicCertProvider dynamiccert.Private,
 			impersonationProxySignerCAProvider dynamiccert.Public,
 			expiringSingletonTokenCacheGet tokenclient.ExpiringSingletonTokenCacheGet,
+			authenticatorCache *authncache.Cache,
+			requestLimiter *impersonator.RequestLimiter,
+			sessionRecorder *impersonator.SessionRecorder,
 		) (func(ctx context.Context) error, error) {
 			impersonatorFuncWasCalled++
 			r.Equal(8444, port)
 			r.NotNil(dynamicCertProvider)
 			r.NotNil(impersonationProxySignerCAProvider)
 			r.Equal(fakeExpiringSingletonTokenCacheGet, expiringSingletonTokenCacheGet)
+			r.Same(fakeAuthenticatorCache, authenticatorCache)
+			r.Same(fakeRequestLimiter, requestLimiter)
+			r.Same(fakeSessionRecorder, sessionRecorder)
 
 			if impersonatorFuncError != nil {
 				return nil, impersonatorFuncError
@@ -594,6 +608,9 @@ func TestImpersonatorConfigControllerSync(t *testing.T) {
 				mTLSClientCertProvider,
 				logger,
 				fakeExpiringSingletonTokenCacheGet,
+				fakeAuthenticatorCache,
+				fakeRequestLimiter,
+				fakeSessionRecorder,
 			)
 			controllerlib.TestWrap(t, subject, func(syncer controllerlib.Syncer) controllerlib.Syncer {
 				tlsServingCertDynamicCertProvider = syncer.(*impersonatorConfigController).tlsServingCertDynamicCertProvider
@@ -3553,6 +3570,35 @@ func TestImpersonatorConfigControllerSync(t *testing.T) {
 			})
 		})
 
+		when("the CredentialIssuer has invalid rateLimits", func() {
+			it.Before(func() {
+				addCredentialIssuerToTrackers(conciergeconfigv1alpha1.CredentialIssuer{
+					ObjectMeta: metav1.ObjectMeta{Name: credentialIssuerResourceName},
+					Spec: conciergeconfigv1alpha1.CredentialIssuerSpec{
+						ImpersonationProxy: &conciergeconfigv1alpha1.ImpersonationProxySpec{
+							Mode: conciergeconfigv1alpha1.ImpersonationProxyModeEnabled,
+							Service: conciergeconfigv1alpha1.ImpersonationProxyServiceSpec{
+								Type: conciergeconfigv1alpha1.ImpersonationProxyServiceTypeLoadBalancer,
+							},
+							RateLimits: &conciergeconfigv1alpha1.ImpersonationProxyRateLimitsSpec{
+								PerUser:  &conciergeconfigv1alpha1.ImpersonationProxyRateLimit{QPS: 10},
+								PerGroup: &conciergeconfigv1alpha1.ImpersonationProxyRateLimit{QPS: 10, Burst: -1},
+							},
+						},
+					},
+				}, pinnipedInformerClient, pinnipedAPIClient)
+			})
+
+			it("returns an error", func() {
+				startInformersAndController()
+				errString := `could not load CredentialIssuer spec.impersonationProxy: invalid rateLimits.perGroup: qps, burst, and maxInFlightLongRunningRequests must not be negative`
+				r.EqualError(runControllerSync(), errString)
+				requireCredentialIssuer(newErrorStrategy(errString))
+				requireMTLSClientCertProviderIsEmpty()
+				requireTLSServerWasNeverStarted()
+			})
+		})
+
 		when("the CredentialIssuer has invalid ExternalEndpoint", func() {
 			it.Before(func() {
 				addCredentialIssuerToTrackers(conciergeconfigv1alpha1.CredentialIssuer{
@@ -1,4 +1,4 @@
-// Copyright 2021-2024 the Pinniped contributors. All Rights Reserved.
+// Copyright 2021-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 // Package issuerconfig contains helpers for updating CredentialIssuer status entries.
@@ -53,7 +53,8 @@ func mergeStrategy(configToUpdate *conciergeconfigv1alpha1.CredentialIssuerStatu
 
 // weights are a set of priorities for each strategy type.
 var weights = map[conciergeconfigv1alpha1.StrategyType]int{ //nolint:gochecknoglobals
-	conciergeconfigv1alpha1.KubeClusterSigningCertificateStrategyType: 2, // most preferred strategy
+	conciergeconfigv1alpha1.KubeClusterSigningCertificateStrategyType: 3, // most preferred strategy
+	conciergeconfigv1alpha1.CertificateSigningRequestStrategyType:     2,
 	conciergeconfigv1alpha1.ImpersonationProxyStrategyType:            1,
 	// unknown strategy types will have weight 0 by default
 }
@@ -1,4 +1,4 @@
-// Copyright 2021-2024 the Pinniped contributors. All Rights Reserved.
+// Copyright 2021-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package issuerconfig
@@ -220,6 +220,7 @@ func TestMergeStrategy(t *testing.T) {
 func TestStrategySorting(t *testing.T) {
 	expected := []conciergeconfigv1alpha1.CredentialIssuerStrategy{
 		{Type: conciergeconfigv1alpha1.KubeClusterSigningCertificateStrategyType},
+		{Type: conciergeconfigv1alpha1.CertificateSigningRequestStrategyType},
 		{Type: conciergeconfigv1alpha1.ImpersonationProxyStrategyType},
 		{Type: "Type1"},
 		{Type: "Type2"},
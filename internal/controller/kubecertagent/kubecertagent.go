@@ -1,4 +1,4 @@
-// Copyright 2021-2025 the Pinniped contributors. All Rights Reserved.
+// Copyright 2021-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 // Package kubecertagent provides controllers that ensure a pod (the kube-cert-agent), is
@@ -67,8 +67,9 @@ const (
 	// This name is determined in the YAML manifests, but this controller needs to treat it as a special case below.
 	conciergeDefaultLabelKeyName = "app"
 
+	// ClusterInfoNamespace and ClusterInfoName identify the ConfigMap which describes how to reach the Kubernetes API.
 	ClusterInfoNamespace    = "kube-public"
-	clusterInfoName         = "cluster-info"
+	ClusterInfoName         = "cluster-info"
 	clusterInfoConfigMapKey = "kubeconfig"
 
 	agentPodContainerName = "sleeper"
@@ -256,7 +257,7 @@ func newAgentController(
 		controllerlib.WithInformer(
 			kubePublicConfigMaps,
 			pinnipedcontroller.SimpleFilterWithSingletonQueue(func(obj metav1.Object) bool {
-				return obj.GetNamespace() == ClusterInfoNamespace && obj.GetName() == clusterInfoName
+				return obj.GetNamespace() == ClusterInfoNamespace && obj.GetName() == ClusterInfoName
 			}),
 			controllerlib.InformerOption{},
 		),
@@ -337,15 +338,15 @@ func (c *agentController) Sync(ctx controllerlib.Context) error {
 	}
 
 	// Load the Kubernetes API info from the kube-public/cluster-info ConfigMap.
-	configMap, err := c.kubePublicConfigMaps.Lister().ConfigMaps(ClusterInfoNamespace).Get(clusterInfoName)
+	configMap, err := c.kubePublicConfigMaps.Lister().ConfigMaps(ClusterInfoNamespace).Get(ClusterInfoName)
 	if err != nil {
-		err := fmt.Errorf("failed to get %s/%s configmap: %w", ClusterInfoNamespace, clusterInfoName, err)
+		err := fmt.Errorf("failed to get %s/%s configmap: %w", ClusterInfoNamespace, ClusterInfoName, err)
 		return c.failStrategyAndErr(ctx.Context, credIssuer, firstErr(depErr, err), conciergeconfigv1alpha1.CouldNotGetClusterInfoStrategyReason)
 	}
 
-	apiInfo, err := c.extractAPIInfo(configMap)
+	apiInfo, err := APIInfoFromClusterInfo(configMap, c.cfg.DiscoveryURLOverride)
 	if err != nil {
-		err := fmt.Errorf("could not extract Kubernetes API endpoint info from %s/%s configmap: %w", ClusterInfoNamespace, clusterInfoName, err)
+		err := fmt.Errorf("could not extract Kubernetes API endpoint info from %s/%s configmap: %w", ClusterInfoNamespace, ClusterInfoName, err)
 		return c.failStrategyAndErr(ctx.Context, credIssuer, firstErr(depErr, err), conciergeconfigv1alpha1.CouldNotGetClusterInfoStrategyReason)
 	}
 
@@ -493,7 +494,9 @@ func (c *agentController) failStrategyAndErr(ctx context.Context, credIssuer *co
 	return utilerrors.NewAggregate([]error{err, updateErr})
 }
 
-func (c *agentController) extractAPIInfo(configMap *corev1.ConfigMap) (*conciergeconfigv1alpha1.TokenCredentialRequestAPIInfo, error) {
+// APIInfoFromClusterInfo extracts the Kubernetes API endpoint info from the kube-public/cluster-info ConfigMap.
+// When discoveryURLOverride is not nil, it is reported instead of the server URL from the ConfigMap.
+func APIInfoFromClusterInfo(configMap *corev1.ConfigMap, discoveryURLOverride *string) (*conciergeconfigv1alpha1.TokenCredentialRequestAPIInfo, error) {
 	kubeConfigYAML, kubeConfigPresent := configMap.Data[clusterInfoConfigMapKey]
 	if !kubeConfigPresent {
 		return nil, fmt.Errorf("missing %q key", clusterInfoConfigMapKey)
@@ -510,8 +513,8 @@ func (c *agentController) extractAPIInfo(configMap *corev1.ConfigMap) (*concierg
 			Server:                   v.Server,
 			CertificateAuthorityData: base64.StdEncoding.EncodeToString(v.CertificateAuthorityData),
 		}
-		if c.cfg.DiscoveryURLOverride != nil {
-			result.Server = *c.cfg.DiscoveryURLOverride
+		if discoveryURLOverride != nil {
+			result.Server = *discoveryURLOverride
 		}
 		return result, nil
 	}
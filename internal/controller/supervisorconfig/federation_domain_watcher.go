@@ -7,6 +7,7 @@ import (
 	"context"
 	"fmt"
 	"net/url"
+	"slices"
 	"sort"
 	"strings"
 	"time"
@@ -19,6 +20,7 @@ import (
 	"k8s.io/apimachinery/pkg/types"
 	utilerrors "k8s.io/apimachinery/pkg/util/errors"
 	"k8s.io/apimachinery/pkg/util/sets"
+	corev1informers "k8s.io/client-go/informers/core/v1"
 	"k8s.io/utils/clock"
 
 	supervisorconfigv1alpha1 "go.pinniped.dev/generated/latest/apis/supervisor/config/v1alpha1"
@@ -29,7 +31,10 @@ import (
 	pinnipedcontroller "go.pinniped.dev/internal/controller"
 	"go.pinniped.dev/internal/controller/conditionsutil"
 	"go.pinniped.dev/internal/controllerlib"
+	"go.pinniped.dev/internal/federationdomain/brandedpages"
+	"go.pinniped.dev/internal/federationdomain/branding"
 	"go.pinniped.dev/internal/federationdomain/federationdomainproviders"
+	"go.pinniped.dev/internal/federationdomain/timeouts"
 	"go.pinniped.dev/internal/idtransform"
 	"go.pinniped.dev/internal/plog"
 )
@@ -47,6 +52,8 @@ const (
 	typeIdentityProvidersObjectRefKindValid  = "IdentityProvidersObjectRefKindValid"
 	typeTransformsExpressionsValid           = "TransformsExpressionsValid"
 	typeTransformsExamplesPassed             = "TransformsExamplesPassed"
+	typeAuthenticationPoliciesValid          = "AuthenticationPoliciesValid"
+	typeBrandingValid                        = "BrandingValid"
 
 	reasonDuplicateIssuer                             = "DuplicateIssuer"
 	reasonDifferentSecretRefsFound                    = "DifferentSecretRefsFound"
@@ -59,11 +66,17 @@ const (
 	reasonKindUnrecognized                            = "KindUnrecognized"
 	reasonInvalidTransformsExpressions                = "InvalidTransformsExpressions"
 	reasonTransformsExamplesFailed                    = "TransformsExamplesFailed"
+	reasonInvalidAuthenticationPolicies               = "InvalidAuthenticationPolicies"
+	reasonBrandingConfigMapNotFound                   = "BrandingConfigMapNotFound"
+	reasonInvalidBranding                             = "InvalidBranding"
 
 	kindLDAPIdentityProvider            = "LDAPIdentityProvider"
 	kindOIDCIdentityProvider            = "OIDCIdentityProvider"
 	kindActiveDirectoryIdentityProvider = "ActiveDirectoryIdentityProvider"
 	kindGitHubIdentityProvider          = "GitHubIdentityProvider"
+	kindSAMLIdentityProvider            = "SAMLIdentityProvider"
+	kindGitLabIdentityProvider          = "GitLabIdentityProvider"
+	kindOAuth2IdentityProvider          = "OAuth2IdentityProvider"
 
 	celTransformerMaxExpressionRuntime = 5 * time.Second
 )
@@ -86,6 +99,10 @@ type federationDomainWatcherController struct {
 	ldapIdentityProviderInformer            idpinformers.LDAPIdentityProviderInformer
 	activeDirectoryIdentityProviderInformer idpinformers.ActiveDirectoryIdentityProviderInformer
 	githubIdentityProviderInformer          idpinformers.GitHubIdentityProviderInformer
+	samlIdentityProviderInformer            idpinformers.SAMLIdentityProviderInformer
+	gitlabIdentityProviderInformer          idpinformers.GitLabIdentityProviderInformer
+	oauth2IdentityProviderInformer          idpinformers.OAuth2IdentityProviderInformer
+	configMapInformer                       corev1informers.ConfigMapInformer
 
 	celTransformer *celtransformer.CELTransformer
 	allowedKinds   sets.Set[string]
@@ -103,9 +120,13 @@ func NewFederationDomainWatcherController(
 	ldapIdentityProviderInformer idpinformers.LDAPIdentityProviderInformer,
 	activeDirectoryIdentityProviderInformer idpinformers.ActiveDirectoryIdentityProviderInformer,
 	githubProviderInformer idpinformers.GitHubIdentityProviderInformer,
+	samlProviderInformer idpinformers.SAMLIdentityProviderInformer,
+	gitlabProviderInformer idpinformers.GitLabIdentityProviderInformer,
+	oauth2ProviderInformer idpinformers.OAuth2IdentityProviderInformer,
+	configMapInformer corev1informers.ConfigMapInformer,
 	withInformer pinnipedcontroller.WithInformerOptionFunc,
 ) controllerlib.Controller {
-	allowedKinds := sets.New(kindActiveDirectoryIdentityProvider, kindLDAPIdentityProvider, kindOIDCIdentityProvider, kindGitHubIdentityProvider)
+	allowedKinds := sets.New(kindActiveDirectoryIdentityProvider, kindLDAPIdentityProvider, kindOIDCIdentityProvider, kindGitHubIdentityProvider, kindSAMLIdentityProvider, kindGitLabIdentityProvider, kindOAuth2IdentityProvider)
 	return controllerlib.New(
 		controllerlib.Config{
 			Name: controllerName,
@@ -119,6 +140,10 @@ func NewFederationDomainWatcherController(
 				ldapIdentityProviderInformer:            ldapIdentityProviderInformer,
 				activeDirectoryIdentityProviderInformer: activeDirectoryIdentityProviderInformer,
 				githubIdentityProviderInformer:          githubProviderInformer,
+				samlIdentityProviderInformer:            samlProviderInformer,
+				gitlabIdentityProviderInformer:          gitlabProviderInformer,
+				oauth2IdentityProviderInformer:          oauth2ProviderInformer,
+				configMapInformer:                       configMapInformer,
 				allowedKinds:                            allowedKinds,
 			},
 		},
@@ -155,6 +180,33 @@ func NewFederationDomainWatcherController(
 			pinnipedcontroller.MatchAnythingIgnoringUpdatesFilter(pinnipedcontroller.SingletonQueue()),
 			controllerlib.InformerOption{},
 		),
+		withInformer(
+			samlProviderInformer,
+			// Since this controller only cares about IDP metadata names and UIDs (immutable fields),
+			// we only need to trigger Sync on creates and deletes.
+			pinnipedcontroller.MatchAnythingIgnoringUpdatesFilter(pinnipedcontroller.SingletonQueue()),
+			controllerlib.InformerOption{},
+		),
+		withInformer(
+			gitlabProviderInformer,
+			// Since this controller only cares about IDP metadata names and UIDs (immutable fields),
+			// we only need to trigger Sync on creates and deletes.
+			pinnipedcontroller.MatchAnythingIgnoringUpdatesFilter(pinnipedcontroller.SingletonQueue()),
+			controllerlib.InformerOption{},
+		),
+		withInformer(
+			oauth2ProviderInformer,
+			// Since this controller only cares about IDP metadata names and UIDs (immutable fields),
+			// we only need to trigger Sync on creates and deletes.
+			pinnipedcontroller.MatchAnythingIgnoringUpdatesFilter(pinnipedcontroller.SingletonQueue()),
+			controllerlib.InformerOption{},
+		),
+		withInformer(
+			configMapInformer,
+			// The branding ConfigMaps of the FederationDomains may be edited at any time.
+			pinnipedcontroller.MatchAnythingFilter(pinnipedcontroller.SingletonQueue()),
+			controllerlib.InformerOption{},
+		),
 	)
 }
 
@@ -249,9 +301,120 @@ func (c *federationDomainWatcherController) makeFederationDomainIssuer(
 		}
 	}
 
+	pages, conditions, err := c.brandedPagesForFederationDomain(federationDomain, conditions)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if federationDomainIssuer != nil {
+		federationDomainIssuer.SetName(federationDomain.Name)
+		federationDomainIssuer.SetSessionPolicy(sessionPolicyFromFederationDomain(federationDomain.Spec.SessionPolicy))
+		if federationDomain.Spec.Signing != nil {
+			federationDomainIssuer.SetIDTokenSigningAlgorithm(string(federationDomain.Spec.Signing.Algorithm))
+		}
+		federationDomainIssuer.SetClientRegistrationPolicy(clientRegistrationPolicyFromFederationDomain(federationDomain))
+		federationDomainIssuer.SetBrandedPages(pages)
+		federationDomainIssuer.SetDefaultLanguage(federationDomain.Spec.DefaultLanguage)
+	}
+
 	return federationDomainIssuer, conditions, nil
 }
 
+func (c *federationDomainWatcherController) brandedPagesForFederationDomain(
+	federationDomain *supervisorconfigv1alpha1.FederationDomain,
+	conditions []*metav1.Condition,
+) (*brandedpages.Pages, []*metav1.Condition, error) {
+	if federationDomain.Spec.Branding == nil {
+		conditions = append(conditions, &metav1.Condition{
+			Type:    typeBrandingValid,
+			Status:  metav1.ConditionTrue,
+			Reason:  conditionsutil.ReasonSuccess,
+			Message: "no branding is specified by .spec.branding, so the default branding is used",
+		})
+		return brandedpages.Default(), conditions, nil
+	}
+
+	configMapName := federationDomain.Spec.Branding.ConfigMapName
+	configMap, err := c.configMapInformer.Lister().ConfigMaps(federationDomain.Namespace).Get(configMapName)
+	if err != nil {
+		if !apierrors.IsNotFound(err) {
+			return nil, nil, err // shouldn't really happen
+		}
+		conditions = append(conditions, &metav1.Condition{
+			Type:    typeBrandingValid,
+			Status:  metav1.ConditionFalse,
+			Reason:  reasonBrandingConfigMapNotFound,
+			Message: fmt.Sprintf("the ConfigMap %q specified by .spec.branding.configMapName was not found", configMapName),
+		})
+		return nil, conditions, nil
+	}
+
+	b, err := branding.FromConfigMap(configMap)
+	var pages *brandedpages.Pages
+	if err == nil {
+		pages, err = brandedpages.New(b)
+	}
+	if err != nil {
+		conditions = append(conditions, &metav1.Condition{
+			Type:    typeBrandingValid,
+			Status:  metav1.ConditionFalse,
+			Reason:  reasonInvalidBranding,
+			Message: fmt.Sprintf("the ConfigMap %q specified by .spec.branding.configMapName is invalid: %s", configMapName, err.Error()),
+		})
+		return nil, conditions, nil
+	}
+
+	conditions = append(conditions, &metav1.Condition{
+		Type:    typeBrandingValid,
+		Status:  metav1.ConditionTrue,
+		Reason:  conditionsutil.ReasonSuccess,
+		Message: fmt.Sprintf("the ConfigMap %q specified by .spec.branding.configMapName is valid", configMapName),
+	})
+	return pages, conditions, nil
+}
+
+func clientRegistrationPolicyFromFederationDomain(
+	federationDomain *supervisorconfigv1alpha1.FederationDomain,
+) *federationdomainproviders.ClientRegistrationPolicy {
+	registration := federationDomain.Spec.ClientRegistration
+	if registration == nil {
+		return nil
+	}
+
+	policy := &federationdomainproviders.ClientRegistrationPolicy{
+		FederationDomainName:          federationDomain.Name,
+		InitialAccessTokenSecretName:  registration.InitialAccessTokenSecretName,
+		AllowKubernetesAuthentication: registration.AllowKubernetesAuthentication,
+		AllowedRedirectURIPatterns:    slices.Clone(registration.AllowedRedirectURIPatterns),
+	}
+	for _, grantType := range registration.AllowedGrantTypes {
+		policy.AllowedGrantTypes = append(policy.AllowedGrantTypes, string(grantType))
+	}
+	for _, scope := range registration.AllowedScopes {
+		policy.AllowedScopes = append(policy.AllowedScopes, string(scope))
+	}
+	return policy
+}
+
+func sessionPolicyFromFederationDomain(policy *supervisorconfigv1alpha1.FederationDomainSessionPolicy) timeouts.SessionPolicy {
+	if policy == nil {
+		return timeouts.SessionPolicy{}
+	}
+	return timeouts.SessionPolicy{
+		MaxSessionAge:        secondsToDuration(policy.MaxSessionAgeSeconds),
+		RefreshTokenLifespan: secondsToDuration(policy.RefreshTokenSeconds),
+		IdleTimeout:          secondsToDuration(policy.IdleTimeoutSeconds),
+		AccessTokenLifespan:  secondsToDuration(policy.AccessTokenSeconds),
+	}
+}
+
+func secondsToDuration(seconds *int32) time.Duration {
+	if seconds == nil {
+		return 0
+	}
+	return time.Duration(*seconds) * time.Second
+}
+
 func (c *federationDomainWatcherController) makeLegacyFederationDomainIssuer(
 	federationDomain *supervisorconfigv1alpha1.FederationDomain,
 	conditions []*metav1.Condition,
@@ -275,8 +438,20 @@ func (c *federationDomainWatcherController) makeLegacyFederationDomainIssuer(
 	if err != nil {
 		return nil, nil, err
 	}
+	samlIdentityProviders, err := c.samlIdentityProviderInformer.Lister().List(labels.Everything())
+	if err != nil {
+		return nil, nil, err
+	}
+	gitlabIdentityProviders, err := c.gitlabIdentityProviderInformer.Lister().List(labels.Everything())
+	if err != nil {
+		return nil, nil, err
+	}
+	oauth2IdentityProviders, err := c.oauth2IdentityProviderInformer.Lister().List(labels.Everything())
+	if err != nil {
+		return nil, nil, err
+	}
 	// Check if that there is exactly one IDP defined in the Supervisor namespace of any IDP CRD type.
-	idpCRsCount := len(oidcIdentityProviders) + len(ldapIdentityProviders) + len(activeDirectoryIdentityProviders) + len(githubIdentityProviders)
+	idpCRsCount := len(oidcIdentityProviders) + len(ldapIdentityProviders) + len(activeDirectoryIdentityProviders) + len(githubIdentityProviders) + len(samlIdentityProviders) + len(gitlabIdentityProviders) + len(oauth2IdentityProviders)
 
 	switch {
 	case idpCRsCount == 1:
@@ -300,6 +475,18 @@ func (c *federationDomainWatcherController) makeLegacyFederationDomainIssuer(
 			defaultFederationDomainIdentityProvider.DisplayName = githubIdentityProviders[0].Name
 			defaultFederationDomainIdentityProvider.UID = githubIdentityProviders[0].UID
 			foundIDPName = githubIdentityProviders[0].Name
+		case len(samlIdentityProviders) == 1:
+			defaultFederationDomainIdentityProvider.DisplayName = samlIdentityProviders[0].Name
+			defaultFederationDomainIdentityProvider.UID = samlIdentityProviders[0].UID
+			foundIDPName = samlIdentityProviders[0].Name
+		case len(gitlabIdentityProviders) == 1:
+			defaultFederationDomainIdentityProvider.DisplayName = gitlabIdentityProviders[0].Name
+			defaultFederationDomainIdentityProvider.UID = gitlabIdentityProviders[0].UID
+			foundIDPName = gitlabIdentityProviders[0].Name
+		case len(oauth2IdentityProviders) == 1:
+			defaultFederationDomainIdentityProvider.DisplayName = oauth2IdentityProviders[0].Name
+			defaultFederationDomainIdentityProvider.UID = oauth2IdentityProviders[0].UID
+			foundIDPName = oauth2IdentityProviders[0].Name
 		}
 		// Backwards compatibility mode always uses an empty identity transformation pipeline since no
 		// transformations are defined on the FederationDomain.
@@ -344,6 +531,7 @@ func (c *federationDomainWatcherController) makeLegacyFederationDomainIssuer(
 	conditions = appendIdentityProviderObjectRefKindCondition(c.sortedAllowedKinds(), []string{}, conditions)
 	conditions = appendTransformsExpressionsValidCondition([]string{}, conditions)
 	conditions = appendTransformsExamplesPassedCondition([]string{}, conditions)
+	conditions = appendAuthenticationPoliciesValidCondition([]string{}, conditions)
 
 	return federationDomainIssuer, conditions, nil
 }
@@ -361,6 +549,7 @@ func (c *federationDomainWatcherController) makeFederationDomainIssuerWithExplic
 	badAPIGroupNames := []string{}
 	badKinds := []string{}
 	validationErrorMessages := &transformsValidationErrorMessages{}
+	authenticationPolicyErrorMessages := []string{}
 
 	for index, idp := range federationDomain.Spec.IdentityProviders {
 		idpIsValid := true
@@ -420,16 +609,24 @@ func (c *federationDomainWatcherController) makeFederationDomainIssuerWithExplic
 			idpIsValid = false
 		}
 
+		authenticationPolicy, authenticationPolicyErrorMessage := c.makeAuthenticationPolicyForIdentityProvider(idp, index)
+		if authenticationPolicyErrorMessage != "" {
+			authenticationPolicyErrorMessages = append(authenticationPolicyErrorMessages, authenticationPolicyErrorMessage)
+			idpIsValid = false
+		}
+
 		if !idpIsValid {
 			// Something about the IDP was not valid. Don't add it.
 			continue
 		}
 
-		// For a valid IDP (unique displayName, valid objectRef, valid transforms), add it to the list.
+		// For a valid IDP (unique displayName, valid objectRef, valid transforms, valid authentication policy),
+		// add it to the list.
 		federationDomainIdentityProviders = append(federationDomainIdentityProviders, &federationdomainproviders.FederationDomainIdentityProvider{
-			DisplayName: idp.DisplayName,
-			UID:         idpResourceUID,
-			Transforms:  pipeline,
+			DisplayName:          idp.DisplayName,
+			UID:                  idpResourceUID,
+			Transforms:           pipeline,
+			AuthenticationPolicy: authenticationPolicy,
 		})
 	}
 
@@ -444,6 +641,7 @@ func (c *federationDomainWatcherController) makeFederationDomainIssuerWithExplic
 
 	conditions = appendTransformsExpressionsValidCondition(validationErrorMessages.errorsForExpressions, conditions)
 	conditions = appendTransformsExamplesPassedCondition(validationErrorMessages.errorsForExamples, conditions)
+	conditions = appendAuthenticationPoliciesValidCondition(authenticationPolicyErrorMessages, conditions)
 
 	return federationDomainIssuer, conditions, nil
 }
@@ -462,6 +660,12 @@ func (c *federationDomainWatcherController) findIDPsUIDByObjectRef(objectRef cor
 		foundIDP, err = c.oidcIdentityProviderInformer.Lister().OIDCIdentityProviders(namespace).Get(objectRef.Name)
 	case kindGitHubIdentityProvider:
 		foundIDP, err = c.githubIdentityProviderInformer.Lister().GitHubIdentityProviders(namespace).Get(objectRef.Name)
+	case kindSAMLIdentityProvider:
+		foundIDP, err = c.samlIdentityProviderInformer.Lister().SAMLIdentityProviders(namespace).Get(objectRef.Name)
+	case kindGitLabIdentityProvider:
+		foundIDP, err = c.gitlabIdentityProviderInformer.Lister().GitLabIdentityProviders(namespace).Get(objectRef.Name)
+	case kindOAuth2IdentityProvider:
+		foundIDP, err = c.oauth2IdentityProviderInformer.Lister().OAuth2IdentityProviders(namespace).Get(objectRef.Name)
 	default:
 		// This shouldn't happen because this helper function is not called when the kind is invalid.
 		return "", false, fmt.Errorf("unexpected kind: %s", objectRef.Kind)
@@ -578,6 +782,32 @@ func (c *federationDomainWatcherController) makeTransformationPipelineForIdentit
 	return pipeline, "", nil
 }
 
+func (c *federationDomainWatcherController) makeAuthenticationPolicyForIdentityProvider(
+	idp supervisorconfigv1alpha1.FederationDomainIdentityProvider,
+	idpIndex int,
+) (*celtransformer.CompiledUpstreamAuthenticationPolicy, string) {
+	if idp.AuthenticationPolicy == nil {
+		return nil, ""
+	}
+
+	// The policy is evaluated against the claims of an upstream ID token, so only OIDC upstreams can support it.
+	if idp.ObjectRef.Kind != kindOIDCIdentityProvider {
+		return nil, fmt.Sprintf(".spec.identityProviders[%d].authenticationPolicy is only supported when "+
+			".spec.identityProviders[%d].objectRef.kind is %q", idpIndex, idpIndex, kindOIDCIdentityProvider)
+	}
+
+	compiledPolicy, err := c.celTransformer.CompileUpstreamAuthenticationPolicy(&celtransformer.UpstreamAuthenticationPolicy{
+		ACRValues:                     idp.AuthenticationPolicy.ACRValues,
+		Expression:                    idp.AuthenticationPolicy.Expression,
+		RejectedAuthenticationMessage: idp.AuthenticationPolicy.Message,
+	})
+	if err != nil {
+		return nil, fmt.Sprintf(".spec.identityProviders[%d].authenticationPolicy was invalid:\n%s", idpIndex, err.Error())
+	}
+
+	return compiledPolicy, ""
+}
+
 func (c *federationDomainWatcherController) evaluateExamplesForIdentityProvider(
 	ctx context.Context,
 	idp supervisorconfigv1alpha1.FederationDomainIdentityProvider,
@@ -762,6 +992,25 @@ func appendTransformsExamplesPassedCondition(messages []string, conditions []*me
 	return conditions
 }
 
+func appendAuthenticationPoliciesValidCondition(messages []string, conditions []*metav1.Condition) []*metav1.Condition {
+	if len(messages) > 0 {
+		conditions = append(conditions, &metav1.Condition{
+			Type:    typeAuthenticationPoliciesValid,
+			Status:  metav1.ConditionFalse,
+			Reason:  reasonInvalidAuthenticationPolicies,
+			Message: strings.Join(messages, "\n\n"),
+		})
+	} else {
+		conditions = append(conditions, &metav1.Condition{
+			Type:    typeAuthenticationPoliciesValid,
+			Status:  metav1.ConditionTrue,
+			Reason:  conditionsutil.ReasonSuccess,
+			Message: "the authentication policies specified by .spec.identityProviders[].authenticationPolicy are valid",
+		})
+	}
+	return conditions
+}
+
 func appendIdentityProviderDuplicateDisplayNamesCondition(duplicateDisplayNames sets.Set[string], conditions []*metav1.Condition) []*metav1.Condition {
 	if duplicateDisplayNames.Len() > 0 {
 		conditions = append(conditions, &metav1.Condition{
@@ -18,6 +18,8 @@ import (
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
+	kubeinformers "k8s.io/client-go/informers"
+	kubefake "k8s.io/client-go/kubernetes/fake"
 	coretesting "k8s.io/client-go/testing"
 	clocktesting "k8s.io/utils/clock/testing"
 	"k8s.io/utils/ptr"
@@ -28,7 +30,10 @@ import (
 	supervisorinformers "go.pinniped.dev/generated/latest/client/supervisor/informers/externalversions"
 	"go.pinniped.dev/internal/celtransformer"
 	"go.pinniped.dev/internal/controllerlib"
+	"go.pinniped.dev/internal/federationdomain/brandedpages"
+	"go.pinniped.dev/internal/federationdomain/branding"
 	"go.pinniped.dev/internal/federationdomain/federationdomainproviders"
+	"go.pinniped.dev/internal/federationdomain/timeouts"
 	"go.pinniped.dev/internal/here"
 	"go.pinniped.dev/internal/idtransform"
 	"go.pinniped.dev/internal/testutil"
@@ -43,6 +48,10 @@ func TestFederationDomainWatcherControllerInformerFilters(t *testing.T) {
 	ldapIdentityProviderInformer := supervisorinformers.NewSharedInformerFactoryWithOptions(nil, 0).IDP().V1alpha1().LDAPIdentityProviders()
 	adIdentityProviderInformer := supervisorinformers.NewSharedInformerFactoryWithOptions(nil, 0).IDP().V1alpha1().ActiveDirectoryIdentityProviders()
 	githubIdentityProviderInformer := supervisorinformers.NewSharedInformerFactoryWithOptions(nil, 0).IDP().V1alpha1().GitHubIdentityProviders()
+	samlIdentityProviderInformer := supervisorinformers.NewSharedInformerFactoryWithOptions(nil, 0).IDP().V1alpha1().SAMLIdentityProviders()
+	gitlabIdentityProviderInformer := supervisorinformers.NewSharedInformerFactoryWithOptions(nil, 0).IDP().V1alpha1().GitLabIdentityProviders()
+	oauth2IdentityProviderInformer := supervisorinformers.NewSharedInformerFactoryWithOptions(nil, 0).IDP().V1alpha1().OAuth2IdentityProviders()
+	configMapInformer := kubeinformers.NewSharedInformerFactoryWithOptions(nil, 0).Core().V1().ConfigMaps()
 
 	tests := []struct {
 		name       string
@@ -90,6 +99,34 @@ func TestFederationDomainWatcherControllerInformerFilters(t *testing.T) {
 			wantAdd:    true,
 			wantUpdate: false,
 			wantDelete: true,
+		}, {
+			name:       "any SAMLIdentityProvider adds or deletes, but updates are ignored",
+			obj:        &idpv1alpha1.SAMLIdentityProvider{},
+			informer:   samlIdentityProviderInformer,
+			wantAdd:    true,
+			wantUpdate: false,
+			wantDelete: true,
+		}, {
+			name:       "any GitLabIdentityProvider adds or deletes, but updates are ignored",
+			obj:        &idpv1alpha1.GitLabIdentityProvider{},
+			informer:   gitlabIdentityProviderInformer,
+			wantAdd:    true,
+			wantUpdate: false,
+			wantDelete: true,
+		}, {
+			name:       "any OAuth2IdentityProvider adds or deletes, but updates are ignored",
+			obj:        &idpv1alpha1.OAuth2IdentityProvider{},
+			informer:   oauth2IdentityProviderInformer,
+			wantAdd:    true,
+			wantUpdate: false,
+			wantDelete: true,
+		}, {
+			name:       "any ConfigMap changes",
+			obj:        &corev1.ConfigMap{},
+			informer:   configMapInformer,
+			wantAdd:    true,
+			wantUpdate: true,
+			wantDelete: true,
 		},
 	}
 	for _, test := range tests {
@@ -108,6 +145,10 @@ func TestFederationDomainWatcherControllerInformerFilters(t *testing.T) {
 				ldapIdentityProviderInformer,
 				adIdentityProviderInformer,
 				githubIdentityProviderInformer,
+				samlIdentityProviderInformer,
+				gitlabIdentityProviderInformer,
+				oauth2IdentityProviderInformer,
+				configMapInformer,
 				withInformer.WithInformer, // make it possible to observe the behavior of the Filters
 			)
 
@@ -155,6 +196,18 @@ func TestTestFederationDomainWatcherControllerSync(t *testing.T) {
 		},
 	}
 
+	brandingConfigMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "some-branding", Namespace: namespace},
+		Data: map[string]string{
+			"primaryColor": "#1a73e8",
+			"helpText":     "Contact the help desk for help.",
+			"footerLinks":  "- text: Help desk\n  url: https://help.example.com\n",
+		},
+		BinaryData: map[string][]byte{
+			"logo": []byte("\x89PNG\r\n\x1a\nsome-fake-image-data"),
+		},
+	}
+
 	ldapIdentityProvider := &idpv1alpha1.LDAPIdentityProvider{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      "some-ldap-idp",
@@ -179,6 +232,30 @@ func TestTestFederationDomainWatcherControllerSync(t *testing.T) {
 		},
 	}
 
+	samlIdentityProvider := &idpv1alpha1.SAMLIdentityProvider{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "some-saml-idp",
+			Namespace: namespace,
+			UID:       "some-saml-idp",
+		},
+	}
+
+	gitLabIdentityProvider := &idpv1alpha1.GitLabIdentityProvider{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "some-gitlab-idp",
+			Namespace: namespace,
+			UID:       "some-gitlab-idp",
+		},
+	}
+
+	oauth2IdentityProvider := &idpv1alpha1.OAuth2IdentityProvider{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "some-oauth2-idp",
+			Namespace: namespace,
+			UID:       "some-oauth2-idp",
+		},
+	}
+
 	federationDomain1 := &supervisorconfigv1alpha1.FederationDomain{
 		ObjectMeta: metav1.ObjectMeta{Name: "config1", Namespace: namespace, Generation: 123},
 		Spec:       supervisorconfigv1alpha1.FederationDomainSpec{Issuer: "https://issuer1.com"},
@@ -211,6 +288,30 @@ func TestTestFederationDomainWatcherControllerSync(t *testing.T) {
 		return fdIssuer
 	}
 
+	withSessionPolicy := func(fdIssuer *federationdomainproviders.FederationDomainIssuer, sessionPolicy timeouts.SessionPolicy) *federationdomainproviders.FederationDomainIssuer {
+		fdIssuer.SetSessionPolicy(sessionPolicy)
+		return fdIssuer
+	}
+
+	withClientRegistrationPolicy := func(fdIssuer *federationdomainproviders.FederationDomainIssuer, policy *federationdomainproviders.ClientRegistrationPolicy) *federationdomainproviders.FederationDomainIssuer {
+		fdIssuer.SetClientRegistrationPolicy(policy)
+		return fdIssuer
+	}
+
+	withDefaultLanguage := func(fdIssuer *federationdomainproviders.FederationDomainIssuer, lang string) *federationdomainproviders.FederationDomainIssuer {
+		fdIssuer.SetDefaultLanguage(lang)
+		return fdIssuer
+	}
+
+	withBrandingFromConfigMap := func(fdIssuer *federationdomainproviders.FederationDomainIssuer, cm *corev1.ConfigMap) *federationdomainproviders.FederationDomainIssuer {
+		b, err := branding.FromConfigMap(cm)
+		require.NoError(t, err)
+		pages, err := brandedpages.New(b)
+		require.NoError(t, err)
+		fdIssuer.SetBrandedPages(pages)
+		return fdIssuer
+	}
+
 	happyReadyCondition := func(issuer string, time metav1.Time, observedGeneration int64) metav1.Condition {
 		return metav1.Condition{
 			Type:               "Ready",
@@ -461,6 +562,56 @@ func TestTestFederationDomainWatcherControllerSync(t *testing.T) {
 		}
 	}
 
+	happyAuthenticationPoliciesCondition := func(time metav1.Time, observedGeneration int64) metav1.Condition {
+		return metav1.Condition{
+			Type:               "AuthenticationPoliciesValid",
+			Status:             "True",
+			ObservedGeneration: observedGeneration,
+			LastTransitionTime: time,
+			Reason:             "Success",
+			Message:            "the authentication policies specified by .spec.identityProviders[].authenticationPolicy are valid",
+		}
+	}
+
+	happyBrandingCondition := func(time metav1.Time, observedGeneration int64) metav1.Condition {
+		return metav1.Condition{
+			Type:               "BrandingValid",
+			Status:             "True",
+			ObservedGeneration: observedGeneration,
+			LastTransitionTime: time,
+			Reason:             "Success",
+			Message:            "no branding is specified by .spec.branding, so the default branding is used",
+		}
+	}
+
+	happyBrandingConditionWithConfigMap := func(configMapName string, time metav1.Time, observedGeneration int64) metav1.Condition {
+		c := happyBrandingCondition(time, observedGeneration)
+		c.Message = fmt.Sprintf("the ConfigMap %q specified by .spec.branding.configMapName is valid", configMapName)
+		return c
+	}
+
+	sadBrandingCondition := func(reason string, message string, time metav1.Time, observedGeneration int64) metav1.Condition {
+		return metav1.Condition{
+			Type:               "BrandingValid",
+			Status:             "False",
+			ObservedGeneration: observedGeneration,
+			LastTransitionTime: time,
+			Reason:             reason,
+			Message:            message,
+		}
+	}
+
+	sadAuthenticationPoliciesCondition := func(errorMessages string, time metav1.Time, observedGeneration int64) metav1.Condition {
+		return metav1.Condition{
+			Type:               "AuthenticationPoliciesValid",
+			Status:             "False",
+			ObservedGeneration: observedGeneration,
+			LastTransitionTime: time,
+			Reason:             "InvalidAuthenticationPolicies",
+			Message:            errorMessages,
+		}
+	}
+
 	happyAPIGroupSuffixCondition := func(time metav1.Time, observedGeneration int64) metav1.Condition {
 		return metav1.Condition{
 			Type:               "IdentityProvidersObjectRefAPIGroupSuffixValid",
@@ -503,12 +654,14 @@ func TestTestFederationDomainWatcherControllerSync(t *testing.T) {
 			LastTransitionTime: time,
 			Reason:             "KindUnrecognized",
 			Message: fmt.Sprintf(`some kinds specified by .spec.identityProviders[].objectRef.kind are `+
-				`not recognized (should be one of "ActiveDirectoryIdentityProvider", "GitHubIdentityProvider", "LDAPIdentityProvider", "OIDCIdentityProvider"): %s`, badKinds),
+				`not recognized (should be one of "ActiveDirectoryIdentityProvider", "GitHubIdentityProvider", "GitLabIdentityProvider", "LDAPIdentityProvider", "OAuth2IdentityProvider", "OIDCIdentityProvider", "SAMLIdentityProvider"): %s`, badKinds),
 		}
 	}
 
 	allHappyConditionsSuccess := func(issuer string, time metav1.Time, observedGeneration int64) []metav1.Condition {
 		return conditionstestutil.SortByType([]metav1.Condition{
+			happyAuthenticationPoliciesCondition(frozenMetav1Now, 123),
+			happyBrandingCondition(frozenMetav1Now, 123),
 			happyTransformationExamplesCondition(frozenMetav1Now, 123),
 			happyTransformationExpressionsCondition(frozenMetav1Now, 123),
 			happyKindCondition(frozenMetav1Now, 123),
@@ -538,6 +691,7 @@ func TestTestFederationDomainWatcherControllerSync(t *testing.T) {
 	tests := []struct {
 		name              string
 		inputObjects      []runtime.Object
+		inputKubeObjects  []runtime.Object
 		configClient      func(*supervisorfake.Clientset)
 		wantErr           string
 		wantStatusUpdates []*supervisorconfigv1alpha1.FederationDomain
@@ -644,6 +798,78 @@ func TestTestFederationDomainWatcherControllerSync(t *testing.T) {
 				),
 			},
 		},
+		{
+			name: "legacy config: when no identity provider is specified on federation domains, but exactly one SAML identity " +
+				"provider resource exists on cluster, the controller will set a default IDP on each federation domain " +
+				"matching the only identity provider found",
+			inputObjects: []runtime.Object{
+				federationDomain1,
+				federationDomain2,
+				samlIdentityProvider,
+			},
+			wantFDIssuers: []*federationdomainproviders.FederationDomainIssuer{
+				federationDomainIssuerWithDefaultIDP(t, federationDomain1.Spec.Issuer, samlIdentityProvider.ObjectMeta),
+				federationDomainIssuerWithDefaultIDP(t, federationDomain2.Spec.Issuer, samlIdentityProvider.ObjectMeta),
+			},
+			wantStatusUpdates: []*supervisorconfigv1alpha1.FederationDomain{
+				expectedFederationDomainStatusUpdate(federationDomain1,
+					supervisorconfigv1alpha1.FederationDomainPhaseReady,
+					allHappyConditionsLegacyConfigurationSuccess(federationDomain1.Spec.Issuer, samlIdentityProvider.Name, frozenMetav1Now, 123),
+				),
+				expectedFederationDomainStatusUpdate(federationDomain2,
+					supervisorconfigv1alpha1.FederationDomainPhaseReady,
+					allHappyConditionsLegacyConfigurationSuccess(federationDomain2.Spec.Issuer, samlIdentityProvider.Name, frozenMetav1Now, 123),
+				),
+			},
+		},
+		{
+			name: "legacy config: when no identity provider is specified on federation domains, but exactly one GitLab identity " +
+				"provider resource exists on cluster, the controller will set a default IDP on each federation domain " +
+				"matching the only identity provider found",
+			inputObjects: []runtime.Object{
+				federationDomain1,
+				federationDomain2,
+				gitLabIdentityProvider,
+			},
+			wantFDIssuers: []*federationdomainproviders.FederationDomainIssuer{
+				federationDomainIssuerWithDefaultIDP(t, federationDomain1.Spec.Issuer, gitLabIdentityProvider.ObjectMeta),
+				federationDomainIssuerWithDefaultIDP(t, federationDomain2.Spec.Issuer, gitLabIdentityProvider.ObjectMeta),
+			},
+			wantStatusUpdates: []*supervisorconfigv1alpha1.FederationDomain{
+				expectedFederationDomainStatusUpdate(federationDomain1,
+					supervisorconfigv1alpha1.FederationDomainPhaseReady,
+					allHappyConditionsLegacyConfigurationSuccess(federationDomain1.Spec.Issuer, gitLabIdentityProvider.Name, frozenMetav1Now, 123),
+				),
+				expectedFederationDomainStatusUpdate(federationDomain2,
+					supervisorconfigv1alpha1.FederationDomainPhaseReady,
+					allHappyConditionsLegacyConfigurationSuccess(federationDomain2.Spec.Issuer, gitLabIdentityProvider.Name, frozenMetav1Now, 123),
+				),
+			},
+		},
+		{
+			name: "legacy config: when no identity provider is specified on federation domains, but exactly one OAuth2 identity " +
+				"provider resource exists on cluster, the controller will set a default IDP on each federation domain " +
+				"matching the only identity provider found",
+			inputObjects: []runtime.Object{
+				federationDomain1,
+				federationDomain2,
+				oauth2IdentityProvider,
+			},
+			wantFDIssuers: []*federationdomainproviders.FederationDomainIssuer{
+				federationDomainIssuerWithDefaultIDP(t, federationDomain1.Spec.Issuer, oauth2IdentityProvider.ObjectMeta),
+				federationDomainIssuerWithDefaultIDP(t, federationDomain2.Spec.Issuer, oauth2IdentityProvider.ObjectMeta),
+			},
+			wantStatusUpdates: []*supervisorconfigv1alpha1.FederationDomain{
+				expectedFederationDomainStatusUpdate(federationDomain1,
+					supervisorconfigv1alpha1.FederationDomainPhaseReady,
+					allHappyConditionsLegacyConfigurationSuccess(federationDomain1.Spec.Issuer, oauth2IdentityProvider.Name, frozenMetav1Now, 123),
+				),
+				expectedFederationDomainStatusUpdate(federationDomain2,
+					supervisorconfigv1alpha1.FederationDomainPhaseReady,
+					allHappyConditionsLegacyConfigurationSuccess(federationDomain2.Spec.Issuer, oauth2IdentityProvider.Name, frozenMetav1Now, 123),
+				),
+			},
+		},
 		{
 			name: "when there are two valid FederationDomains, but one is already up to date, the sync loop only updates " +
 				"the out-of-date FederationDomain",
@@ -1078,6 +1304,9 @@ func TestTestFederationDomainWatcherControllerSync(t *testing.T) {
 				ldapIdentityProvider,
 				adIdentityProvider,
 				gitHubIdentityProvider,
+				samlIdentityProvider,
+				gitLabIdentityProvider,
+				oauth2IdentityProvider,
 				&supervisorconfigv1alpha1.FederationDomain{
 					ObjectMeta: metav1.ObjectMeta{Name: "config1", Namespace: namespace, Generation: 123},
 					Spec: supervisorconfigv1alpha1.FederationDomainSpec{
@@ -1115,6 +1344,30 @@ func TestTestFederationDomainWatcherControllerSync(t *testing.T) {
 									Name:     gitHubIdentityProvider.Name,
 								},
 							},
+							{
+								DisplayName: "can-find-me-five",
+								ObjectRef: corev1.TypedLocalObjectReference{
+									APIGroup: ptr.To(apiGroupSupervisor),
+									Kind:     "SAMLIdentityProvider",
+									Name:     samlIdentityProvider.Name,
+								},
+							},
+							{
+								DisplayName: "can-find-me-six",
+								ObjectRef: corev1.TypedLocalObjectReference{
+									APIGroup: ptr.To(apiGroupSupervisor),
+									Kind:     "GitLabIdentityProvider",
+									Name:     gitLabIdentityProvider.Name,
+								},
+							},
+							{
+								DisplayName: "can-find-me-seven",
+								ObjectRef: corev1.TypedLocalObjectReference{
+									APIGroup: ptr.To(apiGroupSupervisor),
+									Kind:     "OAuth2IdentityProvider",
+									Name:     oauth2IdentityProvider.Name,
+								},
+							},
 						},
 					},
 				},
@@ -1142,6 +1395,21 @@ func TestTestFederationDomainWatcherControllerSync(t *testing.T) {
 							UID:         gitHubIdentityProvider.UID,
 							Transforms:  idtransform.NewTransformationPipeline(),
 						},
+						{
+							DisplayName: "can-find-me-five",
+							UID:         samlIdentityProvider.UID,
+							Transforms:  idtransform.NewTransformationPipeline(),
+						},
+						{
+							DisplayName: "can-find-me-six",
+							UID:         gitLabIdentityProvider.UID,
+							Transforms:  idtransform.NewTransformationPipeline(),
+						},
+						{
+							DisplayName: "can-find-me-seven",
+							UID:         oauth2IdentityProvider.UID,
+							Transforms:  idtransform.NewTransformationPipeline(),
+						},
 					}),
 			},
 			wantStatusUpdates: []*supervisorconfigv1alpha1.FederationDomain{
@@ -2020,6 +2288,385 @@ func TestTestFederationDomainWatcherControllerSync(t *testing.T) {
 				),
 			},
 		},
+		{
+			name: "the federation domain has valid authentication policies",
+			inputObjects: []runtime.Object{
+				oidcIdentityProvider,
+				&supervisorconfigv1alpha1.FederationDomain{
+					ObjectMeta: metav1.ObjectMeta{Name: "config1", Namespace: namespace, Generation: 123},
+					Spec: supervisorconfigv1alpha1.FederationDomainSpec{
+						Issuer: "https://issuer1.com",
+						IdentityProviders: []supervisorconfigv1alpha1.FederationDomainIdentityProvider{
+							{
+								DisplayName: "name1",
+								ObjectRef: corev1.TypedLocalObjectReference{
+									APIGroup: ptr.To(apiGroupSupervisor),
+									Kind:     "OIDCIdentityProvider",
+									Name:     oidcIdentityProvider.Name,
+								},
+								AuthenticationPolicy: &supervisorconfigv1alpha1.FederationDomainAuthenticationPolicy{
+									ACRValues:  []string{"phrh", "phr"},
+									Expression: `"mfa" in claims.amr`,
+									Message:    "please use MFA",
+								},
+							},
+							{
+								DisplayName: "name2",
+								ObjectRef: corev1.TypedLocalObjectReference{
+									APIGroup: ptr.To(apiGroupSupervisor),
+									Kind:     "OIDCIdentityProvider",
+									Name:     oidcIdentityProvider.Name,
+								},
+								AuthenticationPolicy: &supervisorconfigv1alpha1.FederationDomainAuthenticationPolicy{
+									Expression: `has(claims.amr) && "hwk" in claims.amr`,
+								},
+							},
+						},
+					},
+				},
+			},
+			wantFDIssuers: []*federationdomainproviders.FederationDomainIssuer{
+				federationDomainIssuerWithIDPs(t, "https://issuer1.com", []*federationdomainproviders.FederationDomainIdentityProvider{
+					{
+						DisplayName:          "name1",
+						UID:                  oidcIdentityProvider.UID,
+						Transforms:           idtransform.NewTransformationPipeline(),
+						AuthenticationPolicy: newAuthenticationPolicy(t, []string{"phrh", "phr"}, `"mfa" in claims.amr`),
+					},
+					{
+						DisplayName:          "name2",
+						UID:                  oidcIdentityProvider.UID,
+						Transforms:           idtransform.NewTransformationPipeline(),
+						AuthenticationPolicy: newAuthenticationPolicy(t, nil, `has(claims.amr) && "hwk" in claims.amr`),
+					},
+				}),
+			},
+			wantStatusUpdates: []*supervisorconfigv1alpha1.FederationDomain{
+				expectedFederationDomainStatusUpdate(
+					&supervisorconfigv1alpha1.FederationDomain{
+						ObjectMeta: metav1.ObjectMeta{Name: "config1", Namespace: namespace, Generation: 123},
+					},
+					supervisorconfigv1alpha1.FederationDomainPhaseReady,
+					allHappyConditionsSuccess("https://issuer1.com", frozenMetav1Now, 123),
+				),
+			},
+		},
+		{
+			name: "the federation domain has a session policy",
+			inputObjects: []runtime.Object{
+				oidcIdentityProvider,
+				&supervisorconfigv1alpha1.FederationDomain{
+					ObjectMeta: metav1.ObjectMeta{Name: "config1", Namespace: namespace, Generation: 123},
+					Spec: supervisorconfigv1alpha1.FederationDomainSpec{
+						Issuer: "https://issuer1.com",
+						SessionPolicy: &supervisorconfigv1alpha1.FederationDomainSessionPolicy{
+							MaxSessionAgeSeconds: ptr.To[int32](43200),
+							RefreshTokenSeconds:  ptr.To[int32](3600),
+							AccessTokenSeconds:   ptr.To[int32](60),
+						},
+					},
+				},
+			},
+			wantFDIssuers: []*federationdomainproviders.FederationDomainIssuer{
+				withSessionPolicy(
+					federationDomainIssuerWithDefaultIDP(t, "https://issuer1.com", oidcIdentityProvider.ObjectMeta),
+					timeouts.SessionPolicy{
+						MaxSessionAge:        12 * time.Hour,
+						RefreshTokenLifespan: time.Hour,
+						AccessTokenLifespan:  time.Minute,
+					},
+				),
+			},
+			wantStatusUpdates: []*supervisorconfigv1alpha1.FederationDomain{
+				expectedFederationDomainStatusUpdate(
+					&supervisorconfigv1alpha1.FederationDomain{
+						ObjectMeta: metav1.ObjectMeta{Name: "config1", Namespace: namespace, Generation: 123},
+					},
+					supervisorconfigv1alpha1.FederationDomainPhaseReady,
+					allHappyConditionsLegacyConfigurationSuccess("https://issuer1.com", oidcIdentityProvider.Name, frozenMetav1Now, 123),
+				),
+			},
+		},
+		{
+			name: "the federation domain allows dynamic client registration",
+			inputObjects: []runtime.Object{
+				oidcIdentityProvider,
+				&supervisorconfigv1alpha1.FederationDomain{
+					ObjectMeta: metav1.ObjectMeta{Name: "config1", Namespace: namespace, Generation: 123},
+					Spec: supervisorconfigv1alpha1.FederationDomainSpec{
+						Issuer: "https://issuer1.com",
+						ClientRegistration: &supervisorconfigv1alpha1.FederationDomainClientRegistration{
+							InitialAccessTokenSecretName:  "some-secret",
+							AllowKubernetesAuthentication: true,
+							AllowedRedirectURIPatterns:    []string{"https://*.example.com/callback"},
+							AllowedGrantTypes:             []supervisorconfigv1alpha1.GrantType{"authorization_code", "refresh_token"},
+							AllowedScopes:                 []supervisorconfigv1alpha1.Scope{"openid", "offline_access"},
+						},
+					},
+				},
+			},
+			wantFDIssuers: []*federationdomainproviders.FederationDomainIssuer{
+				withClientRegistrationPolicy(
+					federationDomainIssuerWithDefaultIDP(t, "https://issuer1.com", oidcIdentityProvider.ObjectMeta),
+					&federationdomainproviders.ClientRegistrationPolicy{
+						FederationDomainName:          "config1",
+						InitialAccessTokenSecretName:  "some-secret",
+						AllowKubernetesAuthentication: true,
+						AllowedRedirectURIPatterns:    []string{"https://*.example.com/callback"},
+						AllowedGrantTypes:             []string{"authorization_code", "refresh_token"},
+						AllowedScopes:                 []string{"openid", "offline_access"},
+					},
+				),
+			},
+			wantStatusUpdates: []*supervisorconfigv1alpha1.FederationDomain{
+				expectedFederationDomainStatusUpdate(
+					&supervisorconfigv1alpha1.FederationDomain{
+						ObjectMeta: metav1.ObjectMeta{Name: "config1", Namespace: namespace, Generation: 123},
+					},
+					supervisorconfigv1alpha1.FederationDomainPhaseReady,
+					allHappyConditionsLegacyConfigurationSuccess("https://issuer1.com", oidcIdentityProvider.Name, frozenMetav1Now, 123),
+				),
+			},
+		},
+		{
+			name: "the federation domain has a default language for its web pages",
+			inputObjects: []runtime.Object{
+				oidcIdentityProvider,
+				&supervisorconfigv1alpha1.FederationDomain{
+					ObjectMeta: metav1.ObjectMeta{Name: "config1", Namespace: namespace, Generation: 123},
+					Spec: supervisorconfigv1alpha1.FederationDomainSpec{
+						Issuer:          "https://issuer1.com",
+						DefaultLanguage: "fr",
+					},
+				},
+			},
+			wantFDIssuers: []*federationdomainproviders.FederationDomainIssuer{
+				withDefaultLanguage(
+					federationDomainIssuerWithDefaultIDP(t, "https://issuer1.com", oidcIdentityProvider.ObjectMeta),
+					"fr",
+				),
+			},
+			wantStatusUpdates: []*supervisorconfigv1alpha1.FederationDomain{
+				expectedFederationDomainStatusUpdate(
+					&supervisorconfigv1alpha1.FederationDomain{
+						ObjectMeta: metav1.ObjectMeta{Name: "config1", Namespace: namespace, Generation: 123},
+					},
+					supervisorconfigv1alpha1.FederationDomainPhaseReady,
+					allHappyConditionsLegacyConfigurationSuccess("https://issuer1.com", oidcIdentityProvider.Name, frozenMetav1Now, 123),
+				),
+			},
+		},
+		{
+			name: "the federation domain is branded by a valid ConfigMap",
+			inputObjects: []runtime.Object{
+				oidcIdentityProvider,
+				&supervisorconfigv1alpha1.FederationDomain{
+					ObjectMeta: metav1.ObjectMeta{Name: "config1", Namespace: namespace, Generation: 123},
+					Spec: supervisorconfigv1alpha1.FederationDomainSpec{
+						Issuer:   "https://issuer1.com",
+						Branding: &supervisorconfigv1alpha1.FederationDomainBranding{ConfigMapName: "some-branding"},
+					},
+				},
+			},
+			inputKubeObjects: []runtime.Object{brandingConfigMap},
+			wantFDIssuers: []*federationdomainproviders.FederationDomainIssuer{
+				withBrandingFromConfigMap(
+					federationDomainIssuerWithDefaultIDP(t, "https://issuer1.com", oidcIdentityProvider.ObjectMeta),
+					brandingConfigMap,
+				),
+			},
+			wantStatusUpdates: []*supervisorconfigv1alpha1.FederationDomain{
+				expectedFederationDomainStatusUpdate(
+					&supervisorconfigv1alpha1.FederationDomain{
+						ObjectMeta: metav1.ObjectMeta{Name: "config1", Namespace: namespace, Generation: 123},
+					},
+					supervisorconfigv1alpha1.FederationDomainPhaseReady,
+					conditionstestutil.Replace(
+						allHappyConditionsLegacyConfigurationSuccess("https://issuer1.com", oidcIdentityProvider.Name, frozenMetav1Now, 123),
+						[]metav1.Condition{
+							happyBrandingConditionWithConfigMap("some-branding", frozenMetav1Now, 123),
+						}),
+				),
+			},
+		},
+		{
+			name: "the federation domain is branded by a ConfigMap which does not exist",
+			inputObjects: []runtime.Object{
+				oidcIdentityProvider,
+				&supervisorconfigv1alpha1.FederationDomain{
+					ObjectMeta: metav1.ObjectMeta{Name: "config1", Namespace: namespace, Generation: 123},
+					Spec: supervisorconfigv1alpha1.FederationDomainSpec{
+						Issuer:   "https://issuer1.com",
+						Branding: &supervisorconfigv1alpha1.FederationDomainBranding{ConfigMapName: "some-branding"},
+					},
+				},
+			},
+			wantFDIssuers: []*federationdomainproviders.FederationDomainIssuer{},
+			wantStatusUpdates: []*supervisorconfigv1alpha1.FederationDomain{
+				expectedFederationDomainStatusUpdate(
+					&supervisorconfigv1alpha1.FederationDomain{
+						ObjectMeta: metav1.ObjectMeta{Name: "config1", Namespace: namespace, Generation: 123},
+					},
+					supervisorconfigv1alpha1.FederationDomainPhaseError,
+					conditionstestutil.Replace(
+						allHappyConditionsLegacyConfigurationSuccess("https://issuer1.com", oidcIdentityProvider.Name, frozenMetav1Now, 123),
+						[]metav1.Condition{
+							sadBrandingCondition("BrandingConfigMapNotFound",
+								`the ConfigMap "some-branding" specified by .spec.branding.configMapName was not found`,
+								frozenMetav1Now, 123),
+							sadReadyCondition(frozenMetav1Now, 123),
+						}),
+				),
+			},
+		},
+		{
+			name: "the federation domain is branded by an invalid ConfigMap",
+			inputObjects: []runtime.Object{
+				oidcIdentityProvider,
+				&supervisorconfigv1alpha1.FederationDomain{
+					ObjectMeta: metav1.ObjectMeta{Name: "config1", Namespace: namespace, Generation: 123},
+					Spec: supervisorconfigv1alpha1.FederationDomainSpec{
+						Issuer:   "https://issuer1.com",
+						Branding: &supervisorconfigv1alpha1.FederationDomainBranding{ConfigMapName: "some-branding"},
+					},
+				},
+			},
+			inputKubeObjects: []runtime.Object{
+				&corev1.ConfigMap{
+					ObjectMeta: metav1.ObjectMeta{Name: "some-branding", Namespace: namespace},
+					Data:       map[string]string{"primaryColor": "blue"},
+				},
+			},
+			wantFDIssuers: []*federationdomainproviders.FederationDomainIssuer{},
+			wantStatusUpdates: []*supervisorconfigv1alpha1.FederationDomain{
+				expectedFederationDomainStatusUpdate(
+					&supervisorconfigv1alpha1.FederationDomain{
+						ObjectMeta: metav1.ObjectMeta{Name: "config1", Namespace: namespace, Generation: 123},
+					},
+					supervisorconfigv1alpha1.FederationDomainPhaseError,
+					conditionstestutil.Replace(
+						allHappyConditionsLegacyConfigurationSuccess("https://issuer1.com", oidcIdentityProvider.Name, frozenMetav1Now, 123),
+						[]metav1.Condition{
+							sadBrandingCondition("InvalidBranding",
+								`the ConfigMap "some-branding" specified by .spec.branding.configMapName is invalid: `+
+									`invalid branding ConfigMap "some-branding": key "primaryColor" must be a hex color such as #1a73e8`,
+								frozenMetav1Now, 123),
+							sadReadyCondition(frozenMetav1Now, 123),
+						}),
+				),
+			},
+		},
+		{
+			name: "the federation domain is branded by a ConfigMap with a template override which is not compatible with the CSP",
+			inputObjects: []runtime.Object{
+				oidcIdentityProvider,
+				&supervisorconfigv1alpha1.FederationDomain{
+					ObjectMeta: metav1.ObjectMeta{Name: "config1", Namespace: namespace, Generation: 123},
+					Spec: supervisorconfigv1alpha1.FederationDomainSpec{
+						Issuer:   "https://issuer1.com",
+						Branding: &supervisorconfigv1alpha1.FederationDomainBranding{ConfigMapName: "some-branding"},
+					},
+				},
+			},
+			inputKubeObjects: []runtime.Object{
+				&corev1.ConfigMap{
+					ObjectMeta: metav1.ObjectMeta{Name: "some-branding", Namespace: namespace},
+					Data: map[string]string{
+						"choose_idp.gohtml": `<html><body><script>alert(1)</script></body></html>`,
+					},
+				},
+			},
+			wantFDIssuers: []*federationdomainproviders.FederationDomainIssuer{},
+			wantStatusUpdates: []*supervisorconfigv1alpha1.FederationDomain{
+				expectedFederationDomainStatusUpdate(
+					&supervisorconfigv1alpha1.FederationDomain{
+						ObjectMeta: metav1.ObjectMeta{Name: "config1", Namespace: namespace, Generation: 123},
+					},
+					supervisorconfigv1alpha1.FederationDomainPhaseError,
+					conditionstestutil.Replace(
+						allHappyConditionsLegacyConfigurationSuccess("https://issuer1.com", oidcIdentityProvider.Name, frozenMetav1Now, 123),
+						[]metav1.Condition{
+							sadBrandingCondition("InvalidBranding",
+								`the ConfigMap "some-branding" specified by .spec.branding.configMapName is invalid: `+
+									`template "choose_idp.gohtml" is not compatible with the Content-Security-Policy of the page: `+
+									`<script> elements may only contain {{minifiedJS}}`,
+								frozenMetav1Now, 123),
+							sadReadyCondition(frozenMetav1Now, 123),
+						}),
+				),
+			},
+		},
+		{
+			name: "the federation domain has invalid authentication policies",
+			inputObjects: []runtime.Object{
+				oidcIdentityProvider,
+				ldapIdentityProvider,
+				&supervisorconfigv1alpha1.FederationDomain{
+					ObjectMeta: metav1.ObjectMeta{Name: "config1", Namespace: namespace, Generation: 123},
+					Spec: supervisorconfigv1alpha1.FederationDomainSpec{
+						Issuer: "https://issuer1.com",
+						IdentityProviders: []supervisorconfigv1alpha1.FederationDomainIdentityProvider{
+							{
+								DisplayName: "name1",
+								ObjectRef: corev1.TypedLocalObjectReference{
+									APIGroup: ptr.To(apiGroupSupervisor),
+									Kind:     "LDAPIdentityProvider",
+									Name:     ldapIdentityProvider.Name,
+								},
+								AuthenticationPolicy: &supervisorconfigv1alpha1.FederationDomainAuthenticationPolicy{
+									ACRValues: []string{"phr"},
+								},
+							},
+							{
+								DisplayName: "name2",
+								ObjectRef: corev1.TypedLocalObjectReference{
+									APIGroup: ptr.To(apiGroupSupervisor),
+									Kind:     "OIDCIdentityProvider",
+									Name:     oidcIdentityProvider.Name,
+								},
+								AuthenticationPolicy: &supervisorconfigv1alpha1.FederationDomainAuthenticationPolicy{
+									Expression: `"mfa"`,
+								},
+							},
+							{
+								DisplayName: "name3",
+								ObjectRef: corev1.TypedLocalObjectReference{
+									APIGroup: ptr.To(apiGroupSupervisor),
+									Kind:     "OIDCIdentityProvider",
+									Name:     oidcIdentityProvider.Name,
+								},
+								AuthenticationPolicy: &supervisorconfigv1alpha1.FederationDomainAuthenticationPolicy{
+									Message: "policy without any requirements",
+								},
+							},
+						},
+					},
+				},
+			},
+			wantFDIssuers: []*federationdomainproviders.FederationDomainIssuer{},
+			wantStatusUpdates: []*supervisorconfigv1alpha1.FederationDomain{
+				expectedFederationDomainStatusUpdate(
+					&supervisorconfigv1alpha1.FederationDomain{
+						ObjectMeta: metav1.ObjectMeta{Name: "config1", Namespace: namespace, Generation: 123},
+					},
+					supervisorconfigv1alpha1.FederationDomainPhaseError,
+					conditionstestutil.Replace(
+						allHappyConditionsSuccess("https://issuer1.com", frozenMetav1Now, 123),
+						[]metav1.Condition{
+							sadAuthenticationPoliciesCondition(here.Doc(
+								`.spec.identityProviders[0].authenticationPolicy is only supported when .spec.identityProviders[0].objectRef.kind is "OIDCIdentityProvider"
+
+								 .spec.identityProviders[1].authenticationPolicy was invalid:
+								 CEL expression should return type "bool" but returns type "string"
+
+								 .spec.identityProviders[2].authenticationPolicy was invalid:
+								 at least one of acrValues or expression must be specified`,
+							), frozenMetav1Now, 123),
+							sadReadyCondition(frozenMetav1Now, 123),
+						}),
+				),
+			},
+		},
 		{
 			name: "the federation domain specifies illegal const type, which shouldn't really happen since the CRD validates it",
 			inputObjects: []runtime.Object{
@@ -2097,6 +2744,7 @@ func TestTestFederationDomainWatcherControllerSync(t *testing.T) {
 				tt.configClient(pinnipedAPIClient)
 			}
 			pinnipedInformers := supervisorinformers.NewSharedInformerFactory(pinnipedInformerClient, 0)
+			kubeInformers := kubeinformers.NewSharedInformerFactory(kubefake.NewClientset(tt.inputKubeObjects...), 0)
 
 			controller := NewFederationDomainWatcherController(
 				federationDomainsSetter,
@@ -2108,6 +2756,10 @@ func TestTestFederationDomainWatcherControllerSync(t *testing.T) {
 				pinnipedInformers.IDP().V1alpha1().LDAPIdentityProviders(),
 				pinnipedInformers.IDP().V1alpha1().ActiveDirectoryIdentityProviders(),
 				pinnipedInformers.IDP().V1alpha1().GitHubIdentityProviders(),
+				pinnipedInformers.IDP().V1alpha1().SAMLIdentityProviders(),
+				pinnipedInformers.IDP().V1alpha1().GitLabIdentityProviders(),
+				pinnipedInformers.IDP().V1alpha1().OAuth2IdentityProviders(),
+				kubeInformers.Core().V1().ConfigMaps(),
 				controllerlib.WithInformer,
 			)
 
@@ -2115,6 +2767,7 @@ func TestTestFederationDomainWatcherControllerSync(t *testing.T) {
 			defer cancel()
 
 			pinnipedInformers.Start(ctx.Done())
+			kubeInformers.Start(ctx.Done())
 			controllerlib.TestRunSynchronously(t, controller)
 
 			syncCtx := controllerlib.Context{Context: ctx, Key: controllerlib.Key{Namespace: namespace, Name: "config-name"}}
@@ -2160,23 +2813,35 @@ type comparableFederationDomainIssuer struct {
 	issuer                  string
 	identityProviders       []*comparableFederationDomainIdentityProvider
 	defaultIdentityProvider *comparableFederationDomainIdentityProvider
+	sessionPolicy           timeouts.SessionPolicy
+	clientRegistration      *federationdomainproviders.ClientRegistrationPolicy
+	pageCSPs                []string
+	defaultLanguage         string
 }
 
 type comparableFederationDomainIdentityProvider struct {
-	DisplayName      string
-	UID              types.UID
-	TransformsSource []any
+	DisplayName                   string
+	UID                           types.UID
+	TransformsSource              []any
+	HasAuthenticationPolicy       bool
+	AuthenticationPolicyACRValues []string
 }
 
 func makeFederationDomainIdentityProviderComparable(fdi *federationdomainproviders.FederationDomainIdentityProvider) *comparableFederationDomainIdentityProvider {
 	if fdi == nil {
 		return nil
 	}
-	return &comparableFederationDomainIdentityProvider{
+	result := &comparableFederationDomainIdentityProvider{
 		DisplayName:      fdi.DisplayName,
 		UID:              fdi.UID,
 		TransformsSource: fdi.Transforms.Source(),
 	}
+	if fdi.AuthenticationPolicy != nil {
+		// A compiled CEL program cannot be compared for equality, so compare the parts which can be compared.
+		result.HasAuthenticationPolicy = true
+		result.AuthenticationPolicyACRValues = fdi.AuthenticationPolicy.ACRValues()
+	}
+	return result
 }
 
 func convertToComparableType(fdis []*federationdomainproviders.FederationDomainIssuer) []*comparableFederationDomainIssuer {
@@ -2191,6 +2856,14 @@ func convertToComparableType(fdis []*federationdomainproviders.FederationDomainI
 			issuer:                  fdi.Issuer(),
 			identityProviders:       comparableFDIs,
 			defaultIdentityProvider: makeFederationDomainIdentityProviderComparable(fdi.DefaultIdentityProvider()),
+			sessionPolicy:           fdi.SessionPolicy(),
+			clientRegistration:      fdi.ClientRegistrationPolicy(),
+			pageCSPs: []string{
+				fdi.BrandedPages().Login.ContentSecurityPolicy(),
+				fdi.BrandedPages().ChooseIDP.ContentSecurityPolicy(),
+				fdi.BrandedPages().FormPost.ContentSecurityPolicy(),
+			},
+			defaultLanguage: fdi.DefaultLanguage(),
 		}
 		result = append(result, converted)
 	}
@@ -2270,6 +2943,21 @@ func newTransformationPipeline(
 	return pipeline
 }
 
+func newAuthenticationPolicy(t *testing.T, acrValues []string, expression string) *celtransformer.CompiledUpstreamAuthenticationPolicy {
+	t.Helper()
+
+	transformer, err := celtransformer.NewCELTransformer(celTransformerMaxExpressionRuntime)
+	require.NoError(t, err)
+
+	compiled, err := transformer.CompileUpstreamAuthenticationPolicy(&celtransformer.UpstreamAuthenticationPolicy{
+		ACRValues:  acrValues,
+		Expression: expression,
+	})
+	require.NoError(t, err)
+
+	return compiled
+}
+
 func TestTransformationPipelinesCanBeTestedForEqualityUsingSourceToMakeTestingEasier(t *testing.T) {
 	compiler, err := celtransformer.NewCELTransformer(5 * time.Second)
 	require.NoError(t, err)
@@ -0,0 +1,617 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package gitlabupstreamwatcher implements a controller which watches GitLabIdentityProviders.
+package gitlabupstreamwatcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"golang.org/x/oauth2"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/cache"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	corev1informers "k8s.io/client-go/informers/core/v1"
+	"k8s.io/utils/clock"
+
+	idpv1alpha1 "go.pinniped.dev/generated/latest/apis/supervisor/idp/v1alpha1"
+	supervisorclientset "go.pinniped.dev/generated/latest/client/supervisor/clientset/versioned"
+	idpinformers "go.pinniped.dev/generated/latest/client/supervisor/informers/externalversions/idp/v1alpha1"
+	pinnipedcontroller "go.pinniped.dev/internal/controller"
+	"go.pinniped.dev/internal/controller/conditionsutil"
+	"go.pinniped.dev/internal/controller/supervisorconfig/upstreamwatchers"
+	"go.pinniped.dev/internal/controller/tlsconfigutil"
+	"go.pinniped.dev/internal/controllerlib"
+	"go.pinniped.dev/internal/endpointaddr"
+	"go.pinniped.dev/internal/federationdomain/upstreamprovider"
+	"go.pinniped.dev/internal/net/phttp"
+	"go.pinniped.dev/internal/plog"
+	"go.pinniped.dev/internal/setutil"
+	"go.pinniped.dev/internal/upstreamgitlab"
+)
+
+const (
+	controllerName = "gitlab-upstream-observer"
+
+	// Constants related to the client credentials Secret.
+	gitLabClientSecretType               corev1.SecretType = "secrets.pinniped.dev/gitlab-client"
+	clientIDDataKey, clientSecretDataKey string            = "clientID", "clientSecret"
+
+	countExpectedConditions = 6
+
+	HostValid                    string = "HostValid"
+	TLSConfigurationValid        string = "TLSConfigurationValid"
+	GroupsPolicyValid            string = "GroupsPolicyValid"
+	ClientCredentialsSecretValid string = "ClientCredentialsSecretValid" //nolint:gosec // this is not a credential
+	GitLabConnectionValid        string = "GitLabConnectionValid"
+	ClaimsValid                  string = "ClaimsValid"
+
+	reasonInvalid             = "Invalid"
+	reasonInvalidHost         = "InvalidHost"
+	reasonUnableToMakeRequest = "UnableToMakeRequest"
+
+	headerNameUserAgent            = "User-Agent"
+	headerValueUserAgentSupervisor = "pinniped-supervisor/v1"
+	headerNameAccept               = "Accept"
+	headerValueAcceptJSON          = "application/json"
+)
+
+// UpstreamGitLabIdentityProviderICache is a thread safe cache that holds a list of validated upstream GitLab IDP configurations.
+type UpstreamGitLabIdentityProviderICache interface {
+	SetGitLabIdentityProviders([]upstreamprovider.UpstreamGitLabIdentityProviderI)
+}
+
+type GitLabValidatedAPICacheI interface {
+	MarkAsValidated(address string, caBundleHash tlsconfigutil.CABundleHash)
+	IsValid(address string, caBundleHash tlsconfigutil.CABundleHash) bool
+}
+
+type GitLabValidatedAPICache struct {
+	cache *cache.Expiring
+}
+
+type GitLabValidatedAPICacheKey struct {
+	address      string
+	caBundleHash tlsconfigutil.CABundleHash
+}
+
+func (g *GitLabValidatedAPICache) MarkAsValidated(address string, caBundleHash tlsconfigutil.CABundleHash) {
+	key := GitLabValidatedAPICacheKey{
+		address:      address,
+		caBundleHash: caBundleHash,
+	}
+	// Existence in the cache means it has been validated.
+	// The TTL in the cache is not important, it's just a "really long time".
+	g.cache.Set(key, nil, 365*24*time.Hour)
+}
+
+func (g *GitLabValidatedAPICache) IsValid(address string, caBundleHash tlsconfigutil.CABundleHash) bool {
+	key := GitLabValidatedAPICacheKey{
+		address:      address,
+		caBundleHash: caBundleHash,
+	}
+	_, ok := g.cache.Get(key)
+	return ok
+}
+
+func NewGitLabValidatedAPICache(cache *cache.Expiring) GitLabValidatedAPICacheI {
+	return &GitLabValidatedAPICache{cache: cache}
+}
+
+type ProbeURLFunc func(ctx context.Context, client *http.Client, url string) error
+
+type gitLabWatcherController struct {
+	namespace                      string
+	cache                          UpstreamGitLabIdentityProviderICache
+	log                            plog.Logger
+	client                         supervisorclientset.Interface
+	gitLabIdentityProviderInformer idpinformers.GitLabIdentityProviderInformer
+	secretInformer                 corev1informers.SecretInformer
+	configMapInformer              corev1informers.ConfigMapInformer
+	clock                          clock.Clock
+	probeURLFunc                   ProbeURLFunc
+	validatedCache                 GitLabValidatedAPICacheI
+}
+
+// New instantiates a new controllerlib.Controller which will populate the provided UpstreamGitLabIdentityProviderICache.
+func New(
+	namespace string,
+	idpCache UpstreamGitLabIdentityProviderICache,
+	client supervisorclientset.Interface,
+	gitLabIdentityProviderInformer idpinformers.GitLabIdentityProviderInformer,
+	secretInformer corev1informers.SecretInformer,
+	configMapInformer corev1informers.ConfigMapInformer,
+	log plog.Logger,
+	withInformer pinnipedcontroller.WithInformerOptionFunc,
+	clock clock.Clock,
+	probeURLFunc ProbeURLFunc,
+	validatedCache *cache.Expiring,
+) controllerlib.Controller {
+	c := gitLabWatcherController{
+		namespace:                      namespace,
+		cache:                          idpCache,
+		client:                         client,
+		log:                            log.WithName(controllerName),
+		gitLabIdentityProviderInformer: gitLabIdentityProviderInformer,
+		secretInformer:                 secretInformer,
+		configMapInformer:              configMapInformer,
+		clock:                          clock,
+		probeURLFunc:                   probeURLFunc,
+		validatedCache:                 NewGitLabValidatedAPICache(validatedCache),
+	}
+
+	return controllerlib.New(
+		controllerlib.Config{Name: controllerName, Syncer: &c},
+		withInformer(
+			gitLabIdentityProviderInformer,
+			pinnipedcontroller.MatchAnythingFilter(pinnipedcontroller.SingletonQueue()),
+			controllerlib.InformerOption{},
+		),
+		withInformer(
+			secretInformer,
+			pinnipedcontroller.MatchAnySecretOfTypesFilter(
+				[]corev1.SecretType{
+					gitLabClientSecretType,
+					corev1.SecretTypeOpaque,
+					corev1.SecretTypeTLS,
+				},
+				pinnipedcontroller.SingletonQueue(),
+			),
+			controllerlib.InformerOption{},
+		),
+		withInformer(
+			configMapInformer,
+			pinnipedcontroller.MatchAnythingFilter(pinnipedcontroller.SingletonQueue()),
+			controllerlib.InformerOption{},
+		),
+	)
+}
+
+// Sync implements controllerlib.Syncer.
+func (c *gitLabWatcherController) Sync(ctx controllerlib.Context) error {
+	actualUpstreams, err := c.gitLabIdentityProviderInformer.Lister().List(labels.Everything())
+	if err != nil { // untested
+		return fmt.Errorf("failed to list GitLabIdentityProviders: %w", err)
+	}
+
+	// Sort them by name just so that the logs output is consistent
+	slices.SortStableFunc(actualUpstreams, func(a, b *idpv1alpha1.GitLabIdentityProvider) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	var applicationErrors []error
+	validatedUpstreams := make([]upstreamprovider.UpstreamGitLabIdentityProviderI, 0, len(actualUpstreams))
+	for _, upstream := range actualUpstreams {
+		validatedUpstream, applicationErr := c.validateUpstreamAndUpdateConditions(ctx.Context, upstream)
+		if applicationErr != nil {
+			applicationErrors = append(applicationErrors, applicationErr)
+		} else if validatedUpstream != nil {
+			validatedUpstreams = append(validatedUpstreams, validatedUpstream)
+		}
+		// Else:
+		// If both validatedUpstream and applicationErr are nil, this must be because the upstream had configuration errors.
+		// This controller should take no action until the user has reconfigured the upstream.
+	}
+	c.cache.SetGitLabIdentityProviders(validatedUpstreams)
+
+	// If we have recoverable application errors, let's do a requeue and capture all the applicationErrors too
+	if len(applicationErrors) > 0 {
+		applicationErrors = append([]error{controllerlib.ErrSyntheticRequeue}, applicationErrors...)
+	}
+
+	return utilerrors.NewAggregate(applicationErrors)
+}
+
+func (c *gitLabWatcherController) validateClientSecret(secretName string) (*metav1.Condition, string, string, error) {
+	secret, unableToRetrieveSecretErr := c.secretInformer.Lister().Secrets(c.namespace).Get(secretName)
+
+	// This error requires user interaction, so ignore it.
+	if apierrors.IsNotFound(unableToRetrieveSecretErr) {
+		unableToRetrieveSecretErr = nil
+	}
+
+	buildFalseCondition := func(prefix string) (*metav1.Condition, string, string, error) {
+		return &metav1.Condition{
+			Type:   ClientCredentialsSecretValid,
+			Status: metav1.ConditionFalse,
+			Reason: upstreamwatchers.ReasonNotFound,
+			Message: fmt.Sprintf("%s: secret from spec.client.SecretName (%q) must be found in namespace %q with type %q and keys %q and %q",
+				prefix,
+				secretName,
+				c.namespace,
+				gitLabClientSecretType,
+				clientIDDataKey,
+				clientSecretDataKey),
+		}, "", "", unableToRetrieveSecretErr
+	}
+
+	if unableToRetrieveSecretErr != nil || secret == nil {
+		return buildFalseCondition(fmt.Sprintf("secret %q not found", secretName))
+	}
+
+	if secret.Type != gitLabClientSecretType {
+		return buildFalseCondition(fmt.Sprintf("wrong secret type %q", secret.Type))
+	}
+
+	clientID := string(secret.Data[clientIDDataKey])
+	if len(clientID) < 1 {
+		return buildFalseCondition(fmt.Sprintf("missing key %q", clientIDDataKey))
+	}
+
+	clientSecret := string(secret.Data[clientSecretDataKey])
+	if len(clientSecret) < 1 {
+		return buildFalseCondition(fmt.Sprintf("missing key %q", clientSecretDataKey))
+	}
+
+	if len(secret.Data) != 2 {
+		return buildFalseCondition("extra keys found")
+	}
+
+	return &metav1.Condition{
+		Type:    ClientCredentialsSecretValid,
+		Status:  metav1.ConditionTrue,
+		Reason:  conditionsutil.ReasonSuccess,
+		Message: fmt.Sprintf("clientID and clientSecret have been read from spec.client.SecretName (%q)", secretName),
+	}, clientID, clientSecret, nil
+}
+
+func validateGroupsPolicy(groupsSpec *idpv1alpha1.GitLabGroupsSpec) *metav1.Condition {
+	var policy idpv1alpha1.GitLabAllowedAuthGroupsPolicy
+	if groupsSpec.Policy != nil {
+		policy = *groupsSpec.Policy
+	}
+
+	// Should not happen due to CRD defaulting, enum validation, and CEL validation (for recent versions of K8s only!)
+	// That is why the message here is very minimal
+	if (policy == idpv1alpha1.GitLabAllowedAuthGroupsPolicyAllGitLabUsers && len(groupsSpec.Allowed) == 0) ||
+		(policy == idpv1alpha1.GitLabAllowedAuthGroupsPolicyOnlyUsersFromAllowedGroups && len(groupsSpec.Allowed) > 0) {
+		return &metav1.Condition{
+			Type:    GroupsPolicyValid,
+			Status:  metav1.ConditionTrue,
+			Reason:  conditionsutil.ReasonSuccess,
+			Message: fmt.Sprintf("spec.allowAuthentication.groups.policy (%q) is valid", policy),
+		}
+	}
+
+	if len(groupsSpec.Allowed) > 0 {
+		return &metav1.Condition{
+			Type:    GroupsPolicyValid,
+			Status:  metav1.ConditionFalse,
+			Reason:  reasonInvalid,
+			Message: "spec.allowAuthentication.groups.policy must be 'OnlyUsersFromAllowedGroups' when spec.allowAuthentication.groups.allowed has groups listed",
+		}
+	}
+
+	return &metav1.Condition{
+		Type:    GroupsPolicyValid,
+		Status:  metav1.ConditionFalse,
+		Reason:  reasonInvalid,
+		Message: "spec.allowAuthentication.groups.policy must be 'AllGitLabUsers' when spec.allowAuthentication.groups.allowed is empty",
+	}
+}
+
+func (c *gitLabWatcherController) validateUpstreamAndUpdateConditions(ctx context.Context, upstream *idpv1alpha1.GitLabIdentityProvider) (
+	*upstreamgitlab.Provider, // If validated, returns the config
+	error, // This error will only refer to programmatic errors such as inability to perform a connection probe or dereference a pointer, not configuration errors
+) {
+	conditions := make([]*metav1.Condition, 0, countExpectedConditions)
+	applicationErrors := make([]error, 0)
+
+	clientSecretCondition, clientID, clientSecret, clientSecretErr := c.validateClientSecret(upstream.Spec.Client.SecretName)
+	conditions = append(conditions, clientSecretCondition)
+	if clientSecretErr != nil { // untested
+		applicationErrors = append(applicationErrors, clientSecretErr)
+	}
+
+	// CRD defaulting and validation should eliminate the possibility of an error here.
+	userAndGroupCondition, groupNameAttribute, usernameAttribute := validateUserAndGroupAttributes(upstream)
+	conditions = append(conditions, userAndGroupCondition)
+
+	groupsPolicyCondition := validateGroupsPolicy(&upstream.Spec.AllowAuthentication.Groups)
+	conditions = append(conditions, groupsPolicyCondition)
+
+	hostCondition, hostPort := validateHost(upstream.Spec.GitLabAPI.Host)
+	conditions = append(conditions, hostCondition)
+
+	tlsConfigCondition, caBundle := tlsconfigutil.ValidateTLSConfig(
+		tlsconfigutil.TLSSpecForSupervisor(upstream.Spec.GitLabAPI.TLS),
+		"spec.gitlabAPI.tls",
+		c.namespace,
+		c.secretInformer,
+		c.configMapInformer)
+	conditions = append(conditions, tlsConfigCondition)
+
+	gitlabConnectionCondition, httpClient, gitlabConnectionErr := c.validateGitLabConnection(
+		ctx,
+		hostPort,
+		upstream.Spec.GitLabAPI.Host,
+		caBundle,
+		hostCondition.Status == metav1.ConditionTrue,
+		tlsConfigCondition.Status == metav1.ConditionTrue,
+	)
+	if gitlabConnectionErr != nil {
+		applicationErrors = append(applicationErrors, gitlabConnectionErr)
+	}
+	conditions = append(conditions, gitlabConnectionCondition)
+
+	// The critical pattern to maintain is that every run of the sync loop will populate the exact number of the exact
+	// same set of conditions.  Conditions depending on other conditions should get Status: metav1.ConditionUnknown, or
+	// Status: metav1.ConditionFalse, never be omitted.
+	if len(conditions) != countExpectedConditions { // untested since all code paths return the same number of conditions
+		applicationErrors = append(applicationErrors, fmt.Errorf("expected %d conditions but found %d conditions", countExpectedConditions, len(conditions)))
+		return nil, utilerrors.NewAggregate(applicationErrors)
+	}
+	hadErrorCondition, updateStatusErr := c.updateStatus(ctx, upstream, conditions)
+	if updateStatusErr != nil {
+		applicationErrors = append(applicationErrors, updateStatusErr)
+	}
+	// Any error condition means we will not add the IDP to the cache, so just return nil here
+	if hadErrorCondition {
+		return nil, utilerrors.NewAggregate(applicationErrors)
+	}
+
+	gitlabBaseURL := baseURL(hostPort)
+
+	provider := upstreamgitlab.New(
+		upstreamgitlab.ProviderConfig{
+			Name:               upstream.Name,
+			ResourceUID:        upstream.UID,
+			APIBaseURL:         apiBaseURL(hostPort),
+			GroupNameAttribute: groupNameAttribute,
+			UsernameAttribute:  usernameAttribute,
+			OAuth2Config: &oauth2.Config{
+				ClientID:     clientID,
+				ClientSecret: clientSecret,
+				// See https://docs.gitlab.com/ee/api/oauth2.html
+				Endpoint: oauth2.Endpoint{
+					AuthURL:       fmt.Sprintf("%s/oauth/authorize", gitlabBaseURL),
+					DeviceAuthURL: "", // we do not use device code flow
+					TokenURL:      fmt.Sprintf("%s/oauth/token", gitlabBaseURL),
+					AuthStyle:     oauth2.AuthStyleInParams,
+				},
+				RedirectURL: "", // this will be different for each FederationDomain, so we do not set it here
+				// The read_api scope is needed to list the user's group memberships.
+				Scopes: []string{"read_api"},
+			},
+			AllowedGroups: setutil.NewCaseInsensitiveSet(upstream.Spec.AllowAuthentication.Groups.Allowed...),
+			HttpClient:    httpClient,
+		},
+	)
+	return provider, utilerrors.NewAggregate(applicationErrors)
+}
+
+// baseURL returns the base URL of the GitLab instance. Unlike GitHub, GitLab serves its API and its
+// OAuth endpoints from the same host.
+func baseURL(hostPort *endpointaddr.HostPort) string {
+	return fmt.Sprintf("https://%s", hostPortForHTTPS(hostPort))
+}
+
+func apiBaseURL(hostPort *endpointaddr.HostPort) string {
+	return fmt.Sprintf("%s/api/v4/", baseURL(hostPort))
+}
+
+func hostPortForHTTPS(hostPort *endpointaddr.HostPort) string {
+	if hostPort.Port == 443 {
+		// Remove the port specification from the host, because 443 is the default for HTTPS.
+		return hostPort.Host
+	}
+	return hostPort.Endpoint()
+}
+
+func validateHost(specifiedHost *string) (*metav1.Condition, *endpointaddr.HostPort) {
+	buildInvalidHost := func(host, reason string) *metav1.Condition {
+		return &metav1.Condition{
+			Type:    HostValid,
+			Status:  metav1.ConditionFalse,
+			Reason:  reasonInvalidHost,
+			Message: fmt.Sprintf("spec.gitlabAPI.host (%q) is not valid: %s", host, reason),
+		}
+	}
+
+	// Should not happen due to CRD defaulting
+	if specifiedHost == nil || len(*specifiedHost) < 1 {
+		return buildInvalidHost("", "must not be empty"), nil
+	}
+
+	hostPort, addressParseErr := endpointaddr.Parse(*specifiedHost, 443)
+	if addressParseErr != nil {
+		// addressParseErr is not recoverable. It requires user interaction, so do not return the error.
+		return buildInvalidHost(*specifiedHost, addressParseErr.Error()), nil
+	}
+
+	// Use lowercased host internally for readability.
+	hostPort.Host = strings.ToLower(hostPort.Host)
+
+	return &metav1.Condition{
+		Type:    HostValid,
+		Status:  metav1.ConditionTrue,
+		Reason:  conditionsutil.ReasonSuccess,
+		Message: fmt.Sprintf("spec.gitlabAPI.host (%q) is valid", *specifiedHost),
+	}, &hostPort
+}
+
+func (c *gitLabWatcherController) validateGitLabConnection(
+	ctx context.Context,
+	hostPort *endpointaddr.HostPort,
+	specifiedHost *string,
+	caBundle *tlsconfigutil.CABundle,
+	hostConditionOk, tlsConfigConditionOk bool,
+) (*metav1.Condition, *http.Client, error) {
+	if !hostConditionOk || !tlsConfigConditionOk {
+		return &metav1.Condition{
+			Type:    GitLabConnectionValid,
+			Status:  metav1.ConditionUnknown,
+			Reason:  conditionsutil.ReasonUnableToValidate,
+			Message: conditionsutil.MessageUnableToValidate,
+		}, nil, nil
+	}
+
+	address := hostPort.Endpoint()
+
+	// Note that this client already has some reasonable timeouts configured on it.
+	httpClient := phttp.Default(caBundle.CertPool())
+
+	if !c.validatedCache.IsValid(address, caBundle.Hash()) {
+		// In order to provide feedback on the configured host and CA bundle, probe the configured server by using
+		// a real HTTPS request, so we can also respect the pod's proxy settings during the request, if they are present.
+		// To make a real request, we need to choose a real endpoint. The GitLab version API requires authentication,
+		// so this request will get an error response, but it does not need to succeed. It only needs to show that
+		// the host is reachable and that its TLS certificate can be verified.
+		// See https://docs.gitlab.com/ee/api/version.html.
+		versionAPIURL := fmt.Sprintf("%sversion", apiBaseURL(hostPort))
+
+		err := c.probeURLFunc(ctx, httpClient, versionAPIURL)
+		if err != nil {
+			return &metav1.Condition{
+				Type:   GitLabConnectionValid,
+				Status: metav1.ConditionFalse,
+				Reason: reasonUnableToMakeRequest,
+				Message: fmt.Sprintf("cannot make connection probe request for spec.gitlabAPI.host (%q): %s",
+					*specifiedHost, buildProbeErrorMessage(err)),
+			}, nil, err
+		}
+	}
+
+	c.validatedCache.MarkAsValidated(address, caBundle.Hash())
+
+	return &metav1.Condition{
+		Type:   GitLabConnectionValid,
+		Status: metav1.ConditionTrue,
+		Reason: conditionsutil.ReasonSuccess,
+		Message: fmt.Sprintf("probed connection to %q for spec.gitlabAPI.host (%q): host is reachable and TLS verification succeeds",
+			address, *specifiedHost),
+	}, httpClient, nil
+}
+
+// ProbeURL is the production code for how to probe a GitLab URL to test our connection to the GitLab API.
+// It can be replaced via constructor injection for testing. Implements ProbeURLFunc.
+func ProbeURL(ctx context.Context, client *http.Client, url string) error {
+	probeRequestCtx, probeRequestCancel := context.WithTimeout(ctx, 30*time.Second)
+	defer probeRequestCancel()
+
+	probeRequest, err := http.NewRequestWithContext(probeRequestCtx, http.MethodGet, url, nil)
+	if err != nil {
+		// Shouldn't really get here as long as the URL is valid.
+		return err
+	}
+
+	probeRequest.Header.Set(headerNameUserAgent, headerValueUserAgentSupervisor)
+	probeRequest.Header.Set(headerNameAccept, headerValueAcceptJSON)
+
+	probeResponse, err := client.Do(probeRequest)
+	if err != nil {
+		return err
+	}
+
+	// Don't care what the response was, even if it was an error status, as long as we were able to connect
+	// successfully to the specified host using the specified CA bundle and the pod's proxy env var settings.
+	_ = probeResponse.Body.Close()
+
+	return nil
+}
+
+// buildProbeErrorMessage standardizes DNS error messages that appear differently on different platforms,
+// so that tests and log grepping is uniform.
+func buildProbeErrorMessage(err error) string {
+	reason := err.Error()
+
+	var opError *net.OpError
+	var dnsError *net.DNSError
+	if errors.As(err, &opError) && errors.As(err, &dnsError) {
+		dnsError.Server = ""
+		opError.Err = dnsError
+		return opError.Error()
+	}
+
+	return reason
+}
+
+func validateUserAndGroupAttributes(upstream *idpv1alpha1.GitLabIdentityProvider) (*metav1.Condition, idpv1alpha1.GitLabGroupNameAttribute, idpv1alpha1.GitLabUsernameAttribute) {
+	buildInvalidCondition := func(message string) *metav1.Condition {
+		return &metav1.Condition{
+			Type:    ClaimsValid,
+			Status:  metav1.ConditionFalse,
+			Reason:  reasonInvalid,
+			Message: message,
+		}
+	}
+
+	if upstream.Spec.Claims.Username == nil {
+		return buildInvalidCondition("spec.claims.username is required"), "", ""
+	}
+	usernameAttribute := *upstream.Spec.Claims.Username
+
+	if upstream.Spec.Claims.Groups == nil {
+		return buildInvalidCondition("spec.claims.groups is required"), "", ""
+	}
+	groupNameAttribute := *upstream.Spec.Claims.Groups
+
+	switch usernameAttribute {
+	case idpv1alpha1.GitLabUsernameUsernameAndID:
+	case idpv1alpha1.GitLabUsernameUsername:
+	case idpv1alpha1.GitLabUsernameID:
+	default:
+		// Should not happen due to CRD enum validation
+		return buildInvalidCondition(fmt.Sprintf("spec.claims.username (%q) is not valid", usernameAttribute)), "", ""
+	}
+
+	switch groupNameAttribute {
+	case idpv1alpha1.GitLabUseGroupPathForGroupName:
+	case idpv1alpha1.GitLabUseGroupFullPathForGroupName:
+	case idpv1alpha1.GitLabUseGroupIDForGroupName:
+	default:
+		// Should not happen due to CRD enum validation
+		return buildInvalidCondition(fmt.Sprintf("spec.claims.groups (%q) is not valid", groupNameAttribute)), "", ""
+	}
+
+	return &metav1.Condition{
+		Type:    ClaimsValid,
+		Status:  metav1.ConditionTrue,
+		Reason:  conditionsutil.ReasonSuccess,
+		Message: "spec.claims are valid",
+	}, groupNameAttribute, usernameAttribute
+}
+
+func (c *gitLabWatcherController) updateStatus(
+	ctx context.Context,
+	upstream *idpv1alpha1.GitLabIdentityProvider,
+	conditions []*metav1.Condition) (bool, error) {
+	log := c.log.WithValues("namespace", upstream.Namespace, "name", upstream.Name)
+	updated := upstream.DeepCopy()
+
+	hadErrorCondition := conditionsutil.MergeConditions(
+		conditions,
+		&updated.Status.Conditions,
+		upstream.Generation,
+		metav1.NewTime(c.clock.Now()),
+		log,
+	)
+
+	updated.Status.Phase = idpv1alpha1.GitLabPhaseReady
+	if hadErrorCondition {
+		updated.Status.Phase = idpv1alpha1.GitLabPhaseError
+	}
+
+	if equality.Semantic.DeepEqual(upstream, updated) {
+		return hadErrorCondition, nil
+	}
+
+	log.Info("updating GitLabIdentityProvider status", "phase", updated.Status.Phase)
+
+	_, updateStatusError := c.client.
+		IDPV1alpha1().
+		GitLabIdentityProviders(upstream.Namespace).
+		UpdateStatus(ctx, updated, metav1.UpdateOptions{})
+	return hadErrorCondition, updateStatusError
+}
@@ -0,0 +1,1096 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package gitlabupstreamwatcher
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/cache"
+	utilnet "k8s.io/apimachinery/pkg/util/net"
+	k8sinformers "k8s.io/client-go/informers"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	coretesting "k8s.io/client-go/testing"
+	"k8s.io/utils/clock"
+	clocktesting "k8s.io/utils/clock/testing"
+	"k8s.io/utils/ptr"
+
+	idpv1alpha1 "go.pinniped.dev/generated/latest/apis/supervisor/idp/v1alpha1"
+	supervisorfake "go.pinniped.dev/generated/latest/client/supervisor/clientset/versioned/fake"
+	supervisorinformers "go.pinniped.dev/generated/latest/client/supervisor/informers/externalversions"
+	"go.pinniped.dev/internal/certauthority"
+	"go.pinniped.dev/internal/controller/conditionsutil"
+	"go.pinniped.dev/internal/controller/supervisorconfig/upstreamwatchers"
+	"go.pinniped.dev/internal/controller/tlsconfigutil"
+	"go.pinniped.dev/internal/controllerlib"
+	"go.pinniped.dev/internal/federationdomain/dynamicupstreamprovider"
+	"go.pinniped.dev/internal/federationdomain/upstreamprovider"
+	"go.pinniped.dev/internal/net/phttp"
+	"go.pinniped.dev/internal/plog"
+	"go.pinniped.dev/internal/setutil"
+	"go.pinniped.dev/internal/testutil"
+	"go.pinniped.dev/internal/testutil/tlsserver"
+	"go.pinniped.dev/internal/upstreamgitlab"
+)
+
+var (
+	gitlabIDPGVR = schema.GroupVersionResource{
+		Group:    idpv1alpha1.SchemeGroupVersion.Group,
+		Version:  idpv1alpha1.SchemeGroupVersion.Version,
+		Resource: "gitlabidentityproviders",
+	}
+
+	gitlabIDPKind = idpv1alpha1.SchemeGroupVersion.WithKind("GitLabIdentityProvider")
+)
+
+func fakeGitLabProbeFuncDisallowsAllProbes(t *testing.T) ProbeURLFunc {
+	return func(ctx context.Context, client *http.Client, url string) error {
+		t.Helper()
+		t.Errorf("this test should not perform any connection probe")
+		t.FailNow()
+		return nil
+	}
+}
+
+// TestProbeURL tests the production version of the ProbeURLFunc type.
+func TestProbeURL(t *testing.T) {
+	serverEndpointReached := false
+	testServer, testServerCA := tlsserver.TestServerIPv4(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, []string{"pinniped-supervisor/v1"}, r.Header.Values("User-Agent"))
+		require.Equal(t, []string{"application/json"}, r.Header.Values("Accept"))
+		serverEndpointReached = true
+		// An unauthenticated request to the GitLab API gets a 401, which is fine for a probe.
+		w.WriteHeader(http.StatusUnauthorized)
+	}), nil)
+
+	testServerCertPool := x509.NewCertPool()
+	testServerCertPool.AppendCertsFromPEM(testServerCA)
+	httpClient := phttp.Default(testServerCertPool)
+
+	// Happy path.
+	err := ProbeURL(context.Background(), httpClient, testServer.URL)
+	require.NoError(t, err)
+	require.True(t, serverEndpointReached)
+
+	// Invalid URL.
+	err = ProbeURL(context.Background(), httpClient, "https://invalid hostname")
+	require.EqualError(t, err, `parse "https://invalid hostname": invalid character " " in host name`)
+
+	// Did not trust server's CA, as an example of a failed connection.
+	err = ProbeURL(context.Background(), phttp.Default(nil), testServer.URL)
+	require.EqualError(t, err, fmt.Sprintf("Get %q: tls: failed to verify certificate: x509: certificate signed by unknown authority", testServer.URL))
+}
+
+func TestController(t *testing.T) {
+	require.Equal(t, 6, countExpectedConditions)
+
+	goodServer, goodServerCA := tlsserver.TestServerIPv4(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/api/v4/version", r.URL.Path)
+		w.WriteHeader(http.StatusUnauthorized)
+	}), tlsserver.RecordTLSHello)
+	goodServerDomain, _ := strings.CutPrefix(goodServer.URL, "https://")
+	goodServerCAB64 := base64.StdEncoding.EncodeToString(goodServerCA)
+	goodServerCertPool := x509.NewCertPool()
+	goodServerCertPool.AppendCertsFromPEM(goodServerCA)
+
+	caForUnknownServer, err := certauthority.New("Some Unknown CA", time.Hour)
+	require.NoError(t, err)
+	unknownServerPEM, err := caForUnknownServer.IssueServerCertPEM(
+		[]string{"some-dns-name", "some-other-dns-name"},
+		[]net.IP{net.ParseIP("10.2.3.4")},
+		time.Hour,
+	)
+	require.NoError(t, err)
+
+	wantObservedGeneration := int64(1234)
+	namespace := "some-namespace"
+
+	wantFrozenTime := time.Date(1122, time.September, 33, 4, 55, 56, 778899, time.Local)
+	frozenClockForLastTransitionTime := clocktesting.NewFakeClock(wantFrozenTime)
+	wantLastTransitionTime := metav1.Time{Time: wantFrozenTime}
+
+	goodClientCredentialsSecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "some-secret-name",
+			Namespace: namespace,
+		},
+		Type: "secrets.pinniped.dev/gitlab-client",
+		Data: map[string][]byte{
+			"clientID":     []byte("some-client-id"),
+			"clientSecret": []byte("some-client-secret"),
+		},
+	}
+
+	validMinimalIDP := &idpv1alpha1.GitLabIdentityProvider{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:       "minimal-idp-name",
+			Namespace:  namespace,
+			UID:        types.UID("minimal-uid"),
+			Generation: wantObservedGeneration,
+		},
+		Spec: idpv1alpha1.GitLabIdentityProviderSpec{
+			GitLabAPI: idpv1alpha1.GitLabAPIConfig{
+				Host: ptr.To(goodServerDomain),
+				TLS: &idpv1alpha1.TLSSpec{
+					CertificateAuthorityData: goodServerCAB64,
+				},
+			},
+			Client: idpv1alpha1.GitLabClientSpec{
+				SecretName: goodClientCredentialsSecret.Name,
+			},
+			// These claims are optional when using the actual Kubernetes CRD.
+			// However, they are required here because CRD defaulting/validation does not occur during testing.
+			Claims: idpv1alpha1.GitLabClaims{
+				Username: ptr.To(idpv1alpha1.GitLabUsernameUsernameAndID),
+				Groups:   ptr.To(idpv1alpha1.GitLabUseGroupFullPathForGroupName),
+			},
+			AllowAuthentication: idpv1alpha1.GitLabAllowAuthenticationSpec{
+				Groups: idpv1alpha1.GitLabGroupsSpec{
+					Policy: ptr.To(idpv1alpha1.GitLabAllowedAuthGroupsPolicyAllGitLabUsers),
+				},
+			},
+		},
+	}
+
+	validFilledOutIDP := &idpv1alpha1.GitLabIdentityProvider{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:       "some-idp-name",
+			Namespace:  namespace,
+			UID:        types.UID("some-resource-uid"),
+			Generation: wantObservedGeneration,
+		},
+		Spec: idpv1alpha1.GitLabIdentityProviderSpec{
+			GitLabAPI: idpv1alpha1.GitLabAPIConfig{
+				Host: ptr.To(goodServerDomain),
+				TLS: &idpv1alpha1.TLSSpec{
+					CertificateAuthorityData: goodServerCAB64,
+				},
+			},
+			Claims: idpv1alpha1.GitLabClaims{
+				Username: ptr.To(idpv1alpha1.GitLabUsernameID),
+				Groups:   ptr.To(idpv1alpha1.GitLabUseGroupPathForGroupName),
+			},
+			AllowAuthentication: idpv1alpha1.GitLabAllowAuthenticationSpec{
+				Groups: idpv1alpha1.GitLabGroupsSpec{
+					Policy:  ptr.To(idpv1alpha1.GitLabAllowedAuthGroupsPolicyOnlyUsersFromAllowedGroups),
+					Allowed: []string{"group1", "group2"},
+				},
+			},
+			Client: idpv1alpha1.GitLabClientSpec{
+				SecretName: goodClientCredentialsSecret.Name,
+			},
+		},
+	}
+
+	buildHostValidTrue := func(t *testing.T, host string) metav1.Condition {
+		return metav1.Condition{
+			Type:               HostValid,
+			Status:             metav1.ConditionTrue,
+			ObservedGeneration: wantObservedGeneration,
+			LastTransitionTime: wantLastTransitionTime,
+			Reason:             conditionsutil.ReasonSuccess,
+			Message:            fmt.Sprintf("spec.gitlabAPI.host (%q) is valid", host),
+		}
+	}
+
+	buildHostValidFalse := func(t *testing.T, host, message string) metav1.Condition {
+		return metav1.Condition{
+			Type:               HostValid,
+			Status:             metav1.ConditionFalse,
+			ObservedGeneration: wantObservedGeneration,
+			LastTransitionTime: wantLastTransitionTime,
+			Reason:             "InvalidHost",
+			Message:            fmt.Sprintf(`spec.gitlabAPI.host (%q) is not valid: %s`, host, message),
+		}
+	}
+
+	buildTLSConfigurationValidTrue := func(t *testing.T) metav1.Condition {
+		return metav1.Condition{
+			Type:               TLSConfigurationValid,
+			Status:             metav1.ConditionTrue,
+			ObservedGeneration: wantObservedGeneration,
+			LastTransitionTime: wantLastTransitionTime,
+			Reason:             conditionsutil.ReasonSuccess,
+			Message:            "spec.gitlabAPI.tls is valid: using configured CA bundle",
+		}
+	}
+
+	buildTLSConfigurationValidFalse := func(t *testing.T, message string) metav1.Condition {
+		return metav1.Condition{
+			Type:               TLSConfigurationValid,
+			Status:             metav1.ConditionFalse,
+			ObservedGeneration: wantObservedGeneration,
+			LastTransitionTime: wantLastTransitionTime,
+			Reason:             "InvalidTLSConfig",
+			Message:            message,
+		}
+	}
+
+	buildGroupsPolicyValidTrue := func(t *testing.T, policy idpv1alpha1.GitLabAllowedAuthGroupsPolicy) metav1.Condition {
+		return metav1.Condition{
+			Type:               GroupsPolicyValid,
+			Status:             metav1.ConditionTrue,
+			ObservedGeneration: wantObservedGeneration,
+			LastTransitionTime: wantLastTransitionTime,
+			Reason:             conditionsutil.ReasonSuccess,
+			Message:            fmt.Sprintf("spec.allowAuthentication.groups.policy (%q) is valid", policy),
+		}
+	}
+
+	buildGroupsPolicyValidFalse := func(t *testing.T, message string) metav1.Condition {
+		return metav1.Condition{
+			Type:               GroupsPolicyValid,
+			Status:             metav1.ConditionFalse,
+			ObservedGeneration: wantObservedGeneration,
+			LastTransitionTime: wantLastTransitionTime,
+			Reason:             "Invalid",
+			Message:            message,
+		}
+	}
+
+	buildClientCredentialsSecretValidTrue := func(t *testing.T, secretName string) metav1.Condition {
+		return metav1.Condition{
+			Type:               ClientCredentialsSecretValid,
+			Status:             metav1.ConditionTrue,
+			ObservedGeneration: wantObservedGeneration,
+			LastTransitionTime: wantLastTransitionTime,
+			Reason:             conditionsutil.ReasonSuccess,
+			Message:            fmt.Sprintf("clientID and clientSecret have been read from spec.client.SecretName (%q)", secretName),
+		}
+	}
+
+	buildClientCredentialsSecretValidFalse := func(t *testing.T, prefix, secretName, namespace, reason string) metav1.Condition {
+		return metav1.Condition{
+			Type:               ClientCredentialsSecretValid,
+			Status:             metav1.ConditionFalse,
+			ObservedGeneration: wantObservedGeneration,
+			LastTransitionTime: wantLastTransitionTime,
+			Reason:             reason,
+			Message: fmt.Sprintf(
+				`%s: secret from spec.client.SecretName (%q) must be found in namespace %q with type "secrets.pinniped.dev/gitlab-client" and keys "clientID" and "clientSecret"`,
+				prefix,
+				secretName,
+				namespace,
+			),
+		}
+	}
+
+	buildClaimsValidatedTrue := func(t *testing.T) metav1.Condition {
+		return metav1.Condition{
+			Type:               ClaimsValid,
+			Status:             metav1.ConditionTrue,
+			ObservedGeneration: wantObservedGeneration,
+			LastTransitionTime: wantLastTransitionTime,
+			Reason:             conditionsutil.ReasonSuccess,
+			Message:            "spec.claims are valid",
+		}
+	}
+
+	buildClaimsValidatedFalse := func(t *testing.T, message string) metav1.Condition {
+		return metav1.Condition{
+			Type:               ClaimsValid,
+			Status:             metav1.ConditionFalse,
+			ObservedGeneration: wantObservedGeneration,
+			LastTransitionTime: wantLastTransitionTime,
+			Reason:             "Invalid",
+			Message:            message,
+		}
+	}
+
+	buildGitLabConnectionValidTrue := func(t *testing.T, hostProbed string, hostSpecified string) metav1.Condition {
+		return metav1.Condition{
+			Type:               GitLabConnectionValid,
+			Status:             metav1.ConditionTrue,
+			ObservedGeneration: wantObservedGeneration,
+			LastTransitionTime: wantLastTransitionTime,
+			Reason:             conditionsutil.ReasonSuccess,
+			Message:            fmt.Sprintf("probed connection to %q for spec.gitlabAPI.host (%q): host is reachable and TLS verification succeeds", hostProbed, hostSpecified),
+		}
+	}
+
+	buildGitLabConnectionValidFalse := func(t *testing.T, message string) metav1.Condition {
+		return metav1.Condition{
+			Type:               GitLabConnectionValid,
+			Status:             metav1.ConditionFalse,
+			ObservedGeneration: wantObservedGeneration,
+			LastTransitionTime: wantLastTransitionTime,
+			Reason:             "UnableToMakeRequest",
+			Message:            message,
+		}
+	}
+
+	buildGitLabConnectionValidUnknown := func(t *testing.T) metav1.Condition {
+		return metav1.Condition{
+			Type:               GitLabConnectionValid,
+			Status:             metav1.ConditionUnknown,
+			ObservedGeneration: wantObservedGeneration,
+			LastTransitionTime: wantLastTransitionTime,
+			Reason:             "UnableToValidate",
+			Message:            "unable to validate; see other conditions for details",
+		}
+	}
+
+	buildLogForUpdatingCondition := func(name, conditionType, status, reason, message string) string {
+		return fmt.Sprintf(`{"level":"info","timestamp":"2099-08-08T13:57:36.123456Z","logger":"gitlab-upstream-observer","caller":"conditionsutil/conditions_util.go:<line>$conditionsutil.MergeConditions","message":"updated condition","namespace":"some-namespace","name":"%s","type":"%s","status":"%s","reason":"%s","message":"%s"}`, name, conditionType, status, reason, message)
+	}
+
+	buildLogForUpdatingPhase := func(name, phase string) string {
+		return fmt.Sprintf(`{"level":"info","timestamp":"2099-08-08T13:57:36.123456Z","logger":"gitlab-upstream-observer","caller":"gitlabupstreamwatcher/gitlab_upstream_watcher.go:<line>$gitlabupstreamwatcher.(*gitLabWatcherController).updateStatus","message":"updating GitLabIdentityProvider status","namespace":"some-namespace","name":"%s","phase":"%s"}`, name, phase)
+	}
+
+	buildWantProviderConfig := func(idp *idpv1alpha1.GitLabIdentityProvider, usernameAttribute idpv1alpha1.GitLabUsernameAttribute, groupNameAttribute idpv1alpha1.GitLabGroupNameAttribute, allowedGroups ...string) *upstreamgitlab.ProviderConfig {
+		return &upstreamgitlab.ProviderConfig{
+			Name:               idp.Name,
+			ResourceUID:        idp.UID,
+			APIBaseURL:         fmt.Sprintf("https://%s/api/v4/", *idp.Spec.GitLabAPI.Host),
+			UsernameAttribute:  usernameAttribute,
+			GroupNameAttribute: groupNameAttribute,
+			OAuth2Config: &oauth2.Config{
+				ClientID:     "some-client-id",
+				ClientSecret: "some-client-secret",
+				Endpoint: oauth2.Endpoint{
+					AuthURL:       fmt.Sprintf("https://%s/oauth/authorize", *idp.Spec.GitLabAPI.Host),
+					DeviceAuthURL: "", // not used
+					TokenURL:      fmt.Sprintf("https://%s/oauth/token", *idp.Spec.GitLabAPI.Host),
+					AuthStyle:     oauth2.AuthStyleInParams,
+				},
+				RedirectURL: "", // not used
+				Scopes:      []string{"read_api"},
+			},
+			AllowedGroups: setutil.NewCaseInsensitiveSet(allowedGroups...),
+			HttpClient:    phttp.Default(goodServerCertPool),
+		}
+	}
+
+	happyLogsForFilledOutIDP := []string{
+		buildLogForUpdatingCondition("some-idp-name", "ClientCredentialsSecretValid", "True", "Success", `clientID and clientSecret have been read from spec.client.SecretName (\"some-secret-name\")`),
+		buildLogForUpdatingCondition("some-idp-name", "ClaimsValid", "True", "Success", "spec.claims are valid"),
+		buildLogForUpdatingCondition("some-idp-name", "GroupsPolicyValid", "True", "Success", `spec.allowAuthentication.groups.policy (\"OnlyUsersFromAllowedGroups\") is valid`),
+		buildLogForUpdatingCondition("some-idp-name", "HostValid", "True", "Success", fmt.Sprintf(`spec.gitlabAPI.host (\"%s\") is valid`, goodServerDomain)),
+		buildLogForUpdatingCondition("some-idp-name", "TLSConfigurationValid", "True", "Success", "spec.gitlabAPI.tls is valid: using configured CA bundle"),
+		buildLogForUpdatingCondition("some-idp-name", "GitLabConnectionValid", "True", "Success", fmt.Sprintf(`probed connection to \"%s\" for spec.gitlabAPI.host (\"%s\"): host is reachable and TLS verification succeeds`, goodServerDomain, goodServerDomain)),
+		buildLogForUpdatingPhase("some-idp-name", "Ready"),
+	}
+
+	happyStatusForFilledOutIDP := idpv1alpha1.GitLabIdentityProviderStatus{
+		Phase: idpv1alpha1.GitLabPhaseReady,
+		Conditions: []metav1.Condition{
+			buildClaimsValidatedTrue(t),
+			buildClientCredentialsSecretValidTrue(t, validFilledOutIDP.Spec.Client.SecretName),
+			buildGitLabConnectionValidTrue(t, goodServerDomain, goodServerDomain),
+			buildGroupsPolicyValidTrue(t, *validFilledOutIDP.Spec.AllowAuthentication.Groups.Policy),
+			buildHostValidTrue(t, goodServerDomain),
+			buildTLSConfigurationValidTrue(t),
+		},
+	}
+
+	tests := []struct {
+		name                      string
+		gitlabIdentityProviders   []runtime.Object
+		secretsAndConfigMaps      []runtime.Object
+		mockProbeURLFunc          func(t *testing.T) ProbeURLFunc
+		preexistingValidatedCache []GitLabValidatedAPICacheKey
+		wantErr                   string
+		wantLogs                  []string
+		wantResultingCache        []*upstreamgitlab.ProviderConfig
+		wantResultingUpstreams    []idpv1alpha1.GitLabIdentityProvider
+		wantValidatedCache        []GitLabValidatedAPICacheKey
+	}{
+		{
+			name:                   "no GitLabIdentityProviders",
+			wantResultingCache:     []*upstreamgitlab.ProviderConfig{},
+			wantResultingUpstreams: []idpv1alpha1.GitLabIdentityProvider{},
+			wantLogs:               []string{},
+		},
+		{
+			name:                    "happy path with all fields",
+			secretsAndConfigMaps:    []runtime.Object{goodClientCredentialsSecret},
+			gitlabIdentityProviders: []runtime.Object{validFilledOutIDP},
+			wantResultingCache: []*upstreamgitlab.ProviderConfig{
+				buildWantProviderConfig(validFilledOutIDP, "id", "path", "group1", "group2"),
+			},
+			wantValidatedCache: []GitLabValidatedAPICacheKey{
+				{
+					address:      goodServerDomain,
+					caBundleHash: tlsconfigutil.NewCABundleHash(goodServerCA),
+				},
+			},
+			wantResultingUpstreams: []idpv1alpha1.GitLabIdentityProvider{
+				{
+					ObjectMeta: validFilledOutIDP.ObjectMeta,
+					Spec:       validFilledOutIDP.Spec,
+					Status:     happyStatusForFilledOutIDP,
+				},
+			},
+			wantLogs: happyLogsForFilledOutIDP,
+		},
+		{
+			name:                    "happy path with minimal fields",
+			secretsAndConfigMaps:    []runtime.Object{goodClientCredentialsSecret},
+			gitlabIdentityProviders: []runtime.Object{validMinimalIDP},
+			wantResultingCache: []*upstreamgitlab.ProviderConfig{
+				buildWantProviderConfig(validMinimalIDP, "username:id", "full_path"),
+			},
+			wantValidatedCache: []GitLabValidatedAPICacheKey{
+				{
+					address:      goodServerDomain,
+					caBundleHash: tlsconfigutil.NewCABundleHash(goodServerCA),
+				},
+			},
+			wantResultingUpstreams: []idpv1alpha1.GitLabIdentityProvider{
+				{
+					ObjectMeta: validMinimalIDP.ObjectMeta,
+					Spec:       validMinimalIDP.Spec,
+					Status: idpv1alpha1.GitLabIdentityProviderStatus{
+						Phase: idpv1alpha1.GitLabPhaseReady,
+						Conditions: []metav1.Condition{
+							buildClaimsValidatedTrue(t),
+							buildClientCredentialsSecretValidTrue(t, validMinimalIDP.Spec.Client.SecretName),
+							buildGitLabConnectionValidTrue(t, goodServerDomain, goodServerDomain),
+							buildGroupsPolicyValidTrue(t, *validMinimalIDP.Spec.AllowAuthentication.Groups.Policy),
+							buildHostValidTrue(t, goodServerDomain),
+							buildTLSConfigurationValidTrue(t),
+						},
+					},
+				},
+			},
+			wantLogs: []string{
+				buildLogForUpdatingCondition("minimal-idp-name", "ClientCredentialsSecretValid", "True", "Success", `clientID and clientSecret have been read from spec.client.SecretName (\"some-secret-name\")`),
+				buildLogForUpdatingCondition("minimal-idp-name", "ClaimsValid", "True", "Success", "spec.claims are valid"),
+				buildLogForUpdatingCondition("minimal-idp-name", "GroupsPolicyValid", "True", "Success", `spec.allowAuthentication.groups.policy (\"AllGitLabUsers\") is valid`),
+				buildLogForUpdatingCondition("minimal-idp-name", "HostValid", "True", "Success", fmt.Sprintf(`spec.gitlabAPI.host (\"%s\") is valid`, goodServerDomain)),
+				buildLogForUpdatingCondition("minimal-idp-name", "TLSConfigurationValid", "True", "Success", "spec.gitlabAPI.tls is valid: using configured CA bundle"),
+				buildLogForUpdatingCondition("minimal-idp-name", "GitLabConnectionValid", "True", "Success", fmt.Sprintf(`probed connection to \"%s\" for spec.gitlabAPI.host (\"%s\"): host is reachable and TLS verification succeeds`, goodServerDomain, goodServerDomain)),
+				buildLogForUpdatingPhase("minimal-idp-name", "Ready"),
+			},
+		},
+		{
+			name:                    "happy path with previously validated address/CA Bundle does not validate again",
+			secretsAndConfigMaps:    []runtime.Object{goodClientCredentialsSecret},
+			gitlabIdentityProviders: []runtime.Object{validFilledOutIDP},
+			mockProbeURLFunc:        fakeGitLabProbeFuncDisallowsAllProbes,
+			preexistingValidatedCache: []GitLabValidatedAPICacheKey{
+				{
+					address:      goodServerDomain,
+					caBundleHash: tlsconfigutil.NewCABundleHash(goodServerCA),
+				},
+			},
+			wantResultingCache: []*upstreamgitlab.ProviderConfig{
+				buildWantProviderConfig(validFilledOutIDP, "id", "path", "group1", "group2"),
+			},
+			wantValidatedCache: []GitLabValidatedAPICacheKey{
+				{
+					address:      goodServerDomain,
+					caBundleHash: tlsconfigutil.NewCABundleHash(goodServerCA),
+				},
+			},
+			wantResultingUpstreams: []idpv1alpha1.GitLabIdentityProvider{
+				{
+					ObjectMeta: validFilledOutIDP.ObjectMeta,
+					Spec:       validFilledOutIDP.Spec,
+					Status:     happyStatusForFilledOutIDP,
+				},
+			},
+			wantLogs: happyLogsForFilledOutIDP,
+		},
+		{
+			name:                    "Secret error - secret not found",
+			gitlabIdentityProviders: []runtime.Object{validFilledOutIDP},
+			wantResultingCache:      []*upstreamgitlab.ProviderConfig{},
+			wantValidatedCache: []GitLabValidatedAPICacheKey{
+				{
+					address:      goodServerDomain,
+					caBundleHash: tlsconfigutil.NewCABundleHash(goodServerCA),
+				},
+			},
+			wantResultingUpstreams: []idpv1alpha1.GitLabIdentityProvider{
+				{
+					ObjectMeta: validFilledOutIDP.ObjectMeta,
+					Spec:       validFilledOutIDP.Spec,
+					Status: idpv1alpha1.GitLabIdentityProviderStatus{
+						Phase: idpv1alpha1.GitLabPhaseError,
+						Conditions: []metav1.Condition{
+							buildClaimsValidatedTrue(t),
+							buildClientCredentialsSecretValidFalse(t, `secret "some-secret-name" not found`, "some-secret-name", namespace, upstreamwatchers.ReasonNotFound),
+							buildGitLabConnectionValidTrue(t, goodServerDomain, goodServerDomain),
+							buildGroupsPolicyValidTrue(t, *validFilledOutIDP.Spec.AllowAuthentication.Groups.Policy),
+							buildHostValidTrue(t, goodServerDomain),
+							buildTLSConfigurationValidTrue(t),
+						},
+					},
+				},
+			},
+			wantLogs: []string{
+				buildLogForUpdatingCondition("some-idp-name", "ClientCredentialsSecretValid", "False", "SecretNotFound", `secret \"some-secret-name\" not found: secret from spec.client.SecretName (\"some-secret-name\") must be found in namespace \"some-namespace\" with type \"secrets.pinniped.dev/gitlab-client\" and keys \"clientID\" and \"clientSecret\"`),
+				happyLogsForFilledOutIDP[1],
+				happyLogsForFilledOutIDP[2],
+				happyLogsForFilledOutIDP[3],
+				happyLogsForFilledOutIDP[4],
+				happyLogsForFilledOutIDP[5],
+				buildLogForUpdatingPhase("some-idp-name", "Error"),
+			},
+		},
+		{
+			name: "Secret error - wrong secret type",
+			secretsAndConfigMaps: []runtime.Object{
+				func() runtime.Object {
+					wrongTypeSecret := goodClientCredentialsSecret.DeepCopy()
+					wrongTypeSecret.Type = "secrets.pinniped.dev/github-client"
+					return wrongTypeSecret
+				}(),
+			},
+			gitlabIdentityProviders: []runtime.Object{validFilledOutIDP},
+			wantResultingCache:      []*upstreamgitlab.ProviderConfig{},
+			wantValidatedCache: []GitLabValidatedAPICacheKey{
+				{
+					address:      goodServerDomain,
+					caBundleHash: tlsconfigutil.NewCABundleHash(goodServerCA),
+				},
+			},
+			wantResultingUpstreams: []idpv1alpha1.GitLabIdentityProvider{
+				{
+					ObjectMeta: validFilledOutIDP.ObjectMeta,
+					Spec:       validFilledOutIDP.Spec,
+					Status: idpv1alpha1.GitLabIdentityProviderStatus{
+						Phase: idpv1alpha1.GitLabPhaseError,
+						Conditions: []metav1.Condition{
+							buildClaimsValidatedTrue(t),
+							buildClientCredentialsSecretValidFalse(t, `wrong secret type "secrets.pinniped.dev/github-client"`, "some-secret-name", namespace, upstreamwatchers.ReasonNotFound),
+							buildGitLabConnectionValidTrue(t, goodServerDomain, goodServerDomain),
+							buildGroupsPolicyValidTrue(t, *validFilledOutIDP.Spec.AllowAuthentication.Groups.Policy),
+							buildHostValidTrue(t, goodServerDomain),
+							buildTLSConfigurationValidTrue(t),
+						},
+					},
+				},
+			},
+			wantLogs: []string{
+				buildLogForUpdatingCondition("some-idp-name", "ClientCredentialsSecretValid", "False", "SecretNotFound", `wrong secret type \"secrets.pinniped.dev/github-client\": secret from spec.client.SecretName (\"some-secret-name\") must be found in namespace \"some-namespace\" with type \"secrets.pinniped.dev/gitlab-client\" and keys \"clientID\" and \"clientSecret\"`),
+				happyLogsForFilledOutIDP[1],
+				happyLogsForFilledOutIDP[2],
+				happyLogsForFilledOutIDP[3],
+				happyLogsForFilledOutIDP[4],
+				happyLogsForFilledOutIDP[5],
+				buildLogForUpdatingPhase("some-idp-name", "Error"),
+			},
+		},
+		{
+			name:                 "Claims error - invalid username attribute",
+			secretsAndConfigMaps: []runtime.Object{goodClientCredentialsSecret},
+			gitlabIdentityProviders: []runtime.Object{
+				func() runtime.Object {
+					badIDP := validFilledOutIDP.DeepCopy()
+					badIDP.Spec.Claims.Username = ptr.To[idpv1alpha1.GitLabUsernameAttribute]("email")
+					return badIDP
+				}(),
+			},
+			wantResultingCache: []*upstreamgitlab.ProviderConfig{},
+			wantValidatedCache: []GitLabValidatedAPICacheKey{
+				{
+					address:      goodServerDomain,
+					caBundleHash: tlsconfigutil.NewCABundleHash(goodServerCA),
+				},
+			},
+			wantResultingUpstreams: []idpv1alpha1.GitLabIdentityProvider{
+				{
+					ObjectMeta: validFilledOutIDP.ObjectMeta,
+					Spec: func() idpv1alpha1.GitLabIdentityProviderSpec {
+						badSpec := validFilledOutIDP.Spec.DeepCopy()
+						badSpec.Claims.Username = ptr.To[idpv1alpha1.GitLabUsernameAttribute]("email")
+						return *badSpec
+					}(),
+					Status: idpv1alpha1.GitLabIdentityProviderStatus{
+						Phase: idpv1alpha1.GitLabPhaseError,
+						Conditions: []metav1.Condition{
+							buildClaimsValidatedFalse(t, `spec.claims.username ("email") is not valid`),
+							buildClientCredentialsSecretValidTrue(t, validFilledOutIDP.Spec.Client.SecretName),
+							buildGitLabConnectionValidTrue(t, goodServerDomain, goodServerDomain),
+							buildGroupsPolicyValidTrue(t, *validFilledOutIDP.Spec.AllowAuthentication.Groups.Policy),
+							buildHostValidTrue(t, goodServerDomain),
+							buildTLSConfigurationValidTrue(t),
+						},
+					},
+				},
+			},
+			wantLogs: []string{
+				happyLogsForFilledOutIDP[0],
+				buildLogForUpdatingCondition("some-idp-name", "ClaimsValid", "False", "Invalid", `spec.claims.username (\"email\") is not valid`),
+				happyLogsForFilledOutIDP[2],
+				happyLogsForFilledOutIDP[3],
+				happyLogsForFilledOutIDP[4],
+				happyLogsForFilledOutIDP[5],
+				buildLogForUpdatingPhase("some-idp-name", "Error"),
+			},
+		},
+		{
+			name:                 "Groups policy error - AllGitLabUsers with allowed groups listed",
+			secretsAndConfigMaps: []runtime.Object{goodClientCredentialsSecret},
+			gitlabIdentityProviders: []runtime.Object{
+				func() runtime.Object {
+					badIDP := validFilledOutIDP.DeepCopy()
+					badIDP.Spec.AllowAuthentication.Groups.Policy = ptr.To(idpv1alpha1.GitLabAllowedAuthGroupsPolicyAllGitLabUsers)
+					return badIDP
+				}(),
+			},
+			wantResultingCache: []*upstreamgitlab.ProviderConfig{},
+			wantValidatedCache: []GitLabValidatedAPICacheKey{
+				{
+					address:      goodServerDomain,
+					caBundleHash: tlsconfigutil.NewCABundleHash(goodServerCA),
+				},
+			},
+			wantResultingUpstreams: []idpv1alpha1.GitLabIdentityProvider{
+				{
+					ObjectMeta: validFilledOutIDP.ObjectMeta,
+					Spec: func() idpv1alpha1.GitLabIdentityProviderSpec {
+						badSpec := validFilledOutIDP.Spec.DeepCopy()
+						badSpec.AllowAuthentication.Groups.Policy = ptr.To(idpv1alpha1.GitLabAllowedAuthGroupsPolicyAllGitLabUsers)
+						return *badSpec
+					}(),
+					Status: idpv1alpha1.GitLabIdentityProviderStatus{
+						Phase: idpv1alpha1.GitLabPhaseError,
+						Conditions: []metav1.Condition{
+							buildClaimsValidatedTrue(t),
+							buildClientCredentialsSecretValidTrue(t, validFilledOutIDP.Spec.Client.SecretName),
+							buildGitLabConnectionValidTrue(t, goodServerDomain, goodServerDomain),
+							buildGroupsPolicyValidFalse(t, "spec.allowAuthentication.groups.policy must be 'OnlyUsersFromAllowedGroups' when spec.allowAuthentication.groups.allowed has groups listed"),
+							buildHostValidTrue(t, goodServerDomain),
+							buildTLSConfigurationValidTrue(t),
+						},
+					},
+				},
+			},
+			wantLogs: []string{
+				happyLogsForFilledOutIDP[0],
+				happyLogsForFilledOutIDP[1],
+				buildLogForUpdatingCondition("some-idp-name", "GroupsPolicyValid", "False", "Invalid", "spec.allowAuthentication.groups.policy must be 'OnlyUsersFromAllowedGroups' when spec.allowAuthentication.groups.allowed has groups listed"),
+				happyLogsForFilledOutIDP[3],
+				happyLogsForFilledOutIDP[4],
+				happyLogsForFilledOutIDP[5],
+				buildLogForUpdatingPhase("some-idp-name", "Error"),
+			},
+		},
+		{
+			name:                 "Host error - invalid host",
+			secretsAndConfigMaps: []runtime.Object{goodClientCredentialsSecret},
+			gitlabIdentityProviders: []runtime.Object{
+				func() runtime.Object {
+					badIDP := validFilledOutIDP.DeepCopy()
+					badIDP.Spec.GitLabAPI.Host = ptr.To("https://example.com")
+					return badIDP
+				}(),
+			},
+			wantResultingCache: []*upstreamgitlab.ProviderConfig{},
+			wantResultingUpstreams: []idpv1alpha1.GitLabIdentityProvider{
+				{
+					ObjectMeta: validFilledOutIDP.ObjectMeta,
+					Spec: func() idpv1alpha1.GitLabIdentityProviderSpec {
+						badSpec := validFilledOutIDP.Spec.DeepCopy()
+						badSpec.GitLabAPI.Host = ptr.To("https://example.com")
+						return *badSpec
+					}(),
+					Status: idpv1alpha1.GitLabIdentityProviderStatus{
+						Phase: idpv1alpha1.GitLabPhaseError,
+						Conditions: []metav1.Condition{
+							buildClaimsValidatedTrue(t),
+							buildClientCredentialsSecretValidTrue(t, validFilledOutIDP.Spec.Client.SecretName),
+							buildGitLabConnectionValidUnknown(t),
+							buildGroupsPolicyValidTrue(t, *validFilledOutIDP.Spec.AllowAuthentication.Groups.Policy),
+							buildHostValidFalse(t, "https://example.com", `invalid port "//example.com"`),
+							buildTLSConfigurationValidTrue(t),
+						},
+					},
+				},
+			},
+			wantLogs: []string{
+				happyLogsForFilledOutIDP[0],
+				happyLogsForFilledOutIDP[1],
+				happyLogsForFilledOutIDP[2],
+				buildLogForUpdatingCondition("some-idp-name", "HostValid", "False", "InvalidHost", `spec.gitlabAPI.host (\"https://example.com\") is not valid: invalid port \"//example.com\"`),
+				happyLogsForFilledOutIDP[4],
+				buildLogForUpdatingCondition("some-idp-name", "GitLabConnectionValid", "Unknown", "UnableToValidate", "unable to validate; see other conditions for details"),
+				buildLogForUpdatingPhase("some-idp-name", "Error"),
+			},
+		},
+		{
+			name:                 "TLS error - invalid bundle",
+			secretsAndConfigMaps: []runtime.Object{goodClientCredentialsSecret},
+			gitlabIdentityProviders: []runtime.Object{
+				func() runtime.Object {
+					badIDP := validFilledOutIDP.DeepCopy()
+					badIDP.Spec.GitLabAPI.TLS = &idpv1alpha1.TLSSpec{
+						CertificateAuthorityData: base64.StdEncoding.EncodeToString([]byte("foo")),
+					}
+					return badIDP
+				}(),
+			},
+			wantResultingCache: []*upstreamgitlab.ProviderConfig{},
+			wantResultingUpstreams: []idpv1alpha1.GitLabIdentityProvider{
+				{
+					ObjectMeta: validFilledOutIDP.ObjectMeta,
+					Spec: func() idpv1alpha1.GitLabIdentityProviderSpec {
+						badSpec := validFilledOutIDP.Spec.DeepCopy()
+						badSpec.GitLabAPI.TLS = &idpv1alpha1.TLSSpec{
+							CertificateAuthorityData: base64.StdEncoding.EncodeToString([]byte("foo")),
+						}
+						return *badSpec
+					}(),
+					Status: idpv1alpha1.GitLabIdentityProviderStatus{
+						Phase: idpv1alpha1.GitLabPhaseError,
+						Conditions: []metav1.Condition{
+							buildClaimsValidatedTrue(t),
+							buildClientCredentialsSecretValidTrue(t, validFilledOutIDP.Spec.Client.SecretName),
+							buildGitLabConnectionValidUnknown(t),
+							buildGroupsPolicyValidTrue(t, *validFilledOutIDP.Spec.AllowAuthentication.Groups.Policy),
+							buildHostValidTrue(t, goodServerDomain),
+							buildTLSConfigurationValidFalse(t, `spec.gitlabAPI.tls.certificateAuthorityData is invalid: no base64-encoded PEM certificates found in 4 bytes of data (PEM certificates must begin with "-----BEGIN CERTIFICATE-----")`),
+						},
+					},
+				},
+			},
+			wantLogs: []string{
+				happyLogsForFilledOutIDP[0],
+				happyLogsForFilledOutIDP[1],
+				happyLogsForFilledOutIDP[2],
+				happyLogsForFilledOutIDP[3],
+				buildLogForUpdatingCondition("some-idp-name", "TLSConfigurationValid", "False", "InvalidTLSConfig", `spec.gitlabAPI.tls.certificateAuthorityData is invalid: no base64-encoded PEM certificates found in 4 bytes of data (PEM certificates must begin with \"-----BEGIN CERTIFICATE-----\")`),
+				buildLogForUpdatingCondition("some-idp-name", "GitLabConnectionValid", "Unknown", "UnableToValidate", "unable to validate; see other conditions for details"),
+				buildLogForUpdatingPhase("some-idp-name", "Error"),
+			},
+		},
+		{
+			name:                 "Connection error - host not trusted by provided CA bundle",
+			secretsAndConfigMaps: []runtime.Object{goodClientCredentialsSecret},
+			gitlabIdentityProviders: []runtime.Object{
+				func() runtime.Object {
+					badIDP := validFilledOutIDP.DeepCopy()
+					badIDP.Spec.GitLabAPI.TLS = &idpv1alpha1.TLSSpec{
+						CertificateAuthorityData: base64.StdEncoding.EncodeToString(unknownServerPEM.CertPEM),
+					}
+					return badIDP
+				}(),
+			},
+			wantErr:            "tls: failed to verify certificate: x509: certificate signed by unknown authority",
+			wantResultingCache: []*upstreamgitlab.ProviderConfig{},
+			wantResultingUpstreams: []idpv1alpha1.GitLabIdentityProvider{
+				{
+					ObjectMeta: validFilledOutIDP.ObjectMeta,
+					Spec: func() idpv1alpha1.GitLabIdentityProviderSpec {
+						badSpec := validFilledOutIDP.Spec.DeepCopy()
+						badSpec.GitLabAPI.TLS = &idpv1alpha1.TLSSpec{
+							CertificateAuthorityData: base64.StdEncoding.EncodeToString(unknownServerPEM.CertPEM),
+						}
+						return *badSpec
+					}(),
+					Status: idpv1alpha1.GitLabIdentityProviderStatus{
+						Phase: idpv1alpha1.GitLabPhaseError,
+						Conditions: []metav1.Condition{
+							buildClaimsValidatedTrue(t),
+							buildClientCredentialsSecretValidTrue(t, validFilledOutIDP.Spec.Client.SecretName),
+							buildGitLabConnectionValidFalse(t, fmt.Sprintf(`cannot make connection probe request for spec.gitlabAPI.host (%q): Get %q: tls: failed to verify certificate: x509: certificate signed by unknown authority`, goodServerDomain, fmt.Sprintf("https://%s/api/v4/version", goodServerDomain))),
+							buildGroupsPolicyValidTrue(t, *validFilledOutIDP.Spec.AllowAuthentication.Groups.Policy),
+							buildHostValidTrue(t, goodServerDomain),
+							buildTLSConfigurationValidTrue(t),
+						},
+					},
+				},
+			},
+			wantLogs: []string{
+				happyLogsForFilledOutIDP[0],
+				happyLogsForFilledOutIDP[1],
+				happyLogsForFilledOutIDP[2],
+				happyLogsForFilledOutIDP[3],
+				happyLogsForFilledOutIDP[4],
+				buildLogForUpdatingCondition("some-idp-name", "GitLabConnectionValid", "False", "UnableToMakeRequest", fmt.Sprintf(`cannot make connection probe request for spec.gitlabAPI.host (\"%s\"): Get \"https://%s/api/v4/version\": tls: failed to verify certificate: x509: certificate signed by unknown authority`, goodServerDomain, goodServerDomain)),
+				buildLogForUpdatingPhase("some-idp-name", "Error"),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			fakeSupervisorClient := supervisorfake.NewSimpleClientset(tt.gitlabIdentityProviders...)
+			supervisorInformers := supervisorinformers.NewSharedInformerFactory(fakeSupervisorClient, 0)
+
+			fakeKubeClient := kubefake.NewClientset(tt.secretsAndConfigMaps...)
+			kubeInformers := k8sinformers.NewSharedInformerFactoryWithOptions(fakeKubeClient, 0)
+
+			idpCache := dynamicupstreamprovider.NewDynamicUpstreamIDPProvider()
+			idpCache.SetGitLabIdentityProviders([]upstreamprovider.UpstreamGitLabIdentityProviderI{
+				upstreamgitlab.New(
+					upstreamgitlab.ProviderConfig{Name: "initial-entry-to-remove"},
+				),
+			})
+
+			logger, log := plog.TestLogger(t)
+
+			gitLabIdentityProviderInformer := supervisorInformers.IDP().V1alpha1().GitLabIdentityProviders()
+
+			var probeURLFunc ProbeURLFunc = ProbeURL // by default use the production code for probing a URL
+			if tt.mockProbeURLFunc != nil {
+				probeURLFunc = tt.mockProbeURLFunc(t)
+			}
+
+			validatedCache := cache.NewExpiring()
+			for _, item := range tt.preexistingValidatedCache {
+				validatedCache.Set(item, nil, 1*time.Hour)
+			}
+
+			controller := New(
+				namespace,
+				idpCache,
+				fakeSupervisorClient,
+				gitLabIdentityProviderInformer,
+				kubeInformers.Core().V1().Secrets(),
+				kubeInformers.Core().V1().ConfigMaps(),
+				logger,
+				controllerlib.WithInformer,
+				frozenClockForLastTransitionTime,
+				probeURLFunc,
+				validatedCache,
+			)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			supervisorInformers.Start(ctx.Done())
+			kubeInformers.Start(ctx.Done())
+			controllerlib.TestRunSynchronously(t, controller)
+
+			syncCtx := controllerlib.Context{Context: ctx, Key: controllerlib.Key{}}
+
+			if err := controllerlib.TestSync(t, controller, syncCtx); len(tt.wantErr) > 0 {
+				require.ErrorContains(t, err, controllerlib.ErrSyntheticRequeue.Error())
+				require.ErrorContains(t, err, tt.wantErr)
+			} else {
+				require.NoError(t, err)
+			}
+
+			// Verify what's in the IDP cache
+			actualIDPList := idpCache.GetGitLabIdentityProviders()
+			require.Equal(t, len(tt.wantResultingCache), len(actualIDPList))
+			for i := range len(tt.wantResultingCache) {
+				// Do not expect any particular order in the cache
+				var actualProvider *upstreamgitlab.Provider
+				for _, possibleIDP := range actualIDPList {
+					if possibleIDP.GetResourceName() == tt.wantResultingCache[i].Name {
+						var ok bool
+						actualProvider, ok = possibleIDP.(*upstreamgitlab.Provider)
+						require.True(t, ok)
+						break
+					}
+				}
+
+				require.Equal(t, tt.wantResultingCache[i].Name, actualProvider.GetResourceName())
+				require.Equal(t, tt.wantResultingCache[i].ResourceUID, actualProvider.GetResourceUID())
+				require.Equal(t, tt.wantResultingCache[i].OAuth2Config.ClientID, actualProvider.GetClientID())
+				require.Equal(t, tt.wantResultingCache[i].GroupNameAttribute, actualProvider.GetGroupNameAttribute())
+				require.Equal(t, tt.wantResultingCache[i].UsernameAttribute, actualProvider.GetUsernameAttribute())
+				require.Equal(t, tt.wantResultingCache[i].AllowedGroups, actualProvider.GetAllowedGroups())
+
+				compareTLSClientConfigWithinHttpClients(t, tt.wantResultingCache[i].HttpClient, actualProvider.GetConfig().HttpClient)
+				require.Equal(t, tt.wantResultingCache[i].OAuth2Config, actualProvider.GetConfig().OAuth2Config)
+				require.Equal(t, tt.wantResultingCache[i].APIBaseURL, actualProvider.GetConfig().APIBaseURL)
+			}
+
+			// Verify what's in the validated cache
+			for _, item := range tt.wantValidatedCache {
+				_, ok := validatedCache.Get(item)
+				require.True(t, ok, "item with address %q must be found in the validated cache", item.address)
+			}
+
+			// Verify the status conditions as reported in Kubernetes
+			allGitLabIDPs, err := fakeSupervisorClient.IDPV1alpha1().GitLabIdentityProviders(namespace).List(ctx, metav1.ListOptions{})
+			require.NoError(t, err)
+
+			require.Equal(t, len(tt.wantResultingUpstreams), len(allGitLabIDPs.Items))
+			for i := range len(tt.wantResultingUpstreams) {
+				require.Len(t, tt.wantResultingUpstreams[i].Status.Conditions, countExpectedConditions)
+
+				// Do not expect any particular order in the K8s objects
+				var actualIDP *idpv1alpha1.GitLabIdentityProvider
+				for _, possibleMatch := range allGitLabIDPs.Items {
+					if possibleMatch.GetName() == tt.wantResultingUpstreams[i].Name {
+						actualIDP = ptr.To(possibleMatch)
+						break
+					}
+				}
+
+				require.NotNil(t, actualIDP, "must find IDP with name %s", tt.wantResultingUpstreams[i].Name)
+				require.Len(t, actualIDP.Status.Conditions, countExpectedConditions)
+				require.Equal(t, tt.wantResultingUpstreams[i], *actualIDP)
+			}
+			testutil.RequireLogLines(t, tt.wantLogs, log)
+
+			// This needs to happen after the expected condition LastTransitionTime has been updated.
+			wantActions := make([]coretesting.Action, 3+len(tt.wantResultingUpstreams))
+			wantActions[0] = coretesting.NewListAction(gitlabIDPGVR, gitlabIDPKind, "", metav1.ListOptions{})
+			wantActions[1] = coretesting.NewWatchAction(gitlabIDPGVR, "", metav1.ListOptions{Watch: true})
+			for i, want := range tt.wantResultingUpstreams {
+				wantActions[2+i] = coretesting.NewUpdateSubresourceAction(gitlabIDPGVR, "status", want.Namespace, ptr.To(want))
+			}
+			// This List action is coming from the test code when it pulls the GitLabIdentityProviders from K8s
+			wantActions[len(wantActions)-1] = coretesting.NewListAction(gitlabIDPGVR, gitlabIDPKind, namespace, metav1.ListOptions{})
+			require.Equal(t, wantActions, testutil.ScrubListOptionsForActions(t, fakeSupervisorClient.Actions()))
+		})
+	}
+}
+
+func compareTLSClientConfigWithinHttpClients(t *testing.T, expected *http.Client, actual *http.Client) {
+	t.Helper()
+
+	require.NotEmpty(t, expected)
+	require.NotEmpty(t, actual)
+
+	require.Equal(t, expected.Timeout, actual.Timeout)
+
+	expectedConfig, err := utilnet.TLSClientConfig(expected.Transport)
+	require.NoError(t, err)
+
+	actualConfig, err := utilnet.TLSClientConfig(actual.Transport)
+	require.NoError(t, err)
+
+	require.True(t, actualConfig.RootCAs.Equal(expectedConfig.RootCAs))
+	actualConfig.RootCAs = expectedConfig.RootCAs
+	require.Equal(t, expectedConfig, actualConfig)
+}
+
+func TestGitLabUpstreamWatcherControllerFilterSecret(t *testing.T) {
+	goodSecret := &corev1.Secret{
+		Type: "secrets.pinniped.dev/gitlab-client",
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "some-name",
+		},
+	}
+
+	tests := []struct {
+		name       string
+		secret     metav1.Object
+		wantAdd    bool
+		wantUpdate bool
+		wantDelete bool
+	}{
+		{
+			name:       "should return true for a secret of the type secrets.pinniped.dev/gitlab-client",
+			secret:     goodSecret,
+			wantAdd:    true,
+			wantUpdate: true,
+			wantDelete: true,
+		},
+		{
+			name: "should return true for a secret of the type Opaque",
+			secret: func() *corev1.Secret {
+				otherSecret := goodSecret.DeepCopy()
+				otherSecret.Type = corev1.SecretTypeOpaque
+				return otherSecret
+			}(),
+			wantAdd:    true,
+			wantUpdate: true,
+			wantDelete: true,
+		},
+		{
+			name: "should return true for a secret of the type TLS",
+			secret: func() *corev1.Secret {
+				otherSecret := goodSecret.DeepCopy()
+				otherSecret.Type = corev1.SecretTypeTLS
+				return otherSecret
+			}(),
+			wantAdd:    true,
+			wantUpdate: true,
+			wantDelete: true,
+		},
+		{
+			name: "should return false for a secret of the wrong type",
+			secret: func() *corev1.Secret {
+				otherSecret := goodSecret.DeepCopy()
+				otherSecret.Type = "secrets.pinniped.dev/github-client"
+				return otherSecret
+			}(),
+		},
+		{
+			name: "should return false for a resource of wrong data type",
+			secret: &corev1.Namespace{
+				ObjectMeta: metav1.ObjectMeta{Name: "some-name", Namespace: "some-namespace"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			kubeInformers := k8sinformers.NewSharedInformerFactoryWithOptions(kubefake.NewClientset(), 0)
+
+			logger, _ := plog.TestLogger(t)
+
+			observableInformers := testutil.NewObservableWithInformerOption()
+			secretInformer := kubeInformers.Core().V1().Secrets()
+
+			_ = New(
+				"some-namespace",
+				dynamicupstreamprovider.NewDynamicUpstreamIDPProvider(),
+				supervisorfake.NewSimpleClientset(),
+				supervisorinformers.NewSharedInformerFactory(supervisorfake.NewSimpleClientset(), 0).IDP().V1alpha1().GitLabIdentityProviders(),
+				secretInformer,
+				kubeInformers.Core().V1().ConfigMaps(),
+				logger,
+				observableInformers.WithInformer,
+				clock.RealClock{},
+				nil,
+				cache.NewExpiring(),
+			)
+
+			unrelated := &corev1.Secret{}
+			filter := observableInformers.GetFilterForInformer(secretInformer)
+			require.Equal(t, tt.wantAdd, filter.Add(tt.secret))
+			require.Equal(t, tt.wantUpdate, filter.Update(unrelated, tt.secret))
+			require.Equal(t, tt.wantUpdate, filter.Update(tt.secret, unrelated))
+			require.Equal(t, tt.wantDelete, filter.Delete(tt.secret))
+		})
+	}
+}
+
+func TestGitLabUpstreamWatcherControllerFilterGitLabIDP(t *testing.T) {
+	logger, _ := plog.TestLogger(t)
+
+	observableInformers := testutil.NewObservableWithInformerOption()
+	gitLabIdentityProviderInformer := supervisorinformers.NewSharedInformerFactory(supervisorfake.NewSimpleClientset(), 0).IDP().V1alpha1().GitLabIdentityProviders()
+
+	_ = New(
+		"some-namespace",
+		dynamicupstreamprovider.NewDynamicUpstreamIDPProvider(),
+		supervisorfake.NewSimpleClientset(),
+		gitLabIdentityProviderInformer,
+		k8sinformers.NewSharedInformerFactoryWithOptions(kubefake.NewClientset(), 0).Core().V1().Secrets(),
+		k8sinformers.NewSharedInformerFactoryWithOptions(kubefake.NewClientset(), 0).Core().V1().ConfigMaps(),
+		logger,
+		observableInformers.WithInformer,
+		clock.RealClock{},
+		nil,
+		cache.NewExpiring(),
+	)
+
+	idp := &idpv1alpha1.GitLabIdentityProvider{ObjectMeta: metav1.ObjectMeta{Namespace: "some-namespace"}}
+	unrelated := &idpv1alpha1.GitLabIdentityProvider{}
+	filter := observableInformers.GetFilterForInformer(gitLabIdentityProviderInformer)
+	require.True(t, filter.Add(idp))
+	require.True(t, filter.Update(unrelated, idp))
+	require.True(t, filter.Update(idp, unrelated))
+	require.True(t, filter.Delete(idp))
+}
@@ -1,4 +1,4 @@
-// Copyright 2020-2024 the Pinniped contributors. All Rights Reserved.
+// Copyright 2020-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package supervisorconfig
@@ -6,10 +6,12 @@ package supervisorconfig
 import (
 	"encoding/json"
 	"fmt"
+	"time"
 
 	"github.com/go-jose/go-jose/v4"
 	"k8s.io/apimachinery/pkg/labels"
 	corev1informers "k8s.io/client-go/informers/core/v1"
+	"k8s.io/utils/clock"
 
 	"go.pinniped.dev/generated/latest/client/supervisor/informers/externalversions/config/v1alpha1"
 	pinnipedcontroller "go.pinniped.dev/internal/controller"
@@ -21,6 +23,7 @@ type jwksObserverController struct {
 	issuerToJWKSSetter       IssuerToJWKSMapSetter
 	federationDomainInformer v1alpha1.FederationDomainInformer
 	secretInformer           corev1informers.SecretInformer
+	clock                    clock.Clock
 }
 
 type IssuerToJWKSMapSetter interface {
@@ -35,11 +38,14 @@ type IssuerToJWKSMapSetter interface {
 // This controller assumes that the informers passed to it are already scoped down to the
 // appropriate namespace. It also assumes that the IssuerToJWKSMapSetter passed to it has an
 // underlying implementation which is thread-safe.
+// Retired JWKs are left out of the cache once their retention has passed, even when the JWKS writer
+// controller has not removed them from the Secret yet.
 func NewJWKSObserverController(
 	issuerToJWKSSetter IssuerToJWKSMapSetter,
 	secretInformer corev1informers.SecretInformer,
 	federationDomainInformer v1alpha1.FederationDomainInformer,
 	withInformer pinnipedcontroller.WithInformerOptionFunc,
+	clock clock.Clock,
 ) controllerlib.Controller {
 	return controllerlib.New(
 		controllerlib.Config{
@@ -48,6 +54,7 @@ func NewJWKSObserverController(
 				issuerToJWKSSetter:       issuerToJWKSSetter,
 				federationDomainInformer: federationDomainInformer,
 				secretInformer:           secretInformer,
+				clock:                    clock,
 			},
 		},
 		withInformer(
@@ -74,6 +81,7 @@ func (c *jwksObserverController) Sync(ctx controllerlib.Context) error {
 	// can cause the map to need to be updated.
 	issuerToJWKSMap := map[string]*jose.JSONWebKeySet{}
 	issuerToActiveJWKMap := map[string]*jose.JSONWebKey{}
+	now := c.clock.Now()
 
 	for _, provider := range allProviders {
 		secretRef := provider.Status.Secrets.JWKS
@@ -97,6 +105,14 @@ func (c *jwksObserverController) Sync(ctx controllerlib.Context) error {
 			continue
 		}
 
+		jwksFromSecret.Keys = unexpiredJWKs(
+			jwksFromSecret.Keys,
+			activeJWKFromSecret.KeyID,
+			parseJWKTimestamps(jwksSecret),
+			signingConfigFromFederationDomain(provider).retiredKeyRetention,
+			now,
+		)
+
 		issuerToJWKSMap[provider.Spec.Issuer] = &jwksFromSecret
 		issuerToActiveJWKMap[provider.Spec.Issuer] = &activeJWKFromSecret
 	}
@@ -112,3 +128,24 @@ func (c *jwksObserverController) Sync(ctx controllerlib.Context) error {
 
 	return nil
 }
+
+// unexpiredJWKs returns the JWKs which are either active or were retired less than retiredKeyRetention ago.
+// JWKs without timestamps were written by older versions of the Supervisor and are always returned.
+func unexpiredJWKs(
+	jwks []jose.JSONWebKey,
+	activeKeyID string,
+	timestamps map[string]jwkTimestamp,
+	retiredKeyRetention time.Duration,
+	now time.Time,
+) []jose.JSONWebKey {
+	result := make([]jose.JSONWebKey, 0, len(jwks))
+	for _, jwk := range jwks {
+		timestamp, ok := timestamps[jwk.KeyID]
+		if jwk.KeyID != activeKeyID && ok && timestamp.RetiredAt != nil &&
+			!now.Before(timestamp.RetiredAt.Add(retiredKeyRetention)) {
+			continue
+		}
+		result = append(result, jwk)
+	}
+	return result
+}
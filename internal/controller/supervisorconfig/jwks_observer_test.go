@@ -7,6 +7,7 @@ import (
 	"context"
 	"encoding/json"
 	"testing"
+	"time"
 
 	"github.com/go-jose/go-jose/v4"
 	"github.com/sclevine/spec"
@@ -16,6 +17,9 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	k8sinformers "k8s.io/client-go/informers"
 	kubefake "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/utils/clock"
+	clocktesting "k8s.io/utils/clock/testing"
+	"k8s.io/utils/ptr"
 
 	supervisorconfigv1alpha1 "go.pinniped.dev/generated/latest/apis/supervisor/config/v1alpha1"
 	supervisorfake "go.pinniped.dev/generated/latest/client/supervisor/clientset/versioned/fake"
@@ -43,6 +47,7 @@ func TestJWKSObserverControllerInformerFilters(t *testing.T) {
 				secretsInformer,
 				federationDomainInformer,
 				observableWithInformerOption.WithInformer, // make it possible to observe the behavior of the Filters
+				clock.RealClock{},
 			)
 			secretsInformerFilter = observableWithInformerOption.GetFilterForInformer(secretsInformer)
 			federationDomainInformerFilter = observableWithInformerOption.GetFilterForInformer(federationDomainInformer)
@@ -133,6 +138,7 @@ func TestJWKSObserverControllerSync(t *testing.T) {
 			cancelContextCancelFunc context.CancelFunc
 			syncContext             *controllerlib.Context
 			issuerToJWKSSetter      *fakeIssuerToJWKSMapSetter
+			frozenNow               time.Time
 		)
 
 		// Defer starting the informers until the last possible moment so that the
@@ -144,6 +150,7 @@ func TestJWKSObserverControllerSync(t *testing.T) {
 				kubeInformers.Core().V1().Secrets(),
 				pinnipedInformers.Config().V1alpha1().FederationDomains(),
 				controllerlib.WithInformer,
+				clocktesting.NewFakeClock(frozenNow),
 			)
 
 			// Set this at the last second to support calling subject.Name().
@@ -173,6 +180,7 @@ func TestJWKSObserverControllerSync(t *testing.T) {
 			pinnipedInformerClient = supervisorfake.NewSimpleClientset()
 			pinnipedInformers = supervisorinformers.NewSharedInformerFactory(pinnipedInformerClient, 0)
 			issuerToJWKSSetter = &fakeIssuerToJWKSMapSetter{}
+			frozenNow = time.Date(2026, 3, 4, 5, 6, 7, 0, time.UTC)
 
 			unrelatedSecret := &corev1.Secret{
 				ObjectMeta: metav1.ObjectMeta{
@@ -377,5 +385,67 @@ func TestJWKSObserverControllerSync(t *testing.T) {
 				requireJWKJSON(expectedJWK2, issuerToJWKSSetter.issuerToActiveJWKMapReceived["https://issuer-with-good-secret2.com"])
 			})
 		})
+
+		when("there are FederationDomains whose JWKS Secrets have retired JWKs", func() {
+			var (
+				activeJWK, retiredJWK string
+			)
+
+			it.Before(func() {
+				activeJWK = string(readJWKJSON(t, "testdata/public-jwk.json"))
+				retiredJWK = string(readJWKJSON(t, "testdata/public-jwk2.json"))
+				tenMinutesAgo := frozenNow.Add(-10 * time.Minute).Format(time.RFC3339)
+
+				for _, fd := range []struct {
+					name             string
+					retentionSeconds *int32
+				}{
+					{name: "default-retention"},
+					{name: "short-retention", retentionSeconds: ptr.To[int32](300)},
+				} {
+					r.NoError(pinnipedInformerClient.Tracker().Add(&supervisorconfigv1alpha1.FederationDomain{
+						ObjectMeta: metav1.ObjectMeta{Name: fd.name, Namespace: installedInNamespace},
+						Spec: supervisorconfigv1alpha1.FederationDomainSpec{
+							Issuer:  "https://" + fd.name + ".com",
+							Signing: &supervisorconfigv1alpha1.FederationDomainSigning{RetiredKeyRetentionSeconds: fd.retentionSeconds},
+						},
+						Status: supervisorconfigv1alpha1.FederationDomainStatus{
+							Secrets: supervisorconfigv1alpha1.FederationDomainSecrets{
+								JWKS: corev1.LocalObjectReference{Name: fd.name + "-jwks"},
+							},
+						},
+					}))
+					r.NoError(kubeInformerClient.Tracker().Add(&corev1.Secret{
+						ObjectMeta: metav1.ObjectMeta{Name: fd.name + "-jwks", Namespace: installedInNamespace},
+						Data: map[string][]byte{
+							"activeJWK": []byte(activeJWK),
+							"jwks":      []byte(`{"keys": [` + activeJWK + `,` + retiredJWK + `]}`),
+							"jwkTimestamps": []byte(`{` +
+								`"pinniped-supervisor-key": {"createdAt": "` + tenMinutesAgo + `"},` +
+								`"pinniped-supervisor-key2": {"createdAt": "2026-01-01T00:00:00Z", "retiredAt": "` + tenMinutesAgo + `"}` +
+								`}`),
+						},
+					}))
+				}
+			})
+
+			it("leaves out the retired JWKs whose retention has passed", func() {
+				startInformersAndController()
+				r.NoError(controllerlib.TestSync(t, subject, *syncContext))
+
+				defaultRetentionJWKS := issuerToJWKSSetter.issuerToJWKSMapReceived["https://default-retention.com"]
+				r.NotNil(defaultRetentionJWKS)
+				r.Len(defaultRetentionJWKS.Keys, 2)
+				r.Equal("pinniped-supervisor-key", defaultRetentionJWKS.Keys[0].KeyID)
+				r.Equal("pinniped-supervisor-key2", defaultRetentionJWKS.Keys[1].KeyID)
+
+				shortRetentionJWKS := issuerToJWKSSetter.issuerToJWKSMapReceived["https://short-retention.com"]
+				r.NotNil(shortRetentionJWKS)
+				r.Len(shortRetentionJWKS.Keys, 1)
+				r.Equal("pinniped-supervisor-key", shortRetentionJWKS.Keys[0].KeyID)
+
+				r.Equal("pinniped-supervisor-key", issuerToJWKSSetter.issuerToActiveJWKMapReceived["https://short-retention.com"].KeyID)
+			})
+		})
 	}, spec.Parallel(), spec.Report(report.Terminal{}))
 }
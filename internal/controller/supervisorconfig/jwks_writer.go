@@ -1,16 +1,22 @@
-// Copyright 2020-2024 the Pinniped contributors. All Rights Reserved.
+// Copyright 2020-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package supervisorconfig
 
 import (
+	"bytes"
 	"context"
+	"crypto"
 	"crypto/ecdsa"
+	"crypto/ed25519"
 	"crypto/elliptic"
 	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"io"
+	"time"
 
 	"github.com/go-jose/go-jose/v4"
 	corev1 "k8s.io/api/core/v1"
@@ -21,6 +27,7 @@ import (
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/util/retry"
 	"k8s.io/klog/v2"
+	"k8s.io/utils/clock"
 
 	supervisorconfigv1alpha1 "go.pinniped.dev/generated/latest/apis/supervisor/config/v1alpha1"
 	supervisorclientset "go.pinniped.dev/generated/latest/client/supervisor/clientset/versioned"
@@ -37,23 +44,100 @@ const (
 	//
 	// Note! The value for this key will contain private key material!
 	activeJWKKey = "activeJWK"
+	// nextJWKKey points to the private key which will replace the active JWK at its next rotation. Its public key
+	// is already published in the JWKS. Secrets which are not about to rotate their active JWK do not have this key.
+	//
+	// Note! The value for this key will contain private key material!
+	nextJWKKey = "nextJWK"
 	// jwksKey points to the current JWKS used to verify tokens.
 	//
 	// Note! The value for this key will contain only public key material!
 	jwksKey = "jwks"
+	// jwkTimestampsKey points to the times at which each JWK in the JWKS was created, activated and, for JWKs which
+	// are no longer active, retired. Secrets written by older versions of the Supervisor do not have this key.
+	jwkTimestampsKey = "jwkTimestamps"
 
 	jwksSecretTypeValue corev1.SecretType = "secrets.pinniped.dev/federation-domain-jwks"
 )
 
 const (
 	federationDomainKind = "FederationDomain"
+
+	defaultSigningAlgorithm    = jose.ES256
+	defaultRetiredKeyRetention = 24 * time.Hour
+	defaultNextKeyPublication  = time.Hour
+
+	rsaSigningKeySize = 2048
 )
 
-// generateKey is stubbed out for the purpose of testing. The default behavior is to generate an EC key.
-var generateKey = generateECKey //nolint:gochecknoglobals
+// generateKey is stubbed out for the purpose of testing. The default behavior is to generate a key for the algorithm.
+var generateKey = generateKeyForAlgorithm //nolint:gochecknoglobals
+
+func generateKeyForAlgorithm(r io.Reader, algorithm jose.SignatureAlgorithm) (any, error) {
+	switch algorithm {
+	case jose.ES256:
+		return ecdsa.GenerateKey(elliptic.P256(), r)
+	case jose.ES384:
+		return ecdsa.GenerateKey(elliptic.P384(), r)
+	case jose.RS256:
+		return rsa.GenerateKey(r, rsaSigningKeySize)
+	case jose.EdDSA:
+		_, key, err := ed25519.GenerateKey(r)
+		return key, err
+	default:
+		return nil, fmt.Errorf("unsupported signing algorithm %q", algorithm)
+	}
+}
+
+// jwkTimestamp records when a JWK was created, when it became the active JWK and, once it is no longer the active
+// JWK, when it was retired.
+type jwkTimestamp struct {
+	CreatedAt   time.Time  `json:"createdAt"`
+	ActivatedAt *time.Time `json:"activatedAt,omitempty"`
+	RetiredAt   *time.Time `json:"retiredAt,omitempty"`
+}
+
+// activatedAt returns when the JWK became the active JWK. JWKs which were active before activation times were
+// recorded became active when they were created.
+func (t jwkTimestamp) activatedAt() time.Time {
+	if t.ActivatedAt != nil {
+		return *t.ActivatedAt
+	}
+	return t.CreatedAt
+}
 
-func generateECKey(r io.Reader) (any, error) {
-	return ecdsa.GenerateKey(elliptic.P256(), r)
+// signingConfig is the parsed signing configuration of a FederationDomain.
+type signingConfig struct {
+	algorithm           jose.SignatureAlgorithm
+	rotationPeriod      time.Duration // zero means that keys are never rotated
+	retiredKeyRetention time.Duration
+	nextKeyPublication  time.Duration
+}
+
+func signingConfigFromFederationDomain(federationDomain *supervisorconfigv1alpha1.FederationDomain) signingConfig {
+	config := signingConfig{
+		algorithm:           defaultSigningAlgorithm,
+		retiredKeyRetention: defaultRetiredKeyRetention,
+		nextKeyPublication:  defaultNextKeyPublication,
+	}
+
+	signing := federationDomain.Spec.Signing
+	if signing == nil {
+		return config
+	}
+	if signing.Algorithm != "" {
+		config.algorithm = jose.SignatureAlgorithm(signing.Algorithm)
+	}
+	if signing.RotationPeriodSeconds != nil {
+		config.rotationPeriod = time.Duration(*signing.RotationPeriodSeconds) * time.Second
+	}
+	if signing.RetiredKeyRetentionSeconds != nil {
+		config.retiredKeyRetention = time.Duration(*signing.RetiredKeyRetentionSeconds) * time.Second
+	}
+	if signing.NextKeyPublicationSeconds != nil {
+		config.nextKeyPublication = time.Duration(*signing.NextKeyPublicationSeconds) * time.Second
+	}
+	return config
 }
 
 // jwkController holds the fields necessary for the JWKS controller to communicate with FederationDomains and
@@ -64,10 +148,13 @@ type jwksWriterController struct {
 	kubeClient               kubernetes.Interface
 	federationDomainInformer configinformers.FederationDomainInformer
 	secretInformer           corev1informers.SecretInformer
+	clock                    clock.Clock
 }
 
 // NewJWKSWriterController returns a controllerlib.Controller that ensures a FederationDomain has a corresponding
-// Secret that contains a valid active JWK and JWKS.
+// Secret that contains a valid active JWK and JWKS. It also rotates the active JWK according to the signing
+// configuration of the FederationDomain, publishing each new JWK in the JWKS before it becomes active, and removes
+// retired JWKs from the JWKS once their retention has passed.
 func NewJWKSWriterController(
 	jwksSecretLabels map[string]string,
 	kubeClient kubernetes.Interface,
@@ -75,6 +162,7 @@ func NewJWKSWriterController(
 	secretInformer corev1informers.SecretInformer,
 	federationDomainInformer configinformers.FederationDomainInformer,
 	withInformer pinnipedcontroller.WithInformerOptionFunc,
+	clock clock.Clock,
 ) controllerlib.Controller {
 	isSecretToSync := func(obj metav1.Object) bool {
 		return generator.IsFederationDomainSecretOfType(obj, jwksSecretTypeValue)
@@ -89,6 +177,7 @@ func NewJWKSWriterController(
 				pinnipedClient:           pinnipedClient,
 				secretInformer:           secretInformer,
 				federationDomainInformer: federationDomainInformer,
+				clock:                    clock,
 			},
 		},
 		// We want to be notified when a FederationDomain's secret gets updated or deleted. When this happens, we
@@ -136,13 +225,8 @@ func (c *jwksWriterController) Sync(ctx controllerlib.Context) error {
 		return fmt.Errorf("cannot determine secret status: %w", err)
 	}
 	if !secretNeedsUpdate {
-		// Secret is up to date - we are good to go.
-		plog.Debug(
-			"secret is up to date",
-			"federationdomain",
-			klog.KRef(ctx.Key.Namespace, ctx.Key.Name),
-		)
-		return nil
+		// Secret is valid, but its keys might need to be rotated or retired.
+		return c.rotateKeys(ctx, federationDomain)
 	}
 
 	// If the FederationDomain does not have a secret associated with it, that secret does not exist, or the secret
@@ -194,34 +278,16 @@ func (c *jwksWriterController) secretNeedsUpdate(federationDomain *supervisorcon
 }
 
 func (c *jwksWriterController) generateSecret(federationDomain *supervisorconfigv1alpha1.FederationDomain) (*corev1.Secret, error) {
-	// Note! This is where we could potentially add more handling of FederationDomain spec fields which tell us how
-	// this FederationDomain should sign and verify ID tokens (e.g., hardcoded token secret, gRPC
-	// connection to KMS, etc).
-	//
-	// For now, we just generate an new RSA keypair and put that in the secret.
-
-	key, err := generateKey(rand.Reader)
-	if err != nil {
-		return nil, fmt.Errorf("cannot generate key: %w", err)
-	}
-
-	jwk := jose.JSONWebKey{
-		Key:       key,
-		KeyID:     "pinniped-supervisor-key",
-		Algorithm: "ES256",
-		Use:       "sig",
-	}
-	jwkData, err := json.Marshal(jwk)
+	activeJWK, err := newSigningJWK(signingConfigFromFederationDomain(federationDomain).algorithm)
 	if err != nil {
-		return nil, fmt.Errorf("cannot marshal jwk: %w", err)
+		return nil, err
 	}
 
-	jwks := jose.JSONWebKeySet{
-		Keys: []jose.JSONWebKey{jwk.Public()},
-	}
-	jwksData, err := json.Marshal(jwks)
+	data, err := jwksSecretData(activeJWK, nil, nil, map[string]jwkTimestamp{
+		activeJWK.KeyID: {CreatedAt: c.clock.Now().UTC()},
+	})
 	if err != nil {
-		return nil, fmt.Errorf("cannot marshal jwks: %w", err)
+		return nil, err
 	}
 
 	s := corev1.Secret{
@@ -237,16 +303,269 @@ func (c *jwksWriterController) generateSecret(federationDomain *supervisorconfig
 				}),
 			},
 		},
-		Data: map[string][]byte{
-			activeJWKKey: jwkData,
-			jwksKey:      jwksData,
-		},
+		Data: data,
 		Type: jwksSecretTypeValue,
 	}
 
 	return &s, nil
 }
 
+// newSigningJWK generates a new private JWK for the algorithm. Its key ID is its RFC 7638 thumbprint, so that the
+// key IDs of the current and retired JWKs never collide.
+func newSigningJWK(algorithm jose.SignatureAlgorithm) (*jose.JSONWebKey, error) {
+	key, err := generateKey(rand.Reader, algorithm)
+	if err != nil {
+		return nil, fmt.Errorf("cannot generate key: %w", err)
+	}
+
+	jwk := jose.JSONWebKey{
+		Key:       key,
+		Algorithm: string(algorithm),
+		Use:       "sig",
+	}
+	thumbprint, err := jwk.Thumbprint(crypto.SHA256)
+	if err != nil {
+		return nil, fmt.Errorf("cannot compute jwk thumbprint: %w", err)
+	}
+	jwk.KeyID = base64.RawURLEncoding.EncodeToString(thumbprint)
+
+	return &jwk, nil
+}
+
+// jwksSecretData returns the Data of a JWKS Secret. The JWKS contains the public key of the active JWK, followed by
+// the public key of the next JWK when there is one, followed by the retired JWKs.
+func jwksSecretData(activeJWK, nextJWK *jose.JSONWebKey, retiredJWKs []jose.JSONWebKey, timestamps map[string]jwkTimestamp) (map[string][]byte, error) {
+	jwkData, err := json.Marshal(activeJWK)
+	if err != nil {
+		return nil, fmt.Errorf("cannot marshal jwk: %w", err)
+	}
+
+	keys := []jose.JSONWebKey{activeJWK.Public()}
+	if nextJWK != nil {
+		keys = append(keys, nextJWK.Public())
+	}
+	jwks := jose.JSONWebKeySet{
+		Keys: append(keys, retiredJWKs...),
+	}
+	jwksData, err := json.Marshal(jwks)
+	if err != nil {
+		return nil, fmt.Errorf("cannot marshal jwks: %w", err)
+	}
+
+	timestampsData, err := json.Marshal(timestamps)
+	if err != nil {
+		return nil, fmt.Errorf("cannot marshal jwk timestamps: %w", err)
+	}
+
+	data := map[string][]byte{
+		activeJWKKey:     jwkData,
+		jwksKey:          jwksData,
+		jwkTimestampsKey: timestampsData,
+	}
+	if nextJWK != nil {
+		nextJWKData, err := json.Marshal(nextJWK)
+		if err != nil {
+			return nil, fmt.Errorf("cannot marshal next jwk: %w", err)
+		}
+		data[nextJWKKey] = nextJWKData
+	}
+	return data, nil
+}
+
+// parseJWKTimestamps returns the timestamps from a JWKS Secret, or an empty map when the Secret does not have them.
+func parseJWKTimestamps(secret *corev1.Secret) map[string]jwkTimestamp {
+	timestamps := map[string]jwkTimestamp{}
+	if data, ok := secret.Data[jwkTimestampsKey]; ok {
+		if err := json.Unmarshal(data, &timestamps); err != nil {
+			plog.Debug("cannot unmarshal jwk timestamps", "err", err)
+			return map[string]jwkTimestamp{}
+		}
+	}
+	return timestamps
+}
+
+// rotateKeys updates the valid JWKS Secret of the FederationDomain when its active JWK should be replaced, or when
+// any of its retired JWKs should no longer be published, and schedules the next sync for when that will next happen.
+func (c *jwksWriterController) rotateKeys(ctx controllerlib.Context, federationDomain *supervisorconfigv1alpha1.FederationDomain) error {
+	secret, err := c.secretInformer.Lister().Secrets(federationDomain.Namespace).Get(federationDomain.Status.Secrets.JWKS.Name)
+	if err != nil {
+		return fmt.Errorf("cannot get secret: %w", err)
+	}
+
+	newData, requeueAfter, err := rotatedJWKSSecretData(secret, signingConfigFromFederationDomain(federationDomain), c.clock.Now().UTC())
+	if err != nil {
+		return fmt.Errorf("cannot rotate keys: %w", err)
+	}
+
+	if newData != nil {
+		updatedSecret := secret.DeepCopy()
+		updatedSecret.Data = newData
+		if _, err := c.kubeClient.CoreV1().Secrets(secret.Namespace).Update(ctx.Context, updatedSecret, metav1.UpdateOptions{}); err != nil {
+			return fmt.Errorf("cannot update secret: %w", err)
+		}
+		plog.Debug("rotated keys in secret", "secret", klog.KObj(secret))
+	} else {
+		plog.Debug(
+			"secret is up to date",
+			"federationdomain",
+			klog.KRef(ctx.Key.Namespace, ctx.Key.Name),
+		)
+	}
+
+	if requeueAfter > 0 {
+		ctx.Queue.AddAfter(ctx.Key, requeueAfter)
+	}
+
+	return nil
+}
+
+// rotatedJWKSSecretData returns the new Data for a valid JWKS Secret, or nil when the Secret does not need to change.
+// It also returns how long from now the Secret will next need to change, or zero when it will not.
+//
+// A new JWK is never made active by the same call which generates it. It is first published in the JWKS as the next
+// JWK, and it only replaces the active JWK once it has been published for the next key publication period, so that
+// clients which cache the JWKS can verify the first ID tokens which it signs.
+func rotatedJWKSSecretData(secret *corev1.Secret, signing signingConfig, now time.Time) (map[string][]byte, time.Duration, error) {
+	var activeJWK jose.JSONWebKey
+	if err := json.Unmarshal(secret.Data[activeJWKKey], &activeJWK); err != nil {
+		return nil, 0, fmt.Errorf("cannot unmarshal active jwk: %w", err)
+	}
+	var jwks jose.JSONWebKeySet
+	if err := json.Unmarshal(secret.Data[jwksKey], &jwks); err != nil {
+		return nil, 0, fmt.Errorf("cannot unmarshal jwks: %w", err)
+	}
+	nextJWK := parseNextJWK(secret, jwks, signing.algorithm)
+	oldTimestamps := parseJWKTimestamps(secret)
+
+	// Fill in any missing timestamps. Secrets from older versions of the Supervisor have a single JWK, which was
+	// created at the same time as the Secret.
+	timestamps := make(map[string]jwkTimestamp, len(jwks.Keys))
+	retiredJWKs := make([]jose.JSONWebKey, 0, len(jwks.Keys))
+	for _, jwk := range jwks.Keys {
+		timestamp, ok := oldTimestamps[jwk.KeyID]
+		if !ok {
+			timestamp = jwkTimestamp{CreatedAt: secret.CreationTimestamp.UTC()}
+		}
+		switch {
+		case jwk.KeyID == activeJWK.KeyID:
+		case nextJWK != nil && jwk.KeyID == nextJWK.KeyID:
+		case ok && timestamp.RetiredAt == nil && timestamp.ActivatedAt == nil:
+			// This was the next JWK, but it can no longer be used, so it was never active and does not need to
+			// be published.
+			continue
+		default:
+			if timestamp.RetiredAt == nil {
+				timestamp.RetiredAt = &now
+			}
+			retiredJWKs = append(retiredJWKs, jwk)
+		}
+		timestamps[jwk.KeyID] = timestamp
+	}
+
+	// replaceAt returns when the active JWK should be replaced, or false when it should not be replaced.
+	replaceAt := func() (time.Time, bool) {
+		if jose.SignatureAlgorithm(activeJWK.Algorithm) != signing.algorithm {
+			return now, true
+		}
+		if signing.rotationPeriod > 0 {
+			return timestamps[activeJWK.KeyID].activatedAt().Add(signing.rotationPeriod), true
+		}
+		return time.Time{}, false
+	}
+	// promoteAt returns when the next JWK should replace the active JWK.
+	promoteAt := func(activeReplaceAt time.Time) time.Time {
+		publishedUntil := timestamps[nextJWK.KeyID].CreatedAt.Add(signing.nextKeyPublication)
+		if publishedUntil.After(activeReplaceAt) {
+			return publishedUntil
+		}
+		return activeReplaceAt
+	}
+
+	// Replace the active JWK with the next JWK once both are due.
+	if activeReplaceAt, ok := replaceAt(); ok && nextJWK != nil && !now.Before(promoteAt(activeReplaceAt)) {
+		retiredTimestamp := timestamps[activeJWK.KeyID]
+		retiredTimestamp.RetiredAt = &now
+		timestamps[activeJWK.KeyID] = retiredTimestamp
+		retiredJWKs = append([]jose.JSONWebKey{activeJWK.Public()}, retiredJWKs...)
+
+		activatedTimestamp := timestamps[nextJWK.KeyID]
+		activatedTimestamp.ActivatedAt = &now
+		timestamps[nextJWK.KeyID] = activatedTimestamp
+		activeJWK = *nextJWK
+		nextJWK = nil
+	}
+
+	// Publish the next JWK ahead of when it will replace the active JWK.
+	activeReplaceAt, replace := replaceAt()
+	if replace && nextJWK == nil && !now.Before(activeReplaceAt.Add(-signing.nextKeyPublication)) {
+		newNextJWK, err := newSigningJWK(signing.algorithm)
+		if err != nil {
+			return nil, 0, err
+		}
+		timestamps[newNextJWK.KeyID] = jwkTimestamp{CreatedAt: now}
+		nextJWK = newNextJWK
+	}
+
+	var requeueAfter time.Duration
+	requeueAt := func(t time.Time) {
+		if d := t.Sub(now); requeueAfter == 0 || d < requeueAfter {
+			requeueAfter = d
+		}
+	}
+	switch {
+	case replace && nextJWK != nil:
+		requeueAt(promoteAt(activeReplaceAt))
+	case replace:
+		requeueAt(activeReplaceAt.Add(-signing.nextKeyPublication))
+	}
+
+	unexpiredRetiredJWKs := make([]jose.JSONWebKey, 0, len(retiredJWKs))
+	for _, jwk := range retiredJWKs {
+		expiresAt := timestamps[jwk.KeyID].RetiredAt.Add(signing.retiredKeyRetention)
+		if !now.Before(expiresAt) {
+			delete(timestamps, jwk.KeyID)
+			continue
+		}
+		unexpiredRetiredJWKs = append(unexpiredRetiredJWKs, jwk)
+		requeueAt(expiresAt)
+	}
+
+	newData, err := jwksSecretData(&activeJWK, nextJWK, unexpiredRetiredJWKs, timestamps)
+	if err != nil {
+		return nil, 0, err
+	}
+	if bytes.Equal(newData[activeJWKKey], secret.Data[activeJWKKey]) &&
+		bytes.Equal(newData[nextJWKKey], secret.Data[nextJWKKey]) &&
+		bytes.Equal(newData[jwksKey], secret.Data[jwksKey]) &&
+		bytes.Equal(newData[jwkTimestampsKey], secret.Data[jwkTimestampsKey]) {
+		return nil, requeueAfter, nil
+	}
+	return newData, requeueAfter, nil
+}
+
+// parseNextJWK returns the next JWK from a JWKS Secret, or nil when the Secret does not have a next JWK which is
+// published in its JWKS and which uses the algorithm.
+func parseNextJWK(secret *corev1.Secret, jwks jose.JSONWebKeySet, algorithm jose.SignatureAlgorithm) *jose.JSONWebKey {
+	data, ok := secret.Data[nextJWKKey]
+	if !ok {
+		return nil
+	}
+	var nextJWK jose.JSONWebKey
+	if err := json.Unmarshal(data, &nextJWK); err != nil {
+		plog.Debug("cannot unmarshal next jwk", "err", err)
+		return nil
+	}
+	if nextJWK.IsPublic() || !nextJWK.Valid() || jose.SignatureAlgorithm(nextJWK.Algorithm) != algorithm {
+		plog.Debug("next jwk cannot be used", "keyid", nextJWK.KeyID)
+		return nil
+	}
+	if len(jwks.Key(nextJWK.KeyID)) == 0 {
+		plog.Debug("did not find next jwk in jwks", "keyid", nextJWK.KeyID)
+		return nil
+	}
+	return &nextJWK
+}
+
 func (c *jwksWriterController) createOrUpdateSecret(
 	ctx context.Context,
 	newSecret *corev1.Secret,
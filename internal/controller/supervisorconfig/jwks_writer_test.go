@@ -7,12 +7,15 @@ import (
 	"bytes"
 	"context"
 	"crypto/x509"
+	"encoding/json"
 	"encoding/pem"
 	"errors"
 	"io"
 	"os"
 	"testing"
+	"time"
 
+	"github.com/go-jose/go-jose/v4"
 	"github.com/stretchr/testify/require"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -21,6 +24,9 @@ import (
 	k8sinformers "k8s.io/client-go/informers"
 	kubefake "k8s.io/client-go/kubernetes/fake"
 	kubetesting "k8s.io/client-go/testing"
+	"k8s.io/utils/clock"
+	clocktesting "k8s.io/utils/clock/testing"
+	"k8s.io/utils/ptr"
 
 	supervisorconfigv1alpha1 "go.pinniped.dev/generated/latest/apis/supervisor/config/v1alpha1"
 	supervisorfake "go.pinniped.dev/generated/latest/client/supervisor/clientset/versioned/fake"
@@ -186,6 +192,7 @@ func TestJWKSWriterControllerFilterSecret(t *testing.T) {
 				secretInformer,
 				federationDomainInformer,
 				withInformer.WithInformer,
+				clock.RealClock{},
 			)
 
 			unrelated := corev1.Secret{}
@@ -239,6 +246,7 @@ func TestJWKSWriterControllerFilterFederationDomain(t *testing.T) {
 				secretInformer,
 				federationDomainInformer,
 				withInformer.WithInformer,
+				clock.RealClock{},
 			)
 
 			unrelated := supervisorconfigv1alpha1.FederationDomain{}
@@ -254,9 +262,13 @@ func TestJWKSWriterControllerFilterFederationDomain(t *testing.T) {
 
 func TestJWKSWriterControllerSync(t *testing.T) {
 	// We shouldn't run this test in parallel since it messes with a global function (generateKey).
+	originalGenerateKey := generateKey
+	t.Cleanup(func() { generateKey = originalGenerateKey })
 
 	const namespace = "tuna-namespace"
 
+	frozenNow := time.Date(2026, 3, 4, 5, 6, 7, 0, time.UTC)
+
 	goodKeyPEM, err := os.ReadFile("testdata/good-ec-key.pem")
 	require.NoError(t, err)
 	block, _ := pem.Decode(goodKeyPEM)
@@ -321,7 +333,28 @@ func TestJWKSWriterControllerSync(t *testing.T) {
 		return &s
 	}
 
-	goodSecret := newSecret("testdata/good-jwk.json", "testdata/good-jwks.json")
+	goodSecret := newSecret("testdata/good-thumbprint-jwk.json", "testdata/good-thumbprint-jwks.json")
+	goodSecret.Data["jwkTimestamps"] = []byte(`{"r0uJ_lrwjnH59NFsXiEPsUlxbhZ_LYNCdrFWuKnRpec":{"createdAt":"2026-03-04T05:06:07Z"}}`)
+
+	// Secrets written by older versions of the Supervisor have a single JWK with a fixed key ID and no timestamps.
+	legacySecret := newSecret("testdata/good-jwk.json", "testdata/good-jwks.json")
+	legacySecret.CreationTimestamp = metav1.NewTime(frozenNow.Add(-time.Hour))
+
+	upgradedLegacySecret := legacySecret.DeepCopy()
+	upgradedLegacySecret.Data["jwkTimestamps"] = []byte(`{"pinniped-supervisor-key":{"createdAt":"2026-03-04T04:06:07Z"}}`)
+
+	goodFederationDomainWithRotation := goodFederationDomainWithStatus.DeepCopy()
+	goodFederationDomainWithRotation.Spec.Signing = &supervisorconfigv1alpha1.FederationDomainSigning{
+		RotationPeriodSeconds: ptr.To[int32](3600),
+	}
+
+	// The legacy JWK is due for rotation, so the generated JWK is published as the next JWK, but it is not active yet.
+	rotatingLegacySecret := legacySecret.DeepCopy()
+	rotatingLegacySecret.Data["nextJWK"] = readJWKJSON(t, "testdata/good-thumbprint-jwk.json")
+	rotatingLegacySecret.Data["jwks"] = readJWKJSON(t, "testdata/good-jwks-with-next-thumbprint-jwk.json")
+	rotatingLegacySecret.Data["jwkTimestamps"] = []byte(`{` +
+		`"pinniped-supervisor-key":{"createdAt":"2026-03-04T04:06:07Z"},` +
+		`"r0uJ_lrwjnH59NFsXiEPsUlxbhZ_LYNCdrFWuKnRpec":{"createdAt":"2026-03-04T05:06:07Z"}}`)
 
 	secretWithWrongType := newSecret("testdata/good-jwk.json", "testdata/good-jwks.json")
 	secretWithWrongType.Type = "not-the-right-type"
@@ -398,6 +431,35 @@ func TestJWKSWriterControllerSync(t *testing.T) {
 				goodSecret,
 			},
 		},
+		{
+			name: "existing federationDomain with legacy secret",
+			key:  controllerlib.Key{Namespace: goodFederationDomain.Namespace, Name: goodFederationDomain.Name},
+			federationDomains: []*supervisorconfigv1alpha1.FederationDomain{
+				goodFederationDomainWithStatus,
+			},
+			secrets: []*corev1.Secret{
+				legacySecret,
+			},
+			wantSecretActions: []kubetesting.Action{
+				kubetesting.NewUpdateAction(secretGVR, namespace, upgradedLegacySecret),
+			},
+			wantFederationDomainActions: []kubetesting.Action{},
+		},
+		{
+			name: "existing federationDomain with legacy secret which is due for rotation",
+			key:  controllerlib.Key{Namespace: goodFederationDomain.Namespace, Name: goodFederationDomain.Name},
+			federationDomains: []*supervisorconfigv1alpha1.FederationDomain{
+				goodFederationDomainWithRotation,
+			},
+			secrets: []*corev1.Secret{
+				legacySecret,
+			},
+			wantGenerateKeyCount: 1,
+			wantSecretActions: []kubetesting.Action{
+				kubetesting.NewUpdateAction(secretGVR, namespace, rotatingLegacySecret),
+			},
+			wantFederationDomainActions: []kubetesting.Action{},
+		},
 		{
 			name: "deleted federationDomain",
 			key:  controllerlib.Key{Namespace: goodFederationDomain.Namespace, Name: goodFederationDomain.Name},
@@ -665,7 +727,7 @@ func TestJWKSWriterControllerSync(t *testing.T) {
 		t.Run(test.name, func(t *testing.T) {
 			// We shouldn't run this test in parallel since it messes with a global function (generateKey).
 			generateKeyCount := 0
-			generateKey = func(_ io.Reader) (any, error) {
+			generateKey = func(_ io.Reader, _ jose.SignatureAlgorithm) (any, error) {
 				generateKeyCount++
 				return goodKey, test.generateKeyErr
 			}
@@ -712,6 +774,7 @@ func TestJWKSWriterControllerSync(t *testing.T) {
 				kubeInformers.Core().V1().Secrets(),
 				pinnipedInformers.Config().V1alpha1().FederationDomains(),
 				controllerlib.WithInformer,
+				clocktesting.NewFakeClock(frozenNow),
 			)
 
 			// Must start informers before calling TestRunSynchronously().
@@ -722,6 +785,7 @@ func TestJWKSWriterControllerSync(t *testing.T) {
 			err := controllerlib.TestSync(t, c, controllerlib.Context{
 				Context: ctx,
 				Key:     test.key,
+				Queue:   &testQueue{t: t},
 			})
 			if test.wantError != "" {
 				require.EqualError(t, err, test.wantError)
@@ -741,6 +805,494 @@ func TestJWKSWriterControllerSync(t *testing.T) {
 	}
 }
 
+func TestRotatedJWKSSecretData(t *testing.T) {
+	now := time.Date(2026, 3, 4, 5, 6, 7, 0, time.UTC)
+	at := func(d time.Duration) *time.Time {
+		t := now.Add(d)
+		return &t
+	}
+
+	newJWK := func(algorithm jose.SignatureAlgorithm) *jose.JSONWebKey {
+		jwk, err := newSigningJWK(algorithm)
+		require.NoError(t, err)
+		return jwk
+	}
+	activeJWK := newJWK(jose.ES256)
+	nextJWK := newJWK(jose.ES256)
+	nextRS256JWK := newJWK(jose.RS256)
+	retiredJWK := newJWK(jose.ES256)
+
+	newSecret := func(nextJWK *jose.JSONWebKey, retiredJWKs []jose.JSONWebKey, timestamps map[string]jwkTimestamp) *corev1.Secret {
+		data, err := jwksSecretData(activeJWK, nextJWK, retiredJWKs, timestamps)
+		require.NoError(t, err)
+		if timestamps == nil {
+			delete(data, jwkTimestampsKey)
+		}
+		return &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{CreationTimestamp: metav1.NewTime(now.Add(-time.Hour))},
+			Data:       data,
+		}
+	}
+	signingWithRotationPeriod := func(rotationPeriod time.Duration) signingConfig {
+		return signingConfig{
+			algorithm:           jose.ES256,
+			rotationPeriod:      rotationPeriod,
+			retiredKeyRetention: defaultRetiredKeyRetention,
+			nextKeyPublication:  defaultNextKeyPublication,
+		}
+	}
+
+	defaultSigning := signingWithRotationPeriod(0)
+	rs256Signing := defaultSigning
+	rs256Signing.algorithm = jose.RS256
+
+	tests := []struct {
+		name              string
+		secret            *corev1.Secret
+		signing           signingConfig
+		wantUnchanged     bool
+		wantActiveKID     string
+		wantNextKID       string // only checked when the next JWK was not generated
+		wantNewNextJWK    jose.SignatureAlgorithm
+		wantRetiredKIDs   []string
+		wantTimestamps    map[string]jwkTimestamp // the timestamp of a newly generated next JWK is checked separately
+		wantRequeueAfter  time.Duration
+		wantNoNextJWKData bool
+	}{
+		{
+			name:            "legacy secret without timestamps gets timestamps from the secret creation time",
+			secret:          newSecret(nil, nil, nil),
+			signing:         defaultSigning,
+			wantActiveKID:   activeJWK.KeyID,
+			wantRetiredKIDs: []string{},
+			wantTimestamps: map[string]jwkTimestamp{
+				activeJWK.KeyID: {CreatedAt: now.Add(-time.Hour)},
+			},
+		},
+		{
+			name:          "up to date secret without rotation",
+			secret:        newSecret(nil, nil, map[string]jwkTimestamp{activeJWK.KeyID: {CreatedAt: now.Add(-time.Hour)}}),
+			signing:       defaultSigning,
+			wantUnchanged: true,
+		},
+		{
+			name:             "next key publication period before the rotation has not started yet",
+			secret:           newSecret(nil, nil, map[string]jwkTimestamp{activeJWK.KeyID: {CreatedAt: now.Add(-time.Hour)}}),
+			signing:          signingWithRotationPeriod(3 * time.Hour),
+			wantUnchanged:    true,
+			wantRequeueAfter: time.Hour,
+		},
+		{
+			name:            "next key publication period before the rotation has started",
+			secret:          newSecret(nil, nil, map[string]jwkTimestamp{activeJWK.KeyID: {CreatedAt: now.Add(-150 * time.Minute)}}),
+			signing:         signingWithRotationPeriod(3 * time.Hour),
+			wantActiveKID:   activeJWK.KeyID,
+			wantNewNextJWK:  jose.ES256,
+			wantRetiredKIDs: []string{},
+			wantTimestamps: map[string]jwkTimestamp{
+				activeJWK.KeyID: {CreatedAt: now.Add(-150 * time.Minute)},
+			},
+			wantRequeueAfter: time.Hour, // the next key must be published for the full period, even if the rotation is late
+		},
+		{
+			name:            "rotation period has elapsed without a next key",
+			secret:          newSecret(nil, nil, map[string]jwkTimestamp{activeJWK.KeyID: {CreatedAt: now.Add(-time.Hour)}}),
+			signing:         signingWithRotationPeriod(time.Hour),
+			wantActiveKID:   activeJWK.KeyID,
+			wantNewNextJWK:  jose.ES256,
+			wantRetiredKIDs: []string{},
+			wantTimestamps: map[string]jwkTimestamp{
+				activeJWK.KeyID: {CreatedAt: now.Add(-time.Hour)},
+			},
+			wantRequeueAfter: defaultNextKeyPublication,
+		},
+		{
+			name: "next key has not been published for long enough",
+			secret: newSecret(nextJWK, nil, map[string]jwkTimestamp{
+				activeJWK.KeyID: {CreatedAt: now.Add(-2 * time.Hour)},
+				nextJWK.KeyID:   {CreatedAt: now.Add(-30 * time.Minute)},
+			}),
+			signing:          signingWithRotationPeriod(time.Hour),
+			wantUnchanged:    true,
+			wantRequeueAfter: 30 * time.Minute,
+		},
+		{
+			name: "next key has been published for long enough but the rotation period has not elapsed yet",
+			secret: newSecret(nextJWK, nil, map[string]jwkTimestamp{
+				activeJWK.KeyID: {CreatedAt: now.Add(-150 * time.Minute)},
+				nextJWK.KeyID:   {CreatedAt: now.Add(-time.Hour)},
+			}),
+			signing:          signingWithRotationPeriod(3 * time.Hour),
+			wantUnchanged:    true,
+			wantRequeueAfter: 30 * time.Minute,
+		},
+		{
+			name: "next key has been published for long enough and the rotation period has elapsed",
+			secret: newSecret(nextJWK, nil, map[string]jwkTimestamp{
+				activeJWK.KeyID: {CreatedAt: now.Add(-3 * time.Hour)},
+				nextJWK.KeyID:   {CreatedAt: now.Add(-time.Hour)},
+			}),
+			signing:         signingWithRotationPeriod(3 * time.Hour),
+			wantActiveKID:   nextJWK.KeyID,
+			wantRetiredKIDs: []string{activeJWK.KeyID},
+			wantTimestamps: map[string]jwkTimestamp{
+				activeJWK.KeyID: {CreatedAt: now.Add(-3 * time.Hour), RetiredAt: at(0)},
+				nextJWK.KeyID:   {CreatedAt: now.Add(-time.Hour), ActivatedAt: at(0)},
+			},
+			wantRequeueAfter:  2 * time.Hour,
+			wantNoNextJWKData: true,
+		},
+		{
+			name: "rotation period is measured from when the active key was activated",
+			secret: newSecret(nil, nil, map[string]jwkTimestamp{
+				activeJWK.KeyID: {CreatedAt: now.Add(-4 * time.Hour), ActivatedAt: at(-time.Hour)},
+			}),
+			signing:          signingWithRotationPeriod(3 * time.Hour),
+			wantUnchanged:    true,
+			wantRequeueAfter: time.Hour,
+		},
+		{
+			name:            "algorithm has changed",
+			secret:          newSecret(nil, nil, map[string]jwkTimestamp{activeJWK.KeyID: {CreatedAt: now.Add(-time.Hour)}}),
+			signing:         rs256Signing,
+			wantActiveKID:   activeJWK.KeyID,
+			wantNewNextJWK:  jose.RS256,
+			wantRetiredKIDs: []string{},
+			wantTimestamps: map[string]jwkTimestamp{
+				activeJWK.KeyID: {CreatedAt: now.Add(-time.Hour)},
+			},
+			wantRequeueAfter: defaultNextKeyPublication,
+		},
+		{
+			name: "algorithm has changed while a next key for the previous algorithm was published",
+			secret: newSecret(nextJWK, nil, map[string]jwkTimestamp{
+				activeJWK.KeyID: {CreatedAt: now.Add(-2 * time.Hour)},
+				nextJWK.KeyID:   {CreatedAt: now.Add(-30 * time.Minute)},
+			}),
+			signing:         rs256Signing,
+			wantActiveKID:   activeJWK.KeyID,
+			wantNewNextJWK:  jose.RS256,
+			wantRetiredKIDs: []string{},
+			wantTimestamps: map[string]jwkTimestamp{
+				activeJWK.KeyID: {CreatedAt: now.Add(-2 * time.Hour)},
+			},
+			wantRequeueAfter: defaultNextKeyPublication,
+		},
+		{
+			name: "next key for the changed algorithm has been published for long enough",
+			secret: newSecret(nextRS256JWK, nil, map[string]jwkTimestamp{
+				activeJWK.KeyID:    {CreatedAt: now.Add(-2 * time.Hour)},
+				nextRS256JWK.KeyID: {CreatedAt: now.Add(-time.Hour)},
+			}),
+			signing:         rs256Signing,
+			wantActiveKID:   nextRS256JWK.KeyID,
+			wantRetiredKIDs: []string{activeJWK.KeyID},
+			wantTimestamps: map[string]jwkTimestamp{
+				activeJWK.KeyID:    {CreatedAt: now.Add(-2 * time.Hour), RetiredAt: at(0)},
+				nextRS256JWK.KeyID: {CreatedAt: now.Add(-time.Hour), ActivatedAt: at(0)},
+			},
+			wantRequeueAfter:  defaultRetiredKeyRetention,
+			wantNoNextJWKData: true,
+		},
+		{
+			name: "retired key is still within its retention",
+			secret: newSecret(nil, []jose.JSONWebKey{retiredJWK.Public()}, map[string]jwkTimestamp{
+				activeJWK.KeyID:  {CreatedAt: now.Add(-time.Hour)},
+				retiredJWK.KeyID: {CreatedAt: now.Add(-48 * time.Hour), RetiredAt: at(-time.Hour)},
+			}),
+			signing:          defaultSigning,
+			wantUnchanged:    true,
+			wantRequeueAfter: 23 * time.Hour,
+		},
+		{
+			name: "retired key without a retirement time is retired now",
+			secret: newSecret(nil, []jose.JSONWebKey{retiredJWK.Public()}, map[string]jwkTimestamp{
+				activeJWK.KeyID: {CreatedAt: now.Add(-time.Hour)},
+			}),
+			signing:         defaultSigning,
+			wantActiveKID:   activeJWK.KeyID,
+			wantRetiredKIDs: []string{retiredJWK.KeyID},
+			wantTimestamps: map[string]jwkTimestamp{
+				activeJWK.KeyID:  {CreatedAt: now.Add(-time.Hour)},
+				retiredJWK.KeyID: {CreatedAt: now.Add(-time.Hour), RetiredAt: at(0)},
+			},
+			wantRequeueAfter: defaultRetiredKeyRetention,
+		},
+		{
+			name: "retired key has passed its retention",
+			secret: newSecret(nil, []jose.JSONWebKey{retiredJWK.Public()}, map[string]jwkTimestamp{
+				activeJWK.KeyID:  {CreatedAt: now.Add(-time.Hour)},
+				retiredJWK.KeyID: {CreatedAt: now.Add(-48 * time.Hour), RetiredAt: at(-24 * time.Hour)},
+			}),
+			signing:         defaultSigning,
+			wantActiveKID:   activeJWK.KeyID,
+			wantRetiredKIDs: []string{},
+			wantTimestamps: map[string]jwkTimestamp{
+				activeJWK.KeyID: {CreatedAt: now.Add(-time.Hour)},
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			data, requeueAfter, err := rotatedJWKSSecretData(test.secret, test.signing, now)
+			require.NoError(t, err)
+			require.Equal(t, test.wantRequeueAfter, requeueAfter)
+
+			if test.wantUnchanged {
+				require.Nil(t, data)
+				return
+			}
+			require.NotNil(t, data)
+
+			var gotActiveJWK jose.JSONWebKey
+			require.NoError(t, json.Unmarshal(data[activeJWKKey], &gotActiveJWK))
+			require.Equal(t, test.wantActiveKID, gotActiveJWK.KeyID)
+
+			var gotJWKS jose.JSONWebKeySet
+			require.NoError(t, json.Unmarshal(data[jwksKey], &gotJWKS))
+			require.NotEmpty(t, gotJWKS.Keys)
+			require.Equal(t, gotActiveJWK.KeyID, gotJWKS.Keys[0].KeyID, "the active JWK should be first in the JWKS")
+			gotRetiredJWKs := gotJWKS.Keys[1:]
+
+			var gotTimestamps map[string]jwkTimestamp
+			require.NoError(t, json.Unmarshal(data[jwkTimestampsKey], &gotTimestamps))
+
+			if test.wantNewNextJWK != "" {
+				var gotNextJWK jose.JSONWebKey
+				require.NoError(t, json.Unmarshal(data[nextJWKKey], &gotNextJWK))
+				require.False(t, gotNextJWK.IsPublic())
+				require.Equal(t, string(test.wantNewNextJWK), gotNextJWK.Algorithm)
+				require.NotContains(t, []string{activeJWK.KeyID, nextJWK.KeyID, nextRS256JWK.KeyID}, gotNextJWK.KeyID)
+				require.Equal(t, gotNextJWK.KeyID, gotJWKS.Keys[1].KeyID, "the next JWK should follow the active JWK in the JWKS")
+				require.Equal(t, jwkTimestamp{CreatedAt: now}, gotTimestamps[gotNextJWK.KeyID])
+				delete(gotTimestamps, gotNextJWK.KeyID)
+				gotRetiredJWKs = gotJWKS.Keys[2:]
+			} else if test.wantNoNextJWKData {
+				require.NotContains(t, data, nextJWKKey)
+			}
+
+			gotRetiredKIDs := []string{}
+			for _, jwk := range gotRetiredJWKs {
+				require.True(t, jwk.IsPublic())
+				gotRetiredKIDs = append(gotRetiredKIDs, jwk.KeyID)
+			}
+			require.Equal(t, test.wantRetiredKIDs, gotRetiredKIDs)
+			require.Equal(t, test.wantTimestamps, gotTimestamps)
+		})
+	}
+}
+
+func TestRotatedJWKSSecretDataNeverActivatesANewJWKInTheSyncWhichCreatesIt(t *testing.T) {
+	start := time.Date(2026, 3, 4, 5, 6, 7, 0, time.UTC)
+
+	tests := []struct {
+		name               string
+		rotationPeriod     time.Duration
+		nextKeyPublication time.Duration
+		changeAlgorithmAt  time.Duration
+	}{
+		{
+			name:               "rotation period is longer than the next key publication period",
+			rotationPeriod:     3 * time.Hour,
+			nextKeyPublication: time.Hour,
+		},
+		{
+			name:               "rotation period is as long as the next key publication period",
+			rotationPeriod:     time.Hour,
+			nextKeyPublication: time.Hour,
+		},
+		{
+			name:               "rotation period is shorter than the next key publication period",
+			rotationPeriod:     time.Hour,
+			nextKeyPublication: 2 * time.Hour,
+		},
+		{
+			name:               "algorithm changes without a rotation period",
+			nextKeyPublication: time.Hour,
+			changeAlgorithmAt:  5 * time.Hour,
+		},
+		{
+			name:               "algorithm changes while the next key is published",
+			rotationPeriod:     3 * time.Hour,
+			nextKeyPublication: time.Hour,
+			changeAlgorithmAt:  150 * time.Minute,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			activeJWK, err := newSigningJWK(jose.ES256)
+			require.NoError(t, err)
+			data, err := jwksSecretData(activeJWK, nil, nil, map[string]jwkTimestamp{
+				activeJWK.KeyID: {CreatedAt: start},
+			})
+			require.NoError(t, err)
+			secret := &corev1.Secret{Data: data}
+
+			signing := signingConfig{
+				algorithm:           jose.ES256,
+				rotationPeriod:      test.rotationPeriod,
+				retiredKeyRetention: defaultRetiredKeyRetention,
+				nextKeyPublication:  test.nextKeyPublication,
+			}
+
+			// Remember when each JWK was first published, and sync every few minutes for a few days.
+			publishedAt := map[string]time.Time{activeJWK.KeyID: start}
+			activeKID := activeJWK.KeyID
+			activations := 0
+			for elapsed := time.Duration(0); elapsed <= 72*time.Hour; elapsed += 7 * time.Minute {
+				now := start.Add(elapsed)
+				if test.changeAlgorithmAt > 0 && elapsed >= test.changeAlgorithmAt {
+					signing.algorithm = jose.RS256
+				}
+
+				newData, _, err := rotatedJWKSSecretData(secret, signing, now)
+				require.NoError(t, err)
+				if newData == nil {
+					continue
+				}
+				secret.Data = newData
+
+				var gotActiveJWK jose.JSONWebKey
+				require.NoError(t, json.Unmarshal(newData[activeJWKKey], &gotActiveJWK))
+				if gotActiveJWK.KeyID != activeKID {
+					published, ok := publishedAt[gotActiveJWK.KeyID]
+					require.True(t, ok, "JWK %s became active in the sync which created it", gotActiveJWK.KeyID)
+					require.GreaterOrEqual(t, now.Sub(published), test.nextKeyPublication,
+						"JWK %s became active before it was published for long enough", gotActiveJWK.KeyID)
+					activeKID = gotActiveJWK.KeyID
+					activations++
+				}
+
+				var gotJWKS jose.JSONWebKeySet
+				require.NoError(t, json.Unmarshal(newData[jwksKey], &gotJWKS))
+				for _, jwk := range gotJWKS.Keys {
+					if _, ok := publishedAt[jwk.KeyID]; !ok {
+						publishedAt[jwk.KeyID] = now
+					}
+				}
+			}
+
+			require.Positive(t, activations)
+			if test.changeAlgorithmAt > 0 {
+				var gotActiveJWK jose.JSONWebKey
+				require.NoError(t, json.Unmarshal(secret.Data[activeJWKKey], &gotActiveJWK))
+				require.Equal(t, string(jose.RS256), gotActiveJWK.Algorithm)
+			}
+		})
+	}
+}
+
+func TestJWKSWriterControllerSyncRequeuesKeyRotation(t *testing.T) {
+	const namespace = "tuna-namespace"
+
+	now := time.Date(2026, 3, 4, 5, 6, 7, 0, time.UTC)
+
+	activeJWK, err := newSigningJWK(jose.ES256)
+	require.NoError(t, err)
+	data, err := jwksSecretData(activeJWK, nil, nil, map[string]jwkTimestamp{
+		activeJWK.KeyID: {CreatedAt: now.Add(-2 * time.Hour)},
+	})
+	require.NoError(t, err)
+
+	federationDomain := &supervisorconfigv1alpha1.FederationDomain{
+		ObjectMeta: metav1.ObjectMeta{Name: "some-federation-domain", Namespace: namespace, UID: "some-uid"},
+		Spec: supervisorconfigv1alpha1.FederationDomainSpec{
+			Issuer: "https://some-issuer.com",
+			Signing: &supervisorconfigv1alpha1.FederationDomainSigning{
+				RotationPeriodSeconds: ptr.To[int32](3600),
+			},
+		},
+		Status: supervisorconfigv1alpha1.FederationDomainStatus{
+			Secrets: supervisorconfigv1alpha1.FederationDomainSecrets{
+				JWKS: corev1.LocalObjectReference{Name: "some-jwks-secret"},
+			},
+		},
+	}
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "some-jwks-secret",
+			Namespace: namespace,
+			OwnerReferences: []metav1.OwnerReference{
+				{
+					APIVersion: supervisorconfigv1alpha1.SchemeGroupVersion.String(),
+					Kind:       "FederationDomain",
+					Name:       federationDomain.Name,
+					UID:        federationDomain.UID,
+					Controller: boolPtr(true),
+				},
+			},
+		},
+		Type: "secrets.pinniped.dev/federation-domain-jwks",
+		Data: data,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	kubeAPIClient := kubefake.NewClientset(secret)
+	kubeInformerClient := kubefake.NewClientset(secret)
+	pinnipedAPIClient := supervisorfake.NewSimpleClientset(federationDomain)
+	pinnipedInformerClient := supervisorfake.NewSimpleClientset(federationDomain)
+	kubeInformers := k8sinformers.NewSharedInformerFactory(kubeInformerClient, 0)
+	pinnipedInformers := supervisorinformers.NewSharedInformerFactory(pinnipedInformerClient, 0)
+
+	c := NewJWKSWriterController(
+		nil,
+		kubeAPIClient,
+		pinnipedAPIClient,
+		kubeInformers.Core().V1().Secrets(),
+		pinnipedInformers.Config().V1alpha1().FederationDomains(),
+		controllerlib.WithInformer,
+		clocktesting.NewFakeClock(now),
+	)
+
+	kubeInformers.Start(ctx.Done())
+	pinnipedInformers.Start(ctx.Done())
+	controllerlib.TestRunSynchronously(t, c)
+
+	key := controllerlib.Key{Namespace: namespace, Name: federationDomain.Name}
+	queue := &testQueue{t: t}
+	require.NoError(t, controllerlib.TestSync(t, c, controllerlib.Context{Context: ctx, Key: key, Queue: queue}))
+
+	// The active JWK was due for rotation, so a new JWK was published as the next JWK, and it will become active
+	// once it has been published for the default next key publication period of one hour.
+	require.True(t, queue.called)
+	require.Equal(t, key, queue.key)
+	require.Equal(t, time.Hour, queue.duration)
+
+	require.Len(t, kubeAPIClient.Actions(), 1)
+	updatedSecret := kubeAPIClient.Actions()[0].(kubetesting.UpdateAction).GetObject().(*corev1.Secret)
+	require.Equal(t, secret.Data[activeJWKKey], updatedSecret.Data[activeJWKKey])
+	var gotNextJWK jose.JSONWebKey
+	require.NoError(t, json.Unmarshal(updatedSecret.Data[nextJWKKey], &gotNextJWK))
+	var gotJWKS jose.JSONWebKeySet
+	require.NoError(t, json.Unmarshal(updatedSecret.Data[jwksKey], &gotJWKS))
+	require.Len(t, gotJWKS.Keys, 2)
+	require.Equal(t, activeJWK.KeyID, gotJWKS.Keys[0].KeyID)
+	require.Equal(t, gotNextJWK.KeyID, gotJWKS.Keys[1].KeyID)
+	require.NotEqual(t, activeJWK.KeyID, gotNextJWK.KeyID)
+}
+
+type testQueue struct {
+	t *testing.T
+
+	called   bool
+	key      controllerlib.Key
+	duration time.Duration
+
+	controllerlib.Queue // panic if any other methods called
+}
+
+func (q *testQueue) AddAfter(key controllerlib.Key, duration time.Duration) {
+	q.t.Helper()
+
+	require.False(q.t, q.called, "AddAfter should only be called once")
+
+	q.called = true
+	q.key = key
+	q.duration = duration
+}
+
 func readJWKJSON(t *testing.T, path string) []byte {
 	t.Helper()
 
@@ -0,0 +1,450 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package oauth2upstreamwatcher implements a controller which watches OAuth2IdentityProviders.
+package oauth2upstreamwatcher
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"golang.org/x/oauth2"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	corev1informers "k8s.io/client-go/informers/core/v1"
+	"k8s.io/utils/clock"
+
+	idpv1alpha1 "go.pinniped.dev/generated/latest/apis/supervisor/idp/v1alpha1"
+	supervisorclientset "go.pinniped.dev/generated/latest/client/supervisor/clientset/versioned"
+	idpinformers "go.pinniped.dev/generated/latest/client/supervisor/informers/externalversions/idp/v1alpha1"
+	"go.pinniped.dev/internal/celtransformer"
+	pinnipedcontroller "go.pinniped.dev/internal/controller"
+	"go.pinniped.dev/internal/controller/conditionsutil"
+	"go.pinniped.dev/internal/controller/supervisorconfig/upstreamwatchers"
+	"go.pinniped.dev/internal/controller/tlsconfigutil"
+	"go.pinniped.dev/internal/controllerlib"
+	"go.pinniped.dev/internal/federationdomain/upstreamprovider"
+	"go.pinniped.dev/internal/net/phttp"
+	"go.pinniped.dev/internal/plog"
+	"go.pinniped.dev/internal/upstreamoauth2"
+)
+
+const (
+	controllerName = "oauth2-upstream-observer"
+
+	// Constants related to the client credentials Secret.
+	oauth2ClientSecretType               corev1.SecretType = "secrets.pinniped.dev/oauth2-client"
+	clientIDDataKey, clientSecretDataKey string            = "clientID", "clientSecret"
+
+	countExpectedConditions = 5
+
+	ClientCredentialsSecretValid       string = "ClientCredentialsSecretValid" //nolint:gosec // this is not a credential
+	EndpointsValid                     string = "EndpointsValid"
+	TLSConfigurationValid              string = "TLSConfigurationValid"
+	ClaimsValid                        string = "ClaimsValid"
+	AdditionalAuthorizeParametersValid string = "AdditionalAuthorizeParametersValid"
+
+	reasonInvalid                 = "Invalid"
+	reasonInvalidURL              = "InvalidURL"
+	reasonDisallowedParameterName = "DisallowedParameterName"
+
+	celMaxExpressionRuntime = 5 * time.Second
+)
+
+var (
+	disallowedAdditionalAuthorizeParameters = map[string]bool{ //nolint:gochecknoglobals
+		// Reject these AdditionalAuthorizeParameters to avoid allowing the user's config to overwrite the parameters
+		// that are always used by Pinniped in authcode authorization requests. Users can already set the "scope"
+		// param using another setting, and the others never make sense to override. This map should be treated
+		// as read-only since it is a global variable.
+		"response_type":         true,
+		"scope":                 true,
+		"client_id":             true,
+		"state":                 true,
+		"code_challenge":        true,
+		"code_challenge_method": true,
+		"redirect_uri":          true,
+	}
+)
+
+// UpstreamOAuth2IdentityProviderICache is a thread safe cache that holds a list of validated upstream OAuth2 IDP configurations.
+type UpstreamOAuth2IdentityProviderICache interface {
+	SetOAuth2IdentityProviders([]upstreamprovider.UpstreamOAuth2IdentityProviderI)
+}
+
+type oauth2WatcherController struct {
+	namespace                      string
+	cache                          UpstreamOAuth2IdentityProviderICache
+	log                            plog.Logger
+	client                         supervisorclientset.Interface
+	oauth2IdentityProviderInformer idpinformers.OAuth2IdentityProviderInformer
+	secretInformer                 corev1informers.SecretInformer
+	configMapInformer              corev1informers.ConfigMapInformer
+	clock                          clock.Clock
+	celTransformer                 *celtransformer.CELTransformer
+}
+
+// New instantiates a new controllerlib.Controller which will populate the provided UpstreamOAuth2IdentityProviderICache.
+func New(
+	namespace string,
+	idpCache UpstreamOAuth2IdentityProviderICache,
+	client supervisorclientset.Interface,
+	oauth2IdentityProviderInformer idpinformers.OAuth2IdentityProviderInformer,
+	secretInformer corev1informers.SecretInformer,
+	configMapInformer corev1informers.ConfigMapInformer,
+	log plog.Logger,
+	withInformer pinnipedcontroller.WithInformerOptionFunc,
+	clock clock.Clock,
+) controllerlib.Controller {
+	c := oauth2WatcherController{
+		namespace:                      namespace,
+		cache:                          idpCache,
+		client:                         client,
+		log:                            log.WithName(controllerName),
+		oauth2IdentityProviderInformer: oauth2IdentityProviderInformer,
+		secretInformer:                 secretInformer,
+		configMapInformer:              configMapInformer,
+		clock:                          clock,
+	}
+
+	return controllerlib.New(
+		controllerlib.Config{Name: controllerName, Syncer: &c},
+		withInformer(
+			oauth2IdentityProviderInformer,
+			pinnipedcontroller.MatchAnythingFilter(pinnipedcontroller.SingletonQueue()),
+			controllerlib.InformerOption{},
+		),
+		withInformer(
+			secretInformer,
+			pinnipedcontroller.MatchAnySecretOfTypesFilter(
+				[]corev1.SecretType{
+					oauth2ClientSecretType,
+					corev1.SecretTypeOpaque,
+					corev1.SecretTypeTLS,
+				},
+				pinnipedcontroller.SingletonQueue(),
+			),
+			controllerlib.InformerOption{},
+		),
+		withInformer(
+			configMapInformer,
+			pinnipedcontroller.MatchAnythingFilter(pinnipedcontroller.SingletonQueue()),
+			controllerlib.InformerOption{},
+		),
+	)
+}
+
+// Sync implements controllerlib.Syncer.
+func (c *oauth2WatcherController) Sync(ctx controllerlib.Context) error {
+	actualUpstreams, err := c.oauth2IdentityProviderInformer.Lister().List(labels.Everything())
+	if err != nil { // untested
+		return fmt.Errorf("failed to list OAuth2IdentityProviders: %w", err)
+	}
+
+	if c.celTransformer == nil {
+		c.celTransformer, err = celtransformer.NewCELTransformer(celMaxExpressionRuntime)
+		if err != nil {
+			return err // shouldn't really happen
+		}
+	}
+
+	// Sort them by name just so that the logs output is consistent
+	slices.SortStableFunc(actualUpstreams, func(a, b *idpv1alpha1.OAuth2IdentityProvider) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	var applicationErrors []error
+	validatedUpstreams := make([]upstreamprovider.UpstreamOAuth2IdentityProviderI, 0, len(actualUpstreams))
+	for _, upstream := range actualUpstreams {
+		validatedUpstream, applicationErr := c.validateUpstreamAndUpdateConditions(ctx.Context, upstream)
+		if applicationErr != nil {
+			applicationErrors = append(applicationErrors, applicationErr)
+		} else if validatedUpstream != nil {
+			validatedUpstreams = append(validatedUpstreams, validatedUpstream)
+		}
+		// Else:
+		// If both validatedUpstream and applicationErr are nil, this must be because the upstream had configuration errors.
+		// This controller should take no action until the user has reconfigured the upstream.
+	}
+	c.cache.SetOAuth2IdentityProviders(validatedUpstreams)
+
+	// If we have recoverable application errors, let's do a requeue and capture all the applicationErrors too
+	if len(applicationErrors) > 0 {
+		applicationErrors = append([]error{controllerlib.ErrSyntheticRequeue}, applicationErrors...)
+	}
+
+	return utilerrors.NewAggregate(applicationErrors)
+}
+
+func (c *oauth2WatcherController) validateUpstreamAndUpdateConditions(ctx context.Context, upstream *idpv1alpha1.OAuth2IdentityProvider) (
+	*upstreamoauth2.Provider, // If validated, returns the config
+	error, // This error will only refer to programmatic errors such as inability to update the status, not configuration errors
+) {
+	conditions := make([]*metav1.Condition, 0, countExpectedConditions)
+	applicationErrors := make([]error, 0)
+
+	clientSecretCondition, clientID, clientSecret, clientSecretErr := c.validateClientSecret(upstream.Spec.Client.SecretName)
+	conditions = append(conditions, clientSecretCondition)
+	if clientSecretErr != nil { // untested
+		applicationErrors = append(applicationErrors, clientSecretErr)
+	}
+
+	endpointsCondition := validateEndpoints(&upstream.Spec.Endpoints)
+	conditions = append(conditions, endpointsCondition)
+
+	tlsConfigCondition, caBundle := tlsconfigutil.ValidateTLSConfig(
+		tlsconfigutil.TLSSpecForSupervisor(upstream.Spec.TLS),
+		"spec.tls",
+		c.namespace,
+		c.secretInformer,
+		c.configMapInformer)
+	conditions = append(conditions, tlsConfigCondition)
+
+	claimsCondition, compiledClaims := c.validateClaims(&upstream.Spec.Claims)
+	conditions = append(conditions, claimsCondition)
+
+	additionalParamsCondition, additionalAuthcodeParams := validateAdditionalAuthorizeParameters(
+		upstream.Spec.AuthorizationConfig.AdditionalAuthorizeParameters)
+	conditions = append(conditions, additionalParamsCondition)
+
+	// The critical pattern to maintain is that every run of the sync loop will populate the exact number of the exact
+	// same set of conditions.  Conditions depending on other conditions should get Status: metav1.ConditionUnknown, or
+	// Status: metav1.ConditionFalse, never be omitted.
+	if len(conditions) != countExpectedConditions { // untested since all code paths return the same number of conditions
+		applicationErrors = append(applicationErrors, fmt.Errorf("expected %d conditions but found %d conditions", countExpectedConditions, len(conditions)))
+		return nil, utilerrors.NewAggregate(applicationErrors)
+	}
+	hadErrorCondition, updateStatusErr := c.updateStatus(ctx, upstream, conditions)
+	if updateStatusErr != nil {
+		applicationErrors = append(applicationErrors, updateStatusErr)
+	}
+	// Any error condition means we will not add the IDP to the cache, so just return nil here
+	if hadErrorCondition {
+		return nil, utilerrors.NewAggregate(applicationErrors)
+	}
+
+	provider := upstreamoauth2.New(
+		upstreamoauth2.ProviderConfig{
+			Name:                     upstream.Name,
+			ResourceUID:              upstream.UID,
+			UserInfoURL:              upstream.Spec.Endpoints.UserInfoURL,
+			GroupsURL:                upstream.Spec.Endpoints.GroupsURL,
+			Claims:                   compiledClaims,
+			AdditionalAuthcodeParams: additionalAuthcodeParams,
+			// Note that this client already has some reasonable timeouts configured on it.
+			HttpClient: phttp.Default(caBundle.CertPool()),
+			OAuth2Config: &oauth2.Config{
+				ClientID:     clientID,
+				ClientSecret: clientSecret,
+				Endpoint: oauth2.Endpoint{
+					AuthURL:       upstream.Spec.Endpoints.AuthorizationURL,
+					DeviceAuthURL: "", // we do not use device code flow
+					TokenURL:      upstream.Spec.Endpoints.TokenURL,
+					// Providers differ in how they expect client credentials to be sent to the token endpoint,
+					// so let the oauth2 library try HTTP basic auth first and then fall back to using params.
+					AuthStyle: oauth2.AuthStyleAutoDetect,
+				},
+				RedirectURL: "", // this will be different for each FederationDomain, so we do not set it here
+				Scopes:      upstream.Spec.AuthorizationConfig.Scopes,
+			},
+		},
+	)
+	return provider, utilerrors.NewAggregate(applicationErrors)
+}
+
+func (c *oauth2WatcherController) validateClientSecret(secretName string) (*metav1.Condition, string, string, error) {
+	secret, unableToRetrieveSecretErr := c.secretInformer.Lister().Secrets(c.namespace).Get(secretName)
+
+	// This error requires user interaction, so ignore it.
+	if apierrors.IsNotFound(unableToRetrieveSecretErr) {
+		unableToRetrieveSecretErr = nil
+	}
+
+	buildFalseCondition := func(prefix string) (*metav1.Condition, string, string, error) {
+		return &metav1.Condition{
+			Type:   ClientCredentialsSecretValid,
+			Status: metav1.ConditionFalse,
+			Reason: upstreamwatchers.ReasonNotFound,
+			Message: fmt.Sprintf("%s: secret from spec.client.SecretName (%q) must be found in namespace %q with type %q and keys %q and %q",
+				prefix,
+				secretName,
+				c.namespace,
+				oauth2ClientSecretType,
+				clientIDDataKey,
+				clientSecretDataKey),
+		}, "", "", unableToRetrieveSecretErr
+	}
+
+	if unableToRetrieveSecretErr != nil || secret == nil {
+		return buildFalseCondition(fmt.Sprintf("secret %q not found", secretName))
+	}
+
+	if secret.Type != oauth2ClientSecretType {
+		return buildFalseCondition(fmt.Sprintf("wrong secret type %q", secret.Type))
+	}
+
+	clientID := string(secret.Data[clientIDDataKey])
+	if len(clientID) < 1 {
+		return buildFalseCondition(fmt.Sprintf("missing key %q", clientIDDataKey))
+	}
+
+	clientSecret := string(secret.Data[clientSecretDataKey])
+	if len(clientSecret) < 1 {
+		return buildFalseCondition(fmt.Sprintf("missing key %q", clientSecretDataKey))
+	}
+
+	if len(secret.Data) != 2 {
+		return buildFalseCondition("extra keys found")
+	}
+
+	return &metav1.Condition{
+		Type:    ClientCredentialsSecretValid,
+		Status:  metav1.ConditionTrue,
+		Reason:  conditionsutil.ReasonSuccess,
+		Message: fmt.Sprintf("clientID and clientSecret have been read from spec.client.SecretName (%q)", secretName),
+	}, clientID, clientSecret, nil
+}
+
+func validateEndpoints(endpoints *idpv1alpha1.OAuth2Endpoints) *metav1.Condition {
+	urlsToValidate := []struct {
+		fieldName string
+		value     string
+		optional  bool
+	}{
+		{fieldName: "spec.endpoints.authorizationURL", value: endpoints.AuthorizationURL},
+		{fieldName: "spec.endpoints.tokenURL", value: endpoints.TokenURL},
+		{fieldName: "spec.endpoints.userInfoURL", value: endpoints.UserInfoURL},
+		{fieldName: "spec.endpoints.groupsURL", value: endpoints.GroupsURL, optional: true},
+	}
+
+	for _, u := range urlsToValidate {
+		if u.optional && u.value == "" {
+			continue
+		}
+		if err := validateHTTPSURL(u.value); err != nil {
+			return &metav1.Condition{
+				Type:    EndpointsValid,
+				Status:  metav1.ConditionFalse,
+				Reason:  reasonInvalidURL,
+				Message: fmt.Sprintf("%s (%q) is not valid: %s", u.fieldName, u.value, err.Error()),
+			}
+		}
+	}
+
+	return &metav1.Condition{
+		Type:    EndpointsValid,
+		Status:  metav1.ConditionTrue,
+		Reason:  conditionsutil.ReasonSuccess,
+		Message: "spec.endpoints are valid",
+	}
+}
+
+func validateHTTPSURL(maybeHTTPSURL string) error {
+	parsedURL, err := url.Parse(maybeHTTPSURL)
+	if err != nil {
+		return fmt.Errorf("failed to parse URL: %w", err)
+	}
+	if parsedURL.Scheme != "https" {
+		return fmt.Errorf(`must have "https" scheme, not %q`, parsedURL.Scheme)
+	}
+	if parsedURL.Host == "" {
+		return fmt.Errorf("must have a host")
+	}
+	if parsedURL.Fragment != "" {
+		return fmt.Errorf("cannot contain fragment component")
+	}
+	return nil
+}
+
+func (c *oauth2WatcherController) validateClaims(claims *idpv1alpha1.OAuth2Claims) (*metav1.Condition, *celtransformer.CompiledUserInfoClaimsExtraction) {
+	compiled, err := c.celTransformer.CompileUserInfoClaimsExtraction(&celtransformer.UserInfoClaimsExtraction{
+		UsernameExpression: claims.Username,
+		UIDExpression:      claims.UID,
+		GroupsExpression:   claims.Groups,
+	})
+	if err != nil {
+		return &metav1.Condition{
+			Type:    ClaimsValid,
+			Status:  metav1.ConditionFalse,
+			Reason:  reasonInvalid,
+			Message: fmt.Sprintf("spec.claims are not valid: %s", err.Error()),
+		}, nil
+	}
+
+	return &metav1.Condition{
+		Type:    ClaimsValid,
+		Status:  metav1.ConditionTrue,
+		Reason:  conditionsutil.ReasonSuccess,
+		Message: "spec.claims are valid",
+	}, compiled
+}
+
+func validateAdditionalAuthorizeParameters(params []idpv1alpha1.Parameter) (*metav1.Condition, map[string]string) {
+	additionalAuthcodeParams := map[string]string{}
+	var rejectedParams []string
+	for _, p := range params {
+		if disallowedAdditionalAuthorizeParameters[p.Name] {
+			rejectedParams = append(rejectedParams, p.Name)
+		} else {
+			additionalAuthcodeParams[p.Name] = p.Value
+		}
+	}
+
+	if len(rejectedParams) > 0 {
+		return &metav1.Condition{
+			Type:   AdditionalAuthorizeParametersValid,
+			Status: metav1.ConditionFalse,
+			Reason: reasonDisallowedParameterName,
+			Message: fmt.Sprintf("the following spec.authorizationConfig.additionalAuthorizeParameters are not allowed: %s",
+				strings.Join(rejectedParams, ",")),
+		}, nil
+	}
+
+	return &metav1.Condition{
+		Type:    AdditionalAuthorizeParametersValid,
+		Status:  metav1.ConditionTrue,
+		Reason:  conditionsutil.ReasonSuccess,
+		Message: "spec.authorizationConfig.additionalAuthorizeParameters parameter names are allowed",
+	}, additionalAuthcodeParams
+}
+
+func (c *oauth2WatcherController) updateStatus(
+	ctx context.Context,
+	upstream *idpv1alpha1.OAuth2IdentityProvider,
+	conditions []*metav1.Condition) (bool, error) {
+	log := c.log.WithValues("namespace", upstream.Namespace, "name", upstream.Name)
+	updated := upstream.DeepCopy()
+
+	hadErrorCondition := conditionsutil.MergeConditions(
+		conditions,
+		&updated.Status.Conditions,
+		upstream.Generation,
+		metav1.NewTime(c.clock.Now()),
+		log,
+	)
+
+	updated.Status.Phase = idpv1alpha1.OAuth2PhaseReady
+	if hadErrorCondition {
+		updated.Status.Phase = idpv1alpha1.OAuth2PhaseError
+	}
+
+	if equality.Semantic.DeepEqual(upstream, updated) {
+		return hadErrorCondition, nil
+	}
+
+	log.Info("updating OAuth2IdentityProvider status", "phase", updated.Status.Phase)
+
+	_, updateStatusError := c.client.
+		IDPV1alpha1().
+		OAuth2IdentityProviders(upstream.Namespace).
+		UpdateStatus(ctx, updated, metav1.UpdateOptions{})
+	return hadErrorCondition, updateStatusError
+}
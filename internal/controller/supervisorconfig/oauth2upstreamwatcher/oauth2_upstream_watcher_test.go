@@ -0,0 +1,975 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package oauth2upstreamwatcher
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	utilnet "k8s.io/apimachinery/pkg/util/net"
+	k8sinformers "k8s.io/client-go/informers"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	coretesting "k8s.io/client-go/testing"
+	"k8s.io/utils/clock"
+	clocktesting "k8s.io/utils/clock/testing"
+	"k8s.io/utils/ptr"
+
+	idpv1alpha1 "go.pinniped.dev/generated/latest/apis/supervisor/idp/v1alpha1"
+	supervisorfake "go.pinniped.dev/generated/latest/client/supervisor/clientset/versioned/fake"
+	supervisorinformers "go.pinniped.dev/generated/latest/client/supervisor/informers/externalversions"
+	"go.pinniped.dev/internal/certauthority"
+	"go.pinniped.dev/internal/controller/conditionsutil"
+	"go.pinniped.dev/internal/controller/supervisorconfig/upstreamwatchers"
+	"go.pinniped.dev/internal/controllerlib"
+	"go.pinniped.dev/internal/federationdomain/dynamicupstreamprovider"
+	"go.pinniped.dev/internal/federationdomain/upstreamprovider"
+	"go.pinniped.dev/internal/net/phttp"
+	"go.pinniped.dev/internal/plog"
+	"go.pinniped.dev/internal/testutil"
+	"go.pinniped.dev/internal/upstreamoauth2"
+)
+
+var (
+	oauth2IDPGVR = schema.GroupVersionResource{
+		Group:    idpv1alpha1.SchemeGroupVersion.Group,
+		Version:  idpv1alpha1.SchemeGroupVersion.Version,
+		Resource: "oauth2identityproviders",
+	}
+
+	oauth2IDPKind = idpv1alpha1.SchemeGroupVersion.WithKind("OAuth2IdentityProvider")
+)
+
+func TestController(t *testing.T) {
+	require.Equal(t, 5, countExpectedConditions)
+
+	ca, err := certauthority.New("Some CA", time.Hour)
+	require.NoError(t, err)
+	caB64 := base64.StdEncoding.EncodeToString(ca.Bundle())
+
+	wantObservedGeneration := int64(1234)
+	namespace := "some-namespace"
+
+	wantFrozenTime := time.Date(1122, time.September, 33, 4, 55, 56, 778899, time.Local)
+	frozenClockForLastTransitionTime := clocktesting.NewFakeClock(wantFrozenTime)
+	wantLastTransitionTime := metav1.Time{Time: wantFrozenTime}
+
+	goodClientCredentialsSecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "some-secret-name",
+			Namespace: namespace,
+		},
+		Type: "secrets.pinniped.dev/oauth2-client",
+		Data: map[string][]byte{
+			"clientID":     []byte("some-client-id"),
+			"clientSecret": []byte("some-client-secret"),
+		},
+	}
+
+	validMinimalIDP := &idpv1alpha1.OAuth2IdentityProvider{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:       "minimal-idp-name",
+			Namespace:  namespace,
+			UID:        types.UID("minimal-uid"),
+			Generation: wantObservedGeneration,
+		},
+		Spec: idpv1alpha1.OAuth2IdentityProviderSpec{
+			Endpoints: idpv1alpha1.OAuth2Endpoints{
+				AuthorizationURL: "https://auth.example.com/oauth/authorize",
+				TokenURL:         "https://auth.example.com/oauth/token",
+				UserInfoURL:      "https://auth.example.com/api/user",
+			},
+			Claims: idpv1alpha1.OAuth2Claims{
+				Username: "userinfo.login",
+				UID:      "string(int(userinfo.id))",
+			},
+			Client: idpv1alpha1.OAuth2ClientSpec{
+				SecretName: goodClientCredentialsSecret.Name,
+			},
+		},
+	}
+
+	validFilledOutIDP := &idpv1alpha1.OAuth2IdentityProvider{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:       "some-idp-name",
+			Namespace:  namespace,
+			UID:        types.UID("some-resource-uid"),
+			Generation: wantObservedGeneration,
+		},
+		Spec: idpv1alpha1.OAuth2IdentityProviderSpec{
+			Endpoints: idpv1alpha1.OAuth2Endpoints{
+				AuthorizationURL: "https://auth.example.com/oauth/authorize",
+				TokenURL:         "https://auth.example.com/oauth/token",
+				UserInfoURL:      "https://auth.example.com/api/user",
+				GroupsURL:        "https://auth.example.com/api/user/groups",
+			},
+			TLS: &idpv1alpha1.TLSSpec{
+				CertificateAuthorityData: caB64,
+			},
+			AuthorizationConfig: idpv1alpha1.OAuth2AuthorizationConfig{
+				Scopes: []string{"read_user", "offline_access"},
+				AdditionalAuthorizeParameters: []idpv1alpha1.Parameter{
+					{Name: "prompt", Value: "consent"},
+				},
+			},
+			Claims: idpv1alpha1.OAuth2Claims{
+				Username: "userinfo.login",
+				UID:      "string(int(userinfo.id))",
+				Groups:   "groupsResponse.map(g, g.name)",
+			},
+			Client: idpv1alpha1.OAuth2ClientSpec{
+				SecretName: goodClientCredentialsSecret.Name,
+			},
+		},
+	}
+
+	buildClientCredentialsSecretValidTrue := func(t *testing.T, secretName string) metav1.Condition {
+		return metav1.Condition{
+			Type:               ClientCredentialsSecretValid,
+			Status:             metav1.ConditionTrue,
+			ObservedGeneration: wantObservedGeneration,
+			LastTransitionTime: wantLastTransitionTime,
+			Reason:             conditionsutil.ReasonSuccess,
+			Message:            fmt.Sprintf("clientID and clientSecret have been read from spec.client.SecretName (%q)", secretName),
+		}
+	}
+
+	buildClientCredentialsSecretValidFalse := func(t *testing.T, prefix, secretName, namespace, reason string) metav1.Condition {
+		return metav1.Condition{
+			Type:               ClientCredentialsSecretValid,
+			Status:             metav1.ConditionFalse,
+			ObservedGeneration: wantObservedGeneration,
+			LastTransitionTime: wantLastTransitionTime,
+			Reason:             reason,
+			Message: fmt.Sprintf(
+				`%s: secret from spec.client.SecretName (%q) must be found in namespace %q with type "secrets.pinniped.dev/oauth2-client" and keys "clientID" and "clientSecret"`,
+				prefix,
+				secretName,
+				namespace,
+			),
+		}
+	}
+
+	buildEndpointsValidTrue := func(t *testing.T) metav1.Condition {
+		return metav1.Condition{
+			Type:               EndpointsValid,
+			Status:             metav1.ConditionTrue,
+			ObservedGeneration: wantObservedGeneration,
+			LastTransitionTime: wantLastTransitionTime,
+			Reason:             conditionsutil.ReasonSuccess,
+			Message:            "spec.endpoints are valid",
+		}
+	}
+
+	buildEndpointsValidFalse := func(t *testing.T, message string) metav1.Condition {
+		return metav1.Condition{
+			Type:               EndpointsValid,
+			Status:             metav1.ConditionFalse,
+			ObservedGeneration: wantObservedGeneration,
+			LastTransitionTime: wantLastTransitionTime,
+			Reason:             "InvalidURL",
+			Message:            message,
+		}
+	}
+
+	buildTLSConfigurationValidTrue := func(t *testing.T, message string) metav1.Condition {
+		return metav1.Condition{
+			Type:               TLSConfigurationValid,
+			Status:             metav1.ConditionTrue,
+			ObservedGeneration: wantObservedGeneration,
+			LastTransitionTime: wantLastTransitionTime,
+			Reason:             conditionsutil.ReasonSuccess,
+			Message:            message,
+		}
+	}
+
+	buildTLSConfigurationValidFalse := func(t *testing.T, message string) metav1.Condition {
+		return metav1.Condition{
+			Type:               TLSConfigurationValid,
+			Status:             metav1.ConditionFalse,
+			ObservedGeneration: wantObservedGeneration,
+			LastTransitionTime: wantLastTransitionTime,
+			Reason:             "InvalidTLSConfig",
+			Message:            message,
+		}
+	}
+
+	buildClaimsValidTrue := func(t *testing.T) metav1.Condition {
+		return metav1.Condition{
+			Type:               ClaimsValid,
+			Status:             metav1.ConditionTrue,
+			ObservedGeneration: wantObservedGeneration,
+			LastTransitionTime: wantLastTransitionTime,
+			Reason:             conditionsutil.ReasonSuccess,
+			Message:            "spec.claims are valid",
+		}
+	}
+
+	buildClaimsValidFalse := func(t *testing.T, message string) metav1.Condition {
+		return metav1.Condition{
+			Type:               ClaimsValid,
+			Status:             metav1.ConditionFalse,
+			ObservedGeneration: wantObservedGeneration,
+			LastTransitionTime: wantLastTransitionTime,
+			Reason:             "Invalid",
+			Message:            message,
+		}
+	}
+
+	buildAdditionalAuthorizeParametersValidTrue := func(t *testing.T) metav1.Condition {
+		return metav1.Condition{
+			Type:               AdditionalAuthorizeParametersValid,
+			Status:             metav1.ConditionTrue,
+			ObservedGeneration: wantObservedGeneration,
+			LastTransitionTime: wantLastTransitionTime,
+			Reason:             conditionsutil.ReasonSuccess,
+			Message:            "spec.authorizationConfig.additionalAuthorizeParameters parameter names are allowed",
+		}
+	}
+
+	buildAdditionalAuthorizeParametersValidFalse := func(t *testing.T, message string) metav1.Condition {
+		return metav1.Condition{
+			Type:               AdditionalAuthorizeParametersValid,
+			Status:             metav1.ConditionFalse,
+			ObservedGeneration: wantObservedGeneration,
+			LastTransitionTime: wantLastTransitionTime,
+			Reason:             "DisallowedParameterName",
+			Message:            message,
+		}
+	}
+
+	buildLogForUpdatingCondition := func(name, conditionType, status, reason, message string) string {
+		return fmt.Sprintf(`{"level":"info","timestamp":"2099-08-08T13:57:36.123456Z","logger":"oauth2-upstream-observer","caller":"conditionsutil/conditions_util.go:<line>$conditionsutil.MergeConditions","message":"updated condition","namespace":"some-namespace","name":"%s","type":"%s","status":"%s","reason":"%s","message":"%s"}`, name, conditionType, status, reason, message)
+	}
+
+	buildLogForUpdatingPhase := func(name, phase string) string {
+		return fmt.Sprintf(`{"level":"info","timestamp":"2099-08-08T13:57:36.123456Z","logger":"oauth2-upstream-observer","caller":"oauth2upstreamwatcher/oauth2_upstream_watcher.go:<line>$oauth2upstreamwatcher.(*oauth2WatcherController).updateStatus","message":"updating OAuth2IdentityProvider status","namespace":"some-namespace","name":"%s","phase":"%s"}`, name, phase)
+	}
+
+	buildWantProviderConfig := func(idp *idpv1alpha1.OAuth2IdentityProvider, additionalAuthcodeParams map[string]string, httpClient *http.Client) *upstreamoauth2.ProviderConfig {
+		return &upstreamoauth2.ProviderConfig{
+			Name:                     idp.Name,
+			ResourceUID:              idp.UID,
+			UserInfoURL:              idp.Spec.Endpoints.UserInfoURL,
+			GroupsURL:                idp.Spec.Endpoints.GroupsURL,
+			AdditionalAuthcodeParams: additionalAuthcodeParams,
+			HttpClient:               httpClient,
+			OAuth2Config: &oauth2.Config{
+				ClientID:     "some-client-id",
+				ClientSecret: "some-client-secret",
+				Endpoint: oauth2.Endpoint{
+					AuthURL:       idp.Spec.Endpoints.AuthorizationURL,
+					DeviceAuthURL: "", // not used
+					TokenURL:      idp.Spec.Endpoints.TokenURL,
+					AuthStyle:     oauth2.AuthStyleAutoDetect,
+				},
+				RedirectURL: "", // not used
+				Scopes:      idp.Spec.AuthorizationConfig.Scopes,
+			},
+		}
+	}
+
+	happyLogsForFilledOutIDP := []string{
+		buildLogForUpdatingCondition("some-idp-name", "ClientCredentialsSecretValid", "True", "Success", `clientID and clientSecret have been read from spec.client.SecretName (\"some-secret-name\")`),
+		buildLogForUpdatingCondition("some-idp-name", "EndpointsValid", "True", "Success", "spec.endpoints are valid"),
+		buildLogForUpdatingCondition("some-idp-name", "TLSConfigurationValid", "True", "Success", "spec.tls is valid: using configured CA bundle"),
+		buildLogForUpdatingCondition("some-idp-name", "ClaimsValid", "True", "Success", "spec.claims are valid"),
+		buildLogForUpdatingCondition("some-idp-name", "AdditionalAuthorizeParametersValid", "True", "Success", "spec.authorizationConfig.additionalAuthorizeParameters parameter names are allowed"),
+		buildLogForUpdatingPhase("some-idp-name", "Ready"),
+	}
+
+	happyConditionsForFilledOutIDP := func() []metav1.Condition {
+		return []metav1.Condition{
+			buildAdditionalAuthorizeParametersValidTrue(t),
+			buildClaimsValidTrue(t),
+			buildClientCredentialsSecretValidTrue(t, validFilledOutIDP.Spec.Client.SecretName),
+			buildEndpointsValidTrue(t),
+			buildTLSConfigurationValidTrue(t, "spec.tls is valid: using configured CA bundle"),
+		}
+	}
+
+	tests := []struct {
+		name                    string
+		oauth2IdentityProviders []runtime.Object
+		secretsAndConfigMaps    []runtime.Object
+		wantErr                 string
+		wantLogs                []string
+		wantResultingCache      []*upstreamoauth2.ProviderConfig
+		wantResultingUpstreams  []idpv1alpha1.OAuth2IdentityProvider
+	}{
+		{
+			name:                   "no OAuth2IdentityProviders",
+			wantResultingCache:     []*upstreamoauth2.ProviderConfig{},
+			wantResultingUpstreams: []idpv1alpha1.OAuth2IdentityProvider{},
+			wantLogs:               []string{},
+		},
+		{
+			name:                    "happy path with all fields",
+			secretsAndConfigMaps:    []runtime.Object{goodClientCredentialsSecret},
+			oauth2IdentityProviders: []runtime.Object{validFilledOutIDP},
+			wantResultingCache: []*upstreamoauth2.ProviderConfig{
+				buildWantProviderConfig(validFilledOutIDP, map[string]string{"prompt": "consent"}, phttp.Default(ca.Pool())),
+			},
+			wantResultingUpstreams: []idpv1alpha1.OAuth2IdentityProvider{
+				{
+					ObjectMeta: validFilledOutIDP.ObjectMeta,
+					Spec:       validFilledOutIDP.Spec,
+					Status: idpv1alpha1.OAuth2IdentityProviderStatus{
+						Phase:      idpv1alpha1.OAuth2PhaseReady,
+						Conditions: happyConditionsForFilledOutIDP(),
+					},
+				},
+			},
+			wantLogs: happyLogsForFilledOutIDP,
+		},
+		{
+			name:                    "happy path with minimal fields",
+			secretsAndConfigMaps:    []runtime.Object{goodClientCredentialsSecret},
+			oauth2IdentityProviders: []runtime.Object{validMinimalIDP},
+			wantResultingCache: []*upstreamoauth2.ProviderConfig{
+				buildWantProviderConfig(validMinimalIDP, map[string]string{}, phttp.Default(nil)),
+			},
+			wantResultingUpstreams: []idpv1alpha1.OAuth2IdentityProvider{
+				{
+					ObjectMeta: validMinimalIDP.ObjectMeta,
+					Spec:       validMinimalIDP.Spec,
+					Status: idpv1alpha1.OAuth2IdentityProviderStatus{
+						Phase: idpv1alpha1.OAuth2PhaseReady,
+						Conditions: []metav1.Condition{
+							buildAdditionalAuthorizeParametersValidTrue(t),
+							buildClaimsValidTrue(t),
+							buildClientCredentialsSecretValidTrue(t, validMinimalIDP.Spec.Client.SecretName),
+							buildEndpointsValidTrue(t),
+							buildTLSConfigurationValidTrue(t, "spec.tls is valid: no TLS configuration provided: using default root CA bundle from container image"),
+						},
+					},
+				},
+			},
+			wantLogs: []string{
+				buildLogForUpdatingCondition("minimal-idp-name", "ClientCredentialsSecretValid", "True", "Success", `clientID and clientSecret have been read from spec.client.SecretName (\"some-secret-name\")`),
+				buildLogForUpdatingCondition("minimal-idp-name", "EndpointsValid", "True", "Success", "spec.endpoints are valid"),
+				buildLogForUpdatingCondition("minimal-idp-name", "TLSConfigurationValid", "True", "Success", "spec.tls is valid: no TLS configuration provided: using default root CA bundle from container image"),
+				buildLogForUpdatingCondition("minimal-idp-name", "ClaimsValid", "True", "Success", "spec.claims are valid"),
+				buildLogForUpdatingCondition("minimal-idp-name", "AdditionalAuthorizeParametersValid", "True", "Success", "spec.authorizationConfig.additionalAuthorizeParameters parameter names are allowed"),
+				buildLogForUpdatingPhase("minimal-idp-name", "Ready"),
+			},
+		},
+		{
+			name:                    "Secret error - secret not found",
+			oauth2IdentityProviders: []runtime.Object{validFilledOutIDP},
+			wantResultingCache:      []*upstreamoauth2.ProviderConfig{},
+			wantResultingUpstreams: []idpv1alpha1.OAuth2IdentityProvider{
+				{
+					ObjectMeta: validFilledOutIDP.ObjectMeta,
+					Spec:       validFilledOutIDP.Spec,
+					Status: idpv1alpha1.OAuth2IdentityProviderStatus{
+						Phase: idpv1alpha1.OAuth2PhaseError,
+						Conditions: []metav1.Condition{
+							buildAdditionalAuthorizeParametersValidTrue(t),
+							buildClaimsValidTrue(t),
+							buildClientCredentialsSecretValidFalse(t, `secret "some-secret-name" not found`, "some-secret-name", namespace, upstreamwatchers.ReasonNotFound),
+							buildEndpointsValidTrue(t),
+							buildTLSConfigurationValidTrue(t, "spec.tls is valid: using configured CA bundle"),
+						},
+					},
+				},
+			},
+			wantLogs: []string{
+				buildLogForUpdatingCondition("some-idp-name", "ClientCredentialsSecretValid", "False", "SecretNotFound", `secret \"some-secret-name\" not found: secret from spec.client.SecretName (\"some-secret-name\") must be found in namespace \"some-namespace\" with type \"secrets.pinniped.dev/oauth2-client\" and keys \"clientID\" and \"clientSecret\"`),
+				happyLogsForFilledOutIDP[1],
+				happyLogsForFilledOutIDP[2],
+				happyLogsForFilledOutIDP[3],
+				happyLogsForFilledOutIDP[4],
+				buildLogForUpdatingPhase("some-idp-name", "Error"),
+			},
+		},
+		{
+			name: "Secret error - wrong secret type",
+			secretsAndConfigMaps: []runtime.Object{
+				func() runtime.Object {
+					wrongTypeSecret := goodClientCredentialsSecret.DeepCopy()
+					wrongTypeSecret.Type = "secrets.pinniped.dev/gitlab-client"
+					return wrongTypeSecret
+				}(),
+			},
+			oauth2IdentityProviders: []runtime.Object{validFilledOutIDP},
+			wantResultingCache:      []*upstreamoauth2.ProviderConfig{},
+			wantResultingUpstreams: []idpv1alpha1.OAuth2IdentityProvider{
+				{
+					ObjectMeta: validFilledOutIDP.ObjectMeta,
+					Spec:       validFilledOutIDP.Spec,
+					Status: idpv1alpha1.OAuth2IdentityProviderStatus{
+						Phase: idpv1alpha1.OAuth2PhaseError,
+						Conditions: []metav1.Condition{
+							buildAdditionalAuthorizeParametersValidTrue(t),
+							buildClaimsValidTrue(t),
+							buildClientCredentialsSecretValidFalse(t, `wrong secret type "secrets.pinniped.dev/gitlab-client"`, "some-secret-name", namespace, upstreamwatchers.ReasonNotFound),
+							buildEndpointsValidTrue(t),
+							buildTLSConfigurationValidTrue(t, "spec.tls is valid: using configured CA bundle"),
+						},
+					},
+				},
+			},
+			wantLogs: []string{
+				buildLogForUpdatingCondition("some-idp-name", "ClientCredentialsSecretValid", "False", "SecretNotFound", `wrong secret type \"secrets.pinniped.dev/gitlab-client\": secret from spec.client.SecretName (\"some-secret-name\") must be found in namespace \"some-namespace\" with type \"secrets.pinniped.dev/oauth2-client\" and keys \"clientID\" and \"clientSecret\"`),
+				happyLogsForFilledOutIDP[1],
+				happyLogsForFilledOutIDP[2],
+				happyLogsForFilledOutIDP[3],
+				happyLogsForFilledOutIDP[4],
+				buildLogForUpdatingPhase("some-idp-name", "Error"),
+			},
+		},
+		{
+			name: "Secret error - missing clientSecret",
+			secretsAndConfigMaps: []runtime.Object{
+				func() runtime.Object {
+					badSecret := goodClientCredentialsSecret.DeepCopy()
+					delete(badSecret.Data, "clientSecret")
+					return badSecret
+				}(),
+			},
+			oauth2IdentityProviders: []runtime.Object{validFilledOutIDP},
+			wantResultingCache:      []*upstreamoauth2.ProviderConfig{},
+			wantResultingUpstreams: []idpv1alpha1.OAuth2IdentityProvider{
+				{
+					ObjectMeta: validFilledOutIDP.ObjectMeta,
+					Spec:       validFilledOutIDP.Spec,
+					Status: idpv1alpha1.OAuth2IdentityProviderStatus{
+						Phase: idpv1alpha1.OAuth2PhaseError,
+						Conditions: []metav1.Condition{
+							buildAdditionalAuthorizeParametersValidTrue(t),
+							buildClaimsValidTrue(t),
+							buildClientCredentialsSecretValidFalse(t, `missing key "clientSecret"`, "some-secret-name", namespace, upstreamwatchers.ReasonNotFound),
+							buildEndpointsValidTrue(t),
+							buildTLSConfigurationValidTrue(t, "spec.tls is valid: using configured CA bundle"),
+						},
+					},
+				},
+			},
+			wantLogs: []string{
+				buildLogForUpdatingCondition("some-idp-name", "ClientCredentialsSecretValid", "False", "SecretNotFound", `missing key \"clientSecret\": secret from spec.client.SecretName (\"some-secret-name\") must be found in namespace \"some-namespace\" with type \"secrets.pinniped.dev/oauth2-client\" and keys \"clientID\" and \"clientSecret\"`),
+				happyLogsForFilledOutIDP[1],
+				happyLogsForFilledOutIDP[2],
+				happyLogsForFilledOutIDP[3],
+				happyLogsForFilledOutIDP[4],
+				buildLogForUpdatingPhase("some-idp-name", "Error"),
+			},
+		},
+		{
+			name:                 "Endpoints error - URL is not https",
+			secretsAndConfigMaps: []runtime.Object{goodClientCredentialsSecret},
+			oauth2IdentityProviders: []runtime.Object{
+				func() runtime.Object {
+					badIDP := validFilledOutIDP.DeepCopy()
+					badIDP.Spec.Endpoints.UserInfoURL = "http://auth.example.com/api/user"
+					return badIDP
+				}(),
+			},
+			wantResultingCache: []*upstreamoauth2.ProviderConfig{},
+			wantResultingUpstreams: []idpv1alpha1.OAuth2IdentityProvider{
+				{
+					ObjectMeta: validFilledOutIDP.ObjectMeta,
+					Spec: func() idpv1alpha1.OAuth2IdentityProviderSpec {
+						badSpec := validFilledOutIDP.Spec.DeepCopy()
+						badSpec.Endpoints.UserInfoURL = "http://auth.example.com/api/user"
+						return *badSpec
+					}(),
+					Status: idpv1alpha1.OAuth2IdentityProviderStatus{
+						Phase: idpv1alpha1.OAuth2PhaseError,
+						Conditions: []metav1.Condition{
+							buildAdditionalAuthorizeParametersValidTrue(t),
+							buildClaimsValidTrue(t),
+							buildClientCredentialsSecretValidTrue(t, validFilledOutIDP.Spec.Client.SecretName),
+							buildEndpointsValidFalse(t, `spec.endpoints.userInfoURL ("http://auth.example.com/api/user") is not valid: must have "https" scheme, not "http"`),
+							buildTLSConfigurationValidTrue(t, "spec.tls is valid: using configured CA bundle"),
+						},
+					},
+				},
+			},
+			wantLogs: []string{
+				happyLogsForFilledOutIDP[0],
+				buildLogForUpdatingCondition("some-idp-name", "EndpointsValid", "False", "InvalidURL", `spec.endpoints.userInfoURL (\"http://auth.example.com/api/user\") is not valid: must have \"https\" scheme, not \"http\"`),
+				happyLogsForFilledOutIDP[2],
+				happyLogsForFilledOutIDP[3],
+				happyLogsForFilledOutIDP[4],
+				buildLogForUpdatingPhase("some-idp-name", "Error"),
+			},
+		},
+		{
+			name:                 "Endpoints error - optional groups URL has no host",
+			secretsAndConfigMaps: []runtime.Object{goodClientCredentialsSecret},
+			oauth2IdentityProviders: []runtime.Object{
+				func() runtime.Object {
+					badIDP := validFilledOutIDP.DeepCopy()
+					badIDP.Spec.Endpoints.GroupsURL = "https:///groups"
+					return badIDP
+				}(),
+			},
+			wantResultingCache: []*upstreamoauth2.ProviderConfig{},
+			wantResultingUpstreams: []idpv1alpha1.OAuth2IdentityProvider{
+				{
+					ObjectMeta: validFilledOutIDP.ObjectMeta,
+					Spec: func() idpv1alpha1.OAuth2IdentityProviderSpec {
+						badSpec := validFilledOutIDP.Spec.DeepCopy()
+						badSpec.Endpoints.GroupsURL = "https:///groups"
+						return *badSpec
+					}(),
+					Status: idpv1alpha1.OAuth2IdentityProviderStatus{
+						Phase: idpv1alpha1.OAuth2PhaseError,
+						Conditions: []metav1.Condition{
+							buildAdditionalAuthorizeParametersValidTrue(t),
+							buildClaimsValidTrue(t),
+							buildClientCredentialsSecretValidTrue(t, validFilledOutIDP.Spec.Client.SecretName),
+							buildEndpointsValidFalse(t, `spec.endpoints.groupsURL ("https:///groups") is not valid: must have a host`),
+							buildTLSConfigurationValidTrue(t, "spec.tls is valid: using configured CA bundle"),
+						},
+					},
+				},
+			},
+			wantLogs: []string{
+				happyLogsForFilledOutIDP[0],
+				buildLogForUpdatingCondition("some-idp-name", "EndpointsValid", "False", "InvalidURL", `spec.endpoints.groupsURL (\"https:///groups\") is not valid: must have a host`),
+				happyLogsForFilledOutIDP[2],
+				happyLogsForFilledOutIDP[3],
+				happyLogsForFilledOutIDP[4],
+				buildLogForUpdatingPhase("some-idp-name", "Error"),
+			},
+		},
+		{
+			name:                 "TLS error - invalid CA bundle",
+			secretsAndConfigMaps: []runtime.Object{goodClientCredentialsSecret},
+			oauth2IdentityProviders: []runtime.Object{
+				func() runtime.Object {
+					badIDP := validFilledOutIDP.DeepCopy()
+					badIDP.Spec.TLS.CertificateAuthorityData = "invalid-base64"
+					return badIDP
+				}(),
+			},
+			wantResultingCache: []*upstreamoauth2.ProviderConfig{},
+			wantResultingUpstreams: []idpv1alpha1.OAuth2IdentityProvider{
+				{
+					ObjectMeta: validFilledOutIDP.ObjectMeta,
+					Spec: func() idpv1alpha1.OAuth2IdentityProviderSpec {
+						badSpec := validFilledOutIDP.Spec.DeepCopy()
+						badSpec.TLS.CertificateAuthorityData = "invalid-base64"
+						return *badSpec
+					}(),
+					Status: idpv1alpha1.OAuth2IdentityProviderStatus{
+						Phase: idpv1alpha1.OAuth2PhaseError,
+						Conditions: []metav1.Condition{
+							buildAdditionalAuthorizeParametersValidTrue(t),
+							buildClaimsValidTrue(t),
+							buildClientCredentialsSecretValidTrue(t, validFilledOutIDP.Spec.Client.SecretName),
+							buildEndpointsValidTrue(t),
+							buildTLSConfigurationValidFalse(t, "spec.tls.certificateAuthorityData is invalid: illegal base64 data at input byte 7"),
+						},
+					},
+				},
+			},
+			wantLogs: []string{
+				happyLogsForFilledOutIDP[0],
+				happyLogsForFilledOutIDP[1],
+				buildLogForUpdatingCondition("some-idp-name", "TLSConfigurationValid", "False", "InvalidTLSConfig", "spec.tls.certificateAuthorityData is invalid: illegal base64 data at input byte 7"),
+				happyLogsForFilledOutIDP[3],
+				happyLogsForFilledOutIDP[4],
+				buildLogForUpdatingPhase("some-idp-name", "Error"),
+			},
+		},
+		{
+			name:                 "Claims error - expression does not compile",
+			secretsAndConfigMaps: []runtime.Object{goodClientCredentialsSecret},
+			oauth2IdentityProviders: []runtime.Object{
+				func() runtime.Object {
+					badIDP := validFilledOutIDP.DeepCopy()
+					badIDP.Spec.Claims.Groups = "this is not valid CEL"
+					return badIDP
+				}(),
+			},
+			wantResultingCache: []*upstreamoauth2.ProviderConfig{},
+			wantResultingUpstreams: []idpv1alpha1.OAuth2IdentityProvider{
+				{
+					ObjectMeta: validFilledOutIDP.ObjectMeta,
+					Spec: func() idpv1alpha1.OAuth2IdentityProviderSpec {
+						badSpec := validFilledOutIDP.Spec.DeepCopy()
+						badSpec.Claims.Groups = "this is not valid CEL"
+						return *badSpec
+					}(),
+					Status: idpv1alpha1.OAuth2IdentityProviderStatus{
+						Phase: idpv1alpha1.OAuth2PhaseError,
+						Conditions: []metav1.Condition{
+							buildAdditionalAuthorizeParametersValidTrue(t),
+							buildClaimsValidFalse(t, "spec.claims are not valid: groups expression: CEL expression compile error: ERROR: <input>:1:6: Syntax error: mismatched input 'is' expecting <EOF>\n | this is not valid CEL\n | .....^"),
+							buildClientCredentialsSecretValidTrue(t, validFilledOutIDP.Spec.Client.SecretName),
+							buildEndpointsValidTrue(t),
+							buildTLSConfigurationValidTrue(t, "spec.tls is valid: using configured CA bundle"),
+						},
+					},
+				},
+			},
+			wantLogs: []string{
+				happyLogsForFilledOutIDP[0],
+				happyLogsForFilledOutIDP[1],
+				happyLogsForFilledOutIDP[2],
+				buildLogForUpdatingCondition("some-idp-name", "ClaimsValid", "False", "Invalid", `spec.claims are not valid: groups expression: CEL expression compile error: ERROR: <input>:1:6: Syntax error: mismatched input 'is' expecting <EOF>\n | this is not valid CEL\n | .....^`),
+				happyLogsForFilledOutIDP[4],
+				buildLogForUpdatingPhase("some-idp-name", "Error"),
+			},
+		},
+		{
+			name:                 "Claims error - username expression has the wrong type",
+			secretsAndConfigMaps: []runtime.Object{goodClientCredentialsSecret},
+			oauth2IdentityProviders: []runtime.Object{
+				func() runtime.Object {
+					badIDP := validFilledOutIDP.DeepCopy()
+					badIDP.Spec.Claims.Username = "42"
+					return badIDP
+				}(),
+			},
+			wantResultingCache: []*upstreamoauth2.ProviderConfig{},
+			wantResultingUpstreams: []idpv1alpha1.OAuth2IdentityProvider{
+				{
+					ObjectMeta: validFilledOutIDP.ObjectMeta,
+					Spec: func() idpv1alpha1.OAuth2IdentityProviderSpec {
+						badSpec := validFilledOutIDP.Spec.DeepCopy()
+						badSpec.Claims.Username = "42"
+						return *badSpec
+					}(),
+					Status: idpv1alpha1.OAuth2IdentityProviderStatus{
+						Phase: idpv1alpha1.OAuth2PhaseError,
+						Conditions: []metav1.Condition{
+							buildAdditionalAuthorizeParametersValidTrue(t),
+							buildClaimsValidFalse(t, `spec.claims are not valid: username expression: CEL expression should return type "string" but returns type "int"`),
+							buildClientCredentialsSecretValidTrue(t, validFilledOutIDP.Spec.Client.SecretName),
+							buildEndpointsValidTrue(t),
+							buildTLSConfigurationValidTrue(t, "spec.tls is valid: using configured CA bundle"),
+						},
+					},
+				},
+			},
+			wantLogs: []string{
+				happyLogsForFilledOutIDP[0],
+				happyLogsForFilledOutIDP[1],
+				happyLogsForFilledOutIDP[2],
+				buildLogForUpdatingCondition("some-idp-name", "ClaimsValid", "False", "Invalid", `spec.claims are not valid: username expression: CEL expression should return type \"string\" but returns type \"int\"`),
+				happyLogsForFilledOutIDP[4],
+				buildLogForUpdatingPhase("some-idp-name", "Error"),
+			},
+		},
+		{
+			name:                 "AdditionalAuthorizeParameters error - disallowed parameter names",
+			secretsAndConfigMaps: []runtime.Object{goodClientCredentialsSecret},
+			oauth2IdentityProviders: []runtime.Object{
+				func() runtime.Object {
+					badIDP := validFilledOutIDP.DeepCopy()
+					badIDP.Spec.AuthorizationConfig.AdditionalAuthorizeParameters = []idpv1alpha1.Parameter{
+						{Name: "prompt", Value: "consent"},
+						{Name: "client_id", Value: "some-other-client"},
+						{Name: "redirect_uri", Value: "https://example.com"},
+					}
+					return badIDP
+				}(),
+			},
+			wantResultingCache: []*upstreamoauth2.ProviderConfig{},
+			wantResultingUpstreams: []idpv1alpha1.OAuth2IdentityProvider{
+				{
+					ObjectMeta: validFilledOutIDP.ObjectMeta,
+					Spec: func() idpv1alpha1.OAuth2IdentityProviderSpec {
+						badSpec := validFilledOutIDP.Spec.DeepCopy()
+						badSpec.AuthorizationConfig.AdditionalAuthorizeParameters = []idpv1alpha1.Parameter{
+							{Name: "prompt", Value: "consent"},
+							{Name: "client_id", Value: "some-other-client"},
+							{Name: "redirect_uri", Value: "https://example.com"},
+						}
+						return *badSpec
+					}(),
+					Status: idpv1alpha1.OAuth2IdentityProviderStatus{
+						Phase: idpv1alpha1.OAuth2PhaseError,
+						Conditions: []metav1.Condition{
+							buildAdditionalAuthorizeParametersValidFalse(t, "the following spec.authorizationConfig.additionalAuthorizeParameters are not allowed: client_id,redirect_uri"),
+							buildClaimsValidTrue(t),
+							buildClientCredentialsSecretValidTrue(t, validFilledOutIDP.Spec.Client.SecretName),
+							buildEndpointsValidTrue(t),
+							buildTLSConfigurationValidTrue(t, "spec.tls is valid: using configured CA bundle"),
+						},
+					},
+				},
+			},
+			wantLogs: []string{
+				happyLogsForFilledOutIDP[0],
+				happyLogsForFilledOutIDP[1],
+				happyLogsForFilledOutIDP[2],
+				happyLogsForFilledOutIDP[3],
+				buildLogForUpdatingCondition("some-idp-name", "AdditionalAuthorizeParametersValid", "False", "DisallowedParameterName", "the following spec.authorizationConfig.additionalAuthorizeParameters are not allowed: client_id,redirect_uri"),
+				buildLogForUpdatingPhase("some-idp-name", "Error"),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			fakeSupervisorClient := supervisorfake.NewSimpleClientset(tt.oauth2IdentityProviders...)
+			supervisorInformers := supervisorinformers.NewSharedInformerFactory(fakeSupervisorClient, 0)
+
+			fakeKubeClient := kubefake.NewClientset(tt.secretsAndConfigMaps...)
+			kubeInformers := k8sinformers.NewSharedInformerFactoryWithOptions(fakeKubeClient, 0)
+
+			idpCache := dynamicupstreamprovider.NewDynamicUpstreamIDPProvider()
+			idpCache.SetOAuth2IdentityProviders([]upstreamprovider.UpstreamOAuth2IdentityProviderI{
+				upstreamoauth2.New(
+					upstreamoauth2.ProviderConfig{Name: "initial-entry-to-remove"},
+				),
+			})
+
+			logger, log := plog.TestLogger(t)
+
+			oauth2IdentityProviderInformer := supervisorInformers.IDP().V1alpha1().OAuth2IdentityProviders()
+
+			controller := New(
+				namespace,
+				idpCache,
+				fakeSupervisorClient,
+				oauth2IdentityProviderInformer,
+				kubeInformers.Core().V1().Secrets(),
+				kubeInformers.Core().V1().ConfigMaps(),
+				logger,
+				controllerlib.WithInformer,
+				frozenClockForLastTransitionTime,
+			)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			supervisorInformers.Start(ctx.Done())
+			kubeInformers.Start(ctx.Done())
+			controllerlib.TestRunSynchronously(t, controller)
+
+			syncCtx := controllerlib.Context{Context: ctx, Key: controllerlib.Key{}}
+
+			if err := controllerlib.TestSync(t, controller, syncCtx); len(tt.wantErr) > 0 {
+				require.ErrorContains(t, err, controllerlib.ErrSyntheticRequeue.Error())
+				require.ErrorContains(t, err, tt.wantErr)
+			} else {
+				require.NoError(t, err)
+			}
+
+			// Verify what's in the IDP cache
+			actualIDPList := idpCache.GetOAuth2IdentityProviders()
+			require.Equal(t, len(tt.wantResultingCache), len(actualIDPList))
+			for i := range len(tt.wantResultingCache) {
+				// Do not expect any particular order in the cache
+				var actualProvider *upstreamoauth2.Provider
+				for _, possibleIDP := range actualIDPList {
+					if possibleIDP.GetResourceName() == tt.wantResultingCache[i].Name {
+						var ok bool
+						actualProvider, ok = possibleIDP.(*upstreamoauth2.Provider)
+						require.True(t, ok)
+						break
+					}
+				}
+
+				require.Equal(t, tt.wantResultingCache[i].Name, actualProvider.GetResourceName())
+				require.Equal(t, tt.wantResultingCache[i].ResourceUID, actualProvider.GetResourceUID())
+				require.Equal(t, tt.wantResultingCache[i].OAuth2Config.ClientID, actualProvider.GetClientID())
+				require.Equal(t, tt.wantResultingCache[i].OAuth2Config.Scopes, actualProvider.GetScopes())
+				require.Equal(t, tt.wantResultingCache[i].OAuth2Config.Endpoint.AuthURL, actualProvider.GetAuthorizationURL())
+				require.Equal(t, tt.wantResultingCache[i].AdditionalAuthcodeParams, actualProvider.GetAdditionalAuthcodeParams())
+
+				actualConfig := actualProvider.GetConfig()
+				compareTLSClientConfigWithinHttpClients(t, tt.wantResultingCache[i].HttpClient, actualConfig.HttpClient)
+				require.Equal(t, tt.wantResultingCache[i].OAuth2Config, actualConfig.OAuth2Config)
+				require.Equal(t, tt.wantResultingCache[i].UserInfoURL, actualConfig.UserInfoURL)
+				require.Equal(t, tt.wantResultingCache[i].GroupsURL, actualConfig.GroupsURL)
+				require.NotNil(t, actualConfig.Claims)
+			}
+
+			// Verify the status conditions as reported in Kubernetes
+			allOAuth2IDPs, err := fakeSupervisorClient.IDPV1alpha1().OAuth2IdentityProviders(namespace).List(ctx, metav1.ListOptions{})
+			require.NoError(t, err)
+
+			require.Equal(t, len(tt.wantResultingUpstreams), len(allOAuth2IDPs.Items))
+			for i := range len(tt.wantResultingUpstreams) {
+				require.Len(t, tt.wantResultingUpstreams[i].Status.Conditions, countExpectedConditions)
+
+				// Do not expect any particular order in the K8s objects
+				var actualIDP *idpv1alpha1.OAuth2IdentityProvider
+				for _, possibleMatch := range allOAuth2IDPs.Items {
+					if possibleMatch.GetName() == tt.wantResultingUpstreams[i].Name {
+						actualIDP = ptr.To(possibleMatch)
+						break
+					}
+				}
+
+				require.NotNil(t, actualIDP, "must find IDP with name %s", tt.wantResultingUpstreams[i].Name)
+				require.Len(t, actualIDP.Status.Conditions, countExpectedConditions)
+				require.Equal(t, tt.wantResultingUpstreams[i], *actualIDP)
+			}
+			testutil.RequireLogLines(t, tt.wantLogs, log)
+
+			// This needs to happen after the expected condition LastTransitionTime has been updated.
+			wantActions := make([]coretesting.Action, 3+len(tt.wantResultingUpstreams))
+			wantActions[0] = coretesting.NewListAction(oauth2IDPGVR, oauth2IDPKind, "", metav1.ListOptions{})
+			wantActions[1] = coretesting.NewWatchAction(oauth2IDPGVR, "", metav1.ListOptions{Watch: true})
+			for i, want := range tt.wantResultingUpstreams {
+				wantActions[2+i] = coretesting.NewUpdateSubresourceAction(oauth2IDPGVR, "status", want.Namespace, ptr.To(want))
+			}
+			// This List action is coming from the test code when it pulls the OAuth2IdentityProviders from K8s
+			wantActions[len(wantActions)-1] = coretesting.NewListAction(oauth2IDPGVR, oauth2IDPKind, namespace, metav1.ListOptions{})
+			require.Equal(t, wantActions, testutil.ScrubListOptionsForActions(t, fakeSupervisorClient.Actions()))
+		})
+	}
+}
+
+func compareTLSClientConfigWithinHttpClients(t *testing.T, expected *http.Client, actual *http.Client) {
+	t.Helper()
+
+	require.NotEmpty(t, expected)
+	require.NotEmpty(t, actual)
+
+	require.Equal(t, expected.Timeout, actual.Timeout)
+
+	expectedConfig, err := utilnet.TLSClientConfig(expected.Transport)
+	require.NoError(t, err)
+
+	actualConfig, err := utilnet.TLSClientConfig(actual.Transport)
+	require.NoError(t, err)
+
+	if expectedConfig.RootCAs == nil {
+		require.Nil(t, actualConfig.RootCAs)
+	} else {
+		require.True(t, actualConfig.RootCAs.Equal(expectedConfig.RootCAs))
+	}
+	actualConfig.RootCAs = expectedConfig.RootCAs
+	require.Equal(t, expectedConfig, actualConfig)
+}
+
+func TestOAuth2UpstreamWatcherControllerFilterSecret(t *testing.T) {
+	goodSecret := &corev1.Secret{
+		Type: "secrets.pinniped.dev/oauth2-client",
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "some-name",
+		},
+	}
+
+	tests := []struct {
+		name       string
+		secret     metav1.Object
+		wantAdd    bool
+		wantUpdate bool
+		wantDelete bool
+	}{
+		{
+			name:       "should return true for a secret of the type secrets.pinniped.dev/oauth2-client",
+			secret:     goodSecret,
+			wantAdd:    true,
+			wantUpdate: true,
+			wantDelete: true,
+		},
+		{
+			name: "should return true for a secret of the type Opaque",
+			secret: func() *corev1.Secret {
+				otherSecret := goodSecret.DeepCopy()
+				otherSecret.Type = corev1.SecretTypeOpaque
+				return otherSecret
+			}(),
+			wantAdd:    true,
+			wantUpdate: true,
+			wantDelete: true,
+		},
+		{
+			name: "should return true for a secret of the type TLS",
+			secret: func() *corev1.Secret {
+				otherSecret := goodSecret.DeepCopy()
+				otherSecret.Type = corev1.SecretTypeTLS
+				return otherSecret
+			}(),
+			wantAdd:    true,
+			wantUpdate: true,
+			wantDelete: true,
+		},
+		{
+			name: "should return false for a secret of the wrong type",
+			secret: func() *corev1.Secret {
+				otherSecret := goodSecret.DeepCopy()
+				otherSecret.Type = "secrets.pinniped.dev/gitlab-client"
+				return otherSecret
+			}(),
+		},
+		{
+			name: "should return false for a resource of wrong data type",
+			secret: &corev1.Namespace{
+				ObjectMeta: metav1.ObjectMeta{Name: "some-name", Namespace: "some-namespace"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			kubeInformers := k8sinformers.NewSharedInformerFactoryWithOptions(kubefake.NewClientset(), 0)
+
+			logger, _ := plog.TestLogger(t)
+
+			observableInformers := testutil.NewObservableWithInformerOption()
+			secretInformer := kubeInformers.Core().V1().Secrets()
+
+			_ = New(
+				"some-namespace",
+				dynamicupstreamprovider.NewDynamicUpstreamIDPProvider(),
+				supervisorfake.NewSimpleClientset(),
+				supervisorinformers.NewSharedInformerFactory(supervisorfake.NewSimpleClientset(), 0).IDP().V1alpha1().OAuth2IdentityProviders(),
+				secretInformer,
+				kubeInformers.Core().V1().ConfigMaps(),
+				logger,
+				observableInformers.WithInformer,
+				clock.RealClock{},
+			)
+
+			unrelated := &corev1.Secret{}
+			filter := observableInformers.GetFilterForInformer(secretInformer)
+			require.Equal(t, tt.wantAdd, filter.Add(tt.secret))
+			require.Equal(t, tt.wantUpdate, filter.Update(unrelated, tt.secret))
+			require.Equal(t, tt.wantUpdate, filter.Update(tt.secret, unrelated))
+			require.Equal(t, tt.wantDelete, filter.Delete(tt.secret))
+		})
+	}
+}
+
+func TestOAuth2UpstreamWatcherControllerFilterOAuth2IDP(t *testing.T) {
+	logger, _ := plog.TestLogger(t)
+
+	observableInformers := testutil.NewObservableWithInformerOption()
+	oauth2IdentityProviderInformer := supervisorinformers.NewSharedInformerFactory(supervisorfake.NewSimpleClientset(), 0).IDP().V1alpha1().OAuth2IdentityProviders()
+
+	_ = New(
+		"some-namespace",
+		dynamicupstreamprovider.NewDynamicUpstreamIDPProvider(),
+		supervisorfake.NewSimpleClientset(),
+		oauth2IdentityProviderInformer,
+		k8sinformers.NewSharedInformerFactoryWithOptions(kubefake.NewClientset(), 0).Core().V1().Secrets(),
+		k8sinformers.NewSharedInformerFactoryWithOptions(kubefake.NewClientset(), 0).Core().V1().ConfigMaps(),
+		logger,
+		observableInformers.WithInformer,
+		clock.RealClock{},
+	)
+
+	idp := &idpv1alpha1.OAuth2IdentityProvider{ObjectMeta: metav1.ObjectMeta{Namespace: "some-namespace"}}
+	unrelated := &idpv1alpha1.OAuth2IdentityProvider{}
+	filter := observableInformers.GetFilterForInformer(oauth2IdentityProviderInformer)
+	require.True(t, filter.Add(idp))
+	require.True(t, filter.Update(unrelated, idp))
+	require.True(t, filter.Update(idp, unrelated))
+	require.True(t, filter.Delete(idp))
+}
@@ -1,4 +1,4 @@
-// Copyright 2022-2025 the Pinniped contributors. All Rights Reserved.
+// Copyright 2022-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package oidcclientwatcher
@@ -29,29 +29,37 @@ import (
 const (
 	secretTypeToObserve       = "storage.pinniped.dev/oidc-client-secret" //nolint:gosec // this is not a credential
 	oidcClientPrefixToObserve = oidcapi.ClientIDRequiredOIDCClientPrefix
+
+	clientAuthenticationValid            = "ClientAuthenticationValid"
+	reasonClientCertificatesNotRequested = "ClientCertificatesNotRequested"
 )
 
 type oidcClientWatcherController struct {
-	pinnipedClient     supervisorclientset.Interface
-	oidcClientInformer configInformers.OIDCClientInformer
-	secretInformer     corev1informers.SecretInformer
+	pinnipedClient              supervisorclientset.Interface
+	oidcClientInformer          configInformers.OIDCClientInformer
+	secretInformer              corev1informers.SecretInformer
+	clientCertificatesRequested bool
 }
 
 // NewOIDCClientWatcherController returns a controllerlib.Controller that watches OIDCClients and updates
-// their status with validation errors.
+// their status with validation errors. The clientCertificatesRequested param should be true when the
+// Supervisor's HTTPS listener requests TLS client certificates, without which the tls_client_auth
+// client authentication method cannot succeed.
 func NewOIDCClientWatcherController(
 	pinnipedClient supervisorclientset.Interface,
 	secretInformer corev1informers.SecretInformer,
 	oidcClientInformer configInformers.OIDCClientInformer,
 	withInformer pinnipedcontroller.WithInformerOptionFunc,
+	clientCertificatesRequested bool,
 ) controllerlib.Controller {
 	return controllerlib.New(
 		controllerlib.Config{
 			Name: "OIDCClientWatcherController",
 			Syncer: &oidcClientWatcherController{
-				pinnipedClient:     pinnipedClient,
-				secretInformer:     secretInformer,
-				oidcClientInformer: oidcClientInformer,
+				pinnipedClient:              pinnipedClient,
+				secretInformer:              secretInformer,
+				oidcClientInformer:          oidcClientInformer,
+				clientCertificatesRequested: clientCertificatesRequested,
 			},
 		},
 		// We want to be notified when an OIDCClient's corresponding secret gets updated or deleted.
@@ -110,6 +118,11 @@ func (c *oidcClientWatcherController) Sync(ctx controllerlib.Context) error {
 
 		_, conditions, clientSecrets := oidcclientvalidator.Validate(oidcClient, secret, oidcclientvalidator.DefaultMinBcryptCost)
 
+		if !c.clientCertificatesRequested &&
+			oidcclientvalidator.TokenEndpointAuthMethod(oidcClient) == supervisorconfigv1alpha1.TokenEndpointAuthMethodTLSClientAuth {
+			conditions = clientCertificatesNotRequested(conditions)
+		}
+
 		if err := c.updateStatus(ctx.Context, oidcClient, conditions, len(clientSecrets)); err != nil {
 			return fmt.Errorf("cannot update OIDCClient '%s/%s': %w", oidcClient.Namespace, oidcClient.Name, err)
 		}
@@ -125,6 +138,20 @@ func (c *oidcClientWatcherController) Sync(ctx controllerlib.Context) error {
 	return nil
 }
 
+// clientCertificatesNotRequested marks an otherwise valid tls_client_auth configuration as invalid, since
+// clients cannot present their certificates when the HTTPS listener does not request them.
+func clientCertificatesNotRequested(conditions []*metav1.Condition) []*metav1.Condition {
+	for _, cond := range conditions {
+		if cond.Type == clientAuthenticationValid && cond.Status == metav1.ConditionTrue {
+			cond.Status = metav1.ConditionFalse
+			cond.Reason = reasonClientCertificatesNotRequested
+			cond.Message = fmt.Sprintf("client authentication method %q requires the Supervisor's https listener to set requestClientCertificates",
+				supervisorconfigv1alpha1.TokenEndpointAuthMethodTLSClientAuth)
+		}
+	}
+	return conditions
+}
+
 func (c *oidcClientWatcherController) updateStatus(
 	ctx context.Context,
 	upstream *supervisorconfigv1alpha1.OIDCClient,
@@ -5,10 +5,16 @@ package oidcclientwatcher
 
 import (
 	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
 	"fmt"
 	"testing"
 	"time"
 
+	"github.com/go-jose/go-jose/v3"
 	"github.com/stretchr/testify/require"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -19,6 +25,7 @@ import (
 	supervisorconfigv1alpha1 "go.pinniped.dev/generated/latest/apis/supervisor/config/v1alpha1"
 	supervisorfake "go.pinniped.dev/generated/latest/client/supervisor/clientset/versioned/fake"
 	supervisorinformers "go.pinniped.dev/generated/latest/client/supervisor/informers/externalversions"
+	"go.pinniped.dev/internal/certauthority"
 	"go.pinniped.dev/internal/controllerlib"
 	"go.pinniped.dev/internal/testutil"
 )
@@ -76,6 +83,7 @@ func TestOIDCClientWatcherControllerFilterSecret(t *testing.T) {
 				secretInformer,
 				oidcClientsInformer,
 				withInformer.WithInformer,
+				false,
 			)
 
 			unrelated := corev1.Secret{}
@@ -145,6 +153,7 @@ func TestOIDCClientWatcherControllerFilterOIDCClient(t *testing.T) {
 				secretInformer,
 				oidcClientsInformer,
 				withInformer.WithInformer,
+				false,
 			)
 
 			unrelated := supervisorconfigv1alpha1.OIDCClient{}
@@ -224,6 +233,63 @@ func TestOIDCClientWatcherControllerSync(t *testing.T) {
 		}
 	}
 
+	happyClientAuthenticationCondition := func(time metav1.Time, observedGeneration int64) metav1.Condition {
+		return metav1.Condition{
+			Type:               "ClientAuthenticationValid",
+			Status:             "True",
+			LastTransitionTime: time,
+			Reason:             "Success",
+			Message:            `client authentication method "client_secret_basic" is valid`,
+			ObservedGeneration: observedGeneration,
+		}
+	}
+
+	happyClientAuthenticationConditionForMethod := func(method string, time metav1.Time, observedGeneration int64) metav1.Condition {
+		return metav1.Condition{
+			Type:               "ClientAuthenticationValid",
+			Status:             "True",
+			LastTransitionTime: time,
+			Reason:             "Success",
+			Message:            fmt.Sprintf(`client authentication method %q is valid`, method),
+			ObservedGeneration: observedGeneration,
+		}
+	}
+
+	sadClientAuthenticationCondition := func(time metav1.Time, observedGeneration int64, reason, message string) metav1.Condition {
+		return metav1.Condition{
+			Type:               "ClientAuthenticationValid",
+			Status:             "False",
+			LastTransitionTime: time,
+			Reason:             reason,
+			Message:            message,
+			ObservedGeneration: observedGeneration,
+		}
+	}
+
+	notRequiredClientSecretsCondition := func(method string, time metav1.Time, observedGeneration int64) metav1.Condition {
+		return metav1.Condition{
+			Type:               "ClientSecretExists",
+			Status:             "True",
+			LastTransitionTime: time,
+			Reason:             "NotRequired",
+			Message:            fmt.Sprintf(`client secrets are not used when "tokenEndpointAuthMethod" is %q`, method),
+			ObservedGeneration: observedGeneration,
+		}
+	}
+
+	ecdsaKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	jwksBytes, err := json.Marshal(jose.JSONWebKeySet{Keys: []jose.JSONWebKey{{Key: ecdsaKey.Public(), KeyID: "some-key", Use: "sig"}}})
+	require.NoError(t, err)
+	testJWKS := string(jwksBytes)
+	privateJWKSBytes, err := json.Marshal(jose.JSONWebKeySet{Keys: []jose.JSONWebKey{{Key: ecdsaKey, KeyID: "some-key", Use: "sig"}}})
+	require.NoError(t, err)
+	testPrivateJWKS := string(privateJWKSBytes)
+
+	testCA, err := certauthority.New("test-ca", time.Hour)
+	require.NoError(t, err)
+	testCABundle := base64.StdEncoding.EncodeToString(testCA.Bundle())
+
 	happyAllowedScopesCondition := func(time metav1.Time, observedGeneration int64) metav1.Condition {
 		return metav1.Condition{
 			Type:               "AllowedScopesValid",
@@ -247,12 +313,13 @@ func TestOIDCClientWatcherControllerSync(t *testing.T) {
 	}
 
 	tests := []struct {
-		name                     string
-		inputObjects             []runtime.Object
-		inputSecrets             []runtime.Object
-		wantErr                  string
-		wantResultingOIDCClients []supervisorconfigv1alpha1.OIDCClient
-		wantAPIActions           int
+		name                        string
+		inputObjects                []runtime.Object
+		inputSecrets                []runtime.Object
+		clientCertificatesRequested bool
+		wantErr                     string
+		wantResultingOIDCClients    []supervisorconfigv1alpha1.OIDCClient
+		wantAPIActions              int
 	}{
 		{
 			name:           "no OIDCClients",
@@ -295,6 +362,7 @@ func TestOIDCClientWatcherControllerSync(t *testing.T) {
 						Conditions: []metav1.Condition{
 							happyAllowedGrantTypesCondition(now, 1234),
 							happyAllowedScopesCondition(now, 1234),
+							happyClientAuthenticationCondition(now, 1234),
 							happyClientSecretsCondition(1, now, 1234),
 						},
 						TotalClientSecrets: 1,
@@ -320,6 +388,7 @@ func TestOIDCClientWatcherControllerSync(t *testing.T) {
 					Conditions: []metav1.Condition{
 						happyAllowedGrantTypesCondition(now, 1234),
 						happyAllowedScopesCondition(now, 1234),
+						happyClientAuthenticationCondition(now, 1234),
 						happyClientSecretsCondition(2, now, 1234),
 					},
 					TotalClientSecrets: 2,
@@ -339,6 +408,7 @@ func TestOIDCClientWatcherControllerSync(t *testing.T) {
 					Conditions: []metav1.Condition{
 						happyAllowedGrantTypesCondition(earlier, 1234),
 						happyAllowedScopesCondition(earlier, 1234),
+						happyClientAuthenticationCondition(earlier, 1234),
 						happyClientSecretsCondition(1, earlier, 1234),
 					},
 					TotalClientSecrets: 1,
@@ -353,6 +423,7 @@ func TestOIDCClientWatcherControllerSync(t *testing.T) {
 					Conditions: []metav1.Condition{
 						happyAllowedGrantTypesCondition(earlier, 1234),
 						happyAllowedScopesCondition(earlier, 1234),
+						happyClientAuthenticationCondition(earlier, 1234),
 						happyClientSecretsCondition(1, earlier, 1234),
 					},
 					TotalClientSecrets: 1,
@@ -371,8 +442,9 @@ func TestOIDCClientWatcherControllerSync(t *testing.T) {
 				Status: supervisorconfigv1alpha1.OIDCClientStatus{
 					Phase: "Error",
 					Conditions: []metav1.Condition{
-						sadAllowedGrantTypesCondition(now, 1234, `"authorization_code" must always be included in "allowedGrantTypes"`),
+						sadAllowedGrantTypesCondition(now, 1234, `"authorization_code" or "client_credentials" must be included in "allowedGrantTypes"`),
 						sadAllowedScopesCondition(now, 1234, `"openid" must always be included in "allowedScopes"`),
+						happyClientAuthenticationCondition(now, 1234),
 						sadNoClientSecretsCondition(now, 1234, "no client secret found (no Secret storage found)"),
 					},
 				},
@@ -396,6 +468,7 @@ func TestOIDCClientWatcherControllerSync(t *testing.T) {
 					Conditions: []metav1.Condition{
 						happyAllowedGrantTypesCondition(now, 1234),
 						happyAllowedScopesCondition(now, 1234),
+						happyClientAuthenticationCondition(now, 1234),
 						sadNoClientSecretsCondition(now, 1234, "error reading client secret storage: OIDC client secret storage data has wrong version: OIDC client secret storage has version wrong-version instead of 1"),
 					},
 				},
@@ -419,6 +492,7 @@ func TestOIDCClientWatcherControllerSync(t *testing.T) {
 					Conditions: []metav1.Condition{
 						happyAllowedGrantTypesCondition(now, 1234),
 						happyAllowedScopesCondition(now, 1234),
+						happyClientAuthenticationCondition(now, 1234),
 						sadNoClientSecretsCondition(now, 1234, "no client secret found (empty list in storage)"),
 					},
 					TotalClientSecrets: 0,
@@ -446,6 +520,7 @@ func TestOIDCClientWatcherControllerSync(t *testing.T) {
 					Conditions: []metav1.Condition{
 						happyAllowedGrantTypesCondition(now, 1234),
 						happyAllowedScopesCondition(now, 1234),
+						happyClientAuthenticationCondition(now, 1234),
 						sadInvalidClientSecretsCondition(now, 1234,
 							"3 stored client secrets found, but some were invalid, so none will be used: "+
 								"hashed client secret at index 1: bcrypt cost 11 is below the required minimum of 12; "+
@@ -480,6 +555,7 @@ func TestOIDCClientWatcherControllerSync(t *testing.T) {
 						Conditions: []metav1.Condition{
 							happyAllowedGrantTypesCondition(now, 1234),
 							happyAllowedScopesCondition(now, 1234),
+							happyClientAuthenticationCondition(now, 1234),
 							happyClientSecretsCondition(1, now, 1234),
 						},
 						TotalClientSecrets: 1,
@@ -490,8 +566,9 @@ func TestOIDCClientWatcherControllerSync(t *testing.T) {
 					Status: supervisorconfigv1alpha1.OIDCClientStatus{
 						Phase: "Error",
 						Conditions: []metav1.Condition{
-							sadAllowedGrantTypesCondition(now, 4567, `"authorization_code" must always be included in "allowedGrantTypes"`),
+							sadAllowedGrantTypesCondition(now, 4567, `"authorization_code" or "client_credentials" must be included in "allowedGrantTypes"`),
 							sadAllowedScopesCondition(now, 4567, `"openid" must always be included in "allowedScopes"`),
+							happyClientAuthenticationCondition(now, 4567),
 							sadNoClientSecretsCondition(now, 4567, "no client secret found (no Secret storage found)"),
 						},
 						TotalClientSecrets: 0,
@@ -511,8 +588,9 @@ func TestOIDCClientWatcherControllerSync(t *testing.T) {
 				Status: supervisorconfigv1alpha1.OIDCClientStatus{
 					Phase: "Error",
 					Conditions: []metav1.Condition{
-						sadAllowedGrantTypesCondition(earlier, 1234, `"authorization_code" must always be included in "allowedGrantTypes"`),
+						sadAllowedGrantTypesCondition(earlier, 1234, `"authorization_code" or "client_credentials" must be included in "allowedGrantTypes"`),
 						sadAllowedScopesCondition(earlier, 1234, `"openid" must always be included in "allowedScopes"`),
+						happyClientAuthenticationCondition(earlier, 1234),
 						happyClientSecretsCondition(1, earlier, 1234),
 					},
 					TotalClientSecrets: 1,
@@ -528,7 +606,8 @@ func TestOIDCClientWatcherControllerSync(t *testing.T) {
 					Conditions: []metav1.Condition{
 						happyAllowedGrantTypesCondition(now, 4567),
 						happyAllowedScopesCondition(now, 4567),
-						happyClientSecretsCondition(1, earlier, 4567), // was already validated earlier
+						happyClientAuthenticationCondition(earlier, 4567), // was already validated earlier
+						happyClientSecretsCondition(1, earlier, 4567),     // was already validated earlier
 					},
 					TotalClientSecrets: 1,
 				},
@@ -552,6 +631,7 @@ func TestOIDCClientWatcherControllerSync(t *testing.T) {
 					Conditions: []metav1.Condition{
 						sadAllowedGrantTypesCondition(now, 1234, `"refresh_token" must be included in "allowedGrantTypes" when "offline_access" is included in "allowedScopes"`),
 						happyAllowedScopesCondition(now, 1234),
+						happyClientAuthenticationCondition(now, 1234),
 						happyClientSecretsCondition(1, now, 1234),
 					},
 					TotalClientSecrets: 1,
@@ -575,12 +655,13 @@ func TestOIDCClientWatcherControllerSync(t *testing.T) {
 					Phase: "Error",
 					Conditions: []metav1.Condition{
 						sadAllowedGrantTypesCondition(now, 1234,
-							`"authorization_code" must always be included in "allowedGrantTypes"; `+
+							`"authorization_code" or "client_credentials" must be included in "allowedGrantTypes"; `+
 								`"urn:ietf:params:oauth:grant-type:token-exchange" must be included in "allowedGrantTypes" when "pinniped:request-audience" is included in "allowedScopes"`),
 						sadAllowedScopesCondition(now, 1234,
 							`"openid" must always be included in "allowedScopes"; `+
 								`"offline_access" must be included in "allowedScopes" when "refresh_token" is included in "allowedGrantTypes"; `+
 								`"username" and "groups" must be included in "allowedScopes" when "pinniped:request-audience" is included in "allowedScopes"`),
+						happyClientAuthenticationCondition(now, 1234),
 						happyClientSecretsCondition(1, now, 1234),
 					},
 					TotalClientSecrets: 1,
@@ -604,11 +685,12 @@ func TestOIDCClientWatcherControllerSync(t *testing.T) {
 					Phase: "Error",
 					Conditions: []metav1.Condition{
 						sadAllowedGrantTypesCondition(now, 1234,
-							`"authorization_code" must always be included in "allowedGrantTypes"; `+
+							`"authorization_code" or "client_credentials" must be included in "allowedGrantTypes"; `+
 								`"refresh_token" must be included in "allowedGrantTypes" when "offline_access" is included in "allowedScopes"`),
 						sadAllowedScopesCondition(now, 1234,
 							`"openid" must always be included in "allowedScopes"; `+
 								`"pinniped:request-audience" must be included in "allowedScopes" when "urn:ietf:params:oauth:grant-type:token-exchange" is included in "allowedGrantTypes"`),
+						happyClientAuthenticationCondition(now, 1234),
 						happyClientSecretsCondition(1, now, 1234),
 					},
 					TotalClientSecrets: 1,
@@ -633,6 +715,7 @@ func TestOIDCClientWatcherControllerSync(t *testing.T) {
 					Conditions: []metav1.Condition{
 						sadAllowedGrantTypesCondition(now, 1234, `"urn:ietf:params:oauth:grant-type:token-exchange" must be included in "allowedGrantTypes" when "pinniped:request-audience" is included in "allowedScopes"`),
 						happyAllowedScopesCondition(now, 1234),
+						happyClientAuthenticationCondition(now, 1234),
 						happyClientSecretsCondition(1, now, 1234),
 					},
 					TotalClientSecrets: 1,
@@ -657,6 +740,7 @@ func TestOIDCClientWatcherControllerSync(t *testing.T) {
 					Conditions: []metav1.Condition{
 						happyAllowedGrantTypesCondition(now, 1234),
 						sadAllowedScopesCondition(now, 1234, `"offline_access" must be included in "allowedScopes" when "refresh_token" is included in "allowedGrantTypes"`),
+						happyClientAuthenticationCondition(now, 1234),
 						happyClientSecretsCondition(1, now, 1234),
 					},
 					TotalClientSecrets: 1,
@@ -681,6 +765,7 @@ func TestOIDCClientWatcherControllerSync(t *testing.T) {
 					Conditions: []metav1.Condition{
 						happyAllowedGrantTypesCondition(now, 1234),
 						sadAllowedScopesCondition(now, 1234, `"username" and "groups" must be included in "allowedScopes" when "pinniped:request-audience" is included in "allowedScopes"`),
+						happyClientAuthenticationCondition(now, 1234),
 						happyClientSecretsCondition(1, now, 1234),
 					},
 					TotalClientSecrets: 1,
@@ -705,6 +790,7 @@ func TestOIDCClientWatcherControllerSync(t *testing.T) {
 					Conditions: []metav1.Condition{
 						happyAllowedGrantTypesCondition(now, 1234),
 						sadAllowedScopesCondition(now, 1234, `"username" and "groups" must be included in "allowedScopes" when "pinniped:request-audience" is included in "allowedScopes"`),
+						happyClientAuthenticationCondition(now, 1234),
 						happyClientSecretsCondition(1, now, 1234),
 					},
 					TotalClientSecrets: 1,
@@ -729,6 +815,7 @@ func TestOIDCClientWatcherControllerSync(t *testing.T) {
 					Conditions: []metav1.Condition{
 						happyAllowedGrantTypesCondition(now, 1234),
 						sadAllowedScopesCondition(now, 1234, `"username" and "groups" must be included in "allowedScopes" when "pinniped:request-audience" is included in "allowedScopes"`),
+						happyClientAuthenticationCondition(now, 1234),
 						happyClientSecretsCondition(1, now, 1234),
 					},
 					TotalClientSecrets: 1,
@@ -753,6 +840,7 @@ func TestOIDCClientWatcherControllerSync(t *testing.T) {
 					Conditions: []metav1.Condition{
 						happyAllowedGrantTypesCondition(now, 1234),
 						sadAllowedScopesCondition(now, 1234, `"pinniped:request-audience" must be included in "allowedScopes" when "urn:ietf:params:oauth:grant-type:token-exchange" is included in "allowedGrantTypes"`),
+						happyClientAuthenticationCondition(now, 1234),
 						happyClientSecretsCondition(1, now, 1234),
 					},
 					TotalClientSecrets: 1,
@@ -777,6 +865,87 @@ func TestOIDCClientWatcherControllerSync(t *testing.T) {
 					Conditions: []metav1.Condition{
 						happyAllowedGrantTypesCondition(now, 1234),
 						happyAllowedScopesCondition(now, 1234),
+						happyClientAuthenticationCondition(now, 1234),
+						happyClientSecretsCondition(1, now, 1234),
+					},
+					TotalClientSecrets: 1,
+				},
+			}},
+		},
+		{
+			name: "successfully validate an OIDCClient which uses client_credentials without authorization_code or redirect URIs",
+			inputObjects: []runtime.Object{&supervisorconfigv1alpha1.OIDCClient{
+				ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: testName, Generation: 1234, UID: testUID},
+				Spec: supervisorconfigv1alpha1.OIDCClientSpec{
+					AllowedGrantTypes: []supervisorconfigv1alpha1.GrantType{"client_credentials", "urn:ietf:params:oauth:grant-type:token-exchange"},
+					AllowedScopes:     []supervisorconfigv1alpha1.Scope{"openid", "pinniped:request-audience", "username", "groups"},
+					ClientCredentials: &supervisorconfigv1alpha1.OIDCClientClientCredentials{Username: "ci-bot", Groups: []string{"ci"}},
+				},
+			}},
+			inputSecrets:   []runtime.Object{testutil.OIDCClientSecretStorageSecretForUID(t, testNamespace, testUID, []string{testutil.HashedPassword1AtSupervisorMinCost})},
+			wantAPIActions: 1, // one update
+			wantResultingOIDCClients: []supervisorconfigv1alpha1.OIDCClient{{
+				ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: testName, Generation: 1234, UID: testUID},
+				Status: supervisorconfigv1alpha1.OIDCClientStatus{
+					Phase: "Ready",
+					Conditions: []metav1.Condition{
+						happyAllowedGrantTypesCondition(now, 1234),
+						happyAllowedScopesCondition(now, 1234),
+						happyClientAuthenticationCondition(now, 1234),
+						happyClientSecretsCondition(1, now, 1234),
+					},
+					TotalClientSecrets: 1,
+				},
+			}},
+		},
+		{
+			name: "client_credentials must be configured when client_credentials is included in allowedGrantTypes",
+			inputObjects: []runtime.Object{&supervisorconfigv1alpha1.OIDCClient{
+				ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: testName, Generation: 1234, UID: testUID},
+				Spec: supervisorconfigv1alpha1.OIDCClientSpec{
+					AllowedGrantTypes: []supervisorconfigv1alpha1.GrantType{"client_credentials"},
+					AllowedScopes:     []supervisorconfigv1alpha1.Scope{"openid"},
+				},
+			}},
+			inputSecrets:   []runtime.Object{testutil.OIDCClientSecretStorageSecretForUID(t, testNamespace, testUID, []string{testutil.HashedPassword1AtSupervisorMinCost})},
+			wantAPIActions: 1, // one update
+			wantResultingOIDCClients: []supervisorconfigv1alpha1.OIDCClient{{
+				ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: testName, Generation: 1234, UID: testUID},
+				Status: supervisorconfigv1alpha1.OIDCClientStatus{
+					Phase: "Error",
+					Conditions: []metav1.Condition{
+						sadAllowedGrantTypesCondition(now, 1234,
+							`"clientCredentials" must be configured when "client_credentials" is included in "allowedGrantTypes"`),
+						happyAllowedScopesCondition(now, 1234),
+						happyClientAuthenticationCondition(now, 1234),
+						happyClientSecretsCondition(1, now, 1234),
+					},
+					TotalClientSecrets: 1,
+				},
+			}},
+		},
+		{
+			name: "client_credentials must be included in allowedGrantTypes when clientCredentials is configured",
+			inputObjects: []runtime.Object{&supervisorconfigv1alpha1.OIDCClient{
+				ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: testName, Generation: 1234, UID: testUID},
+				Spec: supervisorconfigv1alpha1.OIDCClientSpec{
+					AllowedRedirectURIs: []supervisorconfigv1alpha1.RedirectURI{"https://example.com/callback"},
+					AllowedGrantTypes:   []supervisorconfigv1alpha1.GrantType{"authorization_code"},
+					AllowedScopes:       []supervisorconfigv1alpha1.Scope{"openid"},
+					ClientCredentials:   &supervisorconfigv1alpha1.OIDCClientClientCredentials{Username: "ci-bot"},
+				},
+			}},
+			inputSecrets:   []runtime.Object{testutil.OIDCClientSecretStorageSecretForUID(t, testNamespace, testUID, []string{testutil.HashedPassword1AtSupervisorMinCost})},
+			wantAPIActions: 1, // one update
+			wantResultingOIDCClients: []supervisorconfigv1alpha1.OIDCClient{{
+				ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: testName, Generation: 1234, UID: testUID},
+				Status: supervisorconfigv1alpha1.OIDCClientStatus{
+					Phase: "Error",
+					Conditions: []metav1.Condition{
+						sadAllowedGrantTypesCondition(now, 1234,
+							`"client_credentials" must be included in "allowedGrantTypes" when "clientCredentials" is configured`),
+						happyAllowedScopesCondition(now, 1234),
+						happyClientAuthenticationCondition(now, 1234),
 						happyClientSecretsCondition(1, now, 1234),
 					},
 					TotalClientSecrets: 1,
@@ -801,6 +970,7 @@ func TestOIDCClientWatcherControllerSync(t *testing.T) {
 					Conditions: []metav1.Condition{
 						happyAllowedGrantTypesCondition(now, 1234),
 						happyAllowedScopesCondition(now, 1234),
+						happyClientAuthenticationCondition(now, 1234),
 						happyClientSecretsCondition(1, now, 1234),
 					},
 					TotalClientSecrets: 1,
@@ -825,6 +995,7 @@ func TestOIDCClientWatcherControllerSync(t *testing.T) {
 					Conditions: []metav1.Condition{
 						happyAllowedGrantTypesCondition(now, 1234),
 						happyAllowedScopesCondition(now, 1234),
+						happyClientAuthenticationCondition(now, 1234),
 						happyClientSecretsCondition(1, now, 1234),
 					},
 					TotalClientSecrets: 1,
@@ -849,6 +1020,7 @@ func TestOIDCClientWatcherControllerSync(t *testing.T) {
 					Conditions: []metav1.Condition{
 						happyAllowedGrantTypesCondition(now, 1234),
 						happyAllowedScopesCondition(now, 1234),
+						happyClientAuthenticationCondition(now, 1234),
 						happyClientSecretsCondition(1, now, 1234),
 					},
 					TotalClientSecrets: 1,
@@ -873,6 +1045,7 @@ func TestOIDCClientWatcherControllerSync(t *testing.T) {
 					Conditions: []metav1.Condition{
 						happyAllowedGrantTypesCondition(now, 1234),
 						happyAllowedScopesCondition(now, 1234),
+						happyClientAuthenticationCondition(now, 1234),
 						happyClientSecretsCondition(1, now, 1234),
 					},
 					TotalClientSecrets: 1,
@@ -897,6 +1070,7 @@ func TestOIDCClientWatcherControllerSync(t *testing.T) {
 					Conditions: []metav1.Condition{
 						happyAllowedGrantTypesCondition(now, 1234),
 						happyAllowedScopesCondition(now, 1234),
+						happyClientAuthenticationCondition(now, 1234),
 						happyClientSecretsCondition(1, now, 1234),
 					},
 					TotalClientSecrets: 1,
@@ -921,6 +1095,7 @@ func TestOIDCClientWatcherControllerSync(t *testing.T) {
 					Conditions: []metav1.Condition{
 						happyAllowedGrantTypesCondition(now, 1234),
 						happyAllowedScopesCondition(now, 1234),
+						happyClientAuthenticationCondition(now, 1234),
 						happyClientSecretsCondition(1, now, 1234),
 					},
 					TotalClientSecrets: 1,
@@ -945,13 +1120,324 @@ func TestOIDCClientWatcherControllerSync(t *testing.T) {
 					Conditions: []metav1.Condition{
 						happyAllowedGrantTypesCondition(now, 1234),
 						happyAllowedScopesCondition(now, 1234),
+						happyClientAuthenticationCondition(now, 1234),
 						happyClientSecretsCondition(1, now, 1234),
 					},
 					TotalClientSecrets: 1,
 				},
 			}},
 		},
-	}
+
+		{
+			name: "successfully validate an OIDCClient which uses private_key_jwt with an inline JWKS without any client secrets stored",
+			inputObjects: []runtime.Object{&supervisorconfigv1alpha1.OIDCClient{
+				ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: testName, Generation: 1234, UID: testUID},
+				Spec: supervisorconfigv1alpha1.OIDCClientSpec{
+					AllowedGrantTypes:       []supervisorconfigv1alpha1.GrantType{"authorization_code"},
+					AllowedScopes:           []supervisorconfigv1alpha1.Scope{"openid"},
+					TokenEndpointAuthMethod: "private_key_jwt",
+					PrivateKeyJWT:           &supervisorconfigv1alpha1.OIDCClientPrivateKeyJWT{JWKS: testJWKS, SigningAlgorithm: "ES256"},
+				},
+			}},
+			wantAPIActions: 1, // one update
+			wantResultingOIDCClients: []supervisorconfigv1alpha1.OIDCClient{{
+				ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: testName, Generation: 1234, UID: testUID},
+				Status: supervisorconfigv1alpha1.OIDCClientStatus{
+					Phase: "Ready",
+					Conditions: []metav1.Condition{
+						happyAllowedGrantTypesCondition(now, 1234),
+						happyAllowedScopesCondition(now, 1234),
+						happyClientAuthenticationConditionForMethod("private_key_jwt", now, 1234),
+						notRequiredClientSecretsCondition("private_key_jwt", now, 1234),
+					},
+					TotalClientSecrets: 0,
+				},
+			}},
+		},
+		{
+			name: "successfully validate an OIDCClient which uses private_key_jwt with a JWKS URI and ignore its client secrets",
+			inputObjects: []runtime.Object{&supervisorconfigv1alpha1.OIDCClient{
+				ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: testName, Generation: 1234, UID: testUID},
+				Spec: supervisorconfigv1alpha1.OIDCClientSpec{
+					AllowedGrantTypes:       []supervisorconfigv1alpha1.GrantType{"authorization_code"},
+					AllowedScopes:           []supervisorconfigv1alpha1.Scope{"openid"},
+					TokenEndpointAuthMethod: "private_key_jwt",
+					PrivateKeyJWT:           &supervisorconfigv1alpha1.OIDCClientPrivateKeyJWT{JWKSURI: "https://client.example.com/jwks.json"},
+				},
+			}},
+			inputSecrets:   []runtime.Object{testutil.OIDCClientSecretStorageSecretForUID(t, testNamespace, testUID, []string{testutil.HashedPassword1AtSupervisorMinCost})},
+			wantAPIActions: 1, // one update
+			wantResultingOIDCClients: []supervisorconfigv1alpha1.OIDCClient{{
+				ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: testName, Generation: 1234, UID: testUID},
+				Status: supervisorconfigv1alpha1.OIDCClientStatus{
+					Phase: "Ready",
+					Conditions: []metav1.Condition{
+						happyAllowedGrantTypesCondition(now, 1234),
+						happyAllowedScopesCondition(now, 1234),
+						happyClientAuthenticationConditionForMethod("private_key_jwt", now, 1234),
+						notRequiredClientSecretsCondition("private_key_jwt", now, 1234),
+					},
+					TotalClientSecrets: 0,
+				},
+			}},
+		},
+		{
+			name: "successfully validate an OIDCClient which uses tls_client_auth",
+			inputObjects: []runtime.Object{&supervisorconfigv1alpha1.OIDCClient{
+				ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: testName, Generation: 1234, UID: testUID},
+				Spec: supervisorconfigv1alpha1.OIDCClientSpec{
+					AllowedGrantTypes:       []supervisorconfigv1alpha1.GrantType{"authorization_code"},
+					AllowedScopes:           []supervisorconfigv1alpha1.Scope{"openid"},
+					TokenEndpointAuthMethod: "tls_client_auth",
+					TLSClientAuth:           &supervisorconfigv1alpha1.OIDCClientTLSClientAuth{CertificateAuthorityData: testCABundle, SANDNS: "client.example.com"},
+				},
+			}},
+			clientCertificatesRequested: true,
+			wantAPIActions:              1, // one update
+			wantResultingOIDCClients: []supervisorconfigv1alpha1.OIDCClient{{
+				ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: testName, Generation: 1234, UID: testUID},
+				Status: supervisorconfigv1alpha1.OIDCClientStatus{
+					Phase: "Ready",
+					Conditions: []metav1.Condition{
+						happyAllowedGrantTypesCondition(now, 1234),
+						happyAllowedScopesCondition(now, 1234),
+						happyClientAuthenticationConditionForMethod("tls_client_auth", now, 1234),
+						notRequiredClientSecretsCondition("tls_client_auth", now, 1234),
+					},
+					TotalClientSecrets: 0,
+				},
+			}},
+		},
+		{
+			name: "tls_client_auth is invalid when the https listener does not request client certificates",
+			inputObjects: []runtime.Object{&supervisorconfigv1alpha1.OIDCClient{
+				ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: testName, Generation: 1234, UID: testUID},
+				Spec: supervisorconfigv1alpha1.OIDCClientSpec{
+					AllowedGrantTypes:       []supervisorconfigv1alpha1.GrantType{"authorization_code"},
+					AllowedScopes:           []supervisorconfigv1alpha1.Scope{"openid"},
+					TokenEndpointAuthMethod: "tls_client_auth",
+					TLSClientAuth:           &supervisorconfigv1alpha1.OIDCClientTLSClientAuth{CertificateAuthorityData: testCABundle, SANDNS: "client.example.com"},
+				},
+			}},
+			clientCertificatesRequested: false,
+			wantAPIActions:              1, // one update
+			wantResultingOIDCClients: []supervisorconfigv1alpha1.OIDCClient{{
+				ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: testName, Generation: 1234, UID: testUID},
+				Status: supervisorconfigv1alpha1.OIDCClientStatus{
+					Phase: "Error",
+					Conditions: []metav1.Condition{
+						happyAllowedGrantTypesCondition(now, 1234),
+						happyAllowedScopesCondition(now, 1234),
+						sadClientAuthenticationCondition(now, 1234, "ClientCertificatesNotRequested",
+							`client authentication method "tls_client_auth" requires the Supervisor's https listener to set requestClientCertificates`),
+						notRequiredClientSecretsCondition("tls_client_auth", now, 1234),
+					},
+					TotalClientSecrets: 0,
+				},
+			}},
+		},
+		{
+			name: "private_key_jwt requires privateKeyJWT and does not allow tlsClientAuth",
+			inputObjects: []runtime.Object{&supervisorconfigv1alpha1.OIDCClient{
+				ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: testName, Generation: 1234, UID: testUID},
+				Spec: supervisorconfigv1alpha1.OIDCClientSpec{
+					AllowedGrantTypes:       []supervisorconfigv1alpha1.GrantType{"authorization_code"},
+					AllowedScopes:           []supervisorconfigv1alpha1.Scope{"openid"},
+					TokenEndpointAuthMethod: "private_key_jwt",
+					TLSClientAuth:           &supervisorconfigv1alpha1.OIDCClientTLSClientAuth{CertificateAuthorityData: testCABundle, SANDNS: "client.example.com"},
+				},
+			}},
+			wantAPIActions: 1, // one update
+			wantResultingOIDCClients: []supervisorconfigv1alpha1.OIDCClient{{
+				ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: testName, Generation: 1234, UID: testUID},
+				Status: supervisorconfigv1alpha1.OIDCClientStatus{
+					Phase: "Error",
+					Conditions: []metav1.Condition{
+						happyAllowedGrantTypesCondition(now, 1234),
+						happyAllowedScopesCondition(now, 1234),
+						sadClientAuthenticationCondition(now, 1234, "InvalidPrivateKeyJWT",
+							`"privateKeyJWT" must be configured when "tokenEndpointAuthMethod" is "private_key_jwt"; `+
+								`"tlsClientAuth" must not be configured when "tokenEndpointAuthMethod" is "private_key_jwt"`),
+						notRequiredClientSecretsCondition("private_key_jwt", now, 1234),
+					},
+					TotalClientSecrets: 0,
+				},
+			}},
+		},
+		{
+			name: "private_key_jwt does not allow both jwks and jwksURI",
+			inputObjects: []runtime.Object{&supervisorconfigv1alpha1.OIDCClient{
+				ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: testName, Generation: 1234, UID: testUID},
+				Spec: supervisorconfigv1alpha1.OIDCClientSpec{
+					AllowedGrantTypes:       []supervisorconfigv1alpha1.GrantType{"authorization_code"},
+					AllowedScopes:           []supervisorconfigv1alpha1.Scope{"openid"},
+					TokenEndpointAuthMethod: "private_key_jwt",
+					PrivateKeyJWT: &supervisorconfigv1alpha1.OIDCClientPrivateKeyJWT{
+						JWKS:             testJWKS,
+						JWKSURI:          "https://client.example.com/jwks.json",
+						SigningAlgorithm: "ES256",
+					},
+				},
+			}},
+			wantAPIActions: 1, // one update
+			wantResultingOIDCClients: []supervisorconfigv1alpha1.OIDCClient{{
+				ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: testName, Generation: 1234, UID: testUID},
+				Status: supervisorconfigv1alpha1.OIDCClientStatus{
+					Phase: "Error",
+					Conditions: []metav1.Condition{
+						happyAllowedGrantTypesCondition(now, 1234),
+						happyAllowedScopesCondition(now, 1234),
+						sadClientAuthenticationCondition(now, 1234, "InvalidPrivateKeyJWT",
+							`"privateKeyJWT" is invalid: only one of jwks or jwksURI may be configured`),
+						notRequiredClientSecretsCondition("private_key_jwt", now, 1234),
+					},
+					TotalClientSecrets: 0,
+				},
+			}},
+		},
+		{
+			name: "private_key_jwt does not allow private keys in the JWKS",
+			inputObjects: []runtime.Object{&supervisorconfigv1alpha1.OIDCClient{
+				ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: testName, Generation: 1234, UID: testUID},
+				Spec: supervisorconfigv1alpha1.OIDCClientSpec{
+					AllowedGrantTypes:       []supervisorconfigv1alpha1.GrantType{"authorization_code"},
+					AllowedScopes:           []supervisorconfigv1alpha1.Scope{"openid"},
+					TokenEndpointAuthMethod: "private_key_jwt",
+					PrivateKeyJWT:           &supervisorconfigv1alpha1.OIDCClientPrivateKeyJWT{JWKS: testPrivateJWKS, SigningAlgorithm: "ES256"},
+				},
+			}},
+			wantAPIActions: 1, // one update
+			wantResultingOIDCClients: []supervisorconfigv1alpha1.OIDCClient{{
+				ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: testName, Generation: 1234, UID: testUID},
+				Status: supervisorconfigv1alpha1.OIDCClientStatus{
+					Phase: "Error",
+					Conditions: []metav1.Condition{
+						happyAllowedGrantTypesCondition(now, 1234),
+						happyAllowedScopesCondition(now, 1234),
+						sadClientAuthenticationCondition(now, 1234, "InvalidPrivateKeyJWT",
+							`"privateKeyJWT" is invalid: jwks key at index 0: key must be a public key`),
+						notRequiredClientSecretsCondition("private_key_jwt", now, 1234),
+					},
+					TotalClientSecrets: 0,
+				},
+			}},
+		},
+		{
+			name: "private_key_jwt requires the keys in the JWKS to match the signing algorithm",
+			inputObjects: []runtime.Object{&supervisorconfigv1alpha1.OIDCClient{
+				ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: testName, Generation: 1234, UID: testUID},
+				Spec: supervisorconfigv1alpha1.OIDCClientSpec{
+					AllowedGrantTypes:       []supervisorconfigv1alpha1.GrantType{"authorization_code"},
+					AllowedScopes:           []supervisorconfigv1alpha1.Scope{"openid"},
+					TokenEndpointAuthMethod: "private_key_jwt",
+					PrivateKeyJWT:           &supervisorconfigv1alpha1.OIDCClientPrivateKeyJWT{JWKS: testJWKS}, // defaults to RS256
+				},
+			}},
+			wantAPIActions: 1, // one update
+			wantResultingOIDCClients: []supervisorconfigv1alpha1.OIDCClient{{
+				ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: testName, Generation: 1234, UID: testUID},
+				Status: supervisorconfigv1alpha1.OIDCClientStatus{
+					Phase: "Error",
+					Conditions: []metav1.Condition{
+						happyAllowedGrantTypesCondition(now, 1234),
+						happyAllowedScopesCondition(now, 1234),
+						sadClientAuthenticationCondition(now, 1234, "InvalidPrivateKeyJWT",
+							`"privateKeyJWT" is invalid: jwks key at index 0: key must be an RSA key to be used with signingAlgorithm "RS256"`),
+						notRequiredClientSecretsCondition("private_key_jwt", now, 1234),
+					},
+					TotalClientSecrets: 0,
+				},
+			}},
+		},
+		{
+			name: "tls_client_auth requires a valid CA bundle and exactly one certificate identifier",
+			inputObjects: []runtime.Object{&supervisorconfigv1alpha1.OIDCClient{
+				ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: testName, Generation: 1234, UID: testUID},
+				Spec: supervisorconfigv1alpha1.OIDCClientSpec{
+					AllowedGrantTypes:       []supervisorconfigv1alpha1.GrantType{"authorization_code"},
+					AllowedScopes:           []supervisorconfigv1alpha1.Scope{"openid"},
+					TokenEndpointAuthMethod: "tls_client_auth",
+					TLSClientAuth: &supervisorconfigv1alpha1.OIDCClientTLSClientAuth{
+						CertificateAuthorityData: testCABundle,
+						SubjectDN:                "CN=some-client",
+						SANDNS:                   "client.example.com",
+					},
+				},
+			}},
+			wantAPIActions: 1, // one update
+			wantResultingOIDCClients: []supervisorconfigv1alpha1.OIDCClient{{
+				ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: testName, Generation: 1234, UID: testUID},
+				Status: supervisorconfigv1alpha1.OIDCClientStatus{
+					Phase: "Error",
+					Conditions: []metav1.Condition{
+						happyAllowedGrantTypesCondition(now, 1234),
+						happyAllowedScopesCondition(now, 1234),
+						sadClientAuthenticationCondition(now, 1234, "InvalidTLSClientAuth",
+							`"tlsClientAuth" is invalid: exactly one of subjectDN, sanDNS, or sanURI must be configured`),
+						notRequiredClientSecretsCondition("tls_client_auth", now, 1234),
+					},
+					TotalClientSecrets: 0,
+				},
+			}},
+		},
+		{
+			name: "tls_client_auth requires a CA bundle which contains certificates",
+			inputObjects: []runtime.Object{&supervisorconfigv1alpha1.OIDCClient{
+				ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: testName, Generation: 1234, UID: testUID},
+				Spec: supervisorconfigv1alpha1.OIDCClientSpec{
+					AllowedGrantTypes:       []supervisorconfigv1alpha1.GrantType{"authorization_code"},
+					AllowedScopes:           []supervisorconfigv1alpha1.Scope{"openid"},
+					TokenEndpointAuthMethod: "tls_client_auth",
+					TLSClientAuth: &supervisorconfigv1alpha1.OIDCClientTLSClientAuth{
+						CertificateAuthorityData: base64.StdEncoding.EncodeToString([]byte("not a certificate")),
+						SubjectDN:                "CN=some-client",
+					},
+				},
+			}},
+			wantAPIActions: 1, // one update
+			wantResultingOIDCClients: []supervisorconfigv1alpha1.OIDCClient{{
+				ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: testName, Generation: 1234, UID: testUID},
+				Status: supervisorconfigv1alpha1.OIDCClientStatus{
+					Phase: "Error",
+					Conditions: []metav1.Condition{
+						happyAllowedGrantTypesCondition(now, 1234),
+						happyAllowedScopesCondition(now, 1234),
+						sadClientAuthenticationCondition(now, 1234, "InvalidTLSClientAuth",
+							`"tlsClientAuth" is invalid: certificateAuthorityData does not contain any PEM certificates `+
+								`(PEM certificates must begin with "-----BEGIN CERTIFICATE-----")`),
+						notRequiredClientSecretsCondition("tls_client_auth", now, 1234),
+					},
+					TotalClientSecrets: 0,
+				},
+			}},
+		},
+		{
+			name: "client_secret_basic does not allow privateKeyJWT",
+			inputObjects: []runtime.Object{&supervisorconfigv1alpha1.OIDCClient{
+				ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: testName, Generation: 1234, UID: testUID},
+				Spec: supervisorconfigv1alpha1.OIDCClientSpec{
+					AllowedGrantTypes: []supervisorconfigv1alpha1.GrantType{"authorization_code"},
+					AllowedScopes:     []supervisorconfigv1alpha1.Scope{"openid"},
+					PrivateKeyJWT:     &supervisorconfigv1alpha1.OIDCClientPrivateKeyJWT{JWKS: testJWKS, SigningAlgorithm: "ES256"},
+				},
+			}},
+			inputSecrets:   []runtime.Object{testutil.OIDCClientSecretStorageSecretForUID(t, testNamespace, testUID, []string{testutil.HashedPassword1AtSupervisorMinCost})},
+			wantAPIActions: 1, // one update
+			wantResultingOIDCClients: []supervisorconfigv1alpha1.OIDCClient{{
+				ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: testName, Generation: 1234, UID: testUID},
+				Status: supervisorconfigv1alpha1.OIDCClientStatus{
+					Phase: "Error",
+					Conditions: []metav1.Condition{
+						happyAllowedGrantTypesCondition(now, 1234),
+						happyAllowedScopesCondition(now, 1234),
+						sadClientAuthenticationCondition(now, 1234, "InvalidPrivateKeyJWT",
+							`"privateKeyJWT" must not be configured when "tokenEndpointAuthMethod" is "client_secret_basic"`),
+						happyClientSecretsCondition(1, now, 1234),
+					},
+					TotalClientSecrets: 1,
+				},
+			}},
+		}}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -968,6 +1454,7 @@ func TestOIDCClientWatcherControllerSync(t *testing.T) {
 				kubeInformers.Core().V1().Secrets(),
 				pinnipedInformers.Config().V1alpha1().OIDCClients(),
 				controllerlib.WithInformer,
+				tt.clientCertificatesRequested,
 			)
 
 			ctx, cancel := context.WithCancel(context.Background())
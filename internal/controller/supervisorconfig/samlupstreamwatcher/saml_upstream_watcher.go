@@ -0,0 +1,373 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package samlupstreamwatcher implements a controller which watches SAMLIdentityProviders.
+package samlupstreamwatcher
+
+import (
+	"context"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/crewjam/saml"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/cache"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	corev1informers "k8s.io/client-go/informers/core/v1"
+	"k8s.io/utils/clock"
+
+	idpv1alpha1 "go.pinniped.dev/generated/latest/apis/supervisor/idp/v1alpha1"
+	supervisorclientset "go.pinniped.dev/generated/latest/client/supervisor/clientset/versioned"
+	idpinformers "go.pinniped.dev/generated/latest/client/supervisor/informers/externalversions/idp/v1alpha1"
+	pinnipedcontroller "go.pinniped.dev/internal/controller"
+	"go.pinniped.dev/internal/controller/conditionsutil"
+	"go.pinniped.dev/internal/controller/tlsconfigutil"
+	"go.pinniped.dev/internal/controllerlib"
+	"go.pinniped.dev/internal/federationdomain/upstreamprovider"
+	"go.pinniped.dev/internal/net/phttp"
+	"go.pinniped.dev/internal/plog"
+	"go.pinniped.dev/internal/upstreamsaml"
+)
+
+const (
+	controllerName = "saml-upstream-observer"
+
+	countExpectedConditions = 2
+
+	TLSConfigurationValid string = "TLSConfigurationValid"
+	SAMLMetadataValid     string = "SAMLMetadataValid"
+
+	reasonUnreachable     = "Unreachable"
+	reasonInvalidMetadata = "InvalidMetadata"
+
+	// metadataCacheTTL controls how often the metadata is downloaded again from spec.metadata.url,
+	// which allows the Supervisor to notice when the IDP rotates its signing certificates.
+	metadataCacheTTL = 15 * time.Minute
+
+	// maxMetadataBytes limits the size of the metadata document that will be read from spec.metadata.url.
+	// Metadata which describes many entities can be large, but should not be larger than this.
+	maxMetadataBytes = 10 * 1024 * 1024
+)
+
+// UpstreamSAMLIdentityProviderICache is a thread safe cache that holds a list of validated upstream SAML IDP configurations.
+type UpstreamSAMLIdentityProviderICache interface {
+	SetSAMLIdentityProviders([]upstreamprovider.UpstreamSAMLIdentityProviderI)
+}
+
+// metadataCacheKey is the type of keys in a metadataCache.
+type metadataCacheKey struct {
+	url          string
+	caBundleHash tlsconfigutil.CABundleHash
+}
+
+// metadataCache caches the parsed metadata which was downloaded from a particular URL using a particular
+// CA bundle, for a limited time (TTL).
+type metadataCache struct{ cache *cache.Expiring }
+
+func (c *metadataCache) get(key metadataCacheKey) *saml.EntityDescriptor {
+	if result, ok := c.cache.Get(key); ok {
+		return result.(*saml.EntityDescriptor)
+	}
+	return nil
+}
+
+func (c *metadataCache) put(key metadataCacheKey, value *saml.EntityDescriptor) {
+	c.cache.Set(key, value, metadataCacheTTL)
+}
+
+type samlWatcherController struct {
+	namespace                    string
+	cache                        UpstreamSAMLIdentityProviderICache
+	log                          plog.Logger
+	client                       supervisorclientset.Interface
+	samlIdentityProviderInformer idpinformers.SAMLIdentityProviderInformer
+	secretInformer               corev1informers.SecretInformer
+	configMapInformer            corev1informers.ConfigMapInformer
+	clock                        clock.Clock
+	metadataCache                *metadataCache
+}
+
+// New instantiates a new controllerlib.Controller which will populate the provided UpstreamSAMLIdentityProviderICache.
+func New(
+	namespace string,
+	idpCache UpstreamSAMLIdentityProviderICache,
+	client supervisorclientset.Interface,
+	samlIdentityProviderInformer idpinformers.SAMLIdentityProviderInformer,
+	secretInformer corev1informers.SecretInformer,
+	configMapInformer corev1informers.ConfigMapInformer,
+	log plog.Logger,
+	withInformer pinnipedcontroller.WithInformerOptionFunc,
+	clock clock.Clock,
+	metadataCacheStorage *cache.Expiring,
+) controllerlib.Controller {
+	c := samlWatcherController{
+		namespace:                    namespace,
+		cache:                        idpCache,
+		client:                       client,
+		log:                          log.WithName(controllerName),
+		samlIdentityProviderInformer: samlIdentityProviderInformer,
+		secretInformer:               secretInformer,
+		configMapInformer:            configMapInformer,
+		clock:                        clock,
+		metadataCache:                &metadataCache{cache: metadataCacheStorage},
+	}
+
+	return controllerlib.New(
+		controllerlib.Config{Name: controllerName, Syncer: &c},
+		withInformer(
+			samlIdentityProviderInformer,
+			pinnipedcontroller.MatchAnythingFilter(pinnipedcontroller.SingletonQueue()),
+			controllerlib.InformerOption{},
+		),
+		withInformer(
+			secretInformer,
+			pinnipedcontroller.MatchAnySecretOfTypesFilter(
+				[]corev1.SecretType{
+					corev1.SecretTypeOpaque,
+					corev1.SecretTypeTLS,
+				},
+				pinnipedcontroller.SingletonQueue(),
+			),
+			controllerlib.InformerOption{},
+		),
+		withInformer(
+			configMapInformer,
+			pinnipedcontroller.MatchAnythingFilter(pinnipedcontroller.SingletonQueue()),
+			controllerlib.InformerOption{},
+		),
+	)
+}
+
+// Sync implements controllerlib.Syncer.
+func (c *samlWatcherController) Sync(ctx controllerlib.Context) error {
+	actualUpstreams, err := c.samlIdentityProviderInformer.Lister().List(labels.Everything())
+	if err != nil { // untested
+		return fmt.Errorf("failed to list SAMLIdentityProviders: %w", err)
+	}
+
+	// Sort them by name just so that the logs output is consistent
+	slices.SortStableFunc(actualUpstreams, func(a, b *idpv1alpha1.SAMLIdentityProvider) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	var applicationErrors []error
+	validatedUpstreams := make([]upstreamprovider.UpstreamSAMLIdentityProviderI, 0, len(actualUpstreams))
+	for _, upstream := range actualUpstreams {
+		validatedUpstream, applicationErr := c.validateUpstreamAndUpdateConditions(ctx.Context, upstream)
+		if applicationErr != nil {
+			applicationErrors = append(applicationErrors, applicationErr)
+		} else if validatedUpstream != nil {
+			validatedUpstreams = append(validatedUpstreams, validatedUpstream)
+		}
+		// Else:
+		// If both validatedUpstream and applicationErr are nil, this must be because the upstream had configuration errors.
+		// This controller should take no action until the user has reconfigured the upstream.
+	}
+	c.cache.SetSAMLIdentityProviders(validatedUpstreams)
+
+	// If we have recoverable application errors, let's do a requeue and capture all the applicationErrors too
+	if len(applicationErrors) > 0 {
+		applicationErrors = append([]error{controllerlib.ErrSyntheticRequeue}, applicationErrors...)
+	}
+
+	return utilerrors.NewAggregate(applicationErrors)
+}
+
+func (c *samlWatcherController) validateUpstreamAndUpdateConditions(ctx context.Context, upstream *idpv1alpha1.SAMLIdentityProvider) (
+	*upstreamsaml.Provider, // If validated, returns the config
+	error, // This error will only refer to programmatic errors such as failing to update the status, not configuration errors
+) {
+	conditions := make([]*metav1.Condition, 0, countExpectedConditions)
+	applicationErrors := make([]error, 0)
+
+	tlsConfigCondition, caBundle := tlsconfigutil.ValidateTLSConfig(
+		tlsconfigutil.TLSSpecForSupervisor(upstream.Spec.Metadata.TLS),
+		"spec.metadata.tls",
+		c.namespace,
+		c.secretInformer,
+		c.configMapInformer)
+	conditions = append(conditions, tlsConfigCondition)
+
+	metadataCondition, idpMetadata := c.validateMetadata(ctx, upstream, caBundle, tlsConfigCondition.Status == metav1.ConditionTrue)
+	conditions = append(conditions, metadataCondition)
+
+	// The critical pattern to maintain is that every run of the sync loop will populate the exact number of the exact
+	// same set of conditions.  Conditions depending on other conditions should get Status: metav1.ConditionUnknown, or
+	// Status: metav1.ConditionFalse, never be omitted.
+	if len(conditions) != countExpectedConditions { // untested since all code paths return the same number of conditions
+		applicationErrors = append(applicationErrors, fmt.Errorf("expected %d conditions but found %d conditions", countExpectedConditions, len(conditions)))
+		return nil, utilerrors.NewAggregate(applicationErrors)
+	}
+	hadErrorCondition, updateStatusErr := c.updateStatus(ctx, upstream, conditions)
+	if updateStatusErr != nil {
+		applicationErrors = append(applicationErrors, updateStatusErr)
+	}
+	// Any error condition means we will not add the IDP to the cache, so just return nil here
+	if hadErrorCondition {
+		return nil, utilerrors.NewAggregate(applicationErrors)
+	}
+
+	provider := upstreamsaml.New(
+		upstreamsaml.ProviderConfig{
+			Name:              upstream.Name,
+			ResourceUID:       upstream.UID,
+			IDPMetadata:       idpMetadata,
+			SPEntityID:        upstream.Spec.ServiceProvider.EntityID,
+			UsernameAttribute: upstream.Spec.Claims.Username,
+			GroupsAttribute:   upstream.Spec.Claims.Groups,
+		},
+	)
+	return provider, utilerrors.NewAggregate(applicationErrors)
+}
+
+func (c *samlWatcherController) validateMetadata(
+	ctx context.Context,
+	upstream *idpv1alpha1.SAMLIdentityProvider,
+	caBundle *tlsconfigutil.CABundle,
+	tlsConfigIsValid bool,
+) (*metav1.Condition, *saml.EntityDescriptor) {
+	buildFalseCondition := func(reason, message string) (*metav1.Condition, *saml.EntityDescriptor) {
+		return &metav1.Condition{
+			Type:    SAMLMetadataValid,
+			Status:  metav1.ConditionFalse,
+			Reason:  reason,
+			Message: message,
+		}, nil
+	}
+
+	metadataSpec := upstream.Spec.Metadata
+
+	// CEL validation should prevent these, but only for recent versions of Kubernetes.
+	if metadataSpec.URL != "" && metadataSpec.Inline != "" {
+		return buildFalseCondition(reasonInvalidMetadata, "only one of spec.metadata.url or spec.metadata.inline may be set")
+	}
+	if metadataSpec.URL == "" && metadataSpec.Inline == "" {
+		return buildFalseCondition(reasonInvalidMetadata, "one of spec.metadata.url or spec.metadata.inline must be set")
+	}
+
+	if metadataSpec.Inline != "" {
+		idpMetadata, err := upstreamsaml.ParseMetadata([]byte(metadataSpec.Inline))
+		if err != nil {
+			return buildFalseCondition(reasonInvalidMetadata,
+				fmt.Sprintf("failed to parse spec.metadata.inline: %s", pinnipedcontroller.TruncateMostLongErr(err)))
+		}
+		return &metav1.Condition{
+			Type:    SAMLMetadataValid,
+			Status:  metav1.ConditionTrue,
+			Reason:  conditionsutil.ReasonSuccess,
+			Message: fmt.Sprintf("loaded metadata for IDP with entity ID %q from spec.metadata.inline", idpMetadata.EntityID),
+		}, idpMetadata
+	}
+
+	if !tlsConfigIsValid {
+		return &metav1.Condition{
+			Type:    SAMLMetadataValid,
+			Status:  metav1.ConditionUnknown,
+			Reason:  conditionsutil.ReasonUnableToValidate,
+			Message: conditionsutil.MessageUnableToValidate,
+		}, nil
+	}
+
+	cacheKey := metadataCacheKey{url: metadataSpec.URL, caBundleHash: caBundle.Hash()}
+	idpMetadata := c.metadataCache.get(cacheKey)
+	if idpMetadata != nil {
+		c.log.WithValues(
+			"namespace", upstream.Namespace,
+			"name", upstream.Name,
+			"url", metadataSpec.URL,
+		).Debug("found previously downloaded SAML metadata in cache")
+	} else {
+		metadataBytes, err := fetchMetadata(ctx, defaultClientShortTimeout(caBundle.CertPool()), metadataSpec.URL)
+		if err != nil {
+			c.log.WithValues(
+				"namespace", upstream.Namespace,
+				"name", upstream.Name,
+				"url", metadataSpec.URL,
+			).Error("failed to download SAML metadata", err)
+			return buildFalseCondition(reasonUnreachable,
+				fmt.Sprintf("failed to download metadata from %q:\n%s", metadataSpec.URL, pinnipedcontroller.TruncateMostLongErr(err)))
+		}
+
+		idpMetadata, err = upstreamsaml.ParseMetadata(metadataBytes)
+		if err != nil {
+			return buildFalseCondition(reasonInvalidMetadata,
+				fmt.Sprintf("failed to parse metadata from %q: %s", metadataSpec.URL, pinnipedcontroller.TruncateMostLongErr(err)))
+		}
+
+		// Update the cache with the newly downloaded value.
+		c.metadataCache.put(cacheKey, idpMetadata)
+	}
+
+	return &metav1.Condition{
+		Type:    SAMLMetadataValid,
+		Status:  metav1.ConditionTrue,
+		Reason:  conditionsutil.ReasonSuccess,
+		Message: fmt.Sprintf("loaded metadata for IDP with entity ID %q from %q", idpMetadata.EntityID, metadataSpec.URL),
+	}, idpMetadata
+}
+
+func fetchMetadata(ctx context.Context, client *http.Client, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected response status code %d", resp.StatusCode)
+	}
+
+	return io.ReadAll(io.LimitReader(resp.Body, maxMetadataBytes))
+}
+
+func defaultClientShortTimeout(rootCAs *x509.CertPool) *http.Client {
+	c := phttp.Default(rootCAs)
+	c.Timeout = time.Minute
+	return c
+}
+
+func (c *samlWatcherController) updateStatus(
+	ctx context.Context,
+	upstream *idpv1alpha1.SAMLIdentityProvider,
+	conditions []*metav1.Condition) (bool, error) {
+	log := c.log.WithValues("namespace", upstream.Namespace, "name", upstream.Name)
+	updated := upstream.DeepCopy()
+
+	hadErrorCondition := conditionsutil.MergeConditions(
+		conditions,
+		&updated.Status.Conditions,
+		upstream.Generation,
+		metav1.NewTime(c.clock.Now()),
+		log,
+	)
+
+	updated.Status.Phase = idpv1alpha1.SAMLPhaseReady
+	if hadErrorCondition {
+		updated.Status.Phase = idpv1alpha1.SAMLPhaseError
+	}
+
+	if equality.Semantic.DeepEqual(upstream, updated) {
+		return hadErrorCondition, nil
+	}
+
+	log.Info("updating SAMLIdentityProvider status", "phase", updated.Status.Phase)
+
+	_, updateStatusError := c.client.
+		IDPV1alpha1().
+		SAMLIdentityProviders(upstream.Namespace).
+		UpdateStatus(ctx, updated, metav1.UpdateOptions{})
+	return hadErrorCondition, updateStatusError
+}
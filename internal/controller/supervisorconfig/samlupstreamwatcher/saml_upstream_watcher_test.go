@@ -0,0 +1,678 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package samlupstreamwatcher
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/crewjam/saml"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/cache"
+	k8sinformers "k8s.io/client-go/informers"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/utils/clock"
+	clocktesting "k8s.io/utils/clock/testing"
+	"k8s.io/utils/ptr"
+
+	idpv1alpha1 "go.pinniped.dev/generated/latest/apis/supervisor/idp/v1alpha1"
+	supervisorfake "go.pinniped.dev/generated/latest/client/supervisor/clientset/versioned/fake"
+	supervisorinformers "go.pinniped.dev/generated/latest/client/supervisor/informers/externalversions"
+	"go.pinniped.dev/internal/controller/conditionsutil"
+	"go.pinniped.dev/internal/controller/tlsconfigutil"
+	"go.pinniped.dev/internal/controllerlib"
+	"go.pinniped.dev/internal/federationdomain/dynamicupstreamprovider"
+	"go.pinniped.dev/internal/federationdomain/upstreamprovider"
+	"go.pinniped.dev/internal/plog"
+	"go.pinniped.dev/internal/testutil"
+	"go.pinniped.dev/internal/testutil/samltestutil"
+	"go.pinniped.dev/internal/testutil/tlsserver"
+	"go.pinniped.dev/internal/upstreamsaml"
+)
+
+func TestController(t *testing.T) {
+	require.Equal(t, 2, countExpectedConditions)
+
+	idp := samltestutil.NewIDP(t, "https://idp.example.com")
+
+	metadataServer, metadataServerCA := tlsserver.TestServerIPv4(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/metadata":
+			idp.MetadataHandler().ServeHTTP(w, r)
+		case "/not-metadata":
+			_, _ = w.Write([]byte(`<html><body>this is not SAML metadata</body></html>`))
+		default:
+			http.NotFound(w, r)
+		}
+	}), nil)
+	metadataServerCAB64 := base64.StdEncoding.EncodeToString(metadataServerCA)
+	goodMetadataURL := metadataServer.URL + "/metadata"
+
+	wantObservedGeneration := int64(1234)
+	namespace := "some-namespace"
+
+	wantFrozenTime := time.Date(1122, time.September, 33, 4, 55, 56, 778899, time.Local)
+	frozenClockForLastTransitionTime := clocktesting.NewFakeClock(wantFrozenTime)
+	wantLastTransitionTime := metav1.Time{Time: wantFrozenTime}
+
+	validInlineIDP := &idpv1alpha1.SAMLIdentityProvider{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:       "inline-idp-name",
+			Namespace:  namespace,
+			UID:        types.UID("inline-uid"),
+			Generation: wantObservedGeneration,
+		},
+		Spec: idpv1alpha1.SAMLIdentityProviderSpec{
+			Metadata: idpv1alpha1.SAMLMetadataSpec{
+				Inline: string(idp.MetadataXML()),
+			},
+			Claims: idpv1alpha1.SAMLClaims{
+				Username: "some-username-attribute",
+				Groups:   "some-groups-attribute",
+			},
+			ServiceProvider: idpv1alpha1.SAMLServiceProviderSpec{
+				EntityID: "https://sp.example.com",
+			},
+		},
+	}
+
+	validURLIDP := &idpv1alpha1.SAMLIdentityProvider{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:       "url-idp-name",
+			Namespace:  namespace,
+			UID:        types.UID("url-uid"),
+			Generation: wantObservedGeneration,
+		},
+		Spec: idpv1alpha1.SAMLIdentityProviderSpec{
+			Metadata: idpv1alpha1.SAMLMetadataSpec{
+				URL: goodMetadataURL,
+				TLS: &idpv1alpha1.TLSSpec{
+					CertificateAuthorityData: metadataServerCAB64,
+				},
+			},
+		},
+	}
+
+	withMetadata := func(upstream *idpv1alpha1.SAMLIdentityProvider, metadata idpv1alpha1.SAMLMetadataSpec) *idpv1alpha1.SAMLIdentityProvider {
+		updated := upstream.DeepCopy()
+		updated.Spec.Metadata = metadata
+		return updated
+	}
+
+	buildTLSConfigurationValidTrue := func(message string) metav1.Condition {
+		return metav1.Condition{
+			Type:               TLSConfigurationValid,
+			Status:             metav1.ConditionTrue,
+			ObservedGeneration: wantObservedGeneration,
+			LastTransitionTime: wantLastTransitionTime,
+			Reason:             conditionsutil.ReasonSuccess,
+			Message:            message,
+		}
+	}
+
+	tlsConfigurationValidNoTLS := buildTLSConfigurationValidTrue(
+		"spec.metadata.tls is valid: no TLS configuration provided: using default root CA bundle from container image")
+	tlsConfigurationValidWithCA := buildTLSConfigurationValidTrue("spec.metadata.tls is valid: using configured CA bundle")
+
+	buildSAMLMetadataValid := func(status metav1.ConditionStatus, reason, message string) metav1.Condition {
+		return metav1.Condition{
+			Type:               SAMLMetadataValid,
+			Status:             status,
+			ObservedGeneration: wantObservedGeneration,
+			LastTransitionTime: wantLastTransitionTime,
+			Reason:             reason,
+			Message:            message,
+		}
+	}
+
+	tests := []struct {
+		name                     string
+		samlIdentityProviders    []runtime.Object
+		preexistingMetadataCache map[metadataCacheKey]*saml.EntityDescriptor
+		wantErr                  string
+		wantResultingCache       []*upstreamsaml.ProviderConfig
+		wantResultingUpstreams   []idpv1alpha1.SAMLIdentityProvider
+		wantMetadataCacheKeys    []metadataCacheKey
+	}{
+		{
+			name:                   "no SAMLIdentityProviders",
+			wantResultingCache:     []*upstreamsaml.ProviderConfig{},
+			wantResultingUpstreams: []idpv1alpha1.SAMLIdentityProvider{},
+		},
+		{
+			name:                  "happy path with inline metadata",
+			samlIdentityProviders: []runtime.Object{validInlineIDP},
+			wantResultingCache: []*upstreamsaml.ProviderConfig{
+				{
+					Name:              validInlineIDP.Name,
+					ResourceUID:       validInlineIDP.UID,
+					UsernameAttribute: "some-username-attribute",
+					GroupsAttribute:   "some-groups-attribute",
+				},
+			},
+			wantResultingUpstreams: []idpv1alpha1.SAMLIdentityProvider{
+				{
+					ObjectMeta: validInlineIDP.ObjectMeta,
+					Spec:       validInlineIDP.Spec,
+					Status: idpv1alpha1.SAMLIdentityProviderStatus{
+						Phase: idpv1alpha1.SAMLPhaseReady,
+						Conditions: []metav1.Condition{
+							buildSAMLMetadataValid(metav1.ConditionTrue, conditionsutil.ReasonSuccess,
+								fmt.Sprintf("loaded metadata for IDP with entity ID %q from spec.metadata.inline", idp.EntityID())),
+							tlsConfigurationValidNoTLS,
+						},
+					},
+				},
+			},
+			wantMetadataCacheKeys: []metadataCacheKey{},
+		},
+		{
+			name:                  "happy path with metadata URL",
+			samlIdentityProviders: []runtime.Object{validURLIDP},
+			wantResultingCache: []*upstreamsaml.ProviderConfig{
+				{
+					Name:        validURLIDP.Name,
+					ResourceUID: validURLIDP.UID,
+				},
+			},
+			wantResultingUpstreams: []idpv1alpha1.SAMLIdentityProvider{
+				{
+					ObjectMeta: validURLIDP.ObjectMeta,
+					Spec:       validURLIDP.Spec,
+					Status: idpv1alpha1.SAMLIdentityProviderStatus{
+						Phase: idpv1alpha1.SAMLPhaseReady,
+						Conditions: []metav1.Condition{
+							buildSAMLMetadataValid(metav1.ConditionTrue, conditionsutil.ReasonSuccess,
+								fmt.Sprintf("loaded metadata for IDP with entity ID %q from %q", idp.EntityID(), goodMetadataURL)),
+							tlsConfigurationValidWithCA,
+						},
+					},
+				},
+			},
+			wantMetadataCacheKeys: []metadataCacheKey{
+				{url: goodMetadataURL, caBundleHash: tlsconfigutil.NewCABundleHash(metadataServerCA)},
+			},
+		},
+		{
+			name: "metadata URL which was previously downloaded is not downloaded again",
+			samlIdentityProviders: []runtime.Object{
+				withMetadata(validURLIDP, idpv1alpha1.SAMLMetadataSpec{
+					URL: metadataServer.URL + "/some-path-which-would-fail",
+					TLS: validURLIDP.Spec.Metadata.TLS,
+				}),
+			},
+			preexistingMetadataCache: map[metadataCacheKey]*saml.EntityDescriptor{
+				{url: metadataServer.URL + "/some-path-which-would-fail", caBundleHash: tlsconfigutil.NewCABundleHash(metadataServerCA)}: {
+					EntityID: "https://some-cached-entity-id.example.com",
+				},
+			},
+			wantResultingCache: []*upstreamsaml.ProviderConfig{
+				{
+					Name:        validURLIDP.Name,
+					ResourceUID: validURLIDP.UID,
+				},
+			},
+			wantResultingUpstreams: []idpv1alpha1.SAMLIdentityProvider{
+				{
+					ObjectMeta: validURLIDP.ObjectMeta,
+					Spec: withMetadata(validURLIDP, idpv1alpha1.SAMLMetadataSpec{
+						URL: metadataServer.URL + "/some-path-which-would-fail",
+						TLS: validURLIDP.Spec.Metadata.TLS,
+					}).Spec,
+					Status: idpv1alpha1.SAMLIdentityProviderStatus{
+						Phase: idpv1alpha1.SAMLPhaseReady,
+						Conditions: []metav1.Condition{
+							buildSAMLMetadataValid(metav1.ConditionTrue, conditionsutil.ReasonSuccess,
+								fmt.Sprintf("loaded metadata for IDP with entity ID %q from %q",
+									"https://some-cached-entity-id.example.com", metadataServer.URL+"/some-path-which-would-fail")),
+							tlsConfigurationValidWithCA,
+						},
+					},
+				},
+			},
+			wantMetadataCacheKeys: []metadataCacheKey{
+				{url: metadataServer.URL + "/some-path-which-would-fail", caBundleHash: tlsconfigutil.NewCABundleHash(metadataServerCA)},
+			},
+		},
+		{
+			name: "metadata URL returns an error status",
+			samlIdentityProviders: []runtime.Object{
+				withMetadata(validURLIDP, idpv1alpha1.SAMLMetadataSpec{
+					URL: metadataServer.URL + "/not-found",
+					TLS: validURLIDP.Spec.Metadata.TLS,
+				}),
+			},
+			wantResultingCache: []*upstreamsaml.ProviderConfig{},
+			wantResultingUpstreams: []idpv1alpha1.SAMLIdentityProvider{
+				{
+					ObjectMeta: validURLIDP.ObjectMeta,
+					Spec: withMetadata(validURLIDP, idpv1alpha1.SAMLMetadataSpec{
+						URL: metadataServer.URL + "/not-found",
+						TLS: validURLIDP.Spec.Metadata.TLS,
+					}).Spec,
+					Status: idpv1alpha1.SAMLIdentityProviderStatus{
+						Phase: idpv1alpha1.SAMLPhaseError,
+						Conditions: []metav1.Condition{
+							buildSAMLMetadataValid(metav1.ConditionFalse, "Unreachable",
+								fmt.Sprintf("failed to download metadata from %q:\nunexpected response status code 404", metadataServer.URL+"/not-found")),
+							tlsConfigurationValidWithCA,
+						},
+					},
+				},
+			},
+			wantMetadataCacheKeys: []metadataCacheKey{},
+		},
+		{
+			name: "metadata URL returns something which is not SAML metadata",
+			samlIdentityProviders: []runtime.Object{
+				withMetadata(validURLIDP, idpv1alpha1.SAMLMetadataSpec{
+					URL: metadataServer.URL + "/not-metadata",
+					TLS: validURLIDP.Spec.Metadata.TLS,
+				}),
+			},
+			wantResultingCache: []*upstreamsaml.ProviderConfig{},
+			wantResultingUpstreams: []idpv1alpha1.SAMLIdentityProvider{
+				{
+					ObjectMeta: validURLIDP.ObjectMeta,
+					Spec: withMetadata(validURLIDP, idpv1alpha1.SAMLMetadataSpec{
+						URL: metadataServer.URL + "/not-metadata",
+						TLS: validURLIDP.Spec.Metadata.TLS,
+					}).Spec,
+					Status: idpv1alpha1.SAMLIdentityProviderStatus{
+						Phase: idpv1alpha1.SAMLPhaseError,
+						Conditions: []metav1.Condition{
+							buildSAMLMetadataValid(metav1.ConditionFalse, "InvalidMetadata",
+								fmt.Sprintf("failed to parse metadata from %q: metadata is neither an EntityDescriptor nor an EntitiesDescriptor: expected element type <EntityDescriptor> but have <html>", metadataServer.URL+"/not-metadata")),
+							tlsConfigurationValidWithCA,
+						},
+					},
+				},
+			},
+			wantMetadataCacheKeys: []metadataCacheKey{},
+		},
+		{
+			name: "invalid inline metadata",
+			samlIdentityProviders: []runtime.Object{
+				withMetadata(validInlineIDP, idpv1alpha1.SAMLMetadataSpec{
+					Inline: `<EntityDescriptor xmlns="urn:oasis:names:tc:SAML:2.0:metadata"></EntityDescriptor>`,
+				}),
+			},
+			wantResultingCache: []*upstreamsaml.ProviderConfig{},
+			wantResultingUpstreams: []idpv1alpha1.SAMLIdentityProvider{
+				{
+					ObjectMeta: validInlineIDP.ObjectMeta,
+					Spec: withMetadata(validInlineIDP, idpv1alpha1.SAMLMetadataSpec{
+						Inline: `<EntityDescriptor xmlns="urn:oasis:names:tc:SAML:2.0:metadata"></EntityDescriptor>`,
+					}).Spec,
+					Status: idpv1alpha1.SAMLIdentityProviderStatus{
+						Phase: idpv1alpha1.SAMLPhaseError,
+						Conditions: []metav1.Condition{
+							buildSAMLMetadataValid(metav1.ConditionFalse, "InvalidMetadata",
+								"failed to parse spec.metadata.inline: metadata does not contain an entityID"),
+							tlsConfigurationValidNoTLS,
+						},
+					},
+				},
+			},
+			wantMetadataCacheKeys: []metadataCacheKey{},
+		},
+		{
+			name: "both url and inline metadata are set",
+			samlIdentityProviders: []runtime.Object{
+				withMetadata(validInlineIDP, idpv1alpha1.SAMLMetadataSpec{
+					URL:    goodMetadataURL,
+					Inline: validInlineIDP.Spec.Metadata.Inline,
+				}),
+			},
+			wantResultingCache: []*upstreamsaml.ProviderConfig{},
+			wantResultingUpstreams: []idpv1alpha1.SAMLIdentityProvider{
+				{
+					ObjectMeta: validInlineIDP.ObjectMeta,
+					Spec: withMetadata(validInlineIDP, idpv1alpha1.SAMLMetadataSpec{
+						URL:    goodMetadataURL,
+						Inline: validInlineIDP.Spec.Metadata.Inline,
+					}).Spec,
+					Status: idpv1alpha1.SAMLIdentityProviderStatus{
+						Phase: idpv1alpha1.SAMLPhaseError,
+						Conditions: []metav1.Condition{
+							buildSAMLMetadataValid(metav1.ConditionFalse, "InvalidMetadata",
+								"only one of spec.metadata.url or spec.metadata.inline may be set"),
+							tlsConfigurationValidNoTLS,
+						},
+					},
+				},
+			},
+			wantMetadataCacheKeys: []metadataCacheKey{},
+		},
+		{
+			name: "neither url nor inline metadata are set",
+			samlIdentityProviders: []runtime.Object{
+				withMetadata(validInlineIDP, idpv1alpha1.SAMLMetadataSpec{}),
+			},
+			wantResultingCache: []*upstreamsaml.ProviderConfig{},
+			wantResultingUpstreams: []idpv1alpha1.SAMLIdentityProvider{
+				{
+					ObjectMeta: validInlineIDP.ObjectMeta,
+					Spec:       withMetadata(validInlineIDP, idpv1alpha1.SAMLMetadataSpec{}).Spec,
+					Status: idpv1alpha1.SAMLIdentityProviderStatus{
+						Phase: idpv1alpha1.SAMLPhaseError,
+						Conditions: []metav1.Condition{
+							buildSAMLMetadataValid(metav1.ConditionFalse, "InvalidMetadata",
+								"one of spec.metadata.url or spec.metadata.inline must be set"),
+							tlsConfigurationValidNoTLS,
+						},
+					},
+				},
+			},
+			wantMetadataCacheKeys: []metadataCacheKey{},
+		},
+		{
+			name: "invalid TLS configuration for the metadata URL",
+			samlIdentityProviders: []runtime.Object{
+				withMetadata(validURLIDP, idpv1alpha1.SAMLMetadataSpec{
+					URL: goodMetadataURL,
+					TLS: &idpv1alpha1.TLSSpec{
+						CertificateAuthorityData: "this-is-not-base64-encoded",
+					},
+				}),
+			},
+			wantResultingCache: []*upstreamsaml.ProviderConfig{},
+			wantResultingUpstreams: []idpv1alpha1.SAMLIdentityProvider{
+				{
+					ObjectMeta: validURLIDP.ObjectMeta,
+					Spec: withMetadata(validURLIDP, idpv1alpha1.SAMLMetadataSpec{
+						URL: goodMetadataURL,
+						TLS: &idpv1alpha1.TLSSpec{
+							CertificateAuthorityData: "this-is-not-base64-encoded",
+						},
+					}).Spec,
+					Status: idpv1alpha1.SAMLIdentityProviderStatus{
+						Phase: idpv1alpha1.SAMLPhaseError,
+						Conditions: []metav1.Condition{
+							buildSAMLMetadataValid(metav1.ConditionUnknown, conditionsutil.ReasonUnableToValidate,
+								conditionsutil.MessageUnableToValidate),
+							{
+								Type:               TLSConfigurationValid,
+								Status:             metav1.ConditionFalse,
+								ObservedGeneration: wantObservedGeneration,
+								LastTransitionTime: wantLastTransitionTime,
+								Reason:             "InvalidTLSConfig",
+								Message:            "spec.metadata.tls.certificateAuthorityData is invalid: illegal base64 data at input byte 4",
+							},
+						},
+					},
+				},
+			},
+			wantMetadataCacheKeys: []metadataCacheKey{},
+		},
+		{
+			name: "one valid and one invalid IDP",
+			samlIdentityProviders: []runtime.Object{
+				validInlineIDP,
+				withMetadata(validURLIDP, idpv1alpha1.SAMLMetadataSpec{}),
+			},
+			wantResultingCache: []*upstreamsaml.ProviderConfig{
+				{
+					Name:              validInlineIDP.Name,
+					ResourceUID:       validInlineIDP.UID,
+					UsernameAttribute: "some-username-attribute",
+					GroupsAttribute:   "some-groups-attribute",
+				},
+			},
+			wantResultingUpstreams: []idpv1alpha1.SAMLIdentityProvider{
+				{
+					ObjectMeta: validInlineIDP.ObjectMeta,
+					Spec:       validInlineIDP.Spec,
+					Status: idpv1alpha1.SAMLIdentityProviderStatus{
+						Phase: idpv1alpha1.SAMLPhaseReady,
+						Conditions: []metav1.Condition{
+							buildSAMLMetadataValid(metav1.ConditionTrue, conditionsutil.ReasonSuccess,
+								fmt.Sprintf("loaded metadata for IDP with entity ID %q from spec.metadata.inline", idp.EntityID())),
+							tlsConfigurationValidNoTLS,
+						},
+					},
+				},
+				{
+					ObjectMeta: validURLIDP.ObjectMeta,
+					Spec:       withMetadata(validURLIDP, idpv1alpha1.SAMLMetadataSpec{}).Spec,
+					Status: idpv1alpha1.SAMLIdentityProviderStatus{
+						Phase: idpv1alpha1.SAMLPhaseError,
+						Conditions: []metav1.Condition{
+							buildSAMLMetadataValid(metav1.ConditionFalse, "InvalidMetadata",
+								"one of spec.metadata.url or spec.metadata.inline must be set"),
+							tlsConfigurationValidNoTLS,
+						},
+					},
+				},
+			},
+			wantMetadataCacheKeys: []metadataCacheKey{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			fakeSupervisorClient := supervisorfake.NewSimpleClientset(tt.samlIdentityProviders...)
+			supervisorInformers := supervisorinformers.NewSharedInformerFactory(fakeSupervisorClient, 0)
+
+			fakeKubeClient := kubefake.NewClientset()
+			kubeInformers := k8sinformers.NewSharedInformerFactoryWithOptions(fakeKubeClient, 0)
+
+			idpCache := dynamicupstreamprovider.NewDynamicUpstreamIDPProvider()
+			idpCache.SetSAMLIdentityProviders([]upstreamprovider.UpstreamSAMLIdentityProviderI{
+				upstreamsaml.New(upstreamsaml.ProviderConfig{Name: "initial-entry-to-remove"}),
+			})
+
+			metadataCacheStorage := cache.NewExpiring()
+			for key, value := range tt.preexistingMetadataCache {
+				metadataCacheStorage.Set(key, value, time.Hour)
+			}
+
+			logger, _ := plog.TestLogger(t)
+
+			controller := New(
+				namespace,
+				idpCache,
+				fakeSupervisorClient,
+				supervisorInformers.IDP().V1alpha1().SAMLIdentityProviders(),
+				kubeInformers.Core().V1().Secrets(),
+				kubeInformers.Core().V1().ConfigMaps(),
+				logger,
+				controllerlib.WithInformer,
+				frozenClockForLastTransitionTime,
+				metadataCacheStorage,
+			)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			supervisorInformers.Start(ctx.Done())
+			kubeInformers.Start(ctx.Done())
+			controllerlib.TestRunSynchronously(t, controller)
+
+			syncCtx := controllerlib.Context{Context: ctx, Key: controllerlib.Key{}}
+
+			if err := controllerlib.TestSync(t, controller, syncCtx); len(tt.wantErr) > 0 {
+				require.ErrorContains(t, err, controllerlib.ErrSyntheticRequeue.Error())
+				require.ErrorContains(t, err, tt.wantErr)
+			} else {
+				require.NoError(t, err)
+			}
+
+			// Verify what's in the IDP cache
+			actualIDPList := idpCache.GetSAMLIdentityProviders()
+			require.Equal(t, len(tt.wantResultingCache), len(actualIDPList))
+			for i := range len(tt.wantResultingCache) {
+				// Do not expect any particular order in the cache
+				var actualProvider upstreamprovider.UpstreamSAMLIdentityProviderI
+				for _, possibleIDP := range actualIDPList {
+					if possibleIDP.GetResourceName() == tt.wantResultingCache[i].Name {
+						actualProvider = possibleIDP
+						break
+					}
+				}
+
+				require.NotNil(t, actualProvider, "must find IDP with name %s", tt.wantResultingCache[i].Name)
+				require.Equal(t, tt.wantResultingCache[i].ResourceUID, actualProvider.GetResourceUID())
+				require.Equal(t, tt.wantResultingCache[i].UsernameAttribute, actualProvider.GetUsernameAttribute())
+				require.Equal(t, tt.wantResultingCache[i].GroupsAttribute, actualProvider.GetGroupsAttribute())
+				require.NotEmpty(t, actualProvider.GetIDPEntityID())
+			}
+
+			// Verify what's in the metadata cache
+			for _, key := range tt.wantMetadataCacheKeys {
+				_, ok := metadataCacheStorage.Get(key)
+				require.True(t, ok, "metadata for URL %q must be found in the metadata cache", key.url)
+			}
+			require.Equal(t, len(tt.wantMetadataCacheKeys), metadataCacheStorage.Len())
+
+			// Verify the status conditions as reported in Kubernetes
+			allSAMLIDPs, err := fakeSupervisorClient.IDPV1alpha1().SAMLIdentityProviders(namespace).List(ctx, metav1.ListOptions{})
+			require.NoError(t, err)
+
+			require.Equal(t, len(tt.wantResultingUpstreams), len(allSAMLIDPs.Items))
+			for i := range len(tt.wantResultingUpstreams) {
+				require.Len(t, tt.wantResultingUpstreams[i].Status.Conditions, countExpectedConditions)
+
+				// Do not expect any particular order in the K8s objects
+				var actualIDP *idpv1alpha1.SAMLIdentityProvider
+				for _, possibleMatch := range allSAMLIDPs.Items {
+					if possibleMatch.GetName() == tt.wantResultingUpstreams[i].Name {
+						actualIDP = ptr.To(possibleMatch)
+						break
+					}
+				}
+
+				require.NotNil(t, actualIDP, "must find IDP with name %s", tt.wantResultingUpstreams[i].Name)
+				require.Equal(t, tt.wantResultingUpstreams[i], *actualIDP)
+			}
+		})
+	}
+}
+
+func TestSAMLUpstreamWatcherControllerFilterSecret(t *testing.T) {
+	goodSecret := &corev1.Secret{
+		Type: corev1.SecretTypeOpaque,
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "some-name",
+		},
+	}
+
+	tests := []struct {
+		name       string
+		secret     metav1.Object
+		wantAdd    bool
+		wantUpdate bool
+		wantDelete bool
+	}{
+		{
+			name:       "should return true for a secret of the type Opaque",
+			secret:     goodSecret,
+			wantAdd:    true,
+			wantUpdate: true,
+			wantDelete: true,
+		},
+		{
+			name: "should return true for a secret of the type TLS",
+			secret: func() *corev1.Secret {
+				otherSecret := goodSecret.DeepCopy()
+				otherSecret.Type = corev1.SecretTypeTLS
+				return otherSecret
+			}(),
+			wantAdd:    true,
+			wantUpdate: true,
+			wantDelete: true,
+		},
+		{
+			name: "should return false for a secret of the wrong type",
+			secret: func() *corev1.Secret {
+				otherSecret := goodSecret.DeepCopy()
+				otherSecret.Type = "other-type"
+				return otherSecret
+			}(),
+		},
+		{
+			name: "should return false for a resource of wrong data type",
+			secret: &corev1.Namespace{
+				ObjectMeta: metav1.ObjectMeta{Name: "some-name", Namespace: "some-namespace"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			kubeInformers := k8sinformers.NewSharedInformerFactoryWithOptions(kubefake.NewClientset(), 0)
+
+			logger, _ := plog.TestLogger(t)
+
+			observableInformers := testutil.NewObservableWithInformerOption()
+			secretInformer := kubeInformers.Core().V1().Secrets()
+
+			_ = New(
+				"some-namespace",
+				dynamicupstreamprovider.NewDynamicUpstreamIDPProvider(),
+				supervisorfake.NewSimpleClientset(),
+				supervisorinformers.NewSharedInformerFactory(supervisorfake.NewSimpleClientset(), 0).IDP().V1alpha1().SAMLIdentityProviders(),
+				secretInformer,
+				kubeInformers.Core().V1().ConfigMaps(),
+				logger,
+				observableInformers.WithInformer,
+				clock.RealClock{},
+				cache.NewExpiring(),
+			)
+
+			unrelated := &corev1.Secret{}
+			filter := observableInformers.GetFilterForInformer(secretInformer)
+			require.Equal(t, tt.wantAdd, filter.Add(tt.secret))
+			require.Equal(t, tt.wantUpdate, filter.Update(unrelated, tt.secret))
+			require.Equal(t, tt.wantUpdate, filter.Update(tt.secret, unrelated))
+			require.Equal(t, tt.wantDelete, filter.Delete(tt.secret))
+		})
+	}
+}
+
+func TestSAMLUpstreamWatcherControllerFilterConfigMapsAndSAMLIDPs(t *testing.T) {
+	t.Parallel()
+
+	logger, _ := plog.TestLogger(t)
+
+	observableInformers := testutil.NewObservableWithInformerOption()
+	configMapInformer := k8sinformers.NewSharedInformerFactoryWithOptions(kubefake.NewClientset(), 0).Core().V1().ConfigMaps()
+	samlIdentityProviderInformer := supervisorinformers.NewSharedInformerFactory(supervisorfake.NewSimpleClientset(), 0).IDP().V1alpha1().SAMLIdentityProviders()
+
+	_ = New(
+		"some-namespace",
+		dynamicupstreamprovider.NewDynamicUpstreamIDPProvider(),
+		supervisorfake.NewSimpleClientset(),
+		samlIdentityProviderInformer,
+		k8sinformers.NewSharedInformerFactoryWithOptions(kubefake.NewClientset(), 0).Core().V1().Secrets(),
+		configMapInformer,
+		logger,
+		observableInformers.WithInformer,
+		clock.RealClock{},
+		cache.NewExpiring(),
+	)
+
+	anyConfigMap := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: "some-namespace"}}
+	cmFilter := observableInformers.GetFilterForInformer(configMapInformer)
+	require.True(t, cmFilter.Add(anyConfigMap))
+	require.True(t, cmFilter.Update(&corev1.ConfigMap{}, anyConfigMap))
+	require.True(t, cmFilter.Delete(anyConfigMap))
+
+	anyIDP := &idpv1alpha1.SAMLIdentityProvider{ObjectMeta: metav1.ObjectMeta{Namespace: "some-namespace"}}
+	idpFilter := observableInformers.GetFilterForInformer(samlIdentityProviderInformer)
+	require.True(t, idpFilter.Add(anyIDP))
+	require.True(t, idpFilter.Update(&idpv1alpha1.SAMLIdentityProvider{}, anyIDP))
+	require.True(t, idpFilter.Delete(anyIDP))
+}
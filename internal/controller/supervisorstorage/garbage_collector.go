@@ -1,4 +1,4 @@
-// Copyright 2020-2025 the Pinniped contributors. All Rights Reserved.
+// Copyright 2020-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package supervisorstorage
@@ -6,7 +6,6 @@ package supervisorstorage
 import (
 	"context"
 	"errors"
-	"fmt"
 	"slices"
 	"strings"
 	"time"
@@ -20,20 +19,22 @@ import (
 	"k8s.io/utils/clock"
 	clocktesting "k8s.io/utils/clock/testing"
 
-	oidcapi "go.pinniped.dev/generated/latest/apis/supervisor/oidc"
 	"go.pinniped.dev/internal/auditevent"
 	pinnipedcontroller "go.pinniped.dev/internal/controller"
 	"go.pinniped.dev/internal/controllerlib"
 	"go.pinniped.dev/internal/crud"
 	"go.pinniped.dev/internal/federationdomain/dynamicupstreamprovider"
+	"go.pinniped.dev/internal/federationdomain/sessionrevocation"
 	"go.pinniped.dev/internal/federationdomain/upstreamprovider"
 	"go.pinniped.dev/internal/fositestorage/accesstoken"
 	"go.pinniped.dev/internal/fositestorage/authorizationcode"
+	"go.pinniped.dev/internal/fositestorage/clientassertion"
+	"go.pinniped.dev/internal/fositestorage/devicecode"
 	"go.pinniped.dev/internal/fositestorage/openidconnect"
 	"go.pinniped.dev/internal/fositestorage/pkce"
+	"go.pinniped.dev/internal/fositestorage/pushedauthorizationrequest"
 	"go.pinniped.dev/internal/fositestorage/refreshtoken"
 	"go.pinniped.dev/internal/plog"
-	"go.pinniped.dev/internal/psession"
 )
 
 const minimumRepeatInterval = 30 * time.Second
@@ -43,11 +44,17 @@ type garbageCollectorController struct {
 	secretInformer corev1informers.SecretInformer
 	kubeClient     kubernetes.Interface
 	clock          clock.Clock
+	decrypter      SessionStorageDecrypter
 	auditLogger    plog.AuditLogger
 
 	timeOfMostRecentSweep time.Time
 }
 
+// SessionStorageDecrypter decrypts the payload of session storage Secrets which were written with encryption enabled.
+type SessionStorageDecrypter interface {
+	DecryptSecret(ctx context.Context, secret *corev1.Secret) (*corev1.Secret, error)
+}
+
 // UpstreamOIDCIdentityProviderICache is a thread safe cache that holds a list of validated upstream OIDC IDP configurations.
 type UpstreamOIDCIdentityProviderICache interface {
 	GetOIDCIdentityProviders() []upstreamprovider.UpstreamOIDCIdentityProviderI
@@ -59,6 +66,7 @@ func GarbageCollectorController(
 	kubeClient kubernetes.Interface,
 	secretInformer corev1informers.SecretInformer,
 	withInformer pinnipedcontroller.WithInformerOptionFunc,
+	decrypter SessionStorageDecrypter, // may be nil when session storage is not encrypted
 	auditLogger plog.AuditLogger,
 ) controllerlib.Controller {
 	isSecretWithGCAnnotation := func(obj metav1.Object) bool {
@@ -77,6 +85,7 @@ func GarbageCollectorController(
 				secretInformer: secretInformer,
 				kubeClient:     kubeClient,
 				clock:          clock,
+				decrypter:      decrypter,
 				auditLogger:    auditLogger,
 			},
 		},
@@ -116,6 +125,20 @@ func (c *garbageCollectorController) Sync(ctx controllerlib.Context) error {
 		return err
 	}
 
+	c.sweep(ctx.Context, frozenClock.Now(), listOfSecrets, func(ctx context.Context, secret *corev1.Secret, opts metav1.DeleteOptions) error {
+		return c.kubeClient.CoreV1().Secrets(secret.Namespace).Delete(ctx, secret.Name, opts)
+	})
+
+	return nil
+}
+
+// sweep deletes each of the given Secrets which has expired according to its garbage collection annotation.
+func (c *garbageCollectorController) sweep(
+	ctx context.Context,
+	now time.Time,
+	listOfSecrets []*corev1.Secret,
+	deleteSecret func(ctx context.Context, secret *corev1.Secret, opts metav1.DeleteOptions) error,
+) {
 	// Sort secrets by name so that audit log tests are deterministic
 	slices.SortStableFunc(listOfSecrets, func(a, b *corev1.Secret) int {
 		return strings.Compare(a.Name, b.Name)
@@ -137,26 +160,41 @@ func (c *garbageCollectorController) Sync(ctx controllerlib.Context) error {
 			continue
 		}
 
-		if !garbageCollectAfterTime.Before(frozenClock.Now()) {
+		if !garbageCollectAfterTime.Before(now) {
 			// Secret is not old enough yet, so skip deletion.
 			continue
 		}
 
+		// Deletion may be delayed to retry some failures, but not for too long. We don't want to extend the lifetime
+		// of these session Secrets by too much time, since the garbage collector is the only thing that is cleaning
+		// them out of storage.
+		fourHoursAgo := now.Add(-4 * time.Hour)
+		nowIsLessThanFourHoursBeyondSecretGCTime := garbageCollectAfterTime.After(fourHoursAgo)
+
 		// The Secret has expired. Check if it is a downstream session storage Secret, which may require extra processing.
 		storageType, isSessionStorage := secret.Labels[crud.SecretLabelKey]
+		secretToRead := secret
+		if isSessionStorage && c.decrypter != nil {
+			decryptedSecret, decryptErr := c.decrypter.DecryptSecret(ctx, secret)
+			if decryptErr != nil {
+				plog.WarningErr("garbage collector could not decrypt session storage", decryptErr, logKV(secret)...)
+				if nowIsLessThanFourHoursBeyondSecretGCTime {
+					// The key encryption key might not be loaded yet, so try again later.
+					continue
+				}
+			} else {
+				secretToRead = decryptedSecret
+			}
+		}
 		if isSessionStorage {
-			revokeErr := c.maybeRevokeUpstreamOIDCToken(ctx.Context, storageType, secret)
+			revokeErr := sessionrevocation.MaybeRevokeUpstreamOIDCToken(ctx, c.idpCache, c.auditLogger, storageType, secretToRead)
 			if revokeErr != nil {
 				plog.WarningErr("garbage collector could not revoke upstream OIDC token", revokeErr, logKV(secret)...)
-				// Note that RevokeToken (called by the private helper) might have returned an error of type
+				// Note that RevokeToken (called by sessionrevocation.MaybeRevokeUpstreamOIDCToken) might have returned an error of type
 				// provider.RetryableRevocationError, in which case we would like to retry the revocation later.
 				// If the error is of a type that is worth retrying, then do not delete the Secret right away.
 				// A future call to Sync will try revocation again for that secret. However, if the Secret is
-				// getting too old, then just delete it anyway. We don't want to extend the lifetime of these
-				// session Secrets by too much time, since the garbage collector is the only thing that is
-				// cleaning them out of etcd storage.
-				fourHoursAgo := frozenClock.Now().Add(-4 * time.Hour)
-				nowIsLessThanFourHoursBeyondSecretGCTime := garbageCollectAfterTime.After(fourHoursAgo)
+				// getting too old, then just delete it anyway.
 				if errors.As(revokeErr, &dynamicupstreamprovider.RetryableRevocationError{}) && nowIsLessThanFourHoursBeyondSecretGCTime {
 					// Hasn't been very long since secret expired, so skip deletion to try revocation again later.
 					plog.Trace("garbage collector keeping Secret to retry upstream OIDC token revocation later", logKV(secret)...)
@@ -166,7 +204,7 @@ func (c *garbageCollectorController) Sync(ctx controllerlib.Context) error {
 		}
 
 		// Garbage collect the Secret.
-		err = c.kubeClient.CoreV1().Secrets(secret.Namespace).Delete(ctx.Context, secret.Name, metav1.DeleteOptions{
+		err = deleteSecret(ctx, secret, metav1.DeleteOptions{
 			Preconditions: &metav1.Preconditions{
 				UID:             &secret.UID,
 				ResourceVersion: &secret.ResourceVersion,
@@ -176,141 +214,9 @@ func (c *garbageCollectorController) Sync(ctx controllerlib.Context) error {
 			plog.WarningErr("failed to garbage collect resource", err, logKV(secret)...)
 			continue
 		}
-		c.maybeAuditLogGC(storageType, secret)
+		c.maybeAuditLogGC(storageType, secretToRead)
 		plog.Info("storage garbage collector deleted resource", logKV(secret)...)
 	}
-
-	return nil
-}
-
-func (c *garbageCollectorController) maybeRevokeUpstreamOIDCToken(ctx context.Context, storageType string, secret *corev1.Secret) error {
-	// All downstream session storage types hold upstream tokens when the upstream IDP is an OIDC provider.
-	// However, some of them will be outdated because they are not updated by fosite after creation.
-	// Our goal below is to always revoke the latest upstream refresh token that we are holding for the
-	// session, and only the latest, or to revoke the original upstream access token. Note that we don't
-	// bother to store new upstream access tokens seen during upstream refresh because we only need to store
-	// the upstream access token when we intend to use it *instead* of an upstream refresh token.
-	// This implies that all the storage types will contain a copy of the original upstream access token,
-	// since it is never updated in the session. Thus, we can use the same logic to decide which upstream
-	// access token to revoke as we use for upstream refresh tokens, which allows us to avoid revoking an
-	// upstream access token more than once.
-	switch storageType {
-	case authorizationcode.TypeLabelValue:
-		authorizeCodeSession, err := authorizationcode.ReadFromSecret(secret)
-		if err != nil {
-			return err
-		}
-		// Check if this downstream authcode was already used. If it was already used (i.e. not active anymore),
-		// then the latest upstream token can be found in one of the other storage types handled below instead.
-		if !authorizeCodeSession.Active {
-			return nil
-		}
-		// When the downstream authcode was never used, then its storage must contain the latest upstream token.
-		return c.tryRevokeUpstreamOIDCToken(ctx,
-			authorizeCodeSession.Request.Session.(*psession.PinnipedSession).Custom,
-			authorizeCodeSession.Request,
-			secret)
-
-	case accesstoken.TypeLabelValue:
-		// For access token storage, check if the "offline_access" scope was granted on the downstream session.
-		// If it was granted, then the latest upstream token should be found in the refresh token storage instead.
-		// If it was not granted, then the user could not possibly have performed a downstream refresh, so the
-		// access token storage has the latest version of the upstream token.
-		accessTokenSession, err := accesstoken.ReadFromSecret(secret)
-		if err != nil {
-			return err
-		}
-		if accessTokenSession.Request.GetGrantedScopes().Has(oidcapi.ScopeOfflineAccess) {
-			return nil
-		}
-		return c.tryRevokeUpstreamOIDCToken(ctx,
-			accessTokenSession.Request.Session.(*psession.PinnipedSession).Custom,
-			accessTokenSession.Request,
-			secret)
-
-	case refreshtoken.TypeLabelValue:
-		// For refresh token storage, always revoke its upstream token. This refresh token storage could be
-		// the result of the initial downstream authcode exchange, or it could be the result of a downstream
-		// refresh. Either way, it always contains the latest upstream token when it exists.
-		refreshTokenSession, err := refreshtoken.ReadFromSecret(secret)
-		if err != nil {
-			return err
-		}
-		return c.tryRevokeUpstreamOIDCToken(ctx,
-			refreshTokenSession.Request.Session.(*psession.PinnipedSession).Custom,
-			refreshTokenSession.Request,
-			secret)
-
-	case pkce.TypeLabelValue:
-		// For PKCE storage, its very existence means that the downstream authcode was never exchanged, because
-		// these are deleted during downstream authcode exchange. No need to do anything, since the upstream
-		// token revocation is handled by authcode storage case above.
-		return nil
-
-	case openidconnect.TypeLabelValue:
-		// For OIDC storage, there is no need to do anything for reasons similar to the PKCE storage.
-		// These are deleted during downstream authcode exchange. The upstream token contained inside will
-		// be revoked by one of the other cases above.
-		return nil
-
-	default:
-		// There are no other storage types, so this should never happen in practice.
-		return errors.New("garbage collector saw invalid label on Secret when trying to determine if upstream revocation was needed")
-	}
-}
-
-func (c *garbageCollectorController) tryRevokeUpstreamOIDCToken(
-	ctx context.Context,
-	customSessionData *psession.CustomSessionData,
-	request *fosite.Request,
-	secret *corev1.Secret,
-) error {
-	// When session was for another upstream IDP type, e.g. LDAP, there is no upstream OIDC token involved.
-	if customSessionData.ProviderType != psession.ProviderTypeOIDC {
-		return nil
-	}
-
-	// Try to find the provider that was originally used to create the stored session.
-	var foundOIDCIdentityProviderI upstreamprovider.UpstreamOIDCIdentityProviderI
-	for _, p := range c.idpCache.GetOIDCIdentityProviders() {
-		if p.GetResourceName() == customSessionData.ProviderName && p.GetResourceUID() == customSessionData.ProviderUID {
-			foundOIDCIdentityProviderI = p
-			break
-		}
-	}
-	if foundOIDCIdentityProviderI == nil {
-		return fmt.Errorf("could not find upstream OIDC provider named %q with resource UID %q", customSessionData.ProviderName, customSessionData.ProviderUID)
-	}
-
-	// In practice, there should only be one of these tokens saved in the session.
-	upstreamRefreshToken := customSessionData.OIDC.UpstreamRefreshToken
-	upstreamAccessToken := customSessionData.OIDC.UpstreamAccessToken
-
-	if upstreamRefreshToken != "" {
-		err := foundOIDCIdentityProviderI.RevokeToken(ctx, upstreamRefreshToken, upstreamprovider.RefreshTokenType)
-		if err != nil {
-			return err
-		}
-		c.auditLogger.Audit(auditevent.UpstreamOIDCTokenRevoked, &plog.AuditParams{
-			Session:       request,
-			KeysAndValues: []any{"type", upstreamprovider.RefreshTokenType},
-		})
-		plog.Trace("garbage collector successfully revoked upstream OIDC refresh token (or provider has no revocation endpoint)", logKV(secret)...)
-	}
-
-	if upstreamAccessToken != "" {
-		err := foundOIDCIdentityProviderI.RevokeToken(ctx, upstreamAccessToken, upstreamprovider.AccessTokenType)
-		if err != nil {
-			return err
-		}
-		c.auditLogger.Audit(auditevent.UpstreamOIDCTokenRevoked, &plog.AuditParams{
-			Session:       request,
-			KeysAndValues: []any{"type", upstreamprovider.AccessTokenType},
-		})
-		plog.Trace("garbage collector successfully revoked upstream OIDC access token (or provider has no revocation endpoint)", logKV(secret)...)
-	}
-
-	return nil
 }
 
 func (c *garbageCollectorController) maybeAuditLogGC(storageType string, secret *corev1.Secret) {
@@ -352,6 +258,22 @@ func (c *garbageCollectorController) requestFromSecret(storageType string, secre
 	case openidconnect.TypeLabelValue:
 		return nil, nil // if this still exists, then it means that the user never exchanged their authcode
 
+	case devicecode.TypeLabelValue:
+		deviceCodeSession, err := devicecode.ReadFromSecret(secret)
+		if err != nil {
+			return nil, err
+		}
+		return deviceCodeSession.Request, nil
+
+	case devicecode.UserCodeTypeLabelValue:
+		return nil, nil // user code storage does not hold a session
+
+	case clientassertion.TypeLabelValue:
+		return nil, nil // client assertion storage does not hold a session
+
+	case pushedauthorizationrequest.TypeLabelValue:
+		return nil, nil // if this still exists, then it means that the client never used its request_uri
+
 	default:
 		// There are no other storage types, so this should never happen in practice.
 		return nil, errors.New("garbage collector saw invalid label on Secret when trying to determine session ID")
@@ -19,6 +19,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
 	k8sinformers "k8s.io/client-go/informers"
 	kubefake "k8s.io/client-go/kubernetes/fake"
 	kubetesting "k8s.io/client-go/testing"
@@ -31,6 +32,9 @@ import (
 	"go.pinniped.dev/internal/federationdomain/upstreamprovider"
 	"go.pinniped.dev/internal/fositestorage/accesstoken"
 	"go.pinniped.dev/internal/fositestorage/authorizationcode"
+	"go.pinniped.dev/internal/fositestorage/clientassertion"
+	"go.pinniped.dev/internal/fositestorage/devicecode"
+	"go.pinniped.dev/internal/fositestorage/pushedauthorizationrequest"
 	"go.pinniped.dev/internal/fositestorage/refreshtoken"
 	"go.pinniped.dev/internal/plog"
 	"go.pinniped.dev/internal/psession"
@@ -58,6 +62,7 @@ func TestGarbageCollectorControllerInformerFilters(t *testing.T) {
 				secretsInformer,
 				observableWithInformerOption.WithInformer, // make it possible to observe the behavior of the Filters
 				nil,
+				nil,
 			)
 			secretsInformerFilter = observableWithInformerOption.GetFilterForInformer(secretsInformer)
 		})
@@ -125,7 +130,7 @@ func TestGarbageCollectorControllerSync(t *testing.T) {
 	spec.Run(t, "Sync", func(t *testing.T, when spec.G, it spec.S) {
 		const (
 			installedInNamespace         = "some-namespace"
-			currentSessionStorageVersion = "8" // update this when you update the storage version in the production code
+			currentSessionStorageVersion = "12" // update this when you update the storage version in the production code
 		)
 
 		var (
@@ -155,6 +160,7 @@ func TestGarbageCollectorControllerSync(t *testing.T) {
 				kubeClient,
 				kubeInformers.Core().V1().Secrets(),
 				controllerlib.WithInformer,
+				nil,
 				auditLogger,
 			)
 
@@ -570,6 +576,223 @@ func TestGarbageCollectorControllerSync(t *testing.T) {
 			})
 		})
 
+		when("there are valid, expired device code and user code secrets", func() {
+			it.Before(func() {
+				newDeviceCodeSecret := func(name, uid, requestID string, userCodeState fosite.UserCodeState, upstreamRefreshToken string) *corev1.Secret {
+					deviceCodeSession := &devicecode.Session{
+						Version:       "5",
+						Active:        true,
+						UserCodeState: userCodeState,
+						Request: &fosite.Request{
+							ID:     requestID,
+							Client: &clientregistry.Client{},
+							Session: &psession.PinnipedSession{
+								Custom: &psession.CustomSessionData{
+									Username:     "should be ignored by garbage collector",
+									ProviderUID:  "upstream-oidc-provider-uid",
+									ProviderName: "upstream-oidc-provider-name",
+									ProviderType: psession.ProviderTypeOIDC,
+									OIDC: &psession.OIDCSessionData{
+										UpstreamRefreshToken: upstreamRefreshToken,
+									},
+								},
+							},
+						},
+					}
+					deviceCodeSessionJSON, err := json.Marshal(deviceCodeSession)
+					r.NoError(err)
+					secret := &corev1.Secret{
+						ObjectMeta: metav1.ObjectMeta{
+							Name:            name,
+							Namespace:       installedInNamespace,
+							UID:             types.UID(uid),
+							ResourceVersion: "rv-" + uid,
+							Annotations: map[string]string{
+								"storage.pinniped.dev/garbage-collect-after": frozenNow.Add(-time.Second).Format(time.RFC3339),
+							},
+							Labels: map[string]string{
+								"storage.pinniped.dev/type": devicecode.TypeLabelValue,
+							},
+						},
+						Data: map[string][]byte{
+							"pinniped-storage-data":    deviceCodeSessionJSON,
+							"pinniped-storage-version": []byte("1"),
+						},
+						Type: "storage.pinniped.dev/" + devicecode.TypeLabelValue,
+					}
+					_, err = devicecode.ReadFromSecret(secret)
+					r.NoError(err, "the test author accidentally formed an invalid device code secret")
+					return secret
+				}
+
+				// The user finished logging in, but the device never redeemed its device code.
+				approvedDeviceCodeSessionSecret := newDeviceCodeSecret("approvedDeviceCodeSession", "uid-123", "request-id-1",
+					fosite.UserCodeAccepted, "fake-upstream-refresh-token")
+				r.NoError(kubeInformerClient.Tracker().Add(approvedDeviceCodeSessionSecret))
+				r.NoError(kubeClient.Tracker().Add(approvedDeviceCodeSessionSecret))
+
+				// The user never finished logging in, so there are no upstream tokens yet.
+				unapprovedDeviceCodeSessionSecret := newDeviceCodeSecret("unapprovedDeviceCodeSession", "uid-456", "request-id-2",
+					fosite.UserCodeUnused, "")
+				r.NoError(kubeInformerClient.Tracker().Add(unapprovedDeviceCodeSessionSecret))
+				r.NoError(kubeClient.Tracker().Add(unapprovedDeviceCodeSessionSecret))
+
+				userCodeSecret := &corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:            "userCodeSession",
+						Namespace:       installedInNamespace,
+						UID:             "uid-789",
+						ResourceVersion: "rv-789",
+						Annotations: map[string]string{
+							"storage.pinniped.dev/garbage-collect-after": frozenNow.Add(-time.Second).Format(time.RFC3339),
+						},
+						Labels: map[string]string{
+							"storage.pinniped.dev/type": devicecode.UserCodeTypeLabelValue,
+						},
+					},
+					Data: map[string][]byte{
+						"pinniped-storage-data":    []byte(`{"deviceCodeSignature":"unapproved-device-code-signature","version":"5"}`),
+						"pinniped-storage-version": []byte("1"),
+					},
+					Type: "storage.pinniped.dev/" + devicecode.UserCodeTypeLabelValue,
+				}
+				r.NoError(kubeInformerClient.Tracker().Add(userCodeSecret))
+				r.NoError(kubeClient.Tracker().Add(userCodeSecret))
+			})
+
+			it("should revoke upstream tokens only from the approved device code secrets and delete them all", func() {
+				happyOIDCUpstream := oidctestutil.NewTestUpstreamOIDCIdentityProviderBuilder().
+					WithName("upstream-oidc-provider-name").
+					WithResourceUID("upstream-oidc-provider-uid").
+					WithRevokeTokenError(nil)
+				idpListerBuilder := testidplister.NewUpstreamIDPListerBuilder().WithOIDC(happyOIDCUpstream.Build())
+
+				startInformersAndController(idpListerBuilder.BuildDynamicUpstreamIDPProvider())
+				r.NoError(controllerlib.TestSync(t, subject, *syncContext))
+
+				// The upstream refresh token is only revoked for the approved device code session.
+				idpListerBuilder.RequireExactlyOneCallToRevokeToken(t,
+					"upstream-oidc-provider-name",
+					&oidctestutil.RevokeTokenArgs{
+						Ctx:       syncContext.Context,
+						Token:     "fake-upstream-refresh-token",
+						TokenType: upstreamprovider.RefreshTokenType,
+					},
+				)
+
+				// All secrets are deleted.
+				r.ElementsMatch(
+					[]kubetesting.Action{
+						kubetesting.NewDeleteActionWithOptions(secretsGVR, installedInNamespace, "approvedDeviceCodeSession", testutil.NewPreconditions("uid-123", "rv-uid-123")),
+						kubetesting.NewDeleteActionWithOptions(secretsGVR, installedInNamespace, "unapprovedDeviceCodeSession", testutil.NewPreconditions("uid-456", "rv-uid-456")),
+						kubetesting.NewDeleteActionWithOptions(secretsGVR, installedInNamespace, "userCodeSession", testutil.NewPreconditions("uid-789", "rv-789")),
+					},
+					kubeClient.Actions(),
+				)
+
+				wantAuditLogs = []testutil.WantedAuditLog{
+					testutil.WantAuditLog("Upstream OIDC Token Revoked",
+						map[string]any{
+							"sessionID": "request-id-1",
+							"type":      "refresh_token",
+						},
+					),
+					testutil.WantAuditLog("Session Garbage Collected",
+						map[string]any{
+							"sessionID":   "request-id-1",
+							"storageType": "device-code",
+						},
+					),
+					testutil.WantAuditLog("Session Garbage Collected",
+						map[string]any{
+							"sessionID":   "request-id-2",
+							"storageType": "device-code",
+						},
+					),
+				}
+			})
+		})
+
+		when("there is an expired client assertion secret", func() {
+			it.Before(func() {
+				clientAssertionSecret := &corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:            "clientAssertion",
+						Namespace:       installedInNamespace,
+						UID:             "uid-123",
+						ResourceVersion: "rv-123",
+						Annotations: map[string]string{
+							"storage.pinniped.dev/garbage-collect-after": frozenNow.Add(-time.Second).Format(time.RFC3339),
+						},
+						Labels: map[string]string{
+							"storage.pinniped.dev/type": clientassertion.TypeLabelValue,
+						},
+					},
+					Data: map[string][]byte{
+						"pinniped-storage-data":    []byte(`{"expiry":"2020-01-01T00:00:00Z","version":"1"}`),
+						"pinniped-storage-version": []byte("1"),
+					},
+					Type: "storage.pinniped.dev/" + clientassertion.TypeLabelValue,
+				}
+				r.NoError(kubeInformerClient.Tracker().Add(clientAssertionSecret))
+				r.NoError(kubeClient.Tracker().Add(clientAssertionSecret))
+			})
+
+			it("should delete the secret without revoking anything or auditing a session", func() {
+				idpListerBuilder := testidplister.NewUpstreamIDPListerBuilder()
+
+				startInformersAndController(idpListerBuilder.BuildDynamicUpstreamIDPProvider())
+				r.NoError(controllerlib.TestSync(t, subject, *syncContext))
+
+				r.ElementsMatch(
+					[]kubetesting.Action{
+						kubetesting.NewDeleteActionWithOptions(secretsGVR, installedInNamespace, "clientAssertion", testutil.NewPreconditions("uid-123", "rv-123")),
+					},
+					kubeClient.Actions(),
+				)
+			})
+		})
+
+		when("there is an expired pushed authorization request secret", func() {
+			it.Before(func() {
+				pushedAuthorizationRequestSecret := &corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:            "pushedAuthorizationRequest",
+						Namespace:       installedInNamespace,
+						UID:             "uid-123",
+						ResourceVersion: "rv-123",
+						Annotations: map[string]string{
+							"storage.pinniped.dev/garbage-collect-after": frozenNow.Add(-time.Second).Format(time.RFC3339),
+						},
+						Labels: map[string]string{
+							"storage.pinniped.dev/type": pushedauthorizationrequest.TypeLabelValue,
+						},
+					},
+					Data: map[string][]byte{
+						"pinniped-storage-data":    []byte(`{"request":{"id":"request-id-1"},"version":"1"}`),
+						"pinniped-storage-version": []byte("1"),
+					},
+					Type: "storage.pinniped.dev/" + pushedauthorizationrequest.TypeLabelValue,
+				}
+				r.NoError(kubeInformerClient.Tracker().Add(pushedAuthorizationRequestSecret))
+				r.NoError(kubeClient.Tracker().Add(pushedAuthorizationRequestSecret))
+			})
+
+			it("should delete the secret without revoking anything or auditing a session", func() {
+				idpListerBuilder := testidplister.NewUpstreamIDPListerBuilder()
+
+				startInformersAndController(idpListerBuilder.BuildDynamicUpstreamIDPProvider())
+				r.NoError(controllerlib.TestSync(t, subject, *syncContext))
+
+				r.ElementsMatch(
+					[]kubetesting.Action{
+						kubetesting.NewDeleteActionWithOptions(secretsGVR, installedInNamespace, "pushedAuthorizationRequest", testutil.NewPreconditions("uid-123", "rv-123")),
+					},
+					kubeClient.Actions(),
+				)
+			})
+		})
+
 		when("there is an invalid, expired authcode secret", func() {
 			it.Before(func() {
 				invalidOIDCAuthcodeSession := &authorizationcode.Session{
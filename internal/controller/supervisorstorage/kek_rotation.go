@@ -0,0 +1,97 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package supervisorstorage
+
+import (
+	"fmt"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+
+	"go.pinniped.dev/internal/controllerlib"
+	"go.pinniped.dev/internal/crud"
+	"go.pinniped.dev/internal/crud/envelope"
+	"go.pinniped.dev/internal/oidcclientsecretstorage"
+	"go.pinniped.dev/internal/plog"
+)
+
+const kekRotationInterval = 5 * time.Minute
+
+type kekRotationController struct {
+	transformer    *envelope.Transformer
+	sessionStorage crud.SecretsBackend
+}
+
+// KEKRotationController periodically re-wraps the data encryption keys of session storage using the active
+// key encryption key, so that old key encryption keys can eventually be retired. It also encrypts any session
+// storage which was written before encryption was enabled. The sessionStorage must return the Secrets as they
+// are stored, without decrypting them.
+func KEKRotationController(
+	transformer *envelope.Transformer,
+	sessionStorage crud.SecretsBackend,
+) controllerlib.Controller {
+	return controllerlib.New(
+		controllerlib.Config{
+			Name: "kek-rotation-controller",
+			Syncer: &kekRotationController{
+				transformer:    transformer,
+				sessionStorage: sessionStorage,
+			},
+		},
+		controllerlib.WithInitialEvent(controllerlib.Key{}),
+	)
+}
+
+func (c *kekRotationController) Sync(ctx controllerlib.Context) error {
+	// There are no events to trigger the next check, so always schedule it.
+	defer ctx.Queue.AddAfter(ctx.Key, kekRotationInterval)
+
+	activeKEKID, err := c.transformer.ActiveKEKID(ctx.Context)
+	if err != nil {
+		return fmt.Errorf("failed to get active key encryption key: %w", err)
+	}
+
+	secretList, err := c.sessionStorage.List(ctx.Context, metav1.ListOptions{
+		// OIDCClient secrets may be stored next to the session storage, but they are not encrypted.
+		LabelSelector: crud.SecretLabelKey + "," + crud.SecretLabelKey + "!=" + oidcclientsecretstorage.TypeLabelValue,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list session storage: %w", err)
+	}
+
+	var errs []error
+	rewrapped := 0
+	for i := range secretList.Items {
+		secret := &secretList.Items[i]
+
+		updatedSecret, err := c.transformer.RewrapSecret(ctx.Context, secret, activeKEKID)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if updatedSecret == nil {
+			// Already uses the active key encryption key.
+			continue
+		}
+
+		// The resource version of the Secret causes this update to fail if the Secret has changed since it was listed.
+		// A Secret which changed was rewritten using the active key encryption key, so it does not need to be retried.
+		_, err = c.sessionStorage.Update(ctx.Context, updatedSecret, metav1.UpdateOptions{})
+		if err != nil && !apierrors.IsConflict(err) && !apierrors.IsNotFound(err) {
+			errs = append(errs, fmt.Errorf("failed to update secret %q: %w", secret.Name, err))
+			continue
+		}
+		if err == nil {
+			rewrapped++
+		}
+	}
+
+	if rewrapped > 0 {
+		plog.Info("re-wrapped session storage data encryption keys", "activeKEKID", activeKEKID, "count", rewrapped)
+	}
+
+	return utilerrors.NewAggregate(errs)
+}
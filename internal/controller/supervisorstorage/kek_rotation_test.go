@@ -0,0 +1,150 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package supervisorstorage
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	kubetesting "k8s.io/client-go/testing"
+
+	"go.pinniped.dev/internal/controllerlib"
+	"go.pinniped.dev/internal/crud"
+	"go.pinniped.dev/internal/crud/envelope"
+)
+
+func TestKEKRotationControllerSync(t *testing.T) {
+	const namespace = "some-namespace"
+
+	secretsGVR := schema.GroupVersionResource{Version: "v1", Resource: "secrets"}
+
+	kekSecret := func(active string) *corev1.Secret {
+		return &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "kek"},
+			Data: map[string][]byte{
+				envelope.KEKSecretActiveKey: []byte(active),
+				"kek-1":                     []byte("0123456789abcdef0123456789abcdef"),
+				"kek-2":                     []byte("abcdef0123456789abcdef0123456789"),
+			},
+		}
+	}
+
+	sessionSecret := func(name string) *corev1.Secret {
+		return &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            name,
+				Namespace:       namespace,
+				ResourceVersion: "rv-" + name,
+				Labels:          map[string]string{"storage.pinniped.dev/type": "pkce"},
+			},
+			Data: map[string][]byte{crud.SecretDataKey: []byte(`{"some":"data"}`)},
+		}
+	}
+
+	tests := []struct {
+		name            string
+		noKEKs          bool
+		updateErr       error
+		wantErr         string
+		wantUpdateNames []string
+	}{
+		{
+			name:            "encrypts legacy session storage and re-wraps session storage which uses an old KEK",
+			wantUpdateNames: []string{"legacy", "old-kek"},
+		},
+		{
+			name:    "returns an error when the KEKs have not been loaded and still requeues",
+			noKEKs:  true,
+			wantErr: "failed to get active key encryption key: no key encryption keys have been loaded yet",
+		},
+		{
+			name:            "ignores conflicts",
+			updateErr:       apierrors.NewConflict(secretsGVR.GroupResource(), "some-name", nil),
+			wantUpdateNames: []string{"legacy", "old-kek"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			kekProvider := envelope.NewSecretKEKProvider()
+			transformer := envelope.NewTransformer(kekProvider)
+
+			require.NoError(t, kekProvider.SetKEKsFromSecret(kekSecret("kek-1")))
+			oldKEK, err := transformer.EncryptSecret(ctx, sessionSecret("old-kek"))
+			require.NoError(t, err)
+			require.NoError(t, kekProvider.SetKEKsFromSecret(kekSecret("kek-2")))
+			activeKEK, err := transformer.EncryptSecret(ctx, sessionSecret("active-kek"))
+			require.NoError(t, err)
+
+			if tt.noKEKs {
+				kekProvider = envelope.NewSecretKEKProvider()
+				transformer = envelope.NewTransformer(kekProvider)
+			}
+
+			kubeClient := kubefake.NewClientset(sessionSecret("legacy"), oldKEK, activeKEK)
+			if tt.updateErr != nil {
+				kubeClient.PrependReactor("update", "secrets", func(_ kubetesting.Action) (bool, runtime.Object, error) {
+					return true, nil, tt.updateErr
+				})
+			}
+
+			subject := KEKRotationController(transformer, kubeClient.CoreV1().Secrets(namespace))
+
+			queue := &testQueue{t: t}
+			syncContext := controllerlib.Context{
+				Context: ctx,
+				Name:    subject.Name(),
+				Key:     controllerlib.Key{},
+				Queue:   queue,
+			}
+
+			err = controllerlib.TestSync(t, subject, syncContext)
+			if tt.wantErr != "" {
+				require.EqualError(t, err, tt.wantErr)
+			} else {
+				require.NoError(t, err)
+			}
+
+			var updatedNames []string
+			for _, action := range kubeClient.Actions() {
+				switch a := action.(type) {
+				case kubetesting.ListAction:
+					require.Equal(t,
+						"storage.pinniped.dev/type,storage.pinniped.dev/type!=oidc-client-secret",
+						a.GetListRestrictions().Labels.String(),
+					)
+				case kubetesting.UpdateAction:
+					updated := a.GetObject().(*corev1.Secret)
+					require.True(t, envelope.IsEncrypted(updated))
+					require.Equal(t, "rv-"+updated.Name, updated.ResourceVersion)
+					updatedNames = append(updatedNames, updated.Name)
+				default:
+					t.Fatalf("unexpected action: %#v", action)
+				}
+			}
+			require.ElementsMatch(t, tt.wantUpdateNames, updatedNames)
+
+			if tt.updateErr == nil {
+				for _, name := range tt.wantUpdateNames {
+					stored, err := kubeClient.CoreV1().Secrets(namespace).Get(ctx, name, metav1.GetOptions{})
+					require.NoError(t, err)
+					rewrapped, err := transformer.RewrapSecret(ctx, stored, "kek-2")
+					require.NoError(t, err)
+					require.Nil(t, rewrapped, "secret %q should use the active KEK", name)
+				}
+			}
+
+			require.True(t, queue.called)
+			require.Equal(t, controllerlib.Key{}, queue.key)
+			require.Equal(t, kekRotationInterval, queue.duration)
+		})
+	}
+}
@@ -0,0 +1,73 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package supervisorstorage
+
+import (
+	"fmt"
+
+	corev1informers "k8s.io/client-go/informers/core/v1"
+
+	pinnipedcontroller "go.pinniped.dev/internal/controller"
+	"go.pinniped.dev/internal/controllerlib"
+	"go.pinniped.dev/internal/crud/envelope"
+	"go.pinniped.dev/internal/plog"
+)
+
+type kekSecretWatcherController struct {
+	namespace      string
+	secretName     string
+	secretInformer corev1informers.SecretInformer
+	kekProvider    *envelope.SecretKEKProvider
+}
+
+// KEKSecretWatcherController loads the key encryption keys for session storage encryption from the named Secret
+// into the kekProvider, and reloads them whenever the Secret changes.
+func KEKSecretWatcherController(
+	namespace string,
+	secretName string,
+	kekProvider *envelope.SecretKEKProvider,
+	secretInformer corev1informers.SecretInformer,
+	withInformer pinnipedcontroller.WithInformerOptionFunc,
+	withInitialEvent pinnipedcontroller.WithInitialEventOptionFunc,
+) controllerlib.Controller {
+	return controllerlib.New(
+		controllerlib.Config{
+			Name: "kek-secret-watcher-controller",
+			Syncer: &kekSecretWatcherController{
+				namespace:      namespace,
+				secretName:     secretName,
+				secretInformer: secretInformer,
+				kekProvider:    kekProvider,
+			},
+		},
+		withInformer(
+			secretInformer,
+			pinnipedcontroller.NameAndNamespaceExactMatchFilterFactory(secretName, namespace),
+			controllerlib.InformerOption{},
+		),
+		withInitialEvent(controllerlib.Key{
+			Namespace: namespace,
+			Name:      secretName,
+		}),
+	)
+}
+
+func (c *kekSecretWatcherController) Sync(_ controllerlib.Context) error {
+	secret, err := c.secretInformer.Lister().Secrets(c.namespace).Get(c.secretName)
+	if err != nil {
+		// When the Secret is deleted, keep using the previously loaded keys.
+		return fmt.Errorf("failed to get key encryption key secret %s/%s: %w", c.namespace, c.secretName, err)
+	}
+
+	if err := c.kekProvider.SetKEKsFromSecret(secret); err != nil {
+		return fmt.Errorf("failed to load key encryption keys: %w", err)
+	}
+
+	plog.Info("loaded session storage key encryption keys",
+		"secretName", c.secretName,
+		"secretNamespace", c.namespace,
+		"keyIDs", c.kekProvider.KEKIDs(),
+	)
+	return nil
+}
@@ -0,0 +1,162 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package supervisorstorage
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	kubeinformers "k8s.io/client-go/informers"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+
+	"go.pinniped.dev/internal/controllerlib"
+	"go.pinniped.dev/internal/crud/envelope"
+	"go.pinniped.dev/internal/testutil"
+)
+
+func TestKEKSecretWatcherControllerSync(t *testing.T) {
+	const (
+		namespace  = "some-namespace"
+		secretName = "some-kek-secret"
+	)
+
+	tests := []struct {
+		name          string
+		secrets       []runtime.Object
+		startingKEKs  *corev1.Secret
+		wantErr       string
+		wantKEKIDs    []string
+		wantActiveKEK string
+	}{
+		{
+			name: "loads the KEKs from the secret",
+			secrets: []runtime.Object{
+				&corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{Name: secretName, Namespace: namespace},
+					Data: map[string][]byte{
+						envelope.KEKSecretActiveKey: []byte("kek-2"),
+						"kek-1":                     []byte("0123456789abcdef0123456789abcdef"),
+						"kek-2":                     []byte("abcdef0123456789abcdef0123456789"),
+					},
+				},
+				&corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{Name: "other-secret", Namespace: namespace},
+					Data:       map[string][]byte{envelope.KEKSecretActiveKey: []byte("other")},
+				},
+			},
+			wantKEKIDs:    []string{"kek-1", "kek-2"},
+			wantActiveKEK: "kek-2",
+		},
+		{
+			name:    "the secret does not exist",
+			wantErr: `failed to get key encryption key secret some-namespace/some-kek-secret: secret "some-kek-secret" not found`,
+		},
+		{
+			name: "the secret is invalid and the previously loaded KEKs are kept",
+			secrets: []runtime.Object{
+				&corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{Name: secretName, Namespace: namespace},
+					Data: map[string][]byte{
+						envelope.KEKSecretActiveKey: []byte("kek-2"),
+						"kek-2":                     []byte("too short"),
+					},
+				},
+			},
+			startingKEKs: &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: secretName},
+				Data: map[string][]byte{
+					envelope.KEKSecretActiveKey: []byte("kek-1"),
+					"kek-1":                     []byte("0123456789abcdef0123456789abcdef"),
+				},
+			},
+			wantErr:       `failed to load key encryption keys: secret "some-kek-secret" key "kek-2" must be exactly 32 bytes`,
+			wantKEKIDs:    []string{"kek-1"},
+			wantActiveKEK: "kek-1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			kubeInformerClient := kubefake.NewClientset(tt.secrets...)
+			kubeInformers := kubeinformers.NewSharedInformerFactory(kubeInformerClient, 0)
+
+			kekProvider := envelope.NewSecretKEKProvider()
+			if tt.startingKEKs != nil {
+				require.NoError(t, kekProvider.SetKEKsFromSecret(tt.startingKEKs))
+			}
+
+			subject := KEKSecretWatcherController(
+				namespace,
+				secretName,
+				kekProvider,
+				kubeInformers.Core().V1().Secrets(),
+				controllerlib.WithInformer,
+				controllerlib.WithInitialEvent,
+			)
+
+			kubeInformers.Start(ctx.Done())
+			controllerlib.TestRunSynchronously(t, subject)
+
+			err := controllerlib.TestSync(t, subject, controllerlib.Context{
+				Context: ctx,
+				Name:    subject.Name(),
+				Key:     controllerlib.Key{Namespace: namespace, Name: secretName},
+			})
+			if tt.wantErr != "" {
+				require.EqualError(t, err, tt.wantErr)
+			} else {
+				require.NoError(t, err)
+			}
+
+			require.Equal(t, tt.wantKEKIDs, nilIfEmpty(kekProvider.KEKIDs()))
+			activeKEKID, err := kekProvider.ActiveKEKID(ctx)
+			if tt.wantActiveKEK == "" {
+				require.ErrorIs(t, err, envelope.ErrNoKEKs)
+			} else {
+				require.NoError(t, err)
+				require.Equal(t, tt.wantActiveKEK, activeKEKID)
+			}
+		})
+	}
+}
+
+func TestKEKSecretWatcherControllerFilter(t *testing.T) {
+	kubeInformers := kubeinformers.NewSharedInformerFactory(kubefake.NewClientset(), 0)
+	secretInformer := kubeInformers.Core().V1().Secrets()
+	observableWithInformerOption := testutil.NewObservableWithInformerOption()
+
+	_ = KEKSecretWatcherController(
+		"some-namespace",
+		"some-kek-secret",
+		envelope.NewSecretKEKProvider(),
+		secretInformer,
+		observableWithInformerOption.WithInformer,
+		controllerlib.WithInitialEvent,
+	)
+
+	filter := observableWithInformerOption.GetFilterForInformer(secretInformer)
+	matching := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "some-kek-secret", Namespace: "some-namespace"}}
+	wrongName := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: "some-namespace"}}
+	wrongNamespace := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "some-kek-secret", Namespace: "other"}}
+
+	require.True(t, filter.Add(matching))
+	require.False(t, filter.Add(wrongName))
+	require.False(t, filter.Add(wrongNamespace))
+	require.True(t, filter.Update(wrongName, matching))
+	require.False(t, filter.Update(wrongName, wrongNamespace))
+	require.True(t, filter.Delete(matching))
+}
+
+func nilIfEmpty(s []string) []string {
+	if len(s) == 0 {
+		return nil
+	}
+	return s
+}
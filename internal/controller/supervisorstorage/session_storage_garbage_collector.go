@@ -0,0 +1,72 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package supervisorstorage
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/clock"
+
+	"go.pinniped.dev/internal/controllerlib"
+	"go.pinniped.dev/internal/crud"
+	"go.pinniped.dev/internal/plog"
+)
+
+type sessionStorageGarbageCollectorController struct {
+	garbageCollectorController
+
+	sessionStorage crud.SecretsBackend
+}
+
+// SessionStorageGarbageCollectorController is like GarbageCollectorController, except that it garbage collects
+// the session storage which is kept in the given backend instead of in Kubernetes Secrets. The backend cannot be
+// watched, so this controller runs once at startup and then periodically.
+func SessionStorageGarbageCollectorController(
+	idpCache UpstreamOIDCIdentityProviderICache,
+	clock clock.Clock,
+	sessionStorage crud.SecretsBackend,
+	decrypter SessionStorageDecrypter, // may be nil when session storage is not encrypted
+	auditLogger plog.AuditLogger,
+) controllerlib.Controller {
+	return controllerlib.New(
+		controllerlib.Config{
+			Name: "session-storage-garbage-collector-controller",
+			Syncer: &sessionStorageGarbageCollectorController{
+				garbageCollectorController: garbageCollectorController{
+					idpCache:    idpCache,
+					clock:       clock,
+					decrypter:   decrypter,
+					auditLogger: auditLogger,
+				},
+				sessionStorage: sessionStorage,
+			},
+		},
+		controllerlib.WithInitialEvent(controllerlib.Key{}),
+	)
+}
+
+func (c *sessionStorageGarbageCollectorController) Sync(ctx controllerlib.Context) error {
+	// There are no events to trigger the next sweep, so always schedule it.
+	defer ctx.Queue.AddAfter(ctx.Key, minimumRepeatInterval)
+
+	plog.Info("starting session storage garbage collection sweep")
+
+	secretList, err := c.sessionStorage.List(ctx.Context, metav1.ListOptions{LabelSelector: crud.SecretLabelKey})
+	if err != nil {
+		return err
+	}
+
+	listOfSecrets := make([]*corev1.Secret, 0, len(secretList.Items))
+	for i := range secretList.Items {
+		listOfSecrets = append(listOfSecrets, &secretList.Items[i])
+	}
+
+	c.sweep(ctx.Context, c.clock.Now(), listOfSecrets, func(ctx context.Context, secret *corev1.Secret, opts metav1.DeleteOptions) error {
+		return c.sessionStorage.Delete(ctx, secret.Name, opts)
+	})
+
+	return nil
+}
@@ -0,0 +1,114 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package supervisorstorage
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	kubetesting "k8s.io/client-go/testing"
+	clocktesting "k8s.io/utils/clock/testing"
+
+	"go.pinniped.dev/internal/controllerlib"
+	"go.pinniped.dev/internal/plog"
+	"go.pinniped.dev/internal/testutil"
+)
+
+func TestSessionStorageGarbageCollectorControllerSync(t *testing.T) {
+	const namespace = "some-namespace"
+
+	secretsGVR := schema.GroupVersionResource{Version: "v1", Resource: "secrets"}
+	frozenNow := time.Now().UTC()
+
+	sessionSecret := func(name, uid, rv string, gcAfter time.Time) *corev1.Secret {
+		return &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            name,
+				Namespace:       namespace,
+				UID:             types.UID("uid-" + uid),
+				ResourceVersion: rv,
+				Labels:          map[string]string{"storage.pinniped.dev/type": "pkce"},
+				Annotations: map[string]string{
+					"storage.pinniped.dev/garbage-collect-after": gcAfter.Format(time.RFC3339),
+				},
+			},
+		}
+	}
+
+	tests := []struct {
+		name        string
+		listErr     error
+		wantErr     string
+		wantActions []kubetesting.Action
+	}{
+		{
+			name: "deletes the expired session storage and requeues",
+			wantActions: []kubetesting.Action{
+				kubetesting.NewListActionWithOptions(secretsGVR, schema.GroupVersionKind{Version: "v1", Kind: "Secret"}, namespace,
+					metav1.ListOptions{LabelSelector: "storage.pinniped.dev/type"}),
+				kubetesting.NewDeleteActionWithOptions(secretsGVR, namespace, "expired", testutil.NewPreconditions("uid-1", "rv-1")),
+			},
+		},
+		{
+			name:    "returns list errors and still requeues",
+			listErr: errors.New("some list error"),
+			wantErr: "some list error",
+			wantActions: []kubetesting.Action{
+				kubetesting.NewListActionWithOptions(secretsGVR, schema.GroupVersionKind{Version: "v1", Kind: "Secret"}, namespace,
+					metav1.ListOptions{LabelSelector: "storage.pinniped.dev/type"}),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kubeClient := kubefake.NewClientset(
+				sessionSecret("expired", "1", "rv-1", frozenNow.Add(-time.Second)),
+				sessionSecret("unexpired", "2", "rv-2", frozenNow.Add(time.Hour)),
+			)
+			if tt.listErr != nil {
+				kubeClient.PrependReactor("list", "secrets", func(_ kubetesting.Action) (bool, runtime.Object, error) {
+					return true, nil, tt.listErr
+				})
+			}
+
+			auditLogger, _ := plog.TestAuditLogger(t)
+			subject := SessionStorageGarbageCollectorController(
+				nil,
+				clocktesting.NewFakeClock(frozenNow),
+				kubeClient.CoreV1().Secrets(namespace),
+				nil,
+				auditLogger,
+			)
+
+			queue := &testQueue{t: t}
+			syncContext := controllerlib.Context{
+				Context: context.Background(),
+				Name:    subject.Name(),
+				Key:     controllerlib.Key{},
+				Queue:   queue,
+			}
+
+			err := controllerlib.TestSync(t, subject, syncContext)
+			if tt.wantErr != "" {
+				require.EqualError(t, err, tt.wantErr)
+			} else {
+				require.NoError(t, err)
+			}
+
+			require.Equal(t, tt.wantActions, kubeClient.Actions())
+			require.True(t, queue.called)
+			require.Equal(t, controllerlib.Key{}, queue.key)
+			require.Equal(t, minimumRepeatInterval, queue.duration)
+		})
+	}
+}
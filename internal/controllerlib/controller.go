@@ -1,4 +1,4 @@
-// Copyright 2020-2024 the Pinniped contributors. All Rights Reserved.
+// Copyright 2020-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package controllerlib
@@ -16,6 +16,7 @@ import (
 	"k8s.io/client-go/tools/events"
 	"k8s.io/client-go/util/workqueue"
 
+	"go.pinniped.dev/internal/metrics"
 	"go.pinniped.dev/internal/plog"
 )
 
@@ -196,7 +197,9 @@ func (c *controller) processNextWorkItem(ctx context.Context) {
 		Recorder: c.recorder,
 	}
 
+	start := time.Now()
 	err := c.sync(syncCtx)
+	metrics.ObserveControllerSync(c.Name(), start, err)
 	c.handleKey(key, err)
 }
 
@@ -1,4 +1,4 @@
-// Copyright 2020-2024 the Pinniped contributors. All Rights Reserved.
+// Copyright 2020-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package controllerlib
@@ -14,6 +14,7 @@ import (
 	"k8s.io/client-go/tools/events"
 	"k8s.io/client-go/util/workqueue"
 
+	"go.pinniped.dev/internal/metrics"
 	"go.pinniped.dev/internal/plog"
 )
 
@@ -34,7 +35,8 @@ func WithInitialEvent(key Key) Option {
 func WithRateLimiter(limiter workqueue.TypedRateLimiter[any]) Option {
 	return func(c *controller) {
 		cfg := workqueue.TypedRateLimitingQueueConfig[any]{
-			Name: c.Name(),
+			Name:            c.Name(),
+			MetricsProvider: metrics.WorkqueueMetricsProvider(),
 		}
 		c.queue = workqueue.NewTypedRateLimitingQueueWithConfig(limiter, cfg)
 		c.queueWrapper = &queueWrapper{queue: c.queue}
@@ -1,4 +1,4 @@
-// Copyright 2020-2025 the Pinniped contributors. All Rights Reserved.
+// Copyright 2020-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 // Package controllermanager provides an entrypoint into running all of the controllers that run as
@@ -23,6 +23,7 @@ import (
 	"go.pinniped.dev/internal/controller/authenticator/cachecleaner"
 	"go.pinniped.dev/internal/controller/authenticator/jwtcachefiller"
 	"go.pinniped.dev/internal/controller/authenticator/webhookcachefiller"
+	"go.pinniped.dev/internal/controller/csrsigner"
 	"go.pinniped.dev/internal/controller/impersonatorconfig"
 	"go.pinniped.dev/internal/controller/kubecertagent"
 	"go.pinniped.dev/internal/controller/serviceaccounttokencleanup"
@@ -63,6 +64,14 @@ type Config struct {
 	// the kubecertagent package's controllers should manage the agent pods.
 	KubeCertAgentConfig *concierge.KubeCertAgentSpec
 
+	// CertificateSigningRequestConfig comes from the Pinniped config API (see api.Config). It decides whether
+	// the csrsigner package's controller should report the status of the CertificateSigningRequest strategy.
+	CertificateSigningRequestConfig *concierge.CertificateSigningRequestSpec
+
+	// SessionRecordingConfig comes from the Pinniped config API (see api.Config). It decides whether and where
+	// the impersonation proxy should record exec and attach sessions.
+	SessionRecordingConfig *concierge.SessionRecordingSpec
+
 	// ImpersonationProxyServerPort decides which port the impersonation proxy should bind.
 	ImpersonationProxyServerPort int
 
@@ -99,6 +108,10 @@ type Config struct {
 	// AuthenticatorCache is a cache of authenticators shared amongst various authenticated-related controllers.
 	AuthenticatorCache *authncache.Cache
 
+	// AuditLogger is used by the impersonation proxy to audit log the requests which it throttles
+	// and the sessions which it records.
+	AuditLogger plog.AuditLogger
+
 	// Labels are labels that should be added to any resources created by the controllers.
 	Labels map[string]string
 }
@@ -107,6 +120,11 @@ type Config struct {
 func PrepareControllers(c *Config) (controllerinit.RunnerBuilder, error) { //nolint:funlen // Eh, fair, it is a really long function...but it is wiring the world...so...
 	loginConciergeGroupData, identityConciergeGroupData := groupsuffix.ConciergeAggregatedGroups(c.APIGroupSuffix)
 
+	sessionRecorder, err := newSessionRecorder(c.SessionRecordingConfig, c.AuditLogger)
+	if err != nil {
+		return nil, fmt.Errorf("could not create session recorder: %w", err)
+	}
+
 	dref, deployment, _, err := deploymentref.New(c.ServerInstallationInfo)
 	if err != nil {
 		return nil, fmt.Errorf("cannot create deployment ref: %w", err)
@@ -302,6 +320,9 @@ func PrepareControllers(c *Config) (controllerinit.RunnerBuilder, error) { //nol
 				c.ImpersonationSigningCertProvider,
 				plog.New(),
 				c.ImpersonationProxyTokenCache,
+				c.AuthenticatorCache,
+				impersonator.NewRequestLimiter(c.AuditLogger, clock.RealClock{}),
+				sessionRecorder,
 			),
 			singletonWorker,
 		).
@@ -345,6 +366,22 @@ func PrepareControllers(c *Config) (controllerinit.RunnerBuilder, error) { //nol
 			singletonWorker,
 		)
 
+	// The CSR signer controller reports status on the CertificateSigningRequest cluster integration strategy,
+	// but only when that strategy was enabled, so that it is not reported as an error on clusters which do not use it.
+	if c.CertificateSigningRequestConfig.Enabled() {
+		controllerManager = controllerManager.WithController(
+			csrsigner.NewController(
+				c.NamesConfig.CredentialIssuer,
+				c.DiscoveryURLOverride,
+				client,
+				informers.kubePublicNamespaceK8s.Core().V1().ConfigMaps(),
+				informers.pinniped.Config().V1alpha1().CredentialIssuers(),
+				clock.RealClock{},
+			),
+			singletonWorker,
+		)
+	}
+
 	return controllerinit.Prepare(controllerManager.Start, leaderElector,
 		informers.kubePublicNamespaceK8s,
 		informers.kubeSystemNamespaceK8s,
@@ -388,3 +425,32 @@ func createInformers(
 		),
 	}
 }
+
+// Create the impersonation proxy's session recorder, or return nil when session recording is not enabled.
+func newSessionRecorder(cfg *concierge.SessionRecordingSpec, auditLogger plog.AuditLogger) (*impersonator.SessionRecorder, error) {
+	if cfg == nil || !cfg.Enabled() {
+		return nil, nil
+	}
+
+	redactor, err := impersonator.NewRegexpSessionRedactor(cfg.RedactPatterns)
+	if err != nil {
+		return nil, err
+	}
+
+	sink := impersonator.NewDirectorySessionSink(cfg.Directory)
+	if cfg.Webhook != nil {
+		rootCAs, err := cfg.Webhook.CertPool()
+		if err != nil {
+			return nil, err
+		}
+		sink = impersonator.NewWebhookSessionSink(cfg.Webhook.URL, rootCAs)
+	}
+
+	return impersonator.NewSessionRecorder(
+		sink,
+		*cfg.MaxBytesPerSession,
+		[]impersonator.SessionRedactor{redactor},
+		auditLogger,
+		clock.RealClock{},
+	), nil
+}
@@ -0,0 +1,340 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package boltbackend implements a crud.SecretsBackend which keeps its data in an encrypted bbolt database file
+// on local disk instead of keeping it as Secrets in the Kubernetes API.
+//
+// A bbolt database file may only be opened by one process at a time, so a Supervisor which is configured to use
+// this backend must run as a single replica whose file lives on a persistent volume. The deployment templates and
+// the Supervisor's startup both refuse to run more than one replica when this backend is selected.
+package boltbackend
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/uuid"
+
+	"go.pinniped.dev/internal/constable"
+	"go.pinniped.dev/internal/crud"
+	"go.pinniped.dev/internal/plog"
+)
+
+const (
+	// KeySize is the required length of the encryption key, which selects AES-256.
+	KeySize = 32
+
+	// openTimeout is how long to wait to obtain the file lock on the database file. Another process holding the
+	// lock for longer than this is most likely another Supervisor pod which is using the same file.
+	openTimeout = 30 * time.Second
+
+	// secretsBucket is the name of the bbolt bucket which holds all the Secrets, keyed by name.
+	secretsBucket = "secrets"
+
+	ErrInvalidKeySize = constable.Error("encryption key must be exactly 32 bytes")
+	ErrClosed         = constable.Error("session storage database is closed")
+)
+
+type Backend struct {
+	db    *bolt.DB
+	aead  cipher.AEAD
+	clock func() time.Time
+}
+
+var _ crud.SecretsBackend = &Backend{}
+
+// New opens, or creates, the bbolt database file at path. The contents of each stored Secret are encrypted
+// using AES-GCM with the given 32 byte key. The caller should call Close when the Backend is no longer needed.
+func New(path string, key []byte, clock func() time.Time) (*Backend, error) {
+	if len(key) != KeySize {
+		return nil, ErrInvalidKeySize
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("could not create cipher: %w", err)
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("could not create AEAD: %w", err)
+	}
+
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: openTimeout})
+	if err != nil {
+		return nil, fmt.Errorf("could not open session storage database %q: %w", path, err)
+	}
+
+	if err := db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists([]byte(secretsBucket))
+		return err
+	}); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("could not initialize session storage database %q: %w", path, err)
+	}
+
+	return &Backend{db: db, aead: aead, clock: clock}, nil
+}
+
+func (b *Backend) Close() error {
+	return b.db.Close()
+}
+
+func (b *Backend) Create(_ context.Context, secret *corev1.Secret, _ metav1.CreateOptions) (*corev1.Secret, error) {
+	if secret.Name == "" {
+		return nil, apierrors.NewBadRequest("name is required")
+	}
+
+	created := secret.DeepCopy()
+
+	err := b.update(func(bucket *bolt.Bucket) error {
+		if bucket.Get([]byte(created.Name)) != nil {
+			return apierrors.NewAlreadyExists(corev1.Resource("secrets"), created.Name)
+		}
+
+		created.UID = uuid.NewUUID()
+		// Match what would be read back from storage, which only has second precision.
+		created.CreationTimestamp = metav1.NewTime(b.clock().Truncate(time.Second).Local())
+		return b.put(bucket, created)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return created.DeepCopy(), nil
+}
+
+func (b *Backend) Update(_ context.Context, secret *corev1.Secret, _ metav1.UpdateOptions) (*corev1.Secret, error) {
+	updated := secret.DeepCopy()
+
+	err := b.update(func(bucket *bolt.Bucket) error {
+		existing, err := b.get(bucket, updated.Name)
+		if err != nil {
+			return err
+		}
+
+		if updated.ResourceVersion != "" && updated.ResourceVersion != existing.ResourceVersion {
+			return conflict(updated.Name, "the object has been modified; please apply your changes to the latest version and try again")
+		}
+
+		updated.UID = existing.UID
+		updated.CreationTimestamp = existing.CreationTimestamp
+		return b.put(bucket, updated)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return updated.DeepCopy(), nil
+}
+
+func (b *Backend) Delete(_ context.Context, name string, opts metav1.DeleteOptions) error {
+	return b.update(func(bucket *bolt.Bucket) error {
+		existing, err := b.get(bucket, name)
+		if err != nil {
+			return err
+		}
+
+		if opts.Preconditions != nil {
+			if uid := opts.Preconditions.UID; uid != nil && *uid != existing.UID {
+				return conflict(name, fmt.Sprintf("Precondition failed: UID in precondition: %s, UID in object meta: %s", *uid, existing.UID))
+			}
+			if rv := opts.Preconditions.ResourceVersion; rv != nil && *rv != existing.ResourceVersion {
+				return conflict(name, fmt.Sprintf("Precondition failed: ResourceVersion in precondition: %s, ResourceVersion in object meta: %s", *rv, existing.ResourceVersion))
+			}
+		}
+
+		return bucket.Delete([]byte(name))
+	})
+}
+
+func (b *Backend) Get(_ context.Context, name string, _ metav1.GetOptions) (*corev1.Secret, error) {
+	var secret *corev1.Secret
+
+	err := b.view(func(bucket *bolt.Bucket) error {
+		var err error
+		secret, err = b.get(bucket, name)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return secret, nil
+}
+
+func (b *Backend) List(_ context.Context, opts metav1.ListOptions) (*corev1.SecretList, error) {
+	selector, err := labels.Parse(opts.LabelSelector)
+	if err != nil {
+		return nil, apierrors.NewBadRequest(fmt.Sprintf("invalid label selector %q: %v", opts.LabelSelector, err))
+	}
+
+	list := &corev1.SecretList{}
+
+	err = b.view(func(bucket *bolt.Bucket) error {
+		// bbolt iterates in byte-sorted key order, so the results are sorted by name.
+		return bucket.ForEach(func(k, v []byte) error {
+			secret, err := b.decrypt(k, v)
+			if err != nil {
+				// Skip unreadable records, so they cannot block garbage collection or the listing of sessions.
+				plog.WarningErr("could not read session storage", err, "secretName", string(k))
+				return nil
+			}
+			if selector.Matches(labels.Set(secret.Labels)) {
+				list.Items = append(list.Items, *secret)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
+
+func (b *Backend) update(fn func(bucket *bolt.Bucket) error) error {
+	err := b.db.Update(func(tx *bolt.Tx) error {
+		return fn(tx.Bucket([]byte(secretsBucket)))
+	})
+	return wrapDBError(err)
+}
+
+func (b *Backend) view(fn func(bucket *bolt.Bucket) error) error {
+	err := b.db.View(func(tx *bolt.Tx) error {
+		return fn(tx.Bucket([]byte(secretsBucket)))
+	})
+	return wrapDBError(err)
+}
+
+func (b *Backend) get(bucket *bolt.Bucket, name string) (*corev1.Secret, error) {
+	ciphertext := bucket.Get([]byte(name))
+	if ciphertext == nil {
+		return nil, apierrors.NewNotFound(corev1.Resource("secrets"), name)
+	}
+	return b.decrypt([]byte(name), ciphertext)
+}
+
+// put stores the secret with a new resource version. Must be called in a read-write transaction.
+func (b *Backend) put(bucket *bolt.Bucket, secret *corev1.Secret) error {
+	seq, err := bucket.NextSequence()
+	if err != nil {
+		return err
+	}
+	secret.ResourceVersion = strconv.FormatUint(seq, 10)
+
+	ciphertext, err := b.encrypt(secret)
+	if err != nil {
+		return err
+	}
+
+	return bucket.Put([]byte(secret.Name), ciphertext)
+}
+
+// encrypt seals the JSON of the secret using the secret's name as additional data, so that the value
+// cannot be swapped with the value of another key without detection.
+func (b *Backend) encrypt(secret *corev1.Secret) ([]byte, error) {
+	plaintext, err := json.Marshal(secret)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal secret %q: %w", secret.Name, err)
+	}
+
+	nonce := make([]byte, b.aead.NonceSize(), b.aead.NonceSize()+len(plaintext)+b.aead.Overhead())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	return b.aead.Seal(nonce, nonce, plaintext, []byte(secret.Name)), nil
+}
+
+func (b *Backend) decrypt(name, ciphertext []byte) (*corev1.Secret, error) {
+	nonceSize := b.aead.NonceSize()
+	if len(ciphertext) < nonceSize {
+		return nil, fmt.Errorf("failed to decrypt secret %q: ciphertext too short", name)
+	}
+
+	plaintext, err := b.aead.Open(nil, ciphertext[:nonceSize], ciphertext[nonceSize:], name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt secret %q: %w", name, err)
+	}
+
+	secret := &corev1.Secret{}
+	if err := json.Unmarshal(plaintext, secret); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal secret %q: %w", name, err)
+	}
+
+	return secret, nil
+}
+
+func conflict(name, reason string) error {
+	return apierrors.NewConflict(corev1.Resource("secrets"), name, constable.Error(reason))
+}
+
+func wrapDBError(err error) error {
+	if err == nil || apierrors.ReasonForError(err) != metav1.StatusReasonUnknown {
+		return err
+	}
+	if errors.Is(err, bolt.ErrDatabaseNotOpen) {
+		return ErrClosed
+	}
+	return err
+}
+
+// MigrateFromSecrets copies every Secret from the source which has the crud.SecretLabelKey label into the destination,
+// and then deletes it from the source. Secrets whose storage type is listed in skipTypes are left in the source.
+// Secrets which already exist in the destination are not overwritten, but are still deleted from the source,
+// which allows an interrupted migration to be safely retried.
+func MigrateFromSecrets(ctx context.Context, from, to crud.SecretsBackend, skipTypes ...string) (int, error) {
+	list, err := from.List(ctx, metav1.ListOptions{LabelSelector: crud.SecretLabelKey})
+	if err != nil {
+		return 0, fmt.Errorf("failed to list secrets to migrate: %w", err)
+	}
+
+	migrated := 0
+	for i := range list.Items {
+		secret := &list.Items[i]
+		storageType := secret.Labels[crud.SecretLabelKey]
+		if slices.Contains(skipTypes, storageType) {
+			continue
+		}
+
+		toCreate := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        secret.Name,
+				Labels:      secret.Labels,
+				Annotations: secret.Annotations,
+			},
+			Data: secret.Data,
+			Type: secret.Type,
+		}
+		if _, err := to.Create(ctx, toCreate, metav1.CreateOptions{}); err != nil && !apierrors.IsAlreadyExists(err) {
+			return migrated, fmt.Errorf("failed to migrate secret %q: %w", secret.Name, err)
+		}
+
+		err := from.Delete(ctx, secret.Name, metav1.DeleteOptions{
+			Preconditions: &metav1.Preconditions{UID: &secret.UID, ResourceVersion: &secret.ResourceVersion},
+		})
+		if err != nil && !apierrors.IsNotFound(err) {
+			return migrated, fmt.Errorf("failed to delete migrated secret %q: %w", secret.Name, err)
+		}
+
+		plog.Debug("migrated session storage secret", "secretName", secret.Name, "storageType", storageType)
+		migrated++
+	}
+
+	return migrated, nil
+}
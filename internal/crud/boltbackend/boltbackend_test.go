@@ -0,0 +1,311 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package boltbackend
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	bolt "go.etcd.io/bbolt"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+	coretesting "k8s.io/client-go/testing"
+	"k8s.io/utils/ptr"
+
+	"go.pinniped.dev/internal/constable"
+	"go.pinniped.dev/internal/crud"
+)
+
+const namespace = "test-ns"
+
+func testKey() []byte {
+	return []byte("0123456789abcdef0123456789abcdef")
+}
+
+func newTestBackend(t *testing.T, path string) *Backend {
+	t.Helper()
+
+	fakeNow := time.Date(2030, time.January, 1, 0, 0, 0, 0, time.UTC)
+	b, err := New(path, testKey(), func() time.Time { return fakeNow })
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = b.Close() })
+	return b
+}
+
+func testSecret(name, storageType string) *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: name,
+			Labels: map[string]string{
+				crud.SecretLabelKey: storageType,
+			},
+			Annotations: map[string]string{
+				crud.SecretLifetimeAnnotationKey: "2030-01-01T01:00:00Z",
+			},
+		},
+		Data: map[string][]byte{
+			"pinniped-storage-data":    []byte(`{"some":"data"}`),
+			"pinniped-storage-version": []byte("1"),
+		},
+		Type: corev1.SecretType("storage.pinniped.dev/" + storageType),
+	}
+}
+
+func TestNewRequiresKeyOfCorrectSize(t *testing.T) {
+	_, err := New(filepath.Join(t.TempDir(), "sessions.db"), []byte("too-short"), time.Now)
+	require.ErrorIs(t, err, ErrInvalidKeySize)
+}
+
+func TestCRUD(t *testing.T) {
+	ctx := context.Background()
+	b := newTestBackend(t, filepath.Join(t.TempDir(), "sessions.db"))
+
+	_, err := b.Get(ctx, "secret-1", metav1.GetOptions{})
+	require.True(t, apierrors.IsNotFound(err), "expected not found, got %v", err)
+
+	created, err := b.Create(ctx, testSecret("secret-1", "access-token"), metav1.CreateOptions{})
+	require.NoError(t, err)
+	require.Equal(t, "1", created.ResourceVersion)
+	require.NotEmpty(t, created.UID)
+	require.Equal(t, time.Date(2030, time.January, 1, 0, 0, 0, 0, time.UTC), created.CreationTimestamp.UTC())
+
+	_, err = b.Create(ctx, testSecret("secret-1", "access-token"), metav1.CreateOptions{})
+	require.True(t, apierrors.IsAlreadyExists(err), "expected already exists, got %v", err)
+
+	got, err := b.Get(ctx, "secret-1", metav1.GetOptions{})
+	require.NoError(t, err)
+	require.Equal(t, created, got)
+
+	toUpdate := got.DeepCopy()
+	toUpdate.Data["pinniped-storage-data"] = []byte(`{"some":"other data"}`)
+	updated, err := b.Update(ctx, toUpdate, metav1.UpdateOptions{})
+	require.NoError(t, err)
+	require.Equal(t, "2", updated.ResourceVersion)
+	require.Equal(t, created.UID, updated.UID)
+	require.Equal(t, []byte(`{"some":"other data"}`), updated.Data["pinniped-storage-data"])
+
+	// Updating using the old resource version should fail.
+	_, err = b.Update(ctx, toUpdate, metav1.UpdateOptions{})
+	require.True(t, apierrors.IsConflict(err), "expected conflict, got %v", err)
+
+	_, err = b.Update(ctx, testSecret("does-not-exist", "access-token"), metav1.UpdateOptions{})
+	require.True(t, apierrors.IsNotFound(err), "expected not found, got %v", err)
+
+	err = b.Delete(ctx, "secret-1", metav1.DeleteOptions{Preconditions: &metav1.Preconditions{ResourceVersion: ptr.To("1")}})
+	require.True(t, apierrors.IsConflict(err), "expected conflict, got %v", err)
+
+	err = b.Delete(ctx, "secret-1", metav1.DeleteOptions{Preconditions: &metav1.Preconditions{UID: ptr.To(types.UID("wrong"))}})
+	require.True(t, apierrors.IsConflict(err), "expected conflict, got %v", err)
+
+	err = b.Delete(ctx, "secret-1", metav1.DeleteOptions{Preconditions: &metav1.Preconditions{UID: &created.UID, ResourceVersion: ptr.To("2")}})
+	require.NoError(t, err)
+
+	err = b.Delete(ctx, "secret-1", metav1.DeleteOptions{})
+	require.True(t, apierrors.IsNotFound(err), "expected not found, got %v", err)
+}
+
+func TestList(t *testing.T) {
+	ctx := context.Background()
+	b := newTestBackend(t, filepath.Join(t.TempDir(), "sessions.db"))
+
+	for _, s := range []*corev1.Secret{
+		testSecret("secret-c", "refresh-token"),
+		testSecret("secret-a", "access-token"),
+		testSecret("secret-b", "refresh-token"),
+	} {
+		_, err := b.Create(ctx, s, metav1.CreateOptions{})
+		require.NoError(t, err)
+	}
+
+	all, err := b.List(ctx, metav1.ListOptions{})
+	require.NoError(t, err)
+	require.Equal(t, []string{"secret-a", "secret-b", "secret-c"}, names(all))
+
+	filtered, err := b.List(ctx, metav1.ListOptions{LabelSelector: crud.SecretLabelKey + "=refresh-token"})
+	require.NoError(t, err)
+	require.Equal(t, []string{"secret-b", "secret-c"}, names(filtered))
+
+	_, err = b.List(ctx, metav1.ListOptions{LabelSelector: "this is not a selector!"})
+	require.True(t, apierrors.IsBadRequest(err), "expected bad request, got %v", err)
+}
+
+func TestListSkipsUnreadableRecords(t *testing.T) {
+	ctx := context.Background()
+	b := newTestBackend(t, filepath.Join(t.TempDir(), "sessions.db"))
+
+	for _, s := range []*corev1.Secret{
+		testSecret("secret-a", "access-token"),
+		testSecret("secret-b", "access-token"),
+		testSecret("secret-c", "access-token"),
+	} {
+		_, err := b.Create(ctx, s, metav1.CreateOptions{})
+		require.NoError(t, err)
+	}
+
+	// Corrupt one record, and swap the value of another with a value which was encrypted for a different name.
+	err := b.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(secretsBucket))
+		if err := bucket.Put([]byte("secret-a"), []byte("not encrypted")); err != nil {
+			return err
+		}
+		return bucket.Put([]byte("secret-b"), bucket.Get([]byte("secret-c")))
+	})
+	require.NoError(t, err)
+
+	all, err := b.List(ctx, metav1.ListOptions{})
+	require.NoError(t, err)
+	require.Equal(t, []string{"secret-c"}, names(all))
+
+	_, err = b.Get(ctx, "secret-a", metav1.GetOptions{})
+	require.ErrorContains(t, err, `failed to decrypt secret "secret-a"`)
+}
+
+func TestDataIsEncryptedAndPersisted(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "sessions.db")
+
+	b, err := New(path, testKey(), time.Now)
+	require.NoError(t, err)
+	_, err = b.Create(ctx, testSecret("secret-1", "access-token"), metav1.CreateOptions{})
+	require.NoError(t, err)
+	require.NoError(t, b.Close())
+
+	_, err = b.Get(ctx, "secret-1", metav1.GetOptions{})
+	require.ErrorIs(t, err, ErrClosed)
+
+	// The raw contents of the database should not contain the plaintext data.
+	db, err := bolt.Open(path, 0o600, nil)
+	require.NoError(t, err)
+	err = db.View(func(tx *bolt.Tx) error {
+		raw := tx.Bucket([]byte(secretsBucket)).Get([]byte("secret-1"))
+		require.NotEmpty(t, raw)
+		require.NotContains(t, string(raw), "some")
+		require.NotContains(t, string(raw), "access-token")
+		return nil
+	})
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+
+	// The data can be read after reopening the database with the same key.
+	b = newTestBackend(t, path)
+	got, err := b.Get(ctx, "secret-1", metav1.GetOptions{})
+	require.NoError(t, err)
+	require.Equal(t, []byte(`{"some":"data"}`), got.Data["pinniped-storage-data"])
+	require.NoError(t, b.Close())
+
+	// The data cannot be read using a different key.
+	wrongKey, err := New(path, []byte("abcdef0123456789abcdef0123456789"), time.Now)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = wrongKey.Close() })
+	_, err = wrongKey.Get(ctx, "secret-1", metav1.GetOptions{})
+	require.ErrorContains(t, err, `failed to decrypt secret "secret-1"`)
+}
+
+func TestWorksWithCRUDStorage(t *testing.T) {
+	ctx := context.Background()
+	b := newTestBackend(t, filepath.Join(t.TempDir(), "sessions.db"))
+
+	storage := crud.New("test-resource", b, time.Now)
+
+	type data struct{ Value string }
+	rv, err := storage.Create(ctx, "c2lnbmF0dXJl", &data{Value: "v1"}, nil, nil, time.Hour)
+	require.NoError(t, err)
+
+	var got data
+	_, err = storage.Get(ctx, "c2lnbmF0dXJl", &got)
+	require.NoError(t, err)
+	require.Equal(t, "v1", got.Value)
+
+	newRV, err := storage.Update(ctx, "c2lnbmF0dXJl", rv, &data{Value: "v2"})
+	require.NoError(t, err)
+	require.NotEqual(t, rv, newRV)
+
+	require.NoError(t, storage.DeleteByLabel(ctx, crud.SecretLabelKey, "test-resource"))
+	_, err = storage.Get(ctx, "c2lnbmF0dXJl", &got)
+	require.True(t, apierrors.IsNotFound(err), "expected not found, got %v", err)
+}
+
+func TestMigrateFromSecrets(t *testing.T) {
+	ctx := context.Background()
+
+	toMigrate1 := testSecret("pinniped-storage-access-token-1", "access-token")
+	toMigrate1.Namespace = namespace
+	toMigrate2 := testSecret("pinniped-storage-refresh-token-1", "refresh-token")
+	toMigrate2.Namespace = namespace
+	clientSecret := testSecret("pinniped-storage-oidc-client-secret-1", "oidc-client-secret")
+	clientSecret.Namespace = namespace
+	unrelated := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "some-other-secret", Namespace: namespace}}
+
+	kubeClient := fake.NewClientset(toMigrate1, toMigrate2, clientSecret, unrelated)
+	secrets := kubeClient.CoreV1().Secrets(namespace)
+
+	b := newTestBackend(t, filepath.Join(t.TempDir(), "sessions.db"))
+
+	// Pretend that a previous migration attempt already copied one of the secrets before being interrupted.
+	_, err := b.Create(ctx, testSecret("pinniped-storage-access-token-1", "access-token"), metav1.CreateOptions{})
+	require.NoError(t, err)
+
+	migrated, err := MigrateFromSecrets(ctx, secrets, b, "oidc-client-secret")
+	require.NoError(t, err)
+	require.Equal(t, 2, migrated)
+
+	remaining, err := secrets.List(ctx, metav1.ListOptions{})
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{"pinniped-storage-oidc-client-secret-1", "some-other-secret"}, names(remaining))
+
+	inBolt, err := b.List(ctx, metav1.ListOptions{})
+	require.NoError(t, err)
+	require.Equal(t, []string{"pinniped-storage-access-token-1", "pinniped-storage-refresh-token-1"}, names(inBolt))
+	require.Equal(t, toMigrate2.Data, inBolt.Items[1].Data)
+	require.Equal(t, toMigrate2.Labels, inBolt.Items[1].Labels)
+	require.Equal(t, toMigrate2.Annotations, inBolt.Items[1].Annotations)
+	require.Equal(t, toMigrate2.Type, inBolt.Items[1].Type)
+	require.Empty(t, inBolt.Items[1].Namespace)
+
+	// Running it again is a no-op.
+	migrated, err = MigrateFromSecrets(ctx, secrets, b, "oidc-client-secret")
+	require.NoError(t, err)
+	require.Zero(t, migrated)
+}
+
+func TestMigrateFromSecretsErrors(t *testing.T) {
+	ctx := context.Background()
+
+	s := testSecret("pinniped-storage-access-token-1", "access-token")
+	s.Namespace = namespace
+
+	kubeClient := fake.NewClientset(s)
+	kubeClient.PrependReactor("delete", "secrets", func(_ coretesting.Action) (bool, runtime.Object, error) {
+		return true, nil, apierrors.NewInternalError(constable.Error("some delete error"))
+	})
+
+	b := newTestBackend(t, filepath.Join(t.TempDir(), "sessions.db"))
+
+	migrated, err := MigrateFromSecrets(ctx, kubeClient.CoreV1().Secrets(namespace), b)
+	require.EqualError(t, err, `failed to delete migrated secret "pinniped-storage-access-token-1": Internal error occurred: some delete error`)
+	require.Zero(t, migrated)
+
+	kubeClient = fake.NewClientset()
+	kubeClient.PrependReactor("list", "secrets", func(_ coretesting.Action) (bool, runtime.Object, error) {
+		return true, nil, apierrors.NewForbidden(schema.GroupResource{Resource: "secrets"}, "", constable.Error("nope"))
+	})
+	_, err = MigrateFromSecrets(ctx, kubeClient.CoreV1().Secrets(namespace), b)
+	require.ErrorContains(t, err, "failed to list secrets to migrate")
+}
+
+func names(list *corev1.SecretList) []string {
+	result := make([]string, 0, len(list.Items))
+	for _, s := range list.Items {
+		result = append(result, s.Name)
+	}
+	return result
+}
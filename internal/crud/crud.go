@@ -1,4 +1,4 @@
-// Copyright 2020-2024 the Pinniped contributors. All Rights Reserved.
+// Copyright 2020-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package crud
@@ -28,10 +28,12 @@ const (
 	SecretLifetimeAnnotationKey        = "storage.pinniped.dev/garbage-collect-after"
 	SecretLifetimeAnnotationDateFormat = time.RFC3339
 
+	// SecretDataKey is the key in the Secret's data which holds the stored JSON payload.
+	SecretDataKey = "pinniped-storage-data"
+
 	secretNameFormat = "pinniped-storage-%s-%s"
 	secretTypeFormat = "storage.pinniped.dev/%s"
 	secretVersion    = "1"
-	secretDataKey    = "pinniped-storage-data"
 	secretVersionKey = "pinniped-storage-version"
 
 	ErrSecretTypeMismatch    = constable.Error("secret storage data has incorrect type")
@@ -50,7 +52,21 @@ type Storage interface {
 
 type JSON any // document that we need valid JSON types
 
-func New(resource string, secrets corev1client.SecretInterface, clock func() time.Time) Storage {
+// SecretsBackend is where a Storage keeps its data. Each item of data is kept in the shape of a Secret,
+// and the backend is expected to behave like the Kubernetes API, including returning Kubernetes API errors
+// such as NotFound, AlreadyExists, and Conflict.
+// A corev1client.SecretInterface is a SecretsBackend which keeps the data as Secrets in the Kubernetes API.
+type SecretsBackend interface {
+	Create(ctx context.Context, secret *corev1.Secret, opts metav1.CreateOptions) (*corev1.Secret, error)
+	Update(ctx context.Context, secret *corev1.Secret, opts metav1.UpdateOptions) (*corev1.Secret, error)
+	Delete(ctx context.Context, name string, opts metav1.DeleteOptions) error
+	Get(ctx context.Context, name string, opts metav1.GetOptions) (*corev1.Secret, error)
+	List(ctx context.Context, opts metav1.ListOptions) (*corev1.SecretList, error)
+}
+
+var _ SecretsBackend = corev1client.SecretInterface(nil)
+
+func New(resource string, secrets SecretsBackend, clock func() time.Time) Storage {
 	return &secretsStorage{
 		resource:   resource,
 		secretType: secretType(resource),
@@ -62,7 +78,7 @@ func New(resource string, secrets corev1client.SecretInterface, clock func() tim
 type secretsStorage struct {
 	resource   string
 	secretType corev1.SecretType
-	secrets    corev1client.SecretInterface
+	secrets    SecretsBackend
 	clock      func() time.Time
 }
 
@@ -152,7 +168,7 @@ func FromSecret(resource string, secret *corev1.Secret, data JSON) error {
 	if err := validateSecret(resource, secret); err != nil {
 		return err
 	}
-	if err := json.Unmarshal(secret.Data[secretDataKey], data); err != nil {
+	if err := json.Unmarshal(secret.Data[SecretDataKey], data); err != nil {
 		return fmt.Errorf("failed to decode %s: %w", resource, err)
 	}
 	return nil
@@ -215,7 +231,7 @@ func (s *secretsStorage) toSecret(signature, resourceVersion string, data JSON,
 			OwnerReferences: ownerReferences,
 		},
 		Data: map[string][]byte{
-			secretDataKey:    buf,
+			SecretDataKey:    buf,
 			secretVersionKey: []byte(secretVersion),
 		},
 		Type: s.secretType,
@@ -0,0 +1,327 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package envelope implements envelope encryption of the data payload of storage Secrets.
+//
+// Each time a Secret is written, its payload is encrypted using a newly generated data encryption key (DEK).
+// The DEK is then encrypted ("wrapped") using a key encryption key (KEK) and stored in the Secret next to the
+// encrypted payload. The KEKs are never stored in the Secrets. When the active KEK is rotated, the DEKs of the
+// existing Secrets can be re-wrapped using the new KEK without needing to re-encrypt their payloads.
+//
+// Secrets which were written before encryption was enabled are left unencrypted until they are rewritten
+// or re-wrapped, and can still be read in the meantime.
+package envelope
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/cache"
+
+	"go.pinniped.dev/internal/crud"
+	"go.pinniped.dev/internal/plog"
+)
+
+const (
+	// dekDataKey is the key in the Secret's data which holds the JSON of the WrappedDEK.
+	// Its presence is what indicates that the payload is encrypted.
+	dekDataKey = "pinniped-storage-dek"
+
+	dekSize = 32
+
+	dekCacheSize = 1000
+	dekCacheTTL  = time.Hour
+)
+
+// WrappedDEK is a data encryption key which was encrypted by a KEKProvider.
+type WrappedDEK struct {
+	// KEKID identifies the KEK which was used to wrap the DEK.
+	KEKID string `json:"kekID"`
+	// Ciphertext is the encrypted DEK.
+	Ciphertext []byte `json:"ciphertext"`
+	// Annotations are any additional data which the KEKProvider needs to unwrap the DEK.
+	Annotations map[string][]byte `json:"annotations,omitempty"`
+}
+
+// KEKProvider wraps and unwraps data encryption keys using key encryption keys.
+type KEKProvider interface {
+	// Wrap encrypts the DEK using the currently active KEK.
+	Wrap(ctx context.Context, dek []byte) (*WrappedDEK, error)
+	// Unwrap decrypts a DEK which was previously returned by Wrap, using the KEK identified by wrapped.KEKID.
+	Unwrap(ctx context.Context, wrapped *WrappedDEK) ([]byte, error)
+	// ActiveKEKID returns the ID of the KEK which would currently be used by Wrap.
+	ActiveKEKID(ctx context.Context) (string, error)
+}
+
+// Transformer encrypts and decrypts the payload of storage Secrets.
+type Transformer struct {
+	kek      KEKProvider
+	dekCache *cache.LRUExpireCache
+}
+
+func NewTransformer(kek KEKProvider) *Transformer {
+	return &Transformer{
+		kek:      kek,
+		dekCache: cache.NewLRUExpireCache(dekCacheSize),
+	}
+}
+
+// IsEncrypted returns true when the payload of the Secret is encrypted.
+func IsEncrypted(secret *corev1.Secret) bool {
+	_, ok := secret.Data[dekDataKey]
+	return ok
+}
+
+// EncryptSecret returns a copy of the Secret whose payload is encrypted using a new DEK, wrapped by the active KEK.
+func (t *Transformer) EncryptSecret(ctx context.Context, secret *corev1.Secret) (*corev1.Secret, error) {
+	if IsEncrypted(secret) {
+		return nil, fmt.Errorf("secret %q is already encrypted", secret.Name)
+	}
+
+	dek := make([]byte, dekSize)
+	if _, err := io.ReadFull(rand.Reader, dek); err != nil {
+		return nil, fmt.Errorf("failed to generate data encryption key: %w", err)
+	}
+
+	wrapped, err := t.kek.Wrap(ctx, dek)
+	if err != nil {
+		return nil, fmt.Errorf("failed to wrap data encryption key for secret %q: %w", secret.Name, err)
+	}
+
+	ciphertext, err := seal(dek, secret.Data[crud.SecretDataKey], []byte(secret.Name))
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt secret %q: %w", secret.Name, err)
+	}
+
+	wrappedJSON, err := json.Marshal(wrapped)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode data encryption key for secret %q: %w", secret.Name, err)
+	}
+
+	encrypted := secret.DeepCopy()
+	encrypted.Data[crud.SecretDataKey] = ciphertext
+	encrypted.Data[dekDataKey] = wrappedJSON
+	return encrypted, nil
+}
+
+// DecryptSecret returns a copy of the Secret whose payload is decrypted. Secrets which are not encrypted are
+// returned unchanged, which allows Secrets that were written before encryption was enabled to be read.
+func (t *Transformer) DecryptSecret(ctx context.Context, secret *corev1.Secret) (*corev1.Secret, error) {
+	if !IsEncrypted(secret) {
+		return secret, nil
+	}
+
+	wrapped, err := wrappedDEKFromSecret(secret)
+	if err != nil {
+		return nil, err
+	}
+
+	dek, err := t.unwrap(ctx, wrapped)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unwrap data encryption key for secret %q: %w", secret.Name, err)
+	}
+
+	plaintext, err := open(dek, secret.Data[crud.SecretDataKey], []byte(secret.Name))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt secret %q: %w", secret.Name, err)
+	}
+
+	decrypted := secret.DeepCopy()
+	decrypted.Data[crud.SecretDataKey] = plaintext
+	delete(decrypted.Data, dekDataKey)
+	return decrypted, nil
+}
+
+// RewrapSecret returns a copy of the Secret whose DEK is wrapped by the active KEK, or nil when the Secret's DEK
+// is already wrapped by the active KEK. A Secret which is not encrypted yet is encrypted. The payload of an
+// encrypted Secret is not decrypted, because only its DEK needs to change.
+func (t *Transformer) RewrapSecret(ctx context.Context, secret *corev1.Secret, activeKEKID string) (*corev1.Secret, error) {
+	if !IsEncrypted(secret) {
+		return t.EncryptSecret(ctx, secret)
+	}
+
+	wrapped, err := wrappedDEKFromSecret(secret)
+	if err != nil {
+		return nil, err
+	}
+
+	if wrapped.KEKID == activeKEKID {
+		return nil, nil
+	}
+
+	dek, err := t.unwrap(ctx, wrapped)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unwrap data encryption key for secret %q: %w", secret.Name, err)
+	}
+
+	rewrapped, err := t.kek.Wrap(ctx, dek)
+	if err != nil {
+		return nil, fmt.Errorf("failed to wrap data encryption key for secret %q: %w", secret.Name, err)
+	}
+
+	rewrappedJSON, err := json.Marshal(rewrapped)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode data encryption key for secret %q: %w", secret.Name, err)
+	}
+
+	result := secret.DeepCopy()
+	result.Data[dekDataKey] = rewrappedJSON
+	return result, nil
+}
+
+// ActiveKEKID returns the ID of the KEK which is currently used to wrap new DEKs.
+func (t *Transformer) ActiveKEKID(ctx context.Context) (string, error) {
+	return t.kek.ActiveKEKID(ctx)
+}
+
+// unwrap returns the DEK, avoiding calls to the KEKProvider for recently used DEKs.
+func (t *Transformer) unwrap(ctx context.Context, wrapped *WrappedDEK) ([]byte, error) {
+	cacheKey := dekCacheKey(wrapped)
+
+	if dek, ok := t.dekCache.Get(cacheKey); ok {
+		return dek.([]byte), nil
+	}
+
+	dek, err := t.kek.Unwrap(ctx, wrapped)
+	if err != nil {
+		return nil, err
+	}
+
+	t.dekCache.Add(cacheKey, dek, dekCacheTTL)
+	return dek, nil
+}
+
+func dekCacheKey(wrapped *WrappedDEK) string {
+	sum := sha256.Sum256(append([]byte(wrapped.KEKID+"\x00"), wrapped.Ciphertext...))
+	return string(sum[:])
+}
+
+func wrappedDEKFromSecret(secret *corev1.Secret) (*WrappedDEK, error) {
+	wrapped := &WrappedDEK{}
+	if err := json.Unmarshal(secret.Data[dekDataKey], wrapped); err != nil {
+		return nil, fmt.Errorf("failed to decode data encryption key for secret %q: %w", secret.Name, err)
+	}
+	return wrapped, nil
+}
+
+// seal encrypts using AES-256-GCM, prefixing the ciphertext with a random nonce.
+func seal(key, plaintext, additionalData []byte) ([]byte, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	return aead.Seal(nonce, nonce, plaintext, additionalData), nil
+}
+
+// open decrypts the output of seal.
+func open(key, ciphertext, additionalData []byte) ([]byte, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+
+	nonceSize := aead.NonceSize()
+	if len(ciphertext) < nonceSize {
+		return nil, fmt.Errorf("ciphertext too short")
+	}
+
+	return aead.Open(nil, ciphertext[:nonceSize], ciphertext[nonceSize:], additionalData)
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("could not create cipher: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
+
+type secretsBackend struct {
+	delegate    crud.SecretsBackend
+	transformer *Transformer
+}
+
+var _ crud.SecretsBackend = &secretsBackend{}
+
+// NewSecretsBackend returns a crud.SecretsBackend which encrypts the payload of each Secret before writing it
+// to the delegate, and decrypts the payload of each Secret after reading it from the delegate.
+func NewSecretsBackend(delegate crud.SecretsBackend, transformer *Transformer) crud.SecretsBackend {
+	return &secretsBackend{delegate: delegate, transformer: transformer}
+}
+
+func (b *secretsBackend) Create(ctx context.Context, secret *corev1.Secret, opts metav1.CreateOptions) (*corev1.Secret, error) {
+	encrypted, err := b.transformer.EncryptSecret(ctx, secret)
+	if err != nil {
+		return nil, err
+	}
+
+	created, err := b.delegate.Create(ctx, encrypted, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return b.transformer.DecryptSecret(ctx, created)
+}
+
+func (b *secretsBackend) Update(ctx context.Context, secret *corev1.Secret, opts metav1.UpdateOptions) (*corev1.Secret, error) {
+	encrypted, err := b.transformer.EncryptSecret(ctx, secret)
+	if err != nil {
+		return nil, err
+	}
+
+	updated, err := b.delegate.Update(ctx, encrypted, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return b.transformer.DecryptSecret(ctx, updated)
+}
+
+func (b *secretsBackend) Delete(ctx context.Context, name string, opts metav1.DeleteOptions) error {
+	return b.delegate.Delete(ctx, name, opts)
+}
+
+func (b *secretsBackend) Get(ctx context.Context, name string, opts metav1.GetOptions) (*corev1.Secret, error) {
+	secret, err := b.delegate.Get(ctx, name, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return b.transformer.DecryptSecret(ctx, secret)
+}
+
+func (b *secretsBackend) List(ctx context.Context, opts metav1.ListOptions) (*corev1.SecretList, error) {
+	list, err := b.delegate.List(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	decryptedList := list.DeepCopy()
+	decryptedList.Items = make([]corev1.Secret, 0, len(list.Items))
+	for i := range list.Items {
+		decrypted, err := b.transformer.DecryptSecret(ctx, &list.Items[i])
+		if err != nil {
+			// Skip unreadable Secrets, so they cannot block garbage collection or the listing of sessions.
+			plog.WarningErr("could not read session storage", err, "secretName", list.Items[i].Name)
+			continue
+		}
+		decryptedList.Items = append(decryptedList.Items, *decrypted)
+	}
+
+	return decryptedList, nil
+}
@@ -0,0 +1,306 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package envelope
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	kmsservice "k8s.io/kms/pkg/service"
+
+	"go.pinniped.dev/internal/crud"
+)
+
+const namespace = "test-ns"
+
+func kekSecret(active string, keks map[string]string) *corev1.Secret {
+	data := map[string][]byte{}
+	if active != "" {
+		data[KEKSecretActiveKey] = []byte(active)
+	}
+	for id, kek := range keks {
+		data[id] = []byte(kek)
+	}
+	return &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "kek-secret"}, Data: data}
+}
+
+func newTestSecretKEKProvider(t *testing.T, active string) *SecretKEKProvider {
+	t.Helper()
+
+	p := NewSecretKEKProvider()
+	require.NoError(t, p.SetKEKsFromSecret(kekSecret(active, map[string]string{
+		"kek-1": "0123456789abcdef0123456789abcdef",
+		"kek-2": "abcdef0123456789abcdef0123456789",
+	})))
+	return p
+}
+
+func storageSecret(payload string) *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   "pinniped-storage-access-token-abc",
+			Labels: map[string]string{crud.SecretLabelKey: "access-token"},
+		},
+		Data: map[string][]byte{
+			crud.SecretDataKey:         []byte(payload),
+			"pinniped-storage-version": []byte("1"),
+		},
+		Type: "storage.pinniped.dev/access-token",
+	}
+}
+
+func TestSetKEKsFromSecret(t *testing.T) {
+	tests := []struct {
+		name    string
+		secret  *corev1.Secret
+		wantErr string
+	}{
+		{
+			name:    "missing active key",
+			secret:  kekSecret("", map[string]string{"kek-1": "0123456789abcdef0123456789abcdef"}),
+			wantErr: `secret "kek-secret" must have a "active" key which names the active key encryption key`,
+		},
+		{
+			name:    "active key not found",
+			secret:  kekSecret("kek-2", map[string]string{"kek-1": "0123456789abcdef0123456789abcdef"}),
+			wantErr: `secret "kek-secret" does not contain the active key encryption key "kek-2"`,
+		},
+		{
+			name:    "wrong size",
+			secret:  kekSecret("kek-1", map[string]string{"kek-1": "too short"}),
+			wantErr: `secret "kek-secret" key "kek-1" must be exactly 32 bytes`,
+		},
+		{
+			name:   "valid",
+			secret: kekSecret("kek-1", map[string]string{"kek-1": "0123456789abcdef0123456789abcdef"}),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewSecretKEKProvider()
+			err := p.SetKEKsFromSecret(tt.secret)
+			if tt.wantErr != "" {
+				require.EqualError(t, err, tt.wantErr)
+				_, err = p.ActiveKEKID(context.Background())
+				require.ErrorIs(t, err, ErrNoKEKs)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, []string{"kek-1"}, p.KEKIDs())
+		})
+	}
+}
+
+func TestSecretKEKProviderWithoutKeys(t *testing.T) {
+	p := NewSecretKEKProvider()
+
+	_, err := p.Wrap(context.Background(), []byte("dek"))
+	require.ErrorIs(t, err, ErrNoKEKs)
+
+	_, err = p.Unwrap(context.Background(), &WrappedDEK{KEKID: "kek-1"})
+	require.ErrorIs(t, err, ErrNoKEKs)
+}
+
+func TestEncryptAndDecrypt(t *testing.T) {
+	ctx := context.Background()
+	transformer := NewTransformer(newTestSecretKEKProvider(t, "kek-1"))
+
+	original := storageSecret(`{"some":"data"}`)
+
+	encrypted, err := transformer.EncryptSecret(ctx, original)
+	require.NoError(t, err)
+	require.True(t, IsEncrypted(encrypted))
+	require.False(t, IsEncrypted(original), "the original should not be modified")
+	require.NotContains(t, string(encrypted.Data[crud.SecretDataKey]), "some")
+	require.Equal(t, original.Labels, encrypted.Labels)
+	require.Equal(t, original.Data["pinniped-storage-version"], encrypted.Data["pinniped-storage-version"])
+
+	var wrapped WrappedDEK
+	require.NoError(t, json.Unmarshal(encrypted.Data[dekDataKey], &wrapped))
+	require.Equal(t, "kek-1", wrapped.KEKID)
+
+	_, err = transformer.EncryptSecret(ctx, encrypted)
+	require.EqualError(t, err, `secret "pinniped-storage-access-token-abc" is already encrypted`)
+
+	decrypted, err := transformer.DecryptSecret(ctx, encrypted)
+	require.NoError(t, err)
+	require.Equal(t, original, decrypted)
+
+	// Legacy unencrypted secrets are returned as-is.
+	decrypted, err = transformer.DecryptSecret(ctx, original)
+	require.NoError(t, err)
+	require.Equal(t, original, decrypted)
+
+	// The payload is bound to the name of the secret.
+	renamed := encrypted.DeepCopy()
+	renamed.Name = "some-other-name"
+	_, err = transformer.DecryptSecret(ctx, renamed)
+	require.EqualError(t, err, `failed to decrypt secret "some-other-name": cipher: message authentication failed`)
+
+	// The payload cannot be decrypted without the KEK which was used to wrap its DEK.
+	otherTransformer := NewTransformer(NewSecretKEKProvider())
+	_, err = otherTransformer.DecryptSecret(ctx, encrypted)
+	require.EqualError(t, err, `failed to unwrap data encryption key for secret "pinniped-storage-access-token-abc": no key encryption keys have been loaded yet`)
+}
+
+func TestRewrapSecret(t *testing.T) {
+	ctx := context.Background()
+	kekProvider := newTestSecretKEKProvider(t, "kek-1")
+	transformer := NewTransformer(kekProvider)
+
+	original := storageSecret(`{"some":"data"}`)
+
+	// Legacy unencrypted secrets are encrypted.
+	encrypted, err := transformer.RewrapSecret(ctx, original, "kek-1")
+	require.NoError(t, err)
+	require.True(t, IsEncrypted(encrypted))
+
+	// Secrets which already use the active KEK do not change.
+	unchanged, err := transformer.RewrapSecret(ctx, encrypted, "kek-1")
+	require.NoError(t, err)
+	require.Nil(t, unchanged)
+
+	// Rotate the active KEK.
+	require.NoError(t, kekProvider.SetKEKsFromSecret(kekSecret("kek-2", map[string]string{
+		"kek-1": "0123456789abcdef0123456789abcdef",
+		"kek-2": "abcdef0123456789abcdef0123456789",
+	})))
+
+	rewrapped, err := transformer.RewrapSecret(ctx, encrypted, "kek-2")
+	require.NoError(t, err)
+	require.Equal(t, encrypted.Data[crud.SecretDataKey], rewrapped.Data[crud.SecretDataKey], "the payload should not be re-encrypted")
+	var wrapped WrappedDEK
+	require.NoError(t, json.Unmarshal(rewrapped.Data[dekDataKey], &wrapped))
+	require.Equal(t, "kek-2", wrapped.KEKID)
+
+	// After removing the old KEK, the re-wrapped secret can still be decrypted by a fresh transformer.
+	newKEKProvider := NewSecretKEKProvider()
+	require.NoError(t, newKEKProvider.SetKEKsFromSecret(kekSecret("kek-2", map[string]string{
+		"kek-2": "abcdef0123456789abcdef0123456789",
+	})))
+	newTransformer := NewTransformer(newKEKProvider)
+	decrypted, err := newTransformer.DecryptSecret(ctx, rewrapped)
+	require.NoError(t, err)
+	require.Equal(t, original, decrypted)
+	_, err = newTransformer.DecryptSecret(ctx, encrypted)
+	require.EqualError(t, err, `failed to unwrap data encryption key for secret "pinniped-storage-access-token-abc": key encryption key "kek-1" not found`)
+}
+
+func TestSecretsBackendWithCRUDStorage(t *testing.T) {
+	ctx := context.Background()
+	kubeClient := fake.NewClientset()
+	rawSecrets := kubeClient.CoreV1().Secrets(namespace)
+	transformer := NewTransformer(newTestSecretKEKProvider(t, "kek-1"))
+
+	storage := crud.New("access-token", NewSecretsBackend(rawSecrets, transformer), time.Now)
+
+	type data struct{ Value string }
+	rv, err := storage.Create(ctx, "c2lnbmF0dXJl", &data{Value: "secret value"}, nil, nil, time.Hour)
+	require.NoError(t, err)
+
+	raw, err := rawSecrets.Get(ctx, storage.GetName("c2lnbmF0dXJl"), metav1.GetOptions{})
+	require.NoError(t, err)
+	require.True(t, IsEncrypted(raw))
+	require.NotContains(t, string(raw.Data[crud.SecretDataKey]), "secret value")
+
+	var got data
+	_, err = storage.Get(ctx, "c2lnbmF0dXJl", &got)
+	require.NoError(t, err)
+	require.Equal(t, "secret value", got.Value)
+
+	_, err = storage.Update(ctx, "c2lnbmF0dXJl", rv, &data{Value: "new value"})
+	require.NoError(t, err)
+	_, err = storage.Get(ctx, "c2lnbmF0dXJl", &got)
+	require.NoError(t, err)
+	require.Equal(t, "new value", got.Value)
+
+	// Legacy unencrypted secrets can still be read.
+	legacy := storageSecret(`{"Value":"legacy value"}`)
+	legacy.Name = storage.GetName("bGVnYWN5")
+	legacy.Namespace = namespace
+	require.NoError(t, kubeClient.Tracker().Add(legacy))
+	_, err = storage.Get(ctx, "bGVnYWN5", &got)
+	require.NoError(t, err)
+	require.Equal(t, "legacy value", got.Value)
+
+	// Secrets which cannot be decrypted are skipped by List, so they cannot block the listing of the other Secrets.
+	otherKEKProvider := NewSecretKEKProvider()
+	require.NoError(t, otherKEKProvider.SetKEKsFromSecret(kekSecret("kek-3", map[string]string{
+		"kek-3": "fedcba9876543210fedcba9876543210",
+	})))
+	other := storageSecret(`{"Value":"other value"}`)
+	other.Name = storage.GetName("b3RoZXI")
+	other.Namespace = namespace
+	undecryptable, err := NewTransformer(otherKEKProvider).EncryptSecret(ctx, other)
+	require.NoError(t, err)
+	require.NoError(t, kubeClient.Tracker().Add(undecryptable))
+
+	list, err := NewSecretsBackend(rawSecrets, transformer).List(ctx, metav1.ListOptions{})
+	require.NoError(t, err)
+	require.Len(t, list.Items, 2)
+	for _, item := range list.Items {
+		require.False(t, IsEncrypted(&item))
+		require.NotEqual(t, undecryptable.Name, item.Name)
+	}
+}
+
+type fakeKMSService struct {
+	keyID        string
+	decryptCalls int
+}
+
+func (f *fakeKMSService) Encrypt(_ context.Context, _ string, data []byte) (*kmsservice.EncryptResponse, error) {
+	return &kmsservice.EncryptResponse{
+		Ciphertext:  append([]byte("wrapped:"), data...),
+		KeyID:       f.keyID,
+		Annotations: map[string][]byte{"some.kms.example.com/annotation": []byte("value")},
+	}, nil
+}
+
+func (f *fakeKMSService) Decrypt(_ context.Context, _ string, req *kmsservice.DecryptRequest) ([]byte, error) {
+	f.decryptCalls++
+	if string(req.Annotations["some.kms.example.com/annotation"]) != "value" {
+		return nil, errors.New("missing annotation")
+	}
+	return req.Ciphertext[len("wrapped:"):], nil
+}
+
+func (f *fakeKMSService) Status(_ context.Context) (*kmsservice.StatusResponse, error) {
+	return &kmsservice.StatusResponse{Version: "v2", Healthz: "ok", KeyID: f.keyID}, nil
+}
+
+func TestKMSKEKProvider(t *testing.T) {
+	ctx := context.Background()
+	kms := &fakeKMSService{keyID: "kms-key-1"}
+	transformer := NewTransformer(NewKMSKEKProvider(kms))
+
+	activeKEKID, err := transformer.ActiveKEKID(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "kms-key-1", activeKEKID)
+
+	original := storageSecret(`{"some":"data"}`)
+	encrypted, err := transformer.EncryptSecret(ctx, original)
+	require.NoError(t, err)
+
+	for range 3 {
+		decrypted, err := transformer.DecryptSecret(ctx, encrypted)
+		require.NoError(t, err)
+		require.Equal(t, original, decrypted)
+	}
+	require.Equal(t, 1, kms.decryptCalls, "unwrapped DEKs should be cached")
+
+	kms.keyID = "kms-key-2"
+	rewrapped, err := transformer.RewrapSecret(ctx, encrypted, "kms-key-2")
+	require.NoError(t, err)
+	var wrapped WrappedDEK
+	require.NoError(t, json.Unmarshal(rewrapped.Data[dekDataKey], &wrapped))
+	require.Equal(t, "kms-key-2", wrapped.KEKID)
+}
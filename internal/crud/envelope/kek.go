@@ -0,0 +1,168 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package envelope
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/uuid"
+	kmsservice "k8s.io/kms/pkg/service"
+
+	"go.pinniped.dev/internal/constable"
+)
+
+const (
+	// KEKSecretActiveKey is the key in the data of a KEK Secret whose value is the ID of the active KEK.
+	// Every other key in the data of the Secret is the ID of a KEK, and its value is the 32 byte KEK.
+	KEKSecretActiveKey = "active"
+
+	kekSize = 32
+
+	ErrNoKEKs = constable.Error("no key encryption keys have been loaded yet")
+)
+
+// SecretKEKProvider is a KEKProvider whose KEKs are loaded from a Secret. It is thread-safe.
+type SecretKEKProvider struct {
+	mu          sync.RWMutex
+	activeKEKID string
+	keks        map[string][]byte
+}
+
+var _ KEKProvider = &SecretKEKProvider{}
+
+func NewSecretKEKProvider() *SecretKEKProvider {
+	return &SecretKEKProvider{}
+}
+
+// SetKEKsFromSecret replaces all KEKs using the contents of the Secret. Every KEK which was used to wrap
+// an existing DEK must remain in the Secret until those DEKs have been re-wrapped using the new active KEK.
+func (p *SecretKEKProvider) SetKEKsFromSecret(secret *corev1.Secret) error {
+	activeKEKID := string(secret.Data[KEKSecretActiveKey])
+	if activeKEKID == "" {
+		return fmt.Errorf("secret %q must have a %q key which names the active key encryption key", secret.Name, KEKSecretActiveKey)
+	}
+
+	keks := make(map[string][]byte, len(secret.Data)-1)
+	for id, kek := range secret.Data {
+		if id == KEKSecretActiveKey {
+			continue
+		}
+		if len(kek) != kekSize {
+			return fmt.Errorf("secret %q key %q must be exactly %d bytes", secret.Name, id, kekSize)
+		}
+		keks[id] = kek
+	}
+
+	if _, ok := keks[activeKEKID]; !ok {
+		return fmt.Errorf("secret %q does not contain the active key encryption key %q", secret.Name, activeKEKID)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.activeKEKID = activeKEKID
+	p.keks = keks
+	return nil
+}
+
+// KEKIDs returns the sorted IDs of all loaded KEKs.
+func (p *SecretKEKProvider) KEKIDs() []string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	ids := make([]string, 0, len(p.keks))
+	for id := range p.keks {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+func (p *SecretKEKProvider) Wrap(_ context.Context, dek []byte) (*WrappedDEK, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if p.activeKEKID == "" {
+		return nil, ErrNoKEKs
+	}
+
+	// Use the ID of the KEK as additional data to bind the wrapped DEK to the KEK's ID.
+	ciphertext, err := seal(p.keks[p.activeKEKID], dek, []byte(p.activeKEKID))
+	if err != nil {
+		return nil, err
+	}
+
+	return &WrappedDEK{KEKID: p.activeKEKID, Ciphertext: ciphertext}, nil
+}
+
+func (p *SecretKEKProvider) Unwrap(_ context.Context, wrapped *WrappedDEK) ([]byte, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if p.activeKEKID == "" {
+		return nil, ErrNoKEKs
+	}
+
+	kek, ok := p.keks[wrapped.KEKID]
+	if !ok {
+		return nil, fmt.Errorf("key encryption key %q not found", wrapped.KEKID)
+	}
+
+	return open(kek, wrapped.Ciphertext, []byte(wrapped.KEKID))
+}
+
+func (p *SecretKEKProvider) ActiveKEKID(_ context.Context) (string, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if p.activeKEKID == "" {
+		return "", ErrNoKEKs
+	}
+	return p.activeKEKID, nil
+}
+
+// KMSKEKProvider is a KEKProvider whose KEKs are held by a KMS, which is accessed using a Kubernetes KMS v2 plugin.
+type KMSKEKProvider struct {
+	service kmsservice.Service
+}
+
+var _ KEKProvider = &KMSKEKProvider{}
+
+func NewKMSKEKProvider(service kmsservice.Service) *KMSKEKProvider {
+	return &KMSKEKProvider{service: service}
+}
+
+func (p *KMSKEKProvider) Wrap(ctx context.Context, dek []byte) (*WrappedDEK, error) {
+	resp, err := p.service.Encrypt(ctx, string(uuid.NewUUID()), dek)
+	if err != nil {
+		return nil, fmt.Errorf("kms encrypt: %w", err)
+	}
+	return &WrappedDEK{KEKID: resp.KeyID, Ciphertext: resp.Ciphertext, Annotations: resp.Annotations}, nil
+}
+
+func (p *KMSKEKProvider) Unwrap(ctx context.Context, wrapped *WrappedDEK) ([]byte, error) {
+	dek, err := p.service.Decrypt(ctx, string(uuid.NewUUID()), &kmsservice.DecryptRequest{
+		Ciphertext:  wrapped.Ciphertext,
+		KeyID:       wrapped.KEKID,
+		Annotations: wrapped.Annotations,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("kms decrypt: %w", err)
+	}
+	return dek, nil
+}
+
+func (p *KMSKEKProvider) ActiveKEKID(ctx context.Context) (string, error) {
+	status, err := p.service.Status(ctx)
+	if err != nil {
+		return "", fmt.Errorf("kms status: %w", err)
+	}
+	if status.KeyID == "" {
+		return "", constable.Error("kms status did not return a key ID")
+	}
+	return status.KeyID, nil
+}
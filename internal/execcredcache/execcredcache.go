@@ -1,4 +1,4 @@
-// Copyright 2021-2024 the Pinniped contributors. All Rights Reserved.
+// Copyright 2021-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 // Package execcredcache implements a cache for Kubernetes ExecCredential data.
@@ -111,6 +111,19 @@ func (c *Cache) Put(key any, cred *clientauthenticationv1beta1.ExecCredential) {
 	})
 }
 
+// Clear removes all cached credentials. The cache keys are hashes, so there is no way to select only the credentials
+// for a particular issuer. Clearing them is harmless, since they will be recreated from the session cache when needed.
+func (c *Cache) Clear() {
+	// If the cache file does not exist, exit immediately with no error log
+	if _, err := os.Stat(c.path); errors.Is(err, os.ErrNotExist) {
+		return
+	}
+
+	c.withCache(func(cache *credCache) {
+		cache.Entries = nil
+	})
+}
+
 func jsonSHA256Hex(key any) string {
 	hash := sha256.New()
 	if err := json.NewEncoder(hash).Encode(key); err != nil {
@@ -346,6 +346,50 @@ func TestPutToken(t *testing.T) {
 	}
 }
 
+func TestClear(t *testing.T) {
+	t.Parallel()
+	now := time.Now().Round(1 * time.Second)
+
+	type testKey struct{ K1, K2 string }
+
+	t.Run("cache file does not exist", func(t *testing.T) {
+		t.Parallel()
+		tmp := t.TempDir() + "/cachedir/credentials.yaml"
+		errors := errorCollector{t: t}
+		c := New(tmp)
+		c.errReporter = errors.report
+		c.Clear()
+		errors.require(nil)
+		require.NoFileExists(t, tmp)
+	})
+
+	t.Run("removes all entries", func(t *testing.T) {
+		t.Parallel()
+		tmp := t.TempDir() + "/cachedir/credentials.yaml"
+		errors := errorCollector{t: t}
+		c := New(tmp)
+		c.errReporter = errors.report
+		for _, key := range []testKey{{K1: "v1"}, {K1: "v2"}} {
+			c.Put(key, &clientauthenticationv1beta1.ExecCredential{
+				Status: &clientauthenticationv1beta1.ExecCredentialStatus{
+					ExpirationTimestamp: timePtr(now.Add(1 * time.Hour)),
+					Token:               "some-token",
+				},
+			})
+		}
+		require.NotNil(t, c.Get(testKey{K1: "v1"}))
+
+		c.Clear()
+		errors.require(nil)
+
+		cache, err := readCache(tmp)
+		require.NoError(t, err)
+		require.Empty(t, cache.Entries)
+		require.Nil(t, c.Get(testKey{K1: "v1"}))
+		require.Nil(t, c.Get(testKey{K1: "v2"}))
+	})
+}
+
 func TestHashing(t *testing.T) {
 	type testKey struct{ K1, K2 string }
 	require.Equal(t, "38e0b9de817f645c4bec37c0d4a3e58baecccb040f5718dc069a72c7385a0bed", jsonSHA256Hex(nil))
@@ -0,0 +1,45 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package brandedpages builds the set of web pages which a FederationDomain shows to end users.
+package brandedpages
+
+import (
+	"go.pinniped.dev/internal/federationdomain/branding"
+	"go.pinniped.dev/internal/federationdomain/endpoints/chooseidp/chooseidphtml"
+	"go.pinniped.dev/internal/federationdomain/endpoints/login/loginhtml"
+	"go.pinniped.dev/internal/federationdomain/formposthtml"
+)
+
+// Pages are the web pages of a FederationDomain.
+type Pages struct {
+	Login     *loginhtml.Page
+	ChooseIDP *chooseidphtml.Page
+	FormPost  *formposthtml.Page
+}
+
+// Default returns the pages without any branding.
+func Default() *Pages {
+	return &Pages{
+		Login:     loginhtml.DefaultPage(),
+		ChooseIDP: chooseidphtml.DefaultPage(),
+		FormPost:  formposthtml.DefaultPage(),
+	}
+}
+
+// New returns the pages customized by the given branding, or an error when any of its template overrides are invalid.
+func New(b *branding.Branding) (*Pages, error) {
+	login, err := loginhtml.NewPage(b)
+	if err != nil {
+		return nil, err
+	}
+	chooseIDP, err := chooseidphtml.NewPage(b)
+	if err != nil {
+		return nil, err
+	}
+	formPost, err := formposthtml.NewPage(b)
+	if err != nil {
+		return nil, err
+	}
+	return &Pages{Login: login, ChooseIDP: chooseIDP, FormPost: formPost}, nil
+}
@@ -0,0 +1,213 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package branding parses and validates the ConfigMaps which customize the web pages of a FederationDomain.
+package branding
+
+import (
+	"encoding/base64"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/yaml"
+)
+
+// These are the keys which may be used in a branding ConfigMap.
+const (
+	LogoKey              = "logo"
+	PrimaryColorKey      = "primaryColor"
+	BackgroundColorKey   = "backgroundColor"
+	TextColorKey         = "textColor"
+	HelpTextKey          = "helpText"
+	FooterLinksKey       = "footerLinks"
+	LoginTemplateKey     = "login.gohtml"
+	ChooseIDPTemplateKey = "choose_idp.gohtml"
+	FormPostTemplateKey  = "form_post.gohtml"
+)
+
+const (
+	maxLogoBytes          = 100 * 1024
+	maxHelpTextLength     = 1000
+	maxFooterLinks        = 10
+	maxFooterLinkTextSize = 100
+	maxTemplateBytes      = 64 * 1024
+)
+
+//nolint:gochecknoglobals // These are effectively constants.
+var (
+	hexColorRegexp = regexp.MustCompile(`^#([0-9a-fA-F]{3}|[0-9a-fA-F]{6})$`)
+
+	allowedLogoContentTypes = []string{"image/png", "image/jpeg", "image/gif", "image/webp"}
+
+	dataKeys = []string{
+		PrimaryColorKey, BackgroundColorKey, TextColorKey, HelpTextKey, FooterLinksKey,
+		LoginTemplateKey, ChooseIDPTemplateKey, FormPostTemplateKey,
+	}
+)
+
+// FooterLink is a link which is shown at the bottom of each page.
+type FooterLink struct {
+	Text string `json:"text"`
+	URL  string `json:"url"`
+}
+
+// Branding is the validated content of a branding ConfigMap. Its exported fields are available to the page
+// templates via the "branding" template function, which returns nil when a page is not branded.
+type Branding struct {
+	// Logo is a data URI containing the logo image, or empty when there is no logo.
+	Logo template.URL
+
+	PrimaryColor    string
+	BackgroundColor string
+	TextColor       string
+
+	HelpText    string
+	FooterLinks []FooterLink
+
+	templateOverrides map[string]string
+}
+
+// FromConfigMap validates the given ConfigMap and returns the Branding that it describes.
+// The template overrides are not parsed here. They are validated by the page which they replace.
+func FromConfigMap(cm *corev1.ConfigMap) (*Branding, error) {
+	var errs []string
+	b := &Branding{templateOverrides: map[string]string{}}
+
+	for key := range cm.Data {
+		if !slices.Contains(dataKeys, key) {
+			if key == LogoKey {
+				errs = append(errs, fmt.Sprintf("key %q must be in binaryData", key))
+				continue
+			}
+			errs = append(errs, fmt.Sprintf("unknown key %q in data", key))
+		}
+	}
+	for key := range cm.BinaryData {
+		if key != LogoKey {
+			errs = append(errs, fmt.Sprintf("unknown key %q in binaryData", key))
+		}
+	}
+
+	if logo, ok := cm.BinaryData[LogoKey]; ok {
+		contentType := http.DetectContentType(logo)
+		switch {
+		case len(logo) > maxLogoBytes:
+			errs = append(errs, fmt.Sprintf("key %q must be at most %d bytes", LogoKey, maxLogoBytes))
+		case !slices.Contains(allowedLogoContentTypes, contentType):
+			errs = append(errs, fmt.Sprintf("key %q must be a PNG, JPEG, GIF, or WebP image, but was %q", LogoKey, contentType))
+		default:
+			b.Logo = template.URL("data:" + contentType + ";base64," + base64.StdEncoding.EncodeToString(logo)) //nolint:gosec // This is a validated image, encoded by us.
+		}
+	}
+
+	for key, color := range map[string]*string{
+		PrimaryColorKey:    &b.PrimaryColor,
+		BackgroundColorKey: &b.BackgroundColor,
+		TextColorKey:       &b.TextColor,
+	} {
+		value, ok := cm.Data[key]
+		if !ok {
+			continue
+		}
+		if !hexColorRegexp.MatchString(value) {
+			errs = append(errs, fmt.Sprintf("key %q must be a hex color such as #1a73e8", key))
+			continue
+		}
+		*color = value
+	}
+
+	if helpText, ok := cm.Data[HelpTextKey]; ok {
+		if utf8.RuneCountInString(helpText) > maxHelpTextLength {
+			errs = append(errs, fmt.Sprintf("key %q must be at most %d characters", HelpTextKey, maxHelpTextLength))
+		} else {
+			b.HelpText = strings.TrimSpace(helpText)
+		}
+	}
+
+	if footerLinks, ok := cm.Data[FooterLinksKey]; ok {
+		links, err := parseFooterLinks(footerLinks)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("key %q is invalid: %s", FooterLinksKey, err.Error()))
+		} else {
+			b.FooterLinks = links
+		}
+	}
+
+	for _, key := range []string{LoginTemplateKey, ChooseIDPTemplateKey, FormPostTemplateKey} {
+		if tmpl, ok := cm.Data[key]; ok {
+			if len(tmpl) > maxTemplateBytes {
+				errs = append(errs, fmt.Sprintf("key %q must be at most %d bytes", key, maxTemplateBytes))
+				continue
+			}
+			b.templateOverrides[key] = tmpl
+		}
+	}
+
+	if len(errs) > 0 {
+		slices.Sort(errs)
+		return nil, fmt.Errorf("invalid branding ConfigMap %q: %s", cm.Name, strings.Join(errs, "; "))
+	}
+	return b, nil
+}
+
+func parseFooterLinks(s string) ([]FooterLink, error) {
+	var links []FooterLink
+	if err := yaml.UnmarshalStrict([]byte(s), &links); err != nil {
+		return nil, fmt.Errorf("must be a list of links with text and url: %w", err)
+	}
+	if len(links) > maxFooterLinks {
+		return nil, fmt.Errorf("must have at most %d links", maxFooterLinks)
+	}
+	for i, link := range links {
+		if link.Text == "" || utf8.RuneCountInString(link.Text) > maxFooterLinkTextSize {
+			return nil, fmt.Errorf("link %d must have text of at most %d characters", i, maxFooterLinkTextSize)
+		}
+		u, err := url.Parse(link.URL)
+		if err != nil || u.Scheme != "https" || u.Host == "" {
+			return nil, fmt.Errorf("link %d must have an https url", i)
+		}
+	}
+	return links, nil
+}
+
+// TemplateOverride returns the template which replaces the default template of the page with the given key,
+// e.g. LoginTemplateKey. It is safe to call on a nil Branding.
+func (b *Branding) TemplateOverride(key string) (string, bool) {
+	if b == nil {
+		return "", false
+	}
+	tmpl, ok := b.templateOverrides[key]
+	return tmpl, ok
+}
+
+// CSS returns the CSS rules which style the branded elements of every page and apply the background and text colors.
+// Each page adds its own rules for the primary color. It is safe to call on a nil Branding.
+func (b *Branding) CSS() string {
+	if b == nil {
+		return ""
+	}
+	var css strings.Builder
+	css.WriteString(`
+.branding-logo { display: block; max-width: 100%; max-height: 60px; margin: 0 auto 30px; }
+.branding-footer { max-width: 460px; margin: 20px; text-align: center; font-size: 12px; }
+.branding-help { white-space: pre-line; margin: 0 0 10px; }
+.branding-footer a { margin: 0 8px; }
+`)
+	if b.BackgroundColor != "" {
+		fmt.Fprintf(&css, "body { background: %s; }\n", b.BackgroundColor)
+	}
+	if b.TextColor != "" {
+		fmt.Fprintf(&css, "body { color: %s; }\n", b.TextColor)
+	}
+	if b.PrimaryColor != "" {
+		fmt.Fprintf(&css, ".branding-footer a { color: %s; }\n", b.PrimaryColor)
+	}
+	return css.String()
+}
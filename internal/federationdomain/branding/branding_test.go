@@ -0,0 +1,161 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package branding
+
+import (
+	"bytes"
+	"html/template"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestFromConfigMap(t *testing.T) {
+	fakePNG := []byte("\x89PNG\r\n\x1a\nsome-fake-image-data")
+
+	tests := []struct {
+		name       string
+		data       map[string]string
+		binaryData map[string][]byte
+		want       *Branding
+		wantErr    string
+	}{
+		{
+			name: "empty",
+			want: &Branding{templateOverrides: map[string]string{}},
+		},
+		{
+			name: "all keys",
+			data: map[string]string{
+				"primaryColor":      "#1a73e8",
+				"backgroundColor":   "#FFF",
+				"textColor":         "#333333",
+				"helpText":          "  Contact the help desk.\n",
+				"footerLinks":       "- text: Help\n  url: https://help.example.com\n- text: Privacy\n  url: https://example.com/privacy\n",
+				"login.gohtml":      "some login template",
+				"choose_idp.gohtml": "some choose idp template",
+				"form_post.gohtml":  "some form post template",
+			},
+			binaryData: map[string][]byte{"logo": fakePNG},
+			want: &Branding{
+				Logo:            template.URL("data:image/png;base64,iVBORw0KGgpzb21lLWZha2UtaW1hZ2UtZGF0YQ=="),
+				PrimaryColor:    "#1a73e8",
+				BackgroundColor: "#FFF",
+				TextColor:       "#333333",
+				HelpText:        "Contact the help desk.",
+				FooterLinks: []FooterLink{
+					{Text: "Help", URL: "https://help.example.com"},
+					{Text: "Privacy", URL: "https://example.com/privacy"},
+				},
+				templateOverrides: map[string]string{
+					"login.gohtml":      "some login template",
+					"choose_idp.gohtml": "some choose idp template",
+					"form_post.gohtml":  "some form post template",
+				},
+			},
+		},
+		{
+			name:    "unknown keys",
+			data:    map[string]string{"someKey": "value"},
+			wantErr: `invalid branding ConfigMap "some-branding": unknown key "someKey" in data`,
+		},
+		{
+			name:       "unknown binary keys and logo in data",
+			data:       map[string]string{"logo": "value"},
+			binaryData: map[string][]byte{"primaryColor": []byte("#fff")},
+			wantErr:    `invalid branding ConfigMap "some-branding": key "logo" must be in binaryData; unknown key "primaryColor" in binaryData`,
+		},
+		{
+			name:       "logo is not an image",
+			binaryData: map[string][]byte{"logo": []byte("not an image")},
+			wantErr:    `invalid branding ConfigMap "some-branding": key "logo" must be a PNG, JPEG, GIF, or WebP image, but was "text/plain; charset=utf-8"`,
+		},
+		{
+			name:       "logo is too big",
+			binaryData: map[string][]byte{"logo": append(fakePNG, bytes.Repeat([]byte{0}, 100*1024)...)},
+			wantErr:    `invalid branding ConfigMap "some-branding": key "logo" must be at most 102400 bytes`,
+		},
+		{
+			name: "invalid colors",
+			data: map[string]string{"primaryColor": "blue", "textColor": "#12345", "backgroundColor": "#fff;}body{"},
+			wantErr: `invalid branding ConfigMap "some-branding": ` +
+				`key "backgroundColor" must be a hex color such as #1a73e8; ` +
+				`key "primaryColor" must be a hex color such as #1a73e8; ` +
+				`key "textColor" must be a hex color such as #1a73e8`,
+		},
+		{
+			name:    "help text is too long",
+			data:    map[string]string{"helpText": string(bytes.Repeat([]byte("a"), 1001))},
+			wantErr: `invalid branding ConfigMap "some-branding": key "helpText" must be at most 1000 characters`,
+		},
+		{
+			name:    "footer links are not a list",
+			data:    map[string]string{"footerLinks": "text: Help"},
+			wantErr: `invalid branding ConfigMap "some-branding": key "footerLinks" is invalid: must be a list of links with text and url: error unmarshaling JSON: while decoding JSON: json: cannot unmarshal object into Go value of type []branding.FooterLink`,
+		},
+		{
+			name:    "footer links have unknown fields",
+			data:    map[string]string{"footerLinks": "- text: Help\n  href: https://help.example.com\n"},
+			wantErr: `invalid branding ConfigMap "some-branding": key "footerLinks" is invalid: must be a list of links with text and url: error unmarshaling JSON: while decoding JSON: json: unknown field "href"`,
+		},
+		{
+			name:    "footer link without text",
+			data:    map[string]string{"footerLinks": "- url: https://help.example.com\n"},
+			wantErr: `invalid branding ConfigMap "some-branding": key "footerLinks" is invalid: link 0 must have text of at most 100 characters`,
+		},
+		{
+			name:    "footer link which is not https",
+			data:    map[string]string{"footerLinks": "- text: Help\n  url: https://help.example.com\n- text: Bad\n  url: javascript:alert(1)\n"},
+			wantErr: `invalid branding ConfigMap "some-branding": key "footerLinks" is invalid: link 1 must have an https url`,
+		},
+		{
+			name:    "template is too big",
+			data:    map[string]string{"login.gohtml": string(bytes.Repeat([]byte("a"), 64*1024+1))},
+			wantErr: `invalid branding ConfigMap "some-branding": key "login.gohtml" must be at most 65536 bytes`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := FromConfigMap(&corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: "some-branding"},
+				Data:       tt.data,
+				BinaryData: tt.binaryData,
+			})
+			if tt.wantErr != "" {
+				require.EqualError(t, err, tt.wantErr)
+				require.Nil(t, got)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestTemplateOverride(t *testing.T) {
+	var nilBranding *Branding
+	_, ok := nilBranding.TemplateOverride(LoginTemplateKey)
+	require.False(t, ok)
+
+	b := &Branding{templateOverrides: map[string]string{LoginTemplateKey: "some template"}}
+	tmpl, ok := b.TemplateOverride(LoginTemplateKey)
+	require.True(t, ok)
+	require.Equal(t, "some template", tmpl)
+	_, ok = b.TemplateOverride(FormPostTemplateKey)
+	require.False(t, ok)
+}
+
+func TestCSS(t *testing.T) {
+	var nilBranding *Branding
+	require.Empty(t, nilBranding.CSS())
+
+	require.NotContains(t, (&Branding{}).CSS(), "body {")
+
+	css := (&Branding{PrimaryColor: "#111", BackgroundColor: "#222", TextColor: "#333"}).CSS()
+	require.Contains(t, css, "body { background: #222; }\n")
+	require.Contains(t, css, "body { color: #333; }\n")
+	require.Contains(t, css, ".branding-footer a { color: #111; }\n")
+}
@@ -0,0 +1,86 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package branding
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// CheckRenderedPage returns an error when the rendered HTML of a page uses anything which would be blocked by the
+// strict Content-Security-Policy of the Supervisor's pages, or which could be used to load content from elsewhere.
+// The only inline scripts and styles which are allowed are the ones given, because the CSP allows them by hash.
+func CheckRenderedPage(renderedHTML string, allowedScripts []string, allowedStyles []string) error {
+	tokenizer := html.NewTokenizer(strings.NewReader(renderedHTML))
+	for {
+		tokenType := tokenizer.Next()
+		switch tokenType { //nolint:exhaustive // Only tags need to be checked.
+		case html.ErrorToken:
+			if errors.Is(tokenizer.Err(), io.EOF) {
+				return nil
+			}
+			return fmt.Errorf("could not parse HTML: %w", tokenizer.Err())
+		case html.StartTagToken, html.SelfClosingTagToken:
+			token := tokenizer.Token()
+			if err := checkTag(token); err != nil {
+				return err
+			}
+			switch token.Data {
+			case "script":
+				if err := checkRawText(tokenizer, token.Data, "JS", allowedScripts); err != nil {
+					return err
+				}
+			case "style":
+				if err := checkRawText(tokenizer, token.Data, "CSS", allowedStyles); err != nil {
+					return err
+				}
+			}
+		}
+	}
+}
+
+func checkTag(token html.Token) error {
+	switch token.Data {
+	case "iframe", "frame", "frameset", "object", "embed", "applet", "base", "svg", "math":
+		return fmt.Errorf("<%s> elements are not allowed", token.Data)
+	}
+
+	for _, attr := range token.Attr {
+		key := strings.ToLower(attr.Key)
+		value := strings.ToLower(strings.TrimSpace(attr.Val))
+		switch {
+		case strings.HasPrefix(key, "on"):
+			return fmt.Errorf("event handler attribute %q on <%s> is not allowed", attr.Key, token.Data)
+		case key == "style":
+			return fmt.Errorf("style attribute on <%s> is not allowed, use the page's CSS instead", token.Data)
+		case key == "http-equiv":
+			return fmt.Errorf("http-equiv attribute on <%s> is not allowed", token.Data)
+		case strings.HasPrefix(value, "javascript:"):
+			return fmt.Errorf("javascript: URL in attribute %q on <%s> is not allowed", attr.Key, token.Data)
+		case key == "src" && token.Data == "script":
+			return errors.New("<script> elements may not have a src attribute")
+		case key == "src" && !strings.HasPrefix(value, "data:"):
+			return fmt.Errorf("<%s> elements may only use data: URLs in their src attribute", token.Data)
+		case key == "href" && token.Data == "link" && !strings.HasPrefix(value, "data:"):
+			return errors.New("<link> elements may only use data: URLs in their href attribute")
+		}
+	}
+	return nil
+}
+
+func checkRawText(tokenizer *html.Tokenizer, tagName string, language string, allowed []string) error {
+	text := ""
+	if tokenizer.Next() == html.TextToken {
+		text = string(tokenizer.Text())
+	}
+	if !slices.Contains(allowed, text) {
+		return fmt.Errorf("<%s> elements may only contain {{minified%s}}", tagName, language)
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package branding
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckRenderedPage(t *testing.T) {
+	const (
+		allowedJS  = `window.onload=()=>{}`
+		allowedCSS = `body{color:red}`
+	)
+
+	tests := []struct {
+		name    string
+		html    string
+		wantErr string
+	}{
+		{
+			name: "allowed content",
+			html: `<!DOCTYPE html><html><head><style>` + allowedCSS + `</style><script>` + allowedJS + `</script>` +
+				`<link rel="icon" href="data:image/png;base64,AAAA"/></head>` +
+				`<body><img class="logo" src="data:image/png;base64,AAAA" alt="Logo">` +
+				`<form action="/login" method="post"><input type="submit"></form>` +
+				`<a href="https://example.com">Help</a></body></html>`,
+		},
+		{
+			name:    "script which is not allowed",
+			html:    `<html><body><script>alert(1)</script></body></html>`,
+			wantErr: `<script> elements may only contain {{minifiedJS}}`,
+		},
+		{
+			name:    "empty script",
+			html:    `<html><body><script></script></body></html>`,
+			wantErr: `<script> elements may only contain {{minifiedJS}}`,
+		},
+		{
+			name:    "script with src",
+			html:    `<html><body><script src="https://example.com/script.js"></script></body></html>`,
+			wantErr: `<script> elements may not have a src attribute`,
+		},
+		{
+			name:    "style which is not allowed",
+			html:    `<html><head><style>body{color:blue}</style></head></html>`,
+			wantErr: `<style> elements may only contain {{minifiedCSS}}`,
+		},
+		{
+			name:    "style attribute",
+			html:    `<html><body><div style="color:blue"></div></body></html>`,
+			wantErr: `style attribute on <div> is not allowed, use the page's CSS instead`,
+		},
+		{
+			name:    "event handler attribute",
+			html:    `<html><body><button onclick="alert(1)"></button></body></html>`,
+			wantErr: `event handler attribute "onclick" on <button> is not allowed`,
+		},
+		{
+			name:    "javascript URL",
+			html:    `<html><body><a href=" JavaScript:alert(1)">x</a></body></html>`,
+			wantErr: `javascript: URL in attribute "href" on <a> is not allowed`,
+		},
+		{
+			name:    "remote image",
+			html:    `<html><body><img src="https://example.com/logo.png"></body></html>`,
+			wantErr: `<img> elements may only use data: URLs in their src attribute`,
+		},
+		{
+			name:    "remote stylesheet",
+			html:    `<html><head><link rel="stylesheet" href="https://example.com/style.css"></head></html>`,
+			wantErr: `<link> elements may only use data: URLs in their href attribute`,
+		},
+		{
+			name:    "iframe",
+			html:    `<html><body><iframe></iframe></body></html>`,
+			wantErr: `<iframe> elements are not allowed`,
+		},
+		{
+			name:    "base",
+			html:    `<html><head><base href="https://example.com/"></head></html>`,
+			wantErr: `<base> elements are not allowed`,
+		},
+		{
+			name:    "meta refresh",
+			html:    `<html><head><meta http-equiv="refresh" content="0; url=https://example.com"></head></html>`,
+			wantErr: `http-equiv attribute on <meta> is not allowed`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckRenderedPage(tt.html, []string{allowedJS}, []string{allowedCSS})
+			if tt.wantErr != "" {
+				require.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
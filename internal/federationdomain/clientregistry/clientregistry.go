@@ -1,4 +1,4 @@
-// Copyright 2021-2024 the Pinniped contributors. All Rights Reserved.
+// Copyright 2021-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 // Package clientregistry defines Pinniped's OAuth2/OIDC clients.
@@ -18,11 +18,19 @@ import (
 	supervisorconfigv1alpha1 "go.pinniped.dev/generated/latest/apis/supervisor/config/v1alpha1"
 	oidcapi "go.pinniped.dev/generated/latest/apis/supervisor/oidc"
 	supervisorclient "go.pinniped.dev/generated/latest/client/supervisor/clientset/versioned/typed/config/v1alpha1"
+	"go.pinniped.dev/internal/constable"
 	"go.pinniped.dev/internal/federationdomain/oidcclientvalidator"
+	"go.pinniped.dev/internal/federationdomain/timeouts"
+	"go.pinniped.dev/internal/fositestorage/clientassertion"
 	"go.pinniped.dev/internal/oidcclientsecretstorage"
 	"go.pinniped.dev/internal/plog"
 )
 
+// FederationDomainAnnotation is the annotation on an OIDCClient which was registered by the dynamic client
+// registration endpoint of a FederationDomain. It holds the name of that FederationDomain. A registered client
+// may only be used at the FederationDomain which registered it.
+const FederationDomainAnnotation = "supervisor.pinniped.dev/registered-by-federation-domain"
+
 // Client represents a Pinniped OAuth/OIDC client. It can be the static pinniped-cli client
 // or a dynamic client defined by an OIDCClient CR.
 type Client struct {
@@ -33,12 +41,53 @@ type Client struct {
 	// via RFC8693 token exchange. When zero, the ID token lifetime will be determined by the defaults
 	// for the FederationDomain.
 	IDTokenLifetimeConfiguration time.Duration
+
+	// SessionPolicy optionally overrides the FederationDomain's session policy for this specific client.
+	// It is not saved to session storage, because it is only needed while issuing tokens for the client.
+	SessionPolicy timeouts.SessionPolicy `json:"-"`
+
+	// TLSClientAuth is used to authenticate clients which use the tls_client_auth client authentication method.
+	// It is not saved to session storage, because it is only needed while authenticating the client.
+	TLSClientAuth *TLSClientAuth `json:"-"`
+
+	// ClientCredentials is the identity which this client acts as when it uses the client_credentials grant.
+	// It is not saved to session storage, because it is only needed while starting a new session for the client.
+	ClientCredentials *ClientCredentials `json:"-"`
+
+	// AccessTokenFormat is the format of the access tokens which are issued to the client.
+	// It is not saved to session storage, because it is only needed while issuing tokens for the client.
+	AccessTokenFormat supervisorconfigv1alpha1.AccessTokenFormat `json:"-"`
+
+	// AccessTokenAudience is the aud claim of the JWT access tokens which are issued to the client.
+	// It is not saved to session storage, because it is only needed while issuing tokens for the client.
+	AccessTokenAudience string `json:"-"`
+
+	// requirePushedAuthorizationRequests is true when the client must use pushed authorization requests.
+	// It is not saved to session storage, because it is only needed while starting a new authorization request.
+	requirePushedAuthorizationRequests bool
+}
+
+// ClientCredentials describes the identity of a client which uses the client_credentials grant.
+type ClientCredentials struct {
+	// Username is the username which the client acts as, before the FederationDomain's identity transformations.
+	Username string
+	// Groups are the group names which the client acts as, before the FederationDomain's identity transformations.
+	Groups []string
+	// IdentityProvider is the displayName of the FederationDomain's identity provider whose identity transformations
+	// should be applied to the client's identity. When empty, the FederationDomain's default identity provider is used.
+	IdentityProvider string
 }
 
 func (c *Client) GetIDTokenLifetimeConfiguration() time.Duration {
 	return c.IDTokenLifetimeConfiguration
 }
 
+// RequiresPushedAuthorizationRequests returns true when the client may only start authorization requests at the
+// authorization endpoint by using a request_uri which was issued by the pushed authorization request endpoint.
+func (c *Client) RequiresPushedAuthorizationRequests() bool {
+	return c.requirePushedAuthorizationRequests
+}
+
 // Client implements the base, OIDC, and response_mode client interfaces of Fosite.
 var (
 	_ fosite.Client              = (*Client)(nil)
@@ -57,22 +106,30 @@ func (c *Client) GetResponseModes() []fosite.ResponseModeType {
 
 // ClientManager is a fosite.ClientManager with a statically-defined client and with dynamically-defined clients.
 type ClientManager struct {
-	oidcClientsClient supervisorclient.OIDCClientInterface
-	storage           *oidcclientsecretstorage.OIDCClientSecretStorage
-	minBcryptCost     int
+	oidcClientsClient    supervisorclient.OIDCClientInterface
+	storage              *oidcclientsecretstorage.OIDCClientSecretStorage
+	clientAssertions     clientassertion.Storage
+	federationDomainName string
+	minBcryptCost        int
 }
 
 var _ fosite.ClientManager = (*ClientManager)(nil)
 
+// NewClientManager returns a ClientManager for the FederationDomain with the given name. OIDCClients which were
+// registered by the dynamic client registration endpoint of any other FederationDomain cannot be found by it.
 func NewClientManager(
 	oidcClientsClient supervisorclient.OIDCClientInterface,
 	storage *oidcclientsecretstorage.OIDCClientSecretStorage,
+	clientAssertions clientassertion.Storage,
+	federationDomainName string,
 	minBcryptCost int,
 ) *ClientManager {
 	return &ClientManager{
-		oidcClientsClient: oidcClientsClient,
-		storage:           storage,
-		minBcryptCost:     minBcryptCost,
+		oidcClientsClient:    oidcClientsClient,
+		storage:              storage,
+		clientAssertions:     clientAssertions,
+		federationDomainName: federationDomainName,
+		minBcryptCost:        minBcryptCost,
 	}
 }
 
@@ -104,6 +161,14 @@ func (m *ClientManager) GetClient(ctx context.Context, id string) (fosite.Client
 		return nil, fmt.Errorf("failed to get client %q", id)
 	}
 
+	// A client which was registered by another FederationDomain's registration endpoint is not a client of this
+	// FederationDomain. Act as if it does not exist, so the other FederationDomain's clients are not revealed.
+	if registeredBy, ok := oidcClient.Annotations[FederationDomainAnnotation]; ok && registeredBy != m.federationDomainName {
+		plog.Debug("OIDC client lookup GetClient() found a client which was registered by another FederationDomain",
+			"clientID", id, "registeredByFederationDomain", registeredBy, "federationDomain", m.federationDomainName)
+		return nil, fosite.ErrNotFound.WithDescription("no such client")
+	}
+
 	// Try to find the corresponding client secret storage Secret.
 	storageSecret, err := m.storage.GetStorageSecret(ctx, oidcClient.UID)
 	if err != nil {
@@ -120,26 +185,32 @@ func (m *ClientManager) GetClient(ctx context.Context, id string) (fosite.Client
 		return nil, fmt.Errorf("client %q exists but is invalid or not ready", id)
 	}
 
-	// Everything is valid, so return the client. Note that it has at least one client secret to be considered valid.
-	return oidcClientCRToFositeClient(oidcClient, clientSecrets), nil
+	// Everything is valid, so return the client. Note that a client which uses client_secret_basic
+	// has at least one client secret to be considered valid.
+	client, err := oidcClientCRToFositeClient(oidcClient, clientSecrets)
+	if err != nil {
+		// This should not happen because the OIDCClient was validated above, and by its CRD.
+		plog.Error("OIDC client lookup GetClient() failed to configure OIDCClient", err, "clientID", id)
+		return nil, fmt.Errorf("client %q exists but is invalid or not ready", id)
+	}
+	return client, nil
 }
 
 // ClientAssertionJWTValid returns an error if the JTI is
 // known or the DB check failed and nil if the JTI is not known.
 //
-// This functionality is not supported by the ClientManager.
-func (*ClientManager) ClientAssertionJWTValid(_ctx context.Context, _jti string) error {
-	return fmt.Errorf("not implemented")
+// This is used by the private_key_jwt client authentication method to prevent client assertions from being replayed.
+func (m *ClientManager) ClientAssertionJWTValid(ctx context.Context, jti string) error {
+	return m.clientAssertions.ClientAssertionJWTValid(ctx, jti)
 }
 
 // SetClientAssertionJWT marks a JTI as known for the given
-// expiry time. Before inserting the new JTI, it will clean
-// up any existing JTIs that have expired as those tokens can
-// not be replayed due to the expiry.
+// expiry time. Expired JTIs are removed by the garbage collector
+// as those tokens can not be replayed due to the expiry.
 //
-// This functionality is not supported by the ClientManager.
-func (*ClientManager) SetClientAssertionJWT(_ctx context.Context, _jti string, _exp time.Time) error {
-	return fmt.Errorf("not implemented")
+// This is used by the private_key_jwt client authentication method to prevent client assertions from being replayed.
+func (m *ClientManager) SetClientAssertionJWT(ctx context.Context, jti string, exp time.Time) error {
+	return m.clientAssertions.SetClientAssertionJWT(ctx, jti, exp)
 }
 
 // PinnipedCLI returns the static Client corresponding to the Pinniped CLI.
@@ -154,6 +225,7 @@ func PinnipedCLI() *Client {
 					oidcapi.GrantTypeAuthorizationCode,
 					oidcapi.GrantTypeRefreshToken,
 					oidcapi.GrantTypeTokenExchange,
+					oidcapi.GrantTypeDeviceCode,
 				},
 				ResponseTypes: []string{"code"},
 				Scopes: fosite.Arguments{
@@ -179,7 +251,7 @@ func PinnipedCLI() *Client {
 	}
 }
 
-func oidcClientCRToFositeClient(oidcClient *supervisorconfigv1alpha1.OIDCClient, clientSecrets []string) *Client {
+func oidcClientCRToFositeClient(oidcClient *supervisorconfigv1alpha1.OIDCClient, clientSecrets []string) (*Client, error) {
 	// Allow the user to optionally override the default timeouts for these clients.
 	idTokenLifetimeOverrideInSeconds := oidcClient.Spec.TokenLifetimes.IDTokenSeconds
 	var idTokenLifetime time.Duration
@@ -188,7 +260,7 @@ func oidcClientCRToFositeClient(oidcClient *supervisorconfigv1alpha1.OIDCClient,
 		idTokenLifetime = time.Duration(*(idTokenLifetimeOverrideInSeconds)) * time.Second
 	}
 
-	return &Client{
+	client := &Client{
 		DefaultOpenIDConnectClient: fosite.DefaultOpenIDConnectClient{
 			DefaultClient: &fosite.DefaultClient{
 				ID: oidcClient.Name,
@@ -209,10 +281,86 @@ func oidcClientCRToFositeClient(oidcClient *supervisorconfigv1alpha1.OIDCClient,
 			JSONWebKeysURI:                    "",
 			RequestObjectSigningAlgorithm:     "",
 			TokenEndpointAuthSigningAlgorithm: coreosoidc.RS256,
-			TokenEndpointAuthMethod:           "client_secret_basic",
+			TokenEndpointAuthMethod:           string(oidcclientvalidator.TokenEndpointAuthMethod(oidcClient)),
 		},
 		IDTokenLifetimeConfiguration: idTokenLifetime,
+		SessionPolicy:                sessionPolicyFromOIDCClient(oidcClient.Spec.SessionPolicy),
+		AccessTokenFormat:            oidcClient.Spec.AccessTokenFormat,
+		AccessTokenAudience:          oidcClient.Spec.AccessTokenAudience,
+
+		requirePushedAuthorizationRequests: oidcClient.Spec.RequirePushedAuthorizationRequests,
+	}
+
+	// The CRD validates the access token audience, but check it again in case the OIDCClient was created before the
+	// CRD validated it. The audience of a JWT access token must never be the client ID, which is the audience of
+	// the ID tokens which are issued to the client. See https://datatracker.ietf.org/doc/html/rfc9068#section-4.
+	if oidcClient.Spec.AccessTokenFormat == supervisorconfigv1alpha1.AccessTokenFormatJWT {
+		switch audience := oidcClient.Spec.AccessTokenAudience; {
+		case audience == "":
+			return nil, fmt.Errorf("accessTokenAudience must be configured when accessTokenFormat is %q", supervisorconfigv1alpha1.AccessTokenFormatJWT)
+		case audience == oidcapi.ClientIDPinnipedCLI || strings.HasPrefix(audience, oidcapi.ClientIDRequiredOIDCClientPrefix):
+			return nil, constable.Error("accessTokenAudience must not be a client ID")
+		}
+	}
+
+	if cc := oidcClient.Spec.ClientCredentials; cc != nil {
+		client.ClientCredentials = &ClientCredentials{
+			Username:         cc.Username,
+			Groups:           cc.Groups,
+			IdentityProvider: cc.IdentityProvider,
+		}
+	}
+
+	switch spec := oidcClient.Spec; {
+	case spec.PrivateKeyJWT != nil:
+		alg := oidcclientvalidator.PrivateKeyJWTSigningAlgorithm(spec.PrivateKeyJWT)
+		client.TokenEndpointAuthSigningAlgorithm = string(alg)
+		// The same keys are used to verify the signed request objects which the client sends to the authorization
+		// endpoint. Requiring the same algorithm also prevents the use of unsigned request objects.
+		client.RequestObjectSigningAlgorithm = string(alg)
+		if spec.PrivateKeyJWT.JWKSURI != "" {
+			client.JSONWebKeysURI = spec.PrivateKeyJWT.JWKSURI
+			break
+		}
+		keySet, err := oidcclientvalidator.ParseJWKS(spec.PrivateKeyJWT.JWKS, alg)
+		if err != nil {
+			return nil, err
+		}
+		client.JSONWebKeys = keySet
+	case spec.TLSClientAuth != nil:
+		roots, err := oidcclientvalidator.ParseCertificateAuthorityData(spec.TLSClientAuth.CertificateAuthorityData)
+		if err != nil {
+			return nil, err
+		}
+		client.TLSClientAuth = &TLSClientAuth{
+			roots:     roots,
+			subjectDN: spec.TLSClientAuth.SubjectDN,
+			sanDNS:    spec.TLSClientAuth.SANDNS,
+			sanURI:    spec.TLSClientAuth.SANURI,
+		}
+	}
+
+	return client, nil
+}
+
+func sessionPolicyFromOIDCClient(policy *supervisorconfigv1alpha1.OIDCClientSessionPolicy) timeouts.SessionPolicy {
+	if policy == nil {
+		return timeouts.SessionPolicy{}
+	}
+	return timeouts.SessionPolicy{
+		MaxSessionAge:        secondsToDuration(policy.MaxSessionAgeSeconds),
+		RefreshTokenLifespan: secondsToDuration(policy.RefreshTokenSeconds),
+		IdleTimeout:          secondsToDuration(policy.IdleTimeoutSeconds),
+		AccessTokenLifespan:  secondsToDuration(policy.AccessTokenSeconds),
+	}
+}
+
+func secondsToDuration(seconds *int32) time.Duration {
+	if seconds == nil {
+		return 0
 	}
+	// It should be safe to cast this int32 to time.Duration, because time.Duration is an int64.
+	return time.Duration(*seconds) * time.Second
 }
 
 func scopesToArguments(scopes []supervisorconfigv1alpha1.Scope) fosite.Arguments {
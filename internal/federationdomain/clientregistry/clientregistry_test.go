@@ -5,12 +5,17 @@ package clientregistry
 
 import (
 	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"testing"
 	"time"
 
 	coreosoidc "github.com/coreos/go-oidc/v3/oidc"
+	"github.com/go-jose/go-jose/v3"
 	"github.com/ory/fosite"
 	"github.com/stretchr/testify/require"
 	corev1 "k8s.io/api/core/v1"
@@ -22,7 +27,10 @@ import (
 
 	supervisorconfigv1alpha1 "go.pinniped.dev/generated/latest/apis/supervisor/config/v1alpha1"
 	supervisorfake "go.pinniped.dev/generated/latest/client/supervisor/clientset/versioned/fake"
+	"go.pinniped.dev/internal/certauthority"
 	"go.pinniped.dev/internal/federationdomain/oidcclientvalidator"
+	"go.pinniped.dev/internal/federationdomain/timeouts"
+	"go.pinniped.dev/internal/fositestorage/clientassertion"
 	"go.pinniped.dev/internal/oidcclientsecretstorage"
 	"go.pinniped.dev/internal/testutil"
 )
@@ -31,11 +39,16 @@ func TestClientManager(t *testing.T) {
 	ctx := context.Background()
 
 	const (
-		testName      = "client.oauth.pinniped.dev-test-name"
-		testNamespace = "test-namespace"
-		testUID       = "test-uid-123"
+		testName                 = "client.oauth.pinniped.dev-test-name"
+		testNamespace            = "test-namespace"
+		testUID                  = "test-uid-123"
+		testFederationDomainName = "test-federation-domain"
 	)
 
+	testJWKS := testJWKSWithECDSAKey(t)
+	testCA, err := certauthority.New("test-ca", time.Hour)
+	require.NoError(t, err)
+
 	tests := []struct {
 		name                   string
 		secrets                []*corev1.Secret
@@ -45,10 +58,13 @@ func TestClientManager(t *testing.T) {
 		run                    func(t *testing.T, subject *ClientManager)
 	}{
 		{
-			name: "unimplemented methods",
+			name: "client assertion JWT IDs can only be used once",
 			run: func(t *testing.T, subject *ClientManager) {
-				require.EqualError(t, subject.ClientAssertionJWTValid(ctx, "some-token-id"), "not implemented")
-				require.EqualError(t, subject.SetClientAssertionJWT(ctx, "some-token-id", time.Now()), "not implemented")
+				require.NoError(t, subject.ClientAssertionJWTValid(ctx, "some-token-id"))
+				require.NoError(t, subject.SetClientAssertionJWT(ctx, "some-token-id", time.Now().Add(time.Minute)))
+				require.ErrorIs(t, subject.ClientAssertionJWTValid(ctx, "some-token-id"), fosite.ErrJTIKnown)
+				require.ErrorIs(t, subject.SetClientAssertionJWT(ctx, "some-token-id", time.Now().Add(time.Minute)), fosite.ErrJTIKnown)
+				require.NoError(t, subject.ClientAssertionJWTValid(ctx, "other-token-id"))
 			},
 		},
 		{
@@ -250,6 +266,400 @@ func TestClientManager(t *testing.T) {
 				)
 			},
 		},
+		{
+			name: "find a valid dynamic client which uses private_key_jwt with an inline JWKS, which does not need a client secret",
+			oidcClients: []*supervisorconfigv1alpha1.OIDCClient{
+				{
+					ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: testName, Generation: 1234, UID: testUID},
+					Spec: supervisorconfigv1alpha1.OIDCClientSpec{
+						AllowedGrantTypes:       []supervisorconfigv1alpha1.GrantType{"authorization_code"},
+						AllowedScopes:           []supervisorconfigv1alpha1.Scope{"openid"},
+						AllowedRedirectURIs:     []supervisorconfigv1alpha1.RedirectURI{"http://localhost:8080"},
+						TokenEndpointAuthMethod: supervisorconfigv1alpha1.TokenEndpointAuthMethodPrivateKeyJWT,
+						PrivateKeyJWT: &supervisorconfigv1alpha1.OIDCClientPrivateKeyJWT{
+							JWKS:             testJWKS,
+							SigningAlgorithm: "ES256",
+						},
+					},
+				},
+			},
+			run: func(t *testing.T, subject *ClientManager) {
+				got, err := subject.GetClient(ctx, testName)
+				require.NoError(t, err)
+				require.IsType(t, &Client{}, got)
+				c := got.(*Client)
+
+				require.Empty(t, c.GetRotatedHashes())
+				require.False(t, c.IsPublic())
+				require.Equal(t, "private_key_jwt", c.GetTokenEndpointAuthMethod())
+				require.Equal(t, "ES256", c.GetTokenEndpointAuthSigningAlgorithm())
+				require.Equal(t, "ES256", c.GetRequestObjectSigningAlgorithm())
+				require.NotNil(t, c.GetJSONWebKeys())
+				require.Len(t, c.GetJSONWebKeys().Keys, 1)
+				require.Equal(t, "some-key-id", c.GetJSONWebKeys().Keys[0].KeyID)
+				require.Equal(t, "", c.GetJSONWebKeysURI())
+				require.Nil(t, c.TLSClientAuth)
+			},
+		},
+		{
+			name: "find a valid dynamic client which uses private_key_jwt with a JWKS URI",
+			oidcClients: []*supervisorconfigv1alpha1.OIDCClient{
+				{
+					ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: testName, Generation: 1234, UID: testUID},
+					Spec: supervisorconfigv1alpha1.OIDCClientSpec{
+						AllowedGrantTypes:       []supervisorconfigv1alpha1.GrantType{"authorization_code"},
+						AllowedScopes:           []supervisorconfigv1alpha1.Scope{"openid"},
+						AllowedRedirectURIs:     []supervisorconfigv1alpha1.RedirectURI{"http://localhost:8080"},
+						TokenEndpointAuthMethod: supervisorconfigv1alpha1.TokenEndpointAuthMethodPrivateKeyJWT,
+						PrivateKeyJWT: &supervisorconfigv1alpha1.OIDCClientPrivateKeyJWT{
+							JWKSURI: "https://client.example.com/jwks.json",
+						},
+					},
+				},
+			},
+			run: func(t *testing.T, subject *ClientManager) {
+				got, err := subject.GetClient(ctx, testName)
+				require.NoError(t, err)
+				require.IsType(t, &Client{}, got)
+				c := got.(*Client)
+
+				require.Equal(t, "private_key_jwt", c.GetTokenEndpointAuthMethod())
+				require.Equal(t, "RS256", c.GetTokenEndpointAuthSigningAlgorithm())
+				require.Equal(t, "RS256", c.GetRequestObjectSigningAlgorithm())
+				require.Nil(t, c.GetJSONWebKeys())
+				require.Equal(t, "https://client.example.com/jwks.json", c.GetJSONWebKeysURI())
+				require.Nil(t, c.TLSClientAuth)
+			},
+		},
+		{
+			name: "find a valid dynamic client which uses tls_client_auth",
+			oidcClients: []*supervisorconfigv1alpha1.OIDCClient{
+				{
+					ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: testName, Generation: 1234, UID: testUID},
+					Spec: supervisorconfigv1alpha1.OIDCClientSpec{
+						AllowedGrantTypes:       []supervisorconfigv1alpha1.GrantType{"authorization_code"},
+						AllowedScopes:           []supervisorconfigv1alpha1.Scope{"openid"},
+						AllowedRedirectURIs:     []supervisorconfigv1alpha1.RedirectURI{"http://localhost:8080"},
+						TokenEndpointAuthMethod: supervisorconfigv1alpha1.TokenEndpointAuthMethodTLSClientAuth,
+						TLSClientAuth: &supervisorconfigv1alpha1.OIDCClientTLSClientAuth{
+							CertificateAuthorityData: base64.StdEncoding.EncodeToString(testCA.Bundle()),
+							SubjectDN:                "CN=some-client",
+						},
+					},
+				},
+			},
+			run: func(t *testing.T, subject *ClientManager) {
+				got, err := subject.GetClient(ctx, testName)
+				require.NoError(t, err)
+				require.IsType(t, &Client{}, got)
+				c := got.(*Client)
+
+				require.Empty(t, c.GetRotatedHashes())
+				require.Equal(t, "tls_client_auth", c.GetTokenEndpointAuthMethod())
+				require.Nil(t, c.GetJSONWebKeys())
+				require.Equal(t, "", c.GetJSONWebKeysURI())
+				require.NotNil(t, c.TLSClientAuth)
+				require.Equal(t, "CN=some-client", c.TLSClientAuth.subjectDN)
+
+				// The TLS client authentication configuration is not saved to session storage.
+				marshaled, err := json.Marshal(c)
+				require.NoError(t, err)
+				require.NotContains(t, string(marshaled), "TLSClientAuth")
+			},
+		},
+		{
+			name: "find a valid dynamic client which uses client_credentials",
+			oidcClients: []*supervisorconfigv1alpha1.OIDCClient{
+				{
+					ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: testName, Generation: 1234, UID: testUID},
+					Spec: supervisorconfigv1alpha1.OIDCClientSpec{
+						AllowedGrantTypes: []supervisorconfigv1alpha1.GrantType{"client_credentials", "urn:ietf:params:oauth:grant-type:token-exchange"},
+						AllowedScopes:     []supervisorconfigv1alpha1.Scope{"openid", "pinniped:request-audience", "username", "groups"},
+						ClientCredentials: &supervisorconfigv1alpha1.OIDCClientClientCredentials{
+							Username:         "ci-bot",
+							Groups:           []string{"ci", "deployers"},
+							IdentityProvider: "some-idp",
+						},
+					},
+				},
+			},
+			secrets: []*corev1.Secret{
+				testutil.OIDCClientSecretStorageSecretForUID(t, testNamespace, testUID, []string{testutil.HashedPassword1AtSupervisorMinCost}),
+			},
+			run: func(t *testing.T, subject *ClientManager) {
+				got, err := subject.GetClient(ctx, testName)
+				require.NoError(t, err)
+				require.IsType(t, &Client{}, got)
+				c := got.(*Client)
+
+				requireDynamicOIDCClient(t, c,
+					testName,
+					[]string{testutil.HashedPassword1AtSupervisorMinCost},
+					fosite.Arguments{"client_credentials", "urn:ietf:params:oauth:grant-type:token-exchange"},
+					fosite.Arguments{"openid", "pinniped:request-audience", "username", "groups"},
+					[]string{},
+					0*time.Second,
+				)
+				require.Equal(t, &ClientCredentials{
+					Username:         "ci-bot",
+					Groups:           []string{"ci", "deployers"},
+					IdentityProvider: "some-idp",
+				}, c.ClientCredentials)
+
+				// The client credentials identity is not saved to session storage.
+				marshaled, err := json.Marshal(c)
+				require.NoError(t, err)
+				require.NotContains(t, string(marshaled), "ci-bot")
+			},
+		},
+		{
+			name: "find a valid dynamic client with a session policy",
+			oidcClients: []*supervisorconfigv1alpha1.OIDCClient{
+				{
+					ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: testName, Generation: 1234, UID: testUID},
+					Spec: supervisorconfigv1alpha1.OIDCClientSpec{
+						AllowedGrantTypes:   []supervisorconfigv1alpha1.GrantType{"authorization_code", "refresh_token"},
+						AllowedScopes:       []supervisorconfigv1alpha1.Scope{"openid", "offline_access", "username", "groups"},
+						AllowedRedirectURIs: []supervisorconfigv1alpha1.RedirectURI{"http://localhost:8080"},
+						SessionPolicy: &supervisorconfigv1alpha1.OIDCClientSessionPolicy{
+							MaxSessionAgeSeconds: ptr.To[int32](86400),
+							IdleTimeoutSeconds:   ptr.To[int32](1800),
+							AccessTokenSeconds:   ptr.To[int32](300),
+						},
+					},
+				},
+			},
+			secrets: []*corev1.Secret{
+				testutil.OIDCClientSecretStorageSecretForUID(t, testNamespace, testUID, []string{testutil.HashedPassword1AtSupervisorMinCost}),
+			},
+			run: func(t *testing.T, subject *ClientManager) {
+				got, err := subject.GetClient(ctx, testName)
+				require.NoError(t, err)
+				require.IsType(t, &Client{}, got)
+				c := got.(*Client)
+
+				requireDynamicOIDCClient(t, c,
+					testName,
+					[]string{testutil.HashedPassword1AtSupervisorMinCost},
+					fosite.Arguments{"authorization_code", "refresh_token"},
+					fosite.Arguments{"openid", "offline_access", "username", "groups"},
+					[]string{"http://localhost:8080"},
+					0*time.Second,
+				)
+				require.Equal(t, timeouts.SessionPolicy{
+					MaxSessionAge:       24 * time.Hour,
+					IdleTimeout:         30 * time.Minute,
+					AccessTokenLifespan: 5 * time.Minute,
+				}, c.SessionPolicy)
+
+				// The session policy is not saved to session storage.
+				marshaled, err := json.Marshal(c)
+				require.NoError(t, err)
+				require.NotContains(t, string(marshaled), "SessionPolicy")
+			},
+		},
+		{
+			name: "find a valid dynamic client which uses JWT access tokens",
+			oidcClients: []*supervisorconfigv1alpha1.OIDCClient{
+				{
+					ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: testName, Generation: 1234, UID: testUID},
+					Spec: supervisorconfigv1alpha1.OIDCClientSpec{
+						AllowedGrantTypes:   []supervisorconfigv1alpha1.GrantType{"authorization_code", "refresh_token"},
+						AllowedScopes:       []supervisorconfigv1alpha1.Scope{"openid", "offline_access", "username", "groups"},
+						AllowedRedirectURIs: []supervisorconfigv1alpha1.RedirectURI{"http://localhost:8080"},
+						AccessTokenFormat:   supervisorconfigv1alpha1.AccessTokenFormatJWT,
+						AccessTokenAudience: "https://some-api.example.com",
+					},
+				},
+			},
+			secrets: []*corev1.Secret{
+				testutil.OIDCClientSecretStorageSecretForUID(t, testNamespace, testUID, []string{testutil.HashedPassword1AtSupervisorMinCost}),
+			},
+			run: func(t *testing.T, subject *ClientManager) {
+				got, err := subject.GetClient(ctx, testName)
+				require.NoError(t, err)
+				require.IsType(t, &Client{}, got)
+				c := got.(*Client)
+
+				requireDynamicOIDCClient(t, c,
+					testName,
+					[]string{testutil.HashedPassword1AtSupervisorMinCost},
+					fosite.Arguments{"authorization_code", "refresh_token"},
+					fosite.Arguments{"openid", "offline_access", "username", "groups"},
+					[]string{"http://localhost:8080"},
+					0*time.Second,
+				)
+				require.Equal(t, supervisorconfigv1alpha1.AccessTokenFormatJWT, c.AccessTokenFormat)
+				require.Equal(t, "https://some-api.example.com", c.AccessTokenAudience)
+
+				// The access token format and audience are not saved to session storage.
+				marshaled, err := json.Marshal(c)
+				require.NoError(t, err)
+				require.NotContains(t, string(marshaled), "AccessTokenFormat")
+				require.NotContains(t, string(marshaled), "AccessTokenAudience")
+			},
+		},
+		{
+			name: "find a dynamic client which uses JWT access tokens but has no access token audience",
+			oidcClients: []*supervisorconfigv1alpha1.OIDCClient{
+				{
+					ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: testName, Generation: 1234, UID: testUID},
+					Spec: supervisorconfigv1alpha1.OIDCClientSpec{
+						AllowedGrantTypes:   []supervisorconfigv1alpha1.GrantType{"authorization_code"},
+						AllowedScopes:       []supervisorconfigv1alpha1.Scope{"openid"},
+						AllowedRedirectURIs: []supervisorconfigv1alpha1.RedirectURI{"http://localhost:8080"},
+						AccessTokenFormat:   supervisorconfigv1alpha1.AccessTokenFormatJWT,
+					},
+				},
+			},
+			secrets: []*corev1.Secret{
+				testutil.OIDCClientSecretStorageSecretForUID(t, testNamespace, testUID, []string{testutil.HashedPassword1AtSupervisorMinCost}),
+			},
+			run: func(t *testing.T, subject *ClientManager) {
+				got, err := subject.GetClient(ctx, testName)
+				require.EqualError(t, err, fmt.Sprintf("client %q exists but is invalid or not ready", testName))
+				require.Nil(t, got)
+			},
+		},
+		{
+			name: "find a dynamic client which uses JWT access tokens with a client ID as the access token audience",
+			oidcClients: []*supervisorconfigv1alpha1.OIDCClient{
+				{
+					ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: testName, Generation: 1234, UID: testUID},
+					Spec: supervisorconfigv1alpha1.OIDCClientSpec{
+						AllowedGrantTypes:   []supervisorconfigv1alpha1.GrantType{"authorization_code"},
+						AllowedScopes:       []supervisorconfigv1alpha1.Scope{"openid"},
+						AllowedRedirectURIs: []supervisorconfigv1alpha1.RedirectURI{"http://localhost:8080"},
+						AccessTokenFormat:   supervisorconfigv1alpha1.AccessTokenFormatJWT,
+						AccessTokenAudience: testName,
+					},
+				},
+			},
+			secrets: []*corev1.Secret{
+				testutil.OIDCClientSecretStorageSecretForUID(t, testNamespace, testUID, []string{testutil.HashedPassword1AtSupervisorMinCost}),
+			},
+			run: func(t *testing.T, subject *ClientManager) {
+				got, err := subject.GetClient(ctx, testName)
+				require.EqualError(t, err, fmt.Sprintf("client %q exists but is invalid or not ready", testName))
+				require.Nil(t, got)
+			},
+		},
+		{
+			name: "find a valid dynamic client which requires pushed authorization requests",
+			oidcClients: []*supervisorconfigv1alpha1.OIDCClient{
+				{
+					ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: testName, Generation: 1234, UID: testUID},
+					Spec: supervisorconfigv1alpha1.OIDCClientSpec{
+						AllowedGrantTypes:                  []supervisorconfigv1alpha1.GrantType{"authorization_code"},
+						AllowedScopes:                      []supervisorconfigv1alpha1.Scope{"openid"},
+						AllowedRedirectURIs:                []supervisorconfigv1alpha1.RedirectURI{"http://localhost:8080"},
+						RequirePushedAuthorizationRequests: true,
+					},
+				},
+			},
+			secrets: []*corev1.Secret{
+				testutil.OIDCClientSecretStorageSecretForUID(t, testNamespace, testUID, []string{testutil.HashedPassword1AtSupervisorMinCost}),
+			},
+			run: func(t *testing.T, subject *ClientManager) {
+				got, err := subject.GetClient(ctx, testName)
+				require.NoError(t, err)
+				require.IsType(t, &Client{}, got)
+				c := got.(*Client)
+
+				requireDynamicOIDCClient(t, c,
+					testName,
+					[]string{testutil.HashedPassword1AtSupervisorMinCost},
+					fosite.Arguments{"authorization_code"},
+					fosite.Arguments{"openid"},
+					[]string{"http://localhost:8080"},
+					0*time.Second,
+				)
+				require.True(t, c.RequiresPushedAuthorizationRequests())
+			},
+		},
+		{
+			name: "find a dynamic client which uses private_key_jwt but has an invalid JWKS",
+			oidcClients: []*supervisorconfigv1alpha1.OIDCClient{
+				{
+					ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: testName, Generation: 1234, UID: testUID},
+					Spec: supervisorconfigv1alpha1.OIDCClientSpec{
+						AllowedGrantTypes:       []supervisorconfigv1alpha1.GrantType{"authorization_code"},
+						AllowedScopes:           []supervisorconfigv1alpha1.Scope{"openid"},
+						AllowedRedirectURIs:     []supervisorconfigv1alpha1.RedirectURI{"http://localhost:8080"},
+						TokenEndpointAuthMethod: supervisorconfigv1alpha1.TokenEndpointAuthMethodPrivateKeyJWT,
+						PrivateKeyJWT: &supervisorconfigv1alpha1.OIDCClientPrivateKeyJWT{
+							JWKS:             testJWKS,
+							SigningAlgorithm: "RS256", // the key in the JWKS is an ECDSA key
+						},
+					},
+				},
+			},
+			run: func(t *testing.T, subject *ClientManager) {
+				got, err := subject.GetClient(ctx, testName)
+				require.EqualError(t, err, fmt.Sprintf("client %q exists but is invalid or not ready", testName))
+				require.Nil(t, got)
+			},
+		},
+		{
+			name: "find a valid dynamic client which was registered by this FederationDomain",
+			oidcClients: []*supervisorconfigv1alpha1.OIDCClient{
+				{
+					ObjectMeta: metav1.ObjectMeta{
+						Namespace: testNamespace, Name: testName, Generation: 1234, UID: testUID,
+						Annotations: map[string]string{FederationDomainAnnotation: testFederationDomainName},
+					},
+					Spec: supervisorconfigv1alpha1.OIDCClientSpec{
+						AllowedGrantTypes:   []supervisorconfigv1alpha1.GrantType{"authorization_code", "refresh_token"},
+						AllowedScopes:       []supervisorconfigv1alpha1.Scope{"openid", "offline_access"},
+						AllowedRedirectURIs: []supervisorconfigv1alpha1.RedirectURI{"https://foobar.com/callback"},
+					},
+				},
+			},
+			secrets: []*corev1.Secret{
+				testutil.OIDCClientSecretStorageSecretForUID(t, testNamespace, testUID, []string{testutil.HashedPassword1AtSupervisorMinCost}),
+			},
+			run: func(t *testing.T, subject *ClientManager) {
+				got, err := subject.GetClient(ctx, testName)
+				require.NoError(t, err)
+				require.IsType(t, &Client{}, got)
+				requireDynamicOIDCClient(t, got.(*Client),
+					testName,
+					[]string{testutil.HashedPassword1AtSupervisorMinCost},
+					fosite.Arguments{"authorization_code", "refresh_token"},
+					fosite.Arguments{"openid", "offline_access"},
+					[]string{"https://foobar.com/callback"},
+					0*time.Second,
+				)
+			},
+		},
+		{
+			name: "do not find a valid dynamic client which was registered by another FederationDomain",
+			oidcClients: []*supervisorconfigv1alpha1.OIDCClient{
+				{
+					ObjectMeta: metav1.ObjectMeta{
+						Namespace: testNamespace, Name: testName, Generation: 1234, UID: testUID,
+						Annotations: map[string]string{FederationDomainAnnotation: "other-federation-domain"},
+					},
+					Spec: supervisorconfigv1alpha1.OIDCClientSpec{
+						AllowedGrantTypes:   []supervisorconfigv1alpha1.GrantType{"authorization_code", "refresh_token"},
+						AllowedScopes:       []supervisorconfigv1alpha1.Scope{"openid", "offline_access"},
+						AllowedRedirectURIs: []supervisorconfigv1alpha1.RedirectURI{"https://foobar.com/callback"},
+					},
+				},
+			},
+			secrets: []*corev1.Secret{
+				testutil.OIDCClientSecretStorageSecretForUID(t, testNamespace, testUID, []string{testutil.HashedPassword1AtSupervisorMinCost}),
+			},
+			run: func(t *testing.T, subject *ClientManager) {
+				got, err := subject.GetClient(ctx, testName)
+				require.Error(t, err)
+				require.Nil(t, got)
+				rfcErr := fosite.ErrorToRFC6749Error(err)
+				require.NotNil(t, rfcErr)
+				require.Equal(t, rfcErr.CodeField, 404)
+				require.Equal(t, rfcErr.GetDescription(), "no such client")
+			},
+		},
 	}
 
 	for _, test := range tests {
@@ -263,6 +673,8 @@ func TestClientManager(t *testing.T) {
 			subject := NewClientManager(
 				oidcClientsClient,
 				oidcclientsecretstorage.New(secrets),
+				clientassertion.New(secrets, time.Now),
+				testFederationDomainName,
 				oidcclientvalidator.DefaultMinBcryptCost,
 			)
 
@@ -284,6 +696,23 @@ func TestClientManager(t *testing.T) {
 	}
 }
 
+func testJWKSWithECDSAKey(t *testing.T) string {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	jwks, err := json.Marshal(jose.JSONWebKeySet{Keys: []jose.JSONWebKey{{
+		Key:       key.Public(),
+		KeyID:     "some-key-id",
+		Algorithm: "ES256",
+		Use:       "sig",
+	}}})
+	require.NoError(t, err)
+
+	return string(jwks)
+}
+
 func TestPinnipedCLI(t *testing.T) {
 	requireEqualsPinnipedCLI(t, PinnipedCLI())
 }
@@ -292,7 +721,7 @@ func requireEqualsPinnipedCLI(t *testing.T, c *Client) {
 	require.Equal(t, "pinniped-cli", c.GetID())
 	require.Nil(t, c.GetHashedSecret())
 	require.Equal(t, []string{"http://127.0.0.1/callback"}, c.GetRedirectURIs())
-	require.Equal(t, fosite.Arguments{"authorization_code", "refresh_token", "urn:ietf:params:oauth:grant-type:token-exchange"}, c.GetGrantTypes())
+	require.Equal(t, fosite.Arguments{"authorization_code", "refresh_token", "urn:ietf:params:oauth:grant-type:token-exchange", "urn:ietf:params:oauth:grant-type:device_code"}, c.GetGrantTypes())
 	require.Equal(t, fosite.Arguments{"code"}, c.GetResponseTypes())
 	require.Equal(t, fosite.Arguments{coreosoidc.ScopeOpenID, coreosoidc.ScopeOfflineAccess, "profile", "email", "pinniped:request-audience", "username", "groups"}, c.GetScopes())
 	require.True(t, c.IsPublic())
@@ -317,7 +746,8 @@ func requireEqualsPinnipedCLI(t *testing.T, c *Client) {
 		  "grant_types": [
 			"authorization_code",
 			"refresh_token",
-			"urn:ietf:params:oauth:grant-type:token-exchange"
+			"urn:ietf:params:oauth:grant-type:token-exchange",
+			"urn:ietf:params:oauth:grant-type:device_code"
 		  ],
 		  "response_types": [
 			"code"
@@ -0,0 +1,113 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package clientregistry
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"time"
+
+	"github.com/ory/fosite"
+)
+
+// TLSClientAuth verifies the TLS client certificates of a client which uses the tls_client_auth client
+// authentication method, as described in RFC8705 section 2.1.
+type TLSClientAuth struct {
+	roots *x509.CertPool
+
+	// Exactly one of these identifies the certificate of the client.
+	subjectDN string
+	sanDNS    string
+	sanURI    string
+}
+
+// verify checks that the leaf certificate of the given chain was issued by one of the trusted certificate
+// authorities for TLS client authentication, and that it identifies the client.
+func (a *TLSClientAuth) verify(peerCertificates []*x509.Certificate, now time.Time) error {
+	if len(peerCertificates) == 0 {
+		return errors.New("no TLS client certificate was presented")
+	}
+	leaf := peerCertificates[0]
+
+	intermediates := x509.NewCertPool()
+	for _, cert := range peerCertificates[1:] {
+		intermediates.AddCert(cert)
+	}
+
+	if _, err := leaf.Verify(x509.VerifyOptions{
+		Roots:         a.roots,
+		Intermediates: intermediates,
+		CurrentTime:   now,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}); err != nil {
+		return fmt.Errorf("TLS client certificate could not be verified: %w", err)
+	}
+
+	switch {
+	case a.subjectDN != "":
+		if leaf.Subject.String() != a.subjectDN {
+			return fmt.Errorf("TLS client certificate has subject %q, but %q was expected", leaf.Subject.String(), a.subjectDN)
+		}
+	case a.sanDNS != "":
+		if !slices.Contains(leaf.DNSNames, a.sanDNS) {
+			return fmt.Errorf("TLS client certificate does not have the expected DNS name %q", a.sanDNS)
+		}
+	case a.sanURI != "":
+		if !slices.ContainsFunc(leaf.URIs, func(u *url.URL) bool { return u.String() == a.sanURI }) {
+			return fmt.Errorf("TLS client certificate does not have the expected URI %q", a.sanURI)
+		}
+	default:
+		return errors.New("no TLS client certificate identifier is configured")
+	}
+
+	return nil
+}
+
+// NewClientAuthenticationStrategy returns a fosite.ClientAuthenticationStrategy which adds support for the
+// tls_client_auth client authentication method. Requests which do not use tls_client_auth are authenticated
+// by the given fallback strategy, which is typically fosite's default strategy.
+//
+// A request uses tls_client_auth when it identifies the client only by the client_id form parameter, without any
+// other client credentials, and that client is configured to use tls_client_auth. The client certificate must be
+// presented on the TLS connection to the Supervisor, so this only works when the Supervisor's HTTPS listener
+// requests client certificates.
+func NewClientAuthenticationStrategy(
+	clients fosite.ClientManager,
+	fallback fosite.ClientAuthenticationStrategy,
+) fosite.ClientAuthenticationStrategy {
+	return func(ctx context.Context, r *http.Request, form url.Values) (fosite.Client, error) {
+		clientID := form.Get("client_id")
+		_, _, hasBasicAuth := r.BasicAuth()
+		if clientID == "" || hasBasicAuth || form.Get("client_secret") != "" || form.Get("client_assertion_type") != "" {
+			return fallback(ctx, r, form)
+		}
+
+		client, err := clients.GetClient(ctx, clientID)
+		if err != nil {
+			// Let the fallback strategy produce the usual error for clients which cannot be found.
+			return fallback(ctx, r, form)
+		}
+		c, ok := client.(*Client)
+		if !ok || c.TLSClientAuth == nil {
+			return fallback(ctx, r, form)
+		}
+
+		var peerCertificates []*x509.Certificate
+		if r.TLS != nil {
+			peerCertificates = r.TLS.PeerCertificates
+		}
+		if err := c.TLSClientAuth.verify(peerCertificates, time.Now()); err != nil {
+			return nil, fosite.ErrInvalidClient.
+				WithHint("The OAuth 2.0 Client could not be authenticated using its TLS client certificate.").
+				WithWrap(err).WithDebug(err.Error())
+		}
+
+		return c, nil
+	}
+}
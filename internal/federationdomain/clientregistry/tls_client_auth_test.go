@@ -0,0 +1,255 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package clientregistry
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/ory/fosite"
+	"github.com/stretchr/testify/require"
+
+	"go.pinniped.dev/internal/certauthority"
+)
+
+type fakeClientManager struct {
+	clients map[string]fosite.Client
+}
+
+func (m *fakeClientManager) GetClient(_ context.Context, id string) (fosite.Client, error) {
+	if client, ok := m.clients[id]; ok {
+		return client, nil
+	}
+	return nil, fosite.ErrNotFound
+}
+
+func (m *fakeClientManager) ClientAssertionJWTValid(_ context.Context, _ string) error {
+	return errors.New("not implemented")
+}
+
+func (m *fakeClientManager) SetClientAssertionJWT(_ context.Context, _ string, _ time.Time) error {
+	return errors.New("not implemented")
+}
+
+func TestClientAuthenticationStrategy(t *testing.T) {
+	ca, err := certauthority.New("test-ca", time.Hour)
+	require.NoError(t, err)
+	otherCA, err := certauthority.New("other-test-ca", time.Hour)
+	require.NoError(t, err)
+
+	clientCert := issueTestCert(t, ca, &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "some-client", Organization: []string{"some-org"}},
+		DNSNames:    []string{"client.example.com"},
+		URIs:        []*url.URL{{Scheme: "spiffe", Host: "example.com", Path: "/some-client"}},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	})
+	serverCert := issueTestCert(t, ca, &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "some-client", Organization: []string{"some-org"}},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	})
+	otherCAClientCert := issueTestCert(t, otherCA, &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "some-client", Organization: []string{"some-org"}},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	})
+
+	tlsClient := func(id string, auth *TLSClientAuth) *Client {
+		return &Client{
+			DefaultOpenIDConnectClient: fosite.DefaultOpenIDConnectClient{
+				DefaultClient:           &fosite.DefaultClient{ID: id},
+				TokenEndpointAuthMethod: "tls_client_auth",
+			},
+			TLSClientAuth: auth,
+		}
+	}
+	clients := &fakeClientManager{clients: map[string]fosite.Client{
+		"client-with-subject-dn":    tlsClient("client-with-subject-dn", &TLSClientAuth{roots: ca.Pool(), subjectDN: "CN=some-client,O=some-org"}),
+		"client-with-wrong-subject": tlsClient("client-with-wrong-subject", &TLSClientAuth{roots: ca.Pool(), subjectDN: "CN=other-client"}),
+		"client-with-san-dns":       tlsClient("client-with-san-dns", &TLSClientAuth{roots: ca.Pool(), sanDNS: "client.example.com"}),
+		"client-with-wrong-san-dns": tlsClient("client-with-wrong-san-dns", &TLSClientAuth{roots: ca.Pool(), sanDNS: "other.example.com"}),
+		"client-with-san-uri":       tlsClient("client-with-san-uri", &TLSClientAuth{roots: ca.Pool(), sanURI: "spiffe://example.com/some-client"}),
+		"client-with-wrong-san-uri": tlsClient("client-with-wrong-san-uri", &TLSClientAuth{roots: ca.Pool(), sanURI: "spiffe://example.com/other-client"}),
+		"client-with-secret":        &Client{DefaultOpenIDConnectClient: fosite.DefaultOpenIDConnectClient{DefaultClient: &fosite.DefaultClient{ID: "client-with-secret"}}},
+	}}
+
+	errFallback := errors.New("used the fallback strategy")
+
+	tests := []struct {
+		name             string
+		form             url.Values
+		basicAuth        bool
+		peerCertificates []*x509.Certificate
+		wantClientID     string
+		wantErr          error
+		wantErrDebug     string
+	}{
+		{
+			name:             "client is authenticated by its certificate subject",
+			form:             url.Values{"client_id": {"client-with-subject-dn"}},
+			peerCertificates: []*x509.Certificate{clientCert},
+			wantClientID:     "client-with-subject-dn",
+		},
+		{
+			name:             "client is authenticated by its certificate DNS name",
+			form:             url.Values{"client_id": {"client-with-san-dns"}},
+			peerCertificates: []*x509.Certificate{clientCert},
+			wantClientID:     "client-with-san-dns",
+		},
+		{
+			name:             "client is authenticated by its certificate URI",
+			form:             url.Values{"client_id": {"client-with-san-uri"}},
+			peerCertificates: []*x509.Certificate{clientCert},
+			wantClientID:     "client-with-san-uri",
+		},
+		{
+			name:         "client did not present a certificate",
+			form:         url.Values{"client_id": {"client-with-subject-dn"}},
+			wantErr:      fosite.ErrInvalidClient,
+			wantErrDebug: "no TLS client certificate was presented",
+		},
+		{
+			name:             "client certificate has the wrong subject",
+			form:             url.Values{"client_id": {"client-with-wrong-subject"}},
+			peerCertificates: []*x509.Certificate{clientCert},
+			wantErr:          fosite.ErrInvalidClient,
+			wantErrDebug:     `TLS client certificate has subject "CN=some-client,O=some-org", but "CN=other-client" was expected`,
+		},
+		{
+			name:             "client certificate does not have the expected DNS name",
+			form:             url.Values{"client_id": {"client-with-wrong-san-dns"}},
+			peerCertificates: []*x509.Certificate{clientCert},
+			wantErr:          fosite.ErrInvalidClient,
+			wantErrDebug:     `TLS client certificate does not have the expected DNS name "other.example.com"`,
+		},
+		{
+			name:             "client certificate does not have the expected URI",
+			form:             url.Values{"client_id": {"client-with-wrong-san-uri"}},
+			peerCertificates: []*x509.Certificate{clientCert},
+			wantErr:          fosite.ErrInvalidClient,
+			wantErrDebug:     `TLS client certificate does not have the expected URI "spiffe://example.com/other-client"`,
+		},
+		{
+			name:             "client certificate was issued by another certificate authority",
+			form:             url.Values{"client_id": {"client-with-subject-dn"}},
+			peerCertificates: []*x509.Certificate{otherCAClientCert},
+			wantErr:          fosite.ErrInvalidClient,
+			wantErrDebug:     "TLS client certificate could not be verified: x509: certificate signed by unknown authority",
+		},
+		{
+			name:             "certificate is not usable for client authentication",
+			form:             url.Values{"client_id": {"client-with-subject-dn"}},
+			peerCertificates: []*x509.Certificate{serverCert},
+			wantErr:          fosite.ErrInvalidClient,
+			wantErrDebug:     "TLS client certificate could not be verified: x509: certificate specifies an incompatible key usage",
+		},
+		{
+			name:             "client which does not use tls_client_auth uses the fallback",
+			form:             url.Values{"client_id": {"client-with-secret"}},
+			peerCertificates: []*x509.Certificate{clientCert},
+			wantErr:          errFallback,
+		},
+		{
+			name:    "unknown client uses the fallback",
+			form:    url.Values{"client_id": {"unknown-client"}},
+			wantErr: errFallback,
+		},
+		{
+			name:    "request without client_id uses the fallback",
+			form:    url.Values{},
+			wantErr: errFallback,
+		},
+		{
+			name:             "request with basic auth uses the fallback",
+			form:             url.Values{"client_id": {"client-with-subject-dn"}},
+			basicAuth:        true,
+			peerCertificates: []*x509.Certificate{clientCert},
+			wantErr:          errFallback,
+		},
+		{
+			name:             "request with client secret uses the fallback",
+			form:             url.Values{"client_id": {"client-with-subject-dn"}, "client_secret": {"some-secret"}},
+			peerCertificates: []*x509.Certificate{clientCert},
+			wantErr:          errFallback,
+		},
+		{
+			name: "request with client assertion uses the fallback",
+			form: url.Values{
+				"client_id":             {"client-with-subject-dn"},
+				"client_assertion_type": {"urn:ietf:params:oauth:client-assertion-type:jwt-bearer"},
+				"client_assertion":      {"some-assertion"},
+			},
+			peerCertificates: []*x509.Certificate{clientCert},
+			wantErr:          errFallback,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			subject := NewClientAuthenticationStrategy(clients, func(context.Context, *http.Request, url.Values) (fosite.Client, error) {
+				return nil, errFallback
+			})
+
+			r := httptest.NewRequest(http.MethodPost, "https://issuer.example.com/oauth2/token", nil)
+			if test.basicAuth {
+				r.SetBasicAuth("some-client", "some-secret")
+			}
+			if test.peerCertificates != nil {
+				r.TLS = &tls.ConnectionState{PeerCertificates: test.peerCertificates}
+			} else {
+				r.TLS = &tls.ConnectionState{}
+			}
+
+			got, err := subject(t.Context(), r, test.form)
+
+			if test.wantErr != nil {
+				require.ErrorIs(t, err, test.wantErr)
+				require.Nil(t, got)
+				if test.wantErrDebug != "" {
+					require.Equal(t, test.wantErrDebug, fosite.ErrorToRFC6749Error(err).DebugField)
+				}
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, test.wantClientID, got.GetID())
+		})
+	}
+}
+
+func issueTestCert(t *testing.T, ca *certauthority.CA, template *x509.Certificate) *x509.Certificate {
+	t.Helper()
+
+	caKeyPEM, err := ca.PrivateKeyToPEM()
+	require.NoError(t, err)
+	caKeyPair, err := tls.X509KeyPair(ca.Bundle(), caKeyPEM)
+	require.NoError(t, err)
+	caCert, err := x509.ParseCertificate(caKeyPair.Certificate[0])
+	require.NoError(t, err)
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template.SerialNumber = big.NewInt(time.Now().UnixNano())
+	template.NotBefore = time.Now().Add(-time.Minute)
+	template.NotAfter = time.Now().Add(time.Hour)
+	template.KeyUsage = x509.KeyUsageDigitalSignature
+
+	der, err := x509.CreateCertificate(rand.Reader, template, caCert, key.Public(), caKeyPair.PrivateKey)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	return cert
+}
@@ -1,4 +1,4 @@
-// Copyright 2021-2024 the Pinniped contributors. All Rights Reserved.
+// Copyright 2021-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 // Package downstreamsession provides some shared helpers for creating downstream OIDC sessions.
@@ -6,6 +6,7 @@ package downstreamsession
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"slices"
 	"time"
@@ -100,6 +101,10 @@ func NewPinnipedSession(
 
 	extras[oidcapi.IDTokenClaimAuthorizedParty] = c.ClientID
 
+	// The ID of the fosite request stays the same for the lifetime of the downstream session, including refreshes,
+	// so it can be used by the end_session endpoint to find the session.
+	extras[oidcapi.IDTokenClaimSessionID] = c.SessionIDGetter.GetID()
+
 	if slices.Contains(c.GrantedScopes, oidcapi.ScopeUsername) {
 		extras[oidcapi.IDTokenClaimUsername] = downstreamUsername
 	}
@@ -134,10 +139,133 @@ func NewPinnipedSession(
 	return pinnipedSession, nil
 }
 
+// ClientCredentialsSessionConfig is everything that is needed to start a new downstream Pinniped session for
+// a client which uses the client_credentials grant to act as its own configured identity. All fields are required.
+type ClientCredentialsSessionConfig struct {
+	// The configured username of the client, before identity transformations.
+	Username string
+	// The configured groups of the client, before identity transformations.
+	Groups []string
+	// The ID of the client who started the new downstream session.
+	ClientID string
+	// The scopes that were granted for the new downstream session.
+	GrantedScopes []string
+	// The identity provider whose identity transformations and policies should be applied to the client's identity.
+	IdentityProvider resolvedprovider.FederationDomainResolvedIdentityProvider
+	// The fosite Requester that is starting this session.
+	SessionIDGetter plog.SessionIDGetter
+}
+
+// NewClientCredentialsSession applies the configured FederationDomain identity transformations to the configured
+// identity of a client and creates a downstream Pinniped session for it. The subject of the session is the client ID.
+// There is no upstream identity provider session, so the session will not be associated with an upstream provider.
+func NewClientCredentialsSession(
+	ctx context.Context,
+	auditLogger plog.AuditLogger,
+	c *ClientCredentialsSessionConfig,
+) (*psession.PinnipedSession, error) {
+	now := time.Now().UTC()
+
+	auditLogger.Audit(auditevent.IdentityFromClientCredentials, &plog.AuditParams{
+		ReqCtx: ctx,
+		PIIKeysAndValues: []any{
+			"clientUsername", c.Username,
+			"clientGroups", c.Groups,
+		},
+		KeysAndValues: []any{
+			"clientID", c.ClientID,
+			"transformsIDPDisplayName", c.IdentityProvider.GetDisplayName(),
+		},
+	})
+
+	downstreamUsername, downstreamGroups, err := applyIdentityTransformations(ctx,
+		c.IdentityProvider.GetTransforms(), c.Username, c.Groups)
+	if err != nil {
+		auditLogger.Audit(auditevent.AuthenticationRejectedByTransforms, &plog.AuditParams{
+			ReqCtx:        ctx,
+			KeysAndValues: []any{"reason", err},
+		})
+		return nil, err
+	}
+
+	pinnipedSession := &psession.PinnipedSession{
+		Fosite: &openid.DefaultSession{
+			Claims: &fositejwt.IDTokenClaims{
+				Subject:     c.ClientID,
+				RequestedAt: now,
+				AuthTime:    now,
+			},
+		},
+		Custom: &psession.CustomSessionData{
+			Username:         downstreamUsername,
+			UpstreamUsername: c.Username,
+			UpstreamGroups:   c.Groups,
+		},
+	}
+
+	extras := map[string]any{}
+
+	extras[oidcapi.IDTokenClaimAuthorizedParty] = c.ClientID
+	extras[oidcapi.IDTokenClaimSessionID] = c.SessionIDGetter.GetID()
+
+	if slices.Contains(c.GrantedScopes, oidcapi.ScopeUsername) {
+		extras[oidcapi.IDTokenClaimUsername] = downstreamUsername
+	}
+
+	if slices.Contains(c.GrantedScopes, oidcapi.ScopeGroups) {
+		if downstreamGroups == nil {
+			downstreamGroups = []string{}
+		}
+		extras[oidcapi.IDTokenClaimGroups] = downstreamGroups
+	}
+
+	pinnipedSession.IDTokenClaims().Extra = extras
+
+	auditLogger.Audit(auditevent.SessionStarted, &plog.AuditParams{
+		ReqCtx:  ctx,
+		Session: c.SessionIDGetter,
+		PIIKeysAndValues: []any{
+			"username", downstreamUsername,
+			"groups", downstreamGroups,
+			"subject", c.ClientID,
+		},
+	})
+
+	return pinnipedSession, nil
+}
+
+// AuditIfRejectedByUpstreamAuthenticationPolicy writes an audit log when the given error, which was returned by
+// one of the login or refresh functions of the identity provider, was caused by the authentication policy configured
+// for that identity provider on the FederationDomain. Otherwise, it does nothing. The session param may be nil.
+func AuditIfRejectedByUpstreamAuthenticationPolicy(
+	ctx context.Context,
+	auditLogger plog.AuditLogger,
+	session plog.SessionIDGetter,
+	idp resolvedprovider.FederationDomainResolvedIdentityProvider,
+	err error,
+) {
+	var policyErr *resolvedprovider.UpstreamAuthenticationPolicyRejectedError
+	if !errors.As(err, &policyErr) {
+		return
+	}
+	auditLogger.Audit(auditevent.AuthenticationRejectedByUpstreamPolicy, &plog.AuditParams{
+		ReqCtx:  ctx,
+		Session: session,
+		KeysAndValues: []any{
+			"upstreamIDPDisplayName", idp.GetDisplayName(),
+			"upstreamIDPType", idp.GetSessionProviderType(),
+			"upstreamIDPResourceName", idp.GetProvider().GetResourceName(),
+			"upstreamIDPResourceUID", idp.GetProvider().GetResourceUID(),
+			"reason", policyErr.Reason,
+		},
+	})
+}
+
 // AutoApproveScopes auto-grants the scopes which we support and for which we do not require end-user approval,
 // if they were requested. This should only be called after it has been validated that the client is allowed to request
-// the scopes that it requested (which is a check performed by fosite).
-func AutoApproveScopes(authorizeRequester fosite.AuthorizeRequester) {
+// the scopes that it requested (which is a check performed by fosite). The requester may be an authorize request
+// or a device authorization request.
+func AutoApproveScopes(authorizeRequester fosite.Requester) {
 	for _, scope := range []string{
 		oidcapi.ScopeOpenID,
 		oidcapi.ScopeOfflineAccess,
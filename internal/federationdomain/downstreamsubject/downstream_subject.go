@@ -1,4 +1,4 @@
-// Copyright 2024 the Pinniped contributors. All Rights Reserved.
+// Copyright 2024-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package downstreamsubject
@@ -32,3 +32,25 @@ func GitHub(apiBaseURL, idpDisplayName, login, id string) string {
 		url.QueryEscape(id),
 	)
 }
+
+func GitLab(apiBaseURL, idpDisplayName, username, id string) string {
+	return fmt.Sprintf("%s?%s=%s&username=%s&id=%s", apiBaseURL,
+		oidc.IDTokenSubClaimIDPNameQueryParam, url.QueryEscape(idpDisplayName),
+		url.QueryEscape(username),
+		url.QueryEscape(id),
+	)
+}
+
+func OAuth2(userInfoURL string, uid string, idpDisplayName string) string {
+	return fmt.Sprintf("%s?%s=%s&%s=%s", userInfoURL,
+		oidc.IDTokenSubClaimIDPNameQueryParam, url.QueryEscape(idpDisplayName),
+		oidc.IDTokenClaimSubject, url.QueryEscape(uid),
+	)
+}
+
+func SAML(idpEntityID string, nameID string, idpDisplayName string) string {
+	return fmt.Sprintf("%s?%s=%s&%s=%s", idpEntityID,
+		oidc.IDTokenSubClaimIDPNameQueryParam, url.QueryEscape(idpDisplayName),
+		oidc.IDTokenClaimSubject, url.QueryEscape(nameID),
+	)
+}
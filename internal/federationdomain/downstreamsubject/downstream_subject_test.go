@@ -1,4 +1,4 @@
-// Copyright 2024 the Pinniped contributors. All Rights Reserved.
+// Copyright 2024-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package downstreamsubject
@@ -127,3 +127,111 @@ func TestGitHub(t *testing.T) {
 		})
 	}
 }
+
+func TestGitLab(t *testing.T) {
+	tests := []struct {
+		name           string
+		apiBaseURL     string
+		idpDisplayName string
+		username       string
+		id             string
+		wantSubject    string
+	}{
+		{
+			name:           "simple display name",
+			apiBaseURL:     "https://gitlab.com/api/v4/",
+			idpDisplayName: "simpleName",
+			username:       "some username",
+			id:             "some id",
+			wantSubject:    "https://gitlab.com/api/v4/?idpName=simpleName&username=some+username&id=some+id",
+		},
+		{
+			name:           "interesting display name",
+			apiBaseURL:     "https://server.example.com:1234/api/v4/",
+			idpDisplayName: "this is a 👍 display name that 🦭 can handle",
+			username:       "some other username",
+			id:             "some other id",
+			wantSubject:    "https://server.example.com:1234/api/v4/?idpName=this+is+a+%F0%9F%91%8D+display+name+that+%F0%9F%A6%AD+can+handle&username=some+other+username&id=some+other+id",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			actual := GitLab(test.apiBaseURL, test.idpDisplayName, test.username, test.id)
+
+			require.Equal(t, test.wantSubject, actual)
+		})
+	}
+}
+
+func TestOAuth2(t *testing.T) {
+	tests := []struct {
+		name           string
+		userInfoURL    string
+		uid            string
+		idpDisplayName string
+		wantSubject    string
+	}{
+		{
+			name:           "simple display name",
+			userInfoURL:    "https://auth.example.com/api/user",
+			uid:            "some uid",
+			idpDisplayName: "simpleName",
+			wantSubject:    "https://auth.example.com/api/user?idpName=simpleName&sub=some+uid",
+		},
+		{
+			name:           "interesting display name",
+			userInfoURL:    "https://server.example.com:1234/api/user",
+			uid:            "some/other&uid",
+			idpDisplayName: "this is a 👍 display name that 🦭 can handle",
+			wantSubject:    "https://server.example.com:1234/api/user?idpName=this+is+a+%F0%9F%91%8D+display+name+that+%F0%9F%A6%AD+can+handle&sub=some%2Fother%26uid",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			actual := OAuth2(test.userInfoURL, test.uid, test.idpDisplayName)
+
+			require.Equal(t, test.wantSubject, actual)
+		})
+	}
+}
+
+func TestSAML(t *testing.T) {
+	tests := []struct {
+		name           string
+		idpEntityID    string
+		nameID         string
+		idpDisplayName string
+		wantSubject    string
+	}{
+		{
+			name:           "simple display name",
+			idpEntityID:    "https://adfs.example.com/adfs/services/trust",
+			nameID:         "some name id",
+			idpDisplayName: "simpleName",
+			wantSubject:    "https://adfs.example.com/adfs/services/trust?idpName=simpleName&sub=some+name+id",
+		},
+		{
+			name:           "interesting display name and URN entity ID",
+			idpEntityID:    "urn:example:idp",
+			nameID:         "user@example.com",
+			idpDisplayName: "this is a 👍 display name that 🦭 can handle",
+			wantSubject:    "urn:example:idp?idpName=this+is+a+%F0%9F%91%8D+display+name+that+%F0%9F%A6%AD+can+handle&sub=user%40example.com",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			actual := SAML(test.idpEntityID, test.nameID, test.idpDisplayName)
+
+			require.Equal(t, test.wantSubject, actual)
+		})
+	}
+}
@@ -1,4 +1,4 @@
-// Copyright 2020-2024 the Pinniped contributors. All Rights Reserved.
+// Copyright 2020-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package dynamicupstreamprovider
@@ -19,6 +19,12 @@ type DynamicUpstreamIDPProvider interface {
 	GetActiveDirectoryIdentityProviders() []upstreamprovider.UpstreamLDAPIdentityProviderI
 	SetGitHubIdentityProviders(gitHubIDPs []upstreamprovider.UpstreamGithubIdentityProviderI)
 	GetGitHubIdentityProviders() []upstreamprovider.UpstreamGithubIdentityProviderI
+	SetSAMLIdentityProviders(samlIDPs []upstreamprovider.UpstreamSAMLIdentityProviderI)
+	GetSAMLIdentityProviders() []upstreamprovider.UpstreamSAMLIdentityProviderI
+	SetGitLabIdentityProviders(gitLabIDPs []upstreamprovider.UpstreamGitLabIdentityProviderI)
+	GetGitLabIdentityProviders() []upstreamprovider.UpstreamGitLabIdentityProviderI
+	SetOAuth2IdentityProviders(oauth2IDPs []upstreamprovider.UpstreamOAuth2IdentityProviderI)
+	GetOAuth2IdentityProviders() []upstreamprovider.UpstreamOAuth2IdentityProviderI
 }
 
 type dynamicUpstreamIDPProvider struct {
@@ -26,6 +32,9 @@ type dynamicUpstreamIDPProvider struct {
 	ldapUpstreams            []upstreamprovider.UpstreamLDAPIdentityProviderI
 	activeDirectoryUpstreams []upstreamprovider.UpstreamLDAPIdentityProviderI
 	gitHubUpstreams          []upstreamprovider.UpstreamGithubIdentityProviderI
+	samlUpstreams            []upstreamprovider.UpstreamSAMLIdentityProviderI
+	gitLabUpstreams          []upstreamprovider.UpstreamGitLabIdentityProviderI
+	oauth2Upstreams          []upstreamprovider.UpstreamOAuth2IdentityProviderI
 	mutex                    sync.RWMutex
 }
 
@@ -35,6 +44,9 @@ func NewDynamicUpstreamIDPProvider() DynamicUpstreamIDPProvider {
 		ldapUpstreams:            []upstreamprovider.UpstreamLDAPIdentityProviderI{},
 		activeDirectoryUpstreams: []upstreamprovider.UpstreamLDAPIdentityProviderI{},
 		gitHubUpstreams:          []upstreamprovider.UpstreamGithubIdentityProviderI{},
+		samlUpstreams:            []upstreamprovider.UpstreamSAMLIdentityProviderI{},
+		gitLabUpstreams:          []upstreamprovider.UpstreamGitLabIdentityProviderI{},
+		oauth2Upstreams:          []upstreamprovider.UpstreamOAuth2IdentityProviderI{},
 	}
 }
 
@@ -86,6 +98,42 @@ func (p *dynamicUpstreamIDPProvider) GetGitHubIdentityProviders() []upstreamprov
 	return p.gitHubUpstreams
 }
 
+func (p *dynamicUpstreamIDPProvider) SetSAMLIdentityProviders(samlIDPs []upstreamprovider.UpstreamSAMLIdentityProviderI) {
+	p.mutex.Lock() // acquire a write lock
+	defer p.mutex.Unlock()
+	p.samlUpstreams = samlIDPs
+}
+
+func (p *dynamicUpstreamIDPProvider) GetSAMLIdentityProviders() []upstreamprovider.UpstreamSAMLIdentityProviderI {
+	p.mutex.RLock() // acquire a read lock
+	defer p.mutex.RUnlock()
+	return p.samlUpstreams
+}
+
+func (p *dynamicUpstreamIDPProvider) SetGitLabIdentityProviders(gitLabIDPs []upstreamprovider.UpstreamGitLabIdentityProviderI) {
+	p.mutex.Lock() // acquire a write lock
+	defer p.mutex.Unlock()
+	p.gitLabUpstreams = gitLabIDPs
+}
+
+func (p *dynamicUpstreamIDPProvider) GetGitLabIdentityProviders() []upstreamprovider.UpstreamGitLabIdentityProviderI {
+	p.mutex.RLock() // acquire a read lock
+	defer p.mutex.RUnlock()
+	return p.gitLabUpstreams
+}
+
+func (p *dynamicUpstreamIDPProvider) SetOAuth2IdentityProviders(oauth2IDPs []upstreamprovider.UpstreamOAuth2IdentityProviderI) {
+	p.mutex.Lock() // acquire a write lock
+	defer p.mutex.Unlock()
+	p.oauth2Upstreams = oauth2IDPs
+}
+
+func (p *dynamicUpstreamIDPProvider) GetOAuth2IdentityProviders() []upstreamprovider.UpstreamOAuth2IdentityProviderI {
+	p.mutex.RLock() // acquire a read lock
+	defer p.mutex.RUnlock()
+	return p.oauth2Upstreams
+}
+
 type RetryableRevocationError struct {
 	wrapped error
 }
@@ -1,4 +1,4 @@
-// Copyright 2020-2025 the Pinniped contributors. All Rights Reserved.
+// Copyright 2020-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 // Package auth provides a handler for the OIDC authorization endpoint.
@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"net/http"
 	"net/url"
+	"strings"
 	"time"
 
 	"github.com/ory/fosite"
@@ -17,6 +18,7 @@ import (
 
 	oidcapi "go.pinniped.dev/generated/latest/apis/supervisor/oidc"
 	"go.pinniped.dev/internal/auditevent"
+	"go.pinniped.dev/internal/federationdomain/clientregistry"
 	"go.pinniped.dev/internal/federationdomain/csrftoken"
 	"go.pinniped.dev/internal/federationdomain/downstreamsession"
 	"go.pinniped.dev/internal/federationdomain/federationdomainproviders"
@@ -36,6 +38,9 @@ import (
 const (
 	promptParamName = "prompt"
 	promptParamNone = "none"
+
+	requestURIParamName    = "request_uri"
+	requestObjectParamName = "request"
 )
 
 func paramsSafeToLog() sets.Set[string] {
@@ -77,6 +82,7 @@ func NewHandler(
 	generateNonce func() (nonce.Nonce, error),
 	upstreamStateEncoder oidc.Encoder,
 	cookieCodec oidc.Codec,
+	formPostPage *formposthtml.Page,
 	auditLogger plog.AuditLogger,
 ) http.Handler {
 	h := &authorizeHandler{
@@ -94,7 +100,7 @@ func NewHandler(
 	// During a response_mode=form_post auth request using the browser flow, the custom form_post html page may
 	// be used to post certain errors back to the CLI from this handler's response, so allow the form_post
 	// page's CSS and JS to run.
-	return securityheader.WrapWithCustomCSP(h, formposthtml.ContentSecurityPolicy())
+	return securityheader.WrapWithCustomCSP(h, formPostPage.ContentSecurityPolicy())
 }
 
 func (h *authorizeHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
@@ -191,6 +197,11 @@ func (h *authorizeHandler) authorize(
 		return
 	}
 
+	if err = requirePushedAuthorizationRequestIfConfigured(authorizeRequester); err != nil {
+		oidc.WriteAuthorizeError(r, w, oauthHelper, authorizeRequester, err, requestedBrowserlessFlow)
+		return
+	}
+
 	// Automatically grant certain scopes, but only if they were requested.
 	// Grant the openid scope (for now) if they asked for it so that `NewAuthorizeResponse` will perform its OIDC validations.
 	// There don't seem to be any validations inside `NewAuthorizeResponse` related to the offline_access scope
@@ -242,6 +253,7 @@ func (h *authorizeHandler) authorizeWithoutBrowser(
 				ReqCtx: r.Context(),
 			})
 		}
+		downstreamsession.AuditIfRejectedByUpstreamAuthenticationPolicy(r.Context(), h.auditLogger, nil, idp, err)
 		return err
 	}
 
@@ -289,6 +301,10 @@ func (h *authorizeHandler) authorizeWithBrowser(
 		return "", err
 	}
 
+	if c, ok := idp.(resolvedprovider.UpstreamStateCookieRequirer); ok && c.RequiresUpstreamStateCookie() {
+		oidc.AddUpstreamStateSetCookieHeader(w, authRequestState.EncodedStateParam)
+	}
+
 	http.Redirect(w, r, redirectURL,
 		http.StatusSeeOther, // match fosite and https://tools.ietf.org/id/draft-ietf-oauth-security-topics-18.html#section-4.11
 	)
@@ -317,6 +333,21 @@ func requireStaticClientForUsernameAndPasswordHeaders(authorizeRequester fosite.
 	return nil
 }
 
+// requirePushedAuthorizationRequestIfConfigured returns an error when the client requires pushed authorization
+// requests, but the authorization request did not use a request_uri. Note that NewAuthorizeRequest already rejects
+// any request_uri which was not issued by the pushed authorization request endpoint.
+func requirePushedAuthorizationRequestIfConfigured(authorizeRequester fosite.AuthorizeRequester) error {
+	client, ok := authorizeRequester.GetClient().(*clientregistry.Client)
+	if !ok || !client.RequiresPushedAuthorizationRequests() {
+		return nil
+	}
+	if !strings.HasPrefix(authorizeRequester.GetRequestForm().Get(requestURIParamName), oidc.PushedAuthorizationRequestURIPrefix) {
+		return fosite.ErrInvalidRequest.WithHint("This client requires pushed authorization requests. " +
+			"Use the 'request_uri' which was returned by the pushed authorization request endpoint.")
+	}
+	return nil
+}
+
 func requireNonEmptyUsernameAndPasswordHeaders(r *http.Request) (string, string, error) {
 	username := r.Header.Get(oidcapi.AuthorizeUsernameHeaderName)
 	password := r.Header.Get(oidcapi.AuthorizePasswordHeaderName)
@@ -427,7 +458,7 @@ func generateUpstreamAuthorizeRequestState(
 
 	if csrfFromCookie == "" {
 		// We did not receive an incoming CSRF cookie, so write a new one.
-		err = addCSRFSetCookieHeader(w, csrfValue, cookieCodec)
+		err = oidc.AddCSRFSetCookieHeader(w, csrfValue, cookieCodec)
 		if err != nil {
 			plog.Error("error setting CSRF cookie", err)
 			return nil, fosite.ErrServerError.WithHint("Error encoding CSRF cookie.").WithWrap(err)
@@ -476,7 +507,7 @@ func upstreamStateParam(
 		// that are reading from the encoded upstream state param being built here.
 		// The UpstreamName and UpstreamType struct fields can be used instead.
 		// Remove those params here to avoid potential confusion about which should be used later.
-		AuthParams:    removeCustomIDPParams(authorizeRequester.GetRequestForm()).Encode(),
+		AuthParams:    removeUnnecessaryParams(authorizeRequester.GetRequestForm()).Encode(),
 		UpstreamName:  upstreamDisplayName,
 		UpstreamType:  upstreamType,
 		Nonce:         nonceValue,
@@ -491,7 +522,7 @@ func upstreamStateParam(
 	return stateparam.Encoded(encodedStateParamValue), nil
 }
 
-func removeCustomIDPParams(params url.Values) url.Values {
+func removeUnnecessaryParams(params url.Values) url.Values {
 	p := url.Values{}
 	// Copy all params.
 	for k, v := range params {
@@ -500,43 +531,10 @@ func removeCustomIDPParams(params url.Values) url.Values {
 	// Remove the unnecessary params.
 	delete(p, oidcapi.AuthorizeUpstreamIDPNameParamName)
 	delete(p, oidcapi.AuthorizeUpstreamIDPTypeParamName)
+	// The params from a pushed authorization request or a request object were already merged into the other params
+	// and validated by fosite. A request_uri can only be used once, and a request object may expire before the user
+	// finishes logging in, so remove them to avoid processing them again when the other params are used later.
+	delete(p, requestURIParamName)
+	delete(p, requestObjectParamName)
 	return p
 }
-
-func addCSRFSetCookieHeader(w http.ResponseWriter, csrfValue csrftoken.CSRFToken, codec oidc.Encoder) error {
-	encodedCSRFValue, err := codec.Encode(oidc.CSRFCookieEncodingName, csrfValue)
-	if err != nil {
-		return fmt.Errorf("error encoding CSRF cookie: %w", err)
-	}
-
-	http.SetCookie(w, &http.Cookie{
-		// Because of the other settings below, this value can only be known by the end user's browser, not by other sites.
-		Value: encodedCSRFValue,
-		// Name starting with "__Host-" makes the cookie domain-locked (subdomains cannot set this cookie).
-		Name: oidc.CSRFCookieName,
-		// This cookie can't be accessed by JavaScript.
-		HttpOnly: true,
-		// Okay for requests from other sites to cause the user's browser to send this cookie back to this site,
-		// for allowing response_mode=form_post, in which an upstream IDP needs to host a web form which POSTs back
-		// to the Supervisor's callback endpoint. Note that this allows a malicious 3rd party site to cause the user's
-		// browser to include this cookie on a request to the Supervisor. However, there is no way for 3rd party sites
-		// to create the corresponding state param to include on a callback request to the Supervisor to cause that
-		// callback request be allowed by the Supervisor's callback endpoint. That state param must include this cookie's
-		// value. A 3rd party site cannot receive this cookie (and therefore cannot know its value), and even if it somehow
-		// did learn its value, it could not sign the state param (cannot know the signing key for state params, which never
-		// leaves the Supervisor server). So although a 3rd party site could cause the user's cookie to be sent, that
-		// request will never be considered acceptable by the Supervisor.
-		// Note that SameSite=None was created in a 2019 draft standard, so it requires modern browsers to work.
-		// See https://datatracker.ietf.org/doc/html/draft-west-cookie-incrementalism-00.
-		SameSite: http.SameSiteNoneMode,
-		// This cookie may only be sent via HTTPS (required for domain-locked cookies).
-		Secure: true,
-		// Sending this cookie to any path of this server is acceptable (required for domain-locked cookies).
-		Path: "/",
-		// Note that we do not set "Domain", so this cookie should not be sent to any subdomains (required for domain-locked cookies).
-		// Also note that we do not set "Expires" or "MaxAge", so the client may keep the cookie as long as it likes,
-		// which prevents the cookie from expiring during login flows.
-	})
-
-	return nil
-}
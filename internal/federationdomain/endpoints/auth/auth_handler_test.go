@@ -32,12 +32,15 @@ import (
 	"go.pinniped.dev/generated/latest/client/supervisor/clientset/versioned/typed/config/v1alpha1"
 	"go.pinniped.dev/internal/auditid"
 	"go.pinniped.dev/internal/authenticators"
+	"go.pinniped.dev/internal/celtransformer"
 	"go.pinniped.dev/internal/federationdomain/csrftoken"
 	"go.pinniped.dev/internal/federationdomain/endpoints/jwks"
+	"go.pinniped.dev/internal/federationdomain/formposthtml"
 	"go.pinniped.dev/internal/federationdomain/oidc"
 	"go.pinniped.dev/internal/federationdomain/oidcclientvalidator"
 	"go.pinniped.dev/internal/federationdomain/stateparam"
 	"go.pinniped.dev/internal/federationdomain/storage"
+	"go.pinniped.dev/internal/fositestorage/pushedauthorizationrequest"
 	"go.pinniped.dev/internal/here"
 	"go.pinniped.dev/internal/plog"
 	"go.pinniped.dev/internal/psession"
@@ -89,6 +92,8 @@ func TestAuthorizationEndpoint(t *testing.T) { //nolint:gocyclo
 		dynamicClientID     = "client.oauth.pinniped.dev-test-name"
 		dynamicClientUID    = "fake-client-uid"
 
+		pushedAuthorizationRequestURI = "urn:ietf:params:oauth:request_uri:some-request-uri"
+
 		transformationUsernamePrefix = "username_prefix:"
 		transformationGroupsPrefix   = "groups_prefix:"
 	)
@@ -238,6 +243,12 @@ func TestAuthorizationEndpoint(t *testing.T) { //nolint:gocyclo
 			"state":             happyState,
 		}
 
+		fositeAccessDeniedWithUpstreamAuthenticationPolicyRejectionHintErrorQuery = map[string]string{
+			"error":             "access_denied",
+			"error_description": "The resource owner or authorization server denied the request. Reason: please log in using MFA.",
+			"state":             happyState,
+		}
+
 		fositeLoginRequiredErrorQuery = map[string]string{
 			"error":             "login_required",
 			"error_description": "The Authorization Server requires End-User authentication.",
@@ -650,6 +661,62 @@ func TestAuthorizationEndpoint(t *testing.T) { //nolint:gocyclo
 		require.NoError(t, kubeClient.Tracker().Add(secret))
 	}
 
+	addDynamicClientWhichRequiresPushedAuthorizationRequestsToKubeResources := func(t *testing.T, supervisorClient *supervisorfake.Clientset, kubeClient *kubefake.Clientset) {
+		oidcClient, secret := testutil.FullyCapableOIDCClientAndStorageSecret(t,
+			"some-namespace", dynamicClientID, dynamicClientUID, downstreamRedirectURI, nil,
+			[]string{testutil.HashedPassword1AtGoMinCost}, oidcclientvalidator.Validate)
+		oidcClient.Spec.RequirePushedAuthorizationRequests = true
+		require.NoError(t, supervisorClient.Tracker().Add(oidcClient))
+		require.NoError(t, kubeClient.Tracker().Add(secret))
+	}
+
+	addPushedAuthorizationRequestForDynamicClientToKubeResources := func(t *testing.T, supervisorClient *supervisorfake.Clientset, kubeClient *kubefake.Clientset) {
+		oidcClient, secret := testutil.FullyCapableOIDCClientAndStorageSecret(t,
+			"some-namespace", dynamicClientID, dynamicClientUID, downstreamRedirectURI, nil,
+			[]string{testutil.HashedPassword1AtGoMinCost}, oidcclientvalidator.Validate)
+		oidcClient.Spec.RequirePushedAuthorizationRequests = true
+		require.NoError(t, supervisorClient.Tracker().Add(oidcClient))
+		require.NoError(t, kubeClient.Tracker().Add(secret))
+
+		// Use a different fake client to create the pushed authorization request's Secret,
+		// so the create action is not counted as a stored record by the test.
+		otherKubeClient := kubefake.NewClientset(secret)
+		otherSecretsClient := otherKubeClient.CoreV1().Secrets("some-namespace")
+		otherStorage := storage.NewKubeStorage(otherSecretsClient, supervisorClient.ConfigV1alpha1().OIDCClients("some-namespace"),
+			oidc.DefaultOIDCTimeoutsConfiguration(), bcrypt.MinCost)
+		client, err := otherStorage.GetClient(context.Background(), dynamicClientID)
+		require.NoError(t, err)
+
+		form := url.Values{}
+		for k, v := range modifiedHappyGetRequestQueryMap(map[string]string{"client_id": dynamicClientID, "scope": testutil.AllDynamicClientScopesSpaceSep}) {
+			form.Set(k, v)
+		}
+		redirectURI, err := url.Parse(downstreamRedirectURI)
+		require.NoError(t, err)
+		session := psession.NewPinnipedSession()
+		session.SetExpiresAt(fosite.PushedAuthorizeRequestContext, time.Now().Add(time.Minute))
+
+		require.NoError(t, otherStorage.CreatePARSession(context.Background(), pushedAuthorizationRequestURI, &fosite.AuthorizeRequest{
+			Request: fosite.Request{
+				ID:             "some-request-id",
+				RequestedAt:    time.Now(),
+				Client:         client,
+				RequestedScope: strings.Split(testutil.AllDynamicClientScopesSpaceSep, " "),
+				Form:           form,
+				Session:        session,
+			},
+			ResponseTypes: fosite.Arguments{"code"},
+			RedirectURI:   redirectURI,
+			State:         happyState,
+		}))
+		parSecrets, err := otherSecretsClient.List(context.Background(), metav1.ListOptions{
+			LabelSelector: "storage.pinniped.dev/type=" + pushedauthorizationrequest.TypeLabelValue,
+		})
+		require.NoError(t, err)
+		require.Len(t, parSecrets.Items, 1)
+		require.NoError(t, kubeClient.Tracker().Add(&parSecrets.Items[0]))
+	}
+
 	// Note that fosite puts the granted scopes as a param in the redirect URI even though the spec doesn't seem to require it
 	happyAuthcodeDownstreamRedirectLocationRegexp := downstreamRedirectURI + `\?code=([^&]+)&scope=openid\+username\+groups&state=` + happyState
 
@@ -659,6 +726,13 @@ func TestAuthorizationEndpoint(t *testing.T) { //nolint:gocyclo
 
 	prefixUsernameAndGroupsPipeline := transformtestutil.NewPrefixingPipeline(t, transformationUsernamePrefix, transformationGroupsPrefix)
 	rejectAuthPipeline := transformtestutil.NewRejectAllAuthPipeline(t)
+	requireMFAPolicy := transformtestutil.NewUpstreamAuthenticationPolicy(t, &celtransformer.UpstreamAuthenticationPolicy{
+		Expression:                    `has(claims.amr) && "mfa" in claims.amr`,
+		RejectedAuthenticationMessage: "please log in using MFA",
+	})
+	requireACRPolicy := transformtestutil.NewUpstreamAuthenticationPolicy(t, &celtransformer.UpstreamAuthenticationPolicy{
+		ACRValues: []string{"phrh", "phr"},
+	})
 
 	type testCase struct {
 		name string
@@ -803,6 +877,93 @@ func TestAuthorizationEndpoint(t *testing.T) { //nolint:gocyclo
 				}
 			},
 		},
+		{
+			name:          "OIDC upstream browser flow happy path using a pushed authorization request for a dynamic client",
+			idps:          testidplister.NewUpstreamIDPListerBuilder().WithOIDC(upstreamOIDCIdentityProviderBuilder().Build()),
+			kubeResources: addPushedAuthorizationRequestForDynamicClientToKubeResources,
+			generateCSRF:  happyCSRFGenerator,
+			generatePKCE:  happyPKCEGenerator,
+			generateNonce: happyNonceGenerator,
+			stateEncoder:  happyStateEncoder,
+			cookieEncoder: happyCookieEncoder,
+			method:        http.MethodGet,
+			path: pathWithQuery("/some/path", map[string]string{
+				"client_id":         dynamicClientID,
+				"request_uri":       pushedAuthorizationRequestURI,
+				"pinniped_idp_name": oidcUpstreamName,
+			}),
+			wantStatus:                  http.StatusSeeOther,
+			wantContentType:             htmlContentType,
+			wantCSRFValueInCookieHeader: happyCSRF,
+			// The params from the pushed authorization request are saved into the upstream state param,
+			// but the request_uri is not, because it cannot be used again.
+			wantLocationHeader:                     expectedRedirectLocationForUpstreamOIDC(expectedUpstreamStateParam(map[string]string{"client_id": dynamicClientID, "scope": testutil.AllDynamicClientScopesSpaceSep}, "", oidcUpstreamName, "oidc"), nil),
+			wantUpstreamStateParamInLocationHeader: true,
+			wantBodyStringWithLocationInHref:       true,
+			wantAuditLogs: func(encodedStateParam stateparam.Encoded, sessionID string) []testutil.WantedAuditLog {
+				return []testutil.WantedAuditLog{
+					testutil.WantAuditLog("HTTP Request Parameters", map[string]any{
+						"params": map[string]any{
+							"client_id":         dynamicClientID,
+							"pinniped_idp_name": "some-oidc-idp",
+							"request_uri":       pushedAuthorizationRequestURI,
+						},
+					}),
+					testutil.WantAuditLog("HTTP Request Custom Headers Used", map[string]any{
+						"Pinniped-Username": false,
+						"Pinniped-Password": false,
+					}),
+					testutil.WantAuditLog("Using Upstream IDP", map[string]any{
+						"displayName":  "some-oidc-idp",
+						"resourceName": "some-oidc-idp",
+						"resourceUID":  "oidc-resource-uid",
+						"type":         "oidc",
+					}),
+					testutil.WantAuditLog("Upstream Authorize Redirect", map[string]any{
+						"authorizeID": encodedStateParam.AuthorizeID(),
+					}),
+				}
+			},
+		},
+		{
+			name:            "OIDC upstream browser flow without a pushed authorization request for a dynamic client which requires them",
+			idps:            testidplister.NewUpstreamIDPListerBuilder().WithOIDC(upstreamOIDCIdentityProviderBuilder().Build()),
+			kubeResources:   addDynamicClientWhichRequiresPushedAuthorizationRequestsToKubeResources,
+			generateCSRF:    happyCSRFGenerator,
+			generatePKCE:    happyPKCEGenerator,
+			generateNonce:   happyNonceGenerator,
+			stateEncoder:    happyStateEncoder,
+			cookieEncoder:   happyCookieEncoder,
+			method:          http.MethodGet,
+			path:            modifiedHappyGetRequestPathForOIDCUpstream(map[string]string{"client_id": dynamicClientID, "scope": testutil.AllDynamicClientScopesSpaceSep}),
+			wantStatus:      http.StatusSeeOther,
+			wantContentType: jsonContentType,
+			wantLocationHeader: urlWithQuery(downstreamRedirectURI, map[string]string{
+				"error":             "invalid_request",
+				"error_description": "The request is missing a required parameter, includes an invalid parameter value, includes a parameter more than once, or is otherwise malformed. This client requires pushed authorization requests. Use the 'request_uri' which was returned by the pushed authorization request endpoint.",
+				"state":             happyState,
+			}),
+			wantBodyString: "",
+		},
+		{
+			name:          "OIDC upstream browser flow using a request_uri which does not exist",
+			idps:          testidplister.NewUpstreamIDPListerBuilder().WithOIDC(upstreamOIDCIdentityProviderBuilder().Build()),
+			kubeResources: addDynamicClientWhichRequiresPushedAuthorizationRequestsToKubeResources,
+			generateCSRF:  happyCSRFGenerator,
+			generatePKCE:  happyPKCEGenerator,
+			generateNonce: happyNonceGenerator,
+			stateEncoder:  happyStateEncoder,
+			cookieEncoder: happyCookieEncoder,
+			method:        http.MethodGet,
+			path: pathWithQuery("/some/path", map[string]string{
+				"client_id":         dynamicClientID,
+				"request_uri":       pushedAuthorizationRequestURI,
+				"pinniped_idp_name": oidcUpstreamName,
+			}),
+			wantStatus:      http.StatusBadRequest,
+			wantContentType: jsonContentType,
+			wantBodyJSON:    `{"error": "invalid_request_uri", "error_description": "The request_uri in the Authorization Request returns an error or contains invalid data. Invalid PAR session"}`,
+		},
 		{
 			name:                                   "GitHub upstream browser flow happy path using GET without a CSRF cookie",
 			idps:                                   testidplister.NewUpstreamIDPListerBuilder().WithGitHub(upstreamGitHubIdentityProviderBuilder().Build()),
@@ -1303,6 +1464,54 @@ func TestAuthorizationEndpoint(t *testing.T) { //nolint:gocyclo
 				}
 			},
 		},
+		{
+			name: "OIDC upstream password grant with an authentication policy which rejects auth",
+			idps: testidplister.NewUpstreamIDPListerBuilder().
+				WithOIDC(passwordGrantUpstreamOIDCIdentityProviderBuilder().WithAuthenticationPolicyForFederationDomain(requireMFAPolicy).Build()),
+			method:                http.MethodGet,
+			path:                  happyGetRequestPathForOIDCPasswordGrantUpstream,
+			customUsernameHeader:  ptr.To(oidcUpstreamUsername),
+			customPasswordHeader:  ptr.To(oidcUpstreamPassword),
+			wantPasswordGrantCall: happyUpstreamPasswordGrantMockExpectation,
+			wantStatus:            http.StatusFound,
+			wantContentType:       jsonContentType,
+			wantLocationHeader:    urlWithQuery(downstreamRedirectURI, fositeAccessDeniedWithUpstreamAuthenticationPolicyRejectionHintErrorQuery),
+			wantBodyString:        "",
+			wantAuditLogs: func(encodedStateParam stateparam.Encoded, sessionID string) []testutil.WantedAuditLog {
+				return []testutil.WantedAuditLog{
+					testutil.WantAuditLog("HTTP Request Parameters", map[string]any{
+						"params": map[string]any{
+							"client_id":             "pinniped-cli",
+							"code_challenge":        "redacted",
+							"code_challenge_method": "S256",
+							"nonce":                 "redacted",
+							"pinniped_idp_name":     "some-password-granting-oidc-idp",
+							"redirect_uri":          "http://127.0.0.1/callback",
+							"response_type":         "code",
+							"scope":                 "openid profile email username groups",
+							"state":                 "redacted",
+						},
+					}),
+					testutil.WantAuditLog("HTTP Request Custom Headers Used", map[string]any{
+						"Pinniped-Username": true,
+						"Pinniped-Password": true,
+					}),
+					testutil.WantAuditLog("Using Upstream IDP", map[string]any{
+						"displayName":  "some-password-granting-oidc-idp",
+						"resourceName": "some-password-granting-oidc-idp",
+						"resourceUID":  "some-password-granting-resource-uid",
+						"type":         "oidc",
+					}),
+					testutil.WantAuditLog("Authentication Rejected By Upstream Policy", map[string]any{
+						"upstreamIDPDisplayName":  "some-password-granting-oidc-idp",
+						"upstreamIDPResourceName": "some-password-granting-oidc-idp",
+						"upstreamIDPResourceUID":  "some-password-granting-resource-uid",
+						"upstreamIDPType":         "oidc",
+						"reason":                  "expression evaluated to false",
+					}),
+				}
+			},
+		},
 		{
 			name: "OIDC upstream password grant happy path using GET with additional claim mappings",
 			idps: testidplister.NewUpstreamIDPListerBuilder().WithOIDC(passwordGrantUpstreamOIDCIdentityProviderBuilder().
@@ -1878,6 +2087,26 @@ func TestAuthorizationEndpoint(t *testing.T) { //nolint:gocyclo
 			wantLocationHeader:                     expectedRedirectLocationForUpstreamOIDC(expectedUpstreamStateParam(map[string]string{"prompt": "login"}, "", oidcUpstreamName, "oidc"), map[string]string{"prompt": "consent", "abc": "123", "def": "456"}),
 			wantUpstreamStateParamInLocationHeader: true,
 		},
+		{
+			name: "OIDC upstream browser flow happy path with an authentication policy which requests acr values from the upstream",
+			idps: testidplister.NewUpstreamIDPListerBuilder().WithOIDC(upstreamOIDCIdentityProviderBuilder().
+				WithAdditionalAuthcodeParams(map[string]string{"acr_values": "will-be-overridden", "abc": "123"}).
+				WithAuthenticationPolicyForFederationDomain(requireACRPolicy).
+				Build()),
+			generateCSRF:                           happyCSRFGenerator,
+			generatePKCE:                           happyPKCEGenerator,
+			generateNonce:                          happyNonceGenerator,
+			stateEncoder:                           happyStateEncoder,
+			cookieEncoder:                          happyCookieEncoder,
+			method:                                 http.MethodGet,
+			path:                                   happyGetRequestPathForOIDCUpstream,
+			wantStatus:                             http.StatusSeeOther,
+			wantContentType:                        htmlContentType,
+			wantBodyStringWithLocationInHref:       true,
+			wantCSRFValueInCookieHeader:            happyCSRF,
+			wantLocationHeader:                     expectedRedirectLocationForUpstreamOIDC(expectedUpstreamStateParam(nil, "", oidcUpstreamName, "oidc"), map[string]string{"acr_values": "phrh phr", "abc": "123"}),
+			wantUpstreamStateParamInLocationHeader: true,
+		},
 		{
 			name:               "OIDC upstream browser flow with prompt param none throws an error because we want to independently decide the upstream prompt param",
 			idps:               testidplister.NewUpstreamIDPListerBuilder().WithOIDC(upstreamOIDCIdentityProviderBuilder().Build()),
@@ -4257,6 +4486,7 @@ func TestAuthorizationEndpoint(t *testing.T) { //nolint:gocyclo
 				oauthHelperWithNullStorage, oauthHelperWithRealStorage,
 				test.generateCSRF, test.generatePKCE, test.generateNonce,
 				test.stateEncoder, test.cookieEncoder,
+				formposthtml.DefaultPage(),
 				auditLogger,
 			)
 			runOneTestCase(t, test, subject, kubeOauthStore, supervisorClient, kubeClient, secretsClient, actualAuditLog)
@@ -4282,6 +4512,7 @@ func TestAuthorizationEndpoint(t *testing.T) { //nolint:gocyclo
 			oauthHelperWithNullStorage, oauthHelperWithRealStorage,
 			test.generateCSRF, test.generatePKCE, test.generateNonce,
 			test.stateEncoder, test.cookieEncoder,
+			formposthtml.DefaultPage(),
 			auditLogger,
 		)
 
@@ -1,4 +1,4 @@
-// Copyright 2020-2025 the Pinniped contributors. All Rights Reserved.
+// Copyright 2020-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 // Package callback provides a handler for the OIDC callback endpoint.
@@ -16,6 +16,7 @@ import (
 
 	"go.pinniped.dev/internal/auditevent"
 	"go.pinniped.dev/internal/federationdomain/downstreamsession"
+	"go.pinniped.dev/internal/federationdomain/endpoints/device"
 	"go.pinniped.dev/internal/federationdomain/federationdomainproviders"
 	"go.pinniped.dev/internal/federationdomain/formposthtml"
 	"go.pinniped.dev/internal/federationdomain/oidc"
@@ -30,14 +31,17 @@ func paramsSafeToLog() sets.Set[string] {
 		// authorize errors can have these parameters, which should not contain PII or secrets and are safe to log.
 		"error", "error_description", "error_uri",
 		// Note that this endpoint also receives 'code' and 'state' params, which are not safe to log.
+		// For SAML upstreams, it receives 'SAMLResponse' and 'RelayState' params instead, which are also not safe to log.
 	)
 }
 
 func NewHandler(
 	upstreamIDPs federationdomainproviders.FederationDomainIdentityProvidersFinderI,
 	oauthHelper fosite.OAuth2Provider,
+	deviceStorage device.UserCodeStorage,
 	stateDecoder, cookieDecoder oidc.Decoder,
 	redirectURI string,
+	formPostPage *formposthtml.Page,
 	auditLogger plog.AuditLogger,
 ) http.Handler {
 	handler := httperr.HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
@@ -51,6 +55,11 @@ func NewHandler(
 			return err
 		}
 
+		if relayStateKey := r.FormValue("RelayState"); relayStateKey != "" {
+			// The state param was kept in a cookie, which should not be used again.
+			oidc.AddUpstreamStateDeleteCookieHeader(w, relayStateKey)
+		}
+
 		idp, err := upstreamIDPs.FindUpstreamIDPByDisplayName(decodedState.UpstreamName)
 		if err != nil || idp == nil {
 			plog.Warning("upstream provider not found")
@@ -67,6 +76,20 @@ func NewHandler(
 			},
 		})
 
+		if decodedState.DeviceUserCodeSignature != "" {
+			// This login was started by the device verification page instead of by the authorization endpoint.
+			identity, loginExtras, err := idp.LoginFromCallback(r.Context(), authcode(r), decodedState.PKCECode, decodedState.Nonce, redirectURI)
+			if err != nil {
+				downstreamsession.AuditIfRejectedByUpstreamAuthenticationPolicy(r.Context(), auditLogger, nil, idp, err)
+				plog.WarningErr("unable to complete device login from callback", err,
+					"identityProviderDisplayName", idp.GetDisplayName(),
+					"identityProviderResourceName", idp.GetProvider().GetResourceName(),
+					"supervisorCallbackURL", redirectURI)
+				return err
+			}
+			return device.ApproveAfterLogin(w, r, deviceStorage, decodedState.DeviceUserCodeSignature, identity, loginExtras, idp, auditLogger)
+		}
+
 		downstreamAuthParams, err := url.ParseQuery(decodedState.AuthParams)
 		if err != nil {
 			plog.Error("error reading state downstream auth params", err)
@@ -92,6 +115,7 @@ func NewHandler(
 
 		identity, loginExtras, err := idp.LoginFromCallback(r.Context(), authcode(r), decodedState.PKCECode, decodedState.Nonce, redirectURI)
 		if err != nil {
+			downstreamsession.AuditIfRejectedByUpstreamAuthenticationPolicy(r.Context(), auditLogger, nil, idp, err)
 			plog.WarningErr("unable to complete login from callback", err,
 				"identityProviderDisplayName", idp.GetDisplayName(),
 				"identityProviderResourceName", idp.GetProvider().GetResourceName(),
@@ -129,11 +153,17 @@ func NewHandler(
 
 		return nil
 	})
-	return securityheader.WrapWithCustomCSP(handler, formposthtml.ContentSecurityPolicy())
+	return securityheader.WrapWithCustomCSP(handler, formPostPage.ContentSecurityPolicy())
 }
 
+// authcode returns the authcode from an OIDC, GitHub, GitLab, or OAuth2 upstream, or the SAML response from a SAML upstream.
+// The SAML response is not an authcode, but it plays the same role in the flow: it is the thing which is
+// passed to the upstream IDP's LoginFromCallback to learn the identity of the user.
 func authcode(r *http.Request) string {
-	return r.FormValue("code")
+	if code := r.FormValue("code"); code != "" {
+		return code
+	}
+	return r.FormValue("SAMLResponse")
 }
 
 func validateRequest(r *http.Request, stateDecoder, cookieDecoder oidc.Decoder, auditLogger plog.AuditLogger) (*oidc.UpstreamStateParamData, error) {
@@ -145,6 +175,9 @@ func validateRequest(r *http.Request, stateDecoder, cookieDecoder oidc.Decoder,
 	// and if the admin configured OIDCIdentityProvider.spec.authorizationConfig.additionalAuthorizeParameters
 	// to include response_mode=form_post, then the user's browser will POST the authcode to this endpoint.
 	// See https://openid.net/specs/oauth-v2-form-post-response-mode-1_0.html.
+	//
+	// An upstream SAML provider will cause the user's browser to POST the SAML response to this endpoint,
+	// using the SAML HTTP-POST binding.
 	if r.Method != http.MethodGet && r.Method != http.MethodPost {
 		return nil, httperr.Newf(http.StatusMethodNotAllowed, "%s (try GET or POST)", r.Method)
 	}
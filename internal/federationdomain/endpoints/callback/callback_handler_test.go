@@ -24,7 +24,9 @@ import (
 	supervisorconfigv1alpha1 "go.pinniped.dev/generated/latest/apis/supervisor/config/v1alpha1"
 	supervisorfake "go.pinniped.dev/generated/latest/client/supervisor/clientset/versioned/fake"
 	"go.pinniped.dev/internal/auditid"
+	"go.pinniped.dev/internal/celtransformer"
 	"go.pinniped.dev/internal/federationdomain/endpoints/jwks"
+	"go.pinniped.dev/internal/federationdomain/formposthtml"
 	"go.pinniped.dev/internal/federationdomain/oidc"
 	"go.pinniped.dev/internal/federationdomain/oidcclientvalidator"
 	"go.pinniped.dev/internal/federationdomain/stateparam"
@@ -222,6 +224,10 @@ func TestCallbackEndpoint(t *testing.T) {
 
 	prefixUsernameAndGroupsPipeline := transformtestutil.NewPrefixingPipeline(t, transformationUsernamePrefix, transformationGroupsPrefix)
 	rejectAuthPipeline := transformtestutil.NewRejectAllAuthPipeline(t)
+	requireACRPolicy := transformtestutil.NewUpstreamAuthenticationPolicy(t, &celtransformer.UpstreamAuthenticationPolicy{
+		ACRValues:                     []string{"phrh"},
+		RejectedAuthenticationMessage: "please log in using MFA",
+	})
 
 	tests := []struct {
 		name string
@@ -2118,6 +2124,44 @@ func TestCallbackEndpoint(t *testing.T) {
 				}
 			},
 		},
+		{
+			name: "OIDC: using an authentication policy which rejects the authentication",
+			idps: testidplister.NewUpstreamIDPListerBuilder().
+				WithOIDC(happyOIDCUpstream().WithAuthenticationPolicyForFederationDomain(requireACRPolicy).Build()),
+			method:          http.MethodGet,
+			path:            newRequestPath().WithState(happyOIDCState).String(),
+			csrfCookie:      happyCSRFCookie,
+			wantStatus:      http.StatusUnprocessableEntity,
+			wantContentType: htmlContentType,
+			wantBody:        "Unprocessable Entity: please log in using MFA\n",
+			wantOIDCAuthcodeExchangeCall: &expectedOIDCAuthcodeExchange{
+				performedByUpstreamName: happyOIDCUpstreamIDPName,
+				args:                    happyOIDCUpstreamExchangeAuthcodeAndValidateTokenArgs,
+			},
+			wantAuditLogs: func(encodedStateParam stateparam.Encoded, sessionID string) []testutil.WantedAuditLog {
+				return []testutil.WantedAuditLog{
+					testutil.WantAuditLog("HTTP Request Parameters", map[string]any{
+						"params": map[string]any{"code": "redacted", "state": "redacted"},
+					}),
+					testutil.WantAuditLog("AuthorizeID From Parameters", map[string]any{
+						"authorizeID": encodedStateParam.AuthorizeID(),
+					}),
+					testutil.WantAuditLog("Using Upstream IDP", map[string]any{
+						"displayName":  "upstream-oidc-idp-name",
+						"resourceName": "upstream-oidc-idp-name",
+						"resourceUID":  "upstream-oidc-resource-uid",
+						"type":         "oidc",
+					}),
+					testutil.WantAuditLog("Authentication Rejected By Upstream Policy", map[string]any{
+						"upstreamIDPDisplayName":  "upstream-oidc-idp-name",
+						"upstreamIDPType":         "oidc",
+						"upstreamIDPResourceName": "upstream-oidc-idp-name",
+						"upstreamIDPResourceUID":  "upstream-oidc-resource-uid",
+						"reason":                  `required "acr" claim is missing or is not a string`,
+					}),
+				}
+			},
+		},
 		{
 			name: "GitHub: using identity transformations which reject the authentication",
 			idps: testidplister.NewUpstreamIDPListerBuilder().
@@ -2190,9 +2234,11 @@ func TestCallbackEndpoint(t *testing.T) {
 			subject := NewHandler(
 				test.idps.BuildFederationDomainIdentityProvidersListerFinder(),
 				oauthHelper,
+				oauthStore,
 				happyStateCodec,
 				happyCookieCodec,
 				happyUpstreamRedirectURI,
+				formposthtml.DefaultPage(),
 				auditLogger,
 			)
 
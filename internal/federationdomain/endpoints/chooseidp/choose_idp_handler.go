@@ -1,4 +1,4 @@
-// Copyright 2023-2025 the Pinniped contributors. All Rights Reserved.
+// Copyright 2023-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package chooseidp
@@ -20,8 +20,9 @@ import (
 // to this page, copying all the same parameters from the original authorization request. Each button on this page
 // simply adds the IDP's name as an additional request parameter to the original authorization request's parameters,
 // and sends the user back to the authorization endpoint, where the authorization flow can start from scratch using
-// the original params with the extra pinniped_idp_name param added.
-func NewHandler(authURL string, upstreamIDPs federationdomainproviders.FederationDomainIdentityProvidersListerI) http.Handler {
+// the original params with the extra pinniped_idp_name param added. The page is rendered using the given page,
+// which may be branded.
+func NewHandler(authURL string, upstreamIDPs federationdomainproviders.FederationDomainIdentityProvidersListerI, page *chooseidphtml.Page) http.Handler {
 	handler := httperr.HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
 		if r.Method != http.MethodGet {
 			return httperr.Newf(http.StatusMethodNotAllowed, "%s (try GET)", r.Method)
@@ -58,15 +59,15 @@ func NewHandler(authURL string, upstreamIDPs federationdomainproviders.Federatio
 				"please check the server's configuration: no valid identity providers found for this FederationDomain")
 		}
 
-		return chooseidphtml.Template().Execute(w, &chooseidphtml.PageData{IdentityProviders: idps})
+		return page.TemplateForContext(r.Context()).Execute(w, &chooseidphtml.PageData{IdentityProviders: idps})
 	})
 
-	return wrapSecurityHeaders(handler)
+	return wrapSecurityHeaders(handler, page)
 }
 
-func wrapSecurityHeaders(handler http.Handler) http.Handler {
+func wrapSecurityHeaders(handler http.Handler, page *chooseidphtml.Page) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		wrapped := securityheader.WrapWithCustomCSP(handler, chooseidphtml.ContentSecurityPolicy())
+		wrapped := securityheader.WrapWithCustomCSP(handler, page.ContentSecurityPolicy())
 		wrapped.ServeHTTP(w, r)
 	})
 }
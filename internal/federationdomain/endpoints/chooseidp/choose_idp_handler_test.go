@@ -135,7 +135,7 @@ func TestChooseIDPHandler(t *testing.T) {
 		t.Run(test.name, func(t *testing.T) {
 			t.Parallel()
 
-			handler := NewHandler(testIssuer, test.idps)
+			handler := NewHandler(testIssuer, test.idps, chooseidphtml.DefaultPage())
 
 			req := httptest.NewRequestWithContext(t.Context(), test.method, test.reqTarget, nil)
 			rsp := httptest.NewRecorder()
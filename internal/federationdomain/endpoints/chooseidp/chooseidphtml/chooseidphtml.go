@@ -1,16 +1,21 @@
-// Copyright 2023-2024 the Pinniped contributors. All Rights Reserved.
+// Copyright 2023-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package chooseidphtml
 
 import (
+	"bytes"
+	"context"
 	_ "embed" // Needed to trigger //go:embed directives below.
+	"fmt"
 	"html/template"
 	"strings"
 
 	"github.com/tdewolff/minify/v2/minify"
 
+	"go.pinniped.dev/internal/federationdomain/branding"
 	"go.pinniped.dev/internal/federationdomain/csp"
+	"go.pinniped.dev/internal/federationdomain/i18n"
 )
 
 //nolint:gochecknoglobals // This package uses globals to ensure that all parsing and minifying happens at init.
@@ -26,21 +31,43 @@ var (
 	//go:embed choose_idp.gohtml
 	rawHTMLTemplate string
 
-	// Parse the Go templated HTML and inject functions providing the minified inline CSS and JS.
-	parsedHTMLTemplate = template.Must(template.New("choose_idp.gohtml").Funcs(template.FuncMap{
-		"minifiedCSS": func() template.CSS { return template.CSS(CSS()) }, //nolint:gosec // This is 100% static input, not attacker-controlled.
-		"minifiedJS":  func() template.JS { return template.JS(JS()) },    //nolint:gosec // This is 100% static input, not attacker-controlled.
-	}).Parse(rawHTMLTemplate))
+	// Parse the Go templated HTML once per language and inject functions providing the minified inline CSS and JS.
+	parsedHTMLTemplates = mustParseTemplates(rawHTMLTemplate, minifiedCSS, nil)
+	parsedHTMLTemplate  = parsedHTMLTemplates.ForLanguage(i18n.DefaultLanguage)
 
 	// Generate the CSP header value once since it's effectively constant.
-	cspValue = strings.Join([]string{
+	cspValue = contentSecurityPolicy(minifiedCSS)
+
+	defaultPage = &Page{templates: parsedHTMLTemplates, css: minifiedCSS, csp: cspValue}
+)
+
+func parseTemplates(text string, css string, b *branding.Branding) (*i18n.Templates, error) {
+	return i18n.ParseTemplates(func(c *i18n.Catalog) (*template.Template, error) {
+		return template.New("choose_idp.gohtml").Funcs(template.FuncMap{
+			"minifiedCSS": func() template.CSS { return template.CSS(css) }, //nolint:gosec // This is static or validated input, not attacker-controlled.
+			"minifiedJS":  func() template.JS { return template.JS(JS()) },  //nolint:gosec // This is 100% static input, not attacker-controlled.
+			"branding":    func() *branding.Branding { return b },
+		}).Funcs(i18n.TemplateFuncs(c)).Parse(text)
+	})
+}
+
+func mustParseTemplates(text string, css string, b *branding.Branding) *i18n.Templates {
+	templates, err := parseTemplates(text, css, b)
+	if err != nil {
+		panic(err)
+	}
+	return templates
+}
+
+func contentSecurityPolicy(css string) string {
+	return strings.Join([]string{
 		`default-src 'none'`,
 		`script-src '` + csp.Hash(minifiedJS) + `'`,
-		`style-src '` + csp.Hash(minifiedCSS) + `'`,
+		`style-src '` + csp.Hash(css) + `'`,
 		`img-src data:`,
 		`frame-ancestors 'none'`,
 	}, "; ")
-)
+}
 
 func panicOnError(s string, err error) string {
 	if err != nil {
@@ -54,7 +81,7 @@ func panicOnError(s string, err error) string {
 // See https://developer.mozilla.org/en-US/docs/Web/HTTP/Headers/Content-Security-Policy.
 func ContentSecurityPolicy() string { return cspValue }
 
-// Template returns the html/template.Template for rendering the login page.
+// Template returns the html/template.Template for rendering the login page in the default language.
 func Template() *template.Template { return parsedHTMLTemplate }
 
 // CSS returns the minified CSS that will be embedded into the page template.
@@ -63,6 +90,74 @@ func CSS() string { return minifiedCSS }
 // JS returns the minified JS that will be embedded into the page template.
 func JS() string { return minifiedJS }
 
+// Page is the page for choosing an identity provider of a FederationDomain, which may be customized by branding.
+type Page struct {
+	templates *i18n.Templates
+	css       string
+	csp       string
+}
+
+// DefaultPage returns the page for choosing an identity provider without any branding.
+func DefaultPage() *Page { return defaultPage }
+
+// NewPage returns a page for choosing an identity provider customized by the given branding. When the branding
+// overrides the template of the page, the template is rendered with sample data and validated to be compatible
+// with the page's CSP.
+func NewPage(b *branding.Branding) (*Page, error) {
+	if b == nil {
+		return defaultPage, nil
+	}
+
+	brandedCSS := rawCSS + b.CSS()
+	if b.PrimaryColor != "" {
+		brandedCSS += fmt.Sprintf(`.form-field button, .form-field button:focus, .form-field button:hover { background-color: %s; }`, b.PrimaryColor)
+	}
+	css, err := minify.CSS(brandedCSS)
+	if err != nil {
+		return nil, fmt.Errorf("could not minify CSS for choose identity provider page: %w", err)
+	}
+
+	text, overridden := b.TemplateOverride(branding.ChooseIDPTemplateKey)
+	if !overridden {
+		text = rawHTMLTemplate
+	}
+	templates, err := parseTemplates(text, css, b)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse template %q: %w", branding.ChooseIDPTemplateKey, err)
+	}
+	if overridden {
+		for _, tmpl := range templates.All() {
+			var buf bytes.Buffer
+			if err := tmpl.Execute(&buf, &PageData{IdentityProviders: []IdentityProvider{
+				{DisplayName: "sample-idp-1", URL: "https://sample.example.com/oauth2/authorize?pinniped_idp_name=sample-idp-1"},
+				{DisplayName: "sample-idp-2", URL: "https://sample.example.com/oauth2/authorize?pinniped_idp_name=sample-idp-2"},
+			}}); err != nil {
+				return nil, fmt.Errorf("could not render template %q: %w", branding.ChooseIDPTemplateKey, err)
+			}
+			if err := branding.CheckRenderedPage(buf.String(), []string{minifiedJS}, []string{css}); err != nil {
+				return nil, fmt.Errorf("template %q is not compatible with the Content-Security-Policy of the page: %w", branding.ChooseIDPTemplateKey, err)
+			}
+		}
+	}
+
+	return &Page{templates: templates, css: css, csp: contentSecurityPolicy(css)}, nil
+}
+
+// ContentSecurityPolicy returns the Content-Security-Policy header value to make the Template() operate correctly.
+func (p *Page) ContentSecurityPolicy() string { return p.csp }
+
+// Template returns the html/template.Template for rendering the page in the default language.
+func (p *Page) Template() *template.Template { return p.templates.ForLanguage(i18n.DefaultLanguage) }
+
+// TemplateForContext returns the html/template.Template for rendering the page in the language which was selected
+// for the request by i18n.WithNegotiatedLanguage.
+func (p *Page) TemplateForContext(ctx context.Context) *template.Template {
+	return p.templates.ForContext(ctx)
+}
+
+// CSS returns the minified CSS that will be embedded into the page template.
+func (p *Page) CSS() string { return p.css }
+
 type IdentityProvider struct {
 	DisplayName string
 	URL         string
@@ -1,15 +1,20 @@
-// Copyright 2023 the Pinniped contributors. All Rights Reserved.
+// Copyright 2023-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package chooseidphtml
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"testing"
 
 	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
 
+	"go.pinniped.dev/internal/federationdomain/branding"
+	"go.pinniped.dev/internal/federationdomain/csp"
+	"go.pinniped.dev/internal/federationdomain/i18n"
 	"go.pinniped.dev/internal/testutil"
 )
 
@@ -52,6 +57,21 @@ func TestTemplate(t *testing.T) {
 	require.Equal(t, expectedHTML, buf.String())
 }
 
+func TestTemplateForContext(t *testing.T) {
+	page := DefaultPage()
+	require.Same(t, Template(), page.Template())
+	require.Same(t, Template(), page.TemplateForContext(context.Background()))
+
+	var buf bytes.Buffer
+	require.NoError(t, page.TemplateForContext(i18n.WithCatalog(context.Background(), i18n.ForLanguage("ja"))).Execute(&buf, &PageData{
+		IdentityProviders: []IdentityProvider{{DisplayName: "test-idp-name", URL: "https://pinniped.dev/path"}},
+	}))
+	require.Contains(t, buf.String(), `<html lang="ja">`)
+	require.Contains(t, buf.String(), `<title>IDプロバイダーの選択</title>`)
+	require.Contains(t, buf.String(), `<h1>ログインに使用するIDプロバイダーを選択してください</h1>`)
+	require.Contains(t, buf.String(), `<span>test-idp-name</span>`)
+}
+
 func TestContentSecurityPolicy(t *testing.T) {
 	require.Equal(t, testExpectedCSP, ContentSecurityPolicy())
 }
@@ -68,3 +88,42 @@ func TestHelpers(t *testing.T) {
 	require.Equal(t, "test", panicOnError("test", nil))
 	require.PanicsWithError(t, "some error", func() { panicOnError("", fmt.Errorf("some error")) })
 }
+
+func TestNewPage(t *testing.T) {
+	page, err := NewPage(nil)
+	require.NoError(t, err)
+	require.Same(t, DefaultPage(), page)
+	require.Equal(t, testExpectedCSP, DefaultPage().ContentSecurityPolicy())
+
+	b, err := branding.FromConfigMap(&corev1.ConfigMap{
+		Data: map[string]string{
+			"primaryColor": "#123456",
+			"helpText":     "Contact the help desk.",
+		},
+	})
+	require.NoError(t, err)
+	page, err = NewPage(b)
+	require.NoError(t, err)
+	require.Contains(t, page.CSS(), "background-color:#123456")
+	require.Equal(t, `default-src 'none'; `+
+		`script-src 'sha256-eyuE+qQfuMn4WbDizGOp1wSGReaMYRYmRMXpyEo+8ps='; `+
+		`style-src '`+csp.Hash(page.CSS())+`'; `+
+		`img-src data:; `+
+		`frame-ancestors 'none'`, page.ContentSecurityPolicy())
+
+	var buf bytes.Buffer
+	require.NoError(t, page.Template().Execute(&buf, &PageData{
+		IdentityProviders: []IdentityProvider{{DisplayName: "idp", URL: "https://pinniped.dev/path"}},
+	}))
+	require.Contains(t, buf.String(), `<p class="branding-help">Contact the help desk.</p>`)
+	require.NoError(t, branding.CheckRenderedPage(buf.String(), []string{JS()}, []string{page.CSS()}))
+
+	b, err = branding.FromConfigMap(&corev1.ConfigMap{
+		Data: map[string]string{"choose_idp.gohtml": `<html><body><a href="javascript:alert(1)">x</a></body></html>`},
+	})
+	require.NoError(t, err)
+	page, err = NewPage(b)
+	require.EqualError(t, err, `template "choose_idp.gohtml" is not compatible with the Content-Security-Policy of the page: `+
+		`javascript: URL in attribute "href" on <a> is not allowed`)
+	require.Nil(t, page)
+}
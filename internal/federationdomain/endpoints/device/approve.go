@@ -0,0 +1,92 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package device
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/ory/fosite"
+
+	"go.pinniped.dev/internal/auditevent"
+	"go.pinniped.dev/internal/federationdomain/downstreamsession"
+	"go.pinniped.dev/internal/federationdomain/endpoints/device/devicehtml"
+	"go.pinniped.dev/internal/federationdomain/resolvedprovider"
+	"go.pinniped.dev/internal/fositestorage/devicecode"
+	"go.pinniped.dev/internal/httputil/httperr"
+	"go.pinniped.dev/internal/plog"
+)
+
+// ApproveAfterLogin is used by the endpoints which finish a browser-based login with an upstream IDP when that login
+// was started by the device verification page. Instead of issuing an authorization code, it creates the downstream
+// session for the user and approves the device authorization request which is identified by the user code signature.
+// The device may then redeem its device code at the token endpoint. Finally, it shows a page to tell the user that
+// they are done.
+func ApproveAfterLogin(
+	w http.ResponseWriter,
+	r *http.Request,
+	storage UserCodeStorage,
+	userCodeSignature string,
+	identity *resolvedprovider.Identity,
+	loginExtras *resolvedprovider.IdentityLoginExtras,
+	idp resolvedprovider.FederationDomainResolvedIdentityProvider,
+	auditLogger plog.AuditLogger,
+) error {
+	deviceRequester, err := storage.GetDeviceCodeSessionByUserCode(r.Context(), userCodeSignature)
+	if err != nil {
+		if errors.Is(err, fosite.ErrNotFound) {
+			return httperr.New(http.StatusBadRequest, "device code session not found or already used")
+		}
+		plog.Error("error reading device code session", err)
+		return httperr.New(http.StatusInternalServerError, "error reading device code session")
+	}
+
+	// The user could have taken a long time to log in with the upstream IDP, so check again.
+	if err := validateUnusedUserCode(deviceRequester); err != nil {
+		plog.InfoErr("user code cannot be used", err)
+		return httperr.New(http.StatusBadRequest, "device code session has expired or was already used")
+	}
+
+	// Automatically grant certain scopes, but only if they were requested. Note that NewDeviceRequest would have
+	// returned an error if the client requested a scope that it is not allowed to request.
+	downstreamsession.AutoApproveScopes(deviceRequester)
+
+	session, err := downstreamsession.NewPinnipedSession(r.Context(), auditLogger, &downstreamsession.SessionConfig{
+		UpstreamIdentity:    identity,
+		UpstreamLoginExtras: loginExtras,
+		ClientID:            deviceRequester.GetClient().GetID(),
+		GrantedScopes:       deviceRequester.GetGrantedScopes(),
+		IdentityProvider:    idp,
+		SessionIDGetter:     deviceRequester,
+	})
+	if err != nil {
+		plog.WarningErr("unable to create a Pinniped session", err,
+			"identityProviderDisplayName", idp.GetDisplayName(),
+			"identityProviderResourceName", idp.GetProvider().GetResourceName())
+		return httperr.Wrap(http.StatusUnprocessableEntity, err.Error(), err)
+	}
+
+	// Keep the expiration times that were decided by the device authorization endpoint.
+	previousSession := deviceRequester.GetSession()
+	session.SetExpiresAt(fosite.DeviceCode, previousSession.GetExpiresAt(fosite.DeviceCode))
+	session.SetExpiresAt(fosite.UserCode, previousSession.GetExpiresAt(fosite.UserCode))
+	deviceRequester.SetSession(session)
+
+	if err := storage.ApproveDeviceCodeSession(r.Context(), userCodeSignature, deviceRequester); err != nil {
+		if errors.Is(err, devicecode.ErrUserCodeAlreadyUsed) || errors.Is(err, fosite.ErrNotFound) {
+			return httperr.New(http.StatusBadRequest, "device code session has expired or was already used")
+		}
+		plog.Error("error approving device code session", err)
+		return httperr.New(http.StatusInternalServerError, "error approving device code session")
+	}
+
+	auditLogger.Audit(auditevent.DeviceAuthorizationApproved, &plog.AuditParams{
+		ReqCtx:  r.Context(),
+		Session: deviceRequester,
+	})
+
+	// The endpoints which call this function use a CSP for their own pages, so replace it with the CSP for this page.
+	w.Header().Set("Content-Security-Policy", devicehtml.ContentSecurityPolicy())
+	return devicehtml.Template().Execute(w, &devicehtml.PageData{Approved: true})
+}
@@ -0,0 +1,123 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package device
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ory/fosite"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"go.pinniped.dev/internal/federationdomain/endpoints/device/devicehtml"
+	"go.pinniped.dev/internal/federationdomain/oidc"
+	"go.pinniped.dev/internal/federationdomain/resolvedprovider"
+	"go.pinniped.dev/internal/fositestorage/devicecode"
+	"go.pinniped.dev/internal/httputil/httperr"
+	"go.pinniped.dev/internal/plog"
+	"go.pinniped.dev/internal/psession"
+)
+
+func TestApproveAfterLogin(t *testing.T) {
+	identity := &resolvedprovider.Identity{
+		UpstreamUsername:  "some-upstream-username",
+		UpstreamGroups:    []string{"group1", "group2"},
+		DownstreamSubject: "https://my-upstream-issuer.com?idpName=upstream-oidc-idp-name&sub=some-subject",
+		IDPSpecificSessionData: &psession.OIDCSessionData{
+			UpstreamRefreshToken: "some-upstream-refresh-token",
+			UpstreamIssuer:       "https://my-upstream-issuer.com",
+			UpstreamSubject:      "some-subject",
+		},
+	}
+
+	tests := []struct {
+		name                 string
+		useUnknownSignature  bool
+		approveFirst         bool
+		wantStatus           int
+		wantBodyContains     string
+		wantApprovedUsername string
+	}{
+		{
+			name:                 "happy path approves the device authorization request",
+			wantStatus:           http.StatusOK,
+			wantBodyContains:     "You have successfully logged in.",
+			wantApprovedUsername: "some-upstream-username",
+		},
+		{
+			name:                "device authorization request not found",
+			useUnknownSignature: true,
+			wantStatus:          http.StatusBadRequest,
+			wantBodyContains:    "Bad Request: device code session not found or already used",
+		},
+		{
+			name:             "device authorization request was already approved",
+			approveFirst:     true,
+			wantStatus:       http.StatusBadRequest,
+			wantBodyContains: "Bad Request: device code session not found or already used",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			fixture := newDeviceTestFixture(t)
+			idpLister := newTestIDPs().BuildFederationDomainIdentityProvidersListerFinder()
+			idp, err := idpLister.FindUpstreamIDPByDisplayName(upstreamOIDCIDPName)
+			require.NoError(t, err)
+
+			userCode := startDeviceAuthorization(t, fixture)
+			deviceStrategy := oidc.DeviceStrategy(downstreamIssuer, fixture.hmacSecret, oidc.DefaultOIDCTimeoutsConfiguration())
+			userCodeSignature, err := deviceStrategy.UserCodeSignature(t.Context(), userCode)
+			require.NoError(t, err)
+
+			if test.approveFirst {
+				requester, err := fixture.oauthStore.GetDeviceCodeSessionByUserCode(t.Context(), userCodeSignature)
+				require.NoError(t, err)
+				require.NoError(t, fixture.oauthStore.ApproveDeviceCodeSession(t.Context(), userCodeSignature, requester))
+			}
+
+			signatureToApprove := userCodeSignature
+			if test.useUnknownSignature {
+				signatureToApprove = "some-unknown-signature"
+			}
+
+			auditLogger, _ := plog.TestAuditLogger(t)
+			subject := httperr.HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+				return ApproveAfterLogin(w, r, fixture.oauthStore, signatureToApprove, identity, &resolvedprovider.IdentityLoginExtras{}, idp, auditLogger)
+			})
+			req := httptest.NewRequestWithContext(t.Context(), http.MethodGet, "/callback", nil)
+			rsp := httptest.NewRecorder()
+			subject.ServeHTTP(rsp, req)
+			t.Logf("response body: %q", rsp.Body.String())
+
+			require.Equal(t, test.wantStatus, rsp.Code)
+			require.Contains(t, rsp.Body.String(), test.wantBodyContains)
+			if test.wantApprovedUsername == "" {
+				return
+			}
+			require.Equal(t, devicehtml.ContentSecurityPolicy(), rsp.Header().Get("Content-Security-Policy"))
+
+			// The user code can only be used once.
+			_, err = fixture.oauthStore.GetDeviceCodeSessionByUserCode(t.Context(), userCodeSignature)
+			require.ErrorIs(t, err, fosite.ErrNotFound)
+
+			// The device code session was updated with the user's identity, so the device may now redeem its device code.
+			deviceCodeSecrets, err := fixture.secrets.List(t.Context(), metav1.ListOptions{LabelSelector: "storage.pinniped.dev/type=device-code"})
+			require.NoError(t, err)
+			require.Len(t, deviceCodeSecrets.Items, 1)
+			storedSession, err := devicecode.ReadFromSecret(&deviceCodeSecrets.Items[0])
+			require.NoError(t, err)
+			requester := storedSession.Request
+			require.Equal(t, fosite.UserCodeAccepted, storedSession.UserCodeState)
+			session, ok := requester.GetSession().(*psession.PinnipedSession)
+			require.True(t, ok)
+			require.Equal(t, test.wantApprovedUsername, session.Custom.Username)
+			require.Equal(t, identity.DownstreamSubject, session.Fosite.Claims.Subject)
+			require.True(t, requester.GetGrantedScopes().Has("openid"))
+			require.True(t, requester.GetGrantedScopes().Has("offline_access"))
+		})
+	}
+}
@@ -0,0 +1,81 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package device provides the handlers for the OAuth 2.0 Device Authorization Grant (RFC 8628).
+package device
+
+import (
+	"net/http"
+	"net/url"
+
+	"github.com/ory/fosite"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	oidcapi "go.pinniped.dev/generated/latest/apis/supervisor/oidc"
+	"go.pinniped.dev/internal/federationdomain/federationdomainproviders"
+	"go.pinniped.dev/internal/federationdomain/oidc"
+	"go.pinniped.dev/internal/plog"
+	"go.pinniped.dev/internal/psession"
+)
+
+func authorizationParamsSafeToLog() sets.Set[string] {
+	return sets.New[string](
+		// Standard params from https://datatracker.ietf.org/doc/html/rfc8628#section-3.1.
+		"client_id", "scope",
+		// Custom Pinniped authorization params.
+		oidcapi.AuthorizeUpstreamIDPNameParamName, oidcapi.AuthorizeUpstreamIDPTypeParamName,
+	)
+}
+
+// NewAuthorizationHandler returns a http.Handler that serves the device authorization endpoint.
+// See https://datatracker.ietf.org/doc/html/rfc8628#section-3.1.
+//
+// The client may optionally use the pinniped_idp_name param to choose an upstream IDP. When it does,
+// the IDP name will be added to the verification_uri_complete in the response, so the user will
+// not need to choose an IDP when they visit that URL.
+func NewAuthorizationHandler(
+	idpFinder federationdomainproviders.FederationDomainIdentityProvidersFinderI,
+	oauthHelper fosite.OAuth2Provider,
+	auditLogger plog.AuditLogger,
+) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := auditLogger.AuditRequestParams(r, authorizationParamsSafeToLog()); err != nil {
+			oauthHelper.WriteAccessError(r.Context(), w, nil, err)
+			return
+		}
+
+		// This will check that the request is a POST, authenticate the client, and validate the requested scopes.
+		deviceRequester, err := oauthHelper.NewDeviceRequest(r.Context(), r)
+		if err != nil {
+			plog.Info("device authorization request error", oidc.FositeErrorForLog(err)...)
+			oauthHelper.WriteAccessError(r.Context(), w, nil, err)
+			return
+		}
+
+		idpName := r.PostForm.Get(oidcapi.AuthorizeUpstreamIDPNameParamName)
+		if idpName != "" {
+			if _, err := idpFinder.FindUpstreamIDPByDisplayName(idpName); err != nil {
+				oauthHelper.WriteAccessError(r.Context(), w, nil,
+					fosite.ErrInvalidRequest.
+						WithHintf("%q param error: %s", oidcapi.AuthorizeUpstreamIDPNameParamName, err.Error()).
+						WithWrap(err).WithDebug(err.Error()))
+				return
+			}
+		}
+
+		// This generates the device code and the user code, and saves them to storage.
+		deviceResponder, err := oauthHelper.NewDeviceResponse(r.Context(), deviceRequester, psession.NewPinnipedSession())
+		if err != nil {
+			plog.Info("device authorization response error", oidc.FositeErrorForLog(err)...)
+			oauthHelper.WriteAccessError(r.Context(), w, nil, err)
+			return
+		}
+
+		if idpName != "" {
+			deviceResponder.SetVerificationURIComplete(deviceResponder.GetVerificationURIComplete() +
+				"&" + oidcapi.AuthorizeUpstreamIDPNameParamName + "=" + url.QueryEscape(idpName))
+		}
+
+		oauthHelper.WriteDeviceResponse(r.Context(), w, deviceRequester, deviceResponder)
+	})
+}
@@ -0,0 +1,263 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package device
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/ory/fosite"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+
+	supervisorfake "go.pinniped.dev/generated/latest/client/supervisor/clientset/versioned/fake"
+	"go.pinniped.dev/internal/auditid"
+	"go.pinniped.dev/internal/federationdomain/endpoints/jwks"
+	"go.pinniped.dev/internal/federationdomain/oidc"
+	"go.pinniped.dev/internal/federationdomain/storage"
+	"go.pinniped.dev/internal/plog"
+	"go.pinniped.dev/internal/testutil"
+	"go.pinniped.dev/internal/testutil/oidctestutil"
+	"go.pinniped.dev/internal/testutil/testidplister"
+)
+
+const (
+	downstreamIssuer = "https://my-downstream-issuer.com/path"
+
+	upstreamOIDCIDPName        = "upstream-oidc-idp-name"
+	upstreamOIDCIDPResourceUID = "upstream-oidc-resource-uid"
+)
+
+// deviceTestFixture holds the storage and fosite configuration used by the device endpoint tests, which is configured
+// in the same way as the production code.
+type deviceTestFixture struct {
+	kubeClient  *kubefake.Clientset
+	secrets     corev1client.SecretInterface
+	oauthStore  *storage.KubeStorage
+	oauthHelper fosite.OAuth2Provider
+	hmacSecret  func() []byte
+}
+
+func newDeviceTestFixture(t *testing.T) *deviceTestFixture {
+	t.Helper()
+
+	kubeClient := kubefake.NewClientset()
+	supervisorClient := supervisorfake.NewSimpleClientset()
+	secrets := kubeClient.CoreV1().Secrets("some-namespace")
+	oidcClientsClient := supervisorClient.ConfigV1alpha1().OIDCClients("some-namespace")
+
+	timeoutsConfiguration := oidc.DefaultOIDCTimeoutsConfiguration()
+	// Use lower minimum required bcrypt cost than we would use in production to keep unit the tests fast.
+	oauthStore := storage.NewKubeStorage(secrets, oidcClientsClient, timeoutsConfiguration, bcrypt.MinCost)
+	hmacSecretFunc := func() []byte { return []byte("some secret - must have at least 32 bytes") }
+	require.GreaterOrEqual(t, len(hmacSecretFunc()), 32, "fosite requires that hmac secrets have at least 32 bytes")
+	jwksProviderIsUnused := jwks.NewDynamicJWKSProvider()
+
+	return &deviceTestFixture{
+		kubeClient:  kubeClient,
+		secrets:     secrets,
+		oauthStore:  oauthStore,
+		oauthHelper: oidc.FositeOauth2Helper(oauthStore, downstreamIssuer, hmacSecretFunc, jwksProviderIsUnused, timeoutsConfiguration),
+		hmacSecret:  hmacSecretFunc,
+	}
+}
+
+func newTestIDPs() *testidplister.UpstreamIDPListerBuilder {
+	return testidplister.NewUpstreamIDPListerBuilder().WithOIDC(
+		oidctestutil.NewTestUpstreamOIDCIdentityProviderBuilder().
+			WithName(upstreamOIDCIDPName).
+			WithResourceUID(upstreamOIDCIDPResourceUID).
+			WithClientID("some-upstream-client-id").
+			WithAuthorizationURL(url.URL{Scheme: "https", Host: "my-upstream-issuer.com", Path: "/authorize"}).
+			WithScopes([]string{"scope1", "scope2"}).
+			Build(),
+	)
+}
+
+type deviceAuthorizationResponse struct {
+	DeviceCode              string `json:"device_code"`
+	UserCode                string `json:"user_code"`
+	VerificationURI         string `json:"verification_uri"`
+	VerificationURIComplete string `json:"verification_uri_complete"`
+	ExpiresIn               int64  `json:"expires_in"`
+	Interval                int    `json:"interval"`
+}
+
+func TestDeviceAuthorizationHandler(t *testing.T) {
+	tests := []struct {
+		name             string
+		method           string
+		body             string
+		idps             *testidplister.UpstreamIDPListerBuilder
+		wantStatus       int
+		wantErrorJSON    string
+		wantIDPNameInURI string
+		wantAuditLogs    []testutil.WantedAuditLog
+	}{
+		{
+			name:       "happy path",
+			method:     http.MethodPost,
+			body:       "client_id=pinniped-cli&scope=openid+offline_access+username+groups",
+			idps:       newTestIDPs(),
+			wantStatus: http.StatusOK,
+			wantAuditLogs: []testutil.WantedAuditLog{
+				testutil.WantAuditLog("HTTP Request Parameters", map[string]any{
+					"params": map[string]any{
+						"client_id": "pinniped-cli",
+						"scope":     "openid offline_access username groups",
+					},
+				}),
+			},
+		},
+		{
+			name:             "happy path with an IDP name, which is added to the verification URI",
+			method:           http.MethodPost,
+			body:             "client_id=pinniped-cli&scope=openid&pinniped_idp_name=" + upstreamOIDCIDPName,
+			idps:             newTestIDPs(),
+			wantStatus:       http.StatusOK,
+			wantIDPNameInURI: upstreamOIDCIDPName,
+			wantAuditLogs: []testutil.WantedAuditLog{
+				testutil.WantAuditLog("HTTP Request Parameters", map[string]any{
+					"params": map[string]any{
+						"client_id":         "pinniped-cli",
+						"scope":             "openid",
+						"pinniped_idp_name": upstreamOIDCIDPName,
+					},
+				}),
+			},
+		},
+		{
+			name:       "IDP name does not exist",
+			method:     http.MethodPost,
+			body:       "client_id=pinniped-cli&scope=openid&pinniped_idp_name=does-not-exist",
+			idps:       newTestIDPs(),
+			wantStatus: http.StatusBadRequest,
+			wantErrorJSON: `{"error":"invalid_request","error_description":"The request is missing a required parameter, ` +
+				`includes an invalid parameter value, includes a parameter more than once, or is otherwise malformed. ` +
+				`'pinniped_idp_name' param error: did not find IDP with name 'does-not-exist'"}`,
+			wantAuditLogs: []testutil.WantedAuditLog{
+				testutil.WantAuditLog("HTTP Request Parameters", map[string]any{
+					"params": map[string]any{
+						"client_id":         "pinniped-cli",
+						"scope":             "openid",
+						"pinniped_idp_name": "does-not-exist",
+					},
+				}),
+			},
+		},
+		{
+			name:       "unknown client",
+			method:     http.MethodPost,
+			body:       "client_id=does-not-exist&scope=openid",
+			idps:       newTestIDPs(),
+			wantStatus: http.StatusUnauthorized,
+			wantErrorJSON: `{"error":"invalid_client","error_description":"Client authentication failed ` +
+				`(e.g., unknown client, no client authentication included, or unsupported authentication method)."}`,
+			wantAuditLogs: []testutil.WantedAuditLog{
+				testutil.WantAuditLog("HTTP Request Parameters", map[string]any{
+					"params": map[string]any{
+						"client_id": "does-not-exist",
+						"scope":     "openid",
+					},
+				}),
+			},
+		},
+		{
+			name:       "scope not allowed for client",
+			method:     http.MethodPost,
+			body:       "client_id=pinniped-cli&scope=openid+not-a-real-scope",
+			idps:       newTestIDPs(),
+			wantStatus: http.StatusBadRequest,
+			wantErrorJSON: `{"error":"invalid_scope","error_description":"The requested scope is invalid, unknown, or malformed. ` +
+				`The OAuth 2.0 Client is not allowed to request scope 'not-a-real-scope'."}`,
+			wantAuditLogs: []testutil.WantedAuditLog{
+				testutil.WantAuditLog("HTTP Request Parameters", map[string]any{
+					"params": map[string]any{
+						"client_id": "pinniped-cli",
+						"scope":     "openid not-a-real-scope",
+					},
+				}),
+			},
+		},
+		{
+			name:       "GET is not allowed",
+			method:     http.MethodGet,
+			idps:       newTestIDPs(),
+			wantStatus: http.StatusBadRequest,
+			wantErrorJSON: `{"error":"invalid_request","error_description":"The request is missing a required parameter, ` +
+				`includes an invalid parameter value, includes a parameter more than once, or is otherwise malformed. ` +
+				`HTTP method is 'GET', expected 'POST'."}`,
+			wantAuditLogs: []testutil.WantedAuditLog{
+				testutil.WantAuditLog("HTTP Request Parameters", map[string]any{
+					"params": map[string]any{},
+				}),
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			fixture := newDeviceTestFixture(t)
+			auditLogger, actualAuditLog := plog.TestAuditLogger(t)
+
+			subject := NewAuthorizationHandler(
+				test.idps.BuildFederationDomainIdentityProvidersListerFinder(),
+				fixture.oauthHelper,
+				auditLogger,
+			)
+
+			req := httptest.NewRequestWithContext(t.Context(), test.method, "/oauth2/device_authorization", strings.NewReader(test.body))
+			if test.body != "" {
+				req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			}
+			req, _ = auditid.NewRequestWithAuditID(req, func() string { return "fake-audit-id" })
+			rsp := httptest.NewRecorder()
+			subject.ServeHTTP(rsp, req)
+			t.Logf("response: %#v", rsp)
+			t.Logf("response body: %q", rsp.Body.String())
+
+			require.Equal(t, test.wantStatus, rsp.Code)
+			testutil.RequireEqualContentType(t, rsp.Header().Get("Content-Type"), "application/json")
+			testutil.WantAuditIDOnEveryAuditLog(test.wantAuditLogs, "fake-audit-id")
+			testutil.CompareAuditLogs(t, test.wantAuditLogs, actualAuditLog.String())
+
+			storedSecrets, err := fixture.secrets.List(t.Context(), metav1.ListOptions{})
+			require.NoError(t, err)
+
+			if test.wantErrorJSON != "" {
+				require.JSONEq(t, test.wantErrorJSON, rsp.Body.String())
+				require.Empty(t, storedSecrets.Items, "should not have stored anything")
+				return
+			}
+
+			var response deviceAuthorizationResponse
+			require.NoError(t, json.Unmarshal(rsp.Body.Bytes(), &response))
+
+			require.True(t, strings.HasPrefix(response.DeviceCode, "pin_dc_"), "device code %q did not have expected prefix", response.DeviceCode)
+			require.Len(t, response.UserCode, oidc.UserCodeLength)
+			require.Empty(t, strings.Trim(response.UserCode, oidc.UserCodeSymbols), "user code %q had unexpected characters", response.UserCode)
+			require.Equal(t, downstreamIssuer+oidc.DeviceVerificationEndpointPath, response.VerificationURI)
+			wantURIComplete := downstreamIssuer + oidc.DeviceVerificationEndpointPath + "?user_code=" + response.UserCode
+			if test.wantIDPNameInURI != "" {
+				wantURIComplete += "&pinniped_idp_name=" + url.QueryEscape(test.wantIDPNameInURI)
+			}
+			require.Equal(t, wantURIComplete, response.VerificationURIComplete)
+			require.InDelta(t, oidc.DefaultOIDCTimeoutsConfiguration().DeviceAndUserCodeLifespan.Seconds(), response.ExpiresIn, 2)
+			require.Equal(t, int(oidc.DefaultOIDCTimeoutsConfiguration().DeviceAuthorizationPollingInterval.Seconds()), response.Interval)
+
+			// Stored one device code session and one user code session.
+			require.Len(t, storedSecrets.Items, 2)
+			testutil.RequireNumberOfSecretsMatchingLabelSelector(t, fixture.secrets, labels.Set{"storage.pinniped.dev/type": "device-code"}, 1)
+			testutil.RequireNumberOfSecretsMatchingLabelSelector(t, fixture.secrets, labels.Set{"storage.pinniped.dev/type": "user-code"}, 1)
+		})
+	}
+}
@@ -0,0 +1,364 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package device
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ory/fosite"
+	"github.com/ory/fosite/handler/rfc8628"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	oidcapi "go.pinniped.dev/generated/latest/apis/supervisor/oidc"
+	"go.pinniped.dev/internal/auditevent"
+	"go.pinniped.dev/internal/federationdomain/csrftoken"
+	"go.pinniped.dev/internal/federationdomain/endpoints/device/devicehtml"
+	"go.pinniped.dev/internal/federationdomain/federationdomainproviders"
+	"go.pinniped.dev/internal/federationdomain/oidc"
+	"go.pinniped.dev/internal/federationdomain/resolvedprovider"
+	"go.pinniped.dev/internal/federationdomain/stateparam"
+	"go.pinniped.dev/internal/httputil/httperr"
+	"go.pinniped.dev/internal/httputil/securityheader"
+	"go.pinniped.dev/internal/plog"
+	"go.pinniped.dev/pkg/oidcclient/nonce"
+	"go.pinniped.dev/pkg/oidcclient/pkce"
+)
+
+const (
+	userCodeParamName = "user_code"
+	csrfParamName     = "csrf"
+	confirmParamName  = "confirm"
+
+	confirmApprove = "approve"
+	confirmDeny    = "deny"
+
+	invalidUserCodeMessage  = "The code was not found or has expired. Please check the code shown on your device and try again."
+	chooseIDPMessage        = "Please use the complete link shown on your device, which chooses the identity provider for this login."
+	tooManyAttemptsMessage  = "Too many codes were tried. Please wait a few minutes and try again."
+	userCodeRejectedMessage = "This code was tried too many times and can no longer be used. Please start a new login on your device."
+)
+
+// UserCodeStorage is the storage needed by the device verification page to find and approve
+// device authorization requests using their user codes.
+type UserCodeStorage interface {
+	GetDeviceCodeSessionByUserCode(ctx context.Context, userCodeSignature string) (fosite.DeviceRequester, error)
+	ApproveDeviceCodeSession(ctx context.Context, userCodeSignature string, requester fosite.DeviceRequester) error
+	RejectDeviceCodeSession(ctx context.Context, userCodeSignature string) error
+}
+
+func verificationParamsSafeToLog() sets.Set[string] {
+	return sets.New[string](
+		// Custom Pinniped authorization params. Note that the user_code param is not safe to log.
+		oidcapi.AuthorizeUpstreamIDPNameParamName,
+	)
+}
+
+type verificationHandler struct {
+	downstreamIssuerURL  string
+	postPath             string
+	idpFinder            federationdomainproviders.FederationDomainIdentityProvidersFinderI
+	storage              UserCodeStorage
+	deviceStrategy       rfc8628.RFC8628CodeStrategy
+	generateCSRF         func() (csrftoken.CSRFToken, error)
+	generatePKCE         func() (pkce.Code, error)
+	generateNonce        func() (nonce.Nonce, error)
+	upstreamStateEncoder oidc.Encoder
+	cookieCodec          oidc.Codec
+	limiter              *VerificationLimiter
+	auditLogger          plog.AuditLogger
+}
+
+// NewVerificationHandler returns a http.Handler that serves the device verification page, which is the
+// verification_uri of RFC 8628. See https://datatracker.ietf.org/doc/html/rfc8628#section-3.3.
+//
+// A GET shows a form where the user can enter the user code that is shown on their device. The form is pre-filled
+// when the user_code query param is present, which is how verification_uri_complete works.
+// A POST of that form looks up the device authorization request for the user code, and then shows a page which names
+// the client and the scopes of the request, so the user can confirm that it is their own device which is asking to
+// log in. See https://datatracker.ietf.org/doc/html/rfc8628#section-5.4. When the user denies the login, the device
+// authorization request is rejected. When the user approves, a browser-based login with the upstream IDP is started
+// in the same way that the authorization endpoint does. When that login finishes at the callback endpoint or the
+// login endpoint, the device authorization request is approved by ApproveAfterLogin.
+//
+// The limiter limits how often user codes may be tried, so they cannot be guessed.
+func NewVerificationHandler(
+	downstreamIssuerURL string,
+	postPath string,
+	idpFinder federationdomainproviders.FederationDomainIdentityProvidersFinderI,
+	storage UserCodeStorage,
+	deviceStrategy rfc8628.RFC8628CodeStrategy,
+	generateCSRF func() (csrftoken.CSRFToken, error),
+	generatePKCE func() (pkce.Code, error),
+	generateNonce func() (nonce.Nonce, error),
+	upstreamStateEncoder oidc.Encoder,
+	cookieCodec oidc.Codec,
+	limiter *VerificationLimiter,
+	auditLogger plog.AuditLogger,
+) http.Handler {
+	h := &verificationHandler{
+		downstreamIssuerURL:  downstreamIssuerURL,
+		postPath:             postPath,
+		idpFinder:            idpFinder,
+		storage:              storage,
+		deviceStrategy:       deviceStrategy,
+		generateCSRF:         generateCSRF,
+		generatePKCE:         generatePKCE,
+		generateNonce:        generateNonce,
+		upstreamStateEncoder: upstreamStateEncoder,
+		cookieCodec:          cookieCodec,
+		limiter:              limiter,
+		auditLogger:          auditLogger,
+	}
+	return securityheader.WrapWithCustomCSP(httperr.HandlerFunc(h.serveHTTP), devicehtml.ContentSecurityPolicy())
+}
+
+func (h *verificationHandler) serveHTTP(w http.ResponseWriter, r *http.Request) error {
+	r.Body = http.MaxBytesReader(w, r.Body, 1<<20) // 1 MB limit
+
+	if err := h.auditLogger.AuditRequestParams(r, verificationParamsSafeToLog()); err != nil {
+		plog.DebugErr("error parsing device verification request params", err)
+		return httperr.New(http.StatusBadRequest, "error parsing request params")
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		return h.get(w, r)
+	case http.MethodPost:
+		return h.post(w, r)
+	default:
+		return httperr.Newf(http.StatusMethodNotAllowed, "%s (try GET or POST)", r.Method)
+	}
+}
+
+func (h *verificationHandler) get(w http.ResponseWriter, r *http.Request) error {
+	csrfValue, err := h.csrfValue(w, r)
+	if err != nil {
+		return err
+	}
+
+	return h.renderPage(w, r, http.StatusOK, &devicehtml.PageData{CSRFToken: string(csrfValue)})
+}
+
+func (h *verificationHandler) post(w http.ResponseWriter, r *http.Request) error {
+	// Use the double-submit pattern to prevent a malicious site from submitting a user code on behalf of the user.
+	csrfFromCookie, err := oidc.ReadCSRFCookie(r, h.cookieCodec)
+	if err != nil {
+		plog.InfoErr("CSRF error", err)
+		return err
+	}
+	if csrfFromCookie == "" || subtle.ConstantTimeCompare([]byte(r.PostForm.Get(csrfParamName)), []byte(csrfFromCookie)) != 1 {
+		return httperr.New(http.StatusForbidden, "CSRF value does not match")
+	}
+
+	if h.limiter.sourceLimited(r) {
+		plog.Info("too many user codes were tried by the same source", "source", requestSource(r))
+		return h.renderForm(w, r, http.StatusTooManyRequests, csrfFromCookie, tooManyAttemptsMessage)
+	}
+
+	userCode := normalizeUserCode(r.PostForm.Get(userCodeParamName))
+	if userCode == "" {
+		return h.renderForm(w, r, http.StatusOK, csrfFromCookie, invalidUserCodeMessage)
+	}
+
+	userCodeSignature, err := h.deviceStrategy.UserCodeSignature(r.Context(), userCode)
+	if err != nil {
+		plog.Error("error calculating user code signature", err)
+		return httperr.New(http.StatusInternalServerError, "error calculating user code signature")
+	}
+
+	deviceRequester, err := h.storage.GetDeviceCodeSessionByUserCode(r.Context(), userCodeSignature)
+	if err != nil {
+		if errors.Is(err, fosite.ErrNotFound) {
+			h.limiter.recordSourceFailure(r)
+			return h.renderForm(w, r, http.StatusOK, csrfFromCookie, invalidUserCodeMessage)
+		}
+		plog.Error("error reading device code session", err)
+		return httperr.New(http.StatusInternalServerError, "error reading device code session")
+	}
+
+	if err := validateUnusedUserCode(deviceRequester); err != nil {
+		plog.InfoErr("user code cannot be used", err)
+		h.limiter.recordSourceFailure(r)
+		return h.renderForm(w, r, http.StatusOK, csrfFromCookie, invalidUserCodeMessage)
+	}
+
+	if h.limiter.recordUserCodeAttempt(userCodeSignature) {
+		plog.Info("user code was tried too many times, so it will be rejected", "requestID", deviceRequester.GetID())
+		if err := h.storage.RejectDeviceCodeSession(r.Context(), userCodeSignature); err != nil && !errors.Is(err, fosite.ErrNotFound) {
+			plog.Error("error rejecting device code session", err)
+			return httperr.New(http.StatusInternalServerError, "error rejecting device code session")
+		}
+		return h.renderForm(w, r, http.StatusTooManyRequests, csrfFromCookie, userCodeRejectedMessage)
+	}
+
+	idp, err := h.chooseUpstreamIDP(r.PostForm.Get(oidcapi.AuthorizeUpstreamIDPNameParamName))
+	if err != nil {
+		plog.InfoErr("could not choose upstream IDP for device login", err)
+		return h.renderForm(w, r, http.StatusOK, csrfFromCookie, chooseIDPMessage)
+	}
+
+	switch r.PostForm.Get(confirmParamName) {
+	case "":
+		// Ask the user to confirm that the device which is asking to log in is their own.
+		return h.renderPage(w, r, http.StatusOK, &devicehtml.PageData{
+			CSRFToken: string(csrfFromCookie),
+			Confirm:   true,
+			ClientID:  deviceRequester.GetClient().GetID(),
+			Scopes:    deviceRequester.GetRequestedScopes(),
+		})
+	case confirmDeny:
+		if err := h.storage.RejectDeviceCodeSession(r.Context(), userCodeSignature); err != nil {
+			if errors.Is(err, fosite.ErrNotFound) {
+				return h.renderForm(w, r, http.StatusOK, csrfFromCookie, invalidUserCodeMessage)
+			}
+			plog.Error("error rejecting device code session", err)
+			return httperr.New(http.StatusInternalServerError, "error rejecting device code session")
+		}
+		plog.Info("user denied the device login", "requestID", deviceRequester.GetID())
+		return h.renderPage(w, r, http.StatusOK, &devicehtml.PageData{Denied: true})
+	case confirmApprove:
+		// Continue below to start the login with the upstream IDP.
+	default:
+		return httperr.Newf(http.StatusBadRequest, "invalid %s param", confirmParamName)
+	}
+
+	h.auditLogger.Audit(auditevent.UsingUpstreamIDP, &plog.AuditParams{
+		ReqCtx:  r.Context(),
+		Session: deviceRequester,
+		KeysAndValues: []any{
+			"displayName", idp.GetDisplayName(),
+			"resourceName", idp.GetProvider().GetResourceName(),
+			"resourceUID", idp.GetProvider().GetResourceUID(),
+			"type", idp.GetSessionProviderType(),
+		},
+	})
+
+	nonceValue, err := h.generateNonce()
+	if err != nil {
+		plog.Error("error generating nonce param", err)
+		return httperr.New(http.StatusInternalServerError, "error generating nonce param")
+	}
+	pkceValue, err := h.generatePKCE()
+	if err != nil {
+		plog.Error("error generating PKCE param", err)
+		return httperr.New(http.StatusInternalServerError, "error generating PKCE param")
+	}
+
+	encodedStateParamValue, err := h.upstreamStateEncoder.Encode(oidc.UpstreamStateParamEncodingName, oidc.UpstreamStateParamData{
+		UpstreamName:            idp.GetDisplayName(),
+		UpstreamType:            string(idp.GetSessionProviderType()),
+		Nonce:                   nonceValue,
+		CSRFToken:               csrfFromCookie,
+		PKCECode:                pkceValue,
+		FormatVersion:           oidc.UpstreamStateParamFormatVersion,
+		DeviceUserCodeSignature: userCodeSignature,
+	})
+	if err != nil {
+		plog.Error("error encoding upstream state param", err)
+		return httperr.New(http.StatusInternalServerError, "error encoding upstream state param")
+	}
+
+	authRequestState := &resolvedprovider.UpstreamAuthorizeRequestState{
+		EncodedStateParam: stateparam.Encoded(encodedStateParamValue),
+		PKCE:              pkceValue,
+		Nonce:             nonceValue,
+	}
+
+	redirectURL, err := idp.UpstreamAuthorizeRedirectURL(authRequestState, h.downstreamIssuerURL)
+	if err != nil {
+		plog.Error("error building upstream authorize redirect URL", err)
+		return httperr.New(http.StatusInternalServerError, "error building upstream authorize redirect URL")
+	}
+
+	if c, ok := idp.(resolvedprovider.UpstreamStateCookieRequirer); ok && c.RequiresUpstreamStateCookie() {
+		oidc.AddUpstreamStateSetCookieHeader(w, authRequestState.EncodedStateParam)
+	}
+
+	h.auditLogger.Audit(auditevent.UpstreamAuthorizeRedirect, &plog.AuditParams{
+		ReqCtx:        r.Context(),
+		KeysAndValues: []any{"authorizeID", authRequestState.EncodedStateParam.AuthorizeID()},
+	})
+
+	http.Redirect(w, r, redirectURL,
+		http.StatusSeeOther, // match fosite and https://tools.ietf.org/id/draft-ietf-oauth-security-topics-18.html#section-4.11
+	)
+
+	return nil
+}
+
+// csrfValue returns the CSRF value from the incoming cookie, or generates a new one and adds a cookie for it.
+func (h *verificationHandler) csrfValue(w http.ResponseWriter, r *http.Request) (csrftoken.CSRFToken, error) {
+	csrfFromCookie, err := oidc.ReadCSRFCookie(r, h.cookieCodec)
+	if err == nil && csrfFromCookie != "" {
+		return csrfFromCookie, nil
+	}
+
+	// The cookie was missing or could not be decoded, so make a new one.
+	csrfValue, err := h.generateCSRF()
+	if err != nil {
+		plog.Error("error generating CSRF token", err)
+		return "", httperr.New(http.StatusInternalServerError, "error generating CSRF token")
+	}
+	if err := oidc.AddCSRFSetCookieHeader(w, csrfValue, h.cookieCodec); err != nil {
+		plog.Error("error setting CSRF cookie", err)
+		return "", httperr.New(http.StatusInternalServerError, "error encoding CSRF cookie")
+	}
+	return csrfValue, nil
+}
+
+func (h *verificationHandler) renderForm(w http.ResponseWriter, r *http.Request, status int, csrfValue csrftoken.CSRFToken, alertMessage string) error {
+	return h.renderPage(w, r, status, &devicehtml.PageData{
+		CSRFToken:     string(csrfValue),
+		HasAlertError: alertMessage != "",
+		AlertMessage:  alertMessage,
+	})
+}
+
+// renderPage fills in the parts of the page data which come from the request, and renders the page.
+func (h *verificationHandler) renderPage(w http.ResponseWriter, r *http.Request, status int, data *devicehtml.PageData) error {
+	data.PostPath = h.postPath
+	data.UserCode = r.Form.Get(userCodeParamName)
+	data.IDPName = r.Form.Get(oidcapi.AuthorizeUpstreamIDPNameParamName)
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	return devicehtml.Template().Execute(w, data)
+}
+
+func (h *verificationHandler) chooseUpstreamIDP(idpDisplayName string) (resolvedprovider.FederationDomainResolvedIdentityProvider, error) {
+	if len(idpDisplayName) == 0 {
+		return h.idpFinder.FindDefaultIDP()
+	}
+	return h.idpFinder.FindUpstreamIDPByDisplayName(idpDisplayName)
+}
+
+// normalizeUserCode allows the user to type the code in lower case, and with the optional dashes and spaces
+// which are sometimes used to make codes easier to read. See https://datatracker.ietf.org/doc/html/rfc8628#section-6.1.
+func normalizeUserCode(userCode string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '-' || r == ' ' {
+			return -1
+		}
+		return r
+	}, strings.ToUpper(strings.TrimSpace(userCode)))
+}
+
+// validateUnusedUserCode returns an error when the user code of the device authorization request was already used,
+// or when it has expired.
+func validateUnusedUserCode(deviceRequester fosite.DeviceRequester) error {
+	if deviceRequester.GetUserCodeState() != fosite.UserCodeUnused {
+		return errors.New("user code was already used")
+	}
+	expiresAt := deviceRequester.GetSession().GetExpiresAt(fosite.UserCode)
+	if expiresAt.IsZero() || expiresAt.Before(time.Now().UTC()) {
+		return fmt.Errorf("user code expired at %s", expiresAt)
+	}
+	return nil
+}
@@ -0,0 +1,549 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package device
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/securecookie"
+	"github.com/ory/fosite"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clocktesting "k8s.io/utils/clock/testing"
+
+	"go.pinniped.dev/internal/auditid"
+	"go.pinniped.dev/internal/federationdomain/csrftoken"
+	"go.pinniped.dev/internal/federationdomain/oidc"
+	"go.pinniped.dev/internal/fositestorage/devicecode"
+	"go.pinniped.dev/internal/plog"
+	"go.pinniped.dev/internal/testutil"
+	"go.pinniped.dev/internal/testutil/oidctestutil"
+	"go.pinniped.dev/internal/testutil/testidplister"
+	"go.pinniped.dev/pkg/oidcclient/nonce"
+	"go.pinniped.dev/pkg/oidcclient/pkce"
+)
+
+func TestDeviceVerificationHandler(t *testing.T) {
+	const (
+		postPath = "/some/path/oauth2/device"
+
+		csrfValueFromCookie = "some-csrf-value-from-cookie"
+		generatedCSRFValue  = "some-newly-generated-csrf-value"
+		generatedPKCEValue  = "some-pkce-value-which-is-at-least-43-characters-long"
+		generatedNonceValue = "some-nonce-value"
+	)
+
+	happyStateCodec := securecookie.New([]byte("secret-key-for-state-param-is-32"), []byte("0123456789ABCDEF"))
+	happyStateCodec.SetSerializer(securecookie.JSONEncoder{})
+
+	happyCookieCodec := securecookie.New([]byte("secret-key-for-csrf-cookie-is-32"), []byte("fedcba9876543210"))
+	happyCookieCodec.SetSerializer(securecookie.JSONEncoder{})
+
+	encodedCSRFCookie, err := happyCookieCodec.Encode(oidc.CSRFCookieEncodingName, csrftoken.CSRFToken(csrfValueFromCookie))
+	require.NoError(t, err)
+	happyCSRFCookie := oidc.CSRFCookieName + "=" + encodedCSRFCookie
+
+	twoIDPs := func() *testidplister.UpstreamIDPListerBuilder {
+		return newTestIDPs().WithOIDC(
+			oidctestutil.NewTestUpstreamOIDCIdentityProviderBuilder().
+				WithName("other-upstream-oidc-idp-name").
+				WithResourceUID("other-upstream-oidc-resource-uid").
+				WithClientID("other-upstream-client-id").
+				WithAuthorizationURL(url.URL{Scheme: "https", Host: "my-other-upstream-issuer.com", Path: "/authorize"}).
+				Build(),
+		)
+	}
+
+	// The placeholder in these request bodies is replaced by the user code of the device authorization request
+	// which is created at the start of each test.
+	const userCodePlaceholder = "USER_CODE_PLACEHOLDER"
+
+	tests := []struct {
+		name              string
+		method            string
+		path              string
+		body              string
+		cookie            string
+		idps              *testidplister.UpstreamIDPListerBuilder
+		transformUserCode func(string) string
+		approveFirst      bool
+
+		wantRejected         bool
+		wantStatus           int
+		wantContentType      string
+		wantBodyContains     []string
+		wantBodyNotContains  []string
+		wantCSRFCookieValue  string
+		wantRedirectToHost   string
+		wantRedirectUpstream string
+	}{
+		{
+			name:                "GET without a CSRF cookie renders the form and sets a new CSRF cookie",
+			method:              http.MethodGet,
+			path:                "/oauth2/device",
+			idps:                newTestIDPs(),
+			wantStatus:          http.StatusOK,
+			wantContentType:     "text/html;charset=UTF-8",
+			wantBodyContains:    []string{`action="` + postPath + `"`, `value="` + generatedCSRFValue + `"`},
+			wantBodyNotContains: []string{`role="alert"`},
+			wantCSRFCookieValue: generatedCSRFValue,
+		},
+		{
+			name:                "GET with a CSRF cookie renders the form using the existing CSRF value",
+			method:              http.MethodGet,
+			path:                "/oauth2/device",
+			cookie:              happyCSRFCookie,
+			idps:                newTestIDPs(),
+			wantStatus:          http.StatusOK,
+			wantContentType:     "text/html;charset=UTF-8",
+			wantBodyContains:    []string{`value="` + csrfValueFromCookie + `"`},
+			wantBodyNotContains: []string{generatedCSRFValue},
+		},
+		{
+			name:            "GET with user code and IDP name params pre-fills the form",
+			method:          http.MethodGet,
+			path:            "/oauth2/device?user_code=ABCDWXYZ&pinniped_idp_name=" + upstreamOIDCIDPName,
+			cookie:          happyCSRFCookie,
+			idps:            newTestIDPs(),
+			wantStatus:      http.StatusOK,
+			wantContentType: "text/html;charset=UTF-8",
+			wantBodyContains: []string{
+				`value="ABCDWXYZ"`,
+				`name="pinniped_idp_name" id="pinniped_idp_name" value="` + upstreamOIDCIDPName + `"`,
+			},
+		},
+		{
+			name:             "PUT is not allowed",
+			method:           http.MethodPut,
+			path:             "/oauth2/device",
+			idps:             newTestIDPs(),
+			wantStatus:       http.StatusMethodNotAllowed,
+			wantContentType:  "text/plain; charset=utf-8",
+			wantBodyContains: []string{"Method Not Allowed: PUT (try GET or POST)"},
+		},
+		{
+			name:             "POST without a CSRF cookie",
+			method:           http.MethodPost,
+			path:             "/oauth2/device",
+			body:             "csrf=" + csrfValueFromCookie + "&user_code=" + userCodePlaceholder,
+			idps:             newTestIDPs(),
+			wantStatus:       http.StatusForbidden,
+			wantContentType:  "text/plain; charset=utf-8",
+			wantBodyContains: []string{"Forbidden: CSRF cookie is missing"},
+		},
+		{
+			name:             "POST with a CSRF value which does not match the cookie",
+			method:           http.MethodPost,
+			path:             "/oauth2/device",
+			body:             "csrf=wrong-csrf-value&user_code=" + userCodePlaceholder,
+			cookie:           happyCSRFCookie,
+			idps:             newTestIDPs(),
+			wantStatus:       http.StatusForbidden,
+			wantContentType:  "text/plain; charset=utf-8",
+			wantBodyContains: []string{"Forbidden: CSRF value does not match"},
+		},
+		{
+			name:             "POST with an invalid CSRF cookie",
+			method:           http.MethodPost,
+			path:             "/oauth2/device",
+			body:             "csrf=" + csrfValueFromCookie + "&user_code=" + userCodePlaceholder,
+			cookie:           oidc.CSRFCookieName + "=this-value-was-not-signed-by-pinniped",
+			idps:             newTestIDPs(),
+			wantStatus:       http.StatusForbidden,
+			wantContentType:  "text/plain; charset=utf-8",
+			wantBodyContains: []string{"Forbidden: error reading CSRF cookie"},
+		},
+		{
+			name:             "POST without a user code",
+			method:           http.MethodPost,
+			path:             "/oauth2/device",
+			body:             "csrf=" + csrfValueFromCookie,
+			cookie:           happyCSRFCookie,
+			idps:             newTestIDPs(),
+			wantStatus:       http.StatusOK,
+			wantContentType:  "text/html;charset=UTF-8",
+			wantBodyContains: []string{html.EscapeString(invalidUserCodeMessage)},
+		},
+		{
+			name:             "POST with a user code which does not exist",
+			method:           http.MethodPost,
+			path:             "/oauth2/device",
+			body:             "csrf=" + csrfValueFromCookie + "&user_code=BCDFGHJK",
+			cookie:           happyCSRFCookie,
+			idps:             newTestIDPs(),
+			wantStatus:       http.StatusOK,
+			wantContentType:  "text/html;charset=UTF-8",
+			wantBodyContains: []string{html.EscapeString(invalidUserCodeMessage), `value="BCDFGHJK"`},
+		},
+		{
+			name:             "POST with a user code which was already used",
+			method:           http.MethodPost,
+			path:             "/oauth2/device",
+			body:             "csrf=" + csrfValueFromCookie + "&user_code=" + userCodePlaceholder,
+			cookie:           happyCSRFCookie,
+			idps:             newTestIDPs(),
+			approveFirst:     true,
+			wantStatus:       http.StatusOK,
+			wantContentType:  "text/html;charset=UTF-8",
+			wantBodyContains: []string{html.EscapeString(invalidUserCodeMessage)},
+		},
+		{
+			name:            "POST with a valid user code asks the user to confirm the login",
+			method:          http.MethodPost,
+			path:            "/oauth2/device",
+			body:            "csrf=" + csrfValueFromCookie + "&user_code=" + userCodePlaceholder + "&pinniped_idp_name=" + upstreamOIDCIDPName,
+			cookie:          happyCSRFCookie,
+			idps:            newTestIDPs(),
+			wantStatus:      http.StatusOK,
+			wantContentType: "text/html;charset=UTF-8",
+			wantBodyContains: []string{
+				"<h1>Confirm device login</h1>",
+				"the client <strong>pinniped-cli</strong> is asking to log in with the scopes <strong>openid, offline_access</strong>.",
+				`name="pinniped_idp_name" id="pinniped_idp_name" value="` + upstreamOIDCIDPName + `"`,
+				`<input type="hidden" name="confirm" value="approve">`,
+				`<input type="hidden" name="confirm" value="deny">`,
+			},
+		},
+		{
+			name:                "POST with a valid user code which the user denied rejects the device authorization request",
+			method:              http.MethodPost,
+			path:                "/oauth2/device",
+			body:                "csrf=" + csrfValueFromCookie + "&user_code=" + userCodePlaceholder + "&pinniped_idp_name=" + upstreamOIDCIDPName + "&confirm=deny",
+			cookie:              happyCSRFCookie,
+			idps:                newTestIDPs(),
+			wantStatus:          http.StatusOK,
+			wantContentType:     "text/html;charset=UTF-8",
+			wantBodyContains:    []string{"<h1>Device login denied</h1>"},
+			wantBodyNotContains: []string{"<form"},
+			wantRejected:        true,
+		},
+		{
+			name:             "POST with a valid user code and an invalid confirm param",
+			method:           http.MethodPost,
+			path:             "/oauth2/device",
+			body:             "csrf=" + csrfValueFromCookie + "&user_code=" + userCodePlaceholder + "&pinniped_idp_name=" + upstreamOIDCIDPName + "&confirm=maybe",
+			cookie:           happyCSRFCookie,
+			idps:             newTestIDPs(),
+			wantStatus:       http.StatusBadRequest,
+			wantContentType:  "text/plain; charset=utf-8",
+			wantBodyContains: []string{"Bad Request: invalid confirm param"},
+		},
+		{
+			name:                 "POST with a valid user code redirects to the default upstream IDP",
+			method:               http.MethodPost,
+			path:                 "/oauth2/device",
+			body:                 "csrf=" + csrfValueFromCookie + "&user_code=" + userCodePlaceholder + "&confirm=approve",
+			cookie:               happyCSRFCookie,
+			idps:                 newTestIDPs().WithDefaultIDPDisplayName(upstreamOIDCIDPName),
+			wantStatus:           http.StatusSeeOther,
+			wantContentType:      "text/html; charset=utf-8",
+			wantRedirectToHost:   "my-upstream-issuer.com",
+			wantRedirectUpstream: upstreamOIDCIDPName,
+		},
+		{
+			name:                 "POST with a valid user code which was typed in lower case with dashes and spaces",
+			method:               http.MethodPost,
+			path:                 "/oauth2/device",
+			body:                 "csrf=" + csrfValueFromCookie + "&user_code=" + userCodePlaceholder + "&confirm=approve",
+			cookie:               happyCSRFCookie,
+			idps:                 newTestIDPs().WithDefaultIDPDisplayName(upstreamOIDCIDPName),
+			transformUserCode:    func(s string) string { return url.QueryEscape(" " + strings.ToLower(s[:4]+"-"+s[4:]) + " ") },
+			wantStatus:           http.StatusSeeOther,
+			wantContentType:      "text/html; charset=utf-8",
+			wantRedirectToHost:   "my-upstream-issuer.com",
+			wantRedirectUpstream: upstreamOIDCIDPName,
+		},
+		{
+			name:                 "POST with a valid user code and IDP name redirects to the chosen upstream IDP",
+			method:               http.MethodPost,
+			path:                 "/oauth2/device",
+			body:                 "csrf=" + csrfValueFromCookie + "&user_code=" + userCodePlaceholder + "&pinniped_idp_name=other-upstream-oidc-idp-name&confirm=approve",
+			cookie:               happyCSRFCookie,
+			idps:                 twoIDPs(),
+			wantStatus:           http.StatusSeeOther,
+			wantContentType:      "text/html; charset=utf-8",
+			wantRedirectToHost:   "my-other-upstream-issuer.com",
+			wantRedirectUpstream: "other-upstream-oidc-idp-name",
+		},
+		{
+			name:             "POST with a valid user code but no IDP name when there is no default upstream IDP",
+			method:           http.MethodPost,
+			path:             "/oauth2/device",
+			body:             "csrf=" + csrfValueFromCookie + "&user_code=" + userCodePlaceholder,
+			cookie:           happyCSRFCookie,
+			idps:             twoIDPs(),
+			wantStatus:       http.StatusOK,
+			wantContentType:  "text/html;charset=UTF-8",
+			wantBodyContains: []string{html.EscapeString(chooseIDPMessage)},
+		},
+		{
+			name:             "POST with a valid user code but an IDP name which does not exist",
+			method:           http.MethodPost,
+			path:             "/oauth2/device",
+			body:             "csrf=" + csrfValueFromCookie + "&user_code=" + userCodePlaceholder + "&pinniped_idp_name=does-not-exist",
+			cookie:           happyCSRFCookie,
+			idps:             newTestIDPs(),
+			wantStatus:       http.StatusOK,
+			wantContentType:  "text/html;charset=UTF-8",
+			wantBodyContains: []string{html.EscapeString(chooseIDPMessage)},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			fixture := newDeviceTestFixture(t)
+			idpLister := test.idps.BuildFederationDomainIdentityProvidersListerFinder()
+
+			userCode := startDeviceAuthorization(t, fixture)
+			deviceStrategy := oidc.DeviceStrategy(downstreamIssuer, fixture.hmacSecret, oidc.DefaultOIDCTimeoutsConfiguration())
+			userCodeSignature, err := deviceStrategy.UserCodeSignature(t.Context(), userCode)
+			require.NoError(t, err)
+
+			if test.approveFirst {
+				requester, err := fixture.oauthStore.GetDeviceCodeSessionByUserCode(t.Context(), userCodeSignature)
+				require.NoError(t, err)
+				require.NoError(t, fixture.oauthStore.ApproveDeviceCodeSession(t.Context(), userCodeSignature, requester))
+			}
+
+			submittedUserCode := userCode
+			if test.transformUserCode != nil {
+				submittedUserCode = test.transformUserCode(userCode)
+			}
+
+			auditLogger, _ := plog.TestAuditLogger(t)
+
+			subject := NewVerificationHandler(
+				downstreamIssuer,
+				postPath,
+				idpLister,
+				fixture.oauthStore,
+				deviceStrategy,
+				func() (csrftoken.CSRFToken, error) { return generatedCSRFValue, nil },
+				func() (pkce.Code, error) { return generatedPKCEValue, nil },
+				func() (nonce.Nonce, error) { return generatedNonceValue, nil },
+				happyStateCodec,
+				happyCookieCodec,
+				NewVerificationLimiter(clocktesting.NewFakeClock(time.Now())),
+				auditLogger,
+			)
+
+			body := strings.ReplaceAll(test.body, userCodePlaceholder, submittedUserCode)
+			req := httptest.NewRequestWithContext(t.Context(), test.method, test.path, strings.NewReader(body))
+			if test.body != "" {
+				req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			}
+			if test.cookie != "" {
+				req.Header.Set("Cookie", test.cookie)
+			}
+			req, _ = auditid.NewRequestWithAuditID(req, func() string { return "fake-audit-id" })
+			rsp := httptest.NewRecorder()
+			subject.ServeHTTP(rsp, req)
+			t.Logf("response: %#v", rsp)
+			t.Logf("response body: %q", rsp.Body.String())
+
+			require.Equal(t, test.wantStatus, rsp.Code)
+			testutil.RequireEqualContentType(t, rsp.Header().Get("Content-Type"), test.wantContentType)
+			testutil.RequireSecurityHeadersWithLoginPageCSPs(t, rsp)
+
+			for _, want := range test.wantBodyContains {
+				require.Contains(t, rsp.Body.String(), want)
+			}
+			for _, notWant := range test.wantBodyNotContains {
+				require.NotContains(t, rsp.Body.String(), notWant)
+			}
+
+			if test.wantCSRFCookieValue != "" {
+				cookies := rsp.Result().Cookies()
+				require.Len(t, cookies, 1)
+				require.Equal(t, oidc.CSRFCookieName, cookies[0].Name)
+				var decodedCSRF csrftoken.CSRFToken
+				require.NoError(t, happyCookieCodec.Decode(oidc.CSRFCookieEncodingName, cookies[0].Value, &decodedCSRF))
+				require.Equal(t, csrftoken.CSRFToken(test.wantCSRFCookieValue), decodedCSRF)
+			} else {
+				require.Empty(t, rsp.Header().Values("Set-Cookie"))
+			}
+
+			if test.wantRejected {
+				requireUserCodeRejected(t, fixture, userCodeSignature)
+			}
+
+			if test.wantRedirectToHost == "" {
+				require.Empty(t, rsp.Header().Get("Location"))
+				return
+			}
+
+			redirectURL, err := url.Parse(rsp.Header().Get("Location"))
+			require.NoError(t, err)
+			require.Equal(t, test.wantRedirectToHost, redirectURL.Host)
+			require.Equal(t, downstreamIssuer+"/callback", redirectURL.Query().Get("redirect_uri"))
+			require.Equal(t, generatedNonceValue, redirectURL.Query().Get("nonce"))
+			require.Equal(t, "S256", redirectURL.Query().Get("code_challenge_method"))
+
+			var decodedState oidc.UpstreamStateParamData
+			require.NoError(t, happyStateCodec.Decode(oidc.UpstreamStateParamEncodingName, redirectURL.Query().Get("state"), &decodedState))
+			require.Equal(t, oidc.UpstreamStateParamData{
+				AuthParams:              "",
+				UpstreamName:            test.wantRedirectUpstream,
+				UpstreamType:            "oidc",
+				Nonce:                   generatedNonceValue,
+				CSRFToken:               csrfValueFromCookie,
+				PKCECode:                generatedPKCEValue,
+				FormatVersion:           oidc.UpstreamStateParamFormatVersion,
+				DeviceUserCodeSignature: userCodeSignature,
+			}, decodedState)
+
+			// Starting the login does not use up the user code. It is only used when the login finishes.
+			requester, err := fixture.oauthStore.GetDeviceCodeSessionByUserCode(t.Context(), userCodeSignature)
+			require.NoError(t, err)
+			require.Equal(t, fosite.UserCodeUnused, requester.GetUserCodeState())
+		})
+	}
+}
+
+func TestDeviceVerificationHandlerLimits(t *testing.T) {
+	const csrfValue = "some-csrf-value-from-cookie"
+
+	cookieCodec := securecookie.New([]byte("secret-key-for-csrf-cookie-is-32"), []byte("fedcba9876543210"))
+	cookieCodec.SetSerializer(securecookie.JSONEncoder{})
+	encodedCSRFCookie, err := cookieCodec.Encode(oidc.CSRFCookieEncodingName, csrftoken.CSRFToken(csrfValue))
+	require.NoError(t, err)
+
+	stateCodec := securecookie.New([]byte("secret-key-for-state-param-is-32"), []byte("0123456789ABCDEF"))
+	stateCodec.SetSerializer(securecookie.JSONEncoder{})
+
+	setup := func(t *testing.T) (*deviceTestFixture, *clocktesting.FakeClock, func(remoteAddr, userCode string) *httptest.ResponseRecorder) {
+		fixture := newDeviceTestFixture(t)
+		fakeClock := clocktesting.NewFakeClock(time.Now())
+		auditLogger, _ := plog.TestAuditLogger(t)
+
+		subject := NewVerificationHandler(
+			downstreamIssuer,
+			"/some/path/oauth2/device",
+			newTestIDPs().BuildFederationDomainIdentityProvidersListerFinder(),
+			fixture.oauthStore,
+			oidc.DeviceStrategy(downstreamIssuer, fixture.hmacSecret, oidc.DefaultOIDCTimeoutsConfiguration()),
+			func() (csrftoken.CSRFToken, error) { return csrfValue, nil },
+			func() (pkce.Code, error) { return "some-pkce-value-which-is-at-least-43-characters-long", nil },
+			func() (nonce.Nonce, error) { return "some-nonce-value", nil },
+			stateCodec,
+			cookieCodec,
+			NewVerificationLimiter(fakeClock),
+			auditLogger,
+		)
+
+		post := func(remoteAddr, userCode string) *httptest.ResponseRecorder {
+			req := httptest.NewRequestWithContext(t.Context(), http.MethodPost, "/oauth2/device",
+				strings.NewReader("csrf="+csrfValue+"&user_code="+userCode+"&pinniped_idp_name="+upstreamOIDCIDPName))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			req.Header.Set("Cookie", oidc.CSRFCookieName+"="+encodedCSRFCookie)
+			req.RemoteAddr = remoteAddr
+			rsp := httptest.NewRecorder()
+			subject.ServeHTTP(rsp, req)
+			return rsp
+		}
+
+		return fixture, fakeClock, post
+	}
+
+	t.Run("a source which tried too many user codes which do not exist may not try more", func(t *testing.T) {
+		fixture, fakeClock, post := setup(t)
+		userCode := startDeviceAuthorization(t, fixture)
+
+		for range maxFailedAttemptsPerSource {
+			rsp := post("1.2.3.4:1111", "BCDFGHJK")
+			require.Equal(t, http.StatusOK, rsp.Code)
+			require.Contains(t, rsp.Body.String(), html.EscapeString(invalidUserCodeMessage))
+		}
+
+		// Now even a valid user code is not looked up for this source, regardless of its port.
+		rsp := post("1.2.3.4:2222", userCode)
+		require.Equal(t, http.StatusTooManyRequests, rsp.Code)
+		require.Contains(t, rsp.Body.String(), html.EscapeString(tooManyAttemptsMessage))
+
+		// Other sources are not limited.
+		rsp = post("5.6.7.8:1111", userCode)
+		require.Equal(t, http.StatusOK, rsp.Code)
+		require.Contains(t, rsp.Body.String(), "<h1>Confirm device login</h1>")
+
+		// The source may try again after the window has passed.
+		fakeClock.Step(verificationAttemptsWindow)
+		rsp = post("1.2.3.4:1111", userCode)
+		require.Equal(t, http.StatusOK, rsp.Code)
+		require.Contains(t, rsp.Body.String(), "<h1>Confirm device login</h1>")
+	})
+
+	t.Run("a user code which was tried too many times is rejected", func(t *testing.T) {
+		fixture, _, post := setup(t)
+		userCode := startDeviceAuthorization(t, fixture)
+		userCodeSignature, err := oidc.DeviceStrategy(downstreamIssuer, fixture.hmacSecret, oidc.DefaultOIDCTimeoutsConfiguration()).
+			UserCodeSignature(t.Context(), userCode)
+		require.NoError(t, err)
+
+		// Use different sources, so the source limit does not apply.
+		for i := range maxAttemptsPerUserCode {
+			rsp := post(fmt.Sprintf("10.0.0.%d:1111", i), userCode)
+			require.Equal(t, http.StatusOK, rsp.Code)
+			require.Contains(t, rsp.Body.String(), "<h1>Confirm device login</h1>")
+		}
+
+		rsp := post("10.0.1.1:1111", userCode)
+		require.Equal(t, http.StatusTooManyRequests, rsp.Code)
+		require.Contains(t, rsp.Body.String(), html.EscapeString(userCodeRejectedMessage))
+		requireUserCodeRejected(t, fixture, userCodeSignature)
+
+		// The rejected user code no longer exists.
+		rsp = post("10.0.1.2:1111", userCode)
+		require.Equal(t, http.StatusOK, rsp.Code)
+		require.Contains(t, rsp.Body.String(), html.EscapeString(invalidUserCodeMessage))
+	})
+}
+
+// requireUserCodeRejected asserts that the device authorization request was rejected, so its user code cannot be
+// used again and its device code will get an access_denied error from the token endpoint.
+func requireUserCodeRejected(t *testing.T, fixture *deviceTestFixture, userCodeSignature string) {
+	t.Helper()
+
+	_, err := fixture.oauthStore.GetDeviceCodeSessionByUserCode(t.Context(), userCodeSignature)
+	require.ErrorIs(t, err, fosite.ErrNotFound)
+
+	deviceCodeSecrets, err := fixture.secrets.List(t.Context(), metav1.ListOptions{LabelSelector: "storage.pinniped.dev/type=device-code"})
+	require.NoError(t, err)
+	require.Len(t, deviceCodeSecrets.Items, 1)
+	storedSession, err := devicecode.ReadFromSecret(&deviceCodeSecrets.Items[0])
+	require.NoError(t, err)
+	require.Equal(t, fosite.UserCodeRejected, storedSession.UserCodeState)
+}
+
+// startDeviceAuthorization uses the device authorization endpoint to create a device authorization request
+// in the fixture's storage, and returns its user code.
+func startDeviceAuthorization(t *testing.T, fixture *deviceTestFixture) string {
+	t.Helper()
+
+	auditLogger, _ := plog.TestAuditLogger(t)
+	subject := NewAuthorizationHandler(
+		newTestIDPs().BuildFederationDomainIdentityProvidersListerFinder(),
+		fixture.oauthHelper,
+		auditLogger,
+	)
+
+	req := httptest.NewRequestWithContext(t.Context(), http.MethodPost, "/oauth2/device_authorization",
+		strings.NewReader("client_id=pinniped-cli&scope=openid+offline_access"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rsp := httptest.NewRecorder()
+	subject.ServeHTTP(rsp, req)
+	require.Equal(t, http.StatusOK, rsp.Code, "unexpected response body: %s", rsp.Body.String())
+
+	var response deviceAuthorizationResponse
+	require.NoError(t, json.Unmarshal(rsp.Body.Bytes(), &response))
+	require.NotEmpty(t, response.UserCode)
+	return response.UserCode
+}
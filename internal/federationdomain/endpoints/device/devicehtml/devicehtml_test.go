@@ -0,0 +1,110 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package devicehtml
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	testExpectedCSS = `html{height:100%}body{font-family:metropolis-light,Helvetica,sans-serif;display:flex;flex-flow:column wrap;justify-content:flex-start;align-items:center;background:linear-gradient(to top,#f8f8f8,white);min-height:100%}h1{font-size:20px;margin:0}.box{display:flex;flex-direction:column;flex-wrap:nowrap;border-radius:4px;border-color:#ddd;border-width:1px;border-style:solid;width:400px;padding:30px 30px 0;margin:60px 20px 0;background:#fff;font-size:14px}input{color:inherit;font:inherit;border:0;margin:0;outline:0;padding:0}.form-field{display:flex;margin-bottom:30px}.form-field input[type=password],.form-field input[type=text],.form-field input[type=submit]{width:100%;padding:1em}.form-field input[type=password],.form-field input[type=text]{border-radius:3px;border-width:1px;border-style:solid;border-color:#a6a6a6}.form-field input[type=submit]{background-color:#218fcf;color:#eee;font-weight:700;cursor:pointer;transition:all .3s}.form-field input[type=submit]:focus,.form-field input[type=submit]:hover{background-color:#1abfd3}.form-field input[type=submit]:active{transform:scale(.99)}.hidden{border:0;clip:rect(0 0 0 0);height:1px;margin:-1px;overflow:hidden;padding:0;position:absolute;width:1px}.alert{color:crimson}.notice{color:#555}.form-field input[name=user_code]{text-transform:uppercase;letter-spacing:.2em}`
+
+	// It's okay if this changes in the future, but this gives us a chance to eyeball the formatting.
+	// Our browser-based integration tests should find any incompatibilities.
+	testExpectedCSP = `default-src 'none'; ` +
+		`style-src 'sha256-8aXNTNj59oXjIkmo1hlczsoRScVG7hXG24Q0Ic8YCWo='; ` +
+		`frame-ancestors 'none'`
+)
+
+func TestTemplate(t *testing.T) {
+	const (
+		testPath      = "test-post-path"
+		testCSRFToken = "test-csrf-token"
+		testUserCode  = "ABCD-EFGH"
+		testIDPName   = "test-idp-name"
+		testAlert     = "test-alert-message"
+	)
+
+	var buf bytes.Buffer
+	pageInputs := &PageData{
+		PostPath:      testPath,
+		CSRFToken:     testCSRFToken,
+		UserCode:      testUserCode,
+		IDPName:       testIDPName,
+		HasAlertError: true,
+		AlertMessage:  testAlert,
+	}
+
+	// Render the form with an alert.
+	require.NoError(t, Template().Execute(&buf, pageInputs))
+	html := buf.String()
+	require.Contains(t, html, "<style>"+testExpectedCSS+"</style>")
+	require.Contains(t, html, `<form action="test-post-path" method="post">`)
+	require.Contains(t, html, `<input type="hidden" name="csrf" id="csrf" value="test-csrf-token">`)
+	require.Contains(t, html, `<input type="hidden" name="pinniped_idp_name" id="pinniped_idp_name" value="test-idp-name">`)
+	require.Contains(t, html, `value="ABCD-EFGH"`)
+	require.Contains(t, html, `id="alert">test-alert-message</span>`)
+	require.NotContains(t, html, "Device login complete")
+
+	// Render again without an alert or an IDP name.
+	pageInputs.HasAlertError = false
+	pageInputs.IDPName = ""
+	buf = bytes.Buffer{} // clear previous result from buffer
+	require.NoError(t, Template().Execute(&buf, pageInputs))
+	html = buf.String()
+	require.Contains(t, html, `<input type="hidden" name="csrf" id="csrf" value="test-csrf-token">`)
+	require.NotContains(t, html, "pinniped_idp_name")
+	require.NotContains(t, html, testAlert)
+
+	// Render the confirmation page.
+	buf = bytes.Buffer{}
+	require.NoError(t, Template().Execute(&buf, &PageData{Approved: true}))
+	html = buf.String()
+	require.Contains(t, html, "<h1>Device login complete</h1>")
+	require.NotContains(t, html, "<form")
+
+	// Render the denied page.
+	buf = bytes.Buffer{}
+	require.NoError(t, Template().Execute(&buf, &PageData{Denied: true}))
+	html = buf.String()
+	require.Contains(t, html, "<h1>Device login denied</h1>")
+	require.NotContains(t, html, "<form")
+
+	// Render the page which asks the user to confirm the login.
+	buf = bytes.Buffer{}
+	require.NoError(t, Template().Execute(&buf, &PageData{
+		PostPath:  testPath,
+		CSRFToken: testCSRFToken,
+		UserCode:  testUserCode,
+		IDPName:   testIDPName,
+		Confirm:   true,
+		ClientID:  "test-client-id",
+		Scopes:    []string{"openid", "offline_access"},
+	}))
+	html = buf.String()
+	require.Contains(t, html, "<h1>Confirm device login</h1>")
+	require.Contains(t, html, "the client <strong>test-client-id</strong> is asking to log in with the scopes <strong>openid, offline_access</strong>.")
+	require.Contains(t, html, `<input type="hidden" name="user_code" id="user_code" value="ABCD-EFGH">`)
+	require.Contains(t, html, `<input type="hidden" name="pinniped_idp_name" id="pinniped_idp_name" value="test-idp-name">`)
+	require.Contains(t, html, `<input type="hidden" name="confirm" value="approve">`)
+	require.Contains(t, html, `<input type="hidden" name="confirm" value="deny">`)
+	require.NotContains(t, html, "<h1>Log in to a device</h1>")
+}
+
+func TestContentSecurityPolicy(t *testing.T) {
+	require.Equal(t, testExpectedCSP, ContentSecurityPolicy())
+}
+
+func TestCSS(t *testing.T) {
+	require.Equal(t, testExpectedCSS, CSS())
+}
+
+func TestHelpers(t *testing.T) {
+	require.Equal(t, "test", panicOnError("test", nil))
+	require.PanicsWithError(t, "some error", func() { panicOnError("", fmt.Errorf("some error")) })
+}
@@ -0,0 +1,145 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package device
+
+import (
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"k8s.io/utils/clock"
+)
+
+const (
+	// maxFailedAttemptsPerSource is how many times a single source may submit a user code which does not exist
+	// within verificationAttemptsWindow before it is not allowed to submit any more user codes.
+	maxFailedAttemptsPerSource = 10
+
+	// maxAttemptsPerUserCode is how many times an existing user code may be submitted to the verification page
+	// within verificationAttemptsWindow. Each successful login takes two submissions (entering the code and then
+	// confirming it), so this allows for a few failed logins before the user code is rejected.
+	maxAttemptsPerUserCode = 10
+
+	// verificationAttemptsWindow is how long attempts are remembered.
+	verificationAttemptsWindow = 15 * time.Minute
+)
+
+// VerificationLimiter limits how often user codes may be tried at the device verification page, to make it
+// impractical to guess user codes. See https://datatracker.ietf.org/doc/html/rfc8628#section-5.1.
+// The same VerificationLimiter should be shared by the verification pages of all FederationDomains, so that
+// its state survives when the FederationDomains are reloaded. Its state is kept in memory, so each pod of the
+// Supervisor has its own limits. The zero value is not usable, so use NewVerificationLimiter.
+// A nil *VerificationLimiter does not limit anything.
+type VerificationLimiter struct {
+	clock clock.PassiveClock
+
+	lock      sync.Mutex
+	sources   map[string]*attempts
+	userCodes map[string]*attempts
+	lastPrune time.Time
+}
+
+// attempts counts the attempts of a single source or user code since windowStart.
+type attempts struct {
+	count       int
+	windowStart time.Time
+}
+
+// NewVerificationLimiter returns an empty VerificationLimiter.
+func NewVerificationLimiter(clock clock.PassiveClock) *VerificationLimiter {
+	return &VerificationLimiter{
+		clock:     clock,
+		sources:   map[string]*attempts{},
+		userCodes: map[string]*attempts{},
+	}
+}
+
+// sourceLimited returns true when the source of the request has submitted too many user codes which did not exist.
+func (l *VerificationLimiter) sourceLimited(r *http.Request) bool {
+	if l == nil {
+		return false
+	}
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	return l.current(l.sources, requestSource(r)) >= maxFailedAttemptsPerSource
+}
+
+// recordSourceFailure counts a submitted user code which did not exist against the source of the request.
+func (l *VerificationLimiter) recordSourceFailure(r *http.Request) {
+	if l == nil {
+		return
+	}
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	l.increment(l.sources, requestSource(r))
+}
+
+// recordUserCodeAttempt counts a submission of an existing user code, and returns true when the user code has
+// now been submitted too many times. The caller should then reject the user code.
+func (l *VerificationLimiter) recordUserCodeAttempt(userCodeSignature string) bool {
+	if l == nil {
+		return false
+	}
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	return l.increment(l.userCodes, userCodeSignature) > maxAttemptsPerUserCode
+}
+
+// current returns the number of attempts for the key within the current window. The caller must hold the lock.
+func (l *VerificationLimiter) current(counts map[string]*attempts, key string) int {
+	a, ok := counts[key]
+	if !ok || l.clock.Since(a.windowStart) >= verificationAttemptsWindow {
+		return 0
+	}
+	return a.count
+}
+
+// increment adds an attempt for the key and returns the number of attempts for the key within the current window.
+// The caller must hold the lock.
+func (l *VerificationLimiter) increment(counts map[string]*attempts, key string) int {
+	now := l.clock.Now()
+	l.maybePrune(now)
+
+	a, ok := counts[key]
+	if !ok || now.Sub(a.windowStart) >= verificationAttemptsWindow {
+		a = &attempts{windowStart: now}
+		counts[key] = a
+	}
+	a.count++
+	return a.count
+}
+
+// maybePrune forgets the sources and user codes whose windows have ended, so the maps do not grow forever.
+// The caller must hold the lock.
+func (l *VerificationLimiter) maybePrune(now time.Time) {
+	if now.Sub(l.lastPrune) < verificationAttemptsWindow {
+		return
+	}
+	l.lastPrune = now
+
+	for _, counts := range []map[string]*attempts{l.sources, l.userCodes} {
+		for key, a := range counts {
+			if now.Sub(a.windowStart) >= verificationAttemptsWindow {
+				delete(counts, key)
+			}
+		}
+	}
+}
+
+// requestSource returns the IP address of the client which made the request. Headers such as X-Forwarded-For are
+// not used, because any client could set them to avoid the limits.
+func requestSource(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
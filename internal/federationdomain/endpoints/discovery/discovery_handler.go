@@ -1,16 +1,17 @@
-// Copyright 2020-2023 the Pinniped contributors. All Rights Reserved.
+// Copyright 2020-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 // Package discovery provides a handler for the OIDC discovery endpoint.
 package discovery
 
 import (
-	"bytes"
 	"encoding/json"
 	"net/http"
+	"slices"
 
 	"go.pinniped.dev/generated/latest/apis/supervisor/idpdiscovery/v1alpha1"
 	oidcapi "go.pinniped.dev/generated/latest/apis/supervisor/oidc"
+	"go.pinniped.dev/internal/federationdomain/endpoints/jwks"
 	"go.pinniped.dev/internal/federationdomain/oidc"
 )
 
@@ -35,13 +36,40 @@ type Metadata struct {
 
 	// vvv Optional vvv
 
-	TokenEndpointAuthMethodsSupported []string `json:"token_endpoint_auth_methods_supported"`
-	ScopesSupported                   []string `json:"scopes_supported"`
-	ClaimsSupported                   []string `json:"claims_supported"`
+	TokenEndpointAuthMethodsSupported          []string `json:"token_endpoint_auth_methods_supported"`
+	TokenEndpointAuthSigningAlgValuesSupported []string `json:"token_endpoint_auth_signing_alg_values_supported"`
+	ScopesSupported                            []string `json:"scopes_supported"`
+	ClaimsSupported                            []string `json:"claims_supported"`
 
 	// https://datatracker.ietf.org/doc/html/rfc8414#section-2 says, “If omitted, the authorization server does not support PKCE.”
 	CodeChallengeMethodsSupported []string `json:"code_challenge_methods_supported"`
 
+	// https://datatracker.ietf.org/doc/html/rfc8628#section-4 adds this to the authorization server metadata.
+	DeviceAuthorizationEndpoint string `json:"device_authorization_endpoint"`
+
+	// https://datatracker.ietf.org/doc/html/rfc8414#section-2 adds this for the RFC 7009 token revocation endpoint.
+	RevocationEndpoint string `json:"revocation_endpoint"`
+
+	// https://openid.net/specs/openid-connect-rpinitiated-1_0.html#OPMetadata adds this for RP-initiated logout.
+	EndSessionEndpoint string `json:"end_session_endpoint"`
+
+	// https://datatracker.ietf.org/doc/html/rfc8414#section-2 adds these for the RFC 7662 token introspection endpoint.
+	IntrospectionEndpoint                     string   `json:"introspection_endpoint"`
+	IntrospectionEndpointAuthMethodsSupported []string `json:"introspection_endpoint_auth_methods_supported"`
+
+	// https://datatracker.ietf.org/doc/html/rfc8414#section-2 adds this for the RFC 7591 client registration endpoint.
+	RegistrationEndpoint string `json:"registration_endpoint,omitempty"`
+
+	// https://datatracker.ietf.org/doc/html/rfc9126#section-5 adds these for the pushed authorization request endpoint.
+	PushedAuthorizationRequestEndpoint string `json:"pushed_authorization_request_endpoint"`
+	RequirePushedAuthorizationRequests bool   `json:"require_pushed_authorization_requests"`
+
+	// https://openid.net/specs/openid-connect-discovery-1_0.html#ProviderMetadata adds these for request objects.
+	// Note that request_uri_parameter_supported defaults to true when omitted, so it must be included.
+	RequestParameterSupported              bool     `json:"request_parameter_supported"`
+	RequestURIParameterSupported           bool     `json:"request_uri_parameter_supported"`
+	RequestObjectSigningAlgValuesSupported []string `json:"request_object_signing_alg_values_supported"`
+
 	// ^^^ Optional ^^^
 
 	// vvv Custom vvv
@@ -51,13 +79,38 @@ type Metadata struct {
 	// ^^^ Custom ^^^
 }
 
-// NewHandler returns an http.Handler that serves an OIDC discovery endpoint.
-func NewHandler(issuerURL string) http.Handler {
+// NewHandler returns an http.Handler that serves an OIDC discovery endpoint. The idTokenSigningAlgorithm is the
+// JWS algorithm which the issuer uses to sign ID tokens. The algorithms of the other keys which are published for
+// the issuer by the jwksProvider are also advertised, because ID tokens which were signed by those keys are still
+// valid while the signing algorithm is being changed. The registrationEndpoint is the URL of the dynamic client
+// registration endpoint, or an empty string when dynamic client registration is not enabled. The tls_client_auth
+// client authentication method is only advertised when clientCertificatesRequested is true, since clients cannot
+// present their certificates unless the HTTPS listener requests them.
+func NewHandler(
+	issuerURL string,
+	idTokenSigningAlgorithm string,
+	jwksProvider jwks.DynamicJWKSProvider,
+	registrationEndpoint string,
+	clientCertificatesRequested bool,
+) http.Handler {
+	clientAuthMethods := []string{"client_secret_basic", "private_key_jwt"}
+	if clientCertificatesRequested {
+		clientAuthMethods = append(clientAuthMethods, "tls_client_auth")
+	}
+
 	oidcConfig := Metadata{
-		Issuer:                issuerURL,
-		AuthorizationEndpoint: issuerURL + oidc.AuthorizationEndpointPath,
-		TokenEndpoint:         issuerURL + oidc.TokenEndpointPath,
-		JWKSURI:               issuerURL + oidc.JWKSEndpointPath,
+		Issuer:                      issuerURL,
+		AuthorizationEndpoint:       issuerURL + oidc.AuthorizationEndpointPath,
+		TokenEndpoint:               issuerURL + oidc.TokenEndpointPath,
+		JWKSURI:                     issuerURL + oidc.JWKSEndpointPath,
+		DeviceAuthorizationEndpoint: issuerURL + oidc.DeviceAuthorizationEndpointPath,
+		RevocationEndpoint:          issuerURL + oidc.RevocationEndpointPath,
+		EndSessionEndpoint:          issuerURL + oidc.EndSessionEndpointPath,
+		IntrospectionEndpoint:       issuerURL + oidc.IntrospectionEndpointPath,
+		RegistrationEndpoint:        registrationEndpoint,
+		// Pushed authorization requests are only required by the OIDCClients which opt into requiring them.
+		PushedAuthorizationRequestEndpoint: issuerURL + oidc.PushedAuthorizationRequestEndpointPath,
+		RequirePushedAuthorizationRequests: false,
 		OIDCDiscoveryResponse: v1alpha1.OIDCDiscoveryResponse{
 			SupervisorDiscovery: v1alpha1.OIDCDiscoveryResponseIDPEndpoint{
 				PinnipedIDPsEndpoint: issuerURL + oidc.PinnipedIDPsPathV1Alpha1,
@@ -66,32 +119,60 @@ func NewHandler(issuerURL string) http.Handler {
 		ResponseTypesSupported:            []string{"code"},
 		ResponseModesSupported:            []string{"query", "form_post"},
 		SubjectTypesSupported:             []string{"public"},
-		IDTokenSigningAlgValuesSupported:  []string{"ES256"},
-		TokenEndpointAuthMethodsSupported: []string{"client_secret_basic"},
-		CodeChallengeMethodsSupported:     []string{"S256"},
-		ScopesSupported:                   []string{oidcapi.ScopeOpenID, oidcapi.ScopeOfflineAccess, oidcapi.ScopeRequestAudience, oidcapi.ScopeUsername, oidcapi.ScopeGroups},
-		ClaimsSupported:                   []string{oidcapi.IDTokenClaimUsername, oidcapi.IDTokenClaimGroups, oidcapi.IDTokenClaimAdditionalClaims},
+		TokenEndpointAuthMethodsSupported: clientAuthMethods,
+		// The introspection endpoint supports the same client authentication methods as the token endpoint.
+		IntrospectionEndpointAuthMethodsSupported: clientAuthMethods,
+		TokenEndpointAuthSigningAlgValuesSupported: []string{
+			"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512",
+		},
+		// Request objects may be sent by clients which use private_key_jwt, and must be signed by the same keys
+		// and algorithm that the client uses for client assertions. Only request objects sent by value in the
+		// request param are supported, since request_uri may only be used for pushed authorization requests.
+		RequestParameterSupported:    true,
+		RequestURIParameterSupported: false,
+		RequestObjectSigningAlgValuesSupported: []string{
+			"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512",
+		},
+		CodeChallengeMethodsSupported: []string{"S256"},
+		ScopesSupported:               []string{oidcapi.ScopeOpenID, oidcapi.ScopeOfflineAccess, oidcapi.ScopeRequestAudience, oidcapi.ScopeUsername, oidcapi.ScopeGroups},
+		ClaimsSupported:               []string{oidcapi.IDTokenClaimUsername, oidcapi.IDTokenClaimGroups, oidcapi.IDTokenClaimAdditionalClaims},
 	}
 
-	var b bytes.Buffer
-	encodeErr := json.NewEncoder(&b).Encode(&oidcConfig)
-	encodedMetadata := b.Bytes()
-
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodGet {
 			http.Error(w, `Method not allowed (try GET)`, http.StatusMethodNotAllowed)
 			return
 		}
 
-		if encodeErr != nil {
-			http.Error(w, encodeErr.Error(), http.StatusInternalServerError)
-			return
-		}
+		// The published keys change over time, so the algorithms are found again for each request.
+		metadata := oidcConfig
+		metadata.IDTokenSigningAlgValuesSupported = idTokenSigningAlgorithms(issuerURL, idTokenSigningAlgorithm, jwksProvider)
 
 		w.Header().Set("Content-Type", "application/json")
-		if _, err := w.Write(encodedMetadata); err != nil {
+		if err := json.NewEncoder(w).Encode(&metadata); err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
 	})
 }
+
+// idTokenSigningAlgorithms returns the configured algorithm followed by the algorithms of any other keys which are
+// currently published for the issuer, such as the keys which used the previous algorithm and are not yet retired.
+func idTokenSigningAlgorithms(issuerURL string, idTokenSigningAlgorithm string, jwksProvider jwks.DynamicJWKSProvider) []string {
+	algorithms := []string{idTokenSigningAlgorithm}
+	if jwksProvider == nil {
+		return algorithms
+	}
+
+	publishedJWKS, _ := jwksProvider.GetJWKS(issuerURL)
+	if publishedJWKS == nil {
+		return algorithms
+	}
+
+	for _, key := range publishedJWKS.Keys {
+		if key.Algorithm != "" && !slices.Contains(algorithms, key.Algorithm) {
+			algorithms = append(algorithms, key.Algorithm)
+		}
+	}
+	return algorithms
+}
@@ -8,8 +8,10 @@ import (
 	"net/http/httptest"
 	"testing"
 
+	"github.com/go-jose/go-jose/v4"
 	"github.com/stretchr/testify/require"
 
+	"go.pinniped.dev/internal/federationdomain/endpoints/jwks"
 	"go.pinniped.dev/internal/federationdomain/oidc"
 	"go.pinniped.dev/internal/here"
 )
@@ -18,9 +20,13 @@ func TestDiscovery(t *testing.T) {
 	tests := []struct {
 		name string
 
-		issuer string
-		method string
-		path   string
+		issuer                      string
+		idTokenSigningAlgorithm     string
+		publishedJWKS               *jose.JSONWebKeySet
+		registrationEndpoint        string
+		clientCertificatesRequested bool
+		method                      string
+		path                        string
 
 		wantStatus      int
 		wantContentType string
@@ -28,12 +34,14 @@ func TestDiscovery(t *testing.T) {
 		wantBodyString  string
 	}{
 		{
-			name:            "happy path",
-			issuer:          "https://some-issuer.com/some/path",
-			method:          http.MethodGet,
-			path:            "/some/path" + oidc.WellKnownEndpointPath,
-			wantStatus:      http.StatusOK,
-			wantContentType: "application/json",
+			name:                        "happy path",
+			issuer:                      "https://some-issuer.com/some/path",
+			idTokenSigningAlgorithm:     "ES256",
+			clientCertificatesRequested: true,
+			method:                      http.MethodGet,
+			path:                        "/some/path" + oidc.WellKnownEndpointPath,
+			wantStatus:                  http.StatusOK,
+			wantContentType:             "application/json",
 			wantBodyJSON: here.Doc(`
 			{
 				"issuer": "https://some-issuer.com/some/path",
@@ -44,10 +52,145 @@ func TestDiscovery(t *testing.T) {
 				"response_modes_supported": ["query", "form_post"],
 				"subject_types_supported": ["public"],
 				"id_token_signing_alg_values_supported": ["ES256"],
-				"token_endpoint_auth_methods_supported": ["client_secret_basic"],
+				"token_endpoint_auth_methods_supported": ["client_secret_basic", "private_key_jwt", "tls_client_auth"],
+				"token_endpoint_auth_signing_alg_values_supported": ["RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512"],
 				"scopes_supported": ["openid", "offline_access", "pinniped:request-audience", "username", "groups"],
 				"code_challenge_methods_supported": ["S256"],
 				"claims_supported": ["username", "groups", "additionalClaims"],
+				"device_authorization_endpoint": "https://some-issuer.com/some/path/oauth2/device_authorization",
+				"revocation_endpoint": "https://some-issuer.com/some/path/oauth2/revoke",
+				"end_session_endpoint": "https://some-issuer.com/some/path/oauth2/end_session",
+				"introspection_endpoint": "https://some-issuer.com/some/path/oauth2/introspect",
+				"introspection_endpoint_auth_methods_supported": ["client_secret_basic", "private_key_jwt", "tls_client_auth"],
+				"pushed_authorization_request_endpoint": "https://some-issuer.com/some/path/oauth2/par",
+				"require_pushed_authorization_requests": false,
+				"request_parameter_supported": true,
+				"request_uri_parameter_supported": false,
+				"request_object_signing_alg_values_supported": ["RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512"],
+				"discovery.supervisor.pinniped.dev/v1alpha1": {
+					"pinniped_identity_providers_endpoint": "https://some-issuer.com/some/path/v1alpha1/pinniped_identity_providers"
+				}
+			}
+			`),
+		},
+		{
+			name:                    "happy path with a non-default ID token signing algorithm and without client certificates",
+			issuer:                  "https://some-issuer.com/some/path",
+			idTokenSigningAlgorithm: "RS256",
+			method:                  http.MethodGet,
+			path:                    "/some/path" + oidc.WellKnownEndpointPath,
+			wantStatus:              http.StatusOK,
+			wantContentType:         "application/json",
+			wantBodyJSON: here.Doc(`
+			{
+				"issuer": "https://some-issuer.com/some/path",
+				"authorization_endpoint": "https://some-issuer.com/some/path/oauth2/authorize",
+				"token_endpoint": "https://some-issuer.com/some/path/oauth2/token",
+				"jwks_uri": "https://some-issuer.com/some/path/jwks.json",
+				"response_types_supported": ["code"],
+				"response_modes_supported": ["query", "form_post"],
+				"subject_types_supported": ["public"],
+				"id_token_signing_alg_values_supported": ["RS256"],
+				"token_endpoint_auth_methods_supported": ["client_secret_basic", "private_key_jwt"],
+				"token_endpoint_auth_signing_alg_values_supported": ["RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512"],
+				"scopes_supported": ["openid", "offline_access", "pinniped:request-audience", "username", "groups"],
+				"code_challenge_methods_supported": ["S256"],
+				"claims_supported": ["username", "groups", "additionalClaims"],
+				"device_authorization_endpoint": "https://some-issuer.com/some/path/oauth2/device_authorization",
+				"revocation_endpoint": "https://some-issuer.com/some/path/oauth2/revoke",
+				"end_session_endpoint": "https://some-issuer.com/some/path/oauth2/end_session",
+				"introspection_endpoint": "https://some-issuer.com/some/path/oauth2/introspect",
+				"introspection_endpoint_auth_methods_supported": ["client_secret_basic", "private_key_jwt"],
+				"pushed_authorization_request_endpoint": "https://some-issuer.com/some/path/oauth2/par",
+				"require_pushed_authorization_requests": false,
+				"request_parameter_supported": true,
+				"request_uri_parameter_supported": false,
+				"request_object_signing_alg_values_supported": ["RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512"],
+				"discovery.supervisor.pinniped.dev/v1alpha1": {
+					"pinniped_identity_providers_endpoint": "https://some-issuer.com/some/path/v1alpha1/pinniped_identity_providers"
+				}
+			}
+			`),
+		},
+		{
+			name:                    "happy path while changing the ID token signing algorithm, when keys for the old algorithm are still published",
+			issuer:                  "https://some-issuer.com/some/path",
+			idTokenSigningAlgorithm: "RS256",
+			method:                  http.MethodGet,
+			path:                    "/some/path" + oidc.WellKnownEndpointPath,
+			wantStatus:              http.StatusOK,
+			wantContentType:         "application/json",
+			publishedJWKS: &jose.JSONWebKeySet{Keys: []jose.JSONWebKey{
+				{KeyID: "new-key", Algorithm: "RS256", Use: "sig"},
+				{KeyID: "old-key", Algorithm: "ES256", Use: "sig"},
+				{KeyID: "older-key", Algorithm: "ES256", Use: "sig"},
+			}},
+			wantBodyJSON: here.Doc(`
+			{
+				"issuer": "https://some-issuer.com/some/path",
+				"authorization_endpoint": "https://some-issuer.com/some/path/oauth2/authorize",
+				"token_endpoint": "https://some-issuer.com/some/path/oauth2/token",
+				"jwks_uri": "https://some-issuer.com/some/path/jwks.json",
+				"response_types_supported": ["code"],
+				"response_modes_supported": ["query", "form_post"],
+				"subject_types_supported": ["public"],
+				"id_token_signing_alg_values_supported": ["RS256", "ES256"],
+				"token_endpoint_auth_methods_supported": ["client_secret_basic", "private_key_jwt"],
+				"token_endpoint_auth_signing_alg_values_supported": ["RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512"],
+				"scopes_supported": ["openid", "offline_access", "pinniped:request-audience", "username", "groups"],
+				"code_challenge_methods_supported": ["S256"],
+				"claims_supported": ["username", "groups", "additionalClaims"],
+				"device_authorization_endpoint": "https://some-issuer.com/some/path/oauth2/device_authorization",
+				"revocation_endpoint": "https://some-issuer.com/some/path/oauth2/revoke",
+				"end_session_endpoint": "https://some-issuer.com/some/path/oauth2/end_session",
+				"introspection_endpoint": "https://some-issuer.com/some/path/oauth2/introspect",
+				"introspection_endpoint_auth_methods_supported": ["client_secret_basic", "private_key_jwt"],
+				"pushed_authorization_request_endpoint": "https://some-issuer.com/some/path/oauth2/par",
+				"require_pushed_authorization_requests": false,
+				"request_parameter_supported": true,
+				"request_uri_parameter_supported": false,
+				"request_object_signing_alg_values_supported": ["RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512"],
+				"discovery.supervisor.pinniped.dev/v1alpha1": {
+					"pinniped_identity_providers_endpoint": "https://some-issuer.com/some/path/v1alpha1/pinniped_identity_providers"
+				}
+			}
+			`),
+		},
+		{
+			name:                    "happy path with dynamic client registration enabled",
+			issuer:                  "https://some-issuer.com/some/path",
+			idTokenSigningAlgorithm: "ES256",
+			registrationEndpoint:    "https://some-issuer.com/some/path/oauth2/register",
+			method:                  http.MethodGet,
+			path:                    "/some/path" + oidc.WellKnownEndpointPath,
+			wantStatus:              http.StatusOK,
+			wantContentType:         "application/json",
+			wantBodyJSON: here.Doc(`
+			{
+				"issuer": "https://some-issuer.com/some/path",
+				"authorization_endpoint": "https://some-issuer.com/some/path/oauth2/authorize",
+				"token_endpoint": "https://some-issuer.com/some/path/oauth2/token",
+				"jwks_uri": "https://some-issuer.com/some/path/jwks.json",
+				"response_types_supported": ["code"],
+				"response_modes_supported": ["query", "form_post"],
+				"subject_types_supported": ["public"],
+				"id_token_signing_alg_values_supported": ["ES256"],
+				"token_endpoint_auth_methods_supported": ["client_secret_basic", "private_key_jwt"],
+				"token_endpoint_auth_signing_alg_values_supported": ["RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512"],
+				"scopes_supported": ["openid", "offline_access", "pinniped:request-audience", "username", "groups"],
+				"code_challenge_methods_supported": ["S256"],
+				"claims_supported": ["username", "groups", "additionalClaims"],
+				"device_authorization_endpoint": "https://some-issuer.com/some/path/oauth2/device_authorization",
+				"revocation_endpoint": "https://some-issuer.com/some/path/oauth2/revoke",
+				"end_session_endpoint": "https://some-issuer.com/some/path/oauth2/end_session",
+				"introspection_endpoint": "https://some-issuer.com/some/path/oauth2/introspect",
+				"introspection_endpoint_auth_methods_supported": ["client_secret_basic", "private_key_jwt"],
+				"pushed_authorization_request_endpoint": "https://some-issuer.com/some/path/oauth2/par",
+				"require_pushed_authorization_requests": false,
+				"request_parameter_supported": true,
+				"request_uri_parameter_supported": false,
+				"request_object_signing_alg_values_supported": ["RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512"],
+				"registration_endpoint": "https://some-issuer.com/some/path/oauth2/register",
 				"discovery.supervisor.pinniped.dev/v1alpha1": {
 					"pinniped_identity_providers_endpoint": "https://some-issuer.com/some/path/v1alpha1/pinniped_identity_providers"
 				}
@@ -66,7 +209,17 @@ func TestDiscovery(t *testing.T) {
 	}
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
-			handler := NewHandler(test.issuer)
+			jwksProvider := jwks.NewDynamicJWKSProvider()
+			issuerToJWKSMap := map[string]*jose.JSONWebKeySet{
+				// The keys of other issuers should never be advertised.
+				"https://some-other-issuer.com": {Keys: []jose.JSONWebKey{{KeyID: "other-key", Algorithm: "PS512", Use: "sig"}}},
+			}
+			if test.publishedJWKS != nil {
+				issuerToJWKSMap[test.issuer] = test.publishedJWKS
+			}
+			jwksProvider.SetIssuerToJWKSMap(issuerToJWKSMap, nil)
+
+			handler := NewHandler(test.issuer, test.idTokenSigningAlgorithm, jwksProvider, test.registrationEndpoint, test.clientCertificatesRequested)
 			req := httptest.NewRequestWithContext(t.Context(), test.method, test.path, nil)
 			rsp := httptest.NewRecorder()
 			handler.ServeHTTP(rsp, req)
@@ -0,0 +1,175 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package endsession provides a handler for the OIDC end_session endpoint, as described in
+// https://openid.net/specs/openid-connect-rpinitiated-1_0.html.
+package endsession
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/go-jose/go-jose/v4/jwt"
+	"github.com/ory/fosite"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"go.pinniped.dev/internal/federationdomain/endpoints/endsession/endsessionhtml"
+	"go.pinniped.dev/internal/federationdomain/endpoints/jwks"
+	"go.pinniped.dev/internal/httputil/httperr"
+	"go.pinniped.dev/internal/httputil/securityheader"
+	"go.pinniped.dev/internal/plog"
+)
+
+const (
+	idTokenHintParamName           = "id_token_hint"
+	clientIDParamName              = "client_id"
+	postLogoutRedirectURIParamName = "post_logout_redirect_uri"
+	stateParamName                 = "state"
+)
+
+func paramsSafeToLog() sets.Set[string] {
+	return sets.New(
+		// Standard params from https://openid.net/specs/openid-connect-rpinitiated-1_0.html#RPLogout.
+		// Redacting id_token_hint and state params.
+		clientIDParamName, postLogoutRedirectURIParamName, "logout_hint", "ui_locales",
+	)
+}
+
+// SessionRevoker revokes a downstream session by its ID. See sessionrevocation.Revoker.
+type SessionRevoker interface {
+	RevokeSession(ctx context.Context, requestID string) error
+}
+
+type endSessionHandler struct {
+	issuerURL     string
+	jwksProvider  jwks.DynamicJWKSProvider
+	clientManager fosite.ClientManager
+	revoker       SessionRevoker
+	auditLogger   plog.AuditLogger
+}
+
+// idTokenHintClaims are the claims of a Supervisor-issued ID token which are needed to end its session.
+type idTokenHintClaims struct {
+	jwt.Claims
+	AuthorizedParty string `json:"azp"`
+	SessionID       string `json:"sid"`
+}
+
+// NewHandler returns a http.Handler for the end_session endpoint.
+//
+// The id_token_hint param is required. It must be an ID token issued by this FederationDomain, which may be expired.
+// The session identified by its sid claim is revoked, including its upstream tokens. Then the user is redirected to
+// the post_logout_redirect_uri param when it is one of the client's registered redirect URIs, or else they are shown
+// a page which says that they were logged out.
+func NewHandler(
+	issuerURL string,
+	jwksProvider jwks.DynamicJWKSProvider,
+	clientManager fosite.ClientManager,
+	revoker SessionRevoker,
+	auditLogger plog.AuditLogger,
+) http.Handler {
+	h := &endSessionHandler{
+		issuerURL:     issuerURL,
+		jwksProvider:  jwksProvider,
+		clientManager: clientManager,
+		revoker:       revoker,
+		auditLogger:   auditLogger,
+	}
+	return securityheader.WrapWithCustomCSP(httperr.HandlerFunc(h.serveHTTP), endsessionhtml.ContentSecurityPolicy())
+}
+
+func (h *endSessionHandler) serveHTTP(w http.ResponseWriter, r *http.Request) error {
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		return httperr.Newf(http.StatusMethodNotAllowed, "%s (try GET or POST)", r.Method)
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, 1<<20) // 1 MB limit
+
+	if err := h.auditLogger.AuditRequestParams(r, paramsSafeToLog()); err != nil {
+		plog.DebugErr("error parsing end_session request params", err)
+		return httperr.New(http.StatusBadRequest, "error parsing request params")
+	}
+
+	idTokenHint := r.Form.Get(idTokenHintParamName)
+	if idTokenHint == "" {
+		return httperr.New(http.StatusBadRequest, "id_token_hint param is required")
+	}
+
+	claims, err := h.validateIDTokenHint(idTokenHint)
+	if err != nil {
+		plog.InfoErr("invalid id_token_hint", err)
+		return httperr.New(http.StatusBadRequest, "id_token_hint param is invalid")
+	}
+
+	if clientID := r.Form.Get(clientIDParamName); clientID != "" && clientID != claims.AuthorizedParty {
+		return httperr.New(http.StatusBadRequest, "client_id param does not match the id_token_hint")
+	}
+
+	// Validate the redirect before revoking anything, so the client can fix its request and try again.
+	var redirectURL *url.URL
+	if redirectURI := r.Form.Get(postLogoutRedirectURIParamName); redirectURI != "" {
+		client, err := h.clientManager.GetClient(r.Context(), claims.AuthorizedParty)
+		if err != nil {
+			plog.InfoErr("could not find client of id_token_hint", err, "clientID", claims.AuthorizedParty)
+			return httperr.New(http.StatusBadRequest, "post_logout_redirect_uri param is not registered for the client")
+		}
+		redirectURL, err = fosite.MatchRedirectURIWithClientRedirectURIs(redirectURI, client)
+		if err != nil {
+			return httperr.New(http.StatusBadRequest, "post_logout_redirect_uri param is not registered for the client")
+		}
+	}
+
+	// The session may have already ended, e.g. by an earlier logout, in which case there is nothing to revoke.
+	err = h.revoker.RevokeSession(r.Context(), claims.SessionID)
+	if err != nil && !errors.Is(err, fosite.ErrNotFound) {
+		plog.WarningErr("error revoking session", err, "sessionID", claims.SessionID)
+		return httperr.New(http.StatusServiceUnavailable, "could not end session, please try again later")
+	}
+
+	if redirectURL != nil {
+		if state := r.Form.Get(stateParamName); state != "" {
+			query := redirectURL.Query()
+			query.Set(stateParamName, state)
+			redirectURL.RawQuery = query.Encode()
+		}
+		http.Redirect(w, r, redirectURL.String(), http.StatusSeeOther)
+		return nil
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	return endsessionhtml.Template().Execute(w, nil)
+}
+
+// validateIDTokenHint checks the signature and issuer of the ID token, but not its expiration time, because the
+// spec allows logging out with an expired ID token.
+func (h *endSessionHandler) validateIDTokenHint(idTokenHint string) (*idTokenHintClaims, error) {
+	keySet, _ := h.jwksProvider.GetJWKS(h.issuerURL)
+	if keySet == nil {
+		return nil, errors.New("no JWKS found for issuer")
+	}
+
+	token, err := jwt.ParseSigned(idTokenHint, []jose.SignatureAlgorithm{jose.ES256, jose.ES384, jose.RS256, jose.EdDSA})
+	if err != nil {
+		return nil, err
+	}
+
+	var claims idTokenHintClaims
+	if err := token.Claims(*keySet, &claims); err != nil {
+		return nil, err
+	}
+
+	if claims.Issuer != h.issuerURL {
+		return nil, errors.New("wrong issuer")
+	}
+	if claims.AuthorizedParty == "" || !claims.Audience.Contains(claims.AuthorizedParty) {
+		return nil, errors.New("missing or invalid azp claim")
+	}
+	if claims.SessionID == "" {
+		return nil, errors.New("missing sid claim")
+	}
+
+	return &claims, nil
+}
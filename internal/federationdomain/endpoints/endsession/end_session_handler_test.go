@@ -0,0 +1,299 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package endsession
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/go-jose/go-jose/v4/jwt"
+	"github.com/ory/fosite"
+	"github.com/stretchr/testify/require"
+
+	"go.pinniped.dev/internal/auditid"
+	"go.pinniped.dev/internal/federationdomain/clientregistry"
+	"go.pinniped.dev/internal/federationdomain/endpoints/endsession/endsessionhtml"
+	"go.pinniped.dev/internal/federationdomain/endpoints/jwks"
+	"go.pinniped.dev/internal/plog"
+	"go.pinniped.dev/internal/testutil"
+)
+
+const (
+	downstreamIssuer = "https://my-downstream-issuer.com/path"
+	sessionID        = "some-session-id"
+)
+
+type fakeRevoker struct {
+	err            error
+	revokedSession []string
+}
+
+func (f *fakeRevoker) RevokeSession(_ context.Context, requestID string) error {
+	f.revokedSession = append(f.revokedSession, requestID)
+	return f.err
+}
+
+type fakeClientManager struct{}
+
+func (fakeClientManager) GetClient(_ context.Context, id string) (fosite.Client, error) {
+	if id == "pinniped-cli" {
+		return clientregistry.PinnipedCLI(), nil
+	}
+	return nil, fosite.ErrNotFound
+}
+
+func (fakeClientManager) ClientAssertionJWTValid(_ context.Context, _ string) error {
+	return nil
+}
+
+func (fakeClientManager) SetClientAssertionJWT(_ context.Context, _ string, _ time.Time) error {
+	return nil
+}
+
+func TestEndSessionHandler(t *testing.T) {
+	signingKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	otherSigningKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	signIDToken := func(key *ecdsa.PrivateKey, claims any) string {
+		signer, err := jose.NewSigner(
+			jose.SigningKey{Algorithm: jose.ES256, Key: jose.JSONWebKey{Key: key, KeyID: "some-key-id"}},
+			(&jose.SignerOptions{}).WithType("JWT"),
+		)
+		require.NoError(t, err)
+		token, err := jwt.Signed(signer).Claims(claims).Serialize()
+		require.NoError(t, err)
+		return token
+	}
+
+	idTokenClaims := func(editFunc func(claims map[string]any)) map[string]any {
+		claims := map[string]any{
+			"iss": downstreamIssuer,
+			"sub": "some-subject",
+			"aud": []string{"pinniped-cli"},
+			"azp": "pinniped-cli",
+			"sid": sessionID,
+			// Expired ID tokens are still allowed as hints.
+			"exp": time.Now().Add(-time.Hour).Unix(),
+		}
+		if editFunc != nil {
+			editFunc(claims)
+		}
+		return claims
+	}
+
+	goodIDToken := signIDToken(signingKey, idTokenClaims(nil))
+
+	tests := []struct {
+		name       string
+		method     string
+		params     url.Values
+		revokerErr error
+
+		wantStatus             int
+		wantBody               string
+		wantLocation           string
+		wantRevokedSessions    []string
+		wantLoggedOutPage      bool
+		wantAuditLogParams     map[string]any
+		wantNoRequestParamsLog bool
+	}{
+		{
+			name:                "ends the session and shows a page",
+			method:              http.MethodGet,
+			params:              url.Values{"id_token_hint": {goodIDToken}},
+			wantStatus:          http.StatusOK,
+			wantLoggedOutPage:   true,
+			wantRevokedSessions: []string{sessionID},
+			wantAuditLogParams:  map[string]any{"id_token_hint": "redacted"},
+		},
+		{
+			name:   "ends the session and redirects with the state param using POST",
+			method: http.MethodPost,
+			params: url.Values{
+				"id_token_hint":            {goodIDToken},
+				"client_id":                {"pinniped-cli"},
+				"post_logout_redirect_uri": {"http://127.0.0.1:12345/callback"},
+				"state":                    {"some-state"},
+			},
+			wantStatus:          http.StatusSeeOther,
+			wantLocation:        "http://127.0.0.1:12345/callback?state=some-state",
+			wantRevokedSessions: []string{sessionID},
+			wantAuditLogParams: map[string]any{
+				"id_token_hint":            "redacted",
+				"client_id":                "pinniped-cli",
+				"post_logout_redirect_uri": "http://127.0.0.1:12345/callback",
+				"state":                    "redacted",
+			},
+		},
+		{
+			name:                "session was already ended",
+			method:              http.MethodGet,
+			params:              url.Values{"id_token_hint": {goodIDToken}},
+			revokerErr:          fosite.ErrNotFound,
+			wantStatus:          http.StatusOK,
+			wantLoggedOutPage:   true,
+			wantRevokedSessions: []string{sessionID},
+			wantAuditLogParams:  map[string]any{"id_token_hint": "redacted"},
+		},
+		{
+			name:                "error while ending the session",
+			method:              http.MethodGet,
+			params:              url.Values{"id_token_hint": {goodIDToken}},
+			revokerErr:          errors.New("some revocation error"),
+			wantStatus:          http.StatusServiceUnavailable,
+			wantBody:            "Service Unavailable: could not end session, please try again later\n",
+			wantRevokedSessions: []string{sessionID},
+			wantAuditLogParams:  map[string]any{"id_token_hint": "redacted"},
+		},
+		{
+			name:                   "wrong method",
+			method:                 http.MethodPut,
+			params:                 url.Values{"id_token_hint": {goodIDToken}},
+			wantStatus:             http.StatusMethodNotAllowed,
+			wantBody:               "Method Not Allowed: PUT (try GET or POST)\n",
+			wantNoRequestParamsLog: true,
+		},
+		{
+			name:               "missing id_token_hint",
+			method:             http.MethodGet,
+			params:             url.Values{"client_id": {"pinniped-cli"}},
+			wantStatus:         http.StatusBadRequest,
+			wantBody:           "Bad Request: id_token_hint param is required\n",
+			wantAuditLogParams: map[string]any{"client_id": "pinniped-cli"},
+		},
+		{
+			name:               "id_token_hint is not a JWT",
+			method:             http.MethodGet,
+			params:             url.Values{"id_token_hint": {"not-a-jwt"}},
+			wantStatus:         http.StatusBadRequest,
+			wantBody:           "Bad Request: id_token_hint param is invalid\n",
+			wantAuditLogParams: map[string]any{"id_token_hint": "redacted"},
+		},
+		{
+			name:               "id_token_hint was signed by another key",
+			method:             http.MethodGet,
+			params:             url.Values{"id_token_hint": {signIDToken(otherSigningKey, idTokenClaims(nil))}},
+			wantStatus:         http.StatusBadRequest,
+			wantBody:           "Bad Request: id_token_hint param is invalid\n",
+			wantAuditLogParams: map[string]any{"id_token_hint": "redacted"},
+		},
+		{
+			name:   "id_token_hint has the wrong issuer",
+			method: http.MethodGet,
+			params: url.Values{"id_token_hint": {signIDToken(signingKey, idTokenClaims(func(claims map[string]any) {
+				claims["iss"] = "https://some-other-issuer.com"
+			}))}},
+			wantStatus:         http.StatusBadRequest,
+			wantBody:           "Bad Request: id_token_hint param is invalid\n",
+			wantAuditLogParams: map[string]any{"id_token_hint": "redacted"},
+		},
+		{
+			name:   "id_token_hint has no sid claim",
+			method: http.MethodGet,
+			params: url.Values{"id_token_hint": {signIDToken(signingKey, idTokenClaims(func(claims map[string]any) {
+				delete(claims, "sid")
+			}))}},
+			wantStatus:         http.StatusBadRequest,
+			wantBody:           "Bad Request: id_token_hint param is invalid\n",
+			wantAuditLogParams: map[string]any{"id_token_hint": "redacted"},
+		},
+		{
+			name:   "id_token_hint has an azp claim which is not in the audience",
+			method: http.MethodGet,
+			params: url.Values{"id_token_hint": {signIDToken(signingKey, idTokenClaims(func(claims map[string]any) {
+				claims["azp"] = "some-other-client"
+			}))}},
+			wantStatus:         http.StatusBadRequest,
+			wantBody:           "Bad Request: id_token_hint param is invalid\n",
+			wantAuditLogParams: map[string]any{"id_token_hint": "redacted"},
+		},
+		{
+			name:   "client_id does not match the id_token_hint",
+			method: http.MethodGet,
+			params: url.Values{
+				"id_token_hint": {goodIDToken},
+				"client_id":     {"some-other-client"},
+			},
+			wantStatus:         http.StatusBadRequest,
+			wantBody:           "Bad Request: client_id param does not match the id_token_hint\n",
+			wantAuditLogParams: map[string]any{"id_token_hint": "redacted", "client_id": "some-other-client"},
+		},
+		{
+			name:   "post_logout_redirect_uri is not registered for the client",
+			method: http.MethodGet,
+			params: url.Values{
+				"id_token_hint":            {goodIDToken},
+				"post_logout_redirect_uri": {"https://evil.example.com/callback"},
+			},
+			wantStatus: http.StatusBadRequest,
+			wantBody:   "Bad Request: post_logout_redirect_uri param is not registered for the client\n",
+			wantAuditLogParams: map[string]any{
+				"id_token_hint":            "redacted",
+				"post_logout_redirect_uri": "https://evil.example.com/callback",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			jwksProvider := jwks.NewDynamicJWKSProvider()
+			publicJWK := jose.JSONWebKey{Key: signingKey.Public(), KeyID: "some-key-id", Algorithm: string(jose.ES256), Use: "sig"}
+			jwksProvider.SetIssuerToJWKSMap(
+				map[string]*jose.JSONWebKeySet{downstreamIssuer: {Keys: []jose.JSONWebKey{publicJWK}}},
+				map[string]*jose.JSONWebKey{downstreamIssuer: {Key: signingKey, KeyID: "some-key-id"}},
+			)
+			revoker := &fakeRevoker{err: test.revokerErr}
+			auditLogger, actualAuditLog := plog.TestAuditLogger(t)
+
+			subject := NewHandler(downstreamIssuer, jwksProvider, fakeClientManager{}, revoker, auditLogger)
+
+			var req *http.Request
+			if test.method == http.MethodPost {
+				req = httptest.NewRequest(test.method, "/oauth2/end_session", strings.NewReader(test.params.Encode()))
+				req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			} else {
+				req = httptest.NewRequest(test.method, "/oauth2/end_session?"+test.params.Encode(), nil)
+			}
+			req, _ = auditid.NewRequestWithAuditID(req, func() string { return "fake-audit-id" })
+			rsp := httptest.NewRecorder()
+
+			subject.ServeHTTP(rsp, req)
+
+			require.Equal(t, test.wantStatus, rsp.Code, rsp.Body.String())
+			require.Equal(t, test.wantRevokedSessions, revoker.revokedSession)
+			require.Equal(t, endsessionhtml.ContentSecurityPolicy(), rsp.Header().Get("Content-Security-Policy"))
+
+			switch {
+			case test.wantLoggedOutPage:
+				require.Equal(t, "text/html; charset=utf-8", rsp.Header().Get("Content-Type"))
+				require.Contains(t, rsp.Body.String(), `id="logged-out"`)
+			case test.wantLocation != "":
+				require.Equal(t, test.wantLocation, rsp.Header().Get("Location"))
+			default:
+				require.Equal(t, test.wantBody, rsp.Body.String())
+			}
+
+			var wantAuditLogs []testutil.WantedAuditLog
+			if !test.wantNoRequestParamsLog {
+				wantAuditLogs = []testutil.WantedAuditLog{
+					testutil.WantAuditLog("HTTP Request Parameters", map[string]any{"params": test.wantAuditLogParams}),
+				}
+				testutil.WantAuditIDOnEveryAuditLog(wantAuditLogs, "fake-audit-id")
+			}
+			testutil.CompareAuditLogs(t, wantAuditLogs, actualAuditLog.String())
+		})
+	}
+}
@@ -1,4 +1,4 @@
-// Copyright 2021-2024 the Pinniped contributors. All Rights Reserved.
+// Copyright 2021-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 // Package idpdiscovery provides a handler for the upstream IDP discovery endpoint.
@@ -41,8 +41,11 @@ func responseAsJSON(upstreamIDPs federationdomainproviders.FederationDomainIdent
 		PinnipedSupportedIDPTypes: []v1alpha1.PinnipedSupportedIDPType{
 			{Type: v1alpha1.IDPTypeActiveDirectory},
 			{Type: v1alpha1.IDPTypeGitHub},
+			{Type: v1alpha1.IDPTypeGitLab},
 			{Type: v1alpha1.IDPTypeLDAP},
+			{Type: v1alpha1.IDPTypeOAuth2},
 			{Type: v1alpha1.IDPTypeOIDC},
+			{Type: v1alpha1.IDPTypeSAML},
 		},
 	}
 
@@ -49,38 +49,44 @@ func TestIDPDiscovery(t *testing.T) {
 			wantContentType: "application/json",
 			wantFirstResponseBodyJSON: here.Doc(`{
 				"pinniped_identity_providers": [
-					{"name": "a-some-ldap-idp", "type": "ldap",            "flows": ["cli_password", "browser_authcode"]},
-					{"name": "a-some-oidc-idp", "type": "oidc",            "flows": ["browser_authcode"]},
-					{"name": "g-some-github-idp", "type": "github",        "flows": ["browser_authcode"]},
-					{"name": "x-some-ldap-idp", "type": "ldap",            "flows": ["cli_password", "browser_authcode"]},
-					{"name": "x-some-oidc-idp", "type": "oidc",            "flows": ["browser_authcode"]},
-					{"name": "y-some-ad-idp",   "type": "activedirectory", "flows": ["cli_password", "browser_authcode"]},
-					{"name": "z-some-ad-idp",   "type": "activedirectory", "flows": ["cli_password", "browser_authcode"]},
-					{"name": "z-some-ldap-idp", "type": "ldap",            "flows": ["cli_password", "browser_authcode"]},
-					{"name": "z-some-oidc-idp", "type": "oidc",            "flows": ["browser_authcode", "cli_password"]}
+					{"name": "a-some-ldap-idp", "type": "ldap",            "flows": ["cli_password", "browser_authcode", "device_code"]},
+					{"name": "a-some-oidc-idp", "type": "oidc",            "flows": ["browser_authcode", "device_code"]},
+					{"name": "g-some-github-idp", "type": "github",        "flows": ["browser_authcode", "device_code"]},
+					{"name": "x-some-ldap-idp", "type": "ldap",            "flows": ["cli_password", "browser_authcode", "device_code"]},
+					{"name": "x-some-oidc-idp", "type": "oidc",            "flows": ["browser_authcode", "device_code"]},
+					{"name": "y-some-ad-idp",   "type": "activedirectory", "flows": ["cli_password", "browser_authcode", "device_code"]},
+					{"name": "z-some-ad-idp",   "type": "activedirectory", "flows": ["cli_password", "browser_authcode", "device_code"]},
+					{"name": "z-some-ldap-idp", "type": "ldap",            "flows": ["cli_password", "browser_authcode", "device_code"]},
+					{"name": "z-some-oidc-idp", "type": "oidc",            "flows": ["browser_authcode", "cli_password", "device_code"]}
 				],
 				"pinniped_supported_identity_provider_types": [
 					{"type": "activedirectory"},
 					{"type": "github"},
+					{"type": "gitlab"},
 					{"type": "ldap"},
-					{"type": "oidc"}
+					{"type": "oauth2"},
+					{"type": "oidc"},
+					{"type": "saml"}
 				]
 			}`),
 			wantSecondResponseBodyJSON: here.Doc(`{
 				"pinniped_identity_providers": [
-					{"name": "g-some-github-idp",     "type": "github",          "flows": ["browser_authcode"]},
-					{"name": "some-other-ad-idp-1",   "type": "activedirectory", "flows": ["cli_password", "browser_authcode"]},
-					{"name": "some-other-ad-idp-2",   "type": "activedirectory", "flows": ["cli_password", "browser_authcode"]},
-					{"name": "some-other-ldap-idp-1", "type": "ldap",            "flows": ["cli_password", "browser_authcode"]},
-					{"name": "some-other-ldap-idp-2", "type": "ldap",            "flows": ["cli_password", "browser_authcode"]},
-					{"name": "some-other-oidc-idp-1", "type": "oidc",            "flows": ["browser_authcode", "cli_password"]},
-					{"name": "some-other-oidc-idp-2", "type": "oidc",            "flows": ["browser_authcode"]}
+					{"name": "g-some-github-idp",     "type": "github",          "flows": ["browser_authcode", "device_code"]},
+					{"name": "some-other-ad-idp-1",   "type": "activedirectory", "flows": ["cli_password", "browser_authcode", "device_code"]},
+					{"name": "some-other-ad-idp-2",   "type": "activedirectory", "flows": ["cli_password", "browser_authcode", "device_code"]},
+					{"name": "some-other-ldap-idp-1", "type": "ldap",            "flows": ["cli_password", "browser_authcode", "device_code"]},
+					{"name": "some-other-ldap-idp-2", "type": "ldap",            "flows": ["cli_password", "browser_authcode", "device_code"]},
+					{"name": "some-other-oidc-idp-1", "type": "oidc",            "flows": ["browser_authcode", "cli_password", "device_code"]},
+					{"name": "some-other-oidc-idp-2", "type": "oidc",            "flows": ["browser_authcode", "device_code"]}
 				],
 				"pinniped_supported_identity_provider_types": [
 					{"type": "activedirectory"},
 					{"type": "github"},
+					{"type": "gitlab"},
 					{"type": "ldap"},
-					{"type": "oidc"}
+					{"type": "oauth2"},
+					{"type": "oidc"},
+					{"type": "saml"}
 				]
 			}`),
 		},
@@ -97,24 +103,30 @@ func TestIDPDiscovery(t *testing.T) {
 				"pinniped_supported_identity_provider_types": [
 					{"type": "activedirectory"},
 					{"type": "github"},
+					{"type": "gitlab"},
 					{"type": "ldap"},
-					{"type": "oidc"}
+					{"type": "oauth2"},
+					{"type": "oidc"},
+					{"type": "saml"}
 				]
 			}`),
 			wantSecondResponseBodyJSON: here.Doc(`{
 				"pinniped_identity_providers": [
-					{"name": "some-other-ad-idp-1",   "type": "activedirectory", "flows": ["cli_password", "browser_authcode"]},
-					{"name": "some-other-ad-idp-2",   "type": "activedirectory", "flows": ["cli_password", "browser_authcode"]},
-					{"name": "some-other-ldap-idp-1", "type": "ldap",            "flows": ["cli_password", "browser_authcode"]},
-					{"name": "some-other-ldap-idp-2", "type": "ldap",            "flows": ["cli_password", "browser_authcode"]},
-					{"name": "some-other-oidc-idp-1", "type": "oidc",            "flows": ["browser_authcode", "cli_password"]},
-					{"name": "some-other-oidc-idp-2", "type": "oidc",            "flows": ["browser_authcode"]}
+					{"name": "some-other-ad-idp-1",   "type": "activedirectory", "flows": ["cli_password", "browser_authcode", "device_code"]},
+					{"name": "some-other-ad-idp-2",   "type": "activedirectory", "flows": ["cli_password", "browser_authcode", "device_code"]},
+					{"name": "some-other-ldap-idp-1", "type": "ldap",            "flows": ["cli_password", "browser_authcode", "device_code"]},
+					{"name": "some-other-ldap-idp-2", "type": "ldap",            "flows": ["cli_password", "browser_authcode", "device_code"]},
+					{"name": "some-other-oidc-idp-1", "type": "oidc",            "flows": ["browser_authcode", "cli_password", "device_code"]},
+					{"name": "some-other-oidc-idp-2", "type": "oidc",            "flows": ["browser_authcode", "device_code"]}
 				],
 				"pinniped_supported_identity_provider_types": [
 					{"type": "activedirectory"},
 					{"type": "github"},
+					{"type": "gitlab"},
 					{"type": "ldap"},
-					{"type": "oidc"}
+					{"type": "oauth2"},
+					{"type": "oidc"},
+					{"type": "saml"}
 				]
 			}`),
 		},
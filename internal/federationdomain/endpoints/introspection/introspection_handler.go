@@ -0,0 +1,190 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package introspection provides a handler for the OAuth 2.0 token introspection endpoint, as described in
+// https://datatracker.ietf.org/doc/html/rfc7662.
+package introspection
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/ory/fosite"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	oidcapi "go.pinniped.dev/generated/latest/apis/supervisor/oidc"
+	"go.pinniped.dev/internal/auditevent"
+	"go.pinniped.dev/internal/federationdomain/oidc"
+	"go.pinniped.dev/internal/httputil/httperr"
+	"go.pinniped.dev/internal/plog"
+	"go.pinniped.dev/internal/psession"
+)
+
+func paramsSafeToLog() sets.Set[string] {
+	return sets.New(
+		// Standard params from https://datatracker.ietf.org/doc/html/rfc7662#section-2.1.
+		// Redacting token and client_secret params.
+		"token_type_hint", "client_id",
+		// Client authentication params from https://datatracker.ietf.org/doc/html/rfc7523#section-2.2.
+		// Redact client_assertion.
+		"client_assertion_type",
+	)
+}
+
+// clientAuthenticator authenticates the client of a request using any of the client authentication methods
+// which are supported at the token endpoint. It is implemented by *fosite.Fosite.
+type clientAuthenticator interface {
+	AuthenticateClient(ctx context.Context, r *http.Request, form url.Values) (fosite.Client, error)
+}
+
+// response is the body of an introspection response for an active token, as described in
+// https://datatracker.ietf.org/doc/html/rfc7662#section-2.2.
+type response struct {
+	Active    bool     `json:"active"`
+	Scope     string   `json:"scope"`
+	ClientID  string   `json:"client_id"`
+	TokenType string   `json:"token_type"`
+	Exp       int64    `json:"exp"`
+	Iat       int64    `json:"iat"`
+	Sub       string   `json:"sub"`
+	Iss       string   `json:"iss"`
+	Username  string   `json:"username,omitempty"`
+	Groups    []string `json:"groups,omitempty"`
+}
+
+// NewHandler returns a handler for the token introspection endpoint. Only access tokens may be introspected.
+// The caller must authenticate as a confidential OIDCClient, using any of the client authentication methods
+// which are supported at the token endpoint. A token is only reported as active to the client which it was issued
+// to, or to a client which is in the token's granted audience, so that a client cannot learn about the users of
+// other clients. The oauthHelper must be a *fosite.Fosite.
+func NewHandler(
+	issuerURL string,
+	oauthHelper fosite.OAuth2Provider,
+	auditLogger plog.AuditLogger,
+) http.Handler {
+	return httperr.HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+		if r.Method != http.MethodPost {
+			return httperr.Newf(http.StatusMethodNotAllowed, "%s (try POST)", r.Method)
+		}
+
+		if err := auditLogger.AuditRequestParams(r, paramsSafeToLog()); err != nil {
+			oauthHelper.WriteIntrospectionError(r.Context(), w, fosite.ErrInvalidRequest.WithWrap(err))
+			return nil
+		}
+
+		// For dynamic clients, the client ID is from basic auth, not from the request parameters.
+		if clientIDFromBasicAuth, _, basicAuthUsed := r.BasicAuth(); basicAuthUsed {
+			auditLogger.Audit(auditevent.HTTPRequestBasicAuthUsed, &plog.AuditParams{
+				ReqCtx:        r.Context(),
+				KeysAndValues: []any{"clientID", clientIDFromBasicAuth},
+			})
+		}
+
+		authenticator, ok := oauthHelper.(clientAuthenticator)
+		if !ok {
+			// This should never happen, because the oauthHelper is always a *fosite.Fosite.
+			return httperr.New(http.StatusInternalServerError, "cannot authenticate clients")
+		}
+		client, err := authenticator.AuthenticateClient(r.Context(), r, r.PostForm)
+		if err != nil {
+			plog.Info("introspection request client authentication error", oidc.FositeErrorForLog(err)...)
+			oauthHelper.WriteIntrospectionError(r.Context(), w, fosite.ErrRequestUnauthorized.WithWrap(err))
+			return nil
+		}
+		if client.IsPublic() {
+			// Public clients, like the pinniped-cli client, do not have any credentials, so anyone could act as them.
+			oauthHelper.WriteIntrospectionError(r.Context(), w,
+				fosite.ErrRequestUnauthorized.WithHint("Public clients may not introspect tokens."))
+			return nil
+		}
+
+		token := r.PostForm.Get("token")
+		if token == "" {
+			oauthHelper.WriteIntrospectionError(r.Context(), w, fosite.ErrInvalidRequest.WithHint("Missing 'token' parameter."))
+			return nil
+		}
+
+		tokenUse, requester, err := oauthHelper.IntrospectToken(r.Context(), token, fosite.AccessToken, psession.NewPinnipedSession())
+		if err != nil || tokenUse != fosite.AccessToken {
+			// As required by the RFC, this does not reveal why the token is not active.
+			// This is not an error for the caller, because it is the expected result for expired or revoked tokens.
+			if err != nil {
+				plog.Debug("introspection request found an inactive token", oidc.FositeErrorForLog(err)...)
+			}
+			oauthHelper.WriteIntrospectionError(r.Context(), w, fosite.ErrInactiveToken)
+			return nil
+		}
+
+		if !clientMayIntrospect(client, requester) {
+			// Do not reveal anything about the tokens of other clients, not even whether they are active.
+			plog.Debug("introspection request for a token which was issued to another client",
+				"clientID", client.GetID(), "tokenClientID", requester.GetClient().GetID())
+			oauthHelper.WriteIntrospectionError(r.Context(), w, fosite.ErrInactiveToken)
+			return nil
+		}
+
+		// The storage returns the session which was loaded from the stored request,
+		// rather than filling in the empty session which was passed to IntrospectToken.
+		session, ok := requester.GetSession().(*psession.PinnipedSession)
+		if !ok {
+			return httperr.New(http.StatusInternalServerError, "unexpected session type")
+		}
+
+		writeResponse(w, activeTokenResponse(issuerURL, requester, session))
+		return nil
+	})
+}
+
+// clientMayIntrospect returns whether the token of the requester was issued to the client, or was granted to the
+// client as an audience.
+func clientMayIntrospect(client fosite.Client, requester fosite.AccessRequester) bool {
+	return requester.GetClient().GetID() == client.GetID() ||
+		slices.Contains(requester.GetGrantedAudience(), client.GetID())
+}
+
+func activeTokenResponse(issuerURL string, requester fosite.AccessRequester, session *psession.PinnipedSession) *response {
+	resp := &response{
+		Active:    true,
+		Scope:     strings.Join(requester.GetGrantedScopes(), " "),
+		ClientID:  requester.GetClient().GetID(),
+		TokenType: fosite.BearerAccessToken,
+		Exp:       session.GetExpiresAt(fosite.AccessToken).Unix(),
+		Iat:       requester.GetRequestedAt().Unix(),
+		Sub:       session.IDTokenClaims().Subject,
+		Iss:       issuerURL,
+	}
+
+	// The username and groups are only stored in the session when the client was granted the username and groups
+	// scopes, in the same way that they are only included in the ID tokens which were issued to the client.
+	extra := session.IDTokenClaims().Extra
+	if username, ok := extra[oidcapi.IDTokenClaimUsername].(string); ok {
+		resp.Username = username
+	}
+	switch groups := extra[oidcapi.IDTokenClaimGroups].(type) {
+	case []string:
+		resp.Groups = groups
+	case []any:
+		// The groups are a []any after the session was read from storage.
+		resp.Groups = make([]string, 0, len(groups))
+		for _, group := range groups {
+			if groupString, ok := group.(string); ok {
+				resp.Groups = append(resp.Groups, groupString)
+			}
+		}
+	}
+
+	return resp
+}
+
+func writeResponse(w http.ResponseWriter, resp *response) {
+	w.Header().Set("Content-Type", "application/json;charset=UTF-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Pragma", "no-cache")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		plog.Error("could not write introspection response", err)
+	}
+}
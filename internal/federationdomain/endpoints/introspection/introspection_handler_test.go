@@ -0,0 +1,426 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package introspection
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ory/fosite"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+
+	supervisorfake "go.pinniped.dev/generated/latest/client/supervisor/clientset/versioned/fake"
+	"go.pinniped.dev/internal/auditid"
+	"go.pinniped.dev/internal/federationdomain/endpoints/jwks"
+	"go.pinniped.dev/internal/federationdomain/oidc"
+	"go.pinniped.dev/internal/federationdomain/oidcclientvalidator"
+	"go.pinniped.dev/internal/federationdomain/storage"
+	"go.pinniped.dev/internal/federationdomain/strategy"
+	"go.pinniped.dev/internal/plog"
+	"go.pinniped.dev/internal/psession"
+	"go.pinniped.dev/internal/testutil"
+)
+
+const (
+	downstreamIssuer = "https://my-downstream-issuer.com/path"
+	namespace        = "some-namespace"
+	dynamicClientID  = "client.oauth.pinniped.dev-some-client"
+	dynamicClientUID = "some-client-uid"
+)
+
+type tokens struct {
+	accessToken             string
+	accessTokenWithoutScope string
+	expiredAccessToken      string
+	refreshToken            string
+	otherClientAccessToken  string
+	audienceAccessToken     string
+}
+
+func TestIntrospectionHandler(t *testing.T) {
+	requestedAt := time.Now().Add(-time.Minute).UTC().Truncate(time.Second)
+	expiresAt := requestedAt.Add(5 * time.Minute)
+
+	basicAuthParams := func(token string) url.Values {
+		return url.Values{"token": {token}}
+	}
+
+	tests := []struct {
+		name          string
+		method        string
+		params        func(tokens) url.Values
+		basicAuth     func(*http.Request)
+		wantStatus    int
+		wantBodyJSON  string
+		wantAuditLogs []testutil.WantedAuditLog
+	}{
+		{
+			name:   "an active access token",
+			method: http.MethodPost,
+			params: func(tok tokens) url.Values {
+				return url.Values{"token": {tok.accessToken}, "token_type_hint": {"access_token"}}
+			},
+			basicAuth:  func(r *http.Request) { r.SetBasicAuth(dynamicClientID, testutil.PlaintextPassword1) },
+			wantStatus: http.StatusOK,
+			wantBodyJSON: `{
+				"active": true,
+				"scope": "openid username groups",
+				"client_id": "client.oauth.pinniped.dev-some-client",
+				"token_type": "bearer",
+				"exp": ` + jsonInt(expiresAt.Unix()) + `,
+				"iat": ` + jsonInt(requestedAt.Unix()) + `,
+				"sub": "some-subject",
+				"iss": "https://my-downstream-issuer.com/path",
+				"username": "some-username",
+				"groups": ["some-group1", "some-group2"]
+			}`,
+			wantAuditLogs: []testutil.WantedAuditLog{
+				testutil.WantAuditLog("HTTP Request Parameters", map[string]any{
+					"params": map[string]any{"token": "redacted", "token_type_hint": "access_token"},
+				}),
+				testutil.WantAuditLog("HTTP Request Basic Auth", map[string]any{"clientID": dynamicClientID}),
+			},
+		},
+		{
+			name:   "an active access token whose client was not granted the username and groups scopes",
+			method: http.MethodPost,
+			params: func(tok tokens) url.Values {
+				return basicAuthParams(tok.accessTokenWithoutScope)
+			},
+			basicAuth:  func(r *http.Request) { r.SetBasicAuth(dynamicClientID, testutil.PlaintextPassword2) },
+			wantStatus: http.StatusOK,
+			wantBodyJSON: `{
+				"active": true,
+				"scope": "openid",
+				"client_id": "client.oauth.pinniped.dev-some-client",
+				"token_type": "bearer",
+				"exp": ` + jsonInt(expiresAt.Unix()) + `,
+				"iat": ` + jsonInt(requestedAt.Unix()) + `,
+				"sub": "some-subject",
+				"iss": "https://my-downstream-issuer.com/path"
+			}`,
+			wantAuditLogs: []testutil.WantedAuditLog{
+				testutil.WantAuditLog("HTTP Request Parameters", map[string]any{
+					"params": map[string]any{"token": "redacted"},
+				}),
+				testutil.WantAuditLog("HTTP Request Basic Auth", map[string]any{"clientID": dynamicClientID}),
+			},
+		},
+		{
+			name:   "an access token which was issued to another client is not active",
+			method: http.MethodPost,
+			params: func(tok tokens) url.Values {
+				return basicAuthParams(tok.otherClientAccessToken)
+			},
+			basicAuth:    func(r *http.Request) { r.SetBasicAuth(dynamicClientID, testutil.PlaintextPassword1) },
+			wantStatus:   http.StatusOK,
+			wantBodyJSON: `{"active": false}`,
+			wantAuditLogs: []testutil.WantedAuditLog{
+				testutil.WantAuditLog("HTTP Request Parameters", map[string]any{
+					"params": map[string]any{"token": "redacted"},
+				}),
+				testutil.WantAuditLog("HTTP Request Basic Auth", map[string]any{"clientID": dynamicClientID}),
+			},
+		},
+		{
+			name:   "an access token which was issued to another client with this client in its audience",
+			method: http.MethodPost,
+			params: func(tok tokens) url.Values {
+				return basicAuthParams(tok.audienceAccessToken)
+			},
+			basicAuth:  func(r *http.Request) { r.SetBasicAuth(dynamicClientID, testutil.PlaintextPassword1) },
+			wantStatus: http.StatusOK,
+			wantBodyJSON: `{
+				"active": true,
+				"scope": "openid username groups",
+				"client_id": "pinniped-cli",
+				"token_type": "bearer",
+				"exp": ` + jsonInt(expiresAt.Unix()) + `,
+				"iat": ` + jsonInt(requestedAt.Unix()) + `,
+				"sub": "some-subject",
+				"iss": "https://my-downstream-issuer.com/path",
+				"username": "some-username",
+				"groups": ["some-group1", "some-group2"]
+			}`,
+			wantAuditLogs: []testutil.WantedAuditLog{
+				testutil.WantAuditLog("HTTP Request Parameters", map[string]any{
+					"params": map[string]any{"token": "redacted"},
+				}),
+				testutil.WantAuditLog("HTTP Request Basic Auth", map[string]any{"clientID": dynamicClientID}),
+			},
+		},
+		{
+			name:   "an expired access token is not active",
+			method: http.MethodPost,
+			params: func(tok tokens) url.Values {
+				return basicAuthParams(tok.expiredAccessToken)
+			},
+			basicAuth:    func(r *http.Request) { r.SetBasicAuth(dynamicClientID, testutil.PlaintextPassword1) },
+			wantStatus:   http.StatusOK,
+			wantBodyJSON: `{"active": false}`,
+			wantAuditLogs: []testutil.WantedAuditLog{
+				testutil.WantAuditLog("HTTP Request Parameters", map[string]any{
+					"params": map[string]any{"token": "redacted"},
+				}),
+				testutil.WantAuditLog("HTTP Request Basic Auth", map[string]any{"clientID": dynamicClientID}),
+			},
+		},
+		{
+			name:   "a refresh token is never active",
+			method: http.MethodPost,
+			params: func(tok tokens) url.Values {
+				return url.Values{"token": {tok.refreshToken}, "token_type_hint": {"refresh_token"}}
+			},
+			basicAuth:    func(r *http.Request) { r.SetBasicAuth(dynamicClientID, testutil.PlaintextPassword1) },
+			wantStatus:   http.StatusOK,
+			wantBodyJSON: `{"active": false}`,
+			wantAuditLogs: []testutil.WantedAuditLog{
+				testutil.WantAuditLog("HTTP Request Parameters", map[string]any{
+					"params": map[string]any{"token": "redacted", "token_type_hint": "refresh_token"},
+				}),
+				testutil.WantAuditLog("HTTP Request Basic Auth", map[string]any{"clientID": dynamicClientID}),
+			},
+		},
+		{
+			name:   "an unknown token is not active",
+			method: http.MethodPost,
+			params: func(_ tokens) url.Values {
+				return basicAuthParams("pin_at_not-a-real-token")
+			},
+			basicAuth:    func(r *http.Request) { r.SetBasicAuth(dynamicClientID, testutil.PlaintextPassword1) },
+			wantStatus:   http.StatusOK,
+			wantBodyJSON: `{"active": false}`,
+			wantAuditLogs: []testutil.WantedAuditLog{
+				testutil.WantAuditLog("HTTP Request Parameters", map[string]any{
+					"params": map[string]any{"token": "redacted"},
+				}),
+				testutil.WantAuditLog("HTTP Request Basic Auth", map[string]any{"clientID": dynamicClientID}),
+			},
+		},
+		{
+			name:   "the wrong client secret",
+			method: http.MethodPost,
+			params: func(tok tokens) url.Values {
+				return basicAuthParams(tok.accessToken)
+			},
+			basicAuth:  func(r *http.Request) { r.SetBasicAuth(dynamicClientID, "wrong-secret") },
+			wantStatus: http.StatusUnauthorized,
+			wantBodyJSON: `{
+				"error": "request_unauthorized",
+				"error_description": "The request could not be authorized. Check that you provided valid credentials in the right format."
+			}`,
+			wantAuditLogs: []testutil.WantedAuditLog{
+				testutil.WantAuditLog("HTTP Request Parameters", map[string]any{
+					"params": map[string]any{"token": "redacted"},
+				}),
+				testutil.WantAuditLog("HTTP Request Basic Auth", map[string]any{"clientID": dynamicClientID}),
+			},
+		},
+		{
+			name:   "no client authentication",
+			method: http.MethodPost,
+			params: func(tok tokens) url.Values {
+				return basicAuthParams(tok.accessToken)
+			},
+			wantStatus: http.StatusUnauthorized,
+			wantBodyJSON: `{
+				"error": "request_unauthorized",
+				"error_description": "The request could not be authorized. Check that you provided valid credentials in the right format."
+			}`,
+			wantAuditLogs: []testutil.WantedAuditLog{
+				testutil.WantAuditLog("HTTP Request Parameters", map[string]any{
+					"params": map[string]any{"token": "redacted"},
+				}),
+			},
+		},
+		{
+			name:   "a public client may not introspect tokens",
+			method: http.MethodPost,
+			params: func(tok tokens) url.Values {
+				return url.Values{"token": {tok.accessToken}, "client_id": {"pinniped-cli"}}
+			},
+			wantStatus: http.StatusUnauthorized,
+			wantBodyJSON: `{
+				"error": "request_unauthorized",
+				"error_description": "The request could not be authorized. Public clients may not introspect tokens."
+			}`,
+			wantAuditLogs: []testutil.WantedAuditLog{
+				testutil.WantAuditLog("HTTP Request Parameters", map[string]any{
+					"params": map[string]any{"client_id": "pinniped-cli", "token": "redacted"},
+				}),
+			},
+		},
+		{
+			name:   "missing token",
+			method: http.MethodPost,
+			params: func(_ tokens) url.Values {
+				return url.Values{"token_type_hint": {"access_token"}}
+			},
+			basicAuth:  func(r *http.Request) { r.SetBasicAuth(dynamicClientID, testutil.PlaintextPassword1) },
+			wantStatus: http.StatusBadRequest,
+			wantBodyJSON: `{
+				"error": "invalid_request",
+				"error_description": "The request is missing a required parameter, includes an invalid parameter value, includes a parameter more than once, or is otherwise malformed. Missing 'token' parameter."
+			}`,
+			wantAuditLogs: []testutil.WantedAuditLog{
+				testutil.WantAuditLog("HTTP Request Parameters", map[string]any{
+					"params": map[string]any{"token_type_hint": "access_token"},
+				}),
+				testutil.WantAuditLog("HTTP Request Basic Auth", map[string]any{"clientID": dynamicClientID}),
+			},
+		},
+		{
+			name:   "GET is not allowed",
+			method: http.MethodGet,
+			params: func(tok tokens) url.Values {
+				return basicAuthParams(tok.accessToken)
+			},
+			basicAuth:  func(r *http.Request) { r.SetBasicAuth(dynamicClientID, testutil.PlaintextPassword1) },
+			wantStatus: http.StatusMethodNotAllowed,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			oidcClient, secret := testutil.FullyCapableOIDCClientAndStorageSecret(t,
+				namespace, dynamicClientID, dynamicClientUID, "https://some-webapp.com/callback", nil,
+				[]string{testutil.HashedPassword1AtGoMinCost, testutil.HashedPassword2AtGoMinCost},
+				oidcclientvalidator.Validate)
+			kubeClient := kubefake.NewClientset(secret)
+			secrets := kubeClient.CoreV1().Secrets(namespace)
+			oidcClientsClient := supervisorfake.NewSimpleClientset(oidcClient).ConfigV1alpha1().OIDCClients(namespace)
+			timeoutsConfiguration := oidc.DefaultOIDCTimeoutsConfiguration()
+			// Use lower minimum required bcrypt cost than we would use in production to keep unit the tests fast.
+			kubeStorage := storage.NewKubeStorage(secrets, oidcClientsClient, timeoutsConfiguration, bcrypt.MinCost)
+			hmacSecretFunc := func() []byte { return []byte("some secret - must have at least 32 bytes") }
+			oauthHelper := oidc.FositeOauth2Helper(kubeStorage, downstreamIssuer, hmacSecretFunc, jwks.NewDynamicJWKSProvider(), timeoutsConfiguration)
+
+			client, err := kubeStorage.GetClient(t.Context(), dynamicClientID)
+			require.NoError(t, err)
+			otherClient, err := kubeStorage.GetClient(t.Context(), "pinniped-cli")
+			require.NoError(t, err)
+			tok := tokens{
+				accessToken: createAccessToken(t, kubeStorage, hmacSecretFunc, "request1", client,
+					fosite.Arguments{"openid", "username", "groups"}, nil, requestedAt, expiresAt),
+				accessTokenWithoutScope: createAccessToken(t, kubeStorage, hmacSecretFunc, "request2", client,
+					fosite.Arguments{"openid"}, nil, requestedAt, expiresAt),
+				expiredAccessToken: createAccessToken(t, kubeStorage, hmacSecretFunc, "request3", client,
+					fosite.Arguments{"openid", "username", "groups"}, nil, requestedAt, time.Now().Add(-time.Second)),
+				refreshToken: createRefreshToken(t, kubeStorage, hmacSecretFunc, client),
+				otherClientAccessToken: createAccessToken(t, kubeStorage, hmacSecretFunc, "request5", otherClient,
+					fosite.Arguments{"openid", "username", "groups"}, nil, requestedAt, expiresAt),
+				audienceAccessToken: createAccessToken(t, kubeStorage, hmacSecretFunc, "request6", otherClient,
+					fosite.Arguments{"openid", "username", "groups"}, fosite.Arguments{dynamicClientID}, requestedAt, expiresAt),
+			}
+
+			auditLogger, actualAuditLog := plog.TestAuditLogger(t)
+
+			subject := NewHandler(downstreamIssuer, oauthHelper, auditLogger)
+
+			params := test.params(tok)
+			req := httptest.NewRequest(test.method, "/oauth2/introspect", strings.NewReader(params.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			if test.basicAuth != nil {
+				test.basicAuth(req)
+			}
+			req, _ = auditid.NewRequestWithAuditID(req, func() string { return "fake-audit-id" })
+			rsp := httptest.NewRecorder()
+
+			subject.ServeHTTP(rsp, req)
+
+			require.Equal(t, test.wantStatus, rsp.Code, rsp.Body.String())
+			if test.wantBodyJSON != "" {
+				require.Equal(t, "no-store", rsp.Header().Get("Cache-Control"))
+				require.JSONEq(t, test.wantBodyJSON, rsp.Body.String())
+			}
+
+			testutil.WantAuditIDOnEveryAuditLog(test.wantAuditLogs, "fake-audit-id")
+			testutil.CompareAuditLogs(t, test.wantAuditLogs, actualAuditLog.String())
+		})
+	}
+}
+
+// createAccessToken stores a downstream session with an access token, like the token endpoint would.
+func createAccessToken(
+	t *testing.T,
+	kubeStorage *storage.KubeStorage,
+	hmacSecretFunc func() []byte,
+	requestID string,
+	client fosite.Client,
+	grantedScopes fosite.Arguments,
+	grantedAudience fosite.Arguments,
+	requestedAt time.Time,
+	expiresAt time.Time,
+) string {
+	t.Helper()
+
+	request := fosite.NewRequest()
+	request.ID = requestID
+	request.Client = client
+	request.RequestedAt = requestedAt
+	request.GrantedScope = grantedScopes
+	request.GrantedAudience = grantedAudience
+	session := psession.NewPinnipedSession()
+	session.IDTokenClaims().Subject = "some-subject"
+	session.SetExpiresAt(fosite.AccessToken, expiresAt)
+	session.Custom = &psession.CustomSessionData{
+		Username:     "some-username",
+		ProviderUID:  "some-provider-uid",
+		ProviderName: "some-provider-name",
+		ProviderType: psession.ProviderTypeOIDC,
+		OIDC:         &psession.OIDCSessionData{},
+	}
+	session.IDTokenClaims().Extra = map[string]any{}
+	if grantedScopes.Has("username") {
+		session.IDTokenClaims().Extra["username"] = "some-username"
+	}
+	if grantedScopes.Has("groups") {
+		session.IDTokenClaims().Extra["groups"] = []string{"some-group1", "some-group2"}
+	}
+	request.Session = session
+
+	hmacStrategy := strategy.NewDynamicOauth2HMACStrategy(&fosite.Config{}, hmacSecretFunc)
+	accessToken, accessSignature, err := hmacStrategy.GenerateAccessToken(t.Context(), request)
+	require.NoError(t, err)
+	require.NoError(t, kubeStorage.CreateAccessTokenSession(t.Context(), accessSignature, request))
+
+	return accessToken
+}
+
+// createRefreshToken stores a downstream session with a refresh token, like the token endpoint would.
+func createRefreshToken(t *testing.T, kubeStorage *storage.KubeStorage, hmacSecretFunc func() []byte, client fosite.Client) string {
+	t.Helper()
+
+	request := fosite.NewRequest()
+	request.ID = "request4"
+	request.Client = client
+	request.GrantedScope = fosite.Arguments{"openid", "offline_access"}
+	session := psession.NewPinnipedSession()
+	session.Custom = &psession.CustomSessionData{
+		Username:     "some-username",
+		ProviderUID:  "some-provider-uid",
+		ProviderName: "some-provider-name",
+		ProviderType: psession.ProviderTypeOIDC,
+		OIDC:         &psession.OIDCSessionData{},
+	}
+	request.Session = session
+
+	hmacStrategy := strategy.NewDynamicOauth2HMACStrategy(&fosite.Config{}, hmacSecretFunc)
+	refreshToken, refreshSignature, err := hmacStrategy.GenerateRefreshToken(t.Context(), request)
+	require.NoError(t, err)
+	require.NoError(t, kubeStorage.CreateRefreshTokenSession(t.Context(), refreshSignature, "", request))
+
+	return refreshToken
+}
+
+func jsonInt(i int64) string {
+	return strconv.FormatInt(i, 10)
+}
@@ -1,4 +1,4 @@
-// Copyright 2022-2024 the Pinniped contributors. All Rights Reserved.
+// Copyright 2022-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package login
@@ -8,16 +8,18 @@ import (
 
 	"go.pinniped.dev/internal/federationdomain/endpoints/login/loginhtml"
 	"go.pinniped.dev/internal/federationdomain/endpoints/loginurl"
+	"go.pinniped.dev/internal/federationdomain/i18n"
 	"go.pinniped.dev/internal/federationdomain/oidc"
 	"go.pinniped.dev/internal/federationdomain/stateparam"
 )
 
+// These are the keys of the error messages in the i18n message catalogs.
 const (
-	internalErrorMessage                    = "An internal error occurred. Please contact your administrator for help."
-	incorrectUsernameOrPasswordErrorMessage = "Incorrect username or password."
+	internalErrorMessageKey                    = "login.error.internal"
+	incorrectUsernameOrPasswordErrorMessageKey = "login.error.incorrectUsernameOrPassword"
 )
 
-func NewGetHandler(loginPath string) HandlerFunc {
+func NewGetHandler(loginPath string, page *loginhtml.Page) HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request, encodedState stateparam.Encoded, decodedState *oidc.UpstreamStateParamData) error {
 		alertMessage, hasAlert := getAlert(r)
 
@@ -28,17 +30,17 @@ func NewGetHandler(loginPath string) HandlerFunc {
 			HasAlertError: hasAlert,
 			AlertMessage:  alertMessage,
 		}
-		return loginhtml.Template().Execute(w, pageInputs)
+		return page.TemplateForContext(r.Context()).Execute(w, pageInputs)
 	}
 }
 
 func getAlert(r *http.Request) (string, bool) {
 	errorParamValue := r.URL.Query().Get(loginurl.ErrParamName)
 
-	message := internalErrorMessage
+	messageKey := internalErrorMessageKey
 	if errorParamValue == string(loginurl.ShowBadUserPassErr) {
-		message = incorrectUsernameOrPasswordErrorMessage
+		messageKey = incorrectUsernameOrPasswordErrorMessageKey
 	}
 
-	return message, errorParamValue != ""
+	return i18n.FromContext(r.Context()).Translate(messageKey), errorParamValue != ""
 }
@@ -11,6 +11,7 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"go.pinniped.dev/internal/federationdomain/endpoints/login/loginhtml"
+	"go.pinniped.dev/internal/federationdomain/i18n"
 	"go.pinniped.dev/internal/federationdomain/idplister"
 	"go.pinniped.dev/internal/federationdomain/oidc"
 	"go.pinniped.dev/internal/federationdomain/stateparam"
@@ -98,7 +99,7 @@ func TestGetLogin(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			t.Parallel()
 
-			handler := NewGetHandler(testPath)
+			handler := NewGetHandler(testPath, loginhtml.DefaultPage())
 			target := testPath + "?state=" + tt.encodedState.String()
 			if tt.errParam != "" {
 				target += "&err=" + tt.errParam
@@ -116,3 +117,37 @@ func TestGetLogin(t *testing.T) {
 		})
 	}
 }
+
+func TestGetLoginInNegotiatedLanguage(t *testing.T) {
+	const testPath = "/some/path/login"
+
+	handler := i18n.WithNegotiatedLanguage(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		err := NewGetHandler(testPath, loginhtml.DefaultPage())(w, r, "fake-encoded-state-value", &oidc.UpstreamStateParamData{
+			UpstreamName: "some-ldap-idp",
+			UpstreamType: "ldap",
+		})
+		require.NoError(t, err)
+	}), "fr")
+
+	req := httptest.NewRequestWithContext(t.Context(), http.MethodGet, testPath+"?err=incorrect_username_or_password", nil)
+	req.Header.Set("Accept-Language", "de-DE, de;q=0.9")
+	rsp := httptest.NewRecorder()
+	handler.ServeHTTP(rsp, req)
+
+	require.Equal(t, http.StatusOK, rsp.Code)
+	body := rsp.Body.String()
+	require.Contains(t, body, `<html lang="de">`)
+	require.Contains(t, body, `<h1>Bei some-ldap-idp anmelden</h1>`)
+	require.Contains(t, body, `>Falscher Benutzername oder falsches Passwort.</span>`)
+	require.Contains(t, body, `placeholder="Benutzername"`)
+
+	// Without an Accept-Language header, the default language of the FederationDomain is used.
+	req = httptest.NewRequestWithContext(t.Context(), http.MethodGet, testPath+"?err=internal_error", nil)
+	rsp = httptest.NewRecorder()
+	handler.ServeHTTP(rsp, req)
+
+	body = rsp.Body.String()
+	require.Contains(t, body, `<html lang="fr">`)
+	require.Contains(t, body, `<h1>Se connecter à some-ldap-idp</h1>`)
+	require.Contains(t, body, `>Une erreur interne s&#39;est produite.`)
+}
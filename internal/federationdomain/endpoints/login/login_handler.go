@@ -49,6 +49,8 @@ func NewHandler(
 	cookieDecoder oidc.Decoder,
 	getHandler HandlerFunc, // use NewGetHandler() for production
 	postHandler HandlerFunc, // use NewPostHandler() for production
+	loginPage *loginhtml.Page,
+	formPostPage *formposthtml.Page,
 	auditLogger plog.AuditLogger,
 ) http.Handler {
 	loginHandler := httperr.HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
@@ -90,15 +92,15 @@ func NewHandler(
 		return handler(w, r, encodedState, decodedState)
 	})
 
-	return wrapSecurityHeaders(loginHandler)
+	return wrapSecurityHeaders(loginHandler, loginPage, formPostPage)
 }
 
-func wrapSecurityHeaders(handler http.Handler) http.Handler {
+func wrapSecurityHeaders(handler http.Handler, loginPage *loginhtml.Page, formPostPage *formposthtml.Page) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		wrapped := securityheader.WrapWithCustomCSP(handler, loginhtml.ContentSecurityPolicy())
+		wrapped := securityheader.WrapWithCustomCSP(handler, loginPage.ContentSecurityPolicy())
 		if r.Method == http.MethodPost {
 			// POST requests can result in the form_post html page, so allow it with CSP headers.
-			wrapped = securityheader.WrapWithCustomCSP(handler, formposthtml.ContentSecurityPolicy())
+			wrapped = securityheader.WrapWithCustomCSP(handler, formPostPage.ContentSecurityPolicy())
 		}
 		wrapped.ServeHTTP(w, r)
 	})
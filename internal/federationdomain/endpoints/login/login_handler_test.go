@@ -15,7 +15,9 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"go.pinniped.dev/internal/auditid"
+	"go.pinniped.dev/internal/federationdomain/endpoints/login/loginhtml"
 	"go.pinniped.dev/internal/federationdomain/endpoints/loginurl"
+	"go.pinniped.dev/internal/federationdomain/formposthtml"
 	"go.pinniped.dev/internal/federationdomain/oidc"
 	"go.pinniped.dev/internal/federationdomain/stateparam"
 	"go.pinniped.dev/internal/httputil/httperr"
@@ -527,7 +529,7 @@ func TestLoginEndpoint(t *testing.T) {
 
 			auditLogger, actualAuditLog := plog.TestAuditLogger(t)
 
-			subject := NewHandler(happyStateCodec, happyCookieCodec, testGetHandler, testPostHandler, auditLogger)
+			subject := NewHandler(happyStateCodec, happyCookieCodec, testGetHandler, testPostHandler, loginhtml.DefaultPage(), formposthtml.DefaultPage(), auditLogger)
 
 			subject.ServeHTTP(rsp, req)
 
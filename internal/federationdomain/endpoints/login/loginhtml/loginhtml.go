@@ -1,17 +1,22 @@
-// Copyright 2022-2024 the Pinniped contributors. All Rights Reserved.
+// Copyright 2022-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 // Package loginhtml defines HTML templates used by the Supervisor.
 package loginhtml
 
 import (
+	"bytes"
+	"context"
 	_ "embed" // Needed to trigger //go:embed directives below.
+	"fmt"
 	"html/template"
 	"strings"
 
 	"github.com/tdewolff/minify/v2/minify"
 
+	"go.pinniped.dev/internal/federationdomain/branding"
 	"go.pinniped.dev/internal/federationdomain/csp"
+	"go.pinniped.dev/internal/federationdomain/i18n"
 )
 
 //nolint:gochecknoglobals // This package uses globals to ensure that all parsing and minifying happens at init.
@@ -23,19 +28,44 @@ var (
 	//go:embed login_form.gohtml
 	rawHTMLTemplate string
 
-	// Parse the Go templated HTML and inject functions providing the minified inline CSS and JS.
-	parsedHTMLTemplate = template.Must(template.New("login_form.gohtml").Funcs(template.FuncMap{
-		"minifiedCSS": func() template.CSS { return template.CSS(CSS()) }, //nolint:gosec // This is 100% static input, not attacker-controlled.
-	}).Parse(rawHTMLTemplate))
+	// Parse the Go templated HTML once per language and inject functions providing the minified inline CSS and JS.
+	parsedHTMLTemplates = mustParseTemplates(rawHTMLTemplate, minifiedCSS, nil)
+	parsedHTMLTemplate  = parsedHTMLTemplates.ForLanguage(i18n.DefaultLanguage)
 
 	// Generate the CSP header value once since it's effectively constant.
-	cspValue = strings.Join([]string{
-		`default-src 'none'`,
-		`style-src '` + csp.Hash(minifiedCSS) + `'`,
-		`frame-ancestors 'none'`,
-	}, "; ")
+	cspValue = contentSecurityPolicy(minifiedCSS, false)
+
+	defaultPage = &Page{templates: parsedHTMLTemplates, css: minifiedCSS, csp: cspValue}
 )
 
+func parseTemplates(text string, css string, b *branding.Branding) (*i18n.Templates, error) {
+	return i18n.ParseTemplates(func(c *i18n.Catalog) (*template.Template, error) {
+		return template.New("login_form.gohtml").Funcs(template.FuncMap{
+			"minifiedCSS": func() template.CSS { return template.CSS(css) }, //nolint:gosec // This is static or validated input, not attacker-controlled.
+			"branding":    func() *branding.Branding { return b },
+		}).Funcs(i18n.TemplateFuncs(c)).Parse(text)
+	})
+}
+
+func mustParseTemplates(text string, css string, b *branding.Branding) *i18n.Templates {
+	templates, err := parseTemplates(text, css, b)
+	if err != nil {
+		panic(err)
+	}
+	return templates
+}
+
+func contentSecurityPolicy(css string, allowImages bool) string {
+	directives := []string{
+		`default-src 'none'`,
+		`style-src '` + csp.Hash(css) + `'`,
+	}
+	if allowImages {
+		directives = append(directives, `img-src data:`)
+	}
+	return strings.Join(append(directives, `frame-ancestors 'none'`), "; ")
+}
+
 func panicOnError(s string, err error) string {
 	if err != nil {
 		panic(err)
@@ -48,12 +78,83 @@ func panicOnError(s string, err error) string {
 // See https://developer.mozilla.org/en-US/docs/Web/HTTP/Headers/Content-Security-Policy.
 func ContentSecurityPolicy() string { return cspValue }
 
-// Template returns the html/template.Template for rendering the login page.
+// Template returns the html/template.Template for rendering the login page in the default language.
 func Template() *template.Template { return parsedHTMLTemplate }
 
 // CSS returns the minified CSS that will be embedded into the page template.
 func CSS() string { return minifiedCSS }
 
+// Page is the login page of a FederationDomain, which may be customized by branding.
+type Page struct {
+	templates *i18n.Templates
+	css       string
+	csp       string
+}
+
+// DefaultPage returns the login page without any branding.
+func DefaultPage() *Page { return defaultPage }
+
+// NewPage returns a login page customized by the given branding. When the branding overrides the template of the
+// page, the template is rendered with sample data and validated to be compatible with the page's CSP.
+func NewPage(b *branding.Branding) (*Page, error) {
+	if b == nil {
+		return defaultPage, nil
+	}
+
+	brandedCSS := rawCSS + b.CSS()
+	if b.PrimaryColor != "" {
+		brandedCSS += fmt.Sprintf(`.form-field input[type="submit"], .form-field input[type="submit"]:focus, `+
+			`.form-field input[type="submit"]:hover { background-color: %s; }`, b.PrimaryColor)
+	}
+	css, err := minify.CSS(brandedCSS)
+	if err != nil {
+		return nil, fmt.Errorf("could not minify CSS for login page: %w", err)
+	}
+
+	text, overridden := b.TemplateOverride(branding.LoginTemplateKey)
+	if !overridden {
+		text = rawHTMLTemplate
+	}
+	templates, err := parseTemplates(text, css, b)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse template %q: %w", branding.LoginTemplateKey, err)
+	}
+	if overridden {
+		for _, tmpl := range templates.All() {
+			var buf bytes.Buffer
+			if err := tmpl.Execute(&buf, &PageData{
+				State:         "sample-state",
+				IDPName:       "sample-idp-name",
+				HasAlertError: true,
+				AlertMessage:  "sample alert message",
+				PostPath:      "/sample/login",
+			}); err != nil {
+				return nil, fmt.Errorf("could not render template %q: %w", branding.LoginTemplateKey, err)
+			}
+			if err := branding.CheckRenderedPage(buf.String(), nil, []string{css}); err != nil {
+				return nil, fmt.Errorf("template %q is not compatible with the Content-Security-Policy of the page: %w", branding.LoginTemplateKey, err)
+			}
+		}
+	}
+
+	return &Page{templates: templates, css: css, csp: contentSecurityPolicy(css, b.Logo != "" || overridden)}, nil
+}
+
+// ContentSecurityPolicy returns the Content-Security-Policy header value to make the Template() operate correctly.
+func (p *Page) ContentSecurityPolicy() string { return p.csp }
+
+// Template returns the html/template.Template for rendering the login page in the default language.
+func (p *Page) Template() *template.Template { return p.templates.ForLanguage(i18n.DefaultLanguage) }
+
+// TemplateForContext returns the html/template.Template for rendering the login page in the language which was
+// selected for the request by i18n.WithNegotiatedLanguage.
+func (p *Page) TemplateForContext(ctx context.Context) *template.Template {
+	return p.templates.ForContext(ctx)
+}
+
+// CSS returns the minified CSS that will be embedded into the page template.
+func (p *Page) CSS() string { return p.css }
+
 // PageData represents the inputs to the template.
 type PageData struct {
 	State         string
@@ -1,15 +1,20 @@
-// Copyright 2022-2023 the Pinniped contributors. All Rights Reserved.
+// Copyright 2022-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package loginhtml
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"testing"
 
 	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
 
+	"go.pinniped.dev/internal/federationdomain/branding"
+	"go.pinniped.dev/internal/federationdomain/csp"
+	"go.pinniped.dev/internal/federationdomain/i18n"
 	"go.pinniped.dev/internal/testutil"
 )
 
@@ -54,6 +59,26 @@ func TestTemplate(t *testing.T) {
 	require.Equal(t, expectedHTMLWithoutAlert, buf.String())
 }
 
+func TestTemplateForContext(t *testing.T) {
+	page := DefaultPage()
+	require.Same(t, Template(), page.TemplateForContext(context.Background()))
+
+	for _, lang := range i18n.Languages() {
+		t.Run(lang, func(t *testing.T) {
+			c := i18n.ForLanguage(lang)
+			var buf bytes.Buffer
+			require.NoError(t, page.TemplateForContext(i18n.WithCatalog(context.Background(), c)).Execute(&buf, &PageData{
+				PostPath: "/login",
+				State:    "state",
+				IDPName:  "idp",
+			}))
+			require.Contains(t, buf.String(), `<html lang="`+lang+`">`)
+			require.Contains(t, buf.String(), `<h1>`+c.Translate("login.heading", "idp")+`</h1>`)
+			require.Contains(t, buf.String(), `value="`+c.Translate("login.submit")+`"`)
+		})
+	}
+}
+
 func TestContentSecurityPolicy(t *testing.T) {
 	require.Equal(t, testExpectedCSP, ContentSecurityPolicy())
 }
@@ -66,3 +91,75 @@ func TestHelpers(t *testing.T) {
 	require.Equal(t, "test", panicOnError("test", nil))
 	require.PanicsWithError(t, "some error", func() { panicOnError("", fmt.Errorf("some error")) })
 }
+
+func TestNewPage(t *testing.T) {
+	page, err := NewPage(nil)
+	require.NoError(t, err)
+	require.Same(t, DefaultPage(), page)
+	require.Equal(t, testExpectedCSP, DefaultPage().ContentSecurityPolicy())
+	require.Equal(t, testExpectedCSS, DefaultPage().CSS())
+	require.Same(t, Template(), DefaultPage().Template())
+
+	b, err := branding.FromConfigMap(&corev1.ConfigMap{
+		Data: map[string]string{
+			"primaryColor": "#123456",
+			"helpText":     "Contact the help desk.",
+			"footerLinks":  "- text: Privacy\n  url: https://example.com/privacy\n",
+		},
+		BinaryData: map[string][]byte{"logo": []byte("\x89PNG\r\n\x1a\nsome-fake-image-data")},
+	})
+	require.NoError(t, err)
+	page, err = NewPage(b)
+	require.NoError(t, err)
+	require.Contains(t, page.CSS(), "background-color:#123456")
+	require.Contains(t, page.ContentSecurityPolicy(), "style-src '"+csp.Hash(page.CSS())+"'; img-src data:; ")
+
+	var buf bytes.Buffer
+	require.NoError(t, page.Template().Execute(&buf, &PageData{PostPath: "/login", State: "state", IDPName: "idp"}))
+	require.Contains(t, buf.String(), `<img class="branding-logo" src="data:image/png;base64,`)
+	require.Contains(t, buf.String(), `<p class="branding-help">Contact the help desk.</p>`)
+	require.Contains(t, buf.String(), `<a href="https://example.com/privacy">Privacy</a>`)
+	require.NoError(t, branding.CheckRenderedPage(buf.String(), nil, []string{page.CSS()}))
+}
+
+func TestNewPageWithTemplateOverride(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		wantErr  string
+	}{
+		{
+			name:     "valid",
+			template: `<html><head><style>{{minifiedCSS}}</style></head><body><form action="{{.PostPath}}" method="post"></form></body></html>`,
+		},
+		{
+			name:     "parse error",
+			template: `{{.PostPath`,
+			wantErr:  `could not parse template "login.gohtml": template: login_form.gohtml:1: unclosed action`,
+		},
+		{
+			name:     "render error",
+			template: `{{.NoSuchField}}`,
+			wantErr:  `could not render template "login.gohtml": template: login_form.gohtml:1:2: executing "login_form.gohtml" at <.NoSuchField>: can't evaluate field NoSuchField in type *loginhtml.PageData`,
+		},
+		{
+			name:     "inline script",
+			template: `<html><body><script>alert(1)</script></body></html>`,
+			wantErr:  `template "login.gohtml" is not compatible with the Content-Security-Policy of the page: <script> elements may only contain {{minifiedJS}}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := branding.FromConfigMap(&corev1.ConfigMap{Data: map[string]string{"login.gohtml": tt.template}})
+			require.NoError(t, err)
+			page, err := NewPage(b)
+			if tt.wantErr != "" {
+				require.EqualError(t, err, tt.wantErr)
+				require.Nil(t, page)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, "default-src 'none'; style-src '"+csp.Hash(page.CSS())+"'; img-src data:; frame-ancestors 'none'", page.ContentSecurityPolicy())
+		})
+	}
+}
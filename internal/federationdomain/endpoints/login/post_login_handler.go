@@ -12,9 +12,11 @@ import (
 
 	"go.pinniped.dev/internal/auditevent"
 	"go.pinniped.dev/internal/federationdomain/downstreamsession"
+	"go.pinniped.dev/internal/federationdomain/endpoints/device"
 	"go.pinniped.dev/internal/federationdomain/endpoints/loginurl"
 	"go.pinniped.dev/internal/federationdomain/federationdomainproviders"
 	"go.pinniped.dev/internal/federationdomain/oidc"
+	"go.pinniped.dev/internal/federationdomain/resolvedprovider"
 	"go.pinniped.dev/internal/federationdomain/resolvedprovider/resolvedldap"
 	"go.pinniped.dev/internal/federationdomain/stateparam"
 	"go.pinniped.dev/internal/httputil/httperr"
@@ -25,6 +27,7 @@ func NewPostHandler(
 	issuerURL string,
 	upstreamIDPs federationdomainproviders.FederationDomainIdentityProvidersFinderI,
 	oauthHelper fosite.OAuth2Provider,
+	deviceStorage device.UserCodeStorage,
 	auditLogger plog.AuditLogger,
 ) HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request, encodedState stateparam.Encoded, decodedState *oidc.UpstreamStateParamData) error {
@@ -47,6 +50,11 @@ func NewPostHandler(
 			},
 		})
 
+		if decodedState.DeviceUserCodeSignature != "" {
+			// This login was started by the device verification page instead of by the authorization endpoint.
+			return handleDeviceLogin(w, r, issuerURL, encodedState, decodedState, idp, deviceStorage, auditLogger)
+		}
+
 		// Get the original params that were used at the authorization endpoint.
 		downstreamAuthParams, err := url.ParseQuery(decodedState.AuthParams)
 		if err != nil {
@@ -126,6 +134,43 @@ func NewPostHandler(
 	}
 }
 
+// handleDeviceLogin is like the rest of the POST handler, except that there is no downstream authorization request.
+func handleDeviceLogin(
+	w http.ResponseWriter,
+	r *http.Request,
+	issuerURL string,
+	encodedState stateparam.Encoded,
+	decodedState *oidc.UpstreamStateParamData,
+	idp resolvedprovider.FederationDomainResolvedIdentityProvider,
+	deviceStorage device.UserCodeStorage,
+	auditLogger plog.AuditLogger,
+) error {
+	r.Body = http.MaxBytesReader(w, r.Body, 1<<20) // Limit request body size to 1 MB
+	submittedUsername := r.PostFormValue(loginurl.UsernameParamName)
+	submittedPassword := r.PostFormValue(loginurl.PasswordParamName)
+
+	if submittedUsername == "" || submittedPassword == "" {
+		return redirectToLoginPage(r, w, issuerURL, encodedState, loginurl.ShowBadUserPassErr)
+	}
+
+	identity, loginExtras, err := idp.Login(r.Context(), submittedUsername, submittedPassword)
+	if err != nil {
+		switch {
+		case errors.Is(err, resolvedldap.ErrUnexpectedUpstreamLDAPError):
+			return redirectToLoginPage(r, w, issuerURL, encodedState, loginurl.ShowInternalError)
+		case err == resolvedldap.ErrAccessDeniedDueToUsernamePasswordNotAccepted:
+			auditLogger.Audit(auditevent.IncorrectUsernameOrPassword, &plog.AuditParams{
+				ReqCtx: r.Context(),
+			})
+			return redirectToLoginPage(r, w, issuerURL, encodedState, loginurl.ShowBadUserPassErr)
+		default:
+			return httperr.Wrap(http.StatusUnprocessableEntity, "error during device login", err)
+		}
+	}
+
+	return device.ApproveAfterLogin(w, r, deviceStorage, decodedState.DeviceUserCodeSignature, identity, loginExtras, idp, auditLogger)
+}
+
 // redirectToLoginPage redirects to the GET /login page of the specified issuer.
 func redirectToLoginPage(
 	r *http.Request,
@@ -1326,7 +1326,7 @@ func TestPostLoginEndpoint(t *testing.T) {
 
 			auditLogger, actualAuditLog := plog.TestAuditLogger(t)
 
-			subject := NewPostHandler(downstreamIssuer, tt.idps.BuildFederationDomainIdentityProvidersListerFinder(), oauthHelper, auditLogger)
+			subject := NewPostHandler(downstreamIssuer, tt.idps.BuildFederationDomainIdentityProvidersListerFinder(), oauthHelper, kubeOauthStore, auditLogger)
 
 			err := subject(rsp, req, happyEncodedUpstreamState, tt.decodedState)
 			if tt.wantErr != "" {
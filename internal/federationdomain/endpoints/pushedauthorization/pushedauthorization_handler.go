@@ -0,0 +1,101 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package pushedauthorization provides a handler for the OAuth 2.0 pushed authorization request endpoint, as described
+// in https://datatracker.ietf.org/doc/html/rfc9126.
+package pushedauthorization
+
+import (
+	"net/http"
+	"net/url"
+
+	"github.com/ory/fosite"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	oidcapi "go.pinniped.dev/generated/latest/apis/supervisor/oidc"
+	"go.pinniped.dev/internal/auditevent"
+	"go.pinniped.dev/internal/federationdomain/oidc"
+	"go.pinniped.dev/internal/httputil/httperr"
+	"go.pinniped.dev/internal/plog"
+	"go.pinniped.dev/internal/psession"
+)
+
+func paramsSafeToLog() sets.Set[string] {
+	return sets.New(
+		// The same params as the authorization endpoint, from https://openid.net/specs/openid-connect-core-1_0.html.
+		// Redacting state and nonce params, in case they contain any info that the client considers sensitive.
+		// Also redacting the request param, because a request object may contain the state and nonce params.
+		"scope", "response_type", "client_id", "redirect_uri", "response_mode", "display", "prompt",
+		"max_age", "ui_locales", "id_token_hint", "login_hint", "acr_values", "claims_locales", "claims",
+		"registration",
+		// PKCE params from https://datatracker.ietf.org/doc/html/rfc7636. Let code_challenge be redacted.
+		"code_challenge_method",
+		// Custom Pinniped authorization params.
+		oidcapi.AuthorizeUpstreamIDPNameParamName, oidcapi.AuthorizeUpstreamIDPTypeParamName,
+		// Client authentication params from https://datatracker.ietf.org/doc/html/rfc7523#section-2.2.
+		// Redact client_assertion and client_secret.
+		"client_assertion_type",
+	)
+}
+
+// NewHandler returns a handler for the pushed authorization request endpoint. The caller must authenticate as a
+// confidential OIDCClient, using any of the client authentication methods which are supported at the token endpoint.
+// The pushed authorization request is validated in the same way as a request to the authorization endpoint, and
+// is saved to storage. The client may then send the returned request_uri to the authorization endpoint once.
+func NewHandler(
+	oauthHelper fosite.OAuth2Provider,
+	auditLogger plog.AuditLogger,
+) http.Handler {
+	return httperr.HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+		if r.Method != http.MethodPost {
+			return httperr.Newf(http.StatusMethodNotAllowed, "%s (try POST)", r.Method)
+		}
+
+		if err := auditLogger.AuditRequestParams(r, paramsSafeToLog()); err != nil {
+			oauthHelper.WritePushedAuthorizeError(r.Context(), w, fosite.NewAuthorizeRequest(), fosite.ErrInvalidRequest.WithWrap(err))
+			return nil
+		}
+
+		// For dynamic clients, the client ID is from basic auth, not from the request parameters.
+		if clientIDFromBasicAuth, _, basicAuthUsed := r.BasicAuth(); basicAuthUsed {
+			auditLogger.Audit(auditevent.HTTPRequestBasicAuthUsed, &plog.AuditParams{
+				ReqCtx:        r.Context(),
+				KeysAndValues: []any{"clientID", clientIDFromBasicAuth},
+			})
+		}
+
+		authorizeRequester, err := oauthHelper.NewPushedAuthorizeRequest(r.Context(), r)
+		if err != nil {
+			plog.Info("pushed authorization request error", oidc.FositeErrorForLog(err)...)
+			oauthHelper.WritePushedAuthorizeError(r.Context(), w, authorizeRequester, err)
+			return nil
+		}
+		if authorizeRequester.GetClient().IsPublic() {
+			// Public clients, like the pinniped-cli client, do not have any credentials, so anyone could act as them.
+			// Do not allow anyone to fill the session storage with pushed authorization requests.
+			oauthHelper.WritePushedAuthorizeError(r.Context(), w, authorizeRequester,
+				fosite.ErrInvalidClient.WithHint("Public clients may not push authorization requests."))
+			return nil
+		}
+
+		// The stored params are merged into the params of the authorization request when the request_uri is used,
+		// so do not store the credentials that the client used to authenticate.
+		removeClientAuthenticationParams(authorizeRequester.GetRequestForm())
+
+		response, err := oauthHelper.NewPushedAuthorizeResponse(r.Context(), authorizeRequester, psession.NewPinnipedSession())
+		if err != nil {
+			plog.Info("pushed authorization response error", oidc.FositeErrorForLog(err)...)
+			oauthHelper.WritePushedAuthorizeError(r.Context(), w, authorizeRequester, err)
+			return nil
+		}
+
+		oauthHelper.WritePushedAuthorizeResponse(r.Context(), w, authorizeRequester, response)
+		return nil
+	})
+}
+
+func removeClientAuthenticationParams(params url.Values) {
+	delete(params, "client_secret")
+	delete(params, "client_assertion")
+	delete(params, "client_assertion_type")
+}
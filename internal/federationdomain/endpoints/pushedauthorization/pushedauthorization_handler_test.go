@@ -0,0 +1,259 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package pushedauthorization
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+
+	supervisorfake "go.pinniped.dev/generated/latest/client/supervisor/clientset/versioned/fake"
+	"go.pinniped.dev/internal/auditid"
+	"go.pinniped.dev/internal/federationdomain/endpoints/jwks"
+	"go.pinniped.dev/internal/federationdomain/oidc"
+	"go.pinniped.dev/internal/federationdomain/oidcclientvalidator"
+	"go.pinniped.dev/internal/federationdomain/storage"
+	"go.pinniped.dev/internal/plog"
+	"go.pinniped.dev/internal/testutil"
+)
+
+const (
+	downstreamIssuer    = "https://my-downstream-issuer.com/path"
+	namespace           = "some-namespace"
+	dynamicClientID     = "client.oauth.pinniped.dev-some-client"
+	dynamicClientUID    = "some-client-uid"
+	downstreamRedirect  = "https://some-webapp.com/callback"
+	someState           = "8b-state-value-with-enough-entropy"
+	someNonce           = "some-nonce-value-with-enough-entropy"
+	someCodeChallenge   = "some-code-challenge-value-with-enough-entropy"
+	expectedExpiresIn   = 300
+	expectedRequestURIs = "urn:ietf:params:oauth:request_uri:"
+)
+
+func TestPushedAuthorizationHandler(t *testing.T) {
+	happyParams := func() url.Values {
+		return url.Values{
+			"client_id":             {dynamicClientID},
+			"response_type":         {"code"},
+			"scope":                 {"openid username groups"},
+			"redirect_uri":          {downstreamRedirect},
+			"state":                 {someState},
+			"nonce":                 {someNonce},
+			"code_challenge":        {someCodeChallenge},
+			"code_challenge_method": {"S256"},
+		}
+	}
+
+	happyAuditLogs := []testutil.WantedAuditLog{
+		testutil.WantAuditLog("HTTP Request Parameters", map[string]any{
+			"params": map[string]any{
+				"client_id":             dynamicClientID,
+				"response_type":         "code",
+				"scope":                 "openid username groups",
+				"redirect_uri":          downstreamRedirect,
+				"state":                 "redacted",
+				"nonce":                 "redacted",
+				"code_challenge":        "redacted",
+				"code_challenge_method": "S256",
+			},
+		}),
+		testutil.WantAuditLog("HTTP Request Basic Auth", map[string]any{"clientID": dynamicClientID}),
+	}
+
+	tests := []struct {
+		name             string
+		method           string
+		params           func() url.Values
+		basicAuth        func(*http.Request)
+		wantStatus       int
+		wantBodyJSON     string
+		wantStoredParams url.Values
+		wantAuditLogs    []testutil.WantedAuditLog
+	}{
+		{
+			name:       "a valid pushed authorization request",
+			method:     http.MethodPost,
+			params:     happyParams,
+			basicAuth:  func(r *http.Request) { r.SetBasicAuth(dynamicClientID, testutil.PlaintextPassword1) },
+			wantStatus: http.StatusCreated,
+			// The client's credentials were sent using basic auth, so they were never part of the params.
+			wantStoredParams: happyParams(),
+			wantAuditLogs:    happyAuditLogs,
+		},
+		{
+			name:       "the wrong client secret",
+			method:     http.MethodPost,
+			params:     happyParams,
+			basicAuth:  func(r *http.Request) { r.SetBasicAuth(dynamicClientID, "wrong-secret") },
+			wantStatus: http.StatusUnauthorized,
+			wantBodyJSON: `{
+				"error": "invalid_client",
+				"error_description": "Client authentication failed (e.g., unknown client, no client authentication included, or unsupported authentication method)."
+			}`,
+			wantAuditLogs: happyAuditLogs,
+		},
+		{
+			name:   "a public client may not push authorization requests",
+			method: http.MethodPost,
+			params: func() url.Values {
+				p := happyParams()
+				p.Set("client_id", "pinniped-cli")
+				p.Set("redirect_uri", "http://127.0.0.1/callback")
+				return p
+			},
+			wantStatus: http.StatusUnauthorized,
+			wantBodyJSON: `{
+				"error": "invalid_client",
+				"error_description": "Client authentication failed (e.g., unknown client, no client authentication included, or unsupported authentication method). Public clients may not push authorization requests."
+			}`,
+			wantAuditLogs: []testutil.WantedAuditLog{
+				testutil.WantAuditLog("HTTP Request Parameters", map[string]any{
+					"params": map[string]any{
+						"client_id":             "pinniped-cli",
+						"response_type":         "code",
+						"scope":                 "openid username groups",
+						"redirect_uri":          "http://127.0.0.1/callback",
+						"state":                 "redacted",
+						"nonce":                 "redacted",
+						"code_challenge":        "redacted",
+						"code_challenge_method": "S256",
+					},
+				}),
+			},
+		},
+		{
+			name:   "a pushed authorization request may not contain a request_uri",
+			method: http.MethodPost,
+			params: func() url.Values {
+				p := happyParams()
+				p.Set("request_uri", "urn:ietf:params:oauth:request_uri:some-request-uri")
+				return p
+			},
+			basicAuth:  func(r *http.Request) { r.SetBasicAuth(dynamicClientID, testutil.PlaintextPassword1) },
+			wantStatus: http.StatusBadRequest,
+			wantBodyJSON: `{
+				"error": "invalid_request",
+				"error_description": "The request is missing a required parameter, includes an invalid parameter value, includes a parameter more than once, or is otherwise malformed. The request must not contain 'request_uri'."
+			}`,
+			wantAuditLogs: []testutil.WantedAuditLog{
+				testutil.WantAuditLog("HTTP Request Parameters", map[string]any{
+					"params": map[string]any{
+						"client_id":             dynamicClientID,
+						"response_type":         "code",
+						"scope":                 "openid username groups",
+						"redirect_uri":          downstreamRedirect,
+						"state":                 "redacted",
+						"nonce":                 "redacted",
+						"code_challenge":        "redacted",
+						"code_challenge_method": "S256",
+						"request_uri":           "redacted",
+					},
+				}),
+				testutil.WantAuditLog("HTTP Request Basic Auth", map[string]any{"clientID": dynamicClientID}),
+			},
+		},
+		{
+			name:   "a redirect_uri which is not allowed for the client",
+			method: http.MethodPost,
+			params: func() url.Values {
+				p := happyParams()
+				p.Set("redirect_uri", "https://some-other-webapp.com/callback")
+				return p
+			},
+			basicAuth:  func(r *http.Request) { r.SetBasicAuth(dynamicClientID, testutil.PlaintextPassword1) },
+			wantStatus: http.StatusBadRequest,
+			wantBodyJSON: `{
+				"error": "invalid_request",
+				"error_description": "The request is missing a required parameter, includes an invalid parameter value, includes a parameter more than once, or is otherwise malformed. The 'redirect_uri' parameter does not match any of the OAuth 2.0 Client's pre-registered redirect urls."
+			}`,
+			wantAuditLogs: []testutil.WantedAuditLog{
+				testutil.WantAuditLog("HTTP Request Parameters", map[string]any{
+					"params": map[string]any{
+						"client_id":             dynamicClientID,
+						"response_type":         "code",
+						"scope":                 "openid username groups",
+						"redirect_uri":          "https://some-other-webapp.com/callback",
+						"state":                 "redacted",
+						"nonce":                 "redacted",
+						"code_challenge":        "redacted",
+						"code_challenge_method": "S256",
+					},
+				}),
+				testutil.WantAuditLog("HTTP Request Basic Auth", map[string]any{"clientID": dynamicClientID}),
+			},
+		},
+		{
+			name:       "GET is not allowed",
+			method:     http.MethodGet,
+			params:     happyParams,
+			basicAuth:  func(r *http.Request) { r.SetBasicAuth(dynamicClientID, testutil.PlaintextPassword1) },
+			wantStatus: http.StatusMethodNotAllowed,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			oidcClient, secret := testutil.FullyCapableOIDCClientAndStorageSecret(t,
+				namespace, dynamicClientID, dynamicClientUID, downstreamRedirect, nil,
+				[]string{testutil.HashedPassword1AtGoMinCost, testutil.HashedPassword2AtGoMinCost},
+				oidcclientvalidator.Validate)
+			kubeClient := kubefake.NewClientset(secret)
+			secrets := kubeClient.CoreV1().Secrets(namespace)
+			oidcClientsClient := supervisorfake.NewSimpleClientset(oidcClient).ConfigV1alpha1().OIDCClients(namespace)
+			timeoutsConfiguration := oidc.DefaultOIDCTimeoutsConfiguration()
+			// Use lower minimum required bcrypt cost than we would use in production to keep unit the tests fast.
+			kubeStorage := storage.NewKubeStorage(secrets, oidcClientsClient, timeoutsConfiguration, bcrypt.MinCost)
+			hmacSecretFunc := func() []byte { return []byte("some secret - must have at least 32 bytes") }
+			oauthHelper := oidc.FositeOauth2Helper(kubeStorage, downstreamIssuer, hmacSecretFunc, jwks.NewDynamicJWKSProvider(), timeoutsConfiguration)
+
+			auditLogger, actualAuditLog := plog.TestAuditLogger(t)
+
+			subject := NewHandler(oauthHelper, auditLogger)
+
+			req := httptest.NewRequest(test.method, "/oauth2/par", strings.NewReader(test.params().Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			if test.basicAuth != nil {
+				test.basicAuth(req)
+			}
+			req, _ = auditid.NewRequestWithAuditID(req, func() string { return "fake-audit-id" })
+			rsp := httptest.NewRecorder()
+
+			subject.ServeHTTP(rsp, req)
+
+			require.Equal(t, test.wantStatus, rsp.Code, rsp.Body.String())
+			if test.wantBodyJSON != "" {
+				require.Equal(t, "no-store", rsp.Header().Get("Cache-Control"))
+				require.JSONEq(t, test.wantBodyJSON, rsp.Body.String())
+			}
+
+			if test.wantStoredParams != nil {
+				require.Equal(t, "no-store", rsp.Header().Get("Cache-Control"))
+				var body struct {
+					RequestURI string `json:"request_uri"`
+					ExpiresIn  int    `json:"expires_in"`
+				}
+				require.NoError(t, json.Unmarshal(rsp.Body.Bytes(), &body))
+				require.True(t, strings.HasPrefix(body.RequestURI, expectedRequestURIs), body.RequestURI)
+				require.Equal(t, expectedExpiresIn, body.ExpiresIn)
+
+				stored, err := kubeStorage.GetPARSession(t.Context(), body.RequestURI)
+				require.NoError(t, err)
+				require.Equal(t, dynamicClientID, stored.GetClient().GetID())
+				require.Equal(t, downstreamRedirect, stored.GetRedirectURI().String())
+				require.Equal(t, someState, stored.GetState())
+				require.Equal(t, test.wantStoredParams, stored.GetRequestForm())
+			}
+
+			testutil.WantAuditIDOnEveryAuditLog(test.wantAuditLogs, "fake-audit-id")
+			testutil.CompareAuditLogs(t, test.wantAuditLogs, actualAuditLog.String())
+		})
+	}
+}
@@ -0,0 +1,88 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package registration
+
+import (
+	"context"
+	"fmt"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	authenticationv1client "k8s.io/client-go/kubernetes/typed/authentication/v1"
+	authorizationv1client "k8s.io/client-go/kubernetes/typed/authorization/v1"
+)
+
+// CallerAuthorizer decides whether the holder of a Kubernetes credential may register clients.
+type CallerAuthorizer interface {
+	// AuthorizeCaller returns the username of the caller when the token is a valid Kubernetes credential whose
+	// user may register clients, or an empty string when it is not. An error is returned when the decision could
+	// not be made.
+	AuthorizeCaller(ctx context.Context, token string) (string, error)
+}
+
+type kubeCallerAuthorizer struct {
+	tokenReviews         authenticationv1client.TokenReviewInterface
+	subjectAccessReviews authorizationv1client.SubjectAccessReviewInterface
+	namespace            string
+	oidcClientsAPIGroup  string
+}
+
+// NewKubeCallerAuthorizer returns a CallerAuthorizer which authenticates the token using a TokenReview, and which
+// allows the caller when a SubjectAccessReview shows that they may create OIDCClients in the namespace. In other
+// words, a caller may register clients exactly when they could create OIDCClients directly.
+func NewKubeCallerAuthorizer(
+	tokenReviews authenticationv1client.TokenReviewInterface,
+	subjectAccessReviews authorizationv1client.SubjectAccessReviewInterface,
+	namespace string,
+	oidcClientsAPIGroup string,
+) CallerAuthorizer {
+	return &kubeCallerAuthorizer{
+		tokenReviews:         tokenReviews,
+		subjectAccessReviews: subjectAccessReviews,
+		namespace:            namespace,
+		oidcClientsAPIGroup:  oidcClientsAPIGroup,
+	}
+}
+
+func (a *kubeCallerAuthorizer) AuthorizeCaller(ctx context.Context, token string) (string, error) {
+	tokenReview, err := a.tokenReviews.Create(ctx, &authenticationv1.TokenReview{
+		Spec: authenticationv1.TokenReviewSpec{Token: token},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		return "", fmt.Errorf("could not create TokenReview: %w", err)
+	}
+	if !tokenReview.Status.Authenticated {
+		return "", nil
+	}
+
+	user := tokenReview.Status.User
+	extra := make(map[string]authorizationv1.ExtraValue, len(user.Extra))
+	for k, v := range user.Extra {
+		extra[k] = authorizationv1.ExtraValue(v)
+	}
+
+	subjectAccessReview, err := a.subjectAccessReviews.Create(ctx, &authorizationv1.SubjectAccessReview{
+		Spec: authorizationv1.SubjectAccessReviewSpec{
+			ResourceAttributes: &authorizationv1.ResourceAttributes{
+				Namespace: a.namespace,
+				Verb:      "create",
+				Group:     a.oidcClientsAPIGroup,
+				Resource:  "oidcclients",
+			},
+			User:   user.Username,
+			Groups: user.Groups,
+			UID:    user.UID,
+			Extra:  extra,
+		},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		return "", fmt.Errorf("could not create SubjectAccessReview: %w", err)
+	}
+	if !subjectAccessReview.Status.Allowed {
+		return "", nil
+	}
+
+	return user.Username, nil
+}
@@ -0,0 +1,125 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package registration
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	coretesting "k8s.io/client-go/testing"
+)
+
+func TestKubeCallerAuthorizer(t *testing.T) {
+	authenticatedTokenReview := func(action coretesting.Action) (bool, runtime.Object, error) {
+		tokenReview := action.(coretesting.CreateAction).GetObject().(*authenticationv1.TokenReview)
+		require.Equal(t, "some-token", tokenReview.Spec.Token)
+		tokenReview.Status = authenticationv1.TokenReviewStatus{
+			Authenticated: true,
+			User: authenticationv1.UserInfo{
+				Username: "some-user",
+				UID:      "some-uid",
+				Groups:   []string{"some-group"},
+				Extra:    map[string]authenticationv1.ExtraValue{"some-key": {"some-value"}},
+			},
+		}
+		return true, tokenReview, nil
+	}
+
+	subjectAccessReview := func(allowed bool) func(coretesting.Action) (bool, runtime.Object, error) {
+		return func(action coretesting.Action) (bool, runtime.Object, error) {
+			sar := action.(coretesting.CreateAction).GetObject().(*authorizationv1.SubjectAccessReview)
+			require.Equal(t, authorizationv1.SubjectAccessReviewSpec{
+				ResourceAttributes: &authorizationv1.ResourceAttributes{
+					Namespace: "some-namespace",
+					Verb:      "create",
+					Group:     "config.supervisor.some.suffix.com",
+					Resource:  "oidcclients",
+				},
+				User:   "some-user",
+				Groups: []string{"some-group"},
+				UID:    "some-uid",
+				Extra:  map[string]authorizationv1.ExtraValue{"some-key": {"some-value"}},
+			}, sar.Spec)
+			sar.Status = authorizationv1.SubjectAccessReviewStatus{Allowed: allowed}
+			return true, sar, nil
+		}
+	}
+
+	tests := []struct {
+		name                 string
+		tokenReview          func(coretesting.Action) (bool, runtime.Object, error)
+		subjectAccessReview  func(coretesting.Action) (bool, runtime.Object, error)
+		wantUsername         string
+		wantErr              string
+		wantSubjectAccessReq bool
+	}{
+		{
+			name:                 "authenticated and authorized",
+			tokenReview:          authenticatedTokenReview,
+			subjectAccessReview:  subjectAccessReview(true),
+			wantUsername:         "some-user",
+			wantSubjectAccessReq: true,
+		},
+		{
+			name:                 "authenticated but not authorized",
+			tokenReview:          authenticatedTokenReview,
+			subjectAccessReview:  subjectAccessReview(false),
+			wantSubjectAccessReq: true,
+		},
+		{
+			name: "not authenticated",
+			tokenReview: func(action coretesting.Action) (bool, runtime.Object, error) {
+				return true, action.(coretesting.CreateAction).GetObject(), nil
+			},
+		},
+		{
+			name: "error from TokenReview",
+			tokenReview: func(_ coretesting.Action) (bool, runtime.Object, error) {
+				return true, nil, errors.New("some error")
+			},
+			wantErr: "could not create TokenReview: some error",
+		},
+		{
+			name:        "error from SubjectAccessReview",
+			tokenReview: authenticatedTokenReview,
+			subjectAccessReview: func(_ coretesting.Action) (bool, runtime.Object, error) {
+				return true, nil, errors.New("some error")
+			},
+			wantErr:              "could not create SubjectAccessReview: some error",
+			wantSubjectAccessReq: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			kubeClient := kubefake.NewClientset()
+			kubeClient.PrependReactor("create", "tokenreviews", test.tokenReview)
+			subjectAccessReviewCalled := false
+			kubeClient.PrependReactor("create", "subjectaccessreviews", func(action coretesting.Action) (bool, runtime.Object, error) {
+				subjectAccessReviewCalled = true
+				return test.subjectAccessReview(action)
+			})
+
+			subject := NewKubeCallerAuthorizer(
+				kubeClient.AuthenticationV1().TokenReviews(),
+				kubeClient.AuthorizationV1().SubjectAccessReviews(),
+				"some-namespace",
+				"config.supervisor.some.suffix.com",
+			)
+
+			username, err := subject.AuthorizeCaller(t.Context(), "some-token")
+			if test.wantErr != "" {
+				require.EqualError(t, err, test.wantErr)
+			} else {
+				require.NoError(t, err)
+			}
+			require.Equal(t, test.wantUsername, username)
+			require.Equal(t, test.wantSubjectAccessReq, subjectAccessReviewCalled)
+		})
+	}
+}
@@ -0,0 +1,661 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package registration provides a handler for the OAuth 2.0 Dynamic Client Registration endpoint, as described in
+// https://datatracker.ietf.org/doc/html/rfc7591, and for the client configuration endpoints of the clients which
+// were registered using it, as described in https://datatracker.ietf.org/doc/html/rfc7592.
+package registration
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+
+	supervisorconfigv1alpha1 "go.pinniped.dev/generated/latest/apis/supervisor/config/v1alpha1"
+	oidcapi "go.pinniped.dev/generated/latest/apis/supervisor/oidc"
+	configv1alpha1clientset "go.pinniped.dev/generated/latest/client/supervisor/clientset/versioned/typed/config/v1alpha1"
+	"go.pinniped.dev/internal/auditevent"
+	"go.pinniped.dev/internal/federationdomain/clientregistry"
+	"go.pinniped.dev/internal/federationdomain/federationdomainproviders"
+	"go.pinniped.dev/internal/federationdomain/oidc"
+	"go.pinniped.dev/internal/federationdomain/oidcclientvalidator"
+	"go.pinniped.dev/internal/oidcclientsecretstorage"
+	"go.pinniped.dev/internal/plog"
+)
+
+const (
+	// registrationAccessTokenHashAnnotation holds the hex-encoded SHA-256 hash of the registration access token of
+	// a registered OIDCClient. The token is a long random value, so a fast hash is sufficient.
+	registrationAccessTokenHashAnnotation = "supervisor.pinniped.dev/registration-access-token-hash" //nolint:gosec // this is not a credential
+
+	// clientNameAnnotation holds the optional client_name of a registered OIDCClient.
+	clientNameAnnotation = "supervisor.pinniped.dev/client-name"
+
+	clientIDPrefix = "client.oauth.pinniped.dev-"
+
+	authenticationMethodInitialAccessToken = "initialAccessToken"
+	authenticationMethodKubernetes         = "kubernetes"
+
+	// maxRequestBodySize limits the size of the client metadata which will be read from a request.
+	maxRequestBodySize = 64 * 1024
+
+	// Error codes from https://datatracker.ietf.org/doc/html/rfc7591#section-3.2.2 and
+	// https://datatracker.ietf.org/doc/html/rfc6750#section-3.1.
+	errorInvalidRedirectURI    = "invalid_redirect_uri"
+	errorInvalidClientMetadata = "invalid_client_metadata"
+	errorInvalidToken          = "invalid_token"
+	errorServerError           = "server_error"
+)
+
+// redirectURIRegexp is the same as the validation of the RedirectURI type of OIDCClient.
+var redirectURIRegexp = regexp.MustCompile(`^https://.+|^http://(127\.0\.0\.1|\[::1\])(:\d+)?/`) //nolint:gochecknoglobals
+
+// hostLabelRegexp matches the host labels which a "*" in an allowedRedirectURIPatterns host may match.
+var hostLabelRegexp = regexp.MustCompile(`^[a-z0-9-]+$`) //nolint:gochecknoglobals
+
+type byteHasher func(password []byte, cost int) ([]byte, error)
+
+// clientMetadata is the subset of the client metadata from https://datatracker.ietf.org/doc/html/rfc7591#section-2
+// which is supported by the Supervisor. Other metadata is ignored, as allowed by the RFC.
+type clientMetadata struct {
+	RedirectURIs            []string `json:"redirect_uris,omitempty"`
+	TokenEndpointAuthMethod string   `json:"token_endpoint_auth_method"`
+	GrantTypes              []string `json:"grant_types"`
+	ResponseTypes           []string `json:"response_types"`
+	ClientName              string   `json:"client_name,omitempty"`
+	Scope                   string   `json:"scope"`
+}
+
+// updateRequest is the body of a client update request, as described in
+// https://datatracker.ietf.org/doc/html/rfc7592#section-2.2.
+type updateRequest struct {
+	clientMetadata
+	ClientID     string `json:"client_id"`
+	ClientSecret string `json:"client_secret,omitempty"`
+}
+
+// clientInformation is the body of a client information response, as described in
+// https://datatracker.ietf.org/doc/html/rfc7591#section-3.2.1 and https://datatracker.ietf.org/doc/html/rfc7592#section-3.
+type clientInformation struct {
+	clientMetadata
+	ClientID                string `json:"client_id"`
+	ClientSecret            string `json:"client_secret,omitempty"`
+	ClientIDIssuedAt        int64  `json:"client_id_issued_at"`
+	ClientSecretExpiresAt   int64  `json:"client_secret_expires_at"`
+	RegistrationAccessToken string `json:"registration_access_token,omitempty"`
+	RegistrationClientURI   string `json:"registration_client_uri"`
+}
+
+type errorResponse struct {
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description,omitempty"`
+}
+
+type handler struct {
+	issuerURL         string
+	policy            *federationdomainproviders.ClientRegistrationPolicy
+	oidcClientsClient configv1alpha1clientset.OIDCClientInterface
+	secretsClient     corev1client.SecretInterface
+	secretStorage     *oidcclientsecretstorage.OIDCClientSecretStorage
+	callerAuthorizer  CallerAuthorizer
+	randReader        io.Reader
+	cost              int
+	byteHasher        byteHasher
+	auditLogger       plog.AuditLogger
+}
+
+// NewHandler returns an http.Handler that serves the dynamic client registration endpoint of a FederationDomain
+// at oidc.RegistrationEndpointPath, and the client configuration endpoints of the clients which it registered
+// below that path. Registered clients are created as OIDCClients, and their client secrets are stored using
+// oidcclientsecretstorage. The secretsClient and oidcClientsClient must be able to write from any Supervisor pod.
+func NewHandler(
+	issuerURL string,
+	policy *federationdomainproviders.ClientRegistrationPolicy,
+	oidcClientsClient configv1alpha1clientset.OIDCClientInterface,
+	secretsClient corev1client.SecretInterface,
+	callerAuthorizer CallerAuthorizer,
+	randReader io.Reader,
+	cost int,
+	byteHasher byteHasher,
+	auditLogger plog.AuditLogger,
+) http.Handler {
+	h := &handler{
+		issuerURL:         issuerURL,
+		policy:            policy,
+		oidcClientsClient: oidcClientsClient,
+		secretsClient:     secretsClient,
+		secretStorage:     oidcclientsecretstorage.New(secretsClient),
+		callerAuthorizer:  callerAuthorizer,
+		randReader:        randReader,
+		cost:              cost,
+		byteHasher:        byteHasher,
+		auditLogger:       auditLogger,
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Responses may contain credentials, so they must never be cached.
+		w.Header().Set("Cache-Control", "no-store")
+		w.Header().Set("Pragma", "no-cache")
+
+		if strings.HasSuffix(r.URL.Path, oidc.RegistrationEndpointPath) {
+			h.serveRegistration(w, r)
+			return
+		}
+		if _, clientID, found := strings.Cut(r.URL.Path, oidc.RegistrationEndpointPath+"/"); found && !strings.Contains(clientID, "/") {
+			h.serveClientConfiguration(w, r, clientID)
+			return
+		}
+		http.NotFound(w, r)
+	})
+}
+
+func (h *handler) serveRegistration(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, `Method not allowed (try POST)`, http.StatusMethodNotAllowed)
+		return
+	}
+
+	authenticationMethod, username, ok := h.authorizeRegistration(r)
+	if !ok {
+		writeBearerTokenError(w)
+		return
+	}
+
+	var metadata clientMetadata
+	if err := readJSON(r, &metadata); err != nil {
+		writeError(w, http.StatusBadRequest, errorInvalidClientMetadata, err.Error())
+		return
+	}
+	spec, errCode, errMsg := h.validateMetadata(&metadata)
+	if errCode != "" {
+		writeError(w, http.StatusBadRequest, errCode, errMsg)
+		return
+	}
+
+	clientID, clientSecret, registrationAccessToken, err := h.generateCredentials()
+	if err != nil {
+		plog.Error("could not generate client credentials", err)
+		writeError(w, http.StatusInternalServerError, errorServerError, "")
+		return
+	}
+	secretHash, err := h.byteHasher([]byte(clientSecret), h.cost)
+	if err != nil {
+		plog.Error("could not hash client secret", err)
+		writeError(w, http.StatusInternalServerError, errorServerError, "")
+		return
+	}
+
+	oidcClient := &supervisorconfigv1alpha1.OIDCClient{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: clientID,
+			Annotations: map[string]string{
+				clientregistry.FederationDomainAnnotation: h.policy.FederationDomainName,
+				registrationAccessTokenHashAnnotation:     hashToken(registrationAccessToken),
+			},
+		},
+		Spec: *spec,
+	}
+	if metadata.ClientName != "" {
+		oidcClient.Annotations[clientNameAnnotation] = metadata.ClientName
+	}
+
+	oidcClient, err = h.oidcClientsClient.Create(r.Context(), oidcClient, metav1.CreateOptions{})
+	if err != nil {
+		plog.Error("could not create registered OIDCClient", err, "clientID", clientID)
+		writeError(w, http.StatusInternalServerError, errorServerError, "")
+		return
+	}
+
+	if err := h.secretStorage.Set(r.Context(), "", oidcClient.Name, oidcClient.UID, []string{string(secretHash)}); err != nil {
+		plog.Error("could not store client secret of registered OIDCClient", err, "clientID", clientID)
+		// Do not leave behind a client which cannot be used or managed.
+		if deleteErr := h.oidcClientsClient.Delete(r.Context(), oidcClient.Name, metav1.DeleteOptions{}); deleteErr != nil {
+			plog.Error("could not delete registered OIDCClient after failing to store its client secret", deleteErr, "clientID", clientID)
+		}
+		writeError(w, http.StatusInternalServerError, errorServerError, "")
+		return
+	}
+
+	auditParams := &plog.AuditParams{
+		ReqCtx: r.Context(),
+		KeysAndValues: []any{
+			"clientID", clientID,
+			"authenticationMethod", authenticationMethod,
+			"redirectURIs", metadata.RedirectURIs,
+			"grantTypes", metadata.GrantTypes,
+			"scope", metadata.Scope,
+		},
+	}
+	if username != "" {
+		auditParams.PIIKeysAndValues = []any{"username", username}
+	}
+	h.auditLogger.Audit(auditevent.OIDCClientRegistered, auditParams)
+
+	info := h.clientInformation(oidcClient)
+	info.ClientSecret = clientSecret
+	info.RegistrationAccessToken = registrationAccessToken
+	writeJSON(w, http.StatusCreated, info)
+}
+
+func (h *handler) serveClientConfiguration(w http.ResponseWriter, r *http.Request, clientID string) {
+	oidcClient, ok := h.authorizeClientConfiguration(r, clientID)
+	if !ok {
+		writeBearerTokenError(w)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, h.clientInformation(oidcClient))
+
+	case http.MethodPut:
+		h.updateClient(w, r, oidcClient)
+
+	case http.MethodDelete:
+		if err := h.oidcClientsClient.Delete(r.Context(), oidcClient.Name, metav1.DeleteOptions{
+			Preconditions: &metav1.Preconditions{UID: &oidcClient.UID},
+		}); err != nil && !apierrors.IsNotFound(err) {
+			plog.Error("could not delete registered OIDCClient", err, "clientID", clientID)
+			writeError(w, http.StatusInternalServerError, errorServerError, "")
+			return
+		}
+		// The client secret storage Secret is deleted by garbage collection, because it is owned by the OIDCClient.
+		h.auditLogger.Audit(auditevent.OIDCClientRegistrationDeleted, &plog.AuditParams{
+			ReqCtx:        r.Context(),
+			KeysAndValues: []any{"clientID", clientID},
+		})
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		http.Error(w, `Method not allowed (try GET, PUT, or DELETE)`, http.StatusMethodNotAllowed)
+	}
+}
+
+func (h *handler) updateClient(w http.ResponseWriter, r *http.Request, oidcClient *supervisorconfigv1alpha1.OIDCClient) {
+	var req updateRequest
+	if err := readJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, errorInvalidClientMetadata, err.Error())
+		return
+	}
+	if req.ClientID != oidcClient.Name {
+		writeError(w, http.StatusBadRequest, errorInvalidClientMetadata, "client_id must be the client ID of the client being updated")
+		return
+	}
+	if req.ClientSecret != "" && !h.clientSecretMatches(r.Context(), oidcClient, req.ClientSecret) {
+		writeError(w, http.StatusBadRequest, errorInvalidClientMetadata, "client_secret must be the client secret of the client being updated")
+		return
+	}
+
+	spec, errCode, errMsg := h.validateMetadata(&req.clientMetadata)
+	if errCode != "" {
+		writeError(w, http.StatusBadRequest, errCode, errMsg)
+		return
+	}
+
+	updatedClient := oidcClient.DeepCopy()
+	updatedClient.Spec = *spec
+	if req.ClientName != "" {
+		updatedClient.Annotations[clientNameAnnotation] = req.ClientName
+	} else {
+		delete(updatedClient.Annotations, clientNameAnnotation)
+	}
+
+	updatedClient, err := h.oidcClientsClient.Update(r.Context(), updatedClient, metav1.UpdateOptions{})
+	if err != nil {
+		if apierrors.IsConflict(err) {
+			writeError(w, http.StatusConflict, errorInvalidClientMetadata, "the client was modified concurrently, please try again")
+			return
+		}
+		plog.Error("could not update registered OIDCClient", err, "clientID", oidcClient.Name)
+		writeError(w, http.StatusInternalServerError, errorServerError, "")
+		return
+	}
+
+	h.auditLogger.Audit(auditevent.OIDCClientRegistrationUpdated, &plog.AuditParams{
+		ReqCtx: r.Context(),
+		KeysAndValues: []any{
+			"clientID", oidcClient.Name,
+			"redirectURIs", req.RedirectURIs,
+			"grantTypes", req.GrantTypes,
+			"scope", req.Scope,
+		},
+	})
+
+	writeJSON(w, http.StatusOK, h.clientInformation(updatedClient))
+}
+
+// authorizeRegistration checks the bearer token of a registration request. It returns how the caller was
+// authenticated, the Kubernetes username of the caller if they used a Kubernetes credential, and whether the
+// caller may register a client.
+func (h *handler) authorizeRegistration(r *http.Request) (string, string, bool) {
+	token, ok := bearerToken(r)
+	if !ok {
+		return "", "", false
+	}
+
+	if h.policy.InitialAccessTokenSecretName != "" {
+		secret, err := h.secretsClient.Get(r.Context(), h.policy.InitialAccessTokenSecretName, metav1.GetOptions{})
+		switch {
+		case err != nil:
+			plog.Warning("could not get the initial access token Secret for dynamic client registration",
+				"secretName", h.policy.InitialAccessTokenSecretName, "err", err)
+		default:
+			for _, value := range secret.Data {
+				if subtle.ConstantTimeCompare(value, []byte(token)) == 1 {
+					return authenticationMethodInitialAccessToken, "", true
+				}
+			}
+		}
+	}
+
+	if h.policy.AllowKubernetesAuthentication {
+		username, err := h.callerAuthorizer.AuthorizeCaller(r.Context(), token)
+		if err != nil {
+			plog.Warning("could not authorize the Kubernetes credential of the caller for dynamic client registration", "err", err)
+			return "", "", false
+		}
+		if username != "" {
+			return authenticationMethodKubernetes, username, true
+		}
+	}
+
+	return "", "", false
+}
+
+// authorizeClientConfiguration checks that the bearer token of a client configuration request is the registration
+// access token of a client which was registered by this FederationDomain, and returns that client.
+func (h *handler) authorizeClientConfiguration(r *http.Request, clientID string) (*supervisorconfigv1alpha1.OIDCClient, bool) {
+	token, ok := bearerToken(r)
+	if !ok || !strings.HasPrefix(clientID, clientIDPrefix) {
+		return nil, false
+	}
+
+	oidcClient, err := h.oidcClientsClient.Get(r.Context(), clientID, metav1.GetOptions{})
+	if err != nil {
+		if !apierrors.IsNotFound(err) {
+			plog.Error("could not get registered OIDCClient", err, "clientID", clientID)
+		}
+		// As required by RFC7592 section 2.1, the response does not reveal whether the client exists.
+		return nil, false
+	}
+
+	if oidcClient.Annotations[clientregistry.FederationDomainAnnotation] != h.policy.FederationDomainName {
+		return nil, false
+	}
+	wantHash := oidcClient.Annotations[registrationAccessTokenHashAnnotation]
+	if wantHash == "" || subtle.ConstantTimeCompare([]byte(wantHash), []byte(hashToken(token))) != 1 {
+		return nil, false
+	}
+
+	return oidcClient, true
+}
+
+func (h *handler) clientSecretMatches(ctx context.Context, oidcClient *supervisorconfigv1alpha1.OIDCClient, clientSecret string) bool {
+	_, hashes, err := h.secretStorage.Get(ctx, oidcClient.UID)
+	if err != nil {
+		plog.Error("could not get client secrets of registered OIDCClient", err, "clientID", oidcClient.Name)
+		return false
+	}
+	for _, hash := range hashes {
+		if bcrypt.CompareHashAndPassword([]byte(hash), []byte(clientSecret)) == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// validateMetadata applies the defaults from RFC7591 section 2 to the client metadata, and checks that it is
+// allowed by the policy and would make a valid OIDCClient. It returns the spec of the OIDCClient, or an error
+// code and description.
+func (h *handler) validateMetadata(metadata *clientMetadata) (*supervisorconfigv1alpha1.OIDCClientSpec, string, string) {
+	if metadata.TokenEndpointAuthMethod == "" {
+		metadata.TokenEndpointAuthMethod = string(supervisorconfigv1alpha1.TokenEndpointAuthMethodClientSecretBasic)
+	}
+	if len(metadata.GrantTypes) == 0 {
+		metadata.GrantTypes = []string{oidcapi.GrantTypeAuthorizationCode}
+	}
+	if len(metadata.ResponseTypes) == 0 {
+		metadata.ResponseTypes = []string{"code"}
+	}
+	if metadata.Scope == "" {
+		metadata.Scope = oidcapi.ScopeOpenID
+	}
+
+	if metadata.TokenEndpointAuthMethod != string(supervisorconfigv1alpha1.TokenEndpointAuthMethodClientSecretBasic) {
+		return nil, errorInvalidClientMetadata, fmt.Sprintf("token_endpoint_auth_method must be %q",
+			supervisorconfigv1alpha1.TokenEndpointAuthMethodClientSecretBasic)
+	}
+	for _, responseType := range metadata.ResponseTypes {
+		if responseType != "code" {
+			return nil, errorInvalidClientMetadata, fmt.Sprintf("response_types value %q is not supported", responseType)
+		}
+	}
+
+	spec := &supervisorconfigv1alpha1.OIDCClientSpec{
+		TokenEndpointAuthMethod: supervisorconfigv1alpha1.TokenEndpointAuthMethodClientSecretBasic,
+	}
+	for _, grantType := range metadata.GrantTypes {
+		if !slices.Contains(h.policy.AllowedGrantTypes, grantType) || grantType == oidcapi.GrantTypeClientCredentials {
+			return nil, errorInvalidClientMetadata, fmt.Sprintf("grant_types value %q is not allowed", grantType)
+		}
+		if !slices.Contains(spec.AllowedGrantTypes, supervisorconfigv1alpha1.GrantType(grantType)) {
+			spec.AllowedGrantTypes = append(spec.AllowedGrantTypes, supervisorconfigv1alpha1.GrantType(grantType))
+		}
+	}
+	for _, scope := range strings.Fields(metadata.Scope) {
+		if !slices.Contains(h.policy.AllowedScopes, scope) {
+			return nil, errorInvalidClientMetadata, fmt.Sprintf("scope value %q is not allowed", scope)
+		}
+		if !slices.Contains(spec.AllowedScopes, supervisorconfigv1alpha1.Scope(scope)) {
+			spec.AllowedScopes = append(spec.AllowedScopes, supervisorconfigv1alpha1.Scope(scope))
+		}
+	}
+
+	if slices.Contains(metadata.GrantTypes, oidcapi.GrantTypeAuthorizationCode) && len(metadata.RedirectURIs) == 0 {
+		return nil, errorInvalidRedirectURI, fmt.Sprintf("redirect_uris is required when grant_types includes %q",
+			oidcapi.GrantTypeAuthorizationCode)
+	}
+	for _, redirectURI := range metadata.RedirectURIs {
+		if !redirectURIRegexp.MatchString(redirectURI) || !h.redirectURIAllowed(redirectURI) {
+			return nil, errorInvalidRedirectURI, fmt.Sprintf("redirect_uris value %q is not allowed", redirectURI)
+		}
+		if !slices.Contains(spec.AllowedRedirectURIs, supervisorconfigv1alpha1.RedirectURI(redirectURI)) {
+			spec.AllowedRedirectURIs = append(spec.AllowedRedirectURIs, supervisorconfigv1alpha1.RedirectURI(redirectURI))
+		}
+	}
+
+	if messages := oidcclientvalidator.ValidateAllowedGrantTypesAndScopes(
+		&supervisorconfigv1alpha1.OIDCClient{Spec: *spec},
+	); len(messages) > 0 {
+		return nil, errorInvalidClientMetadata, strings.Join(messages, "; ")
+	}
+
+	return spec, "", ""
+}
+
+// redirectURIAllowed returns whether the redirect URI matches any of the allowedRedirectURIPatterns of the policy.
+// Redirect URIs with userinfo, a query or a fragment are never allowed, since those could be used to make a
+// redirect URI which appears to match a pattern point somewhere else.
+func (h *handler) redirectURIAllowed(redirectURI string) bool {
+	u, err := url.Parse(redirectURI)
+	if err != nil || u.Opaque != "" || u.Host == "" || u.User != nil ||
+		u.RawQuery != "" || u.ForceQuery || strings.Contains(redirectURI, "#") {
+		return false
+	}
+
+	for _, pattern := range h.policy.AllowedRedirectURIPatterns {
+		matched, err := redirectURIMatches(pattern, u)
+		if err != nil {
+			plog.Warning("invalid allowedRedirectURIPatterns value for dynamic client registration",
+				"federationDomain", h.policy.FederationDomainName, "pattern", pattern, "err", err)
+			continue
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
+
+// redirectURIMatches returns whether the redirect URI matches the pattern. The scheme and path of the redirect URI
+// must be the same as those of the pattern. Its port must be the same as that of the pattern, unless the pattern's
+// port is "*", which matches any port. Its host must have the same labels as that of the pattern, except that within
+// a label of the pattern, "*" matches any sequence of letters, digits and hyphens.
+func redirectURIMatches(pattern string, u *url.URL) (bool, error) {
+	scheme, rest, ok := strings.Cut(pattern, "://")
+	if !ok {
+		return false, errors.New("pattern does not have a scheme")
+	}
+	authority, patternPath := rest, ""
+	if i := strings.IndexByte(rest, '/'); i >= 0 {
+		authority, patternPath = rest[:i], rest[i:]
+	}
+	if strings.ContainsAny(authority, "@?#") || strings.ContainsAny(patternPath, "*?#") {
+		return false, errors.New(`pattern may only use "*" in its host and port, and may not have userinfo, a query, or a fragment`)
+	}
+	patternHost, patternPort := authority, ""
+	if i := strings.LastIndexByte(authority, ':'); i >= 0 && !strings.Contains(authority[i:], "]") {
+		patternHost, patternPort = authority[:i], authority[i+1:]
+	}
+	patternHost = strings.TrimSuffix(strings.TrimPrefix(patternHost, "["), "]")
+
+	if !strings.EqualFold(scheme, u.Scheme) || patternPath != u.EscapedPath() {
+		return false, nil
+	}
+	if patternPort == "*" {
+		if u.Port() == "" {
+			return false, nil
+		}
+	} else if patternPort != u.Port() {
+		return false, nil
+	}
+
+	patternLabels := strings.Split(strings.ToLower(patternHost), ".")
+	labels := strings.Split(strings.ToLower(u.Hostname()), ".")
+	if len(patternLabels) != len(labels) {
+		return false, nil
+	}
+	for i, patternLabel := range patternLabels {
+		if !strings.Contains(patternLabel, "*") {
+			if patternLabel != labels[i] {
+				return false, nil
+			}
+			continue
+		}
+		if !hostLabelRegexp.MatchString(labels[i]) {
+			return false, nil
+		}
+		matched, err := path.Match(patternLabel, labels[i])
+		if err != nil {
+			return false, err
+		}
+		if !matched {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+func (h *handler) clientInformation(oidcClient *supervisorconfigv1alpha1.OIDCClient) *clientInformation {
+	info := &clientInformation{
+		clientMetadata: clientMetadata{
+			TokenEndpointAuthMethod: string(oidcclientvalidator.TokenEndpointAuthMethod(oidcClient)),
+			ResponseTypes:           []string{"code"},
+			ClientName:              oidcClient.Annotations[clientNameAnnotation],
+		},
+		ClientID:              oidcClient.Name,
+		ClientIDIssuedAt:      oidcClient.CreationTimestamp.Unix(),
+		ClientSecretExpiresAt: 0, // never expires
+		RegistrationClientURI: h.issuerURL + oidc.RegistrationEndpointPath + "/" + oidcClient.Name,
+	}
+	for _, redirectURI := range oidcClient.Spec.AllowedRedirectURIs {
+		info.RedirectURIs = append(info.RedirectURIs, string(redirectURI))
+	}
+	for _, grantType := range oidcClient.Spec.AllowedGrantTypes {
+		info.GrantTypes = append(info.GrantTypes, string(grantType))
+	}
+	scopes := make([]string, 0, len(oidcClient.Spec.AllowedScopes))
+	for _, scope := range oidcClient.Spec.AllowedScopes {
+		scopes = append(scopes, string(scope))
+	}
+	info.Scope = strings.Join(scopes, " ")
+	return info
+}
+
+func (h *handler) generateCredentials() (string, string, string, error) {
+	clientIDSuffix, err := randomHex(h.randReader, 16)
+	if err != nil {
+		return "", "", "", err
+	}
+	clientSecret, err := randomHex(h.randReader, 32)
+	if err != nil {
+		return "", "", "", err
+	}
+	registrationAccessToken, err := randomHex(h.randReader, 32)
+	if err != nil {
+		return "", "", "", err
+	}
+	return clientIDPrefix + clientIDSuffix, clientSecret, registrationAccessToken, nil
+}
+
+func randomHex(rand io.Reader, numBytes int) (string, error) {
+	buf := make([]byte, numBytes)
+	if _, err := io.ReadFull(rand, buf); err != nil {
+		return "", fmt.Errorf("could not generate random bytes: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+func hashToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
+
+func bearerToken(r *http.Request) (string, bool) {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	token = strings.TrimSpace(token)
+	return token, ok && token != ""
+}
+
+func readJSON(r *http.Request, v any) error {
+	if contentType := r.Header.Get("Content-Type"); !strings.HasPrefix(contentType, "application/json") {
+		return fmt.Errorf("content type must be application/json")
+	}
+	if err := json.NewDecoder(io.LimitReader(r.Body, maxRequestBodySize)).Decode(v); err != nil {
+		return fmt.Errorf("could not parse client metadata: %w", err)
+	}
+	return nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		plog.Error("could not write dynamic client registration response", err)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, errorCode, description string) {
+	writeJSON(w, status, &errorResponse{Error: errorCode, ErrorDescription: description})
+}
+
+func writeBearerTokenError(w http.ResponseWriter) {
+	w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+	writeError(w, http.StatusUnauthorized, errorInvalidToken, "")
+}
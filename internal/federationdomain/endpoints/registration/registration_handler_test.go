@@ -0,0 +1,853 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package registration
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	coretesting "k8s.io/client-go/testing"
+
+	supervisorconfigv1alpha1 "go.pinniped.dev/generated/latest/apis/supervisor/config/v1alpha1"
+	supervisorfake "go.pinniped.dev/generated/latest/client/supervisor/clientset/versioned/fake"
+	"go.pinniped.dev/internal/auditid"
+	"go.pinniped.dev/internal/federationdomain/federationdomainproviders"
+	"go.pinniped.dev/internal/oidcclientsecretstorage"
+	"go.pinniped.dev/internal/plog"
+	"go.pinniped.dev/internal/testutil"
+)
+
+const (
+	namespace                    = "some-namespace"
+	issuer                       = "https://some-issuer.com/some/path"
+	federationDomainName         = "some-federation-domain"
+	initialAccessTokenSecretName = "some-initial-access-token"
+	initialAccessToken           = "some-initial-access-token-value" //nolint:gosec // this is not a credential
+	registeredClientID           = "client.oauth.pinniped.dev-registered"
+	registeredClientUID          = "some-registered-client-uid"
+	registrationAccessToken      = "some-registration-access-token" //nolint:gosec // this is not a credential
+	registeredClientSecret       = "some-registered-client-secret"  //nolint:gosec // this is not a credential
+
+	// These are the values generated from fakeRandReader.
+	generatedClientID                = "client.oauth.pinniped.dev-01010101010101010101010101010101"
+	generatedClientSecret            = "0202020202020202020202020202020202020202020202020202020202020202"
+	generatedRegistrationAccessToken = "0303030303030303030303030303030303030303030303030303030303030303"
+)
+
+type fakeCallerAuthorizer struct {
+	username  string
+	err       error
+	gotTokens []string
+}
+
+func (f *fakeCallerAuthorizer) AuthorizeCaller(_ context.Context, token string) (string, error) {
+	f.gotTokens = append(f.gotTokens, token)
+	return f.username, f.err
+}
+
+func TestRegistrationHandler(t *testing.T) {
+	creationTimestamp := metav1.NewTime(time.Date(2026, 3, 4, 5, 6, 7, 0, time.UTC))
+
+	registeredClientSecretHash, err := bcrypt.GenerateFromPassword([]byte(registeredClientSecret), bcrypt.MinCost)
+	require.NoError(t, err)
+
+	happyPolicy := func() *federationdomainproviders.ClientRegistrationPolicy {
+		return &federationdomainproviders.ClientRegistrationPolicy{
+			FederationDomainName:         federationDomainName,
+			InitialAccessTokenSecretName: initialAccessTokenSecretName,
+			AllowedRedirectURIPatterns:   []string{"https://*.example.com/callback", "http://127.0.0.1:*/callback"},
+			AllowedGrantTypes:            []string{"authorization_code", "refresh_token", "client_credentials"},
+			AllowedScopes:                []string{"openid", "offline_access", "username", "groups"},
+		}
+	}
+
+	initialAccessTokenSecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: initialAccessTokenSecretName, Namespace: namespace},
+		Data:       map[string][]byte{"token": []byte(initialAccessToken)},
+	}
+
+	registeredClient := func() *supervisorconfigv1alpha1.OIDCClient {
+		return &supervisorconfigv1alpha1.OIDCClient{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              registeredClientID,
+				Namespace:         namespace,
+				UID:               registeredClientUID,
+				CreationTimestamp: creationTimestamp,
+				Annotations: map[string]string{
+					"supervisor.pinniped.dev/registered-by-federation-domain": federationDomainName,
+					"supervisor.pinniped.dev/registration-access-token-hash":  sha256Hex(registrationAccessToken),
+					"supervisor.pinniped.dev/client-name":                     "Some Client",
+				},
+			},
+			Spec: supervisorconfigv1alpha1.OIDCClientSpec{
+				AllowedRedirectURIs: []supervisorconfigv1alpha1.RedirectURI{"https://app.example.com/callback"},
+				AllowedGrantTypes:   []supervisorconfigv1alpha1.GrantType{"authorization_code"},
+				AllowedScopes:       []supervisorconfigv1alpha1.Scope{"openid"},
+			},
+		}
+	}
+
+	registeredClientJSON := `{
+		"client_id": "client.oauth.pinniped.dev-registered",
+		"client_id_issued_at": 1772600767,
+		"client_secret_expires_at": 0,
+		"registration_client_uri": "https://some-issuer.com/some/path/oauth2/register/client.oauth.pinniped.dev-registered",
+		"redirect_uris": ["https://app.example.com/callback"],
+		"token_endpoint_auth_method": "client_secret_basic",
+		"grant_types": ["authorization_code"],
+		"response_types": ["code"],
+		"client_name": "Some Client",
+		"scope": "openid"
+	}`
+
+	happyRegistrationBody := `{
+		"redirect_uris": ["https://app.example.com/callback", "http://127.0.0.1:12345/callback"],
+		"grant_types": ["authorization_code", "refresh_token"],
+		"scope": "openid offline_access username",
+		"client_name": "My Client",
+		"some_unsupported_metadata": "ignored"
+	}`
+
+	happyRegistrationResponseJSON := `{
+		"client_id": "client.oauth.pinniped.dev-01010101010101010101010101010101",
+		"client_secret": "0202020202020202020202020202020202020202020202020202020202020202",
+		"client_id_issued_at": 1772600767,
+		"client_secret_expires_at": 0,
+		"registration_access_token": "0303030303030303030303030303030303030303030303030303030303030303",
+		"registration_client_uri": "https://some-issuer.com/some/path/oauth2/register/client.oauth.pinniped.dev-01010101010101010101010101010101",
+		"redirect_uris": ["https://app.example.com/callback", "http://127.0.0.1:12345/callback"],
+		"token_endpoint_auth_method": "client_secret_basic",
+		"grant_types": ["authorization_code", "refresh_token"],
+		"response_types": ["code"],
+		"client_name": "My Client",
+		"scope": "openid offline_access username"
+	}`
+
+	happyRegisteredClient := &supervisorconfigv1alpha1.OIDCClient{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      generatedClientID,
+			Namespace: namespace,
+			Annotations: map[string]string{
+				"supervisor.pinniped.dev/registered-by-federation-domain": federationDomainName,
+				"supervisor.pinniped.dev/registration-access-token-hash":  sha256Hex(generatedRegistrationAccessToken),
+				"supervisor.pinniped.dev/client-name":                     "My Client",
+			},
+		},
+		Spec: supervisorconfigv1alpha1.OIDCClientSpec{
+			AllowedRedirectURIs:     []supervisorconfigv1alpha1.RedirectURI{"https://app.example.com/callback", "http://127.0.0.1:12345/callback"},
+			AllowedGrantTypes:       []supervisorconfigv1alpha1.GrantType{"authorization_code", "refresh_token"},
+			AllowedScopes:           []supervisorconfigv1alpha1.Scope{"openid", "offline_access", "username"},
+			TokenEndpointAuthMethod: supervisorconfigv1alpha1.TokenEndpointAuthMethodClientSecretBasic,
+		},
+	}
+
+	happyRegistrationAuditLog := func(authenticationMethod string, personalInfo map[string]any) testutil.WantedAuditLog {
+		params := map[string]any{
+			"clientID":             generatedClientID,
+			"authenticationMethod": authenticationMethod,
+			"redirectURIs":         []any{"https://app.example.com/callback", "http://127.0.0.1:12345/callback"},
+			"grantTypes":           []any{"authorization_code", "refresh_token"},
+			"scope":                "openid offline_access username",
+		}
+		if personalInfo != nil {
+			params["personalInfo"] = personalInfo
+		}
+		return testutil.WantAuditLog("OIDCClient Registered", params)
+	}
+
+	invalidClientMetadata := func(description string) string {
+		return errorJSON(t, "invalid_client_metadata", description)
+	}
+	invalidRedirectURI := func(description string) string {
+		return errorJSON(t, "invalid_redirect_uri", description)
+	}
+	const invalidTokenJSON = `{"error": "invalid_token"}`
+	const serverErrorJSON = `{"error": "server_error"}`
+
+	tests := []struct {
+		name             string
+		policy           func(*federationdomainproviders.ClientRegistrationPolicy)
+		callerAuthorizer *fakeCallerAuthorizer
+		addReactors      func(*kubefake.Clientset, *supervisorfake.Clientset)
+		method           string
+		path             string
+		token            string
+		contentType      string
+		body             string
+
+		wantStatus                 int
+		wantBodyJSON               string
+		wantBodyString             string
+		wantCallerAuthorizerTokens []string
+		wantOIDCClients            []*supervisorconfigv1alpha1.OIDCClient
+		wantClientSecrets          map[string]string
+		wantAuditLogs              []testutil.WantedAuditLog
+	}{
+		{
+			name:            "registration with the wrong method",
+			method:          http.MethodGet,
+			path:            "/some/path/oauth2/register",
+			token:           initialAccessToken,
+			wantStatus:      http.StatusMethodNotAllowed,
+			wantBodyString:  "Method not allowed (try POST)\n",
+			wantOIDCClients: []*supervisorconfigv1alpha1.OIDCClient{registeredClient()},
+		},
+		{
+			name:            "registration without a bearer token",
+			method:          http.MethodPost,
+			path:            "/some/path/oauth2/register",
+			body:            happyRegistrationBody,
+			wantStatus:      http.StatusUnauthorized,
+			wantBodyJSON:    invalidTokenJSON,
+			wantOIDCClients: []*supervisorconfigv1alpha1.OIDCClient{registeredClient()},
+		},
+		{
+			name:            "registration with the wrong initial access token",
+			method:          http.MethodPost,
+			path:            "/some/path/oauth2/register",
+			token:           "wrong-token",
+			body:            happyRegistrationBody,
+			wantStatus:      http.StatusUnauthorized,
+			wantBodyJSON:    invalidTokenJSON,
+			wantOIDCClients: []*supervisorconfigv1alpha1.OIDCClient{registeredClient()},
+		},
+		{
+			name: "registration with an initial access token when the initial access token Secret does not exist",
+			policy: func(p *federationdomainproviders.ClientRegistrationPolicy) {
+				p.InitialAccessTokenSecretName = "does-not-exist"
+			},
+			method:          http.MethodPost,
+			path:            "/some/path/oauth2/register",
+			token:           initialAccessToken,
+			body:            happyRegistrationBody,
+			wantStatus:      http.StatusUnauthorized,
+			wantBodyJSON:    invalidTokenJSON,
+			wantOIDCClients: []*supervisorconfigv1alpha1.OIDCClient{registeredClient()},
+		},
+		{
+			name:         "happy registration with an initial access token",
+			method:       http.MethodPost,
+			path:         "/some/path/oauth2/register",
+			token:        initialAccessToken,
+			body:         happyRegistrationBody,
+			wantStatus:   http.StatusCreated,
+			wantBodyJSON: happyRegistrationResponseJSON,
+			wantOIDCClients: []*supervisorconfigv1alpha1.OIDCClient{
+				happyRegisteredClient,
+				registeredClient(),
+			},
+			wantClientSecrets: map[string]string{generatedClientID: generatedClientSecret},
+			wantAuditLogs: []testutil.WantedAuditLog{
+				happyRegistrationAuditLog("initialAccessToken", nil),
+			},
+		},
+		{
+			name: "happy registration with a Kubernetes credential",
+			policy: func(p *federationdomainproviders.ClientRegistrationPolicy) {
+				p.InitialAccessTokenSecretName = ""
+				p.AllowKubernetesAuthentication = true
+			},
+			callerAuthorizer:           &fakeCallerAuthorizer{username: "some-kube-user"},
+			method:                     http.MethodPost,
+			path:                       "/some/path/oauth2/register",
+			token:                      "some-kube-token",
+			body:                       happyRegistrationBody,
+			wantStatus:                 http.StatusCreated,
+			wantBodyJSON:               happyRegistrationResponseJSON,
+			wantCallerAuthorizerTokens: []string{"some-kube-token"},
+			wantOIDCClients: []*supervisorconfigv1alpha1.OIDCClient{
+				happyRegisteredClient,
+				registeredClient(),
+			},
+			wantClientSecrets: map[string]string{generatedClientID: generatedClientSecret},
+			wantAuditLogs: []testutil.WantedAuditLog{
+				happyRegistrationAuditLog("kubernetes", map[string]any{"username": "some-kube-user"}),
+			},
+		},
+		{
+			name: "registration falls back to a Kubernetes credential when the token is not an initial access token",
+			policy: func(p *federationdomainproviders.ClientRegistrationPolicy) {
+				p.AllowKubernetesAuthentication = true
+			},
+			callerAuthorizer:           &fakeCallerAuthorizer{username: "some-kube-user"},
+			method:                     http.MethodPost,
+			path:                       "/some/path/oauth2/register",
+			token:                      "some-kube-token",
+			body:                       happyRegistrationBody,
+			wantStatus:                 http.StatusCreated,
+			wantBodyJSON:               happyRegistrationResponseJSON,
+			wantCallerAuthorizerTokens: []string{"some-kube-token"},
+			wantOIDCClients: []*supervisorconfigv1alpha1.OIDCClient{
+				happyRegisteredClient,
+				registeredClient(),
+			},
+			wantClientSecrets: map[string]string{generatedClientID: generatedClientSecret},
+			wantAuditLogs: []testutil.WantedAuditLog{
+				happyRegistrationAuditLog("kubernetes", map[string]any{"username": "some-kube-user"}),
+			},
+		},
+		{
+			name:             "registration with a Kubernetes credential when it is not allowed by the policy",
+			callerAuthorizer: &fakeCallerAuthorizer{username: "some-kube-user"},
+			method:           http.MethodPost,
+			path:             "/some/path/oauth2/register",
+			token:            "some-kube-token",
+			body:             happyRegistrationBody,
+			wantStatus:       http.StatusUnauthorized,
+			wantBodyJSON:     invalidTokenJSON,
+			wantOIDCClients:  []*supervisorconfigv1alpha1.OIDCClient{registeredClient()},
+		},
+		{
+			name: "registration with a Kubernetes credential which is not authorized",
+			policy: func(p *federationdomainproviders.ClientRegistrationPolicy) {
+				p.AllowKubernetesAuthentication = true
+			},
+			callerAuthorizer:           &fakeCallerAuthorizer{},
+			method:                     http.MethodPost,
+			path:                       "/some/path/oauth2/register",
+			token:                      "some-kube-token",
+			body:                       happyRegistrationBody,
+			wantStatus:                 http.StatusUnauthorized,
+			wantBodyJSON:               invalidTokenJSON,
+			wantCallerAuthorizerTokens: []string{"some-kube-token"},
+			wantOIDCClients:            []*supervisorconfigv1alpha1.OIDCClient{registeredClient()},
+		},
+		{
+			name: "registration when the Kubernetes credential cannot be checked",
+			policy: func(p *federationdomainproviders.ClientRegistrationPolicy) {
+				p.AllowKubernetesAuthentication = true
+			},
+			callerAuthorizer:           &fakeCallerAuthorizer{username: "some-kube-user", err: errors.New("some error")},
+			method:                     http.MethodPost,
+			path:                       "/some/path/oauth2/register",
+			token:                      "some-kube-token",
+			body:                       happyRegistrationBody,
+			wantStatus:                 http.StatusUnauthorized,
+			wantBodyJSON:               invalidTokenJSON,
+			wantCallerAuthorizerTokens: []string{"some-kube-token"},
+			wantOIDCClients:            []*supervisorconfigv1alpha1.OIDCClient{registeredClient()},
+		},
+		{
+			name:            "registration with the wrong content type",
+			method:          http.MethodPost,
+			path:            "/some/path/oauth2/register",
+			token:           initialAccessToken,
+			contentType:     "application/x-www-form-urlencoded",
+			body:            happyRegistrationBody,
+			wantStatus:      http.StatusBadRequest,
+			wantBodyJSON:    invalidClientMetadata("content type must be application/json"),
+			wantOIDCClients: []*supervisorconfigv1alpha1.OIDCClient{registeredClient()},
+		},
+		{
+			name:            "registration with invalid JSON",
+			method:          http.MethodPost,
+			path:            "/some/path/oauth2/register",
+			token:           initialAccessToken,
+			body:            `{"redirect_uris": "not-a-list"}`,
+			wantStatus:      http.StatusBadRequest,
+			wantBodyJSON:    invalidClientMetadata("could not parse client metadata: json: cannot unmarshal string into Go struct field clientMetadata.redirect_uris of type []string"),
+			wantOIDCClients: []*supervisorconfigv1alpha1.OIDCClient{registeredClient()},
+		},
+		{
+			name:            "registration with an unsupported token endpoint auth method",
+			method:          http.MethodPost,
+			path:            "/some/path/oauth2/register",
+			token:           initialAccessToken,
+			body:            `{"redirect_uris": ["https://app.example.com/callback"], "token_endpoint_auth_method": "none"}`,
+			wantStatus:      http.StatusBadRequest,
+			wantBodyJSON:    invalidClientMetadata(`token_endpoint_auth_method must be "client_secret_basic"`),
+			wantOIDCClients: []*supervisorconfigv1alpha1.OIDCClient{registeredClient()},
+		},
+		{
+			name:            "registration with an unsupported response type",
+			method:          http.MethodPost,
+			path:            "/some/path/oauth2/register",
+			token:           initialAccessToken,
+			body:            `{"redirect_uris": ["https://app.example.com/callback"], "response_types": ["token"]}`,
+			wantStatus:      http.StatusBadRequest,
+			wantBodyJSON:    invalidClientMetadata(`response_types value "token" is not supported`),
+			wantOIDCClients: []*supervisorconfigv1alpha1.OIDCClient{registeredClient()},
+		},
+		{
+			name:            "registration with a grant type which is not allowed by the policy",
+			method:          http.MethodPost,
+			path:            "/some/path/oauth2/register",
+			token:           initialAccessToken,
+			body:            `{"redirect_uris": ["https://app.example.com/callback"], "grant_types": ["authorization_code", "urn:ietf:params:oauth:grant-type:token-exchange"]}`,
+			wantStatus:      http.StatusBadRequest,
+			wantBodyJSON:    invalidClientMetadata(`grant_types value "urn:ietf:params:oauth:grant-type:token-exchange" is not allowed`),
+			wantOIDCClients: []*supervisorconfigv1alpha1.OIDCClient{registeredClient()},
+		},
+		{
+			name:            "registration with the client credentials grant type is never allowed",
+			method:          http.MethodPost,
+			path:            "/some/path/oauth2/register",
+			token:           initialAccessToken,
+			body:            `{"grant_types": ["client_credentials"]}`,
+			wantStatus:      http.StatusBadRequest,
+			wantBodyJSON:    invalidClientMetadata(`grant_types value "client_credentials" is not allowed`),
+			wantOIDCClients: []*supervisorconfigv1alpha1.OIDCClient{registeredClient()},
+		},
+		{
+			name:            "registration with a scope which is not allowed by the policy",
+			method:          http.MethodPost,
+			path:            "/some/path/oauth2/register",
+			token:           initialAccessToken,
+			body:            `{"redirect_uris": ["https://app.example.com/callback"], "scope": "openid pinniped:request-audience"}`,
+			wantStatus:      http.StatusBadRequest,
+			wantBodyJSON:    invalidClientMetadata(`scope value "pinniped:request-audience" is not allowed`),
+			wantOIDCClients: []*supervisorconfigv1alpha1.OIDCClient{registeredClient()},
+		},
+		{
+			name:            "registration with allowed values which would not make a valid OIDCClient",
+			method:          http.MethodPost,
+			path:            "/some/path/oauth2/register",
+			token:           initialAccessToken,
+			body:            `{"redirect_uris": ["https://app.example.com/callback"], "grant_types": ["authorization_code", "refresh_token"], "scope": "openid"}`,
+			wantStatus:      http.StatusBadRequest,
+			wantBodyJSON:    invalidClientMetadata(`"offline_access" must be included in "allowedScopes" when "refresh_token" is included in "allowedGrantTypes"`),
+			wantOIDCClients: []*supervisorconfigv1alpha1.OIDCClient{registeredClient()},
+		},
+		{
+			name:            "registration without redirect URIs",
+			method:          http.MethodPost,
+			path:            "/some/path/oauth2/register",
+			token:           initialAccessToken,
+			body:            `{}`,
+			wantStatus:      http.StatusBadRequest,
+			wantBodyJSON:    invalidRedirectURI(`redirect_uris is required when grant_types includes "authorization_code"`),
+			wantOIDCClients: []*supervisorconfigv1alpha1.OIDCClient{registeredClient()},
+		},
+		{
+			name:            "registration with a redirect URI which is not allowed by the policy",
+			method:          http.MethodPost,
+			path:            "/some/path/oauth2/register",
+			token:           initialAccessToken,
+			body:            `{"redirect_uris": ["https://app.example.com/callback", "https://evil.com/callback"]}`,
+			wantStatus:      http.StatusBadRequest,
+			wantBodyJSON:    invalidRedirectURI(`redirect_uris value "https://evil.com/callback" is not allowed`),
+			wantOIDCClients: []*supervisorconfigv1alpha1.OIDCClient{registeredClient()},
+		},
+		{
+			name:            "registration with a redirect URI which only appears to match a pattern of the policy",
+			method:          http.MethodPost,
+			path:            "/some/path/oauth2/register",
+			token:           initialAccessToken,
+			body:            `{"redirect_uris": ["https://attacker.com?.example.com/callback"]}`,
+			wantStatus:      http.StatusBadRequest,
+			wantBodyJSON:    invalidRedirectURI(`redirect_uris value "https://attacker.com?.example.com/callback" is not allowed`),
+			wantOIDCClients: []*supervisorconfigv1alpha1.OIDCClient{registeredClient()},
+		},
+		{
+			name: "registration with a redirect URI which is allowed by the policy but is never allowed for OIDCClients",
+			policy: func(p *federationdomainproviders.ClientRegistrationPolicy) {
+				p.AllowedRedirectURIPatterns = []string{"http://*.example.com/callback"}
+			},
+			method:          http.MethodPost,
+			path:            "/some/path/oauth2/register",
+			token:           initialAccessToken,
+			body:            `{"redirect_uris": ["http://app.example.com/callback"]}`,
+			wantStatus:      http.StatusBadRequest,
+			wantBodyJSON:    invalidRedirectURI(`redirect_uris value "http://app.example.com/callback" is not allowed`),
+			wantOIDCClients: []*supervisorconfigv1alpha1.OIDCClient{registeredClient()},
+		},
+		{
+			name: "registration when the OIDCClient cannot be created",
+			addReactors: func(_ *kubefake.Clientset, supervisorClient *supervisorfake.Clientset) {
+				supervisorClient.PrependReactor("create", "oidcclients", func(_ coretesting.Action) (bool, runtime.Object, error) {
+					return true, nil, errors.New("some create error")
+				})
+			},
+			method:          http.MethodPost,
+			path:            "/some/path/oauth2/register",
+			token:           initialAccessToken,
+			body:            happyRegistrationBody,
+			wantStatus:      http.StatusInternalServerError,
+			wantBodyJSON:    serverErrorJSON,
+			wantOIDCClients: []*supervisorconfigv1alpha1.OIDCClient{registeredClient()},
+		},
+		{
+			name: "registration when the client secret cannot be stored deletes the OIDCClient",
+			addReactors: func(kubeClient *kubefake.Clientset, _ *supervisorfake.Clientset) {
+				kubeClient.PrependReactor("create", "secrets", func(_ coretesting.Action) (bool, runtime.Object, error) {
+					return true, nil, errors.New("some create error")
+				})
+			},
+			method:          http.MethodPost,
+			path:            "/some/path/oauth2/register",
+			token:           initialAccessToken,
+			body:            happyRegistrationBody,
+			wantStatus:      http.StatusInternalServerError,
+			wantBodyJSON:    serverErrorJSON,
+			wantOIDCClients: []*supervisorconfigv1alpha1.OIDCClient{registeredClient()},
+		},
+		{
+			name:            "get client configuration",
+			method:          http.MethodGet,
+			path:            "/some/path/oauth2/register/" + registeredClientID,
+			token:           registrationAccessToken,
+			wantStatus:      http.StatusOK,
+			wantBodyJSON:    registeredClientJSON,
+			wantOIDCClients: []*supervisorconfigv1alpha1.OIDCClient{registeredClient()},
+		},
+		{
+			name:            "get client configuration without a bearer token",
+			method:          http.MethodGet,
+			path:            "/some/path/oauth2/register/" + registeredClientID,
+			wantStatus:      http.StatusUnauthorized,
+			wantBodyJSON:    invalidTokenJSON,
+			wantOIDCClients: []*supervisorconfigv1alpha1.OIDCClient{registeredClient()},
+		},
+		{
+			name:            "get client configuration with the wrong registration access token",
+			method:          http.MethodGet,
+			path:            "/some/path/oauth2/register/" + registeredClientID,
+			token:           initialAccessToken,
+			wantStatus:      http.StatusUnauthorized,
+			wantBodyJSON:    invalidTokenJSON,
+			wantOIDCClients: []*supervisorconfigv1alpha1.OIDCClient{registeredClient()},
+		},
+		{
+			name:            "get client configuration of a client which does not exist",
+			method:          http.MethodGet,
+			path:            "/some/path/oauth2/register/client.oauth.pinniped.dev-does-not-exist",
+			token:           registrationAccessToken,
+			wantStatus:      http.StatusUnauthorized,
+			wantBodyJSON:    invalidTokenJSON,
+			wantOIDCClients: []*supervisorconfigv1alpha1.OIDCClient{registeredClient()},
+		},
+		{
+			name: "get client configuration of a client which was registered by another FederationDomain",
+			policy: func(p *federationdomainproviders.ClientRegistrationPolicy) {
+				p.FederationDomainName = "some-other-federation-domain"
+			},
+			method:          http.MethodGet,
+			path:            "/some/path/oauth2/register/" + registeredClientID,
+			token:           registrationAccessToken,
+			wantStatus:      http.StatusUnauthorized,
+			wantBodyJSON:    invalidTokenJSON,
+			wantOIDCClients: []*supervisorconfigv1alpha1.OIDCClient{registeredClient()},
+		},
+		{
+			name:            "client configuration with the wrong method",
+			method:          http.MethodPatch,
+			path:            "/some/path/oauth2/register/" + registeredClientID,
+			token:           registrationAccessToken,
+			wantStatus:      http.StatusMethodNotAllowed,
+			wantBodyString:  "Method not allowed (try GET, PUT, or DELETE)\n",
+			wantOIDCClients: []*supervisorconfigv1alpha1.OIDCClient{registeredClient()},
+		},
+		{
+			name:   "update client configuration",
+			method: http.MethodPut,
+			path:   "/some/path/oauth2/register/" + registeredClientID,
+			token:  registrationAccessToken,
+			body: `{
+				"client_id": "client.oauth.pinniped.dev-registered",
+				"client_secret": "some-registered-client-secret",
+				"redirect_uris": ["https://other.example.com/callback"],
+				"grant_types": ["authorization_code", "refresh_token"],
+				"scope": "openid offline_access groups"
+			}`,
+			wantStatus: http.StatusOK,
+			wantBodyJSON: `{
+				"client_id": "client.oauth.pinniped.dev-registered",
+				"client_id_issued_at": 1772600767,
+				"client_secret_expires_at": 0,
+				"registration_client_uri": "https://some-issuer.com/some/path/oauth2/register/client.oauth.pinniped.dev-registered",
+				"redirect_uris": ["https://other.example.com/callback"],
+				"token_endpoint_auth_method": "client_secret_basic",
+				"grant_types": ["authorization_code", "refresh_token"],
+				"response_types": ["code"],
+				"scope": "openid offline_access groups"
+			}`,
+			wantOIDCClients: []*supervisorconfigv1alpha1.OIDCClient{
+				func() *supervisorconfigv1alpha1.OIDCClient {
+					c := registeredClient()
+					delete(c.Annotations, "supervisor.pinniped.dev/client-name")
+					c.Spec = supervisorconfigv1alpha1.OIDCClientSpec{
+						AllowedRedirectURIs:     []supervisorconfigv1alpha1.RedirectURI{"https://other.example.com/callback"},
+						AllowedGrantTypes:       []supervisorconfigv1alpha1.GrantType{"authorization_code", "refresh_token"},
+						AllowedScopes:           []supervisorconfigv1alpha1.Scope{"openid", "offline_access", "groups"},
+						TokenEndpointAuthMethod: supervisorconfigv1alpha1.TokenEndpointAuthMethodClientSecretBasic,
+					}
+					return c
+				}(),
+			},
+			wantAuditLogs: []testutil.WantedAuditLog{
+				testutil.WantAuditLog("OIDCClient Registration Updated", map[string]any{
+					"clientID":     registeredClientID,
+					"redirectURIs": []any{"https://other.example.com/callback"},
+					"grantTypes":   []any{"authorization_code", "refresh_token"},
+					"scope":        "openid offline_access groups",
+				}),
+			},
+		},
+		{
+			name:            "update client configuration with the wrong client ID",
+			method:          http.MethodPut,
+			path:            "/some/path/oauth2/register/" + registeredClientID,
+			token:           registrationAccessToken,
+			body:            `{"client_id": "client.oauth.pinniped.dev-other", "redirect_uris": ["https://other.example.com/callback"]}`,
+			wantStatus:      http.StatusBadRequest,
+			wantBodyJSON:    invalidClientMetadata("client_id must be the client ID of the client being updated"),
+			wantOIDCClients: []*supervisorconfigv1alpha1.OIDCClient{registeredClient()},
+		},
+		{
+			name:            "update client configuration with the wrong client secret",
+			method:          http.MethodPut,
+			path:            "/some/path/oauth2/register/" + registeredClientID,
+			token:           registrationAccessToken,
+			body:            `{"client_id": "client.oauth.pinniped.dev-registered", "client_secret": "wrong", "redirect_uris": ["https://other.example.com/callback"]}`,
+			wantStatus:      http.StatusBadRequest,
+			wantBodyJSON:    invalidClientMetadata("client_secret must be the client secret of the client being updated"),
+			wantOIDCClients: []*supervisorconfigv1alpha1.OIDCClient{registeredClient()},
+		},
+		{
+			name:            "update client configuration with metadata which is not allowed by the policy",
+			method:          http.MethodPut,
+			path:            "/some/path/oauth2/register/" + registeredClientID,
+			token:           registrationAccessToken,
+			body:            `{"client_id": "client.oauth.pinniped.dev-registered", "redirect_uris": ["https://evil.com/callback"]}`,
+			wantStatus:      http.StatusBadRequest,
+			wantBodyJSON:    invalidRedirectURI(`redirect_uris value "https://evil.com/callback" is not allowed`),
+			wantOIDCClients: []*supervisorconfigv1alpha1.OIDCClient{registeredClient()},
+		},
+		{
+			name:       "delete client",
+			method:     http.MethodDelete,
+			path:       "/some/path/oauth2/register/" + registeredClientID,
+			token:      registrationAccessToken,
+			wantStatus: http.StatusNoContent,
+			wantAuditLogs: []testutil.WantedAuditLog{
+				testutil.WantAuditLog("OIDCClient Registration Deleted", map[string]any{
+					"clientID": registeredClientID,
+				}),
+			},
+		},
+		{
+			name: "delete client when the OIDCClient cannot be deleted",
+			addReactors: func(_ *kubefake.Clientset, supervisorClient *supervisorfake.Clientset) {
+				supervisorClient.PrependReactor("delete", "oidcclients", func(_ coretesting.Action) (bool, runtime.Object, error) {
+					return true, nil, errors.New("some delete error")
+				})
+			},
+			method:          http.MethodDelete,
+			path:            "/some/path/oauth2/register/" + registeredClientID,
+			token:           registrationAccessToken,
+			wantStatus:      http.StatusInternalServerError,
+			wantBodyJSON:    serverErrorJSON,
+			wantOIDCClients: []*supervisorconfigv1alpha1.OIDCClient{registeredClient()},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			kubeClient := kubefake.NewClientset(initialAccessTokenSecret)
+			secretsClient := kubeClient.CoreV1().Secrets(namespace)
+			supervisorClient := supervisorfake.NewSimpleClientset(registeredClient())
+			oidcClientsClient := supervisorClient.ConfigV1alpha1().OIDCClients(namespace)
+
+			require.NoError(t, oidcclientsecretstorage.New(secretsClient).Set(
+				t.Context(), "", registeredClientID, registeredClientUID, []string{string(registeredClientSecretHash)}),
+			)
+
+			// The fake client does not fill in the fields which would be filled in by the API server.
+			supervisorClient.PrependReactor("create", "oidcclients", func(action coretesting.Action) (bool, runtime.Object, error) {
+				oidcClient := action.(coretesting.CreateAction).GetObject().(*supervisorconfigv1alpha1.OIDCClient)
+				oidcClient.UID = types.UID("uid-of-" + oidcClient.Name)
+				oidcClient.CreationTimestamp = creationTimestamp
+				return false, nil, nil
+			})
+			if test.addReactors != nil {
+				test.addReactors(kubeClient, supervisorClient)
+			}
+
+			policy := happyPolicy()
+			if test.policy != nil {
+				test.policy(policy)
+			}
+
+			callerAuthorizer := test.callerAuthorizer
+			if callerAuthorizer == nil {
+				callerAuthorizer = &fakeCallerAuthorizer{}
+			}
+
+			auditLogger, actualAuditLog := plog.TestAuditLogger(t)
+
+			subject := NewHandler(
+				issuer,
+				policy,
+				oidcClientsClient,
+				secretsClient,
+				callerAuthorizer,
+				fakeRandReader(),
+				bcrypt.MinCost,
+				bcrypt.GenerateFromPassword,
+				auditLogger,
+			)
+
+			req := httptest.NewRequest(test.method, test.path, strings.NewReader(test.body))
+			if test.token != "" {
+				req.Header.Set("Authorization", "Bearer "+test.token)
+			}
+			contentType := test.contentType
+			if contentType == "" && test.body != "" {
+				contentType = "application/json"
+			}
+			req.Header.Set("Content-Type", contentType)
+			req, _ = auditid.NewRequestWithAuditID(req, func() string { return "fake-audit-id" })
+			rsp := httptest.NewRecorder()
+
+			subject.ServeHTTP(rsp, req)
+
+			require.Equal(t, test.wantStatus, rsp.Code, rsp.Body.String())
+			require.Equal(t, "no-store", rsp.Header().Get("Cache-Control"))
+			if test.wantStatus == http.StatusUnauthorized {
+				require.Equal(t, `Bearer error="invalid_token"`, rsp.Header().Get("WWW-Authenticate"))
+			}
+			if test.wantBodyJSON != "" {
+				require.Equal(t, "application/json", rsp.Header().Get("Content-Type"))
+				require.JSONEq(t, test.wantBodyJSON, rsp.Body.String())
+			}
+			if test.wantBodyString != "" {
+				require.Equal(t, test.wantBodyString, rsp.Body.String())
+			}
+			if test.wantBodyJSON == "" && test.wantBodyString == "" {
+				require.Empty(t, rsp.Body.String())
+			}
+
+			require.Equal(t, test.wantCallerAuthorizerTokens, callerAuthorizer.gotTokens)
+
+			actualOIDCClients, err := oidcClientsClient.List(t.Context(), metav1.ListOptions{})
+			require.NoError(t, err)
+			require.Len(t, actualOIDCClients.Items, len(test.wantOIDCClients))
+			for i, wantOIDCClient := range test.wantOIDCClients {
+				actualOIDCClient := actualOIDCClients.Items[i]
+				require.Equal(t, wantOIDCClient.Name, actualOIDCClient.Name)
+				require.Equal(t, wantOIDCClient.Annotations, actualOIDCClient.Annotations)
+				require.Equal(t, wantOIDCClient.Spec, actualOIDCClient.Spec)
+			}
+
+			actualSecrets, err := secretsClient.List(t.Context(), metav1.ListOptions{})
+			require.NoError(t, err)
+			// The client secret storage Secret of the client which already existed is never deleted by the handler.
+			wantClientSecrets := map[string]string{registeredClientID: registeredClientSecret}
+			maps.Copy(wantClientSecrets, test.wantClientSecrets)
+			require.Len(t, actualSecrets.Items, len(wantClientSecrets)+1) // plus the initial access token Secret
+			for clientID, wantClientSecret := range wantClientSecrets {
+				uid := types.UID(registeredClientUID)
+				if clientID != registeredClientID {
+					uid = types.UID("uid-of-" + clientID)
+				}
+				_, hashes, err := oidcclientsecretstorage.New(secretsClient).Get(t.Context(), uid)
+				require.NoError(t, err)
+				require.Len(t, hashes, 1)
+				require.NoError(t, bcrypt.CompareHashAndPassword([]byte(hashes[0]), []byte(wantClientSecret)))
+			}
+
+			testutil.WantAuditIDOnEveryAuditLog(test.wantAuditLogs, "fake-audit-id")
+			testutil.CompareAuditLogs(t, test.wantAuditLogs, actualAuditLog.String())
+		})
+	}
+}
+
+// fakeRandReader returns 16 bytes of 0x01 for the client ID, then 32 bytes of 0x02 for the client secret,
+// then 32 bytes of 0x03 for the registration access token.
+func fakeRandReader() *bytes.Reader {
+	return bytes.NewReader(bytes.Join([][]byte{
+		bytes.Repeat([]byte{0x01}, 16),
+		bytes.Repeat([]byte{0x02}, 32),
+		bytes.Repeat([]byte{0x03}, 32),
+	}, nil))
+}
+
+func sha256Hex(s string) string {
+	hash := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(hash[:])
+}
+
+func errorJSON(t *testing.T, errorCode, description string) string {
+	t.Helper()
+
+	b, err := json.Marshal(map[string]string{"error": errorCode, "error_description": description})
+	require.NoError(t, err)
+	return string(b)
+}
+
+func TestRedirectURIAllowed(t *testing.T) {
+	h := &handler{policy: &federationdomainproviders.ClientRegistrationPolicy{
+		AllowedRedirectURIPatterns: []string{
+			"https://*.preview.example.com/callback",
+			"https://pr-*.example.com/callback",
+			"https://app.example.com",
+			"http://127.0.0.1:*/callback",
+			"http://[::1]:*/callback",
+			"https://app.example.com/*",          // invalid, since "*" is only allowed in the host and port
+			"https://*@app.example.com/callback", // invalid, since userinfo is never allowed
+			"https://[.example.com/callback",     // invalid host pattern
+		},
+	}}
+
+	tests := []struct {
+		redirectURI string
+		want        bool
+	}{
+		{redirectURI: "https://foo.preview.example.com/callback", want: true},
+		{redirectURI: "https://FOO.Preview.Example.com/callback", want: true},
+		{redirectURI: "https://pr-123.example.com/callback", want: true},
+		{redirectURI: "https://app.example.com", want: true},
+		{redirectURI: "http://127.0.0.1:12345/callback", want: true},
+		{redirectURI: "http://[::1]:12345/callback", want: true},
+
+		// The wildcard must not match anything other than the characters of a single host label.
+		{redirectURI: "https://attacker.com?.preview.example.com/callback"},
+		{redirectURI: "https://attacker.com#.preview.example.com/callback"},
+		{redirectURI: "https://attacker.com/.preview.example.com/callback"},
+		{redirectURI: "https://attacker.com:@foo.preview.example.com/callback"},
+		{redirectURI: "https://attacker.com@foo.preview.example.com/callback"},
+		{redirectURI: "https://foo.preview.example.com@attacker.com/callback"},
+		{redirectURI: "https://attacker.com.foo.preview.example.com/callback"},
+		{redirectURI: "https://attacker_com.preview.example.com/callback"},
+		{redirectURI: "https://.preview.example.com/callback"},
+		{redirectURI: "https://pr-.attacker.com/callback"},
+
+		// The rest of the redirect URI must match exactly.
+		{redirectURI: "https://foo.preview.example.com/callback?next=https://attacker.com"},
+		{redirectURI: "https://foo.preview.example.com/callback?"},
+		{redirectURI: "https://foo.preview.example.com/callback#fragment"},
+		{redirectURI: "https://foo.preview.example.com/callback#"},
+		{redirectURI: "https://foo.preview.example.com/callback/other"},
+		{redirectURI: "https://foo.preview.example.com:8443/callback"},
+		{redirectURI: "http://foo.preview.example.com/callback"},
+		{redirectURI: "https://app.example.com/"},
+		{redirectURI: "https://app.example.com/anything"},
+		{redirectURI: "http://127.0.0.1/callback"},
+		{redirectURI: "http://127.0.0.2:12345/callback"},
+		{redirectURI: "https://user@app.example.com/callback"},
+		{redirectURI: "not a url"},
+	}
+	for _, test := range tests {
+		t.Run(test.redirectURI, func(t *testing.T) {
+			require.Equal(t, test.want, h.redirectURIAllowed(test.redirectURI))
+		})
+	}
+}
@@ -0,0 +1,63 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package revocation provides a handler for the OAuth 2.0 token revocation endpoint, as described in
+// https://datatracker.ietf.org/doc/html/rfc7009.
+package revocation
+
+import (
+	"net/http"
+
+	"github.com/ory/fosite"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"go.pinniped.dev/internal/auditevent"
+	"go.pinniped.dev/internal/federationdomain/oidc"
+	"go.pinniped.dev/internal/httputil/httperr"
+	"go.pinniped.dev/internal/plog"
+)
+
+func paramsSafeToLog() sets.Set[string] {
+	return sets.New(
+		// Standard params from https://datatracker.ietf.org/doc/html/rfc7009#section-2.1.
+		// Redacting token and client_secret params.
+		"token_type_hint", "client_id",
+		// Client authentication params from https://datatracker.ietf.org/doc/html/rfc7523#section-2.2.
+		// Redact client_assertion.
+		"client_assertion_type",
+	)
+}
+
+// NewHandler returns a handler for the token revocation endpoint. The oauthHelper should use storage which
+// revokes the whole downstream session, including its upstream tokens, when a token is revoked.
+// See sessionrevocation.Revoker.WrapStorage.
+func NewHandler(
+	oauthHelper fosite.OAuth2Provider,
+	auditLogger plog.AuditLogger,
+) http.Handler {
+	return httperr.HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+		if err := auditLogger.AuditRequestParams(r, paramsSafeToLog()); err != nil {
+			oauthHelper.WriteRevocationResponse(r.Context(), w, fosite.ErrInvalidRequest.WithWrap(err))
+			return nil
+		}
+
+		// For dynamic clients, the client ID is from basic auth, not from the request parameters.
+		if clientIDFromBasicAuth, _, basicAuthUsed := r.BasicAuth(); basicAuthUsed {
+			auditLogger.Audit(auditevent.HTTPRequestBasicAuthUsed, &plog.AuditParams{
+				ReqCtx:        r.Context(),
+				KeysAndValues: []any{"clientID", clientIDFromBasicAuth},
+			})
+		}
+
+		// This authenticates the client, finds the session of the token, and checks that the session belongs to
+		// the client. Then it revokes the session using the storage of the oauthHelper.
+		// As required by the RFC, this responds with success when the token was invalid or was already revoked.
+		err := oauthHelper.NewRevocationRequest(r.Context(), r)
+		if err != nil {
+			plog.Info("revocation request error", oidc.FositeErrorForLog(err)...)
+		}
+
+		oauthHelper.WriteRevocationResponse(r.Context(), w, err)
+		return nil
+	})
+}
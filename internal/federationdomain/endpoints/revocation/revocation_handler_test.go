@@ -0,0 +1,249 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package revocation
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/ory/fosite"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+
+	supervisorfake "go.pinniped.dev/generated/latest/client/supervisor/clientset/versioned/fake"
+	"go.pinniped.dev/internal/auditid"
+	"go.pinniped.dev/internal/federationdomain/clientregistry"
+	"go.pinniped.dev/internal/federationdomain/endpoints/jwks"
+	"go.pinniped.dev/internal/federationdomain/oidc"
+	"go.pinniped.dev/internal/federationdomain/sessionrevocation"
+	"go.pinniped.dev/internal/federationdomain/storage"
+	"go.pinniped.dev/internal/federationdomain/strategy"
+	"go.pinniped.dev/internal/plog"
+	"go.pinniped.dev/internal/psession"
+	"go.pinniped.dev/internal/testutil"
+	"go.pinniped.dev/internal/testutil/oidctestutil"
+	"go.pinniped.dev/internal/testutil/testidplister"
+)
+
+const (
+	downstreamIssuer = "https://my-downstream-issuer.com/path"
+
+	upstreamOIDCIDPName        = "upstream-oidc-idp-name"
+	upstreamOIDCIDPResourceUID = "upstream-oidc-resource-uid"
+
+	requestID = "some-request-id"
+)
+
+func TestRevocationHandler(t *testing.T) {
+	tests := []struct {
+		name              string
+		method            string
+		params            func(refreshToken, accessToken string) url.Values
+		wantStatus        int
+		wantBodyContains  string
+		wantSessionExists bool
+		wantUpstreamCalls int
+		wantAuditLogs     func(refreshToken, accessToken string) []testutil.WantedAuditLog
+	}{
+		{
+			name:   "revoking the refresh token ends the session",
+			method: http.MethodPost,
+			params: func(refreshToken, _ string) url.Values {
+				return url.Values{"token": {refreshToken}, "client_id": {"pinniped-cli"}}
+			},
+			wantStatus:        http.StatusOK,
+			wantUpstreamCalls: 1,
+			wantAuditLogs: func(_, _ string) []testutil.WantedAuditLog {
+				return []testutil.WantedAuditLog{
+					testutil.WantAuditLog("HTTP Request Parameters", map[string]any{
+						"params": map[string]any{"client_id": "pinniped-cli", "token": "redacted"},
+					}),
+					testutil.WantAuditLog("Upstream OIDC Token Revoked", map[string]any{
+						"sessionID": requestID,
+						"type":      "refresh_token",
+					}),
+					testutil.WantAuditLog("Session Revoked", map[string]any{
+						"sessionID":    requestID,
+						"storageTypes": []any{"access-token", "refresh-token"},
+					}),
+				}
+			},
+		},
+		{
+			name:   "revoking the access token ends the session",
+			method: http.MethodPost,
+			params: func(_, accessToken string) url.Values {
+				return url.Values{"token": {accessToken}, "token_type_hint": {"access_token"}, "client_id": {"pinniped-cli"}}
+			},
+			wantStatus:        http.StatusOK,
+			wantUpstreamCalls: 1,
+			wantAuditLogs: func(_, _ string) []testutil.WantedAuditLog {
+				return []testutil.WantedAuditLog{
+					testutil.WantAuditLog("HTTP Request Parameters", map[string]any{
+						"params": map[string]any{"client_id": "pinniped-cli", "token": "redacted", "token_type_hint": "access_token"},
+					}),
+					testutil.WantAuditLog("Upstream OIDC Token Revoked", map[string]any{
+						"sessionID": requestID,
+						"type":      "refresh_token",
+					}),
+					testutil.WantAuditLog("Session Revoked", map[string]any{
+						"sessionID":    requestID,
+						"storageTypes": []any{"access-token", "refresh-token"},
+					}),
+				}
+			},
+		},
+		{
+			name:   "unknown tokens are treated as already revoked, as required by the RFC",
+			method: http.MethodPost,
+			params: func(_, _ string) url.Values {
+				return url.Values{"token": {"pin_rt_not-a-real-token"}, "client_id": {"pinniped-cli"}}
+			},
+			wantStatus:        http.StatusOK,
+			wantSessionExists: true,
+			wantAuditLogs: func(_, _ string) []testutil.WantedAuditLog {
+				return []testutil.WantedAuditLog{
+					testutil.WantAuditLog("HTTP Request Parameters", map[string]any{
+						"params": map[string]any{"client_id": "pinniped-cli", "token": "redacted"},
+					}),
+				}
+			},
+		},
+		{
+			name:   "unknown client",
+			method: http.MethodPost,
+			params: func(refreshToken, _ string) url.Values {
+				return url.Values{"token": {refreshToken}, "client_id": {"some-other-client"}}
+			},
+			wantStatus:        http.StatusUnauthorized,
+			wantBodyContains:  `"error":"invalid_client"`,
+			wantSessionExists: true,
+			wantAuditLogs: func(_, _ string) []testutil.WantedAuditLog {
+				return []testutil.WantedAuditLog{
+					testutil.WantAuditLog("HTTP Request Parameters", map[string]any{
+						"params": map[string]any{"client_id": "some-other-client", "token": "redacted"},
+					}),
+				}
+			},
+		},
+		{
+			name:   "GET is not allowed",
+			method: http.MethodGet,
+			params: func(refreshToken, _ string) url.Values {
+				return url.Values{"token": {refreshToken}, "client_id": {"pinniped-cli"}}
+			},
+			wantStatus:        http.StatusBadRequest,
+			wantBodyContains:  `"error":"invalid_request"`,
+			wantSessionExists: true,
+			wantAuditLogs: func(_, _ string) []testutil.WantedAuditLog {
+				return []testutil.WantedAuditLog{
+					testutil.WantAuditLog("HTTP Request Parameters", map[string]any{
+						"params": map[string]any{"client_id": "pinniped-cli", "token": "redacted"},
+					}),
+				}
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			kubeClient := kubefake.NewClientset()
+			secrets := kubeClient.CoreV1().Secrets("some-namespace")
+			oidcClientsClient := supervisorfake.NewSimpleClientset().ConfigV1alpha1().OIDCClients("some-namespace")
+			timeoutsConfiguration := oidc.DefaultOIDCTimeoutsConfiguration()
+			// Use lower minimum required bcrypt cost than we would use in production to keep unit the tests fast.
+			kubeStorage := storage.NewKubeStorage(secrets, oidcClientsClient, timeoutsConfiguration, bcrypt.MinCost)
+			hmacSecretFunc := func() []byte { return []byte("some secret - must have at least 32 bytes") }
+
+			upstream := oidctestutil.NewTestUpstreamOIDCIdentityProviderBuilder().
+				WithName(upstreamOIDCIDPName).
+				WithResourceUID(upstreamOIDCIDPResourceUID).
+				WithRevokeTokenError(nil).
+				Build()
+			idpLister := testidplister.NewUpstreamIDPListerBuilder().WithOIDC(upstream).BuildDynamicUpstreamIDPProvider()
+			auditLogger, actualAuditLog := plog.TestAuditLogger(t)
+
+			revoker := sessionrevocation.New(secrets, idpLister, auditLogger)
+			oauthHelper := oidc.FositeOauth2Helper(revoker.WrapStorage(kubeStorage), downstreamIssuer, hmacSecretFunc, jwks.NewDynamicJWKSProvider(), timeoutsConfiguration)
+
+			refreshToken, accessToken := createSession(t, kubeStorage, hmacSecretFunc)
+
+			subject := NewHandler(oauthHelper, auditLogger)
+
+			params := test.params(refreshToken, accessToken)
+			var req *http.Request
+			if test.method == http.MethodGet {
+				req = httptest.NewRequest(test.method, "/oauth2/revoke?"+params.Encode(), nil)
+			} else {
+				req = httptest.NewRequest(test.method, "/oauth2/revoke", strings.NewReader(params.Encode()))
+				req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			}
+			req, _ = auditid.NewRequestWithAuditID(req, func() string { return "fake-audit-id" })
+			rsp := httptest.NewRecorder()
+
+			subject.ServeHTTP(rsp, req)
+
+			require.Equal(t, test.wantStatus, rsp.Code, rsp.Body.String())
+			if test.wantBodyContains != "" {
+				require.Contains(t, rsp.Body.String(), test.wantBodyContains)
+			}
+
+			remaining, err := secrets.List(t.Context(), metav1.ListOptions{})
+			require.NoError(t, err)
+			if test.wantSessionExists {
+				require.Len(t, remaining.Items, 2)
+			} else {
+				require.Empty(t, remaining.Items)
+			}
+
+			require.Equal(t, test.wantUpstreamCalls, upstream.RevokeTokenCallCount())
+			if test.wantUpstreamCalls > 0 {
+				require.Equal(t, "fake-upstream-refresh-token", upstream.RevokeTokenArgs(0).Token)
+			}
+
+			wantAuditLogs := test.wantAuditLogs(refreshToken, accessToken)
+			testutil.WantAuditIDOnEveryAuditLog(wantAuditLogs, "fake-audit-id")
+			testutil.CompareAuditLogs(t, wantAuditLogs, actualAuditLog.String())
+		})
+	}
+}
+
+// createSession stores a downstream session with an access token and a refresh token, like the token endpoint would.
+func createSession(t *testing.T, kubeStorage *storage.KubeStorage, hmacSecretFunc func() []byte) (string, string) {
+	t.Helper()
+
+	request := fosite.NewRequest()
+	request.ID = requestID
+	request.Client = clientregistry.PinnipedCLI()
+	request.GrantedScope = fosite.Arguments{"openid", "offline_access"}
+	session := psession.NewPinnipedSession()
+	session.Custom = &psession.CustomSessionData{
+		Username:     "some-username",
+		ProviderUID:  upstreamOIDCIDPResourceUID,
+		ProviderName: upstreamOIDCIDPName,
+		ProviderType: psession.ProviderTypeOIDC,
+		//nolint:gosec // not a real credential
+		OIDC: &psession.OIDCSessionData{
+			UpstreamRefreshToken: "fake-upstream-refresh-token",
+		},
+	}
+	request.Session = session
+
+	hmacStrategy := strategy.NewDynamicOauth2HMACStrategy(&fosite.Config{}, hmacSecretFunc)
+
+	refreshToken, refreshSignature, err := hmacStrategy.GenerateRefreshToken(t.Context(), request)
+	require.NoError(t, err)
+	require.NoError(t, kubeStorage.CreateRefreshTokenSession(t.Context(), refreshSignature, "", request))
+
+	accessToken, accessSignature, err := hmacStrategy.GenerateAccessToken(t.Context(), request)
+	require.NoError(t, err)
+	require.NoError(t, kubeStorage.CreateAccessTokenSession(t.Context(), accessSignature, request))
+
+	return refreshToken, accessToken
+}
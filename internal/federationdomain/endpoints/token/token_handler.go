@@ -20,6 +20,8 @@ import (
 
 	oidcapi "go.pinniped.dev/generated/latest/apis/supervisor/oidc"
 	"go.pinniped.dev/internal/auditevent"
+	"go.pinniped.dev/internal/federationdomain/clientregistry"
+	"go.pinniped.dev/internal/federationdomain/downstreamsession"
 	"go.pinniped.dev/internal/federationdomain/federationdomainproviders"
 	"go.pinniped.dev/internal/federationdomain/idtokenlifespan"
 	"go.pinniped.dev/internal/federationdomain/oidc"
@@ -41,13 +43,17 @@ func paramsSafeToLog() sets.Set[string] {
 		// We don't allow all of these, but they should be safe to log.
 		// "scope" is already included from the authcode grant.
 		"audience", "resource", "requested_token_type", "actor_token_type", "subject_token_type",
+		// Client authentication params from https://datatracker.ietf.org/doc/html/rfc7523#section-2.2.
+		// Redact client_assertion.
+		"client_assertion_type",
 	)
 }
 
 func NewHandler(
-	idpLister federationdomainproviders.FederationDomainIdentityProvidersListerI,
+	idpLister federationdomainproviders.FederationDomainIdentityProvidersListerFinderI,
 	oauthHelper fosite.OAuth2Provider,
 	overrideAccessTokenLifespan timeouts.OverrideLifespan,
+	overrideRefreshTokenLifespan timeouts.OverrideLifespan,
 	overrideIDTokenLifespan timeouts.OverrideLifespan,
 	auditLogger plog.AuditLogger,
 ) http.Handler {
@@ -72,6 +78,15 @@ func NewHandler(
 			Session: accessRequest,
 		})
 
+		// Depending on the request, sometimes override the default refresh token lifespan which was determined by the
+		// above call to NewAccessRequest. When the session has reached its maximum age, then do not issue any more
+		// tokens for it, and do not bother to perform an upstream refresh for it.
+		if err = maybeOverrideDefaultRefreshTokenLifetime(overrideRefreshTokenLifespan, accessRequest); err != nil {
+			plog.Info("token request error", oidc.FositeErrorForLog(err)...)
+			oauthHelper.WriteAccessError(r.Context(), w, accessRequest, err)
+			return nil
+		}
+
 		// Check if we are performing a refresh grant.
 		if accessRequest.GetGrantTypes().ExactOne(oidcapi.GrantTypeRefreshToken) {
 			// The above call to NewAccessRequest has loaded the session from storage into the accessRequest variable.
@@ -86,9 +101,21 @@ func NewHandler(
 			}
 		}
 
-		// When we are in the authorization code flow, check if we have any warnings that previous handlers want us
-		// to send to the client to be printed on the CLI.
-		if accessRequest.GetGrantTypes().ExactOne(oidcapi.GrantTypeAuthorizationCode) {
+		// Check if we are performing a client credentials grant. There was no authorization request for this grant,
+		// so the session for the client's configured identity must be started now.
+		if accessRequest.GetGrantTypes().ExactOne(oidcapi.GrantTypeClientCredentials) {
+			err = startClientCredentialsSession(r.Context(), accessRequest, idpLister, auditLogger)
+			if err != nil {
+				plog.Info("client credentials error", oidc.FositeErrorForLog(err)...)
+				oauthHelper.WriteAccessError(r.Context(), w, accessRequest, err)
+				return nil
+			}
+		}
+
+		// When we are in the authorization code flow or the device authorization flow, check if we have any warnings
+		// that previous handlers want us to send to the client to be printed on the CLI.
+		if accessRequest.GetGrantTypes().ExactOne(oidcapi.GrantTypeAuthorizationCode) ||
+			accessRequest.GetGrantTypes().ExactOne(oidcapi.GrantTypeDeviceCode) {
 			storedSession := accessRequest.GetSession().(*psession.PinnipedSession)
 			customSessionData := storedSession.Custom
 			if customSessionData != nil {
@@ -130,6 +157,18 @@ func maybeOverrideDefaultAccessTokenLifetime(overrideAccessTokenLifespan timeout
 	}
 }
 
+func maybeOverrideDefaultRefreshTokenLifetime(overrideRefreshTokenLifespan timeouts.OverrideLifespan, accessRequest fosite.AccessRequester) error {
+	newLifespan, doOverride := overrideRefreshTokenLifespan(accessRequest)
+	if !doOverride {
+		return nil
+	}
+	if newLifespan <= 0 {
+		return errSessionMaxAgeReached()
+	}
+	accessRequest.GetSession().SetExpiresAt(fosite.RefreshToken, time.Now().UTC().Add(newLifespan).Round(time.Second))
+	return nil
+}
+
 func maybeOverrideDefaultIDTokenLifetime(baseCtx context.Context, overrideIDTokenLifespan timeouts.OverrideLifespan, accessRequest fosite.AccessRequester) context.Context {
 	if newLifespan, doOverride := overrideIDTokenLifespan(accessRequest); doOverride {
 		return idtokenlifespan.OverrideIDTokenLifespanInContext(baseCtx, newLifespan)
@@ -146,6 +185,10 @@ func errMissingUpstreamSessionInternalError() *fosite.RFC6749Error {
 	}
 }
 
+func errSessionMaxAgeReached() *fosite.RFC6749Error {
+	return fosite.ErrInvalidGrant.WithHint("The session has reached its maximum age.")
+}
+
 func errUpstreamRefreshError() *fosite.RFC6749Error {
 	return &fosite.RFC6749Error{
 		ErrorField:       "error",
@@ -217,6 +260,7 @@ func upstreamRefresh(
 	// Perform the upstream refresh.
 	refreshedIdentity, err := idp.UpstreamRefresh(ctx, previousIdentity)
 	if err != nil {
+		downstreamsession.AuditIfRejectedByUpstreamAuthenticationPolicy(ctx, auditLogger, accessRequest, idp, err)
 		return err
 	}
 
@@ -287,6 +331,68 @@ func upstreamRefresh(
 	return nil
 }
 
+func startClientCredentialsSession(
+	ctx context.Context,
+	accessRequest fosite.AccessRequester,
+	idpFinder federationdomainproviders.FederationDomainIdentityProvidersFinderI,
+	auditLogger plog.AuditLogger,
+) error {
+	// Fosite only checks that the client is allowed to use this grant type after the session would have been started,
+	// so check it first here.
+	if !accessRequest.GetClient().GetGrantTypes().Has(oidcapi.GrantTypeClientCredentials) {
+		return fosite.ErrUnauthorizedClient.WithHintf("The OAuth 2.0 Client is not allowed to use authorization grant '%s'.",
+			oidcapi.GrantTypeClientCredentials)
+	}
+
+	client, ok := accessRequest.GetClient().(*clientregistry.Client)
+	if !ok || client.ClientCredentials == nil {
+		// This shouldn't really happen because the OIDCClient validations require the client's identity to be
+		// configured whenever the client_credentials grant type is allowed.
+		return fosite.ErrUnauthorizedClient.WithHint("The OAuth 2.0 Client does not have a configured identity for the client credentials grant.")
+	}
+
+	var idp resolvedprovider.FederationDomainResolvedIdentityProvider
+	var err error
+	if client.ClientCredentials.IdentityProvider == "" {
+		idp, err = idpFinder.FindDefaultIDP()
+	} else {
+		idp, err = idpFinder.FindUpstreamIDPByDisplayName(client.ClientCredentials.IdentityProvider)
+	}
+	if err != nil {
+		return fosite.ErrAccessDenied.WithHint("The identity provider configured for the client credentials grant was not found.").
+			WithWrap(err).WithDebug(err.Error())
+	}
+
+	// Auto-grant the requested scopes which make sense for this grant type. There is no refresh token for
+	// this grant type, so never grant offline_access.
+	for _, scope := range []string{
+		oidcapi.ScopeOpenID,
+		oidcapi.ScopeRequestAudience,
+		oidcapi.ScopeUsername,
+		oidcapi.ScopeGroups,
+	} {
+		oidc.GrantScopeIfRequested(accessRequest, scope)
+	}
+
+	session, err := downstreamsession.NewClientCredentialsSession(ctx, auditLogger, &downstreamsession.ClientCredentialsSessionConfig{
+		Username:         client.ClientCredentials.Username,
+		Groups:           client.ClientCredentials.Groups,
+		ClientID:         client.GetID(),
+		GrantedScopes:    accessRequest.GetGrantedScopes(),
+		IdentityProvider: idp,
+		SessionIDGetter:  accessRequest,
+	})
+	if err != nil {
+		return fosite.ErrAccessDenied.WithHintf("Reason: %s.", err.Error())
+	}
+
+	// Keep the access token expiration time which was already set on the session by fosite.
+	session.Fosite.ExpiresAt = accessRequest.GetSession().(*psession.PinnipedSession).Fosite.ExpiresAt
+	accessRequest.SetSession(session)
+
+	return nil
+}
+
 // findProviderByNameAndType finds the IDP by its resource name and IDP type,
 // and validates that its resource UID matches the expected UID.
 func findProviderByNameAndType(
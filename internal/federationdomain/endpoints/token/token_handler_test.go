@@ -10,6 +10,8 @@ import (
 	"crypto/elliptic"
 	"crypto/rand"
 	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/base32"
 	"encoding/base64"
 	"encoding/json"
@@ -26,6 +28,7 @@ import (
 
 	"github.com/go-jose/go-jose/v4"
 	josejwt "github.com/go-jose/go-jose/v4/jwt"
+	"github.com/google/uuid"
 	"github.com/ory/fosite"
 	fositeoauth2 "github.com/ory/fosite/handler/oauth2"
 	"github.com/ory/fosite/handler/openid"
@@ -46,6 +49,7 @@ import (
 	supervisorfake "go.pinniped.dev/generated/latest/client/supervisor/clientset/versioned/fake"
 	"go.pinniped.dev/internal/auditid"
 	"go.pinniped.dev/internal/celtransformer"
+	"go.pinniped.dev/internal/certauthority"
 	"go.pinniped.dev/internal/crud"
 	"go.pinniped.dev/internal/federationdomain/clientregistry"
 	"go.pinniped.dev/internal/federationdomain/endpoints/jwks"
@@ -56,12 +60,14 @@ import (
 	"go.pinniped.dev/internal/federationdomain/upstreamprovider"
 	"go.pinniped.dev/internal/fositestorage/accesstoken"
 	"go.pinniped.dev/internal/fositestorage/authorizationcode"
+	"go.pinniped.dev/internal/fositestorage/clientassertion"
 	"go.pinniped.dev/internal/fositestorage/openidconnect"
 	"go.pinniped.dev/internal/fositestorage/pkce"
 	"go.pinniped.dev/internal/fositestorage/refreshtoken"
 	"go.pinniped.dev/internal/fositestoragei"
 	"go.pinniped.dev/internal/here"
 	"go.pinniped.dev/internal/httputil/httperr"
+	"go.pinniped.dev/internal/idtransform"
 	"go.pinniped.dev/internal/oidcclientsecretstorage"
 	"go.pinniped.dev/internal/plog"
 	"go.pinniped.dev/internal/psession"
@@ -182,6 +188,13 @@ var (
 		}
 	`)
 
+	fositeSessionMaxAgeReachedErrorBody = here.Doc(`
+		{
+			"error":             "invalid_grant",
+			"error_description": "The provided authorization grant (e.g., authorization code, resource owner credentials) or refresh token is invalid, expired, revoked, does not match the redirection URI used in the authorization request, or was issued to another client. The session has reached its maximum age."
+		}
+	`)
+
 	fositeReusedAuthCodeErrorBody = here.Doc(`
 		{
 			"error":             "invalid_grant",
@@ -323,10 +336,15 @@ type tokenEndpointResponseExpectedValues struct {
 	wantCustomSessionDataStored            *psession.CustomSessionData
 	wantWarnings                           []RecordedWarning
 	wantAdditionalClaims                   map[string]any
+	// The number of client assertion JWT IDs which are expected to be remembered to prevent replays.
+	wantClientAssertionsStored int
 	// The expected lifetime of the ID tokens issued by authcode exchange and refresh, but not token exchange.
 	// When zero, will assume that the test wants the default value for ID token lifetime.
 	wantIDTokenLifetimeSeconds int
-	wantAuditLogs              func(sessionID string, idToken string) []testutil.WantedAuditLog
+	// Whether the access tokens issued by authcode exchange and refresh are expected to be RFC 9068 JWTs,
+	// instead of opaque access tokens.
+	wantJWTAccessToken bool
+	wantAuditLogs      func(sessionID string, idToken string) []testutil.WantedAuditLog
 }
 
 func withWantCustomIDTokenLifetime(wantIDTokenLifetimeSeconds int, w tokenEndpointResponseExpectedValues) tokenEndpointResponseExpectedValues {
@@ -334,6 +352,11 @@ func withWantCustomIDTokenLifetime(wantIDTokenLifetimeSeconds int, w tokenEndpoi
 	return w
 }
 
+func withWantJWTAccessToken(w tokenEndpointResponseExpectedValues) tokenEndpointResponseExpectedValues {
+	w.wantJWTAccessToken = true
+	return w
+}
+
 type authcodeExchangeInputs struct {
 	modifyAuthRequest             func(authRequest *http.Request)
 	modifyTokenRequest            func(tokenRequest *http.Request, authCode string)
@@ -373,6 +396,42 @@ func addFullyCapableDynamicClientWithCustomIDTokenLifetimeAndSecretToKubeResourc
 	}
 }
 
+func addFullyCapableDynamicClientWithSessionPolicyAndSecretToKubeResources(sessionPolicy *supervisorconfigv1alpha1.OIDCClientSessionPolicy) func(t *testing.T, supervisorClient *supervisorfake.Clientset, kubeClient *kubefake.Clientset) {
+	return func(t *testing.T, supervisorClient *supervisorfake.Clientset, kubeClient *kubefake.Clientset) {
+		oidcClient, secret := testutil.FullyCapableOIDCClientAndStorageSecret(t,
+			"some-namespace",
+			dynamicClientID,
+			dynamicClientUID,
+			goodRedirectURI,
+			nil, // no custom ID token lifetime
+			[]string{testutil.HashedPassword1AtGoMinCost, testutil.HashedPassword2AtGoMinCost},
+			oidcclientvalidator.Validate,
+		)
+		oidcClient.Spec.SessionPolicy = sessionPolicy
+		require.NoError(t, supervisorClient.Tracker().Add(oidcClient))
+		require.NoError(t, kubeClient.Tracker().Add(secret))
+	}
+}
+
+// dynamicClientJWTAccessTokenAudience is the aud claim of the JWT access tokens which are issued to the dynamic client.
+const dynamicClientJWTAccessTokenAudience = "https://some-api.example.com"
+
+func addFullyCapableDynamicClientWithJWTAccessTokensAndSecretToKubeResources(t *testing.T, supervisorClient *supervisorfake.Clientset, kubeClient *kubefake.Clientset) {
+	oidcClient, secret := testutil.FullyCapableOIDCClientAndStorageSecret(t,
+		"some-namespace",
+		dynamicClientID,
+		dynamicClientUID,
+		goodRedirectURI,
+		nil, // no custom ID token lifetime
+		[]string{testutil.HashedPassword1AtGoMinCost, testutil.HashedPassword2AtGoMinCost},
+		oidcclientvalidator.Validate,
+	)
+	oidcClient.Spec.AccessTokenFormat = supervisorconfigv1alpha1.AccessTokenFormatJWT
+	oidcClient.Spec.AccessTokenAudience = dynamicClientJWTAccessTokenAudience
+	require.NoError(t, supervisorClient.Tracker().Add(oidcClient))
+	require.NoError(t, kubeClient.Tracker().Add(secret))
+}
+
 func modifyAuthcodeTokenRequestWithDynamicClientAuth(r *http.Request, authCode string) {
 	r.Body = happyAuthcodeRequestBody(authCode).WithClientID("").ReadCloser() // No client_id in body.
 	r.SetBasicAuth(dynamicClientID, testutil.PlaintextPassword1)              // Use basic auth header instead.
@@ -501,6 +560,27 @@ func TestTokenEndpointAuthcodeExchange(t *testing.T) {
 				},
 			},
 		},
+		{
+			name:          "request is valid and tokens are issued for dynamic client which uses JWT access tokens",
+			kubeResources: addFullyCapableDynamicClientWithJWTAccessTokensAndSecretToKubeResources,
+			authcodeExchange: authcodeExchangeInputs{
+				modifyAuthRequest: func(r *http.Request) {
+					addDynamicClientIDToFormPostBody(r)
+					r.Form.Set("scope", "openid pinniped:request-audience username groups")
+				},
+				modifyTokenRequest: modifyAuthcodeTokenRequestWithDynamicClientAuth,
+				want: tokenEndpointResponseExpectedValues{
+					wantStatus:            http.StatusOK,
+					wantClientID:          dynamicClientID,
+					wantSuccessBodyFields: []string{"id_token", "access_token", "token_type", "scope", "expires_in"}, // no refresh token
+					wantRequestedScopes:   []string{"openid", "pinniped:request-audience", "username", "groups"},
+					wantGrantedScopes:     []string{"openid", "pinniped:request-audience", "username", "groups"},
+					wantUsername:          goodUsername,
+					wantGroups:            goodGroups,
+					wantJWTAccessToken:    true,
+				},
+			},
+		},
 		{
 			name:          "request is valid and tokens are issued for dynamic client which has a custom ID token lifetime",
 			kubeResources: addFullyCapableDynamicClientWithCustomIDTokenLifetimeAndSecretToKubeResources(4242),
@@ -522,6 +602,23 @@ func TestTokenEndpointAuthcodeExchange(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "dynamic client has a session policy with a maximum session age which the session has already reached",
+			kubeResources: addFullyCapableDynamicClientWithSessionPolicyAndSecretToKubeResources(&supervisorconfigv1alpha1.OIDCClientSessionPolicy{
+				MaxSessionAgeSeconds: ptr.To[int32](3600), // the session's auth time is goodAuthTime, which was long ago
+			}),
+			authcodeExchange: authcodeExchangeInputs{
+				modifyAuthRequest: func(r *http.Request) {
+					addDynamicClientIDToFormPostBody(r)
+					r.Form.Set("scope", "openid pinniped:request-audience username groups")
+				},
+				modifyTokenRequest: modifyAuthcodeTokenRequestWithDynamicClientAuth,
+				want: tokenEndpointResponseExpectedValues{
+					wantStatus:            http.StatusBadRequest,
+					wantErrorResponseBody: fositeSessionMaxAgeReachedErrorBody,
+				},
+			},
+		},
 		{
 			name:          "request is valid and tokens are issued for dynamic client with additional claims",
 			kubeResources: addFullyCapableDynamicClientAndSecretToKubeResources,
@@ -1063,6 +1160,200 @@ func TestTokenEndpointAuthcodeExchange(t *testing.T) {
 	}
 }
 
+func TestTokenEndpointAuthcodeExchangeWithClientAuthenticationMethods(t *testing.T) {
+	clientKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	clientJWKS, err := json.Marshal(jose.JSONWebKeySet{Keys: []jose.JSONWebKey{{Key: clientKey.Public(), KeyID: "client-key", Use: "sig"}}})
+	require.NoError(t, err)
+
+	clientCA, err := certauthority.New("client-ca", time.Hour)
+	require.NoError(t, err)
+	clientCert, err := clientCA.IssueClientCert("some-client", nil, nil, time.Hour)
+	require.NoError(t, err)
+	clientLeafCert, err := x509.ParseCertificate(clientCert.Certificate[0])
+	require.NoError(t, err)
+
+	addDynamicClientWithAuthMethodToKubeResources := func(modifySpec func(spec *supervisorconfigv1alpha1.OIDCClientSpec)) func(t *testing.T, supervisorClient *supervisorfake.Clientset, kubeClient *kubefake.Clientset) {
+		return func(t *testing.T, supervisorClient *supervisorfake.Clientset, kubeClient *kubefake.Clientset) {
+			oidcClient, _ := testutil.FullyCapableOIDCClientAndStorageSecret(t,
+				"some-namespace",
+				dynamicClientID,
+				dynamicClientUID,
+				goodRedirectURI,
+				nil, // no custom ID token lifetime
+				[]string{testutil.HashedPassword1AtGoMinCost},
+				oidcclientvalidator.Validate,
+			)
+			modifySpec(&oidcClient.Spec)
+			// Note that no client secret storage Secret is needed for these client authentication methods.
+			require.NoError(t, supervisorClient.Tracker().Add(oidcClient))
+		}
+	}
+
+	withPrivateKeyJWT := addDynamicClientWithAuthMethodToKubeResources(func(spec *supervisorconfigv1alpha1.OIDCClientSpec) {
+		spec.TokenEndpointAuthMethod = supervisorconfigv1alpha1.TokenEndpointAuthMethodPrivateKeyJWT
+		spec.PrivateKeyJWT = &supervisorconfigv1alpha1.OIDCClientPrivateKeyJWT{JWKS: string(clientJWKS), SigningAlgorithm: "ES256"}
+	})
+
+	withTLSClientAuth := addDynamicClientWithAuthMethodToKubeResources(func(spec *supervisorconfigv1alpha1.OIDCClientSpec) {
+		spec.TokenEndpointAuthMethod = supervisorconfigv1alpha1.TokenEndpointAuthMethodTLSClientAuth
+		spec.TLSClientAuth = &supervisorconfigv1alpha1.OIDCClientTLSClientAuth{
+			CertificateAuthorityData: base64.StdEncoding.EncodeToString(clientCA.Bundle()),
+			SubjectDN:                "CN=some-client",
+		}
+	})
+
+	makeClientAssertion := func(t *testing.T, audience string) string {
+		t.Helper()
+
+		signer, err := jose.NewSigner(
+			jose.SigningKey{Algorithm: jose.ES256, Key: clientKey},
+			(&jose.SignerOptions{}).WithType("JWT").WithHeader("kid", "client-key"),
+		)
+		require.NoError(t, err)
+
+		assertion, err := josejwt.Signed(signer).Claims(josejwt.Claims{
+			Issuer:   dynamicClientID,
+			Subject:  dynamicClientID,
+			Audience: josejwt.Audience{audience},
+			ID:       uuid.NewString(),
+			Expiry:   josejwt.NewNumericDate(time.Now().Add(time.Minute)),
+			IssuedAt: josejwt.NewNumericDate(time.Now()),
+		}).Serialize()
+		require.NoError(t, err)
+
+		return assertion
+	}
+
+	modifyTokenRequestWithClientAssertion := func(audience string) func(r *http.Request, authCode string) {
+		return func(r *http.Request, authCode string) {
+			b := happyAuthcodeRequestBody(authCode).WithClientID(dynamicClientID)
+			b = b.with("client_assertion_type", "urn:ietf:params:oauth:client-assertion-type:jwt-bearer")
+			b = b.with("client_assertion", makeClientAssertion(t, audience))
+			r.Body = b.ReadCloser()
+		}
+	}
+
+	happyDynamicClientWant := tokenEndpointResponseExpectedValues{
+		wantStatus:            http.StatusOK,
+		wantClientID:          dynamicClientID,
+		wantSuccessBodyFields: []string{"id_token", "access_token", "token_type", "scope", "expires_in"},
+		wantRequestedScopes:   []string{"openid", "username", "groups"},
+		wantGrantedScopes:     []string{"openid", "username", "groups"},
+		wantUsername:          goodUsername,
+		wantGroups:            goodGroups,
+	}
+
+	tests := []struct {
+		name             string
+		authcodeExchange authcodeExchangeInputs
+		kubeResources    func(t *testing.T, supervisorClient *supervisorfake.Clientset, kubeClient *kubefake.Clientset)
+	}{
+		{
+			name:          "dynamic client authenticates using private_key_jwt",
+			kubeResources: withPrivateKeyJWT,
+			authcodeExchange: authcodeExchangeInputs{
+				modifyAuthRequest: func(r *http.Request) {
+					addDynamicClientIDToFormPostBody(r)
+					r.Form.Set("scope", "openid username groups")
+				},
+				modifyTokenRequest: modifyTokenRequestWithClientAssertion(goodIssuer + "/oauth2/token"),
+				want: func() tokenEndpointResponseExpectedValues {
+					want := happyDynamicClientWant
+					want.wantClientAssertionsStored = 1
+					return want
+				}(),
+			},
+		},
+		{
+			name:          "dynamic client which uses private_key_jwt sends a client assertion with the wrong audience",
+			kubeResources: withPrivateKeyJWT,
+			authcodeExchange: authcodeExchangeInputs{
+				modifyAuthRequest: func(r *http.Request) {
+					addDynamicClientIDToFormPostBody(r)
+					r.Form.Set("scope", "openid username groups")
+				},
+				modifyTokenRequest: modifyTokenRequestWithClientAssertion("https://some-other-server.example.com/token"),
+				want: tokenEndpointResponseExpectedValues{
+					wantStatus: http.StatusUnauthorized,
+					wantErrorResponseBody: here.Doc(`
+						{
+							"error":             "invalid_client",
+							"error_description": "Client authentication failed (e.g., unknown client, no client authentication included, or unsupported authentication method). Claim 'audience' from 'client_assertion' must match the authorization server's token endpoint 'https://some-issuer.com/oauth2/token'."
+						}
+					`),
+				},
+			},
+		},
+		{
+			name:          "dynamic client which uses private_key_jwt tries to use basic auth instead",
+			kubeResources: withPrivateKeyJWT,
+			authcodeExchange: authcodeExchangeInputs{
+				modifyAuthRequest: func(r *http.Request) {
+					addDynamicClientIDToFormPostBody(r)
+					r.Form.Set("scope", "openid username groups")
+				},
+				modifyTokenRequest: modifyAuthcodeTokenRequestWithDynamicClientAuth,
+				want: tokenEndpointResponseExpectedValues{
+					wantStatus: http.StatusUnauthorized,
+					wantErrorResponseBody: here.Doc(`
+						{
+							"error":             "invalid_client",
+							"error_description": "Client authentication failed (e.g., unknown client, no client authentication included, or unsupported authentication method). The OAuth 2.0 Client supports client authentication method 'private_key_jwt', but method 'client_secret_basic' was requested. You must configure the OAuth 2.0 client's 'token_endpoint_auth_method' value to accept 'client_secret_basic'."
+						}
+					`),
+				},
+			},
+		},
+		{
+			name:          "dynamic client authenticates using tls_client_auth",
+			kubeResources: withTLSClientAuth,
+			authcodeExchange: authcodeExchangeInputs{
+				modifyAuthRequest: func(r *http.Request) {
+					addDynamicClientIDToFormPostBody(r)
+					r.Form.Set("scope", "openid username groups")
+				},
+				modifyTokenRequest: func(r *http.Request, authCode string) {
+					r.Body = happyAuthcodeRequestBody(authCode).WithClientID(dynamicClientID).ReadCloser()
+					r.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{clientLeafCert}}
+				},
+				want: happyDynamicClientWant,
+			},
+		},
+		{
+			name:          "dynamic client which uses tls_client_auth does not present a client certificate",
+			kubeResources: withTLSClientAuth,
+			authcodeExchange: authcodeExchangeInputs{
+				modifyAuthRequest: func(r *http.Request) {
+					addDynamicClientIDToFormPostBody(r)
+					r.Form.Set("scope", "openid username groups")
+				},
+				modifyTokenRequest: func(r *http.Request, authCode string) {
+					r.Body = happyAuthcodeRequestBody(authCode).WithClientID(dynamicClientID).ReadCloser()
+				},
+				want: tokenEndpointResponseExpectedValues{
+					wantStatus: http.StatusUnauthorized,
+					wantErrorResponseBody: here.Doc(`
+						{
+							"error":             "invalid_client",
+							"error_description": "Client authentication failed (e.g., unknown client, no client authentication included, or unsupported authentication method). The OAuth 2.0 Client could not be authenticated using its TLS client certificate."
+						}
+					`),
+				},
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			// Authcode exchange doesn't use the upstream provider cache, so just pass an empty cache.
+			exchangeAuthcodeForTokens(t,
+				test.authcodeExchange, testidplister.NewUpstreamIDPListerBuilder().BuildFederationDomainIdentityProvidersListerFinder(), test.kubeResources)
+		})
+	}
+}
+
 func TestTokenEndpointWhenAuthcodeIsUsedTwice(t *testing.T) {
 	tests := []struct {
 		name             string
@@ -1305,6 +1596,23 @@ func TestTokenEndpointTokenExchange(t *testing.T) { // tests for grant_type "urn
 			requestedAudience: "some-workload-cluster",
 			wantStatus:        http.StatusOK,
 		},
+		{
+			name:          "happy path with dynamic client which uses JWT access tokens",
+			kubeResources: addFullyCapableDynamicClientWithJWTAccessTokensAndSecretToKubeResources,
+			authcodeExchange: authcodeExchangeInputs{
+				modifyAuthRequest:  doValidAuthCodeExchangeUsingDynamicClient().modifyAuthRequest,
+				modifyTokenRequest: doValidAuthCodeExchangeUsingDynamicClient().modifyTokenRequest,
+				want:               withWantJWTAccessToken(doValidAuthCodeExchangeUsingDynamicClient().want),
+			},
+			modifyRequestParams: func(t *testing.T, params url.Values) {
+				params.Del("client_id") // client auth for dynamic clients must be in basic auth header
+			},
+			modifyRequestHeaders: func(r *http.Request) {
+				r.SetBasicAuth(dynamicClientID, testutil.PlaintextPassword1)
+			},
+			requestedAudience: "some-workload-cluster",
+			wantStatus:        http.StatusOK,
+		},
 		{
 			name:          "happy path with dynamic client which has a custom ID token lifetime configuration (which does not apply to ID tokens from token exchanges)",
 			kubeResources: addFullyCapableDynamicClientWithCustomIDTokenLifetimeAndSecretToKubeResources(4242),
@@ -2014,6 +2322,383 @@ func TestTokenEndpointTokenExchange(t *testing.T) { // tests for grant_type "urn
 	}
 }
 
+func TestTokenEndpointClientCredentials(t *testing.T) { // tests for grant_type "client_credentials"
+	const (
+		clientUsername               = "ci-bot"
+		transformationUsernamePrefix = "username_prefix:"
+		transformationGroupsPrefix   = "groups_prefix:"
+	)
+	clientGroups := []string{"ci", "deployers"}
+
+	prefixUsernameAndGroupsPipeline := transformtestutil.NewPrefixingPipeline(t, transformationUsernamePrefix, transformationGroupsPrefix)
+	rejectAuthPipeline := transformtestutil.NewRejectAllAuthPipeline(t)
+
+	addClientCredentialsClientToKubeResources := func(modifySpec func(spec *supervisorconfigv1alpha1.OIDCClientSpec)) func(t *testing.T, supervisorClient *supervisorfake.Clientset, kubeClient *kubefake.Clientset) {
+		return func(t *testing.T, supervisorClient *supervisorfake.Clientset, kubeClient *kubefake.Clientset) {
+			oidcClient, secret := testutil.FullyCapableOIDCClientAndStorageSecret(t,
+				"some-namespace",
+				dynamicClientID,
+				dynamicClientUID,
+				goodRedirectURI,
+				nil, // no custom ID token lifetime
+				[]string{testutil.HashedPassword1AtGoMinCost},
+				oidcclientvalidator.Validate,
+			)
+			oidcClient.Spec.AllowedGrantTypes = []supervisorconfigv1alpha1.GrantType{"client_credentials", "urn:ietf:params:oauth:grant-type:token-exchange"}
+			oidcClient.Spec.AllowedScopes = []supervisorconfigv1alpha1.Scope{"openid", "pinniped:request-audience", "username", "groups"}
+			oidcClient.Spec.ClientCredentials = &supervisorconfigv1alpha1.OIDCClientClientCredentials{
+				Username: clientUsername,
+				Groups:   clientGroups,
+			}
+			if modifySpec != nil {
+				modifySpec(&oidcClient.Spec)
+			}
+			require.NoError(t, supervisorClient.Tracker().Add(oidcClient))
+			require.NoError(t, kubeClient.Tracker().Add(secret))
+		}
+	}
+
+	idpsWithDefaultIDP := func(transforms *idtransform.TransformationPipeline) *testidplister.UpstreamIDPListerBuilder {
+		return testidplister.NewUpstreamIDPListerBuilder().WithOIDC(
+			oidctestutil.NewTestUpstreamOIDCIdentityProviderBuilder().
+				WithName("some-oidc-idp").
+				WithResourceUID("some-oidc-resource-uid").
+				WithDisplayNameForFederationDomain("some-oidc-idp-display-name").
+				WithTransformsForFederationDomain(transforms).
+				Build(),
+		).WithDefaultIDPDisplayName("some-oidc-idp-display-name")
+	}
+
+	tests := []struct {
+		name          string
+		idps          *testidplister.UpstreamIDPListerBuilder
+		kubeResources func(t *testing.T, supervisorClient *supervisorfake.Clientset, kubeClient *kubefake.Clientset)
+		modifyRequest func(r *http.Request)
+		scope         string
+
+		wantStatus                          int
+		wantErrorResponseBody               string
+		wantGrantedScopes                   []string
+		wantUsername                        string
+		wantGroups                          []string
+		wantTokenExchangeErrorDescContains  string
+		wantClientCredentialsAuditLogs      func(sessionID string) []testutil.WantedAuditLog
+		wantAccessTokenSessionCustomSession *psession.CustomSessionData
+	}{
+		{
+			name:              "happy path using the default identity provider's transformations",
+			idps:              idpsWithDefaultIDP(prefixUsernameAndGroupsPipeline),
+			kubeResources:     addClientCredentialsClientToKubeResources(nil),
+			scope:             "openid pinniped:request-audience username groups",
+			wantStatus:        http.StatusOK,
+			wantGrantedScopes: []string{"openid", "pinniped:request-audience", "username", "groups"},
+			wantUsername:      transformationUsernamePrefix + clientUsername,
+			wantGroups:        testutil.AddPrefixToEach(transformationGroupsPrefix, clientGroups),
+			wantAccessTokenSessionCustomSession: &psession.CustomSessionData{
+				Username:         transformationUsernamePrefix + clientUsername,
+				UpstreamUsername: clientUsername,
+				UpstreamGroups:   clientGroups,
+			},
+			wantClientCredentialsAuditLogs: func(sessionID string) []testutil.WantedAuditLog {
+				return []testutil.WantedAuditLog{
+					testutil.WantAuditLog("HTTP Request Parameters", map[string]any{
+						"params": map[string]any{
+							"grant_type": "client_credentials",
+							"scope":      "openid pinniped:request-audience username groups",
+						},
+					}),
+					testutil.WantAuditLog("HTTP Request Basic Auth", map[string]any{
+						"clientID": dynamicClientID,
+					}),
+					testutil.WantAuditLog("Session Found", map[string]any{"sessionID": sessionID}),
+					testutil.WantAuditLog("Identity From Client Credentials", map[string]any{
+						"personalInfo": map[string]any{
+							"clientUsername": clientUsername,
+							"clientGroups":   []any{"ci", "deployers"},
+						},
+						"clientID":                 dynamicClientID,
+						"transformsIDPDisplayName": "some-oidc-idp-display-name",
+					}),
+					testutil.WantAuditLog("Session Started", map[string]any{
+						"sessionID": sessionID,
+						"personalInfo": map[string]any{
+							"username": transformationUsernamePrefix + clientUsername,
+							"groups":   []any{transformationGroupsPrefix + "ci", transformationGroupsPrefix + "deployers"},
+							"subject":  dynamicClientID,
+						},
+					}),
+				}
+			},
+		},
+		{
+			name: "happy path using the client's configured identity provider",
+			idps: testidplister.NewUpstreamIDPListerBuilder().WithOIDC(
+				oidctestutil.NewTestUpstreamOIDCIdentityProviderBuilder().
+					WithName("some-oidc-idp").
+					WithResourceUID("some-oidc-resource-uid").
+					WithDisplayNameForFederationDomain("some-oidc-idp-display-name").
+					WithTransformsForFederationDomain(prefixUsernameAndGroupsPipeline).
+					Build(),
+			), // no default IDP
+			kubeResources: addClientCredentialsClientToKubeResources(func(spec *supervisorconfigv1alpha1.OIDCClientSpec) {
+				spec.ClientCredentials.IdentityProvider = "some-oidc-idp-display-name"
+			}),
+			scope:             "openid pinniped:request-audience username groups",
+			wantStatus:        http.StatusOK,
+			wantGrantedScopes: []string{"openid", "pinniped:request-audience", "username", "groups"},
+			wantUsername:      transformationUsernamePrefix + clientUsername,
+			wantGroups:        testutil.AddPrefixToEach(transformationGroupsPrefix, clientGroups),
+		},
+		{
+			name:              "happy path without any groups",
+			idps:              idpsWithDefaultIDP(transformtestutil.NewPipeline(t, nil)),
+			kubeResources:     addClientCredentialsClientToKubeResources(func(spec *supervisorconfigv1alpha1.OIDCClientSpec) { spec.ClientCredentials.Groups = nil }),
+			scope:             "openid pinniped:request-audience username groups",
+			wantStatus:        http.StatusOK,
+			wantGrantedScopes: []string{"openid", "pinniped:request-audience", "username", "groups"},
+			wantUsername:      clientUsername,
+			wantGroups:        []string{},
+		},
+		{
+			name:                               "happy path without the username scope results in an access token which cannot be used for token exchange",
+			idps:                               idpsWithDefaultIDP(prefixUsernameAndGroupsPipeline),
+			kubeResources:                      addClientCredentialsClientToKubeResources(nil),
+			scope:                              "openid pinniped:request-audience groups",
+			wantStatus:                         http.StatusOK,
+			wantGrantedScopes:                  []string{"openid", "pinniped:request-audience", "groups"},
+			wantGroups:                         testutil.AddPrefixToEach(transformationGroupsPrefix, clientGroups),
+			wantTokenExchangeErrorDescContains: "No username found in session. Ensure that the 'username' scope was requested and granted at the authorization endpoint.",
+		},
+		{
+			name:          "the identity transformations reject the client's identity",
+			idps:          idpsWithDefaultIDP(rejectAuthPipeline),
+			kubeResources: addClientCredentialsClientToKubeResources(nil),
+			scope:         "openid pinniped:request-audience username groups",
+			wantStatus:    http.StatusForbidden,
+			wantErrorResponseBody: here.Doc(`
+				{
+					"error":             "access_denied",
+					"error_description": "The resource owner or authorization server denied the request. Reason: configured identity policy rejected this authentication: authentication was rejected by a configured policy."
+				}
+			`),
+		},
+		{
+			name: "the client's configured identity provider is not found",
+			idps: idpsWithDefaultIDP(prefixUsernameAndGroupsPipeline),
+			kubeResources: addClientCredentialsClientToKubeResources(func(spec *supervisorconfigv1alpha1.OIDCClientSpec) {
+				spec.ClientCredentials.IdentityProvider = "some-other-idp-display-name"
+			}),
+			scope:      "openid pinniped:request-audience username groups",
+			wantStatus: http.StatusForbidden,
+			wantErrorResponseBody: here.Doc(`
+				{
+					"error":             "access_denied",
+					"error_description": "The resource owner or authorization server denied the request. The identity provider configured for the client credentials grant was not found."
+				}
+			`),
+		},
+		{
+			name:          "the federation domain has no default identity provider and the client did not configure one",
+			idps:          testidplister.NewUpstreamIDPListerBuilder(),
+			kubeResources: addClientCredentialsClientToKubeResources(nil),
+			scope:         "openid pinniped:request-audience username groups",
+			wantStatus:    http.StatusForbidden,
+			wantErrorResponseBody: here.Doc(`
+				{
+					"error":             "access_denied",
+					"error_description": "The resource owner or authorization server denied the request. The identity provider configured for the client credentials grant was not found."
+				}
+			`),
+		},
+		{
+			name:          "the client is not allowed to use the client_credentials grant",
+			idps:          idpsWithDefaultIDP(prefixUsernameAndGroupsPipeline),
+			kubeResources: addFullyCapableDynamicClientAndSecretToKubeResources,
+			scope:         "openid pinniped:request-audience username groups",
+			wantStatus:    http.StatusBadRequest,
+			wantErrorResponseBody: here.Doc(`
+				{
+					"error":             "unauthorized_client",
+					"error_description": "The client is not authorized to request a token using this method. The OAuth 2.0 Client is not allowed to use authorization grant 'client_credentials'."
+				}
+			`),
+		},
+		{
+			name:          "the client requests a scope which it is not allowed to request",
+			idps:          idpsWithDefaultIDP(prefixUsernameAndGroupsPipeline),
+			kubeResources: addClientCredentialsClientToKubeResources(nil),
+			scope:         "openid offline_access",
+			wantStatus:    http.StatusBadRequest,
+			wantErrorResponseBody: here.Doc(`
+				{
+					"error":             "invalid_scope",
+					"error_description": "The requested scope is invalid, unknown, or malformed. The OAuth 2.0 Client is not allowed to request scope 'offline_access'."
+				}
+			`),
+		},
+		{
+			name:          "the client uses the wrong client secret",
+			idps:          idpsWithDefaultIDP(prefixUsernameAndGroupsPipeline),
+			kubeResources: addClientCredentialsClientToKubeResources(nil),
+			modifyRequest: func(r *http.Request) {
+				r.SetBasicAuth(dynamicClientID, "wrong-secret")
+			},
+			scope:      "openid pinniped:request-audience username groups",
+			wantStatus: http.StatusUnauthorized,
+			wantErrorResponseBody: here.Doc(`
+				{
+					"error":             "invalid_client",
+					"error_description": "Client authentication failed (e.g., unknown client, no client authentication included, or unsupported authentication method)."
+				}
+			`),
+		},
+		{
+			name: "the public pinniped-cli client is not allowed to use the client_credentials grant",
+			idps: idpsWithDefaultIDP(prefixUsernameAndGroupsPipeline),
+			modifyRequest: func(r *http.Request) {
+				r.Header.Del("Authorization")
+				r.Body = body(url.Values{
+					"grant_type": {"client_credentials"},
+					"client_id":  {pinnipedCLIClientID},
+					"scope":      {"openid"},
+				}).ReadCloser()
+			},
+			wantStatus: http.StatusBadRequest,
+			wantErrorResponseBody: here.Doc(`
+				{
+					"error":             "invalid_grant",
+					"error_description": "The provided authorization grant (e.g., authorization code, resource owner credentials) or refresh token is invalid, expired, revoked, does not match the redirection URI used in the authorization request, or was issued to another client. The OAuth 2.0 Client is marked as public and is thus not allowed to use authorization grant 'client_credentials'."
+				}
+			`),
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			kubeClient := kubefake.NewClientset()
+			supervisorClient := supervisorfake.NewSimpleClientset()
+			secrets := kubeClient.CoreV1().Secrets("some-namespace")
+			oidcClientsClient := supervisorClient.ConfigV1alpha1().OIDCClients("some-namespace")
+			if test.kubeResources != nil {
+				test.kubeResources(t, supervisorClient, kubeClient)
+			}
+
+			// Use the same timeouts configuration as the production code will use.
+			timeoutsConfiguration := oidc.DefaultOIDCTimeoutsConfiguration()
+			// Use lower minimum required bcrypt cost than we would use in production to keep unit the tests fast.
+			oauthStore := storage.NewKubeStorage(secrets, oidcClientsClient, timeoutsConfiguration, bcrypt.MinCost)
+			jwtSigningKey, jwkProvider := generateJWTSigningKeyAndJWKSProvider(t, goodIssuer)
+			oauthHelper := oidc.FositeOauth2Helper(oauthStore, goodIssuer, hmacSecretFunc, jwkProvider, timeoutsConfiguration)
+			auditLogger, actualAuditLog := plog.TestAuditLogger(t)
+
+			subject := NewHandler(
+				test.idps.BuildFederationDomainIdentityProvidersListerFinder(),
+				oauthHelper,
+				timeoutsConfiguration.OverrideDefaultAccessTokenLifespan,
+				timeoutsConfiguration.OverrideDefaultRefreshTokenLifespan,
+				timeoutsConfiguration.OverrideDefaultIDTokenLifespan,
+				auditLogger,
+			)
+
+			req := httptest.NewRequestWithContext(t.Context(), "POST", "/path/shouldn't/matter", body(url.Values{
+				"grant_type": {"client_credentials"},
+				"scope":      {test.scope},
+			}).ReadCloser())
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			req.SetBasicAuth(dynamicClientID, testutil.PlaintextPassword1)
+			if test.modifyRequest != nil {
+				test.modifyRequest(req)
+			}
+			req, _ = auditid.NewRequestWithAuditID(req, func() string { return "fake-client-credentials-audit-id" })
+			rsp := httptest.NewRecorder()
+
+			approxRequestTime := time.Now()
+			subject.ServeHTTP(rsp, req)
+			t.Logf("response: %#v", rsp)
+			t.Logf("response body: %q", rsp.Body.String())
+
+			require.Equal(t, test.wantStatus, rsp.Code)
+			testutil.RequireEqualContentType(t, rsp.Header().Get("Content-Type"), "application/json")
+
+			if test.wantErrorResponseBody != "" {
+				require.JSONEq(t, test.wantErrorResponseBody, rsp.Body.String())
+				// No session should have been stored.
+				testutil.RequireNumberOfSecretsMatchingLabelSelector(t, secrets, labels.Set{crud.SecretLabelKey: accesstoken.TypeLabelValue}, 0)
+				return
+			}
+
+			var parsedResponseBody map[string]any
+			require.NoError(t, json.Unmarshal(rsp.Body.Bytes(), &parsedResponseBody))
+			// There is no ID token or refresh token for the client_credentials grant.
+			require.ElementsMatch(t, []string{"access_token", "token_type", "expires_in", "scope"}, getMapKeys(parsedResponseBody))
+			require.Equal(t, "bearer", parsedResponseBody["token_type"])
+			require.Equal(t, strings.Join(test.wantGrantedScopes, " "), parsedResponseBody["scope"])
+			require.InDelta(t, accessTokenExpirationSeconds, parsedResponseBody["expires_in"], 2)
+
+			// Only the access token session should be stored, excluding any OIDCClient's storage secret.
+			testutil.RequireNumberOfSecretsMatchingLabelSelector(t, secrets, labels.Set{crud.SecretLabelKey: accesstoken.TypeLabelValue}, 1)
+			testutil.RequireNumberOfSecretsExcludingLabelSelector(t, secrets, labels.Set{crud.SecretLabelKey: oidcclientsecretstorage.TypeLabelValue}, 1)
+
+			accessToken := parsedResponseBody["access_token"].(string)
+			storedRequest, err := oauthStore.GetAccessTokenSession(context.Background(), getFositeDataSignature(t, accessToken), nil)
+			require.NoError(t, err)
+			storedSession := storedRequest.GetSession().(*psession.PinnipedSession)
+			require.Equal(t, dynamicClientID, storedSession.Fosite.Claims.Subject)
+			testutil.RequireTimeInDelta(t, approxRequestTime.UTC(), storedSession.Fosite.Claims.AuthTime, timeComparisonFudge)
+			testutil.RequireTimeInDelta(t, approxRequestTime.UTC().Add(accessTokenExpirationSeconds*time.Second),
+				storedSession.Fosite.ExpiresAt[fosite.AccessToken], timeComparisonFudge)
+			require.Equal(t, dynamicClientID, storedSession.Fosite.Claims.Extra["azp"])
+			require.Equal(t, storedRequest.GetID(), storedSession.Fosite.Claims.Extra["sid"])
+			require.Empty(t, storedSession.Custom.ProviderName)
+			require.Empty(t, storedSession.Custom.ProviderUID)
+			require.Empty(t, storedSession.Custom.ProviderType)
+			if test.wantAccessTokenSessionCustomSession != nil {
+				require.Equal(t, test.wantAccessTokenSessionCustomSession, storedSession.Custom)
+			}
+
+			if test.wantClientCredentialsAuditLogs != nil {
+				wantAuditLogs := test.wantClientCredentialsAuditLogs(storedRequest.GetID())
+				testutil.WantAuditIDOnEveryAuditLog(wantAuditLogs, "fake-client-credentials-audit-id")
+				testutil.CompareAuditLogs(t, wantAuditLogs, actualAuditLog.String())
+			}
+
+			// The access token can be used for a token exchange to get a cluster-scoped ID token.
+			tokenExchangeRequest := happyTokenExchangeRequest("some-workload-cluster", accessToken)
+			tokenExchangeRequest.Form.Del("client_id")
+			req = httptest.NewRequestWithContext(t.Context(), "POST", "/path/shouldn't/matter", body(tokenExchangeRequest.Form).ReadCloser())
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			req.SetBasicAuth(dynamicClientID, testutil.PlaintextPassword1)
+			rsp = httptest.NewRecorder()
+			subject.ServeHTTP(rsp, req)
+			t.Logf("token exchange response body: %q", rsp.Body.String())
+
+			require.NoError(t, json.Unmarshal(rsp.Body.Bytes(), &parsedResponseBody))
+			if test.wantTokenExchangeErrorDescContains != "" {
+				require.Equal(t, http.StatusForbidden, rsp.Code)
+				require.Contains(t, parsedResponseBody["error_description"], test.wantTokenExchangeErrorDescContains)
+				return
+			}
+			require.Equal(t, http.StatusOK, rsp.Code)
+
+			idToken := parsedResponseBody["access_token"].(string)
+			parsedJWT, err := jose.ParseSigned(idToken, []jose.SignatureAlgorithm{jose.ES256})
+			require.NoError(t, err)
+			var tokenClaims map[string]any
+			require.NoError(t, json.Unmarshal(parsedJWT.UnsafePayloadWithoutVerification(), &tokenClaims))
+			_, err = parsedJWT.Verify(jwtSigningKey.Public())
+			require.NoError(t, err)
+
+			require.Equal(t, dynamicClientID, tokenClaims["sub"])
+			require.Equal(t, dynamicClientID, tokenClaims["azp"])
+			require.Equal(t, goodIssuer, tokenClaims["iss"])
+			require.Equal(t, []any{"some-workload-cluster"}, tokenClaims["aud"])
+			require.Equal(t, test.wantUsername, tokenClaims["username"])
+			require.Equal(t, toSliceOfInterface(test.wantGroups), tokenClaims["groups"])
+		})
+	}
+}
+
 type refreshRequestInputs struct {
 	modifyTokenRequest func(tokenRequest *http.Request, refreshToken string, accessToken string)
 	want               tokenEndpointResponseExpectedValues
@@ -2129,6 +2814,13 @@ func TestRefreshGrant(t *testing.T) {
 		return sessionData
 	}
 
+	upstreamOIDCCustomSessionDataWithAuthenticationContext := func(refreshToken string, acr string, amr []string) *psession.CustomSessionData {
+		sessionData := upstreamOIDCCustomSessionDataWithNewRefreshToken(refreshToken)
+		sessionData.OIDC.UpstreamACR = acr
+		sessionData.OIDC.UpstreamAMR = amr
+		return sessionData
+	}
+
 	upstreamOIDCCustomSessionDataWithNewRefreshTokenWithUsername := func(newRefreshToken string, downstreamUsername string) *psession.CustomSessionData {
 		sessionData := initialUpstreamOIDCRefreshTokenCustomSessionDataWithUsername(downstreamUsername)
 		sessionData.OIDC.UpstreamRefreshToken = newRefreshToken
@@ -2375,6 +3067,10 @@ func TestRefreshGrant(t *testing.T) {
 
 	prefixUsernameAndGroupsPipeline := transformtestutil.NewPrefixingPipeline(t, transformationUsernamePrefix, transformationGroupsPrefix)
 	rejectAuthPipeline := transformtestutil.NewRejectAllAuthPipeline(t)
+	requireACRPolicy := transformtestutil.NewUpstreamAuthenticationPolicy(t, &celtransformer.UpstreamAuthenticationPolicy{
+		ACRValues:                     []string{"phrh"},
+		RejectedAuthenticationMessage: "please log in using MFA",
+	})
 
 	tests := []struct {
 		name                      string
@@ -2630,6 +3326,153 @@ func TestRefreshGrant(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "refresh grant with OIDC upstream with an authentication policy which rejects the refreshed ID token",
+			idps: testidplister.NewUpstreamIDPListerBuilder().WithOIDC(
+				upstreamOIDCIdentityProviderBuilder().WithValidatedAndMergedWithUserInfoTokens(&oidctypes.Token{
+					IDToken: &oidctypes.IDToken{
+						Claims: map[string]any{
+							"sub": goodUpstreamSubject,
+							"acr": "pwd",
+						},
+					},
+				}).WithRefreshedTokens(refreshedUpstreamTokensWithIDAndRefreshTokens()).
+					WithAuthenticationPolicyForFederationDomain(requireACRPolicy).Build()),
+			authcodeExchange: happyAuthcodeExchangeInputsForOIDCUpstream,
+			refreshRequest: refreshRequestInputs{
+				want: tokenEndpointResponseExpectedValues{
+					wantOIDCUpstreamRefreshCall:       happyOIDCUpstreamRefreshCall(),
+					wantUpstreamOIDCValidateTokenCall: happyUpstreamValidateTokenCall(refreshedUpstreamTokensWithIDAndRefreshTokens(), true),
+					wantStatus:                        http.StatusUnauthorized,
+					wantErrorResponseBody: here.Doc(`
+						{
+							"error":             "error",
+							"error_description": "Error during upstream refresh. Upstream refresh rejected by authentication policy: please log in using MFA."
+						}
+					`),
+					wantAuditLogs: func(sessionID string, idToken string) []testutil.WantedAuditLog {
+						return []testutil.WantedAuditLog{
+							testutil.WantAuditLog("HTTP Request Parameters", map[string]any{
+								"params": map[string]any{
+									"client_id":     "pinniped-cli",
+									"grant_type":    "refresh_token",
+									"refresh_token": "redacted",
+									"scope":         "openid",
+								},
+							}),
+							testutil.WantAuditLog("Session Found", map[string]any{"sessionID": sessionID}),
+							testutil.WantAuditLog("Authentication Rejected By Upstream Policy", map[string]any{
+								"sessionID":               sessionID,
+								"upstreamIDPDisplayName":  "some-oidc-idp",
+								"upstreamIDPType":         "oidc",
+								"upstreamIDPResourceName": "some-oidc-idp",
+								"upstreamIDPResourceUID":  "oidc-resource-uid",
+								"reason":                  `"acr" claim value "pwd" is not one of the required values [phrh]`,
+							}),
+						}
+					},
+				},
+			},
+		},
+		{
+			name: "refresh grant with OIDC upstream with an authentication policy when the upstream refresh does not return a new ID token uses the stored acr claim",
+			idps: testidplister.NewUpstreamIDPListerBuilder().WithOIDC(
+				upstreamOIDCIdentityProviderBuilder().WithValidatedAndMergedWithUserInfoTokens(&oidctypes.Token{
+					IDToken: &oidctypes.IDToken{
+						Claims: map[string]any{},
+					},
+				}).WithRefreshedTokens(refreshedUpstreamTokensWithRefreshTokenWithoutIDToken()).
+					WithAuthenticationPolicyForFederationDomain(requireACRPolicy).Build()),
+			authcodeExchange: authcodeExchangeInputs{
+				modifyAuthRequest: func(r *http.Request) { r.Form.Set("scope", "openid offline_access username groups") },
+				customSessionData: upstreamOIDCCustomSessionDataWithAuthenticationContext(oidcUpstreamInitialRefreshToken, "phrh", []string{"pwd", "otp"}),
+				want: happyAuthcodeExchangeTokenResponseForOpenIDAndOfflineAccess(
+					upstreamOIDCCustomSessionDataWithAuthenticationContext(oidcUpstreamInitialRefreshToken, "phrh", []string{"pwd", "otp"}),
+				),
+			},
+			refreshRequest: refreshRequestInputs{
+				want: tokenEndpointResponseExpectedValues{
+					wantStatus:                        http.StatusOK,
+					wantClientID:                      pinnipedCLIClientID,
+					wantSuccessBodyFields:             []string{"refresh_token", "access_token", "id_token", "token_type", "expires_in", "scope"},
+					wantRequestedScopes:               []string{"openid", "offline_access", "username", "groups"},
+					wantGrantedScopes:                 []string{"openid", "offline_access", "username", "groups"},
+					wantUsername:                      goodUsername,
+					wantGroups:                        goodGroups,
+					wantOIDCUpstreamRefreshCall:       happyOIDCUpstreamRefreshCall(),
+					wantUpstreamOIDCValidateTokenCall: happyUpstreamValidateTokenCall(refreshedUpstreamTokensWithRefreshTokenWithoutIDToken(), false),
+					wantCustomSessionDataStored:       upstreamOIDCCustomSessionDataWithAuthenticationContext(oidcUpstreamRefreshedRefreshToken, "phrh", []string{"pwd", "otp"}),
+				},
+			},
+		},
+		{
+			name: "refresh grant with OIDC upstream with an authentication policy which rejects the stored acr claim when the upstream refresh does not return a new ID token",
+			idps: testidplister.NewUpstreamIDPListerBuilder().WithOIDC(
+				upstreamOIDCIdentityProviderBuilder().WithValidatedAndMergedWithUserInfoTokens(&oidctypes.Token{
+					IDToken: &oidctypes.IDToken{
+						Claims: map[string]any{
+							"sub": goodUpstreamSubject,
+							"acr": "phrh", // from the userinfo endpoint, which should not be trusted to describe how the user authenticated
+						},
+					},
+				}).WithRefreshedTokens(refreshedUpstreamTokensWithRefreshTokenWithoutIDToken()).
+					WithAuthenticationPolicyForFederationDomain(requireACRPolicy).Build()),
+			authcodeExchange: authcodeExchangeInputs{
+				modifyAuthRequest: func(r *http.Request) { r.Form.Set("scope", "openid offline_access username groups") },
+				customSessionData: upstreamOIDCCustomSessionDataWithAuthenticationContext(oidcUpstreamInitialRefreshToken, "pwd", nil),
+				want: happyAuthcodeExchangeTokenResponseForOpenIDAndOfflineAccess(
+					upstreamOIDCCustomSessionDataWithAuthenticationContext(oidcUpstreamInitialRefreshToken, "pwd", nil),
+				),
+			},
+			refreshRequest: refreshRequestInputs{
+				want: tokenEndpointResponseExpectedValues{
+					wantOIDCUpstreamRefreshCall:       happyOIDCUpstreamRefreshCall(),
+					wantUpstreamOIDCValidateTokenCall: happyUpstreamValidateTokenCall(refreshedUpstreamTokensWithRefreshTokenWithoutIDToken(), false),
+					wantStatus:                        http.StatusUnauthorized,
+					wantErrorResponseBody: here.Doc(`
+						{
+							"error":             "error",
+							"error_description": "Error during upstream refresh. Upstream refresh rejected by authentication policy: please log in using MFA."
+						}
+					`),
+				},
+			},
+		},
+		{
+			name: "refresh grant with OIDC upstream with an authentication policy stores the acr and amr claims of the refreshed ID token",
+			idps: testidplister.NewUpstreamIDPListerBuilder().WithOIDC(
+				upstreamOIDCIdentityProviderBuilder().WithValidatedAndMergedWithUserInfoTokens(&oidctypes.Token{
+					IDToken: &oidctypes.IDToken{
+						Claims: map[string]any{
+							"sub": goodUpstreamSubject,
+							"acr": "phrh",
+							"amr": []any{"hwk"},
+						},
+					},
+				}).WithRefreshedTokens(refreshedUpstreamTokensWithIDAndRefreshTokens()).
+					WithAuthenticationPolicyForFederationDomain(requireACRPolicy).Build()),
+			authcodeExchange: authcodeExchangeInputs{
+				modifyAuthRequest: func(r *http.Request) { r.Form.Set("scope", "openid offline_access username groups") },
+				customSessionData: upstreamOIDCCustomSessionDataWithAuthenticationContext(oidcUpstreamInitialRefreshToken, "pwd", nil),
+				want: happyAuthcodeExchangeTokenResponseForOpenIDAndOfflineAccess(
+					upstreamOIDCCustomSessionDataWithAuthenticationContext(oidcUpstreamInitialRefreshToken, "pwd", nil),
+				),
+			},
+			refreshRequest: refreshRequestInputs{
+				want: tokenEndpointResponseExpectedValues{
+					wantStatus:                        http.StatusOK,
+					wantClientID:                      pinnipedCLIClientID,
+					wantSuccessBodyFields:             []string{"refresh_token", "access_token", "id_token", "token_type", "expires_in", "scope"},
+					wantRequestedScopes:               []string{"openid", "offline_access", "username", "groups"},
+					wantGrantedScopes:                 []string{"openid", "offline_access", "username", "groups"},
+					wantUsername:                      goodUsername,
+					wantGroups:                        goodGroups,
+					wantOIDCUpstreamRefreshCall:       happyOIDCUpstreamRefreshCall(),
+					wantUpstreamOIDCValidateTokenCall: happyUpstreamValidateTokenCall(refreshedUpstreamTokensWithIDAndRefreshTokens(), true),
+					wantCustomSessionDataStored:       upstreamOIDCCustomSessionDataWithAuthenticationContext(oidcUpstreamRefreshedRefreshToken, "phrh", []string{"hwk"}),
+				},
+			},
+		},
 		{
 			name: "happy path refresh grant with openid scope granted (id token returned) and additionalClaims",
 			idps: testidplister.NewUpstreamIDPListerBuilder().WithOIDC(
@@ -2739,6 +3582,38 @@ func TestRefreshGrant(t *testing.T) {
 				)),
 			},
 		},
+		{
+			name: "happy path refresh grant with openid scope granted (id token returned) using dynamic client which uses JWT access tokens",
+			idps: testidplister.NewUpstreamIDPListerBuilder().WithOIDC(
+				upstreamOIDCIdentityProviderBuilder().WithValidatedAndMergedWithUserInfoTokens(&oidctypes.Token{
+					IDToken: &oidctypes.IDToken{
+						Claims: map[string]any{
+							"sub": goodUpstreamSubject,
+						},
+					},
+				}).WithRefreshedTokens(refreshedUpstreamTokensWithIDAndRefreshTokens()).Build()),
+			kubeResources: addFullyCapableDynamicClientWithJWTAccessTokensAndSecretToKubeResources,
+			authcodeExchange: authcodeExchangeInputs{
+				customSessionData: initialUpstreamOIDCRefreshTokenCustomSessionData(),
+				modifyAuthRequest: func(r *http.Request) {
+					addDynamicClientIDToFormPostBody(r)
+					r.Form.Set("scope", "openid offline_access username groups")
+				},
+				modifyTokenRequest: modifyAuthcodeTokenRequestWithDynamicClientAuth,
+				want: withWantJWTAccessToken(
+					withWantDynamicClientID(happyAuthcodeExchangeTokenResponseForOpenIDAndOfflineAccess(initialUpstreamOIDCRefreshTokenCustomSessionData())),
+				),
+			},
+			refreshRequest: refreshRequestInputs{
+				modifyTokenRequest: modifyRefreshTokenRequestWithDynamicClientAuth,
+				want: withWantJWTAccessToken(
+					withWantDynamicClientID(happyRefreshTokenResponseForOpenIDAndOfflineAccess(
+						upstreamOIDCCustomSessionDataWithNewRefreshToken(oidcUpstreamRefreshedRefreshToken),
+						refreshedUpstreamTokensWithIDAndRefreshTokens(),
+					)),
+				),
+			},
+		},
 		{
 			name: "happy path refresh grant with openid scope granted (id token returned) using dynamic client which has custom ID token lifetime configured",
 			idps: testidplister.NewUpstreamIDPListerBuilder().WithOIDC(
@@ -5249,6 +6124,7 @@ func exchangeAuthcodeForTokens(
 		idps,
 		oauthHelper,
 		timeoutsConfiguration.OverrideDefaultAccessTokenLifespan,
+		timeoutsConfiguration.OverrideDefaultRefreshTokenLifespan,
 		timeoutsConfiguration.OverrideDefaultIDTokenLifespan,
 		auditLogger,
 	)
@@ -5344,6 +6220,9 @@ func requireTokenEndpointBehavior(
 
 		requireInvalidAuthCodeStorage(t, authCode, oauthStore, secrets, requestTime)
 		requireValidAccessTokenStorage(t, parsedResponseBody, oauthStore, test.wantClientID, test.wantRequestedScopes, test.wantGrantedScopes, test.wantUsername, test.wantGroups, test.wantCustomSessionDataStored, test.wantAdditionalClaims, secrets, requestTime)
+		if test.wantJWTAccessToken {
+			requireValidJWTAccessToken(t, parsedResponseBody, jwtSigningKey, test.wantClientID, test.wantGrantedScopes, test.wantUsername, test.wantGroups)
+		}
 		requireInvalidPKCEStorage(t, authCode, oauthStore)
 		requireDeletedOIDCStorage(t, authCode, oauthStore) // The OIDC storage was deleted during the authcode exchange.
 
@@ -5363,8 +6242,9 @@ func requireTokenEndpointBehavior(
 		testutil.RequireNumberOfSecretsMatchingLabelSelector(t, secrets, labels.Set{crud.SecretLabelKey: pkce.TypeLabelValue}, 0)
 		testutil.RequireNumberOfSecretsMatchingLabelSelector(t, secrets, labels.Set{crud.SecretLabelKey: refreshtoken.TypeLabelValue}, expectedNumberOfRefreshTokenSessionsStored)
 		testutil.RequireNumberOfSecretsMatchingLabelSelector(t, secrets, labels.Set{crud.SecretLabelKey: openidconnect.TypeLabelValue}, 0)
+		testutil.RequireNumberOfSecretsMatchingLabelSelector(t, secrets, labels.Set{crud.SecretLabelKey: clientassertion.TypeLabelValue}, test.wantClientAssertionsStored)
 		// Assert the number of all secrets, excluding any OIDCClient's storage secret, since those are not related to session storage.
-		testutil.RequireNumberOfSecretsExcludingLabelSelector(t, secrets, labels.Set{crud.SecretLabelKey: oidcclientsecretstorage.TypeLabelValue}, 2+expectedNumberOfRefreshTokenSessionsStored)
+		testutil.RequireNumberOfSecretsExcludingLabelSelector(t, secrets, labels.Set{crud.SecretLabelKey: oidcclientsecretstorage.TypeLabelValue}, 2+expectedNumberOfRefreshTokenSessionsStored+test.wantClientAssertionsStored)
 	} else {
 		require.NotNil(t, test.wantErrorResponseBody, "problem with test table setup: wanted failure but did not specify failure response body")
 
@@ -5463,6 +6343,18 @@ func getFositeDataSignature(t *testing.T, data string) string {
 	return split[1]
 }
 
+// getAccessTokenSignature returns the signature of the provided access token, which could be an opaque access token
+// or a JWT access token. For the other kinds of tokens, it returns the same value as getFositeDataSignature.
+func getAccessTokenSignature(t *testing.T, accessToken string) string {
+	split := strings.Split(accessToken, ".")
+	if len(split) != 3 {
+		return getFositeDataSignature(t, accessToken)
+	}
+	// JWT access tokens are stored using a hash of their signature.
+	hash := sha256.Sum256([]byte(split[2]))
+	return base64.RawURLEncoding.EncodeToString(hash[:])
+}
+
 type MakeJwksSigningKeyAndProviderFunc func(t *testing.T, issuer string) (*ecdsa.PrivateKey, jwks.DynamicJWKSProvider)
 
 func makeHappyOauthHelper(
@@ -5571,9 +6463,12 @@ func generateJWTSigningKeyAndJWKSProvider(t *testing.T, issuer string) (*ecdsa.P
 
 	jwksProvider := jwks.NewDynamicJWKSProvider()
 	jwksProvider.SetIssuerToJWKSMap(
-		nil, // public JWKS unused
+		map[string]*jose.JSONWebKeySet{
+			// The public JWKS is used to validate JWT access tokens.
+			issuer: {Keys: []jose.JSONWebKey{{Key: &key.PublicKey, KeyID: "some-key-id"}}},
+		},
 		map[string]*jose.JSONWebKey{
-			issuer: {Key: key},
+			issuer: {Key: key, KeyID: "some-key-id"},
 		},
 	)
 
@@ -5665,11 +6560,14 @@ func requireValidAccessTokenStorage(
 	accessTokenString, ok := accessToken.(string)
 	require.Truef(t, ok, "wanted access_token to be a string, but got %T", accessToken)
 	require.NotEmpty(t, accessTokenString)
-	storedRequest, err := storage.GetAccessTokenSession(context.Background(), getFositeDataSignature(t, accessTokenString), nil)
+	storedRequest, err := storage.GetAccessTokenSession(context.Background(), getAccessTokenSignature(t, accessTokenString), nil)
 	require.NoError(t, err)
 
-	// Access tokens should start with the custom prefix "pin_at_" to make them identifiable as access tokens when seen by a user out of context.
-	require.True(t, strings.HasPrefix(accessTokenString, "pin_at_"), "token %q did not have expected prefix 'pin_at_'", accessTokenString)
+	// Opaque access tokens should start with the custom prefix "pin_at_" to make them identifiable as access tokens when seen by a user out of context.
+	// JWT access tokens are checked by requireValidJWTAccessToken.
+	if strings.Count(accessTokenString, ".") != 2 {
+		require.True(t, strings.HasPrefix(accessTokenString, "pin_at_"), "token %q did not have expected prefix 'pin_at_'", accessTokenString)
+	}
 
 	// Make sure the other body fields are valid.
 	tokenType, ok := body["token_type"]
@@ -5721,10 +6619,52 @@ func requireInvalidAccessTokenStorage(
 	require.True(t, ok)
 	accessTokenString, ok := accessToken.(string)
 	require.Truef(t, ok, "wanted access_token to be a string, but got %T", accessToken)
-	_, err := storage.GetAccessTokenSession(context.Background(), getFositeDataSignature(t, accessTokenString), nil)
+	_, err := storage.GetAccessTokenSession(context.Background(), getAccessTokenSignature(t, accessTokenString), nil)
 	require.True(t, errors.Is(err, fosite.ErrNotFound))
 }
 
+// requireValidJWTAccessToken checks the signature and claims of an RFC 9068 JWT access token.
+func requireValidJWTAccessToken(
+	t *testing.T,
+	body map[string]any,
+	jwtSigningKey *ecdsa.PrivateKey,
+	wantClientID string,
+	wantGrantedScopes []string,
+	wantUsername string,
+	wantGroups []string,
+) {
+	t.Helper()
+
+	accessTokenString, ok := body["access_token"].(string)
+	require.True(t, ok)
+
+	token, err := josejwt.ParseSigned(accessTokenString, []jose.SignatureAlgorithm{jose.ES256})
+	require.NoError(t, err)
+	require.Len(t, token.Headers, 1)
+	require.Equal(t, "at+jwt", token.Headers[0].ExtraHeaders[jose.HeaderType])
+
+	var claims map[string]any
+	require.NoError(t, token.Claims(jwtSigningKey.Public(), &claims))
+	require.Equal(t, goodIssuer, claims["iss"])
+	require.Equal(t, goodSubject, claims["sub"])
+	require.Equal(t, dynamicClientJWTAccessTokenAudience, claims["aud"])
+	require.Equal(t, wantClientID, claims["client_id"])
+	require.Equal(t, strings.Join(wantGrantedScopes, " "), claims["scope"])
+	require.NotEmpty(t, claims["jti"])
+	testutil.RequireTimeInDelta(t, time.Now().Add(accessTokenExpirationSeconds*time.Second), time.Unix(int64(claims["exp"].(float64)), 0), timeComparisonFudge)
+
+	if wantUsername != "" {
+		require.Equal(t, wantUsername, claims["username"])
+	} else {
+		require.NotContains(t, claims, "username")
+	}
+	if len(wantGroups) > 0 {
+		require.Equal(t, toSliceOfInterface(wantGroups), claims["groups"])
+	} else {
+		require.NotContains(t, claims, "groups")
+	}
+}
+
 func requireInvalidPKCEStorage(
 	t *testing.T,
 	code string,
@@ -5857,7 +6797,7 @@ func requireValidStoredRequest(
 
 func requireGarbageCollectTimeInDelta(t *testing.T, tokenString string, typeLabel string, secrets v1.SecretInterface, wantExpirationTime time.Time, deltaTime time.Duration) {
 	t.Helper()
-	signature := getFositeDataSignature(t, tokenString)
+	signature := getAccessTokenSignature(t, tokenString)
 	signatureBytes, err := base64.RawURLEncoding.DecodeString(signature)
 	require.NoError(t, err)
 	// lower case base32 encoding insures that our secret name is valid per ValidateSecretName in k/k
@@ -6083,6 +7023,7 @@ func TestParamsSafeToLog(t *testing.T) {
 	wantParams := []string{
 		"actor_token_type",
 		"audience",
+		"client_assertion_type",
 		"client_id",
 		"grant_type",
 		"redirect_uri",
@@ -1,34 +1,47 @@
-// Copyright 2020-2024 the Pinniped contributors. All Rights Reserved.
+// Copyright 2020-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package endpointsmanager
 
 import (
+	"crypto/rand"
 	"net/http"
 	"strings"
 	"sync"
 
+	"golang.org/x/crypto/bcrypt"
 	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/utils/clock"
 
 	"go.pinniped.dev/generated/latest/client/supervisor/clientset/versioned/typed/config/v1alpha1"
 	"go.pinniped.dev/internal/auditid"
 	"go.pinniped.dev/internal/config/supervisor"
+	"go.pinniped.dev/internal/crud"
 	"go.pinniped.dev/internal/federationdomain/csrftoken"
 	"go.pinniped.dev/internal/federationdomain/dynamiccodec"
 	"go.pinniped.dev/internal/federationdomain/endpoints/auth"
 	"go.pinniped.dev/internal/federationdomain/endpoints/callback"
 	"go.pinniped.dev/internal/federationdomain/endpoints/chooseidp"
+	"go.pinniped.dev/internal/federationdomain/endpoints/device"
 	"go.pinniped.dev/internal/federationdomain/endpoints/discovery"
+	"go.pinniped.dev/internal/federationdomain/endpoints/endsession"
 	"go.pinniped.dev/internal/federationdomain/endpoints/idpdiscovery"
+	"go.pinniped.dev/internal/federationdomain/endpoints/introspection"
 	"go.pinniped.dev/internal/federationdomain/endpoints/jwks"
 	"go.pinniped.dev/internal/federationdomain/endpoints/login"
+	"go.pinniped.dev/internal/federationdomain/endpoints/pushedauthorization"
+	"go.pinniped.dev/internal/federationdomain/endpoints/registration"
+	"go.pinniped.dev/internal/federationdomain/endpoints/revocation"
 	"go.pinniped.dev/internal/federationdomain/endpoints/token"
 	"go.pinniped.dev/internal/federationdomain/federationdomainproviders"
+	"go.pinniped.dev/internal/federationdomain/i18n"
 	"go.pinniped.dev/internal/federationdomain/idplister"
 	"go.pinniped.dev/internal/federationdomain/oidc"
 	"go.pinniped.dev/internal/federationdomain/oidcclientvalidator"
 	"go.pinniped.dev/internal/federationdomain/requestlogger"
+	"go.pinniped.dev/internal/federationdomain/sessionrevocation"
 	"go.pinniped.dev/internal/federationdomain/storage"
+	"go.pinniped.dev/internal/metrics"
 	"go.pinniped.dev/internal/plog"
 	"go.pinniped.dev/internal/secret"
 	"go.pinniped.dev/pkg/oidcclient/nonce"
@@ -47,23 +60,34 @@ type Manager struct {
 	upstreamIDPs        idplister.UpstreamIdentityProvidersLister // in-memory cache of upstream IDPs
 	secretCache         *secret.Cache                             // in-memory cache of cryptographic material
 	secretsClient       corev1client.SecretInterface
+	sessionStorage      crud.SecretsBackend
 	oidcClientsClient   v1alpha1.OIDCClientInterface
+	callerAuthorizer    registration.CallerAuthorizer
+	deviceLimiter       *device.VerificationLimiter // shared by all providers, so it survives when they are reloaded
 	auditLogger         plog.AuditLogger
+
+	clientCertificatesRequested bool
 }
 
 // NewManager returns an empty Manager.
 // nextHandler will be invoked for any requests that could not be handled by this manager's providers.
 // dynamicJWKSProvider will be used as an in-memory cache for per-issuer JWKS data.
 // upstreamIDPs will be used as an in-memory cache of currently configured upstream IDPs.
+// sessionStorage will be used to store downstream session data, while secretsClient will be used for OIDCClient secrets.
+// callerAuthorizer will be used by dynamic client registration endpoints which allow Kubernetes authentication.
+// clientCertificatesRequested should be true when the HTTPS listener requests TLS client certificates.
 func NewManager(
 	nextHandler http.Handler,
 	dynamicJWKSProvider jwks.DynamicJWKSProvider,
 	upstreamIDPs idplister.UpstreamIdentityProvidersLister,
 	secretCache *secret.Cache,
 	secretsClient corev1client.SecretInterface,
+	sessionStorage crud.SecretsBackend,
 	oidcClientsClient v1alpha1.OIDCClientInterface,
+	callerAuthorizer registration.CallerAuthorizer,
 	auditLogger plog.AuditLogger,
 	auditInternalPathsCfg supervisor.AuditInternalPaths,
+	clientCertificatesRequested bool,
 ) *Manager {
 	m := &Manager{
 		providerHandlers:    make(map[string]http.Handler),
@@ -71,8 +95,13 @@ func NewManager(
 		upstreamIDPs:        upstreamIDPs,
 		secretCache:         secretCache,
 		secretsClient:       secretsClient,
+		sessionStorage:      sessionStorage,
 		oidcClientsClient:   oidcClientsClient,
+		callerAuthorizer:    callerAuthorizer,
+		deviceLimiter:       device.NewVerificationLimiter(clock.RealClock{}),
 		auditLogger:         auditLogger,
+
+		clientCertificatesRequested: clientCertificatesRequested,
 	}
 	// nextHandler is the next handler in the chain, called when this manager didn't know how to handle a request
 	m.buildHandlerChain(nextHandler, auditInternalPathsCfg)
@@ -110,25 +139,47 @@ func (m *Manager) SetFederationDomains(federationDomains ...*federationdomainpro
 
 		tokenHMACKeyGetter := wrapGetter(incomingFederationDomain.Issuer(), m.secretCache.GetTokenHMACKey)
 
-		timeoutsConfiguration := oidc.DefaultOIDCTimeoutsConfiguration()
+		timeoutsConfiguration := oidc.OIDCTimeoutsConfiguration(incomingFederationDomain.SessionPolicy())
+
+		pages := incomingFederationDomain.BrandedPages()
+
+		// The handlers which render web pages for end users show them in the language requested by the browser.
+		localized := func(handler http.Handler) http.Handler {
+			return i18n.WithNegotiatedLanguage(handler, incomingFederationDomain.DefaultLanguage())
+		}
 
 		// Use NullStorage for the authorize endpoint because we do not actually want to store anything until
 		// the upstream callback endpoint is called later.
-		oauthHelperWithNullStorage := oidc.FositeOauth2Helper(
-			storage.NewNullStorage(m.secretsClient, m.oidcClientsClient, oidcclientvalidator.DefaultMinBcryptCost),
+		oauthHelperWithNullStorage := oidc.FositeOauth2HelperWithFormPostTemplate(
+			storage.NewNullStorageWithSessionStorage(m.secretsClient, m.sessionStorage, m.oidcClientsClient, incomingFederationDomain.Name(), oidcclientvalidator.DefaultMinBcryptCost),
 			issuerURL,
 			tokenHMACKeyGetter,
 			nil,
 			timeoutsConfiguration,
+			pages.FormPost,
 		)
 
 		// For all the other endpoints, make another oauth helper with exactly the same settings except use real storage.
-		oauthHelperWithKubeStorage := oidc.FositeOauth2Helper(
-			storage.NewKubeStorage(m.secretsClient, m.oidcClientsClient, timeoutsConfiguration, oidcclientvalidator.DefaultMinBcryptCost),
+		kubeStorage := storage.NewKubeStorageWithSessionStorage(m.secretsClient, m.sessionStorage, m.oidcClientsClient, incomingFederationDomain.Name(), timeoutsConfiguration, oidcclientvalidator.DefaultMinBcryptCost)
+		oauthHelperWithKubeStorage := oidc.FositeOauth2HelperWithFormPostTemplate(
+			kubeStorage,
+			issuerURL,
+			tokenHMACKeyGetter,
+			m.dynamicJWKSProvider,
+			timeoutsConfiguration,
+			pages.FormPost,
+		)
+
+		// For the revocation endpoint, make another oauth helper whose storage revokes the whole downstream session,
+		// including the upstream tokens held by the session, when any of its tokens are revoked.
+		sessionRevoker := sessionrevocation.New(m.sessionStorage, m.upstreamIDPs, m.auditLogger)
+		oauthHelperWithRevokingKubeStorage := oidc.FositeOauth2HelperWithFormPostTemplate(
+			sessionRevoker.WrapStorage(kubeStorage),
 			issuerURL,
 			tokenHMACKeyGetter,
 			m.dynamicJWKSProvider,
 			timeoutsConfiguration,
+			pages.FormPost,
 		)
 
 		upstreamStateEncoder := dynamiccodec.New(
@@ -139,13 +190,32 @@ func (m *Manager) SetFederationDomains(federationDomains ...*federationdomainpro
 
 		idpLister := federationdomainproviders.NewFederationDomainIdentityProvidersListerFinder(incomingFederationDomain, m.upstreamIDPs)
 
-		m.providerHandlers[(issuerHostWithPath + oidc.WellKnownEndpointPath)] = discovery.NewHandler(issuerURL)
+		registrationEndpoint := ""
+		if clientRegistrationPolicy := incomingFederationDomain.ClientRegistrationPolicy(); clientRegistrationPolicy != nil {
+			registrationEndpoint = issuerURL + oidc.RegistrationEndpointPath
+			registrationHandler := metrics.InstrumentFederationDomainEndpoint(metrics.EndpointRegistration, registration.NewHandler(
+				issuerURL,
+				clientRegistrationPolicy,
+				m.oidcClientsClient,
+				m.secretsClient,
+				m.callerAuthorizer,
+				rand.Reader,
+				oidcclientvalidator.DefaultMinBcryptCost,
+				bcrypt.GenerateFromPassword,
+				m.auditLogger,
+			))
+			// The registration endpoint, and the client configuration endpoints of the clients which it registered.
+			m.providerHandlers[(issuerHostWithPath + oidc.RegistrationEndpointPath)] = registrationHandler
+			m.providerHandlers[(issuerHostWithPath + oidc.RegistrationEndpointPath + "/")] = registrationHandler
+		}
+
+		m.providerHandlers[(issuerHostWithPath + oidc.WellKnownEndpointPath)] = discovery.NewHandler(issuerURL, incomingFederationDomain.IDTokenSigningAlgorithm(), m.dynamicJWKSProvider, registrationEndpoint, m.clientCertificatesRequested)
 
 		m.providerHandlers[(issuerHostWithPath + oidc.JWKSEndpointPath)] = jwks.NewHandler(issuerURL, m.dynamicJWKSProvider)
 
 		m.providerHandlers[(issuerHostWithPath + oidc.PinnipedIDPsPathV1Alpha1)] = idpdiscovery.NewHandler(idpLister)
 
-		m.providerHandlers[(issuerHostWithPath + oidc.AuthorizationEndpointPath)] = auth.NewHandler(
+		m.providerHandlers[(issuerHostWithPath + oidc.AuthorizationEndpointPath)] = metrics.InstrumentFederationDomainEndpoint(metrics.EndpointAuthorize, localized(auth.NewHandler(
 			issuerURL,
 			idpLister,
 			oauthHelperWithNullStorage,
@@ -155,38 +225,90 @@ func (m *Manager) SetFederationDomains(federationDomains ...*federationdomainpro
 			nonce.Generate,
 			upstreamStateEncoder,
 			csrfCookieEncoder,
+			pages.FormPost,
 			m.auditLogger,
-		)
+		)))
 
-		m.providerHandlers[(issuerHostWithPath + oidc.CallbackEndpointPath)] = callback.NewHandler(
+		m.providerHandlers[(issuerHostWithPath + oidc.CallbackEndpointPath)] = metrics.InstrumentFederationDomainEndpoint(metrics.EndpointCallback, localized(callback.NewHandler(
 			idpLister,
 			oauthHelperWithKubeStorage,
+			kubeStorage,
 			upstreamStateEncoder,
 			csrfCookieEncoder,
 			issuerURL+oidc.CallbackEndpointPath,
+			pages.FormPost,
 			m.auditLogger,
-		)
+		)))
 
-		m.providerHandlers[(issuerHostWithPath + oidc.ChooseIDPEndpointPath)] = chooseidp.NewHandler(
+		m.providerHandlers[(issuerHostWithPath + oidc.ChooseIDPEndpointPath)] = metrics.InstrumentFederationDomainEndpoint(metrics.EndpointChooseIDP, localized(chooseidp.NewHandler(
 			issuerURL+oidc.AuthorizationEndpointPath,
 			idpLister,
-		)
+			pages.ChooseIDP,
+		)))
 
-		m.providerHandlers[(issuerHostWithPath + oidc.TokenEndpointPath)] = token.NewHandler(
+		m.providerHandlers[(issuerHostWithPath + oidc.TokenEndpointPath)] = metrics.InstrumentFederationDomainEndpoint(metrics.EndpointToken, token.NewHandler(
 			idpLister,
 			oauthHelperWithKubeStorage,
 			timeoutsConfiguration.OverrideDefaultAccessTokenLifespan,
+			timeoutsConfiguration.OverrideDefaultRefreshTokenLifespan,
 			timeoutsConfiguration.OverrideDefaultIDTokenLifespan,
 			m.auditLogger,
-		)
+		))
 
-		m.providerHandlers[(issuerHostWithPath + oidc.PinnipedLoginPath)] = login.NewHandler(
+		m.providerHandlers[(issuerHostWithPath + oidc.PinnipedLoginPath)] = metrics.InstrumentFederationDomainEndpoint(metrics.EndpointLogin, localized(login.NewHandler(
 			upstreamStateEncoder,
 			csrfCookieEncoder,
-			login.NewGetHandler(incomingFederationDomain.IssuerPath()+oidc.PinnipedLoginPath),
-			login.NewPostHandler(issuerURL, idpLister, oauthHelperWithKubeStorage, m.auditLogger),
+			login.NewGetHandler(incomingFederationDomain.IssuerPath()+oidc.PinnipedLoginPath, pages.Login),
+			login.NewPostHandler(issuerURL, idpLister, oauthHelperWithKubeStorage, kubeStorage, m.auditLogger),
+			pages.Login,
+			pages.FormPost,
 			m.auditLogger,
-		)
+		)))
+
+		m.providerHandlers[(issuerHostWithPath + oidc.DeviceAuthorizationEndpointPath)] = metrics.InstrumentFederationDomainEndpoint(metrics.EndpointDeviceAuthorization, device.NewAuthorizationHandler(
+			idpLister,
+			oauthHelperWithKubeStorage,
+			m.auditLogger,
+		))
+
+		m.providerHandlers[(issuerHostWithPath + oidc.DeviceVerificationEndpointPath)] = metrics.InstrumentFederationDomainEndpoint(metrics.EndpointDeviceVerification, device.NewVerificationHandler(
+			issuerURL,
+			incomingFederationDomain.IssuerPath()+oidc.DeviceVerificationEndpointPath,
+			idpLister,
+			kubeStorage,
+			oidc.DeviceStrategy(issuerURL, tokenHMACKeyGetter, timeoutsConfiguration),
+			csrftoken.Generate,
+			pkce.Generate,
+			nonce.Generate,
+			upstreamStateEncoder,
+			csrfCookieEncoder,
+			m.deviceLimiter,
+			m.auditLogger,
+		))
+
+		m.providerHandlers[(issuerHostWithPath + oidc.RevocationEndpointPath)] = metrics.InstrumentFederationDomainEndpoint(metrics.EndpointRevocation, revocation.NewHandler(
+			oauthHelperWithRevokingKubeStorage,
+			m.auditLogger,
+		))
+
+		m.providerHandlers[(issuerHostWithPath + oidc.EndSessionEndpointPath)] = metrics.InstrumentFederationDomainEndpoint(metrics.EndpointEndSession, endsession.NewHandler(
+			issuerURL,
+			m.dynamicJWKSProvider,
+			kubeStorage,
+			sessionRevoker,
+			m.auditLogger,
+		))
+
+		m.providerHandlers[(issuerHostWithPath + oidc.IntrospectionEndpointPath)] = metrics.InstrumentFederationDomainEndpoint(metrics.EndpointIntrospection, introspection.NewHandler(
+			issuerURL,
+			oauthHelperWithKubeStorage,
+			m.auditLogger,
+		))
+
+		m.providerHandlers[(issuerHostWithPath + oidc.PushedAuthorizationRequestEndpointPath)] = metrics.InstrumentFederationDomainEndpoint(metrics.EndpointPushedAuthorization, pushedauthorization.NewHandler(
+			oauthHelperWithKubeStorage,
+			m.auditLogger,
+		))
 
 		plog.Debug("oidc provider manager added or updated issuer", "issuer", issuerURL)
 	}
@@ -220,7 +342,17 @@ func (m *Manager) findHandler(req *http.Request) http.Handler {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
-	return m.providerHandlers[strings.ToLower(req.Host)+"/"+req.URL.Path]
+	key := strings.ToLower(req.Host) + "/" + req.URL.Path
+	if handler, ok := m.providerHandlers[key]; ok {
+		return handler
+	}
+
+	// The client configuration endpoints of dynamically registered clients have the client ID as their last path segment.
+	if i := strings.LastIndex(key, "/"); i >= 0 && strings.HasSuffix(key[:i+1], oidc.RegistrationEndpointPath+"/") {
+		return m.providerHandlers[key[:i+1]]
+	}
+
+	return nil
 }
 
 func wrapGetter(issuer string, getter func(string) []byte) func() []byte {
@@ -9,22 +9,28 @@ import (
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/sets"
 
+	"go.pinniped.dev/internal/celtransformer"
 	"go.pinniped.dev/internal/federationdomain/idplister"
 	"go.pinniped.dev/internal/federationdomain/resolvedprovider"
 	"go.pinniped.dev/internal/federationdomain/resolvedprovider/resolvedgithub"
+	"go.pinniped.dev/internal/federationdomain/resolvedprovider/resolvedgitlab"
 	"go.pinniped.dev/internal/federationdomain/resolvedprovider/resolvedldap"
+	"go.pinniped.dev/internal/federationdomain/resolvedprovider/resolvedoauth2"
 	"go.pinniped.dev/internal/federationdomain/resolvedprovider/resolvedoidc"
+	"go.pinniped.dev/internal/federationdomain/resolvedprovider/resolvedsaml"
 	"go.pinniped.dev/internal/idtransform"
 	"go.pinniped.dev/internal/psession"
 )
 
 // FederationDomainIdentityProvider represents an identity provider as configured in a FederationDomain's spec.
-// All the fields are required and must be non-zero values. Note that this might be a reference to an IDP
-// which is not currently loaded into the cache of available IDPs, e.g. due to the IDP's CR having validation errors.
+// All the fields are required and must be non-zero values, except for AuthenticationPolicy which is optional.
+// Note that this might be a reference to an IDP which is not currently loaded into the cache of available IDPs,
+// e.g. due to the IDP's CR having validation errors.
 type FederationDomainIdentityProvider struct {
-	DisplayName string
-	UID         types.UID
-	Transforms  *idtransform.TransformationPipeline
+	DisplayName          string
+	UID                  types.UID
+	Transforms           *idtransform.TransformationPipeline
+	AuthenticationPolicy *celtransformer.CompiledUpstreamAuthenticationPolicy
 }
 
 type FederationDomainIdentityProvidersFinderI interface {
@@ -146,6 +152,9 @@ func (u *FederationDomainIdentityProvidersListerFinder) GetIdentityProviders() [
 	cachedLDAPProviders := u.wrappedLister.GetLDAPIdentityProviders()
 	cachedADProviders := u.wrappedLister.GetActiveDirectoryIdentityProviders()
 	cachedGitHubProviders := u.wrappedLister.GetGitHubIdentityProviders()
+	cachedSAMLProviders := u.wrappedLister.GetSAMLIdentityProviders()
+	cachedGitLabProviders := u.wrappedLister.GetGitLabIdentityProviders()
+	cachedOAuth2Providers := u.wrappedLister.GetOAuth2IdentityProviders()
 	providers := []resolvedprovider.FederationDomainResolvedIdentityProvider{}
 	// Every configured identityProvider on the FederationDomain uses an objetRef to an underlying IDP CR that might
 	// be available as a provider in the wrapped cache. For each configured identityProvider/displayName...
@@ -155,10 +164,11 @@ func (u *FederationDomainIdentityProvidersListerFinder) GetIdentityProviders() [
 			if idp.UID == p.GetResourceUID() {
 				// Found it, so append it to the result.
 				providers = append(providers, &resolvedoidc.FederationDomainResolvedOIDCIdentityProvider{
-					DisplayName:         idp.DisplayName,
-					Provider:            p,
-					SessionProviderType: psession.ProviderTypeOIDC,
-					Transforms:          idp.Transforms,
+					DisplayName:          idp.DisplayName,
+					Provider:             p,
+					SessionProviderType:  psession.ProviderTypeOIDC,
+					Transforms:           idp.Transforms,
+					AuthenticationPolicy: idp.AuthenticationPolicy,
 				})
 			}
 		}
@@ -196,6 +206,36 @@ func (u *FederationDomainIdentityProvidersListerFinder) GetIdentityProviders() [
 				})
 			}
 		}
+		for _, p := range cachedSAMLProviders {
+			if idp.UID == p.GetResourceUID() {
+				providers = append(providers, &resolvedsaml.FederationDomainResolvedSAMLIdentityProvider{
+					DisplayName:         idp.DisplayName,
+					Provider:            p,
+					SessionProviderType: psession.ProviderTypeSAML,
+					Transforms:          idp.Transforms,
+				})
+			}
+		}
+		for _, p := range cachedGitLabProviders {
+			if idp.UID == p.GetResourceUID() {
+				providers = append(providers, &resolvedgitlab.FederationDomainResolvedGitLabIdentityProvider{
+					DisplayName:         idp.DisplayName,
+					Provider:            p,
+					SessionProviderType: psession.ProviderTypeGitLab,
+					Transforms:          idp.Transforms,
+				})
+			}
+		}
+		for _, p := range cachedOAuth2Providers {
+			if idp.UID == p.GetResourceUID() {
+				providers = append(providers, &resolvedoauth2.FederationDomainResolvedOAuth2IdentityProvider{
+					DisplayName:         idp.DisplayName,
+					Provider:            p,
+					SessionProviderType: psession.ProviderTypeOAuth2,
+					Transforms:          idp.Transforms,
+				})
+			}
+		}
 	}
 	return providers
 }
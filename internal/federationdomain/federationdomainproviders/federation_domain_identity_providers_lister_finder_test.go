@@ -1,4 +1,4 @@
-// Copyright 2023-2024 the Pinniped contributors. All Rights Reserved.
+// Copyright 2023-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package federationdomainproviders
@@ -11,8 +11,11 @@ import (
 	"go.pinniped.dev/internal/federationdomain/idplister"
 	"go.pinniped.dev/internal/federationdomain/resolvedprovider"
 	"go.pinniped.dev/internal/federationdomain/resolvedprovider/resolvedgithub"
+	"go.pinniped.dev/internal/federationdomain/resolvedprovider/resolvedgitlab"
 	"go.pinniped.dev/internal/federationdomain/resolvedprovider/resolvedldap"
+	"go.pinniped.dev/internal/federationdomain/resolvedprovider/resolvedoauth2"
 	"go.pinniped.dev/internal/federationdomain/resolvedprovider/resolvedoidc"
+	"go.pinniped.dev/internal/federationdomain/resolvedprovider/resolvedsaml"
 	"go.pinniped.dev/internal/testutil/oidctestutil"
 	"go.pinniped.dev/internal/testutil/testidplister"
 )
@@ -61,6 +64,18 @@ func TestFederationDomainIdentityProvidersListerFinder(t *testing.T) {
 		WithName("my-github-idp1").
 		WithResourceUID("my-github-uid-idp1").
 		Build()
+	mySAMLIDP1 := oidctestutil.NewTestUpstreamSAMLIdentityProviderBuilder().
+		WithName("my-saml-idp1").
+		WithResourceUID("my-saml-uid-idp1").
+		Build()
+	myGitLabIDP1 := oidctestutil.NewTestUpstreamGitLabIdentityProviderBuilder().
+		WithName("my-gitlab-idp1").
+		WithResourceUID("my-gitlab-uid-idp1").
+		Build()
+	myOAuth2IDP1 := oidctestutil.NewTestUpstreamOAuth2IdentityProviderBuilder().
+		WithName("my-oauth2-idp1").
+		WithResourceUID("my-oauth2-uid-idp1").
+		Build()
 
 	// FederationDomainIssuers
 	fakeIssuerURL := "https://www.fakeissuerurl.com"
@@ -116,6 +131,9 @@ func TestFederationDomainIdentityProvidersListerFinder(t *testing.T) {
 		{DisplayName: "my-ad-idp2", UID: "my-ad-uid-idp2"},
 		{DisplayName: "my-ad-idp3", UID: "my-ad-uid-idp3"},
 		{DisplayName: "my-github-idp1", UID: "my-github-uid-idp1"},
+		{DisplayName: "my-saml-idp1", UID: "my-saml-uid-idp1"},
+		{DisplayName: "my-gitlab-idp1", UID: "my-gitlab-uid-idp1"},
+		{DisplayName: "my-oauth2-idp1", UID: "my-oauth2-uid-idp1"},
 	})
 	require.NoError(t, err)
 
@@ -155,6 +173,21 @@ func TestFederationDomainIdentityProvidersListerFinder(t *testing.T) {
 		Provider:            myGitHubIDP1,
 		SessionProviderType: "github",
 	}
+	mySAML1Resolved := &resolvedsaml.FederationDomainResolvedSAMLIdentityProvider{
+		DisplayName:         "my-saml-idp1",
+		Provider:            mySAMLIDP1,
+		SessionProviderType: "saml",
+	}
+	myGitLab1Resolved := &resolvedgitlab.FederationDomainResolvedGitLabIdentityProvider{
+		DisplayName:         "my-gitlab-idp1",
+		Provider:            myGitLabIDP1,
+		SessionProviderType: "gitlab",
+	}
+	myOAuth21Resolved := &resolvedoauth2.FederationDomainResolvedOAuth2IdentityProvider{
+		DisplayName:         "my-oauth2-idp1",
+		Provider:            myOAuth2IDP1,
+		SessionProviderType: "oauth2",
+	}
 
 	myDefaultOIDCIDPResolved := &resolvedoidc.FederationDomainResolvedOIDCIdentityProvider{
 		DisplayName:         "my-default-oidc-idp",
@@ -444,6 +477,9 @@ func TestFederationDomainIdentityProvidersListerFinder(t *testing.T) {
 				WithLDAP(myLDAPIDP1).
 				WithActiveDirectory(myADIDP1).
 				WithGitHub(myGitHubIDP1).
+				WithSAML(mySAMLIDP1).
+				WithGitLab(myGitLabIDP1).
+				WithOAuth2(myOAuth2IDP1).
 				BuildDynamicUpstreamIDPProvider(),
 			federationDomainIssuer: fdIssuerWithLotsOfIDPs,
 			wantIDPs: []resolvedprovider.FederationDomainResolvedIdentityProvider{
@@ -452,6 +488,9 @@ func TestFederationDomainIdentityProvidersListerFinder(t *testing.T) {
 				myLDAPIDP1Resolved,
 				myADIDP1Resolved,
 				myGitHub1Resolved,
+				mySAML1Resolved,
+				myGitLab1Resolved,
+				myOAuth21Resolved,
 			},
 		},
 		{
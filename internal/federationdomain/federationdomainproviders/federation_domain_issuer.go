@@ -1,4 +1,4 @@
-// Copyright 2020-2023 the Pinniped contributors. All Rights Reserved.
+// Copyright 2020-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package federationdomainproviders
@@ -9,6 +9,9 @@ import (
 	"strings"
 
 	"go.pinniped.dev/internal/constable"
+	"go.pinniped.dev/internal/federationdomain/brandedpages"
+	"go.pinniped.dev/internal/federationdomain/i18n"
+	"go.pinniped.dev/internal/federationdomain/timeouts"
 )
 
 // FederationDomainIssuer is a parsed FederationDomain representing all the settings for a downstream OIDC provider
@@ -24,6 +27,44 @@ type FederationDomainIssuer struct {
 	// are not explicitly specified in the FederationDomain's spec, and there is exactly one IDP CR defined in the
 	// Supervisor's namespace.
 	defaultIdentityProvider *FederationDomainIdentityProvider
+
+	// name is the name of the FederationDomain.
+	name string
+
+	// sessionPolicy configures the lifetimes of the sessions and tokens of the FederationDomain.
+	sessionPolicy timeouts.SessionPolicy
+
+	// idTokenSigningAlgorithm is the JWS algorithm which the FederationDomain uses to sign ID tokens.
+	idTokenSigningAlgorithm string
+
+	// clientRegistrationPolicy configures the dynamic client registration endpoint, which is disabled when nil.
+	clientRegistrationPolicy *ClientRegistrationPolicy
+
+	// brandedPages are the web pages of the FederationDomain, which use the default branding when nil.
+	brandedPages *brandedpages.Pages
+
+	// defaultLanguage is the language of the web pages of the FederationDomain when the request does not ask for
+	// any supported language, or empty to use the default language of the i18n package.
+	defaultLanguage string
+}
+
+// ClientRegistrationPolicy is the configuration of the dynamic client registration endpoint of a FederationDomain.
+type ClientRegistrationPolicy struct {
+	// FederationDomainName is the name of the FederationDomain. Registered clients are annotated with this name,
+	// so that they can only be managed by the registration endpoint of the FederationDomain which registered them.
+	FederationDomainName string
+
+	// InitialAccessTokenSecretName is the name of the Secret holding the initial access tokens, or empty when
+	// initial access tokens are not accepted.
+	InitialAccessTokenSecretName string
+
+	// AllowKubernetesAuthentication allows callers which are authorized to create OIDCClients to register clients.
+	AllowKubernetesAuthentication bool
+
+	// AllowedRedirectURIPatterns, AllowedGrantTypes, and AllowedScopes constrain the metadata of registered clients.
+	AllowedRedirectURIPatterns []string
+	AllowedGrantTypes          []string
+	AllowedScopes              []string
 }
 
 // NewFederationDomainIssuer returns a FederationDomainIssuer.
@@ -116,3 +157,75 @@ func (p *FederationDomainIssuer) IdentityProviders() []*FederationDomainIdentity
 func (p *FederationDomainIssuer) DefaultIdentityProvider() *FederationDomainIdentityProvider {
 	return p.defaultIdentityProvider
 }
+
+// SessionPolicy returns the session policy. Settings which were not configured are zero.
+func (p *FederationDomainIssuer) SessionPolicy() timeouts.SessionPolicy {
+	return p.sessionPolicy
+}
+
+// SetSessionPolicy sets the session policy.
+func (p *FederationDomainIssuer) SetSessionPolicy(sessionPolicy timeouts.SessionPolicy) {
+	p.sessionPolicy = sessionPolicy
+}
+
+// Name returns the name of the FederationDomain.
+func (p *FederationDomainIssuer) Name() string {
+	return p.name
+}
+
+// SetName sets the name of the FederationDomain.
+func (p *FederationDomainIssuer) SetName(name string) {
+	p.name = name
+}
+
+// IDTokenSigningAlgorithm returns the JWS algorithm which is used to sign ID tokens, which is ES256 by default.
+func (p *FederationDomainIssuer) IDTokenSigningAlgorithm() string {
+	if p.idTokenSigningAlgorithm == "" {
+		return "ES256"
+	}
+	return p.idTokenSigningAlgorithm
+}
+
+// SetIDTokenSigningAlgorithm sets the JWS algorithm which is used to sign ID tokens.
+func (p *FederationDomainIssuer) SetIDTokenSigningAlgorithm(algorithm string) {
+	p.idTokenSigningAlgorithm = algorithm
+}
+
+// ClientRegistrationPolicy returns the configuration of the dynamic client registration endpoint, or nil when
+// dynamic client registration is not enabled.
+func (p *FederationDomainIssuer) ClientRegistrationPolicy() *ClientRegistrationPolicy {
+	return p.clientRegistrationPolicy
+}
+
+// SetClientRegistrationPolicy sets the configuration of the dynamic client registration endpoint.
+func (p *FederationDomainIssuer) SetClientRegistrationPolicy(policy *ClientRegistrationPolicy) {
+	p.clientRegistrationPolicy = policy
+}
+
+// BrandedPages returns the web pages of the FederationDomain.
+func (p *FederationDomainIssuer) BrandedPages() *brandedpages.Pages {
+	if p.brandedPages == nil {
+		return brandedpages.Default()
+	}
+	return p.brandedPages
+}
+
+// SetBrandedPages sets the web pages of the FederationDomain.
+func (p *FederationDomainIssuer) SetBrandedPages(pages *brandedpages.Pages) {
+	p.brandedPages = pages
+}
+
+// DefaultLanguage returns the language of the web pages of the FederationDomain when the request does not ask for
+// any supported language.
+func (p *FederationDomainIssuer) DefaultLanguage() string {
+	if p.defaultLanguage == "" {
+		return i18n.DefaultLanguage
+	}
+	return p.defaultLanguage
+}
+
+// SetDefaultLanguage sets the language of the web pages of the FederationDomain when the request does not ask for
+// any supported language.
+func (p *FederationDomainIssuer) SetDefaultLanguage(lang string) {
+	p.defaultLanguage = lang
+}
@@ -1,17 +1,23 @@
-// Copyright 2021-2024 the Pinniped contributors. All Rights Reserved.
+// Copyright 2021-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 // Package formposthtml defines HTML templates used by the Supervisor.
 package formposthtml
 
 import (
+	"bytes"
+	"context"
 	_ "embed" // Needed to trigger //go:embed directives below.
+	"fmt"
 	"html/template"
+	"net/url"
 	"strings"
 
 	"github.com/tdewolff/minify/v2/minify"
 
+	"go.pinniped.dev/internal/federationdomain/branding"
 	"go.pinniped.dev/internal/federationdomain/csp"
+	"go.pinniped.dev/internal/federationdomain/i18n"
 )
 
 //nolint:gochecknoglobals // This package uses globals to ensure that all parsing and minifying happens at init.
@@ -27,22 +33,44 @@ var (
 	//go:embed form_post.gohtml
 	rawHTMLTemplate string
 
-	// Parse the Go templated HTML and inject functions providing the minified inline CSS and JS.
-	parsedHTMLTemplate = template.Must(template.New("form_post.gohtml").Funcs(template.FuncMap{
-		"minifiedCSS": func() template.CSS { return template.CSS(minifiedCSS) }, //nolint:gosec // This is 100% static input, not attacker-controlled.
-		"minifiedJS":  func() template.JS { return template.JS(minifiedJS) },    //nolint:gosec // This is 100% static input, not attacker-controlled.
-	}).Parse(rawHTMLTemplate))
+	// Parse the Go templated HTML once per language and inject functions providing the minified inline CSS and JS.
+	parsedHTMLTemplates = mustParseTemplates(rawHTMLTemplate, minifiedCSS, nil)
+	parsedHTMLTemplate  = parsedHTMLTemplates.ForLanguage(i18n.DefaultLanguage)
 
 	// Generate the CSP header value once since it's effectively constant.
-	cspValue = strings.Join([]string{
+	cspValue = contentSecurityPolicy(minifiedCSS)
+
+	defaultPage = &Page{templates: parsedHTMLTemplates, csp: cspValue}
+)
+
+func parseTemplates(text string, css string, b *branding.Branding) (*i18n.Templates, error) {
+	return i18n.ParseTemplates(func(c *i18n.Catalog) (*template.Template, error) {
+		return template.New("form_post.gohtml").Funcs(template.FuncMap{
+			"minifiedCSS": func() template.CSS { return template.CSS(css) },      //nolint:gosec // This is static or validated input, not attacker-controlled.
+			"minifiedJS":  func() template.JS { return template.JS(minifiedJS) }, //nolint:gosec // This is 100% static input, not attacker-controlled.
+			"branding":    func() *branding.Branding { return b },
+		}).Funcs(i18n.TemplateFuncs(c)).Parse(text)
+	})
+}
+
+func mustParseTemplates(text string, css string, b *branding.Branding) *i18n.Templates {
+	templates, err := parseTemplates(text, css, b)
+	if err != nil {
+		panic(err)
+	}
+	return templates
+}
+
+func contentSecurityPolicy(css string) string {
+	return strings.Join([]string{
 		`default-src 'none'`,
 		`script-src '` + csp.Hash(minifiedJS) + `'`,
-		`style-src '` + csp.Hash(minifiedCSS) + `'`,
+		`style-src '` + csp.Hash(css) + `'`,
 		`img-src data:`,
 		`connect-src *`,
 		`frame-ancestors 'none'`,
 	}, "; ")
-)
+}
 
 func panicOnError(s string, err error) string {
 	if err != nil {
@@ -56,5 +84,80 @@ func panicOnError(s string, err error) string {
 // See https://developer.mozilla.org/en-US/docs/Web/HTTP/Headers/Content-Security-Policy.
 func ContentSecurityPolicy() string { return cspValue }
 
-// Template returns the html/template.Template for rendering the response_type=form_post response page.
+// Template returns the html/template.Template for rendering the response_type=form_post response page
+// in the default language.
 func Template() *template.Template { return parsedHTMLTemplate }
+
+// Page is the response_type=form_post response page of a FederationDomain, which may be customized by branding.
+type Page struct {
+	templates *i18n.Templates
+	csp       string
+}
+
+// DefaultPage returns the response_type=form_post response page without any branding.
+func DefaultPage() *Page { return defaultPage }
+
+// NewPage returns a response_type=form_post response page customized by the given branding. When the branding
+// overrides the template of the page, the template is rendered with sample data and validated to be compatible
+// with the page's CSP.
+func NewPage(b *branding.Branding) (*Page, error) {
+	if b == nil {
+		return defaultPage, nil
+	}
+
+	// The states of this page are absolutely positioned, so keep the branding out of their way.
+	brandedCSS := rawCSS + b.CSS() + `
+.branding-logo { max-height: 40px; margin: 10px auto; }
+.branding-footer { position: fixed; bottom: 0; left: 0; right: 0; max-width: none; }
+`
+	if b.PrimaryColor != "" {
+		brandedCSS += fmt.Sprintf(`#loading { border-top-color: %s; }`, b.PrimaryColor)
+	}
+	css, err := minify.CSS(brandedCSS)
+	if err != nil {
+		return nil, fmt.Errorf("could not minify CSS for form_post page: %w", err)
+	}
+
+	text, overridden := b.TemplateOverride(branding.FormPostTemplateKey)
+	if !overridden {
+		text = rawHTMLTemplate
+	}
+	templates, err := parseTemplates(text, css, b)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse template %q: %w", branding.FormPostTemplateKey, err)
+	}
+	if overridden {
+		for _, tmpl := range templates.All() {
+			// This is the same data that fosite uses when it renders the template.
+			var buf bytes.Buffer
+			if err := tmpl.Execute(&buf, struct {
+				RedirURL   string
+				Parameters url.Values
+			}{
+				RedirURL:   "https://sample.example.com/callback",
+				Parameters: url.Values{"code": []string{"sample-code"}, "state": []string{"sample-state"}},
+			}); err != nil {
+				return nil, fmt.Errorf("could not render template %q: %w", branding.FormPostTemplateKey, err)
+			}
+			if err := branding.CheckRenderedPage(buf.String(), []string{minifiedJS}, []string{css}); err != nil {
+				return nil, fmt.Errorf("template %q is not compatible with the Content-Security-Policy of the page: %w", branding.FormPostTemplateKey, err)
+			}
+		}
+	}
+
+	return &Page{templates: templates, csp: contentSecurityPolicy(css)}, nil
+}
+
+// ContentSecurityPolicy returns the Content-Security-Policy header value to make the Template() operate correctly.
+func (p *Page) ContentSecurityPolicy() string { return p.csp }
+
+// Template returns the html/template.Template for rendering the response_type=form_post response page
+// in the default language.
+func (p *Page) Template() *template.Template { return p.templates.ForLanguage(i18n.DefaultLanguage) }
+
+// GetFormPostHTMLTemplate returns the html/template.Template for rendering the response_type=form_post response
+// page in the language which was selected for the request by i18n.WithNegotiatedLanguage. It implements
+// fosite.FormPostHTMLTemplateProvider, so that fosite renders the page in the language of each request.
+func (p *Page) GetFormPostHTMLTemplate(ctx context.Context) *template.Template {
+	return p.templates.ForContext(ctx)
+}
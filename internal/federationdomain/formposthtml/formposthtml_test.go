@@ -1,17 +1,21 @@
-// Copyright 2021-2023 the Pinniped contributors. All Rights Reserved.
+// Copyright 2021-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package formposthtml
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"net/url"
 	"testing"
 
 	"github.com/ory/fosite"
 	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
 
+	"go.pinniped.dev/internal/federationdomain/branding"
+	"go.pinniped.dev/internal/federationdomain/i18n"
 	"go.pinniped.dev/internal/here"
 )
 
@@ -93,6 +97,23 @@ func TestTemplate(t *testing.T) {
 	require.Equal(t, testExpectedFormPostOutput, buf.String())
 }
 
+func TestGetFormPostHTMLTemplate(t *testing.T) {
+	page := DefaultPage()
+	require.Same(t, Template(), page.Template())
+	require.Same(t, Template(), page.GetFormPostHTMLTemplate(context.Background()))
+
+	// Use the Fosite helper to render the form, just like Fosite does when it gets the template from its config.
+	var buf bytes.Buffer
+	fosite.WriteAuthorizeFormPostResponse(testRedirectURL, testResponseParams,
+		page.GetFormPostHTMLTemplate(i18n.WithCatalog(context.Background(), i18n.ForLanguage("es"))), &buf)
+
+	require.Contains(t, buf.String(), `<html lang="es">`)
+	require.Contains(t, buf.String(), `data-title="Inicio de sesión correcto"`)
+	require.Contains(t, buf.String(), `<p>Ha iniciado sesión correctamente. Ya puede cerrar esta pestaña.</p>`)
+	require.Contains(t, buf.String(), `<p>Vuelva a intentarlo.</p>`)
+	require.NoError(t, branding.CheckRenderedPage(buf.String(), []string{minifiedJS}, []string{minifiedCSS}))
+}
+
 func TestContentSecurityPolicyHashes(t *testing.T) {
 	require.Equal(t, testExpectedCSP, ContentSecurityPolicy())
 }
@@ -101,3 +122,42 @@ func TestHelpers(t *testing.T) {
 	require.Equal(t, "test", panicOnError("test", nil))
 	require.PanicsWithError(t, "some error", func() { panicOnError("", fmt.Errorf("some error")) })
 }
+
+func TestNewPage(t *testing.T) {
+	page, err := NewPage(nil)
+	require.NoError(t, err)
+	require.Same(t, DefaultPage(), page)
+	require.Equal(t, testExpectedCSP, DefaultPage().ContentSecurityPolicy())
+	require.Same(t, Template(), DefaultPage().Template())
+
+	b, err := branding.FromConfigMap(&corev1.ConfigMap{
+		Data: map[string]string{
+			"primaryColor": "#123456",
+			"footerLinks":  "- text: Privacy\n  url: https://example.com/privacy\n",
+		},
+	})
+	require.NoError(t, err)
+	page, err = NewPage(b)
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, page.Template().Execute(&buf, struct {
+		RedirURL   string
+		Parameters url.Values
+	}{
+		RedirURL:   testRedirectURL,
+		Parameters: testResponseParams,
+	}))
+	require.Contains(t, buf.String(), `border-top-color:#123456`)
+	require.Contains(t, buf.String(), `<a href="https://example.com/privacy">Privacy</a>`)
+	require.NotEqual(t, testExpectedCSP, page.ContentSecurityPolicy())
+
+	b, err = branding.FromConfigMap(&corev1.ConfigMap{
+		Data: map[string]string{"form_post.gohtml": `<html><head><style>body{}</style></head></html>`},
+	})
+	require.NoError(t, err)
+	page, err = NewPage(b)
+	require.EqualError(t, err, `template "form_post.gohtml" is not compatible with the Content-Security-Policy of the page: `+
+		`<style> elements may only contain {{minifiedCSS}}`)
+	require.Nil(t, page)
+}
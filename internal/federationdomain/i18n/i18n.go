@@ -0,0 +1,196 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package i18n provides the translations of the user-visible strings of the Supervisor's end-user web pages,
+// and selects the language of each request from its Accept-Language header.
+package i18n
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"html/template"
+	"net/http"
+	"path"
+	"slices"
+	"strings"
+
+	"golang.org/x/text/language"
+	"sigs.k8s.io/yaml"
+)
+
+// DefaultLanguage is the language which is used when neither the request nor the FederationDomain
+// selects one of the supported languages. Its catalog is also the fallback for missing translations.
+const DefaultLanguage = "en"
+
+// contextKey type is unexported to prevent collisions.
+type contextKey int
+
+const catalogKey contextKey = iota
+
+//nolint:gochecknoglobals // This package uses globals to ensure that all catalogs are loaded at init.
+var (
+	//go:embed catalogs/*.yaml
+	catalogFiles embed.FS
+
+	// The supported languages, with the default language first so that it wins any ties during matching.
+	supportedLanguages = []string{DefaultLanguage, "de", "es", "fr", "ja"}
+
+	catalogs = loadCatalogs()
+
+	matcher = language.NewMatcher(func() []language.Tag {
+		tags := make([]language.Tag, len(supportedLanguages))
+		for i, lang := range supportedLanguages {
+			tags[i] = language.MustParse(lang)
+		}
+		return tags
+	}())
+)
+
+// Catalog holds the translations of the message keys into one language.
+type Catalog struct {
+	language string
+	messages map[string]string
+}
+
+func loadCatalogs() map[string]*Catalog {
+	loaded := make(map[string]*Catalog, len(supportedLanguages))
+	for _, lang := range supportedLanguages {
+		data, err := catalogFiles.ReadFile(path.Join("catalogs", lang+".yaml"))
+		if err != nil {
+			panic(err)
+		}
+		var messages map[string]string
+		if err := yaml.UnmarshalStrict(data, &messages); err != nil {
+			panic(fmt.Errorf("could not parse message catalog for language %q: %w", lang, err))
+		}
+		loaded[lang] = &Catalog{language: lang, messages: messages}
+	}
+	return loaded
+}
+
+// Languages returns the supported languages, starting with the default language.
+func Languages() []string { return slices.Clone(supportedLanguages) }
+
+// ForLanguage returns the catalog of the given language, or the catalog of the default language
+// when the given language is not supported.
+func ForLanguage(lang string) *Catalog {
+	if c, ok := catalogs[lang]; ok {
+		return c
+	}
+	return catalogs[DefaultLanguage]
+}
+
+// Language returns the language of the catalog, e.g. "en".
+func (c *Catalog) Language() string { return c.language }
+
+// Translate returns the message with the given key, formatted with the given args like fmt.Sprintf.
+// Messages which are missing from the catalog are taken from the catalog of the default language.
+func (c *Catalog) Translate(key string, args ...any) string {
+	message, ok := c.messages[key]
+	if !ok {
+		message, ok = catalogs[DefaultLanguage].messages[key]
+	}
+	if !ok {
+		// This would be a programming error, so make it obvious on the page.
+		return key
+	}
+	if len(args) == 0 {
+		return message
+	}
+	return fmt.Sprintf(message, args...)
+}
+
+// Negotiate returns the catalog of the supported language which best matches the given Accept-Language header value.
+// When the header does not ask for any supported language, the catalog of the given default language is returned.
+func Negotiate(acceptLanguage string, defaultLanguage string) *Catalog {
+	fallback := ForLanguage(defaultLanguage)
+	if strings.TrimSpace(acceptLanguage) == "" {
+		return fallback
+	}
+	desired, _, err := language.ParseAcceptLanguage(acceptLanguage)
+	if err != nil || len(desired) == 0 {
+		return fallback
+	}
+	_, index, confidence := matcher.Match(desired...)
+	if confidence == language.No {
+		return fallback
+	}
+	return catalogs[supportedLanguages[index]]
+}
+
+// WithCatalog returns a copy of the context which carries the given catalog.
+func WithCatalog(ctx context.Context, c *Catalog) context.Context {
+	return context.WithValue(ctx, catalogKey, c)
+}
+
+// FromContext returns the catalog which was selected for the request, or the catalog of the default language
+// when none was selected.
+func FromContext(ctx context.Context) *Catalog {
+	if c, ok := ctx.Value(catalogKey).(*Catalog); ok {
+		return c
+	}
+	return catalogs[DefaultLanguage]
+}
+
+// WithNegotiatedLanguage wraps the handler so that it can find the catalog of the language which best matches the
+// Accept-Language header of each request using FromContext. The given default language is used when the request
+// does not ask for any supported language.
+func WithNegotiatedLanguage(handler http.Handler, defaultLanguage string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c := Negotiate(r.Header.Get("Accept-Language"), defaultLanguage)
+		w.Header().Add("Vary", "Accept-Language")
+		handler.ServeHTTP(w, r.WithContext(WithCatalog(r.Context(), c)))
+	})
+}
+
+// Templates are the translations of a page template, one for each supported language.
+type Templates struct {
+	byLanguage map[string]*template.Template
+}
+
+// ParseTemplates calls parse once for the catalog of each supported language, and returns the resulting templates.
+// The parse function is expected to make the catalog available to the template, e.g. as template functions.
+func ParseTemplates(parse func(c *Catalog) (*template.Template, error)) (*Templates, error) {
+	t := &Templates{byLanguage: make(map[string]*template.Template, len(supportedLanguages))}
+	for _, lang := range supportedLanguages {
+		tmpl, err := parse(catalogs[lang])
+		if err != nil {
+			return nil, err
+		}
+		t.byLanguage[lang] = tmpl
+	}
+	return t, nil
+}
+
+// TemplateFuncs returns the template functions which make the catalog available to a page template.
+// The "t" function translates a message key, and the "lang" function returns the language of the page.
+func TemplateFuncs(c *Catalog) template.FuncMap {
+	return template.FuncMap{
+		"t":    c.Translate,
+		"lang": c.Language,
+	}
+}
+
+// ForLanguage returns the template of the given language, or the template of the default language when the
+// given language is not supported.
+func (t *Templates) ForLanguage(lang string) *template.Template {
+	if tmpl, ok := t.byLanguage[lang]; ok {
+		return tmpl
+	}
+	return t.byLanguage[DefaultLanguage]
+}
+
+// ForContext returns the template of the language which was selected for the request.
+func (t *Templates) ForContext(ctx context.Context) *template.Template {
+	return t.ForLanguage(FromContext(ctx).Language())
+}
+
+// All returns the templates of all supported languages, starting with the default language.
+func (t *Templates) All() []*template.Template {
+	all := make([]*template.Template, len(supportedLanguages))
+	for i, lang := range supportedLanguages {
+		all[i] = t.byLanguage[lang]
+	}
+	return all
+}
@@ -0,0 +1,137 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package i18n
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"html/template"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCatalogsHaveTheSameMessagesAsTheDefaultCatalog(t *testing.T) {
+	defaultCatalog := catalogs[DefaultLanguage]
+	wantKeys := slices.Sorted(maps.Keys(defaultCatalog.messages))
+	require.NotEmpty(t, wantKeys)
+
+	for _, lang := range Languages() {
+		t.Run(lang, func(t *testing.T) {
+			c := ForLanguage(lang)
+			require.Equal(t, lang, c.Language())
+			require.Equal(t, wantKeys, slices.Sorted(maps.Keys(c.messages)))
+			for key, message := range c.messages {
+				require.NotEmpty(t, strings.TrimSpace(message), "message %q is empty", key)
+				require.Equal(t, strings.Count(defaultCatalog.messages[key], "%"), strings.Count(message, "%"),
+					"message %q has different formatting verbs than the default catalog", key)
+			}
+		})
+	}
+}
+
+func TestLanguages(t *testing.T) {
+	require.Equal(t, []string{"en", "de", "es", "fr", "ja"}, Languages())
+
+	// Callers cannot change the supported languages.
+	Languages()[0] = "xx"
+	require.Equal(t, DefaultLanguage, Languages()[0])
+}
+
+func TestTranslate(t *testing.T) {
+	require.Equal(t, "Log in to my-idp", ForLanguage("en").Translate("login.heading", "my-idp"))
+	require.Equal(t, "Bei my-idp anmelden", ForLanguage("de").Translate("login.heading", "my-idp"))
+	require.Equal(t, "Incorrect username or password.", ForLanguage("en").Translate("login.error.incorrectUsernameOrPassword"))
+	require.Equal(t, "Nom d'utilisateur ou mot de passe incorrect.", ForLanguage("fr").Translate("login.error.incorrectUsernameOrPassword"))
+
+	// Unsupported languages use the default catalog.
+	require.Equal(t, "Log in to my-idp", ForLanguage("xx").Translate("login.heading", "my-idp"))
+
+	// Messages which are missing from a catalog come from the default catalog.
+	c := &Catalog{language: "test", messages: map[string]string{}}
+	require.Equal(t, "Log in to my-idp", c.Translate("login.heading", "my-idp"))
+
+	// Unknown keys are returned as-is.
+	require.Equal(t, "no.such.key", ForLanguage("de").Translate("no.such.key"))
+}
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		name            string
+		acceptLanguage  string
+		defaultLanguage string
+		want            string
+	}{
+		{name: "no header", want: "en"},
+		{name: "no header with default language", defaultLanguage: "fr", want: "fr"},
+		{name: "blank header with default language", acceptLanguage: "  ", defaultLanguage: "fr", want: "fr"},
+		{name: "unsupported default language", defaultLanguage: "xx", want: "en"},
+		{name: "exact match", acceptLanguage: "de", want: "de"},
+		{name: "regional variant", acceptLanguage: "es-MX", want: "es"},
+		{name: "regional variant of the default language", acceptLanguage: "en-GB", defaultLanguage: "ja", want: "en"},
+		{name: "first supported language wins", acceptLanguage: "zh-CN, fr-CA;q=0.9, de;q=0.8", want: "fr"},
+		{name: "quality values are respected", acceptLanguage: "de;q=0.5, ja;q=0.9", want: "ja"},
+		{name: "only unsupported languages", acceptLanguage: "zh-CN, ko", defaultLanguage: "es", want: "es"},
+		{name: "wildcard", acceptLanguage: "*", defaultLanguage: "de", want: "de"},
+		{name: "invalid header", acceptLanguage: "!!!", defaultLanguage: "de", want: "de"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, Negotiate(tt.acceptLanguage, tt.defaultLanguage).Language())
+		})
+	}
+}
+
+func TestContext(t *testing.T) {
+	require.Equal(t, DefaultLanguage, FromContext(context.Background()).Language())
+	require.Equal(t, "ja", FromContext(WithCatalog(context.Background(), ForLanguage("ja"))).Language())
+}
+
+func TestWithNegotiatedLanguage(t *testing.T) {
+	var gotLanguage string
+	handler := WithNegotiatedLanguage(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		gotLanguage = FromContext(r.Context()).Language()
+	}), "es")
+
+	req := httptest.NewRequest(http.MethodGet, "/some/path", nil)
+	req.Header.Set("Accept-Language", "fr-FR,fr;q=0.9,en;q=0.8")
+	rsp := httptest.NewRecorder()
+	handler.ServeHTTP(rsp, req)
+	require.Equal(t, "fr", gotLanguage)
+	require.Equal(t, []string{"Accept-Language"}, rsp.Header().Values("Vary"))
+
+	req = httptest.NewRequest(http.MethodGet, "/some/path", nil)
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	require.Equal(t, "es", gotLanguage)
+}
+
+func TestParseTemplates(t *testing.T) {
+	templates, err := ParseTemplates(func(c *Catalog) (*template.Template, error) {
+		return template.New("test").Funcs(TemplateFuncs(c)).Parse(`<html lang="{{ lang }}">{{ t "login.heading" . }}</html>`)
+	})
+	require.NoError(t, err)
+	require.Len(t, templates.All(), len(Languages()))
+
+	render := func(tmpl *template.Template) string {
+		var buf bytes.Buffer
+		require.NoError(t, tmpl.Execute(&buf, "<idp>"))
+		return buf.String()
+	}
+	require.Equal(t, `<html lang="en">Log in to &lt;idp&gt;</html>`, render(templates.All()[0]))
+	require.Equal(t, `<html lang="de">Bei &lt;idp&gt; anmelden</html>`, render(templates.ForLanguage("de")))
+	require.Equal(t, `<html lang="en">Log in to &lt;idp&gt;</html>`, render(templates.ForLanguage("xx")))
+	require.Equal(t, `<html lang="ja">&lt;idp&gt; にログイン</html>`,
+		render(templates.ForContext(WithCatalog(context.Background(), ForLanguage("ja")))))
+
+	_, err = ParseTemplates(func(_ *Catalog) (*template.Template, error) {
+		return nil, errors.New("some parse error")
+	})
+	require.EqualError(t, err, "some parse error")
+}
@@ -0,0 +1,75 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package idtokenlifespan
+
+import (
+	"context"
+
+	"github.com/ory/fosite"
+	"github.com/ory/fosite/handler/openid"
+)
+
+// OpenIDConnectDeviceFactory is similar to the function of the same name in the fosite compose package,
+// except it allows wrapping the IDTokenLifespanProvider, and it does not use OpenID Connect request storage.
+//
+// Fosite's handler expects the implementer to create an OpenID Connect session keyed by the signature of the
+// device code when the user finishes logging in. That does not fit with how our OpenID Connect request storage is
+// keyed, and it is also not needed, because the device code session already holds our whole downstream session.
+// By the time this handler runs, the device code token endpoint handler has already loaded that session onto the
+// request, so the ID token can be issued from there.
+//
+// **Important note:** You must add this handler *after* you have added an OAuth2 device authorization token handler!
+func OpenIDConnectDeviceFactory(config fosite.Configurator, _ any, strategy any) any {
+	return &openIDConnectDeviceHandler{
+		IDTokenHandleHelper: &openid.IDTokenHandleHelper{
+			IDTokenStrategy: strategy.(openid.OpenIDConnectTokenStrategy),
+		},
+		config: &contextAwareIDTokenLifespanProvider{DelegateConfig: config},
+	}
+}
+
+var _ fosite.TokenEndpointHandler = (*openIDConnectDeviceHandler)(nil)
+
+type openIDConnectDeviceHandler struct {
+	*openid.IDTokenHandleHelper
+	config fosite.IDTokenLifespanProvider
+}
+
+func (c *openIDConnectDeviceHandler) HandleTokenEndpointRequest(_ context.Context, _ fosite.AccessRequester) error {
+	return fosite.ErrUnknownRequest
+}
+
+func (c *openIDConnectDeviceHandler) PopulateTokenEndpointResponse(ctx context.Context, requester fosite.AccessRequester, responder fosite.AccessResponder) error {
+	if !c.CanHandleTokenEndpointRequest(ctx, requester) {
+		return fosite.ErrUnknownRequest
+	}
+
+	if !requester.GetGrantedScopes().Has("openid") {
+		// Only issue ID tokens when the openid scope was requested and granted.
+		return fosite.ErrUnknownRequest
+	}
+
+	session, ok := requester.GetSession().(openid.Session)
+	if !ok {
+		return fosite.ErrServerError.WithDebug("Failed to generate id token because session must be of type fosite/handler/openid.Session.")
+	}
+
+	claims := session.IDTokenClaims()
+	if claims.Subject == "" {
+		return fosite.ErrServerError.WithDebug("Failed to generate id token because subject is an empty string.")
+	}
+
+	claims.AccessTokenHash = c.GetAccessTokenHash(ctx, requester, responder)
+
+	idTokenLifespan := fosite.GetEffectiveLifespan(requester.GetClient(), fosite.GrantTypeDeviceCode, fosite.IDToken, c.config.GetIDTokenLifespan(ctx))
+	return c.IssueExplicitIDToken(ctx, idTokenLifespan, requester, responder)
+}
+
+func (c *openIDConnectDeviceHandler) CanSkipClientAuth(_ context.Context, _ fosite.AccessRequester) bool {
+	return false
+}
+
+func (c *openIDConnectDeviceHandler) CanHandleTokenEndpointRequest(_ context.Context, requester fosite.AccessRequester) bool {
+	return requester.GetGrantTypes().ExactOne(string(fosite.GrantTypeDeviceCode))
+}
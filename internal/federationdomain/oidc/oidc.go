@@ -6,15 +6,21 @@
 package oidc
 
 import (
+	"context"
 	"crypto/subtle"
 	"errors"
+	"fmt"
+	"html/template"
 	"net/http"
 	"reflect"
+	"sync"
 	"time"
 
 	"github.com/felixge/httpsnoop"
+	"github.com/hashicorp/go-retryablehttp"
 	"github.com/ory/fosite"
 	"github.com/ory/fosite/compose"
+	"github.com/ory/fosite/handler/rfc8628"
 
 	oidcapi "go.pinniped.dev/generated/latest/apis/supervisor/oidc"
 	"go.pinniped.dev/internal/federationdomain/clientregistry"
@@ -27,6 +33,7 @@ import (
 	"go.pinniped.dev/internal/federationdomain/strategy"
 	"go.pinniped.dev/internal/federationdomain/timeouts"
 	"go.pinniped.dev/internal/httputil/httperr"
+	"go.pinniped.dev/internal/net/phttp"
 	"go.pinniped.dev/internal/plog"
 	"go.pinniped.dev/internal/psession"
 	"go.pinniped.dev/pkg/oidcclient/nonce"
@@ -42,6 +49,16 @@ const (
 	JWKSEndpointPath          = "/jwks.json"
 	PinnipedIDPsPathV1Alpha1  = "/v1alpha1/pinniped_identity_providers"
 	PinnipedLoginPath         = "/login"
+
+	DeviceAuthorizationEndpointPath = "/oauth2/device_authorization"
+	DeviceVerificationEndpointPath  = "/oauth2/device"
+
+	RevocationEndpointPath    = "/oauth2/revoke"
+	EndSessionEndpointPath    = "/oauth2/end_session"
+	RegistrationEndpointPath  = "/oauth2/register"
+	IntrospectionEndpointPath = "/oauth2/introspect"
+
+	PushedAuthorizationRequestEndpointPath = "/oauth2/par"
 )
 
 const (
@@ -69,6 +86,25 @@ const (
 	// cookie contents.
 	CSRFCookieEncodingName = "csrf"
 
+	// UpstreamStateCookieNamePrefix is the prefix of the names of the browser cookies which hold the upstream state
+	// params of the upstreams which cannot carry the whole state param, e.g. SAML upstreams. Each cookie is named
+	// using the RelayStateKey of its state param, so that concurrent logins in the same browser do not collide.
+	UpstreamStateCookieNamePrefix = "__Host-pinniped-upstream-state-"
+
+	// UpstreamStateCookieLifespan is the length of time that an upstream state cookie is kept by the browser,
+	// which limits how long the user may take to log in at the upstream.
+	UpstreamStateCookieLifespan = 30 * time.Minute
+
+	// UserCodeLength is the number of characters in a user code issued by the device authorization endpoint.
+	UserCodeLength = 8
+
+	// UserCodeSymbols are the characters which may appear in a user code issued by the device authorization endpoint.
+	UserCodeSymbols = "BCDFGHJKLMNPQRSTVWXZ"
+
+	// PushedAuthorizationRequestURIPrefix is the prefix of the request_uri values which are issued by the pushed
+	// authorization request endpoint, as suggested by https://datatracker.ietf.org/doc/html/rfc9126#section-2.2.
+	PushedAuthorizationRequestURIPrefix = "urn:ietf:params:oauth:request_uri:"
+
 	// CSRFCookieLifespan is the length of time that the CSRF cookie is valid. After this time, the
 	// Supervisor's authorization endpoint should give the browser a new CSRF cookie. We set it to
 	// a week so that it is unlikely to expire during a login.
@@ -105,10 +141,22 @@ type UpstreamStateParamData struct {
 	CSRFToken     csrftoken.CSRFToken `json:"c"`
 	PKCECode      pkce.Code           `json:"k"`
 	FormatVersion string              `json:"v"`
+
+	// DeviceUserCodeSignature is only set when the login was started from the device verification page,
+	// in which case AuthParams will be empty. It identifies the device authorization request to approve
+	// when the login finishes.
+	DeviceUserCodeSignature string `json:"d,omitempty"`
 }
 
 // DefaultOIDCTimeoutsConfiguration returns the default timeouts for the Supervisor server.
 func DefaultOIDCTimeoutsConfiguration() timeouts.Configuration {
+	return OIDCTimeoutsConfiguration(timeouts.SessionPolicy{})
+}
+
+// OIDCTimeoutsConfiguration returns the timeouts for a FederationDomain, given the session policy of
+// the FederationDomain. Each setting which is not configured by the session policy will use the default.
+// The session policies of individual clients are applied per request.
+func OIDCTimeoutsConfiguration(federationDomainSessionPolicy timeouts.SessionPolicy) timeouts.Configuration {
 	// Note: The maximum time that users can access Kubernetes clusters without
 	// needing to do a Supervisor refresh is the sum of the authorization code,
 	// the access token lifetime, the ID token lifetime, and the Concierge's mTLS
@@ -130,7 +178,7 @@ func DefaultOIDCTimeoutsConfiguration() timeouts.Configuration {
 	// identity provider that should be noticed by the Supervisor during an upstream
 	// refresh.
 	//
-	// Given the timeouts specified below, this is:
+	// Given the default timeouts specified below, this is:
 	// 10 + 2 + 2 + 5 = 19 minutes since an initial login, or
 	// 2 + 2 + 5 = 9 minutes since a refresh.
 	// Note that this may be different if an OIDCClient's configuration has changed
@@ -150,22 +198,40 @@ func DefaultOIDCTimeoutsConfiguration() timeouts.Configuration {
 	// its authcode for tokens.
 	authorizationCodeLifespan := 10 * time.Minute
 
+	// Give the end user a similar amount of time to enter the user code of a
+	// device authorization grant and finish logging in with their browser.
+	deviceAndUserCodeLifespan := 10 * time.Minute
+
+	// A pushed authorization request is expected to be used by the client right away, but also
+	// allow time for the end user to choose an identity provider before it is used.
+	pushedAuthorizationRequestLifespan := 5 * time.Minute
+
 	// This is intended to give a very short amount of time to allow the client to
 	// use the access token to exchange for cluster-scoped ID token(s). After this
 	// time runs out, they will need to perform a refresh to get a new tokens,
 	// ensuring the Supervisor has a chance to revalidate their session often.
-	accessTokenLifespan := 2 * time.Minute
+	defaultAccessTokenLifespan := 2 * time.Minute
 
 	// The ID token will have the same default lifespan as the access token for a
 	// similar reason. This is the default lifespan for ID tokens issued by the
 	// authcode flow, the refresh flow, and the cluster-scoped token exchange.
 	// The cluster-scoped ID token can be exchanged for an mTLS client cert, so
 	// limit the window of opportunity to make that exchange to be small.
-	idTokenLifespan := accessTokenLifespan
+	// This is not configurable by the session policies, which only configure the access token lifespan.
+	idTokenLifespan := defaultAccessTokenLifespan
 
 	// This is just long enough to cover a typical work day, giving the end user an
 	// experience of logging in once per day to access all their Kubernetes clusters.
-	refreshTokenLifespan := 9 * time.Hour
+	defaultRefreshTokenLifespan := 9 * time.Hour
+
+	// Apply the FederationDomain's session policy on top of the defaults. The session policy of the client
+	// is applied on top of this, per request.
+	policy := timeouts.SessionPolicy{
+		RefreshTokenLifespan: defaultRefreshTokenLifespan,
+		AccessTokenLifespan:  defaultAccessTokenLifespan,
+	}.Merge(federationDomainSessionPolicy)
+	accessTokenLifespan := policy.AccessTokenLifespan
+	refreshTokenLifespan := effectiveRefreshTokenLifespan(policy)
 
 	// Give a little extra time for some storage lifetimes, to avoid the possibility
 	// that the storage be garbage collected in the middle of trying to look up the token.
@@ -178,38 +244,54 @@ func DefaultOIDCTimeoutsConfiguration() timeouts.Configuration {
 
 		AuthorizeCodeLifespan: authorizationCodeLifespan,
 
+		DeviceAndUserCodeLifespan: deviceAndUserCodeLifespan,
+
+		PushedAuthorizationRequestLifespan: pushedAuthorizationRequestLifespan,
+
+		// The default polling interval suggested by https://datatracker.ietf.org/doc/html/rfc8628#section-3.2.
+		DeviceAuthorizationPollingInterval: 5 * time.Second,
+
 		AccessTokenLifespan: accessTokenLifespan,
-		OverrideDefaultAccessTokenLifespan: func(_ fosite.AccessRequester) (time.Duration, bool) {
-			// Not currently overriding the defaults.
-			return 0, false
+		OverrideDefaultAccessTokenLifespan: func(accessRequest fosite.AccessRequester) (time.Duration, bool) {
+			requestPolicy := sessionPolicyForRequest(policy, accessRequest)
+			return limitLifespanToMaxSessionAge(requestPolicy, accessRequest, requestPolicy.AccessTokenLifespan, accessTokenLifespan)
 		},
 
 		IDTokenLifespan: idTokenLifespan,
 		OverrideDefaultIDTokenLifespan: func(accessRequest fosite.AccessRequester) (time.Duration, bool) {
-			client := accessRequest.GetClient()
+			if accessRequest == nil {
+				return 0, false
+			}
+			lifespan := idTokenLifespan
 			// Don't allow OIDCClients to override the default lifetime for ID tokens returned
 			// by RFC8693 token exchange. This is not user configurable for now.
 			if !accessRequest.GetGrantTypes().Has(oidcapi.GrantTypeTokenExchange) {
-				if castClient, ok := client.(*clientregistry.Client); !ok {
-					// All clients returned by our client registry implement clientregistry.Client,
-					// so this should be a safe cast in practice.
-					plog.Error("could not check if client overrides token lifetimes",
-						errors.New("could not cast client to *clientregistry.Client"),
-						"clientID", client.GetID(), "clientType", reflect.TypeOf(client))
-				} else if castClient.GetIDTokenLifetimeConfiguration() > 0 {
+				if registryClient, ok := castClient(accessRequest.GetClient()); ok && registryClient.GetIDTokenLifetimeConfiguration() > 0 {
 					// An OIDCClient resource has provided an override, so use it.
 					// Note that the pinniped-cli client never overrides this value.
-					return castClient.GetIDTokenLifetimeConfiguration(), true
+					lifespan = registryClient.GetIDTokenLifetimeConfiguration()
 				}
 			}
-			// Otherwise, do not override the defaults.
-			return 0, false
+			// Whether it was overridden or not, an ID token should not outlive the maximum age of the session.
+			return limitLifespanToMaxSessionAge(sessionPolicyForRequest(policy, accessRequest), accessRequest, lifespan, idTokenLifespan)
 		},
 
 		RefreshTokenLifespan: refreshTokenLifespan,
+		OverrideDefaultRefreshTokenLifespan: func(accessRequest fosite.AccessRequester) (time.Duration, bool) {
+			requestPolicy := sessionPolicyForRequest(policy, accessRequest)
+			return limitLifespanToMaxSessionAge(requestPolicy, accessRequest, effectiveRefreshTokenLifespan(requestPolicy), refreshTokenLifespan)
+		},
+
+		AuthorizationCodeSessionStorageLifetime: func(requester fosite.Requester) time.Duration {
+			return authorizationCodeLifespan + effectiveRefreshTokenLifespan(sessionPolicyForRequest(policy, requester))
+		},
+
+		DeviceCodeSessionStorageLifetime: func(requester fosite.Requester) time.Duration {
+			return deviceAndUserCodeLifespan + effectiveRefreshTokenLifespan(sessionPolicyForRequest(policy, requester))
+		},
 
-		AuthorizationCodeSessionStorageLifetime: func(_ fosite.Requester) time.Duration {
-			return authorizationCodeLifespan + refreshTokenLifespan
+		UserCodeSessionStorageLifetime: func(_ fosite.Requester) time.Duration {
+			return deviceAndUserCodeLifespan + storageExtraLifetime
 		},
 
 		PKCESessionStorageLifetime: func(_ fosite.Requester) time.Duration {
@@ -220,16 +302,81 @@ func DefaultOIDCTimeoutsConfiguration() timeouts.Configuration {
 			return authorizationCodeLifespan + storageExtraLifetime
 		},
 
-		AccessTokenSessionStorageLifetime: func(_ fosite.Requester) time.Duration {
-			return refreshTokenLifespan + accessTokenLifespan
+		PushedAuthorizationRequestSessionStorageLifetime: func(_ fosite.Requester) time.Duration {
+			return pushedAuthorizationRequestLifespan + storageExtraLifetime
+		},
+
+		AccessTokenSessionStorageLifetime: func(requester fosite.Requester) time.Duration {
+			requestPolicy := sessionPolicyForRequest(policy, requester)
+			return effectiveRefreshTokenLifespan(requestPolicy) + requestPolicy.AccessTokenLifespan
 		},
 
-		RefreshTokenSessionStorageLifetime: func(_ fosite.Requester) time.Duration {
-			return refreshTokenLifespan + accessTokenLifespan
+		RefreshTokenSessionStorageLifetime: func(requester fosite.Requester) time.Duration {
+			requestPolicy := sessionPolicyForRequest(policy, requester)
+			return effectiveRefreshTokenLifespan(requestPolicy) + requestPolicy.AccessTokenLifespan
 		},
 	}
 }
 
+// effectiveRefreshTokenLifespan returns the lifespan of refresh tokens for the policy. The idle timeout
+// is enforced by the lifespan of the refresh tokens, so it takes precedence when it is shorter.
+func effectiveRefreshTokenLifespan(policy timeouts.SessionPolicy) time.Duration {
+	if policy.IdleTimeout > 0 && policy.IdleTimeout < policy.RefreshTokenLifespan {
+		return policy.IdleTimeout
+	}
+	return policy.RefreshTokenLifespan
+}
+
+// sessionPolicyForRequest returns the policy with the session policy of the request's client applied on top of it.
+func sessionPolicyForRequest(policy timeouts.SessionPolicy, requester fosite.Requester) timeouts.SessionPolicy {
+	if requester == nil || requester.GetClient() == nil {
+		return policy
+	}
+	client, ok := castClient(requester.GetClient())
+	if !ok {
+		return policy
+	}
+	// Note that the pinniped-cli client never overrides the FederationDomain's session policy.
+	return policy.Merge(client.SessionPolicy)
+}
+
+// limitLifespanToMaxSessionAge returns the lifespan, shortened when needed so that a token issued now
+// would not outlive the maximum age of the session, along with whether it differs from the default lifespan.
+func limitLifespanToMaxSessionAge(
+	policy timeouts.SessionPolicy,
+	requester fosite.Requester,
+	lifespan time.Duration,
+	defaultLifespan time.Duration,
+) (time.Duration, bool) {
+	if policy.MaxSessionAge > 0 && requester != nil {
+		if session, ok := requester.GetSession().(*psession.PinnipedSession); ok {
+			if authTime := session.IDTokenClaims().AuthTime; !authTime.IsZero() {
+				remaining := max(time.Until(authTime.Add(policy.MaxSessionAge)), 0)
+				if remaining < lifespan {
+					return remaining, true
+				}
+			}
+		}
+	}
+	if lifespan != defaultLifespan {
+		return lifespan, true
+	}
+	// Otherwise, do not override the defaults.
+	return 0, false
+}
+
+func castClient(client fosite.Client) (*clientregistry.Client, bool) {
+	registryClient, ok := client.(*clientregistry.Client)
+	if !ok {
+		// All clients returned by our client registry implement clientregistry.Client,
+		// so this should be a safe cast in practice.
+		plog.Error("could not check if client overrides token lifetimes",
+			errors.New("could not cast client to *clientregistry.Client"),
+			"clientID", client.GetID(), "clientType", reflect.TypeOf(client))
+	}
+	return registryClient, ok
+}
+
 func FositeOauth2Helper(
 	oauthStore any,
 	issuer string,
@@ -237,7 +384,116 @@ func FositeOauth2Helper(
 	jwksProvider jwks.DynamicJWKSProvider,
 	timeoutsConfiguration timeouts.Configuration,
 ) fosite.OAuth2Provider {
-	oauthConfig := &fosite.Config{
+	return FositeOauth2HelperWithFormPostTemplate(
+		oauthStore,
+		issuer,
+		hmacSecretOfLengthAtLeast32Func,
+		jwksProvider,
+		timeoutsConfiguration,
+		formposthtml.DefaultPage(),
+	)
+}
+
+// FositeOauth2HelperWithFormPostTemplate is the same as FositeOauth2Helper, except that it renders authorization
+// responses for requests which have response_mode=form_post using the template returned by the given provider for
+// each request, e.g. a branded template in the language of the request.
+func FositeOauth2HelperWithFormPostTemplate(
+	oauthStore any,
+	issuer string,
+	hmacSecretOfLengthAtLeast32Func func() []byte,
+	jwksProvider jwks.DynamicJWKSProvider,
+	timeoutsConfiguration timeouts.Configuration,
+	formPostHTMLTemplateProvider fosite.FormPostHTMLTemplateProvider,
+) fosite.OAuth2Provider {
+	oauthConfig := fositeConfig(issuer, timeoutsConfiguration)
+
+	oAuth2Provider := compose.Compose(
+		oauthConfig,
+		oauthStore,
+		&compose.CommonStrategy{
+			// Issue JWT access tokens to the clients which opted into them, and HMAC access tokens to all other clients.
+			CoreStrategy: strategy.NewDynamicOauth2JWTStrategy(
+				oauthConfig,
+				// Note that Fosite requires the HMAC secret to be at least 32 bytes.
+				strategy.NewDynamicOauth2HMACStrategy(oauthConfig, hmacSecretOfLengthAtLeast32Func),
+				jwksProvider,
+			),
+			RFC8628CodeStrategy:        strategy.NewDynamicDeviceStrategy(oauthConfig, hmacSecretOfLengthAtLeast32Func),
+			OpenIDConnectTokenStrategy: strategy.NewDynamicOpenIDConnectECDSAStrategy(oauthConfig, jwksProvider),
+		},
+		compose.OAuth2AuthorizeExplicitFactory,
+		compose.OAuth2RefreshTokenGrantFactory,
+		// handle the "client_credentials" grant type, for which the token endpoint handler starts the session
+		compose.OAuth2ClientCredentialsGrantFactory,
+		// Use a custom factory to allow selective overrides of the ID token lifespan during authcode exchange.
+		idtokenlifespan.OpenIDConnectExplicitFactory,
+		// Use a custom factory to allow selective overrides of the ID token lifespan during refresh.
+		idtokenlifespan.OpenIDConnectRefreshFactory,
+		compose.OAuth2PKCEFactory,
+		tokenexchange.HandlerFactory, // handle the "urn:ietf:params:oauth:grant-type:token-exchange" grant type
+		compose.RFC8628DeviceFactory, // handle the device authorization endpoint
+		// handle the "urn:ietf:params:oauth:grant-type:device_code" grant type
+		compose.RFC8628DeviceAuthorizationTokenFactory,
+		// Use a custom factory to issue ID tokens for the device code grant from the device code session.
+		idtokenlifespan.OpenIDConnectDeviceFactory,
+		compose.OAuth2TokenRevocationFactory,    // handle the revocation endpoint
+		compose.OAuth2TokenIntrospectionFactory, // validate access tokens for the introspection endpoint
+		compose.PushedAuthorizeHandlerFactory,   // handle the pushed authorization request endpoint
+	)
+
+	// Add support for the tls_client_auth client authentication method to fosite's default client authentication,
+	// which already supports the client_secret_basic and private_key_jwt client authentication methods.
+	fositeProvider := oAuth2Provider.(*fosite.Fosite)
+	oauthConfig.ClientAuthenticationStrategy = clientregistry.NewClientAuthenticationStrategy(
+		fositeProvider.Store,
+		fositeProvider.DefaultClientAuthenticationStrategy,
+	)
+
+	// Fosite renders form_post responses using the template from its own config, so overwrite its config
+	// with a wrapper which chooses the template for each request.
+	fositeProvider.Config = &formPostTemplateConfig{Config: oauthConfig, templateProvider: formPostHTMLTemplateProvider}
+
+	return oAuth2Provider
+}
+
+var _ fosite.FormPostHTMLTemplateProvider = (*formPostTemplateConfig)(nil)
+
+type formPostTemplateConfig struct {
+	*fosite.Config
+	templateProvider fosite.FormPostHTMLTemplateProvider
+}
+
+func (c *formPostTemplateConfig) GetFormPostHTMLTemplate(ctx context.Context) *template.Template {
+	return c.templateProvider.GetFormPostHTMLTemplate(ctx)
+}
+
+// clientJWKSFetcherStrategy fetches and caches the JWKS of clients which use the private_key_jwt client
+// authentication method with a jwks_uri. It is shared by all FederationDomains, because it owns a cache
+// which should not be created again each time that the FederationDomains are reloaded.
+var clientJWKSFetcherStrategy = sync.OnceValue(func() fosite.JWKSFetcherStrategy { //nolint:gochecknoglobals
+	httpClient := phttp.Default(nil)
+	httpClient.Timeout = 30 * time.Second
+
+	client := retryablehttp.NewClient()
+	client.HTTPClient = httpClient
+	client.Logger = nil
+	client.RetryMax = 1
+
+	return fosite.NewDefaultJWKSFetcherStrategy(fosite.JWKSFetcherWithHTTPClient(client))
+})
+
+// DeviceStrategy returns the same strategy for device codes and user codes that is used by FositeOauth2Helper,
+// for use by the user code entry page, which needs to find device code sessions by their user codes.
+func DeviceStrategy(
+	issuer string,
+	hmacSecretOfLengthAtLeast32Func func() []byte,
+	timeoutsConfiguration timeouts.Configuration,
+) rfc8628.RFC8628CodeStrategy {
+	return strategy.NewDynamicDeviceStrategy(fositeConfig(issuer, timeoutsConfiguration), hmacSecretOfLengthAtLeast32Func)
+}
+
+func fositeConfig(issuer string, timeoutsConfiguration timeouts.Configuration) *fosite.Config {
+	return &fosite.Config{
 		IDTokenIssuer: issuer,
 
 		AuthorizeCodeLifespan: timeoutsConfiguration.AuthorizeCodeLifespan,
@@ -245,9 +501,33 @@ func FositeOauth2Helper(
 		AccessTokenLifespan:   timeoutsConfiguration.AccessTokenLifespan,
 		RefreshTokenLifespan:  timeoutsConfiguration.RefreshTokenLifespan,
 
+		DeviceAndUserCodeLifespan:      timeoutsConfiguration.DeviceAndUserCodeLifespan,
+		DeviceAuthTokenPollingInterval: timeoutsConfiguration.DeviceAuthorizationPollingInterval,
+		DeviceVerificationURL:          issuer + DeviceVerificationEndpointPath,
+
+		// Pushed authorization requests are optional, unless the OIDCClient requires them.
+		PushedAuthorizeRequestURIPrefix: PushedAuthorizationRequestURIPrefix,
+		PushedAuthorizeContextLifespan:  timeoutsConfiguration.PushedAuthorizationRequestLifespan,
+		IsPushedAuthorizeEnforced:       false,
+
+		// Client assertions of the private_key_jwt client authentication method must have the token endpoint as
+		// their audience, as described in https://openid.net/specs/openid-connect-core-1_0.html#ClientAuthentication.
+		TokenURL:            issuer + TokenEndpointPath,
+		JWKSFetcherStrategy: clientJWKSFetcherStrategy(),
+
+		// User codes are typed by the end user, so use the character set suggested by
+		// https://datatracker.ietf.org/doc/html/rfc8628#section-6.1, which avoids vowels to avoid
+		// accidentally spelling words, and avoids characters which are easily confused with each other.
+		// Eight of these characters provide about 34.5 bits of entropy.
+		UserCodeLength:  UserCodeLength,
+		UserCodeSymbols: []rune(UserCodeSymbols),
+
 		ScopeStrategy: fosite.ExactScopeStrategy,
 		EnforcePKCE:   true,
 
+		// Only access tokens may be introspected. Refresh tokens are only ever sent to the token endpoint.
+		DisableRefreshTokenValidation: true,
+
 		// "offline_access" as per https://openid.net/specs/openid-connect-core-1_0.html#OfflineAccess
 		RefreshTokenScopes: []string{oidcapi.ScopeOfflineAccess},
 
@@ -267,26 +547,6 @@ func FositeOauth2Helper(
 		// defaults to using BCrypt when nil
 		ClientSecretsHasher: nil,
 	}
-
-	oAuth2Provider := compose.Compose(
-		oauthConfig,
-		oauthStore,
-		&compose.CommonStrategy{
-			// Note that Fosite requires the HMAC secret to be at least 32 bytes.
-			CoreStrategy:               strategy.NewDynamicOauth2HMACStrategy(oauthConfig, hmacSecretOfLengthAtLeast32Func),
-			OpenIDConnectTokenStrategy: strategy.NewDynamicOpenIDConnectECDSAStrategy(oauthConfig, jwksProvider),
-		},
-		compose.OAuth2AuthorizeExplicitFactory,
-		compose.OAuth2RefreshTokenGrantFactory,
-		// Use a custom factory to allow selective overrides of the ID token lifespan during authcode exchange.
-		idtokenlifespan.OpenIDConnectExplicitFactory,
-		// Use a custom factory to allow selective overrides of the ID token lifespan during refresh.
-		idtokenlifespan.OpenIDConnectRefreshFactory,
-		compose.OAuth2PKCEFactory,
-		tokenexchange.HandlerFactory, // handle the "urn:ietf:params:oauth:grant-type:token-exchange" grant type
-	)
-
-	return oAuth2Provider
 }
 
 // FositeErrorForLog generates a list of information about the provided Fosite error that can be
@@ -317,14 +577,14 @@ func FositeErrorForLog(err error) []any {
 	return keysAndValues
 }
 
-func GrantScopeIfRequested(authorizeRequester fosite.AuthorizeRequester, scopeName string) {
-	if ScopeWasRequested(authorizeRequester, scopeName) {
-		authorizeRequester.GrantScope(scopeName)
+func GrantScopeIfRequested(requester fosite.Requester, scopeName string) {
+	if ScopeWasRequested(requester, scopeName) {
+		requester.GrantScope(scopeName)
 	}
 }
 
-func ScopeWasRequested(authorizeRequester fosite.AuthorizeRequester, scopeName string) bool {
-	for _, scope := range authorizeRequester.GetRequestedScopes() {
+func ScopeWasRequested(requester fosite.Requester, scopeName string) bool {
+	for _, scope := range requester.GetRequestedScopes() {
 		if scope == scopeName {
 			return true
 		}
@@ -333,7 +593,7 @@ func ScopeWasRequested(authorizeRequester fosite.AuthorizeRequester, scopeName s
 }
 
 func ReadStateParamAndValidateCSRFCookie(r *http.Request, cookieDecoder Decoder, stateDecoder Decoder) (stateparam.Encoded, *UpstreamStateParamData, error) {
-	csrfValue, err := readCSRFCookie(r, cookieDecoder)
+	csrfValue, err := ReadCSRFCookie(r, cookieDecoder)
 	if err != nil {
 		return "", nil, err
 	}
@@ -351,7 +611,8 @@ func ReadStateParamAndValidateCSRFCookie(r *http.Request, cookieDecoder Decoder,
 	return stateparam.Encoded(encodedState), decodedState, nil
 }
 
-func readCSRFCookie(r *http.Request, cookieDecoder Decoder) (csrftoken.CSRFToken, error) {
+// ReadCSRFCookie reads and decodes the CSRF cookie from the request. Returns an error when it is missing or invalid.
+func ReadCSRFCookie(r *http.Request, cookieDecoder Decoder) (csrftoken.CSRFToken, error) {
 	receivedCSRFCookie, err := r.Cookie(CSRFCookieName)
 	if err != nil {
 		// Error means that the cookie was not found
@@ -367,8 +628,86 @@ func readCSRFCookie(r *http.Request, cookieDecoder Decoder) (csrftoken.CSRFToken
 	return csrfFromCookie, nil
 }
 
+// AddCSRFSetCookieHeader adds a Set-Cookie header to the response which holds the encoded CSRF value.
+func AddCSRFSetCookieHeader(w http.ResponseWriter, csrfValue csrftoken.CSRFToken, codec Encoder) error {
+	encodedCSRFValue, err := codec.Encode(CSRFCookieEncodingName, csrfValue)
+	if err != nil {
+		return fmt.Errorf("error encoding CSRF cookie: %w", err)
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		// Because of the other settings below, this value can only be known by the end user's browser, not by other sites.
+		Value: encodedCSRFValue,
+		// Name starting with "__Host-" makes the cookie domain-locked (subdomains cannot set this cookie).
+		Name: CSRFCookieName,
+		// This cookie can't be accessed by JavaScript.
+		HttpOnly: true,
+		// Okay for requests from other sites to cause the user's browser to send this cookie back to this site,
+		// for allowing response_mode=form_post, in which an upstream IDP needs to host a web form which POSTs back
+		// to the Supervisor's callback endpoint. Note that this allows a malicious 3rd party site to cause the user's
+		// browser to include this cookie on a request to the Supervisor. However, there is no way for 3rd party sites
+		// to create the corresponding state param to include on a callback request to the Supervisor to cause that
+		// callback request be allowed by the Supervisor's callback endpoint. That state param must include this cookie's
+		// value. A 3rd party site cannot receive this cookie (and therefore cannot know its value), and even if it somehow
+		// did learn its value, it could not sign the state param (cannot know the signing key for state params, which never
+		// leaves the Supervisor server). So although a 3rd party site could cause the user's cookie to be sent, that
+		// request will never be considered acceptable by the Supervisor.
+		// Note that SameSite=None was created in a 2019 draft standard, so it requires modern browsers to work.
+		// See https://datatracker.ietf.org/doc/html/draft-west-cookie-incrementalism-00.
+		SameSite: http.SameSiteNoneMode,
+		// This cookie may only be sent via HTTPS (required for domain-locked cookies).
+		Secure: true,
+		// Sending this cookie to any path of this server is acceptable (required for domain-locked cookies).
+		Path: "/",
+		// Note that we do not set "Domain", so this cookie should not be sent to any subdomains (required for domain-locked cookies).
+		// Also note that we do not set "Expires" or "MaxAge", so the client may keep the cookie as long as it likes,
+		// which prevents the cookie from expiring during login flows.
+	})
+
+	return nil
+}
+
+// AddUpstreamStateSetCookieHeader adds a Set-Cookie header to the response which holds the encoded upstream state
+// param, for upstreams which are only sent the RelayStateKey of the state param instead of the whole state param.
+func AddUpstreamStateSetCookieHeader(w http.ResponseWriter, encodedState stateparam.Encoded) {
+	http.SetCookie(w, &http.Cookie{
+		// The state param is already signed and encrypted, so it does not need to be encoded again.
+		Value: encodedState.String(),
+		// See AddCSRFSetCookieHeader for the reasons behind the rest of these settings. In particular, the upstream
+		// sends the user's browser back to the callback endpoint using a form POST, which requires SameSite=None.
+		Name:     UpstreamStateCookieNamePrefix + encodedState.RelayStateKey(),
+		HttpOnly: true,
+		SameSite: http.SameSiteNoneMode,
+		Secure:   true,
+		Path:     "/",
+		MaxAge:   int(UpstreamStateCookieLifespan.Seconds()),
+	})
+}
+
+// AddUpstreamStateDeleteCookieHeader adds a Set-Cookie header to the response which deletes the upstream state
+// cookie which was identified by the relayStateKey, since each state param may only be used once.
+func AddUpstreamStateDeleteCookieHeader(w http.ResponseWriter, relayStateKey string) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     UpstreamStateCookieNamePrefix + relayStateKey,
+		HttpOnly: true,
+		SameSite: http.SameSiteNoneMode,
+		Secure:   true,
+		Path:     "/",
+		MaxAge:   -1,
+	})
+}
+
 func readStateParam(r *http.Request, stateDecoder Decoder) (string, *UpstreamStateParamData, error) {
 	encodedState := r.FormValue("state")
+	if relayStateKey := r.FormValue("RelayState"); encodedState == "" && relayStateKey != "" {
+		// SAML upstreams return the RelayStateKey of the state param to the callback endpoint as the SAML
+		// "RelayState" param, and the state param itself was kept in a cookie.
+		stateCookie, err := r.Cookie(UpstreamStateCookieNamePrefix + relayStateKey)
+		if err != nil {
+			return "", nil, httperr.Wrap(http.StatusBadRequest, "state cookie not found", err)
+		}
+		encodedState = stateCookie.Value
+	}
 
 	if encodedState == "" {
 		return "", nil, httperr.New(http.StatusBadRequest, "state param not found")
@@ -377,7 +716,7 @@ func readStateParam(r *http.Request, stateDecoder Decoder) (string, *UpstreamSta
 	var state UpstreamStateParamData
 	if err := stateDecoder.Decode(
 		UpstreamStateParamEncodingName,
-		r.FormValue("state"),
+		encodedState,
 		&state,
 	); err != nil {
 		return "", nil, httperr.New(http.StatusBadRequest, "error reading state")
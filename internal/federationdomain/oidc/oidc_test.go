@@ -1,16 +1,25 @@
-// Copyright 2024 the Pinniped contributors. All Rights Reserved.
+// Copyright 2024-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package oidc
 
 import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/gorilla/securecookie"
 	"github.com/ory/fosite"
 	"github.com/stretchr/testify/require"
 
 	"go.pinniped.dev/internal/federationdomain/clientregistry"
+	"go.pinniped.dev/internal/federationdomain/csrftoken"
+	"go.pinniped.dev/internal/federationdomain/stateparam"
+	"go.pinniped.dev/internal/federationdomain/timeouts"
+	"go.pinniped.dev/internal/psession"
 )
 
 func TestDefaultLifespans(t *testing.T) {
@@ -23,32 +32,193 @@ func TestDefaultLifespans(t *testing.T) {
 	require.Equal(t, 9*time.Hour, c.RefreshTokenLifespan)
 }
 
+func TestFederationDomainSessionPolicyLifespans(t *testing.T) {
+	c := OIDCTimeoutsConfiguration(timeouts.SessionPolicy{
+		RefreshTokenLifespan: 4 * time.Hour,
+		AccessTokenLifespan:  5 * time.Minute,
+	})
+
+	require.Equal(t, 10*time.Minute, c.AuthorizeCodeLifespan)
+	require.Equal(t, 5*time.Minute, c.AccessTokenLifespan)
+	require.Equal(t, 2*time.Minute, c.IDTokenLifespan)
+	require.Equal(t, 4*time.Hour, c.RefreshTokenLifespan)
+	require.Equal(t, 4*time.Hour+10*time.Minute, c.AuthorizationCodeSessionStorageLifetime(nil))
+	require.Equal(t, 4*time.Hour+5*time.Minute, c.RefreshTokenSessionStorageLifetime(nil))
+
+	// A shorter idle timeout takes precedence over the refresh token lifespan.
+	c = OIDCTimeoutsConfiguration(timeouts.SessionPolicy{
+		RefreshTokenLifespan: 4 * time.Hour,
+		IdleTimeout:          time.Hour,
+	})
+	require.Equal(t, time.Hour, c.RefreshTokenLifespan)
+	require.Equal(t, time.Hour+2*time.Minute, c.AccessTokenSessionStorageLifetime(nil))
+}
+
 func TestStorageLifetimes(t *testing.T) {
 	c := DefaultOIDCTimeoutsConfiguration()
 
-	// These are currently hard-coded.
+	// These are the defaults when neither the FederationDomain nor the client configure a session policy.
 	require.Equal(t, 9*time.Hour+10*time.Minute, c.AuthorizationCodeSessionStorageLifetime(nil))
 	require.Equal(t, 11*time.Minute, c.PKCESessionStorageLifetime(nil))
 	require.Equal(t, 11*time.Minute, c.OIDCSessionStorageLifetime(nil))
+	require.Equal(t, 6*time.Minute, c.PushedAuthorizationRequestSessionStorageLifetime(nil))
 	require.Equal(t, 9*time.Hour+2*time.Minute, c.AccessTokenSessionStorageLifetime(nil))
 	require.Equal(t, 9*time.Hour+2*time.Minute, c.RefreshTokenSessionStorageLifetime(nil))
 }
 
-func TestOverrideDefaultAccessTokenLifespan(t *testing.T) {
+func TestStorageLifetimesWithClientSessionPolicy(t *testing.T) {
 	c := DefaultOIDCTimeoutsConfiguration()
+	requester := &fosite.Request{
+		Client: &clientregistry.Client{
+			SessionPolicy: timeouts.SessionPolicy{RefreshTokenLifespan: time.Hour, AccessTokenLifespan: 10 * time.Minute},
+		},
+	}
 
-	// We are not yet overriding access token lifetimes.
-	newLifespan, doOverride := c.OverrideDefaultAccessTokenLifespan(nil)
-	require.Equal(t, false, doOverride)
-	require.Equal(t, time.Duration(0), newLifespan)
+	require.Equal(t, time.Hour+10*time.Minute, c.AuthorizationCodeSessionStorageLifetime(requester))
+	require.Equal(t, time.Hour+10*time.Minute, c.DeviceCodeSessionStorageLifetime(requester))
+	require.Equal(t, time.Hour+10*time.Minute, c.AccessTokenSessionStorageLifetime(requester))
+	require.Equal(t, time.Hour+10*time.Minute, c.RefreshTokenSessionStorageLifetime(requester))
+}
+
+func TestOverrideDefaultAccessTokenAndRefreshTokenLifespans(t *testing.T) {
+	sessionWithAuthTime := func(authTime time.Time) *psession.PinnipedSession {
+		session := psession.NewPinnipedSession()
+		session.IDTokenClaims().AuthTime = authTime
+		return session
+	}
+
+	tests := []struct {
+		name                    string
+		federationDomainPolicy  timeouts.SessionPolicy
+		accessRequest           fosite.AccessRequester
+		wantAccessOverride      bool
+		wantAccessLifespan      time.Duration
+		wantRefreshOverride     bool
+		wantRefreshLifespan     time.Duration
+		wantLifespanApproximate bool
+	}{
+		{
+			name:          "there is no request, so the defaults are not overridden",
+			accessRequest: nil,
+		},
+		{
+			name: "neither the FederationDomain nor the client configure a session policy",
+			accessRequest: &fosite.AccessRequest{Request: fosite.Request{
+				Client:  &clientregistry.Client{},
+				Session: sessionWithAuthTime(time.Now()),
+			}},
+		},
+		{
+			name:                   "the FederationDomain configures lifespans, which are the defaults for the FederationDomain, so there is no override",
+			federationDomainPolicy: timeouts.SessionPolicy{AccessTokenLifespan: 5 * time.Minute, RefreshTokenLifespan: time.Hour},
+			accessRequest: &fosite.AccessRequest{Request: fosite.Request{
+				Client:  &clientregistry.Client{},
+				Session: sessionWithAuthTime(time.Now()),
+			}},
+		},
+		{
+			name:                   "the client overrides the lifespans of the FederationDomain",
+			federationDomainPolicy: timeouts.SessionPolicy{AccessTokenLifespan: 5 * time.Minute, RefreshTokenLifespan: time.Hour},
+			accessRequest: &fosite.AccessRequest{Request: fosite.Request{
+				Client: &clientregistry.Client{
+					SessionPolicy: timeouts.SessionPolicy{AccessTokenLifespan: 10 * time.Minute, RefreshTokenLifespan: 2 * time.Hour},
+				},
+				Session: sessionWithAuthTime(time.Now()),
+			}},
+			wantAccessOverride:  true,
+			wantAccessLifespan:  10 * time.Minute,
+			wantRefreshOverride: true,
+			wantRefreshLifespan: 2 * time.Hour,
+		},
+		{
+			name: "the client configures an idle timeout which is shorter than the refresh token lifespan",
+			accessRequest: &fosite.AccessRequest{Request: fosite.Request{
+				Client: &clientregistry.Client{
+					SessionPolicy: timeouts.SessionPolicy{IdleTimeout: 30 * time.Minute},
+				},
+				Session: sessionWithAuthTime(time.Now()),
+			}},
+			wantRefreshOverride: true,
+			wantRefreshLifespan: 30 * time.Minute,
+		},
+		{
+			name:                   "the session will reach its maximum age before the tokens would expire, so the lifespans are shortened",
+			federationDomainPolicy: timeouts.SessionPolicy{MaxSessionAge: 10 * time.Hour},
+			accessRequest: &fosite.AccessRequest{Request: fosite.Request{
+				Client:  &clientregistry.Client{},
+				Session: sessionWithAuthTime(time.Now().Add(-10*time.Hour + time.Minute)),
+			}},
+			wantAccessOverride:      true,
+			wantAccessLifespan:      time.Minute,
+			wantRefreshOverride:     true,
+			wantRefreshLifespan:     time.Minute,
+			wantLifespanApproximate: true,
+		},
+		{
+			name: "the client configures a maximum session age which has already been reached",
+			accessRequest: &fosite.AccessRequest{Request: fosite.Request{
+				Client: &clientregistry.Client{
+					SessionPolicy: timeouts.SessionPolicy{MaxSessionAge: time.Hour},
+				},
+				Session: sessionWithAuthTime(time.Now().Add(-2 * time.Hour)),
+			}},
+			wantAccessOverride:  true,
+			wantAccessLifespan:  0,
+			wantRefreshOverride: true,
+			wantRefreshLifespan: 0,
+		},
+		{
+			name:                   "the session has no auth time, so the maximum session age cannot be applied",
+			federationDomainPolicy: timeouts.SessionPolicy{MaxSessionAge: time.Hour},
+			accessRequest: &fosite.AccessRequest{Request: fosite.Request{
+				Client:  &clientregistry.Client{},
+				Session: psession.NewPinnipedSession(),
+			}},
+		},
+		{
+			name: "the client is not the expected data type (which shouldn't really happen), so it is assumed to not override the lifespans",
+			accessRequest: &fosite.AccessRequest{Request: fosite.Request{
+				Client:  &fosite.DefaultClient{},
+				Session: sessionWithAuthTime(time.Now()),
+			}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			c := OIDCTimeoutsConfiguration(tt.federationDomainPolicy)
+
+			newAccessLifespan, doAccessOverride := c.OverrideDefaultAccessTokenLifespan(tt.accessRequest)
+			newRefreshLifespan, doRefreshOverride := c.OverrideDefaultRefreshTokenLifespan(tt.accessRequest)
+			require.Equal(t, tt.wantAccessOverride, doAccessOverride)
+			require.Equal(t, tt.wantRefreshOverride, doRefreshOverride)
+			if tt.wantLifespanApproximate {
+				require.InDelta(t, tt.wantAccessLifespan, newAccessLifespan, float64(5*time.Second))
+				require.InDelta(t, tt.wantRefreshLifespan, newRefreshLifespan, float64(5*time.Second))
+			} else {
+				require.Equal(t, tt.wantAccessLifespan, newAccessLifespan)
+				require.Equal(t, tt.wantRefreshLifespan, newRefreshLifespan)
+			}
+		})
+	}
 }
 
 func TestOverrideIDTokenLifespan(t *testing.T) {
+	sessionWithAuthTime := func(authTime time.Time) *psession.PinnipedSession {
+		session := psession.NewPinnipedSession()
+		session.IDTokenClaims().AuthTime = authTime
+		return session
+	}
+
 	tests := []struct {
-		name          string
-		accessRequest fosite.AccessRequester
-		wantOverride  bool
-		wantLifespan  time.Duration
+		name                    string
+		federationDomainPolicy  timeouts.SessionPolicy
+		accessRequest           fosite.AccessRequester
+		wantOverride            bool
+		wantLifespan            time.Duration
+		wantLifespanApproximate bool
 	}{
 		{
 			name: "the client does not override the default ID token lifespan",
@@ -89,6 +259,65 @@ func TestOverrideIDTokenLifespan(t *testing.T) {
 			wantOverride: false,
 			wantLifespan: 0,
 		},
+		{
+			name:                   "the client overrides the default ID token lifespan with a lifespan which is longer than the remaining session age, so it is shortened",
+			federationDomainPolicy: timeouts.SessionPolicy{MaxSessionAge: 10 * time.Hour},
+			accessRequest: &fosite.AccessRequest{
+				GrantTypes: fosite.Arguments{"refresh_token"},
+				Request: fosite.Request{
+					Client: &clientregistry.Client{
+						IDTokenLifetimeConfiguration: time.Hour,
+					},
+					Session: sessionWithAuthTime(time.Now().Add(-10*time.Hour + 5*time.Minute)),
+				},
+			},
+			wantOverride:            true,
+			wantLifespan:            5 * time.Minute,
+			wantLifespanApproximate: true,
+		},
+		{
+			name:                   "the client overrides the default ID token lifespan with a lifespan which is shorter than the remaining session age",
+			federationDomainPolicy: timeouts.SessionPolicy{MaxSessionAge: 10 * time.Hour},
+			accessRequest: &fosite.AccessRequest{
+				GrantTypes: fosite.Arguments{"refresh_token"},
+				Request: fosite.Request{
+					Client: &clientregistry.Client{
+						IDTokenLifetimeConfiguration: time.Hour,
+					},
+					Session: sessionWithAuthTime(time.Now()),
+				},
+			},
+			wantOverride: true,
+			wantLifespan: time.Hour,
+		},
+		{
+			name: "the default ID token lifespan is longer than the remaining session age of the client's session policy, so it is shortened",
+			accessRequest: &fosite.AccessRequest{
+				GrantTypes: fosite.Arguments{"refresh_token"},
+				Request: fosite.Request{
+					Client: &clientregistry.Client{
+						SessionPolicy: timeouts.SessionPolicy{MaxSessionAge: time.Hour},
+					},
+					Session: sessionWithAuthTime(time.Now().Add(-time.Hour + 30*time.Second)),
+				},
+			},
+			wantOverride:            true,
+			wantLifespan:            30 * time.Second,
+			wantLifespanApproximate: true,
+		},
+		{
+			name:                   "the session has already reached its maximum age, so the ID token of a token exchange has no lifespan left",
+			federationDomainPolicy: timeouts.SessionPolicy{MaxSessionAge: time.Hour},
+			accessRequest: &fosite.AccessRequest{
+				GrantTypes: fosite.Arguments{"urn:ietf:params:oauth:grant-type:token-exchange"},
+				Request: fosite.Request{
+					Client:  &clientregistry.Client{},
+					Session: sessionWithAuthTime(time.Now().Add(-2 * time.Hour)),
+				},
+			},
+			wantOverride: true,
+			wantLifespan: 0,
+		},
 		{
 			name: "the client is not the expected data type (which shouldn't really happen), so it is assumed to not override the ID token lifespan",
 			accessRequest: &fosite.AccessRequest{
@@ -106,11 +335,75 @@ func TestOverrideIDTokenLifespan(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			t.Parallel()
 
-			c := DefaultOIDCTimeoutsConfiguration()
+			c := OIDCTimeoutsConfiguration(tt.federationDomainPolicy)
 
 			newLifespan, doOverride := c.OverrideDefaultIDTokenLifespan(tt.accessRequest)
 			require.Equal(t, tt.wantOverride, doOverride)
-			require.Equal(t, tt.wantLifespan, newLifespan)
+			if tt.wantLifespanApproximate {
+				require.InDelta(t, tt.wantLifespan, newLifespan, float64(5*time.Second))
+			} else {
+				require.Equal(t, tt.wantLifespan, newLifespan)
+			}
 		})
 	}
 }
+
+func TestReadStateParamAndValidateCSRFCookieFromRelayState(t *testing.T) {
+	stateCodec := securecookie.New([]byte("some-state-encoder-hash-key"), []byte("16-bytes-STATE01"))
+	cookieCodec := securecookie.New([]byte("some-cookie-encoder-hash-key"), []byte("16-bytes-COOKIE1"))
+
+	encodedCSRF, err := cookieCodec.Encode(CSRFCookieEncodingName, csrftoken.CSRFToken("some-csrf"))
+	require.NoError(t, err)
+	encodedStateValue, err := stateCodec.Encode(UpstreamStateParamEncodingName, UpstreamStateParamData{
+		UpstreamName:  "some-saml-idp",
+		UpstreamType:  "saml",
+		CSRFToken:     "some-csrf",
+		FormatVersion: UpstreamStateParamFormatVersion,
+	})
+	require.NoError(t, err)
+	encodedState := stateparam.Encoded(encodedStateValue)
+
+	// The state cookie is set when redirecting to the upstream.
+	rec := httptest.NewRecorder()
+	AddUpstreamStateSetCookieHeader(rec, encodedState)
+	stateCookies := rec.Result().Cookies()
+	require.Len(t, stateCookies, 1)
+	require.Equal(t, UpstreamStateCookieNamePrefix+encodedState.RelayStateKey(), stateCookies[0].Name)
+	require.Equal(t, 1800, stateCookies[0].MaxAge)
+	require.Equal(t, http.SameSiteNoneMode, stateCookies[0].SameSite)
+	require.True(t, stateCookies[0].Secure)
+	require.True(t, stateCookies[0].HttpOnly)
+
+	newCallbackRequest := func(relayState string, withStateCookie bool) *http.Request {
+		r := httptest.NewRequestWithContext(t.Context(), http.MethodPost, "/callback",
+			strings.NewReader(url.Values{"SAMLResponse": {"some-response"}, "RelayState": {relayState}}.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		r.AddCookie(&http.Cookie{Name: CSRFCookieName, Value: encodedCSRF})
+		if withStateCookie {
+			r.AddCookie(stateCookies[0])
+		}
+		return r
+	}
+
+	gotEncodedState, gotState, err := ReadStateParamAndValidateCSRFCookie(
+		newCallbackRequest(encodedState.RelayStateKey(), true), cookieCodec, stateCodec)
+	require.NoError(t, err)
+	require.Equal(t, encodedState, gotEncodedState)
+	require.Equal(t, "some-saml-idp", gotState.UpstreamName)
+
+	_, _, err = ReadStateParamAndValidateCSRFCookie(
+		newCallbackRequest(encodedState.RelayStateKey(), false), cookieCodec, stateCodec)
+	require.EqualError(t, err, "state cookie not found: http: named cookie not present")
+
+	_, _, err = ReadStateParamAndValidateCSRFCookie(
+		newCallbackRequest("some-other-key", true), cookieCodec, stateCodec)
+	require.EqualError(t, err, "state cookie not found: http: named cookie not present")
+
+	// The state cookie is deleted by the callback endpoint.
+	rec = httptest.NewRecorder()
+	AddUpstreamStateDeleteCookieHeader(rec, encodedState.RelayStateKey())
+	deletedCookies := rec.Result().Cookies()
+	require.Len(t, deletedCookies, 1)
+	require.Equal(t, stateCookies[0].Name, deletedCookies[0].Name)
+	require.Equal(t, -1, deletedCookies[0].MaxAge)
+}
@@ -1,12 +1,21 @@
-// Copyright 2022-2024 the Pinniped contributors. All Rights Reserved.
+// Copyright 2022-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package oidcclientvalidator
 
 import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"net/url"
 	"strings"
 
+	"github.com/go-jose/go-jose/v3"
 	"golang.org/x/crypto/bcrypt"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -20,16 +29,24 @@ import (
 const (
 	DefaultMinBcryptCost = 12
 
-	clientSecretExists     = "ClientSecretExists"
-	allowedGrantTypesValid = "AllowedGrantTypesValid"
-	allowedScopesValid     = "AllowedScopesValid"
+	clientSecretExists        = "ClientSecretExists"
+	allowedGrantTypesValid    = "AllowedGrantTypesValid"
+	allowedScopesValid        = "AllowedScopesValid"
+	clientAuthenticationValid = "ClientAuthenticationValid"
 
 	reasonMissingRequiredValue     = "MissingRequiredValue"
 	reasonNoClientSecretFound      = "NoClientSecretFound"
 	reasonInvalidClientSecretFound = "InvalidClientSecretFound"
+	reasonNotRequired              = "NotRequired"
+	reasonInvalidPrivateKeyJWT     = "InvalidPrivateKeyJWT"
+	reasonInvalidTLSClientAuth     = "InvalidTLSClientAuth"
 
-	allowedGrantTypesFieldName = "allowedGrantTypes"
-	allowedScopesFieldName     = "allowedScopes"
+	allowedGrantTypesFieldName       = "allowedGrantTypes"
+	allowedScopesFieldName           = "allowedScopes"
+	tokenEndpointAuthMethodFieldName = "tokenEndpointAuthMethod"
+	privateKeyJWTFieldName           = "privateKeyJWT"
+	tlsClientAuthFieldName           = "tlsClientAuth"
+	clientCredentialsFieldName       = "clientCredentials"
 )
 
 // Validate validates the OIDCClient and its corresponding client secret storage Secret.
@@ -38,11 +55,24 @@ const (
 // along with a slice of conditions containing more details, and the list of client secrets in the
 // case that the client was valid.
 func Validate(oidcClient *supervisorconfigv1alpha1.OIDCClient, secret *corev1.Secret, minBcryptCost int) (bool, []*metav1.Condition, []string) {
-	conds := make([]*metav1.Condition, 0, 3)
+	conds := make([]*metav1.Condition, 0, 4)
 
-	conds, clientSecrets := validateSecret(secret, conds, minBcryptCost)
+	var clientSecrets []string
+	if authMethod := TokenEndpointAuthMethod(oidcClient); authMethod == supervisorconfigv1alpha1.TokenEndpointAuthMethodClientSecretBasic {
+		conds, clientSecrets = validateSecret(secret, conds, minBcryptCost)
+	} else {
+		// Client secrets are not used by the other client authentication methods, so ignore any which may exist.
+		conds = append(conds, &metav1.Condition{
+			Type:    clientSecretExists,
+			Status:  metav1.ConditionTrue,
+			Reason:  reasonNotRequired,
+			Message: fmt.Sprintf("client secrets are not used when %q is %q", tokenEndpointAuthMethodFieldName, authMethod),
+		})
+		clientSecrets = []string{}
+	}
 	conds = validateAllowedGrantTypes(oidcClient, conds)
 	conds = validateAllowedScopes(oidcClient, conds)
+	conds = validateClientAuthentication(oidcClient, conds)
 
 	valid := true
 	for _, cond := range conds {
@@ -54,6 +84,22 @@ func Validate(oidcClient *supervisorconfigv1alpha1.OIDCClient, secret *corev1.Se
 	return valid, conds, clientSecrets
 }
 
+// ValidateAllowedGrantTypesAndScopes validates only the allowedGrantTypes and allowedScopes of the OIDCClient,
+// which is useful before the OIDCClient and its client secret storage Secret have been created. It returns a
+// message for each of them which is not valid, or an empty slice when both are valid.
+func ValidateAllowedGrantTypesAndScopes(oidcClient *supervisorconfigv1alpha1.OIDCClient) []string {
+	conds := validateAllowedGrantTypes(oidcClient, make([]*metav1.Condition, 0, 2))
+	conds = validateAllowedScopes(oidcClient, conds)
+
+	messages := []string{}
+	for _, cond := range conds {
+		if cond.Status != metav1.ConditionTrue {
+			messages = append(messages, cond.Message)
+		}
+	}
+	return messages
+}
+
 // validateAllowedScopes checks if allowedScopes is valid on the OIDCClient.
 func validateAllowedScopes(oidcClient *supervisorconfigv1alpha1.OIDCClient, conditions []*metav1.Condition) []*metav1.Condition {
 	m := make([]string, 0, 4)
@@ -96,11 +142,20 @@ func validateAllowedScopes(oidcClient *supervisorconfigv1alpha1.OIDCClient, cond
 
 // validateAllowedGrantTypes checks if allowedGrantTypes is valid on the OIDCClient.
 func validateAllowedGrantTypes(oidcClient *supervisorconfigv1alpha1.OIDCClient, conditions []*metav1.Condition) []*metav1.Condition {
-	m := make([]string, 0, 3)
+	m := make([]string, 0, 5)
 
-	if !allowedGrantTypesContains(oidcClient, oidcapi.GrantTypeAuthorizationCode) {
-		m = append(m, fmt.Sprintf("%q must always be included in %q",
-			oidcapi.GrantTypeAuthorizationCode, allowedGrantTypesFieldName))
+	if !allowedGrantTypesContains(oidcClient, oidcapi.GrantTypeAuthorizationCode) &&
+		!allowedGrantTypesContains(oidcClient, oidcapi.GrantTypeClientCredentials) {
+		m = append(m, fmt.Sprintf("%q or %q must be included in %q",
+			oidcapi.GrantTypeAuthorizationCode, oidcapi.GrantTypeClientCredentials, allowedGrantTypesFieldName))
+	}
+	if allowedGrantTypesContains(oidcClient, oidcapi.GrantTypeClientCredentials) && oidcClient.Spec.ClientCredentials == nil {
+		m = append(m, fmt.Sprintf("%q must be configured when %q is included in %q",
+			clientCredentialsFieldName, oidcapi.GrantTypeClientCredentials, allowedGrantTypesFieldName))
+	}
+	if !allowedGrantTypesContains(oidcClient, oidcapi.GrantTypeClientCredentials) && oidcClient.Spec.ClientCredentials != nil {
+		m = append(m, fmt.Sprintf("%q must be included in %q when %q is configured",
+			oidcapi.GrantTypeClientCredentials, allowedGrantTypesFieldName, clientCredentialsFieldName))
 	}
 	if allowedScopesContains(oidcClient, oidcapi.ScopeOfflineAccess) && !allowedGrantTypesContains(oidcClient, oidcapi.GrantTypeRefreshToken) {
 		m = append(m, fmt.Sprintf("%q must be included in %q when %q is included in %q",
@@ -207,6 +262,198 @@ func validateSecret(secret *corev1.Secret, conditions []*metav1.Condition, minBc
 	return conditions, storedClientSecrets
 }
 
+// TokenEndpointAuthMethod returns the client authentication method of the OIDCClient, taking the default into account.
+func TokenEndpointAuthMethod(oidcClient *supervisorconfigv1alpha1.OIDCClient) supervisorconfigv1alpha1.TokenEndpointAuthMethod {
+	if oidcClient.Spec.TokenEndpointAuthMethod == "" {
+		return supervisorconfigv1alpha1.TokenEndpointAuthMethodClientSecretBasic
+	}
+	return oidcClient.Spec.TokenEndpointAuthMethod
+}
+
+// validateClientAuthentication checks if the configuration of the client authentication method is valid on the OIDCClient.
+func validateClientAuthentication(oidcClient *supervisorconfigv1alpha1.OIDCClient, conditions []*metav1.Condition) []*metav1.Condition {
+	authMethod := TokenEndpointAuthMethod(oidcClient)
+	privateKeyJWT := oidcClient.Spec.PrivateKeyJWT
+	tlsClientAuth := oidcClient.Spec.TLSClientAuth
+
+	var reason string
+	m := make([]string, 0, 2)
+
+	switch authMethod {
+	case supervisorconfigv1alpha1.TokenEndpointAuthMethodPrivateKeyJWT:
+		if privateKeyJWT == nil {
+			m = append(m, fmt.Sprintf("%q must be configured when %q is %q", privateKeyJWTFieldName, tokenEndpointAuthMethodFieldName, authMethod))
+		} else if err := validatePrivateKeyJWT(privateKeyJWT); err != nil {
+			m = append(m, fmt.Sprintf("%q is invalid: %s", privateKeyJWTFieldName, err.Error()))
+		}
+		if tlsClientAuth != nil {
+			m = append(m, fmt.Sprintf("%q must not be configured when %q is %q", tlsClientAuthFieldName, tokenEndpointAuthMethodFieldName, authMethod))
+		}
+		reason = reasonInvalidPrivateKeyJWT
+	case supervisorconfigv1alpha1.TokenEndpointAuthMethodTLSClientAuth:
+		if tlsClientAuth == nil {
+			m = append(m, fmt.Sprintf("%q must be configured when %q is %q", tlsClientAuthFieldName, tokenEndpointAuthMethodFieldName, authMethod))
+		} else if err := validateTLSClientAuth(tlsClientAuth); err != nil {
+			m = append(m, fmt.Sprintf("%q is invalid: %s", tlsClientAuthFieldName, err.Error()))
+		}
+		if privateKeyJWT != nil {
+			m = append(m, fmt.Sprintf("%q must not be configured when %q is %q", privateKeyJWTFieldName, tokenEndpointAuthMethodFieldName, authMethod))
+		}
+		reason = reasonInvalidTLSClientAuth
+	default:
+		if privateKeyJWT != nil {
+			m = append(m, fmt.Sprintf("%q must not be configured when %q is %q", privateKeyJWTFieldName, tokenEndpointAuthMethodFieldName, authMethod))
+			reason = reasonInvalidPrivateKeyJWT
+		}
+		if tlsClientAuth != nil {
+			m = append(m, fmt.Sprintf("%q must not be configured when %q is %q", tlsClientAuthFieldName, tokenEndpointAuthMethodFieldName, authMethod))
+			reason = reasonInvalidTLSClientAuth
+		}
+	}
+
+	if len(m) == 0 {
+		conditions = append(conditions, &metav1.Condition{
+			Type:    clientAuthenticationValid,
+			Status:  metav1.ConditionTrue,
+			Reason:  conditionsutil.ReasonSuccess,
+			Message: fmt.Sprintf("client authentication method %q is valid", authMethod),
+		})
+	} else {
+		conditions = append(conditions, &metav1.Condition{
+			Type:    clientAuthenticationValid,
+			Status:  metav1.ConditionFalse,
+			Reason:  reason,
+			Message: strings.Join(m, "; "),
+		})
+	}
+
+	return conditions
+}
+
+func validatePrivateKeyJWT(privateKeyJWT *supervisorconfigv1alpha1.OIDCClientPrivateKeyJWT) error {
+	switch {
+	case privateKeyJWT.JWKS != "" && privateKeyJWT.JWKSURI != "":
+		return errors.New("only one of jwks or jwksURI may be configured")
+	case privateKeyJWT.JWKS != "":
+		_, err := ParseJWKS(privateKeyJWT.JWKS, PrivateKeyJWTSigningAlgorithm(privateKeyJWT))
+		return err
+	case privateKeyJWT.JWKSURI != "":
+		u, err := url.Parse(privateKeyJWT.JWKSURI)
+		if err != nil {
+			return fmt.Errorf("jwksURI could not be parsed: %w", err)
+		}
+		if u.Scheme != "https" || u.Host == "" {
+			return errors.New("jwksURI must be an https URL")
+		}
+		return nil
+	default:
+		return errors.New("one of jwks or jwksURI must be configured")
+	}
+}
+
+// PrivateKeyJWTSigningAlgorithm returns the JWS algorithm which must be used by the client assertions of the
+// OIDCClient, taking the default into account.
+func PrivateKeyJWTSigningAlgorithm(privateKeyJWT *supervisorconfigv1alpha1.OIDCClientPrivateKeyJWT) jose.SignatureAlgorithm {
+	if privateKeyJWT.SigningAlgorithm == "" {
+		return jose.RS256
+	}
+	return jose.SignatureAlgorithm(privateKeyJWT.SigningAlgorithm)
+}
+
+// ParseJWKS parses an inline JSON Web Key Set and checks that all of its keys are public keys which can be used
+// to verify client assertions which were signed using the given algorithm.
+func ParseJWKS(jwks string, alg jose.SignatureAlgorithm) (*jose.JSONWebKeySet, error) {
+	var keySet jose.JSONWebKeySet
+	if err := json.Unmarshal([]byte(jwks), &keySet); err != nil {
+		return nil, fmt.Errorf("jwks could not be parsed: %w", err)
+	}
+	if len(keySet.Keys) == 0 {
+		return nil, errors.New("jwks does not contain any keys")
+	}
+
+	for i, key := range keySet.Keys {
+		if err := validatePublicKey(key, alg); err != nil {
+			return nil, fmt.Errorf("jwks key at index %d: %w", i, err)
+		}
+	}
+
+	return &keySet, nil
+}
+
+func validatePublicKey(key jose.JSONWebKey, alg jose.SignatureAlgorithm) error {
+	if !key.Valid() {
+		return errors.New("key is not valid")
+	}
+	if !key.IsPublic() {
+		return errors.New("key must be a public key")
+	}
+	if key.Use != "sig" {
+		return errors.New(`key must have "use" set to "sig"`)
+	}
+	if key.Algorithm != "" && key.Algorithm != string(alg) {
+		return fmt.Errorf("key has algorithm %q but signingAlgorithm is %q", key.Algorithm, alg)
+	}
+
+	switch alg {
+	case jose.RS256, jose.RS384, jose.RS512, jose.PS256, jose.PS384, jose.PS512:
+		if _, ok := key.Key.(*rsa.PublicKey); !ok {
+			return fmt.Errorf("key must be an RSA key to be used with signingAlgorithm %q", alg)
+		}
+	case jose.ES256, jose.ES384, jose.ES512:
+		ecKey, ok := key.Key.(*ecdsa.PublicKey)
+		if !ok || ecKey.Curve != curveForAlgorithm(alg) {
+			return fmt.Errorf("key must be an EC key using curve %s to be used with signingAlgorithm %q", curveForAlgorithm(alg).Params().Name, alg)
+		}
+	default:
+		return fmt.Errorf("signingAlgorithm %q is not supported", alg)
+	}
+
+	return nil
+}
+
+func curveForAlgorithm(alg jose.SignatureAlgorithm) elliptic.Curve {
+	switch alg { //nolint:exhaustive // only the ECDSA algorithms are relevant here
+	case jose.ES384:
+		return elliptic.P384()
+	case jose.ES512:
+		return elliptic.P521()
+	default:
+		return elliptic.P256()
+	}
+}
+
+func validateTLSClientAuth(tlsClientAuth *supervisorconfigv1alpha1.OIDCClientTLSClientAuth) error {
+	if _, err := ParseCertificateAuthorityData(tlsClientAuth.CertificateAuthorityData); err != nil {
+		return err
+	}
+
+	identifiers := 0
+	for _, identifier := range []string{tlsClientAuth.SubjectDN, tlsClientAuth.SANDNS, tlsClientAuth.SANURI} {
+		if identifier != "" {
+			identifiers++
+		}
+	}
+	if identifiers != 1 {
+		return errors.New("exactly one of subjectDN, sanDNS, or sanURI must be configured")
+	}
+
+	return nil
+}
+
+// ParseCertificateAuthorityData parses the base64-encoded PEM bundle of certificate authorities which issue the
+// TLS client certificates of an OIDCClient.
+func ParseCertificateAuthorityData(certificateAuthorityData string) (*x509.CertPool, error) {
+	pemBytes, err := base64.StdEncoding.DecodeString(certificateAuthorityData)
+	if err != nil {
+		return nil, fmt.Errorf("certificateAuthorityData is not valid base64: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pemBytes) {
+		return nil, errors.New(`certificateAuthorityData does not contain any PEM certificates (PEM certificates must begin with "-----BEGIN CERTIFICATE-----")`)
+	}
+	return pool, nil
+}
+
 func allowedGrantTypesContains(haystack *supervisorconfigv1alpha1.OIDCClient, needle string) bool {
 	for _, hay := range haystack.Spec.AllowedGrantTypes {
 		if hay == supervisorconfigv1alpha1.GrantType(needle) {
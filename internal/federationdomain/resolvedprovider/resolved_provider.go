@@ -1,4 +1,4 @@
-// Copyright 2023-2024 the Pinniped contributors. All Rights Reserved.
+// Copyright 2023-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package resolvedprovider
@@ -92,6 +92,14 @@ type UpstreamAuthorizeRequestState struct {
 	Nonce             nonce.Nonce
 }
 
+// UpstreamStateCookieRequirer may be implemented by a FederationDomainResolvedIdentityProvider whose upstream cannot
+// carry the whole state param, e.g. because SAML limits its RelayState param to 80 bytes. Such identity providers send
+// only the RelayStateKey of the state param to their upstream, so the handlers which redirect to the upstream must
+// also keep the state param in a cookie using oidc.AddUpstreamStateSetCookieHeader.
+type UpstreamStateCookieRequirer interface {
+	RequiresUpstreamStateCookie() bool
+}
+
 type FederationDomainResolvedIdentityProvider interface {
 	// GetDisplayName returns the display name of this identity provider, as configured in the FederationDomain.
 	GetDisplayName() string
@@ -172,3 +180,16 @@ func ErrUpstreamRefreshError() *fosite.RFC6749Error {
 		CodeField:        http.StatusUnauthorized,
 	}
 }
+
+// UpstreamAuthenticationPolicyRejectedError is wrapped by the errors returned by the functions of a
+// FederationDomainResolvedIdentityProvider when the user's upstream authentication did not satisfy the
+// authentication policy configured on the FederationDomain for that identity provider.
+// Callers may use errors.As to detect it.
+type UpstreamAuthenticationPolicyRejectedError struct {
+	// Reason describes why the authentication was rejected. It is intended for logs, not for the end user.
+	Reason string
+}
+
+func (e *UpstreamAuthenticationPolicyRejectedError) Error() string {
+	return "rejected by upstream authentication policy: " + e.Reason
+}
@@ -51,7 +51,7 @@ func (p *FederationDomainResolvedGitHubIdentityProvider) GetIDPDiscoveryType() v
 }
 
 func (p *FederationDomainResolvedGitHubIdentityProvider) GetIDPDiscoveryFlows() []v1alpha1.IDPFlow {
-	return []v1alpha1.IDPFlow{v1alpha1.IDPFlowBrowserAuthcode}
+	return []v1alpha1.IDPFlow{v1alpha1.IDPFlowBrowserAuthcode, v1alpha1.IDPFlowDeviceCode}
 }
 
 func (p *FederationDomainResolvedGitHubIdentityProvider) GetTransforms() *idtransform.TransformationPipeline {
@@ -0,0 +1,418 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package resolvedgitlab
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ory/fosite"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+
+	idpv1alpha1 "go.pinniped.dev/generated/latest/apis/supervisor/idp/v1alpha1"
+	idpdiscoveryv1alpha1 "go.pinniped.dev/generated/latest/apis/supervisor/idpdiscovery/v1alpha1"
+	"go.pinniped.dev/internal/federationdomain/resolvedprovider"
+	"go.pinniped.dev/internal/federationdomain/upstreamprovider"
+	"go.pinniped.dev/internal/httputil/httperr"
+	"go.pinniped.dev/internal/psession"
+	"go.pinniped.dev/internal/setutil"
+	"go.pinniped.dev/internal/testutil/oidctestutil"
+	"go.pinniped.dev/internal/testutil/transformtestutil"
+	"go.pinniped.dev/internal/upstreamgitlab"
+	"go.pinniped.dev/pkg/oidcclient/pkce"
+)
+
+func TestFederationDomainResolvedGitLabIdentityProvider(t *testing.T) {
+	transforms := transformtestutil.NewRejectAllAuthPipeline(t)
+
+	provider := upstreamgitlab.New(upstreamgitlab.ProviderConfig{
+		Name:               "fake-provider-config",
+		ResourceUID:        "fake-resource-uid",
+		APIBaseURL:         "https://fake-api-host.com/api/v4/",
+		UsernameAttribute:  idpv1alpha1.GitLabUsernameID,
+		GroupNameAttribute: idpv1alpha1.GitLabUseGroupFullPathForGroupName,
+		AllowedGroups:      setutil.NewCaseInsensitiveSet("group1", "group2"),
+		HttpClient:         nil, // not needed yet for this test
+		//nolint:gosec // not a real credential
+		OAuth2Config: &oauth2.Config{
+			ClientID:     "fake-client-id",
+			ClientSecret: "fake-client-secret",
+			Scopes:       []string{"read_api"},
+			Endpoint: oauth2.Endpoint{
+				AuthURL:   "https://fake-authorization-url",
+				TokenURL:  "https://fake-token-url",
+				AuthStyle: oauth2.AuthStyleInParams,
+			},
+		},
+	})
+
+	subject := FederationDomainResolvedGitLabIdentityProvider{
+		DisplayName:         "fake-display-name",
+		Provider:            provider,
+		SessionProviderType: psession.ProviderTypeGitLab,
+		Transforms:          transforms,
+	}
+
+	require.Equal(t, "fake-display-name", subject.GetDisplayName())
+	require.Equal(t, provider, subject.GetProvider())
+	require.Equal(t, psession.ProviderTypeGitLab, subject.GetSessionProviderType())
+	require.Equal(t, idpdiscoveryv1alpha1.IDPTypeGitLab, subject.GetIDPDiscoveryType())
+	require.Equal(t, []idpdiscoveryv1alpha1.IDPFlow{idpdiscoveryv1alpha1.IDPFlowBrowserAuthcode, idpdiscoveryv1alpha1.IDPFlowDeviceCode}, subject.GetIDPDiscoveryFlows())
+	require.Equal(t, transforms, subject.GetTransforms())
+
+	originalCustomSession := &psession.CustomSessionData{
+		Username:         "fake-username",
+		UpstreamUsername: "fake-upstream-username",
+		GitLab:           &psession.GitLabSessionData{UpstreamRefreshToken: "fake-upstream-refresh-token"},
+	}
+	clonedCustomSession := subject.CloneIDPSpecificSessionDataFromSession(originalCustomSession)
+	require.Equal(t,
+		&psession.GitLabSessionData{UpstreamRefreshToken: "fake-upstream-refresh-token"},
+		clonedCustomSession,
+	)
+	require.NotSame(t, originalCustomSession.GitLab, clonedCustomSession)
+	require.Nil(t, subject.CloneIDPSpecificSessionDataFromSession(&psession.CustomSessionData{}))
+
+	customSessionToBeMutated := &psession.CustomSessionData{
+		Username:         "fake-username2",
+		UpstreamUsername: "fake-upstream-username2",
+	}
+	subject.ApplyIDPSpecificSessionDataToSession(customSessionToBeMutated, &psession.GitLabSessionData{UpstreamRefreshToken: "OTHER-upstream-refresh-token"})
+	require.Equal(t, &psession.CustomSessionData{
+		Username:         "fake-username2",
+		UpstreamUsername: "fake-upstream-username2",
+		GitLab:           &psession.GitLabSessionData{UpstreamRefreshToken: "OTHER-upstream-refresh-token"},
+	}, customSessionToBeMutated)
+
+	redirectURL, err := subject.UpstreamAuthorizeRedirectURL(
+		&resolvedprovider.UpstreamAuthorizeRequestState{
+			EncodedStateParam: "encodedStateParam12345",
+			PKCE:              "pkce6789",
+			Nonce:             "nonce1289",
+		},
+		"https://localhost/fake/path",
+	)
+	require.NoError(t, err)
+	require.Equal(t,
+		"https://fake-authorization-url?"+
+			"client_id=fake-client-id&"+
+			"code_challenge=3indzSdebyEpaWs9NNOPrtx0sw3z6VhUM9MPRjji134&"+
+			"code_challenge_method=S256&"+
+			"redirect_uri=https%3A%2F%2Flocalhost%2Ffake%2Fpath%2Fcallback&"+
+			"response_type=code&"+
+			"scope=read_api&"+
+			"state=encodedStateParam12345",
+		redirectURL,
+	)
+
+	_, _, err = subject.Login(context.Background(), "username", "password")
+	require.EqualError(t, err, "function Login not yet implemented for GitLab IDP")
+}
+
+func TestLoginFromCallback(t *testing.T) {
+	uniqueCtx := context.WithValue(context.Background(), "some-unique-key", "some-value") //nolint:staticcheck // okay to use string key for test
+
+	wantExchangeAuthcodeArgs := &oidctestutil.ExchangeGitLabAuthcodeArgs{
+		Ctx:              uniqueCtx,
+		Authcode:         "fake-authcode",
+		PKCECodeVerifier: "fake-pkce",
+		RedirectURI:      "https://fake-redirect-uri",
+	}
+	wantGetUserArgs := &oidctestutil.GetGitLabUserArgs{
+		Ctx:            uniqueCtx,
+		AccessToken:    "fake-access-token",
+		IDPDisplayName: "fake-display-name",
+	}
+	fakeToken := &oauth2.Token{AccessToken: "fake-access-token", RefreshToken: "fake-refresh-token"}
+
+	tests := []struct {
+		name     string
+		provider *oidctestutil.TestUpstreamGitLabIdentityProvider
+
+		wantGetUserCall    bool
+		wantIdentity       *resolvedprovider.Identity
+		wantExtras         *resolvedprovider.IdentityLoginExtras
+		wantErrMsg         string
+		wantErrResponseMsg string
+		wantErrStatusCode  int
+	}{
+		{
+			name: "happy path",
+			provider: oidctestutil.NewTestUpstreamGitLabIdentityProviderBuilder().
+				WithToken(fakeToken).
+				WithUser(&upstreamprovider.GitLabUser{
+					Username:          "fake-username",
+					Groups:            []string{"fake-group1", "fake-group2"},
+					DownstreamSubject: "https://fake-downstream-subject",
+				}).
+				Build(),
+			wantGetUserCall: true,
+			wantIdentity: &resolvedprovider.Identity{
+				UpstreamUsername:  "fake-username",
+				UpstreamGroups:    []string{"fake-group1", "fake-group2"},
+				DownstreamSubject: "https://fake-downstream-subject",
+				IDPSpecificSessionData: &psession.GitLabSessionData{
+					UpstreamRefreshToken: "fake-refresh-token",
+				},
+			},
+			wantExtras: &resolvedprovider.IdentityLoginExtras{},
+		},
+		{
+			name: "error while exchanging authcode",
+			provider: oidctestutil.NewTestUpstreamGitLabIdentityProviderBuilder().
+				WithAuthcodeExchangeError(errors.New("fake authcode exchange error")).
+				Build(),
+			wantGetUserCall:    false,
+			wantErrMsg:         "failed to exchange authcode using GitLab API: fake authcode exchange error",
+			wantErrResponseMsg: "Bad Gateway: failed to exchange authcode using GitLab API",
+			wantErrStatusCode:  http.StatusBadGateway,
+		},
+		{
+			name: "no refresh token returned by authcode exchange",
+			provider: oidctestutil.NewTestUpstreamGitLabIdentityProviderBuilder().
+				WithToken(&oauth2.Token{AccessToken: "fake-access-token"}).
+				Build(),
+			wantGetUserCall:    false,
+			wantErrMsg:         "GitLab API did not return a refresh token",
+			wantErrResponseMsg: "Bad Gateway: GitLab API did not return a refresh token",
+			wantErrStatusCode:  http.StatusBadGateway,
+		},
+		{
+			name: "generic error while getting user info",
+			provider: oidctestutil.NewTestUpstreamGitLabIdentityProviderBuilder().
+				WithToken(fakeToken).
+				WithGetUserError(errors.New("fake user info error")).
+				Build(),
+			wantGetUserCall:    true,
+			wantErrMsg:         "failed to get user info from GitLab API: fake user info error",
+			wantErrResponseMsg: "Unprocessable Entity: failed to get user info from GitLab API",
+			wantErrStatusCode:  http.StatusUnprocessableEntity,
+		},
+		{
+			name: "loginDenied error while getting user info",
+			provider: oidctestutil.NewTestUpstreamGitLabIdentityProviderBuilder().
+				WithToken(fakeToken).
+				WithGetUserError(upstreamprovider.NewGitLabLoginDeniedError("some login denied error")).
+				Build(),
+			wantGetUserCall:    true,
+			wantErrMsg:         `login denied due to configuration on GitLabIdentityProvider with display name "fake-display-name": some login denied error`,
+			wantErrResponseMsg: `Forbidden: login denied due to configuration on GitLabIdentityProvider with display name "fake-display-name": some login denied error`,
+			wantErrStatusCode:  http.StatusForbidden,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			subject := FederationDomainResolvedGitLabIdentityProvider{
+				DisplayName:         "fake-display-name",
+				Provider:            test.provider,
+				SessionProviderType: psession.ProviderTypeGitLab,
+				Transforms:          transformtestutil.NewRejectAllAuthPipeline(t),
+			}
+
+			identity, loginExtras, err := subject.LoginFromCallback(uniqueCtx,
+				"fake-authcode",
+				pkce.Code("fake-pkce"),
+				"nonce-will-be-ignored",
+				"https://fake-redirect-uri",
+			)
+
+			require.Equal(t, 1, test.provider.ExchangeAuthcodeCallCount())
+			require.Equal(t, wantExchangeAuthcodeArgs, test.provider.ExchangeAuthcodeArgs(0))
+
+			if test.wantGetUserCall {
+				require.Equal(t, 1, test.provider.GetUserCallCount())
+				require.Equal(t, wantGetUserArgs, test.provider.GetUserArgs(0))
+			} else {
+				require.Zero(t, test.provider.GetUserCallCount())
+			}
+
+			if test.wantErrResponseMsg == "" {
+				require.NoError(t, err)
+			} else {
+				require.Implements(t, (*httperr.Responder)(nil), err)
+				errAsResponder := err.(httperr.Responder)
+				rec := httptest.NewRecorder()
+				errAsResponder.Respond(rec)
+				require.Equal(t, test.wantErrStatusCode, rec.Code)
+				require.Equal(t, test.wantErrResponseMsg+"\n", rec.Body.String())
+
+				require.EqualError(t, errAsResponder, test.wantErrMsg)
+			}
+			require.Equal(t, test.wantExtras, loginExtras)
+			require.Equal(t, test.wantIdentity, identity)
+		})
+	}
+}
+
+func TestUpstreamRefresh(t *testing.T) {
+	uniqueCtx := context.WithValue(context.Background(), "some-unique-key", "some-value") //nolint:staticcheck // okay to use string key for test
+
+	initialIdentity := &resolvedprovider.Identity{
+		UpstreamUsername:       "initial-username",
+		UpstreamGroups:         []string{"initial-group1", "initial-group2"},
+		DownstreamSubject:      "https://fake-downstream-subject",
+		IDPSpecificSessionData: &psession.GitLabSessionData{UpstreamRefreshToken: "initial-refresh-token"},
+	}
+	refreshedToken := &oauth2.Token{AccessToken: "refreshed-access-token", RefreshToken: "refreshed-refresh-token"}
+
+	tests := []struct {
+		name     string
+		provider *oidctestutil.TestUpstreamGitLabIdentityProvider
+		identity *resolvedprovider.Identity
+
+		wantPerformRefreshCall bool
+		wantGetUserCall        bool
+		wantRefreshedIdentity  *resolvedprovider.RefreshedIdentity
+		wantWrappedErr         string
+	}{
+		{
+			name: "happy path",
+			provider: oidctestutil.NewTestUpstreamGitLabIdentityProviderBuilder().
+				WithName("fake-provider-name").
+				WithRefreshedToken(refreshedToken).
+				WithUser(&upstreamprovider.GitLabUser{
+					Username:          "refreshed-username",
+					Groups:            []string{"refreshed-group1", "refreshed-group2"},
+					DownstreamSubject: "https://fake-downstream-subject",
+				}).
+				Build(),
+			identity:               initialIdentity,
+			wantPerformRefreshCall: true,
+			wantGetUserCall:        true,
+			wantRefreshedIdentity: &resolvedprovider.RefreshedIdentity{
+				UpstreamUsername:       "refreshed-username",
+				UpstreamGroups:         []string{"refreshed-group1", "refreshed-group2"},
+				IDPSpecificSessionData: &psession.GitLabSessionData{UpstreamRefreshToken: "refreshed-refresh-token"},
+			},
+		},
+		{
+			name: "error while refreshing tokens",
+			provider: oidctestutil.NewTestUpstreamGitLabIdentityProviderBuilder().
+				WithName("fake-provider-name").
+				WithPerformRefreshError(errors.New("fake gitlab PerformRefresh error message")).
+				Build(),
+			identity:               initialIdentity,
+			wantPerformRefreshCall: true,
+			wantGetUserCall:        false,
+			wantWrappedErr:         "fake gitlab PerformRefresh error message",
+		},
+		{
+			name: "no new refresh token returned by refresh",
+			provider: oidctestutil.NewTestUpstreamGitLabIdentityProviderBuilder().
+				WithName("fake-provider-name").
+				WithRefreshedToken(&oauth2.Token{AccessToken: "refreshed-access-token"}).
+				Build(),
+			identity:               initialIdentity,
+			wantPerformRefreshCall: true,
+			wantGetUserCall:        false,
+			wantWrappedErr:         "GitLab API did not return a new refresh token",
+		},
+		{
+			name: "error while getting user info",
+			provider: oidctestutil.NewTestUpstreamGitLabIdentityProviderBuilder().
+				WithName("fake-provider-name").
+				WithRefreshedToken(refreshedToken).
+				WithGetUserError(upstreamprovider.NewGitLabLoginDeniedError("user is not allowed to log in due to group membership policy")).
+				Build(),
+			identity:               initialIdentity,
+			wantPerformRefreshCall: true,
+			wantGetUserCall:        true,
+			wantWrappedErr:         "user is not allowed to log in due to group membership policy",
+		},
+		{
+			name: "wrong session data type, which should not really happen",
+			provider: oidctestutil.NewTestUpstreamGitLabIdentityProviderBuilder().
+				WithName("fake-provider-name").
+				Build(),
+			identity: &resolvedprovider.Identity{
+				UpstreamUsername:       "initial-username",
+				DownstreamSubject:      "https://fake-downstream-subject",
+				IDPSpecificSessionData: &psession.GitHubSessionData{}, // wrong type
+			},
+			wantWrappedErr: "wrong data type found for IDPSpecificSessionData",
+		},
+		{
+			name: "session is missing gitlab refresh token, which should not really happen",
+			provider: oidctestutil.NewTestUpstreamGitLabIdentityProviderBuilder().
+				WithName("fake-provider-name").
+				Build(),
+			identity: &resolvedprovider.Identity{
+				UpstreamUsername:       "initial-username",
+				DownstreamSubject:      "https://fake-downstream-subject",
+				IDPSpecificSessionData: &psession.GitLabSessionData{UpstreamRefreshToken: ""},
+			},
+			wantWrappedErr: "session is missing GitLab refresh token",
+		},
+		{
+			name: "users downstream subject changes based on an unexpected change in the upstream identity",
+			provider: oidctestutil.NewTestUpstreamGitLabIdentityProviderBuilder().
+				WithName("fake-provider-name").
+				WithRefreshedToken(refreshedToken).
+				WithUser(&upstreamprovider.GitLabUser{
+					Username:          "refreshed-username",
+					DownstreamSubject: "https://unexpected-different-downstream-subject", // unexpected change in calculated subject during refresh
+				}).
+				Build(),
+			identity:               initialIdentity,
+			wantPerformRefreshCall: true,
+			wantGetUserCall:        true,
+			wantWrappedErr: `user's calculated downstream subject at initial login was "https://fake-downstream-subject" ` +
+				`but now is "https://unexpected-different-downstream-subject"`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			subject := FederationDomainResolvedGitLabIdentityProvider{
+				DisplayName:         "fake-display-name",
+				Provider:            test.provider,
+				SessionProviderType: psession.ProviderTypeGitLab,
+				Transforms:          transformtestutil.NewRejectAllAuthPipeline(t),
+			}
+
+			refreshedIdentity, err := subject.UpstreamRefresh(uniqueCtx, test.identity)
+
+			if test.wantPerformRefreshCall {
+				require.Equal(t, 1, test.provider.PerformRefreshCallCount())
+				require.Equal(t, &oidctestutil.PerformGitLabRefreshArgs{
+					Ctx:          uniqueCtx,
+					RefreshToken: "initial-refresh-token",
+				}, test.provider.PerformRefreshArgs(0))
+			} else {
+				require.Zero(t, test.provider.PerformRefreshCallCount())
+			}
+
+			if test.wantGetUserCall {
+				require.Equal(t, 1, test.provider.GetUserCallCount())
+				require.Equal(t, &oidctestutil.GetGitLabUserArgs{
+					Ctx:            uniqueCtx,
+					AccessToken:    "refreshed-access-token",
+					IDPDisplayName: "fake-display-name",
+				}, test.provider.GetUserArgs(0))
+			} else {
+				require.Zero(t, test.provider.GetUserCallCount())
+			}
+
+			if test.wantWrappedErr == "" {
+				require.NoError(t, err)
+			} else {
+				require.NotNil(t, err, "expected to get an error but did not get one")
+				errAsFositeErr, ok := err.(*fosite.RFC6749Error)
+				require.True(t, ok)
+				require.EqualError(t, errAsFositeErr.Unwrap(), test.wantWrappedErr)
+				require.Equal(t, "error", errAsFositeErr.ErrorField)
+				require.Equal(t, "Error during upstream refresh.", errAsFositeErr.DescriptionField)
+				require.Equal(t, http.StatusUnauthorized, errAsFositeErr.CodeField)
+				require.Equal(t, `provider name: "fake-provider-name", provider type: "gitlab"`, errAsFositeErr.DebugField)
+			}
+
+			require.Equal(t, test.wantRefreshedIdentity, refreshedIdentity)
+		})
+	}
+}
@@ -0,0 +1,206 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package resolvedoauth2
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/ory/fosite"
+	"golang.org/x/oauth2"
+
+	"go.pinniped.dev/generated/latest/apis/supervisor/idpdiscovery/v1alpha1"
+	"go.pinniped.dev/internal/federationdomain/resolvedprovider"
+	"go.pinniped.dev/internal/federationdomain/upstreamprovider"
+	"go.pinniped.dev/internal/httputil/httperr"
+	"go.pinniped.dev/internal/idtransform"
+	"go.pinniped.dev/internal/psession"
+	"go.pinniped.dev/pkg/oidcclient/nonce"
+	"go.pinniped.dev/pkg/oidcclient/pkce"
+)
+
+// FederationDomainResolvedOAuth2IdentityProvider represents a FederationDomainIdentityProvider which has
+// been resolved dynamically based on the currently loaded IDP CRs to include the provider.UpstreamOAuth2IdentityProviderI
+// and other metadata about the provider.
+type FederationDomainResolvedOAuth2IdentityProvider struct {
+	DisplayName         string
+	Provider            upstreamprovider.UpstreamOAuth2IdentityProviderI
+	SessionProviderType psession.ProviderType
+	Transforms          *idtransform.TransformationPipeline
+}
+
+var _ resolvedprovider.FederationDomainResolvedIdentityProvider = (*FederationDomainResolvedOAuth2IdentityProvider)(nil)
+
+func (p *FederationDomainResolvedOAuth2IdentityProvider) GetDisplayName() string {
+	return p.DisplayName
+}
+
+func (p *FederationDomainResolvedOAuth2IdentityProvider) GetProvider() upstreamprovider.UpstreamIdentityProviderI {
+	return p.Provider
+}
+
+func (p *FederationDomainResolvedOAuth2IdentityProvider) GetSessionProviderType() psession.ProviderType {
+	return p.SessionProviderType
+}
+
+func (p *FederationDomainResolvedOAuth2IdentityProvider) GetIDPDiscoveryType() v1alpha1.IDPType {
+	return v1alpha1.IDPTypeOAuth2
+}
+
+func (p *FederationDomainResolvedOAuth2IdentityProvider) GetIDPDiscoveryFlows() []v1alpha1.IDPFlow {
+	return []v1alpha1.IDPFlow{v1alpha1.IDPFlowBrowserAuthcode, v1alpha1.IDPFlowDeviceCode}
+}
+
+func (p *FederationDomainResolvedOAuth2IdentityProvider) GetTransforms() *idtransform.TransformationPipeline {
+	return p.Transforms
+}
+
+func (p *FederationDomainResolvedOAuth2IdentityProvider) CloneIDPSpecificSessionDataFromSession(session *psession.CustomSessionData) any {
+	if session.OAuth2 == nil {
+		return nil
+	}
+	return session.OAuth2.Clone()
+}
+
+func (p *FederationDomainResolvedOAuth2IdentityProvider) ApplyIDPSpecificSessionDataToSession(session *psession.CustomSessionData, idpSpecificSessionData any) {
+	session.OAuth2 = idpSpecificSessionData.(*psession.OAuth2SessionData)
+}
+
+func (p *FederationDomainResolvedOAuth2IdentityProvider) UpstreamAuthorizeRedirectURL(
+	state *resolvedprovider.UpstreamAuthorizeRequestState,
+	downstreamIssuerURL string,
+) (string, error) {
+	upstreamOAuthConfig := oauth2.Config{
+		ClientID: p.Provider.GetClientID(),
+		Endpoint: oauth2.Endpoint{
+			AuthURL: p.Provider.GetAuthorizationURL(),
+		},
+		RedirectURL: fmt.Sprintf("%s/callback", downstreamIssuerURL),
+		Scopes:      p.Provider.GetScopes(),
+	}
+	authCodeOptions := []oauth2.AuthCodeOption{
+		state.PKCE.Challenge(),
+		state.PKCE.Method(),
+	}
+	for key, val := range p.Provider.GetAdditionalAuthcodeParams() {
+		authCodeOptions = append(authCodeOptions, oauth2.SetAuthURLParam(key, val))
+	}
+	redirectURL := upstreamOAuthConfig.AuthCodeURL(
+		state.EncodedStateParam.String(),
+		authCodeOptions...,
+	)
+	return redirectURL, nil
+}
+
+func (p *FederationDomainResolvedOAuth2IdentityProvider) Login(
+	_ context.Context,
+	_ string,
+	_ string,
+) (*resolvedprovider.Identity, *resolvedprovider.IdentityLoginExtras, error) {
+	return nil, nil, errors.New("function Login not yet implemented for OAuth2 IDP")
+}
+
+func (p *FederationDomainResolvedOAuth2IdentityProvider) LoginFromCallback(
+	ctx context.Context,
+	authCode string,
+	pkce pkce.Code,
+	_ nonce.Nonce, // OAuth2 providers do not return an ID token that could contain the "nonce".
+	redirectURI string,
+) (*resolvedprovider.Identity, *resolvedprovider.IdentityLoginExtras, error) {
+	token, err := p.Provider.ExchangeAuthcode(ctx, authCode, pkce, redirectURI)
+	if err != nil {
+		return nil, nil, httperr.Wrap(http.StatusBadGateway,
+			"failed to exchange authcode using OAuth2 token endpoint",
+			err,
+		)
+	}
+
+	user, err := p.Provider.GetUser(ctx, token.AccessToken, p.GetDisplayName())
+	if err != nil {
+		return nil, nil, httperr.Wrap(http.StatusUnprocessableEntity,
+			"failed to get user info from OAuth2 provider",
+			err,
+		)
+	}
+
+	// Prefer to keep the refresh token, since access tokens may expire before the end of the downstream session.
+	// When the provider did not return a refresh token, keep the access token instead.
+	sessionData := &psession.OAuth2SessionData{}
+	if len(token.RefreshToken) > 0 {
+		sessionData.UpstreamRefreshToken = token.RefreshToken
+	} else {
+		sessionData.UpstreamAccessToken = token.AccessToken
+	}
+
+	return &resolvedprovider.Identity{
+			UpstreamUsername:       user.Username,
+			UpstreamGroups:         user.Groups,
+			DownstreamSubject:      user.DownstreamSubject,
+			IDPSpecificSessionData: sessionData,
+		},
+		&resolvedprovider.IdentityLoginExtras{
+			DownstreamAdditionalClaims: nil, // not using this for OAuth2
+			Warnings:                   nil, // not using this for OAuth2
+		},
+		nil // no error
+}
+
+func (p *FederationDomainResolvedOAuth2IdentityProvider) UpstreamRefresh(
+	ctx context.Context,
+	identity *resolvedprovider.Identity,
+) (*resolvedprovider.RefreshedIdentity, error) {
+	oauth2SessionData, ok := identity.IDPSpecificSessionData.(*psession.OAuth2SessionData)
+	if !ok {
+		// This should not really happen.
+		return nil, p.refreshErr(errors.New("wrong data type found for IDPSpecificSessionData"))
+	}
+
+	refreshedSessionData := oauth2SessionData.Clone()
+	accessToken := oauth2SessionData.UpstreamAccessToken
+
+	switch {
+	case len(oauth2SessionData.UpstreamRefreshToken) > 0:
+		// Get a new access token. This also checks that the user's authorization of the client was not revoked.
+		refreshedToken, err := p.Provider.PerformRefresh(ctx, oauth2SessionData.UpstreamRefreshToken)
+		if err != nil {
+			return nil, p.refreshErr(err)
+		}
+		accessToken = refreshedToken.AccessToken
+		if len(refreshedToken.RefreshToken) > 0 {
+			// Some providers rotate refresh tokens, so the new one must be saved for the next refresh.
+			refreshedSessionData.UpstreamRefreshToken = refreshedToken.RefreshToken
+		}
+	case len(accessToken) == 0:
+		// This should not really happen.
+		return nil, p.refreshErr(errors.New("session is missing OAuth2 refresh token and access token"))
+	}
+
+	// Get the user's identity again using the access token. When there is no refresh token, this will fail
+	// after the original access token has expired or has been revoked.
+	refreshedUserInfo, err := p.Provider.GetUser(ctx, accessToken, p.GetDisplayName())
+	if err != nil {
+		return nil, p.refreshErr(err)
+	}
+
+	if refreshedUserInfo.DownstreamSubject != identity.DownstreamSubject {
+		// The user's upstream identity changed since the initial login in a surprising way.
+		return nil, p.refreshErr(fmt.Errorf("user's calculated downstream subject at initial login was %q but now is %q",
+			identity.DownstreamSubject, refreshedUserInfo.DownstreamSubject))
+	}
+
+	return &resolvedprovider.RefreshedIdentity{
+		UpstreamUsername:       refreshedUserInfo.Username,
+		UpstreamGroups:         refreshedUserInfo.Groups,
+		IDPSpecificSessionData: refreshedSessionData,
+	}, nil
+}
+
+func (p *FederationDomainResolvedOAuth2IdentityProvider) refreshErr(err error) *fosite.RFC6749Error {
+	return resolvedprovider.ErrUpstreamRefreshError().
+		WithHint("Upstream refresh failed.").
+		WithTrace(err).
+		WithDebugf("provider name: %q, provider type: %q", p.Provider.GetResourceName(), p.GetSessionProviderType())
+}
@@ -0,0 +1,441 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package resolvedoauth2
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ory/fosite"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+
+	idpdiscoveryv1alpha1 "go.pinniped.dev/generated/latest/apis/supervisor/idpdiscovery/v1alpha1"
+	"go.pinniped.dev/internal/federationdomain/resolvedprovider"
+	"go.pinniped.dev/internal/federationdomain/upstreamprovider"
+	"go.pinniped.dev/internal/httputil/httperr"
+	"go.pinniped.dev/internal/psession"
+	"go.pinniped.dev/internal/testutil/oidctestutil"
+	"go.pinniped.dev/internal/testutil/transformtestutil"
+	"go.pinniped.dev/internal/upstreamoauth2"
+	"go.pinniped.dev/pkg/oidcclient/pkce"
+)
+
+func TestFederationDomainResolvedOAuth2IdentityProvider(t *testing.T) {
+	transforms := transformtestutil.NewRejectAllAuthPipeline(t)
+
+	provider := upstreamoauth2.New(upstreamoauth2.ProviderConfig{
+		Name:                     "fake-provider-config",
+		ResourceUID:              "fake-resource-uid",
+		UserInfoURL:              "https://fake-userinfo-url",
+		AdditionalAuthcodeParams: map[string]string{"prompt": "consent"},
+		HttpClient:               nil, // not needed yet for this test
+		//nolint:gosec // not a real credential
+		OAuth2Config: &oauth2.Config{
+			ClientID:     "fake-client-id",
+			ClientSecret: "fake-client-secret",
+			Scopes:       []string{"read:user", "offline"},
+			Endpoint: oauth2.Endpoint{
+				AuthURL:   "https://fake-authorization-url",
+				TokenURL:  "https://fake-token-url",
+				AuthStyle: oauth2.AuthStyleInParams,
+			},
+		},
+	})
+
+	subject := FederationDomainResolvedOAuth2IdentityProvider{
+		DisplayName:         "fake-display-name",
+		Provider:            provider,
+		SessionProviderType: psession.ProviderTypeOAuth2,
+		Transforms:          transforms,
+	}
+
+	require.Equal(t, "fake-display-name", subject.GetDisplayName())
+	require.Equal(t, provider, subject.GetProvider())
+	require.Equal(t, psession.ProviderTypeOAuth2, subject.GetSessionProviderType())
+	require.Equal(t, idpdiscoveryv1alpha1.IDPTypeOAuth2, subject.GetIDPDiscoveryType())
+	require.Equal(t, []idpdiscoveryv1alpha1.IDPFlow{idpdiscoveryv1alpha1.IDPFlowBrowserAuthcode, idpdiscoveryv1alpha1.IDPFlowDeviceCode}, subject.GetIDPDiscoveryFlows())
+	require.Equal(t, transforms, subject.GetTransforms())
+
+	originalCustomSession := &psession.CustomSessionData{
+		Username:         "fake-username",
+		UpstreamUsername: "fake-upstream-username",
+		OAuth2:           &psession.OAuth2SessionData{UpstreamRefreshToken: "fake-upstream-refresh-token"},
+	}
+	clonedCustomSession := subject.CloneIDPSpecificSessionDataFromSession(originalCustomSession)
+	require.Equal(t,
+		&psession.OAuth2SessionData{UpstreamRefreshToken: "fake-upstream-refresh-token"},
+		clonedCustomSession,
+	)
+	require.NotSame(t, originalCustomSession.OAuth2, clonedCustomSession)
+	require.Nil(t, subject.CloneIDPSpecificSessionDataFromSession(&psession.CustomSessionData{}))
+
+	customSessionToBeMutated := &psession.CustomSessionData{
+		Username:         "fake-username2",
+		UpstreamUsername: "fake-upstream-username2",
+	}
+	subject.ApplyIDPSpecificSessionDataToSession(customSessionToBeMutated, &psession.OAuth2SessionData{UpstreamAccessToken: "OTHER-upstream-access-token"})
+	require.Equal(t, &psession.CustomSessionData{
+		Username:         "fake-username2",
+		UpstreamUsername: "fake-upstream-username2",
+		OAuth2:           &psession.OAuth2SessionData{UpstreamAccessToken: "OTHER-upstream-access-token"},
+	}, customSessionToBeMutated)
+
+	redirectURL, err := subject.UpstreamAuthorizeRedirectURL(
+		&resolvedprovider.UpstreamAuthorizeRequestState{
+			EncodedStateParam: "encodedStateParam12345",
+			PKCE:              "pkce6789",
+			Nonce:             "nonce1289",
+		},
+		"https://localhost/fake/path",
+	)
+	require.NoError(t, err)
+	require.Equal(t,
+		"https://fake-authorization-url?"+
+			"client_id=fake-client-id&"+
+			"code_challenge=3indzSdebyEpaWs9NNOPrtx0sw3z6VhUM9MPRjji134&"+
+			"code_challenge_method=S256&"+
+			"prompt=consent&"+
+			"redirect_uri=https%3A%2F%2Flocalhost%2Ffake%2Fpath%2Fcallback&"+
+			"response_type=code&"+
+			"scope=read%3Auser+offline&"+
+			"state=encodedStateParam12345",
+		redirectURL,
+	)
+
+	_, _, err = subject.Login(context.Background(), "username", "password")
+	require.EqualError(t, err, "function Login not yet implemented for OAuth2 IDP")
+}
+
+func TestLoginFromCallback(t *testing.T) {
+	uniqueCtx := context.WithValue(context.Background(), "some-unique-key", "some-value") //nolint:staticcheck // okay to use string key for test
+
+	wantExchangeAuthcodeArgs := &oidctestutil.ExchangeOAuth2AuthcodeArgs{
+		Ctx:              uniqueCtx,
+		Authcode:         "fake-authcode",
+		PKCECodeVerifier: "fake-pkce",
+		RedirectURI:      "https://fake-redirect-uri",
+	}
+	wantGetUserArgs := &oidctestutil.GetOAuth2UserArgs{
+		Ctx:            uniqueCtx,
+		AccessToken:    "fake-access-token",
+		IDPDisplayName: "fake-display-name",
+	}
+	fakeUser := &upstreamprovider.OAuth2User{
+		Username:          "fake-username",
+		Groups:            []string{"fake-group1", "fake-group2"},
+		DownstreamSubject: "https://fake-downstream-subject",
+	}
+
+	tests := []struct {
+		name     string
+		provider *oidctestutil.TestUpstreamOAuth2IdentityProvider
+
+		wantGetUserCall    bool
+		wantIdentity       *resolvedprovider.Identity
+		wantExtras         *resolvedprovider.IdentityLoginExtras
+		wantErrMsg         string
+		wantErrResponseMsg string
+		wantErrStatusCode  int
+	}{
+		{
+			name: "happy path when a refresh token is returned",
+			provider: oidctestutil.NewTestUpstreamOAuth2IdentityProviderBuilder().
+				WithToken(&oauth2.Token{AccessToken: "fake-access-token", RefreshToken: "fake-refresh-token"}).
+				WithUser(fakeUser).
+				Build(),
+			wantGetUserCall: true,
+			wantIdentity: &resolvedprovider.Identity{
+				UpstreamUsername:  "fake-username",
+				UpstreamGroups:    []string{"fake-group1", "fake-group2"},
+				DownstreamSubject: "https://fake-downstream-subject",
+				IDPSpecificSessionData: &psession.OAuth2SessionData{
+					UpstreamRefreshToken: "fake-refresh-token",
+				},
+			},
+			wantExtras: &resolvedprovider.IdentityLoginExtras{},
+		},
+		{
+			name: "happy path when no refresh token is returned",
+			provider: oidctestutil.NewTestUpstreamOAuth2IdentityProviderBuilder().
+				WithToken(&oauth2.Token{AccessToken: "fake-access-token"}).
+				WithUser(fakeUser).
+				Build(),
+			wantGetUserCall: true,
+			wantIdentity: &resolvedprovider.Identity{
+				UpstreamUsername:  "fake-username",
+				UpstreamGroups:    []string{"fake-group1", "fake-group2"},
+				DownstreamSubject: "https://fake-downstream-subject",
+				IDPSpecificSessionData: &psession.OAuth2SessionData{
+					UpstreamAccessToken: "fake-access-token",
+				},
+			},
+			wantExtras: &resolvedprovider.IdentityLoginExtras{},
+		},
+		{
+			name: "error while exchanging authcode",
+			provider: oidctestutil.NewTestUpstreamOAuth2IdentityProviderBuilder().
+				WithAuthcodeExchangeError(errors.New("fake authcode exchange error")).
+				Build(),
+			wantGetUserCall:    false,
+			wantErrMsg:         "failed to exchange authcode using OAuth2 token endpoint: fake authcode exchange error",
+			wantErrResponseMsg: "Bad Gateway: failed to exchange authcode using OAuth2 token endpoint",
+			wantErrStatusCode:  http.StatusBadGateway,
+		},
+		{
+			name: "error while getting user info",
+			provider: oidctestutil.NewTestUpstreamOAuth2IdentityProviderBuilder().
+				WithToken(&oauth2.Token{AccessToken: "fake-access-token"}).
+				WithGetUserError(errors.New("fake user info error")).
+				Build(),
+			wantGetUserCall:    true,
+			wantErrMsg:         "failed to get user info from OAuth2 provider: fake user info error",
+			wantErrResponseMsg: "Unprocessable Entity: failed to get user info from OAuth2 provider",
+			wantErrStatusCode:  http.StatusUnprocessableEntity,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			subject := FederationDomainResolvedOAuth2IdentityProvider{
+				DisplayName:         "fake-display-name",
+				Provider:            test.provider,
+				SessionProviderType: psession.ProviderTypeOAuth2,
+				Transforms:          transformtestutil.NewRejectAllAuthPipeline(t),
+			}
+
+			identity, loginExtras, err := subject.LoginFromCallback(uniqueCtx,
+				"fake-authcode",
+				pkce.Code("fake-pkce"),
+				"nonce-will-be-ignored",
+				"https://fake-redirect-uri",
+			)
+
+			require.Equal(t, 1, test.provider.ExchangeAuthcodeCallCount())
+			require.Equal(t, wantExchangeAuthcodeArgs, test.provider.ExchangeAuthcodeArgs(0))
+
+			if test.wantGetUserCall {
+				require.Equal(t, 1, test.provider.GetUserCallCount())
+				require.Equal(t, wantGetUserArgs, test.provider.GetUserArgs(0))
+			} else {
+				require.Zero(t, test.provider.GetUserCallCount())
+			}
+
+			if test.wantErrResponseMsg == "" {
+				require.NoError(t, err)
+			} else {
+				require.Implements(t, (*httperr.Responder)(nil), err)
+				errAsResponder := err.(httperr.Responder)
+				rec := httptest.NewRecorder()
+				errAsResponder.Respond(rec)
+				require.Equal(t, test.wantErrStatusCode, rec.Code)
+				require.Equal(t, test.wantErrResponseMsg+"\n", rec.Body.String())
+
+				require.EqualError(t, errAsResponder, test.wantErrMsg)
+			}
+			require.Equal(t, test.wantExtras, loginExtras)
+			require.Equal(t, test.wantIdentity, identity)
+		})
+	}
+}
+
+func TestUpstreamRefresh(t *testing.T) {
+	uniqueCtx := context.WithValue(context.Background(), "some-unique-key", "some-value") //nolint:staticcheck // okay to use string key for test
+
+	initialIdentityWithRefreshToken := &resolvedprovider.Identity{
+		UpstreamUsername:       "initial-username",
+		UpstreamGroups:         []string{"initial-group1", "initial-group2"},
+		DownstreamSubject:      "https://fake-downstream-subject",
+		IDPSpecificSessionData: &psession.OAuth2SessionData{UpstreamRefreshToken: "initial-refresh-token"},
+	}
+	initialIdentityWithAccessToken := &resolvedprovider.Identity{
+		UpstreamUsername:       "initial-username",
+		UpstreamGroups:         []string{"initial-group1", "initial-group2"},
+		DownstreamSubject:      "https://fake-downstream-subject",
+		IDPSpecificSessionData: &psession.OAuth2SessionData{UpstreamAccessToken: "initial-access-token"},
+	}
+	refreshedUser := &upstreamprovider.OAuth2User{
+		Username:          "refreshed-username",
+		Groups:            []string{"refreshed-group1", "refreshed-group2"},
+		DownstreamSubject: "https://fake-downstream-subject",
+	}
+
+	tests := []struct {
+		name     string
+		provider *oidctestutil.TestUpstreamOAuth2IdentityProvider
+		identity *resolvedprovider.Identity
+
+		wantPerformRefreshCall bool
+		wantGetUserCall        bool
+		wantGetUserAccessToken string
+		wantRefreshedIdentity  *resolvedprovider.RefreshedIdentity
+		wantWrappedErr         string
+	}{
+		{
+			name: "happy path with a rotated refresh token",
+			provider: oidctestutil.NewTestUpstreamOAuth2IdentityProviderBuilder().
+				WithName("fake-provider-name").
+				WithRefreshedToken(&oauth2.Token{AccessToken: "refreshed-access-token", RefreshToken: "refreshed-refresh-token"}).
+				WithUser(refreshedUser).
+				Build(),
+			identity:               initialIdentityWithRefreshToken,
+			wantPerformRefreshCall: true,
+			wantGetUserCall:        true,
+			wantGetUserAccessToken: "refreshed-access-token",
+			wantRefreshedIdentity: &resolvedprovider.RefreshedIdentity{
+				UpstreamUsername:       "refreshed-username",
+				UpstreamGroups:         []string{"refreshed-group1", "refreshed-group2"},
+				IDPSpecificSessionData: &psession.OAuth2SessionData{UpstreamRefreshToken: "refreshed-refresh-token"},
+			},
+		},
+		{
+			name: "happy path when the refresh does not return a new refresh token",
+			provider: oidctestutil.NewTestUpstreamOAuth2IdentityProviderBuilder().
+				WithName("fake-provider-name").
+				WithRefreshedToken(&oauth2.Token{AccessToken: "refreshed-access-token"}).
+				WithUser(refreshedUser).
+				Build(),
+			identity:               initialIdentityWithRefreshToken,
+			wantPerformRefreshCall: true,
+			wantGetUserCall:        true,
+			wantGetUserAccessToken: "refreshed-access-token",
+			wantRefreshedIdentity: &resolvedprovider.RefreshedIdentity{
+				UpstreamUsername:       "refreshed-username",
+				UpstreamGroups:         []string{"refreshed-group1", "refreshed-group2"},
+				IDPSpecificSessionData: &psession.OAuth2SessionData{UpstreamRefreshToken: "initial-refresh-token"},
+			},
+		},
+		{
+			name: "happy path when the session only has an access token",
+			provider: oidctestutil.NewTestUpstreamOAuth2IdentityProviderBuilder().
+				WithName("fake-provider-name").
+				WithUser(refreshedUser).
+				Build(),
+			identity:               initialIdentityWithAccessToken,
+			wantPerformRefreshCall: false,
+			wantGetUserCall:        true,
+			wantGetUserAccessToken: "initial-access-token",
+			wantRefreshedIdentity: &resolvedprovider.RefreshedIdentity{
+				UpstreamUsername:       "refreshed-username",
+				UpstreamGroups:         []string{"refreshed-group1", "refreshed-group2"},
+				IDPSpecificSessionData: &psession.OAuth2SessionData{UpstreamAccessToken: "initial-access-token"},
+			},
+		},
+		{
+			name: "error while refreshing tokens",
+			provider: oidctestutil.NewTestUpstreamOAuth2IdentityProviderBuilder().
+				WithName("fake-provider-name").
+				WithPerformRefreshError(errors.New("fake oauth2 PerformRefresh error message")).
+				Build(),
+			identity:               initialIdentityWithRefreshToken,
+			wantPerformRefreshCall: true,
+			wantGetUserCall:        false,
+			wantWrappedErr:         "fake oauth2 PerformRefresh error message",
+		},
+		{
+			name: "error while getting user info",
+			provider: oidctestutil.NewTestUpstreamOAuth2IdentityProviderBuilder().
+				WithName("fake-provider-name").
+				WithGetUserError(errors.New("fake oauth2 GetUser error message")).
+				Build(),
+			identity:               initialIdentityWithAccessToken,
+			wantPerformRefreshCall: false,
+			wantGetUserCall:        true,
+			wantGetUserAccessToken: "initial-access-token",
+			wantWrappedErr:         "fake oauth2 GetUser error message",
+		},
+		{
+			name: "wrong session data type, which should not really happen",
+			provider: oidctestutil.NewTestUpstreamOAuth2IdentityProviderBuilder().
+				WithName("fake-provider-name").
+				Build(),
+			identity: &resolvedprovider.Identity{
+				UpstreamUsername:       "initial-username",
+				DownstreamSubject:      "https://fake-downstream-subject",
+				IDPSpecificSessionData: &psession.GitHubSessionData{}, // wrong type
+			},
+			wantWrappedErr: "wrong data type found for IDPSpecificSessionData",
+		},
+		{
+			name: "session is missing both tokens, which should not really happen",
+			provider: oidctestutil.NewTestUpstreamOAuth2IdentityProviderBuilder().
+				WithName("fake-provider-name").
+				Build(),
+			identity: &resolvedprovider.Identity{
+				UpstreamUsername:       "initial-username",
+				DownstreamSubject:      "https://fake-downstream-subject",
+				IDPSpecificSessionData: &psession.OAuth2SessionData{},
+			},
+			wantWrappedErr: "session is missing OAuth2 refresh token and access token",
+		},
+		{
+			name: "users downstream subject changes based on an unexpected change in the upstream identity",
+			provider: oidctestutil.NewTestUpstreamOAuth2IdentityProviderBuilder().
+				WithName("fake-provider-name").
+				WithUser(&upstreamprovider.OAuth2User{
+					Username:          "refreshed-username",
+					DownstreamSubject: "https://unexpected-different-downstream-subject", // unexpected change in calculated subject during refresh
+				}).
+				Build(),
+			identity:               initialIdentityWithAccessToken,
+			wantPerformRefreshCall: false,
+			wantGetUserCall:        true,
+			wantGetUserAccessToken: "initial-access-token",
+			wantWrappedErr: `user's calculated downstream subject at initial login was "https://fake-downstream-subject" ` +
+				`but now is "https://unexpected-different-downstream-subject"`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			subject := FederationDomainResolvedOAuth2IdentityProvider{
+				DisplayName:         "fake-display-name",
+				Provider:            test.provider,
+				SessionProviderType: psession.ProviderTypeOAuth2,
+				Transforms:          transformtestutil.NewRejectAllAuthPipeline(t),
+			}
+
+			refreshedIdentity, err := subject.UpstreamRefresh(uniqueCtx, test.identity)
+
+			if test.wantPerformRefreshCall {
+				require.Equal(t, 1, test.provider.PerformRefreshCallCount())
+				require.Equal(t, &oidctestutil.PerformOAuth2RefreshArgs{
+					Ctx:          uniqueCtx,
+					RefreshToken: "initial-refresh-token",
+				}, test.provider.PerformRefreshArgs(0))
+			} else {
+				require.Zero(t, test.provider.PerformRefreshCallCount())
+			}
+
+			if test.wantGetUserCall {
+				require.Equal(t, 1, test.provider.GetUserCallCount())
+				require.Equal(t, &oidctestutil.GetOAuth2UserArgs{
+					Ctx:            uniqueCtx,
+					AccessToken:    test.wantGetUserAccessToken,
+					IDPDisplayName: "fake-display-name",
+				}, test.provider.GetUserArgs(0))
+			} else {
+				require.Zero(t, test.provider.GetUserCallCount())
+			}
+
+			if test.wantWrappedErr == "" {
+				require.NoError(t, err)
+			} else {
+				require.NotNil(t, err, "expected to get an error but did not get one")
+				errAsFositeErr, ok := err.(*fosite.RFC6749Error)
+				require.True(t, ok)
+				require.EqualError(t, errAsFositeErr.Unwrap(), test.wantWrappedErr)
+				require.Equal(t, "error", errAsFositeErr.ErrorField)
+				require.Equal(t, "Error during upstream refresh.", errAsFositeErr.DescriptionField)
+				require.Equal(t, http.StatusUnauthorized, errAsFositeErr.CodeField)
+				require.Equal(t, `provider name: "fake-provider-name", provider type: "oauth2"`, errAsFositeErr.DebugField)
+			}
+
+			require.Equal(t, test.wantRefreshedIdentity, refreshedIdentity)
+		})
+	}
+}
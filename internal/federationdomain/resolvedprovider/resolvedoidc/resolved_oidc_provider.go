@@ -7,8 +7,10 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"maps"
 	"net/http"
 	"net/url"
+	"strings"
 	"time"
 
 	"github.com/ory/fosite"
@@ -17,6 +19,7 @@ import (
 
 	"go.pinniped.dev/generated/latest/apis/supervisor/idpdiscovery/v1alpha1"
 	oidcapi "go.pinniped.dev/generated/latest/apis/supervisor/oidc"
+	"go.pinniped.dev/internal/celtransformer"
 	"go.pinniped.dev/internal/constable"
 	"go.pinniped.dev/internal/federationdomain/downstreamsubject"
 	"go.pinniped.dev/internal/federationdomain/resolvedprovider"
@@ -37,6 +40,14 @@ const (
 	// The name of the email_verified claim from https://openid.net/specs/openid-connect-core-1_0.html#StandardClaims
 	emailVerifiedClaimName = "email_verified"
 
+	// The name of the authorize request param from https://openid.net/specs/openid-connect-core-1_0.html#AuthRequest
+	acrValuesParamName = "acr_values"
+
+	// The names of the claims which describe how the user authenticated, from
+	// https://openid.net/specs/openid-connect-core-1_0.html#IDToken
+	acrClaimName = "acr"
+	amrClaimName = "amr"
+
 	requiredClaimMissingErr            = constable.Error("required claim in upstream ID token missing")
 	requiredClaimInvalidFormatErr      = constable.Error("required claim in upstream ID token has invalid format")
 	requiredClaimEmptyErr              = constable.Error("required claim in upstream ID token is empty")
@@ -48,10 +59,11 @@ const (
 // been resolved dynamically based on the currently loaded IDP CRs to include the provider.UpstreamOIDCIdentityProviderI
 // and other metadata about the provider.
 type FederationDomainResolvedOIDCIdentityProvider struct {
-	DisplayName         string
-	Provider            upstreamprovider.UpstreamOIDCIdentityProviderI
-	SessionProviderType psession.ProviderType
-	Transforms          *idtransform.TransformationPipeline
+	DisplayName          string
+	Provider             upstreamprovider.UpstreamOIDCIdentityProviderI
+	SessionProviderType  psession.ProviderType
+	Transforms           *idtransform.TransformationPipeline
+	AuthenticationPolicy *celtransformer.CompiledUpstreamAuthenticationPolicy // optional
 }
 
 var _ resolvedprovider.FederationDomainResolvedIdentityProvider = (*FederationDomainResolvedOIDCIdentityProvider)(nil)
@@ -77,7 +89,7 @@ func (p *FederationDomainResolvedOIDCIdentityProvider) GetIDPDiscoveryFlows() []
 	if p.Provider.AllowsPasswordGrant() {
 		flows = append(flows, v1alpha1.IDPFlowCLIPassword)
 	}
-	return flows
+	return append(flows, v1alpha1.IDPFlowDeviceCode)
 }
 
 func (p *FederationDomainResolvedOIDCIdentityProvider) GetTransforms() *idtransform.TransformationPipeline {
@@ -116,6 +128,13 @@ func (p *FederationDomainResolvedOIDCIdentityProvider) UpstreamAuthorizeRedirect
 		authCodeOptions = append(authCodeOptions, oauth2.SetAuthURLParam(key, val))
 	}
 
+	// The acr_values of the authentication policy replace any acr_values from the additional params,
+	// since the later options win.
+	if p.AuthenticationPolicy != nil && len(p.AuthenticationPolicy.ACRValues()) > 0 {
+		authCodeOptions = append(authCodeOptions,
+			oauth2.SetAuthURLParam(acrValuesParamName, strings.Join(p.AuthenticationPolicy.ACRValues(), " ")))
+	}
+
 	redirectURL := upstreamOAuthConfig.AuthCodeURL(
 		state.EncodedStateParam.String(),
 		authCodeOptions...,
@@ -147,6 +166,10 @@ func (p *FederationDomainResolvedOIDCIdentityProvider) Login(
 		return nil, nil, fosite.ErrAccessDenied.WithDebug(err.Error()) // WithDebug hides the error from the client
 	}
 
+	if rejectedMessage, policyErr := p.enforceAuthenticationPolicy(ctx, token.IDToken.Claims); policyErr != nil {
+		return nil, nil, fosite.ErrAccessDenied.WithHintf("Reason: %s.", rejectedMessage).WithWrap(policyErr)
+	}
+
 	subject, upstreamUsername, upstreamGroups, err := getIdentityFromUpstreamIDToken(
 		p.Provider, token.IDToken.Claims, p.GetDisplayName(),
 	)
@@ -193,6 +216,10 @@ func (p *FederationDomainResolvedOIDCIdentityProvider) LoginFromCallback(
 		return nil, nil, httperr.Wrap(http.StatusBadGateway, "error exchanging and validating upstream tokens", err)
 	}
 
+	if rejectedMessage, policyErr := p.enforceAuthenticationPolicy(ctx, token.IDToken.Claims); policyErr != nil {
+		return nil, nil, httperr.Wrap(http.StatusUnprocessableEntity, rejectedMessage, policyErr)
+	}
+
 	subject, upstreamUsername, upstreamGroups, err := getIdentityFromUpstreamIDToken(
 		p.Provider, token.IDToken.Claims, p.GetDisplayName(),
 	)
@@ -275,6 +302,19 @@ func (p *FederationDomainResolvedOIDCIdentityProvider) UpstreamRefresh(
 	}
 	mergedClaims := validatedTokens.IDToken.Claims
 
+	// Only an ID token can tell us how the user authenticated. When the upstream refresh returned a new ID token,
+	// then enforce the authentication policy using its claims. Otherwise, enforce the current authentication policy
+	// using how the user authenticated when the stored claims were last updated, since the policy may have changed.
+	policyClaims := mergedClaims
+	if !hasIDTok {
+		policyClaims = claimsWithStoredAuthenticationContext(mergedClaims, sessionData)
+	}
+	if rejectedMessage, policyErr := p.enforceAuthenticationPolicy(ctx, policyClaims); policyErr != nil {
+		return nil, resolvedprovider.ErrUpstreamRefreshError().WithHintf(
+			"Upstream refresh rejected by authentication policy: %s.", rejectedMessage).WithTrace(policyErr).
+			WithDebugf("provider name: %q, provider type: %q", p.Provider.GetResourceName(), p.GetSessionProviderType())
+	}
+
 	// To the extent possible, check that the user's basic identity hasn't changed. We check that their downstream
 	// username has not changed separately below, as part of reapplying the transformations.
 	err = validateUpstreamSubjectAndIssuerUnchangedSinceInitialLogin(mergedClaims, sessionData, p.Provider.GetResourceName(), p.GetSessionProviderType())
@@ -308,6 +348,11 @@ func (p *FederationDomainResolvedOIDCIdentityProvider) UpstreamRefresh(
 
 	updatedSessionData := sessionData.Clone()
 
+	// A new ID token tells us how the user authenticated most recently, so remember that for future refreshes.
+	if hasIDTok {
+		updatedSessionData.UpstreamACR, updatedSessionData.UpstreamAMR = getAuthenticationContext(mergedClaims)
+	}
+
 	// Upstream refresh may or may not return a new refresh token. If we got a new refresh token, then update it in
 	// the user's session. If we did not get a new refresh token, then keep the old one in the session by avoiding
 	// overwriting the old one.
@@ -327,6 +372,83 @@ func (p *FederationDomainResolvedOIDCIdentityProvider) UpstreamRefresh(
 	}, nil
 }
 
+// enforceAuthenticationPolicy checks the validated claims against the authentication policy, if there is one.
+// When the authentication is rejected, it returns the message that should be shown to the user, along with
+// the error that callers should wrap.
+func (p *FederationDomainResolvedOIDCIdentityProvider) enforceAuthenticationPolicy(
+	ctx context.Context,
+	claims map[string]any,
+) (string, *resolvedprovider.UpstreamAuthenticationPolicyRejectedError) {
+	if p.AuthenticationPolicy == nil {
+		return "", nil
+	}
+
+	result, err := p.AuthenticationPolicy.Evaluate(ctx, claims)
+	if err != nil {
+		plog.Warning("error while evaluating the authentication policy",
+			"identityProviderDisplayName", p.GetDisplayName(),
+			"identityProviderResourceName", p.Provider.GetResourceName(),
+			"err", err)
+		return celtransformer.DefaultUpstreamAuthenticationPolicyRejectedAuthMessage,
+			&resolvedprovider.UpstreamAuthenticationPolicyRejectedError{
+				Reason: fmt.Sprintf("error while evaluating expression: %s", err.Error()),
+			}
+	}
+
+	if !result.AuthenticationAllowed {
+		return result.RejectedAuthenticationMessage,
+			&resolvedprovider.UpstreamAuthenticationPolicyRejectedError{Reason: result.Reason}
+	}
+
+	return "", nil
+}
+
+// getAuthenticationContext returns the "acr" and "amr" claims, which describe how the user authenticated.
+// Claims which are missing or have an unexpected type are returned as empty values.
+func getAuthenticationContext(claims map[string]any) (string, []string) {
+	acr, _ := getString(claims, acrClaimName)
+
+	var amr []string
+	if amrValues, ok := claims[amrClaimName].([]any); ok {
+		for _, amrValue := range amrValues {
+			if method, ok := amrValue.(string); ok {
+				amr = append(amr, method)
+			}
+		}
+	}
+
+	return acr, amr
+}
+
+// claimsWithStoredAuthenticationContext returns a copy of the claims which uses the stored "acr" and "amr" claims,
+// and the stored subject and issuer, instead of any which were in the claims. It is used when an upstream refresh
+// did not return a new ID token, because only an ID token is trusted to describe how the user authenticated.
+func claimsWithStoredAuthenticationContext(claims map[string]any, s *psession.OIDCSessionData) map[string]any {
+	result := maps.Clone(claims)
+	if result == nil {
+		result = map[string]any{}
+	}
+
+	result[oidcapi.IDTokenClaimSubject] = s.UpstreamSubject
+	result[oidcapi.IDTokenClaimIssuer] = s.UpstreamIssuer
+
+	delete(result, acrClaimName)
+	if s.UpstreamACR != "" {
+		result[acrClaimName] = s.UpstreamACR
+	}
+
+	delete(result, amrClaimName)
+	if len(s.UpstreamAMR) > 0 {
+		amr := make([]any, len(s.UpstreamAMR))
+		for i, method := range s.UpstreamAMR {
+			amr[i] = method
+		}
+		result[amrClaimName] = amr
+	}
+
+	return result
+}
+
 func validateUpstreamSubjectAndIssuerUnchangedSinceInitialLogin(
 	mergedClaims map[string]any,
 	s *psession.OIDCSessionData,
@@ -386,6 +508,7 @@ func makeDownstreamOIDCSessionData(
 		UpstreamIssuer:  upstreamIssuer,
 		UpstreamSubject: upstreamSubject,
 	}
+	sessionData.UpstreamACR, sessionData.UpstreamAMR = getAuthenticationContext(token.IDToken.Claims)
 
 	const pleaseCheck = "please check configuration of OIDCIdentityProvider and the client in the " +
 		"upstream provider's API/UI and try to get a refresh token if possible"
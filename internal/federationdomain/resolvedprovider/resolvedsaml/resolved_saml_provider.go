@@ -0,0 +1,180 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package resolvedsaml
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ory/fosite"
+
+	"go.pinniped.dev/generated/latest/apis/supervisor/idpdiscovery/v1alpha1"
+	"go.pinniped.dev/internal/federationdomain/resolvedprovider"
+	"go.pinniped.dev/internal/federationdomain/upstreamprovider"
+	"go.pinniped.dev/internal/httputil/httperr"
+	"go.pinniped.dev/internal/idtransform"
+	"go.pinniped.dev/internal/psession"
+	"go.pinniped.dev/pkg/oidcclient/nonce"
+	"go.pinniped.dev/pkg/oidcclient/pkce"
+)
+
+// FederationDomainResolvedSAMLIdentityProvider represents a FederationDomainIdentityProvider which has
+// been resolved dynamically based on the currently loaded IDP CRs to include the provider.UpstreamSAMLIdentityProviderI
+// and other metadata about the provider.
+type FederationDomainResolvedSAMLIdentityProvider struct {
+	DisplayName         string
+	Provider            upstreamprovider.UpstreamSAMLIdentityProviderI
+	SessionProviderType psession.ProviderType
+	Transforms          *idtransform.TransformationPipeline
+}
+
+var (
+	_ resolvedprovider.FederationDomainResolvedIdentityProvider = (*FederationDomainResolvedSAMLIdentityProvider)(nil)
+	_ resolvedprovider.UpstreamStateCookieRequirer              = (*FederationDomainResolvedSAMLIdentityProvider)(nil)
+)
+
+func (p *FederationDomainResolvedSAMLIdentityProvider) GetDisplayName() string {
+	return p.DisplayName
+}
+
+func (p *FederationDomainResolvedSAMLIdentityProvider) GetProvider() upstreamprovider.UpstreamIdentityProviderI {
+	return p.Provider
+}
+
+func (p *FederationDomainResolvedSAMLIdentityProvider) GetSessionProviderType() psession.ProviderType {
+	return p.SessionProviderType
+}
+
+func (p *FederationDomainResolvedSAMLIdentityProvider) GetIDPDiscoveryType() v1alpha1.IDPType {
+	return v1alpha1.IDPTypeSAML
+}
+
+func (p *FederationDomainResolvedSAMLIdentityProvider) GetIDPDiscoveryFlows() []v1alpha1.IDPFlow {
+	return []v1alpha1.IDPFlow{v1alpha1.IDPFlowBrowserAuthcode, v1alpha1.IDPFlowDeviceCode}
+}
+
+func (p *FederationDomainResolvedSAMLIdentityProvider) GetTransforms() *idtransform.TransformationPipeline {
+	return p.Transforms
+}
+
+func (p *FederationDomainResolvedSAMLIdentityProvider) CloneIDPSpecificSessionDataFromSession(session *psession.CustomSessionData) any {
+	if session.SAML == nil {
+		return nil
+	}
+	return session.SAML.Clone()
+}
+
+func (p *FederationDomainResolvedSAMLIdentityProvider) ApplyIDPSpecificSessionDataToSession(session *psession.CustomSessionData, idpSpecificSessionData any) {
+	session.SAML = idpSpecificSessionData.(*psession.SAMLSessionData)
+}
+
+func (p *FederationDomainResolvedSAMLIdentityProvider) UpstreamAuthorizeRedirectURL(
+	state *resolvedprovider.UpstreamAuthorizeRequestState,
+	downstreamIssuerURL string,
+) (string, error) {
+	return p.Provider.AuthnRequestURL(
+		requestIDFromNonce(state.Nonce),
+		// The encoded state param is too long to be sent as the RelayState, so it is kept in a cookie instead.
+		state.EncodedStateParam.RelayStateKey(),
+		fmt.Sprintf("%s/callback", downstreamIssuerURL),
+		downstreamIssuerURL,
+	)
+}
+
+// RequiresUpstreamStateCookie returns true, because the SAML bindings spec limits the RelayState param to 80 bytes.
+func (p *FederationDomainResolvedSAMLIdentityProvider) RequiresUpstreamStateCookie() bool {
+	return true
+}
+
+func (p *FederationDomainResolvedSAMLIdentityProvider) Login(
+	_ context.Context,
+	_ string,
+	_ string,
+) (*resolvedprovider.Identity, *resolvedprovider.IdentityLoginExtras, error) {
+	return nil, nil, errors.New("function Login not yet implemented for SAML IDP")
+}
+
+func (p *FederationDomainResolvedSAMLIdentityProvider) LoginFromCallback(
+	_ context.Context,
+	samlResponse string, // for SAML, the callback endpoint receives a SAML response instead of an authcode
+	_ pkce.Code, // SAML does not use PKCE
+	nonce nonce.Nonce, // the AuthnRequest ID was derived from the nonce
+	redirectURI string,
+) (*resolvedprovider.Identity, *resolvedprovider.IdentityLoginExtras, error) {
+	user, err := p.Provider.ParseResponse(
+		samlResponse,
+		requestIDFromNonce(nonce),
+		redirectURI,
+		strings.TrimSuffix(redirectURI, "/callback"),
+		p.GetDisplayName(),
+	)
+	if err != nil {
+		return nil, nil, httperr.Wrap(http.StatusUnprocessableEntity,
+			"failed to validate SAML response",
+			err,
+		)
+	}
+
+	return &resolvedprovider.Identity{
+			UpstreamUsername:  user.Username,
+			UpstreamGroups:    user.Groups,
+			DownstreamSubject: user.DownstreamSubject,
+			IDPSpecificSessionData: &psession.SAMLSessionData{
+				NameID:              user.NameID,
+				SessionNotOnOrAfter: user.SessionNotOnOrAfter,
+			},
+		},
+		&resolvedprovider.IdentityLoginExtras{
+			DownstreamAdditionalClaims: nil, // not using this for SAML
+			Warnings:                   nil, // not using this for SAML
+		},
+		nil // no error
+}
+
+// UpstreamRefresh cannot ask a SAML IDP about the user again, because SAML has no back-channel API for that.
+// Instead, it enforces the session lifetime which was asserted by the IDP at login time, if any,
+// and otherwise keeps the identity from the initial login.
+func (p *FederationDomainResolvedSAMLIdentityProvider) UpstreamRefresh(
+	_ context.Context,
+	identity *resolvedprovider.Identity,
+) (*resolvedprovider.RefreshedIdentity, error) {
+	samlSessionData, ok := identity.IDPSpecificSessionData.(*psession.SAMLSessionData)
+	if !ok {
+		// This should not really happen.
+		return nil, p.refreshErr(errors.New("wrong data type found for IDPSpecificSessionData"))
+	}
+	if len(samlSessionData.NameID) == 0 {
+		// This should not really happen.
+		return nil, p.refreshErr(errors.New("session is missing SAML NameID"))
+	}
+
+	if !samlSessionData.SessionNotOnOrAfter.IsZero() && !time.Now().Before(samlSessionData.SessionNotOnOrAfter) {
+		return nil, p.refreshErr(fmt.Errorf("SAML session expired at %s",
+			samlSessionData.SessionNotOnOrAfter.UTC().Format(time.RFC3339)))
+	}
+
+	return &resolvedprovider.RefreshedIdentity{
+		UpstreamUsername:       identity.UpstreamUsername,
+		UpstreamGroups:         identity.UpstreamGroups,
+		IDPSpecificSessionData: nil, // nil means that no update to the SAML-specific portion of the session data is required
+	}, nil
+}
+
+func (p *FederationDomainResolvedSAMLIdentityProvider) refreshErr(err error) *fosite.RFC6749Error {
+	return resolvedprovider.ErrUpstreamRefreshError().
+		WithHint("Upstream refresh failed.").
+		WithTrace(err).
+		WithDebugf("provider name: %q, provider type: %q", p.Provider.GetResourceName(), p.GetSessionProviderType())
+}
+
+// requestIDFromNonce returns the ID of the SAML AuthnRequest for a login. The nonce is random, is different
+// for each login, and is stored in the state param, so the callback can use it to check the InResponseTo of
+// the SAML response. SAML IDs must not start with a digit, so prefix it.
+func requestIDFromNonce(n nonce.Nonce) string {
+	return "id-" + n.String()
+}
@@ -0,0 +1,288 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package resolvedsaml
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ory/fosite"
+	"github.com/stretchr/testify/require"
+
+	idpdiscoveryv1alpha1 "go.pinniped.dev/generated/latest/apis/supervisor/idpdiscovery/v1alpha1"
+	"go.pinniped.dev/internal/federationdomain/resolvedprovider"
+	"go.pinniped.dev/internal/federationdomain/stateparam"
+	"go.pinniped.dev/internal/federationdomain/upstreamprovider"
+	"go.pinniped.dev/internal/httputil/httperr"
+	"go.pinniped.dev/internal/psession"
+	"go.pinniped.dev/internal/testutil/oidctestutil"
+	"go.pinniped.dev/internal/testutil/transformtestutil"
+)
+
+func TestFederationDomainResolvedSAMLIdentityProvider(t *testing.T) {
+	transforms := transformtestutil.NewRejectAllAuthPipeline(t)
+
+	provider := oidctestutil.NewTestUpstreamSAMLIdentityProviderBuilder().
+		WithName("fake-provider-config").
+		WithResourceUID("fake-resource-uid").
+		WithSSOURL("https://fake-sso-url").
+		Build()
+
+	subject := FederationDomainResolvedSAMLIdentityProvider{
+		DisplayName:         "fake-display-name",
+		Provider:            provider,
+		SessionProviderType: psession.ProviderTypeSAML,
+		Transforms:          transforms,
+	}
+
+	require.Equal(t, "fake-display-name", subject.GetDisplayName())
+	require.Equal(t, provider, subject.GetProvider())
+	require.Equal(t, psession.ProviderTypeSAML, subject.GetSessionProviderType())
+	require.Equal(t, idpdiscoveryv1alpha1.IDPTypeSAML, subject.GetIDPDiscoveryType())
+	require.Equal(t, []idpdiscoveryv1alpha1.IDPFlow{idpdiscoveryv1alpha1.IDPFlowBrowserAuthcode, idpdiscoveryv1alpha1.IDPFlowDeviceCode}, subject.GetIDPDiscoveryFlows())
+	require.Equal(t, transforms, subject.GetTransforms())
+
+	originalCustomSession := &psession.CustomSessionData{
+		Username:         "fake-username",
+		UpstreamUsername: "fake-upstream-username",
+		SAML:             &psession.SAMLSessionData{NameID: "fake-name-id"},
+	}
+	clonedCustomSession := subject.CloneIDPSpecificSessionDataFromSession(originalCustomSession)
+	require.Equal(t, &psession.SAMLSessionData{NameID: "fake-name-id"}, clonedCustomSession)
+	require.NotSame(t, originalCustomSession.SAML, clonedCustomSession)
+
+	require.Nil(t, subject.CloneIDPSpecificSessionDataFromSession(&psession.CustomSessionData{}))
+
+	customSessionToBeMutated := &psession.CustomSessionData{
+		Username:         "fake-username2",
+		UpstreamUsername: "fake-upstream-username2",
+	}
+	subject.ApplyIDPSpecificSessionDataToSession(customSessionToBeMutated, &psession.SAMLSessionData{NameID: "OTHER-name-id"})
+	require.Equal(t, &psession.CustomSessionData{
+		Username:         "fake-username2",
+		UpstreamUsername: "fake-upstream-username2",
+		SAML:             &psession.SAMLSessionData{NameID: "OTHER-name-id"},
+	}, customSessionToBeMutated)
+
+	redirectURL, err := subject.UpstreamAuthorizeRedirectURL(
+		&resolvedprovider.UpstreamAuthorizeRequestState{
+			EncodedStateParam: "encodedStateParam12345",
+			PKCE:              "pkce6789",
+			Nonce:             "nonce1289",
+		},
+		"https://localhost/fake/path",
+	)
+	require.NoError(t, err)
+	require.Equal(t,
+		"https://fake-sso-url?"+
+			"SAMLRequest=fake-authn-request%28id%3Did-nonce1289%2Cacs%3Dhttps%3A%2F%2Flocalhost%2Ffake%2Fpath%2Fcallback%2Csp%3Dhttps%3A%2F%2Flocalhost%2Ffake%2Fpath%29&"+
+			"RelayState="+stateparam.Encoded("encodedStateParam12345").RelayStateKey(),
+		redirectURL,
+	)
+	require.True(t, subject.RequiresUpstreamStateCookie())
+
+	identity, extras, err := subject.Login(context.Background(), "fake-username", "fake-password")
+	require.EqualError(t, err, "function Login not yet implemented for SAML IDP")
+	require.Nil(t, identity)
+	require.Nil(t, extras)
+}
+
+func TestLoginFromCallback(t *testing.T) {
+	sessionNotOnOrAfter := time.Date(2040, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	tests := []struct {
+		name        string
+		provider    *oidctestutil.TestUpstreamSAMLIdentityProvider
+		redirectURI string
+
+		wantParseResponseArgs *oidctestutil.ParseSAMLResponseArgs
+		wantIdentity          *resolvedprovider.Identity
+		wantExtras            *resolvedprovider.IdentityLoginExtras
+		wantErrMsg            string
+		wantErrResponseMsg    string
+		wantErrStatusCode     int
+	}{
+		{
+			name: "happy path",
+			provider: oidctestutil.NewTestUpstreamSAMLIdentityProviderBuilder().
+				WithUser(&upstreamprovider.SAMLUser{
+					Username:            "fake-username",
+					Groups:              []string{"fake-group1", "fake-group2"},
+					DownstreamSubject:   "https://fake-downstream-subject",
+					NameID:              "fake-name-id",
+					SessionNotOnOrAfter: sessionNotOnOrAfter,
+				}).
+				Build(),
+			redirectURI: "https://fake-issuer.com/some/path/callback",
+			wantParseResponseArgs: &oidctestutil.ParseSAMLResponseArgs{
+				EncodedResponse:   "fake-saml-response",
+				RequestID:         "id-fake-nonce",
+				ACSURL:            "https://fake-issuer.com/some/path/callback",
+				DefaultSPEntityID: "https://fake-issuer.com/some/path",
+				IDPDisplayName:    "fake-display-name",
+			},
+			wantIdentity: &resolvedprovider.Identity{
+				UpstreamUsername:  "fake-username",
+				UpstreamGroups:    []string{"fake-group1", "fake-group2"},
+				DownstreamSubject: "https://fake-downstream-subject",
+				IDPSpecificSessionData: &psession.SAMLSessionData{
+					NameID:              "fake-name-id",
+					SessionNotOnOrAfter: sessionNotOnOrAfter,
+				},
+			},
+			wantExtras: &resolvedprovider.IdentityLoginExtras{},
+		},
+		{
+			name: "error while parsing the SAML response",
+			provider: oidctestutil.NewTestUpstreamSAMLIdentityProviderBuilder().
+				WithParseResponseError(errors.New("fake parse error")).
+				Build(),
+			redirectURI: "https://fake-issuer.com/callback",
+			wantParseResponseArgs: &oidctestutil.ParseSAMLResponseArgs{
+				EncodedResponse:   "fake-saml-response",
+				RequestID:         "id-fake-nonce",
+				ACSURL:            "https://fake-issuer.com/callback",
+				DefaultSPEntityID: "https://fake-issuer.com",
+				IDPDisplayName:    "fake-display-name",
+			},
+			wantErrMsg:         "failed to validate SAML response: fake parse error",
+			wantErrResponseMsg: "Unprocessable Entity: failed to validate SAML response",
+			wantErrStatusCode:  http.StatusUnprocessableEntity,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			subject := FederationDomainResolvedSAMLIdentityProvider{
+				DisplayName:         "fake-display-name",
+				Provider:            test.provider,
+				SessionProviderType: psession.ProviderTypeSAML,
+				Transforms:          transformtestutil.NewRejectAllAuthPipeline(t),
+			}
+
+			identity, loginExtras, err := subject.LoginFromCallback(context.Background(),
+				"fake-saml-response",
+				"fake-pkce",
+				"fake-nonce",
+				test.redirectURI,
+			)
+
+			require.Equal(t, 1, test.provider.ParseResponseCallCount())
+			require.Equal(t, test.wantParseResponseArgs, test.provider.ParseResponseArgs(0))
+
+			if test.wantErrResponseMsg == "" {
+				require.NoError(t, err)
+			} else {
+				require.Implements(t, (*httperr.Responder)(nil), err)
+				errAsResponder := err.(httperr.Responder)
+				rec := httptest.NewRecorder()
+				errAsResponder.Respond(rec)
+				require.Equal(t, test.wantErrStatusCode, rec.Code)
+				require.Equal(t, test.wantErrResponseMsg+"\n", rec.Body.String())
+
+				require.EqualError(t, errAsResponder, test.wantErrMsg)
+			}
+			require.Equal(t, test.wantExtras, loginExtras)
+			require.Equal(t, test.wantIdentity, identity)
+		})
+	}
+}
+
+func TestUpstreamRefresh(t *testing.T) {
+	provider := oidctestutil.NewTestUpstreamSAMLIdentityProviderBuilder().WithName("fake-provider-name").Build()
+
+	tests := []struct {
+		name         string
+		identity     *resolvedprovider.Identity
+		wantIdentity *resolvedprovider.RefreshedIdentity
+		wantErr      string
+	}{
+		{
+			name: "happy path without a session time limit keeps the original identity",
+			identity: &resolvedprovider.Identity{
+				UpstreamUsername:       "fake-username",
+				UpstreamGroups:         []string{"fake-group1", "fake-group2"},
+				DownstreamSubject:      "https://fake-downstream-subject",
+				IDPSpecificSessionData: &psession.SAMLSessionData{NameID: "fake-name-id"},
+			},
+			wantIdentity: &resolvedprovider.RefreshedIdentity{
+				UpstreamUsername:       "fake-username",
+				UpstreamGroups:         []string{"fake-group1", "fake-group2"},
+				IDPSpecificSessionData: nil,
+			},
+		},
+		{
+			name: "happy path with a session time limit in the future",
+			identity: &resolvedprovider.Identity{
+				UpstreamUsername:  "fake-username",
+				UpstreamGroups:    []string{"fake-group1"},
+				DownstreamSubject: "https://fake-downstream-subject",
+				IDPSpecificSessionData: &psession.SAMLSessionData{
+					NameID:              "fake-name-id",
+					SessionNotOnOrAfter: time.Now().Add(time.Hour),
+				},
+			},
+			wantIdentity: &resolvedprovider.RefreshedIdentity{
+				UpstreamUsername:       "fake-username",
+				UpstreamGroups:         []string{"fake-group1"},
+				IDPSpecificSessionData: nil,
+			},
+		},
+		{
+			name: "session time limit has passed",
+			identity: &resolvedprovider.Identity{
+				UpstreamUsername:  "fake-username",
+				DownstreamSubject: "https://fake-downstream-subject",
+				IDPSpecificSessionData: &psession.SAMLSessionData{
+					NameID:              "fake-name-id",
+					SessionNotOnOrAfter: time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC),
+				},
+			},
+			wantErr: "SAML session expired at 2020-01-02T03:04:05Z",
+		},
+		{
+			name: "wrong session data type",
+			identity: &resolvedprovider.Identity{
+				IDPSpecificSessionData: &psession.GitHubSessionData{},
+			},
+			wantErr: "wrong data type found for IDPSpecificSessionData",
+		},
+		{
+			name: "missing NameID",
+			identity: &resolvedprovider.Identity{
+				IDPSpecificSessionData: &psession.SAMLSessionData{},
+			},
+			wantErr: "session is missing SAML NameID",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			subject := FederationDomainResolvedSAMLIdentityProvider{
+				DisplayName:         "fake-display-name",
+				Provider:            provider,
+				SessionProviderType: psession.ProviderTypeSAML,
+			}
+
+			refreshedIdentity, err := subject.UpstreamRefresh(context.Background(), test.identity)
+
+			if test.wantErr == "" {
+				require.NoError(t, err)
+			} else {
+				require.NotNil(t, err, "expected to get an error but did not get one")
+				errAsFositeErr, ok := err.(*fosite.RFC6749Error)
+				require.True(t, ok)
+				require.EqualError(t, errAsFositeErr.Unwrap(), test.wantErr)
+				require.Equal(t, "error", errAsFositeErr.ErrorField)
+				require.Equal(t, "Error during upstream refresh.", errAsFositeErr.DescriptionField)
+				require.Equal(t, http.StatusUnauthorized, errAsFositeErr.CodeField)
+				require.Equal(t, `provider name: "fake-provider-name", provider type: "saml"`, errAsFositeErr.DebugField)
+			}
+
+			require.Equal(t, test.wantIdentity, refreshedIdentity)
+		})
+	}
+}
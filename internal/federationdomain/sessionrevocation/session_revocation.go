@@ -0,0 +1,311 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package sessionrevocation ends downstream sessions before they expire, by revoking the upstream OIDC tokens
+// which are held by the session and by deleting the session's storage.
+package sessionrevocation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/ory/fosite"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+
+	oidcapi "go.pinniped.dev/generated/latest/apis/supervisor/oidc"
+	"go.pinniped.dev/internal/auditevent"
+	"go.pinniped.dev/internal/crud"
+	"go.pinniped.dev/internal/federationdomain/idplister"
+	"go.pinniped.dev/internal/federationdomain/upstreamprovider"
+	"go.pinniped.dev/internal/fositestorage"
+	"go.pinniped.dev/internal/fositestorage/accesstoken"
+	"go.pinniped.dev/internal/fositestorage/authorizationcode"
+	"go.pinniped.dev/internal/fositestorage/clientassertion"
+	"go.pinniped.dev/internal/fositestorage/devicecode"
+	"go.pinniped.dev/internal/fositestorage/openidconnect"
+	"go.pinniped.dev/internal/fositestorage/pkce"
+	"go.pinniped.dev/internal/fositestorage/pushedauthorizationrequest"
+	"go.pinniped.dev/internal/fositestorage/refreshtoken"
+	"go.pinniped.dev/internal/fositestoragei"
+	"go.pinniped.dev/internal/plog"
+	"go.pinniped.dev/internal/psession"
+)
+
+// Revoker ends downstream sessions on demand, e.g. when a client revokes one of its tokens or when the user logs out.
+type Revoker struct {
+	secrets     crud.SecretsBackend
+	idpCache    idplister.UpstreamOIDCIdentityProvidersLister
+	auditLogger plog.AuditLogger
+}
+
+// New returns a Revoker which finds and deletes session storage using the given Secrets client, and which
+// uses the given cache of upstream OIDC providers to revoke the upstream tokens held by the sessions.
+func New(
+	secrets crud.SecretsBackend,
+	idpCache idplister.UpstreamOIDCIdentityProvidersLister,
+	auditLogger plog.AuditLogger,
+) *Revoker {
+	return &Revoker{
+		secrets:     secrets,
+		idpCache:    idpCache,
+		auditLogger: auditLogger,
+	}
+}
+
+// RevokeSession revokes the upstream OIDC tokens held by the downstream session with the given request ID,
+// and then deletes all the storage of that session, e.g. its authcode, PKCE, OIDC, access token, refresh token,
+// and device code storage.
+// Failure to revoke upstream tokens is logged but does not prevent the downstream session from being revoked,
+// since the user is asking for their session to end. Returns an error wrapping fosite.ErrNotFound when there is
+// no storage for the session, e.g. because it was already revoked.
+func (r *Revoker) RevokeSession(ctx context.Context, requestID string) error {
+	secretList, err := r.secrets.List(ctx, metav1.ListOptions{
+		LabelSelector: labels.Set{fositestorage.StorageRequestIDLabelName: requestID}.String(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list session storage: %w", err)
+	}
+	if len(secretList.Items) == 0 {
+		return fosite.ErrNotFound.WithDebug("no session storage found")
+	}
+
+	storageTypes := make([]string, 0, len(secretList.Items))
+	for i := range secretList.Items {
+		secret := &secretList.Items[i]
+		storageType := secret.Labels[crud.SecretLabelKey]
+
+		if revokeErr := MaybeRevokeUpstreamOIDCToken(ctx, r.idpCache, r.auditLogger, storageType, secret); revokeErr != nil {
+			plog.WarningErr("failed to revoke upstream OIDC token while revoking downstream session", revokeErr,
+				"secretName", secret.Name, "storageType", storageType)
+		}
+
+		err = r.secrets.Delete(ctx, secret.Name, metav1.DeleteOptions{
+			Preconditions: &metav1.Preconditions{UID: &secret.UID, ResourceVersion: &secret.ResourceVersion},
+		})
+		if err != nil && !apierrors.IsNotFound(err) {
+			return fmt.Errorf("failed to delete session storage %s: %w", secret.Name, err)
+		}
+
+		storageTypes = append(storageTypes, storageType)
+	}
+
+	slices.Sort(storageTypes)
+	r.auditLogger.Audit(auditevent.SessionRevoked, &plog.AuditParams{
+		ReqCtx:        ctx,
+		Session:       sessionID(requestID),
+		KeysAndValues: []any{"storageTypes", storageTypes},
+	})
+
+	return nil
+}
+
+// WrapStorage returns storage which behaves like the given storage, except that revoking a refresh token or an access
+// token revokes the whole downstream session using RevokeSession. This allows the fosite token revocation handler
+// to perform the same upstream revocation that the garbage collector performs when a session expires.
+func (r *Revoker) WrapStorage(storage fositestoragei.AllFositeStorage) fositestoragei.AllFositeStorage {
+	return &revokingStorage{AllFositeStorage: storage, revoker: r}
+}
+
+type revokingStorage struct {
+	fositestoragei.AllFositeStorage
+	revoker *Revoker
+}
+
+// RevokeRefreshToken is called first by fosite, so it revokes the whole session.
+func (s *revokingStorage) RevokeRefreshToken(ctx context.Context, requestID string) error {
+	return s.revoker.RevokeSession(ctx, requestID)
+}
+
+// RevokeAccessToken is called after RevokeRefreshToken by fosite, so the session was usually already revoked.
+// In that case, fosite treats the resulting fosite.ErrNotFound as success.
+func (s *revokingStorage) RevokeAccessToken(ctx context.Context, requestID string) error {
+	return s.revoker.RevokeSession(ctx, requestID)
+}
+
+type sessionID string
+
+func (s sessionID) GetID() string { return string(s) }
+
+// MaybeRevokeUpstreamOIDCToken revokes the upstream OIDC token held by the session in the given session storage
+// Secret, but only when that Secret holds the latest upstream token for the session.
+func MaybeRevokeUpstreamOIDCToken(
+	ctx context.Context,
+	idpCache idplister.UpstreamOIDCIdentityProvidersLister,
+	auditLogger plog.AuditLogger,
+	storageType string,
+	secret *corev1.Secret,
+) error {
+	// All downstream session storage types hold upstream tokens when the upstream IDP is an OIDC provider.
+	// However, some of them will be outdated because they are not updated by fosite after creation.
+	// Our goal below is to always revoke the latest upstream refresh token that we are holding for the
+	// session, and only the latest, or to revoke the original upstream access token. Note that we don't
+	// bother to store new upstream access tokens seen during upstream refresh because we only need to store
+	// the upstream access token when we intend to use it *instead* of an upstream refresh token.
+	// This implies that all the storage types will contain a copy of the original upstream access token,
+	// since it is never updated in the session. Thus, we can use the same logic to decide which upstream
+	// access token to revoke as we use for upstream refresh tokens, which allows us to avoid revoking an
+	// upstream access token more than once.
+	switch storageType {
+	case authorizationcode.TypeLabelValue:
+		authorizeCodeSession, err := authorizationcode.ReadFromSecret(secret)
+		if err != nil {
+			return err
+		}
+		// Check if this downstream authcode was already used. If it was already used (i.e. not active anymore),
+		// then the latest upstream token can be found in one of the other storage types handled below instead.
+		if !authorizeCodeSession.Active {
+			return nil
+		}
+		// When the downstream authcode was never used, then its storage must contain the latest upstream token.
+		return tryRevokeUpstreamOIDCToken(ctx, idpCache, auditLogger,
+			authorizeCodeSession.Request.Session.(*psession.PinnipedSession).Custom,
+			authorizeCodeSession.Request,
+			secret)
+
+	case accesstoken.TypeLabelValue:
+		// For access token storage, check if the "offline_access" scope was granted on the downstream session.
+		// If it was granted, then the latest upstream token should be found in the refresh token storage instead.
+		// If it was not granted, then the user could not possibly have performed a downstream refresh, so the
+		// access token storage has the latest version of the upstream token.
+		accessTokenSession, err := accesstoken.ReadFromSecret(secret)
+		if err != nil {
+			return err
+		}
+		if accessTokenSession.Request.GetGrantedScopes().Has(oidcapi.ScopeOfflineAccess) {
+			return nil
+		}
+		return tryRevokeUpstreamOIDCToken(ctx, idpCache, auditLogger,
+			accessTokenSession.Request.Session.(*psession.PinnipedSession).Custom,
+			accessTokenSession.Request,
+			secret)
+
+	case refreshtoken.TypeLabelValue:
+		// For refresh token storage, always revoke its upstream token. This refresh token storage could be
+		// the result of the initial downstream authcode exchange, or it could be the result of a downstream
+		// refresh. Either way, it always contains the latest upstream token when it exists.
+		refreshTokenSession, err := refreshtoken.ReadFromSecret(secret)
+		if err != nil {
+			return err
+		}
+		return tryRevokeUpstreamOIDCToken(ctx, idpCache, auditLogger,
+			refreshTokenSession.Request.Session.(*psession.PinnipedSession).Custom,
+			refreshTokenSession.Request,
+			secret)
+
+	case pkce.TypeLabelValue:
+		// For PKCE storage, its very existence means that the downstream authcode was never exchanged, because
+		// these are deleted during downstream authcode exchange. No need to do anything, since the upstream
+		// token revocation is handled by authcode storage case above.
+		return nil
+
+	case openidconnect.TypeLabelValue:
+		// For OIDC storage, there is no need to do anything for reasons similar to the PKCE storage.
+		// These are deleted during downstream authcode exchange. The upstream token contained inside will
+		// be revoked by one of the other cases above.
+		return nil
+
+	case devicecode.TypeLabelValue:
+		deviceCodeSession, err := devicecode.ReadFromSecret(secret)
+		if err != nil {
+			return err
+		}
+		// Similar to authcodes, if the downstream device code was already used, then the latest upstream token
+		// can be found in the access token or refresh token storage instead. If the user never finished logging in
+		// with their upstream IDP, then there is no upstream token in the session yet.
+		if !deviceCodeSession.Active || deviceCodeSession.UserCodeState != fosite.UserCodeAccepted {
+			return nil
+		}
+		return tryRevokeUpstreamOIDCToken(ctx, idpCache, auditLogger,
+			deviceCodeSession.Request.Session.(*psession.PinnipedSession).Custom,
+			deviceCodeSession.Request,
+			secret)
+
+	case devicecode.UserCodeTypeLabelValue:
+		// User code storage only points to device code storage, so it never contains any upstream tokens.
+		return nil
+
+	case clientassertion.TypeLabelValue:
+		// Client assertion storage belongs to a client, not to a session, so it never contains any upstream tokens.
+		return nil
+
+	case pushedauthorizationrequest.TypeLabelValue:
+		// Pushed authorization requests are stored before the user logs in, so they never contain any upstream tokens.
+		return nil
+
+	default:
+		// There are no other storage types, so this should never happen in practice.
+		return errors.New("saw invalid label on Secret when trying to determine if upstream revocation was needed")
+	}
+}
+
+func tryRevokeUpstreamOIDCToken(
+	ctx context.Context,
+	idpCache idplister.UpstreamOIDCIdentityProvidersLister,
+	auditLogger plog.AuditLogger,
+	customSessionData *psession.CustomSessionData,
+	request *fosite.Request,
+	secret *corev1.Secret,
+) error {
+	// When session was for another upstream IDP type, e.g. LDAP, there is no upstream OIDC token involved.
+	if customSessionData.ProviderType != psession.ProviderTypeOIDC {
+		return nil
+	}
+
+	// Try to find the provider that was originally used to create the stored session.
+	var foundOIDCIdentityProviderI upstreamprovider.UpstreamOIDCIdentityProviderI
+	for _, p := range idpCache.GetOIDCIdentityProviders() {
+		if p.GetResourceName() == customSessionData.ProviderName && p.GetResourceUID() == customSessionData.ProviderUID {
+			foundOIDCIdentityProviderI = p
+			break
+		}
+	}
+	if foundOIDCIdentityProviderI == nil {
+		return fmt.Errorf("could not find upstream OIDC provider named %q with resource UID %q", customSessionData.ProviderName, customSessionData.ProviderUID)
+	}
+
+	// In practice, there should only be one of these tokens saved in the session.
+	upstreamRefreshToken := customSessionData.OIDC.UpstreamRefreshToken
+	upstreamAccessToken := customSessionData.OIDC.UpstreamAccessToken
+
+	if upstreamRefreshToken != "" {
+		err := foundOIDCIdentityProviderI.RevokeToken(ctx, upstreamRefreshToken, upstreamprovider.RefreshTokenType)
+		if err != nil {
+			return err
+		}
+		auditLogger.Audit(auditevent.UpstreamOIDCTokenRevoked, &plog.AuditParams{
+			ReqCtx:        ctx,
+			Session:       request,
+			KeysAndValues: []any{"type", upstreamprovider.RefreshTokenType},
+		})
+		plog.Trace("successfully revoked upstream OIDC refresh token (or provider has no revocation endpoint)", logKV(secret)...)
+	}
+
+	if upstreamAccessToken != "" {
+		err := foundOIDCIdentityProviderI.RevokeToken(ctx, upstreamAccessToken, upstreamprovider.AccessTokenType)
+		if err != nil {
+			return err
+		}
+		auditLogger.Audit(auditevent.UpstreamOIDCTokenRevoked, &plog.AuditParams{
+			ReqCtx:        ctx,
+			Session:       request,
+			KeysAndValues: []any{"type", upstreamprovider.AccessTokenType},
+		})
+		plog.Trace("successfully revoked upstream OIDC access token (or provider has no revocation endpoint)", logKV(secret)...)
+	}
+
+	return nil
+}
+
+func logKV(secret *corev1.Secret) []any {
+	return []any{
+		"secretName", secret.Name,
+		"secretNamespace", secret.Namespace,
+		"secretType", string(secret.Type),
+		"storageTypeLabelValue", secret.Labels[crud.SecretLabelKey],
+	}
+}
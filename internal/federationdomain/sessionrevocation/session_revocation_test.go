@@ -0,0 +1,201 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package sessionrevocation
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ory/fosite"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+
+	supervisorfake "go.pinniped.dev/generated/latest/client/supervisor/clientset/versioned/fake"
+	"go.pinniped.dev/internal/federationdomain/clientregistry"
+	"go.pinniped.dev/internal/federationdomain/dynamicupstreamprovider"
+	"go.pinniped.dev/internal/federationdomain/oidc"
+	"go.pinniped.dev/internal/federationdomain/storage"
+	"go.pinniped.dev/internal/federationdomain/upstreamprovider"
+	"go.pinniped.dev/internal/plog"
+	"go.pinniped.dev/internal/psession"
+	"go.pinniped.dev/internal/testutil"
+	"go.pinniped.dev/internal/testutil/oidctestutil"
+	"go.pinniped.dev/internal/testutil/testidplister"
+)
+
+func TestRevokeSession(t *testing.T) {
+	const (
+		requestID      = "some-request-id"
+		otherRequestID = "some-other-request-id"
+	)
+
+	newRequest := func(id string, providerType psession.ProviderType, scopes ...string) *fosite.Request {
+		request := fosite.NewRequest()
+		request.ID = id
+		request.Client = clientregistry.PinnipedCLI()
+		request.GrantedScope = scopes
+		request.Session = &psession.PinnipedSession{
+			Fosite: psession.NewPinnipedSession().Fosite,
+			Custom: &psession.CustomSessionData{
+				Username:     "some-username",
+				ProviderUID:  "upstream-oidc-provider-uid",
+				ProviderName: "upstream-oidc-provider-name",
+				ProviderType: providerType,
+				//nolint:gosec // not a real credential
+				OIDC: &psession.OIDCSessionData{
+					UpstreamRefreshToken: "fake-upstream-refresh-token",
+				},
+			},
+		}
+		return request
+	}
+
+	tests := []struct {
+		name              string
+		requests          []*fosite.Request
+		revokeTokenErr    error
+		wantErr           error
+		wantUpstreamCalls int
+		wantRemaining     int
+		wantAuditLogs     []testutil.WantedAuditLog
+	}{
+		{
+			name: "revokes the upstream refresh token and deletes all storage of the session",
+			requests: []*fosite.Request{
+				newRequest(requestID, psession.ProviderTypeOIDC, "openid", "offline_access"),
+				newRequest(otherRequestID, psession.ProviderTypeOIDC, "openid", "offline_access"),
+			},
+			wantUpstreamCalls: 1,
+			wantRemaining:     5,
+			wantAuditLogs: []testutil.WantedAuditLog{
+				testutil.WantAuditLog("Upstream OIDC Token Revoked", map[string]any{
+					"sessionID": requestID,
+					"type":      "refresh_token",
+				}),
+				testutil.WantAuditLog("Session Revoked", map[string]any{
+					"sessionID":    requestID,
+					"storageTypes": []any{"access-token", "authcode", "oidc", "pkce", "refresh-token"},
+				}),
+			},
+		},
+		{
+			name: "does not try to revoke upstream tokens for other types of upstream IDPs",
+			requests: []*fosite.Request{
+				newRequest(requestID, psession.ProviderTypeLDAP, "openid", "offline_access"),
+			},
+			wantUpstreamCalls: 0,
+			wantAuditLogs: []testutil.WantedAuditLog{
+				testutil.WantAuditLog("Session Revoked", map[string]any{
+					"sessionID":    requestID,
+					"storageTypes": []any{"access-token", "authcode", "oidc", "pkce", "refresh-token"},
+				}),
+			},
+		},
+		{
+			name: "still deletes the storage when upstream revocation fails",
+			requests: []*fosite.Request{
+				newRequest(requestID, psession.ProviderTypeOIDC, "openid", "offline_access"),
+			},
+			revokeTokenErr:    dynamicupstreamprovider.NewRetryableRevocationError(errors.New("some upstream revocation error")),
+			wantUpstreamCalls: 1,
+			wantAuditLogs: []testutil.WantedAuditLog{
+				testutil.WantAuditLog("Session Revoked", map[string]any{
+					"sessionID":    requestID,
+					"storageTypes": []any{"access-token", "authcode", "oidc", "pkce", "refresh-token"},
+				}),
+			},
+		},
+		{
+			name: "session not found",
+			requests: []*fosite.Request{
+				newRequest(otherRequestID, psession.ProviderTypeOIDC, "openid", "offline_access"),
+			},
+			wantErr:       fosite.ErrNotFound,
+			wantRemaining: 5,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			kubeClient := kubefake.NewClientset()
+			secrets := kubeClient.CoreV1().Secrets("some-namespace")
+			oidcClientsClient := supervisorfake.NewSimpleClientset().ConfigV1alpha1().OIDCClients("some-namespace")
+			kubeStorage := storage.NewKubeStorage(secrets, oidcClientsClient, oidc.DefaultOIDCTimeoutsConfiguration(), bcrypt.MinCost)
+
+			for i, request := range test.requests {
+				// Create all the storage of a session after its authcode was exchanged.
+				require.NoError(t, kubeStorage.CreateAuthorizeCodeSession(t.Context(), "authcode-signature-"+request.ID, request))
+				require.NoError(t, kubeStorage.InvalidateAuthorizeCodeSession(t.Context(), "authcode-signature-"+request.ID))
+				require.NoError(t, kubeStorage.CreatePKCERequestSession(t.Context(), "authcode-signature-"+request.ID, request))
+				require.NoError(t, kubeStorage.CreateOpenIDConnectSession(t.Context(), "authcode."+"authcode-signature-"+request.ID, request))
+				require.NoError(t, kubeStorage.CreateAccessTokenSession(t.Context(), "access-token-signature-"+request.ID, request))
+				require.NoError(t, kubeStorage.CreateRefreshTokenSession(t.Context(), "refresh-token-signature-"+request.ID, "", request), "request %d", i)
+			}
+
+			upstream := oidctestutil.NewTestUpstreamOIDCIdentityProviderBuilder().
+				WithName("upstream-oidc-provider-name").
+				WithResourceUID("upstream-oidc-provider-uid").
+				WithRevokeTokenError(test.revokeTokenErr).
+				Build()
+			idpCache := testidplister.NewUpstreamIDPListerBuilder().WithOIDC(upstream).BuildDynamicUpstreamIDPProvider()
+			auditLogger, actualAuditLog := plog.TestAuditLogger(t)
+
+			err := New(secrets, idpCache, auditLogger).RevokeSession(t.Context(), requestID)
+			if test.wantErr != nil {
+				require.ErrorIs(t, err, test.wantErr)
+			} else {
+				require.NoError(t, err)
+			}
+
+			require.Equal(t, test.wantUpstreamCalls, upstream.RevokeTokenCallCount())
+			if test.wantUpstreamCalls > 0 {
+				require.Equal(t, "fake-upstream-refresh-token", upstream.RevokeTokenArgs(0).Token)
+				require.Equal(t, upstreamprovider.RefreshTokenType, upstream.RevokeTokenArgs(0).TokenType)
+			}
+
+			remaining, err := secrets.List(t.Context(), metav1.ListOptions{})
+			require.NoError(t, err)
+			require.Len(t, remaining.Items, test.wantRemaining)
+			for _, secret := range remaining.Items {
+				require.Equal(t, otherRequestID, secret.Labels["storage.pinniped.dev/request-id"])
+			}
+
+			testutil.CompareAuditLogs(t, test.wantAuditLogs, actualAuditLog.String())
+		})
+	}
+}
+
+func TestWrapStorage(t *testing.T) {
+	kubeClient := kubefake.NewClientset()
+	secrets := kubeClient.CoreV1().Secrets("some-namespace")
+	oidcClientsClient := supervisorfake.NewSimpleClientset().ConfigV1alpha1().OIDCClients("some-namespace")
+	kubeStorage := storage.NewKubeStorage(secrets, oidcClientsClient, oidc.DefaultOIDCTimeoutsConfiguration(), bcrypt.MinCost)
+
+	request := fosite.NewRequest()
+	request.ID = "some-request-id"
+	request.Client = clientregistry.PinnipedCLI()
+	request.Session = psession.NewPinnipedSession()
+	request.Session.(*psession.PinnipedSession).Custom = &psession.CustomSessionData{ProviderType: psession.ProviderTypeLDAP}
+	require.NoError(t, kubeStorage.CreateAccessTokenSession(t.Context(), "access-token-signature", request))
+	require.NoError(t, kubeStorage.CreateRefreshTokenSession(t.Context(), "refresh-token-signature", "", request))
+
+	auditLogger, _ := plog.TestAuditLogger(t)
+	idpCache := testidplister.NewUpstreamIDPListerBuilder().BuildDynamicUpstreamIDPProvider()
+	subject := New(secrets, idpCache, auditLogger).WrapStorage(kubeStorage)
+
+	// Revoking the refresh token revokes the whole session, like fosite expects.
+	require.NoError(t, subject.RevokeRefreshToken(t.Context(), "some-request-id"))
+	remaining, err := secrets.List(t.Context(), metav1.ListOptions{})
+	require.NoError(t, err)
+	require.Empty(t, remaining.Items)
+
+	// Then revoking the access token finds nothing, which fosite treats as success.
+	require.ErrorIs(t, subject.RevokeAccessToken(t.Context(), "some-request-id"), fosite.ErrNotFound)
+
+	// Other methods are delegated.
+	_, err = subject.GetClient(t.Context(), "pinniped-cli")
+	require.NoError(t, err)
+}
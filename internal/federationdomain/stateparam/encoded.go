@@ -1,10 +1,11 @@
-// Copyright 2024 the Pinniped contributors. All Rights Reserved.
+// Copyright 2024-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package stateparam
 
 import (
 	"crypto/sha256"
+	"encoding/base64"
 	"fmt"
 )
 
@@ -17,3 +18,10 @@ func (e Encoded) String() string {
 func (e Encoded) AuthorizeID() string {
 	return fmt.Sprintf("%x", sha256.Sum256([]byte(e)))
 }
+
+// RelayStateKey returns a short opaque key which identifies the encoded state param. It is sent to upstreams
+// which cannot carry the whole state param, e.g. SAML limits its RelayState param to 80 bytes.
+func (e Encoded) RelayStateKey() string {
+	sum := sha256.Sum256([]byte(e))
+	return base64.RawURLEncoding.EncodeToString(sum[:16])
+}
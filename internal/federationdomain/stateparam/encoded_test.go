@@ -1,9 +1,10 @@
-// Copyright 2024 the Pinniped contributors. All Rights Reserved.
+// Copyright 2024-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package stateparam
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -20,3 +21,11 @@ func TestAuthorizeID(t *testing.T) {
 	require.Equal(t, "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855",
 		Encoded("").AuthorizeID())
 }
+
+func TestRelayStateKey(t *testing.T) {
+	// $ echo -n "foo" | shasum -a 256 | cut -c1-32 | xxd -r -p | base64 | tr '+/' '-_' | tr -d '='
+	require.Equal(t, "LCa0a2j_xo_5m0U8HTBBNA", Encoded("foo").RelayStateKey())
+
+	// Even a long state param results in a short key, since SAML limits its RelayState param to 80 bytes.
+	require.Len(t, Encoded(strings.Repeat("x", 4096)).RelayStateKey(), 22)
+}
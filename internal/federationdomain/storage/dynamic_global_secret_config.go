@@ -1,4 +1,4 @@
-// Copyright 2020-2025 the Pinniped contributors. All Rights Reserved.
+// Copyright 2020-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package storage
@@ -19,7 +19,11 @@ type DynamicGlobalSecretConfig struct {
 	keyFunc      func() []byte
 }
 
-var _ compose.HMACSHAStrategyConfigurator = &DynamicGlobalSecretConfig{}
+var (
+	_ compose.HMACSHAStrategyConfigurator = &DynamicGlobalSecretConfig{}
+	_ fosite.DeviceProvider               = &DynamicGlobalSecretConfig{}
+	_ fosite.UserCodeProvider             = &DynamicGlobalSecretConfig{}
+)
 
 func NewDynamicGlobalSecretConfig(
 	fositeConfig *fosite.Config,
@@ -62,6 +66,22 @@ func (d *DynamicGlobalSecretConfig) GetRotatedGlobalSecrets(_ctx context.Context
 	return nil, nil
 }
 
-func (d *DynamicGlobalSecretConfig) GetDeviceAndUserCodeLifespan(_ctx context.Context) time.Duration {
-	return d.fositeConfig.DeviceAndUserCodeLifespan
+func (d *DynamicGlobalSecretConfig) GetDeviceAndUserCodeLifespan(ctx context.Context) time.Duration {
+	return d.fositeConfig.GetDeviceAndUserCodeLifespan(ctx)
+}
+
+func (d *DynamicGlobalSecretConfig) GetDeviceVerificationURL(ctx context.Context) string {
+	return d.fositeConfig.GetDeviceVerificationURL(ctx)
+}
+
+func (d *DynamicGlobalSecretConfig) GetDeviceAuthTokenPollingInterval(ctx context.Context) time.Duration {
+	return d.fositeConfig.GetDeviceAuthTokenPollingInterval(ctx)
+}
+
+func (d *DynamicGlobalSecretConfig) GetUserCodeLength(ctx context.Context) int {
+	return d.fositeConfig.GetUserCodeLength(ctx)
+}
+
+func (d *DynamicGlobalSecretConfig) GetUserCodeSymbols(ctx context.Context) []rune {
+	return d.fositeConfig.GetUserCodeSymbols(ctx)
 }
@@ -1,4 +1,4 @@
-// Copyright 2020-2024 the Pinniped contributors. All Rights Reserved.
+// Copyright 2020-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package storage
@@ -14,12 +14,16 @@ import (
 	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
 
 	"go.pinniped.dev/generated/latest/client/supervisor/clientset/versioned/typed/config/v1alpha1"
+	"go.pinniped.dev/internal/crud"
 	"go.pinniped.dev/internal/federationdomain/clientregistry"
 	"go.pinniped.dev/internal/federationdomain/timeouts"
 	"go.pinniped.dev/internal/fositestorage/accesstoken"
 	"go.pinniped.dev/internal/fositestorage/authorizationcode"
+	"go.pinniped.dev/internal/fositestorage/clientassertion"
+	"go.pinniped.dev/internal/fositestorage/devicecode"
 	"go.pinniped.dev/internal/fositestorage/openidconnect"
 	"go.pinniped.dev/internal/fositestorage/pkce"
+	"go.pinniped.dev/internal/fositestorage/pushedauthorizationrequest"
 	"go.pinniped.dev/internal/fositestorage/refreshtoken"
 	"go.pinniped.dev/internal/fositestoragei"
 	"go.pinniped.dev/internal/oidcclientsecretstorage"
@@ -32,6 +36,8 @@ type KubeStorage struct {
 	oidcStorage              openid.OpenIDConnectRequestStorage
 	accessTokenStorage       accesstoken.RevocationStorage
 	refreshTokenStorage      refreshtoken.RevocationStorage
+	deviceCodeStorage        devicecode.RevocationStorage
+	parStorage               fosite.PARStorage
 }
 
 var _ fositestoragei.AllFositeStorage = &KubeStorage{}
@@ -41,15 +47,34 @@ func NewKubeStorage(
 	oidcClientsClient v1alpha1.OIDCClientInterface,
 	timeoutsConfiguration timeouts.Configuration,
 	minBcryptCost int,
+) *KubeStorage {
+	return NewKubeStorageWithSessionStorage(secrets, secrets, oidcClientsClient, "", timeoutsConfiguration, minBcryptCost)
+}
+
+// NewKubeStorageWithSessionStorage is like NewKubeStorage, except that the session data (authcodes, tokens, etc.)
+// is kept in sessionStorage instead of in Secrets. OIDCClient secrets are always kept in Secrets.
+// The federationDomainName is the name of the FederationDomain which uses the storage, which is needed
+// to find the OIDCClients which were registered by its dynamic client registration endpoint.
+func NewKubeStorageWithSessionStorage(
+	secrets corev1client.SecretInterface,
+	sessionStorage crud.SecretsBackend,
+	oidcClientsClient v1alpha1.OIDCClientInterface,
+	federationDomainName string,
+	timeoutsConfiguration timeouts.Configuration,
+	minBcryptCost int,
 ) *KubeStorage {
 	nowFunc := time.Now
 	return &KubeStorage{
-		clientManager:            clientregistry.NewClientManager(oidcClientsClient, oidcclientsecretstorage.New(secrets), minBcryptCost),
-		authorizationCodeStorage: authorizationcode.New(secrets, nowFunc, timeoutsConfiguration.AuthorizationCodeSessionStorageLifetime),
-		pkceStorage:              pkce.New(secrets, nowFunc, timeoutsConfiguration.PKCESessionStorageLifetime),
-		oidcStorage:              openidconnect.New(secrets, nowFunc, timeoutsConfiguration.OIDCSessionStorageLifetime),
-		accessTokenStorage:       accesstoken.New(secrets, nowFunc, timeoutsConfiguration.AccessTokenSessionStorageLifetime),
-		refreshTokenStorage:      refreshtoken.New(secrets, nowFunc, timeoutsConfiguration.RefreshTokenSessionStorageLifetime),
+		clientManager:            clientregistry.NewClientManager(oidcClientsClient, oidcclientsecretstorage.New(secrets), clientassertion.New(sessionStorage, nowFunc), federationDomainName, minBcryptCost),
+		authorizationCodeStorage: authorizationcode.New(sessionStorage, nowFunc, timeoutsConfiguration.AuthorizationCodeSessionStorageLifetime),
+		pkceStorage:              pkce.New(sessionStorage, nowFunc, timeoutsConfiguration.PKCESessionStorageLifetime),
+		oidcStorage:              openidconnect.New(sessionStorage, nowFunc, timeoutsConfiguration.OIDCSessionStorageLifetime),
+		accessTokenStorage:       accesstoken.New(sessionStorage, nowFunc, timeoutsConfiguration.AccessTokenSessionStorageLifetime),
+		refreshTokenStorage:      refreshtoken.New(sessionStorage, nowFunc, timeoutsConfiguration.RefreshTokenSessionStorageLifetime),
+		deviceCodeStorage: devicecode.New(sessionStorage, nowFunc,
+			timeoutsConfiguration.DeviceCodeSessionStorageLifetime, timeoutsConfiguration.UserCodeSessionStorageLifetime),
+		parStorage: pushedauthorizationrequest.New(sessionStorage, nowFunc,
+			timeoutsConfiguration.PushedAuthorizationRequestSessionStorageLifetime),
 	}
 }
 
@@ -195,6 +220,68 @@ func (k KubeStorage) RotateRefreshToken(ctx context.Context, requestID string, _
 	return k.accessTokenStorage.RevokeAccessToken(ctx, requestID)
 }
 
+//
+// Device code sessions:
+//
+// These are keyed by the signature of the device code. Each is created along with a user code session, which is keyed
+// by the signature of the user code and points to the device code session.
+//
+// Fosite will create these in the device authorization endpoint.
+//
+// The user code entry page will find these using the user code, and will mark them as accepted after the user has
+// successfully logged in with their upstream identity provider. That also deletes the user code session.
+//
+// Fosite will never delete them. Instead, it wants to mark them as invalidated once the device code is used to redeem
+// tokens. That way, it can later detect the case where a device code that was already redeemed gets used again.
+//
+
+func (k KubeStorage) CreateDeviceAuthSession(ctx context.Context, signatureOfDeviceCode string, signatureOfUserCode string, request fosite.DeviceRequester) error {
+	return k.deviceCodeStorage.CreateDeviceAuthSession(ctx, signatureOfDeviceCode, signatureOfUserCode, request)
+}
+
+func (k KubeStorage) GetDeviceCodeSession(ctx context.Context, signatureOfDeviceCode string, session fosite.Session) (fosite.DeviceRequester, error) {
+	return k.deviceCodeStorage.GetDeviceCodeSession(ctx, signatureOfDeviceCode, session)
+}
+
+func (k KubeStorage) InvalidateDeviceCodeSession(ctx context.Context, signatureOfDeviceCode string) error {
+	return k.deviceCodeStorage.InvalidateDeviceCodeSession(ctx, signatureOfDeviceCode)
+}
+
+func (k KubeStorage) GetDeviceCodeSessionByUserCode(ctx context.Context, signatureOfUserCode string) (fosite.DeviceRequester, error) {
+	return k.deviceCodeStorage.GetDeviceCodeSessionByUserCode(ctx, signatureOfUserCode)
+}
+
+func (k KubeStorage) ApproveDeviceCodeSession(ctx context.Context, signatureOfUserCode string, request fosite.DeviceRequester) error {
+	return k.deviceCodeStorage.ApproveDeviceCodeSession(ctx, signatureOfUserCode, request)
+}
+
+func (k KubeStorage) RejectDeviceCodeSession(ctx context.Context, signatureOfUserCode string) error {
+	return k.deviceCodeStorage.RejectDeviceCodeSession(ctx, signatureOfUserCode)
+}
+
+//
+// Pushed authorization requests:
+//
+// These are keyed by the request_uri which was issued by the pushed authorization request endpoint.
+//
+// Fosite will create these in the pushed authorization request endpoint.
+//
+// Fosite will look these up and delete them in the authorization endpoint when the client sends the request_uri,
+// so each can only be used once. If the client never uses the request_uri, then fosite will never delete these.
+//
+
+func (k KubeStorage) CreatePARSession(ctx context.Context, requestURI string, request fosite.AuthorizeRequester) error {
+	return k.parStorage.CreatePARSession(ctx, requestURI, request)
+}
+
+func (k KubeStorage) GetPARSession(ctx context.Context, requestURI string) (fosite.AuthorizeRequester, error) {
+	return k.parStorage.GetPARSession(ctx, requestURI)
+}
+
+func (k KubeStorage) DeletePARSession(ctx context.Context, requestURI string) error {
+	return k.parStorage.DeletePARSession(ctx, requestURI)
+}
+
 //
 // OAuth client definitions:
 //
@@ -1,17 +1,21 @@
-// Copyright 2020-2024 the Pinniped contributors. All Rights Reserved.
+// Copyright 2020-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package storage
 
 import (
 	"context"
+	"time"
 
 	"github.com/ory/fosite"
 	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
 
 	"go.pinniped.dev/generated/latest/client/supervisor/clientset/versioned/typed/config/v1alpha1"
 	"go.pinniped.dev/internal/constable"
+	"go.pinniped.dev/internal/crud"
 	"go.pinniped.dev/internal/federationdomain/clientregistry"
+	"go.pinniped.dev/internal/fositestorage/clientassertion"
+	"go.pinniped.dev/internal/fositestorage/pushedauthorizationrequest"
 	"go.pinniped.dev/internal/fositestoragei"
 	"go.pinniped.dev/internal/oidcclientsecretstorage"
 )
@@ -21,6 +25,10 @@ const errNullStorageNotImplemented = constable.Error("NullStorage does not imple
 type NullStorage struct {
 	// The authorization endpoint uses NullStorage to avoid saving any data, but it still needs to perform client lookups.
 	*clientregistry.ClientManager
+
+	// The authorization endpoint also needs to look up and delete the pushed authorization requests
+	// which were created by the pushed authorization request endpoint.
+	parStorage fosite.PARStorage
 }
 
 var _ fositestoragei.AllFositeStorage = &NullStorage{}
@@ -29,9 +37,25 @@ func NewNullStorage(
 	secrets corev1client.SecretInterface,
 	oidcClientsClient v1alpha1.OIDCClientInterface,
 	minBcryptCost int,
+) *NullStorage {
+	return NewNullStorageWithSessionStorage(secrets, secrets, oidcClientsClient, "", minBcryptCost)
+}
+
+// NewNullStorageWithSessionStorage is like NewNullStorage, except that the client assertions which are
+// remembered during client lookups, and the pushed authorization requests, are kept in sessionStorage
+// instead of in Secrets. The federationDomainName is the name of the FederationDomain which uses the storage,
+// which is needed to find the OIDCClients which were registered by its dynamic client registration endpoint.
+func NewNullStorageWithSessionStorage(
+	secrets corev1client.SecretInterface,
+	sessionStorage crud.SecretsBackend,
+	oidcClientsClient v1alpha1.OIDCClientInterface,
+	federationDomainName string,
+	minBcryptCost int,
 ) *NullStorage {
 	return &NullStorage{
-		ClientManager: clientregistry.NewClientManager(oidcClientsClient, oidcclientsecretstorage.New(secrets), minBcryptCost),
+		ClientManager: clientregistry.NewClientManager(oidcClientsClient, oidcclientsecretstorage.New(secrets), clientassertion.New(sessionStorage, time.Now), federationDomainName, minBcryptCost),
+		// The storage lifetime is not needed, because NullStorage never creates pushed authorization requests.
+		parStorage: pushedauthorizationrequest.New(sessionStorage, time.Now, nil),
 	}
 }
 
@@ -106,3 +130,27 @@ func (NullStorage) GetAuthorizeCodeSession(_ context.Context, _ string, _ fosite
 func (NullStorage) InvalidateAuthorizeCodeSession(_ context.Context, _ string) (err error) {
 	return errNullStorageNotImplemented
 }
+
+func (NullStorage) CreateDeviceAuthSession(_ context.Context, _ string, _ string, _ fosite.DeviceRequester) error {
+	return errNullStorageNotImplemented
+}
+
+func (NullStorage) GetDeviceCodeSession(_ context.Context, _ string, _ fosite.Session) (fosite.DeviceRequester, error) {
+	return nil, errNullStorageNotImplemented
+}
+
+func (NullStorage) InvalidateDeviceCodeSession(_ context.Context, _ string) error {
+	return errNullStorageNotImplemented
+}
+
+func (NullStorage) CreatePARSession(_ context.Context, _ string, _ fosite.AuthorizeRequester) error {
+	return errNullStorageNotImplemented
+}
+
+func (n NullStorage) GetPARSession(ctx context.Context, requestURI string) (fosite.AuthorizeRequester, error) {
+	return n.parStorage.GetPARSession(ctx, requestURI)
+}
+
+func (n NullStorage) DeletePARSession(ctx context.Context, requestURI string) error {
+	return n.parStorage.DeletePARSession(ctx, requestURI)
+}
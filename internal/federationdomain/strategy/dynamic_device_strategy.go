@@ -0,0 +1,89 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package strategy
+
+import (
+	"context"
+	"strings"
+
+	"github.com/ory/fosite"
+	"github.com/ory/fosite/handler/rfc8628"
+	"github.com/ory/fosite/token/hmac"
+
+	"go.pinniped.dev/internal/federationdomain/storage"
+)
+
+const (
+	pinDeviceCodePrefix = "pin_dc_" // "Pinniped device code" abbreviated.
+	oryDeviceCodePrefix = "ory_dc_"
+)
+
+// DynamicDeviceStrategy is an rfc8628.RFC8628CodeStrategy that can dynamically load an HMAC key to sign
+// device codes and user codes, for the same reasons as DynamicOauth2HMACStrategy.
+//
+// Like the other tokens, device codes start with a custom prefix to make them identifiable when seen out of context.
+// User codes are meant to be typed by a human, so they do not have a prefix.
+type DynamicDeviceStrategy struct {
+	fositeConfig *fosite.Config
+	keyFunc      func() []byte
+}
+
+var _ rfc8628.RFC8628CodeStrategy = &DynamicDeviceStrategy{}
+
+func NewDynamicDeviceStrategy(
+	fositeConfig *fosite.Config,
+	keyFunc func() []byte,
+) *DynamicDeviceStrategy {
+	return &DynamicDeviceStrategy{
+		fositeConfig: fositeConfig,
+		keyFunc:      keyFunc,
+	}
+}
+
+func (s *DynamicDeviceStrategy) ShouldRateLimit(ctx context.Context, code string) (bool, error) {
+	return s.delegate().ShouldRateLimit(ctx, code)
+}
+
+func (s *DynamicDeviceStrategy) DeviceCodeSignature(ctx context.Context, code string) (string, error) {
+	return s.delegate().DeviceCodeSignature(ctx, code)
+}
+
+func (s *DynamicDeviceStrategy) GenerateDeviceCode(ctx context.Context) (string, string, error) {
+	code, sig, err := s.delegate().GenerateDeviceCode(ctx)
+	if err == nil {
+		if !strings.HasPrefix(code, oryDeviceCodePrefix) {
+			// This would only happen if fosite changed how it generates tokens. Defensive programming here.
+			return "", "", fosite.ErrInvalidTokenFormat.WithDebugf("Generated token does not have expected prefix")
+		}
+		code = replacePrefix(code, oryDeviceCodePrefix, pinDeviceCodePrefix)
+	}
+	return code, sig, err
+}
+
+func (s *DynamicDeviceStrategy) ValidateDeviceCode(ctx context.Context, requester fosite.DeviceRequester, code string) error {
+	if !strings.HasPrefix(code, pinDeviceCodePrefix) {
+		return fosite.ErrInvalidTokenFormat.WithDebugf("Device code did not have prefix %q", pinDeviceCodePrefix)
+	}
+	return s.delegate().ValidateDeviceCode(ctx, requester, replacePrefix(code, pinDeviceCodePrefix, oryDeviceCodePrefix))
+}
+
+func (s *DynamicDeviceStrategy) UserCodeSignature(ctx context.Context, code string) (string, error) {
+	return s.delegate().UserCodeSignature(ctx, code)
+}
+
+func (s *DynamicDeviceStrategy) GenerateUserCode(ctx context.Context) (string, string, error) {
+	return s.delegate().GenerateUserCode(ctx)
+}
+
+func (s *DynamicDeviceStrategy) ValidateUserCode(ctx context.Context, requester fosite.DeviceRequester, code string) error {
+	return s.delegate().ValidateUserCode(ctx, requester, code)
+}
+
+func (s *DynamicDeviceStrategy) delegate() *rfc8628.DefaultDeviceStrategy {
+	config := storage.NewDynamicGlobalSecretConfig(s.fositeConfig, s.keyFunc)
+	return &rfc8628.DefaultDeviceStrategy{
+		Enigma: &hmac.HMACStrategy{Config: config},
+		Config: config,
+	}
+}
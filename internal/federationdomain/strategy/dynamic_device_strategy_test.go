@@ -0,0 +1,114 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package strategy
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ory/fosite"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDynamicDeviceStrategy_DeviceCodes(t *testing.T) {
+	s := NewDynamicDeviceStrategy(
+		&fosite.Config{}, // defaults are good enough for device codes
+		func() []byte { return []byte("12345678901234567890123456789012") }, // 32 character secret key
+	)
+
+	generateTokenErrorCausingStrategy := NewDynamicDeviceStrategy(
+		&fosite.Config{},
+		func() []byte { return []byte("too_short_causes_error") }, // secret key is below required 32 characters
+	)
+
+	ctx := context.Background()
+
+	unexpiredSession := &fosite.DefaultSession{}
+	unexpiredSession.SetExpiresAt(fosite.DeviceCode, time.Now().Add(time.Hour))
+	requesterWithUnexpiredCode := &fosite.DeviceRequest{Request: fosite.Request{Session: unexpiredSession}}
+
+	expiredSession := &fosite.DefaultSession{}
+	expiredSession.SetExpiresAt(fosite.DeviceCode, time.Now().Add(-time.Hour))
+	requesterWithExpiredCode := &fosite.DeviceRequest{Request: fosite.Request{Session: expiredSession}}
+
+	generatedCode1, signature1, err := s.GenerateDeviceCode(ctx)
+	require.NoError(t, err)
+	generatedCode2, signature2, err := s.GenerateDeviceCode(ctx)
+	require.NoError(t, err)
+
+	for _, generated := range []struct{ code, signature string }{{generatedCode1, signature1}, {generatedCode2, signature2}} {
+		// Device codes should start with a custom prefix to make them identifiable when seen by a user out of context.
+		require.True(t, strings.HasPrefix(generated.code, "pin_dc_"), "device code %q did not have expected prefix", generated.code)
+		require.Equal(t, 1, strings.Count(generated.code, "."))
+		require.Len(t, generated.signature, 43)
+		require.True(t, strings.HasSuffix(generated.code, "."+generated.signature), "device code %q did not end with dot followed by signature", generated.code)
+
+		signature, err := s.DeviceCodeSignature(ctx, generated.code)
+		require.NoError(t, err)
+		require.Equal(t, generated.signature, signature)
+
+		require.NoError(t, s.ValidateDeviceCode(ctx, requesterWithUnexpiredCode, generated.code))
+	}
+
+	// Each generated code is random/different.
+	require.NotEqual(t, generatedCode1, generatedCode2)
+	require.NotEqual(t, signature1, signature2)
+
+	// Validate when expired according to session.
+	require.ErrorIs(t, s.ValidateDeviceCode(ctx, requesterWithExpiredCode, generatedCode1), fosite.ErrDeviceExpiredToken)
+
+	// Validate when missing prefix.
+	require.EqualError(t, s.ValidateDeviceCode(ctx, requesterWithUnexpiredCode, strings.TrimPrefix(generatedCode1, "pin_dc_")), "invalid_token")
+
+	// Validate when the code has fosite's default prefix instead of ours.
+	require.EqualError(t, s.ValidateDeviceCode(ctx, requesterWithUnexpiredCode, strings.Replace(generatedCode1, "pin_dc_", "ory_dc_", 1)), "invalid_token")
+
+	// Validate when the code was tampered with.
+	require.Error(t, s.ValidateDeviceCode(ctx, requesterWithUnexpiredCode, "pin_dc_x"+strings.TrimPrefix(generatedCode1, "pin_dc_")))
+
+	// Test the return values when an error is encountered during generation.
+	generatedCode3, signature3, err := generateTokenErrorCausingStrategy.GenerateDeviceCode(ctx)
+	require.EqualError(t, err, "secret for signing HMAC-SHA512/256 is expected to be 32 byte long, got 22 byte")
+	require.Empty(t, generatedCode3)
+	require.Empty(t, signature3)
+}
+
+func TestDynamicDeviceStrategy_UserCodes(t *testing.T) {
+	s := NewDynamicDeviceStrategy(
+		&fosite.Config{UserCodeLength: 8, UserCodeSymbols: []rune("BCDFGHJKLMNPQRSTVWXZ")},
+		func() []byte { return []byte("12345678901234567890123456789012") }, // 32 character secret key
+	)
+
+	ctx := context.Background()
+
+	userCode1, signature1, err := s.GenerateUserCode(ctx)
+	require.NoError(t, err)
+	userCode2, signature2, err := s.GenerateUserCode(ctx)
+	require.NoError(t, err)
+
+	for _, generated := range []struct{ code, signature string }{{userCode1, signature1}, {userCode2, signature2}} {
+		// User codes are meant to be typed by a human, so they do not have a prefix.
+		require.Len(t, generated.code, 8)
+		require.Empty(t, strings.Trim(generated.code, "BCDFGHJKLMNPQRSTVWXZ"), "user code %q had unexpected characters", generated.code)
+
+		// The signature of a user code can be calculated from the user code alone.
+		signature, err := s.UserCodeSignature(ctx, generated.code)
+		require.NoError(t, err)
+		require.Equal(t, generated.signature, signature)
+	}
+
+	// Each generated code is random/different.
+	require.NotEqual(t, userCode1, userCode2)
+	require.NotEqual(t, signature1, signature2)
+
+	unexpiredSession := &fosite.DefaultSession{}
+	unexpiredSession.SetExpiresAt(fosite.UserCode, time.Now().Add(time.Hour))
+	require.NoError(t, s.ValidateUserCode(ctx, &fosite.DeviceRequest{Request: fosite.Request{Session: unexpiredSession}}, userCode1))
+
+	expiredSession := &fosite.DefaultSession{}
+	expiredSession.SetExpiresAt(fosite.UserCode, time.Now().Add(-time.Hour))
+	require.ErrorIs(t, s.ValidateUserCode(ctx, &fosite.DeviceRequest{Request: fosite.Request{Session: expiredSession}}, userCode1), fosite.ErrDeviceExpiredToken)
+}
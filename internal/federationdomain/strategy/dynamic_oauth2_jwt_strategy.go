@@ -0,0 +1,231 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package strategy
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"strings"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/go-jose/go-jose/v4/jwt"
+	"github.com/google/uuid"
+	"github.com/ory/fosite"
+	fositeoauth2 "github.com/ory/fosite/handler/oauth2"
+	"github.com/ory/fosite/handler/openid"
+
+	supervisorconfigv1alpha1 "go.pinniped.dev/generated/latest/apis/supervisor/config/v1alpha1"
+	oidcapi "go.pinniped.dev/generated/latest/apis/supervisor/oidc"
+	"go.pinniped.dev/internal/constable"
+	"go.pinniped.dev/internal/federationdomain/clientregistry"
+	"go.pinniped.dev/internal/federationdomain/endpoints/jwks"
+	"go.pinniped.dev/internal/plog"
+)
+
+// jwtAccessTokenType is the value of the typ header of JWT access tokens, as required by
+// https://datatracker.ietf.org/doc/html/rfc9068#section-2.1.
+const jwtAccessTokenType = "at+jwt"
+
+// DynamicOauth2JWTStrategy is an oauth2.CoreStrategy which issues JWT access tokens, as described in RFC 9068,
+// to the clients which have opted into them. All other access tokens, and all refresh tokens and authorization codes,
+// are handled by the wrapped DynamicOauth2HMACStrategy.
+//
+// Like the ID tokens, the JWT access tokens are signed by the active signing key of the FederationDomain, which is
+// loaded dynamically. They are validated using any key of the FederationDomain's JWKS, so that they continue to work
+// after the signing key was rotated, until they expire. JWT access tokens are still saved to session storage, in the
+// same way as the other access tokens, so that they can be introspected and revoked, and so that they can be used
+// for token exchange.
+type DynamicOauth2JWTStrategy struct {
+	*DynamicOauth2HMACStrategy
+
+	fositeConfig *fosite.Config
+	jwksProvider jwks.DynamicJWKSProvider
+}
+
+var _ fositeoauth2.CoreStrategy = &DynamicOauth2JWTStrategy{}
+
+func NewDynamicOauth2JWTStrategy(
+	fositeConfig *fosite.Config,
+	hmacStrategy *DynamicOauth2HMACStrategy,
+	jwksProvider jwks.DynamicJWKSProvider,
+) *DynamicOauth2JWTStrategy {
+	return &DynamicOauth2JWTStrategy{
+		DynamicOauth2HMACStrategy: hmacStrategy,
+		fositeConfig:              fositeConfig,
+		jwksProvider:              jwksProvider,
+	}
+}
+
+// jwtAccessTokenClaims are the claims of a JWT access token, as described in
+// https://datatracker.ietf.org/doc/html/rfc9068#section-2.2. Like ID tokens, they also contain
+// the username and groups of the user when the client was granted the username and groups scopes.
+type jwtAccessTokenClaims struct {
+	jwt.Claims
+
+	ClientID string   `json:"client_id"`
+	Scope    string   `json:"scope,omitempty"`
+	Username string   `json:"username,omitempty"`
+	Groups   []string `json:"groups,omitempty"`
+}
+
+func (s *DynamicOauth2JWTStrategy) AccessTokenSignature(ctx context.Context, token string) string {
+	if strings.HasPrefix(token, pinAccessTokenPrefix) {
+		return s.DynamicOauth2HMACStrategy.AccessTokenSignature(ctx, token)
+	}
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 || parts[2] == "" {
+		return ""
+	}
+	// The signature of a JWT can be much longer than the signature of an HMAC token, for example when it was
+	// signed by an RSA key, which would make it too long to be used in the names of the session storage Secrets.
+	// Use a hash of the JWT's signature instead, which has the same length as the signature of an HMAC token.
+	hash := sha256.Sum256([]byte(parts[2]))
+	return base64.RawURLEncoding.EncodeToString(hash[:])
+}
+
+func (s *DynamicOauth2JWTStrategy) GenerateAccessToken(
+	ctx context.Context,
+	requester fosite.Requester,
+) (string, string, error) {
+	client, ok := requester.GetClient().(*clientregistry.Client)
+	if !ok || client.AccessTokenFormat != supervisorconfigv1alpha1.AccessTokenFormatJWT {
+		return s.DynamicOauth2HMACStrategy.GenerateAccessToken(ctx, requester)
+	}
+
+	token, err := s.generateJWT(requester)
+	if err != nil {
+		return "", "", err
+	}
+	return token, s.AccessTokenSignature(ctx, token), nil
+}
+
+func (s *DynamicOauth2JWTStrategy) ValidateAccessToken(
+	ctx context.Context,
+	requester fosite.Requester,
+	token string,
+) error {
+	if strings.HasPrefix(token, pinAccessTokenPrefix) {
+		return s.DynamicOauth2HMACStrategy.ValidateAccessToken(ctx, requester, token)
+	}
+
+	keySet, _ := s.jwksProvider.GetJWKS(s.fositeConfig.IDTokenIssuer)
+	if keySet == nil {
+		plog.Debug("no JWKS found for issuer", "issuer", s.fositeConfig.IDTokenIssuer)
+		return fosite.ErrTemporarilyUnavailable.WithWrap(constable.Error("no JWKS found for issuer"))
+	}
+
+	parsed, err := jwt.ParseSigned(token, []jose.SignatureAlgorithm{jose.ES256, jose.ES384, jose.RS256, jose.EdDSA})
+	if err != nil {
+		return fosite.ErrInvalidTokenFormat.WithWrap(err).WithDebug(err.Error())
+	}
+	// Only JWT access tokens may be used as access tokens, and not other JWTs such as ID tokens, which are signed by
+	// the same keys. See https://datatracker.ietf.org/doc/html/rfc9068#section-4.
+	if typ, _ := parsed.Headers[0].ExtraHeaders[jose.HeaderType].(string); !isJWTAccessTokenType(typ) {
+		return fosite.ErrInvalidTokenFormat.WithDebugf("Access token has wrong type %q", typ)
+	}
+	var claims jwtAccessTokenClaims
+	if err := parsed.Claims(*keySet, &claims); err != nil {
+		return fosite.ErrTokenSignatureMismatch.WithWrap(err).WithDebug(err.Error())
+	}
+	if claims.Issuer != s.fositeConfig.IDTokenIssuer {
+		return fosite.ErrTokenClaim.WithDebugf("Access token has wrong issuer %q", claims.Issuer)
+	}
+
+	// Use the expiration time from session storage, in the same way as the HMAC strategy,
+	// which is the same as the expiration time in the JWT's claims.
+	exp := requester.GetSession().GetExpiresAt(fosite.AccessToken)
+	if !exp.IsZero() && exp.Before(time.Now().UTC()) {
+		return fosite.ErrTokenExpired.WithHintf("Access token expired at '%s'.", exp)
+	}
+	return nil
+}
+
+func (s *DynamicOauth2JWTStrategy) generateJWT(requester fosite.Requester) (string, error) {
+	_, activeJwk := s.jwksProvider.GetJWKS(s.fositeConfig.IDTokenIssuer)
+	if activeJwk == nil {
+		plog.Debug("no JWK found for issuer", "issuer", s.fositeConfig.IDTokenIssuer)
+		return "", fosite.ErrTemporarilyUnavailable.WithWrap(constable.Error("no JWK found for issuer"))
+	}
+	algorithm := signingAlgorithm(activeJwk)
+	if algorithm == "" {
+		return "", fosite.ErrServerError.WithWrap(constable.Error("JWK must be of type ecdsa, rsa, or ed25519"))
+	}
+
+	// Signing with the JWK, rather than with its private key, includes the key ID in the token's header.
+	signer, err := jose.NewSigner(
+		jose.SigningKey{Algorithm: algorithm, Key: activeJwk},
+		(&jose.SignerOptions{}).WithType(jwtAccessTokenType),
+	)
+	if err != nil {
+		return "", fosite.ErrServerError.WithWrap(err).WithDebug(err.Error())
+	}
+
+	// The subject, username, and groups are the same as in the ID tokens which are issued for the session.
+	// The username and groups are only in the session when the client was granted the username and groups scopes.
+	session, ok := requester.GetSession().(openid.Session)
+	if !ok {
+		return "", fosite.ErrServerError.WithWrap(constable.Error("session must be an openid.Session"))
+	}
+	idTokenClaims := session.IDTokenClaims()
+
+	// The audience identifies the resource servers which should accept the access token. It must never default to
+	// the client ID, which is the audience of ID tokens, so that an access token cannot be used as an ID token.
+	// See https://datatracker.ietf.org/doc/html/rfc9068#section-4.
+	audience := jwt.Audience(requester.GetGrantedAudience())
+	if len(audience) == 0 {
+		if client, ok := requester.GetClient().(*clientregistry.Client); ok && client.AccessTokenAudience != "" {
+			audience = jwt.Audience{client.AccessTokenAudience}
+		}
+	}
+	if len(audience) == 0 || audience.Contains(requester.GetClient().GetID()) {
+		return "", fosite.ErrServerError.WithWrap(constable.Error("JWT access tokens require an audience which is not the client ID"))
+	}
+	claims := jwtAccessTokenClaims{
+		Claims: jwt.Claims{
+			Issuer:   s.fositeConfig.IDTokenIssuer,
+			Subject:  idTokenClaims.Subject,
+			Audience: audience,
+			Expiry:   jwt.NewNumericDate(session.GetExpiresAt(fosite.AccessToken)),
+			IssuedAt: jwt.NewNumericDate(time.Now()),
+			ID:       uuid.NewString(),
+		},
+		ClientID: requester.GetClient().GetID(),
+		Scope:    strings.Join(requester.GetGrantedScopes(), " "),
+		Groups:   groupsFromExtra(idTokenClaims.Extra),
+	}
+	if username, ok := idTokenClaims.Extra[oidcapi.IDTokenClaimUsername].(string); ok {
+		claims.Username = username
+	}
+
+	return jwt.Signed(signer).Claims(claims).Serialize()
+}
+
+// isJWTAccessTokenType returns true when the typ header is the media type of JWT access tokens, with or without
+// its "application/" prefix, as described in https://datatracker.ietf.org/doc/html/rfc9068#section-2.1.
+func isJWTAccessTokenType(typ string) bool {
+	typ = strings.ToLower(typ)
+	return typ == jwtAccessTokenType || typ == "application/"+jwtAccessTokenType
+}
+
+// groupsFromExtra returns the groups from the extra claims of the session. The groups are a []string when the
+// session was created during this request, but they are a []any after the session was read from session storage.
+func groupsFromExtra(extra map[string]any) []string {
+	switch groups := extra[oidcapi.IDTokenClaimGroups].(type) {
+	case []string:
+		return groups
+	case []any:
+		result := make([]string, 0, len(groups))
+		for _, group := range groups {
+			if groupString, ok := group.(string); ok {
+				result = append(result, groupString)
+			}
+		}
+		return result
+	default:
+		return nil
+	}
+}
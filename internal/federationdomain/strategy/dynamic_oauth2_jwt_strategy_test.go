@@ -0,0 +1,334 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package strategy
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/go-jose/go-jose/v4/jwt"
+	"github.com/ory/fosite"
+	"github.com/stretchr/testify/require"
+	"k8s.io/utils/ptr"
+
+	supervisorconfigv1alpha1 "go.pinniped.dev/generated/latest/apis/supervisor/config/v1alpha1"
+	"go.pinniped.dev/internal/federationdomain/clientregistry"
+	"go.pinniped.dev/internal/federationdomain/endpoints/jwks"
+	"go.pinniped.dev/internal/psession"
+)
+
+func TestDynamicOauth2JWTStrategy(t *testing.T) {
+	const (
+		goodIssuer          = "https://some-good-issuer.com"
+		clientID            = "client.oauth.pinniped.dev-some-client"
+		accessTokenAudience = "https://some-api.example.com"
+	)
+
+	ecPrivateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	ecJWK := &jose.JSONWebKey{Key: ecPrivateKey, KeyID: "ec-key", Algorithm: string(jose.ES256), Use: "sig"}
+
+	rsaPrivateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	rsaJWK := &jose.JSONWebKey{Key: rsaPrivateKey, KeyID: "rsa-key", Algorithm: string(jose.RS256), Use: "sig"}
+
+	otherPrivateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	otherJWK := &jose.JSONWebKey{Key: otherPrivateKey, KeyID: "other-key", Algorithm: string(jose.ES256), Use: "sig"}
+
+	keySetOf := func(keys ...*jose.JSONWebKey) *jose.JSONWebKeySet {
+		keySet := &jose.JSONWebKeySet{}
+		for _, key := range keys {
+			keySet.Keys = append(keySet.Keys, key.Public())
+		}
+		return keySet
+	}
+
+	newRequest := func(jwtAccessTokens bool, audience *string, expiresAt time.Time) *fosite.Request {
+		accessTokenFormat := supervisorconfigv1alpha1.AccessTokenFormatOpaque
+		if jwtAccessTokens {
+			accessTokenFormat = supervisorconfigv1alpha1.AccessTokenFormatJWT
+		}
+		request := fosite.NewRequest()
+		request.Client = &clientregistry.Client{
+			DefaultOpenIDConnectClient: fosite.DefaultOpenIDConnectClient{DefaultClient: &fosite.DefaultClient{ID: clientID}},
+			AccessTokenFormat:          accessTokenFormat,
+			AccessTokenAudience:        accessTokenAudience,
+		}
+		if audience != nil {
+			request.Client.(*clientregistry.Client).AccessTokenAudience = *audience
+		}
+		request.GrantedScope = fosite.Arguments{"openid", "username", "groups"}
+		session := psession.NewPinnipedSession()
+		session.IDTokenClaims().Subject = "some-subject"
+		session.SetExpiresAt(fosite.AccessToken, expiresAt)
+		session.IDTokenClaims().Extra = map[string]any{
+			"username": "some-username",
+			// The groups are a []any after the session was read from storage.
+			"groups": []any{"some-group1", "some-group2"},
+		}
+		request.Session = session
+		return request
+	}
+
+	tests := []struct {
+		name                    string
+		jwtAccessTokens         bool
+		accessTokenAudience     *string
+		activeJWK               *jose.JSONWebKey
+		validationJWKS          *jose.JSONWebKeySet
+		validationIssuer        string
+		expiresAt               time.Time
+		wantGenerateErrorType   *fosite.RFC6749Error
+		wantValidateErrorType   *fosite.RFC6749Error
+		wantJWTAlgorithm        jose.SignatureAlgorithm
+		wantJWTKeyID            string
+		wantOpaqueAccessToken   bool
+		skipValidationOfJWTBody bool
+	}{
+		{
+			name:                  "client which did not opt into JWT access tokens",
+			activeJWK:             ecJWK,
+			validationJWKS:        keySetOf(ecJWK),
+			expiresAt:             time.Now().Add(time.Hour),
+			wantOpaqueAccessToken: true,
+		},
+		{
+			name:             "client which opted into JWT access tokens, signed by an ecdsa key",
+			jwtAccessTokens:  true,
+			activeJWK:        ecJWK,
+			validationJWKS:   keySetOf(ecJWK),
+			expiresAt:        time.Now().Add(time.Hour),
+			wantJWTAlgorithm: jose.ES256,
+			wantJWTKeyID:     "ec-key",
+		},
+		{
+			name:             "client which opted into JWT access tokens, signed by an rsa key",
+			jwtAccessTokens:  true,
+			activeJWK:        rsaJWK,
+			validationJWKS:   keySetOf(rsaJWK),
+			expiresAt:        time.Now().Add(time.Hour),
+			wantJWTAlgorithm: jose.RS256,
+			wantJWTKeyID:     "rsa-key",
+		},
+		{
+			name:             "JWT access token which was signed by a key which has since been retired from signing",
+			jwtAccessTokens:  true,
+			activeJWK:        ecJWK,
+			validationJWKS:   keySetOf(otherJWK, ecJWK),
+			expiresAt:        time.Now().Add(time.Hour),
+			wantJWTAlgorithm: jose.ES256,
+			wantJWTKeyID:     "ec-key",
+		},
+		{
+			name:                  "JWT access token which was signed by a key which is no longer in the JWKS",
+			jwtAccessTokens:       true,
+			activeJWK:             ecJWK,
+			validationJWKS:        keySetOf(otherJWK),
+			expiresAt:             time.Now().Add(time.Hour),
+			wantJWTAlgorithm:      jose.ES256,
+			wantJWTKeyID:          "ec-key",
+			wantValidateErrorType: fosite.ErrTokenSignatureMismatch,
+		},
+		{
+			name:                  "expired JWT access token",
+			jwtAccessTokens:       true,
+			activeJWK:             ecJWK,
+			validationJWKS:        keySetOf(ecJWK),
+			expiresAt:             time.Now().Add(-time.Second),
+			wantJWTAlgorithm:      jose.ES256,
+			wantJWTKeyID:          "ec-key",
+			wantValidateErrorType: fosite.ErrTokenExpired,
+		},
+		{
+			name:                    "JWT access token which was issued by another issuer",
+			jwtAccessTokens:         true,
+			activeJWK:               ecJWK,
+			validationJWKS:          keySetOf(ecJWK),
+			validationIssuer:        "https://some-other-issuer.com",
+			expiresAt:               time.Now().Add(time.Hour),
+			wantJWTAlgorithm:        jose.ES256,
+			wantJWTKeyID:            "ec-key",
+			wantValidateErrorType:   fosite.ErrTokenClaim,
+			skipValidationOfJWTBody: true,
+		},
+		{
+			name:                  "no JWKS for the issuer while validating",
+			jwtAccessTokens:       true,
+			activeJWK:             ecJWK,
+			expiresAt:             time.Now().Add(time.Hour),
+			wantJWTAlgorithm:      jose.ES256,
+			wantJWTKeyID:          "ec-key",
+			wantValidateErrorType: fosite.ErrTemporarilyUnavailable,
+		},
+		{
+			name:                  "client which opted into JWT access tokens without an access token audience",
+			jwtAccessTokens:       true,
+			accessTokenAudience:   ptr.To(""),
+			activeJWK:             ecJWK,
+			expiresAt:             time.Now().Add(time.Hour),
+			wantGenerateErrorType: fosite.ErrServerError,
+		},
+		{
+			name:                  "client which opted into JWT access tokens with its client ID as the access token audience",
+			jwtAccessTokens:       true,
+			accessTokenAudience:   ptr.To(clientID),
+			activeJWK:             ecJWK,
+			expiresAt:             time.Now().Add(time.Hour),
+			wantGenerateErrorType: fosite.ErrServerError,
+		},
+		{
+			name:                  "no active signing key for the issuer while generating",
+			jwtAccessTokens:       true,
+			expiresAt:             time.Now().Add(time.Hour),
+			wantGenerateErrorType: fosite.ErrTemporarilyUnavailable,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			hmacSecretFunc := func() []byte { return []byte("some secret - must have at least 32 bytes") }
+
+			generatingProvider := jwks.NewDynamicJWKSProvider()
+			if test.activeJWK != nil {
+				generatingProvider.SetIssuerToJWKSMap(nil, map[string]*jose.JSONWebKey{goodIssuer: test.activeJWK})
+			}
+			generatingConfig := &fosite.Config{IDTokenIssuer: goodIssuer}
+			generatingStrategy := NewDynamicOauth2JWTStrategy(
+				generatingConfig,
+				NewDynamicOauth2HMACStrategy(generatingConfig, hmacSecretFunc),
+				generatingProvider,
+			)
+
+			request := newRequest(test.jwtAccessTokens, test.accessTokenAudience, test.expiresAt)
+			token, signature, err := generatingStrategy.GenerateAccessToken(t.Context(), request)
+			if test.wantGenerateErrorType != nil {
+				require.ErrorIs(t, err, test.wantGenerateErrorType)
+				require.Empty(t, token)
+				require.Empty(t, signature)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, signature, generatingStrategy.AccessTokenSignature(t.Context(), token))
+			// Signatures of both kinds of tokens have the same length, which is short enough for session storage.
+			require.Len(t, signature, 43)
+
+			if test.wantOpaqueAccessToken {
+				require.True(t, strings.HasPrefix(token, pinAccessTokenPrefix))
+				require.NoError(t, generatingStrategy.ValidateAccessToken(t.Context(), request, token))
+				return
+			}
+
+			parsed, err := jwt.ParseSigned(token, []jose.SignatureAlgorithm{test.wantJWTAlgorithm})
+			require.NoError(t, err)
+			require.Len(t, parsed.Headers, 1)
+			require.Equal(t, test.wantJWTKeyID, parsed.Headers[0].KeyID)
+			require.Equal(t, "at+jwt", parsed.Headers[0].ExtraHeaders[jose.HeaderType])
+
+			if !test.skipValidationOfJWTBody {
+				var claims map[string]any
+				require.NoError(t, parsed.Claims(test.activeJWK.Public().Key, &claims))
+				require.NotEmpty(t, claims["jti"])
+				require.NotEmpty(t, claims["iat"])
+				delete(claims, "jti")
+				delete(claims, "iat")
+				require.Equal(t, map[string]any{
+					"iss":       goodIssuer,
+					"sub":       "some-subject",
+					"aud":       accessTokenAudience,
+					"exp":       float64(test.expiresAt.Unix()),
+					"client_id": clientID,
+					"scope":     "openid username groups",
+					"username":  "some-username",
+					"groups":    []any{"some-group1", "some-group2"},
+				}, claims)
+			}
+
+			validationIssuer := goodIssuer
+			if test.validationIssuer != "" {
+				validationIssuer = test.validationIssuer
+			}
+			validatingProvider := jwks.NewDynamicJWKSProvider()
+			if test.validationJWKS != nil {
+				validatingProvider.SetIssuerToJWKSMap(map[string]*jose.JSONWebKeySet{validationIssuer: test.validationJWKS}, nil)
+			}
+			validatingConfig := &fosite.Config{IDTokenIssuer: validationIssuer}
+			validatingStrategy := NewDynamicOauth2JWTStrategy(
+				validatingConfig,
+				NewDynamicOauth2HMACStrategy(validatingConfig, hmacSecretFunc),
+				validatingProvider,
+			)
+
+			err = validatingStrategy.ValidateAccessToken(t.Context(), request, token)
+			if test.wantValidateErrorType != nil {
+				require.ErrorIs(t, err, test.wantValidateErrorType)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestDynamicOauth2JWTStrategyValidatesTokenType(t *testing.T) {
+	const (
+		issuer   = "https://some-issuer.com"
+		clientID = "client.oauth.pinniped.dev-some-client"
+	)
+
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	jwk := &jose.JSONWebKey{Key: privateKey, KeyID: "some-key", Algorithm: string(jose.ES256), Use: "sig"}
+
+	provider := jwks.NewDynamicJWKSProvider()
+	provider.SetIssuerToJWKSMap(map[string]*jose.JSONWebKeySet{issuer: {Keys: []jose.JSONWebKey{jwk.Public()}}}, nil)
+	config := &fosite.Config{IDTokenIssuer: issuer}
+	subject := NewDynamicOauth2JWTStrategy(
+		config,
+		NewDynamicOauth2HMACStrategy(config, func() []byte { return []byte("some secret - must have at least 32 bytes") }),
+		provider,
+	)
+
+	tests := []struct {
+		name      string
+		typ       string
+		wantValid bool
+	}{
+		{name: "JWT access token", typ: "at+jwt", wantValid: true},
+		{name: "JWT access token with the full media type", typ: "application/at+jwt", wantValid: true},
+		// ID tokens are signed by the same keys and have the same issuer, so they must not be accepted as access tokens.
+		{name: "ID token", typ: "JWT"},
+		{name: "no type", typ: ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			opts := &jose.SignerOptions{}
+			if test.typ != "" {
+				opts = opts.WithType(jose.ContentType(test.typ))
+			}
+			signer, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.ES256, Key: jwk}, opts)
+			require.NoError(t, err)
+			token, err := jwt.Signed(signer).Claims(jwt.Claims{
+				Issuer:   issuer,
+				Subject:  "some-subject",
+				Audience: jwt.Audience{clientID},
+				Expiry:   jwt.NewNumericDate(time.Now().Add(time.Hour)),
+			}).Serialize()
+			require.NoError(t, err)
+
+			request := fosite.NewRequest()
+			request.Session = psession.NewPinnipedSession()
+			err = subject.ValidateAccessToken(t.Context(), request, token)
+			if test.wantValid {
+				require.NoError(t, err)
+				return
+			}
+			require.ErrorIs(t, err, fosite.ErrInvalidTokenFormat)
+		})
+	}
+}
@@ -1,4 +1,4 @@
-// Copyright 2020-2024 the Pinniped contributors. All Rights Reserved.
+// Copyright 2020-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package strategy
@@ -6,9 +6,14 @@ package strategy
 import (
 	"context"
 	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
 	"reflect"
 	"time"
 
+	oldjosev3 "github.com/go-jose/go-jose/v3" // we need to use the same version of jose that fosite uses for signing
+	"github.com/go-jose/go-jose/v4"
 	"github.com/ory/fosite"
 	"github.com/ory/fosite/compose"
 	"github.com/ory/fosite/handler/openid"
@@ -26,6 +31,10 @@ import (
 // If we ever update FederationDomain's to hold their signing key, we might not need this type, since we
 // could have an invariant that routes to an FederationDomain's endpoints are only wired up if an
 // FederationDomain has a valid signing key.
+//
+// Despite its name, this strategy can sign using ECDSA, RSA, or Ed25519 keys, depending on the type of the active
+// signing key. The key ID of the active signing key is included in the header of each ID token, so that the ID
+// token can be verified by the matching key in the JWKS while retired keys are still published.
 type DynamicOpenIDConnectECDSAStrategy struct {
 	fositeConfig *fosite.Config
 	jwksProvider jwks.DynamicJWKSProvider
@@ -53,22 +62,29 @@ func (s *DynamicOpenIDConnectECDSAStrategy) GenerateIDToken(
 		plog.Debug("no JWK found for issuer", "issuer", s.fositeConfig.IDTokenIssuer)
 		return "", fosite.ErrTemporarilyUnavailable.WithWrap(constable.Error("no JWK found for issuer"))
 	}
-	key, ok := activeJwk.Key.(*ecdsa.PrivateKey)
-	if !ok {
+	algorithm := signingAlgorithm(activeJwk)
+	if algorithm == "" {
 		actualType := "nil"
 		if t := reflect.TypeOf(activeJwk.Key); t != nil {
 			actualType = t.String()
 		}
 		plog.Debug(
-			"JWK must be of type ecdsa",
+			"JWK must be of type ecdsa, rsa, or ed25519",
 			"issuer",
 			s.fositeConfig.IDTokenIssuer,
 			"actualType",
 			actualType,
 		)
-		return "", fosite.ErrServerError.WithWrap(constable.Error("JWK must be of type ecdsa"))
+		return "", fosite.ErrServerError.WithWrap(constable.Error("JWK must be of type ecdsa, rsa, or ed25519"))
 	}
 
+	// Sign using a JWK which has the algorithm and key ID, so that they are used for the token's header.
+	key := &oldjosev3.JSONWebKey{
+		Key:       activeJwk.Key,
+		KeyID:     activeJwk.KeyID,
+		Algorithm: string(algorithm),
+		Use:       activeJwk.Use,
+	}
 	keyGetter := func(context.Context) (any, error) {
 		return key, nil
 	}
@@ -76,3 +92,27 @@ func (s *DynamicOpenIDConnectECDSAStrategy) GenerateIDToken(
 
 	return strategy.GenerateIDToken(ctx, lifespan, requester)
 }
+
+// signingAlgorithm returns the algorithm of the JWK, or the default algorithm for the type of its private key when
+// the JWK does not specify an algorithm. It returns an empty string when the JWK does not hold a supported private key.
+func signingAlgorithm(jwk *jose.JSONWebKey) jose.SignatureAlgorithm {
+	var defaultAlgorithm jose.SignatureAlgorithm
+	switch key := jwk.Key.(type) {
+	case *ecdsa.PrivateKey:
+		defaultAlgorithm = jose.ES256
+		if key.Curve == elliptic.P384() {
+			defaultAlgorithm = jose.ES384
+		}
+	case *rsa.PrivateKey:
+		defaultAlgorithm = jose.RS256
+	case ed25519.PrivateKey:
+		defaultAlgorithm = jose.EdDSA
+	default:
+		return ""
+	}
+
+	if jwk.Algorithm != "" {
+		return jose.SignatureAlgorithm(jwk.Algorithm)
+	}
+	return defaultAlgorithm
+}
@@ -1,14 +1,17 @@
-// Copyright 2020-2024 the Pinniped contributors. All Rights Reserved.
+// Copyright 2020-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package strategy
 
 import (
 	"context"
+	"crypto"
 	"crypto/ecdsa"
+	"crypto/ed25519"
 	"crypto/elliptic"
 	"crypto/rand"
 	"crypto/rsa"
+	"encoding/json"
 	"errors"
 	"net/url"
 	"testing"
@@ -36,9 +39,21 @@ func TestDynamicOpenIDConnectECDSAStrategy(t *testing.T) {
 	ecPrivateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
 	require.NoError(t, err)
 
+	ecP384PrivateKey, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	require.NoError(t, err)
+
 	rsaPrivateKey, err := rsa.GenerateKey(rand.Reader, 2048)
 	require.NoError(t, err)
 
+	_, ed25519PrivateKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	providerWithKey := func(jwk *jose.JSONWebKey) func(jwks.DynamicJWKSProvider) {
+		return func(provider jwks.DynamicJWKSProvider) {
+			provider.SetIssuerToJWKSMap(nil, map[string]*jose.JSONWebKey{goodIssuer: jwk})
+		}
+	}
+
 	tests := []struct {
 		name           string
 		issuer         string
@@ -46,6 +61,7 @@ func TestDynamicOpenIDConnectECDSAStrategy(t *testing.T) {
 		wantErrorType  *fosite.RFC6749Error
 		wantErrorCause string
 		wantSigningJWK *jose.JSONWebKey
+		wantAlgorithm  jose.SignatureAlgorithm
 	}{
 		{
 			name:   "jwks provider does contain signing key for issuer",
@@ -63,6 +79,28 @@ func TestDynamicOpenIDConnectECDSAStrategy(t *testing.T) {
 			wantSigningJWK: &jose.JSONWebKey{
 				Key: ecPrivateKey,
 			},
+			wantAlgorithm: jose.ES256,
+		},
+		{
+			name:           "jwks provider contains an ES384 signing key for issuer",
+			issuer:         goodIssuer,
+			jwksProvider:   providerWithKey(&jose.JSONWebKey{Key: ecP384PrivateKey, KeyID: "some-es384-key", Algorithm: "ES384"}),
+			wantSigningJWK: &jose.JSONWebKey{Key: ecP384PrivateKey, KeyID: "some-es384-key"},
+			wantAlgorithm:  jose.ES384,
+		},
+		{
+			name:           "jwks provider contains an RSA signing key without an algorithm for issuer",
+			issuer:         goodIssuer,
+			jwksProvider:   providerWithKey(&jose.JSONWebKey{Key: rsaPrivateKey, KeyID: "some-rsa-key"}),
+			wantSigningJWK: &jose.JSONWebKey{Key: rsaPrivateKey, KeyID: "some-rsa-key"},
+			wantAlgorithm:  jose.RS256,
+		},
+		{
+			name:           "jwks provider contains an Ed25519 signing key for issuer",
+			issuer:         goodIssuer,
+			jwksProvider:   providerWithKey(&jose.JSONWebKey{Key: ed25519PrivateKey, KeyID: "some-ed25519-key", Algorithm: "EdDSA"}),
+			wantSigningJWK: &jose.JSONWebKey{Key: ed25519PrivateKey, KeyID: "some-ed25519-key"},
+			wantAlgorithm:  jose.EdDSA,
 		},
 		{
 			name:           "jwks provider does not contain signing key for issuer",
@@ -78,13 +116,13 @@ func TestDynamicOpenIDConnectECDSAStrategy(t *testing.T) {
 					nil,
 					map[string]*jose.JSONWebKey{
 						goodIssuer: {
-							Key: rsaPrivateKey,
+							Key: []byte("some-symmetric-key"),
 						},
 					},
 				)
 			},
 			wantErrorType:  fosite.ErrServerError,
-			wantErrorCause: "JWK must be of type ecdsa",
+			wantErrorCause: "JWK must be of type ecdsa, rsa, or ed25519",
 		},
 	}
 	for _, test := range tests {
@@ -120,15 +158,33 @@ func TestDynamicOpenIDConnectECDSAStrategy(t *testing.T) {
 			} else {
 				require.NoError(t, err)
 
-				privateKey, ok := test.wantSigningJWK.Key.(*ecdsa.PrivateKey)
-				require.True(t, ok, "wanted private key to be *ecdsa.PrivateKey, but was %T", test.wantSigningJWK)
+				if test.wantAlgorithm == jose.ES256 {
+					privateKey, ok := test.wantSigningJWK.Key.(*ecdsa.PrivateKey)
+					require.True(t, ok, "wanted private key to be *ecdsa.PrivateKey, but was %T", test.wantSigningJWK)
+
+					// Perform a light validation on the token to make sure 1) we passed through the correct
+					// signing key and 2) we forwarded the fosite.Requester correctly. Token generation is
+					// tested more expansively in the token endpoint.
+					token := oidctestutil.VerifyECDSAIDToken(t, goodIssuer, clientID, privateKey, idToken)
+					require.Equal(t, goodSubject, token.Subject)
+					require.Equal(t, goodNonce, token.Nonce)
+				}
 
-				// Perform a light validation on the token to make sure 1) we passed through the correct
-				// signing key and 2) we forwarded the fosite.Requester correctly. Token generation is
-				// tested more expansively in the token endpoint.
-				token := oidctestutil.VerifyECDSAIDToken(t, goodIssuer, clientID, privateKey, idToken)
-				require.Equal(t, goodSubject, token.Subject)
-				require.Equal(t, goodNonce, token.Nonce)
+				// Make sure that the token was signed using the expected algorithm and key, and that its header
+				// names the key, so that it can be verified by the right key in a JWKS with multiple keys.
+				jws, err := jose.ParseSigned(idToken, []jose.SignatureAlgorithm{test.wantAlgorithm})
+				require.NoError(t, err)
+				require.Len(t, jws.Signatures, 1)
+				require.Equal(t, string(test.wantAlgorithm), jws.Signatures[0].Header.Algorithm)
+				require.Equal(t, test.wantSigningJWK.KeyID, jws.Signatures[0].Header.KeyID)
+				signer, ok := test.wantSigningJWK.Key.(crypto.Signer)
+				require.True(t, ok)
+				payload, err := jws.Verify(signer.Public())
+				require.NoError(t, err)
+				var claims map[string]any
+				require.NoError(t, json.Unmarshal(payload, &claims))
+				require.Equal(t, goodSubject, claims["sub"])
+				require.Equal(t, goodNonce, claims["nonce"])
 			}
 		})
 	}
@@ -1,4 +1,4 @@
-// Copyright 2020-2024 the Pinniped contributors. All Rights Reserved.
+// Copyright 2020-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package timeouts
@@ -16,6 +16,37 @@ type StorageLifetime func(requester fosite.Requester) time.Duration
 // by returning true along with a new lifespan. When false is returned, the returned duration should be ignored.
 type OverrideLifespan func(accessRequest fosite.AccessRequester) (time.Duration, bool)
 
+// SessionPolicy describes the configurable lifetimes of the sessions and tokens of a FederationDomain or of a client.
+// Each zero value means that the setting was not configured.
+type SessionPolicy struct {
+	// MaxSessionAge is the longest time that a session may continue after the initial login.
+	MaxSessionAge time.Duration
+	// RefreshTokenLifespan is the lifetime of each refresh token.
+	RefreshTokenLifespan time.Duration
+	// IdleTimeout is the longest time that a session may go unused before it ends.
+	IdleTimeout time.Duration
+	// AccessTokenLifespan is the lifetime of each access token.
+	AccessTokenLifespan time.Duration
+}
+
+// Merge returns a copy of the policy where each setting is replaced by the corresponding setting
+// of the overrides, when the setting of the overrides is configured.
+func (p SessionPolicy) Merge(overrides SessionPolicy) SessionPolicy {
+	if overrides.MaxSessionAge > 0 {
+		p.MaxSessionAge = overrides.MaxSessionAge
+	}
+	if overrides.RefreshTokenLifespan > 0 {
+		p.RefreshTokenLifespan = overrides.RefreshTokenLifespan
+	}
+	if overrides.IdleTimeout > 0 {
+		p.IdleTimeout = overrides.IdleTimeout
+	}
+	if overrides.AccessTokenLifespan > 0 {
+		p.AccessTokenLifespan = overrides.AccessTokenLifespan
+	}
+	return p
+}
+
 type Configuration struct {
 	// The length of time that our state param that we encrypt and pass to the upstream OIDC IDP should be considered
 	// valid. If a state param generated by the authorize endpoint is sent to the callback endpoint after this much
@@ -29,6 +60,19 @@ type Configuration struct {
 	// has to come back to exchange the authcode for tokens at the token endpoint.
 	AuthorizeCodeLifespan time.Duration
 
+	// How long the device code and user code issued by the device authorization endpoint are valid. This determines
+	// how much time the end user has to enter the user code into the user code entry page and finish their login with
+	// the upstream IDP, while the device which requested the codes keeps polling the token endpoint.
+	DeviceAndUserCodeLifespan time.Duration
+
+	// How long a request_uri issued by the pushed authorization request endpoint is valid. This determines how much
+	// time the client has to send the end user's browser to the authorization endpoint with the request_uri.
+	PushedAuthorizationRequestLifespan time.Duration
+
+	// The minimum amount of time that the device which requested a device code should wait between requests
+	// to the token endpoint while polling for the result of the device authorization grant.
+	DeviceAuthorizationPollingInterval time.Duration
+
 	// The lifetime of an downstream access token issued by the token endpoint. Access tokens should generally
 	// be fairly short-lived.
 	AccessTokenLifespan time.Duration
@@ -62,6 +106,13 @@ type Configuration struct {
 	// in their web browser.
 	RefreshTokenLifespan time.Duration
 
+	// Optionally override the default RefreshTokenLifespan depending on the specific request.
+	// Note that refresh tokens can be issued by authcode exchanges, device code exchanges, and refreshes
+	// (with different grant types on the request), so implementations of this method should handle choosing
+	// lifespans for all cases as desired. A returned lifespan which is not positive means that the session
+	// has reached its maximum age, and no more tokens should be issued for it.
+	OverrideDefaultRefreshTokenLifespan OverrideLifespan
+
 	// AuthorizationCodeSessionStorageLifetime is the length of time after which an authcode is allowed to be garbage
 	// collected from storage. Authcodes are kept in storage after they are redeemed to allow the system to mark the
 	// authcode as already used, so it can reject any future uses of the same authcode with special case handling which
@@ -70,6 +121,19 @@ type Configuration struct {
 	// the sum of the AuthorizeCodeLifespan and the RefreshTokenLifespan.
 	AuthorizationCodeSessionStorageLifetime StorageLifetime
 
+	// DeviceCodeSessionStorageLifetime is the length of time after which a device code session is allowed to be
+	// garbage collected from storage. Similar to authcodes, device codes are kept in storage after they are redeemed
+	// to allow the system to detect and reject any future uses of the same device code. Therefore, this should be
+	// significantly longer than the DeviceAndUserCodeLifespan, and there is probably no reason to make it longer
+	// than the sum of the DeviceAndUserCodeLifespan and the RefreshTokenLifespan.
+	DeviceCodeSessionStorageLifetime StorageLifetime
+
+	// UserCodeSessionStorageLifetime is the length of time after which the lookup data for a user code is allowed to
+	// be garbage collected from storage. A user code can only be used once, and it is explicitly deleted when it is
+	// used. Otherwise, it is not needed anymore after it has expired. Therefore, this can be just slightly longer than
+	// the DeviceAndUserCodeLifespan.
+	UserCodeSessionStorageLifetime StorageLifetime
+
 	// PKCESessionStorageLifetime is the length of time after which PKCE data is allowed to be garbage collected from
 	// storage. PKCE sessions are closely related to authorization code sessions. After the authcode is successfully
 	// redeemed, the PKCE session is explicitly deleted. After the authcode expires, the PKCE session is no longer needed,
@@ -85,6 +149,12 @@ type Configuration struct {
 	// as AuthorizeCodeLifespan to avoid any chance of the garbage collector deleting it while it is being used.
 	OIDCSessionStorageLifetime StorageLifetime
 
+	// PushedAuthorizationRequestSessionStorageLifetime is the length of time after which a pushed authorization request
+	// is allowed to be garbage collected from storage. A pushed authorization request can only be used once, and it is
+	// explicitly deleted when it is used. Otherwise, it is not needed anymore after it has expired. Therefore, this can
+	// be just slightly longer than the PushedAuthorizationRequestLifespan.
+	PushedAuthorizationRequestSessionStorageLifetime StorageLifetime
+
 	// AccessTokenSessionStorageLifetime is the length of time after which an access token's session data is allowed
 	// to be garbage collected from storage.  These must exist in storage for as long as the refresh token is valid
 	// or else the refresh flow will not work properly. So this must be longer than RefreshTokenLifespan.
@@ -6,6 +6,7 @@ package upstreamprovider
 import (
 	"context"
 	"net/url"
+	"time"
 
 	"golang.org/x/oauth2"
 	"k8s.io/apimachinery/pkg/types"
@@ -206,3 +207,149 @@ type UpstreamGithubIdentityProviderI interface {
 	// token that was just issued.
 	GetUser(ctx context.Context, accessToken string, idpDisplayName string, retryOnUnauthorized UnauthorizedRetryBehavior) (*GitHubUser, error)
 }
+
+type GitLabUser struct {
+	Username          string   // could be username, id, or username:id
+	Groups            []string // could be paths, full paths, or ids
+	DownstreamSubject string   // the whole downstream subject URI
+}
+
+// GitLabLoginDeniedError can be returned by UpstreamGitLabIdentityProviderI GetUser() when a policy
+// configured on GitLabIdentityProvider should prevent this user from completing authentication.
+type GitLabLoginDeniedError struct {
+	message string
+}
+
+func NewGitLabLoginDeniedError(message string) GitLabLoginDeniedError {
+	return GitLabLoginDeniedError{message: message}
+}
+
+func (g GitLabLoginDeniedError) Error() string {
+	return g.message
+}
+
+var _ error = &GitLabLoginDeniedError{}
+
+type UpstreamGitLabIdentityProviderI interface {
+	UpstreamIdentityProviderI
+
+	// GetClientID returns the OAuth client ID registered with the upstream provider to be used in the authorization code flow.
+	GetClientID() string
+
+	// GetScopes returns the scopes to request in authorization flow.
+	GetScopes() []string
+
+	// GetUsernameAttribute returns the attribute from the GitLab API user response to use for the downstream username.
+	// See https://docs.gitlab.com/ee/api/users.html#for-non-administrator-users.
+	// Note that this is a constructed value - do not expect that the result will exactly match one of the JSON fields.
+	GetUsernameAttribute() idpv1alpha1.GitLabUsernameAttribute
+
+	// GetGroupNameAttribute returns the attribute from the GitLab API groups response to use for the downstream group names.
+	// See https://docs.gitlab.com/ee/api/groups.html#list-groups.
+	GetGroupNameAttribute() idpv1alpha1.GitLabGroupNameAttribute
+
+	// GetAllowedGroups returns a list of top-level groups configured to allow authentication.
+	// If this list has contents, a user must have membership in at least one of these groups or their subgroups
+	// to log in, and only these groups and their subgroups should be represented as groups for the downstream token.
+	// If this list is empty, then any user can log in regardless of group membership, and any observable
+	// group memberships should be represented as groups for the downstream token.
+	GetAllowedGroups() *setutil.CaseInsensitiveSet
+
+	// GetAuthorizationURL returns the authorization URL for the configured GitLab. This will look like:
+	// https://<spec.gitlabAPI.host>/oauth/authorize
+	// It will not include any query parameters or fragment. Any subdomains or port will come from <spec.gitlabAPI.host>.
+	// It will never include a username or password in the authority section.
+	GetAuthorizationURL() string
+
+	// ExchangeAuthcode performs an upstream GitLab authorization code exchange.
+	// Returns the raw access token and refresh token.
+	ExchangeAuthcode(ctx context.Context, authcode string, pkceCodeVerifier pkce.Code, redirectURI string) (*oauth2.Token, error)
+
+	// PerformRefresh uses the given refresh token to get new tokens from GitLab. GitLab rotates refresh tokens,
+	// so the returned refresh token must be used for the next refresh.
+	PerformRefresh(ctx context.Context, refreshToken string) (*oauth2.Token, error)
+
+	// GetUser calls the user and groups APIs of GitLab using the accessToken.
+	// It validates any required group memberships. It returns a User or an error.
+	// The IDP display name is passed to aid in building a suitable downstream subject string.
+	GetUser(ctx context.Context, accessToken string, idpDisplayName string) (*GitLabUser, error)
+}
+
+type OAuth2User struct {
+	Username          string   // from the configured username expression
+	Groups            []string // from the configured groups expression
+	DownstreamSubject string   // the whole downstream subject URI
+}
+
+type UpstreamOAuth2IdentityProviderI interface {
+	UpstreamIdentityProviderI
+
+	// GetClientID returns the OAuth client ID registered with the upstream provider to be used in the authorization code flow.
+	GetClientID() string
+
+	// GetScopes returns the scopes to request in authorization flow.
+	GetScopes() []string
+
+	// GetAdditionalAuthcodeParams returns additional params to be sent on authcode requests.
+	GetAdditionalAuthcodeParams() map[string]string
+
+	// GetAuthorizationURL returns the configured authorization URL of the upstream provider.
+	GetAuthorizationURL() string
+
+	// ExchangeAuthcode performs an upstream OAuth2 authorization code exchange.
+	// Returns the raw access token, and the refresh token when the provider returned one.
+	ExchangeAuthcode(ctx context.Context, authcode string, pkceCodeVerifier pkce.Code, redirectURI string) (*oauth2.Token, error)
+
+	// PerformRefresh uses the given refresh token to get new tokens from the upstream provider.
+	// Some providers rotate refresh tokens, so the returned refresh token, if any, should be used for the next refresh.
+	PerformRefresh(ctx context.Context, refreshToken string) (*oauth2.Token, error)
+
+	// GetUser calls the user info endpoint, and the groups endpoint when configured, using the accessToken.
+	// It evaluates the configured claims expressions to determine the user's identity. It returns a User or an error.
+	// The IDP display name is passed to aid in building a suitable downstream subject string.
+	GetUser(ctx context.Context, accessToken string, idpDisplayName string) (*OAuth2User, error)
+}
+
+// SAMLUser is the identity of a user as determined from a validated SAML response.
+type SAMLUser struct {
+	Username          string   // from the configured username attribute, or the NameID
+	Groups            []string // from the configured groups attribute
+	DownstreamSubject string   // the whole downstream subject URI
+
+	// NameID is the NameID of the subject of the SAML assertion.
+	NameID string
+
+	// SessionNotOnOrAfter is the time after which the upstream IDP requires the session to be considered ended,
+	// or the zero value when the upstream IDP did not specify one.
+	SessionNotOnOrAfter time.Time
+}
+
+type UpstreamSAMLIdentityProviderI interface {
+	UpstreamIdentityProviderI
+
+	// GetIDPEntityID returns the entity ID of the upstream SAML IDP, as found in its metadata.
+	GetIDPEntityID() string
+
+	// GetUsernameAttribute returns the name of the SAML attribute to use for the downstream username.
+	// Returns an empty string when the NameID of the subject of the SAML assertion should be used instead.
+	GetUsernameAttribute() string
+
+	// GetGroupsAttribute returns the name of the SAML attribute to use for the downstream group names.
+	// Returns an empty string when groups should not be read from the SAML assertion.
+	GetGroupsAttribute() string
+
+	// AuthnRequestURL returns the URL of the single sign-on service of the upstream IDP, including an
+	// AuthnRequest encoded using the HTTP-Redirect binding. The AuthnRequest will have the given request ID and
+	// will ask for the SAML response to be sent to the given assertion consumer service URL using the HTTP-POST
+	// binding. The relayState will be sent back by the upstream IDP along with the SAML response.
+	// The defaultSPEntityID is used as the entity ID of the service provider when one was not configured
+	// on the SAMLIdentityProvider.
+	AuthnRequestURL(requestID string, relayState string, acsURL string, defaultSPEntityID string) (string, error)
+
+	// ParseResponse decodes the base64-encoded SAML response, validates its signature against the metadata
+	// of the upstream IDP, and validates that it was issued in response to the AuthnRequest with the given
+	// request ID for the given assertion consumer service URL and service provider entity ID. It returns the
+	// user's identity as determined from the assertion. The IDP display name is passed to aid in building a
+	// suitable downstream subject string.
+	ParseResponse(encodedResponse string, requestID string, acsURL string, defaultSPEntityID string, idpDisplayName string) (*SAMLUser, error)
+}
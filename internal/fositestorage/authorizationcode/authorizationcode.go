@@ -1,4 +1,4 @@
-// Copyright 2020-2024 the Pinniped contributors. All Rights Reserved.
+// Copyright 2020-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package authorizationcode
@@ -13,7 +13,6 @@ import (
 	fositeoauth2 "github.com/ory/fosite/handler/oauth2"
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
-	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
 
 	"go.pinniped.dev/internal/constable"
 	"go.pinniped.dev/internal/crud"
@@ -37,7 +36,11 @@ const (
 	// Version 6 is when we upgraded fosite in Dec 2023.
 	// Version 7 is when OIDCClients were given configurable ID token lifetimes.
 	// Version 8 is when GitHubIdentityProvider was added.
-	authorizeCodeStorageVersion = "8"
+	// Version 9 is when SAMLIdentityProvider was added.
+	// Version 10 is when GitLabIdentityProvider was added.
+	// Version 11 is when OAuth2IdentityProvider was added.
+	// Version 12 is when the acr and amr claims were added to the OIDC session data.
+	authorizeCodeStorageVersion = "12"
 )
 
 var _ fositeoauth2.AuthorizeCodeStorage = &authorizeCodeStorage{}
@@ -53,7 +56,7 @@ type Session struct {
 	Version string          `json:"version"`
 }
 
-func New(secrets corev1client.SecretInterface, clock func() time.Time, sessionStorageLifetime timeouts.StorageLifetime) fositeoauth2.AuthorizeCodeStorage {
+func New(secrets crud.SecretsBackend, clock func() time.Time, sessionStorageLifetime timeouts.StorageLifetime) fositeoauth2.AuthorizeCodeStorage {
 	return &authorizeCodeStorage{storage: crud.New(TypeLabelValue, secrets, clock), lifetime: sessionStorageLifetime}
 }
 
@@ -99,7 +102,7 @@ func (a *authorizeCodeStorage) CreateAuthorizeCodeSession(ctx context.Context, s
 	_, err = a.storage.Create(ctx,
 		signature,
 		&Session{Active: true, Request: request, Version: authorizeCodeStorageVersion},
-		nil,
+		map[string]string{fositestorage.StorageRequestIDLabelName: requester.GetID()},
 		nil,
 		a.lifetime(requester),
 	)
@@ -269,134 +272,141 @@ const ExpectedAuthorizeCodeSessionJSONFromFuzzing = `{
 			"IDTokenLifetimeConfiguration": 2593156354696908951
 		},
 		"scopes": [
-			"ǀŻQ'k頂箨J-",
-			"銈ɓ"
+			"櫗Pu4銈ɓ啶",
+			"ȑǕÄ"
 		],
 		"grantedScopes": [
-			"#昏Q遐*\\髎bŸ1慂UFƼ",
-			"Oǹ冟[ǟ褾攚ŝlĆ",
-			"駳骪l拁乖¡J¿Ƈ妔M"
+			"ǻ并峸Tćɇ}ĈÉhOǹ冟[",
+			"篚h°"
 		],
 		"form": {
-			"¥": [
-				"碓ɎǛƍdÚ慂+槰蚪i齥篗裢?霃谥v"
-			],
-			"囡莒汗狲N": [
-				"霋Ɔ輡5ȏ樛ȧ.mĔ櫓Ǩ療",
-				"Ǉ/"
-			],
-			"礐jµ": [
-				"A",
-				"Jǽȭ$奍囀ǅ悷鵱民撲ʓeŘ嬀",
-				"行"
+			"Ć厦駳骪l拁乖¡J¿Ƈ妔Mʑ": [
+				"ɂĵ~Čyʊ恀c\"Ǌřðȿ/槱黧郛",
+				"谥"
 			]
 		},
 		"session": {
 			"fosite": {
 				"id_token_claims": {
-					"jti": "8",
-					"iss": "[ĝU噤'pX ʨ裄@",
-					"sub": "!ȁu狍ɶȳsčɦƦ诱ļ攬林Ñ",
+					"jti": "礐jµ",
+					"iss": "濔Aʉ\u003cS獾蔀OƭUǦ",
+					"sub": "民撲ʓeŘ嬀j¤囡莒汗狲N\u003cCq",
 					"aud": [
-						"ƍ",
-						"¿o\u003e"
+						"5ȏ樛ȧ.mĔ櫓Ǩ療騃Ǐ}ɟ",
+						"潠[ĝU噤'",
+						"ŁȗɉY妶ǵ!ȁ"
 					],
-					"nonce": "ɔ闏À1#锰劝旣樎Ȱ",
-					"exp": "2008-03-21T05:57:43.261171532Z",
-					"iat": "2080-07-31T09:39:36.259602759Z",
-					"rat": "2093-01-01T11:32:44.398071123Z",
-					"auth_time": "2088-07-12T21:20:22.8199645Z",
-					"at_hash": "鎅ǸÖ绝TFǊĆw宵ɚe",
-					"acr": "ùZ蛆鬣a\"ÙǞ0觢Û±¤ǟaȭ_Ǣ",
+					"nonce": "褰ʎɰ癟VĎĢ婄磫绒u妔隤ʑƍš駎竪",
+					"exp": "2070-12-18T20:33:00.486996036Z",
+					"iat": "2054-02-24T12:24:17.071493764Z",
+					"rat": "2102-07-02T02:13:48.271212495Z",
+					"auth_time": "1976-12-06T10:20:01.18795832Z",
+					"at_hash": "Ż麤ã桒嘞\\摗Ǘū稖咾鎅ǸÖ绝TFǊĆ",
+					"acr": "đų",
 					"amr": [
-						"-{5£踉4"
+						"Y48珎²Lcéã越|j¦鲶H股"
 					],
-					"c_hash": "5^驜Ŗ~ů崧軒q腟u尿",
+					"c_hash": "_Ǣ肟v\u0026đehpƧ蓟炆ç侎Ě·",
 					"ext": {
-						"ğ": 1479850437,
-						"ǎ^嫯R忑隯ƗƋ*L\u0026": {
-							"4鞀腉篓ğǫ\\aȊ4ț髄AlȒ曓蓳n匟": [
-								1260036883
+						"q腟u尿宲!N檇雨缠蕖¤'+ʣȍ": {
+							"\u0026ɽ艄ʬʏ": [
+								963092435
 							],
-							"磹*金爃鶴滱ůĮǐ": {
-								"c3#\u0026PƢ曰l騌蘙螤": null,
-								"Ð嫹Sx镯荫őł": {
-									"鿞ČY\u0026鶡萷ɵ啜s攦Ɩ": true
-								}
+							"ęN\u003c": {
+								"G-壧丵礴鋈k蟵pAɂʅ": {
+									"#\u0026PƢ曰l騌蘙螤\\阏Đ镴Ƥm蔻ǭ\\鿞": true
+								},
+								"Ȋ4ț髄AlȒ曓蓳n匟": null
 							}
-						}
+						},
+						"崧": 829625319
 					}
 				},
 				"headers": {
 					"extra": {
-						"Rë_g\"": 573016912,
-						"啴SƇMǃļū@$": {
-							"i\u0026\u0026Q@Ǥ": {
-								"ĊƑ÷Ƒ螞费": null,
-								"Ƈ畋rɞ?Ɵ]旎Ȳ濡胉室癑勦e": {
-									"9ǍȬ劘$iA砳_": true
-								}
-							},
-							"胬龯,t": [
-								1355041984
-							]
+						"ɓ騒": 3014043041,
+						"鑳绪": {
+							"s攦Ɩïdnǔ鰙钻煐ɨ": [
+								2860726132
+							],
+							"ÅD": {
+								",t猟i\u0026\u0026Q@ǤǟǗǪ飘ȱF?Ƈ": {
+									"~劰û橸ɽ銐ƭ?}H": false
+								},
+								"ǃļū@$Ţ麈": null
+							}
 						}
 					}
 				},
 				"expires_at": {
-					"埅ȜʁɁ;Bd謺錳4帳Ņ": "1982-04-18T19:26:28.008651843Z",
-					"碼Ǫ": "2028-05-31T03:22:30.23394531Z"
+					"~ē埅ȜʁɁ;Bd謺錳4": "2015-08-19T13:08:18.773609011Z",
+					"聢螈鋖颤ōɓɡ Ǽǟ迍阊v\"豑觳": "2011-06-06T02:48:43.823221218Z",
+					"鳚ţ9ǍȬ劘$iA砳_屃ȹ": "2056-04-25T18:03:08.8479111Z"
 				},
-				"username": "鋖颤ōɓɡ Ǽǟ迍阊v\"豑觳翢砜",
-				"subject": "ɆƊ#XɗD愌铵ĸYų厷ɁOƪ"
+				"username": "趘ɆƊ#XɗD愌铵ĸYų厷ɁOƪ穋嶿鳈",
+				"subject": "圭V燣\u003e鷦D\u0026\u00265廃'荤Ý呐ʣ®ǅ"
 			},
 			"custom": {
-				"username": "嶿鳈恱va|载ǰɱ汶C]ɲ'=ĸ",
-				"upstreamUsername": "ʣ®ǅȪǣǎǔ爣縗ɦüHêQ仏1őƖ2",
+				"username": "ǣǎ",
+				"upstreamUsername": "ʜ3ǶB臤H :靥湤庤毩",
 				"upstreamGroups": [
-					"Ȇ",
-					"ǞʜƢú4¶鎰"
+					"暮唍Ǟ",
+					"Ɔu"
 				],
-				"providerUID": "韁臯氃妪婝rȤ\"h丬鎒ơ娻}ɼƟ",
-				"providerName": "闺髉龳ǽÙ龦O亾EW莛8嘶×",
-				"providerType": "戙鵮碡ʯiŬŽ非Ĝ眧Ĭ葜SŦ",
+				"providerUID": "4¶鎰飔搠uŌ魪o_ȝŀ?h$\"ȯ輦È",
+				"providerName": "ȥ",
+				"providerType": "髉龳ǽÙ",
 				"warnings": [
-					"觛ǂ焺nŐǛ3}Ü#",
-					"(ý綃ʃʚƟ覣k眐4ĈtC嵽痊w©"
+					"¥潝邎Ȗ莅ŝǔ盕戙鵮碡ʯ"
 				],
 				"oidc": {
-					"upstreamRefreshToken": "榨Q|ôɵt毇",
-					"upstreamAccessToken": "瓕巈",
-					"upstreamSubject": "鉢緋uƴŤȱʀļÂ?",
-					"upstreamIssuer": "27就伒犘c钡ɏȫ"
+					"upstreamRefreshToken": "ŬŽ非Ĝ眧Ĭ葜SŦ餧Ĭ倏4ĵ嶼仒篻",
+					"upstreamAccessToken": "}",
+					"upstreamSubject": "ʬ橳(ý綃ʃʚƟ覣k眐4ĈtC嵽痊w©",
+					"upstreamIssuer": "紽ǒ|鰽ŋ猊Ia瓕巈環_ɑ彨ƍ蛊ʚ£",
+					"upstreamACR": "Â?墖\u003cƬb獭潜Ʃ饾k|鬌R蜚蠣",
+					"upstreamAMR": [
+						"ȱ藚ɏ¬Ê蒭堜]ȗ韚ʫ"
+					]
 				},
 				"ldap": {
-					"userDN": "š%OpKȱ藚ɏ¬Ê蒭堜",
+					"userDN": "ȫ碰+ʫ怓曥Ċi磊ůď逳鞪?3)藵睋邔",
 					"extraRefreshAttributes": {
-						"1飞": "笿0D餹",
-						"誮rʨ鷞aŚB碠k9帴ʘ赱ŕ瑹xȢ~": ")藵睋邔\u0026Ű惫蜀Ģ¡圔鎥墀"
+						"úT妼É4İ\u003e×1飞O+î艔垎0OƉǢ": "Ä摱ìÓȐĨf跞@"
 					}
 				},
 				"activedirectory": {
-					"userDN": "êĝ",
+					"userDN": "¿,ɭS隑ip偶宾儮猷V麹Œ颛Ė應,",
 					"extraRefreshAttributes": {
-						"IȽ齤士bEǎ": "跞@)¿,ɭS隑ip偶宾儮猷V麹",
-						"ȝƋ鬯犦獢9c5¤.岵": "浛a齙\\蹼偦歛"
+						"9c5¤.岵骘胲ƤkǦ闧鸖I¶媁y": "拁Ȃ縅ǅķ?吭匞",
+						"擦28": "zvưã置bņ抰蛖a",
+						"錝D": "承dʬ)ġ,TÀqy_º$+溪Ÿ"
 					}
 				},
 				"github": {
-					"upstreamAccessToken": " 皦pSǬŝ社Vƅȭǝ*擦28ǅ"
+					"upstreamAccessToken": "Œų崓ļ憽-蹐È_¸]fś"
+				},
+				"saml": {
+					"nameID": "ɂ/",
+					"sessionNotOnOrAfter": "1983-08-30T23:18:03.444798132Z"
+				},
+				"gitlab": {
+					"upstreamRefreshToken": "囤1+,Ȳ齠@ɍB鳛Nč乿ƔǴę鏶"
+				},
+				"oauth2": {
+					"upstreamRefreshToken": "ɣƜ/気ū齢q萮左/篣AÚƄŕ~čfV",
+					"upstreamAccessToken": "x荃墎]ac["
 				}
 			}
 		},
 		"requestedAudience": [
-			"甍 ć\u003cʘ筫",
-			"蛖a³2ʫ承dʬ)ġ,TÀqy_"
+			"XôĖ给溬d鞕",
+			"腿tʏƲ%}ſ¯Ɣ 籌Tǘ乚Ȥ2Ķě"
 		],
 		"grantedAudience": [
-			"$+溪ŸȢŒų崓ļ憽",
-			"姧骦:駝重EȫʆɵʮGɃ"
+			"=瑅ƍ逤ŔfȀ箬+橇",
+			"苚栽ŷ2葕箈¶T1峱ĊYů7ɼȣʒM"
 		]
 	},
-	"version": "8"
+	"version": "12"
 }`
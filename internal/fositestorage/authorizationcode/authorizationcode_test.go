@@ -34,6 +34,7 @@ import (
 	kubetesting "k8s.io/client-go/testing"
 	clocktesting "k8s.io/utils/clock/testing"
 
+	supervisorconfigv1alpha1 "go.pinniped.dev/generated/latest/apis/supervisor/config/v1alpha1"
 	"go.pinniped.dev/internal/federationdomain/clientregistry"
 	"go.pinniped.dev/internal/federationdomain/timeouts"
 	"go.pinniped.dev/internal/fositestorage"
@@ -43,7 +44,7 @@ import (
 
 const (
 	namespace       = "test-ns"
-	expectedVersion = "8" // update this when you update the storage version in the production code
+	expectedVersion = "12" // update this when you update the storage version in the production code
 )
 
 var (
@@ -66,7 +67,8 @@ func TestAuthorizationCodeStorage(t *testing.T) {
 				Name:            "pinniped-storage-authcode-pwu5zs7lekbhnln2w4",
 				ResourceVersion: "",
 				Labels: map[string]string{
-					"storage.pinniped.dev/type": "authcode",
+					"storage.pinniped.dev/type":       "authcode",
+					"storage.pinniped.dev/request-id": "abcd-1",
 				},
 				Annotations: map[string]string{
 					"storage.pinniped.dev/garbage-collect-after": fakeNowPlusLifetimeAsString,
@@ -86,7 +88,8 @@ func TestAuthorizationCodeStorage(t *testing.T) {
 				Name:            "pinniped-storage-authcode-pwu5zs7lekbhnln2w4",
 				ResourceVersion: "",
 				Labels: map[string]string{
-					"storage.pinniped.dev/type": "authcode",
+					"storage.pinniped.dev/type":       "authcode",
+					"storage.pinniped.dev/request-id": "abcd-1",
 				},
 				Annotations: map[string]string{
 					"storage.pinniped.dev/garbage-collect-after": fakeNowPlusLifetimeAsString,
@@ -317,6 +320,8 @@ func TestFuzzAndJSONNewValidEmptyAuthorizeCodeSession(t *testing.T) {
 		// if fosite.Request changes to add more, the fuzzer will panic
 		func(fc *fosite.Client, c fuzz.Continue) {
 			c.Fuzz(defaultClient)
+			// the access token audience of a client is never saved to session storage
+			defaultClient.AccessTokenAudience = ""
 			*fc = defaultClient
 		},
 		func(fs *fosite.Session, c fuzz.Continue) {
@@ -355,6 +360,26 @@ func TestFuzzAndJSONNewValidEmptyAuthorizeCodeSession(t *testing.T) {
 			jwk.CertificateThumbprintSHA256 = []byte{}
 		},
 
+		// the TLS client auth configuration of a client is never saved to session storage
+		func(a **clientregistry.TLSClientAuth, c fuzz.Continue) {
+			*a = nil
+		},
+
+		// the client credentials identity of a client is never saved to session storage
+		func(a **clientregistry.ClientCredentials, c fuzz.Continue) {
+			*a = nil
+		},
+
+		// the session policy of a client is never saved to session storage
+		func(a *timeouts.SessionPolicy, c fuzz.Continue) {
+			*a = timeouts.SessionPolicy{}
+		},
+
+		// the access token format of a client is never saved to session storage
+		func(a *supervisorconfigv1alpha1.AccessTokenFormat, c fuzz.Continue) {
+			*a = ""
+		},
+
 		// set this to make the .Equal comparison work
 		// this is safe because Time explicitly implements JSON marshalling and unmarshalling
 		func(tp *time.Time, c fuzz.Continue) {
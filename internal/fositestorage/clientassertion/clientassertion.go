@@ -0,0 +1,131 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package clientassertion stores the JWT IDs of the client assertions which were used by clients to authenticate
+// using the private_key_jwt client authentication method, so that each client assertion can only be used once.
+package clientassertion
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"time"
+
+	"github.com/ory/fosite"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+
+	"go.pinniped.dev/internal/constable"
+	"go.pinniped.dev/internal/crud"
+)
+
+const (
+	TypeLabelValue = "client-assertion"
+
+	ErrInvalidClientAssertionVersion = constable.Error("client assertion data has wrong version")
+
+	// Version 1 was the initial release of storage.
+	clientAssertionStorageVersion = "1"
+
+	// Keep the storage around a little longer than the client assertion is valid, to avoid the possibility
+	// that the storage be garbage collected while the client assertion is still usable.
+	storageExtraLifetime = time.Minute
+)
+
+// Storage remembers the JWT IDs of client assertions until the client assertions expire.
+// Its methods have the same semantics as the corresponding methods of fosite.ClientManager.
+type Storage interface {
+	ClientAssertionJWTValid(ctx context.Context, jti string) error
+	SetClientAssertionJWT(ctx context.Context, jti string, exp time.Time) error
+}
+
+type clientAssertionStorage struct {
+	storage crud.Storage
+	clock   func() time.Time
+}
+
+type session struct {
+	Expiry  time.Time `json:"expiry"`
+	Version string    `json:"version"`
+}
+
+func New(secrets crud.SecretsBackend, clock func() time.Time) Storage {
+	return &clientAssertionStorage{storage: crud.New(TypeLabelValue, secrets, clock), clock: clock}
+}
+
+// ClientAssertionJWTValid returns fosite.ErrJTIKnown if the JWT ID was already used by a client assertion
+// which has not expired yet, and nil if the JWT ID is not known.
+func (s *clientAssertionStorage) ClientAssertionJWTValid(ctx context.Context, jti string) error {
+	session, err := s.getSession(ctx, jti)
+	if err != nil {
+		return err
+	}
+	if session != nil && session.Expiry.After(s.clock()) {
+		return fosite.ErrJTIKnown
+	}
+	return nil
+}
+
+// SetClientAssertionJWT marks the JWT ID as known until the given expiry time.
+// It returns fosite.ErrJTIKnown if the JWT ID is already known.
+func (s *clientAssertionStorage) SetClientAssertionJWT(ctx context.Context, jti string, exp time.Time) error {
+	err := s.create(ctx, jti, exp)
+	if !apierrors.IsAlreadyExists(err) {
+		return err
+	}
+
+	// The storage of an expired client assertion may not have been garbage collected yet.
+	// Replace it in that case, since its JWT ID may be used again.
+	if err := s.ClientAssertionJWTValid(ctx, jti); err != nil {
+		return err
+	}
+	if err := s.storage.Delete(ctx, signature(jti)); err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("failed to delete expired client assertion: %w", err)
+	}
+	err = s.create(ctx, jti, exp)
+	if apierrors.IsAlreadyExists(err) {
+		// Another request used the same JWT ID at the same time.
+		return fosite.ErrJTIKnown
+	}
+	return err
+}
+
+func (s *clientAssertionStorage) create(ctx context.Context, jti string, exp time.Time) error {
+	lifetime := max(exp.Sub(s.clock()), 0)
+
+	_, err := s.storage.Create(ctx,
+		signature(jti),
+		&session{Expiry: exp.UTC(), Version: clientAssertionStorageVersion},
+		nil,
+		nil,
+		lifetime+storageExtraLifetime,
+	)
+	return err
+}
+
+func (s *clientAssertionStorage) getSession(ctx context.Context, jti string) (*session, error) {
+	session := &session{}
+	_, err := s.storage.Get(ctx, signature(jti), session)
+
+	if apierrors.IsNotFound(err) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to get client assertion: %w", err)
+	}
+
+	if version := session.Version; version != clientAssertionStorageVersion {
+		return nil, fmt.Errorf("%w: client assertion has version %s instead of %s",
+			ErrInvalidClientAssertionVersion, version, clientAssertionStorageVersion)
+	}
+
+	return session, nil
+}
+
+// signature returns a fixed length signature of the JWT ID, because the JWT ID is chosen by the client and could
+// be any length, but it needs to fit into the name of a Secret.
+func signature(jti string) string {
+	hash := sha256.Sum256([]byte(jti))
+	return base64.RawURLEncoding.EncodeToString(hash[:])
+}
@@ -0,0 +1,106 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package clientassertion
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ory/fosite"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	coretesting "k8s.io/client-go/testing"
+	clocktesting "k8s.io/utils/clock/testing"
+)
+
+const namespace = "test-ns"
+
+var fakeNow = time.Date(2030, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+func TestClientAssertionStorage(t *testing.T) {
+	client := kubefake.NewClientset()
+	secrets := client.CoreV1().Secrets(namespace)
+	fakeClock := clocktesting.NewFakeClock(fakeNow)
+	storage := New(secrets, fakeClock.Now)
+
+	// Unknown JWT IDs are valid.
+	require.NoError(t, storage.ClientAssertionJWTValid(t.Context(), "some-jti"))
+
+	require.NoError(t, storage.SetClientAssertionJWT(t.Context(), "some-jti", fakeNow.Add(5*time.Minute)))
+
+	secretList, err := secrets.List(t.Context(), metav1.ListOptions{})
+	require.NoError(t, err)
+	require.Len(t, secretList.Items, 1)
+	secret := secretList.Items[0]
+	require.Equal(t, "pinniped-storage-client-assertion-lsunzjls3sazqgwsvfcf2ngbwgoo7j2us6x3svct3vpojc5mty7q", secret.Name)
+	require.Equal(t, map[string]string{"storage.pinniped.dev/type": "client-assertion"}, secret.Labels)
+	require.Equal(t, map[string]string{"storage.pinniped.dev/garbage-collect-after": "2030-01-01T00:06:00Z"}, secret.Annotations)
+	require.Equal(t, map[string][]byte{
+		"pinniped-storage-data":    []byte(`{"expiry":"2030-01-01T00:05:00Z","version":"1"}`),
+		"pinniped-storage-version": []byte("1"),
+	}, secret.Data)
+	require.Equal(t, corev1.SecretType("storage.pinniped.dev/client-assertion"), secret.Type)
+
+	// Known JWT IDs cannot be used again until they expire.
+	require.ErrorIs(t, storage.ClientAssertionJWTValid(t.Context(), "some-jti"), fosite.ErrJTIKnown)
+	require.ErrorIs(t, storage.SetClientAssertionJWT(t.Context(), "some-jti", fakeNow.Add(5*time.Minute)), fosite.ErrJTIKnown)
+	require.NoError(t, storage.ClientAssertionJWTValid(t.Context(), "other-jti"))
+
+	// After they expire, they may be used again, even when their storage was not garbage collected yet.
+	fakeClock.Step(5 * time.Minute)
+	require.NoError(t, storage.ClientAssertionJWTValid(t.Context(), "some-jti"))
+	require.NoError(t, storage.SetClientAssertionJWT(t.Context(), "some-jti", fakeNow.Add(10*time.Minute)))
+	require.ErrorIs(t, storage.ClientAssertionJWTValid(t.Context(), "some-jti"), fosite.ErrJTIKnown)
+
+	secretList, err = secrets.List(t.Context(), metav1.ListOptions{})
+	require.NoError(t, err)
+	require.Len(t, secretList.Items, 1)
+	require.Equal(t, "2030-01-01T00:11:00Z", secretList.Items[0].Annotations["storage.pinniped.dev/garbage-collect-after"])
+}
+
+func TestLongJWTIDs(t *testing.T) {
+	secrets := kubefake.NewClientset().CoreV1().Secrets(namespace)
+	storage := New(secrets, time.Now)
+
+	veryLongJTI := string(make([]byte, 1000))
+	require.NoError(t, storage.SetClientAssertionJWT(t.Context(), veryLongJTI, time.Now().Add(time.Minute)))
+	require.ErrorIs(t, storage.ClientAssertionJWTValid(t.Context(), veryLongJTI), fosite.ErrJTIKnown)
+}
+
+func TestWrongVersion(t *testing.T) {
+	secrets := kubefake.NewClientset().CoreV1().Secrets(namespace)
+	storage := New(secrets, time.Now)
+
+	_, err := secrets.Create(t.Context(), &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   "pinniped-storage-client-assertion-lsunzjls3sazqgwsvfcf2ngbwgoo7j2us6x3svct3vpojc5mty7q",
+			Labels: map[string]string{"storage.pinniped.dev/type": "client-assertion"},
+		},
+		Data: map[string][]byte{
+			"pinniped-storage-data":    []byte(`{"expiry":"2030-01-01T00:05:00Z","version":"not-the-right-version"}`),
+			"pinniped-storage-version": []byte("1"),
+		},
+		Type: "storage.pinniped.dev/client-assertion",
+	}, metav1.CreateOptions{})
+	require.NoError(t, err)
+
+	err = storage.ClientAssertionJWTValid(t.Context(), "some-jti")
+	require.EqualError(t, err, "client assertion data has wrong version: client assertion has version not-the-right-version instead of 1")
+}
+
+func TestGetError(t *testing.T) {
+	client := kubefake.NewClientset()
+	client.PrependReactor("get", "secrets", func(_ coretesting.Action) (bool, runtime.Object, error) {
+		return true, nil, errors.New("some get error")
+	})
+	storage := New(client.CoreV1().Secrets(namespace), time.Now)
+
+	err := storage.ClientAssertionJWTValid(t.Context(), "some-jti")
+	require.EqualError(t, err, "failed to get client assertion: failed to get client-assertion for signature "+
+		"XKjcpXLcgZga0qlEXTTBsZzvp1SXr7lUU91e5Iusnj8: some get error")
+}
@@ -0,0 +1,372 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package devicecode
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"time"
+
+	"github.com/ory/fosite"
+	"github.com/ory/fosite/handler/rfc8628"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+
+	"go.pinniped.dev/internal/constable"
+	"go.pinniped.dev/internal/crud"
+	"go.pinniped.dev/internal/federationdomain/clientregistry"
+	"go.pinniped.dev/internal/federationdomain/timeouts"
+	"go.pinniped.dev/internal/fositestorage"
+	"go.pinniped.dev/internal/psession"
+)
+
+const (
+	TypeLabelValue         = "device-code"
+	UserCodeTypeLabelValue = "user-code"
+
+	ErrInvalidDeviceCodeRequestData    = constable.Error("device code request data must be present")
+	ErrInvalidDeviceCodeRequestVersion = constable.Error("device code request data has wrong version")
+	ErrInvalidUserCodeData             = constable.Error("user code data must be present")
+	ErrUserCodeAlreadyUsed             = constable.Error("user code has already been used")
+
+	// Version 1 was the initial release of storage.
+	// Version 2 is when SAMLIdentityProvider was added.
+	// Version 3 is when GitLabIdentityProvider was added.
+	// Version 4 is when OAuth2IdentityProvider was added.
+	// Version 5 is when the acr and amr claims were added to the OIDC session data.
+	deviceCodeStorageVersion = "5"
+)
+
+// RevocationStorage is the storage needed by the device authorization grant. In addition to the storage interface
+// defined by fosite, it allows the user code entry page to find and approve a device code session using the user code.
+type RevocationStorage interface {
+	rfc8628.DeviceAuthStorage
+
+	// GetDeviceCodeSessionByUserCode finds the device code session which was created along with the given user code.
+	// Returns a fosite.ErrNotFound error when the user code does not exist, including when it was already used.
+	GetDeviceCodeSessionByUserCode(ctx context.Context, userCodeSignature string) (fosite.DeviceRequester, error)
+
+	// ApproveDeviceCodeSession marks the device code session which was created along with the given user code as
+	// accepted, and saves the session and granted scopes from the given requester. The user code cannot be used
+	// again afterward. The device which holds the device code may then redeem it at the token endpoint.
+	ApproveDeviceCodeSession(ctx context.Context, userCodeSignature string, requester fosite.DeviceRequester) error
+
+	// RejectDeviceCodeSession marks the device code session which was created along with the given user code as
+	// rejected. The user code cannot be used again afterward, and the device which holds the device code will get
+	// an access_denied error from the token endpoint.
+	RejectDeviceCodeSession(ctx context.Context, userCodeSignature string) error
+
+	// RevokeDeviceCodeSession deletes any device code sessions for the given request ID.
+	RevokeDeviceCodeSession(ctx context.Context, requestID string) error
+}
+
+var _ RevocationStorage = &deviceCodeStorage{}
+
+type deviceCodeStorage struct {
+	storage          crud.Storage
+	userCodeStorage  crud.Storage
+	lifetime         timeouts.StorageLifetime
+	userCodeLifetime timeouts.StorageLifetime
+}
+
+// Session is stored under the signature of the device code.
+type Session struct {
+	Active        bool                 `json:"active"`
+	UserCodeState fosite.UserCodeState `json:"userCodeState"`
+	Request       *fosite.Request      `json:"request"`
+	Version       string               `json:"version"`
+}
+
+// UserCodeSession is stored under the signature of the user code. It only points to the device code session,
+// since the user code entry page only knows the user code.
+type UserCodeSession struct {
+	DeviceCodeSignature string `json:"deviceCodeSignature"`
+	Version             string `json:"version"`
+}
+
+func New(
+	secrets crud.SecretsBackend,
+	clock func() time.Time,
+	sessionStorageLifetime timeouts.StorageLifetime,
+	userCodeStorageLifetime timeouts.StorageLifetime,
+) RevocationStorage {
+	return &deviceCodeStorage{
+		storage:          crud.New(TypeLabelValue, secrets, clock),
+		userCodeStorage:  crud.New(UserCodeTypeLabelValue, secrets, clock),
+		lifetime:         sessionStorageLifetime,
+		userCodeLifetime: userCodeStorageLifetime,
+	}
+}
+
+// ReadFromSecret reads the contents of a Secret as a Session.
+func ReadFromSecret(secret *corev1.Secret) (*Session, error) {
+	session := NewValidEmptyDeviceCodeSession()
+	err := crud.FromSecret(TypeLabelValue, secret, session)
+	if err != nil {
+		return nil, err
+	}
+	if session.Version != deviceCodeStorageVersion {
+		return nil, fmt.Errorf("%w: device code session has version %s instead of %s",
+			ErrInvalidDeviceCodeRequestVersion, session.Version, deviceCodeStorageVersion)
+	}
+	if session.Request.ID == "" {
+		return nil, fmt.Errorf("malformed device code session: %w", ErrInvalidDeviceCodeRequestData)
+	}
+	return session, nil
+}
+
+func (d *deviceCodeStorage) CreateDeviceAuthSession(
+	ctx context.Context,
+	deviceCodeSignature string,
+	userCodeSignature string,
+	requester fosite.DeviceRequester,
+) error {
+	deviceRequest, ok := requester.(*fosite.DeviceRequest)
+	if !ok {
+		return fositestorage.ErrInvalidRequestType
+	}
+	request, err := fositestorage.ValidateAndExtractAuthorizeRequest(&deviceRequest.Request)
+	if err != nil {
+		return err
+	}
+
+	// Create the user code first, so a collision with an existing user code can be detected
+	// before anything else is stored. Fosite will retry with a new user code in that case.
+	_, err = d.userCodeStorage.Create(ctx,
+		userCodeSignature,
+		&UserCodeSession{DeviceCodeSignature: deviceCodeSignature, Version: deviceCodeStorageVersion},
+		nil,
+		nil,
+		d.userCodeLifetime(requester),
+	)
+	if err != nil {
+		if apierrors.IsAlreadyExists(err) {
+			return fmt.Errorf("%w: %w", fosite.ErrExistingUserCodeSignature, err)
+		}
+		return err
+	}
+
+	_, err = d.storage.Create(ctx,
+		deviceCodeSignature,
+		&Session{Active: true, UserCodeState: deviceRequest.UserCodeState, Request: request, Version: deviceCodeStorageVersion},
+		map[string]string{fositestorage.StorageRequestIDLabelName: requester.GetID()},
+		nil,
+		d.lifetime(requester),
+	)
+	return err
+}
+
+func (d *deviceCodeStorage) GetDeviceCodeSession(ctx context.Context, deviceCodeSignature string, _ fosite.Session) (fosite.DeviceRequester, error) {
+	session, _, err := d.getSession(ctx, deviceCodeSignature)
+
+	// we need to always pass both the request and error back
+	if session == nil {
+		return nil, err
+	}
+
+	return session.toDeviceRequest(), err
+}
+
+func (d *deviceCodeStorage) InvalidateDeviceCodeSession(ctx context.Context, deviceCodeSignature string) error {
+	session, rv, err := d.getSession(ctx, deviceCodeSignature)
+	if err != nil {
+		return err
+	}
+
+	session.Active = false
+	if _, err := d.storage.Update(ctx, deviceCodeSignature, rv, session); err != nil {
+		if apierrors.IsConflict(err) {
+			return &errSerializationFailureWithCause{cause: err}
+		}
+		return err
+	}
+
+	return nil
+}
+
+func (d *deviceCodeStorage) GetDeviceCodeSessionByUserCode(ctx context.Context, userCodeSignature string) (fosite.DeviceRequester, error) {
+	userCodeSession, err := d.getUserCodeSession(ctx, userCodeSignature)
+	if err != nil {
+		return nil, err
+	}
+
+	session, _, err := d.getSession(ctx, userCodeSession.DeviceCodeSignature)
+	if err != nil {
+		return nil, err
+	}
+
+	return session.toDeviceRequest(), nil
+}
+
+func (d *deviceCodeStorage) ApproveDeviceCodeSession(ctx context.Context, userCodeSignature string, requester fosite.DeviceRequester) error {
+	deviceRequest, ok := requester.(*fosite.DeviceRequest)
+	if !ok {
+		return fositestorage.ErrInvalidRequestType
+	}
+	request, err := fositestorage.ValidateAndExtractAuthorizeRequest(&deviceRequest.Request)
+	if err != nil {
+		return err
+	}
+
+	userCodeSession, err := d.getUserCodeSession(ctx, userCodeSignature)
+	if err != nil {
+		return err
+	}
+
+	session, rv, err := d.getSession(ctx, userCodeSession.DeviceCodeSignature)
+	if err != nil {
+		return err
+	}
+
+	if session.UserCodeState != fosite.UserCodeUnused || session.Request.ID != request.ID {
+		return ErrUserCodeAlreadyUsed
+	}
+
+	session.UserCodeState = fosite.UserCodeAccepted
+	session.Request.Session = request.Session
+	session.Request.GrantedScope = request.GrantedScope
+	session.Request.GrantedAudience = request.GrantedAudience
+
+	if _, err := d.storage.Update(ctx, userCodeSession.DeviceCodeSignature, rv, session); err != nil {
+		if apierrors.IsConflict(err) {
+			return &errSerializationFailureWithCause{cause: err}
+		}
+		return err
+	}
+
+	// The user code is not needed anymore, and deleting it ensures that it cannot be used twice.
+	if err := d.userCodeStorage.Delete(ctx, userCodeSignature); err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+
+	return nil
+}
+
+func (d *deviceCodeStorage) RejectDeviceCodeSession(ctx context.Context, userCodeSignature string) error {
+	userCodeSession, err := d.getUserCodeSession(ctx, userCodeSignature)
+	if err != nil {
+		return err
+	}
+
+	session, rv, err := d.getSession(ctx, userCodeSession.DeviceCodeSignature)
+	if err != nil {
+		return err
+	}
+
+	if session.UserCodeState != fosite.UserCodeUnused {
+		return ErrUserCodeAlreadyUsed
+	}
+
+	session.UserCodeState = fosite.UserCodeRejected
+
+	if _, err := d.storage.Update(ctx, userCodeSession.DeviceCodeSignature, rv, session); err != nil {
+		if apierrors.IsConflict(err) {
+			return &errSerializationFailureWithCause{cause: err}
+		}
+		return err
+	}
+
+	// The user code is not needed anymore, and deleting it ensures that it cannot be used again.
+	if err := d.userCodeStorage.Delete(ctx, userCodeSignature); err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+
+	return nil
+}
+
+func (d *deviceCodeStorage) RevokeDeviceCodeSession(ctx context.Context, requestID string) error {
+	return d.storage.DeleteByLabel(ctx, fositestorage.StorageRequestIDLabelName, requestID)
+}
+
+func (d *deviceCodeStorage) getUserCodeSession(ctx context.Context, userCodeSignature string) (*UserCodeSession, error) {
+	userCodeSession := &UserCodeSession{}
+	_, err := d.userCodeStorage.Get(ctx, userCodeSignature, userCodeSession)
+
+	if apierrors.IsNotFound(err) {
+		return nil, fosite.ErrNotFound.WithWrap(err).WithDebug(err.Error())
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user code session for %s: %w", userCodeSignature, err)
+	}
+
+	if version := userCodeSession.Version; version != deviceCodeStorageVersion {
+		return nil, fmt.Errorf("%w: user code session for %s has version %s instead of %s",
+			ErrInvalidDeviceCodeRequestVersion, userCodeSignature, version, deviceCodeStorageVersion)
+	}
+
+	if userCodeSession.DeviceCodeSignature == "" {
+		return nil, fmt.Errorf("malformed user code session for %s: %w", userCodeSignature, ErrInvalidUserCodeData)
+	}
+
+	return userCodeSession, nil
+}
+
+func (d *deviceCodeStorage) getSession(ctx context.Context, deviceCodeSignature string) (*Session, string, error) {
+	session := NewValidEmptyDeviceCodeSession()
+	rv, err := d.storage.Get(ctx, deviceCodeSignature, session)
+
+	if apierrors.IsNotFound(err) {
+		return nil, "", fosite.ErrNotFound.WithWrap(err).WithDebug(err.Error())
+	}
+
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to get device code session for %s: %w", deviceCodeSignature, err)
+	}
+
+	if version := session.Version; version != deviceCodeStorageVersion {
+		return nil, "", fmt.Errorf("%w: device code session for %s has version %s instead of %s",
+			ErrInvalidDeviceCodeRequestVersion, deviceCodeSignature, version, deviceCodeStorageVersion)
+	}
+
+	if session.Request.ID == "" {
+		return nil, "", fmt.Errorf("malformed device code session for %s: %w", deviceCodeSignature, ErrInvalidDeviceCodeRequestData)
+	}
+
+	// we must return the session in this case to allow fosite to revoke the associated tokens
+	if !session.Active {
+		return session, rv, fmt.Errorf("device code session for %s has already been used: %w", deviceCodeSignature, fosite.ErrInvalidatedDeviceCode)
+	}
+
+	return session, rv, nil
+}
+
+func (s *Session) toDeviceRequest() *fosite.DeviceRequest {
+	return &fosite.DeviceRequest{
+		UserCodeState: s.UserCodeState,
+		Request:       *s.Request,
+	}
+}
+
+func NewValidEmptyDeviceCodeSession() *Session {
+	return &Session{
+		Request: &fosite.Request{
+			Client:  &clientregistry.Client{},
+			Session: &psession.PinnipedSession{},
+		},
+	}
+}
+
+var _ interface {
+	Is(error) bool
+	Unwrap() error
+	error
+} = &errSerializationFailureWithCause{}
+
+type errSerializationFailureWithCause struct {
+	cause error
+}
+
+func (e *errSerializationFailureWithCause) Is(err error) bool {
+	return stderrors.Is(err, fosite.ErrSerializationFailure)
+}
+
+func (e *errSerializationFailureWithCause) Unwrap() error {
+	return e.cause
+}
+
+func (e *errSerializationFailureWithCause) Error() string {
+	return fmt.Sprintf("%s: %s", fosite.ErrSerializationFailure, e.cause)
+}
@@ -0,0 +1,297 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package devicecode
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/ory/fosite"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+	kubetesting "k8s.io/client-go/testing"
+	clocktesting "k8s.io/utils/clock/testing"
+
+	"go.pinniped.dev/internal/federationdomain/clientregistry"
+	"go.pinniped.dev/internal/fositestorage"
+	"go.pinniped.dev/internal/psession"
+	"go.pinniped.dev/internal/testutil"
+)
+
+const (
+	namespace       = "test-ns"
+	expectedVersion = "5" // update this when you update the storage version in the production code
+)
+
+var (
+	fakeNow                             = time.Date(2030, time.January, 1, 0, 0, 0, 0, time.UTC)
+	lifetime                            = time.Minute * 10
+	userCodeLifetime                    = time.Minute * 5
+	fakeNowPlusLifetimeAsString         = metav1.Time{Time: fakeNow.Add(lifetime)}.Format(time.RFC3339)
+	fakeNowPlusUserCodeLifetimeAsString = metav1.Time{Time: fakeNow.Add(userCodeLifetime)}.Format(time.RFC3339)
+	lifetimeFunc                        = func(requester fosite.Requester) time.Duration { return lifetime }
+	userCodeLifetimeFunc                = func(requester fosite.Requester) time.Duration { return userCodeLifetime }
+)
+
+func newTestDeviceRequest(id string) *fosite.DeviceRequest {
+	return &fosite.DeviceRequest{
+		UserCodeState: fosite.UserCodeUnused,
+		Request: fosite.Request{
+			ID: id,
+			Client: &clientregistry.Client{
+				DefaultOpenIDConnectClient: fosite.DefaultOpenIDConnectClient{
+					DefaultClient: &fosite.DefaultClient{ID: "pinny", Public: true},
+				},
+			},
+			RequestedScope: fosite.Arguments{"openid", "username"},
+			Session:        psession.NewPinnipedSession(),
+		},
+	}
+}
+
+func TestDeviceCodeStorageLifecycle(t *testing.T) {
+	ctx, client, _, storage := makeTestSubject()
+
+	request := newTestDeviceRequest("abcd-1")
+	require.NoError(t, storage.CreateDeviceAuthSession(ctx, "fancy-device-signature", "fancy-user-signature", request))
+
+	// One secret for the device code and one secret for the user code.
+	secrets, err := client.CoreV1().Secrets(namespace).List(ctx, metav1.ListOptions{})
+	require.NoError(t, err)
+	require.Len(t, secrets.Items, 2)
+	secretsByType := map[corev1.SecretType]corev1.Secret{}
+	for _, secret := range secrets.Items {
+		secretsByType[secret.Type] = secret
+	}
+	deviceCodeSecret := secretsByType["storage.pinniped.dev/device-code"]
+	require.Equal(t, map[string]string{
+		"storage.pinniped.dev/type":       "device-code",
+		"storage.pinniped.dev/request-id": "abcd-1",
+	}, deviceCodeSecret.Labels)
+	require.Equal(t, fakeNowPlusLifetimeAsString, deviceCodeSecret.Annotations["storage.pinniped.dev/garbage-collect-after"])
+	userCodeSecret := secretsByType["storage.pinniped.dev/user-code"]
+	require.Equal(t, map[string]string{"storage.pinniped.dev/type": "user-code"}, userCodeSecret.Labels)
+	require.Equal(t, fakeNowPlusUserCodeLifetimeAsString, userCodeSecret.Annotations["storage.pinniped.dev/garbage-collect-after"])
+	require.JSONEq(t, `{"deviceCodeSignature":"fancy-device-signature","version":"`+expectedVersion+`"}`,
+		string(userCodeSecret.Data["pinniped-storage-data"]))
+
+	// The device code session can be found by its device code signature.
+	gotRequest, err := storage.GetDeviceCodeSession(ctx, "fancy-device-signature", nil)
+	require.NoError(t, err)
+	require.Equal(t, request, gotRequest)
+
+	// The device code session can be found by its user code signature.
+	gotRequest, err = storage.GetDeviceCodeSessionByUserCode(ctx, "fancy-user-signature")
+	require.NoError(t, err)
+	require.Equal(t, request, gotRequest)
+
+	// Approve it with a new session and granted scopes.
+	approvedSession := testutil.NewFakePinnipedSession()
+	request.SetSession(approvedSession)
+	request.GrantScope("openid")
+	require.NoError(t, storage.ApproveDeviceCodeSession(ctx, "fancy-user-signature", request))
+
+	// The user code was deleted, so it cannot be used again.
+	_, err = storage.GetDeviceCodeSessionByUserCode(ctx, "fancy-user-signature")
+	require.ErrorIs(t, err, fosite.ErrNotFound)
+	err = storage.ApproveDeviceCodeSession(ctx, "fancy-user-signature", request)
+	require.ErrorIs(t, err, fosite.ErrNotFound)
+
+	// The device code session now has the approved session and granted scopes.
+	gotRequest, err = storage.GetDeviceCodeSession(ctx, "fancy-device-signature", nil)
+	require.NoError(t, err)
+	require.Equal(t, fosite.UserCodeAccepted, gotRequest.GetUserCodeState())
+	require.Equal(t, fosite.Arguments{"openid"}, gotRequest.GetGrantedScopes())
+	require.Equal(t, approvedSession, gotRequest.GetSession())
+
+	// After invalidation, getting the session should still return the session, but also return an error.
+	require.NoError(t, storage.InvalidateDeviceCodeSession(ctx, "fancy-device-signature"))
+	invalidatedRequest, err := storage.GetDeviceCodeSession(ctx, "fancy-device-signature", nil)
+	require.ErrorIs(t, err, fosite.ErrInvalidatedDeviceCode)
+	require.EqualError(t, err, "device code session for fancy-device-signature has already been used: Device code has been invalidated")
+	require.Equal(t, "abcd-1", invalidatedRequest.GetID())
+
+	// Revoking by request ID deletes the device code session.
+	require.NoError(t, storage.RevokeDeviceCodeSession(ctx, "abcd-1"))
+	_, err = storage.GetDeviceCodeSession(ctx, "fancy-device-signature", nil)
+	require.ErrorIs(t, err, fosite.ErrNotFound)
+}
+
+func TestCreateWhenUserCodeAlreadyExists(t *testing.T) {
+	ctx, client, _, storage := makeTestSubject()
+
+	require.NoError(t, storage.CreateDeviceAuthSession(ctx, "device-signature-1", "same-user-signature", newTestDeviceRequest("abcd-1")))
+
+	// Fosite will retry with a new user code when it sees this error.
+	err := storage.CreateDeviceAuthSession(ctx, "device-signature-2", "same-user-signature", newTestDeviceRequest("abcd-2"))
+	require.ErrorIs(t, err, fosite.ErrExistingUserCodeSignature)
+
+	// The second device code session was not created.
+	secrets, err := client.CoreV1().Secrets(namespace).List(ctx, metav1.ListOptions{})
+	require.NoError(t, err)
+	require.Len(t, secrets.Items, 2)
+}
+
+func TestApproveWhenUserCodeWasAlreadyUsed(t *testing.T) {
+	ctx, _, _, storage := makeTestSubject()
+
+	request := newTestDeviceRequest("abcd-1")
+	request.UserCodeState = fosite.UserCodeAccepted
+	require.NoError(t, storage.CreateDeviceAuthSession(ctx, "fancy-device-signature", "fancy-user-signature", request))
+
+	err := storage.ApproveDeviceCodeSession(ctx, "fancy-user-signature", newTestDeviceRequest("abcd-1"))
+	require.ErrorIs(t, err, ErrUserCodeAlreadyUsed)
+}
+
+func TestApproveWithDifferentRequestID(t *testing.T) {
+	ctx, _, _, storage := makeTestSubject()
+
+	require.NoError(t, storage.CreateDeviceAuthSession(ctx, "fancy-device-signature", "fancy-user-signature", newTestDeviceRequest("abcd-1")))
+
+	err := storage.ApproveDeviceCodeSession(ctx, "fancy-user-signature", newTestDeviceRequest("some-other-id"))
+	require.ErrorIs(t, err, ErrUserCodeAlreadyUsed)
+}
+
+func TestReject(t *testing.T) {
+	ctx, _, _, storage := makeTestSubject()
+
+	require.NoError(t, storage.CreateDeviceAuthSession(ctx, "fancy-device-signature", "fancy-user-signature", newTestDeviceRequest("abcd-1")))
+
+	require.NoError(t, storage.RejectDeviceCodeSession(ctx, "fancy-user-signature"))
+
+	// The user code was deleted, so it cannot be used again.
+	_, err := storage.GetDeviceCodeSessionByUserCode(ctx, "fancy-user-signature")
+	require.ErrorIs(t, err, fosite.ErrNotFound)
+	err = storage.RejectDeviceCodeSession(ctx, "fancy-user-signature")
+	require.ErrorIs(t, err, fosite.ErrNotFound)
+
+	// The device code session is still there, so the device can learn that it was rejected.
+	gotRequest, err := storage.GetDeviceCodeSession(ctx, "fancy-device-signature", nil)
+	require.NoError(t, err)
+	require.Equal(t, fosite.UserCodeRejected, gotRequest.GetUserCodeState())
+}
+
+func TestRejectWhenUserCodeWasAlreadyUsed(t *testing.T) {
+	ctx, _, _, storage := makeTestSubject()
+
+	request := newTestDeviceRequest("abcd-1")
+	request.UserCodeState = fosite.UserCodeAccepted
+	require.NoError(t, storage.CreateDeviceAuthSession(ctx, "fancy-device-signature", "fancy-user-signature", request))
+
+	err := storage.RejectDeviceCodeSession(ctx, "fancy-user-signature")
+	require.ErrorIs(t, err, ErrUserCodeAlreadyUsed)
+}
+
+func TestGetNotFound(t *testing.T) {
+	ctx, _, _, storage := makeTestSubject()
+
+	_, notFoundErr := storage.GetDeviceCodeSession(ctx, "non-existent-signature", nil)
+	require.EqualError(t, notFoundErr, "not_found")
+	require.True(t, errors.Is(notFoundErr, fosite.ErrNotFound))
+
+	_, notFoundErr = storage.GetDeviceCodeSessionByUserCode(ctx, "non-existent-signature")
+	require.EqualError(t, notFoundErr, "not_found")
+	require.True(t, errors.Is(notFoundErr, fosite.ErrNotFound))
+}
+
+func TestInvalidateWhenNotFound(t *testing.T) {
+	ctx, _, _, storage := makeTestSubject()
+
+	notFoundErr := storage.InvalidateDeviceCodeSession(ctx, "non-existent-signature")
+	require.EqualError(t, notFoundErr, "not_found")
+	require.True(t, errors.Is(notFoundErr, fosite.ErrNotFound))
+}
+
+func TestInvalidateWhenConflictOnUpdateHappens(t *testing.T) {
+	ctx, client, _, storage := makeTestSubject()
+
+	client.PrependReactor("update", "secrets", func(_ kubetesting.Action) (bool, runtime.Object, error) {
+		return true, nil, apierrors.NewConflict(schema.GroupResource{
+			Group:    "",
+			Resource: "secrets",
+		}, "some-secret-name", fmt.Errorf("there was a conflict"))
+	})
+
+	require.NoError(t, storage.CreateDeviceAuthSession(ctx, "fancy-device-signature", "fancy-user-signature", newTestDeviceRequest("abcd-1")))
+	err := storage.InvalidateDeviceCodeSession(ctx, "fancy-device-signature")
+	require.EqualError(t, err, `error: failed to update device-code for signature fancy-device-signature at resource version : Operation cannot be fulfilled on secrets "some-secret-name": there was a conflict`)
+}
+
+func TestWrongVersion(t *testing.T) {
+	ctx, _, secrets, storage := makeTestSubject()
+
+	require.NoError(t, storage.CreateDeviceAuthSession(ctx, "fancy-device-signature", "fancy-user-signature", newTestDeviceRequest("abcd-1")))
+
+	secretList, err := secrets.List(ctx, metav1.ListOptions{})
+	require.NoError(t, err)
+	for _, secret := range secretList.Items {
+		secret.Data["pinniped-storage-data"] = []byte(`{"request":{"id":"abcd-1"},"deviceCodeSignature":"fancy-device-signature","version":"not-the-right-version","active": true}`)
+		_, err = secrets.Update(ctx, &secret, metav1.UpdateOptions{})
+		require.NoError(t, err)
+	}
+
+	_, err = storage.GetDeviceCodeSession(ctx, "fancy-device-signature", nil)
+	require.EqualError(t, err, "device code request data has wrong version: device code session for fancy-device-signature has version not-the-right-version instead of "+expectedVersion)
+
+	_, err = storage.GetDeviceCodeSessionByUserCode(ctx, "fancy-user-signature")
+	require.EqualError(t, err, "device code request data has wrong version: user code session for fancy-user-signature has version not-the-right-version instead of "+expectedVersion)
+}
+
+func TestCreateWithWrongRequesterDataTypes(t *testing.T) {
+	ctx, _, _, storage := makeTestSubject()
+
+	err := storage.CreateDeviceAuthSession(ctx, "signature-doesnt-matter", "signature-doesnt-matter", nil)
+	require.EqualError(t, err, "requester must be of type fosite.Request")
+
+	request := newTestDeviceRequest("abcd-1")
+	request.Session = nil
+	err = storage.CreateDeviceAuthSession(ctx, "signature-doesnt-matter", "signature-doesnt-matter", request)
+	require.EqualError(t, err, "requester's session must be of type PinnipedSession")
+
+	request = newTestDeviceRequest("abcd-1")
+	request.Client = nil
+	err = storage.CreateDeviceAuthSession(ctx, "signature-doesnt-matter", "signature-doesnt-matter", request)
+	require.EqualError(t, err, "requester's client must be of type clientregistry.Client")
+}
+
+func TestReadFromSecret(t *testing.T) {
+	ctx, _, secrets, storage := makeTestSubject()
+
+	require.NoError(t, storage.CreateDeviceAuthSession(ctx, "fancy-device-signature", "fancy-user-signature", newTestDeviceRequest("abcd-1")))
+
+	secretList, err := secrets.List(ctx, metav1.ListOptions{LabelSelector: "storage.pinniped.dev/type=device-code"})
+	require.NoError(t, err)
+	require.Len(t, secretList.Items, 1)
+
+	session, err := ReadFromSecret(&secretList.Items[0])
+	require.NoError(t, err)
+	require.Equal(t, "abcd-1", session.Request.GetID())
+	require.True(t, session.Active)
+	require.Equal(t, fosite.UserCodeUnused, session.UserCodeState)
+	_, err = fositestorage.ValidateAndExtractAuthorizeRequest(session.Request)
+	require.NoError(t, err)
+
+	wrongTypeSecret := secretList.Items[0].DeepCopy()
+	wrongTypeSecret.Type = "storage.pinniped.dev/not-device-code"
+	_, err = ReadFromSecret(wrongTypeSecret)
+	require.EqualError(t, err, "secret storage data has incorrect type: storage.pinniped.dev/not-device-code must equal storage.pinniped.dev/device-code")
+}
+
+func makeTestSubject() (context.Context, *kubefake.Clientset, corev1client.SecretInterface, RevocationStorage) {
+	client := kubefake.NewClientset()
+	secrets := client.CoreV1().Secrets(namespace)
+	return context.Background(),
+		client,
+		secrets,
+		New(secrets, clocktesting.NewFakeClock(fakeNow).Now, lifetimeFunc, userCodeLifetimeFunc)
+}
@@ -0,0 +1,130 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package pushedauthorizationrequest stores the authorization requests which were pushed by clients to the
+// pushed authorization request endpoint, as described in https://datatracker.ietf.org/doc/html/rfc9126,
+// until they are used at the authorization endpoint.
+package pushedauthorizationrequest
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"time"
+
+	"github.com/ory/fosite"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+
+	"go.pinniped.dev/internal/constable"
+	"go.pinniped.dev/internal/crud"
+	"go.pinniped.dev/internal/federationdomain/clientregistry"
+	"go.pinniped.dev/internal/federationdomain/timeouts"
+	"go.pinniped.dev/internal/fositestorage"
+	"go.pinniped.dev/internal/psession"
+)
+
+const (
+	TypeLabelValue = "pushed-authorization-request"
+
+	ErrInvalidPushedAuthorizationRequestType    = constable.Error("requester must be of type fosite.AuthorizeRequest")
+	ErrInvalidPushedAuthorizationRequestVersion = constable.Error("pushed authorization request data has wrong version")
+	ErrInvalidPushedAuthorizationRequestData    = constable.Error("pushed authorization request data must be present")
+
+	// Version 1 was the initial release of storage.
+	pushedAuthorizationRequestStorageVersion = "1"
+)
+
+var _ fosite.PARStorage = &pushedAuthorizationRequestStorage{}
+
+type pushedAuthorizationRequestStorage struct {
+	storage  crud.Storage
+	clock    func() time.Time
+	lifetime timeouts.StorageLifetime
+}
+
+type session struct {
+	Request *fosite.AuthorizeRequest `json:"request"`
+	Version string                   `json:"version"`
+}
+
+func New(secrets crud.SecretsBackend, clock func() time.Time, sessionStorageLifetime timeouts.StorageLifetime) fosite.PARStorage {
+	return &pushedAuthorizationRequestStorage{
+		storage:  crud.New(TypeLabelValue, secrets, clock),
+		clock:    clock,
+		lifetime: sessionStorageLifetime,
+	}
+}
+
+func (p *pushedAuthorizationRequestStorage) CreatePARSession(ctx context.Context, requestURI string, requester fosite.AuthorizeRequester) error {
+	request, ok := requester.(*fosite.AuthorizeRequest)
+	if !ok {
+		return ErrInvalidPushedAuthorizationRequestType
+	}
+	if _, err := fositestorage.ValidateAndExtractAuthorizeRequest(&request.Request); err != nil {
+		return err
+	}
+
+	_, err := p.storage.Create(ctx,
+		signature(requestURI),
+		&session{Request: request, Version: pushedAuthorizationRequestStorageVersion},
+		map[string]string{fositestorage.StorageRequestIDLabelName: requester.GetID()},
+		nil,
+		p.lifetime(requester),
+	)
+	return err
+}
+
+// GetPARSession returns the pushed authorization request for the request_uri. It returns a fosite.ErrNotFound error
+// when the request_uri does not exist, including when it was already used or has expired.
+func (p *pushedAuthorizationRequestStorage) GetPARSession(ctx context.Context, requestURI string) (fosite.AuthorizeRequester, error) {
+	session := newValidEmptyPushedAuthorizationRequestSession()
+	_, err := p.storage.Get(ctx, signature(requestURI), session)
+
+	if apierrors.IsNotFound(err) {
+		return nil, fosite.ErrNotFound.WithWrap(err).WithDebug(err.Error())
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pushed authorization request: %w", err)
+	}
+
+	if version := session.Version; version != pushedAuthorizationRequestStorageVersion {
+		return nil, fmt.Errorf("%w: pushed authorization request has version %s instead of %s",
+			ErrInvalidPushedAuthorizationRequestVersion, version, pushedAuthorizationRequestStorageVersion)
+	}
+
+	if session.Request.ID == "" {
+		return nil, fmt.Errorf("malformed pushed authorization request: %w", ErrInvalidPushedAuthorizationRequestData)
+	}
+
+	// Fosite does not check the expiration of pushed authorization requests, and the storage of expired
+	// requests may not have been garbage collected yet.
+	if !session.Request.Session.GetExpiresAt(fosite.PushedAuthorizeRequestContext).After(p.clock()) {
+		return nil, fosite.ErrNotFound.WithDebug("pushed authorization request has expired")
+	}
+
+	return session.Request, nil
+}
+
+func (p *pushedAuthorizationRequestStorage) DeletePARSession(ctx context.Context, requestURI string) error {
+	return p.storage.Delete(ctx, signature(requestURI))
+}
+
+// signature returns a fixed length signature of the request_uri, because the request_uri
+// is too long to fit into the name of a Secret.
+func signature(requestURI string) string {
+	hash := sha256.Sum256([]byte(requestURI))
+	return base64.RawURLEncoding.EncodeToString(hash[:])
+}
+
+func newValidEmptyPushedAuthorizationRequestSession() *session {
+	return &session{
+		Request: &fosite.AuthorizeRequest{
+			Request: fosite.Request{
+				Client:  &clientregistry.Client{},
+				Session: &psession.PinnipedSession{},
+			},
+		},
+	}
+}
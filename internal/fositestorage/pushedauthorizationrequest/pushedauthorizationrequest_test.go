@@ -0,0 +1,244 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package pushedauthorizationrequest
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/ory/fosite"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+	coretesting "k8s.io/client-go/testing"
+	clocktesting "k8s.io/utils/clock/testing"
+
+	"go.pinniped.dev/internal/federationdomain/clientregistry"
+	"go.pinniped.dev/internal/federationdomain/timeouts"
+	"go.pinniped.dev/internal/psession"
+	"go.pinniped.dev/internal/testutil"
+)
+
+const (
+	namespace       = "test-ns"
+	expectedVersion = "1" // update this when you update the storage version in the production code
+
+	requestURI         = "urn:ietf:params:oauth:request_uri:fake-request-uri"
+	expectedSecretName = "pinniped-storage-pushed-authorization-request-6uvnryvddtnrvckw5im4slxfuzer4aqd6iibi4w5nnv6lxjjg35a"
+)
+
+var (
+	fakeNow                     = time.Date(2030, time.January, 1, 0, 0, 0, 0, time.UTC)
+	lifetime                    = time.Minute * 5
+	fakeNowPlusLifetimeAsString = metav1.Time{Time: fakeNow.Add(lifetime)}.Format(time.RFC3339)
+	lifetimeFunc                = func(requester fosite.Requester) time.Duration { return lifetime }
+	secretsGVR                  = schema.GroupVersionResource{
+		Group:    "",
+		Version:  "v1",
+		Resource: "secrets",
+	}
+)
+
+func TestPushedAuthorizationRequestStorage(t *testing.T) {
+	wantActions := []coretesting.Action{
+		coretesting.NewCreateAction(secretsGVR, namespace, &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            expectedSecretName,
+				ResourceVersion: "",
+				Labels: map[string]string{
+					"storage.pinniped.dev/type":       "pushed-authorization-request",
+					"storage.pinniped.dev/request-id": "abcd-1",
+				},
+				Annotations: map[string]string{
+					"storage.pinniped.dev/garbage-collect-after": fakeNowPlusLifetimeAsString,
+				},
+			},
+			Data: map[string][]byte{
+				"pinniped-storage-data":    []byte(`{"request":{"responseTypes":["code"],"redirectUri":{"Scheme":"https","Opaque":"","User":null,"Host":"client.example.com","Path":"/callback","Fragment":"","RawQuery":"","RawPath":"","RawFragment":"","ForceQuery":false,"OmitHost":false},"state":"some-state-value-with-enough-bytes","handledResponseTypes":null,"ResponseModes":"","DefaultResponseMode":"","id":"abcd-1","requestedAt":"0001-01-01T00:00:00Z","client":{"id":"client.oauth.pinniped.dev-test","redirect_uris":["https://client.example.com/callback"],"grant_types":["authorization_code"],"response_types":["code"],"scopes":["openid"],"audience":null,"public":false,"jwks_uri":"","jwks":null,"token_endpoint_auth_method":"client_secret_basic","request_uris":null,"request_object_signing_alg":"","token_endpoint_auth_signing_alg":"","IDTokenLifetimeConfiguration":0},"scopes":["openid"],"grantedScopes":null,"form":{"scope":["openid"],"state":["some-state-value-with-enough-bytes"]},"session":{"fosite":{"id_token_claims":null,"headers":null,"expires_at":{"par_context":"2030-01-01T00:01:00Z"},"username":"snorlax","subject":"panda"},"custom":{"username":"fake-username","upstreamUsername":"fake-upstream-username","upstreamGroups":["fake-upstream-group1","fake-upstream-group2"],"providerUID":"fake-provider-uid","providerName":"fake-provider-name","providerType":"fake-provider-type","warnings":null,"oidc":{"upstreamRefreshToken":"fake-upstream-refresh-token","upstreamAccessToken":"","upstreamSubject":"some-subject","upstreamIssuer":"some-issuer"}}},"requestedAudience":null,"grantedAudience":null},"version":"` + expectedVersion + `"}`),
+				"pinniped-storage-version": []byte("1"),
+			},
+			Type: "storage.pinniped.dev/pushed-authorization-request",
+		}),
+		coretesting.NewGetAction(secretsGVR, namespace, expectedSecretName),
+		coretesting.NewDeleteAction(secretsGVR, namespace, expectedSecretName),
+	}
+
+	storageLifetimeFuncCallCount := 0
+	var storageLifetimeFuncCallRequesterArg fosite.Requester
+	ctx, client, _, storage := makeTestSubject(func(requester fosite.Requester) time.Duration {
+		storageLifetimeFuncCallCount++
+		storageLifetimeFuncCallRequesterArg = requester
+		return lifetime
+	})
+
+	request := newValidRequest()
+	err := storage.CreatePARSession(ctx, requestURI, request)
+	require.NoError(t, err)
+	require.Equal(t, 1, storageLifetimeFuncCallCount)
+	require.Equal(t, request, storageLifetimeFuncCallRequesterArg)
+
+	newRequest, err := storage.GetPARSession(ctx, requestURI)
+	require.NoError(t, err)
+	require.Equal(t, request, newRequest)
+
+	err = storage.DeletePARSession(ctx, requestURI)
+	require.NoError(t, err)
+
+	testutil.LogActualJSONFromCreateAction(t, client, 0) // makes it easier to update expected values when needed
+	require.Equal(t, wantActions, client.Actions())
+	// Check that there were no more calls to the lifetime func since the original create.
+	require.Equal(t, 1, storageLifetimeFuncCallCount)
+}
+
+func TestGetNotFound(t *testing.T) {
+	ctx, _, _, storage := makeTestSubject(lifetimeFunc)
+
+	_, notFoundErr := storage.GetPARSession(ctx, "non-existent-request-uri")
+	require.EqualError(t, notFoundErr, "not_found")
+	require.True(t, errors.Is(notFoundErr, fosite.ErrNotFound))
+}
+
+func TestGetExpired(t *testing.T) {
+	ctx, _, _, storage := makeTestSubject(lifetimeFunc)
+
+	request := newValidRequest()
+	request.Session.SetExpiresAt(fosite.PushedAuthorizeRequestContext, fakeNow)
+	err := storage.CreatePARSession(ctx, requestURI, request)
+	require.NoError(t, err)
+
+	_, expiredErr := storage.GetPARSession(ctx, requestURI)
+	require.EqualError(t, expiredErr, "not_found")
+	require.True(t, errors.Is(expiredErr, fosite.ErrNotFound))
+}
+
+func TestWrongVersion(t *testing.T) {
+	ctx, _, secrets, storage := makeTestSubject(lifetimeFunc)
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            expectedSecretName,
+			ResourceVersion: "",
+			Labels: map[string]string{
+				"storage.pinniped.dev/type": "pushed-authorization-request",
+			},
+			Annotations: map[string]string{
+				"storage.pinniped.dev/garbage-collect-after": fakeNowPlusLifetimeAsString,
+			},
+		},
+		Data: map[string][]byte{
+			"pinniped-storage-data":    []byte(`{"request":{"id":"abcd-1"},"version":"not-the-right-version"}`),
+			"pinniped-storage-version": []byte("1"),
+		},
+		Type: "storage.pinniped.dev/pushed-authorization-request",
+	}
+	_, err := secrets.Create(ctx, secret, metav1.CreateOptions{})
+	require.NoError(t, err)
+
+	_, err = storage.GetPARSession(ctx, requestURI)
+
+	require.EqualError(t, err, "pushed authorization request data has wrong version: pushed authorization request has version not-the-right-version instead of "+expectedVersion)
+}
+
+func TestNilSessionRequest(t *testing.T) {
+	ctx, _, secrets, storage := makeTestSubject(lifetimeFunc)
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            expectedSecretName,
+			ResourceVersion: "",
+			Labels: map[string]string{
+				"storage.pinniped.dev/type": "pushed-authorization-request",
+			},
+			Annotations: map[string]string{
+				"storage.pinniped.dev/garbage-collect-after": fakeNowPlusLifetimeAsString,
+			},
+		},
+		Data: map[string][]byte{
+			"pinniped-storage-data":    []byte(`{"nonsense-key": "nonsense-value","version":"` + expectedVersion + `"}`),
+			"pinniped-storage-version": []byte("1"),
+		},
+		Type: "storage.pinniped.dev/pushed-authorization-request",
+	}
+
+	_, err := secrets.Create(ctx, secret, metav1.CreateOptions{})
+	require.NoError(t, err)
+
+	_, err = storage.GetPARSession(ctx, requestURI)
+	require.EqualError(t, err, "malformed pushed authorization request: pushed authorization request data must be present")
+}
+
+func TestCreateWithNilRequester(t *testing.T) {
+	ctx, _, _, storage := makeTestSubject(lifetimeFunc)
+
+	err := storage.CreatePARSession(ctx, requestURI, nil)
+	require.EqualError(t, err, "requester must be of type fosite.AuthorizeRequest")
+}
+
+func TestCreateWithWrongRequesterDataTypes(t *testing.T) {
+	ctx, _, _, storage := makeTestSubject(lifetimeFunc)
+
+	request := &fosite.AuthorizeRequest{
+		Request: fosite.Request{
+			Session: nil,
+			Client:  &clientregistry.Client{},
+		},
+	}
+	err := storage.CreatePARSession(ctx, requestURI, request)
+	require.EqualError(t, err, "requester's session must be of type PinnipedSession")
+
+	request = &fosite.AuthorizeRequest{
+		Request: fosite.Request{
+			Session: &psession.PinnipedSession{},
+			Client:  nil,
+		},
+	}
+	err = storage.CreatePARSession(ctx, requestURI, request)
+	require.EqualError(t, err, "requester's client must be of type clientregistry.Client")
+}
+
+func newValidRequest() *fosite.AuthorizeRequest {
+	session := testutil.NewFakePinnipedSession()
+	session.SetExpiresAt(fosite.PushedAuthorizeRequestContext, fakeNow.Add(time.Minute))
+
+	return &fosite.AuthorizeRequest{
+		ResponseTypes: fosite.Arguments{"code"},
+		RedirectURI:   &url.URL{Scheme: "https", Host: "client.example.com", Path: "/callback"},
+		State:         "some-state-value-with-enough-bytes",
+		ResponseMode:  fosite.ResponseModeDefault,
+		Request: fosite.Request{
+			ID:          "abcd-1",
+			RequestedAt: time.Time{},
+			Client: &clientregistry.Client{
+				DefaultOpenIDConnectClient: fosite.DefaultOpenIDConnectClient{
+					DefaultClient: &fosite.DefaultClient{
+						ID:            "client.oauth.pinniped.dev-test",
+						RedirectURIs:  []string{"https://client.example.com/callback"},
+						GrantTypes:    []string{"authorization_code"},
+						ResponseTypes: []string{"code"},
+						Scopes:        []string{"openid"},
+					},
+					TokenEndpointAuthMethod: "client_secret_basic",
+				},
+			},
+			RequestedScope: fosite.Arguments{"openid"},
+			Form:           url.Values{"scope": []string{"openid"}, "state": []string{"some-state-value-with-enough-bytes"}},
+			Session:        session,
+		},
+	}
+}
+
+func makeTestSubject(lifetimeFunc timeouts.StorageLifetime) (context.Context, *kubefake.Clientset, corev1client.SecretInterface, fosite.PARStorage) {
+	client := kubefake.NewClientset()
+	secrets := client.CoreV1().Secrets(namespace)
+	return context.Background(),
+		client,
+		secrets,
+		New(secrets, clocktesting.NewFakeClock(fakeNow).Now, lifetimeFunc)
+}
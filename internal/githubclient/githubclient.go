@@ -16,6 +16,7 @@ import (
 	"github.com/google/go-github/v90/github"
 	"k8s.io/apimachinery/pkg/util/sets"
 
+	"go.pinniped.dev/internal/metrics"
 	"go.pinniped.dev/internal/plog"
 	"go.pinniped.dev/internal/setutil"
 )
@@ -89,7 +90,7 @@ func NewGitHubClient(httpClient *http.Client, apiBaseURL, token string) (GitHubI
 	// Teams.ListUserTeams), all of which use the base URL. The upload URL is never
 	// exercised today. It should be updated if an upload-style call is ever used.
 	client, err := github.NewClient(
-		github.WithHTTPClient(httpClient),
+		github.WithHTTPClient(withMetrics(httpClient)),
 		github.WithAuthToken(token),
 		github.WithEnterpriseURLs(parsedURL.String(), parsedURL.String()),
 	)
@@ -104,6 +105,35 @@ func NewGitHubClient(httpClient *http.Client, apiBaseURL, token string) (GitHubI
 	}, nil
 }
 
+// withMetrics returns a shallow copy of the httpClient which records metrics for every GitHub API call.
+func withMetrics(httpClient *http.Client) *http.Client {
+	instrumented := *httpClient
+	transport := instrumented.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	instrumented.Transport = metricsRoundTripper{delegate: transport}
+	return &instrumented
+}
+
+type metricsRoundTripper struct {
+	delegate http.RoundTripper
+}
+
+func (rt metricsRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	resp, err := rt.delegate.RoundTrip(req)
+	result := metrics.ResultSuccess
+	switch {
+	case err != nil, resp.StatusCode >= http.StatusInternalServerError:
+		result = metrics.ResultError
+	case resp.StatusCode >= http.StatusBadRequest:
+		result = metrics.ResultFailure
+	}
+	metrics.ObserveUpstreamCallResult(metrics.UpstreamTypeGitHub, metrics.UpstreamOperationGitHubAPI, result, start)
+	return resp, err
+}
+
 // isUnauthorized returns true only when err is a *github.ErrorResponse for an HTTP 401. GitHub's
 // rate-limiting conditions are surfaced by go-github as the distinct *github.RateLimitError and
 // *github.AbuseRateLimitError types (HTTP 403/429), so this check can never match those and cannot
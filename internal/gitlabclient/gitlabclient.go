@@ -0,0 +1,272 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package gitlabclient is a small client for the parts of the GitLab REST API which are used to determine
+// the identity and group memberships of a GitLab user.
+package gitlabclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"go.pinniped.dev/internal/metrics"
+	"go.pinniped.dev/internal/plog"
+	"go.pinniped.dev/internal/setutil"
+)
+
+const (
+	pageSize = 100
+
+	// minAccessLevelGuest is the lowest access level of a GitLab group member. Asking GitLab to list only the groups
+	// where the user has at least this access level lists all groups where the user is a member, including the
+	// subgroups where their membership is inherited from a parent group.
+	// See https://docs.gitlab.com/ee/api/members.html#roles.
+	minAccessLevelGuest = "10"
+
+	// maxErrorBodyLength limits how much of an unsuccessful response body will be included in an error message.
+	maxErrorBodyLength = 256
+)
+
+type UserInfo struct {
+	ID       string
+	Username string
+}
+
+type GroupInfo struct {
+	ID       string
+	Path     string
+	FullPath string
+}
+
+// TopLevelGroupPath returns the path of the top-level group which contains this group,
+// or the path of this group when this group is itself a top-level group.
+func (g GroupInfo) TopLevelGroupPath() string {
+	topLevelPath, _, _ := strings.Cut(g.FullPath, "/")
+	return topLevelPath
+}
+
+type GitLabInterface interface {
+	GetUserInfo(ctx context.Context) (*UserInfo, error)
+	GetGroupMembership(ctx context.Context, allowedTopLevelGroups *setutil.CaseInsensitiveSet) ([]GroupInfo, error)
+}
+
+type gitlabClient struct {
+	httpClient *http.Client
+	apiBaseURL *url.URL
+	token      string
+}
+
+var _ GitLabInterface = (*gitlabClient)(nil)
+
+// NewGitLabClient returns a client for the GitLab API. The apiBaseURL should look like https://HOSTNAME/api/v4/.
+func NewGitLabClient(httpClient *http.Client, apiBaseURL, token string) (GitLabInterface, error) {
+	const errorPrefix = "unable to build new gitlab client"
+
+	if httpClient == nil {
+		return nil, fmt.Errorf("%s: httpClient cannot be nil", errorPrefix)
+	}
+
+	parsedURL, err := url.Parse(apiBaseURL)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", errorPrefix, err)
+	}
+
+	if !strings.HasSuffix(parsedURL.Path, "/") {
+		parsedURL.Path += "/"
+	}
+
+	if parsedURL.Scheme != "https" {
+		return nil, fmt.Errorf(`%s: apiBaseURL must use "https" protocol, found %q instead`, errorPrefix, parsedURL.Scheme)
+	}
+
+	if token == "" {
+		return nil, fmt.Errorf("%s: token cannot be empty string", errorPrefix)
+	}
+
+	return &gitlabClient{
+		httpClient: withMetrics(httpClient),
+		apiBaseURL: parsedURL,
+		token:      token,
+	}, nil
+}
+
+// withMetrics returns a shallow copy of the httpClient which records metrics for every GitLab API call.
+func withMetrics(httpClient *http.Client) *http.Client {
+	instrumented := *httpClient
+	transport := instrumented.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	instrumented.Transport = metricsRoundTripper{delegate: transport}
+	return &instrumented
+}
+
+type metricsRoundTripper struct {
+	delegate http.RoundTripper
+}
+
+func (rt metricsRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	resp, err := rt.delegate.RoundTrip(req)
+	result := metrics.ResultSuccess
+	switch {
+	case err != nil, resp.StatusCode >= http.StatusInternalServerError:
+		result = metrics.ResultError
+	case resp.StatusCode >= http.StatusBadRequest:
+		result = metrics.ResultFailure
+	}
+	metrics.ObserveUpstreamCallResult(metrics.UpstreamTypeGitLab, metrics.UpstreamOperationGitLabAPI, result, start)
+	return resp, err
+}
+
+// get performs a GET request for the given path relative to the API base URL, decodes the JSON response body
+// into result, and returns the response headers.
+func (g *gitlabClient) get(ctx context.Context, path string, query url.Values, result any) (http.Header, error) {
+	requestURL := g.apiBaseURL.JoinPath(path)
+	requestURL.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, requestURL.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+g.token)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := g.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodyLength))
+		return nil, fmt.Errorf("GET %s: unexpected response status %q: %s", requestURL.String(), resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+		return nil, fmt.Errorf("GET %s: could not decode response: %w", requestURL.String(), err)
+	}
+
+	return resp.Header, nil
+}
+
+type gitlabUser struct {
+	ID       int64  `json:"id"`
+	Username string `json:"username"`
+}
+
+type gitlabGroup struct {
+	ID       int64  `json:"id"`
+	Path     string `json:"path"`
+	FullPath string `json:"full_path"`
+}
+
+// GetUserInfo returns the "username" and "id" attributes of the logged-in user.
+func (g *gitlabClient) GetUserInfo(ctx context.Context) (*UserInfo, error) {
+	const errorPrefix = "error fetching authenticated user"
+
+	var user gitlabUser
+	if _, err := g.get(ctx, "user", nil, &user); err != nil {
+		return nil, fmt.Errorf("%s: %w", errorPrefix, err)
+	}
+	plog.Trace("got raw GitLab API user results", "user", user)
+
+	if user.ID == 0 {
+		return nil, fmt.Errorf(`%s: the "id" attribute is missing`, errorPrefix)
+	}
+	if user.Username == "" {
+		return nil, fmt.Errorf(`%s: the "username" attribute is missing`, errorPrefix)
+	}
+
+	userInfo := &UserInfo{
+		ID:       strconv.FormatInt(user.ID, 10),
+		Username: user.Username,
+	}
+
+	plog.Trace("calculated response from GitLab user endpoint", "user", userInfo)
+	return userInfo, nil
+}
+
+func isGroupAllowed(allowedTopLevelGroups *setutil.CaseInsensitiveSet, group GroupInfo) bool {
+	return allowedTopLevelGroups.Empty() || allowedTopLevelGroups.ContainsIgnoringCase(group.TopLevelGroupPath())
+}
+
+func buildAndValidateGroup(group gitlabGroup) (*GroupInfo, error) {
+	groupInfo := &GroupInfo{
+		ID:       strconv.FormatInt(group.ID, 10),
+		Path:     group.Path,
+		FullPath: group.FullPath,
+	}
+	if group.ID == 0 {
+		return nil, errors.New(`the "id" attribute is missing for a group`)
+	}
+	if groupInfo.Path == "" {
+		return nil, errors.New(`the "path" attribute is missing for a group`)
+	}
+	if groupInfo.FullPath == "" {
+		return nil, errors.New(`the "full_path" attribute is missing for a group`)
+	}
+	return groupInfo, nil
+}
+
+// GetGroupMembership returns a description of each group to which the authenticated user belongs, including the
+// subgroups to which the user belongs due to their membership in a parent group.
+// If allowedTopLevelGroups is not empty, will filter the results to only those groups which are one of the allowed
+// top-level groups or which are subgroups of the allowed top-level groups.
+func (g *gitlabClient) GetGroupMembership(ctx context.Context, allowedTopLevelGroups *setutil.CaseInsensitiveSet) ([]GroupInfo, error) {
+	const errorPrefix = "error fetching group membership for authenticated user"
+	groupInfos := sets.New[GroupInfo]()
+
+	query := url.Values{
+		"min_access_level": []string{minAccessLevelGuest},
+		"per_page":         []string{strconv.Itoa(pageSize)},
+		"page":             []string{"1"},
+	}
+	// get all pages of results
+	for {
+		var groupResults []gitlabGroup
+		headers, err := g.get(ctx, "groups", query, &groupResults)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", errorPrefix, err)
+		}
+		nextPage := headers.Get("X-Next-Page")
+		plog.Trace("got raw GitLab API group results", "groups", groupResults, "nextPage", nextPage)
+
+		for _, group := range groupResults {
+			groupInfo, err := buildAndValidateGroup(group)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", errorPrefix, err)
+			}
+
+			if !isGroupAllowed(allowedTopLevelGroups, *groupInfo) {
+				continue
+			}
+
+			groupInfos.Insert(*groupInfo)
+		}
+		if nextPage == "" {
+			break
+		}
+		query.Set("page", nextPage)
+	}
+
+	// Sort by full path, just so we always return groups in the same order.
+	sortedGroups := groupInfos.UnsortedList()
+	slices.SortStableFunc(sortedGroups, func(a, b GroupInfo) int {
+		return strings.Compare(a.FullPath, b.FullPath)
+	})
+
+	plog.Trace("calculated response from GitLab groups endpoint", "groups", sortedGroups)
+	return sortedGroups, nil
+}
@@ -0,0 +1,300 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package gitlabclient
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"k8s.io/client-go/util/cert"
+
+	"go.pinniped.dev/internal/net/phttp"
+	"go.pinniped.dev/internal/setutil"
+	"go.pinniped.dev/internal/testutil/tlsserver"
+)
+
+func TestNewGitLabClient(t *testing.T) {
+	t.Parallel()
+
+	t.Run("rejects nil http client", func(t *testing.T) {
+		_, err := NewGitLabClient(nil, "https://gitlab.com/api/v4/", "")
+		require.EqualError(t, err, "unable to build new gitlab client: httpClient cannot be nil")
+	})
+
+	tests := []struct {
+		name        string
+		apiBaseURL  string
+		token       string
+		wantBaseURL string
+		wantErr     string
+	}{
+		{
+			name:        "happy path with https://gitlab.com/api/v4/",
+			apiBaseURL:  "https://gitlab.com/api/v4/",
+			token:       "some-token",
+			wantBaseURL: "https://gitlab.com/api/v4/",
+		},
+		{
+			name:        "adds trailing slash to path",
+			apiBaseURL:  "https://gitlab.example.com:8443/api/v4",
+			token:       "other-token",
+			wantBaseURL: "https://gitlab.example.com:8443/api/v4/",
+		},
+		{
+			name:       "rejects apiBaseURL without https:// scheme",
+			apiBaseURL: "http://gitlab.com/api/v4/",
+			token:      "some-token",
+			wantErr:    `unable to build new gitlab client: apiBaseURL must use "https" protocol, found "http" instead`,
+		},
+		{
+			name:       "rejects empty token",
+			apiBaseURL: "https://gitlab.com/api/v4/",
+			wantErr:    "unable to build new gitlab client: token cannot be empty string",
+		},
+		{
+			name:       "returns errors from url.Parse",
+			apiBaseURL: "https:// example.com",
+			token:      "some-token",
+			wantErr:    `unable to build new gitlab client: parse "https:// example.com": invalid character " " in host name`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			actualI, err := NewGitLabClient(&http.Client{}, test.apiBaseURL, test.token)
+
+			if test.wantErr != "" {
+				require.EqualError(t, err, test.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			actual, ok := actualI.(*gitlabClient)
+			require.True(t, ok)
+			require.Equal(t, test.wantBaseURL, actual.apiBaseURL.String())
+			require.Equal(t, test.token, actual.token)
+		})
+	}
+}
+
+func newTestClient(t *testing.T, handler http.HandlerFunc) GitLabInterface {
+	t.Helper()
+
+	testServer, testServerCA := tlsserver.TestServerIPv4(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodGet, r.Method)
+		require.Equal(t, "Bearer some-token", r.Header.Get("Authorization"))
+		require.Equal(t, "application/json", r.Header.Get("Accept"))
+		handler(w, r)
+	}), nil)
+
+	pool, err := cert.NewPoolFromBytes(testServerCA)
+	require.NoError(t, err)
+
+	client, err := NewGitLabClient(phttp.Default(pool), testServer.URL+"/api/v4", "some-token")
+	require.NoError(t, err)
+	return client
+}
+
+func TestGetUserInfo(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name         string
+		status       int
+		responseBody string
+		wantUserInfo *UserInfo
+		wantErr      string
+	}{
+		{
+			name:         "happy path",
+			status:       http.StatusOK,
+			responseBody: `{"id": 1234, "username": "some-username", "name": "Some Name", "state": "active"}`,
+			wantUserInfo: &UserInfo{ID: "1234", Username: "some-username"},
+		},
+		{
+			name:         "missing id",
+			status:       http.StatusOK,
+			responseBody: `{"username": "some-username"}`,
+			wantErr:      `error fetching authenticated user: the "id" attribute is missing`,
+		},
+		{
+			name:         "missing username",
+			status:       http.StatusOK,
+			responseBody: `{"id": 1234}`,
+			wantErr:      `error fetching authenticated user: the "username" attribute is missing`,
+		},
+		{
+			name:         "unauthorized",
+			status:       http.StatusUnauthorized,
+			responseBody: `{"message": "401 Unauthorized"}`,
+			wantErr:      `error fetching authenticated user: GET https://127.0.0.1:PORT/api/v4/user: unexpected response status "401 Unauthorized": {"message": "401 Unauthorized"}`,
+		},
+		{
+			name:         "invalid json",
+			status:       http.StatusOK,
+			responseBody: `not json`,
+			wantErr:      `error fetching authenticated user: GET https://127.0.0.1:PORT/api/v4/user: could not decode response: invalid character 'o' in literal null (expecting 'u')`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			var serverHost string
+			client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+				serverHost = r.Host
+				require.Equal(t, "/api/v4/user", r.URL.Path)
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(test.status)
+				_, _ = w.Write([]byte(test.responseBody))
+			})
+
+			actual, err := client.GetUserInfo(context.Background())
+			if test.wantErr != "" {
+				require.EqualError(t, err, replacePort(test.wantErr, serverHost))
+				require.Nil(t, actual)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, test.wantUserInfo, actual)
+		})
+	}
+}
+
+func TestGetGroupMembership(t *testing.T) {
+	t.Parallel()
+
+	page1 := `[
+		{"id": 1, "name": "Org One", "path": "org1", "full_path": "org1"},
+		{"id": 2, "name": "Team A", "path": "team-a", "full_path": "org1/team-a"},
+		{"id": 3, "name": "Org Two", "path": "org2", "full_path": "org2"}
+	]`
+	page2 := `[
+		{"id": 4, "name": "Nested", "path": "nested", "full_path": "org2/team-b/nested"},
+		{"id": 5, "name": "Org Three", "path": "Org3", "full_path": "Org3"},
+		{"id": 2, "name": "Team A", "path": "team-a", "full_path": "org1/team-a"}
+	]`
+
+	tests := []struct {
+		name                  string
+		allowedTopLevelGroups *setutil.CaseInsensitiveSet
+		pages                 map[string]string
+		lastPage              string
+		wantGroups            []GroupInfo
+		wantErr               string
+	}{
+		{
+			name:                  "returns all groups from all pages, sorted and de-duplicated, when no groups are allowed",
+			allowedTopLevelGroups: setutil.NewCaseInsensitiveSet(),
+			pages:                 map[string]string{"1": page1, "2": page2},
+			lastPage:              "2",
+			wantGroups: []GroupInfo{
+				{ID: "5", Path: "Org3", FullPath: "Org3"},
+				{ID: "1", Path: "org1", FullPath: "org1"},
+				{ID: "2", Path: "team-a", FullPath: "org1/team-a"},
+				{ID: "3", Path: "org2", FullPath: "org2"},
+				{ID: "4", Path: "nested", FullPath: "org2/team-b/nested"},
+			},
+		},
+		{
+			name:                  "filters by allowed top-level groups, ignoring case",
+			allowedTopLevelGroups: setutil.NewCaseInsensitiveSet("ORG2", "org3", "org-not-found"),
+			pages:                 map[string]string{"1": page1, "2": page2},
+			lastPage:              "2",
+			wantGroups: []GroupInfo{
+				{ID: "5", Path: "Org3", FullPath: "Org3"},
+				{ID: "3", Path: "org2", FullPath: "org2"},
+				{ID: "4", Path: "nested", FullPath: "org2/team-b/nested"},
+			},
+		},
+		{
+			name:                  "returns empty list when the user belongs to no groups",
+			allowedTopLevelGroups: setutil.NewCaseInsensitiveSet(),
+			pages:                 map[string]string{"1": `[]`},
+			lastPage:              "1",
+			wantGroups:            []GroupInfo{},
+		},
+		{
+			name:                  "missing id",
+			allowedTopLevelGroups: setutil.NewCaseInsensitiveSet(),
+			pages:                 map[string]string{"1": `[{"path": "org1", "full_path": "org1"}]`},
+			lastPage:              "1",
+			wantErr:               `error fetching group membership for authenticated user: the "id" attribute is missing for a group`,
+		},
+		{
+			name:                  "missing path",
+			allowedTopLevelGroups: setutil.NewCaseInsensitiveSet(),
+			pages:                 map[string]string{"1": `[{"id": 1, "full_path": "org1"}]`},
+			lastPage:              "1",
+			wantErr:               `error fetching group membership for authenticated user: the "path" attribute is missing for a group`,
+		},
+		{
+			name:                  "missing full_path",
+			allowedTopLevelGroups: setutil.NewCaseInsensitiveSet(),
+			pages:                 map[string]string{"1": `[{"id": 1, "path": "org1"}]`},
+			lastPage:              "1",
+			wantErr:               `error fetching group membership for authenticated user: the "full_path" attribute is missing for a group`,
+		},
+		{
+			name:                  "error on second page",
+			allowedTopLevelGroups: setutil.NewCaseInsensitiveSet(),
+			pages:                 map[string]string{"1": page1},
+			lastPage:              "2",
+			wantErr:               `error fetching group membership for authenticated user: GET https://127.0.0.1:PORT/api/v4/groups?min_access_level=10&page=2&per_page=100: unexpected response status "404 Not Found": {"message": "404 Not Found"}`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			var serverHost string
+			client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+				serverHost = r.Host
+				require.Equal(t, "/api/v4/groups", r.URL.Path)
+				require.Equal(t, "10", r.URL.Query().Get("min_access_level"))
+				require.Equal(t, "100", r.URL.Query().Get("per_page"))
+
+				page := r.URL.Query().Get("page")
+				body, ok := test.pages[page]
+				if !ok {
+					w.WriteHeader(http.StatusNotFound)
+					_, _ = w.Write([]byte(`{"message": "404 Not Found"}`))
+					return
+				}
+				if page != test.lastPage {
+					nextPage, err := strconv.Atoi(page)
+					require.NoError(t, err)
+					w.Header().Set("X-Next-Page", strconv.Itoa(nextPage+1))
+				}
+				w.Header().Set("Content-Type", "application/json")
+				_, _ = w.Write([]byte(body))
+			})
+
+			actual, err := client.GetGroupMembership(context.Background(), test.allowedTopLevelGroups)
+			if test.wantErr != "" {
+				require.EqualError(t, err, replacePort(test.wantErr, serverHost))
+				require.Nil(t, actual)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, test.wantGroups, actual)
+		})
+	}
+}
+
+func TestTopLevelGroupPath(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, "org1", GroupInfo{FullPath: "org1"}.TopLevelGroupPath())
+	require.Equal(t, "org1", GroupInfo{FullPath: "org1/team-a/nested"}.TopLevelGroupPath())
+}
+
+// replacePort replaces "127.0.0.1:PORT" in the expected error message with the actual host of the test server.
+func replacePort(wantErr string, serverHost string) string {
+	return strings.ReplaceAll(wantErr, "127.0.0.1:PORT", serverHost)
+}
@@ -0,0 +1,45 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// ResultNoSuchAuthenticator is used as the "result" label when a TokenCredentialRequest names an
+// authenticator which is not currently loaded.
+const ResultNoSuchAuthenticator = "no_such_authenticator"
+
+//nolint:gochecknoglobals // Metrics are registered once per process.
+var (
+	tokenCredentialRequests = mustRegister(prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "concierge",
+			Name:      "token_credential_requests_total",
+			Help:      "Number of TokenCredentialRequest authentications, by authenticator kind and result.",
+		},
+		[]string{"authenticator_kind", "result"},
+	))
+
+	tokenCredentialRequestDuration = mustRegister(prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "concierge",
+			Name:      "token_credential_request_duration_seconds",
+			Help:      "Latency of TokenCredentialRequest authentications, by authenticator kind.",
+			Buckets:   prometheus.DefBuckets,
+		},
+		[]string{"authenticator_kind"},
+	))
+)
+
+// ObserveTokenCredentialRequest records a single TokenCredentialRequest authentication which started at the
+// given time. The result should be one of ResultSuccess, ResultFailure, ResultError, or ResultNoSuchAuthenticator.
+func ObserveTokenCredentialRequest(authenticatorKind, result string, start time.Time) {
+	tokenCredentialRequests.WithLabelValues(authenticatorKind, result).Inc()
+	tokenCredentialRequestDuration.WithLabelValues(authenticatorKind).Observe(time.Since(start).Seconds())
+}
@@ -0,0 +1,134 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/client-go/util/workqueue"
+)
+
+//nolint:gochecknoglobals // Metrics are registered once per process.
+var (
+	controllerQueueDepth = mustRegister(prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: "controller",
+			Name:      "queue_depth",
+			Help:      "Current number of keys waiting in the work queue of each controller.",
+		},
+		[]string{"controller"},
+	))
+
+	controllerQueueAdds = mustRegister(prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "controller",
+			Name:      "queue_adds_total",
+			Help:      "Number of keys added to the work queue of each controller.",
+		},
+		[]string{"controller"},
+	))
+
+	controllerQueueRetries = mustRegister(prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "controller",
+			Name:      "queue_retries_total",
+			Help:      "Number of rate-limited retries of keys in the work queue of each controller.",
+		},
+		[]string{"controller"},
+	))
+
+	controllerQueueWaitDuration = mustRegister(prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "controller",
+			Name:      "queue_wait_duration_seconds",
+			Help:      "How long keys wait in the work queue of each controller before being processed.",
+			Buckets:   prometheus.ExponentialBuckets(0.001, 4, 10),
+		},
+		[]string{"controller"},
+	))
+
+	controllerQueueUnfinishedWork = mustRegister(prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: "controller",
+			Name:      "queue_unfinished_work_seconds",
+			Help:      "Seconds of work which has been done by each controller but is still in progress.",
+		},
+		[]string{"controller"},
+	))
+
+	controllerQueueLongestRunningProcessor = mustRegister(prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: "controller",
+			Name:      "queue_longest_running_processor_seconds",
+			Help:      "How long the longest running sync of each controller has been running.",
+		},
+		[]string{"controller"},
+	))
+
+	controllerSyncDuration = mustRegister(prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "controller",
+			Name:      "sync_duration_seconds",
+			Help:      "Latency of each call to a controller's sync function, by controller and result.",
+			Buckets:   prometheus.ExponentialBuckets(0.001, 4, 10),
+		},
+		[]string{"controller", "result"},
+	))
+)
+
+// ObserveControllerSync records a single call to a controller's sync function which started at the given time.
+func ObserveControllerSync(controller string, start time.Time, err error) {
+	controllerSyncDuration.WithLabelValues(controller, resultForError(err)).Observe(time.Since(start).Seconds())
+}
+
+// WorkqueueMetricsProvider returns a workqueue.MetricsProvider which records work queue metrics using the
+// name of each queue as the value of the "controller" label.
+func WorkqueueMetricsProvider() workqueue.MetricsProvider {
+	return workqueueMetricsProvider{}
+}
+
+type workqueueMetricsProvider struct{}
+
+var _ workqueue.MetricsProvider = workqueueMetricsProvider{}
+
+func (workqueueMetricsProvider) NewDepthMetric(name string) workqueue.GaugeMetric {
+	return controllerQueueDepth.WithLabelValues(name)
+}
+
+func (workqueueMetricsProvider) NewAddsMetric(name string) workqueue.CounterMetric {
+	return controllerQueueAdds.WithLabelValues(name)
+}
+
+func (workqueueMetricsProvider) NewLatencyMetric(name string) workqueue.HistogramMetric {
+	return controllerQueueWaitDuration.WithLabelValues(name)
+}
+
+func (workqueueMetricsProvider) NewWorkDurationMetric(_ string) workqueue.HistogramMetric {
+	// Sync durations are recorded by ObserveControllerSync instead, which also records the result of the sync.
+	return noopHistogram{}
+}
+
+func (workqueueMetricsProvider) NewUnfinishedWorkSecondsMetric(name string) workqueue.SettableGaugeMetric {
+	return controllerQueueUnfinishedWork.WithLabelValues(name)
+}
+
+func (workqueueMetricsProvider) NewLongestRunningProcessorSecondsMetric(name string) workqueue.SettableGaugeMetric {
+	return controllerQueueLongestRunningProcessor.WithLabelValues(name)
+}
+
+func (workqueueMetricsProvider) NewRetriesMetric(name string) workqueue.CounterMetric {
+	return controllerQueueRetries.WithLabelValues(name)
+}
+
+type noopHistogram struct{}
+
+func (noopHistogram) Observe(float64) {}
@@ -0,0 +1,75 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/felixge/httpsnoop"
+	"github.com/prometheus/client_golang/prometheus"
+
+	oidcapi "go.pinniped.dev/generated/latest/apis/supervisor/oidc"
+)
+
+// Names of the FederationDomain endpoints, used as the value of the "endpoint" label.
+const (
+	EndpointAuthorize           = "authorize"
+	EndpointCallback            = "callback"
+	EndpointLogin               = "login"
+	EndpointChooseIDP           = "choose_idp"
+	EndpointToken               = "token"
+	EndpointTokenExchange       = "token_exchange"
+	EndpointDeviceAuthorization = "device_authorization"
+	EndpointDeviceVerification  = "device_verification"
+	EndpointRevocation          = "revocation"
+	EndpointEndSession          = "end_session"
+	EndpointRegistration        = "registration"
+	EndpointIntrospection       = "introspection"
+	EndpointPushedAuthorization = "pushed_authorization"
+)
+
+//nolint:gochecknoglobals // Metrics are registered once per process.
+var (
+	federationDomainRequests = mustRegister(prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "supervisor",
+			Name:      "federation_domain_requests_total",
+			Help:      "Number of HTTP requests handled by FederationDomain endpoints, by endpoint and response status code.",
+		},
+		[]string{"endpoint", "code"},
+	))
+
+	federationDomainRequestDuration = mustRegister(prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "supervisor",
+			Name:      "federation_domain_request_duration_seconds",
+			Help:      "Latency of HTTP requests handled by FederationDomain endpoints, by endpoint.",
+			Buckets:   prometheus.DefBuckets,
+		},
+		[]string{"endpoint"},
+	))
+)
+
+// InstrumentFederationDomainEndpoint wraps the handler of a FederationDomain endpoint to record request counts
+// and latencies using the given endpoint name.
+//
+// Requests to EndpointToken which turn out to be RFC 8693 token exchanges are recorded as EndpointTokenExchange.
+// The token endpoint handler parses the request's form, so the grant type can be read from the request after
+// the wrapped handler has returned without consuming the request body a second time.
+func InstrumentFederationDomainEndpoint(endpoint string, handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		m := httpsnoop.CaptureMetrics(handler, w, r)
+
+		label := endpoint
+		if endpoint == EndpointToken && r.PostForm.Get("grant_type") == oidcapi.GrantTypeTokenExchange {
+			label = EndpointTokenExchange
+		}
+
+		federationDomainRequests.WithLabelValues(label, strconv.Itoa(m.Code)).Inc()
+		federationDomainRequestDuration.WithLabelValues(label).Observe(m.Duration.Seconds())
+	})
+}
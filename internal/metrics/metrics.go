@@ -0,0 +1,121 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package metrics defines the Prometheus metrics exported by the Supervisor and the Concierge,
+// and provides an optional HTTP listener for serving them.
+//
+// Metrics are always recorded into a private registry owned by this package, but they are only exposed
+// when the server's static configuration opts in to running the metrics listener.
+package metrics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"go.pinniped.dev/internal/plog"
+)
+
+const (
+	namespace = "pinniped"
+
+	// Path is the path on which the metrics listener serves metrics.
+	Path = "/metrics"
+
+	ResultSuccess = "success"
+	ResultFailure = "failure"
+	ResultError   = "error"
+)
+
+//nolint:gochecknoglobals // The registry is a process-wide singleton, similar to the default Prometheus registry.
+var registry = newRegistry()
+
+func newRegistry() *prometheus.Registry {
+	r := prometheus.NewRegistry()
+	r.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+	return r
+}
+
+// mustRegister registers the collector into the registry and returns it, for convenience when declaring metrics.
+func mustRegister[T prometheus.Collector](c T) T {
+	registry.MustRegister(c)
+	return c
+}
+
+// Registry returns the registry into which all Pinniped metrics are recorded.
+func Registry() *prometheus.Registry {
+	return registry
+}
+
+// Handler returns an http.Handler which serves all Pinniped metrics in the Prometheus exposition format.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{
+		ErrorLog:      promErrorLogger{},
+		ErrorHandling: promhttp.ContinueOnError,
+	})
+}
+
+// Serve starts a plain HTTP listener on the given port which serves metrics on Path. It returns an error
+// if the listener could not be created. Otherwise, it serves in the background until ctx is cancelled.
+func Serve(ctx context.Context, port int64) error {
+	mux := http.NewServeMux()
+	mux.Handle(Path, Handler())
+
+	listenConfig := net.ListenConfig{}
+	listener, err := listenConfig.Listen(ctx, "tcp", fmt.Sprintf(":%d", port))
+	if err != nil {
+		return fmt.Errorf("cannot create metrics listener on port %d: %w", port, err)
+	}
+
+	server := &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		err := server.Serve(listener)
+		if !errors.Is(err, http.ErrServerClosed) {
+			plog.Error("metrics server exited", err)
+		}
+	}()
+
+	//nolint:gosec // using background context because we can't use ctx here because it is already done
+	go func() {
+		<-ctx.Done()
+		plog.Debug("metrics server context cancelled", "err", ctx.Err())
+
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			plog.Debug("metrics server shutdown failed", "err", err)
+		}
+	}()
+
+	plog.Debug("metrics listener started", "address", listener.Addr().String())
+	return nil
+}
+
+// resultForError returns ResultSuccess when err is nil, and ResultError otherwise.
+func resultForError(err error) string {
+	if err != nil {
+		return ResultError
+	}
+	return ResultSuccess
+}
+
+type promErrorLogger struct{}
+
+func (promErrorLogger) Println(v ...any) {
+	plog.Warning("error while serving metrics", "message", fmt.Sprint(v...))
+}
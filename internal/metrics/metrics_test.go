@@ -0,0 +1,107 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package metrics
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+
+	oidcapi "go.pinniped.dev/generated/latest/apis/supervisor/oidc"
+)
+
+func TestInstrumentFederationDomainEndpoint(t *testing.T) {
+	federationDomainRequests.Reset()
+	federationDomainRequestDuration.Reset()
+
+	tokenHandler := InstrumentFederationDomainEndpoint(EndpointToken, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The real token handler parses the form, which is what allows the wrapper to see the grant type.
+		require.NoError(t, r.ParseForm())
+		if r.PostForm.Get("grant_type") == oidcapi.GrantTypeTokenExchange {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		_, _ = w.Write([]byte("ok"))
+	}))
+
+	doPost := func(grantType string) {
+		body := url.Values{"grant_type": {grantType}}.Encode()
+		req := httptest.NewRequest(http.MethodPost, "/token", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		tokenHandler.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	doPost(oidcapi.GrantTypeAuthorizationCode)
+	doPost(oidcapi.GrantTypeRefreshToken)
+	doPost(oidcapi.GrantTypeTokenExchange)
+
+	require.Equal(t, 2.0, testutil.ToFloat64(federationDomainRequests.WithLabelValues(EndpointToken, "200")))
+	require.Equal(t, 1.0, testutil.ToFloat64(federationDomainRequests.WithLabelValues(EndpointTokenExchange, "400")))
+	require.Equal(t, 2, testutil.CollectAndCount(federationDomainRequestDuration))
+}
+
+func TestObserveUpstreamCall(t *testing.T) {
+	upstreamRequests.Reset()
+	upstreamRequestDuration.Reset()
+
+	start := time.Now()
+	ObserveUpstreamCall(UpstreamTypeOIDC, UpstreamOperationRefresh, start, nil)
+	ObserveUpstreamCall(UpstreamTypeOIDC, UpstreamOperationRefresh, start, errors.New("some error"))
+	ObserveUpstreamCallResult(UpstreamTypeLDAP, UpstreamOperationBind, ResultFailure, start)
+
+	require.Equal(t, 1.0, testutil.ToFloat64(upstreamRequests.WithLabelValues(UpstreamTypeOIDC, UpstreamOperationRefresh, ResultSuccess)))
+	require.Equal(t, 1.0, testutil.ToFloat64(upstreamRequests.WithLabelValues(UpstreamTypeOIDC, UpstreamOperationRefresh, ResultError)))
+	require.Equal(t, 1.0, testutil.ToFloat64(upstreamRequests.WithLabelValues(UpstreamTypeLDAP, UpstreamOperationBind, ResultFailure)))
+	require.Equal(t, 2, testutil.CollectAndCount(upstreamRequestDuration))
+}
+
+func TestObserveTokenCredentialRequest(t *testing.T) {
+	tokenCredentialRequests.Reset()
+
+	start := time.Now()
+	ObserveTokenCredentialRequest("JWTAuthenticator", ResultSuccess, start)
+	ObserveTokenCredentialRequest("JWTAuthenticator", ResultSuccess, start)
+	ObserveTokenCredentialRequest("", ResultNoSuchAuthenticator, start)
+
+	require.Equal(t, 2.0, testutil.ToFloat64(tokenCredentialRequests.WithLabelValues("JWTAuthenticator", ResultSuccess)))
+	require.Equal(t, 1.0, testutil.ToFloat64(tokenCredentialRequests.WithLabelValues("", ResultNoSuchAuthenticator)))
+}
+
+func TestWorkqueueMetricsProvider(t *testing.T) {
+	controllerQueueDepth.Reset()
+	controllerQueueAdds.Reset()
+	controllerQueueRetries.Reset()
+
+	provider := WorkqueueMetricsProvider()
+
+	depth := provider.NewDepthMetric("some-controller")
+	depth.Inc()
+	depth.Inc()
+	depth.Dec()
+	provider.NewAddsMetric("some-controller").Inc()
+	provider.NewRetriesMetric("some-controller").Inc()
+	provider.NewWorkDurationMetric("some-controller").Observe(1) // noop
+
+	require.Equal(t, 1.0, testutil.ToFloat64(controllerQueueDepth.WithLabelValues("some-controller")))
+	require.Equal(t, 1.0, testutil.ToFloat64(controllerQueueAdds.WithLabelValues("some-controller")))
+	require.Equal(t, 1.0, testutil.ToFloat64(controllerQueueRetries.WithLabelValues("some-controller")))
+}
+
+func TestHandler(t *testing.T) {
+	ObserveControllerSync("some-controller", time.Now(), nil)
+
+	rec := httptest.NewRecorder()
+	Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, Path, nil))
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Contains(t, rec.Body.String(), `pinniped_controller_sync_duration_seconds_count{controller="some-controller",result="success"}`)
+	require.Contains(t, rec.Body.String(), "go_goroutines")
+}
@@ -0,0 +1,68 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Types of upstream identity providers, used as the value of the "idp_type" label.
+const (
+	UpstreamTypeOIDC   = "oidc"
+	UpstreamTypeLDAP   = "ldap"
+	UpstreamTypeGitHub = "github"
+	UpstreamTypeGitLab = "gitlab"
+	UpstreamTypeOAuth2 = "oauth2"
+)
+
+// Operations performed against upstream identity providers, used as the value of the "operation" label.
+const (
+	UpstreamOperationAuthcodeExchange = "authcode_exchange"
+	UpstreamOperationPasswordGrant    = "password_grant"
+	UpstreamOperationRefresh          = "refresh"
+	UpstreamOperationBind             = "bind"
+	UpstreamOperationSearch           = "search"
+	UpstreamOperationGitHubAPI        = "github_api"
+	UpstreamOperationGitLabAPI        = "gitlab_api"
+	UpstreamOperationUserInfo         = "userinfo"
+)
+
+//nolint:gochecknoglobals // Metrics are registered once per process.
+var (
+	upstreamRequests = mustRegister(prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "supervisor",
+			Name:      "upstream_requests_total",
+			Help:      "Number of calls made to upstream identity providers, by provider type, operation, and result.",
+		},
+		[]string{"idp_type", "operation", "result"},
+	))
+
+	upstreamRequestDuration = mustRegister(prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "supervisor",
+			Name:      "upstream_request_duration_seconds",
+			Help:      "Latency of calls made to upstream identity providers, by provider type and operation.",
+			Buckets:   prometheus.DefBuckets,
+		},
+		[]string{"idp_type", "operation"},
+	))
+)
+
+// ObserveUpstreamCall records a single call to an upstream identity provider which started at the given time.
+// A nil err is recorded as a success, and any other err is recorded as an error.
+func ObserveUpstreamCall(idpType, operation string, start time.Time, err error) {
+	ObserveUpstreamCallResult(idpType, operation, resultForError(err), start)
+}
+
+// ObserveUpstreamCallResult is like ObserveUpstreamCall, but allows the caller to decide the result,
+// e.g. to record a rejected end user password as ResultFailure rather than as ResultError.
+func ObserveUpstreamCallResult(idpType, operation, result string, start time.Time) {
+	upstreamRequests.WithLabelValues(idpType, operation, result).Inc()
+	upstreamRequestDuration.WithLabelValues(idpType, operation).Observe(time.Since(start).Seconds())
+}
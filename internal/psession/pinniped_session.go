@@ -1,10 +1,11 @@
-// Copyright 2021-2024 the Pinniped contributors. All Rights Reserved.
+// Copyright 2021-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package psession
 
 import (
 	"maps"
+	"slices"
 	"time"
 
 	"github.com/mohae/deepcopy"
@@ -77,6 +78,15 @@ type CustomSessionData struct {
 
 	// Only used when ProviderType == "github".
 	GitHub *GitHubSessionData `json:"github,omitempty"`
+
+	// Only used when ProviderType == "saml".
+	SAML *SAMLSessionData `json:"saml,omitempty"`
+
+	// Only used when ProviderType == "gitlab".
+	GitLab *GitLabSessionData `json:"gitlab,omitempty"`
+
+	// Only used when ProviderType == "oauth2".
+	OAuth2 *OAuth2SessionData `json:"oauth2,omitempty"`
 }
 
 type ProviderType string
@@ -86,6 +96,9 @@ const (
 	ProviderTypeLDAP            ProviderType = "ldap"
 	ProviderTypeActiveDirectory ProviderType = "activedirectory"
 	ProviderTypeGitHub          ProviderType = "github"
+	ProviderTypeSAML            ProviderType = "saml"
+	ProviderTypeGitLab          ProviderType = "gitlab"
+	ProviderTypeOAuth2          ProviderType = "oauth2"
 )
 
 // OIDCSessionData is the additional data needed by Pinniped when the upstream IDP is an OIDC provider.
@@ -111,10 +124,20 @@ type OIDCSessionData struct {
 	// UpstreamIssuer is the "iss" claim from the upstream identity provider from the user's initial login. We store this
 	// so that we can validate that it does not change upon refresh.
 	UpstreamIssuer string `json:"upstreamIssuer"`
+
+	// UpstreamACR is the "acr" claim from the upstream identity provider's ID token from the user's initial login,
+	// or from the most recent upstream refresh which returned a new ID token. We store this so that the
+	// authentication policy of the identity provider can be enforced again upon refresh, even when the upstream
+	// refresh does not return a new ID token.
+	UpstreamACR string `json:"upstreamACR,omitempty"`
+
+	// UpstreamAMR is the "amr" claim which was stored along with the UpstreamACR, for the same reason.
+	UpstreamAMR []string `json:"upstreamAMR,omitempty"`
 }
 
 func (s *OIDCSessionData) Clone() *OIDCSessionData {
-	dataCopy := *s // this shortcut works because all fields in this type are currently strings (no pointers)
+	dataCopy := *s
+	dataCopy.UpstreamAMR = slices.Clone(s.UpstreamAMR) // shallow copy works because all values are strings
 	return &dataCopy
 }
 
@@ -153,6 +176,56 @@ func (s *GitHubSessionData) Clone() *GitHubSessionData {
 	return &dataCopy
 }
 
+// SAMLSessionData is the additional data needed by Pinniped when the upstream IDP is a SAML provider.
+type SAMLSessionData struct {
+	// NameID is the NameID of the subject of the SAML assertion from the user's initial login. We store this
+	// so that it can be used to identify the user's session at the upstream IDP.
+	NameID string `json:"nameID"`
+
+	// SessionNotOnOrAfter is the time after which the upstream IDP said that the user's session must be
+	// considered to have ended, as determined by the SessionNotOnOrAfter attribute of the AuthnStatement in the
+	// SAML assertion from the user's initial login. SAML does not offer a way to refresh the user's identity
+	// without their interaction, so downstream refreshes will be allowed until this time. A zero value means
+	// that the upstream IDP did not set any time limit for the session.
+	SessionNotOnOrAfter time.Time `json:"sessionNotOnOrAfter"`
+}
+
+func (s *SAMLSessionData) Clone() *SAMLSessionData {
+	dataCopy := *s // this shortcut works because all fields in this type are currently values (no pointers)
+	return &dataCopy
+}
+
+// GitLabSessionData is the additional data needed by Pinniped when the upstream IDP is a GitLab provider.
+type GitLabSessionData struct {
+	// UpstreamRefreshToken is the most recent refresh token returned by GitLab. GitLab access tokens expire after
+	// a short time, so the refresh token is used during each downstream refresh to get a new access token.
+	// GitLab rotates refresh tokens, so this must be updated after each upstream refresh.
+	UpstreamRefreshToken string `json:"upstreamRefreshToken"`
+}
+
+func (s *GitLabSessionData) Clone() *GitLabSessionData {
+	dataCopy := *s // this shortcut works because all fields in this type are currently strings (no pointers)
+	return &dataCopy
+}
+
+// OAuth2SessionData is the additional data needed by Pinniped when the upstream IDP is a generic OAuth2 provider.
+// Exactly one of the fields should be non-empty.
+type OAuth2SessionData struct {
+	// UpstreamRefreshToken is the most recent refresh token returned by the OAuth2 provider, if it returned one.
+	// It is used during each downstream refresh to get a new access token. Some providers rotate refresh tokens,
+	// so this must be updated after each upstream refresh when a new refresh token is returned.
+	UpstreamRefreshToken string `json:"upstreamRefreshToken,omitempty"`
+
+	// UpstreamAccessToken is the access token returned by the OAuth2 provider when it did not return a refresh token.
+	// It is used during each downstream refresh to call the user info endpoint again.
+	UpstreamAccessToken string `json:"upstreamAccessToken,omitempty"`
+}
+
+func (s *OAuth2SessionData) Clone() *OAuth2SessionData {
+	dataCopy := *s // this shortcut works because all fields in this type are currently strings (no pointers)
+	return &dataCopy
+}
+
 // NewPinnipedSession returns a new empty session.
 func NewPinnipedSession() *PinnipedSession {
 	return &PinnipedSession{
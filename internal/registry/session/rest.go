@@ -0,0 +1,380 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package session provides REST functionality for the Session resource.
+package session
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/ory/fosite"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metainternalversion "k8s.io/apimachinery/pkg/apis/meta/internalversion"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/selection"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	genericapirequest "k8s.io/apiserver/pkg/endpoints/request"
+	"k8s.io/apiserver/pkg/registry/rest"
+	"k8s.io/utils/trace"
+
+	oidcapi "go.pinniped.dev/generated/latest/apis/supervisor/oidc"
+	sessionapi "go.pinniped.dev/generated/latest/apis/supervisor/session"
+	"go.pinniped.dev/internal/crud"
+	"go.pinniped.dev/internal/fositestorage"
+	"go.pinniped.dev/internal/fositestorage/accesstoken"
+	"go.pinniped.dev/internal/fositestorage/refreshtoken"
+	"go.pinniped.dev/internal/plog"
+	"go.pinniped.dev/internal/psession"
+)
+
+// SessionRevoker ends downstream sessions. See sessionrevocation.Revoker.
+type SessionRevoker interface {
+	RevokeSession(ctx context.Context, requestID string) error
+}
+
+func NewREST(
+	resource schema.GroupResource,
+	secretsClient crud.SecretsBackend,
+	revoker SessionRevoker,
+	namespace string,
+) *REST {
+	return &REST{
+		secretsClient:  secretsClient,
+		revoker:        revoker,
+		namespace:      namespace,
+		tableConvertor: rest.NewDefaultTableConvertor(resource),
+	}
+}
+
+type REST struct {
+	secretsClient  crud.SecretsBackend
+	revoker        SessionRevoker
+	namespace      string
+	tableConvertor rest.TableConvertor
+}
+
+// Assert that our *REST implements all the optional interfaces that we expect it to implement.
+var _ interface {
+	rest.Getter
+	rest.GracefulDeleter
+	rest.Scoper
+	rest.Storage
+	rest.CategoriesProvider
+	rest.SingularNameProvider
+	rest.Lister
+	rest.TableConvertor
+} = (*REST)(nil)
+
+func (*REST) New() runtime.Object {
+	return &sessionapi.Session{}
+}
+
+func (*REST) Destroy() {}
+
+func (*REST) NewList() runtime.Object {
+	return &sessionapi.SessionList{}
+}
+
+func (r *REST) ConvertToTable(ctx context.Context, obj runtime.Object, tableOptions runtime.Object) (*metav1.Table, error) {
+	return r.tableConvertor.ConvertToTable(ctx, obj, tableOptions)
+}
+
+func (*REST) NamespaceScoped() bool {
+	return true
+}
+
+func (*REST) Categories() []string {
+	return []string{"pinniped"}
+}
+
+func (*REST) GetSingularName() string {
+	return "session"
+}
+
+// A downstream session is active once its authcode was exchanged for tokens, at which point it has access token
+// storage and/or refresh token storage. Those are the only storage types which are updated by downstream refreshes,
+// so they always have the latest version of the session.
+var activeSessionStorageTypes = []string{accesstoken.TypeLabelValue, refreshtoken.TypeLabelValue}
+
+func (r *REST) Get(ctx context.Context, name string, _ *metav1.GetOptions) (runtime.Object, error) {
+	t := trace.FromContext(ctx).Nest("get",
+		trace.Field{Key: "kind", Value: "Session"},
+		trace.Field{Key: "metadata.name", Value: name},
+	)
+	defer t.Log()
+
+	return r.get(ctx, name, t)
+}
+
+func (r *REST) List(ctx context.Context, options *metainternalversion.ListOptions) (runtime.Object, error) {
+	t := trace.FromContext(ctx).Nest("list", trace.Field{Key: "kind", Value: "Session"})
+	defer t.Log()
+
+	list := &sessionapi.SessionList{
+		Items: []sessionapi.Session{}, // avoid sending nil items list
+	}
+
+	// Sessions only exist in the Supervisor's namespace, but they are also included when listing across all namespaces.
+	requestNamespace := genericapirequest.NamespaceValue(ctx)
+	if requestNamespace != "" && requestNamespace != r.namespace {
+		return list, nil
+	}
+
+	sessions, err := r.listSessions(ctx, labels.Everything(), t)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, session := range sessions {
+		if options != nil && options.LabelSelector != nil && !options.LabelSelector.Matches(labels.Set(session.Labels)) {
+			continue
+		}
+		if options != nil && options.FieldSelector != nil && !options.FieldSelector.Matches(fields.Set{
+			"metadata.name":      session.Name,
+			"metadata.namespace": session.Namespace,
+		}) {
+			continue
+		}
+		list.Items = append(list.Items, *session)
+	}
+
+	return list, nil
+}
+
+// Delete ends the session by revoking its upstream tokens and deleting all of its storage, which revokes all
+// the downstream tokens of the session.
+func (r *REST) Delete(
+	ctx context.Context,
+	name string,
+	deleteValidation rest.ValidateObjectFunc,
+	options *metav1.DeleteOptions,
+) (runtime.Object, bool, error) {
+	t := trace.FromContext(ctx).Nest("delete",
+		trace.Field{Key: "kind", Value: "Session"},
+		trace.Field{Key: "metadata.name", Value: name},
+	)
+	defer t.Log()
+
+	session, err := r.get(ctx, name, t)
+	if err != nil {
+		return nil, false, err
+	}
+
+	// just a sanity check, not sure how to honor a dry run on a virtual API
+	if options != nil && len(options.DryRun) != 0 {
+		traceValidationFailure(t, "dryRun not supported")
+		errs := field.ErrorList{field.NotSupported(field.NewPath("dryRun"), options.DryRun, []string(nil))}
+		return nil, false, apierrors.NewInvalid(kindFromContext(ctx), name, errs)
+	}
+
+	if deleteValidation != nil {
+		if err := deleteValidation(ctx, session.DeepCopyObject()); err != nil {
+			traceFailureWithError(t, "validation webhook", err)
+			return nil, false, err
+		}
+	}
+
+	if err := r.revoker.RevokeSession(ctx, name); err != nil {
+		traceFailureWithError(t, "revoker.RevokeSession", err)
+		if errors.Is(err, fosite.ErrNotFound) {
+			// The session ended after we found it above, e.g. because it was concurrently revoked.
+			return nil, false, apierrors.NewNotFound(qualifiedResourceFromContext(ctx), name)
+		}
+		return nil, false, apierrors.NewInternalError(fmt.Errorf("ending session %q failed", name))
+	}
+	t.Step("revoker.RevokeSession")
+
+	return session, true, nil
+}
+
+func (r *REST) get(ctx context.Context, name string, tracer *trace.Trace) (*sessionapi.Session, error) {
+	notFoundErr := apierrors.NewNotFound(qualifiedResourceFromContext(ctx), name)
+
+	if genericapirequest.NamespaceValue(ctx) != r.namespace {
+		traceValidationFailure(tracer, "wrong namespace")
+		return nil, notFoundErr
+	}
+
+	requestIDRequirement, err := labels.NewRequirement(fositestorage.StorageRequestIDLabelName, selection.Equals, []string{name})
+	if err != nil {
+		// The name is not a valid label value, so there could never be a session with this name.
+		traceValidationFailure(tracer, err.Error())
+		return nil, notFoundErr
+	}
+
+	sessions, err := r.listSessions(ctx, labels.NewSelector().Add(*requestIDRequirement), tracer)
+	if err != nil {
+		return nil, err
+	}
+	if len(sessions) == 0 {
+		traceFailure(tracer, "listSessions", "session not found")
+		return nil, notFoundErr
+	}
+
+	return sessions[0], nil
+}
+
+// listSessions finds the active sessions by listing their storage Secrets, optionally restricted by the given selector.
+// The returned sessions are sorted by name.
+func (r *REST) listSessions(ctx context.Context, selector labels.Selector, tracer *trace.Trace) ([]*sessionapi.Session, error) {
+	typeRequirement, err := labels.NewRequirement(crud.SecretLabelKey, selection.In, activeSessionStorageTypes)
+	if err != nil {
+		return nil, apierrors.NewInternalError(err) // this should never happen in practice
+	}
+	requestIDRequirement, err := labels.NewRequirement(fositestorage.StorageRequestIDLabelName, selection.Exists, nil)
+	if err != nil {
+		return nil, apierrors.NewInternalError(err) // this should never happen in practice
+	}
+
+	secretList, err := r.secretsClient.List(ctx, metav1.ListOptions{
+		LabelSelector: selector.Add(*typeRequirement, *requestIDRequirement).String(),
+	})
+	if err != nil {
+		traceFailureWithError(tracer, "secretsClient.List", err)
+		return nil, apierrors.NewInternalError(errors.New("listing session storage failed"))
+	}
+	tracer.Step("secretsClient.List")
+
+	sessionsByName := map[string]*sessionapi.Session{}
+	for i := range secretList.Items {
+		secret := &secretList.Items[i]
+
+		request, err := readRequestFromSecret(secret)
+		if err != nil {
+			// Do not let one corrupt session make all the other sessions unreadable.
+			plog.WarningErr("could not read session storage", err, "secretName", secret.Name)
+			continue
+		}
+
+		expiresAt, err := time.Parse(crud.SecretLifetimeAnnotationDateFormat, secret.Annotations[crud.SecretLifetimeAnnotationKey])
+		if err != nil {
+			plog.WarningErr("could not read session storage lifetime", err, "secretName", secret.Name)
+			continue
+		}
+
+		// Each storage type of a session holds the same identity. The session expires when the last of its storage expires.
+		session, found := sessionsByName[request.GetID()]
+		if !found {
+			session = toSession(request, r.namespace)
+			sessionsByName[request.GetID()] = session
+		}
+		if expiresAt.After(session.Status.ExpiresAt.Time) {
+			session.Status.ExpiresAt = metav1.NewTime(expiresAt)
+		}
+	}
+
+	sessions := make([]*sessionapi.Session, 0, len(sessionsByName))
+	for _, session := range sessionsByName {
+		sessions = append(sessions, session)
+	}
+	slices.SortFunc(sessions, func(a, b *sessionapi.Session) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	return sessions, nil
+}
+
+func readRequestFromSecret(secret *corev1.Secret) (*fosite.Request, error) {
+	switch secret.Labels[crud.SecretLabelKey] {
+	case accesstoken.TypeLabelValue:
+		session, err := accesstoken.ReadFromSecret(secret)
+		if err != nil {
+			return nil, err
+		}
+		return session.Request, nil
+	case refreshtoken.TypeLabelValue:
+		session, err := refreshtoken.ReadFromSecret(secret)
+		if err != nil {
+			return nil, err
+		}
+		return session.Request, nil
+	default:
+		return nil, fmt.Errorf("unexpected storage type %q", secret.Labels[crud.SecretLabelKey])
+	}
+}
+
+// toSession copies the identity of the user out of the downstream session. It must never copy any tokens.
+func toSession(request *fosite.Request, namespace string) *sessionapi.Session {
+	session := &sessionapi.Session{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      request.GetID(),
+			Namespace: namespace,
+		},
+		Status: sessionapi.SessionStatus{
+			ClientID: request.GetClient().GetID(),
+		},
+	}
+
+	pinnipedSession, ok := request.GetSession().(*psession.PinnipedSession)
+	if !ok {
+		return session
+	}
+
+	if custom := pinnipedSession.Custom; custom != nil {
+		session.Status.Username = custom.Username
+		session.Status.IdentityProvider = sessionapi.SessionIdentityProvider{
+			Name: custom.ProviderName,
+			Type: string(custom.ProviderType),
+			UID:  custom.ProviderUID,
+		}
+	}
+
+	if pinnipedSession.Fosite != nil && pinnipedSession.Fosite.Claims != nil {
+		claims := pinnipedSession.Fosite.Claims
+		session.CreationTimestamp = metav1.NewTime(claims.AuthTime)
+		// The downstream groups are only kept in the session when the groups scope was granted.
+		if groups, ok := claims.Extra[oidcapi.IDTokenClaimGroups].([]any); ok {
+			for _, group := range groups {
+				if g, ok := group.(string); ok {
+					session.Status.Groups = append(session.Status.Groups, g)
+				}
+			}
+		}
+	}
+
+	return session
+}
+
+func traceFailure(t *trace.Trace, failureType string, msg string) {
+	t.Step("failure",
+		trace.Field{Key: "failureType", Value: failureType},
+		trace.Field{Key: "msg", Value: msg},
+	)
+}
+
+func traceValidationFailure(t *trace.Trace, msg string) {
+	traceFailure(t, "request validation", msg)
+}
+
+func traceFailureWithError(t *trace.Trace, failureType string, err error) {
+	t.Step("failure",
+		trace.Field{Key: "failureType", Value: failureType},
+		trace.Field{Key: "msg", Value: err.Error()},
+	)
+}
+
+func qualifiedResourceFromContext(ctx context.Context) schema.GroupResource {
+	if info, ok := genericapirequest.RequestInfoFrom(ctx); ok {
+		return schema.GroupResource{Group: info.APIGroup, Resource: info.Resource}
+	}
+	// this should never happen in practice
+	return sessionapi.Resource("sessions")
+}
+
+func kindFromContext(ctx context.Context) schema.GroupKind {
+	if info, ok := genericapirequest.RequestInfoFrom(ctx); ok {
+		return schema.GroupKind{Group: info.APIGroup, Kind: "Session"}
+	}
+	// this should never happen in practice
+	return sessionapi.Kind("Session")
+}
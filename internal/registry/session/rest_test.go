@@ -0,0 +1,417 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package session
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ory/fosite"
+	"github.com/ory/fosite/handler/openid"
+	fositejwt "github.com/ory/fosite/token/jwt"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metainternalversion "k8s.io/apimachinery/pkg/apis/meta/internalversion"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	genericapirequest "k8s.io/apiserver/pkg/endpoints/request"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+
+	sessionapi "go.pinniped.dev/generated/latest/apis/supervisor/session"
+	supervisorfake "go.pinniped.dev/generated/latest/client/supervisor/clientset/versioned/fake"
+	"go.pinniped.dev/internal/federationdomain/clientregistry"
+	"go.pinniped.dev/internal/federationdomain/oidc"
+	"go.pinniped.dev/internal/federationdomain/sessionrevocation"
+	"go.pinniped.dev/internal/federationdomain/storage"
+	"go.pinniped.dev/internal/plog"
+	"go.pinniped.dev/internal/psession"
+	"go.pinniped.dev/internal/testutil/testidplister"
+)
+
+const namespace = "some-namespace"
+
+type fakeRevoker struct {
+	err             error
+	revokedSessions []string
+}
+
+func (f *fakeRevoker) RevokeSession(_ context.Context, requestID string) error {
+	f.revokedSessions = append(f.revokedSessions, requestID)
+	return f.err
+}
+
+func TestNew(t *testing.T) {
+	r := NewREST(schema.GroupResource{Group: "bears", Resource: "panda"}, nil, nil, namespace)
+
+	require.NotNil(t, r)
+	require.True(t, r.NamespaceScoped())
+	require.Equal(t, []string{"pinniped"}, r.Categories())
+	require.Equal(t, "session", r.GetSingularName())
+
+	require.IsType(t, &sessionapi.Session{}, r.New())
+	require.IsType(t, &sessionapi.SessionList{}, r.NewList())
+
+	// make sure we can turn lists into tables if needed
+	table, err := r.ConvertToTable(context.Background(), &sessionapi.SessionList{}, nil)
+	require.NoError(t, err)
+	require.NotNil(t, table)
+
+	// exercise group resource - force error by passing a runtime.Object that does not have an embedded object meta
+	_, err = r.ConvertToTable(context.Background(), &metav1.APIGroup{}, nil)
+	require.Error(t, err, "the resource panda.bears does not support being converted to a Table")
+}
+
+func TestGetAndList(t *testing.T) {
+	authTime := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	secrets := kubefake.NewClientset().CoreV1().Secrets(namespace)
+	kubeStorage := newKubeStorage(secrets)
+
+	// A session with both an access token and a refresh token, which was granted the groups scope.
+	oidcRequest := newRequest("session-1", authTime, &psession.CustomSessionData{
+		Username:     "some-username",
+		ProviderUID:  "some-oidc-idp-uid",
+		ProviderName: "some-oidc-idp",
+		ProviderType: psession.ProviderTypeOIDC,
+		//nolint:gosec // not a real credential
+		OIDC: &psession.OIDCSessionData{UpstreamRefreshToken: "some-upstream-refresh-token"},
+	}, []string{"group1", "group2"})
+	require.NoError(t, kubeStorage.CreateAccessTokenSession(t.Context(), "access.signature.1", oidcRequest))
+	require.NoError(t, kubeStorage.CreateRefreshTokenSession(t.Context(), "refresh.signature.1", "", oidcRequest))
+
+	// A session with only an access token, which was not granted the groups scope.
+	ldapRequest := newRequest("session-2", authTime, &psession.CustomSessionData{
+		Username:     "other-username",
+		ProviderUID:  "some-ldap-idp-uid",
+		ProviderName: "some-ldap-idp",
+		ProviderType: psession.ProviderTypeLDAP,
+		LDAP:         &psession.LDAPSessionData{UserDN: "some-user-dn"},
+	}, nil)
+	require.NoError(t, kubeStorage.CreateAccessTokenSession(t.Context(), "access.signature.2", ldapRequest))
+
+	// A session which has not exchanged its authcode yet is not active.
+	require.NoError(t, kubeStorage.CreateAuthorizeCodeSession(t.Context(), "authcode-signature-for-session-3",
+		newRequest("session-3", authTime, &psession.CustomSessionData{ProviderType: psession.ProviderTypeLDAP}, nil)))
+
+	// Storage which could not be decoded is skipped.
+	_, err := secrets.Create(t.Context(), newCorruptAccessTokenSecret("session-4"), metav1.CreateOptions{})
+	require.NoError(t, err)
+
+	// Read back the expiry times instead of faking the clock of the storage.
+	refreshSecret, err := secrets.Get(t.Context(), "pinniped-storage-refresh-token-"+signatureSuffix(t, secrets, "refresh-token", "session-1"), metav1.GetOptions{})
+	require.NoError(t, err)
+	accessSecret2, err := secrets.Get(t.Context(), "pinniped-storage-access-token-"+signatureSuffix(t, secrets, "access-token", "session-2"), metav1.GetOptions{})
+	require.NoError(t, err)
+
+	wantSession1 := &sessionapi.Session{
+		ObjectMeta: metav1.ObjectMeta{Name: "session-1", Namespace: namespace, CreationTimestamp: metav1.NewTime(authTime)},
+		Status: sessionapi.SessionStatus{
+			Username: "some-username",
+			Groups:   []string{"group1", "group2"},
+			ClientID: "pinniped-cli",
+			IdentityProvider: sessionapi.SessionIdentityProvider{
+				Name: "some-oidc-idp",
+				Type: "oidc",
+				UID:  "some-oidc-idp-uid",
+			},
+			ExpiresAt: parseExpiry(t, refreshSecret.Annotations["storage.pinniped.dev/garbage-collect-after"]),
+		},
+	}
+	wantSession2 := &sessionapi.Session{
+		ObjectMeta: metav1.ObjectMeta{Name: "session-2", Namespace: namespace, CreationTimestamp: metav1.NewTime(authTime)},
+		Status: sessionapi.SessionStatus{
+			Username: "other-username",
+			ClientID: "pinniped-cli",
+			IdentityProvider: sessionapi.SessionIdentityProvider{
+				Name: "some-ldap-idp",
+				Type: "ldap",
+				UID:  "some-ldap-idp-uid",
+			},
+			ExpiresAt: parseExpiry(t, accessSecret2.Annotations["storage.pinniped.dev/garbage-collect-after"]),
+		},
+	}
+
+	r := NewREST(sessionapi.Resource("sessions"), secrets, &fakeRevoker{}, namespace)
+
+	t.Run("get", func(t *testing.T) {
+		got, err := r.Get(namespacedContext(namespace), "session-1", &metav1.GetOptions{})
+		require.NoError(t, err)
+		require.Equal(t, wantSession1, got)
+
+		got, err = r.Get(namespacedContext(namespace), "session-2", &metav1.GetOptions{})
+		require.NoError(t, err)
+		require.Equal(t, wantSession2, got)
+	})
+
+	t.Run("get sessions which are not active", func(t *testing.T) {
+		for _, name := range []string{"session-3", "session-4", "does-not-exist", "not a valid label value!"} {
+			_, err := r.Get(namespacedContext(namespace), name, &metav1.GetOptions{})
+			require.True(t, apierrors.IsNotFound(err), "name %q: %v", name, err)
+			require.EqualError(t, err, `sessions.session.supervisor.pinniped.dev "`+name+`" not found`)
+		}
+	})
+
+	t.Run("get from the wrong namespace", func(t *testing.T) {
+		_, err := r.Get(namespacedContext("other-namespace"), "session-1", &metav1.GetOptions{})
+		require.True(t, apierrors.IsNotFound(err))
+	})
+
+	tests := []struct {
+		name      string
+		ctx       context.Context
+		options   *metainternalversion.ListOptions
+		wantItems []sessionapi.Session
+	}{
+		{
+			name:      "list",
+			ctx:       namespacedContext(namespace),
+			wantItems: []sessionapi.Session{*wantSession1, *wantSession2},
+		},
+		{
+			name:      "list across all namespaces",
+			ctx:       namespacedContext(""),
+			options:   &metainternalversion.ListOptions{},
+			wantItems: []sessionapi.Session{*wantSession1, *wantSession2},
+		},
+		{
+			name:      "list in another namespace",
+			ctx:       namespacedContext("other-namespace"),
+			wantItems: []sessionapi.Session{},
+		},
+		{
+			name:      "list with field selector",
+			ctx:       namespacedContext(namespace),
+			options:   &metainternalversion.ListOptions{FieldSelector: fields.OneTermEqualSelector("metadata.name", "session-2")},
+			wantItems: []sessionapi.Session{*wantSession2},
+		},
+		{
+			name:      "list with label selector",
+			ctx:       namespacedContext(namespace),
+			options:   &metainternalversion.ListOptions{LabelSelector: labels.SelectorFromSet(labels.Set{"some-label": "some-value"})},
+			wantItems: []sessionapi.Session{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := r.List(tt.ctx, tt.options)
+			require.NoError(t, err)
+			require.Equal(t, &sessionapi.SessionList{Items: tt.wantItems}, got)
+		})
+	}
+}
+
+func TestDelete(t *testing.T) {
+	tests := []struct {
+		name             string
+		ctx              context.Context
+		sessionName      string
+		deleteValidation func(ctx context.Context, obj runtime.Object) error
+		options          *metav1.DeleteOptions
+		revokerErr       error
+
+		wantErr             string
+		wantNotFound        bool
+		wantRevokedSessions []string
+	}{
+		{
+			name:                "success",
+			ctx:                 namespacedContext(namespace),
+			sessionName:         "session-1",
+			wantRevokedSessions: []string{"session-1"},
+		},
+		{
+			name:         "session not found",
+			ctx:          namespacedContext(namespace),
+			sessionName:  "does-not-exist",
+			wantErr:      `sessions.session.supervisor.pinniped.dev "does-not-exist" not found`,
+			wantNotFound: true,
+		},
+		{
+			name:         "wrong namespace",
+			ctx:          namespacedContext("other-namespace"),
+			sessionName:  "session-1",
+			wantErr:      `sessions.session.supervisor.pinniped.dev "session-1" not found`,
+			wantNotFound: true,
+		},
+		{
+			name:        "dry run",
+			ctx:         namespacedContext(namespace),
+			sessionName: "session-1",
+			options:     &metav1.DeleteOptions{DryRun: []string{metav1.DryRunAll}},
+			wantErr:     `Session.session.supervisor.pinniped.dev "session-1" is invalid: dryRun: Unsupported value: ["All"]`,
+		},
+		{
+			name:        "validation webhook rejects the request",
+			ctx:         namespacedContext(namespace),
+			sessionName: "session-1",
+			deleteValidation: func(_ context.Context, obj runtime.Object) error {
+				require.Equal(t, "session-1", obj.(*sessionapi.Session).Name)
+				return errors.New("some validation error")
+			},
+			wantErr: "some validation error",
+		},
+		{
+			name:                "session was concurrently revoked",
+			ctx:                 namespacedContext(namespace),
+			sessionName:         "session-1",
+			revokerErr:          fosite.ErrNotFound,
+			wantErr:             `sessions.session.supervisor.pinniped.dev "session-1" not found`,
+			wantNotFound:        true,
+			wantRevokedSessions: []string{"session-1"},
+		},
+		{
+			name:                "revocation fails",
+			ctx:                 namespacedContext(namespace),
+			sessionName:         "session-1",
+			revokerErr:          errors.New("some revocation error"),
+			wantErr:             `Internal error occurred: ending session "session-1" failed`,
+			wantRevokedSessions: []string{"session-1"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			secrets := kubefake.NewClientset().CoreV1().Secrets(namespace)
+			request := newRequest("session-1", time.Now(), &psession.CustomSessionData{ProviderType: psession.ProviderTypeLDAP}, nil)
+			require.NoError(t, newKubeStorage(secrets).CreateAccessTokenSession(t.Context(), "access.signature", request))
+
+			revoker := &fakeRevoker{err: tt.revokerErr}
+			r := NewREST(sessionapi.Resource("sessions"), secrets, revoker, namespace)
+
+			got, deletedImmediately, err := r.Delete(tt.ctx, tt.sessionName, tt.deleteValidation, tt.options)
+			require.Equal(t, tt.wantRevokedSessions, revoker.revokedSessions)
+			if tt.wantErr != "" {
+				require.EqualError(t, err, tt.wantErr)
+				require.Equal(t, tt.wantNotFound, apierrors.IsNotFound(err))
+				require.Nil(t, got)
+				require.False(t, deletedImmediately)
+				return
+			}
+			require.NoError(t, err)
+			require.True(t, deletedImmediately)
+			require.Equal(t, tt.sessionName, got.(*sessionapi.Session).Name)
+		})
+	}
+}
+
+func TestDeleteRemovesAllSessionStorage(t *testing.T) {
+	secrets := kubefake.NewClientset().CoreV1().Secrets(namespace)
+	kubeStorage := newKubeStorage(secrets)
+
+	for _, id := range []string{"session-1", "session-2"} {
+		request := newRequest(id, time.Now(), &psession.CustomSessionData{ProviderType: psession.ProviderTypeLDAP}, nil)
+		require.NoError(t, kubeStorage.CreateAuthorizeCodeSession(t.Context(), "authcode-signature-for-"+id, request))
+		require.NoError(t, kubeStorage.InvalidateAuthorizeCodeSession(t.Context(), "authcode-signature-for-"+id))
+		require.NoError(t, kubeStorage.CreatePKCERequestSession(t.Context(), "authcode-signature-for-"+id, request))
+		require.NoError(t, kubeStorage.CreateOpenIDConnectSession(t.Context(), "authcode.authcode-signature-for-"+id, request))
+		require.NoError(t, kubeStorage.CreateAccessTokenSession(t.Context(), "access.signature."+id, request))
+		require.NoError(t, kubeStorage.CreateRefreshTokenSession(t.Context(), "refresh.signature."+id, "", request))
+	}
+
+	auditLogger, _ := plog.TestAuditLogger(t)
+	revoker := sessionrevocation.New(secrets, testidplister.NewUpstreamIDPListerBuilder().BuildDynamicUpstreamIDPProvider(), auditLogger)
+	r := NewREST(sessionapi.Resource("sessions"), secrets, revoker, namespace)
+
+	_, _, err := r.Delete(namespacedContext(namespace), "session-1", nil, &metav1.DeleteOptions{})
+	require.NoError(t, err)
+
+	remaining, err := secrets.List(t.Context(), metav1.ListOptions{})
+	require.NoError(t, err)
+	require.Len(t, remaining.Items, 5)
+	for _, secret := range remaining.Items {
+		require.Equal(t, "session-2", secret.Labels["storage.pinniped.dev/request-id"])
+	}
+
+	_, err = r.Get(namespacedContext(namespace), "session-1", &metav1.GetOptions{})
+	require.True(t, apierrors.IsNotFound(err))
+}
+
+func namespacedContext(ns string) context.Context {
+	return genericapirequest.WithNamespace(
+		genericapirequest.WithRequestInfo(
+			genericapirequest.NewContext(),
+			&genericapirequest.RequestInfo{
+				APIGroup:   "session.supervisor.pinniped.dev",
+				APIVersion: "v1alpha1",
+				Resource:   "sessions",
+			},
+		),
+		ns,
+	)
+}
+
+func newKubeStorage(secrets corev1client.SecretInterface) *storage.KubeStorage {
+	oidcClientsClient := supervisorfake.NewSimpleClientset().ConfigV1alpha1().OIDCClients(namespace)
+	// Use lower minimum required bcrypt cost than we would use in production to keep unit the tests fast.
+	return storage.NewKubeStorage(secrets, oidcClientsClient, oidc.DefaultOIDCTimeoutsConfiguration(), bcrypt.MinCost)
+}
+
+func newRequest(id string, authTime time.Time, custom *psession.CustomSessionData, groups []string) *fosite.Request {
+	request := fosite.NewRequest()
+	request.ID = id
+	request.Client = clientregistry.PinnipedCLI()
+	request.GrantedScope = fosite.Arguments{"openid", "offline_access"}
+
+	extra := map[string]any{}
+	if groups != nil {
+		extra["groups"] = groups
+	}
+	request.Session = &psession.PinnipedSession{
+		Fosite: &openid.DefaultSession{
+			Claims: &fositejwt.IDTokenClaims{
+				Subject:  "some-subject",
+				AuthTime: authTime,
+				Extra:    extra,
+			},
+		},
+		Custom: custom,
+	}
+	return request
+}
+
+func newCorruptAccessTokenSecret(id string) *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "pinniped-storage-access-token-corrupt",
+			Labels: map[string]string{
+				"storage.pinniped.dev/type":       "access-token",
+				"storage.pinniped.dev/request-id": id,
+			},
+			Annotations: map[string]string{
+				"storage.pinniped.dev/garbage-collect-after": time.Now().Add(time.Hour).UTC().Format(time.RFC3339),
+			},
+		},
+		Data: map[string][]byte{
+			"pinniped-storage-data":    []byte("not json"),
+			"pinniped-storage-version": []byte("1"),
+		},
+		Type: "storage.pinniped.dev/access-token",
+	}
+}
+
+func signatureSuffix(t *testing.T, secrets corev1client.SecretInterface, storageType string, id string) string {
+	t.Helper()
+
+	list, err := secrets.List(t.Context(), metav1.ListOptions{
+		LabelSelector: labels.Set{"storage.pinniped.dev/type": storageType, "storage.pinniped.dev/request-id": id}.String(),
+	})
+	require.NoError(t, err)
+	require.Len(t, list.Items, 1)
+	return list.Items[0].Name[len("pinniped-storage-"+storageType+"-"):]
+}
+
+func parseExpiry(t *testing.T, value string) metav1.Time {
+	t.Helper()
+
+	expiry, err := time.Parse(time.RFC3339, value)
+	require.NoError(t, err)
+	return metav1.NewTime(expiry)
+}
@@ -21,9 +21,11 @@ import (
 
 	configv1alpha1clientset "go.pinniped.dev/generated/latest/client/supervisor/clientset/versioned/typed/config/v1alpha1"
 	"go.pinniped.dev/internal/controllerinit"
+	"go.pinniped.dev/internal/crud"
 	"go.pinniped.dev/internal/plog"
 	"go.pinniped.dev/internal/pversion"
 	"go.pinniped.dev/internal/registry/clientsecretrequest"
+	"go.pinniped.dev/internal/registry/session"
 )
 
 type Config struct {
@@ -36,8 +38,11 @@ type ExtraConfig struct {
 	Scheme                             *runtime.Scheme
 	NegotiatedSerializer               runtime.NegotiatedSerializer
 	ClientSecretSupervisorGroupVersion schema.GroupVersion
+	SessionSupervisorGroupVersion      schema.GroupVersion
 	Secrets                            corev1client.SecretInterface
+	SessionStorage                     crud.SecretsBackend
 	OIDCClients                        configv1alpha1clientset.OIDCClientInterface
+	SessionRevoker                     session.SessionRevoker
 	Namespace                          string
 	AuditLogger                        plog.AuditLogger
 }
@@ -97,6 +102,16 @@ func (c completedConfig) New() (*PinnipedServer, error) {
 			)
 			return clientSecretReqGVR, clientSecretReqStorage
 		},
+		func() (schema.GroupVersionResource, rest.Storage) {
+			sessionGVR := c.ExtraConfig.SessionSupervisorGroupVersion.WithResource("sessions")
+			sessionStorage := session.NewREST(
+				sessionGVR.GroupResource(),
+				c.ExtraConfig.SessionStorage,
+				c.ExtraConfig.SessionRevoker,
+				c.ExtraConfig.Namespace,
+			)
+			return sessionGVR, sessionStorage
+		},
 	} {
 		gvr, storage := f()
 		errs = append(errs,
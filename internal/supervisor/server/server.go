@@ -33,6 +33,7 @@ import (
 	"k8s.io/apiserver/pkg/features"
 	genericapiserver "k8s.io/apiserver/pkg/server"
 	genericoptions "k8s.io/apiserver/pkg/server/options"
+	"k8s.io/apiserver/pkg/storage/value/encrypt/envelope/kmsv2"
 	k8sinformers "k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
 	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
@@ -54,12 +55,18 @@ import (
 	"go.pinniped.dev/internal/controller/supervisorconfig/activedirectoryupstreamwatcher"
 	"go.pinniped.dev/internal/controller/supervisorconfig/generator"
 	"go.pinniped.dev/internal/controller/supervisorconfig/githubupstreamwatcher"
+	"go.pinniped.dev/internal/controller/supervisorconfig/gitlabupstreamwatcher"
 	"go.pinniped.dev/internal/controller/supervisorconfig/ldapupstreamwatcher"
+	"go.pinniped.dev/internal/controller/supervisorconfig/oauth2upstreamwatcher"
 	"go.pinniped.dev/internal/controller/supervisorconfig/oidcclientwatcher"
 	"go.pinniped.dev/internal/controller/supervisorconfig/oidcupstreamwatcher"
+	"go.pinniped.dev/internal/controller/supervisorconfig/samlupstreamwatcher"
 	"go.pinniped.dev/internal/controller/supervisorstorage"
 	"go.pinniped.dev/internal/controllerinit"
 	"go.pinniped.dev/internal/controllerlib"
+	"go.pinniped.dev/internal/crud"
+	"go.pinniped.dev/internal/crud/boltbackend"
+	"go.pinniped.dev/internal/crud/envelope"
 	"go.pinniped.dev/internal/crypto/ptls"
 	"go.pinniped.dev/internal/deploymentref"
 	"go.pinniped.dev/internal/downward"
@@ -67,10 +74,14 @@ import (
 	"go.pinniped.dev/internal/federationdomain/dynamictlscertprovider"
 	"go.pinniped.dev/internal/federationdomain/dynamicupstreamprovider"
 	"go.pinniped.dev/internal/federationdomain/endpoints/jwks"
+	"go.pinniped.dev/internal/federationdomain/endpoints/registration"
 	"go.pinniped.dev/internal/federationdomain/endpointsmanager"
+	"go.pinniped.dev/internal/federationdomain/sessionrevocation"
 	"go.pinniped.dev/internal/groupsuffix"
 	"go.pinniped.dev/internal/kubeclient"
 	"go.pinniped.dev/internal/leaderelection"
+	"go.pinniped.dev/internal/metrics"
+	"go.pinniped.dev/internal/oidcclientsecretstorage"
 	"go.pinniped.dev/internal/plog"
 	"go.pinniped.dev/internal/pversion"
 	"go.pinniped.dev/internal/secret"
@@ -81,6 +92,7 @@ import (
 const (
 	singletonWorker       = 1
 	defaultResyncInterval = 3 * time.Minute
+	kmsCallTimeout        = 3 * time.Second
 )
 
 func startServer(ctx context.Context, shutdown *sync.WaitGroup, l net.Listener, handler http.Handler) {
@@ -151,27 +163,55 @@ func prepareControllers(
 	pinnipedInformers supervisorinformers.SharedInformerFactory,
 	leaderElector controllerinit.RunnerWrapper,
 	podInfo *downward.PodInfo,
+	sessionStorage crud.SecretsBackend,
+	sessionStorageEncryption *sessionStorageEncryption,
 	auditLogger plog.AuditLogger,
 ) controllerinit.RunnerBuilder {
 	const certificateName string = "pinniped-supervisor-api-tls-serving-certificate"
-	clientSecretSupervisorGroupData := groupsuffix.SupervisorAggregatedGroups(*cfg.APIGroupSuffix)
+	clientSecretSupervisorGroupData, sessionSupervisorGroupData := groupsuffix.SupervisorAggregatedGroups(*cfg.APIGroupSuffix)
 	federationDomainInformer := pinnipedInformers.Config().V1alpha1().FederationDomains()
 	oidcClientInformer := pinnipedInformers.Config().V1alpha1().OIDCClients()
 	secretInformer := kubeInformers.Core().V1().Secrets()
 	configMapInformer := kubeInformers.Core().V1().ConfigMaps()
 
+	// Writes to session storage which is kept in Secrets should only be performed by the leader.
+	leaderSessionStorage := sessionStorage
+	if !cfg.SessionStorage.UsesBolt() {
+		leaderSessionStorage = kubeClient.CoreV1().Secrets(podInfo.Namespace)
+	}
+
+	var sessionStorageDecrypter supervisorstorage.SessionStorageDecrypter
+	if sessionStorageEncryption != nil {
+		sessionStorageDecrypter = sessionStorageEncryption.transformer
+	}
+
+	// When session storage is not kept in Secrets, it cannot be watched by an informer, so it is swept periodically instead.
+	var garbageCollector controllerlib.Controller
+	if cfg.SessionStorage.UsesBolt() {
+		garbageCollector = supervisorstorage.SessionStorageGarbageCollectorController(
+			dynamicUpstreamIDPProvider,
+			clock.RealClock{},
+			leaderSessionStorage,
+			sessionStorageDecrypter,
+			auditLogger,
+		)
+	} else {
+		garbageCollector = supervisorstorage.GarbageCollectorController(
+			dynamicUpstreamIDPProvider,
+			clock.RealClock{},
+			kubeClient,
+			secretInformer,
+			controllerlib.WithInformer,
+			sessionStorageDecrypter,
+			auditLogger,
+		)
+	}
+
 	// Create controller manager.
 	controllerManager := controllerlib.
 		NewManager().
 		WithController(
-			supervisorstorage.GarbageCollectorController(
-				dynamicUpstreamIDPProvider,
-				clock.RealClock{},
-				kubeClient,
-				secretInformer,
-				controllerlib.WithInformer,
-				auditLogger,
-			),
+			garbageCollector,
 			singletonWorker,
 		).
 		WithController(
@@ -185,6 +225,10 @@ func prepareControllers(
 				pinnipedInformers.IDP().V1alpha1().LDAPIdentityProviders(),
 				pinnipedInformers.IDP().V1alpha1().ActiveDirectoryIdentityProviders(),
 				pinnipedInformers.IDP().V1alpha1().GitHubIdentityProviders(),
+				pinnipedInformers.IDP().V1alpha1().SAMLIdentityProviders(),
+				pinnipedInformers.IDP().V1alpha1().GitLabIdentityProviders(),
+				pinnipedInformers.IDP().V1alpha1().OAuth2IdentityProviders(),
+				configMapInformer,
 				controllerlib.WithInformer,
 			),
 			singletonWorker,
@@ -197,6 +241,7 @@ func prepareControllers(
 				secretInformer,
 				federationDomainInformer,
 				controllerlib.WithInformer,
+				clock.RealClock{},
 			),
 			singletonWorker,
 		).
@@ -206,6 +251,7 @@ func prepareControllers(
 				secretInformer,
 				federationDomainInformer,
 				controllerlib.WithInformer,
+				clock.RealClock{},
 			),
 			singletonWorker,
 		).
@@ -355,6 +401,48 @@ func prepareControllers(
 				cache.NewExpiring(),
 			),
 			singletonWorker).
+		WithController(
+			samlupstreamwatcher.New(
+				podInfo.Namespace,
+				dynamicUpstreamIDPProvider,
+				pinnipedClient,
+				pinnipedInformers.IDP().V1alpha1().SAMLIdentityProviders(),
+				secretInformer,
+				configMapInformer,
+				plog.New(),
+				controllerlib.WithInformer,
+				clock.RealClock{},
+				cache.NewExpiring(),
+			),
+			singletonWorker).
+		WithController(
+			gitlabupstreamwatcher.New(
+				podInfo.Namespace,
+				dynamicUpstreamIDPProvider,
+				pinnipedClient,
+				pinnipedInformers.IDP().V1alpha1().GitLabIdentityProviders(),
+				secretInformer,
+				configMapInformer,
+				plog.New(),
+				controllerlib.WithInformer,
+				clock.RealClock{},
+				gitlabupstreamwatcher.ProbeURL,
+				cache.NewExpiring(),
+			),
+			singletonWorker).
+		WithController(
+			oauth2upstreamwatcher.New(
+				podInfo.Namespace,
+				dynamicUpstreamIDPProvider,
+				pinnipedClient,
+				pinnipedInformers.IDP().V1alpha1().OAuth2IdentityProviders(),
+				secretInformer,
+				configMapInformer,
+				plog.New(),
+				controllerlib.WithInformer,
+				clock.RealClock{},
+			),
+			singletonWorker).
 		WithController(
 			apicerts.NewCertsManagerController(
 				podInfo.Namespace,
@@ -381,6 +469,17 @@ func prepareControllers(
 			),
 			singletonWorker,
 		).
+		WithController(
+			apicerts.NewAPIServiceUpdaterController(
+				podInfo.Namespace,
+				certificateName,
+				sessionSupervisorGroupData.APIServiceName(),
+				aggregatorClient,
+				secretInformer,
+				controllerlib.WithInformer,
+			),
+			singletonWorker,
+		).
 		WithController(
 			apicerts.NewCertsObserverController(
 				podInfo.Namespace,
@@ -410,10 +509,34 @@ func prepareControllers(
 				secretInformer,
 				oidcClientInformer,
 				controllerlib.WithInformer,
+				cfg.Endpoints.ClientCertificatesRequested(),
 			),
 			singletonWorker,
 		)
 
+	if sessionStorageEncryption != nil {
+		controllerManager = controllerManager.WithController(
+			supervisorstorage.KEKRotationController(
+				sessionStorageEncryption.transformer,
+				leaderSessionStorage,
+			),
+			singletonWorker,
+		)
+		if sessionStorageEncryption.secretKEKProvider != nil {
+			controllerManager = controllerManager.WithController(
+				supervisorstorage.KEKSecretWatcherController(
+					podInfo.Namespace,
+					cfg.SessionStorage.Encryption.KEKSecretName,
+					sessionStorageEncryption.secretKEKProvider,
+					secretInformer,
+					controllerlib.WithInformer,
+					controllerlib.WithInitialEvent,
+				),
+				singletonWorker,
+			)
+		}
+	}
+
 	return controllerinit.Prepare(controllerManager.Start, leaderElector, kubeInformers, pinnipedInformers)
 }
 
@@ -427,7 +550,7 @@ func runSupervisor(ctx context.Context, podInfo *downward.PodInfo, cfg *supervis
 	featuregates.DisableKubeFeatureGate(features.UnauthenticatedHTTP2DOSMitigation)
 
 	serverInstallationNamespace := podInfo.Namespace
-	clientSecretSupervisorGroupData := groupsuffix.SupervisorAggregatedGroups(*cfg.APIGroupSuffix)
+	clientSecretSupervisorGroupData, _ := groupsuffix.SupervisorAggregatedGroups(*cfg.APIGroupSuffix)
 
 	apiServiceRef, err := apiserviceref.New(clientSecretSupervisorGroupData.APIServiceName())
 	if err != nil {
@@ -439,6 +562,13 @@ func runSupervisor(ctx context.Context, podInfo *downward.PodInfo, cfg *supervis
 		return fmt.Errorf("cannot create deployment ref: %w", err)
 	}
 
+	// Only one process may open the bolt database file at a time, so refuse to run more than one replica,
+	// e.g. when the Deployment was scaled up after installation.
+	if cfg.SessionStorage.UsesBolt() && supervisorDeployment.Spec.Replicas != nil && *supervisorDeployment.Spec.Replicas > 1 {
+		return fmt.Errorf("session storage backend %q requires the Supervisor to run as a single replica, but its Deployment has %d replicas",
+			supervisor.SessionStorageBackendBolt, *supervisorDeployment.Spec.Replicas)
+	}
+
 	opts := []kubeclient.Option{
 		dref,
 		apiServiceRef,
@@ -481,6 +611,25 @@ func runSupervisor(ctx context.Context, podInfo *downward.PodInfo, cfg *supervis
 		_, _ = w.Write([]byte("ok"))
 	}))
 
+	secretsClient := clientWithoutLeaderElection.Kubernetes.CoreV1().Secrets(serverInstallationNamespace) // writes to kube storage are allowed for non-leaders
+
+	sessionStorage, closeSessionStorage, err := openSessionStorage(ctx, &cfg.SessionStorage, secretsClient)
+	if err != nil {
+		return fmt.Errorf("cannot open session storage: %w", err)
+	}
+	defer closeSessionStorage()
+
+	sessionStorageEncryption, err := newSessionStorageEncryption(ctx, cfg.SessionStorage.Encryption)
+	if err != nil {
+		return fmt.Errorf("cannot configure session storage encryption: %w", err)
+	}
+	// Other controllers read and write the session storage exactly as it is stored, but everything else
+	// reads and writes it through the encryption.
+	rawSessionStorage := sessionStorage
+	if sessionStorageEncryption != nil {
+		sessionStorage = envelope.NewSecretsBackend(sessionStorage, sessionStorageEncryption.transformer)
+	}
+
 	dynamicServingCertProvider := dynamiccert.NewServingCert("supervisor-serving-cert")
 
 	dynamicJWKSProvider := jwks.NewDynamicJWKSProvider()
@@ -488,21 +637,35 @@ func runSupervisor(ctx context.Context, podInfo *downward.PodInfo, cfg *supervis
 	dynamicUpstreamIDPProvider := dynamicupstreamprovider.NewDynamicUpstreamIDPProvider()
 	secretCache := secret.Cache{}
 
+	// Dynamic client registration may authenticate callers using their Kubernetes credentials, in which case they
+	// may register clients when they are allowed to create OIDCClients.
+	oidcClientsAPIGroup, _ := groupsuffix.Replace(supervisorconfigv1alpha1.GroupName, *cfg.APIGroupSuffix)
+	registrationCallerAuthorizer := registration.NewKubeCallerAuthorizer(
+		clientWithoutLeaderElection.Kubernetes.AuthenticationV1().TokenReviews(),
+		clientWithoutLeaderElection.Kubernetes.AuthorizationV1().SubjectAccessReviews(),
+		serverInstallationNamespace,
+		oidcClientsAPIGroup,
+	)
+
 	// OIDC endpoints will be served by the endpoints manager, and any non-OIDC paths will fallback to the healthMux.
 	oidProvidersManager := endpointsmanager.NewManager(
 		healthMux,
 		dynamicJWKSProvider,
 		dynamicUpstreamIDPProvider,
 		&secretCache,
-		clientWithoutLeaderElection.Kubernetes.CoreV1().Secrets(serverInstallationNamespace), // writes to kube storage are allowed for non-leaders
-		client.PinnipedSupervisor.ConfigV1alpha1().OIDCClients(serverInstallationNamespace),
+		secretsClient,
+		sessionStorage,
+		// Dynamic client registration creates OIDCClients, so writes must be allowed for non-leaders.
+		clientWithoutLeaderElection.PinnipedSupervisor.ConfigV1alpha1().OIDCClients(serverInstallationNamespace),
+		registrationCallerAuthorizer,
 		auditLogger,
 		cfg.Audit.LogInternalPaths,
+		cfg.Endpoints.ClientCertificatesRequested(),
 	)
 
-	// Get the "real" name of the client secret supervisor API group (i.e., the API group name with the
+	// Get the "real" names of the supervisor API groups (i.e., the API group names with the
 	// injected suffix).
-	scheme, clientSecretGV := supervisorscheme.New(*cfg.APIGroupSuffix)
+	scheme, clientSecretGV, sessionGV := supervisorscheme.New(*cfg.APIGroupSuffix)
 
 	buildControllersFunc := prepareControllers(
 		cfg,
@@ -520,6 +683,8 @@ func runSupervisor(ctx context.Context, podInfo *downward.PodInfo, cfg *supervis
 		pinnipedInformers,
 		leaderElector,
 		podInfo,
+		rawSessionStorage,
+		sessionStorageEncryption,
 		auditLogger,
 	)
 
@@ -533,8 +698,15 @@ func runSupervisor(ctx context.Context, podInfo *downward.PodInfo, cfg *supervis
 		*cfg.AggregatedAPIServerPort,
 		scheme,
 		clientSecretGV,
-		clientWithoutLeaderElection.Kubernetes.CoreV1().Secrets(serverInstallationNamespace),
+		sessionGV,
+		secretsClient,
+		sessionStorage,
 		client.PinnipedSupervisor.ConfigV1alpha1().OIDCClients(serverInstallationNamespace),
+		sessionrevocation.New(
+			sessionStorage,
+			dynamicUpstreamIDPProvider,
+			auditLogger,
+		),
 		serverInstallationNamespace,
 		auditLogger,
 		cfg.AggregatedAPIServerDisableAdmissionPlugins,
@@ -610,6 +782,13 @@ func runSupervisor(ctx context.Context, podInfo *downward.PodInfo, cfg *supervis
 			return cert, nil
 		}
 
+		if e.RequestClientCertificates {
+			// Ask for, but do not require or verify, TLS client certificates. They are verified later by the
+			// token endpoint when an OIDCClient authenticates using the tls_client_auth method. Note that this
+			// listener also serves the browser-based login pages, so browsers may prompt users to choose a certificate.
+			c.ClientAuth = tls.RequestClientCert
+		}
+
 		httpsListener, err := tls.Listen(e.Network, e.Address, c)
 		if err != nil {
 			return fmt.Errorf("cannot create https listener with network %q and address %q: %w", e.Network, e.Address, err)
@@ -624,6 +803,12 @@ func runSupervisor(ctx context.Context, podInfo *downward.PodInfo, cfg *supervis
 		plog.Debug("supervisor https listener started", "address", httpsListener.Addr().String())
 	}
 
+	if cfg.Metrics.Port != nil {
+		if err := metrics.Serve(ctx, *cfg.Metrics.Port); err != nil {
+			return err
+		}
+	}
+
 	plog.Debug("supervisor started")
 	defer plog.Debug("supervisor exiting")
 
@@ -646,8 +831,11 @@ func getAggregatedAPIServerConfig(
 	aggregatedAPIServerPort int64,
 	scheme *runtime.Scheme,
 	clientSecretSupervisorGroupVersion schema.GroupVersion,
+	sessionSupervisorGroupVersion schema.GroupVersion,
 	secrets corev1client.SecretInterface,
+	sessionStorage crud.SecretsBackend,
 	oidcClients v1alpha1.OIDCClientInterface,
+	sessionRevoker *sessionrevocation.Revoker,
 	serverInstallationNamespace string,
 	auditLogger plog.AuditLogger,
 	disableAdmissionPlugins []string,
@@ -714,8 +902,11 @@ func getAggregatedAPIServerConfig(
 			Scheme:                             scheme,
 			NegotiatedSerializer:               codecs,
 			ClientSecretSupervisorGroupVersion: clientSecretSupervisorGroupVersion,
+			SessionSupervisorGroupVersion:      sessionSupervisorGroupVersion,
 			Secrets:                            secrets,
+			SessionStorage:                     sessionStorage,
 			OIDCClients:                        oidcClients,
+			SessionRevoker:                     sessionRevoker,
 			Namespace:                          serverInstallationNamespace,
 			AuditLogger:                        auditLogger,
 		},
@@ -723,6 +914,74 @@ func getAggregatedAPIServerConfig(
 	return apiServerConfig, nil
 }
 
+// openSessionStorage returns the configured storage for downstream sessions, along with a func to close it.
+// When the sessions are stored somewhere other than in Secrets, any sessions which are still stored in Secrets
+// (e.g. from before the configuration was changed) are moved into the configured storage.
+func openSessionStorage(
+	ctx context.Context,
+	sessionStorageConfig *supervisor.SessionStorageSpec,
+	secrets corev1client.SecretInterface,
+) (crud.SecretsBackend, func(), error) {
+	if !sessionStorageConfig.UsesBolt() {
+		return secrets, func() {}, nil
+	}
+
+	key, err := os.ReadFile(sessionStorageConfig.Bolt.EncryptionKeyPath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not read encryption key: %w", err)
+	}
+
+	backend, err := boltbackend.New(sessionStorageConfig.Bolt.Path, key, time.Now)
+	if err != nil {
+		return nil, nil, err
+	}
+	closeFunc := func() {
+		if err := backend.Close(); err != nil {
+			plog.WarningErr("error closing session storage", err)
+		}
+	}
+
+	// OIDCClient secrets always stay in Secrets.
+	migrated, err := boltbackend.MigrateFromSecrets(ctx, secrets, backend, oidcclientsecretstorage.TypeLabelValue)
+	if err != nil {
+		closeFunc()
+		return nil, nil, fmt.Errorf("could not migrate session storage from Secrets: %w", err)
+	}
+	plog.Info("opened bolt session storage", "path", sessionStorageConfig.Bolt.Path, "migratedFromSecrets", migrated)
+
+	return backend, closeFunc, nil
+}
+
+// sessionStorageEncryption holds what is needed to encrypt session storage.
+type sessionStorageEncryption struct {
+	transformer *envelope.Transformer
+	// secretKEKProvider is only set when the key encryption keys are loaded from a Secret.
+	secretKEKProvider *envelope.SecretKEKProvider
+}
+
+// newSessionStorageEncryption returns nil when session storage encryption is not configured.
+func newSessionStorageEncryption(ctx context.Context, encryptionConfig *supervisor.SessionStorageEncryptionSpec) (*sessionStorageEncryption, error) {
+	if encryptionConfig == nil {
+		return nil, nil
+	}
+
+	if encryptionConfig.KMS != nil {
+		service, err := kmsv2.NewGRPCService(ctx, encryptionConfig.KMS.Endpoint, "pinniped-supervisor-session-storage", kmsCallTimeout)
+		if err != nil {
+			return nil, fmt.Errorf("could not connect to kms plugin: %w", err)
+		}
+		return &sessionStorageEncryption{
+			transformer: envelope.NewTransformer(envelope.NewKMSKEKProvider(service)),
+		}, nil
+	}
+
+	secretKEKProvider := envelope.NewSecretKEKProvider()
+	return &sessionStorageEncryption{
+		transformer:       envelope.NewTransformer(secretKEKProvider),
+		secretKEKProvider: secretKEKProvider,
+	}, nil
+}
+
 func maybeSetupUnixPerms(endpoint *supervisor.Endpoint, pod *corev1.Pod) func() error {
 	if endpoint.Network != supervisor.NetworkUnix {
 		return func() error { return nil }
@@ -1,4 +1,4 @@
-// Copyright 2020-2025 the Pinniped contributors. All Rights Reserved.
+// Copyright 2020-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package oidctestutil
@@ -199,7 +199,9 @@ func validateAuthcodeStorage(
 
 	// Should always have an azp claim.
 	require.Equal(t, wantDownstreamClientID, actualClaims.Extra["azp"])
-	wantDownstreamIDTokenExtraClaimsCount := 1 // should always have azp claim
+	// Should always have a sid claim, which is the ID of the stored request.
+	require.Equal(t, storedRequestFromAuthcode.ID, actualClaims.Extra["sid"])
+	wantDownstreamIDTokenExtraClaimsCount := 2 // should always have azp and sid claims
 
 	if len(wantDownstreamAdditionalClaims) > 0 {
 		wantDownstreamIDTokenExtraClaimsCount++
@@ -327,7 +329,8 @@ func castStoredAuthorizeRequest(t *testing.T, storedAuthorizeRequest fosite.Requ
 
 // FilterClientSecretCreateActions ignores any reads made to get a storage secret corresponding to an OIDCClient, since these
 // are normal actions when the request is using a dynamic client's client_id, and we don't need to make assertions
-// about these Secrets since they are not related to session storage.
+// about these Secrets since they are not related to session storage. It also ignores the reads and deletes made to
+// consume a pushed authorization request, since these are normal actions when the request is using a request_uri.
 func FilterClientSecretCreateActions(actions []kubetesting.Action) []kubetesting.Action {
 	filtered := make([]kubetesting.Action, 0, len(actions))
 	for _, action := range actions {
@@ -336,6 +339,15 @@ func FilterClientSecretCreateActions(actions []kubetesting.Action) []kubetesting
 			if strings.HasPrefix(getAction.GetName(), "pinniped-storage-oidc-client-secret-") {
 				continue // filter out OIDCClient's storage secret reads
 			}
+			if strings.HasPrefix(getAction.GetName(), "pinniped-storage-pushed-authorization-request-") {
+				continue // filter out pushed authorization request reads
+			}
+		}
+		if action.Matches("delete", "secrets") {
+			deleteAction := action.(kubetesting.DeleteAction)
+			if strings.HasPrefix(deleteAction.GetName(), "pinniped-storage-pushed-authorization-request-") {
+				continue // filter out pushed authorization request deletes, since they can only be used once
+			}
 		}
 		filtered = append(filtered, action) // otherwise include the action
 	}
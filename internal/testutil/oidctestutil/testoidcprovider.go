@@ -1,4 +1,4 @@
-// Copyright 2020-2024 the Pinniped contributors. All Rights Reserved.
+// Copyright 2020-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package oidctestutil
@@ -11,6 +11,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 
+	"go.pinniped.dev/internal/celtransformer"
 	"go.pinniped.dev/internal/federationdomain/upstreamprovider"
 	"go.pinniped.dev/internal/idtransform"
 	"go.pinniped.dev/pkg/oidcclient/nonce"
@@ -77,6 +78,8 @@ type TestUpstreamOIDCIdentityProvider struct {
 	DisplayNameForFederationDomain string
 	TransformsForFederationDomain  *idtransform.TransformationPipeline
 
+	AuthenticationPolicyForFederationDomain *celtransformer.CompiledUpstreamAuthenticationPolicy
+
 	ExchangeAuthcodeAndValidateTokensFunc func(
 		ctx context.Context,
 		authcode string,
@@ -309,6 +312,7 @@ type TestUpstreamOIDCIdentityProviderBuilder struct {
 	validateTokenAndMergeWithUserInfoErr error
 	displayNameForFederationDomain       string
 	transformsForFederationDomain        *idtransform.TransformationPipeline
+	authenticationPolicy                 *celtransformer.CompiledUpstreamAuthenticationPolicy
 }
 
 func (u *TestUpstreamOIDCIdentityProviderBuilder) WithName(value string) *TestUpstreamOIDCIdentityProviderBuilder {
@@ -468,6 +472,11 @@ func (u *TestUpstreamOIDCIdentityProviderBuilder) WithTransformsForFederationDom
 	return u
 }
 
+func (u *TestUpstreamOIDCIdentityProviderBuilder) WithAuthenticationPolicyForFederationDomain(policy *celtransformer.CompiledUpstreamAuthenticationPolicy) *TestUpstreamOIDCIdentityProviderBuilder {
+	u.authenticationPolicy = policy
+	return u
+}
+
 func (u *TestUpstreamOIDCIdentityProviderBuilder) Build() *TestUpstreamOIDCIdentityProvider {
 	if u.displayNameForFederationDomain == "" {
 		// default it to the CR name
@@ -479,19 +488,20 @@ func (u *TestUpstreamOIDCIdentityProviderBuilder) Build() *TestUpstreamOIDCIdent
 	}
 
 	return &TestUpstreamOIDCIdentityProvider{
-		Name:                           u.name,
-		ClientID:                       u.clientID,
-		ResourceUID:                    u.resourceUID,
-		UsernameClaim:                  u.usernameClaim,
-		GroupsClaim:                    u.groupsClaim,
-		Scopes:                         u.scopes,
-		AllowPasswordGrant:             u.allowPasswordGrant,
-		AuthorizationURL:               u.authorizationURL,
-		UserInfoURL:                    u.hasUserInfoURL,
-		AdditionalAuthcodeParams:       u.additionalAuthcodeParams,
-		AdditionalClaimMappings:        u.additionalClaimMappings,
-		DisplayNameForFederationDomain: u.displayNameForFederationDomain,
-		TransformsForFederationDomain:  u.transformsForFederationDomain,
+		Name:                                    u.name,
+		ClientID:                                u.clientID,
+		ResourceUID:                             u.resourceUID,
+		UsernameClaim:                           u.usernameClaim,
+		GroupsClaim:                             u.groupsClaim,
+		Scopes:                                  u.scopes,
+		AllowPasswordGrant:                      u.allowPasswordGrant,
+		AuthorizationURL:                        u.authorizationURL,
+		UserInfoURL:                             u.hasUserInfoURL,
+		AdditionalAuthcodeParams:                u.additionalAuthcodeParams,
+		AdditionalClaimMappings:                 u.additionalClaimMappings,
+		DisplayNameForFederationDomain:          u.displayNameForFederationDomain,
+		TransformsForFederationDomain:           u.transformsForFederationDomain,
+		AuthenticationPolicyForFederationDomain: u.authenticationPolicy,
 		ExchangeAuthcodeAndValidateTokensFunc: func(ctx context.Context, authcode string, pkceCodeVerifier oidcpkce.Code, expectedIDTokenNonce nonce.Nonce) (*oidctypes.Token, error) {
 			if u.authcodeExchangeErr != nil {
 				return nil, u.authcodeExchangeErr
@@ -0,0 +1,198 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package oidctestutil
+
+import (
+	"fmt"
+	"net/url"
+
+	"k8s.io/apimachinery/pkg/types"
+
+	"go.pinniped.dev/internal/federationdomain/upstreamprovider"
+	"go.pinniped.dev/internal/idtransform"
+)
+
+// ParseSAMLResponseArgs is used to spy on calls to
+// TestUpstreamSAMLIdentityProvider.ParseResponseFunc().
+type ParseSAMLResponseArgs struct {
+	EncodedResponse   string
+	RequestID         string
+	ACSURL            string
+	DefaultSPEntityID string
+	IDPDisplayName    string
+}
+
+type TestUpstreamSAMLIdentityProviderBuilder struct {
+	name                           string
+	resourceUID                    types.UID
+	idpEntityID                    string
+	ssoURL                         string
+	usernameAttribute              string
+	groupsAttribute                string
+	displayNameForFederationDomain string
+	transformsForFederationDomain  *idtransform.TransformationPipeline
+	parseResponseErr               error
+	parseResponseUser              *upstreamprovider.SAMLUser
+}
+
+func (u *TestUpstreamSAMLIdentityProviderBuilder) WithName(value string) *TestUpstreamSAMLIdentityProviderBuilder {
+	u.name = value
+	return u
+}
+
+func (u *TestUpstreamSAMLIdentityProviderBuilder) WithResourceUID(value types.UID) *TestUpstreamSAMLIdentityProviderBuilder {
+	u.resourceUID = value
+	return u
+}
+
+func (u *TestUpstreamSAMLIdentityProviderBuilder) WithIDPEntityID(value string) *TestUpstreamSAMLIdentityProviderBuilder {
+	u.idpEntityID = value
+	return u
+}
+
+func (u *TestUpstreamSAMLIdentityProviderBuilder) WithSSOURL(value string) *TestUpstreamSAMLIdentityProviderBuilder {
+	u.ssoURL = value
+	return u
+}
+
+func (u *TestUpstreamSAMLIdentityProviderBuilder) WithUsernameAttribute(value string) *TestUpstreamSAMLIdentityProviderBuilder {
+	u.usernameAttribute = value
+	return u
+}
+
+func (u *TestUpstreamSAMLIdentityProviderBuilder) WithGroupsAttribute(value string) *TestUpstreamSAMLIdentityProviderBuilder {
+	u.groupsAttribute = value
+	return u
+}
+
+func (u *TestUpstreamSAMLIdentityProviderBuilder) WithDisplayNameForFederationDomain(value string) *TestUpstreamSAMLIdentityProviderBuilder {
+	u.displayNameForFederationDomain = value
+	return u
+}
+
+func (u *TestUpstreamSAMLIdentityProviderBuilder) WithTransformsForFederationDomain(transforms *idtransform.TransformationPipeline) *TestUpstreamSAMLIdentityProviderBuilder {
+	u.transformsForFederationDomain = transforms
+	return u
+}
+
+func (u *TestUpstreamSAMLIdentityProviderBuilder) WithUser(user *upstreamprovider.SAMLUser) *TestUpstreamSAMLIdentityProviderBuilder {
+	u.parseResponseUser = user
+	return u
+}
+
+func (u *TestUpstreamSAMLIdentityProviderBuilder) WithParseResponseError(err error) *TestUpstreamSAMLIdentityProviderBuilder {
+	u.parseResponseErr = err
+	return u
+}
+
+func (u *TestUpstreamSAMLIdentityProviderBuilder) Build() *TestUpstreamSAMLIdentityProvider {
+	if u.displayNameForFederationDomain == "" {
+		// default it to the CR name
+		u.displayNameForFederationDomain = u.name
+	}
+	if u.transformsForFederationDomain == nil {
+		// default to an empty pipeline
+		u.transformsForFederationDomain = idtransform.NewTransformationPipeline()
+	}
+	return &TestUpstreamSAMLIdentityProvider{
+		Name:                           u.name,
+		ResourceUID:                    u.resourceUID,
+		IDPEntityID:                    u.idpEntityID,
+		SSOURL:                         u.ssoURL,
+		UsernameAttribute:              u.usernameAttribute,
+		GroupsAttribute:                u.groupsAttribute,
+		DisplayNameForFederationDomain: u.displayNameForFederationDomain,
+		TransformsForFederationDomain:  u.transformsForFederationDomain,
+		ParseResponseFunc: func(encodedResponse string) (*upstreamprovider.SAMLUser, error) {
+			if u.parseResponseErr != nil {
+				return nil, u.parseResponseErr
+			}
+			return u.parseResponseUser, nil
+		},
+	}
+}
+
+func NewTestUpstreamSAMLIdentityProviderBuilder() *TestUpstreamSAMLIdentityProviderBuilder {
+	return &TestUpstreamSAMLIdentityProviderBuilder{}
+}
+
+type TestUpstreamSAMLIdentityProvider struct {
+	Name                           string
+	ResourceUID                    types.UID
+	IDPEntityID                    string
+	SSOURL                         string
+	UsernameAttribute              string
+	GroupsAttribute                string
+	DisplayNameForFederationDomain string
+	TransformsForFederationDomain  *idtransform.TransformationPipeline
+	ParseResponseFunc              func(encodedResponse string) (*upstreamprovider.SAMLUser, error)
+
+	// Fields for tracking actual calls make to mock functions.
+	parseResponseCallCount int
+	parseResponseArgs      []*ParseSAMLResponseArgs
+}
+
+var _ upstreamprovider.UpstreamSAMLIdentityProviderI = &TestUpstreamSAMLIdentityProvider{}
+
+func (u *TestUpstreamSAMLIdentityProvider) GetResourceUID() types.UID {
+	return u.ResourceUID
+}
+
+func (u *TestUpstreamSAMLIdentityProvider) GetResourceName() string {
+	return u.Name
+}
+
+func (u *TestUpstreamSAMLIdentityProvider) GetIDPEntityID() string {
+	return u.IDPEntityID
+}
+
+func (u *TestUpstreamSAMLIdentityProvider) GetUsernameAttribute() string {
+	return u.UsernameAttribute
+}
+
+func (u *TestUpstreamSAMLIdentityProvider) GetGroupsAttribute() string {
+	return u.GroupsAttribute
+}
+
+// AuthnRequestURL returns a predictable fake URL, since real AuthnRequests are compressed and signed
+// in ways that are inconvenient to assert upon in tests.
+func (u *TestUpstreamSAMLIdentityProvider) AuthnRequestURL(requestID string, relayState string, acsURL string, defaultSPEntityID string) (string, error) {
+	return fmt.Sprintf("%s?SAMLRequest=%s&RelayState=%s",
+		u.SSOURL,
+		url.QueryEscape(fmt.Sprintf("fake-authn-request(id=%s,acs=%s,sp=%s)", requestID, acsURL, defaultSPEntityID)),
+		url.QueryEscape(relayState),
+	), nil
+}
+
+func (u *TestUpstreamSAMLIdentityProvider) ParseResponse(
+	encodedResponse string,
+	requestID string,
+	acsURL string,
+	defaultSPEntityID string,
+	idpDisplayName string,
+) (*upstreamprovider.SAMLUser, error) {
+	if u.parseResponseArgs == nil {
+		u.parseResponseArgs = make([]*ParseSAMLResponseArgs, 0)
+	}
+	u.parseResponseCallCount++
+	u.parseResponseArgs = append(u.parseResponseArgs, &ParseSAMLResponseArgs{
+		EncodedResponse:   encodedResponse,
+		RequestID:         requestID,
+		ACSURL:            acsURL,
+		DefaultSPEntityID: defaultSPEntityID,
+		IDPDisplayName:    idpDisplayName,
+	})
+	return u.ParseResponseFunc(encodedResponse)
+}
+
+func (u *TestUpstreamSAMLIdentityProvider) ParseResponseCallCount() int {
+	return u.parseResponseCallCount
+}
+
+func (u *TestUpstreamSAMLIdentityProvider) ParseResponseArgs(call int) *ParseSAMLResponseArgs {
+	if u.parseResponseArgs == nil {
+		u.parseResponseArgs = make([]*ParseSAMLResponseArgs, 0)
+	}
+	return u.parseResponseArgs[call]
+}
@@ -0,0 +1,178 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package samltestutil provides a local stand-in for a SAML identity provider, for use in tests.
+package samltestutil
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/xml"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/crewjam/saml"
+	"github.com/crewjam/saml/logger"
+	"github.com/stretchr/testify/require"
+)
+
+// IDP is a local stand-in for an upstream SAML identity provider. It can validate the AuthnRequests created
+// by the Supervisor and respond with signed SAML responses, without any browser or network interaction.
+type IDP struct {
+	t   *testing.T
+	idp *saml.IdentityProvider
+
+	// serviceProviders maps the entity ID of each registered service provider to its ACS URL.
+	serviceProviders map[string]string
+
+	// Session is the user session which the stand-in IDP will use to create assertions.
+	// Tests may change this at any time before calling Login.
+	Session *saml.Session
+
+	// SessionNotOnOrAfter, when not nil, will be set on the AuthnStatement of the assertions.
+	SessionNotOnOrAfter *time.Time
+
+	// MutateAssertion, when not nil, will be called to allow tests to change the assertion before it is signed.
+	MutateAssertion func(assertion *saml.Assertion)
+}
+
+// NewIDP creates a stand-in IDP which claims to be hosted at baseURL, e.g. "https://idp.example.com".
+// Its entity ID will be baseURL + "/metadata" and its single sign-on service will be baseURL + "/sso".
+func NewIDP(t *testing.T, baseURL string) *IDP {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "test-saml-idp"},
+		NotBefore:    now.Add(-time.Hour),
+		NotAfter:     now.Add(24 * time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+	}
+	certDER, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(certDER)
+	require.NoError(t, err)
+
+	metadataURL, err := url.Parse(baseURL + "/metadata")
+	require.NoError(t, err)
+	ssoURL, err := url.Parse(baseURL + "/sso")
+	require.NoError(t, err)
+
+	i := &IDP{
+		t:                t,
+		serviceProviders: map[string]string{},
+		Session: &saml.Session{
+			ID:         "some-session-id",
+			CreateTime: now,
+			ExpireTime: now.Add(time.Hour),
+			Index:      "some-session-index",
+			NameID:     "some-name-id",
+		},
+	}
+
+	i.idp = &saml.IdentityProvider{
+		Key:                     key,
+		Certificate:             cert,
+		Logger:                  logger.DefaultLogger,
+		MetadataURL:             *metadataURL,
+		SSOURL:                  *ssoURL,
+		ServiceProviderProvider: i,
+		SessionProvider:         i,
+		AssertionMaker:          i,
+	}
+
+	return i
+}
+
+// RegisterServiceProvider tells the stand-in IDP to accept AuthnRequests from the service provider with
+// the given entity ID, and to send SAML responses for that service provider to the given ACS URL.
+func (i *IDP) RegisterServiceProvider(entityID string, acsURL string) {
+	i.serviceProviders[entityID] = acsURL
+}
+
+// EntityID returns the entity ID of the stand-in IDP.
+func (i *IDP) EntityID() string {
+	return i.idp.Metadata().EntityID
+}
+
+// MetadataXML returns the SAML metadata document of the stand-in IDP.
+func (i *IDP) MetadataXML() []byte {
+	i.t.Helper()
+	metadata, err := xml.MarshalIndent(i.idp.Metadata(), "", "  ")
+	require.NoError(i.t, err)
+	return metadata
+}
+
+// MetadataHandler returns an http.Handler which serves the SAML metadata document of the stand-in IDP.
+func (i *IDP) MetadataHandler() http.Handler {
+	return http.HandlerFunc(i.idp.ServeMetadata)
+}
+
+// Login simulates a browser following the given redirect to the single sign-on service of the stand-in IDP,
+// and the user successfully logging in. It returns the SAML response and the relay state which the IDP
+// would have caused the browser to POST to the ACS URL of the service provider.
+func (i *IDP) Login(authnRequestURL string) (samlResponse string, relayState string) {
+	i.t.Helper()
+
+	req := httptest.NewRequest(http.MethodGet, authnRequestURL, nil)
+	idpAuthnRequest, err := saml.NewIdpAuthnRequest(i.idp, req)
+	require.NoError(i.t, err)
+	require.NoError(i.t, idpAuthnRequest.Validate())
+
+	require.NoError(i.t, i.MakeAssertion(idpAuthnRequest, i.Session))
+
+	form, err := idpAuthnRequest.PostBinding()
+	require.NoError(i.t, err)
+
+	return form.SAMLResponse, form.RelayState
+}
+
+// GetServiceProvider implements saml.ServiceProviderProvider.
+func (i *IDP) GetServiceProvider(_ *http.Request, serviceProviderID string) (*saml.EntityDescriptor, error) {
+	acsURL, ok := i.serviceProviders[serviceProviderID]
+	if !ok {
+		return nil, os.ErrNotExist
+	}
+	return &saml.EntityDescriptor{
+		EntityID: serviceProviderID,
+		SPSSODescriptors: []saml.SPSSODescriptor{{
+			AssertionConsumerServices: []saml.IndexedEndpoint{{
+				Binding:  saml.HTTPPostBinding,
+				Location: acsURL,
+				Index:    1,
+			}},
+		}},
+	}, nil
+}
+
+// GetSession implements saml.SessionProvider.
+func (i *IDP) GetSession(_ http.ResponseWriter, _ *http.Request, _ *saml.IdpAuthnRequest) *saml.Session {
+	return i.Session
+}
+
+// MakeAssertion implements saml.AssertionMaker.
+func (i *IDP) MakeAssertion(req *saml.IdpAuthnRequest, session *saml.Session) error {
+	if err := (saml.DefaultAssertionMaker{}).MakeAssertion(req, session); err != nil {
+		return err
+	}
+	if i.SessionNotOnOrAfter != nil {
+		for idx := range req.Assertion.AuthnStatements {
+			req.Assertion.AuthnStatements[idx].SessionNotOnOrAfter = i.SessionNotOnOrAfter
+		}
+	}
+	if i.MutateAssertion != nil {
+		i.MutateAssertion(req.Assertion)
+	}
+	return nil
+}
@@ -1,4 +1,4 @@
-// Copyright 2023-2024 the Pinniped contributors. All Rights Reserved.
+// Copyright 2023-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package transformtestutil
@@ -73,3 +73,15 @@ func NewPipeline(t *testing.T, transforms []celtransformer.CELTransformation) *i
 
 	return p
 }
+
+func NewUpstreamAuthenticationPolicy(t *testing.T, policy *celtransformer.UpstreamAuthenticationPolicy) *celtransformer.CompiledUpstreamAuthenticationPolicy {
+	t.Helper()
+
+	transformer, err := celtransformer.NewCELTransformer(5 * time.Second)
+	require.NoError(t, err)
+
+	compiledPolicy, err := transformer.CompileUpstreamAuthenticationPolicy(policy)
+	require.NoError(t, err)
+
+	return compiledPolicy
+}
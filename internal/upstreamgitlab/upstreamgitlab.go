@@ -0,0 +1,209 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package upstreamgitlab implements an abstraction of upstream GitLab provider interactions.
+package upstreamgitlab
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	coreosoidc "github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/oauth2"
+	"k8s.io/apimachinery/pkg/types"
+
+	idpv1alpha1 "go.pinniped.dev/generated/latest/apis/supervisor/idp/v1alpha1"
+	"go.pinniped.dev/internal/federationdomain/downstreamsubject"
+	"go.pinniped.dev/internal/federationdomain/upstreamprovider"
+	"go.pinniped.dev/internal/gitlabclient"
+	"go.pinniped.dev/internal/metrics"
+	"go.pinniped.dev/internal/plog"
+	"go.pinniped.dev/internal/setutil"
+	"go.pinniped.dev/pkg/oidcclient/pkce"
+)
+
+// ProviderConfig holds the active configuration of an upstream GitLab provider.
+type ProviderConfig struct {
+	Name        string
+	ResourceUID types.UID
+
+	// APIBaseURL is the url of the GitLab API, not including the path to a specific API endpoint.
+	// It should look like https://HOSTNAME/api/v4/.
+	APIBaseURL string
+
+	UsernameAttribute  idpv1alpha1.GitLabUsernameAttribute
+	GroupNameAttribute idpv1alpha1.GitLabGroupNameAttribute
+
+	// AllowedGroups, when empty, means to allow users from all groups.
+	AllowedGroups *setutil.CaseInsensitiveSet
+
+	// HttpClient is a client that can be used to call the GitLab APIs and token endpoint.
+	// This client should be configured with the user-provided CA bundle and a timeout.
+	HttpClient *http.Client
+
+	// OAuth2Config contains ClientID, ClientSecret, Scopes, and Endpoint (which contains auth and token endpoint URLs,
+	// and auth style for the token endpoint).
+	// OAuth2Config will not be used to compute the authorize URL because the redirect back to the Supervisor's
+	// callback must be different per FederationDomain. It holds data that may be useful when calculating the
+	// authorize URL, so that data is exposed by interface methods. However, it can be used to call the token endpoint,
+	// for which there is no RedirectURL needed.
+	OAuth2Config *oauth2.Config
+}
+
+type Provider struct {
+	c                 ProviderConfig
+	buildGitLabClient func(httpClient *http.Client, apiBaseURL, token string) (gitlabclient.GitLabInterface, error)
+}
+
+var _ upstreamprovider.UpstreamGitLabIdentityProviderI = &Provider{}
+
+// New creates a Provider. The config is not a pointer to ensure that a copy of the config is created,
+// making the resulting Provider use an effectively read-only configuration.
+func New(config ProviderConfig) *Provider {
+	return &Provider{
+		c:                 config,
+		buildGitLabClient: gitlabclient.NewGitLabClient,
+	}
+}
+
+func (p *Provider) GetResourceName() string {
+	return p.c.Name
+}
+
+func (p *Provider) GetResourceUID() types.UID {
+	return p.c.ResourceUID
+}
+
+func (p *Provider) GetClientID() string {
+	return p.c.OAuth2Config.ClientID
+}
+
+func (p *Provider) GetScopes() []string {
+	return p.c.OAuth2Config.Scopes
+}
+
+func (p *Provider) GetUsernameAttribute() idpv1alpha1.GitLabUsernameAttribute {
+	return p.c.UsernameAttribute
+}
+
+func (p *Provider) GetGroupNameAttribute() idpv1alpha1.GitLabGroupNameAttribute {
+	return p.c.GroupNameAttribute
+}
+
+func (p *Provider) GetAllowedGroups() *setutil.CaseInsensitiveSet {
+	return p.c.AllowedGroups
+}
+
+func (p *Provider) GetAuthorizationURL() string {
+	return p.c.OAuth2Config.Endpoint.AuthURL
+}
+
+func (p *Provider) ExchangeAuthcode(ctx context.Context, authcode string, pkceCodeVerifier pkce.Code, redirectURI string) (*oauth2.Token, error) {
+	start := time.Now()
+	tok, err := p.c.OAuth2Config.Exchange(
+		coreosoidc.ClientContext(ctx, p.c.HttpClient),
+		authcode,
+		pkceCodeVerifier.Verifier(),
+		oauth2.SetAuthURLParam("redirect_uri", redirectURI),
+	)
+	metrics.ObserveUpstreamCall(metrics.UpstreamTypeGitLab, metrics.UpstreamOperationAuthcodeExchange, start, err)
+	if err != nil {
+		return nil, fmt.Errorf("error exchanging authorization code using GitLab API: %w", err)
+	}
+	return tok, nil
+}
+
+func (p *Provider) PerformRefresh(ctx context.Context, refreshToken string) (*oauth2.Token, error) {
+	// Create a TokenSource without an access token, so it thinks that a refresh is immediately required.
+	// Then ask it for the tokens to cause it to perform the refresh and return the results.
+	start := time.Now()
+	tok, err := p.c.OAuth2Config.TokenSource(
+		coreosoidc.ClientContext(ctx, p.c.HttpClient),
+		&oauth2.Token{RefreshToken: refreshToken},
+	).Token()
+	metrics.ObserveUpstreamCall(metrics.UpstreamTypeGitLab, metrics.UpstreamOperationRefresh, start, err)
+	if err != nil {
+		return nil, fmt.Errorf("error refreshing tokens using GitLab API: %w", err)
+	}
+	return tok, nil
+}
+
+// GetUser will use the provided configuration to make HTTPS calls to the GitLab API to get the identity of the
+// authenticated user and to discover their group memberships.
+// If the user's information meets the allowed groups criteria specified on the GitLabIdentityProvider,
+// they will be allowed to log in.
+// Note that errors from the gitlabclient package already have helpful error prefixes, so there is no need for additional prefixes here.
+func (p *Provider) GetUser(ctx context.Context, accessToken string, idpDisplayName string) (*upstreamprovider.GitLabUser, error) {
+	gitlabClient, err := p.buildGitLabClient(p.c.HttpClient, p.c.APIBaseURL, accessToken)
+	if err != nil {
+		return nil, err
+	}
+
+	gitlabUser := upstreamprovider.GitLabUser{}
+
+	userInfo, err := gitlabClient.GetUserInfo(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	gitlabUser.DownstreamSubject = downstreamsubject.GitLab(p.c.APIBaseURL, idpDisplayName, userInfo.Username, userInfo.ID)
+
+	switch p.c.UsernameAttribute {
+	case idpv1alpha1.GitLabUsernameUsernameAndID:
+		gitlabUser.Username = fmt.Sprintf("%s:%s", userInfo.Username, userInfo.ID)
+	case idpv1alpha1.GitLabUsernameUsername:
+		gitlabUser.Username = userInfo.Username
+	case idpv1alpha1.GitLabUsernameID:
+		gitlabUser.Username = userInfo.ID
+	default:
+		return nil, fmt.Errorf("bad configuration: unknown GitLab username attribute: %s", p.c.UsernameAttribute)
+	}
+
+	groupMembership, err := gitlabClient.GetGroupMembership(ctx, p.c.AllowedGroups)
+	if err != nil {
+		return nil, err
+	}
+
+	// The group membership was already filtered to only include the allowed top-level groups and their subgroups,
+	// so the user belongs to at least one of the allowed groups when there is anything left after filtering.
+	if !p.c.AllowedGroups.Empty() && len(groupMembership) == 0 {
+		plog.Warning("user is not allowed to log in due to group membership policy", // do not log username to avoid PII
+			"configuredAllowedGroups", p.c.AllowedGroups,
+			"identityProviderDisplayName", idpDisplayName,
+			"identityProviderResourceName", p.GetResourceName())
+		plog.Trace("user is not allowed to log in due to group membership policy", // okay to log PII at trace level
+			"gitlabUsername", userInfo.Username,
+			"gitlabID", userInfo.ID,
+			"calculatedUsername", gitlabUser.Username,
+			"configuredAllowedGroups", p.c.AllowedGroups,
+			"identityProviderDisplayName", idpDisplayName,
+			"identityProviderResourceName", p.GetResourceName())
+		return nil, upstreamprovider.NewGitLabLoginDeniedError("user is not allowed to log in due to group membership policy")
+	}
+
+	for _, group := range groupMembership {
+		downstreamGroup := ""
+
+		switch p.c.GroupNameAttribute {
+		case idpv1alpha1.GitLabUseGroupPathForGroupName:
+			downstreamGroup = group.Path
+		case idpv1alpha1.GitLabUseGroupFullPathForGroupName:
+			downstreamGroup = group.FullPath
+		case idpv1alpha1.GitLabUseGroupIDForGroupName:
+			downstreamGroup = group.ID
+		default:
+			return nil, fmt.Errorf("bad configuration: unknown GitLab group name attribute: %s", p.c.GroupNameAttribute)
+		}
+
+		gitlabUser.Groups = append(gitlabUser.Groups, downstreamGroup)
+	}
+
+	return &gitlabUser, nil
+}
+
+// GetConfig returns the config. This is not part of the UpstreamGitLabIdentityProviderI interface and is just for testing.
+func (p *Provider) GetConfig() ProviderConfig {
+	return p.c
+}
@@ -0,0 +1,451 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package upstreamgitlab
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"golang.org/x/oauth2"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/rand"
+	"k8s.io/client-go/util/cert"
+
+	idpv1alpha1 "go.pinniped.dev/generated/latest/apis/supervisor/idp/v1alpha1"
+	"go.pinniped.dev/internal/federationdomain/upstreamprovider"
+	"go.pinniped.dev/internal/gitlabclient"
+	"go.pinniped.dev/internal/mocks/mockgitlabclient"
+	"go.pinniped.dev/internal/setutil"
+	"go.pinniped.dev/internal/testutil/tlsserver"
+	"go.pinniped.dev/pkg/oidcclient/pkce"
+)
+
+func TestGitLabProvider(t *testing.T) {
+	config := ProviderConfig{
+		Name:               "foo",
+		ResourceUID:        "resource-uid-12345",
+		APIBaseURL:         "https://fake-base-url/api/v4/",
+		UsernameAttribute:  "fake-username-attribute",
+		GroupNameAttribute: "fake-group-name-attribute",
+		//nolint:gosec // not a real credential
+		OAuth2Config: &oauth2.Config{
+			ClientID:     "fake-client-id",
+			ClientSecret: "fake-client-secret",
+			Scopes:       []string{"scope1", "scope2"},
+			Endpoint: oauth2.Endpoint{
+				AuthURL:   "https://fake-authorization-url",
+				TokenURL:  "https://fake-token-url",
+				AuthStyle: oauth2.AuthStyleInParams,
+			},
+		},
+		AllowedGroups: setutil.NewCaseInsensitiveSet("fake-group", "fake-group2"),
+		HttpClient: &http.Client{
+			Timeout: 1234509,
+		},
+	}
+	subject := New(config)
+
+	require.Equal(t, config, subject.GetConfig())
+	require.Equal(t, "foo", subject.GetResourceName())
+	require.Equal(t, types.UID("resource-uid-12345"), subject.GetResourceUID())
+	require.Equal(t, "fake-client-id", subject.GetClientID())
+	require.Equal(t, []string{"scope1", "scope2"}, subject.GetScopes())
+	require.Equal(t, idpv1alpha1.GitLabUsernameAttribute("fake-username-attribute"), subject.GetUsernameAttribute())
+	require.Equal(t, idpv1alpha1.GitLabGroupNameAttribute("fake-group-name-attribute"), subject.GetGroupNameAttribute())
+	require.Equal(t, setutil.NewCaseInsensitiveSet("fake-group", "fake-group2"), subject.GetAllowedGroups())
+	require.Equal(t, "https://fake-authorization-url", subject.GetAuthorizationURL())
+}
+
+func newTokenEndpointTestProvider(t *testing.T, handler http.HandlerFunc, tokenEndpointPath string) *Provider {
+	t.Helper()
+
+	testServer, testServerCA := tlsserver.TestServerIPv4(t, handler, nil)
+	testServerPool, err := cert.NewPoolFromBytes(testServerCA)
+	require.NoError(t, err)
+
+	return New(ProviderConfig{
+		//nolint:gosec // not a real credential
+		OAuth2Config: &oauth2.Config{
+			ClientID:     "fake-client-id",
+			ClientSecret: "fake-client-secret",
+			Scopes:       []string{"read_api"},
+			Endpoint: oauth2.Endpoint{
+				AuthURL:   "https://fake-auth-url",
+				TokenURL:  testServer.URL + tokenEndpointPath,
+				AuthStyle: oauth2.AuthStyleInParams,
+			},
+		},
+		HttpClient: &http.Client{
+			Timeout: 10 * time.Second,
+			Transport: &http.Transport{TLSClientConfig: &tls.Config{
+				MinVersion: tls.VersionTLS12,
+				RootCAs:    testServerPool,
+			}},
+		},
+	})
+}
+
+func writeTokenResponse(t *testing.T, w http.ResponseWriter, path string) {
+	t.Helper()
+
+	w.Header().Set("content-type", "application/json")
+	switch path {
+	case "/token":
+		// Example response from GitLab docs.
+		w.WriteHeader(http.StatusOK)
+		_, err := w.Write([]byte(`{"access_token": "fake-access-token", "token_type": "bearer", "expires_in": 7200, ` +
+			`"refresh_token": "fake-refresh-token", "created_at": 1607635748}`))
+		require.NoError(t, err)
+	case "/token-error":
+		w.WriteHeader(http.StatusBadRequest)
+		_, err := w.Write([]byte(`{"error": "invalid_grant", "error_description": "some gitlab error"}`))
+		require.NoError(t, err)
+	default:
+		t.Fatalf("tried to call provider at unexpected endpoint: %s", path)
+	}
+}
+
+func TestExchangeAuthcode(t *testing.T) {
+	tests := []struct {
+		name              string
+		tokenEndpointPath string
+		wantErr           string
+	}{
+		{
+			name:              "happy path",
+			tokenEndpointPath: "/token",
+		},
+		{
+			name:              "when the GitLab token endpoint returns an error",
+			tokenEndpointPath: "/token-error",
+			wantErr:           `error exchanging authorization code using GitLab API: oauth2: "invalid_grant" "some gitlab error"`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			subject := newTokenEndpointTestProvider(t, func(w http.ResponseWriter, r *http.Request) {
+				// See documentation at https://docs.gitlab.com/ee/api/oauth2.html#authorization-code-with-proof-key-for-code-exchange-pkce
+				require.Equal(t, http.MethodPost, r.Method)
+
+				//nolint:gosec // this is a test, so we don't care about limiting request body size
+				require.NoError(t, r.ParseForm())
+				params := r.PostForm
+				require.Len(t, params, 6)
+				require.Equal(t, "fake-client-id", params.Get("client_id"))
+				require.Equal(t, "fake-client-secret", params.Get("client_secret"))
+				require.Equal(t, "https://fake-redirect-url", params.Get("redirect_uri"))
+				require.Equal(t, "fake-authcode", params.Get("code"))
+				require.Equal(t, "authorization_code", params.Get("grant_type"))
+				require.Equal(t, "fake-pkce-verifier", params.Get("code_verifier"))
+
+				writeTokenResponse(t, w, r.URL.Path)
+			}, test.tokenEndpointPath)
+
+			tok, err := subject.ExchangeAuthcode(context.Background(), "fake-authcode", pkce.Code("fake-pkce-verifier"), "https://fake-redirect-url")
+			if test.wantErr != "" {
+				require.EqualError(t, err, test.wantErr)
+				require.Nil(t, tok)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, "fake-access-token", tok.AccessToken)
+			require.Equal(t, "fake-refresh-token", tok.RefreshToken)
+		})
+	}
+}
+
+func TestPerformRefresh(t *testing.T) {
+	tests := []struct {
+		name              string
+		tokenEndpointPath string
+		wantErr           string
+	}{
+		{
+			name:              "happy path",
+			tokenEndpointPath: "/token",
+		},
+		{
+			name:              "when the GitLab token endpoint returns an error",
+			tokenEndpointPath: "/token-error",
+			wantErr:           `error refreshing tokens using GitLab API: oauth2: "invalid_grant" "some gitlab error"`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			subject := newTokenEndpointTestProvider(t, func(w http.ResponseWriter, r *http.Request) {
+				require.Equal(t, http.MethodPost, r.Method)
+
+				//nolint:gosec // this is a test, so we don't care about limiting request body size
+				require.NoError(t, r.ParseForm())
+				params := r.PostForm
+				require.Len(t, params, 4)
+				require.Equal(t, "fake-client-id", params.Get("client_id"))
+				require.Equal(t, "fake-client-secret", params.Get("client_secret"))
+				require.Equal(t, "refresh_token", params.Get("grant_type"))
+				require.Equal(t, "old-refresh-token", params.Get("refresh_token"))
+
+				writeTokenResponse(t, w, r.URL.Path)
+			}, test.tokenEndpointPath)
+
+			tok, err := subject.PerformRefresh(context.Background(), "old-refresh-token")
+			if test.wantErr != "" {
+				require.EqualError(t, err, test.wantErr)
+				require.Nil(t, tok)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, "fake-access-token", tok.AccessToken)
+			require.Equal(t, "fake-refresh-token", tok.RefreshToken)
+		})
+	}
+}
+
+func TestGetUser(t *testing.T) {
+	const idpDisplayName = "idp display name 😀"
+	const encodedIDPDisplayName = "idp+display+name+%F0%9F%98%80"
+
+	someContext := context.Background()
+
+	someHttpClient := &http.Client{
+		Timeout: 1234509,
+	}
+
+	someGroups := []gitlabclient.GroupInfo{
+		{ID: "11", Path: "group1", FullPath: "group1"},
+		{ID: "22", Path: "subgroup", FullPath: "group1/subgroup"},
+	}
+
+	tests := []struct {
+		name                   string
+		providerConfig         ProviderConfig
+		buildGitLabClientError error
+		buildMockResponses     func(mockGitLabInterface *mockgitlabclient.MockGitLabInterface)
+		wantUser               *upstreamprovider.GitLabUser
+		wantErrMsg             string
+		wantErr                error
+	}{
+		{
+			name: "happy path with username=username:id and groups=full_path",
+			providerConfig: ProviderConfig{
+				APIBaseURL:         "https://some-url/api/v4/",
+				HttpClient:         someHttpClient,
+				UsernameAttribute:  idpv1alpha1.GitLabUsernameUsernameAndID,
+				GroupNameAttribute: idpv1alpha1.GitLabUseGroupFullPathForGroupName,
+				AllowedGroups:      setutil.NewCaseInsensitiveSet(),
+			},
+			buildMockResponses: func(mockGitLabInterface *mockgitlabclient.MockGitLabInterface) {
+				mockGitLabInterface.EXPECT().GetUserInfo(someContext).Return(&gitlabclient.UserInfo{
+					Username: "some-gitlab-username",
+					ID:       "some-gitlab-id",
+				}, nil)
+				mockGitLabInterface.EXPECT().GetGroupMembership(someContext, setutil.NewCaseInsensitiveSet()).Return(someGroups, nil)
+			},
+			wantUser: &upstreamprovider.GitLabUser{
+				Username:          "some-gitlab-username:some-gitlab-id",
+				Groups:            []string{"group1", "group1/subgroup"},
+				DownstreamSubject: fmt.Sprintf("https://some-url/api/v4/?idpName=%s&username=some-gitlab-username&id=some-gitlab-id", encodedIDPDisplayName),
+			},
+		},
+		{
+			name: "happy path with username=username and groups=path",
+			providerConfig: ProviderConfig{
+				APIBaseURL:         "https://some-url/api/v4/",
+				HttpClient:         someHttpClient,
+				UsernameAttribute:  idpv1alpha1.GitLabUsernameUsername,
+				GroupNameAttribute: idpv1alpha1.GitLabUseGroupPathForGroupName,
+				AllowedGroups:      setutil.NewCaseInsensitiveSet("group1"),
+			},
+			buildMockResponses: func(mockGitLabInterface *mockgitlabclient.MockGitLabInterface) {
+				mockGitLabInterface.EXPECT().GetUserInfo(someContext).Return(&gitlabclient.UserInfo{
+					Username: "some-gitlab-username",
+					ID:       "some-gitlab-id",
+				}, nil)
+				mockGitLabInterface.EXPECT().GetGroupMembership(someContext, setutil.NewCaseInsensitiveSet("group1")).Return(someGroups, nil)
+			},
+			wantUser: &upstreamprovider.GitLabUser{
+				Username:          "some-gitlab-username",
+				Groups:            []string{"group1", "subgroup"},
+				DownstreamSubject: fmt.Sprintf("https://some-url/api/v4/?idpName=%s&username=some-gitlab-username&id=some-gitlab-id", encodedIDPDisplayName),
+			},
+		},
+		{
+			name: "happy path with username=id and groups=id",
+			providerConfig: ProviderConfig{
+				APIBaseURL:         "https://some-url/api/v4/",
+				HttpClient:         someHttpClient,
+				UsernameAttribute:  idpv1alpha1.GitLabUsernameID,
+				GroupNameAttribute: idpv1alpha1.GitLabUseGroupIDForGroupName,
+				AllowedGroups:      setutil.NewCaseInsensitiveSet("group1"),
+			},
+			buildMockResponses: func(mockGitLabInterface *mockgitlabclient.MockGitLabInterface) {
+				mockGitLabInterface.EXPECT().GetUserInfo(someContext).Return(&gitlabclient.UserInfo{
+					Username: "some-gitlab-username",
+					ID:       "some-gitlab-id",
+				}, nil)
+				mockGitLabInterface.EXPECT().GetGroupMembership(someContext, setutil.NewCaseInsensitiveSet("group1")).Return(someGroups, nil)
+			},
+			wantUser: &upstreamprovider.GitLabUser{
+				Username:          "some-gitlab-id",
+				Groups:            []string{"11", "22"},
+				DownstreamSubject: fmt.Sprintf("https://some-url/api/v4/?idpName=%s&username=some-gitlab-username&id=some-gitlab-id", encodedIDPDisplayName),
+			},
+		},
+		{
+			name: "happy path when the user belongs to no groups and all users are allowed",
+			providerConfig: ProviderConfig{
+				APIBaseURL:         "https://some-url/api/v4/",
+				HttpClient:         someHttpClient,
+				UsernameAttribute:  idpv1alpha1.GitLabUsernameUsername,
+				GroupNameAttribute: idpv1alpha1.GitLabUseGroupFullPathForGroupName,
+			},
+			buildMockResponses: func(mockGitLabInterface *mockgitlabclient.MockGitLabInterface) {
+				mockGitLabInterface.EXPECT().GetUserInfo(someContext).Return(&gitlabclient.UserInfo{
+					Username: "some-gitlab-username",
+					ID:       "some-gitlab-id",
+				}, nil)
+				mockGitLabInterface.EXPECT().GetGroupMembership(someContext, nil).Return([]gitlabclient.GroupInfo{}, nil)
+			},
+			wantUser: &upstreamprovider.GitLabUser{
+				Username:          "some-gitlab-username",
+				DownstreamSubject: fmt.Sprintf("https://some-url/api/v4/?idpName=%s&username=some-gitlab-username&id=some-gitlab-id", encodedIDPDisplayName),
+			},
+		},
+		{
+			name: "returns a GitLabLoginDeniedError when the user does not belong to any allowed group",
+			providerConfig: ProviderConfig{
+				APIBaseURL:         "https://some-url/api/v4/",
+				HttpClient:         someHttpClient,
+				UsernameAttribute:  idpv1alpha1.GitLabUsernameUsername,
+				GroupNameAttribute: idpv1alpha1.GitLabUseGroupFullPathForGroupName,
+				AllowedGroups:      setutil.NewCaseInsensitiveSet("allowed-group"),
+			},
+			buildMockResponses: func(mockGitLabInterface *mockgitlabclient.MockGitLabInterface) {
+				mockGitLabInterface.EXPECT().GetUserInfo(someContext).Return(&gitlabclient.UserInfo{
+					Username: "some-gitlab-username",
+					ID:       "some-gitlab-id",
+				}, nil)
+				mockGitLabInterface.EXPECT().GetGroupMembership(someContext, setutil.NewCaseInsensitiveSet("allowed-group")).Return([]gitlabclient.GroupInfo{}, nil)
+			},
+			wantErr: upstreamprovider.NewGitLabLoginDeniedError("user is not allowed to log in due to group membership policy"),
+		},
+		{
+			name: "returns errors from building the client",
+			providerConfig: ProviderConfig{
+				APIBaseURL: "https://some-url/api/v4/",
+				HttpClient: someHttpClient,
+			},
+			buildGitLabClientError: errors.New("error from building a gitlab client"),
+			wantErrMsg:             "error from building a gitlab client",
+		},
+		{
+			name: "returns errors from GetUserInfo",
+			providerConfig: ProviderConfig{
+				APIBaseURL: "https://some-url/api/v4/",
+				HttpClient: someHttpClient,
+			},
+			buildMockResponses: func(mockGitLabInterface *mockgitlabclient.MockGitLabInterface) {
+				mockGitLabInterface.EXPECT().GetUserInfo(someContext).Return(nil, errors.New("error from GetUserInfo"))
+			},
+			wantErrMsg: "error from GetUserInfo",
+		},
+		{
+			name: "returns errors from GetGroupMembership",
+			providerConfig: ProviderConfig{
+				APIBaseURL:        "https://some-url/api/v4/",
+				HttpClient:        someHttpClient,
+				UsernameAttribute: idpv1alpha1.GitLabUsernameUsername,
+			},
+			buildMockResponses: func(mockGitLabInterface *mockgitlabclient.MockGitLabInterface) {
+				mockGitLabInterface.EXPECT().GetUserInfo(someContext).Return(&gitlabclient.UserInfo{
+					Username: "some-gitlab-username",
+					ID:       "some-gitlab-id",
+				}, nil)
+				mockGitLabInterface.EXPECT().GetGroupMembership(someContext, nil).Return(nil, errors.New("error from GetGroupMembership"))
+			},
+			wantErrMsg: "error from GetGroupMembership",
+		},
+		{
+			name: "bad configuration: UsernameAttribute",
+			providerConfig: ProviderConfig{
+				APIBaseURL:        "https://some-url/api/v4/",
+				HttpClient:        someHttpClient,
+				UsernameAttribute: "this-is-not-legal-value-from-the-enum",
+			},
+			buildMockResponses: func(mockGitLabInterface *mockgitlabclient.MockGitLabInterface) {
+				mockGitLabInterface.EXPECT().GetUserInfo(someContext).Return(&gitlabclient.UserInfo{
+					Username: "some-gitlab-username",
+					ID:       "some-gitlab-id",
+				}, nil)
+			},
+			wantErrMsg: "bad configuration: unknown GitLab username attribute: this-is-not-legal-value-from-the-enum",
+		},
+		{
+			name: "bad configuration: GroupNameAttribute",
+			providerConfig: ProviderConfig{
+				APIBaseURL:         "https://some-url/api/v4/",
+				HttpClient:         someHttpClient,
+				UsernameAttribute:  idpv1alpha1.GitLabUsernameUsername,
+				GroupNameAttribute: "this-is-not-legal-value-from-the-enum",
+			},
+			buildMockResponses: func(mockGitLabInterface *mockgitlabclient.MockGitLabInterface) {
+				mockGitLabInterface.EXPECT().GetUserInfo(someContext).Return(&gitlabclient.UserInfo{
+					Username: "some-gitlab-username",
+					ID:       "some-gitlab-id",
+				}, nil)
+				mockGitLabInterface.EXPECT().GetGroupMembership(someContext, nil).Return(someGroups, nil)
+			},
+			wantErrMsg: "bad configuration: unknown GitLab group name attribute: this-is-not-legal-value-from-the-enum",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctrl := gomock.NewController(t)
+			t.Cleanup(ctrl.Finish)
+
+			accessToken := "some-opaque-gitlab-access-token" + rand.String(8)
+			mockGitLabInterface := mockgitlabclient.NewMockGitLabInterface(ctrl)
+			if test.buildMockResponses != nil {
+				test.buildMockResponses(mockGitLabInterface)
+			}
+
+			p := New(test.providerConfig)
+			p.buildGitLabClient = func(httpClient *http.Client, apiBaseURL, token string) (gitlabclient.GitLabInterface, error) {
+				require.Equal(t, test.providerConfig.HttpClient, httpClient)
+				require.Equal(t, test.providerConfig.APIBaseURL, apiBaseURL)
+				require.Equal(t, accessToken, token)
+
+				return mockGitLabInterface, test.buildGitLabClientError
+			}
+
+			actualUser, actualErr := p.GetUser(context.Background(), accessToken, idpDisplayName)
+
+			switch {
+			case test.wantErrMsg != "":
+				require.EqualError(t, actualErr, test.wantErrMsg)
+				require.Nil(t, actualUser)
+			case test.wantErr != nil:
+				require.Equal(t, test.wantErr, actualErr)
+				require.Nil(t, actualUser)
+			default:
+				require.NoError(t, actualErr)
+				require.Equal(t, test.wantUser, actualUser)
+			}
+		})
+	}
+}
@@ -1,4 +1,4 @@
-// Copyright 2021-2025 the Pinniped contributors. All Rights Reserved.
+// Copyright 2021-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 // Package upstreamldap implements an abstraction of upstream LDAP IDP interactions.
@@ -27,6 +27,7 @@ import (
 	"go.pinniped.dev/internal/endpointaddr"
 	"go.pinniped.dev/internal/federationdomain/downstreamsubject"
 	"go.pinniped.dev/internal/federationdomain/upstreamprovider"
+	"go.pinniped.dev/internal/metrics"
 	"go.pinniped.dev/internal/plog"
 )
 
@@ -191,7 +192,7 @@ func (p *Provider) PerformRefresh(ctx context.Context, storedRefreshAttributes u
 	defer t.LogIfLong(500 * time.Millisecond) // to help users debug slow LDAP searches
 	userDN := storedRefreshAttributes.DN
 
-	conn, err := p.dial(ctx)
+	conn, err := p.dialWithMetrics(ctx)
 	if err != nil {
 		return nil, fmt.Errorf(`error dialing host %q: %w`, p.c.Host, err)
 	}
@@ -310,6 +311,49 @@ func (p *Provider) dial(ctx context.Context) (Conn, error) {
 	return dialFunc(ctx, addr)
 }
 
+// dialWithMetrics is like dial, but the returned Conn records metrics for its binds and searches.
+func (p *Provider) dialWithMetrics(ctx context.Context) (Conn, error) {
+	conn, err := p.dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &metricsConn{Conn: conn}, nil
+}
+
+// metricsConn records metrics for the binds and searches performed on the wrapped Conn.
+type metricsConn struct {
+	Conn
+}
+
+func (c *metricsConn) Bind(username, password string) error {
+	start := time.Now()
+	err := c.Conn.Bind(username, password)
+	result := metrics.ResultSuccess
+	if err != nil {
+		result = metrics.ResultError
+		ldapErr := &ldap.Error{}
+		if errors.As(err, &ldapErr) && ldapErr.ResultCode == ldap.LDAPResultInvalidCredentials {
+			result = metrics.ResultFailure
+		}
+	}
+	metrics.ObserveUpstreamCallResult(metrics.UpstreamTypeLDAP, metrics.UpstreamOperationBind, result, start)
+	return err
+}
+
+func (c *metricsConn) Search(searchRequest *ldap.SearchRequest) (*ldap.SearchResult, error) {
+	start := time.Now()
+	result, err := c.Conn.Search(searchRequest)
+	metrics.ObserveUpstreamCall(metrics.UpstreamTypeLDAP, metrics.UpstreamOperationSearch, start, err)
+	return result, err
+}
+
+func (c *metricsConn) SearchWithPaging(searchRequest *ldap.SearchRequest, pagingSize uint32) (*ldap.SearchResult, error) {
+	start := time.Now()
+	result, err := c.Conn.SearchWithPaging(searchRequest, pagingSize)
+	metrics.ObserveUpstreamCall(metrics.UpstreamTypeLDAP, metrics.UpstreamOperationSearch, start, err)
+	return result, err
+}
+
 // dialTLS is a default implementation of the Dialer, used when Dialer is nil and ConnectionProtocol is TLS.
 // Unfortunately, the go-ldap library does not seem to support dialing with a context.Context,
 // so we implement it ourselves, heavily inspired by ldap.DialURL.
@@ -404,7 +448,7 @@ func (p *Provider) TestConnection(ctx context.Context) error {
 		return err
 	}
 
-	conn, err := p.dial(ctx)
+	conn, err := p.dialWithMetrics(ctx)
 	if err != nil {
 		return fmt.Errorf(`error dialing host %q: %w`, p.c.Host, err)
 	}
@@ -454,7 +498,7 @@ func (p *Provider) authenticateUserImpl(ctx context.Context, username string, bi
 		return nil, false, nil
 	}
 
-	conn, err := p.dial(ctx)
+	conn, err := p.dialWithMetrics(ctx)
 	if err != nil {
 		p.traceAuthFailure(t, err)
 		return nil, false, fmt.Errorf(`error dialing host %q: %w`, p.c.Host, err)
@@ -535,7 +579,7 @@ func (p *Provider) SearchForDefaultNamingContext(ctx context.Context) (string, e
 	t := trace.FromContext(ctx).Nest("slow ldap attempt when searching for default naming context", trace.Field{Key: "providerName", Value: p.GetResourceName()})
 	defer t.LogIfLong(500 * time.Millisecond) // to help users debug slow LDAP searches
 
-	conn, err := p.dial(ctx)
+	conn, err := p.dialWithMetrics(ctx)
 	if err != nil {
 		p.traceSearchBaseDiscoveryFailure(t, err)
 		return "", fmt.Errorf(`error dialing host %q: %w`, p.c.Host, err)
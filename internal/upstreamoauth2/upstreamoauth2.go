@@ -0,0 +1,209 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package upstreamoauth2 implements an abstraction of upstream generic OAuth2 provider interactions.
+package upstreamoauth2
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	coreosoidc "github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/oauth2"
+	"k8s.io/apimachinery/pkg/types"
+
+	"go.pinniped.dev/internal/celtransformer"
+	"go.pinniped.dev/internal/federationdomain/downstreamsubject"
+	"go.pinniped.dev/internal/federationdomain/upstreamprovider"
+	"go.pinniped.dev/internal/metrics"
+	"go.pinniped.dev/internal/plog"
+	"go.pinniped.dev/pkg/oidcclient/pkce"
+)
+
+const (
+	// maxResponseBodyLength limits the size of the JSON responses read from the user info and groups endpoints.
+	maxResponseBodyLength = 1024 * 1024
+
+	// maxErrorBodyLength limits how much of an error response body is included in error messages.
+	maxErrorBodyLength = 256
+)
+
+// ProviderConfig holds the active configuration of an upstream OAuth2 provider.
+type ProviderConfig struct {
+	Name        string
+	ResourceUID types.UID
+
+	// UserInfoURL is the URL of the endpoint which returns a JSON object describing the authenticated user.
+	UserInfoURL string
+
+	// GroupsURL is the URL of the endpoint which returns JSON describing the user's groups. Optional.
+	GroupsURL string
+
+	// Claims determines the user's identity from the responses of the user info and groups endpoints.
+	Claims *celtransformer.CompiledUserInfoClaimsExtraction
+
+	// AdditionalAuthcodeParams are additional params to be sent on authcode requests.
+	AdditionalAuthcodeParams map[string]string
+
+	// HttpClient is a client that can be used to call the token, user info, and groups endpoints.
+	// This client should be configured with the user-provided CA bundle and a timeout.
+	HttpClient *http.Client
+
+	// OAuth2Config contains ClientID, ClientSecret, Scopes, and Endpoint (which contains auth and token endpoint URLs,
+	// and auth style for the token endpoint).
+	// OAuth2Config will not be used to compute the authorize URL because the redirect back to the Supervisor's
+	// callback must be different per FederationDomain. It holds data that may be useful when calculating the
+	// authorize URL, so that data is exposed by interface methods. However, it can be used to call the token endpoint,
+	// for which there is no RedirectURL needed.
+	OAuth2Config *oauth2.Config
+}
+
+type Provider struct {
+	c ProviderConfig
+}
+
+var _ upstreamprovider.UpstreamOAuth2IdentityProviderI = &Provider{}
+
+// New creates a Provider. The config is not a pointer to ensure that a copy of the config is created,
+// making the resulting Provider use an effectively read-only configuration.
+func New(config ProviderConfig) *Provider {
+	return &Provider{c: config}
+}
+
+func (p *Provider) GetResourceName() string {
+	return p.c.Name
+}
+
+func (p *Provider) GetResourceUID() types.UID {
+	return p.c.ResourceUID
+}
+
+func (p *Provider) GetClientID() string {
+	return p.c.OAuth2Config.ClientID
+}
+
+func (p *Provider) GetScopes() []string {
+	return p.c.OAuth2Config.Scopes
+}
+
+func (p *Provider) GetAdditionalAuthcodeParams() map[string]string {
+	return p.c.AdditionalAuthcodeParams
+}
+
+func (p *Provider) GetAuthorizationURL() string {
+	return p.c.OAuth2Config.Endpoint.AuthURL
+}
+
+func (p *Provider) ExchangeAuthcode(ctx context.Context, authcode string, pkceCodeVerifier pkce.Code, redirectURI string) (*oauth2.Token, error) {
+	start := time.Now()
+	tok, err := p.c.OAuth2Config.Exchange(
+		coreosoidc.ClientContext(ctx, p.c.HttpClient),
+		authcode,
+		pkceCodeVerifier.Verifier(),
+		oauth2.SetAuthURLParam("redirect_uri", redirectURI),
+	)
+	metrics.ObserveUpstreamCall(metrics.UpstreamTypeOAuth2, metrics.UpstreamOperationAuthcodeExchange, start, err)
+	if err != nil {
+		return nil, fmt.Errorf("error exchanging authorization code using OAuth2 token endpoint: %w", err)
+	}
+	return tok, nil
+}
+
+func (p *Provider) PerformRefresh(ctx context.Context, refreshToken string) (*oauth2.Token, error) {
+	// Create a TokenSource without an access token, so it thinks that a refresh is immediately required.
+	// Then ask it for the tokens to cause it to perform the refresh and return the results.
+	start := time.Now()
+	tok, err := p.c.OAuth2Config.TokenSource(
+		coreosoidc.ClientContext(ctx, p.c.HttpClient),
+		&oauth2.Token{RefreshToken: refreshToken},
+	).Token()
+	metrics.ObserveUpstreamCall(metrics.UpstreamTypeOAuth2, metrics.UpstreamOperationRefresh, start, err)
+	if err != nil {
+		return nil, fmt.Errorf("error refreshing tokens using OAuth2 token endpoint: %w", err)
+	}
+	return tok, nil
+}
+
+// GetUser will use the provided configuration to call the user info endpoint, and the groups endpoint when
+// configured, and will evaluate the configured claims expressions on their responses to determine the
+// identity of the authenticated user.
+func (p *Provider) GetUser(ctx context.Context, accessToken string, idpDisplayName string) (*upstreamprovider.OAuth2User, error) {
+	var userInfo map[string]any
+	if err := p.getJSON(ctx, p.c.UserInfoURL, accessToken, &userInfo); err != nil {
+		return nil, fmt.Errorf("error calling user info endpoint: %w", err)
+	}
+	if userInfo == nil {
+		return nil, fmt.Errorf("error calling user info endpoint: GET %s: response was not a JSON object", p.c.UserInfoURL)
+	}
+
+	var groupsResponse any
+	if p.c.GroupsURL != "" {
+		if err := p.getJSON(ctx, p.c.GroupsURL, accessToken, &groupsResponse); err != nil {
+			return nil, fmt.Errorf("error calling groups endpoint: %w", err)
+		}
+	}
+
+	claims, err := p.c.Claims.Evaluate(ctx, userInfo, groupsResponse)
+	if err != nil {
+		plog.Trace("could not determine identity from OAuth2 responses", // okay to log PII at trace level
+			"userInfo", userInfo,
+			"groupsResponse", groupsResponse,
+			"identityProviderDisplayName", idpDisplayName,
+			"identityProviderResourceName", p.GetResourceName())
+		return nil, fmt.Errorf("error evaluating claims expressions: %w", err)
+	}
+
+	return &upstreamprovider.OAuth2User{
+		Username:          claims.Username,
+		Groups:            claims.Groups,
+		DownstreamSubject: downstreamsubject.OAuth2(p.c.UserInfoURL, claims.UID, idpDisplayName),
+	}, nil
+}
+
+// getJSON performs a GET request using the access token as a bearer token and decodes the JSON response body into result.
+func (p *Provider) getJSON(ctx context.Context, requestURL string, accessToken string, result any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, requestURL, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	req.Header.Set("Accept", "application/json")
+
+	start := time.Now()
+	resp, err := p.c.HttpClient.Do(req)
+	if err != nil {
+		metrics.ObserveUpstreamCallResult(metrics.UpstreamTypeOAuth2, metrics.UpstreamOperationUserInfo, metrics.ResultError, start)
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	switch {
+	case resp.StatusCode >= http.StatusInternalServerError:
+		metrics.ObserveUpstreamCallResult(metrics.UpstreamTypeOAuth2, metrics.UpstreamOperationUserInfo, metrics.ResultError, start)
+	case resp.StatusCode != http.StatusOK:
+		metrics.ObserveUpstreamCallResult(metrics.UpstreamTypeOAuth2, metrics.UpstreamOperationUserInfo, metrics.ResultFailure, start)
+	default:
+		metrics.ObserveUpstreamCallResult(metrics.UpstreamTypeOAuth2, metrics.UpstreamOperationUserInfo, metrics.ResultSuccess, start)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodyLength))
+		return fmt.Errorf("GET %s: unexpected response status %q: %s", requestURL, resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxResponseBodyLength)).Decode(result); err != nil {
+		return fmt.Errorf("GET %s: could not decode response: %w", requestURL, err)
+	}
+
+	return nil
+}
+
+// GetConfig returns the config. This is not part of the UpstreamOAuth2IdentityProviderI interface and is just for testing.
+func (p *Provider) GetConfig() ProviderConfig {
+	return p.c
+}
@@ -0,0 +1,387 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package upstreamoauth2
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/cert"
+
+	"go.pinniped.dev/internal/celtransformer"
+	"go.pinniped.dev/internal/federationdomain/upstreamprovider"
+	"go.pinniped.dev/internal/testutil/tlsserver"
+	"go.pinniped.dev/pkg/oidcclient/pkce"
+)
+
+func TestOAuth2Provider(t *testing.T) {
+	config := ProviderConfig{
+		Name:                     "foo",
+		ResourceUID:              "resource-uid-12345",
+		UserInfoURL:              "https://fake-userinfo-url",
+		GroupsURL:                "https://fake-groups-url",
+		AdditionalAuthcodeParams: map[string]string{"prompt": "consent"},
+		//nolint:gosec // not a real credential
+		OAuth2Config: &oauth2.Config{
+			ClientID:     "fake-client-id",
+			ClientSecret: "fake-client-secret",
+			Scopes:       []string{"scope1", "scope2"},
+			Endpoint: oauth2.Endpoint{
+				AuthURL:   "https://fake-authorization-url",
+				TokenURL:  "https://fake-token-url",
+				AuthStyle: oauth2.AuthStyleInParams,
+			},
+		},
+		HttpClient: &http.Client{
+			Timeout: 1234509,
+		},
+	}
+	subject := New(config)
+
+	require.Equal(t, config, subject.GetConfig())
+	require.Equal(t, "foo", subject.GetResourceName())
+	require.Equal(t, types.UID("resource-uid-12345"), subject.GetResourceUID())
+	require.Equal(t, "fake-client-id", subject.GetClientID())
+	require.Equal(t, []string{"scope1", "scope2"}, subject.GetScopes())
+	require.Equal(t, map[string]string{"prompt": "consent"}, subject.GetAdditionalAuthcodeParams())
+	require.Equal(t, "https://fake-authorization-url", subject.GetAuthorizationURL())
+}
+
+func newTestServerHTTPClient(t *testing.T, handler http.HandlerFunc) (string, *http.Client) {
+	t.Helper()
+
+	testServer, testServerCA := tlsserver.TestServerIPv4(t, handler, nil)
+	testServerPool, err := cert.NewPoolFromBytes(testServerCA)
+	require.NoError(t, err)
+
+	return testServer.URL, &http.Client{
+		Timeout: 10 * time.Second,
+		Transport: &http.Transport{TLSClientConfig: &tls.Config{
+			MinVersion: tls.VersionTLS12,
+			RootCAs:    testServerPool,
+		}},
+	}
+}
+
+func newTokenEndpointTestProvider(t *testing.T, handler http.HandlerFunc, tokenEndpointPath string) *Provider {
+	t.Helper()
+
+	testServerURL, httpClient := newTestServerHTTPClient(t, handler)
+
+	return New(ProviderConfig{
+		//nolint:gosec // not a real credential
+		OAuth2Config: &oauth2.Config{
+			ClientID:     "fake-client-id",
+			ClientSecret: "fake-client-secret",
+			Scopes:       []string{"read:user"},
+			Endpoint: oauth2.Endpoint{
+				AuthURL:   "https://fake-auth-url",
+				TokenURL:  testServerURL + tokenEndpointPath,
+				AuthStyle: oauth2.AuthStyleInParams,
+			},
+		},
+		HttpClient: httpClient,
+	})
+}
+
+func writeTokenResponse(t *testing.T, w http.ResponseWriter, path string) {
+	t.Helper()
+
+	w.Header().Set("content-type", "application/json")
+	switch path {
+	case "/token":
+		w.WriteHeader(http.StatusOK)
+		_, err := w.Write([]byte(`{"access_token": "fake-access-token", "token_type": "bearer", "expires_in": 7200, ` +
+			`"refresh_token": "fake-refresh-token"}`))
+		require.NoError(t, err)
+	case "/token-error":
+		w.WriteHeader(http.StatusBadRequest)
+		_, err := w.Write([]byte(`{"error": "invalid_grant", "error_description": "some provider error"}`))
+		require.NoError(t, err)
+	default:
+		t.Fatalf("tried to call provider at unexpected endpoint: %s", path)
+	}
+}
+
+func TestExchangeAuthcode(t *testing.T) {
+	tests := []struct {
+		name              string
+		tokenEndpointPath string
+		wantErr           string
+	}{
+		{
+			name:              "happy path",
+			tokenEndpointPath: "/token",
+		},
+		{
+			name:              "when the token endpoint returns an error",
+			tokenEndpointPath: "/token-error",
+			wantErr:           `error exchanging authorization code using OAuth2 token endpoint: oauth2: "invalid_grant" "some provider error"`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			subject := newTokenEndpointTestProvider(t, func(w http.ResponseWriter, r *http.Request) {
+				require.Equal(t, http.MethodPost, r.Method)
+
+				//nolint:gosec // this is a test, so we don't care about limiting request body size
+				require.NoError(t, r.ParseForm())
+				params := r.PostForm
+				require.Len(t, params, 6)
+				require.Equal(t, "fake-client-id", params.Get("client_id"))
+				require.Equal(t, "fake-client-secret", params.Get("client_secret"))
+				require.Equal(t, "https://fake-redirect-url", params.Get("redirect_uri"))
+				require.Equal(t, "fake-authcode", params.Get("code"))
+				require.Equal(t, "authorization_code", params.Get("grant_type"))
+				require.Equal(t, "fake-pkce-verifier", params.Get("code_verifier"))
+
+				writeTokenResponse(t, w, r.URL.Path)
+			}, test.tokenEndpointPath)
+
+			tok, err := subject.ExchangeAuthcode(context.Background(), "fake-authcode", pkce.Code("fake-pkce-verifier"), "https://fake-redirect-url")
+			if test.wantErr != "" {
+				require.EqualError(t, err, test.wantErr)
+				require.Nil(t, tok)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, "fake-access-token", tok.AccessToken)
+			require.Equal(t, "fake-refresh-token", tok.RefreshToken)
+		})
+	}
+}
+
+func TestPerformRefresh(t *testing.T) {
+	tests := []struct {
+		name              string
+		tokenEndpointPath string
+		wantErr           string
+	}{
+		{
+			name:              "happy path",
+			tokenEndpointPath: "/token",
+		},
+		{
+			name:              "when the token endpoint returns an error",
+			tokenEndpointPath: "/token-error",
+			wantErr:           `error refreshing tokens using OAuth2 token endpoint: oauth2: "invalid_grant" "some provider error"`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			subject := newTokenEndpointTestProvider(t, func(w http.ResponseWriter, r *http.Request) {
+				require.Equal(t, http.MethodPost, r.Method)
+
+				//nolint:gosec // this is a test, so we don't care about limiting request body size
+				require.NoError(t, r.ParseForm())
+				params := r.PostForm
+				require.Len(t, params, 4)
+				require.Equal(t, "fake-client-id", params.Get("client_id"))
+				require.Equal(t, "fake-client-secret", params.Get("client_secret"))
+				require.Equal(t, "refresh_token", params.Get("grant_type"))
+				require.Equal(t, "old-refresh-token", params.Get("refresh_token"))
+
+				writeTokenResponse(t, w, r.URL.Path)
+			}, test.tokenEndpointPath)
+
+			tok, err := subject.PerformRefresh(context.Background(), "old-refresh-token")
+			if test.wantErr != "" {
+				require.EqualError(t, err, test.wantErr)
+				require.Nil(t, tok)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, "fake-access-token", tok.AccessToken)
+			require.Equal(t, "fake-refresh-token", tok.RefreshToken)
+		})
+	}
+}
+
+func TestGetUser(t *testing.T) {
+	const idpDisplayName = "idp display name 😀"
+	const encodedIDPDisplayName = "idp+display+name+%F0%9F%98%80"
+
+	transformer, err := celtransformer.NewCELTransformer(5 * time.Second)
+	require.NoError(t, err)
+
+	compile := func(t *testing.T, e celtransformer.UserInfoClaimsExtraction) *celtransformer.CompiledUserInfoClaimsExtraction {
+		t.Helper()
+		compiled, err := transformer.CompileUserInfoClaimsExtraction(&e)
+		require.NoError(t, err)
+		return compiled
+	}
+
+	handler := func(t *testing.T) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			require.Equal(t, http.MethodGet, r.Method)
+			require.Equal(t, "Bearer fake-access-token", r.Header.Get("Authorization"))
+			require.Equal(t, "application/json", r.Header.Get("Accept"))
+
+			w.Header().Set("content-type", "application/json")
+			var body string
+			switch r.URL.Path {
+			case "/user":
+				body = `{"login": "some-login", "id": 1234, "teams": [{"name": "team1"}]}`
+			case "/groups":
+				body = `[{"slug": "group1"}, {"slug": "group2"}]`
+			case "/not-an-object":
+				body = `null`
+			case "/not-json":
+				body = `this is not json`
+			case "/unauthorized":
+				w.WriteHeader(http.StatusUnauthorized)
+				body = `{"message": "bad token"}`
+			default:
+				t.Fatalf("tried to call provider at unexpected endpoint: %s", r.URL.Path)
+			}
+			_, err := w.Write([]byte(body))
+			require.NoError(t, err)
+		}
+	}
+
+	tests := []struct {
+		name            string
+		userInfoPath    string
+		groupsPath      string
+		claims          celtransformer.UserInfoClaimsExtraction
+		wantUser        func(serverURL string) *upstreamprovider.OAuth2User
+		wantErrTemplate string // use %s for the server URL
+	}{
+		{
+			name:         "happy path without a groups endpoint",
+			userInfoPath: "/user",
+			claims: celtransformer.UserInfoClaimsExtraction{
+				UsernameExpression: `userinfo.login`,
+				UIDExpression:      `string(int(userinfo.id))`,
+				GroupsExpression:   `userinfo.teams.map(t, t.name)`,
+			},
+			wantUser: func(serverURL string) *upstreamprovider.OAuth2User {
+				return &upstreamprovider.OAuth2User{
+					Username:          "some-login",
+					Groups:            []string{"team1"},
+					DownstreamSubject: fmt.Sprintf("%s/user?idpName=%s&sub=1234", serverURL, encodedIDPDisplayName),
+				}
+			},
+		},
+		{
+			name:         "happy path with a groups endpoint",
+			userInfoPath: "/user",
+			groupsPath:   "/groups",
+			claims: celtransformer.UserInfoClaimsExtraction{
+				UsernameExpression: `userinfo.login + ":" + string(int(userinfo.id))`,
+				UIDExpression:      `string(int(userinfo.id))`,
+				GroupsExpression:   `groupsResponse.map(g, g.slug)`,
+			},
+			wantUser: func(serverURL string) *upstreamprovider.OAuth2User {
+				return &upstreamprovider.OAuth2User{
+					Username:          "some-login:1234",
+					Groups:            []string{"group1", "group2"},
+					DownstreamSubject: fmt.Sprintf("%s/user?idpName=%s&sub=1234", serverURL, encodedIDPDisplayName),
+				}
+			},
+		},
+		{
+			name:         "without a groups expression",
+			userInfoPath: "/user",
+			claims: celtransformer.UserInfoClaimsExtraction{
+				UsernameExpression: `userinfo.login`,
+				UIDExpression:      `string(int(userinfo.id))`,
+			},
+			wantUser: func(serverURL string) *upstreamprovider.OAuth2User {
+				return &upstreamprovider.OAuth2User{
+					Username:          "some-login",
+					Groups:            []string{},
+					DownstreamSubject: fmt.Sprintf("%s/user?idpName=%s&sub=1234", serverURL, encodedIDPDisplayName),
+				}
+			},
+		},
+		{
+			name:         "user info endpoint returns an error status",
+			userInfoPath: "/unauthorized",
+			claims: celtransformer.UserInfoClaimsExtraction{
+				UsernameExpression: `userinfo.login`,
+				UIDExpression:      `userinfo.login`,
+			},
+			wantErrTemplate: `error calling user info endpoint: GET %s/unauthorized: unexpected response status "401 Unauthorized": {"message": "bad token"}`,
+		},
+		{
+			name:         "user info endpoint returns invalid JSON",
+			userInfoPath: "/not-json",
+			claims: celtransformer.UserInfoClaimsExtraction{
+				UsernameExpression: `userinfo.login`,
+				UIDExpression:      `userinfo.login`,
+			},
+			wantErrTemplate: `error calling user info endpoint: GET %s/not-json: could not decode response: invalid character 'h' in literal true (expecting 'r')`,
+		},
+		{
+			name:         "user info endpoint does not return a JSON object",
+			userInfoPath: "/not-an-object",
+			claims: celtransformer.UserInfoClaimsExtraction{
+				UsernameExpression: `userinfo.login`,
+				UIDExpression:      `userinfo.login`,
+			},
+			wantErrTemplate: `error calling user info endpoint: GET %s/not-an-object: response was not a JSON object`,
+		},
+		{
+			name:         "groups endpoint returns an error status",
+			userInfoPath: "/user",
+			groupsPath:   "/unauthorized",
+			claims: celtransformer.UserInfoClaimsExtraction{
+				UsernameExpression: `userinfo.login`,
+				UIDExpression:      `userinfo.login`,
+			},
+			wantErrTemplate: `error calling groups endpoint: GET %s/unauthorized: unexpected response status "401 Unauthorized": {"message": "bad token"}`,
+		},
+		{
+			name:         "claims expression evaluation fails",
+			userInfoPath: "/user",
+			claims: celtransformer.UserInfoClaimsExtraction{
+				UsernameExpression: `userinfo.missing`,
+				UIDExpression:      `userinfo.login`,
+			},
+			wantErrTemplate: `error evaluating claims expressions: username expression: no such key: missing`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			serverURL, httpClient := newTestServerHTTPClient(t, handler(t))
+
+			config := ProviderConfig{
+				Name:        "some-name",
+				UserInfoURL: serverURL + test.userInfoPath,
+				Claims:      compile(t, test.claims),
+				HttpClient:  httpClient,
+			}
+			if test.groupsPath != "" {
+				config.GroupsURL = serverURL + test.groupsPath
+			}
+
+			user, err := New(config).GetUser(context.Background(), "fake-access-token", idpDisplayName)
+			if test.wantErrTemplate != "" {
+				require.EqualError(t, err, strings.ReplaceAll(test.wantErrTemplate, "%s", serverURL))
+				require.Nil(t, user)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, test.wantUser(serverURL), user)
+		})
+	}
+}
@@ -1,4 +1,4 @@
-// Copyright 2020-2025 the Pinniped contributors. All Rights Reserved.
+// Copyright 2020-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 // Package upstreamoidc implements an abstraction of upstream OIDC provider interactions.
@@ -24,6 +24,7 @@ import (
 	"go.pinniped.dev/internal/federationdomain/dynamicupstreamprovider"
 	"go.pinniped.dev/internal/federationdomain/upstreamprovider"
 	"go.pinniped.dev/internal/httputil/httperr"
+	"go.pinniped.dev/internal/metrics"
 	"go.pinniped.dev/internal/plog"
 	"go.pinniped.dev/pkg/oidcclient/nonce"
 	"go.pinniped.dev/pkg/oidcclient/oidctypes"
@@ -127,11 +128,13 @@ func (p *ProviderConfig) PasswordCredentialsGrantAndValidateTokens(ctx context.C
 	}
 
 	// Note that this implicitly uses the scopes from p.Config.Scopes.
+	start := time.Now()
 	tok, err := p.Config.PasswordCredentialsToken(
 		coreosoidc.ClientContext(ctx, p.Client),
 		username,
 		password,
 	)
+	metrics.ObserveUpstreamCall(metrics.UpstreamTypeOIDC, metrics.UpstreamOperationPasswordGrant, start, err)
 	if err != nil {
 		return nil, err
 	}
@@ -143,12 +146,14 @@ func (p *ProviderConfig) PasswordCredentialsGrantAndValidateTokens(ctx context.C
 }
 
 func (p *ProviderConfig) ExchangeAuthcodeAndValidateTokens(ctx context.Context, authcode string, pkceCodeVerifier pkce.Code, expectedIDTokenNonce nonce.Nonce, redirectURI string) (*oidctypes.Token, error) {
+	start := time.Now()
 	tok, err := p.Config.Exchange(
 		coreosoidc.ClientContext(ctx, p.Client),
 		authcode,
 		pkceCodeVerifier.Verifier(),
 		oauth2.SetAuthURLParam("redirect_uri", redirectURI),
 	)
+	metrics.ObserveUpstreamCall(metrics.UpstreamTypeOIDC, metrics.UpstreamOperationAuthcodeExchange, start, err)
 	if err != nil {
 		return nil, err
 	}
@@ -161,7 +166,10 @@ func (p *ProviderConfig) PerformRefresh(ctx context.Context, refreshToken string
 	httpClientContext := coreosoidc.ClientContext(ctx, p.Client)
 	// Create a TokenSource without an access token, so it thinks that a refresh is immediately required.
 	// Then ask it for the tokens to cause it to perform the refresh and return the results.
-	return p.Config.TokenSource(httpClientContext, &oauth2.Token{RefreshToken: refreshToken}).Token()
+	start := time.Now()
+	tok, err := p.Config.TokenSource(httpClientContext, &oauth2.Token{RefreshToken: refreshToken}).Token()
+	metrics.ObserveUpstreamCall(metrics.UpstreamTypeOIDC, metrics.UpstreamOperationRefresh, start, err)
+	return tok, err
 }
 
 // RevokeToken will attempt to revoke the given token, if the provider has a revocation endpoint.
@@ -0,0 +1,281 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package upstreamsaml implements an abstraction of upstream SAML provider interactions.
+package upstreamsaml
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/crewjam/saml"
+	xrv "github.com/mattermost/xml-roundtrip-validator"
+	"k8s.io/apimachinery/pkg/types"
+
+	"go.pinniped.dev/internal/federationdomain/downstreamsubject"
+	"go.pinniped.dev/internal/federationdomain/upstreamprovider"
+)
+
+// ProviderConfig holds the active configuration of an upstream SAML provider.
+type ProviderConfig struct {
+	Name        string
+	ResourceUID types.UID
+
+	// IDPMetadata is the parsed SAML metadata of the upstream IDP. It provides the entity ID of the IDP,
+	// the location of its single sign-on service, and the certificates used to validate its signatures.
+	IDPMetadata *saml.EntityDescriptor
+
+	// SPEntityID is the entity ID of the Supervisor as a service provider. When empty, a default which
+	// depends on the FederationDomain will be used.
+	SPEntityID string
+
+	// UsernameAttribute is the name of the SAML attribute to use for the username. When empty, the NameID is used.
+	UsernameAttribute string
+
+	// GroupsAttribute is the name of the SAML attribute to use for the group names. When empty, no groups are used.
+	GroupsAttribute string
+}
+
+type Provider struct {
+	c ProviderConfig
+}
+
+var _ upstreamprovider.UpstreamSAMLIdentityProviderI = &Provider{}
+
+// New creates a Provider. The config is not a pointer to ensure that a copy of the config is created,
+// making the resulting Provider use an effectively read-only configuration.
+func New(config ProviderConfig) *Provider {
+	return &Provider{c: config}
+}
+
+func (p *Provider) GetResourceName() string {
+	return p.c.Name
+}
+
+func (p *Provider) GetResourceUID() types.UID {
+	return p.c.ResourceUID
+}
+
+func (p *Provider) GetIDPEntityID() string {
+	return p.c.IDPMetadata.EntityID
+}
+
+func (p *Provider) GetUsernameAttribute() string {
+	return p.c.UsernameAttribute
+}
+
+func (p *Provider) GetGroupsAttribute() string {
+	return p.c.GroupsAttribute
+}
+
+// ParseMetadata parses the SAML metadata document of an upstream IDP. The document may either be an
+// EntityDescriptor, or an EntitiesDescriptor which contains exactly one EntityDescriptor with an IDPSSODescriptor.
+// It returns an error when the metadata does not contain what is needed to use the IDP for SP-initiated logins:
+// an entity ID, a single sign-on service which uses the HTTP-Redirect binding, and at least one signing certificate.
+func ParseMetadata(data []byte) (*saml.EntityDescriptor, error) {
+	if err := xrv.Validate(bytes.NewReader(data)); err != nil {
+		return nil, fmt.Errorf("metadata is not valid XML: %w", err)
+	}
+
+	var entity saml.EntityDescriptor
+	if err := xml.Unmarshal(data, &entity); err != nil {
+		// Try again as an EntitiesDescriptor, which some IDPs use even when they describe only one entity.
+		var entities saml.EntitiesDescriptor
+		if err2 := xml.Unmarshal(data, &entities); err2 != nil {
+			return nil, fmt.Errorf("metadata is neither an EntityDescriptor nor an EntitiesDescriptor: %w", err)
+		}
+		var idpEntities []saml.EntityDescriptor
+		for _, e := range entities.EntityDescriptors {
+			if len(e.IDPSSODescriptors) > 0 {
+				idpEntities = append(idpEntities, e)
+			}
+		}
+		if len(idpEntities) != 1 {
+			return nil, fmt.Errorf("metadata EntitiesDescriptor must contain exactly one EntityDescriptor with an IDPSSODescriptor, but found %d",
+				len(idpEntities))
+		}
+		entity = idpEntities[0]
+	}
+
+	if entity.EntityID == "" {
+		return nil, errors.New("metadata does not contain an entityID")
+	}
+
+	if (&saml.ServiceProvider{IDPMetadata: &entity}).GetSSOBindingLocation(saml.HTTPRedirectBinding) == "" {
+		return nil, errors.New("metadata does not contain a SingleSignOnService which uses the HTTP-Redirect binding")
+	}
+
+	countSigningCerts := 0
+	for _, idpSSODescriptor := range entity.IDPSSODescriptors {
+		for _, keyDescriptor := range idpSSODescriptor.KeyDescriptors {
+			if keyDescriptor.Use != "" && keyDescriptor.Use != "signing" {
+				continue
+			}
+			for _, cert := range keyDescriptor.KeyInfo.X509Data.X509Certificates {
+				certBytes, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(cert.Data), ""))
+				if err != nil {
+					return nil, fmt.Errorf("metadata contains a signing certificate which is not valid base64: %w", err)
+				}
+				if _, err := x509.ParseCertificate(certBytes); err != nil {
+					return nil, fmt.Errorf("metadata contains a signing certificate which cannot be parsed: %w", err)
+				}
+				countSigningCerts++
+			}
+		}
+	}
+	if countSigningCerts == 0 {
+		return nil, errors.New("metadata does not contain any signing certificates for the IDPSSODescriptor")
+	}
+
+	return &entity, nil
+}
+
+// serviceProvider returns the crewjam/saml representation of the Supervisor as a service provider for one
+// FederationDomain. The ACS URL is different for each FederationDomain, so this cannot be computed in advance.
+func (p *Provider) serviceProvider(acsURL string, defaultSPEntityID string) (*saml.ServiceProvider, error) {
+	parsedACSURL, err := url.Parse(acsURL)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse assertion consumer service URL: %w", err)
+	}
+
+	entityID := p.c.SPEntityID
+	if entityID == "" {
+		entityID = defaultSPEntityID
+	}
+
+	return &saml.ServiceProvider{
+		EntityID:    entityID,
+		AcsURL:      *parsedACSURL,
+		IDPMetadata: p.c.IDPMetadata,
+		// Let the IDP choose the NameID format that was configured for this service provider at the IDP.
+		AuthnNameIDFormat: saml.UnspecifiedNameIDFormat,
+		// Only accept responses which were sent in response to an AuthnRequest which was sent by the Supervisor.
+		AllowIDPInitiated: false,
+	}, nil
+}
+
+func (p *Provider) AuthnRequestURL(requestID string, relayState string, acsURL string, defaultSPEntityID string) (string, error) {
+	sp, err := p.serviceProvider(acsURL, defaultSPEntityID)
+	if err != nil {
+		return "", err
+	}
+
+	ssoURL := sp.GetSSOBindingLocation(saml.HTTPRedirectBinding)
+	if ssoURL == "" {
+		// This should not happen because the controller checks for this before adding the provider to the cache.
+		return "", errors.New("IDP metadata does not include a single sign-on service using the HTTP-Redirect binding")
+	}
+
+	authnRequest, err := sp.MakeAuthenticationRequest(ssoURL, saml.HTTPRedirectBinding, saml.HTTPPostBinding)
+	if err != nil {
+		return "", fmt.Errorf("could not create AuthnRequest: %w", err)
+	}
+	authnRequest.ID = requestID
+
+	// Note that Redirect() does not escape the relay state, so do that here.
+	redirectURL, err := authnRequest.Redirect(url.QueryEscape(relayState), sp)
+	if err != nil {
+		return "", fmt.Errorf("could not encode AuthnRequest: %w", err)
+	}
+
+	return redirectURL.String(), nil
+}
+
+func (p *Provider) ParseResponse(
+	encodedResponse string,
+	requestID string,
+	acsURL string,
+	defaultSPEntityID string,
+	idpDisplayName string,
+) (*upstreamprovider.SAMLUser, error) {
+	sp, err := p.serviceProvider(acsURL, defaultSPEntityID)
+	if err != nil {
+		return nil, err
+	}
+
+	decodedResponse, err := base64.StdEncoding.DecodeString(encodedResponse)
+	if err != nil {
+		return nil, fmt.Errorf("could not base64 decode SAML response: %w", err)
+	}
+
+	// This validates the signatures, the issuer, the audience, the destination, the InResponseTo,
+	// and the time constraints of the response and its assertion.
+	assertion, err := sp.ParseXMLResponse(decodedResponse, []string{requestID})
+	if err != nil {
+		var invalidResponseErr *saml.InvalidResponseError
+		if errors.As(err, &invalidResponseErr) {
+			// The library hides the actual reason in PrivateErr. Show it, since it will only be logged.
+			err = invalidResponseErr.PrivateErr
+		}
+		return nil, fmt.Errorf("SAML response was not valid: %w", err)
+	}
+
+	if assertion.Subject == nil || assertion.Subject.NameID == nil || assertion.Subject.NameID.Value == "" {
+		return nil, errors.New("SAML assertion does not contain a NameID for its subject")
+	}
+	nameID := assertion.Subject.NameID.Value
+
+	username := nameID
+	if p.c.UsernameAttribute != "" {
+		values := attributeValues(assertion, p.c.UsernameAttribute)
+		if len(values) != 1 || values[0] == "" {
+			return nil, fmt.Errorf("SAML assertion must contain exactly one non-empty value for username attribute %q, but found %d values",
+				p.c.UsernameAttribute, len(values))
+		}
+		username = values[0]
+	}
+
+	var groups []string
+	if p.c.GroupsAttribute != "" {
+		groups = attributeValues(assertion, p.c.GroupsAttribute)
+	}
+	if groups == nil {
+		groups = []string{}
+	}
+
+	return &upstreamprovider.SAMLUser{
+		Username:            username,
+		Groups:              groups,
+		DownstreamSubject:   downstreamsubject.SAML(p.GetIDPEntityID(), nameID, idpDisplayName),
+		NameID:              nameID,
+		SessionNotOnOrAfter: sessionNotOnOrAfter(assertion),
+	}, nil
+}
+
+// attributeValues returns all values of all attributes which have the given name or friendly name.
+func attributeValues(assertion *saml.Assertion, name string) []string {
+	var values []string
+	for _, attributeStatement := range assertion.AttributeStatements {
+		for _, attribute := range attributeStatement.Attributes {
+			if attribute.Name != name && attribute.FriendlyName != name {
+				continue
+			}
+			for _, value := range attribute.Values {
+				values = append(values, value.Value)
+			}
+		}
+	}
+	return values
+}
+
+// sessionNotOnOrAfter returns the earliest SessionNotOnOrAfter of the assertion's AuthnStatements,
+// or the zero value if there is none.
+func sessionNotOnOrAfter(assertion *saml.Assertion) time.Time {
+	var earliest time.Time
+	for _, authnStatement := range assertion.AuthnStatements {
+		if authnStatement.SessionNotOnOrAfter == nil {
+			continue
+		}
+		if earliest.IsZero() || authnStatement.SessionNotOnOrAfter.Before(earliest) {
+			earliest = *authnStatement.SessionNotOnOrAfter
+		}
+	}
+	return earliest
+}
@@ -0,0 +1,375 @@
+// Copyright 2026 the Pinniped contributors. All Rights Reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package upstreamsaml
+
+import (
+	"encoding/base64"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/crewjam/saml"
+	"github.com/stretchr/testify/require"
+
+	"go.pinniped.dev/internal/federationdomain/upstreamprovider"
+	"go.pinniped.dev/internal/testutil/samltestutil"
+)
+
+const (
+	idpBaseURL        = "https://idp.example.com"
+	issuer            = "https://issuer.example.com/some/path"
+	acsURL            = issuer + "/callback"
+	requestID         = "id-some-request-id"
+	relayState        = "some-encoded-state-param=="
+	idpDisplayName    = "some display name"
+	groupsAttribute   = "http://schemas.xmlsoap.org/claims/Group"
+	usernameAttribute = "http://schemas.xmlsoap.org/ws/2005/05/identity/claims/upn"
+)
+
+func TestSAMLProvider(t *testing.T) {
+	metadata := &saml.EntityDescriptor{EntityID: "https://idp.example.com/metadata"}
+
+	subject := New(ProviderConfig{
+		Name:              "foo",
+		ResourceUID:       "resource-uid-12345",
+		IDPMetadata:       metadata,
+		SPEntityID:        "some-sp-entity-id",
+		UsernameAttribute: "some-username-attribute",
+		GroupsAttribute:   "some-groups-attribute",
+	})
+
+	require.Equal(t, "foo", subject.GetResourceName())
+	require.Equal(t, "resource-uid-12345", string(subject.GetResourceUID()))
+	require.Equal(t, "https://idp.example.com/metadata", subject.GetIDPEntityID())
+	require.Equal(t, "some-username-attribute", subject.GetUsernameAttribute())
+	require.Equal(t, "some-groups-attribute", subject.GetGroupsAttribute())
+}
+
+func TestParseMetadata(t *testing.T) {
+	idp := samltestutil.NewIDP(t, idpBaseURL)
+	validMetadata := string(idp.MetadataXML())
+
+	tests := []struct {
+		name         string
+		metadata     string
+		wantEntityID string
+		wantErr      string
+	}{
+		{
+			name:         "valid EntityDescriptor",
+			metadata:     validMetadata,
+			wantEntityID: "https://idp.example.com/metadata",
+		},
+		{
+			name: "valid EntitiesDescriptor with one IDP entity",
+			metadata: `<EntitiesDescriptor xmlns="urn:oasis:names:tc:SAML:2.0:metadata">` +
+				strings.Replace(validMetadata, "EntityDescriptor", "EntityDescriptor", 1) +
+				`<EntityDescriptor entityID="some-sp"><SPSSODescriptor/></EntityDescriptor>` +
+				`</EntitiesDescriptor>`,
+			wantEntityID: "https://idp.example.com/metadata",
+		},
+		{
+			name: "EntitiesDescriptor with no IDP entities",
+			metadata: `<EntitiesDescriptor xmlns="urn:oasis:names:tc:SAML:2.0:metadata">` +
+				`<EntityDescriptor entityID="some-sp"><SPSSODescriptor/></EntityDescriptor>` +
+				`</EntitiesDescriptor>`,
+			wantErr: "metadata EntitiesDescriptor must contain exactly one EntityDescriptor with an IDPSSODescriptor, but found 0",
+		},
+		{
+			name:     "not XML",
+			metadata: "this is not xml",
+			wantErr:  "metadata is neither an EntityDescriptor nor an EntitiesDescriptor: EOF",
+		},
+		{
+			name:     "some other XML document",
+			metadata: `<foo xmlns="bar"/>`,
+			wantErr:  `metadata is neither an EntityDescriptor nor an EntitiesDescriptor: expected element type <EntityDescriptor> but have <foo>`,
+		},
+		{
+			name:     "missing entity ID",
+			metadata: strings.Replace(validMetadata, `entityID="https://idp.example.com/metadata"`, "", 1),
+			wantErr:  "metadata does not contain an entityID",
+		},
+		{
+			name:     "missing HTTP-Redirect SSO binding",
+			metadata: strings.ReplaceAll(validMetadata, saml.HTTPRedirectBinding, "urn:some-other-binding"),
+			wantErr:  "metadata does not contain a SingleSignOnService which uses the HTTP-Redirect binding",
+		},
+		{
+			name:     "missing signing certificates",
+			metadata: strings.ReplaceAll(validMetadata, `use="signing"`, `use="something-else"`),
+			wantErr:  "metadata does not contain any signing certificates for the IDPSSODescriptor",
+		},
+		{
+			name:     "signing certificate is not base64",
+			metadata: replaceFirstCertificate(t, validMetadata, "not base64!"),
+			wantErr:  "metadata contains a signing certificate which is not valid base64: illegal base64 data at input byte 9",
+		},
+		{
+			name:     "signing certificate is not a certificate",
+			metadata: replaceFirstCertificate(t, validMetadata, base64.StdEncoding.EncodeToString([]byte("not a cert"))),
+			wantErr:  "metadata contains a signing certificate which cannot be parsed: x509: malformed certificate",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entity, err := ParseMetadata([]byte(tt.metadata))
+			if tt.wantErr != "" {
+				require.EqualError(t, err, tt.wantErr)
+				require.Nil(t, entity)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.wantEntityID, entity.EntityID)
+		})
+	}
+}
+
+func TestAuthnRequestURLAndParseResponse(t *testing.T) {
+	sessionNotOnOrAfter := time.Date(2040, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	tests := []struct {
+		name                     string
+		spEntityID               string
+		usernameAttribute        string
+		groupsAttribute          string
+		registerSPEntityID       string
+		setupIDP                 func(idp *samltestutil.IDP)
+		mutateResponse           func(samlResponse string) string
+		parseRequestID           string
+		parseACSURL              string
+		parseMetadataFromOtherID bool
+		wantUser                 *upstreamprovider.SAMLUser
+		wantErr                  string
+	}{
+		{
+			name:               "happy path using the NameID as the username and no groups attribute configured",
+			registerSPEntityID: issuer,
+			setupIDP: func(idp *samltestutil.IDP) {
+				idp.Session.Groups = []string{"ignored"}
+			},
+			wantUser: &upstreamprovider.SAMLUser{
+				Username:          "some-name-id",
+				Groups:            []string{},
+				DownstreamSubject: "https://idp.example.com/metadata?idpName=some+display+name&sub=some-name-id",
+				NameID:            "some-name-id",
+			},
+		},
+		{
+			name:               "happy path using attributes for username and groups, with a session time limit",
+			usernameAttribute:  usernameAttribute,
+			groupsAttribute:    groupsAttribute,
+			registerSPEntityID: issuer,
+			setupIDP: func(idp *samltestutil.IDP) {
+				idp.SessionNotOnOrAfter = &sessionNotOnOrAfter
+				idp.Session.CustomAttributes = []saml.Attribute{
+					{Name: usernameAttribute, Values: []saml.AttributeValue{{Value: "pinny@example.com"}}},
+					{Name: groupsAttribute, Values: []saml.AttributeValue{{Value: "group1"}, {Value: "group2"}}},
+				}
+			},
+			wantUser: &upstreamprovider.SAMLUser{
+				Username:            "pinny@example.com",
+				Groups:              []string{"group1", "group2"},
+				DownstreamSubject:   "https://idp.example.com/metadata?idpName=some+display+name&sub=some-name-id",
+				NameID:              "some-name-id",
+				SessionNotOnOrAfter: sessionNotOnOrAfter,
+			},
+		},
+		{
+			name:               "happy path using friendly names for attributes",
+			usernameAttribute:  "upn",
+			groupsAttribute:    "eduPersonAffiliation",
+			registerSPEntityID: issuer,
+			setupIDP: func(idp *samltestutil.IDP) {
+				idp.Session.Groups = []string{"group1", "group2"}
+				idp.Session.CustomAttributes = []saml.Attribute{
+					{FriendlyName: "upn", Name: usernameAttribute, Values: []saml.AttributeValue{{Value: "pinny@example.com"}}},
+				}
+			},
+			wantUser: &upstreamprovider.SAMLUser{
+				Username:          "pinny@example.com",
+				Groups:            []string{"group1", "group2"},
+				DownstreamSubject: "https://idp.example.com/metadata?idpName=some+display+name&sub=some-name-id",
+				NameID:            "some-name-id",
+			},
+		},
+		{
+			name:               "happy path with a configured SP entity ID",
+			spEntityID:         "urn:some-configured-sp-entity-id",
+			registerSPEntityID: "urn:some-configured-sp-entity-id",
+			wantUser: &upstreamprovider.SAMLUser{
+				Username:          "some-name-id",
+				Groups:            []string{},
+				DownstreamSubject: "https://idp.example.com/metadata?idpName=some+display+name&sub=some-name-id",
+				NameID:            "some-name-id",
+			},
+		},
+		{
+			name:               "username attribute is missing",
+			usernameAttribute:  usernameAttribute,
+			registerSPEntityID: issuer,
+			wantErr:            `SAML assertion must contain exactly one non-empty value for username attribute "http://schemas.xmlsoap.org/ws/2005/05/identity/claims/upn", but found 0 values`,
+		},
+		{
+			name:               "username attribute has multiple values",
+			usernameAttribute:  usernameAttribute,
+			registerSPEntityID: issuer,
+			setupIDP: func(idp *samltestutil.IDP) {
+				idp.Session.CustomAttributes = []saml.Attribute{
+					{Name: usernameAttribute, Values: []saml.AttributeValue{{Value: "a"}, {Value: "b"}}},
+				}
+			},
+			wantErr: `SAML assertion must contain exactly one non-empty value for username attribute "http://schemas.xmlsoap.org/ws/2005/05/identity/claims/upn", but found 2 values`,
+		},
+		{
+			name:               "NameID is empty",
+			registerSPEntityID: issuer,
+			setupIDP: func(idp *samltestutil.IDP) {
+				idp.Session.NameID = ""
+			},
+			wantErr: "SAML assertion does not contain a NameID for its subject",
+		},
+		{
+			name:               "response is for a different request ID",
+			registerSPEntityID: issuer,
+			parseRequestID:     "id-some-other-request-id",
+			wantErr:            "SAML response was not valid: `InResponseTo` does not match any of the possible request IDs (expected [id-some-other-request-id])",
+		},
+		{
+			name:               "response is for a different ACS URL",
+			registerSPEntityID: issuer,
+			parseACSURL:        "https://some-other-issuer.example.com/callback",
+			wantErr:            "SAML response was not valid: `Destination` does not match AcsURL (expected \"https://some-other-issuer.example.com/callback\", actual \"https://issuer.example.com/some/path/callback\")",
+		},
+		{
+			name:               "assertion is for a different audience",
+			registerSPEntityID: issuer,
+			setupIDP: func(idp *samltestutil.IDP) {
+				idp.MutateAssertion = func(assertion *saml.Assertion) {
+					assertion.Conditions.AudienceRestrictions[0].Audience.Value = "some-other-sp"
+				}
+			},
+			wantErr: `SAML response was not valid: assertion Conditions AudienceRestriction does not contain "https://issuer.example.com/some/path"`,
+		},
+		{
+			name:               "assertion is expired",
+			registerSPEntityID: issuer,
+			setupIDP: func(idp *samltestutil.IDP) {
+				idp.MutateAssertion = func(assertion *saml.Assertion) {
+					assertion.Conditions.NotOnOrAfter = time.Now().Add(-time.Hour)
+				}
+			},
+			wantErr: "SAML response was not valid: assertion Conditions is expired",
+		},
+		{
+			name:                     "assertion is signed by a different IDP with the same entity ID",
+			registerSPEntityID:       issuer,
+			parseMetadataFromOtherID: true,
+			wantErr:                  "SAML response was not valid: cannot validate signature on Response: Could not verify certificate against trusted certs",
+		},
+		{
+			name:               "assertion was tampered with after being signed",
+			registerSPEntityID: issuer,
+			mutateResponse: func(samlResponse string) string {
+				decoded, err := base64.StdEncoding.DecodeString(samlResponse)
+				require.NoError(t, err)
+				tampered := strings.Replace(string(decoded), "some-name-id", "some-admin", 1)
+				return base64.StdEncoding.EncodeToString([]byte(tampered))
+			},
+			wantErr: "SAML response was not valid: cannot validate signature on Response: Signature could not be verified",
+		},
+		{
+			name:               "response is not base64",
+			registerSPEntityID: issuer,
+			mutateResponse: func(_ string) string {
+				return "not base64!"
+			},
+			wantErr: "could not base64 decode SAML response: illegal base64 data at input byte 3",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			idp := samltestutil.NewIDP(t, idpBaseURL)
+			idp.RegisterServiceProvider(tt.registerSPEntityID, acsURL)
+			if tt.setupIDP != nil {
+				tt.setupIDP(idp)
+			}
+
+			metadata, err := ParseMetadata(idp.MetadataXML())
+			require.NoError(t, err)
+
+			subject := New(ProviderConfig{
+				Name:              "some-name",
+				ResourceUID:       "some-uid",
+				IDPMetadata:       metadata,
+				SPEntityID:        tt.spEntityID,
+				UsernameAttribute: tt.usernameAttribute,
+				GroupsAttribute:   tt.groupsAttribute,
+			})
+
+			authnRequestURL, err := subject.AuthnRequestURL(requestID, relayState, acsURL, issuer)
+			require.NoError(t, err)
+
+			parsedAuthnRequestURL, err := url.Parse(authnRequestURL)
+			require.NoError(t, err)
+			require.Equal(t, "https://idp.example.com/sso", parsedAuthnRequestURL.Scheme+"://"+parsedAuthnRequestURL.Host+parsedAuthnRequestURL.Path)
+			require.Equal(t, relayState, parsedAuthnRequestURL.Query().Get("RelayState"))
+			require.NotEmpty(t, parsedAuthnRequestURL.Query().Get("SAMLRequest"))
+
+			samlResponse, gotRelayState := idp.Login(authnRequestURL)
+			require.Equal(t, relayState, gotRelayState)
+
+			if tt.mutateResponse != nil {
+				samlResponse = tt.mutateResponse(samlResponse)
+			}
+
+			parser := subject
+			if tt.parseMetadataFromOtherID {
+				otherIDPMetadata, err := ParseMetadata(samltestutil.NewIDP(t, idpBaseURL).MetadataXML())
+				require.NoError(t, err)
+				parser = New(ProviderConfig{IDPMetadata: otherIDPMetadata})
+			}
+
+			parseRequestID := requestID
+			if tt.parseRequestID != "" {
+				parseRequestID = tt.parseRequestID
+			}
+			parseACSURL := acsURL
+			if tt.parseACSURL != "" {
+				parseACSURL = tt.parseACSURL
+			}
+
+			user, err := parser.ParseResponse(samlResponse, parseRequestID, parseACSURL, issuer, idpDisplayName)
+			if tt.wantErr != "" {
+				require.EqualError(t, err, tt.wantErr)
+				require.Nil(t, user)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.wantUser, user)
+		})
+	}
+}
+
+func TestAuthnRequestURLErrors(t *testing.T) {
+	subject := New(ProviderConfig{IDPMetadata: &saml.EntityDescriptor{EntityID: "some-idp"}})
+
+	_, err := subject.AuthnRequestURL(requestID, relayState, acsURL, issuer)
+	require.EqualError(t, err, "IDP metadata does not include a single sign-on service using the HTTP-Redirect binding")
+
+	_, err = subject.AuthnRequestURL(requestID, relayState, "https://bad url%", issuer)
+	require.EqualError(t, err, `could not parse assertion consumer service URL: parse "https://bad url%": invalid character " " in host name`)
+}
+
+func replaceFirstCertificate(t *testing.T, metadata string, replacement string) string {
+	t.Helper()
+	const startTag = "<X509Certificate xmlns=\"http://www.w3.org/2000/09/xmldsig#\">"
+	start := strings.Index(metadata, startTag)
+	require.NotEqual(t, -1, start, "could not find certificate in metadata: %s", metadata)
+	start += len(startTag)
+	end := strings.Index(metadata[start:], "</X509Certificate>")
+	require.NotEqual(t, -1, end)
+	return metadata[:start] + replacement + metadata[start+end:]
+}
@@ -1,4 +1,4 @@
-// Copyright 2020-2025 the Pinniped contributors. All Rights Reserved.
+// Copyright 2020-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 // Package filesession implements the file format for session caches.
@@ -156,3 +156,16 @@ func (c *sessionCache) lookup(key oidcclient.SessionCacheKey) *sessionEntry {
 func (c *sessionCache) insert(entries ...sessionEntry) {
 	c.Sessions = slices.Concat(c.Sessions, entries)
 }
+
+// delete the cache entries whose keys match, returning their tokens.
+func (c *sessionCache) delete(match func(oidcclient.SessionCacheKey) bool) []*oidctypes.Token {
+	var deleted []*oidctypes.Token
+	c.Sessions = slices.DeleteFunc(c.Sessions, func(s sessionEntry) bool {
+		if match(s.Key) {
+			deleted = append(deleted, &s.Tokens)
+			return true
+		}
+		return false
+	})
+	return deleted
+}
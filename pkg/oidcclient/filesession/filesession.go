@@ -1,4 +1,4 @@
-// Copyright 2020-2021 the Pinniped contributors. All Rights Reserved.
+// Copyright 2020-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 // Package filesession implements a simple YAML file-based login.sessionCache.
@@ -113,6 +113,23 @@ func (c *Cache) PutToken(key oidcclient.SessionCacheKey, token *oidctypes.Token)
 	})
 }
 
+// DeleteTokens removes all cached sessions for the given issuer and client ID, regardless of their other key fields,
+// and returns the tokens which were removed. It does not return an error but may silently fail to update the session cache.
+func (c *Cache) DeleteTokens(issuer string, clientID string) []*oidctypes.Token {
+	// If the cache file does not exist, exit immediately with no error log
+	if _, err := os.Stat(c.path); errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+
+	var result []*oidctypes.Token
+	c.withCache(func(cache *sessionCache) {
+		result = cache.delete(func(key oidcclient.SessionCacheKey) bool {
+			return key.Issuer == issuer && key.ClientID == clientID
+		})
+	})
+	return result
+}
+
 // withCache is an internal helper which locks, reads the cache, processes/mutates it with the provided function, then
 // saves it back to the file.
 func (c *Cache) withCache(transact func(*sessionCache)) {
@@ -494,6 +494,66 @@ func TestPutToken(t *testing.T) {
 	}
 }
 
+func TestDeleteTokens(t *testing.T) {
+	t.Parallel()
+	now := time.Now().Round(1 * time.Second)
+
+	newEntry := func(issuer, clientID, refreshToken string) sessionEntry {
+		return sessionEntry{
+			Key: oidcclient.SessionCacheKey{
+				Issuer:      issuer,
+				ClientID:    clientID,
+				Scopes:      []string{"offline_access", "openid"},
+				RedirectURI: "http://localhost:0/callback",
+			},
+			CreationTimestamp: metav1.NewTime(now.Add(-2 * time.Hour)),
+			LastUsedTimestamp: metav1.NewTime(now.Add(-1 * time.Hour)),
+			Tokens: oidctypes.Token{
+				RefreshToken: &oidctypes.RefreshToken{Token: refreshToken},
+			},
+		}
+	}
+
+	t.Run("cache file does not exist", func(t *testing.T) {
+		t.Parallel()
+		tmp := t.TempDir() + "/sessiondir/sessions.yaml"
+		errors := errorCollector{t: t}
+		require.Empty(t, New(tmp, errors.collect()).DeleteTokens("test-issuer", "test-client-id"))
+		errors.require(nil)
+		require.NoFileExists(t, tmp)
+	})
+
+	t.Run("removes only the matching entries", func(t *testing.T) {
+		t.Parallel()
+		tmp := t.TempDir() + "/sessiondir/sessions.yaml"
+		validCache := emptySessionCache()
+		matchingWithUpstream := newEntry("test-issuer", "test-client-id", "refresh-token-2")
+		matchingWithUpstream.Key.UpstreamProviderName = "some-upstream"
+		validCache.insert(
+			newEntry("test-issuer", "test-client-id", "refresh-token-1"),
+			matchingWithUpstream,
+			newEntry("other-issuer", "test-client-id", "refresh-token-3"),
+			newEntry("test-issuer", "other-client-id", "refresh-token-4"),
+		)
+		require.NoError(t, os.MkdirAll(filepath.Dir(tmp), 0700))
+		require.NoError(t, validCache.writeTo(tmp))
+
+		errors := errorCollector{t: t}
+		deleted := New(tmp, errors.collect()).DeleteTokens("test-issuer", "test-client-id")
+		errors.require(nil)
+
+		require.Len(t, deleted, 2)
+		require.Equal(t, "refresh-token-1", deleted[0].RefreshToken.Token)
+		require.Equal(t, "refresh-token-2", deleted[1].RefreshToken.Token)
+
+		cache, err := readSessionCache(tmp)
+		require.NoError(t, err)
+		require.Len(t, cache.Sessions, 2)
+		require.Equal(t, "refresh-token-3", cache.Sessions[0].Tokens.RefreshToken.Token)
+		require.Equal(t, "refresh-token-4", cache.Sessions[1].Tokens.RefreshToken.Token)
+	})
+}
+
 type errorCollector struct {
 	t   *testing.T
 	saw []error
@@ -319,20 +319,26 @@ func WithCLISendingCredentials() Option {
 // could potentially get logged somewhere by the issuer.
 // When the argument is equal to idpdiscoveryv1alpha1.IDPFlowBrowserAuthcode, it will attempt to open a web browser
 // and perform the OIDC authcode flow.
+// When the argument is equal to idpdiscoveryv1alpha1.IDPFlowDeviceCode, it will perform the OAuth 2.0 device
+// authorization grant (RFC 8628). It prints a link and a code for the user to visit in a web browser on any other
+// device, and polls the token endpoint until the user has logged in. This is useful when the CLI is running on a
+// machine which has no web browser, such as a remote server accessed over SSH.
 // When not used, the default when the issuer is a Pinniped Supervisor will be determined automatically,
 // and the default for non-Supervisor issuers will be the browser authcode flow.
 func WithLoginFlow(loginFlow idpdiscoveryv1alpha1.IDPFlow, flowSource string) Option {
 	return func(h *handlerState) error {
 		switch loginFlow {
 		case idpdiscoveryv1alpha1.IDPFlowCLIPassword,
-			idpdiscoveryv1alpha1.IDPFlowBrowserAuthcode:
+			idpdiscoveryv1alpha1.IDPFlowBrowserAuthcode,
+			idpdiscoveryv1alpha1.IDPFlowDeviceCode:
 		default:
 			return fmt.Errorf(
-				"WithLoginFlow error: loginFlow '%s' from '%s' must be '%s' or '%s'",
+				"WithLoginFlow error: loginFlow '%s' from '%s' must be '%s', '%s', or '%s'",
 				loginFlow,
 				flowSource,
 				idpdiscoveryv1alpha1.IDPFlowCLIPassword,
 				idpdiscoveryv1alpha1.IDPFlowBrowserAuthcode,
+				idpdiscoveryv1alpha1.IDPFlowDeviceCode,
 			)
 		}
 		h.loginFlow = loginFlow
@@ -606,6 +612,11 @@ func (h *handlerState) baseLogin() (*oidctypes.Token, error) {
 		authFunc = h.cliBasedAuth
 	case idpdiscoveryv1alpha1.IDPFlowBrowserAuthcode:
 		// NOOP
+	case idpdiscoveryv1alpha1.IDPFlowDeviceCode:
+		// The device authorization request does not use the nonce or PKCE, so only send the Supervisor's options.
+		authFunc = func(_ *[]oauth2.AuthCodeOption) (*oidctypes.Token, error) {
+			return h.deviceCodeBasedAuth(pinnipedSupervisorOptions)
+		}
 	}
 
 	// Perform the authorize request and authcode exchange to get back OIDC tokens.
@@ -812,6 +823,50 @@ func (h *handlerState) cliBasedAuth(authorizeOptions *[]oauth2.AuthCodeOption) (
 	return token, nil
 }
 
+// Start a device authorization request, and ask the user to visit the verification URL in a web browser
+// on any device. Poll the token endpoint until the user has finished logging in. Return the tokens or an error.
+// See https://datatracker.ietf.org/doc/html/rfc8628.
+func (h *handlerState) deviceCodeBasedAuth(deviceAuthOptions []oauth2.AuthCodeOption) (*oidctypes.Token, error) {
+	if h.oauth2Config.Endpoint.DeviceAuthURL == "" {
+		return nil, fmt.Errorf("login failed: OIDC issuer %q does not offer a device authorization endpoint", h.issuer)
+	}
+	if err := validateURLUsesHTTPS(h.oauth2Config.Endpoint.DeviceAuthURL, "discovered device authorization URL from issuer"); err != nil {
+		return nil, err
+	}
+
+	deviceAuthCtx, deviceAuthCtxCancelFunc := context.WithTimeout(h.ctx, httpRequestTimeout)
+	defer deviceAuthCtxCancelFunc()
+	deviceAuth, err := h.oauth2Config.DeviceAuth(deviceAuthCtx, deviceAuthOptions...)
+	if err != nil {
+		return nil, fmt.Errorf("device authorization request failed: %w", err)
+	}
+
+	if deviceAuth.VerificationURIComplete != "" {
+		_, _ = fmt.Fprintf(h.out, "Log in by visiting this link on any device:\n\n    %s\n\n", deviceAuth.VerificationURIComplete)
+		_, _ = fmt.Fprintf(h.out, "Confirm that the page shows this code: %s\n\n", deviceAuth.UserCode)
+	} else {
+		_, _ = fmt.Fprintf(h.out, "Log in by visiting this link on any device:\n\n    %s\n\n", deviceAuth.VerificationURI)
+		_, _ = fmt.Fprintf(h.out, "Enter this code: %s\n\n", deviceAuth.UserCode)
+	}
+
+	// This polls the token endpoint until the user finishes, the device code expires, or the context is cancelled.
+	tok, err := h.oauth2Config.DeviceAccessToken(h.ctx, deviceAuth)
+	if err != nil {
+		return nil, fmt.Errorf("could not complete device authorization: %w", err)
+	}
+
+	// The device authorization grant does not use a nonce, so skip nonce validation (but not other validations).
+	tokenCtx, tokenCtxCancelFunc := context.WithTimeout(h.ctx, httpRequestTimeout)
+	defer tokenCtxCancelFunc()
+	token, err := h.getProvider(h.oauth2Config, h.provider, h.httpClient).
+		ValidateTokenAndMergeWithUserInfo(tokenCtx, tok, "", true, false)
+	if err != nil {
+		return nil, fmt.Errorf("could not complete device authorization: %w", err)
+	}
+
+	return token, nil
+}
+
 // Prompt for the user's username and password, or read them from env vars if they are available.
 func (h *handlerState) getUsernameAndPassword() (string, string, error) {
 	var err error
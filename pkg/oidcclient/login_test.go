@@ -271,6 +271,7 @@ func TestLogin(t *testing.T) { //nolint:gocyclo
 				AuthURL                string                                                `json:"authorization_endpoint"`
 				TokenURL               string                                                `json:"token_endpoint"`
 				JWKSURL                string                                                `json:"jwks_uri"`
+				DeviceAuthURL          string                                                `json:"device_authorization_endpoint"`
 				ResponseModesSupported []string                                              `json:"response_modes_supported,omitempty"`
 				SupervisorDiscovery    idpdiscoveryv1alpha1.OIDCDiscoveryResponseIDPEndpoint `json:"discovery.supervisor.pinniped.dev/v1alpha1"`
 			}{
@@ -278,6 +279,7 @@ func TestLogin(t *testing.T) { //nolint:gocyclo
 				AuthURL:                server.URL + "/authorize",
 				TokenURL:               server.URL + "/token",
 				JWKSURL:                server.URL + "/keys",
+				DeviceAuthURL:          server.URL + "/device_authorization",
 				ResponseModesSupported: responseModes,
 				SupervisorDiscovery: idpdiscoveryv1alpha1.OIDCDiscoveryResponseIDPEndpoint{
 					PinnipedIDPsEndpoint: server.URL + federationdomainoidc.PinnipedIDPsPathV1Alpha1,
@@ -301,6 +303,7 @@ func TestLogin(t *testing.T) { //nolint:gocyclo
 						Flows: []idpdiscoveryv1alpha1.IDPFlow{
 							idpdiscoveryv1alpha1.IDPFlowCLIPassword,
 							idpdiscoveryv1alpha1.IDPFlowBrowserAuthcode,
+							idpdiscoveryv1alpha1.IDPFlowDeviceCode,
 						},
 					},
 					{
@@ -517,14 +520,14 @@ func TestLogin(t *testing.T) { //nolint:gocyclo
 			opt: func(t *testing.T) Option {
 				return WithLoginFlow("this is not one of the enum values", "some-flow-source")
 			},
-			wantErr: "WithLoginFlow error: loginFlow 'this is not one of the enum values' from 'some-flow-source' must be 'cli_password' or 'browser_authcode'",
+			wantErr: "WithLoginFlow error: loginFlow 'this is not one of the enum values' from 'some-flow-source' must be 'cli_password', 'browser_authcode', or 'device_code'",
 		},
 		{
 			name: "WithLoginFlow option will not accept empty string either",
 			opt: func(t *testing.T) Option {
 				return WithLoginFlow("", "other-flow-source")
 			},
-			wantErr: "WithLoginFlow error: loginFlow '' from 'other-flow-source' must be 'cli_password' or 'browser_authcode'",
+			wantErr: "WithLoginFlow error: loginFlow '' from 'other-flow-source' must be 'cli_password', 'browser_authcode', or 'device_code'",
 		},
 		{
 			name: "error generating state",
@@ -1777,6 +1780,204 @@ func TestLogin(t *testing.T) { //nolint:gocyclo
 			wantStdErr: "^\nLog in to upstream-idp-name-with-cli-password-flow-first\n\n$",
 			wantToken:  &testToken,
 		},
+		{
+			name:     "successful device code login",
+			clientID: "test-client-id",
+			opt: func(t *testing.T) Option {
+				return func(h *handlerState) error {
+					h.getProvider = func(_ *oauth2.Config, _ *coreosoidc.Provider, _ *http.Client) upstreamprovider.UpstreamOIDCIdentityProviderI {
+						mock := mockUpstream(t)
+						mock.EXPECT().
+							ValidateTokenAndMergeWithUserInfo(gomock.Any(), gomock.Any(), nonce.Nonce(""), true, false).
+							DoAndReturn(func(_ context.Context, tok *oauth2.Token, _ nonce.Nonce, _ bool, _ bool) (*oidctypes.Token, error) {
+								require.Equal(t, "test-device-access-token", tok.AccessToken)
+								require.Equal(t, "test-device-id-token", tok.Extra("id_token"))
+								return &testToken, nil
+							})
+						return mock
+					}
+
+					cache := &mockSessionCache{t: t, getReturnsToken: nil}
+					cacheKey := SessionCacheKey{
+						Issuer:               successServer.URL,
+						ClientID:             "test-client-id",
+						Scopes:               []string{"test-scope"},
+						RedirectURI:          "http://localhost:0/callback",
+						UpstreamProviderName: "upstream-idp-name-with-cli-password-flow-first",
+					}
+					t.Cleanup(func() {
+						require.Equal(t, []SessionCacheKey{cacheKey}, cache.sawGetKeys)
+						require.Equal(t, []SessionCacheKey{cacheKey}, cache.sawPutKeys)
+						require.Equal(t, []*oidctypes.Token{&testToken}, cache.sawPutTokens)
+					})
+					require.NoError(t, WithSessionCache(cache)(h))
+					require.NoError(t, WithLoginFlow(idpdiscoveryv1alpha1.IDPFlowDeviceCode, "flowSource")(h))
+					require.NoError(t, WithUpstreamIdentityProvider("upstream-idp-name-with-cli-password-flow-first", "upstream-idp-type-with-cli-password-flow-first")(h))
+
+					deviceAuthorizationRequestWasMade := false
+					tokenRequestCount := 0
+					t.Cleanup(func() {
+						require.True(t, deviceAuthorizationRequestWasMade, "should have made a device authorization request")
+						require.Equal(t, 2, tokenRequestCount, "should have polled the token endpoint twice")
+					})
+
+					client := buildHTTPClientForPEM(successServerCA)
+					client.Transport = roundtripper.Func(func(req *http.Request) (*http.Response, error) {
+						switch req.URL.Scheme + "://" + req.URL.Host + req.URL.Path {
+						case "https://" + successServer.Listener.Addr().String() + "/.well-known/openid-configuration":
+							return defaultDiscoveryResponse(req)
+						case "https://" + successServer.Listener.Addr().String() + federationdomainoidc.PinnipedIDPsPathV1Alpha1:
+							return defaultDiscoveryResponse(req)
+						case "https://" + successServer.Listener.Addr().String() + "/device_authorization":
+							deviceAuthorizationRequestWasMade = true
+							require.Equal(t, http.MethodPost, req.Method)
+							require.NoError(t, req.ParseForm())
+							require.Equal(t, url.Values{
+								"client_id":         []string{"test-client-id"},
+								"scope":             []string{"test-scope"},
+								"pinniped_idp_name": []string{"upstream-idp-name-with-cli-password-flow-first"},
+								"pinniped_idp_type": []string{"upstream-idp-type-with-cli-password-flow-first"},
+							}, req.PostForm)
+							return &http.Response{
+								StatusCode: http.StatusOK,
+								Header:     http.Header{"content-type": []string{"application/json"}},
+								Body: io.NopCloser(strings.NewReader(`{
+									"device_code": "test-device-code",
+									"user_code": "ABCD-EFGH",
+									"verification_uri": "https://example.com/oauth2/device",
+									"verification_uri_complete": "https://example.com/oauth2/device?user_code=ABCD-EFGH",
+									"expires_in": 300,
+									"interval": 1
+								}`)),
+							}, nil
+						case "https://" + successServer.Listener.Addr().String() + "/token":
+							tokenRequestCount++
+							require.NoError(t, req.ParseForm())
+							require.Equal(t, url.Values{
+								"client_id":   []string{"test-client-id"},
+								"grant_type":  []string{"urn:ietf:params:oauth:grant-type:device_code"},
+								"device_code": []string{"test-device-code"},
+								"scope":       []string{"test-scope"},
+							}, req.PostForm)
+							if tokenRequestCount == 1 {
+								return &http.Response{
+									StatusCode: http.StatusBadRequest,
+									Header:     http.Header{"content-type": []string{"application/json"}},
+									Body:       io.NopCloser(strings.NewReader(`{"error": "authorization_pending"}`)),
+								}, nil
+							}
+							return &http.Response{
+								StatusCode: http.StatusOK,
+								Header:     http.Header{"content-type": []string{"application/json"}},
+								Body: io.NopCloser(strings.NewReader(`{
+									"access_token": "test-device-access-token",
+									"id_token": "test-device-id-token",
+									"refresh_token": "test-device-refresh-token",
+									"token_type": "bearer",
+									"expires_in": 120
+								}`)),
+							}, nil
+						default:
+							require.FailNow(t, fmt.Sprintf("saw unexpected http call from the CLI: %s", req.URL.String()))
+							return nil, nil
+						}
+					})
+					require.NoError(t, WithClient(client)(h))
+					return nil
+				}
+			},
+			issuer:     successServer.URL,
+			wantLogs:   []string{`"level"=4 "msg"="Pinniped: Performing OIDC discovery"  "issuer"="` + successServer.URL + `"`},
+			wantStdErr: "^Log in by visiting this link on any device:\n\n    https://example.com/oauth2/device\\?user_code=ABCD-EFGH\n\nConfirm that the page shows this code: ABCD-EFGH\n\n$",
+			wantToken:  &testToken,
+		},
+		{
+			name:     "device code login when the user denies access",
+			clientID: "test-client-id",
+			opt: func(t *testing.T) Option {
+				return func(h *handlerState) error {
+					cache := &mockSessionCache{t: t, getReturnsToken: nil}
+					require.NoError(t, WithSessionCache(cache)(h))
+					require.NoError(t, WithLoginFlow(idpdiscoveryv1alpha1.IDPFlowDeviceCode, "flowSource")(h))
+					require.NoError(t, WithUpstreamIdentityProvider("upstream-idp-name-with-cli-password-flow-first", "upstream-idp-type-with-cli-password-flow-first")(h))
+
+					client := buildHTTPClientForPEM(successServerCA)
+					client.Transport = roundtripper.Func(func(req *http.Request) (*http.Response, error) {
+						switch req.URL.Scheme + "://" + req.URL.Host + req.URL.Path {
+						case "https://" + successServer.Listener.Addr().String() + "/.well-known/openid-configuration":
+							return defaultDiscoveryResponse(req)
+						case "https://" + successServer.Listener.Addr().String() + federationdomainoidc.PinnipedIDPsPathV1Alpha1:
+							return defaultDiscoveryResponse(req)
+						case "https://" + successServer.Listener.Addr().String() + "/device_authorization":
+							return &http.Response{
+								StatusCode: http.StatusOK,
+								Header:     http.Header{"content-type": []string{"application/json"}},
+								Body: io.NopCloser(strings.NewReader(`{
+									"device_code": "test-device-code",
+									"user_code": "ABCD-EFGH",
+									"verification_uri": "https://example.com/oauth2/device",
+									"expires_in": 300,
+									"interval": 1
+								}`)),
+							}, nil
+						case "https://" + successServer.Listener.Addr().String() + "/token":
+							return &http.Response{
+								StatusCode: http.StatusBadRequest,
+								Header:     http.Header{"content-type": []string{"application/json"}},
+								Body:       io.NopCloser(strings.NewReader(`{"error": "access_denied"}`)),
+							}, nil
+						default:
+							require.FailNow(t, fmt.Sprintf("saw unexpected http call from the CLI: %s", req.URL.String()))
+							return nil, nil
+						}
+					})
+					require.NoError(t, WithClient(client)(h))
+					return nil
+				}
+			},
+			issuer:     successServer.URL,
+			wantLogs:   []string{`"level"=4 "msg"="Pinniped: Performing OIDC discovery"  "issuer"="` + successServer.URL + `"`},
+			wantStdErr: "^Log in by visiting this link on any device:\n\n    https://example.com/oauth2/device\n\nEnter this code: ABCD-EFGH\n\n$",
+			wantErr:    `could not complete device authorization: oauth2: "access_denied"`,
+		},
+		{
+			name:     "device code login with an issuer that does not offer a device authorization endpoint",
+			clientID: "test-client-id",
+			opt: func(t *testing.T) Option {
+				return func(h *handlerState) error {
+					cache := &mockSessionCache{t: t, getReturnsToken: nil}
+					require.NoError(t, WithSessionCache(cache)(h))
+					require.NoError(t, WithLoginFlow(idpdiscoveryv1alpha1.IDPFlowDeviceCode, "flowSource")(h))
+					require.NoError(t, WithUpstreamIdentityProvider("upstream-idp-name-with-cli-password-flow-first", "upstream-idp-type-with-cli-password-flow-first")(h))
+
+					client := buildHTTPClientForPEM(successServerCA)
+					client.Transport = roundtripper.Func(func(req *http.Request) (*http.Response, error) {
+						switch req.URL.Scheme + "://" + req.URL.Host + req.URL.Path {
+						case "https://" + successServer.Listener.Addr().String() + "/.well-known/openid-configuration":
+							resp, err := defaultDiscoveryResponse(req)
+							require.NoError(t, err)
+							var discoveryJSON map[string]any
+							require.NoError(t, json.NewDecoder(resp.Body).Decode(&discoveryJSON))
+							delete(discoveryJSON, "device_authorization_endpoint")
+							discoveryBytes, err := json.Marshal(discoveryJSON)
+							require.NoError(t, err)
+							resp.Body = io.NopCloser(bytes.NewReader(discoveryBytes))
+							return resp, nil
+						case "https://" + successServer.Listener.Addr().String() + federationdomainoidc.PinnipedIDPsPathV1Alpha1:
+							return defaultDiscoveryResponse(req)
+						default:
+							require.FailNow(t, fmt.Sprintf("saw unexpected http call from the CLI: %s", req.URL.String()))
+							return nil, nil
+						}
+					})
+					require.NoError(t, WithClient(client)(h))
+					return nil
+				}
+			},
+			issuer:   successServer.URL,
+			wantLogs: []string{`"level"=4 "msg"="Pinniped: Performing OIDC discovery"  "issuer"="` + successServer.URL + `"`},
+			wantErr:  `login failed: OIDC issuer "` + successServer.URL + `" does not offer a device authorization endpoint`,
+		},
 		{
 			name:     "unable to login with unknown IDP, when Supervisor provides its supported IDP types",
 			clientID: "test-client-id",
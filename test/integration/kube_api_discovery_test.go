@@ -1,4 +1,4 @@
-// Copyright 2020-2025 the Pinniped contributors. All Rights Reserved.
+// Copyright 2020-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package integration
@@ -57,6 +57,7 @@ func TestGetAPIResourceList(t *testing.T) { //nolint:gocyclo // each t.Run is pr
 	idpSupervisorGV := makeGV("idp", "supervisor")
 	configSupervisorGV := makeGV("config", "supervisor")
 	clientSecretSupervisorGV := makeGV("clientsecret", "supervisor")
+	sessionSupervisorGV := makeGV("session", "supervisor")
 
 	tests := []struct {
 		group             metav1.APIGroup
@@ -143,6 +144,33 @@ func TestGetAPIResourceList(t *testing.T) { //nolint:gocyclo // each t.Run is pr
 				},
 			},
 		},
+		{
+			group: metav1.APIGroup{
+				Name: sessionSupervisorGV.Group,
+				Versions: []metav1.GroupVersionForDiscovery{
+					{
+						GroupVersion: sessionSupervisorGV.String(),
+						Version:      sessionSupervisorGV.Version,
+					},
+				},
+				PreferredVersion: metav1.GroupVersionForDiscovery{
+					GroupVersion: sessionSupervisorGV.String(),
+					Version:      sessionSupervisorGV.Version,
+				},
+			},
+			resourceByVersion: map[string][]metav1.APIResource{
+				sessionSupervisorGV.String(): {
+					{
+						Name:         "sessions",
+						SingularName: "session",
+						Kind:         "Session",
+						Verbs:        []string{"delete", "get", "list"},
+						Namespaced:   true,
+						Categories:   []string{"pinniped"},
+					},
+				},
+			},
+		},
 		{
 			group: metav1.APIGroup{
 				Name: configSupervisorGV.Group,
@@ -262,6 +290,48 @@ func TestGetAPIResourceList(t *testing.T) { //nolint:gocyclo // each t.Run is pr
 						Kind:       "GitHubIdentityProvider",
 						Verbs:      []string{"get", "patch", "update"},
 					},
+					{
+						Name:         "samlidentityproviders",
+						SingularName: "samlidentityprovider",
+						Namespaced:   true,
+						Kind:         "SAMLIdentityProvider",
+						Verbs:        []string{"delete", "deletecollection", "get", "list", "patch", "create", "update", "watch"},
+						Categories:   []string{"pinniped", "pinniped-idp", "pinniped-idps"},
+					},
+					{
+						Name:       "samlidentityproviders/status",
+						Namespaced: true,
+						Kind:       "SAMLIdentityProvider",
+						Verbs:      []string{"get", "patch", "update"},
+					},
+					{
+						Name:         "gitlabidentityproviders",
+						SingularName: "gitlabidentityprovider",
+						Namespaced:   true,
+						Kind:         "GitLabIdentityProvider",
+						Verbs:        []string{"delete", "deletecollection", "get", "list", "patch", "create", "update", "watch"},
+						Categories:   []string{"pinniped", "pinniped-idp", "pinniped-idps"},
+					},
+					{
+						Name:       "gitlabidentityproviders/status",
+						Namespaced: true,
+						Kind:       "GitLabIdentityProvider",
+						Verbs:      []string{"get", "patch", "update"},
+					},
+					{
+						Name:         "oauth2identityproviders",
+						SingularName: "oauth2identityprovider",
+						Namespaced:   true,
+						Kind:         "OAuth2IdentityProvider",
+						Verbs:        []string{"delete", "deletecollection", "get", "list", "patch", "create", "update", "watch"},
+						Categories:   []string{"pinniped", "pinniped-idp", "pinniped-idps"},
+					},
+					{
+						Name:       "oauth2identityproviders/status",
+						Namespaced: true,
+						Kind:       "OAuth2IdentityProvider",
+						Verbs:      []string{"get", "patch", "update"},
+					},
 				},
 			},
 		},
@@ -400,7 +470,7 @@ func TestGetAPIResourceList(t *testing.T) { //nolint:gocyclo // each t.Run is pr
 	t.Run("every API has a status subresource", func(t *testing.T) {
 		t.Parallel()
 
-		aggregatedAPIs := sets.NewString("tokencredentialrequests", "whoamirequests", "oidcclientsecretrequests")
+		aggregatedAPIs := sets.NewString("tokencredentialrequests", "whoamirequests", "oidcclientsecretrequests", "sessions")
 
 		var regular, status []string
 
@@ -600,6 +670,27 @@ func TestCRDAdditionalPrinterColumns_Parallel(t *testing.T) {
 				{Name: "Age", Type: "date", JSONPath: ".metadata.creationTimestamp"},
 			},
 		},
+		addSuffix("samlidentityproviders.idp.supervisor"): {
+			"v1alpha1": []apiextensionsv1.CustomResourceColumnDefinition{
+				{Name: "Metadata URL", Type: "string", JSONPath: ".spec.metadata.url"},
+				{Name: "Status", Type: "string", JSONPath: ".status.phase"},
+				{Name: "Age", Type: "date", JSONPath: ".metadata.creationTimestamp"},
+			},
+		},
+		addSuffix("gitlabidentityproviders.idp.supervisor"): {
+			"v1alpha1": []apiextensionsv1.CustomResourceColumnDefinition{
+				{Name: "Host", Type: "string", JSONPath: ".spec.gitlabAPI.host"},
+				{Name: "Status", Type: "string", JSONPath: ".status.phase"},
+				{Name: "Age", Type: "date", JSONPath: ".metadata.creationTimestamp"},
+			},
+		},
+		addSuffix("oauth2identityproviders.idp.supervisor"): {
+			"v1alpha1": []apiextensionsv1.CustomResourceColumnDefinition{
+				{Name: "Authorization URL", Type: "string", JSONPath: ".spec.endpoints.authorizationURL"},
+				{Name: "Status", Type: "string", JSONPath: ".status.phase"},
+				{Name: "Age", Type: "date", JSONPath: ".metadata.creationTimestamp"},
+			},
+		},
 		addSuffix("oidcclients.config.supervisor"): {
 			"v1alpha1": []apiextensionsv1.CustomResourceColumnDefinition{
 				{Name: "Privileged Scopes", Type: "string", JSONPath: `.spec.allowedScopes[?(@ == "pinniped:request-audience")]`},
@@ -620,6 +711,9 @@ func TestCRDAdditionalPrinterColumns_Parallel(t *testing.T) {
 		"ldapidentityproviders.idp.supervisor." + env.APIGroupSuffix,
 		"oidcclients.config.supervisor." + env.APIGroupSuffix,
 		"oidcidentityproviders.idp.supervisor." + env.APIGroupSuffix,
+		"samlidentityproviders.idp.supervisor." + env.APIGroupSuffix,
+		"gitlabidentityproviders.idp.supervisor." + env.APIGroupSuffix,
+		"oauth2identityproviders.idp.supervisor." + env.APIGroupSuffix,
 		"webhookauthenticators.authentication.concierge." + env.APIGroupSuffix,
 	}
 
@@ -1,4 +1,4 @@
-// Copyright 2020-2025 the Pinniped contributors. All Rights Reserved.
+// Copyright 2020-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package integration
@@ -616,7 +616,18 @@ func requireWellKnownEndpointIsWorking(t *testing.T, supervisorScheme, superviso
       "issuer": "%s",
       "authorization_endpoint": "%s/oauth2/authorize",
       "token_endpoint": "%s/oauth2/token",
-      "token_endpoint_auth_methods_supported": ["client_secret_basic"],
+      "device_authorization_endpoint": "%s/oauth2/device_authorization",
+      "revocation_endpoint": "%s/oauth2/revoke",
+      "end_session_endpoint": "%s/oauth2/end_session",
+      "introspection_endpoint": "%s/oauth2/introspect",
+      "introspection_endpoint_auth_methods_supported": ["client_secret_basic", "private_key_jwt"],
+      "pushed_authorization_request_endpoint": "%s/oauth2/par",
+      "require_pushed_authorization_requests": false,
+      "request_parameter_supported": true,
+      "request_uri_parameter_supported": false,
+      "request_object_signing_alg_values_supported": ["RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512"],
+      "token_endpoint_auth_methods_supported": ["client_secret_basic", "private_key_jwt"],
+      "token_endpoint_auth_signing_alg_values_supported": ["RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512"],
       "jwks_uri": "%s/jwks.json",
       "scopes_supported": ["openid", "offline_access", "pinniped:request-audience", "username", "groups"],
       "response_types_supported": ["code"],
@@ -627,7 +638,7 @@ func requireWellKnownEndpointIsWorking(t *testing.T, supervisorScheme, superviso
       "subject_types_supported": ["public"],
       "id_token_signing_alg_values_supported": ["ES256"]
     }`)
-	expectedJSON := fmt.Sprintf(expectedResultTemplate, issuerName, issuerName, issuerName, issuerName, issuerName)
+	expectedJSON := fmt.Sprintf(expectedResultTemplate, issuerName, issuerName, issuerName, issuerName, issuerName, issuerName, issuerName, issuerName, issuerName, issuerName)
 
 	require.Equal(t, "application/json", response.Header.Get("content-type"))
 	require.JSONEq(t, expectedJSON, responseBody)
@@ -821,6 +832,8 @@ func withAllSuccessfulConditions() map[string]metav1.ConditionStatus {
 		"IdentityProvidersDisplayNamesUnique":           metav1.ConditionTrue,
 		"TransformsExpressionsValid":                    metav1.ConditionTrue,
 		"TransformsExamplesPassed":                      metav1.ConditionTrue,
+		"AuthenticationPoliciesValid":                   metav1.ConditionTrue,
+		"BrandingValid":                                 metav1.ConditionTrue,
 	}
 }
 
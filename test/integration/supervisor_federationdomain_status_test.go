@@ -999,6 +999,14 @@ func allSuccessfulLegacyFederationDomainConditions(t *testing.T, idpName string,
 
 func allSuccessfulFederationDomainConditions(federationDomainSpec supervisorconfigv1alpha1.FederationDomainSpec) []metav1.Condition {
 	return []metav1.Condition{
+		{
+			Type: "AuthenticationPoliciesValid", Status: "True", Reason: "Success",
+			Message: "the authentication policies specified by .spec.identityProviders[].authenticationPolicy are valid",
+		},
+		{
+			Type: "BrandingValid", Status: "True", Reason: "Success",
+			Message: "no branding is specified by .spec.branding, so the default branding is used",
+		},
 		{
 			Type: "IdentityProvidersDisplayNamesUnique", Status: "True", Reason: "Success",
 			Message: "the names specified by .spec.identityProviders[].displayName are unique",
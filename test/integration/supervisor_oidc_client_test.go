@@ -1,4 +1,4 @@
-// Copyright 2022-2024 the Pinniped contributors. All Rights Reserved.
+// Copyright 2022-2026 the Pinniped contributors. All Rights Reserved.
 // SPDX-License-Identifier: Apache-2.0
 
 package integration
@@ -224,7 +224,7 @@ func TestOIDCClientStaticValidation_Parallel(t *testing.T) {
 					},
 				},
 			},
-			wantErr: `OIDCClient.config.supervisor.pinniped.dev "client.oauth.pinniped.dev-sky" is invalid: spec.allowedGrantTypes[2]: Unsupported value: "bird": supported values: "authorization_code", "refresh_token", "urn:ietf:params:oauth:grant-type:token-exchange"`,
+			wantErr: `OIDCClient.config.supervisor.pinniped.dev "client.oauth.pinniped.dev-sky" is invalid: spec.allowedGrantTypes[2]: Unsupported value: "bird": supported values: "authorization_code", "refresh_token", "urn:ietf:params:oauth:grant-type:token-exchange", "client_credentials"`,
 		},
 		{
 			name: "bad scope",
@@ -250,26 +250,26 @@ func TestOIDCClientStaticValidation_Parallel(t *testing.T) {
 		{
 			name:    "empty unset all",
 			client:  &supervisorconfigv1alpha1.OIDCClient{},
-			wantErr: `OIDCClient.config.supervisor.pinniped.dev "" is invalid: [metadata.name: Required value: name or generateName is required, spec.allowedGrantTypes: Required value, spec.allowedRedirectURIs: Required value, spec.allowedScopes: Required value]`,
+			wantErr: `OIDCClient.config.supervisor.pinniped.dev "" is invalid: [metadata.name: Required value: name or generateName is required, spec.allowedGrantTypes: Required value, spec.allowedScopes: Required value]`,
 			skip:    reallyOld, // the error is both different and has unstable order on older servers
 		},
 		{
-			name: "empty uris",
+			name: "empty client credentials username",
 			client: &supervisorconfigv1alpha1.OIDCClient{
 				ObjectMeta: metav1.ObjectMeta{
 					Name: "client.oauth.pinniped.dev-green-1",
 				},
 				Spec: supervisorconfigv1alpha1.OIDCClientSpec{
-					AllowedRedirectURIs: []supervisorconfigv1alpha1.RedirectURI{},
 					AllowedGrantTypes: []supervisorconfigv1alpha1.GrantType{
-						"refresh_token",
+						"client_credentials",
 					},
 					AllowedScopes: []supervisorconfigv1alpha1.Scope{
 						"username",
 					},
+					ClientCredentials: &supervisorconfigv1alpha1.OIDCClientClientCredentials{},
 				},
 			},
-			wantErr: `OIDCClient.config.supervisor.pinniped.dev "client.oauth.pinniped.dev-green-1" is invalid: spec.allowedRedirectURIs: Invalid value: 0: spec.allowedRedirectURIs in body should have at least 1 items`,
+			wantErr: `OIDCClient.config.supervisor.pinniped.dev "client.oauth.pinniped.dev-green-1" is invalid: spec.clientCredentials.username: Invalid value: "": spec.clientCredentials.username in body should be at least 1 chars long`,
 		},
 		{
 			name: "empty grants",
@@ -407,7 +407,7 @@ func TestOIDCClientStaticValidation_Parallel(t *testing.T) {
 				statusErr.ErrStatus.Message = errPrefix + strings.Join(out, ", ") + "]"
 				return want // leave the wanted error unchanged
 			},
-			wantErr: `OIDCClient.config.supervisor.pinniped.dev "zone" is invalid: [metadata.name: Invalid value: "zone": metadata.name in body should match '^client\.oauth\.pinniped\.dev-', spec.allowedGrantTypes[0]: Unsupported value: "the": supported values: "authorization_code", "refresh_token", "urn:ietf:params:oauth:grant-type:token-exchange", spec.allowedRedirectURIs[0]: Invalid value: "of": spec.allowedRedirectURIs[0] in body should match '^https://.+|^http://(127\.0\.0\.1|\[::1\])(:\d+)?/', spec.allowedScopes[0]: Unsupported value: "enders": supported values: "openid", "offline_access", "username", "groups", "pinniped:request-audience"]`,
+			wantErr: `OIDCClient.config.supervisor.pinniped.dev "zone" is invalid: [metadata.name: Invalid value: "zone": metadata.name in body should match '^client\.oauth\.pinniped\.dev-', spec.allowedGrantTypes[0]: Unsupported value: "the": supported values: "authorization_code", "refresh_token", "urn:ietf:params:oauth:grant-type:token-exchange", "client_credentials", spec.allowedRedirectURIs[0]: Invalid value: "of": spec.allowedRedirectURIs[0] in body should match '^https://.+|^http://(127\.0\.0\.1|\[::1\])(:\d+)?/', spec.allowedScopes[0]: Unsupported value: "enders": supported values: "openid", "offline_access", "username", "groups", "pinniped:request-audience"]`,
 		},
 		{
 			name: "just the prefix is not valid",
@@ -483,6 +483,31 @@ func TestOIDCClientStaticValidation_Parallel(t *testing.T) {
 			},
 			wantErr: "",
 		},
+		{
+			name: "client credentials without redirect uris",
+			client: &supervisorconfigv1alpha1.OIDCClient{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "client.oauth.pinniped.dev-basalt",
+				},
+				Spec: supervisorconfigv1alpha1.OIDCClientSpec{
+					AllowedGrantTypes: []supervisorconfigv1alpha1.GrantType{
+						"client_credentials",
+						"urn:ietf:params:oauth:grant-type:token-exchange",
+					},
+					AllowedScopes: []supervisorconfigv1alpha1.Scope{
+						"openid",
+						"username",
+						"groups",
+						"pinniped:request-audience",
+					},
+					ClientCredentials: &supervisorconfigv1alpha1.OIDCClientClientCredentials{
+						Username: "ci-bot",
+						Groups:   []string{"ci", "deployers"},
+					},
+				},
+			},
+			wantErr: "",
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -594,7 +619,7 @@ func TestOIDCClientControllerValidations_Parallel(t *testing.T) {
 					Type:    "AllowedGrantTypesValid",
 					Status:  "False",
 					Reason:  "MissingRequiredValue",
-					Message: `"authorization_code" must always be included in "allowedGrantTypes"`,
+					Message: `"authorization_code" or "client_credentials" must be included in "allowedGrantTypes"`,
 				},
 				{
 					Type:    "AllowedScopesValid",
@@ -602,6 +627,12 @@ func TestOIDCClientControllerValidations_Parallel(t *testing.T) {
 					Reason:  "MissingRequiredValue",
 					Message: `"openid" must always be included in "allowedScopes"; "offline_access" must be included in "allowedScopes" when "refresh_token" is included in "allowedGrantTypes"`,
 				},
+				{
+					Type:    "ClientAuthenticationValid",
+					Status:  "True",
+					Reason:  "Success",
+					Message: `client authentication method "client_secret_basic" is valid`,
+				},
 				{
 					Type:    "ClientSecretExists",
 					Status:  "False",
@@ -637,6 +668,12 @@ func TestOIDCClientControllerValidations_Parallel(t *testing.T) {
 					Reason:  "Success",
 					Message: `"allowedScopes" is valid`,
 				},
+				{
+					Type:    "ClientAuthenticationValid",
+					Status:  "True",
+					Reason:  "Success",
+					Message: `client authentication method "client_secret_basic" is valid`,
+				},
 				{
 					Type:    "ClientSecretExists",
 					Status:  "False",
@@ -672,6 +709,12 @@ func TestOIDCClientControllerValidations_Parallel(t *testing.T) {
 					Reason:  "Success",
 					Message: `"allowedScopes" is valid`,
 				},
+				{
+					Type:    "ClientAuthenticationValid",
+					Status:  "True",
+					Reason:  "Success",
+					Message: `client authentication method "client_secret_basic" is valid`,
+				},
 				{
 					Type:    "ClientSecretExists",
 					Status:  "True",
@@ -680,6 +723,50 @@ func TestOIDCClientControllerValidations_Parallel(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "happy path example with private_key_jwt client authentication, which does not need any client secrets",
+			client: &supervisorconfigv1alpha1.OIDCClient{
+				ObjectMeta: metav1.ObjectMeta{
+					GenerateName: "client.oauth.pinniped.dev-",
+				},
+				Spec: supervisorconfigv1alpha1.OIDCClientSpec{
+					AllowedRedirectURIs:     []supervisorconfigv1alpha1.RedirectURI{"https://some-redirect-url.test.pinniped.dev/some/path"},
+					AllowedGrantTypes:       []supervisorconfigv1alpha1.GrantType{"authorization_code"},
+					AllowedScopes:           []supervisorconfigv1alpha1.Scope{"openid"},
+					TokenEndpointAuthMethod: supervisorconfigv1alpha1.TokenEndpointAuthMethodPrivateKeyJWT,
+					PrivateKeyJWT: &supervisorconfigv1alpha1.OIDCClientPrivateKeyJWT{
+						JWKSURI: "https://some-client.test.pinniped.dev/jwks.json",
+					},
+				},
+			},
+			wantPhase: "Ready",
+			wantConditions: []metav1.Condition{
+				{
+					Type:    "AllowedGrantTypesValid",
+					Status:  "True",
+					Reason:  "Success",
+					Message: `"allowedGrantTypes" is valid`,
+				},
+				{
+					Type:    "AllowedScopesValid",
+					Status:  "True",
+					Reason:  "Success",
+					Message: `"allowedScopes" is valid`,
+				},
+				{
+					Type:    "ClientAuthenticationValid",
+					Status:  "True",
+					Reason:  "Success",
+					Message: `client authentication method "private_key_jwt" is valid`,
+				},
+				{
+					Type:    "ClientSecretExists",
+					Status:  "True",
+					Reason:  "NotRequired",
+					Message: `client secrets are not used when "tokenEndpointAuthMethod" is "private_key_jwt"`,
+				},
+			},
+		},
 		// Note: there are many more possible combinations of these settings, but they are covered by the controller's
 		// unit tests. This test ensures that everything is wired up correctly in regard to this controller, enough to
 		// allow the controller to work correctly.